@@ -0,0 +1,358 @@
+// ib builds images with the image builder api, e.g. in automation which
+// would otherwise use curl.
+//
+// It authenticates with the first of these which is set:
+//
+//	IMAGE_BUILDER_API_TOKEN       api token created with POST /tokens
+//	IMAGE_BUILDER_CLIENT_SECRET   secret of the service account IMAGE_BUILDER_CLIENT_ID
+//	IMAGE_BUILDER_OFFLINE_TOKEN   offline token of access.redhat.com
+//	IMAGE_BUILDER_IDENTITY        identity header, for a service run locally
+//
+// IMAGE_BUILDER_URL and IMAGE_BUILDER_TOKEN_URL point it at other
+// deployments.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/osbuild/image-builder/internal/client"
+	v1 "github.com/osbuild/image-builder/internal/v1"
+)
+
+// how long a wait for a compose asks the service to hold each request
+const statusPollWait = 60 * time.Second
+
+type cli struct {
+	client *client.Client
+	out    io.Writer
+	json   bool
+
+	// persistent flags of the root command
+	url    string
+	output string
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err := newRootCommand(os.Stdout).ExecuteContext(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ib: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func newRootCommand(out io.Writer) *cobra.Command {
+	c := &cli{out: out}
+	root := &cobra.Command{
+		Use:           "ib",
+		Short:         "Build images with image builder",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		// the client is only set up once the command and its flags parsed
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.setup()
+		},
+	}
+	root.SetOut(out)
+	root.CompletionOptions.DisableDefaultCmd = true
+	root.PersistentFlags().StringVar(&c.url, "url", os.Getenv("IMAGE_BUILDER_URL"), "url of image builder")
+	root.PersistentFlags().StringVarP(&c.output, "output", "o", "table", "output format, table or json")
+
+	compose := &cobra.Command{
+		Use:   "compose",
+		Short: "Compose images and follow their status",
+	}
+	compose.AddCommand(
+		c.composeCreateCommand(),
+		c.composeListCommand(),
+		c.composeStatusCommand(),
+		c.composeLogsCommand(),
+	)
+	root.AddCommand(compose, c.cloneCommand())
+	return root
+}
+
+func (c *cli) setup() error {
+	if c.output != "table" && c.output != "json" {
+		return fmt.Errorf("unknown output format %q", c.output)
+	}
+	c.json = c.output == "json"
+
+	var err error
+	c.client, err = client.New(client.Config{
+		URL:          c.url,
+		APIToken:     os.Getenv("IMAGE_BUILDER_API_TOKEN"),
+		TokenURL:     os.Getenv("IMAGE_BUILDER_TOKEN_URL"),
+		ClientId:     os.Getenv("IMAGE_BUILDER_CLIENT_ID"),
+		ClientSecret: os.Getenv("IMAGE_BUILDER_CLIENT_SECRET"),
+		OfflineToken: os.Getenv("IMAGE_BUILDER_OFFLINE_TOKEN"),
+		Identity:     os.Getenv("IMAGE_BUILDER_IDENTITY"),
+	})
+	return err
+}
+
+// readRequest reads a request from a yaml or json file, "-" is stdin. Yaml is
+// turned into json, so the unions of the api decode the same way.
+func readRequest(path string, request interface{}) error {
+	var raw []byte
+	var err error
+	if path == "-" {
+		raw, err = io.ReadAll(os.Stdin)
+	} else {
+		raw, err = os.ReadFile(filepath.Clean(path))
+	}
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	err = yaml.Unmarshal(raw, &doc)
+	if err != nil {
+		return fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+	raw, err = json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+	err = json.Unmarshal(raw, request)
+	if err != nil {
+		return fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+	return nil
+}
+
+// idArg accepts the id of a compose or clone as the only argument.
+func idArg(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%s expects one id, got %d arguments", cmd.CommandPath(), len(args))
+	}
+	_, err := uuid.Parse(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid id %q", args[0])
+	}
+	return nil
+}
+
+func (c *cli) printJSON(v interface{}) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (c *cli) printTable(header []string, rows [][]string) error {
+	w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, r := range rows {
+		fmt.Fprintln(w, strings.Join(r, "\t"))
+	}
+	return w.Flush()
+}
+
+func (c *cli) composeCreateCommand() *cobra.Command {
+	var file string
+	var wait bool
+	cmd := &cobra.Command{
+		Use:   "create -f REQUEST",
+		Short: "Compose the image of a compose request in yaml or json",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var cr v1.ComposeRequest
+			err := readRequest(file, &cr)
+			if err != nil {
+				return err
+			}
+			id, err := c.client.Compose(cmd.Context(), cr)
+			if err != nil {
+				return err
+			}
+			if !wait {
+				if c.json {
+					return c.printJSON(v1.ComposeResponse{Id: id})
+				}
+				return c.printTable([]string{"ID"}, [][]string{{id.String()}})
+			}
+			return c.waitForCompose(cmd.Context(), id)
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "compose request, in yaml or json")
+	cmd.Flags().BoolVar(&wait, "wait", false, "wait for the compose to finish")
+	_ = cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+// waitForCompose long-polls the status of a compose until it finished, and
+// fails if the compose failed.
+func (c *cli) waitForCompose(ctx context.Context, id uuid.UUID) error {
+	for {
+		status, err := c.client.ComposeStatus(ctx, id, statusPollWait)
+		if err != nil {
+			return err
+		}
+		switch status.ImageStatus.Status {
+		case v1.ImageStatusStatusSuccess:
+			return c.printStatus(id, status)
+		case v1.ImageStatusStatusFailure:
+			err = c.printStatus(id, status)
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("compose %s failed", id)
+		}
+	}
+}
+
+func (c *cli) composeListCommand() *cobra.Command {
+	var limit, offset int
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the composes of the last 14 days",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			composes, err := c.client.Composes(cmd.Context(), limit, offset)
+			if err != nil {
+				return err
+			}
+			if c.json {
+				return c.printJSON(composes)
+			}
+			var rows [][]string
+			for _, cp := range composes.Data {
+				name := ""
+				if cp.ImageName != nil {
+					name = *cp.ImageName
+				}
+				imageType := ""
+				if len(cp.Request.ImageRequests) > 0 {
+					imageType = string(cp.Request.ImageRequests[0].ImageType)
+				}
+				rows = append(rows, []string{cp.Id.String(), name, string(cp.Request.Distribution), imageType, cp.CreatedAt})
+			}
+			return c.printTable([]string{"ID", "NAME", "DISTRIBUTION", "IMAGE TYPE", "CREATED"}, rows)
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 100, "most composes to list")
+	cmd.Flags().IntVar(&offset, "offset", 0, "composes to skip")
+	return cmd
+}
+
+func (c *cli) composeStatusCommand() *cobra.Command {
+	var wait bool
+	cmd := &cobra.Command{
+		Use:   "status ID",
+		Short: "Show the status of a compose",
+		Args:  idArg,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id := uuid.MustParse(args[0])
+			if wait {
+				return c.waitForCompose(cmd.Context(), id)
+			}
+			status, err := c.client.ComposeStatus(cmd.Context(), id, 0)
+			if err != nil {
+				return err
+			}
+			return c.printStatus(id, status)
+		},
+	}
+	cmd.Flags().BoolVar(&wait, "wait", false, "wait for the compose to finish")
+	return cmd
+}
+
+func (c *cli) printStatus(id uuid.UUID, status *v1.ComposeStatus) error {
+	if c.json {
+		return c.printJSON(status)
+	}
+	upload := ""
+	if us := status.ImageStatus.UploadStatus; us != nil {
+		upload = fmt.Sprintf("%s %s", us.Type, us.Status)
+	}
+	reason := ""
+	if e := status.ImageStatus.Error; e != nil {
+		reason = e.Reason
+	}
+	return c.printTable([]string{"ID", "STATUS", "UPLOAD", "ERROR"}, [][]string{{id.String(), string(status.ImageStatus.Status), upload, reason}})
+}
+
+func (c *cli) composeLogsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "logs ID",
+		Short: "Show the statuses a compose went through",
+		Args:  idArg,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			events, err := c.client.ComposeEvents(cmd.Context(), uuid.MustParse(args[0]))
+			if err != nil {
+				return err
+			}
+			if c.json {
+				return c.printJSON(events)
+			}
+			var rows [][]string
+			for _, e := range events.Data {
+				reason := ""
+				if e.Reason != nil {
+					reason = *e.Reason
+				}
+				rows = append(rows, []string{e.CreatedAt, e.Status, reason})
+			}
+			return c.printTable([]string{"TIME", "STATUS", "REASON"}, rows)
+		},
+	}
+}
+
+// cloneCommand clones the image of a compose, a clone is followed with the
+// status subcommand.
+func (c *cli) cloneCommand() *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "clone -f REQUEST ID",
+		Short: "Clone the image of a compose, e.g. to another region",
+		Args:  idArg,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var clone v1.CloneRequest
+			err := readRequest(file, &clone)
+			if err != nil {
+				return err
+			}
+			cloneId, err := c.client.Clone(cmd.Context(), uuid.MustParse(args[0]), clone)
+			if err != nil {
+				return err
+			}
+			if c.json {
+				return c.printJSON(v1.CloneResponse{Id: cloneId})
+			}
+			return c.printTable([]string{"ID"}, [][]string{{cloneId.String()}})
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "clone request, in yaml or json")
+	_ = cmd.MarkFlagRequired("file")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status ID",
+		Short: "Show the status of a clone",
+		Args:  idArg,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id := uuid.MustParse(args[0])
+			us, err := c.client.CloneStatus(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			if c.json {
+				return c.printJSON(us)
+			}
+			return c.printTable([]string{"ID", "TYPE", "STATUS"}, [][]string{{id.String(), string(us.Type), string(us.Status)}})
+		},
+	})
+	return cmd
+}
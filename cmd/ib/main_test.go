@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/osbuild/image-builder/internal/v1"
+)
+
+// runIB runs ib against srv and returns what it printed.
+func runIB(t *testing.T, srv *httptest.Server, args ...string) (string, error) {
+	t.Helper()
+	t.Setenv("IMAGE_BUILDER_IDENTITY", "identity")
+	var out bytes.Buffer
+	root := newRootCommand(&out)
+	root.SetErr(&out)
+	if srv != nil {
+		args = append([]string{"--url", srv.URL}, args...)
+	}
+	root.SetArgs(args)
+	err := root.Execute()
+	return out.String(), err
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestParseErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	}))
+	defer srv.Close()
+
+	id := uuid.NewString()
+	for _, tc := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"build"}, `unknown command "build" for "ib"`},
+		{[]string{"compose", "create"}, `required flag(s) "file" not set`},
+		{[]string{"compose", "create", "-f", "request.yaml", id}, `unknown command "` + id + `" for "ib compose create"`},
+		{[]string{"compose", "status"}, "ib compose status expects one id, got 0 arguments"},
+		{[]string{"compose", "status", id, id}, "ib compose status expects one id, got 2 arguments"},
+		{[]string{"compose", "logs", "not-an-id"}, `invalid id "not-an-id"`},
+		{[]string{"compose", "list", "--limit", "many"}, `invalid argument "many" for "--limit" flag`},
+		{[]string{"compose", "list", "--wait"}, "unknown flag: --wait"},
+		{[]string{"clone", id}, `required flag(s) "file" not set`},
+		{[]string{"clone", "-f", "clone.yaml"}, "ib clone expects one id, got 0 arguments"},
+		{[]string{"clone", "status", "not-an-id"}, `invalid id "not-an-id"`},
+		{[]string{"-o", "yaml", "compose", "list"}, `unknown output format "yaml"`},
+	} {
+		_, err := runIB(t, srv, tc.args...)
+		require.Error(t, err, tc.args)
+		require.Contains(t, err.Error(), tc.err, tc.args)
+	}
+
+	// without credentials no client is set up
+	t.Setenv("IMAGE_BUILDER_IDENTITY", "")
+	root := newRootCommand(&bytes.Buffer{})
+	root.SetArgs([]string{"--url", srv.URL, "compose", "list"})
+	require.Error(t, root.Execute())
+}
+
+func TestHelp(t *testing.T) {
+	out, err := runIB(t, nil, "--help")
+	require.NoError(t, err)
+	require.Contains(t, out, "compose")
+	require.Contains(t, out, "clone")
+	require.Contains(t, out, "--output")
+
+	out, err = runIB(t, nil, "compose", "create", "--help")
+	require.NoError(t, err)
+	require.Contains(t, out, "--file")
+	require.Contains(t, out, "--wait")
+}
+
+func TestComposeCreate(t *testing.T) {
+	id := uuid.New()
+	var received v1.ComposeRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "identity", r.Header.Get("X-Rh-Identity"))
+		switch r.URL.Path {
+		case "/api/image-builder/v1/compose":
+			require.Equal(t, http.MethodPost, r.Method)
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+			require.NoError(t, json.NewEncoder(w).Encode(v1.ComposeResponse{Id: id}))
+		case "/api/image-builder/v1/composes/" + id.String():
+			require.Equal(t, "1m0s", r.URL.Query().Get("wait"))
+			require.NoError(t, json.NewEncoder(w).Encode(v1.ComposeStatus{
+				ImageStatus: v1.ImageStatus{Status: v1.ImageStatusStatusFailure, Error: &v1.ComposeStatusError{Reason: "depsolve failed"}},
+			}))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	request := writeFile(t, "request.yaml", `
+distribution: rhel-92
+image_requests:
+  - architecture: x86_64
+    image_type: guest-image
+    upload_request:
+      type: aws.s3
+      options: {}
+`)
+
+	out, err := runIB(t, srv, "compose", "create", "-f", request)
+	require.NoError(t, err)
+	require.Equal(t, "ID\n"+id.String()+"\n", out)
+	require.Equal(t, v1.Distributions("rhel-92"), received.Distribution)
+	require.Equal(t, v1.ImageTypesGuestImage, received.ImageRequests[0].ImageType)
+
+	out, err = runIB(t, srv, "-o", "json", "compose", "create", "--file", request)
+	require.NoError(t, err)
+	var resp v1.ComposeResponse
+	require.NoError(t, json.Unmarshal([]byte(out), &resp))
+	require.Equal(t, id, resp.Id)
+
+	// waiting for a failed compose fails
+	out, err = runIB(t, srv, "compose", "create", "-f", request, "--wait")
+	require.EqualError(t, err, "compose "+id.String()+" failed")
+	require.Contains(t, out, "depsolve failed")
+}
+
+func TestComposeList(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/image-builder/v1/composes", r.URL.Path)
+		require.Equal(t, "5", r.URL.Query().Get("limit"))
+		require.Equal(t, "10", r.URL.Query().Get("offset"))
+		require.NoError(t, json.NewEncoder(w).Encode(v1.ComposesResponse{}))
+	}))
+	defer srv.Close()
+
+	out, err := runIB(t, srv, "compose", "list", "--limit", "5", "--offset=10")
+	require.NoError(t, err)
+	require.Equal(t, "ID  NAME  DISTRIBUTION  IMAGE TYPE  CREATED\n", out)
+}
+
+func TestCloneStatus(t *testing.T) {
+	composeId := uuid.New()
+	cloneId := uuid.New()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/image-builder/v1/composes/" + composeId.String() + "/clone":
+			require.NoError(t, json.NewEncoder(w).Encode(v1.CloneResponse{Id: cloneId}))
+		case "/api/image-builder/v1/clones/" + cloneId.String():
+			require.NoError(t, json.NewEncoder(w).Encode(v1.UploadStatus{Type: v1.UploadTypesAws, Status: v1.UploadStatusStatusSuccess}))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	clone := writeFile(t, "clone.json", `{"region": "eu-central-1"}`)
+	out, err := runIB(t, srv, "clone", "-f", clone, composeId.String())
+	require.NoError(t, err)
+	require.Equal(t, "ID\n"+cloneId.String()+"\n", out)
+
+	out, err = runIB(t, srv, "clone", "status", cloneId.String())
+	require.NoError(t, err)
+	require.Contains(t, out, cloneId.String()+"  aws   success")
+}
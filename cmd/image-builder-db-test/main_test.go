@@ -4,6 +4,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os/exec"
 	"testing"
@@ -13,6 +14,7 @@ import (
 	"github.com/jackc/pgx/v4"
 	"github.com/stretchr/testify/require"
 
+	"github.com/osbuild/image-builder/internal/common"
 	"github.com/osbuild/image-builder/internal/config"
 	"github.com/osbuild/image-builder/internal/db"
 )
@@ -27,6 +29,7 @@ const (
 	ORGID3 = "100002"
 
 	EMAIL1 = "user1@test.test"
+	EMAIL2 = "user2@test.test"
 
 	fortnight = time.Hour * 24 * 14
 )
@@ -77,7 +80,40 @@ func connect(t *testing.T) *pgx.Conn {
 func tearDown(t *testing.T) {
 	conn := connect(t)
 	defer conn.Close(context.Background())
+	conn.Exec(context.Background(), "drop table org_events")
+	conn.Exec(context.Background(), "drop table outbox")
+	conn.Exec(context.Background(), "drop table awx_jobs")
+	conn.Exec(context.Background(), "drop table awx_settings")
+	conn.Exec(context.Background(), "drop table artifact_signing_settings")
+	conn.Exec(context.Background(), "drop table pulp_settings")
+	conn.Exec(context.Background(), "drop table signing_key_usage")
+	conn.Exec(context.Background(), "drop table signing_keys")
+	conn.Exec(context.Background(), "drop table compliance_exports")
+	conn.Exec(context.Background(), "drop table compliance_export_settings")
+	conn.Exec(context.Background(), "drop table webhook_deliveries")
+	conn.Exec(context.Background(), "drop table webhooks")
+	conn.Exec(context.Background(), "drop table launches")
+	conn.Exec(context.Background(), "drop table compose_replications")
+	conn.Exec(context.Background(), "drop table compose_encryptions")
 	conn.Exec(context.Background(), "drop table clones")
+	conn.Exec(context.Background(), "drop table compose_artifacts")
+	conn.Exec(context.Background(), "drop table compose_signatures")
+	conn.Exec(context.Background(), "drop table compose_commit_signatures")
+	conn.Exec(context.Background(), "drop table compose_vulnerability_scans")
+	conn.Exec(context.Background(), "drop table compose_sboms")
+	conn.Exec(context.Background(), "drop table api_tokens")
+	conn.Exec(context.Background(), "drop table compose_events")
+	conn.Exec(context.Background(), "drop table ip_allowlist")
+	conn.Exec(context.Background(), "drop table quotas")
+	conn.Exec(context.Background(), "drop table quota_boosts")
+	conn.Exec(context.Background(), "drop table compose_queue")
+	conn.Exec(context.Background(), "drop table build_reservations")
+	conn.Exec(context.Background(), "drop table upload_target_policies")
+	conn.Exec(context.Background(), "drop table approval_settings")
+	conn.Exec(context.Background(), "drop table secret_scanning_settings")
+	conn.Exec(context.Background(), "drop table repository_signature_settings")
+	conn.Exec(context.Background(), "drop table audit_log")
+	conn.Exec(context.Background(), "drop table aws_share_allowlist")
 	conn.Exec(context.Background(), "drop table composes")
 	conn.Exec(context.Background(), "drop table if exists schema_migrations")
 	conn.Exec(context.Background(), "drop table if exists schema_version")
@@ -126,6 +162,19 @@ func testGetCompose(t *testing.T) {
 	require.Equal(t, 4, count)
 	require.Equal(t, 1, len(composes))
 
+	// pages after a cursor follow each other
+	page, err := d.GetComposesAfter(ORGID1, fortnight, 3, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, page, 3)
+	rest, err := d.GetComposesAfter(ORGID1, fortnight, 3, &db.ComposeCursor{CreatedAt: page[2].CreatedAt, Id: page[2].Id}, nil)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	require.NotContains(t, page, rest[0])
+	require.True(t, !rest[0].CreatedAt.After(page[2].CreatedAt))
+	page, err = d.GetComposesAfter(ORGID2, fortnight, 3, nil, nil)
+	require.NoError(t, err)
+	require.Empty(t, page)
+
 	// GetCompose works as expected
 	compose, err := d.GetCompose(composes[0].Id, ORGID1)
 	require.NoError(t, err)
@@ -342,6 +391,1889 @@ func testClones(t *testing.T) {
 	require.Equal(t, clones[1], *entry)
 }
 
+func testLaunches(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	composeId := uuid.New()
+	// fkey constraint on compose id
+	require.Error(t, d.InsertLaunch(composeId, 1, "aws", []byte(`{"source_id": "1"}`)))
+
+	require.NoError(t, d.InsertCompose(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}")))
+	require.NoError(t, d.InsertLaunch(composeId, 1, "aws", []byte(`{"source_id": "1"}`)))
+	require.NoError(t, d.InsertLaunch(composeId, 2, "aws", []byte(`{"source_id": "2"}`)))
+	// reservations are unique
+	require.Error(t, d.InsertLaunch(composeId, 2, "aws", []byte(`{"source_id": "2"}`)))
+
+	launches, count, err := d.GetLaunchesForCompose(composeId, ORGID1, 1, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	require.Len(t, launches, 1)
+	require.Equal(t, int64(2), launches[0].ReservationId)
+
+	launches, count, err = d.GetLaunchesForCompose(composeId, ORGID2, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+	require.Empty(t, launches)
+
+	launch, err := d.GetLaunch(1, ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, composeId, launch.ComposeId)
+	require.Equal(t, "aws", launch.Provider)
+	require.JSONEq(t, `{"source_id": "1"}`, string(launch.Request))
+
+	_, err = d.GetLaunch(1, ORGID2)
+	require.ErrorIs(t, err, db.LaunchNotFoundError)
+}
+
+func testAWX(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	_, err = d.GetAWXSettings(ORGID1)
+	require.ErrorIs(t, err, db.AWXSettingsNotFoundError)
+	require.ErrorIs(t, d.DeleteAWXSettings(ORGID1), db.AWXSettingsNotFoundError)
+
+	token := db.SealedSecretEntry{KEKId: "kek1", EncryptedDataKey: []byte("data key"), Ciphertext: []byte("token")}
+	token2 := db.SealedSecretEntry{KEKId: "kek1", EncryptedDataKey: []byte("data key"), Ciphertext: []byte("token2")}
+	require.NoError(t, d.SetAWXSettings(db.AWXSettingsEntry{OrgId: ORGID1, URL: "https://awx.example.com", Token: token, JobTemplateId: 1}))
+	require.NoError(t, d.SetAWXSettings(db.AWXSettingsEntry{OrgId: ORGID1, URL: "https://awx.example.com", Token: token2, JobTemplateId: 7}))
+	settings, err := d.GetAWXSettings(ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, token2, settings.Token)
+	require.Equal(t, 7, settings.JobTemplateId)
+
+	composeId := uuid.New()
+	require.NoError(t, d.InsertCompose(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}")))
+	require.NoError(t, d.SetAWXJobResult(composeId, ORGID1, 7, db.AWXJobPending, nil, common.ToPtr("unreachable")))
+	require.NoError(t, d.SetAWXJobResult(composeId, ORGID1, 7, db.AWXJobLaunched, common.ToPtr(42), nil))
+
+	jobs, count, err := d.GetAWXJobs(ORGID1, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Equal(t, composeId, jobs[0].ComposeId)
+	require.Equal(t, db.AWXJobLaunched, jobs[0].Status)
+	require.Equal(t, 42, *jobs[0].JobId)
+	require.Equal(t, 2, jobs[0].Attempts)
+	require.Nil(t, jobs[0].LastError)
+	require.NotNil(t, jobs[0].LaunchedAt)
+
+	_, count, err = d.GetAWXJobs(ORGID2, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	require.NoError(t, d.DeleteAWXSettings(ORGID1))
+	_, err = d.GetAWXSettings(ORGID1)
+	require.ErrorIs(t, err, db.AWXSettingsNotFoundError)
+}
+
+func testPulpSettings(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	_, err = d.GetPulpSettings(ORGID1)
+	require.ErrorIs(t, err, db.PulpSettingsNotFoundError)
+	require.ErrorIs(t, d.DeletePulpSettings(ORGID1), db.PulpSettingsNotFoundError)
+
+	require.NoError(t, d.SetPulpSettings(db.PulpSettingsEntry{OrgId: ORGID1, ServerAddress: "https://pulp.example.com"}))
+	require.NoError(t, d.SetPulpSettings(db.PulpSettingsEntry{OrgId: ORGID1, ServerAddress: "https://pulp.example.com", Basepath: common.ToPtr("edge/rhel-9")}))
+	settings, err := d.GetPulpSettings(ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, "https://pulp.example.com", settings.ServerAddress)
+	require.Nil(t, settings.Repository)
+	require.Equal(t, "edge/rhel-9", *settings.Basepath)
+	_, err = d.GetPulpSettings(ORGID2)
+	require.ErrorIs(t, err, db.PulpSettingsNotFoundError)
+
+	require.NoError(t, d.DeletePulpSettings(ORGID1))
+	_, err = d.GetPulpSettings(ORGID1)
+	require.ErrorIs(t, err, db.PulpSettingsNotFoundError)
+}
+
+func testArtifactSigningSettings(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	_, err = d.GetArtifactSigningSettings(ORGID1)
+	require.ErrorIs(t, err, db.ArtifactSigningSettingsNotFoundError)
+	require.ErrorIs(t, d.DeleteArtifactSigningSettings(ORGID1), db.ArtifactSigningSettingsNotFoundError)
+
+	require.NoError(t, d.SetArtifactSigningSettings(db.ArtifactSigningSettingsEntry{OrgId: ORGID1}))
+	require.NoError(t, d.SetArtifactSigningSettings(db.ArtifactSigningSettingsEntry{OrgId: ORGID1, ServiceURL: common.ToPtr("https://sign.example.com")}))
+	settings, err := d.GetArtifactSigningSettings(ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, "https://sign.example.com", *settings.ServiceURL)
+	require.False(t, settings.UpdatedAt.IsZero())
+
+	// the private keys and tokens of settings from before they were signing
+	// keys are moved to them
+	conn := connect(t)
+	defer conn.Close(context.Background())
+	_, err = conn.Exec(context.Background(), "INSERT INTO artifact_signing_settings(org_id, private_key, service_token, updated_at) VALUES ($1, 'private key', 'token', CURRENT_TIMESTAMP)", ORGID2)
+	require.NoError(t, err)
+	plaintext, err := d.GetPlaintextArtifactSigningSecrets()
+	require.NoError(t, err)
+	require.Len(t, plaintext, 1)
+	require.Equal(t, ORGID2, plaintext[0].OrgId)
+	require.Equal(t, "private key", *plaintext[0].PrivateKey)
+	require.Equal(t, "token", *plaintext[0].ServiceToken)
+	key := db.SigningKeyEntry{Id: uuid.New(), OrgId: ORGID2, Purpose: "artifact", KEKId: "kek1", EncryptedDataKey: []byte("data key"), Ciphertext: []byte("private key")}
+	require.NoError(t, d.MoveArtifactSigningSecrets(ORGID2, []db.SigningKeyEntry{key}))
+	plaintext, err = d.GetPlaintextArtifactSigningSecrets()
+	require.NoError(t, err)
+	require.Empty(t, plaintext)
+	moved, err := d.GetSigningKeyInUse(ORGID2, "artifact")
+	require.NoError(t, err)
+	require.Equal(t, key.Id, moved.Id)
+	require.NoError(t, d.DeleteArtifactSigningSettings(ORGID2))
+
+	_, err = d.GetArtifactSigningSettings(ORGID2)
+	require.ErrorIs(t, err, db.ArtifactSigningSettingsNotFoundError)
+
+	require.NoError(t, d.DeleteArtifactSigningSettings(ORGID1))
+	_, err = d.GetArtifactSigningSettings(ORGID1)
+	require.ErrorIs(t, err, db.ArtifactSigningSettingsNotFoundError)
+}
+
+func testAWSShareAllowList(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+	conn := connect(t)
+	defer conn.Close(context.Background())
+
+	accounts, err := d.GetAWSShareAllowList(ORGID1)
+	require.NoError(t, err)
+	require.Empty(t, accounts)
+
+	_, err = conn.Exec(context.Background(), "INSERT INTO aws_share_allowlist(org_id, account_id) VALUES ($1, $2), ($1, $3), ($4, $5)",
+		ORGID1, "222222222222", "111111111111", ORGID2, "333333333333")
+	require.NoError(t, err)
+
+	accounts, err = d.GetAWSShareAllowList(ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"111111111111", "222222222222"}, accounts)
+
+	accounts, err = d.GetAWSShareAllowList(ORGID3)
+	require.NoError(t, err)
+	require.Empty(t, accounts)
+}
+
+func testComposeArtifacts(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	composeId := uuid.New()
+	err = d.InsertCompose(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}"))
+	require.NoError(t, err)
+
+	artifacts, err := d.GetComposeArtifacts(composeId, ORGID1)
+	require.NoError(t, err)
+	require.Empty(t, artifacts)
+
+	ami := "ami-0c830793775595d4b"
+	expected := []db.ArtifactEntry{
+		// cloud images have no size and checksum
+		{
+			Filename:     ami,
+			CloudImageId: &ami,
+		},
+		{
+			Filename: "disk.qcow2",
+			Size:     common.ToPtr(int64(1024)),
+			Sha256:   common.ToPtr("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"),
+		},
+		{
+			Filename: "image.raw",
+			Size:     common.ToPtr(int64(4294967296)),
+			Sha256:   common.ToPtr("a948904f2f0f479b8f8197694b30184b0d2ed1c1cd2a1ec0fb85d299a192a447"),
+		},
+	}
+	err = d.InsertComposeArtifacts(composeId, expected)
+	require.NoError(t, err)
+	// inserting the same artifacts again is a no-op
+	err = d.InsertComposeArtifacts(composeId, expected)
+	require.NoError(t, err)
+
+	artifacts, err = d.GetComposeArtifacts(composeId, ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, expected, artifacts)
+
+	// artifacts are only visible to the org owning the compose
+	artifacts, err = d.GetComposeArtifacts(composeId, ORGID2)
+	require.NoError(t, err)
+	require.Empty(t, artifacts)
+
+	signature := "-----BEGIN PGP SIGNATURE-----"
+	require.NoError(t, d.SetComposeArtifactSignature(composeId, "disk.qcow2", signature))
+	expected[1].Signature = &signature
+	artifacts, err = d.GetComposeArtifacts(composeId, ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, expected, artifacts)
+
+	// artifacts signed while they were measured are stored with their
+	// signature
+	other := uuid.New()
+	require.NoError(t, d.InsertCompose(other, ANR1, EMAIL1, ORGID1, nil, []byte("{}")))
+	signed := []db.ArtifactEntry{{
+		Filename:  "disk.qcow2",
+		Size:      common.ToPtr(int64(1024)),
+		Sha256:    common.ToPtr("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"),
+		Signature: &signature,
+	}}
+	require.NoError(t, d.InsertComposeArtifacts(other, signed))
+	artifacts, err = d.GetComposeArtifacts(other, ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, signed, artifacts)
+
+	size, err := d.GetStorageUsage(ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, int64(4294969344), size)
+	size, err = d.GetStorageUsage(ORGID2)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), size)
+
+	// deleted composes don't take up storage anymore
+	err = d.DeleteCompose(composeId, ORGID1)
+	require.NoError(t, err)
+	size, err = d.GetStorageUsage(ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), size)
+}
+
+func testComposeSignatures(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	composeId := uuid.New()
+	reference := "quay.io/myorg/edge:sha256-f5fb.sig"
+	// fkey constraint on compose id
+	require.Error(t, d.SetComposeSignatureResult(composeId, db.ComposeSignatureSigned, &reference, nil))
+
+	require.NoError(t, d.InsertCompose(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}")))
+	_, err = d.GetComposeSignature(composeId, ORGID1)
+	require.ErrorIs(t, err, db.ComposeSignatureNotFoundError)
+
+	require.NoError(t, d.SetComposeSignatureResult(composeId, db.ComposeSignaturePending, nil, common.ToPtr("unauthorized")))
+	sig, err := d.GetComposeSignature(composeId, ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, db.ComposeSignaturePending, sig.Status)
+	require.Nil(t, sig.Reference)
+	require.Equal(t, 1, sig.Attempts)
+	require.Equal(t, "unauthorized", *sig.LastError)
+
+	require.NoError(t, d.SetComposeSignatureResult(composeId, db.ComposeSignatureSigned, &reference, nil))
+	// the first signature is kept
+	require.NoError(t, d.SetComposeSignatureResult(composeId, db.ComposeSignatureSigned, common.ToPtr("quay.io/myorg/other:sha256-f5fb.sig"), nil))
+	require.NoError(t, d.SetComposeSignatureResult(composeId, db.ComposeSignatureFailed, nil, common.ToPtr("unauthorized")))
+	sig, err = d.GetComposeSignature(composeId, ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, db.ComposeSignatureSigned, sig.Status)
+	require.Equal(t, reference, *sig.Reference)
+	require.Equal(t, 2, sig.Attempts)
+	require.Nil(t, sig.LastError)
+
+	_, err = d.GetComposeSignature(composeId, ORGID2)
+	require.ErrorIs(t, err, db.ComposeSignatureNotFoundError)
+}
+
+func testCommitSignatures(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	composeId := uuid.New()
+	commit := "b0bca2a4e6b1d6a84f0c6e1c3c47f5e1cdcc35a8fe6d2b16c4a4c06fbc1d8d2e"
+	// fkey constraint on compose id
+	require.Error(t, d.InsertCommitSignature(composeId, db.CommitSignatureEntry{Commit: commit, Signature: []byte{0x89, 0x02}}))
+
+	require.NoError(t, d.InsertCompose(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}")))
+	_, err = d.GetCommitSignature(composeId, ORGID1)
+	require.ErrorIs(t, err, db.CommitSignatureNotFoundError)
+
+	require.NoError(t, d.InsertCommitSignature(composeId, db.CommitSignatureEntry{Commit: commit, Signature: []byte{0x89, 0x02}}))
+	// the first signature is kept
+	require.NoError(t, d.InsertCommitSignature(composeId, db.CommitSignatureEntry{Commit: commit, Signature: []byte{0x89, 0x03}}))
+	signature, err := d.GetCommitSignature(composeId, ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, &db.CommitSignatureEntry{Commit: commit, Signature: []byte{0x89, 0x02}}, signature)
+
+	_, err = d.GetCommitSignature(composeId, ORGID2)
+	require.ErrorIs(t, err, db.CommitSignatureNotFoundError)
+}
+
+func testVulnerabilityScans(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	composeId := uuid.New()
+	findings := []byte(`[{"id": "CVE-2023-0001", "package": "bash", "severity": "high"}]`)
+	// fkey constraint on compose id
+	require.Error(t, d.InsertVulnerabilityScan(composeId, db.VulnerabilityScanEntry{Scanner: "trivy", Findings: findings}))
+
+	require.NoError(t, d.InsertCompose(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}")))
+	_, err = d.GetVulnerabilityScan(composeId, ORGID1)
+	require.ErrorIs(t, err, db.VulnerabilityScanNotFoundError)
+
+	require.NoError(t, d.InsertVulnerabilityScan(composeId, db.VulnerabilityScanEntry{Scanner: "trivy", Findings: findings}))
+	// the first scan is kept
+	require.NoError(t, d.InsertVulnerabilityScan(composeId, db.VulnerabilityScanEntry{Scanner: "trivy", Findings: []byte(`[]`)}))
+	scan, err := d.GetVulnerabilityScan(composeId, ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, "trivy", scan.Scanner)
+	require.JSONEq(t, string(findings), string(scan.Findings))
+	require.False(t, scan.ScannedAt.IsZero())
+
+	_, err = d.GetVulnerabilityScan(composeId, ORGID2)
+	require.ErrorIs(t, err, db.VulnerabilityScanNotFoundError)
+}
+
+func testComposeSBOMs(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	composeId := uuid.New()
+	// fkey constraint on compose id
+	require.Error(t, d.InsertComposeSBOM(composeId, "spdx", []byte(`{"spdxVersion": "SPDX-2.3"}`)))
+
+	require.NoError(t, d.InsertCompose(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}")))
+	_, err = d.GetComposeSBOM(composeId, ORGID1, "spdx")
+	require.ErrorIs(t, err, db.ComposeSBOMNotFoundError)
+
+	require.NoError(t, d.InsertComposeSBOM(composeId, "spdx", []byte(`{"spdxVersion": "SPDX-2.3"}`)))
+	// the first document is kept
+	require.NoError(t, d.InsertComposeSBOM(composeId, "spdx", []byte(`{"spdxVersion": "SPDX-2.2"}`)))
+	require.NoError(t, d.InsertComposeSBOM(composeId, "cyclonedx", []byte(`{"bomFormat": "CycloneDX"}`)))
+	document, err := d.GetComposeSBOM(composeId, ORGID1, "spdx")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"spdxVersion": "SPDX-2.3"}`, string(document))
+	document, err = d.GetComposeSBOM(composeId, ORGID1, "cyclonedx")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"bomFormat": "CycloneDX"}`, string(document))
+
+	_, err = d.GetComposeSBOM(composeId, ORGID2, "spdx")
+	require.ErrorIs(t, err, db.ComposeSBOMNotFoundError)
+}
+
+func testAPITokens(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	tokens, err := d.GetAPITokens(ORGID1)
+	require.NoError(t, err)
+	require.Empty(t, tokens)
+
+	createdBy := "user1"
+	userId := "1234"
+	token := db.APITokenEntry{
+		Id:            uuid.New(),
+		OrgId:         ORGID1,
+		AccountNumber: common.ToPtr(ANR1),
+		Name:          "ci",
+		ReadOnly:      true,
+		UserId:        &userId,
+		CreatedBy:     &createdBy,
+	}
+	err = d.InsertAPIToken(token, "hash1")
+	require.NoError(t, err)
+	// hashes are unique
+	err = d.InsertAPIToken(db.APITokenEntry{Id: uuid.New(), OrgId: ORGID2, Name: "other"}, "hash1")
+	require.Error(t, err)
+	expiresAt := time.Now().Add(-time.Minute)
+	err = d.InsertAPIToken(db.APITokenEntry{Id: uuid.New(), OrgId: ORGID2, Name: "expired", ExpiresAt: &expiresAt}, "hash3")
+	require.NoError(t, err)
+
+	tokens, err = d.GetAPITokens(ORGID1)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.Equal(t, token.Id, tokens[0].Id)
+	require.Equal(t, "ci", tokens[0].Name)
+	require.True(t, tokens[0].ReadOnly)
+	require.Equal(t, &createdBy, tokens[0].CreatedBy)
+	require.Equal(t, &userId, tokens[0].UserId)
+	require.Equal(t, ANR1, *tokens[0].AccountNumber)
+	require.Nil(t, tokens[0].ExpiresAt)
+	require.Nil(t, tokens[0].LastUsedAt)
+
+	tokens, err = d.GetAPITokens(ORGID2)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.NotNil(t, tokens[0].ExpiresAt)
+
+	// using a token records it, expired tokens can't be used
+	entry, err := d.UseAPIToken("hash1")
+	require.NoError(t, err)
+	require.Equal(t, token.Id, entry.Id)
+	require.NotNil(t, entry.LastUsedAt)
+	_, err = d.UseAPIToken("hash2")
+	require.ErrorIs(t, err, db.APITokenNotFoundError)
+	_, err = d.UseAPIToken("hash3")
+	require.ErrorIs(t, err, db.APITokenNotFoundError)
+
+	// tokens can only be revoked by their org, and only once
+	err = d.RevokeAPIToken(token.Id, ORGID2)
+	require.ErrorIs(t, err, db.APITokenNotFoundError)
+	err = d.RevokeAPIToken(token.Id, ORGID1)
+	require.NoError(t, err)
+	err = d.RevokeAPIToken(token.Id, ORGID1)
+	require.ErrorIs(t, err, db.APITokenNotFoundError)
+
+	_, err = d.UseAPIToken("hash1")
+	require.ErrorIs(t, err, db.APITokenNotFoundError)
+	tokens, err = d.GetAPITokens(ORGID1)
+	require.NoError(t, err)
+	require.Empty(t, tokens)
+}
+
+func testComposeForSupport(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	imageName := "MyImageName"
+	composeId := uuid.New()
+	err = d.InsertCompose(composeId, ANR1, EMAIL1, ORGID1, &imageName, []byte("{}"))
+	require.NoError(t, err)
+	err = d.DeleteCompose(composeId, ORGID1)
+	require.NoError(t, err)
+
+	// deleted composes of any org are returned
+	compose, err := d.GetComposeForSupport(composeId)
+	require.NoError(t, err)
+	require.Equal(t, composeId, compose.Id)
+	require.Equal(t, ORGID1, compose.OrgId)
+	require.Equal(t, ANR1, compose.AccountNumber)
+	require.Equal(t, EMAIL1, *compose.Email)
+	require.Equal(t, &imageName, compose.ImageName)
+	require.True(t, compose.Deleted)
+
+	_, err = d.GetComposeForSupport(uuid.New())
+	require.ErrorIs(t, err, db.ComposeNotFoundError)
+
+	// composes are found by the ami of any of their images
+	otherId := uuid.New()
+	err = d.InsertCompose(otherId, ANR2, EMAIL2, ORGID2, &imageName, []byte("{}"))
+	require.NoError(t, err)
+	err = d.SetCachedComposeStatus(composeId, []byte(`{"image_status": {"status": "success", "upload_status": {"type": "aws", "options": {"ami": "ami-0123456789abcdef0"}}}}`))
+	require.NoError(t, err)
+	err = d.SetCachedComposeStatus(otherId, []byte(`{"image_status": {"status": "success"}, "image_statuses": [{"status": "success"}, {"status": "success", "upload_status": {"type": "aws", "options": {"ami": "ami-00000000"}}}]}`))
+	require.NoError(t, err)
+
+	ami := "ami-0123456789abcdef0"
+	composes, err := d.SearchComposesForSupport(&ami, nil, 10)
+	require.NoError(t, err)
+	require.Len(t, composes, 1)
+	require.Equal(t, composeId, composes[0].Id)
+	require.True(t, composes[0].Deleted)
+	ami = "ami-00000000"
+	composes, err = d.SearchComposesForSupport(&ami, nil, 10)
+	require.NoError(t, err)
+	require.Len(t, composes, 1)
+	require.Equal(t, otherId, composes[0].Id)
+	require.Equal(t, ORGID2, composes[0].OrgId)
+
+	// newest first
+	composes, err = d.SearchComposesForSupport(nil, &imageName, 10)
+	require.NoError(t, err)
+	require.Len(t, composes, 2)
+	require.Equal(t, otherId, composes[0].Id)
+	require.Equal(t, composeId, composes[1].Id)
+	composes, err = d.SearchComposesForSupport(nil, &imageName, 1)
+	require.NoError(t, err)
+	require.Len(t, composes, 1)
+	composes, err = d.SearchComposesForSupport(&ami, &imageName, 10)
+	require.NoError(t, err)
+	require.Len(t, composes, 1)
+	otherName := "OtherImageName"
+	composes, err = d.SearchComposesForSupport(&ami, &otherName, 10)
+	require.NoError(t, err)
+	require.Empty(t, composes)
+}
+
+func testOrgAnalytics(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	var ids []uuid.UUID
+	for _, orgId := range []string{ORGID1, ORGID1, ORGID1, ORGID2} {
+		id := uuid.New()
+		ids = append(ids, id)
+		err = d.InsertCompose(id, ANR1, EMAIL1, orgId, nil, []byte("{}"))
+		require.NoError(t, err)
+	}
+	// composes count by their last status
+	for i, statuses := range [][]string{{"building", "success"}, {"building", "failure"}, {"building"}, {"success"}} {
+		for _, s := range statuses {
+			_, err = d.InsertComposeEvent(ids[i], s, nil)
+			require.NoError(t, err)
+		}
+	}
+	err = d.InsertComposeArtifacts(ids[0], []db.ArtifactEntry{
+		{Filename: "disk.qcow2", Size: common.ToPtr(int64(1000)), Sha256: common.ToPtr("aa")},
+		{Filename: "disk.qcow2.sig", Size: common.ToPtr(int64(24)), Sha256: common.ToPtr("bb")},
+	})
+	require.NoError(t, err)
+	err = d.DeleteCompose(ids[0], ORGID1)
+	require.NoError(t, err)
+
+	since := time.Now().Add(-time.Hour)
+	until := time.Now().Add(time.Hour)
+	entries, err := d.GetOrgAnalytics("", since, until, "month")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, ORGID1, entries[0].OrgId)
+	require.Equal(t, 3, entries[0].Builds)
+	require.Equal(t, 1, entries[0].Succeeded)
+	require.Equal(t, 1, entries[0].Failed)
+	require.Equal(t, 2, entries[0].Artifacts)
+	require.Equal(t, int64(1024), entries[0].ArtifactBytes)
+	require.Equal(t, 1, entries[0].PeriodStart.Day())
+	require.Equal(t, ORGID2, entries[1].OrgId)
+	require.Equal(t, 1, entries[1].Succeeded)
+
+	entries, err = d.GetOrgAnalytics(ORGID2, since, until, "day")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, 1, entries[0].Builds)
+	require.Equal(t, int64(0), entries[0].ArtifactBytes)
+
+	entries, err = d.GetOrgAnalytics("", until, until.Add(time.Hour), "week")
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func testUsageReport(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	var ids []uuid.UUID
+	for _, request := range []string{
+		`{"image_requests": [{"image_type": "aws", "upload_request": {"type": "aws"}}]}`,
+		`{"image_requests": [{"image_type": "aws", "upload_request": {"type": "aws"}}]}`,
+		`{"image_requests": [{"image_type": "guest-image", "upload_request": {"type": "aws.s3"}}]}`,
+		`{}`,
+	} {
+		id := uuid.New()
+		ids = append(ids, id)
+		err = d.InsertCompose(id, ANR1, EMAIL1, ORGID1, nil, []byte(request))
+		require.NoError(t, err)
+	}
+	err = d.InsertCompose(uuid.New(), ANR2, EMAIL2, ORGID2, nil, []byte(`{}`))
+	require.NoError(t, err)
+	for i, statuses := range [][]string{{"building", "success"}, {"failure"}} {
+		for _, s := range statuses {
+			_, err = d.InsertComposeEvent(ids[i], s, nil)
+			require.NoError(t, err)
+		}
+	}
+	err = d.InsertComposeArtifacts(ids[0], []db.ArtifactEntry{{Filename: "image.raw", Size: common.ToPtr(int64(2048)), Sha256: common.ToPtr("aa")}})
+	require.NoError(t, err)
+
+	since := time.Now().Add(-time.Hour)
+	until := time.Now().Add(time.Hour)
+	entries, err := d.GetUsageReport(ORGID1, since, until)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	// composes without image requests have no image type
+	require.Equal(t, "", entries[0].ImageType)
+	require.Equal(t, "", entries[0].UploadTarget)
+	require.Equal(t, 1, entries[0].Builds)
+	require.Equal(t, "aws", entries[1].ImageType)
+	require.Equal(t, "aws", entries[1].UploadTarget)
+	require.Equal(t, 2, entries[1].Builds)
+	require.Equal(t, 1, entries[1].Succeeded)
+	require.Equal(t, 1, entries[1].Failed)
+	require.GreaterOrEqual(t, entries[1].BuildSeconds, int64(0))
+	require.Equal(t, int64(2048), entries[1].ArtifactBytes)
+	require.Equal(t, 1, entries[1].Month.Day())
+	require.Equal(t, "guest-image", entries[2].ImageType)
+	require.Equal(t, "aws.s3", entries[2].UploadTarget)
+	require.Equal(t, 0, entries[2].Succeeded)
+
+	entries, err = d.GetUsageReport("", since, until)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	require.Equal(t, ORGID2, entries[3].OrgId)
+
+	entries, err = d.GetUsageReport("", until, until.Add(time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func testBuildDurations(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	conn := connect(t)
+	defer conn.Close(context.Background())
+	insert := "INSERT INTO composes(job_id, request, created_at, account_number, org_id) VALUES ($1, $2, CURRENT_TIMESTAMP - $3::interval, $4, $5)"
+	request := `{"distribution": "rhel-9", "image_requests": [{"image_type": "aws", "architecture": "x86_64"}]}`
+	var ids []uuid.UUID
+	for i, ago := range []string{"10 minutes", "20 minutes", "30 minutes"} {
+		id := uuid.New()
+		ids = append(ids, id)
+		orgId := ORGID1
+		if i == 2 {
+			orgId = ORGID2
+		}
+		_, err = conn.Exec(context.Background(), insert, id, request, ago, ANR1, orgId)
+		require.NoError(t, err)
+	}
+	unfinished := uuid.New()
+	_, err = conn.Exec(context.Background(), insert, unfinished, request, "1 hour", ANR1, ORGID1)
+	require.NoError(t, err)
+	other := uuid.New()
+	_, err = conn.Exec(context.Background(), insert, other, `{"distribution": "rhel-8"}`, "5 minutes", ANR1, ORGID1)
+	require.NoError(t, err)
+
+	for _, id := range ids {
+		for _, s := range []string{"building", "success"} {
+			_, err = d.InsertComposeEvent(id, s, nil)
+			require.NoError(t, err)
+		}
+	}
+	// only the first success of a compose counts
+	for _, s := range []string{"failure", "success"} {
+		_, err = d.InsertComposeEvent(ids[0], s, nil)
+		require.NoError(t, err)
+	}
+	_, err = d.InsertComposeEvent(unfinished, "failure", nil)
+	require.NoError(t, err)
+	// queued composes are measured from their submission
+	for _, s := range []string{"queued", "submitted", "success"} {
+		_, err = d.InsertComposeEvent(other, s, nil)
+		require.NoError(t, err)
+	}
+
+	since := time.Now().Add(-time.Hour)
+	until := time.Now().Add(time.Hour)
+	entries, err := d.GetBuildDurations(since, until)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, db.BuildDurationEntry{Distribution: "rhel-8", Builds: 1, P50Seconds: entries[0].P50Seconds, P95Seconds: entries[0].P95Seconds}, entries[0])
+	require.InDelta(t, 0, entries[0].P50Seconds, 5)
+	require.Equal(t, "rhel-9", entries[1].Distribution)
+	require.Equal(t, "aws", entries[1].ImageType)
+	require.Equal(t, "x86_64", entries[1].Arch)
+	require.Equal(t, 3, entries[1].Builds)
+	require.InDelta(t, 1200, entries[1].P50Seconds, 5)
+	require.InDelta(t, 1740, entries[1].P95Seconds, 5)
+
+	entries, err = d.GetBuildDurations(until, until.Add(time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func testComposeEvents(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	composeId := uuid.New()
+	err = d.InsertCompose(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}"))
+	require.NoError(t, err)
+
+	events, err := d.GetComposeEvents(composeId)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	// repeated statuses are only recorded once
+	for i, s := range []string{"pending", "pending", "building", "building", "success"} {
+		recorded, err := d.InsertComposeEvent(composeId, s, nil)
+		require.NoError(t, err)
+		require.Equal(t, i != 1 && i != 3, recorded)
+	}
+
+	events, err = d.GetComposeEvents(composeId)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, "pending", events[0].Status)
+	require.Equal(t, "building", events[1].Status)
+	require.Equal(t, "success", events[2].Status)
+	require.Nil(t, events[2].Reason)
+
+	failed := uuid.New()
+	err = d.InsertCompose(failed, ANR1, EMAIL1, ORGID1, nil, []byte("{}"))
+	require.NoError(t, err)
+	_, err = d.InsertComposeEvent(failed, "created", nil)
+	require.NoError(t, err)
+	_, err = d.InsertComposeEvent(failed, "failure", common.ToPtr("osbuild failed"))
+	require.NoError(t, err)
+	events, err = d.GetComposeEvents(failed)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "failure", events[1].Status)
+	require.Equal(t, "osbuild failed", *events[1].Reason)
+}
+
+func testComposeBackend(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	composeId := uuid.New()
+	err = d.InsertCompose(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}"))
+	require.NoError(t, err)
+	cloneId := uuid.New()
+	err = d.InsertClone(composeId, cloneId, []byte("{}"))
+	require.NoError(t, err)
+
+	compose, err := d.GetCompose(composeId, ORGID1)
+	require.NoError(t, err)
+	require.Empty(t, compose.ComposerBackend)
+
+	err = d.SetComposeBackend(composeId, "eu")
+	require.NoError(t, err)
+	compose, err = d.GetCompose(composeId, ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, "eu", compose.ComposerBackend)
+	composes, _, err := d.GetComposes(ORGID1, time.Hour, 10, 0, nil)
+	require.NoError(t, err)
+	require.Equal(t, "eu", composes[0].ComposerBackend)
+	clone, err := d.GetClone(cloneId, ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, "eu", clone.ComposerBackend)
+
+	_, err = d.GetClone(cloneId, ORGID2)
+	require.ErrorIs(t, err, db.CloneNotFoundError)
+	err = d.SetComposeBackend(uuid.New(), "eu")
+	require.ErrorIs(t, err, db.ComposeNotFoundError)
+
+	email, err := d.GetComposeNotifyEmail(composeId)
+	require.NoError(t, err)
+	require.Nil(t, email)
+	err = d.SetComposeNotifyEmail(composeId, EMAIL1)
+	require.NoError(t, err)
+	email, err = d.GetComposeNotifyEmail(composeId)
+	require.NoError(t, err)
+	require.Equal(t, EMAIL1, *email)
+	_, err = d.GetComposeNotifyEmail(uuid.New())
+	require.ErrorIs(t, err, db.ComposeNotFoundError)
+}
+
+func testCachedComposeStatus(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	composeId := uuid.New()
+	err = d.InsertCompose(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}"))
+	require.NoError(t, err)
+
+	_, err = d.GetCachedComposeStatus(composeId, time.Minute)
+	require.ErrorIs(t, err, db.ComposeStatusNotFoundError)
+
+	err = d.SetCachedComposeStatus(composeId, []byte(`{"image_status": {"status": "building"}}`))
+	require.NoError(t, err)
+	cached, err := d.GetCachedComposeStatus(composeId, time.Minute)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"image_status": {"status": "building"}}`, string(cached.Status))
+	require.True(t, cached.Fresh)
+
+	cached, err = d.GetCachedComposeStatus(composeId, 0)
+	require.NoError(t, err)
+	require.False(t, cached.Fresh)
+
+	err = d.SetCachedComposeStatus(uuid.New(), []byte("{}"))
+	require.ErrorIs(t, err, db.ComposeNotFoundError)
+}
+
+func testIPAllowList(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	cidrs, err := d.GetIPAllowList(ORGID1)
+	require.NoError(t, err)
+	require.Empty(t, cidrs)
+
+	err = d.SetIPAllowList(ORGID1, []string{"192.0.2.0/24", "10.0.0.0/8", "10.0.0.0/8"})
+	require.NoError(t, err)
+	err = d.SetIPAllowList(ORGID2, []string{"198.51.100.0/24"})
+	require.NoError(t, err)
+
+	cidrs, err = d.GetIPAllowList(ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.0/8", "192.0.2.0/24"}, cidrs)
+
+	// setting the list replaces it
+	err = d.SetIPAllowList(ORGID1, []string{"2001:db8::/32"})
+	require.NoError(t, err)
+	cidrs, err = d.GetIPAllowList(ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"2001:db8::/32"}, cidrs)
+
+	err = d.SetIPAllowList(ORGID1, nil)
+	require.NoError(t, err)
+	cidrs, err = d.GetIPAllowList(ORGID1)
+	require.NoError(t, err)
+	require.Empty(t, cidrs)
+
+	cidrs, err = d.GetIPAllowList(ORGID2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"198.51.100.0/24"}, cidrs)
+}
+
+func testQuotas(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	_, err = d.GetQuota(ORGID1)
+	require.ErrorIs(t, err, db.QuotaNotFoundError)
+
+	err = d.SetQuota(db.QuotaEntry{OrgId: ORGID1, BuildsPerDay: common.ToPtr(10)})
+	require.NoError(t, err)
+	quota, err := d.GetQuota(ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, 10, *quota.BuildsPerDay)
+	require.Nil(t, quota.BuildsPerMonth)
+	require.Nil(t, quota.ConcurrentBuilds)
+
+	// setting a quota replaces all limits
+	err = d.SetQuota(db.QuotaEntry{OrgId: ORGID1, BuildsPerMonth: common.ToPtr(100), ConcurrentBuilds: common.ToPtr(2)})
+	require.NoError(t, err)
+	quota, err = d.GetQuota(ORGID1)
+	require.NoError(t, err)
+	require.Nil(t, quota.BuildsPerDay)
+	require.Equal(t, 100, *quota.BuildsPerMonth)
+	require.Equal(t, 2, *quota.ConcurrentBuilds)
+
+	_, err = d.GetQuota(ORGID2)
+	require.ErrorIs(t, err, db.QuotaNotFoundError)
+
+	// composes without a final status count as unfinished
+	finished := uuid.New()
+	err = d.InsertCompose(finished, ANR1, EMAIL1, ORGID1, nil, []byte("{}"))
+	require.NoError(t, err)
+	err = d.InsertCompose(uuid.New(), ANR1, EMAIL1, ORGID1, nil, []byte("{}"))
+	require.NoError(t, err)
+	count, err := d.CountUnfinishedComposesSince(ORGID1, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	_, err = d.InsertComposeEvent(finished, "building", nil)
+	require.NoError(t, err)
+	count, err = d.CountUnfinishedComposesSince(ORGID1, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	_, err = d.InsertComposeEvent(finished, "failure", nil)
+	require.NoError(t, err)
+	count, err = d.CountUnfinishedComposesSince(ORGID1, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	count, err = d.CountUnfinishedComposesSince(ORGID2, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	unfinished, err := d.GetUnfinishedComposesSince(ORGID1, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, unfinished, 1)
+	require.Equal(t, ORGID1, unfinished[0].OrgId)
+	orgs, err := d.GetOrgsWithUnfinishedComposesSince(time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, []string{ORGID1}, orgs)
+
+	// the status sync of one replica claims a compose
+	claimed, err := d.ClaimComposeStatusSync(unfinished[0].Id, time.Minute)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	claimed, err = d.ClaimComposeStatusSync(unfinished[0].Id, time.Minute)
+	require.NoError(t, err)
+	require.False(t, claimed)
+	claimed, err = d.ClaimComposeStatusSync(unfinished[0].Id, 0)
+	require.NoError(t, err)
+	require.True(t, claimed)
+}
+
+func testComposeQueue(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	_, err = d.GetQueuedCompose(uuid.New())
+	require.ErrorIs(t, err, db.QueuedComposeNotFoundError)
+
+	first := uuid.New()
+	err = d.InsertQueuedCompose(first, ANR1, EMAIL1, ORGID1, nil, []byte("{}"), []byte(`{"distribution": "rhel-9"}`), false, nil)
+	require.NoError(t, err)
+	second := uuid.New()
+	err = d.InsertQueuedCompose(second, ANR1, EMAIL1, ORGID1, nil, []byte("{}"), []byte("{}"), false, nil)
+	require.NoError(t, err)
+
+	queued, err := d.GetQueuedCompose(first)
+	require.NoError(t, err)
+	require.Equal(t, ORGID1, queued.OrgId)
+	require.JSONEq(t, `{"distribution": "rhel-9"}`, string(queued.ComposerRequest))
+	require.Nil(t, queued.Error)
+
+	// queued composes are listed, but don't count as building
+	compose, err := d.GetCompose(first, ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, first, compose.ComposerId)
+	count, err := d.CountUnfinishedComposesSince(ORGID1, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	orgs, err := d.GetOrgsWithQueuedComposes()
+	require.NoError(t, err)
+	require.Equal(t, []string{ORGID1}, orgs)
+	count, err = d.CountQueuedComposes(ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	count, err = d.CountQueuedComposes(ORGID2)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	claimed, err := d.ClaimQueuedComposes(ORGID1, 1, nil, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, first, claimed[0].ComposeId)
+
+	// claimed composes aren't handed out twice
+	claimed, err = d.ClaimQueuedComposes(ORGID1, 10, nil, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, second, claimed[0].ComposeId)
+	claimed, err = d.ClaimQueuedComposes(ORGID1, 10, nil, time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+
+	// claimed composes count as building while they're submitted
+	count, err = d.CountUnfinishedComposesSince(ORGID1, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	composerId := uuid.New()
+	err = d.CompleteQueuedCompose(first, composerId)
+	require.NoError(t, err)
+	_, err = d.GetQueuedCompose(first)
+	require.ErrorIs(t, err, db.QueuedComposeNotFoundError)
+	compose, err = d.GetCompose(first, ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, composerId, compose.ComposerId)
+	count, err = d.CountUnfinishedComposesSince(ORGID1, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	err = d.FailQueuedCompose(second, "refused")
+	require.NoError(t, err)
+	queued, err = d.GetQueuedCompose(second)
+	require.NoError(t, err)
+	require.Equal(t, "refused", *queued.Error)
+	orgs, err = d.GetOrgsWithQueuedComposes()
+	require.NoError(t, err)
+	require.Empty(t, orgs)
+	count, err = d.CountQueuedComposes(ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+	count, err = d.CountUnfinishedComposesSince(ORGID1, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	err = d.FailQueuedCompose(first, "refused")
+	require.ErrorIs(t, err, db.QueuedComposeNotFoundError)
+}
+
+// the relations of a queued compose are stored with it or not at all
+func testComposeQueueRelations(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	composeId := uuid.New()
+	secret := db.SealedSecretEntry{KEKId: "kek1", EncryptedDataKey: []byte("data key"), Ciphertext: []byte("secret")}
+	webhook := db.WebhookEntry{Id: uuid.New(), OrgId: ORGID1, URL: "https://example.com/compose", Secret: secret}
+	relations := db.ComposeRelations{
+		ReplicationRegions: []string{"eu-west-1", "us-west-2"},
+		ShareWithAccounts:  []string{"123456789012"},
+		Encryption:         &db.ComposeEncryptionEntry{Region: "us-east-1", KmsKeyId: "arn:aws:kms:us-east-1:123456789012:key/1", ShareWithAccounts: []string{"123456789012"}},
+		Webhook:            &webhook,
+		NotifyEmail:        common.ToPtr("notify@example.com"),
+	}
+	require.NoError(t, d.InsertQueuedCompose(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}"), []byte("{}"), false, &relations))
+
+	replications, err := d.GetComposeReplications(composeId, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, replications, 2)
+	require.Equal(t, []string{"123456789012"}, replications[0].ShareWithAccounts)
+	encryption, err := d.GetComposeEncryption(composeId)
+	require.NoError(t, err)
+	require.Equal(t, "us-east-1", encryption.Region)
+	hook, err := d.GetWebhook(webhook.Id, ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, composeId, *hook.ComposeId)
+	email, err := d.GetComposeNotifyEmail(composeId)
+	require.NoError(t, err)
+	require.Equal(t, "notify@example.com", *email)
+
+	// the webhook exists already, so the compose isn't queued either
+	other := uuid.New()
+	require.Error(t, d.InsertQueuedCompose(other, ANR1, EMAIL1, ORGID1, nil, []byte("{}"), []byte("{}"), false, &relations))
+	_, err = d.GetQueuedCompose(other)
+	require.ErrorIs(t, err, db.QueuedComposeNotFoundError)
+	_, err = d.GetCompose(other, ORGID1)
+	require.ErrorIs(t, err, db.ComposeNotFoundError)
+	replications, err = d.GetComposeReplications(other, time.Minute)
+	require.NoError(t, err)
+	require.Empty(t, replications)
+}
+
+func testIdempotencyKeys(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	_, err = d.GetIdempotencyKey(ORGID1, "key")
+	require.ErrorIs(t, err, db.IdempotencyKeyNotFoundError)
+
+	composeId := uuid.New()
+	key := db.IdempotencyKeyEntry{Key: "key", RequestHash: "hash"}
+	require.NoError(t, d.InsertComposeWithRelations(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}"), &db.ComposeRelations{IdempotencyKey: &key}))
+	entry, err := d.GetIdempotencyKey(ORGID1, "key")
+	require.NoError(t, err)
+	require.Equal(t, db.IdempotencyKeyEntry{ComposeId: composeId, Key: "key", RequestHash: "hash"}, *entry)
+	_, err = d.GetIdempotencyKey(ORGID2, "key")
+	require.ErrorIs(t, err, db.IdempotencyKeyNotFoundError)
+
+	// the key is taken within the org, neither a compose nor a queued
+	// compose is stored with it again
+	other := uuid.New()
+	err = d.InsertComposeWithRelations(other, ANR1, EMAIL1, ORGID1, nil, []byte("{}"), &db.ComposeRelations{IdempotencyKey: &key})
+	require.ErrorIs(t, err, db.IdempotencyKeyExistsError)
+	err = d.InsertQueuedCompose(other, ANR1, EMAIL1, ORGID1, nil, []byte("{}"), []byte("{}"), false, &db.ComposeRelations{IdempotencyKey: &key})
+	require.ErrorIs(t, err, db.IdempotencyKeyExistsError)
+	_, err = d.GetCompose(other, ORGID1)
+	require.ErrorIs(t, err, db.ComposeNotFoundError)
+
+	// other orgs have their own keys
+	require.NoError(t, d.InsertQueuedCompose(other, ANR2, EMAIL1, ORGID2, nil, []byte("{}"), []byte("{}"), false, &db.ComposeRelations{IdempotencyKey: &key}))
+	entry, err = d.GetIdempotencyKey(ORGID2, "key")
+	require.NoError(t, err)
+	require.Equal(t, other, entry.ComposeId)
+
+	// the key of a deleted compose stays taken
+	require.NoError(t, d.DeleteCompose(composeId, ORGID1))
+	entry, err = d.GetIdempotencyKey(ORGID1, "key")
+	require.NoError(t, err)
+	require.Equal(t, composeId, entry.ComposeId)
+}
+
+func testBuildReservations(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+	defer tearDown(t)
+
+	err = d.InsertCompose(uuid.New(), ANR1, EMAIL1, ORGID1, nil, []byte("{}"))
+	require.NoError(t, err)
+	queued := uuid.New()
+	err = d.InsertQueuedCompose(queued, ANR1, EMAIL1, ORGID1, nil, []byte("{}"), []byte("{}"), false, nil)
+	require.NoError(t, err)
+
+	first := uuid.New()
+	usage, err := d.ReserveBuilds(first, ORGID1, 2, time.Hour, time.Hour, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, &db.BuildUsageEntry{
+		BuildsToday:      2,
+		BuildsThisMonth:  2,
+		BuildsInProgress: 1,
+		Queued:           true,
+	}, usage)
+
+	// reserved builds are counted until they're released
+	second := uuid.New()
+	usage, err = d.ReserveBuilds(second, ORGID1, 1, time.Hour, time.Hour, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, &db.BuildUsageEntry{
+		BuildsToday:      4,
+		BuildsThisMonth:  4,
+		BuildsInProgress: 3,
+		Queued:           true,
+	}, usage)
+	usage, err = d.ReserveBuilds(uuid.New(), ORGID2, 1, time.Hour, time.Hour, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, &db.BuildUsageEntry{}, usage)
+
+	// the reservations take up the concurrent builds
+	claimed, err := d.ClaimQueuedComposes(ORGID1, 10, common.ToPtr(4), time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+	require.NoError(t, d.ReleaseBuilds(first))
+	claimed, err = d.ClaimQueuedComposes(ORGID1, 10, common.ToPtr(4), time.Hour)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, queued, claimed[0].ComposeId)
+
+	require.NoError(t, d.ReleaseBuilds(second))
+	require.NoError(t, d.ReleaseBuilds(second))
+	usage, err = d.ReserveBuilds(uuid.New(), ORGID1, 1, time.Hour, time.Hour, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, &db.BuildUsageEntry{
+		BuildsToday:      2,
+		BuildsThisMonth:  2,
+		BuildsInProgress: 2,
+		Queued:           true,
+	}, usage)
+}
+
+func testQuotaBoosts(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	boosts, err := d.GetQuotaBoosts(ORGID1)
+	require.NoError(t, err)
+	require.Empty(t, boosts)
+
+	boost, err := d.InsertQuotaBoost(db.QuotaBoostEntry{
+		Id:           uuid.New(),
+		OrgId:        ORGID1,
+		BuildsPerDay: 10,
+		Reason:       "release deadline",
+		CreatedBy:    "support@example.com",
+	}, time.Hour)
+	require.NoError(t, err)
+	require.WithinDuration(t, boost.CreatedAt.Add(time.Hour), boost.ExpiresAt, time.Second)
+
+	_, err = d.InsertQuotaBoost(db.QuotaBoostEntry{
+		Id:               uuid.New(),
+		OrgId:            ORGID1,
+		ConcurrentBuilds: 2,
+		Reason:           "expired",
+		CreatedBy:        "support@example.com",
+	}, -time.Hour)
+	require.NoError(t, err)
+
+	// expired boosts aren't returned
+	boosts, err = d.GetQuotaBoosts(ORGID1)
+	require.NoError(t, err)
+	require.Len(t, boosts, 1)
+	require.Equal(t, *boost, boosts[0])
+	boosts, err = d.GetQuotaBoosts(ORGID2)
+	require.NoError(t, err)
+	require.Empty(t, boosts)
+
+	err = d.DeleteQuotaBoost(boost.Id, ORGID2)
+	require.ErrorIs(t, err, db.QuotaBoostNotFoundError)
+	err = d.DeleteQuotaBoost(boost.Id, ORGID1)
+	require.NoError(t, err)
+	boosts, err = d.GetQuotaBoosts(ORGID1)
+	require.NoError(t, err)
+	require.Empty(t, boosts)
+}
+
+func testUploadTargetPolicy(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	targets, err := d.GetUploadTargetPolicy(ORGID1)
+	require.NoError(t, err)
+	require.Nil(t, targets)
+
+	err = d.SetUploadTargetPolicy(ORGID1, json.RawMessage(`[{"type": "aws", "regions": ["eu-west-1"]}]`))
+	require.NoError(t, err)
+	targets, err = d.GetUploadTargetPolicy(ORGID1)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"type": "aws", "regions": ["eu-west-1"]}]`, string(targets))
+
+	// setting the policy replaces it
+	err = d.SetUploadTargetPolicy(ORGID1, json.RawMessage(`[{"type": "gcp"}]`))
+	require.NoError(t, err)
+	targets, err = d.GetUploadTargetPolicy(ORGID1)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"type": "gcp"}]`, string(targets))
+
+	targets, err = d.GetUploadTargetPolicy(ORGID2)
+	require.NoError(t, err)
+	require.Nil(t, targets)
+}
+
+func testSecretScanningSettings(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	mode, err := d.GetSecretScanningMode(ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, "", mode)
+	require.NoError(t, d.SetSecretScanningMode(ORGID1, "reject"))
+	require.NoError(t, d.SetSecretScanningMode(ORGID1, "warn"))
+	mode, err = d.GetSecretScanningMode(ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, "warn", mode)
+	mode, err = d.GetSecretScanningMode(ORGID2)
+	require.NoError(t, err)
+	require.Equal(t, "", mode)
+}
+
+func testRepositorySignatureSettings(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	required, err := d.GetRepositorySignaturesRequired(ORGID1)
+	require.NoError(t, err)
+	require.False(t, required)
+	require.NoError(t, d.SetRepositorySignaturesRequired(ORGID1, true))
+	required, err = d.GetRepositorySignaturesRequired(ORGID1)
+	require.NoError(t, err)
+	require.True(t, required)
+	required, err = d.GetRepositorySignaturesRequired(ORGID2)
+	require.NoError(t, err)
+	require.False(t, required)
+}
+
+func testSigningKeys(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	newKey := func() db.SigningKeyEntry {
+		return db.SigningKeyEntry{
+			Id:               uuid.New(),
+			OrgId:            ORGID1,
+			Purpose:          "artifact",
+			Fingerprint:      common.ToPtr("0123456789ABCDEF"),
+			PublicKey:        common.ToPtr("-----BEGIN PGP PUBLIC KEY BLOCK-----"),
+			KEKId:            "kek-1",
+			EncryptedDataKey: []byte{1, 2, 3},
+			Ciphertext:       []byte{4, 5, 6},
+		}
+	}
+	first := newKey()
+	require.NoError(t, d.InsertSigningKey(first))
+	// a single key of each purpose is in use
+	require.Error(t, d.InsertSigningKey(newKey()))
+	webhookKey := newKey()
+	webhookKey.Purpose = "webhook"
+	webhookKey.Fingerprint = nil
+	webhookKey.PublicKey = nil
+	require.NoError(t, d.InsertSigningKey(webhookKey))
+
+	key, err := d.GetSigningKeyInUse(ORGID1, "artifact")
+	require.NoError(t, err)
+	require.Equal(t, first.Id, key.Id)
+	require.Equal(t, first.EncryptedDataKey, key.EncryptedDataKey)
+	require.Equal(t, first.Ciphertext, key.Ciphertext)
+	require.Equal(t, "kek-1", key.KEKId)
+	require.Nil(t, key.ReplacedBy)
+	_, err = d.GetSigningKeyInUse(ORGID2, "artifact")
+	require.ErrorIs(t, err, db.SigningKeyNotFoundError)
+
+	second := newKey()
+	require.NoError(t, d.RotateSigningKey(first.Id, ORGID1, second))
+	// only keys in use can be rotated
+	require.ErrorIs(t, d.RotateSigningKey(first.Id, ORGID1, newKey()), db.SigningKeyNotFoundError)
+	require.ErrorIs(t, d.RotateSigningKey(second.Id, ORGID2, newKey()), db.SigningKeyNotFoundError)
+	key, err = d.GetSigningKey(first.Id, ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, second.Id, *key.ReplacedBy)
+	require.NotNil(t, key.RotatedAt)
+	key, err = d.GetSigningKeyInUse(ORGID1, "artifact")
+	require.NoError(t, err)
+	require.Equal(t, second.Id, key.Id)
+
+	require.NoError(t, d.RevokeSigningKey(second.Id, ORGID1))
+	require.ErrorIs(t, d.RevokeSigningKey(second.Id, ORGID1), db.SigningKeyNotFoundError)
+	_, err = d.GetSigningKeyInUse(ORGID1, "artifact")
+	require.ErrorIs(t, err, db.SigningKeyNotFoundError)
+	_, err = d.GetSigningKey(second.Id, ORGID2)
+	require.ErrorIs(t, err, db.SigningKeyNotFoundError)
+
+	keys, err := d.GetSigningKeys(ORGID1)
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+	keys, err = d.GetSigningKeys(ORGID2)
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	// fkey constraint on key id
+	require.Error(t, d.InsertSigningKeyUsage(db.SigningKeyUsageEntry{KeyId: uuid.New(), Operation: "sign_artifact"}))
+	composeId := uuid.New()
+	require.NoError(t, d.InsertSigningKeyUsage(db.SigningKeyUsageEntry{KeyId: first.Id, Operation: "sign_artifact", ResourceId: &composeId}))
+	require.NoError(t, d.InsertSigningKeyUsage(db.SigningKeyUsageEntry{KeyId: first.Id, Operation: "sign_commit", ResourceId: &composeId}))
+	usage, count, err := d.GetSigningKeyUsage(first.Id, ORGID1, 1, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	require.Len(t, usage, 1)
+	require.Equal(t, "sign_commit", usage[0].Operation)
+	require.Equal(t, composeId, *usage[0].ResourceId)
+	usage, count, err = d.GetSigningKeyUsage(first.Id, ORGID2, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+	require.Empty(t, usage)
+}
+
+func testComposeApprovals(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	required, err := d.GetApprovalRequired(ORGID1)
+	require.NoError(t, err)
+	require.False(t, required)
+	err = d.SetApprovalRequired(ORGID1, true)
+	require.NoError(t, err)
+	required, err = d.GetApprovalRequired(ORGID1)
+	require.NoError(t, err)
+	require.True(t, required)
+	required, err = d.GetApprovalRequired(ORGID2)
+	require.NoError(t, err)
+	require.False(t, required)
+
+	approved := uuid.New()
+	err = d.InsertQueuedCompose(approved, ANR1, EMAIL1, ORGID1, nil, []byte("{}"), []byte("{}"), true, nil)
+	require.NoError(t, err)
+	rejected := uuid.New()
+	err = d.InsertQueuedCompose(rejected, ANR1, EMAIL1, ORGID1, nil, []byte("{}"), []byte("{}"), true, nil)
+	require.NoError(t, err)
+
+	q, err := d.GetQueuedCompose(approved)
+	require.NoError(t, err)
+	require.True(t, q.PendingApproval)
+	require.Equal(t, EMAIL1, *q.RequestedBy)
+	require.Nil(t, q.ReviewedBy)
+
+	// composes pending approval aren't queued for submission yet
+	orgs, err := d.GetOrgsWithQueuedComposes()
+	require.NoError(t, err)
+	require.Empty(t, orgs)
+	count, err := d.CountQueuedComposes(ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+	claimed, err := d.ClaimQueuedComposes(ORGID1, 10, nil, time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+
+	err = d.ApproveQueuedCompose(approved, EMAIL2)
+	require.NoError(t, err)
+	err = d.ApproveQueuedCompose(approved, EMAIL2)
+	require.ErrorIs(t, err, db.ComposeNotPendingApprovalError)
+	err = d.RejectQueuedCompose(rejected, EMAIL2, "not allowed")
+	require.NoError(t, err)
+	err = d.RejectQueuedCompose(rejected, EMAIL2, "not allowed")
+	require.ErrorIs(t, err, db.ComposeNotPendingApprovalError)
+
+	orgs, err = d.GetOrgsWithQueuedComposes()
+	require.NoError(t, err)
+	require.Equal(t, []string{ORGID1}, orgs)
+	claimed, err = d.ClaimQueuedComposes(ORGID1, 10, nil, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, approved, claimed[0].ComposeId)
+	require.Equal(t, EMAIL2, *claimed[0].ReviewedBy)
+
+	q, err = d.GetQueuedCompose(rejected)
+	require.NoError(t, err)
+	require.False(t, q.PendingApproval)
+	require.Equal(t, "not allowed", *q.Error)
+}
+
+func testComplianceExports(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	_, err = d.GetComplianceExportSettings(ORGID1)
+	require.ErrorIs(t, err, db.ComplianceExportSettingsNotFoundError)
+	require.NoError(t, d.SetComplianceExportSettings(db.ComplianceExportSettingsEntry{OrgId: ORGID1, Bucket: "evidence", Region: "us-east-1", VerificationToken: "token"}))
+	settings, err := d.GetComplianceExportSettings(ORGID1)
+	require.NoError(t, err)
+	createdAt := settings.CreatedAt
+	require.NoError(t, d.SetComplianceExportSettings(db.ComplianceExportSettingsEntry{OrgId: ORGID1, Bucket: "evidence", Region: "eu-west-1", Prefix: "image-builder/", VerificationToken: "token2"}))
+	settings, err = d.GetComplianceExportSettings(ORGID1)
+	require.NoError(t, err)
+	require.Equal(t, "eu-west-1", settings.Region)
+	require.Equal(t, "image-builder/", settings.Prefix)
+	require.Equal(t, "token2", settings.VerificationToken)
+	require.Equal(t, createdAt, settings.CreatedAt)
+	require.NoError(t, d.SetComplianceExportSettings(db.ComplianceExportSettingsEntry{OrgId: ORGID2, Bucket: "other", Region: "us-east-1", VerificationToken: "token3"}))
+	all, err := d.GetAllComplianceExportSettings()
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	require.NoError(t, d.DeleteComplianceExportSettings(ORGID2))
+	require.ErrorIs(t, d.DeleteComplianceExportSettings(ORGID2), db.ComplianceExportSettingsNotFoundError)
+
+	since := time.Now().UTC().Add(-time.Minute)
+	succeeded := uuid.New()
+	require.NoError(t, d.InsertCompose(succeeded, ANR1, EMAIL1, ORGID1, nil, []byte("{}")))
+	_, err = d.InsertComposeEvent(succeeded, "success", nil)
+	require.NoError(t, err)
+	failed := uuid.New()
+	require.NoError(t, d.InsertCompose(failed, ANR1, EMAIL1, ORGID1, nil, []byte("{}")))
+	_, err = d.InsertComposeEvent(failed, "failure", nil)
+	require.NoError(t, err)
+	composes, err := d.GetComposesFinishedBetween(ORGID1, since, time.Now().UTC().Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, composes, 1)
+	require.Equal(t, succeeded, composes[0].Id)
+	composes, err = d.GetComposesFinishedBetween(ORGID2, since, time.Now().UTC().Add(time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, composes)
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	claimed, err := d.ClaimComplianceExport(ORGID1, start, end, time.Minute)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	claimed, err = d.ClaimComplianceExport(ORGID1, start, end, time.Minute)
+	require.NoError(t, err)
+	require.False(t, claimed)
+	// a failed export can be claimed again once its retry is due
+	require.NoError(t, d.SetComplianceExportResult(ORGID1, start, 0, 0, common.ToPtr("access denied"), 0))
+	claimed, err = d.ClaimComplianceExport(ORGID1, start, end, time.Minute)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	require.NoError(t, d.SetComplianceExportResult(ORGID1, start, 3, 1, nil, 0))
+	claimed, err = d.ClaimComplianceExport(ORGID1, start, end, time.Minute)
+	require.NoError(t, err)
+	require.False(t, claimed)
+	claimed, err = d.ClaimComplianceExport(ORGID1, end, end.Add(24*time.Hour), time.Minute)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	exports, count, err := d.GetComplianceExports(ORGID1, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	require.Equal(t, end, exports[0].WindowStart)
+	require.Nil(t, exports[0].ExportedAt)
+	require.Equal(t, start, exports[1].WindowStart)
+	require.Equal(t, end, exports[1].WindowEnd)
+	require.Equal(t, 2, exports[1].Attempts)
+	require.Equal(t, 3, exports[1].AuditLogEntries)
+	require.Equal(t, 1, exports[1].Composes)
+	require.Nil(t, exports[1].LastError)
+	require.NotNil(t, exports[1].ExportedAt)
+	_, count, err = d.GetComplianceExports(ORGID2, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+}
+
+func testAuditLog(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	since := time.Now().UTC().Add(-time.Minute)
+	resource := uuid.New().String()
+	for _, entry := range []db.AuditLogEntry{
+		{OrgId: ORGID1, UserId: "user1", Email: EMAIL1, Method: "POST", Path: "/compose", ResourceId: &resource, RequestDigest: "abc", Status: 201, RemoteIP: "10.0.0.1"},
+		{OrgId: ORGID1, UserId: "user2", Email: EMAIL2, Method: "DELETE", Path: "/composes/:composeId", ResourceId: &resource, RequestDigest: "def", Status: 200, RemoteIP: "10.0.0.2"},
+		{OrgId: ORGID2, UserId: "user3", Email: EMAIL1, Method: "PUT", Path: "/settings/ip-allowlist", RequestDigest: "ghi", Status: 400, RemoteIP: "10.0.0.3"},
+	} {
+		err = d.InsertAuditLogEntry(entry)
+		require.NoError(t, err)
+	}
+
+	entries, count, err := d.GetAuditLog(ORGID1, 1, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	require.Len(t, entries, 1)
+	require.Equal(t, "DELETE", entries[0].Method)
+	require.Equal(t, EMAIL2, entries[0].Email)
+	require.Equal(t, resource, *entries[0].ResourceId)
+	entries, _, err = d.GetAuditLog(ORGID1, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "POST", entries[0].Method)
+	require.Equal(t, 201, entries[0].Status)
+
+	entries, count, err = d.GetAuditLog(ORGID2, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Nil(t, entries[0].ResourceId)
+
+	entries, err = d.GetAuditLogBetween("", since, time.Now().UTC().Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, ORGID1, entries[0].OrgId)
+	require.Equal(t, ORGID2, entries[2].OrgId)
+	entries, err = d.GetAuditLogBetween(ORGID2, since, time.Now().UTC().Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	entries, err = d.GetAuditLogBetween("", since.Add(-time.Hour), since)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	// entries can't be changed or removed
+	conn := connect(t)
+	defer conn.Close(context.Background())
+	_, err = conn.Exec(context.Background(), "UPDATE audit_log SET status = 200")
+	require.Error(t, err)
+	_, err = conn.Exec(context.Background(), "DELETE FROM audit_log")
+	require.Error(t, err)
+}
+
+func testWebhooks(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	composeId := uuid.New()
+	otherId := uuid.New()
+	require.NoError(t, d.InsertCompose(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}")))
+	require.NoError(t, d.InsertCompose(otherId, ANR1, EMAIL1, ORGID1, nil, []byte("{}")))
+	cloneId := uuid.New()
+	require.NoError(t, d.InsertClone(composeId, cloneId, []byte("{}")))
+
+	secret := db.SealedSecretEntry{KEKId: "kek1", EncryptedDataKey: []byte("data key"), Ciphertext: []byte("secret")}
+	orgHook := db.WebhookEntry{Id: uuid.New(), OrgId: ORGID1, URL: "https://example.com/org", Secret: secret}
+	composeHook := db.WebhookEntry{Id: uuid.New(), OrgId: ORGID1, ComposeId: &otherId, URL: "https://example.com/compose", Secret: secret}
+	require.NoError(t, d.InsertWebhook(orgHook))
+	require.NoError(t, d.InsertWebhook(composeHook))
+
+	webhooks, err := d.GetWebhooks(ORGID1)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 2)
+	require.Equal(t, secret, webhooks[0].Secret)
+	webhooks, err = d.GetWebhooks(ORGID2)
+	require.NoError(t, err)
+	require.Empty(t, webhooks)
+	_, err = d.GetWebhook(orgHook.Id, ORGID2)
+	require.ErrorIs(t, err, db.WebhookNotFoundError)
+
+	orgs, err := d.GetOrgsWithWebhooks(time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, []string{ORGID1}, orgs)
+
+	clones, err := d.GetUnnotifiedClonesSince(ORGID1, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, clones, 1)
+	require.Equal(t, composeId, clones[0].ComposeId)
+
+	// only the org webhook is interested in the compose, and events are
+	// stored once
+	n, err := d.InsertWebhookDeliveries(composeId, "compose_finished", composeId, []byte(`{"status": "success"}`))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	n, err = d.InsertWebhookDeliveries(composeId, "compose_finished", composeId, []byte(`{"status": "success"}`))
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	n, err = d.InsertWebhookDeliveries(otherId, "compose_finished", otherId, []byte(`{"status": "failure"}`))
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	n, err = d.InsertWebhookDeliveries(composeId, "clone_finished", cloneId, []byte(`{"status": "success"}`))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	clones, err = d.GetUnnotifiedClonesSince(ORGID1, time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, clones)
+
+	claimed, err := d.ClaimWebhookDeliveries(10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 4)
+	again, err := d.ClaimWebhookDeliveries(10)
+	require.NoError(t, err)
+	require.Empty(t, again)
+
+	for _, c := range claimed {
+		require.Equal(t, secret, c.Secret)
+		require.Equal(t, ORGID1, c.OrgId)
+		if c.WebhookId == composeHook.Id {
+			require.NoError(t, d.SetWebhookDeliveryResult(c.Id, db.WebhookDeliveryDelivered, common.ToPtr(200), nil, 0))
+		} else {
+			require.NoError(t, d.SetWebhookDeliveryResult(c.Id, db.WebhookDeliveryPending, common.ToPtr(500), common.ToPtr("webhook responded with 500"), 0))
+		}
+	}
+	// pending deliveries are due again right away
+	again, err = d.ClaimWebhookDeliveries(10)
+	require.NoError(t, err)
+	require.Len(t, again, 3)
+
+	deliveries, count, err := d.GetWebhookDeliveries(orgHook.Id, ORGID1, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+	for _, dl := range deliveries {
+		require.Equal(t, db.WebhookDeliveryPending, dl.Status)
+		require.Equal(t, 1, dl.Attempts)
+		require.Equal(t, 500, *dl.ResponseCode)
+		require.Nil(t, dl.DeliveredAt)
+	}
+	deliveries, _, err = d.GetWebhookDeliveries(composeHook.Id, ORGID1, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, db.WebhookDeliveryDelivered, deliveries[0].Status)
+	require.NotNil(t, deliveries[0].DeliveredAt)
+	_, count, err = d.GetWebhookDeliveries(orgHook.Id, ORGID2, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	require.ErrorIs(t, d.DeleteWebhook(orgHook.Id, ORGID2), db.WebhookNotFoundError)
+	require.NoError(t, d.DeleteWebhook(orgHook.Id, ORGID1))
+	_, count, err = d.GetWebhookDeliveries(orgHook.Id, ORGID1, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+}
+
+func testOutbox(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	entry := func(sink string, composeId uuid.UUID) db.OutboxEntry {
+		return db.OutboxEntry{Sink: sink, Event: "compose_finished", ComposeId: composeId, Payload: []byte(`{"status":"success"}`)}
+	}
+
+	// entries are written along with the change
+	composeId := uuid.New()
+	require.NoError(t, d.InsertCompose(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}"), entry("events", composeId)))
+	recorded, err := d.InsertComposeEvent(composeId, "success", nil, entry("webhooks", composeId))
+	require.NoError(t, err)
+	require.True(t, recorded)
+	// but not if there's no change
+	recorded, err = d.InsertComposeEvent(composeId, "success", nil, entry("webhooks", composeId))
+	require.NoError(t, err)
+	require.False(t, recorded)
+
+	queuedId := uuid.New()
+	require.NoError(t, d.InsertQueuedCompose(queuedId, ANR1, EMAIL1, ORGID1, nil, []byte("{}"), []byte("{}"), false, nil))
+	err = d.FailQueuedCompose(uuid.New(), "gone", entry("events", queuedId))
+	require.ErrorIs(t, err, db.QueuedComposeNotFoundError)
+	require.NoError(t, d.FailQueuedCompose(queuedId, "refused", entry("events", queuedId)))
+
+	entries, err := d.ClaimOutboxEntries(10)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, "events", entries[0].Sink)
+	require.Equal(t, composeId, entries[0].ComposeId)
+	require.Equal(t, "webhooks", entries[1].Sink)
+	require.Equal(t, queuedId, entries[2].ComposeId)
+	require.JSONEq(t, `{"status":"success"}`, string(entries[0].Payload))
+
+	// claimed entries aren't claimed again
+	claimed, err := d.ClaimOutboxEntries(10)
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+
+	require.NoError(t, d.SetOutboxEntryResult(entries[0].Id, db.OutboxDispatched, nil, 0))
+	require.NoError(t, d.SetOutboxEntryResult(entries[1].Id, db.OutboxFailed, common.ToPtr("gone"), 0))
+	require.NoError(t, d.SetOutboxEntryResult(entries[2].Id, db.OutboxPending, common.ToPtr("unavailable"), 0))
+	claimed, err = d.ClaimOutboxEntries(10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, entries[2].Id, claimed[0].Id)
+	require.Equal(t, 1, claimed[0].Attempts)
+
+	deleted, err := d.DeleteOutboxEntriesBefore(time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 0, deleted)
+	deleted, err = d.DeleteOutboxEntriesBefore(0)
+	require.NoError(t, err)
+	require.Equal(t, 2, deleted)
+}
+
+func testOrgEvents(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	last, err := d.GetLastOrgEventId()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), last)
+
+	composeId := uuid.New()
+	cloneId := uuid.New()
+	require.NoError(t, d.InsertCompose(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}")))
+	require.NoError(t, d.InsertOrgEvent(composeId, "compose_created", composeId, []byte(`{"event":"compose_created"}`)))
+	require.NoError(t, d.InsertOrgEvent(composeId, "clone_finished", cloneId, []byte(`{"event":"clone_finished"}`)))
+	// events are only stored once
+	require.NoError(t, d.InsertOrgEvent(composeId, "clone_finished", cloneId, []byte(`{"event":"clone_finished"}`)))
+	// and not at all for unknown composes
+	require.NoError(t, d.InsertOrgEvent(uuid.New(), "compose_created", uuid.New(), []byte(`{}`)))
+
+	events, err := d.GetOrgEventsAfter(0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, ORGID1, events[0].OrgId)
+	require.Equal(t, "compose_created", events[0].Event)
+	require.Equal(t, composeId, events[0].ResourceId)
+	require.Equal(t, cloneId, events[1].ResourceId)
+	require.JSONEq(t, `{"event":"clone_finished"}`, string(events[1].Payload))
+	require.Greater(t, events[1].Id, events[0].Id)
+
+	last, err = d.GetLastOrgEventId()
+	require.NoError(t, err)
+	require.Equal(t, events[1].Id, last)
+	events, err = d.GetOrgEventsAfter(events[0].Id, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, cloneId, events[0].ResourceId)
+
+	deleted, err := d.DeleteOrgEventsBefore(time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 0, deleted)
+	deleted, err = d.DeleteOrgEventsBefore(0)
+	require.NoError(t, err)
+	require.Equal(t, 2, deleted)
+}
+
+func testSeedCompose(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+	seeder, err := db.InitSeederConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	finished := uuid.New()
+	cloneId := uuid.New()
+	createdAt := time.Now().UTC().Add(-20 * 24 * time.Hour).Truncate(time.Second)
+	require.NoError(t, seeder.InsertSeedCompose(db.SeedComposeEntry{
+		Id:            finished,
+		OrgId:         ORGID1,
+		AccountNumber: ANR1,
+		Email:         EMAIL1,
+		Request:       []byte("{}"),
+		CreatedAt:     createdAt,
+		Status:        []byte(`{"image_status": {"status": "success"}}`),
+		Events: []db.ComposeEventEntry{
+			{Status: "pending", CreatedAt: createdAt},
+			{Status: "success", CreatedAt: createdAt.Add(10 * time.Minute)},
+		},
+		Clones: []db.SeedCloneEntry{{Id: cloneId, Request: []byte(`{"region": "eu-central-1"}`), CreatedAt: createdAt.Add(time.Hour)}},
+	}))
+	queued := uuid.New()
+	require.NoError(t, seeder.InsertSeedCompose(db.SeedComposeEntry{
+		Id:              queued,
+		OrgId:           ORGID1,
+		AccountNumber:   ANR1,
+		Email:           EMAIL1,
+		Request:         []byte("{}"),
+		CreatedAt:       time.Now().UTC(),
+		ComposerRequest: []byte(`{"distribution": "rhel-9"}`),
+		PendingApproval: true,
+	}))
+
+	composes, count, err := d.GetComposes(ORGID1, fortnight, 10, 0, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Equal(t, queued, composes[0].Id)
+	_, count, err = d.GetComposes(ORGID1, 30*24*time.Hour, 10, 0, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	cached, err := d.GetCachedComposeStatus(finished, 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"image_status": {"status": "success"}}`, string(cached.Status))
+	require.Equal(t, createdAt, cached.RefreshedAt.UTC())
+	_, err = d.GetCachedComposeStatus(queued, 0)
+	require.ErrorIs(t, err, db.ComposeStatusNotFoundError)
+
+	events, err := d.GetComposeEvents(finished)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	clones, _, err := d.GetClonesForCompose(finished, ORGID1, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, clones, 1)
+	require.Equal(t, cloneId, clones[0].Id)
+
+	q, err := d.GetQueuedCompose(queued)
+	require.NoError(t, err)
+	require.True(t, q.PendingApproval)
+	require.Equal(t, EMAIL1, *q.RequestedBy)
+}
+
+func testPasswordFunc(t *testing.T) {
+	c := conf(t)
+	wrong := fmt.Sprintf("postgres://%s:wrong@%s:%s/%s?sslmode=%s", c.PGUser, c.PGHost, c.PGPort, c.PGDatabase, c.PGSSLMode)
+	d, err := db.InitDBConnectionPoolWithPassword(wrong, func() string { return c.PGPassword })
+	require.NoError(t, err)
+	require.NoError(t, d.Ping(context.Background()))
+}
+
+func testComposeReplications(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	composeId := uuid.New()
+	// fkey constraint on compose id
+	require.Error(t, d.InsertComposeReplications(composeId, []string{"us-east-2"}, []string{"123456789012"}))
+
+	require.NoError(t, d.InsertCompose(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}")))
+	require.NoError(t, d.InsertComposeReplications(composeId, []string{"us-west-1", "eu-central-1"}, []string{"123456789012"}))
+	// inserting again keeps what's there
+	require.NoError(t, d.InsertComposeReplications(composeId, []string{"us-west-1"}, nil))
+
+	replications, err := d.GetComposeReplications(composeId, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, replications, 2)
+	require.Equal(t, "eu-central-1", replications[0].Region)
+	require.Equal(t, []string{"123456789012"}, replications[1].ShareWithAccounts)
+	require.Nil(t, replications[0].CloneId)
+	require.Nil(t, replications[0].Status)
+	require.False(t, replications[0].Fresh)
+
+	// a region is only claimed once
+	claimed, err := d.ClaimComposeReplication(composeId, "us-west-1")
+	require.NoError(t, err)
+	require.True(t, claimed)
+	claimed, err = d.ClaimComposeReplication(composeId, "us-west-1")
+	require.NoError(t, err)
+	require.False(t, claimed)
+	claimed, err = d.ClaimComposeReplication(composeId, "ap-south-1")
+	require.NoError(t, err)
+	require.False(t, claimed)
+
+	// releasing the claim makes it claimable again
+	require.NoError(t, d.SetComposeReplicationClone(composeId, "us-west-1", nil))
+	claimed, err = d.ClaimComposeReplication(composeId, "us-west-1")
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	cloneId := uuid.New()
+	require.NoError(t, d.SetComposeReplicationClone(composeId, "us-west-1", &cloneId))
+	claimed, err = d.ClaimComposeReplication(composeId, "us-west-1")
+	require.NoError(t, err)
+	require.False(t, claimed)
+	require.ErrorIs(t, d.SetComposeReplicationClone(composeId, "ap-south-1", nil), db.ComposeReplicationNotFoundError)
+
+	require.NoError(t, d.SetComposeReplicationStatus(composeId, "us-west-1", []byte(`{"status": "running"}`)))
+	require.ErrorIs(t, d.SetComposeReplicationStatus(composeId, "ap-south-1", []byte(`{}`)), db.ComposeReplicationNotFoundError)
+	replications, err = d.GetComposeReplications(composeId, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, cloneId, *replications[1].CloneId)
+	require.JSONEq(t, `{"status": "running"}`, string(replications[1].Status))
+	require.NotNil(t, replications[1].StatusRefreshedAt)
+	require.True(t, replications[1].Fresh)
+}
+
+func testComposeEncryptions(t *testing.T) {
+	d, err := db.InitDBConnectionPool(connStr(t))
+	require.NoError(t, err)
+
+	composeId := uuid.New()
+	encryption := db.ComposeEncryptionEntry{
+		ComposeId:         composeId,
+		Region:            "us-east-1",
+		KmsKeyId:          "arn:aws:kms:us-east-1:123456789012:key/k",
+		ShareWithAccounts: []string{"123456789012"},
+	}
+	// fkey constraint on compose id
+	require.Error(t, d.InsertComposeEncryption(encryption))
+	_, err = d.GetComposeEncryption(composeId)
+	require.ErrorIs(t, err, db.ComposeEncryptionNotFoundError)
+
+	require.NoError(t, d.InsertCompose(composeId, ANR1, EMAIL1, ORGID1, nil, []byte("{}")))
+	require.NoError(t, d.InsertComposeEncryption(encryption))
+	e, err := d.GetComposeEncryption(composeId)
+	require.NoError(t, err)
+	require.Equal(t, db.ComposeEncryptionPending, e.Status)
+	require.Equal(t, []string{"123456789012"}, e.ShareWithAccounts)
+	require.Nil(t, e.ImageId)
+
+	// it's only claimed once
+	claimed, err := d.ClaimComposeEncryption(composeId)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	claimed, err = d.ClaimComposeEncryption(composeId)
+	require.NoError(t, err)
+	require.False(t, claimed)
+
+	// storing the state releases the claim
+	e.SourceImageId = common.ToPtr("ami-plain")
+	e.ImageId = common.ToPtr("ami-encrypted")
+	e.Status = db.ComposeEncryptionCopying
+	require.NoError(t, d.SetComposeEncryption(*e))
+	claimed, err = d.ClaimComposeEncryption(composeId)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	// finished ones aren't claimed anymore
+	e.Status = db.ComposeEncryptionDone
+	require.NoError(t, d.SetComposeEncryption(*e))
+	claimed, err = d.ClaimComposeEncryption(composeId)
+	require.NoError(t, err)
+	require.False(t, claimed)
+	e, err = d.GetComposeEncryption(composeId)
+	require.NoError(t, err)
+	require.Equal(t, "ami-plain", *e.SourceImageId)
+	require.Equal(t, "ami-encrypted", *e.ImageId)
+	require.Equal(t, db.ComposeEncryptionDone, e.Status)
+
+	e.ComposeId = uuid.New()
+	require.ErrorIs(t, d.SetComposeEncryption(*e), db.ComposeEncryptionNotFoundError)
+}
+
 func TestMain(t *testing.T) {
 	fns := []func(*testing.T){
 		testInsertCompose,
@@ -350,6 +2282,45 @@ func TestMain(t *testing.T) {
 		testGetComposeImageType,
 		testDeleteCompose,
 		testClones,
+		testComposeReplications,
+		testComposeEncryptions,
+		testAWSShareAllowList,
+		testComposeArtifacts,
+		testComposeSignatures,
+		testCommitSignatures,
+		testVulnerabilityScans,
+		testComposeSBOMs,
+		testAPITokens,
+		testComposeForSupport,
+		testComposeEvents,
+		testCachedComposeStatus,
+		testComposeBackend,
+		testIPAllowList,
+		testQuotas,
+		testComposeQueue,
+		testComposeQueueRelations,
+		testIdempotencyKeys,
+		testBuildReservations,
+		testQuotaBoosts,
+		testUploadTargetPolicy,
+		testComposeApprovals,
+		testSecretScanningSettings,
+		testRepositorySignatureSettings,
+		testSigningKeys,
+		testComplianceExports,
+		testOrgAnalytics,
+		testUsageReport,
+		testBuildDurations,
+		testAuditLog,
+		testWebhooks,
+		testOutbox,
+		testLaunches,
+		testAWX,
+		testArtifactSigningSettings,
+		testPulpSettings,
+		testOrgEvents,
+		testSeedCompose,
+		testPasswordFunc,
 	}
 
 	for _, f := range fns {
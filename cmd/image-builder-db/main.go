@@ -0,0 +1,95 @@
+// image-builder-db works on the database of image builder, outside of the
+// service. It connects with the PG* variables of the service.
+//
+//	image-builder-db seed [-orgs N] [-composes N] [-max-age DURATION]
+//
+// seed fills a development database with composes of several orgs, of
+// different image types, states and ages, some with clones, e.g. to work on
+// paging, the retention of composes or the UI without production data.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/config"
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+const usage = `Usage: image-builder-db COMMAND
+
+Commands:
+  seed [-orgs N] [-composes N] [-max-age DURATION]   fill a development database with composes
+`
+
+func main() {
+	err := run(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "image-builder-db: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 || args[0] != "seed" {
+		fmt.Fprint(os.Stderr, usage)
+		return flag.ErrHelp
+	}
+
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	orgs := flags.Int("orgs", 5, "orgs the composes belong to, starting with org 000000")
+	composes := flags.Int("composes", 200, "composes to create")
+	maxAge := flags.Duration("max-age", 30*24*time.Hour, "composes are spread over this time")
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	if *orgs < 1 || *composes < 0 || *maxAge <= 0 {
+		return fmt.Errorf("-orgs and -max-age have to be positive, -composes can't be negative")
+	}
+
+	conf := config.ImageBuilderConfig{
+		ListenAddress: "unused",
+		PGHost:        "localhost",
+		PGPort:        "5432",
+		PGDatabase:    "imagebuilder",
+		PGUser:        "postgres",
+		PGPassword:    "foobar",
+		PGSSLMode:     "prefer",
+	}
+	err = config.LoadConfigFromEnv(&conf)
+	if err != nil {
+		return err
+	}
+	seeder, err := db.InitSeederConnectionPool(fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
+		conf.PGUser, conf.PGPassword, conf.PGHost, conf.PGPort, conf.PGDatabase, conf.PGSSLMode))
+	if err != nil {
+		return err
+	}
+
+	/* #nosec G404 -- the composes only have to look real */
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	entries, err := generateSeed(r, seedConfig{Orgs: *orgs, Composes: *composes, MaxAge: *maxAge}, time.Now())
+	if err != nil {
+		return err
+	}
+	clones := 0
+	for _, e := range entries {
+		err = seeder.InsertSeedCompose(e)
+		if err != nil {
+			return fmt.Errorf("unable to insert compose %v: %v", e.Id, err)
+		}
+		clones += len(e.Clones)
+	}
+	logrus.Infof("Seeded %d composes and %d clones of %d orgs", len(entries), clones, *orgs)
+	return nil
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+	v1 "github.com/osbuild/image-builder/internal/v1"
+)
+
+type seedConfig struct {
+	Orgs     int
+	Composes int
+	// Composes are spread over MaxAge, beyond the 14 days composes are
+	// listed for.
+	MaxAge time.Duration
+}
+
+type imageTemplate struct {
+	imageType    v1.ImageTypes
+	uploadType   v1.UploadTypes
+	composerType composer.ImageTypes
+}
+
+var (
+	seedDistributions = []v1.Distributions{"rhel-8", "rhel-9", "centos-9", "fedora-39"}
+	seedArchitectures = []v1.ImageRequestArchitecture{"x86_64", "x86_64", "x86_64", "aarch64"}
+	seedImages        = []imageTemplate{
+		{v1.ImageTypesAws, v1.UploadTypesAws, composer.ImageTypesAws},
+		{v1.ImageTypesAws, v1.UploadTypesAws, composer.ImageTypesAws},
+		{v1.ImageTypesGuestImage, v1.UploadTypesAwsS3, composer.ImageTypesGuestImage},
+		{v1.ImageTypesImageInstaller, v1.UploadTypesAwsS3, composer.ImageTypesImageInstaller},
+		{v1.ImageTypesGcp, v1.UploadTypesGcp, composer.ImageTypesGcp},
+		{v1.ImageTypesAzure, v1.UploadTypesAzure, composer.ImageTypesAzure},
+		{v1.ImageTypesVsphereOva, v1.UploadTypesAwsS3, composer.ImageTypesVsphereOva},
+		{v1.ImageTypesWsl, v1.UploadTypesAwsS3, composer.ImageTypesWsl},
+	}
+	seedRegions  = []string{"us-east-2", "eu-central-1", "ap-south-1"}
+	seedFailures = []string{
+		"osbuild did not return any output",
+		"Failed to depsolve: package kernel-rt not found",
+		"Upload to AWS failed: AccessDenied",
+	}
+)
+
+// seedOrgId is the org of the n-th org, the first one is the org of the
+// stand-alone mode and of the fixtures of the tests.
+func seedOrgId(n int) string {
+	return fmt.Sprintf("%06d", n)
+}
+
+// generateSeed generates the composes of conf. Most succeeded, some failed or
+// were deleted, recent ones may still wait in the queue.
+func generateSeed(r *rand.Rand, conf seedConfig, now time.Time) ([]db.SeedComposeEntry, error) {
+	var composes []db.SeedComposeEntry
+	for i := 0; i < conf.Composes; i++ {
+		org := r.Intn(conf.Orgs)
+		// a tenth of the composes are of the last hour, as if they were
+		// just built
+		age := conf.MaxAge
+		if r.Intn(10) == 0 && age > time.Hour {
+			age = time.Hour
+		}
+		createdAt := now.Add(-time.Duration(r.Int63n(int64(age)))).UTC().Truncate(time.Second)
+		dist := seedDistributions[r.Intn(len(seedDistributions))]
+		arch := seedArchitectures[r.Intn(len(seedArchitectures))]
+		image := seedImages[r.Intn(len(seedImages))]
+
+		cr, err := seedComposeRequest(dist, arch, image)
+		if err != nil {
+			return nil, err
+		}
+		request, err := json.Marshal(cr)
+		if err != nil {
+			return nil, err
+		}
+		c := db.SeedComposeEntry{
+			Id:            uuid.New(),
+			OrgId:         seedOrgId(org),
+			AccountNumber: fmt.Sprintf("%07d", 1000000+org),
+			Email:         fmt.Sprintf("user%d@org%s.example.com", r.Intn(3), seedOrgId(org)),
+			ImageName:     cr.ImageName,
+			Request:       request,
+			CreatedAt:     createdAt,
+		}
+
+		state := r.Intn(100)
+		switch {
+		case now.Sub(createdAt) < time.Hour && state < 30:
+			c.ComposerRequest, err = json.Marshal(composer.ComposeRequest{
+				Distribution: string(dist),
+				ImageRequest: &composer.ImageRequest{
+					Architecture: string(arch),
+					ImageType:    image.composerType,
+					Repositories: []composer.Repository{},
+				},
+			})
+			c.PendingApproval = state < 10
+			queued := "queued"
+			if c.PendingApproval {
+				queued = "pending_approval"
+			}
+			c.Events = []db.ComposeEventEntry{
+				{Status: "created", CreatedAt: createdAt},
+				{Status: queued, CreatedAt: createdAt},
+			}
+		case state < 15:
+			reason := seedFailures[r.Intn(len(seedFailures))]
+			c.Status, err = seedStatus(image.uploadType, composer.ImageStatusValueFailure, reason)
+			c.Events = []db.ComposeEventEntry{
+				{Status: "created", CreatedAt: createdAt},
+				{Status: "pending", CreatedAt: createdAt},
+				{Status: "building", CreatedAt: createdAt.Add(time.Minute)},
+				{Status: "failure", Reason: &reason, CreatedAt: createdAt.Add(6 * time.Minute)},
+			}
+		default:
+			c.Status, err = seedStatus(image.uploadType, composer.ImageStatusValueSuccess, "")
+			c.Events = []db.ComposeEventEntry{
+				{Status: "created", CreatedAt: createdAt},
+				{Status: "pending", CreatedAt: createdAt},
+				{Status: "building", CreatedAt: createdAt.Add(time.Minute)},
+				{Status: "uploading", CreatedAt: createdAt.Add(8 * time.Minute)},
+				{Status: "success", CreatedAt: createdAt.Add(10 * time.Minute)},
+			}
+			c.Deleted = state >= 95
+			if image.uploadType == v1.UploadTypesAws && state < 40 {
+				c.Clones, err = seedClones(r, createdAt, now)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		// events can't be seen before they happened
+		for j := range c.Events {
+			if c.Events[j].CreatedAt.After(now) {
+				c.Events[j].CreatedAt = now
+			}
+		}
+		composes = append(composes, c)
+	}
+	return composes, nil
+}
+
+func seedComposeRequest(dist v1.Distributions, arch v1.ImageRequestArchitecture, image imageTemplate) (*v1.ComposeRequest, error) {
+	var uo v1.UploadRequest_Options
+	var err error
+	switch image.uploadType {
+	case v1.UploadTypesAws:
+		err = uo.FromAWSUploadRequestOptions(v1.AWSUploadRequestOptions{
+			ShareWithAccounts: &[]string{"123456789012"},
+		})
+	case v1.UploadTypesGcp:
+		err = uo.FromGCPUploadRequestOptions(v1.GCPUploadRequestOptions{
+			ShareWithAccounts: &[]string{"user:developer@example.com"},
+		})
+	case v1.UploadTypesAzure:
+		err = uo.FromAzureUploadRequestOptions(v1.AzureUploadRequestOptions{
+			ResourceGroup:  "images",
+			SubscriptionId: common.ToPtr(uuid.NewString()),
+			TenantId:       common.ToPtr(uuid.NewString()),
+		})
+	default:
+		err = uo.FromAWSS3UploadRequestOptions(v1.AWSS3UploadRequestOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ComposeRequest{
+		Distribution: dist,
+		ImageName:    common.ToPtr(fmt.Sprintf("%s-%s-%s", dist, image.imageType, uuid.NewString()[:8])),
+		ImageRequests: []v1.ImageRequest{
+			{
+				Architecture: arch,
+				ImageType:    image.imageType,
+				UploadRequest: v1.UploadRequest{
+					Type:    image.uploadType,
+					Options: uo,
+				},
+			},
+		},
+	}, nil
+}
+
+// seedStatus is the status composer would have reported for a finished
+// compose.
+func seedStatus(ut v1.UploadTypes, value composer.ImageStatusValue, reason string) (json.RawMessage, error) {
+	status := composer.ComposeStatus{
+		Kind:   "ComposeStatus",
+		Status: composer.ComposeStatusValueSuccess,
+		ImageStatus: composer.ImageStatus{
+			Status: value,
+		},
+	}
+	if value == composer.ImageStatusValueFailure {
+		status.Status = composer.ComposeStatusValueFailure
+		status.ImageStatus.Error = &composer.ComposeStatusError{Id: 10, Reason: reason}
+		return json.Marshal(status)
+	}
+
+	us := composer.UploadStatus{Status: composer.Success, Type: composer.UploadTypes(ut)}
+	var err error
+	switch ut {
+	case v1.UploadTypesAws:
+		err = us.Options.FromAWSEC2UploadStatus(composer.AWSEC2UploadStatus{
+			Ami:    "ami-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:17],
+			Region: "us-east-1",
+		})
+	case v1.UploadTypesGcp:
+		err = us.Options.FromGCPUploadStatus(composer.GCPUploadStatus{
+			ImageName: "composer-api-" + uuid.NewString(),
+			ProjectId: "image-builder-seed",
+		})
+	case v1.UploadTypesAzure:
+		err = us.Options.FromAzureUploadStatus(composer.AzureUploadStatus{
+			ImageName: "composer-api-" + uuid.NewString(),
+		})
+	default:
+		err = us.Options.FromAWSS3UploadStatus(composer.AWSS3UploadStatus{
+			Url: fmt.Sprintf("https://image-builder-service-production.s3.amazonaws.com/composer-api-%s-disk.qcow2", uuid.NewString()),
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	status.ImageStatus.UploadStatus = &us
+	return json.Marshal(status)
+}
+
+func seedClones(r *rand.Rand, createdAt, now time.Time) ([]db.SeedCloneEntry, error) {
+	var clones []db.SeedCloneEntry
+	for i := 0; i < 1+r.Intn(3); i++ {
+		var clone v1.CloneRequest
+		err := clone.FromAWSEC2Clone(v1.AWSEC2Clone{Region: seedRegions[i]})
+		if err != nil {
+			return nil, err
+		}
+		request, err := json.Marshal(clone)
+		if err != nil {
+			return nil, err
+		}
+		cloneAt := createdAt.Add(time.Duration(1+i) * time.Hour)
+		if cloneAt.After(now) {
+			break
+		}
+		clones = append(clones, db.SeedCloneEntry{
+			Id:        uuid.New(),
+			Request:   request,
+			CreatedAt: cloneAt,
+		})
+	}
+	return clones, nil
+}
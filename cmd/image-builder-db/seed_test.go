@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	v1 "github.com/osbuild/image-builder/internal/v1"
+)
+
+func TestGenerateSeed(t *testing.T) {
+	now := time.Now()
+	conf := seedConfig{Orgs: 3, Composes: 500, MaxAge: 30 * 24 * time.Hour}
+	composes, err := generateSeed(rand.New(rand.NewSource(1)), conf, now)
+	require.NoError(t, err)
+	require.Len(t, composes, 500)
+
+	orgs := map[string]bool{}
+	statuses := map[composer.ImageStatusValue]int{}
+	var queued, deleted, clones, old int
+	for _, c := range composes {
+		orgs[c.OrgId] = true
+		require.False(t, c.CreatedAt.After(now))
+		if now.Sub(c.CreatedAt) > 14*24*time.Hour {
+			old++
+		}
+
+		var cr v1.ComposeRequest
+		require.NoError(t, json.Unmarshal(c.Request, &cr))
+		require.Len(t, cr.ImageRequests, 1)
+		require.Equal(t, cr.ImageName, c.ImageName)
+
+		if c.ComposerRequest != nil {
+			queued++
+			require.Nil(t, c.Status)
+			require.Less(t, now.Sub(c.CreatedAt), time.Hour)
+			continue
+		}
+		var status composer.ComposeStatus
+		require.NoError(t, json.Unmarshal(c.Status, &status))
+		statuses[status.ImageStatus.Status]++
+		if status.ImageStatus.Status == composer.ImageStatusValueSuccess {
+			require.Equal(t, string(cr.ImageRequests[0].UploadRequest.Type), string(status.ImageStatus.UploadStatus.Type))
+		}
+		if c.Deleted {
+			deleted++
+		}
+		clones += len(c.Clones)
+		for _, cl := range c.Clones {
+			require.True(t, cl.CreatedAt.After(c.CreatedAt))
+		}
+		for _, e := range c.Events {
+			require.False(t, e.CreatedAt.After(now))
+		}
+	}
+	require.Len(t, orgs, 3)
+	require.True(t, orgs["000000"])
+	require.Greater(t, statuses[composer.ImageStatusValueSuccess], statuses[composer.ImageStatusValueFailure])
+	require.NotZero(t, statuses[composer.ImageStatusValueFailure])
+	require.NotZero(t, deleted)
+	require.NotZero(t, clones)
+	require.NotZero(t, old)
+	require.NotZero(t, queued)
+	require.Less(t, queued, 50)
+}
@@ -1,29 +1,61 @@
 package main
 
 import (
+	"context"
+	"crypto"
+	"flag"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/labstack/gommon/random"
+	"github.com/labstack/gommon/bytes"
 	"github.com/osbuild/image-builder/internal/common"
 	"github.com/sirupsen/logrus"
 
 	"github.com/osbuild/image-builder/internal/composer"
 	"github.com/osbuild/image-builder/internal/config"
+	"github.com/osbuild/image-builder/internal/cosign"
 	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/devmode"
+	"github.com/osbuild/image-builder/internal/diagnostics"
 	"github.com/osbuild/image-builder/internal/distribution"
+	"github.com/osbuild/image-builder/internal/ec2"
+	"github.com/osbuild/image-builder/internal/email"
+	"github.com/osbuild/image-builder/internal/featureflags"
+	"github.com/osbuild/image-builder/internal/kafka"
+	"github.com/osbuild/image-builder/internal/keystore"
+	"github.com/osbuild/image-builder/internal/kms"
 	"github.com/osbuild/image-builder/internal/logger"
+	"github.com/osbuild/image-builder/internal/policy"
+	"github.com/osbuild/image-builder/internal/prometheus"
 	"github.com/osbuild/image-builder/internal/provisioning"
+	"github.com/osbuild/image-builder/internal/ratelimit"
+	"github.com/osbuild/image-builder/internal/rbac"
+	"github.com/osbuild/image-builder/internal/s3"
+	"github.com/osbuild/image-builder/internal/secrets"
+	"github.com/osbuild/image-builder/internal/signing"
 	v1 "github.com/osbuild/image-builder/internal/v1"
+	"github.com/osbuild/image-builder/internal/vulnscan"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/net/http2"
 
 	"github.com/getsentry/sentry-go"
 	sentryecho "github.com/getsentry/sentry-go/echo"
 )
 
 func main() {
+	configFile := flag.String("config", "", "yaml or toml config file, env variables override it")
+	printConfig := flag.Bool("print-config", false, "print the config with secrets redacted and exit")
+	dev := flag.Bool("dev", false, "run against fakes of composer and provisioning, only postgres is needed")
+	demo := flag.Bool("demo", false, "like -dev but with an in-memory database, nothing is needed and nothing is kept")
+	flag.Parse()
+	// the demo mode is the development mode without postgres
+	*dev = *dev || *demo
+
 	conf := config.ImageBuilderConfig{
 		ListenAddress: "localhost:8086",
 		LogLevel:      "INFO",
@@ -33,12 +65,138 @@ func main() {
 		PGUser:        "postgres",
 		PGPassword:    "foobar",
 		PGSSLMode:     "prefer",
+
+		SplunkBatchSize:     "1000",
+		SplunkFlushInterval: "5s",
+		SplunkQueueSize:     "10000",
+
+		ComposerHealthCheckInterval: "30s",
+		ComposerCapabilitiesRefresh: "10m",
+		ComposerQueueInterval:       "1m",
+		ComposerConnectTimeout:      "5s",
+		ComposerReadTimeout:         "30s",
+		ComposerRequestTimeout:      "45s",
+		ComposerTokenConnectTimeout: "5s",
+		ComposerTokenReadTimeout:    "10s",
+		ComposerTokenRequestTimeout: "15s",
+		ProvisioningConnectTimeout:  "5s",
+		ProvisioningReadTimeout:     "10s",
+		ProvisioningRequestTimeout:  "15s",
+		RequestDeadline:             "60s",
+
+		RateLimitInterval:     "1m",
+		ComposeQueueInterval:  "30s",
+		WebhookInterval:       "30s",
+		StatusSyncInterval:    "1m",
+		OutboxInterval:        "5s",
+		KafkaTopic:            "platform.image-builder.events",
+		EmailRateLimit:        "20",
+		ComposeStatusCacheTTL: "10s",
+		RequestBodyLimit:      "1MiB",
+		RequestValidation:     "enforce",
+		ResponseValidation:    "off",
+		ProvenanceBuilderId:   v1.DefaultProvenanceBuilderId,
+		PolicyPath:            "imagebuilder/compose",
+
+		SecretsRefreshInterval: "5m",
+	}
+
+	if *dev {
+		conf.DistributionsDir = "distributions"
+	}
+	// reachable from outside of a container
+	if *demo {
+		conf.ListenAddress = ":8086"
+	}
+	// the defaults are kept around to load the config again on reloads
+	defaults := conf
+	conf, err := loadConfig(defaults, *configFile)
+	if err != nil {
+		panic(err)
 	}
 
-	err := config.LoadConfigFromEnv(&conf)
+	if *dev {
+		fakes, err := devmode.New().Start()
+		if err != nil {
+			panic(err)
+		}
+		conf.ComposerURL = fakes.ComposerURL
+		conf.ComposerTokenURL = fakes.TokenURL
+		conf.ComposerClientId = "devmode"
+		conf.ComposerOfflineToken = "devmode"
+		conf.ComposerClientSecret = ""
+		conf.ComposerCA = ""
+		conf.ComposerCert = ""
+		conf.ComposerKey = ""
+		conf.ComposerBackends = ""
+		conf.ProvisioningURL = fakes.ProvisioningURL
+		if conf.AuthProvider == "" {
+			conf.AuthProvider = "standalone"
+		}
+		if conf.StandaloneOrgId == "" {
+			conf.StandaloneOrgId = "000000"
+		}
+		logrus.Infof("Development mode, composer and provisioning are faked at %s", fakes.ComposerURL)
+	}
+
+	if *printConfig {
+		err = config.PrintConfig(os.Stdout, &conf)
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	// references to secrets are resolved at startup, those of the
+	// credentials clients send for each connection or token are resolved
+	// again, so they can be rotated without a restart
+	secretStores := map[string]secrets.Store{}
+	if conf.VaultAddress != "" {
+		vault, err := secrets.NewVault(secrets.VaultConfig{
+			Address:   conf.VaultAddress,
+			Token:     conf.VaultToken,
+			Namespace: conf.VaultNamespace,
+		})
+		if err != nil {
+			panic(err)
+		}
+		secretStores[secrets.SchemeVault] = vault
+	}
+	if conf.SecretsAWSRegion != "" {
+		sm, err := secrets.NewAWSSecretsManager(secrets.AWSConfig{
+			Region: conf.SecretsAWSRegion,
+		})
+		if err != nil {
+			panic(err)
+		}
+		secretStores[secrets.SchemeAWS] = sm
+	}
+	secretResolver := secrets.NewResolver(secretStores)
+	refreshed := func(ref string) func() string {
+		if !secrets.IsReference(ref) {
+			return nil
+		}
+		return func() string {
+			return secretResolver.Value(ref)
+		}
+	}
+	pgPassword := refreshed(conf.PGPassword)
+	composerOfflineToken := refreshed(conf.ComposerOfflineToken)
+	composerClientSecret := refreshed(conf.ComposerClientSecret)
+	redisPassword := refreshed(conf.RedisPassword)
+	kafkaPassword := refreshed(conf.KafkaPassword)
+	smtpPassword := refreshed(conf.SMTPPassword)
+	err = config.ResolveSecrets(context.Background(), &conf, secretResolver)
 	if err != nil {
 		panic(err)
 	}
+	if len(secretStores) > 0 {
+		secretsRefreshInterval, err := time.ParseDuration(conf.SecretsRefreshInterval)
+		if err != nil {
+			panic(err)
+		}
+		go secretResolver.Run(context.Background(), secretsRefreshInterval)
+	}
 
 	if conf.GlitchTipDSN != "" {
 		err = sentry.Init(sentry.ClientOptions{
@@ -53,6 +211,16 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	err = logger.SetModuleLevels(conf.LogModuleLevels)
+	if err != nil {
+		panic(err)
+	}
+	logger.ToggleDebugOnSignal()
+	logrus.AddHook(common.ContextHook{})
+
+	if conf.DiagnosticsAddress != "" {
+		go diagnostics.Serve(conf.DiagnosticsAddress)
+	}
 
 	if conf.CwAccessKeyID != "" {
 		err = logger.AddCloudWatchHook(logrus.StandardLogger(), conf.CwAccessKeyID, conf.CwSecretAccessKey, conf.CwRegion, conf.LogGroup)
@@ -61,38 +229,383 @@ func main() {
 		}
 	}
 
+	// the records only turn into metrics in cloudwatch
+	if conf.EMFNamespace != "" {
+		go prometheus.NewEMFEmitter(conf.EMFNamespace, logrus.StandardLogger()).Run(context.Background(), time.Minute)
+	}
+
 	if conf.SplunkHost != "" {
-		err = logger.AddSplunkHook(logrus.StandardLogger(), conf.SplunkHost, conf.SplunkPort, conf.SplunkToken)
+		batchSize, err := strconv.Atoi(conf.SplunkBatchSize)
+		if err != nil {
+			panic(err)
+		}
+		flushInterval, err := time.ParseDuration(conf.SplunkFlushInterval)
+		if err != nil {
+			panic(err)
+		}
+		queueSize, err := strconv.Atoi(conf.SplunkQueueSize)
+		if err != nil {
+			panic(err)
+		}
+		err = logger.AddSplunkHook(logrus.StandardLogger(), logger.SplunkConfig{
+			Host:          conf.SplunkHost,
+			Port:          conf.SplunkPort,
+			Token:         conf.SplunkToken,
+			BatchSize:     batchSize,
+			FlushInterval: flushInterval,
+			QueueSize:     queueSize,
+		})
 		if err != nil {
 			panic(err)
 		}
 	}
 
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s", conf.PGUser, conf.PGPassword, conf.PGHost, conf.PGPort, conf.PGDatabase, conf.PGSSLMode)
-	dbase, err := db.InitDBConnectionPool(connStr)
-	if err != nil {
-		panic(err)
+	var dbase db.DB
+	if *demo {
+		dbase = db.NewMemoryDB()
+		logrus.Warnf("Demo mode, composes are kept in memory and lost on exit")
+	} else {
+		connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s", conf.PGUser, conf.PGPassword, conf.PGHost, conf.PGPort, conf.PGDatabase, conf.PGSSLMode)
+		dbase, err = db.InitDBConnectionPoolWithPassword(connStr, pgPassword)
+		if err != nil {
+			panic(err)
+		}
 	}
 
 	composerConf := composer.ComposerClientConfig{
-		ComposerURL:  conf.ComposerURL,
-		CA:           conf.ComposerCA,
-		TokenURL:     conf.ComposerTokenURL,
-		ClientId:     conf.ComposerClientId,
-		OfflineToken: conf.ComposerOfflineToken,
-		ClientSecret: conf.ComposerClientSecret,
+		ComposerURL:      conf.ComposerURL,
+		CA:               conf.ComposerCA,
+		ClientCert:       conf.ComposerCert,
+		ClientKey:        conf.ComposerKey,
+		TokenURL:         conf.ComposerTokenURL,
+		ClientId:         conf.ComposerClientId,
+		OfflineToken:     conf.ComposerOfflineToken,
+		ClientSecret:     conf.ComposerClientSecret,
+		OfflineTokenFunc: composerOfflineToken,
+		ClientSecretFunc: composerClientSecret,
+		Timeouts:         parseTimeouts(conf.ComposerConnectTimeout, conf.ComposerReadTimeout, conf.ComposerRequestTimeout),
+		TokenTimeouts:    parseTimeouts(conf.ComposerTokenConnectTimeout, conf.ComposerTokenReadTimeout, conf.ComposerTokenRequestTimeout),
+		TokenCacheFile:   conf.ComposerTokenCacheFile,
+		MetricsURL:       conf.ComposerMetricsURL,
 	}
 	compClient, err := composer.NewClient(composerConf)
 	if err != nil {
 		panic(err)
 	}
+	go compClient.RunTokenRefresh(context.Background())
+
+	// additional composers share the credentials of the default one
+	backends := []composer.Backend{{Name: composer.DefaultBackend, Client: compClient}}
+	backendConfs, err := composer.ParseBackends(conf.ComposerBackends)
+	if err != nil {
+		panic(err)
+	}
+	for _, bc := range backendConfs {
+		bConf := composerConf
+		bConf.ComposerURL = bc.URL
+		bConf.TokenCacheFile = ""
+		bConf.MetricsURL = bc.MetricsURL
+		client, err := composer.NewClient(bConf)
+		if err != nil {
+			panic(err)
+		}
+		go client.RunTokenRefresh(context.Background())
+		backends = append(backends, composer.Backend{
+			Name:    bc.Name,
+			Client:  client,
+			Distros: bc.Distros,
+			Regions: bc.Regions,
+		})
+	}
+	composers, err := composer.NewPool(backends)
+	if err != nil {
+		panic(err)
+	}
+	// composer needs credentials of the partition to upload into GovCloud
+	if conf.OsbuildGovRegion != "" {
+		if _, _, ok := composers.RegionBackend(conf.OsbuildGovRegion); !ok {
+			panic(fmt.Sprintf("OSBUILD_AWS_GOV_REGION needs a composer backend listing region %s", conf.OsbuildGovRegion))
+		}
+	}
+	capabilitiesRefresh, err := time.ParseDuration(conf.ComposerCapabilitiesRefresh)
+	if err != nil {
+		panic(err)
+	}
+	for _, b := range backends {
+		go b.Client.RunCapabilityRefresh(context.Background(), capabilitiesRefresh)
+	}
+	composerQueueInterval, err := time.ParseDuration(conf.ComposerQueueInterval)
+	if err != nil {
+		panic(err)
+	}
+	if len(backends) > 1 {
+		healthCheckInterval, err := time.ParseDuration(conf.ComposerHealthCheckInterval)
+		if err != nil {
+			panic(err)
+		}
+		go composers.RunHealthChecks(context.Background(), healthCheckInterval)
+	}
 	provClient, err := provisioning.NewClient(provisioning.ProvisioningClientConfig{
-		URL: conf.ProvisioningURL,
+		URL:      conf.ProvisioningURL,
+		Timeouts: parseTimeouts(conf.ProvisioningConnectTimeout, conf.ProvisioningReadTimeout, conf.ProvisioningRequestTimeout),
 	})
 	if err != nil {
 		panic(err)
 	}
 
+	var rbacClient *rbac.RBACClient
+	if conf.RBACURL != "" {
+		rbacClient, err = rbac.NewClient(rbac.RBACClientConfig{
+			URL: conf.RBACURL,
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var policyClient *policy.PolicyClient
+	if conf.PolicyURL != "" {
+		policyClient, err = policy.NewClient(policy.PolicyClientConfig{
+			URL:  conf.PolicyURL,
+			Path: conf.PolicyPath,
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var featureFlags featureflags.Flags
+	if conf.UnleashURL != "" {
+		unleashClient, err := featureflags.NewUnleashClient(featureflags.UnleashConfig{
+			URL:     conf.UnleashURL,
+			Token:   conf.UnleashToken,
+			AppName: "image-builder",
+		})
+		if err != nil {
+			panic(err)
+		}
+		// until the flags are fetched only restricted distributions are
+		// gated, by the allow file
+		err = unleashClient.Refresh(context.Background())
+		if err != nil {
+			logrus.Warnf("Unable to fetch feature flags: %v", err)
+		}
+		go unleashClient.Run(context.Background(), 15*time.Second)
+		featureFlags = unleashClient
+	}
+
+	// RATE_LIMIT_REQUESTS requests per RATE_LIMIT_INTERVAL, with bursts of
+	// up to as many requests
+	var rateLimiter ratelimit.Limiter
+	if conf.RateLimitRequests != "" {
+		requests, err := strconv.Atoi(conf.RateLimitRequests)
+		if err != nil {
+			panic(err)
+		}
+		interval, err := time.ParseDuration(conf.RateLimitInterval)
+		if err != nil {
+			panic(err)
+		}
+		rateLimitConf := ratelimit.Config{
+			Rate:  float64(requests) / interval.Seconds(),
+			Burst: requests,
+		}
+		if conf.RedisAddress != "" {
+			rateLimiter = ratelimit.NewRedisLimiter(rateLimitConf, ratelimit.RedisConfig{
+				Address:      conf.RedisAddress,
+				Password:     conf.RedisPassword,
+				PasswordFunc: redisPassword,
+			})
+		} else {
+			rateLimiter = ratelimit.NewMemoryLimiter(rateLimitConf)
+		}
+	}
+
+	composeQueueInterval, err := time.ParseDuration(conf.ComposeQueueInterval)
+	if err != nil {
+		panic(err)
+	}
+
+	webhookInterval, err := time.ParseDuration(conf.WebhookInterval)
+	if err != nil {
+		panic(err)
+	}
+
+	statusSyncInterval, err := time.ParseDuration(conf.StatusSyncInterval)
+	if err != nil {
+		panic(err)
+	}
+
+	outboxInterval, err := time.ParseDuration(conf.OutboxInterval)
+	if err != nil {
+		panic(err)
+	}
+
+	// compose lifecycle events, notifications and images are only
+	// published if brokers are configured, notifications and images only to
+	// topics of their own
+	var events, notifications, inventory v1.EventPublisher
+	if conf.KafkaBrokers != "" {
+		kafkaConf := kafka.Config{
+			Brokers:      strings.Split(conf.KafkaBrokers, ","),
+			Topic:        conf.KafkaTopic,
+			ClientId:     "image-builder",
+			TLS:          conf.KafkaTLS == "true",
+			CA:           conf.KafkaCA,
+			Username:     conf.KafkaUsername,
+			Password:     conf.KafkaPassword,
+			Mechanism:    conf.KafkaMechanism,
+			PasswordFunc: kafkaPassword,
+		}
+		events, err = kafka.NewProducer(kafkaConf)
+		if err != nil {
+			panic(err)
+		}
+		if conf.NotificationsTopic != "" {
+			kafkaConf.Topic = conf.NotificationsTopic
+			notifications, err = kafka.NewProducer(kafkaConf)
+			if err != nil {
+				panic(err)
+			}
+		}
+		if conf.InventoryTopic != "" {
+			kafkaConf.Topic = conf.InventoryTopic
+			inventory, err = kafka.NewProducer(kafkaConf)
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	// composes can only ask for emails if smtp is configured
+	var mailer v1.Mailer
+	if conf.SMTPAddress != "" {
+		emailRateLimit, err := strconv.Atoi(conf.EmailRateLimit)
+		if err != nil {
+			panic(err)
+		}
+		mailer, err = email.NewMailer(email.Config{
+			Address:      conf.SMTPAddress,
+			Username:     conf.SMTPUsername,
+			Password:     conf.SMTPPassword,
+			PasswordFunc: smtpPassword,
+			From:         conf.SMTPFrom,
+			TemplatesDir: conf.EmailTemplatesDir,
+			RateLimit:    emailRateLimit,
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	composeStatusCacheTTL, err := time.ParseDuration(conf.ComposeStatusCacheTTL)
+	if err != nil {
+		panic(err)
+	}
+
+	maxBodySize, err := bytes.Parse(conf.RequestBodyLimit)
+	if err != nil {
+		panic(err)
+	}
+	operationBodySizes, err := v1.ParseBodySizes(conf.RequestBodyLimits)
+	if err != nil {
+		panic(err)
+	}
+
+	requestValidation, err := v1.ParseValidationMode(conf.RequestValidation, v1.ValidationEnforce)
+	if err != nil {
+		panic(err)
+	}
+	responseValidation, err := v1.ParseValidationMode(conf.ResponseValidation, v1.ValidationOff)
+	if err != nil {
+		panic(err)
+	}
+
+	var provenanceSigner crypto.Signer
+	if conf.ProvenanceSigningKey != "" {
+		provenanceSigner, err = signing.LoadKey(conf.ProvenanceSigningKey)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var cosignConfig v1.CosignConfig
+	if tmpl := conf.CosignKeyStore; tmpl != "" {
+		cosignConfig.OrgSecret = func(ctx context.Context, orgId, key string) (string, error) {
+			ref, err := secrets.OrgReference(tmpl, orgId, key)
+			if err != nil {
+				return "", err
+			}
+			return secretResolver.Lookup(ctx, ref)
+		}
+	}
+	if conf.CosignFulcioURL != "" {
+		cosignConfig.Fulcio = &cosign.Fulcio{
+			URL:       conf.CosignFulcioURL,
+			TokenPath: conf.CosignOIDCTokenPath,
+		}
+	}
+
+	var scanner v1.VulnerabilityScanner
+	if conf.TrivyURL != "" {
+		scanner = vulnscan.NewTrivy(vulnscan.TrivyConfig{
+			URL:   conf.TrivyURL,
+			Token: conf.TrivyToken,
+		})
+	}
+
+	var keys *keystore.Keystore
+	if conf.KeyEncryptionKeys != "" {
+		keys, err = keystore.Parse(conf.KeyEncryptionKeys)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// the audit log and provenance aren't exported if the window is empty
+	var complianceExport v1.ComplianceExportConfig
+	if conf.ComplianceExportWindow != "" {
+		complianceExport.Window, err = time.ParseDuration(conf.ComplianceExportWindow)
+		if err != nil {
+			panic(err)
+		}
+		complianceExport.Store, err = s3.NewClient(s3.Config{
+			Endpoint: conf.ComplianceExportEndpoint,
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// AMIs are encrypted by copying them with the credentials of the pod,
+	// which have to be of the account composer uploads into
+	awsConfig := v1.AWSConfig{
+		Region:           conf.OsbuildRegion,
+		GovRegion:        conf.OsbuildGovRegion,
+		DefaultKMSKeyArn: conf.OsbuildKMSKeyArn,
+	}
+	if conf.OsbuildEncryptImages == "true" {
+		awsConfig.EC2, err = ec2.NewClient(ec2.Config{})
+		if err != nil {
+			panic(err)
+		}
+		awsConfig.KMS, err = kms.NewClient(kms.Config{})
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var streamOrigins []string
+	if conf.StreamAllowedOrigins != "" {
+		streamOrigins = strings.Split(conf.StreamAllowedOrigins, ",")
+	}
+
+	// 0 disables the deadline
+	requestDeadline, err := time.ParseDuration(conf.RequestDeadline)
+	if err != nil {
+		panic(err)
+	}
+
 	adr, err := distribution.LoadDistroRegistry(conf.DistributionsDir)
 	if err != nil {
 		panic(err)
@@ -105,6 +618,21 @@ func main() {
 	echoServer := echo.New()
 	echoServer.HideBanner = true
 	echoServer.Logger = common.Logger()
+	echoServer.Use(common.RequestIdMiddleware)
+	if requestDeadline > 0 {
+		echoServer.Use(common.RequestDeadlineMiddleware(requestDeadline))
+	}
+	// package searches and compose lists compress well, the metrics handler
+	// compresses by itself and the probes are tiny
+	echoServer.Use(middleware.GzipWithConfig(middleware.GzipConfig{
+		Skipper: func(c echo.Context) bool {
+			switch c.Request().URL.Path {
+			case "/metrics", "/status", "/live", "/ready":
+				return true
+			}
+			return false
+		},
+	}))
 	echoServer.Use(middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
 		LogURI:     true,
 		LogStatus:  true,
@@ -117,16 +645,10 @@ func main() {
 				"status":     values.Status,
 				"latency_ms": values.Latency.Milliseconds(),
 			}
-			rid := c.Request().Header.Get("X-Rh-Edge-Request-Id")
-			if rid != "" {
-				fields["request_id"] = strings.Replace(rid, "\n", "", -1)
-			} else {
-				fields["request_id"] = random.String(12)
-			}
 			if values.Error != nil {
 				fields["error"] = values.Error
 			}
-			logrus.WithFields(fields).Infof("Processed request %s %s", values.Method, values.URI)
+			logrus.WithContext(c.Request().Context()).WithFields(fields).Infof("Processed request %s %s", values.Method, values.URI)
 
 			return nil
 		},
@@ -140,11 +662,10 @@ func main() {
 	serverConfig := &v1.ServerConfig{
 		EchoServer: echoServer,
 		CompClient: compClient,
+		Composers:  composers,
 		ProvClient: provClient,
 		DBase:      dbase,
-		AwsConfig: v1.AWSConfig{
-			Region: conf.OsbuildRegion,
-		},
+		AwsConfig:  awsConfig,
 		GcpConfig: v1.GCPConfig{
 			Region: conf.OsbuildGCPRegion,
 			Bucket: conf.OsbuildGCPBucket,
@@ -153,6 +674,77 @@ func main() {
 		AllowFile:        conf.AllowFile,
 		AllDistros:       adr,
 		DistributionsDir: conf.DistributionsDir,
+		RBACClient:       rbacClient,
+		RateLimiter:      rateLimiter,
+		PolicyClient:     policyClient,
+		RequestLimits: v1.RequestLimits{
+			MaxBodySize:        maxBodySize,
+			OperationBodySizes: operationBodySizes,
+		},
+		ComposeQueueInterval:  composeQueueInterval,
+		ComposerQueueInterval: composerQueueInterval,
+		WebhookInterval:       webhookInterval,
+		Events:                events,
+		Notifications:         notifications,
+		Inventory:             inventory,
+		Mailer:                mailer,
+		StatusSyncInterval:    statusSyncInterval,
+		OutboxInterval:        outboxInterval,
+		ComposeStatusCacheTTL: composeStatusCacheTTL,
+		ApprovalWebhookURL:    conf.ApprovalWebhookURL,
+		FeatureFlags:          featureFlags,
+		RequestValidation:     requestValidation,
+		ResponseValidation:    responseValidation,
+		Reload:                watchReloads(defaults, *configFile),
+		Provenance: v1.ProvenanceConfig{
+			BuilderId: conf.ProvenanceBuilderId,
+			Signer:    provenanceSigner,
+		},
+		Cosign:               cosignConfig,
+		Scanner:              scanner,
+		Keystore:             keys,
+		ComplianceExport:     complianceExport,
+		StreamAllowedOrigins: streamOrigins,
+	}
+
+	switch conf.AuthProvider {
+	case "", "identity-header":
+		if conf.ServiceAccountJWKS != "" && conf.ServiceAccountAudiences == "" {
+			panic("SERVICE_ACCOUNT_AUDIENCES is required to accept service account tokens")
+		}
+		serverConfig.Authenticator = v1.NewIdentityHeaderAuthenticator(v1.ServiceAccountConfig{
+			JWKSURL: conf.ServiceAccountJWKS,
+			Issuer:  conf.ServiceAccountIssuer,
+			TokenClaimsConfig: v1.TokenClaimsConfig{
+				Audiences:    splitList(conf.ServiceAccountAudiences),
+				Entitlements: splitList(conf.ServiceAccountEntitlements),
+			},
+		})
+	case "oidc":
+		serverConfig.Authenticator, err = v1.NewOIDCAuthenticator(v1.OIDCConfig{
+			Issuer:     conf.OIDCIssuer,
+			JWKSURL:    conf.OIDCJWKS,
+			OrgIdClaim: conf.OIDCOrgIdClaim,
+			TokenClaimsConfig: v1.TokenClaimsConfig{
+				Audiences:         splitList(conf.OIDCAudiences),
+				Entitlements:      splitList(conf.OIDCEntitlements),
+				EntitlementsClaim: conf.OIDCEntitlementsClaim,
+			},
+		})
+		if err != nil {
+			panic(err)
+		}
+	case "standalone":
+		serverConfig.Authenticator, err = v1.NewStandaloneAuthenticator(v1.StandaloneConfig{
+			OrgId:     conf.StandaloneOrgId,
+			Username:  conf.StandaloneUsername,
+			UsersFile: conf.StandaloneUsersFile,
+		})
+		if err != nil {
+			panic(err)
+		}
+	default:
+		panic(fmt.Sprintf("unknown auth provider %q", conf.AuthProvider))
 	}
 
 	err = v1.Attach(serverConfig)
@@ -160,9 +752,50 @@ func main() {
 		panic(err)
 	}
 
+	// keep idle connections of clients, and the gateway, around for reuse
+	// but don't let slow clients hold connections with partial headers
+	echoServer.Server.IdleTimeout = 2 * time.Minute
+	echoServer.Server.ReadHeaderTimeout = 10 * time.Second
+
+	// HTTP/2 without TLS, the gateway terminates TLS, HTTP/1.1 clients are
+	// still served
 	logrus.Infof("🚀 Starting image-builder server on %v ...\n", conf.ListenAddress)
-	err = echoServer.Start(conf.ListenAddress)
+	err = echoServer.StartH2CServer(conf.ListenAddress, &http2.Server{
+		MaxConcurrentStreams: 250,
+		IdleTimeout:          2 * time.Minute,
+	})
 	if err != nil {
 		panic(err)
 	}
 }
+
+// parseTimeouts of the connections to another service, empty or 0 disables a
+// timeout.
+func parseTimeouts(connect, read, request string) common.HTTPTimeouts {
+	parse := func(d string) time.Duration {
+		if d == "" {
+			return 0
+		}
+		duration, err := time.ParseDuration(d)
+		if err != nil {
+			panic(err)
+		}
+		return duration
+	}
+	return common.HTTPTimeouts{
+		Connect: parse(connect),
+		Read:    parse(read),
+		Request: parse(request),
+	}
+}
+
+// splitList of comma separated values, empty if the list is.
+func splitList(list string) []string {
+	var values []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/config"
+	"github.com/osbuild/image-builder/internal/logger"
+	v1 "github.com/osbuild/image-builder/internal/v1"
+)
+
+// How often the config file is checked for changes, config maps mounted into
+// pods are updated without a signal.
+const configFileCheckInterval = 10 * time.Second
+
+// loadConfig loads the config file, if any, and the env variables on top of
+// defaults.
+func loadConfig(defaults config.ImageBuilderConfig, configFile string) (config.ImageBuilderConfig, error) {
+	conf := defaults
+	if configFile != "" {
+		err := config.LoadConfigFromFile(&conf, configFile)
+		if err != nil {
+			return conf, err
+		}
+	}
+	err := config.LoadConfigFromEnv(&conf)
+	return conf, err
+}
+
+// watchReloads loads the config again on SIGHUP and whenever the config
+// file changes. The log levels are applied right away, the settings of the
+// server are sent on the returned channel. Everything else needs a restart.
+func watchReloads(defaults config.ImageBuilderConfig, configFile string) <-chan v1.ReloadableConfig {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	reloads := make(chan v1.ReloadableConfig)
+
+	go func() {
+		ticker := time.NewTicker(configFileCheckInterval)
+		defer ticker.Stop()
+		modTime := configModTime(configFile)
+		for {
+			select {
+			case <-signals:
+				logrus.Infof("Reloading the config on SIGHUP")
+			case <-ticker.C:
+				mt := configModTime(configFile)
+				if mt.Equal(modTime) {
+					continue
+				}
+				modTime = mt
+				logrus.Infof("Reloading the config, %s changed", configFile)
+			}
+
+			conf, err := loadConfig(defaults, configFile)
+			if err != nil {
+				logrus.Errorf("Unable to reload the config, keeping the previous one: %v", err)
+				continue
+			}
+			err = logger.ReloadLevels(conf.LogLevel, conf.LogModuleLevels)
+			if err != nil {
+				logrus.Errorf("Unable to reload the log levels: %v", err)
+			}
+			reloads <- v1.ReloadableConfig{
+				QuotaFile:        conf.QuotaFile,
+				AllowFile:        conf.AllowFile,
+				DistributionsDir: conf.DistributionsDir,
+			}
+		}
+	}()
+	return reloads
+}
+
+// configModTime is the time the config file was last modified, zero if
+// there's none. Stat follows the symlinks config maps are mounted with.
+func configModTime(configFile string) time.Time {
+	if configFile == "" {
+		return time.Time{}
+	}
+	fi, err := os.Stat(filepath.Clean(configFile))
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
@@ -17,4 +17,4 @@ else
     echo "Distributions dir: ${DISTRIBUTIONS_DIR}"
 fi
 
-/app/image-builder -v
+exec /app/image-builder "$@"
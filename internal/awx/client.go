@@ -0,0 +1,112 @@
+// Package awx launches job templates of AWX, or of the Ansible Automation
+// Platform controller which shares its api.
+package awx
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/osbuild/image-builder/internal/common"
+)
+
+// Default timeout of launching a job, the controllers are run by the
+// organizations and may be slow or unreachable.
+const defaultTimeout = 30 * time.Second
+
+// JobTemplate is a job template on a controller and the token launching it.
+type JobTemplate struct {
+	// Base url of the controller, e.g. https://awx.example.com
+	URL   string
+	Token string
+	Id    int
+}
+
+type Client struct {
+	client *http.Client
+}
+
+type Config struct {
+	// Defaults to a request timeout of 30 seconds.
+	Timeouts common.HTTPTimeouts
+	// Optional, e.g. for controllers with certificates of a private CA.
+	TLSConfig *tls.Config
+	// Optional, replaces the transport which only connects to public
+	// addresses, e.g. for tests with a controller on the loopback address.
+	Transport http.RoundTripper
+}
+
+// NewClient returns a client which only connects to public addresses, the
+// urls of the controllers are set by the orgs.
+func NewClient(conf Config) *Client {
+	if conf.Timeouts == (common.HTTPTimeouts{}) {
+		conf.Timeouts.Request = defaultTimeout
+	}
+	client := common.NewPublicHTTPClient(conf.Timeouts, conf.TLSConfig)
+	if conf.Transport != nil {
+		client.Transport = conf.Transport
+	}
+	return &Client{
+		client: client,
+	}
+}
+
+// LaunchError is returned when the controller refused to launch a job.
+type LaunchError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *LaunchError) Error() string {
+	return fmt.Sprintf("launching the job template failed with %d: %s", e.StatusCode, e.Body)
+}
+
+// Launch launches a job template with extraVars, which the template has to
+// prompt for on launch, and returns the id of the job.
+func (c *Client) Launch(ctx context.Context, t JobTemplate, extraVars interface{}) (int, error) {
+	body, err := json.Marshal(struct {
+		ExtraVars interface{} `json:"extra_vars"`
+	}{extraVars})
+	if err != nil {
+		return 0, err
+	}
+
+	url := fmt.Sprintf("%s/api/v2/job_templates/%d/launch/", strings.TrimSuffix(t.URL, "/"), t.Id)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.Token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		// the controller describes the problem in the body, keep it short
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return 0, &LaunchError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(msg))}
+	}
+
+	var launched struct {
+		Job int `json:"job"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&launched)
+	if err != nil {
+		return 0, fmt.Errorf("unable to decode the launched job: %w", err)
+	}
+	if launched.Job == 0 {
+		return 0, fmt.Errorf("the controller didn't return the launched job")
+	}
+	return launched.Job, nil
+}
@@ -0,0 +1,52 @@
+package awx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLaunch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, err := w.Write([]byte(`{"detail": "Authentication credentials were not provided."}`))
+			require.NoError(t, err)
+			return
+		}
+		require.Equal(t, "/api/v2/job_templates/7/launch/", r.URL.Path)
+
+		var body struct {
+			ExtraVars map[string]string `json:"extra_vars"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, map[string]string{"ami": "ami-1"}, body.ExtraVars)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte(`{"job": 42, "id": 42, "status": "pending"}`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	// controllers on internal addresses aren't launched
+	_, err := NewClient(Config{}).Launch(context.Background(), JobTemplate{URL: srv.URL, Token: "token", Id: 7}, map[string]string{"ami": "ami-1"})
+	require.ErrorContains(t, err, "127.0.0.1 isn't a public address")
+
+	c := NewClient(Config{Transport: srv.Client().Transport})
+	job, err := c.Launch(context.Background(), JobTemplate{URL: srv.URL + "/", Token: "token", Id: 7}, map[string]string{"ami": "ami-1"})
+	require.NoError(t, err)
+	require.Equal(t, 42, job)
+
+	_, err = c.Launch(context.Background(), JobTemplate{URL: srv.URL, Token: "wrong", Id: 7}, map[string]string{"ami": "ami-1"})
+	var launchErr *LaunchError
+	require.True(t, errors.As(err, &launchErr))
+	require.Equal(t, http.StatusUnauthorized, launchErr.StatusCode)
+	require.Contains(t, launchErr.Body, "Authentication credentials")
+}
@@ -0,0 +1,273 @@
+// Package client talks to the v1 api of image builder, for tools and
+// automation which run outside of the service.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	v1 "github.com/osbuild/image-builder/internal/v1"
+)
+
+const (
+	DefaultURL      = "https://console.redhat.com"
+	DefaultTokenURL = "https://sso.redhat.com/auth/realms/redhat-external/protocol/openid-connect/token"
+	// the client of offline tokens generated on access.redhat.com
+	DefaultClientId = "rhsm-api"
+	DefaultTimeout  = 3 * time.Minute
+)
+
+type Config struct {
+	// Where the service runs, DefaultURL if empty.
+	URL string
+
+	// An api token of image builder, it's sent as is.
+	APIToken string
+
+	// An offline token, or the id and secret of a service account, which
+	// are exchanged for access tokens at TokenURL.
+	TokenURL     string
+	ClientId     string
+	OfflineToken string
+	ClientSecret string
+
+	// The identity header of the caller, for deployments which aren't
+	// behind the gateway of console.redhat.com, e.g. one run locally.
+	Identity string
+
+	Timeout time.Duration
+}
+
+type Client struct {
+	apiURL string
+	conf   Config
+	http   *http.Client
+
+	tokenMu     sync.Mutex
+	accessToken string
+}
+
+// Error is an error response of the api.
+type Error struct {
+	StatusCode int
+	Problem    v1.HTTPErrorList
+}
+
+func (e *Error) Error() string {
+	if e.Problem.Detail == "" {
+		return fmt.Sprintf("image builder responded with %d", e.StatusCode)
+	}
+	if e.Problem.RequestId != nil {
+		return fmt.Sprintf("%s (%s, request %s)", e.Problem.Detail, e.Problem.Code, *e.Problem.RequestId)
+	}
+	return fmt.Sprintf("%s (%s)", e.Problem.Detail, e.Problem.Code)
+}
+
+func New(conf Config) (*Client, error) {
+	if conf.URL == "" {
+		conf.URL = DefaultURL
+	}
+	if conf.TokenURL == "" {
+		conf.TokenURL = DefaultTokenURL
+	}
+	if conf.ClientId == "" {
+		conf.ClientId = DefaultClientId
+	}
+	if conf.Timeout == 0 {
+		conf.Timeout = DefaultTimeout
+	}
+	if conf.APIToken == "" && conf.OfflineToken == "" && conf.ClientSecret == "" && conf.Identity == "" {
+		return nil, fmt.Errorf("Client needs an api token, an offline token, a service account or an identity")
+	}
+	_, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid url of image builder: %v", err)
+	}
+	return &Client{
+		apiURL: strings.TrimSuffix(conf.URL, "/") + "/api/image-builder/v1",
+		conf:   conf,
+		http:   &http.Client{Timeout: conf.Timeout},
+	}, nil
+}
+
+// Compose starts a compose and returns its id.
+func (c *Client) Compose(ctx context.Context, cr v1.ComposeRequest) (uuid.UUID, error) {
+	var resp v1.ComposeResponse
+	err := c.do(ctx, http.MethodPost, "/compose", cr, &resp)
+	return resp.Id, err
+}
+
+// Composes lists the composes of the last 14 days, the newest first.
+func (c *Client) Composes(ctx context.Context, limit, offset int) (*v1.ComposesResponse, error) {
+	var resp v1.ComposesResponse
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/composes?limit=%d&offset=%d", limit, offset), nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ComposeStatus returns the status of a compose. With a wait, the service
+// holds the request until the status changes or the wait is over.
+func (c *Client) ComposeStatus(ctx context.Context, id uuid.UUID, wait time.Duration) (*v1.ComposeStatus, error) {
+	path := fmt.Sprintf("/composes/%s", id)
+	if wait > 0 {
+		path += "?wait=" + url.QueryEscape(wait.String())
+	}
+	var resp v1.ComposeStatus
+	err := c.do(ctx, http.MethodGet, path, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ComposeEvents returns the statuses a compose went through, oldest first.
+func (c *Client) ComposeEvents(ctx context.Context, id uuid.UUID) (*v1.ComposeEvents, error) {
+	var resp v1.ComposeEvents
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/composes/%s/events", id), nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Clone copies the image of a compose, e.g. to another region, and returns
+// the id of the clone.
+func (c *Client) Clone(ctx context.Context, id uuid.UUID, clone v1.CloneRequest) (uuid.UUID, error) {
+	var resp v1.CloneResponse
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/composes/%s/clone", id), clone, &resp)
+	return resp.Id, err
+}
+
+// CloneStatus returns the status of the upload of a clone.
+func (c *Client) CloneStatus(ctx context.Context, id uuid.UUID) (*v1.UploadStatus, error) {
+	var resp v1.UploadStatus
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/clones/%s", id), nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var raw []byte
+	if body != nil {
+		var err error
+		raw, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.send(ctx, method, path, raw, false)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && c.usesAccessTokens() {
+		// the access token expired
+		resp.Body.Close()
+		resp, err = c.send(ctx, method, path, raw, true)
+		if err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		// errors without a body, e.g. of the gateway, only have a status
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr.Problem)
+		return apiErr
+	}
+	if out == nil {
+		return nil
+	}
+	err = json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		return fmt.Errorf("Unable to decode the response of %s %s: %v", method, path, err)
+	}
+	return nil
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body []byte, refresh bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.apiURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	switch {
+	case c.conf.APIToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.conf.APIToken)
+	case c.usesAccessTokens():
+		token, err := c.token(ctx, refresh)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	default:
+		req.Header.Set("X-Rh-Identity", c.conf.Identity)
+	}
+	return c.http.Do(req)
+}
+
+func (c *Client) usesAccessTokens() bool {
+	return c.conf.APIToken == "" && (c.conf.OfflineToken != "" || c.conf.ClientSecret != "")
+}
+
+// token returns an access token, it's only asked for again after it was
+// rejected.
+func (c *Client) token(ctx context.Context, refresh bool) (string, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.accessToken != "" && !refresh {
+		return c.accessToken, nil
+	}
+
+	data := url.Values{}
+	data.Set("client_id", c.conf.ClientId)
+	if c.conf.ClientSecret != "" {
+		data.Set("grant_type", "client_credentials")
+		data.Set("client_secret", c.conf.ClientSecret)
+	} else {
+		data.Set("grant_type", "refresh_token")
+		data.Set("refresh_token", c.conf.OfflineToken)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.conf.TokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Unable to get an access token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("Token endpoint responded with %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	var tr struct {
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&tr)
+	if err != nil {
+		return "", fmt.Errorf("Unable to decode the access token: %v", err)
+	}
+	c.accessToken = tr.AccessToken
+	return c.accessToken, nil
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/osbuild/image-builder/internal/v1"
+)
+
+func TestNewNeedsCredentials(t *testing.T) {
+	_, err := New(Config{})
+	require.Error(t, err)
+	_, err = New(Config{APIToken: "ibt_token"})
+	require.NoError(t, err)
+}
+
+func TestClientAccessToken(t *testing.T) {
+	id := uuid.New()
+	var tokens []string
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "refresh_token", r.Form.Get("grant_type"))
+		require.Equal(t, "rhsm-api", r.Form.Get("client_id"))
+		require.Equal(t, "offline", r.Form.Get("refresh_token"))
+		token := uuid.NewString()
+		tokens = append(tokens, token)
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]string{"access_token": token}))
+	}))
+	defer tokenSrv.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first token expired
+		if r.Header.Get("Authorization") != "Bearer "+tokens[len(tokens)-1] || len(tokens) == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.Equal(t, "/api/image-builder/v1/composes/"+id.String(), r.URL.Path)
+		require.Equal(t, "1m0s", r.URL.Query().Get("wait"))
+		require.NoError(t, json.NewEncoder(w).Encode(v1.ComposeStatus{
+			ImageStatus: v1.ImageStatus{Status: v1.ImageStatusStatusBuilding},
+		}))
+	}))
+	defer srv.Close()
+
+	c, err := New(Config{URL: srv.URL, TokenURL: tokenSrv.URL, OfflineToken: "offline"})
+	require.NoError(t, err)
+	status, err := c.ComposeStatus(context.Background(), id, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, v1.ImageStatusStatusBuilding, status.ImageStatus.Status)
+	require.Len(t, tokens, 2)
+}
+
+func TestClientErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "identity", r.Header.Get("X-Rh-Identity"))
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusForbidden)
+		require.NoError(t, json.NewEncoder(w).Encode(v1.HTTPErrorList{
+			Code:   "UPLOAD_TARGET_NOT_ALLOWED",
+			Detail: "aws uploads are not allowed",
+		}))
+	}))
+	defer srv.Close()
+
+	c, err := New(Config{URL: srv.URL, Identity: "identity"})
+	require.NoError(t, err)
+	_, err = c.Compose(context.Background(), v1.ComposeRequest{})
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+	require.Equal(t, "aws uploads are not allowed (UPLOAD_TARGET_NOT_ALLOWED)", err.Error())
+}
@@ -0,0 +1,98 @@
+package common
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CertReloader holds a client certificate and reloads it when the files on
+// disk change, so rotated certificates are picked up without a restart. The
+// files are checked on every tls handshake, which only happens when a new
+// connection gets established.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu           sync.Mutex
+	cert         *tls.Certificate
+	certModTime  time.Time
+	keyModTime   time.Time
+	reloadFailed bool
+}
+
+// NewCertReloader loads the certificate and key, and fails if they're not
+// valid.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	cr := CertReloader{
+		certFile: filepath.Clean(certFile),
+		keyFile:  filepath.Clean(keyFile),
+	}
+	err := cr.reload()
+	if err != nil {
+		return nil, err
+	}
+	return &cr, nil
+}
+
+func (cr *CertReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(cr.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	keyInfo, err := os.Stat(cr.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+func (cr *CertReloader) reload() error {
+	certModTime, keyModTime, err := cr.modTimes()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return fmt.Errorf("Unable to load client certificate %s: %v", cr.certFile, err)
+	}
+	cr.cert = &cert
+	cr.certModTime = certModTime
+	cr.keyModTime = keyModTime
+	return nil
+}
+
+// GetClientCertificate can be used as tls.Config.GetClientCertificate. If
+// reloading fails, e.g. because only one of the files was replaced so far,
+// the previous certificate keeps being used.
+func (cr *CertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	certModTime, keyModTime, err := cr.modTimes()
+	if err != nil {
+		logrus.Errorf("Unable to check client certificate %s for changes: %v", cr.certFile, err)
+		return cr.cert, nil
+	}
+	if certModTime.Equal(cr.certModTime) && keyModTime.Equal(cr.keyModTime) {
+		return cr.cert, nil
+	}
+
+	err = cr.reload()
+	if err != nil {
+		// don't log every handshake until the files are fixed
+		if !cr.reloadFailed {
+			logrus.Errorf("Keeping the previous client certificate: %v", err)
+		}
+		cr.reloadFailed = true
+		return cr.cert, nil
+	}
+	cr.reloadFailed = false
+	logrus.Infof("Reloaded client certificate %s", cr.certFile)
+	return cr.cert, nil
+}
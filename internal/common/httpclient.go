@@ -0,0 +1,61 @@
+package common
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTPTimeouts of the requests to another service, zero disables a timeout.
+type HTTPTimeouts struct {
+	// Establishing the connection, including the TLS handshake
+	Connect time.Duration
+	// Waiting for the response headers once the request was sent
+	Read time.Duration
+	// The whole request, including reading the response body
+	Request time.Duration
+}
+
+// NewHTTPClient returns a client with the timeouts set, and tlsConfig if it
+// isn't nil.
+func NewHTTPClient(timeouts HTTPTimeouts, tlsConfig *tls.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// requests go to a handful of hosts, keep enough connections to them for
+	// concurrent requests instead of the default of two
+	transport.MaxIdleConnsPerHost = 32
+	transport.IdleConnTimeout = 90 * time.Second
+	if timeouts.Connect > 0 {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   timeouts.Connect,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+		transport.TLSHandshakeTimeout = timeouts.Connect
+	}
+	transport.ResponseHeaderTimeout = timeouts.Read
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeouts.Request,
+	}
+}
+
+// RequestDeadlineMiddleware bounds the time spent on each request, the context
+// of the request is done once the budget is spent. Requests to other services
+// aren't retried past the deadline, and those which can be abandoned are
+// cancelled.
+func RequestDeadlineMiddleware(budget time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx, cancel := context.WithTimeout(c.Request().Context(), budget)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
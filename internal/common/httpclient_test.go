@@ -0,0 +1,40 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	_, err := NewHTTPClient(HTTPTimeouts{Read: 10 * time.Millisecond}, nil).Get(srv.URL)
+	require.ErrorContains(t, err, "timeout awaiting response headers")
+
+	client := NewHTTPClient(HTTPTimeouts{Read: time.Second}, nil)
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, 32, client.Transport.(*http.Transport).MaxIdleConnsPerHost)
+}
+
+func TestRequestDeadlineMiddleware(t *testing.T) {
+	var deadline time.Time
+	handler := RequestDeadlineMiddleware(time.Minute)(func(c echo.Context) error {
+		var ok bool
+		deadline, ok = c.Request().Context().Deadline()
+		require.True(t, ok)
+		return nil
+	})
+	start := time.Now()
+	require.NoError(t, handler(echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())))
+	require.WithinDuration(t, start.Add(time.Minute), deadline, time.Second)
+}
@@ -0,0 +1,100 @@
+package common
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ranges which aren't reachable from the internet, on top of what the
+// methods of net.IP cover
+var nonPublicNets = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	// carrier-grade NAT
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("192.0.0.0/24"),
+	mustParseCIDR("198.18.0.0/15"),
+	mustParseCIDR("240.0.0.0/4"),
+	// NAT64 can translate to any IPv4 address
+	mustParseCIDR("64:ff9b::/96"),
+	mustParseCIDR("64:ff9b:1::/48"),
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// IsPublicIP returns whether ip is a unicast address on the internet, and not
+// one of a private network, the host or the cluster.
+func IsPublicIP(ip net.IP) bool {
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, n := range nonPublicNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidatePublicHost rejects hosts which are known not to be public without
+// resolving them, i.e. localhost and addresses which aren't public. Names are
+// checked when they're dialed, as they can resolve to anything.
+func ValidatePublicHost(host string) error {
+	if host == "" {
+		return fmt.Errorf("the url has no host")
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%s isn't a public host", host)
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	if ip != nil && !IsPublicIP(ip) {
+		return fmt.Errorf("%s isn't a public address", ip)
+	}
+	return nil
+}
+
+// dialPublicOnly is the control of a dialer, it's called with the resolved
+// address of each connection before it's made. Checking there instead of the
+// url covers names resolving to internal addresses and rebinding them
+// between the check and the request.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !IsPublicIP(ip) {
+		return fmt.Errorf("%s isn't a public address", host)
+	}
+	return nil
+}
+
+// NewPublicHTTPClient returns a client for urls users control, it only
+// connects to public addresses, so it can't be used to reach or probe the
+// services next to image-builder. The proxy of the environment isn't used, as
+// it would be dialed instead of the host, and redirects aren't followed.
+func NewPublicHTTPClient(timeouts HTTPTimeouts, tlsConfig *tls.Config) *http.Client {
+	client := NewHTTPClient(timeouts, tlsConfig)
+	transport := client.Transport.(*http.Transport)
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   timeouts.Connect,
+		KeepAlive: 30 * time.Second,
+		Control:   dialPublicOnly,
+	}).DialContext
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return client
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	for _, ip := range []string{"1.1.1.1", "52.94.236.248", "2606:4700:4700::1111"} {
+		require.True(t, IsPublicIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"100.64.0.1", "0.0.0.0", "255.255.255.255", "224.0.0.1", "198.18.0.1",
+		"::1", "::", "fe80::1", "fd00::1", "::ffff:10.0.0.1", "64:ff9b::a00:1",
+	} {
+		require.False(t, IsPublicIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestValidatePublicHost(t *testing.T) {
+	require.NoError(t, ValidatePublicHost("ci.example.com"))
+	require.NoError(t, ValidatePublicHost("1.1.1.1"))
+	require.Error(t, ValidatePublicHost(""))
+	require.Error(t, ValidatePublicHost("localhost"))
+	require.Error(t, ValidatePublicHost("api.localhost."))
+	require.Error(t, ValidatePublicHost("169.254.169.254"))
+	require.Error(t, ValidatePublicHost("[::1]"))
+}
+
+func TestNewPublicHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// the test server listens on the loopback address
+	_, err := NewPublicHTTPClient(HTTPTimeouts{Connect: time.Second}, nil).Get(srv.URL)
+	require.ErrorContains(t, err, "127.0.0.1 isn't a public address")
+}
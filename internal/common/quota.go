@@ -41,11 +41,12 @@ type Quota struct {
 	SlidingWindow time.Duration `json:"slidingWindow"`
 }
 
-// Returns true if the number of requests made by OrgID during a sliding window is below a threshold.
+// Returns true if the number of requests made by OrgID during a sliding window, along with the number of new
+// builds, doesn't exceed a threshold.
 // The duration of the sliding window and the value of the threshold must be set in a file pointed by the QUOTA_FILE
 // environment variable.
 // If the variable is unset (or an empty string), the check is disabled and always returns true.
-func CheckQuota(orgID string, dB db.DB, quotaFile string) (bool, error) {
+func CheckQuota(orgID string, dB db.DB, quotaFile string, builds int) (bool, error) {
 	if quotaFile == "" {
 		return true, nil
 	}
@@ -82,5 +83,5 @@ func CheckQuota(orgID string, dB db.DB, quotaFile string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	return count < authorizedRequests, nil
+	return count+builds <= authorizedRequests, nil
 }
@@ -0,0 +1,93 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// Headers request ids are taken from, in order. The ids of requests are
+// returned in, and forwarded to other services as, the first two.
+var requestIdHeaders = []string{
+	"X-Request-Id",
+	"X-Rh-Insights-Request-Id",
+	"X-Rh-Edge-Request-Id",
+}
+
+// Ids longer than this are cut, they end up in every log entry.
+const maxRequestIdLength = 128
+
+type requestIdKey struct{}
+
+func WithRequestId(ctx context.Context, rid string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, rid)
+}
+
+// RequestId returns the id of the request ctx belongs to, or an empty string.
+func RequestId(ctx context.Context) string {
+	rid, _ := ctx.Value(requestIdKey{}).(string)
+	return rid
+}
+
+// SetRequestIdHeaders forwards the id of the request ctx belongs to in the
+// headers of a request to another service.
+func SetRequestIdHeaders(ctx context.Context, header http.Header) {
+	rid := RequestId(ctx)
+	if rid == "" {
+		return
+	}
+	for _, h := range requestIdHeaders[:2] {
+		header.Set(h, rid)
+	}
+}
+
+// RequestIdMiddleware honors the request id set by the client, or the
+// gateway, and generates one otherwise. The id is stored in the context of the
+// request, which the logger of the echo context logs with, and returned in the
+// response.
+func RequestIdMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var rid string
+		for _, h := range requestIdHeaders {
+			rid = strings.TrimSpace(strings.ReplaceAll(c.Request().Header.Get(h), "\n", ""))
+			if rid != "" {
+				break
+			}
+		}
+		if len(rid) > maxRequestIdLength {
+			rid = rid[:maxRequestIdLength]
+		}
+		if rid == "" {
+			rid = uuid.NewString()
+		}
+
+		ctx := WithRequestId(c.Request().Context(), rid)
+		c.SetRequest(c.Request().WithContext(ctx))
+		c.SetLogger(Logger().WithContext(ctx))
+		for _, h := range requestIdHeaders[:2] {
+			c.Response().Header().Set(h, rid)
+		}
+		return next(c)
+	}
+}
+
+// ContextHook adds the request id of the context of log entries to them.
+type ContextHook struct{}
+
+func (ContextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (ContextHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if rid := RequestId(entry.Context); rid != "" {
+		entry.Data["request_id"] = rid
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIdMiddleware(t *testing.T) {
+	e := echo.New()
+	var rid string
+	handler := RequestIdMiddleware(func(c echo.Context) error {
+		rid = RequestId(c.Request().Context())
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, tc := range []struct {
+		header string
+		value  string
+		want   string
+	}{
+		{"X-Request-Id", "abc", "abc"},
+		{"X-Rh-Insights-Request-Id", "def", "def"},
+		{"X-Rh-Edge-Request-Id", "ghi\n", "ghi"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(tc.header, tc.value)
+		rec := httptest.NewRecorder()
+		require.NoError(t, handler(e.NewContext(req, rec)))
+		require.Equal(t, tc.want, rid)
+		require.Equal(t, tc.want, rec.Header().Get("X-Request-Id"))
+		require.Equal(t, tc.want, rec.Header().Get("X-Rh-Insights-Request-Id"))
+	}
+
+	// ids are generated for requests without one
+	rec := httptest.NewRecorder()
+	require.NoError(t, handler(e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)))
+	require.Len(t, rid, 36)
+	require.Equal(t, rid, rec.Header().Get("X-Request-Id"))
+}
+
+func TestSetRequestIdHeaders(t *testing.T) {
+	header := http.Header{}
+	SetRequestIdHeaders(WithRequestId(httptest.NewRequest(http.MethodGet, "/", nil).Context(), "abc"), header)
+	require.Equal(t, "abc", header.Get("X-Request-Id"))
+	require.Equal(t, "abc", header.Get("X-Rh-Insights-Request-Id"))
+}
+
+func TestContextHook(t *testing.T) {
+	var buf bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&buf)
+	log.SetFormatter(&logrus.TextFormatter{DisableColors: true})
+	log.AddHook(ContextHook{})
+
+	ctx := WithRequestId(httptest.NewRequest(http.MethodGet, "/", nil).Context(), "abc")
+	(&EchoLogrusLogger{Logger: log}).WithContext(ctx).Infof("logged")
+	require.Contains(t, buf.String(), "request_id=abc")
+
+	buf.Reset()
+	log.Info("logged")
+	require.NotContains(t, buf.String(), "request_id")
+}
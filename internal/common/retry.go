@@ -0,0 +1,118 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/osbuild/image-builder/internal/prometheus"
+)
+
+// RetryPolicy retries idempotent requests to other services which failed with
+// a connection error or a 5xx, waiting a random time between zero and the
+// exponential backoff before each retry so clients don't retry in lockstep.
+type RetryPolicy struct {
+	// Attempts including the first one
+	Attempts int
+	// Backoff before the first retry, doubled for each following one
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:  3,
+	BaseDelay: 200 * time.Millisecond,
+	MaxDelay:  5 * time.Second,
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks an error as not worth retrying, Retry returns the error
+// itself.
+func Permanent(err error) error {
+	return &permanentError{err}
+}
+
+// Idempotent returns whether requests with method can be sent more than once.
+func Idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// Retry calls send until it doesn't fail, up to p.Attempts times. client names
+// the service in the metrics. The failed responses whose request is retried
+// are closed, the last one is returned as is. Retry gives up early if the
+// deadline of ctx would pass before the next attempt.
+func (p RetryPolicy) Retry(ctx context.Context, client string, send func() (*http.Response, error)) (*http.Response, error) {
+	delay := p.BaseDelay
+	for attempt := 1; ; attempt++ {
+		resp, err := send()
+
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return resp, perm.err
+		}
+		reason := retryReason(resp, err)
+		if reason == "" {
+			return resp, err
+		}
+		if attempt >= p.Attempts {
+			if p.Attempts > 1 {
+				prometheus.OutboundRetriesExhausted.WithLabelValues(client).Inc()
+			}
+			return resp, err
+		}
+
+		// #nosec G404 -- the jitter doesn't need to be unpredictable
+		wait := time.Duration(rand.Int63n(int64(delay) + 1))
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return resp, err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, err
+		case <-timer.C:
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		prometheus.OutboundRetries.WithLabelValues(client, reason).Inc()
+		delay *= 2
+		if delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+}
+
+func retryReason(resp *http.Response, err error) string {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return ""
+		}
+		return "error"
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return "5xx"
+	}
+	return ""
+}
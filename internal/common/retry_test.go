@@ -0,0 +1,79 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	respond := func(status int) *http.Response {
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(""))}
+	}
+
+	calls := 0
+	resp, err := policy.Retry(context.Background(), "test", func() (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("connection refused")
+		}
+		if calls == 2 {
+			return respond(http.StatusBadGateway), nil
+		}
+		return respond(http.StatusOK), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 3, calls)
+
+	// the last failure is returned
+	calls = 0
+	resp, err = policy.Retry(context.Background(), "test", func() (*http.Response, error) {
+		calls++
+		return respond(http.StatusServiceUnavailable), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.Equal(t, 3, calls)
+
+	// client errors and permanent errors aren't retried
+	calls = 0
+	resp, err = policy.Retry(context.Background(), "test", func() (*http.Response, error) {
+		calls++
+		return respond(http.StatusNotFound), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, 1, calls)
+
+	calls = 0
+	_, err = policy.Retry(context.Background(), "test", func() (*http.Response, error) {
+		calls++
+		return nil, Permanent(errors.New("unavailable"))
+	})
+	require.EqualError(t, err, "unavailable")
+	require.Equal(t, 1, calls)
+}
+
+func TestRetryDeadline(t *testing.T) {
+	policy := RetryPolicy{Attempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	calls := 0
+	start := time.Now()
+	_, err := policy.Retry(ctx, "test", func() (*http.Response, error) {
+		calls++
+		return nil, errors.New("connection refused")
+	})
+	require.EqualError(t, err, "connection refused")
+	require.Less(t, time.Since(start), time.Second)
+	require.LessOrEqual(t, calls, 2)
+}
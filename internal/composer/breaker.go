@@ -0,0 +1,107 @@
+package composer
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/osbuild/image-builder/internal/logger"
+)
+
+const (
+	// consecutive failures after which requests to an endpoint fail fast
+	DefaultBreakerThreshold = 5
+	// how long requests fail fast before one is let through to probe composer
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+// CircuitOpenError is returned instead of sending a request to an endpoint of
+// composer which failed repeatedly.
+type CircuitOpenError struct {
+	Endpoint   string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("composer endpoint %s is unavailable, retry after %v", e.Endpoint, e.RetryAfter)
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker stops sending requests to an endpoint after threshold
+// consecutive failures, so callers don't each wait for composer to time out.
+// Once the cooldown passed a single request is let through, the breaker closes
+// again if it succeeds.
+type circuitBreaker struct {
+	endpoint  string
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func newCircuitBreaker(endpoint string, threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		endpoint:  endpoint,
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// allow returns an error if the request shouldn't be sent.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		remaining := b.cooldown - b.now().Sub(b.openedAt)
+		if remaining > 0 {
+			return &CircuitOpenError{Endpoint: b.endpoint, RetryAfter: remaining}
+		}
+		b.state = breakerHalfOpen
+		return nil
+	case breakerHalfOpen:
+		// the probe is in flight
+		return &CircuitOpenError{Endpoint: b.endpoint, RetryAfter: time.Second}
+	}
+	return nil
+}
+
+// record the outcome of a request which was allowed.
+func (b *circuitBreaker) record(resp *http.Response, err error) {
+	failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		if b.state != breakerClosed {
+			logger.Module(logger.ModuleComposer).Infof("Composer endpoint %s recovered", b.endpoint)
+		}
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		if b.state == breakerClosed {
+			logger.Module(logger.ModuleComposer).Warnf("Composer endpoint %s failed %d times in a row, failing requests to it for %v", b.endpoint, b.failures, b.cooldown)
+		}
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
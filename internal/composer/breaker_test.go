@@ -0,0 +1,99 @@
+package composer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var status, requests atomic.Int64
+	status.Store(http.StatusInternalServerError)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer srv.Close()
+
+	cc, err := NewClient(ComposerClientConfig{
+		ComposerURL:      srv.URL,
+		TokenURL:         srv.URL,
+		ClientId:         "id",
+		ClientSecret:     "secret",
+		BreakerThreshold: 3,
+		Retry:            common.RetryPolicy{Attempts: 1},
+	})
+	require.NoError(t, err)
+	now := time.Now()
+	cc.breaker("compose_status").now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		resp, err := cc.ComposeStatus(context.Background(), uuid.New())
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	// open, composer isn't called anymore
+	_, err = cc.ComposeStatus(context.Background(), uuid.New())
+	var open *CircuitOpenError
+	require.True(t, errors.As(err, &open))
+	require.Equal(t, "compose_status", open.Endpoint)
+	require.Equal(t, DefaultBreakerCooldown, open.RetryAfter)
+	require.Equal(t, int64(3), requests.Load())
+
+	// other endpoints are unaffected
+	status.Store(http.StatusOK)
+	resp, err := cc.OpenAPI(context.Background())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, int64(4), requests.Load())
+
+	// a failing probe opens the breaker again
+	now = now.Add(DefaultBreakerCooldown)
+	status.Store(http.StatusBadGateway)
+	resp, err = cc.ComposeStatus(context.Background(), uuid.New())
+	require.NoError(t, err)
+	resp.Body.Close()
+	_, err = cc.ComposeStatus(context.Background(), uuid.New())
+	require.True(t, errors.As(err, &open))
+	require.Equal(t, int64(5), requests.Load())
+
+	// a successful one closes it
+	now = now.Add(DefaultBreakerCooldown)
+	status.Store(http.StatusNotFound)
+	for i := 0; i < 2; i++ {
+		resp, err = cc.ComposeStatus(context.Background(), uuid.New())
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+		resp.Body.Close()
+	}
+	require.Equal(t, int64(7), requests.Load())
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	b := newCircuitBreaker("compose", 1, time.Minute)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	require.NoError(t, b.allow())
+	b.record(nil, errors.New("connection refused"))
+	require.Error(t, b.allow())
+
+	// only a single probe is let through
+	now = now.Add(time.Minute)
+	require.NoError(t, b.allow())
+	require.Error(t, b.allow())
+	b.record(&http.Response{StatusCode: http.StatusOK}, nil)
+	require.NoError(t, b.allow())
+	require.NoError(t, b.allow())
+}
@@ -0,0 +1,149 @@
+package composer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/osbuild/image-builder/internal/logger"
+)
+
+// Capabilities are the image types, upload targets and customizations a
+// deployment of composer supports, as listed in the enums and properties of
+// the schemas of its openapi document.
+type Capabilities struct {
+	ImageTypes     map[string]bool
+	UploadTypes    map[string]bool
+	Customizations map[string]bool
+}
+
+// ParseCapabilities reads the capabilities from composer's openapi document,
+// json or yaml.
+func ParseCapabilities(doc []byte) (*Capabilities, error) {
+	spec, err := openapi3.NewLoader().LoadFromData(doc)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load composer's openapi document: %v", err)
+	}
+	schema := func(name string) (*openapi3.Schema, error) {
+		ref, ok := spec.Components.Schemas[name]
+		if !ok || ref.Value == nil {
+			return nil, fmt.Errorf("composer's openapi document has no %s schema", name)
+		}
+		return ref.Value, nil
+	}
+	enum := func(name string) (map[string]bool, error) {
+		s, err := schema(name)
+		if err != nil {
+			return nil, err
+		}
+		values := map[string]bool{}
+		for _, v := range s.Enum {
+			if str, ok := v.(string); ok {
+				values[str] = true
+			}
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("the %s schema of composer's openapi document lists no values", name)
+		}
+		return values, nil
+	}
+
+	var caps Capabilities
+	caps.ImageTypes, err = enum("ImageTypes")
+	if err != nil {
+		return nil, err
+	}
+	caps.UploadTypes, err = enum("UploadTypes")
+	if err != nil {
+		return nil, err
+	}
+	customizations, err := schema("Customizations")
+	if err != nil {
+		return nil, err
+	}
+	caps.Customizations = map[string]bool{}
+	for name := range customizations.Properties {
+		caps.Customizations[name] = true
+	}
+	return &caps, nil
+}
+
+// UnsupportedCustomizations returns the customizations set in c which
+// composer doesn't know about, sorted.
+func (caps *Capabilities) UnsupportedCustomizations(c *Customizations) ([]string, error) {
+	if c == nil {
+		return nil, nil
+	}
+	buf, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var set map[string]json.RawMessage
+	err = json.Unmarshal(buf, &set)
+	if err != nil {
+		return nil, err
+	}
+	var unsupported []string
+	for name := range set {
+		if !caps.Customizations[name] {
+			unsupported = append(unsupported, name)
+		}
+	}
+	sort.Strings(unsupported)
+	return unsupported, nil
+}
+
+// Capabilities returns what composer was last detected to support, nil if it
+// wasn't detected yet.
+func (cc *ComposerClient) Capabilities() *Capabilities {
+	return cc.capabilities.Load()
+}
+
+// RefreshCapabilities detects the capabilities of composer from its openapi
+// document. The previous capabilities are kept if that fails.
+func (cc *ComposerClient) RefreshCapabilities(ctx context.Context) error {
+	resp, err := cc.OpenAPI(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("composer responded with %d to the request for its openapi document", resp.StatusCode)
+	}
+	doc, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	caps, err := ParseCapabilities(doc)
+	if err != nil {
+		return err
+	}
+	cc.capabilities.Store(caps)
+	return nil
+}
+
+// RunCapabilityRefresh detects the capabilities of composer right away and
+// then every interval, until ctx is done.
+func (cc *ComposerClient) RunCapabilityRefresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := cc.RefreshCapabilities(ctx)
+		if err != nil {
+			logger.Module(logger.ModuleComposer).Warnf("Unable to detect the capabilities of composer at %s: %v", cc.composerURL, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
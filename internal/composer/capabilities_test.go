@@ -0,0 +1,36 @@
+package composer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+)
+
+func TestParseCapabilities(t *testing.T) {
+	doc, err := os.ReadFile("openapi.v2.yml")
+	require.NoError(t, err)
+	caps, err := ParseCapabilities(doc)
+	require.NoError(t, err)
+	require.True(t, caps.ImageTypes[string(ImageTypesGuestImage)])
+	require.False(t, caps.ImageTypes["ami"])
+	require.True(t, caps.UploadTypes[string(UploadTypesAwsS3)])
+	require.True(t, caps.Customizations["packages"])
+
+	unsupported, err := caps.UnsupportedCustomizations(&Customizations{Packages: &[]string{"vim"}})
+	require.NoError(t, err)
+	require.Empty(t, unsupported)
+
+	delete(caps.Customizations, "packages")
+	unsupported, err = caps.UnsupportedCustomizations(&Customizations{
+		Packages: &[]string{"vim"},
+		Users:    &[]User{{Name: "user", Groups: common.ToPtr([]string{"wheel"})}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"packages"}, unsupported)
+
+	_, err = ParseCapabilities([]byte(`{"openapi": "3.0.1", "info": {"title": "composer", "version": "2"}, "paths": {}}`))
+	require.Error(t, err)
+}
@@ -4,33 +4,52 @@ package composer
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/logger"
 )
 
 type ComposerClient struct {
 	composerURL string
+	metricsURL  string
 
 	tokenURL     string
-	offlineToken string
+	offlineToken func() string
 	accessToken  string
 	clientId     string
-	clientSecret string
+	clientSecret func() string
 	tokenMu      sync.RWMutex
+	// zero if the token endpoint didn't say
+	tokenExpiry    time.Time
+	tokenCacheFile string
+
+	client      *http.Client
+	tokenClient *http.Client
+
+	retry common.RetryPolicy
 
-	client *http.Client
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	breakersMu       sync.Mutex
+	breakers         map[string]*circuitBreaker
+
+	// nil until detected
+	capabilities atomic.Pointer[Capabilities]
 }
 
 type ComposerClientConfig struct {
@@ -40,10 +59,34 @@ type ComposerClientConfig struct {
 	ClientId     string
 	OfflineToken string
 	ClientSecret string
-}
-
-type tokenResponse struct {
-	AccessToken string `json:"access_token"`
+	// Optional, asked for the offline token or client secret whenever a
+	// token is fetched instead, so rotated ones are picked up.
+	OfflineTokenFunc func() string
+	ClientSecretFunc func() string
+
+	// Optional client certificate, reloaded when it changes on disk.
+	ClientCert string
+	ClientKey  string
+
+	// Consecutive failures after which requests to an endpoint fail fast, and
+	// for how long. Defaults to DefaultBreakerThreshold and
+	// DefaultBreakerCooldown.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// Retries of idempotent requests, defaults to common.DefaultRetryPolicy.
+	Retry common.RetryPolicy
+
+	Timeouts      common.HTTPTimeouts
+	TokenTimeouts common.HTTPTimeouts
+
+	// Optional file the access token is kept in, so restarted instances
+	// don't all ask the token endpoint for one at once.
+	TokenCacheFile string
+
+	// Optional url of composer's prometheus metrics, its queue and workers
+	// are read from, e.g. https://composer.example.com/metrics.
+	MetricsURL string
 }
 
 var contentHeaders = map[string]string{"Content-Type": "application/json"}
@@ -59,47 +102,115 @@ func NewClient(conf ComposerClientConfig) (*ComposerClient, error) {
 		return nil, fmt.Errorf("Client needs offline token, or client secret")
 	}
 
-	client, err := createClient(conf.ComposerURL, conf.CA)
+	client, err := createClient(conf.ComposerURL, conf.CA, conf.ClientCert, conf.ClientKey, conf.Timeouts)
 	if err != nil {
-		return nil, fmt.Errorf("Error creating compose http client")
+		return nil, fmt.Errorf("Error creating compose http client: %v", err)
 	}
 
 	cc := ComposerClient{
 		composerURL:  fmt.Sprintf("%s/api/image-builder-composer/v2", conf.ComposerURL),
+		metricsURL:   conf.MetricsURL,
 		tokenURL:     conf.TokenURL,
 		clientId:     conf.ClientId,
-		offlineToken: conf.OfflineToken,
-		clientSecret: conf.ClientSecret,
+		offlineToken: conf.OfflineTokenFunc,
+		clientSecret: conf.ClientSecretFunc,
 		client:       client,
+		tokenClient:  common.NewHTTPClient(conf.TokenTimeouts, nil),
+
+		tokenCacheFile: conf.TokenCacheFile,
+
+		retry: conf.Retry,
+
+		breakerThreshold: conf.BreakerThreshold,
+		breakerCooldown:  conf.BreakerCooldown,
+		breakers:         map[string]*circuitBreaker{},
+	}
+	if cc.offlineToken == nil {
+		cc.offlineToken = func() string { return conf.OfflineToken }
+	}
+	if cc.clientSecret == nil {
+		cc.clientSecret = func() string { return conf.ClientSecret }
+	}
+	if cc.retry.Attempts <= 0 {
+		cc.retry = common.DefaultRetryPolicy
 	}
+	if cc.breakerThreshold <= 0 {
+		cc.breakerThreshold = DefaultBreakerThreshold
+	}
+	if cc.breakerCooldown <= 0 {
+		cc.breakerCooldown = DefaultBreakerCooldown
+	}
+	cc.loadCachedToken()
 
 	return &cc, nil
 }
 
-func createClient(composerURL string, ca string) (*http.Client, error) {
-	if !strings.HasPrefix(composerURL, "https") || ca == "" {
-		return &http.Client{}, nil
+func createClient(composerURL, ca, clientCert, clientKey string, timeouts common.HTTPTimeouts) (*http.Client, error) {
+	if !strings.HasPrefix(composerURL, "https") || (ca == "" && clientCert == "") {
+		return common.NewHTTPClient(timeouts, nil), nil
 	}
 
-	var tlsConfig *tls.Config
-	caCert, err := os.ReadFile(filepath.Clean(ca))
-	if err != nil {
-		return nil, err
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
 	}
 
-	caCertPool := x509.NewCertPool()
-	caCertPool.AppendCertsFromPEM(caCert)
+	if ca != "" {
+		caCert, err := os.ReadFile(filepath.Clean(ca))
+		if err != nil {
+			return nil, err
+		}
+
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(caCert)
+		tlsConfig.RootCAs = caCertPool
+	}
 
-	tlsConfig = &tls.Config{
-		MinVersion: tls.VersionTLS12,
-		RootCAs:    caCertPool,
+	if clientCert != "" {
+		cr, err := common.NewCertReloader(clientCert, clientKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = cr.GetClientCertificate
+	}
+
+	return common.NewHTTPClient(timeouts, tlsConfig), nil
+}
+
+func (cc *ComposerClient) breaker(endpoint string) *circuitBreaker {
+	cc.breakersMu.Lock()
+	defer cc.breakersMu.Unlock()
+	b, ok := cc.breakers[endpoint]
+	if !ok {
+		b = newCircuitBreaker(endpoint, cc.breakerThreshold, cc.breakerCooldown)
+		cc.breakers[endpoint] = b
 	}
+	return b
+}
 
-	transport := &http.Transport{TLSClientConfig: tlsConfig}
-	return &http.Client{Transport: transport}, nil
+// request sends a request to composer. The id of the request ctx belongs to
+// is forwarded, ctx doesn't cancel the request though: composer would carry on
+// with a compose whose client went away before the id was stored.
+//
+// Requests without a body which can be repeated are retried, every attempt
+// counts towards the circuit breaker of the endpoint. Requests to an endpoint
+// which keeps failing return a *CircuitOpenError without being sent.
+func (cc *ComposerClient) request(ctx context.Context, endpoint, method, url string, headers map[string]string, body io.Reader) (*http.Response, error) {
+	policy := cc.retry
+	if body != nil || !common.Idempotent(method) {
+		policy.Attempts = 1
+	}
+	b := cc.breaker(endpoint)
+	return policy.Retry(ctx, "composer", func() (*http.Response, error) {
+		if err := b.allow(); err != nil {
+			return nil, common.Permanent(err)
+		}
+		resp, err := cc.send(ctx, method, url, headers, body)
+		b.record(resp, err)
+		return resp, err
+	})
 }
 
-func (cc *ComposerClient) request(method, url string, headers map[string]string, body io.Reader) (*http.Response, error) {
+func (cc *ComposerClient) send(ctx context.Context, method, url string, headers map[string]string, body io.Reader) (*http.Response, error) {
 	req, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return nil, err
@@ -108,97 +219,65 @@ func (cc *ComposerClient) request(method, url string, headers map[string]string,
 	for k, v := range headers {
 		req.Header.Add(k, v)
 	}
+	common.SetRequestIdHeaders(ctx, req.Header)
 
-	token := func() string {
-		cc.tokenMu.RLock()
-		defer cc.tokenMu.RUnlock()
-		return cc.accessToken
-	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token()))
+	token := cc.token()
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
+	log := logger.Module(logger.ModuleComposer).WithContext(ctx)
 	resp, err := cc.client.Do(req)
 	if err != nil {
+		log.Debugf("%s %s failed: %v", method, url, err)
 		return nil, err
 	}
+	log.Debugf("%s %s: %d", method, url, resp.StatusCode)
 
 	if resp.StatusCode == http.StatusUnauthorized {
-		err = cc.refreshToken()
+		log.Debug("Refreshing the composer access token")
+		err = cc.refreshTokenIfStale(token)
 		if err != nil {
 			return nil, err
 		}
 
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token()))
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", cc.token()))
 		resp, err = cc.client.Do(req)
-	}
-
-	return resp, err
-}
-
-func (cc *ComposerClient) refreshToken() error {
-	cc.tokenMu.Lock()
-	defer cc.tokenMu.Unlock()
-
-	data := url.Values{}
-	if cc.offlineToken != "" {
-		data.Set("grant_type", "refresh_token")
-		data.Set("client_id", cc.clientId)
-		data.Set("refresh_token", cc.offlineToken)
-	}
-	if cc.clientSecret != "" {
-		data.Set("grant_type", "client_credentials")
-		data.Set("client_id", cc.clientId)
-		data.Set("client_secret", cc.clientSecret)
-	}
-
-	resp, err := http.PostForm(cc.tokenURL, data)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			logrus.Errorf("Error closing body after refreshing composer client token: %v", err)
+		if err == nil {
+			log.Debugf("%s %s: %d", method, url, resp.StatusCode)
 		}
-	}()
-
-	var tr tokenResponse
-	err = json.NewDecoder(resp.Body).Decode(&tr)
-	if err != nil {
-		return err
 	}
 
-	cc.accessToken = tr.AccessToken
-	return nil
+	return resp, err
 }
 
-func (cc *ComposerClient) ComposeStatus(id uuid.UUID) (*http.Response, error) {
-	return cc.request("GET", fmt.Sprintf("%s/composes/%s", cc.composerURL, id), nil, nil)
+func (cc *ComposerClient) ComposeStatus(ctx context.Context, id uuid.UUID) (*http.Response, error) {
+	return cc.request(ctx, "compose_status", "GET", fmt.Sprintf("%s/composes/%s", cc.composerURL, id), nil, nil)
 }
 
-func (cc *ComposerClient) ComposeMetadata(id uuid.UUID) (*http.Response, error) {
-	return cc.request("GET", fmt.Sprintf("%s/composes/%s/metadata", cc.composerURL, id), nil, nil)
+func (cc *ComposerClient) ComposeMetadata(ctx context.Context, id uuid.UUID) (*http.Response, error) {
+	return cc.request(ctx, "compose_metadata", "GET", fmt.Sprintf("%s/composes/%s/metadata", cc.composerURL, id), nil, nil)
 }
 
-func (cc *ComposerClient) Compose(compose ComposeRequest) (*http.Response, error) {
+func (cc *ComposerClient) Compose(ctx context.Context, compose ComposeRequest) (*http.Response, error) {
 	buf, err := json.Marshal(compose)
 	if err != nil {
 		return nil, err
 	}
 
-	return cc.request("POST", fmt.Sprintf("%s/compose", cc.composerURL), contentHeaders, bytes.NewReader(buf))
+	return cc.request(ctx, "compose", "POST", fmt.Sprintf("%s/compose", cc.composerURL), contentHeaders, bytes.NewReader(buf))
 }
 
-func (cc *ComposerClient) OpenAPI() (*http.Response, error) {
-	return cc.request("GET", fmt.Sprintf("%s/openapi", cc.composerURL), nil, nil)
+func (cc *ComposerClient) OpenAPI(ctx context.Context) (*http.Response, error) {
+	return cc.request(ctx, "openapi", "GET", fmt.Sprintf("%s/openapi", cc.composerURL), nil, nil)
 }
 
-func (cc *ComposerClient) CloneCompose(id uuid.UUID, clone CloneComposeBody) (*http.Response, error) {
+func (cc *ComposerClient) CloneCompose(ctx context.Context, id uuid.UUID, clone CloneComposeBody) (*http.Response, error) {
 	buf, err := json.Marshal(clone)
 	if err != nil {
 		return nil, err
 	}
-	return cc.request("POST", fmt.Sprintf("%s/composes/%s/clone", cc.composerURL, id), contentHeaders, bytes.NewReader(buf))
+	return cc.request(ctx, "clone_compose", "POST", fmt.Sprintf("%s/composes/%s/clone", cc.composerURL, id), contentHeaders, bytes.NewReader(buf))
 }
 
-func (cc *ComposerClient) CloneStatus(id uuid.UUID) (*http.Response, error) {
-	return cc.request("GET", fmt.Sprintf("%s/clones/%s", cc.composerURL, id), nil, nil)
+func (cc *ComposerClient) CloneStatus(ctx context.Context, id uuid.UUID) (*http.Response, error) {
+	return cc.request(ctx, "clone_status", "GET", fmt.Sprintf("%s/clones/%s", cc.composerURL, id), nil, nil)
 }
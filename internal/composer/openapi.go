@@ -0,0 +1,11 @@
+package composer
+
+import (
+	_ "embed"
+)
+
+// OpenAPIDocument is the openapi document of composer the client is
+// generated from, e.g. for fakes of composer.
+//
+//go:embed openapi.v2.yml
+var OpenAPIDocument []byte
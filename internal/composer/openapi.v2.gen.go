@@ -82,6 +82,7 @@ const (
 	UploadTypesContainer        UploadTypes = "container"
 	UploadTypesGcp              UploadTypes = "gcp"
 	UploadTypesOciObjectstorage UploadTypes = "oci.objectstorage"
+	UploadTypesPulpOstree       UploadTypes = "pulp.ostree"
 )
 
 // AWSEC2CloneCompose defines model for AWSEC2CloneCompose.
@@ -320,7 +321,12 @@ type Customizations struct {
 
 	// InstallationDevice Name of the installation device, currently only useful for the edge-simplified-installer type
 	InstallationDevice *string `json:"installation_device,omitempty"`
-	Kernel             *Kernel `json:"kernel,omitempty"`
+
+	// Installer Customizations for the installer of the image-installer and
+	// edge-installer image types. They are written to an embedded kickstart
+	// file.
+	Installer *Installer `json:"installer,omitempty"`
+	Kernel    *Kernel    `json:"kernel,omitempty"`
 
 	// Locale Locale configuration
 	Locale   *Locale   `json:"locale,omitempty"`
@@ -585,6 +591,18 @@ type ImageStatusValue string
 // ImageTypes defines model for ImageTypes.
 type ImageTypes string
 
+// Installer Customizations for the installer of the image-installer and
+// edge-installer image types. They are written to an embedded kickstart
+// file.
+type Installer struct {
+	// SudoNopasswd Users and groups (prefixed with %) which are allowed to use sudo
+	// without a password.
+	SudoNopasswd *[]string `json:"sudo-nopasswd,omitempty"`
+
+	// Unattended Install the embedded payload without user interaction.
+	Unattended *bool `json:"unattended,omitempty"`
+}
+
 // Kernel defines model for Kernel.
 type Kernel struct {
 	// Append Appends arguments to the bootloader kernel command line
@@ -695,6 +713,21 @@ type PackageMetadata struct {
 	Version   string  `json:"version"`
 }
 
+// PulpOSTreeUploadOptions defines model for PulpOSTreeUploadOptions.
+type PulpOSTreeUploadOptions struct {
+	// Basepath Basepath for distributing the repository
+	Basepath string `json:"basepath"`
+
+	// Repository Repository to import the ostree commit to
+	Repository    *string `json:"repository,omitempty"`
+	ServerAddress *string `json:"server_address,omitempty"`
+}
+
+// PulpOSTreeUploadStatus defines model for PulpOSTreeUploadStatus.
+type PulpOSTreeUploadStatus struct {
+	RepoUrl string `json:"repo_url"`
+}
+
 // Repository Repository configuration.
 // At least one of the 'baseurl', 'mirrorlist', 'metalink' properties must
 // be specified. If more of them are specified, the order of precedence is
@@ -1006,6 +1039,32 @@ func (t *CloneStatus_Options) MergeOCIUploadStatus(v OCIUploadStatus) error {
 	return err
 }
 
+// AsPulpOSTreeUploadStatus returns the union data inside the CloneStatus_Options as a PulpOSTreeUploadStatus
+func (t CloneStatus_Options) AsPulpOSTreeUploadStatus() (PulpOSTreeUploadStatus, error) {
+	var body PulpOSTreeUploadStatus
+	err := json.Unmarshal(t.union, &body)
+	return body, err
+}
+
+// FromPulpOSTreeUploadStatus overwrites any union data inside the CloneStatus_Options as the provided PulpOSTreeUploadStatus
+func (t *CloneStatus_Options) FromPulpOSTreeUploadStatus(v PulpOSTreeUploadStatus) error {
+	b, err := json.Marshal(v)
+	t.union = b
+	return err
+}
+
+// MergePulpOSTreeUploadStatus performs a merge with any union data inside the CloneStatus_Options, using the provided PulpOSTreeUploadStatus
+func (t *CloneStatus_Options) MergePulpOSTreeUploadStatus(v PulpOSTreeUploadStatus) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	merged, err := runtime.JsonMerge(b, t.union)
+	t.union = merged
+	return err
+}
+
 func (t CloneStatus_Options) MarshalJSON() ([]byte, error) {
 	b, err := t.union.MarshalJSON()
 	return b, err
@@ -1446,6 +1505,32 @@ func (t *UploadOptions) MergeOCIUploadOptions(v OCIUploadOptions) error {
 	return err
 }
 
+// AsPulpOSTreeUploadOptions returns the union data inside the UploadOptions as a PulpOSTreeUploadOptions
+func (t UploadOptions) AsPulpOSTreeUploadOptions() (PulpOSTreeUploadOptions, error) {
+	var body PulpOSTreeUploadOptions
+	err := json.Unmarshal(t.union, &body)
+	return body, err
+}
+
+// FromPulpOSTreeUploadOptions overwrites any union data inside the UploadOptions as the provided PulpOSTreeUploadOptions
+func (t *UploadOptions) FromPulpOSTreeUploadOptions(v PulpOSTreeUploadOptions) error {
+	b, err := json.Marshal(v)
+	t.union = b
+	return err
+}
+
+// MergePulpOSTreeUploadOptions performs a merge with any union data inside the UploadOptions, using the provided PulpOSTreeUploadOptions
+func (t *UploadOptions) MergePulpOSTreeUploadOptions(v PulpOSTreeUploadOptions) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	merged, err := runtime.JsonMerge(b, t.union)
+	t.union = merged
+	return err
+}
+
 func (t UploadOptions) MarshalJSON() ([]byte, error) {
 	b, err := t.union.MarshalJSON()
 	return b, err
@@ -1612,6 +1697,32 @@ func (t *UploadStatus_Options) MergeOCIUploadStatus(v OCIUploadStatus) error {
 	return err
 }
 
+// AsPulpOSTreeUploadStatus returns the union data inside the UploadStatus_Options as a PulpOSTreeUploadStatus
+func (t UploadStatus_Options) AsPulpOSTreeUploadStatus() (PulpOSTreeUploadStatus, error) {
+	var body PulpOSTreeUploadStatus
+	err := json.Unmarshal(t.union, &body)
+	return body, err
+}
+
+// FromPulpOSTreeUploadStatus overwrites any union data inside the UploadStatus_Options as the provided PulpOSTreeUploadStatus
+func (t *UploadStatus_Options) FromPulpOSTreeUploadStatus(v PulpOSTreeUploadStatus) error {
+	b, err := json.Marshal(v)
+	t.union = b
+	return err
+}
+
+// MergePulpOSTreeUploadStatus performs a merge with any union data inside the UploadStatus_Options, using the provided PulpOSTreeUploadStatus
+func (t *UploadStatus_Options) MergePulpOSTreeUploadStatus(v PulpOSTreeUploadStatus) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	merged, err := runtime.JsonMerge(b, t.union)
+	t.union = merged
+	return err
+}
+
 func (t UploadStatus_Options) MarshalJSON() ([]byte, error) {
 	b, err := t.union.MarshalJSON()
 	return b, err
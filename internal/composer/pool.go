@@ -0,0 +1,241 @@
+package composer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/osbuild/image-builder/internal/logger"
+)
+
+// DefaultBackend is the name of the composer all composes went to before
+// there were several, and still go to if no other backend is configured.
+const DefaultBackend = "default"
+
+// Backend is one of the composers composes can be sent to. New composes of
+// the listed distributions, or targeting the listed regions, prefer it. A
+// backend without either takes any compose.
+type Backend struct {
+	Name    string
+	Client  *ComposerClient
+	Distros []string
+	Regions []string
+}
+
+type member struct {
+	Backend
+	healthy bool
+}
+
+// Pool routes new composes to healthy backends, and requests about existing
+// composes to the backend which owns them.
+type Pool struct {
+	mu       sync.RWMutex
+	backends []*member
+	byName   map[string]*member
+}
+
+// NewPool of backends, the first one is the default backend.
+func NewPool(backends []Backend) (*Pool, error) {
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("composer pool needs at least one backend")
+	}
+	p := &Pool{byName: map[string]*member{}}
+	for i, b := range backends {
+		if b.Name == "" || b.Client == nil {
+			return nil, fmt.Errorf("composer backend %d needs a name and a client", i)
+		}
+		if _, ok := p.byName[b.Name]; ok {
+			return nil, fmt.Errorf("composer backend %s is listed more than once", b.Name)
+		}
+		// unknown until checked, assume the best
+		m := &member{Backend: b, healthy: true}
+		p.backends = append(p.backends, m)
+		p.byName[b.Name] = m
+	}
+	return p, nil
+}
+
+// Default returns the client of the default backend.
+func (p *Pool) Default() *ComposerClient {
+	return p.backends[0].Client
+}
+
+// Client returns the client of the backend a compose was sent to, the empty
+// name is the default backend.
+func (p *Pool) Client(name string) (*ComposerClient, error) {
+	if name == "" {
+		return p.Default(), nil
+	}
+	b, ok := p.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown composer backend %s", name)
+	}
+	return b.Client, nil
+}
+
+// Route picks the backend of a new compose: a healthy backend preferring the
+// distribution or region, then any healthy backend which doesn't prefer
+// others, then the default backend.
+func (p *Pool) Route(distro, region string) (string, *ComposerClient) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var general *member
+	for _, b := range p.backends {
+		if !b.healthy {
+			continue
+		}
+		if contains(b.Distros, distro) || (region != "" && contains(b.Regions, region)) {
+			return b.Name, b.Client
+		}
+		if general == nil && len(b.Distros) == 0 && len(b.Regions) == 0 {
+			general = b
+		}
+	}
+	if general != nil {
+		return general.Name, general.Client
+	}
+	return p.backends[0].Name, p.backends[0].Client
+}
+
+// RegionBackend returns the first backend listing the region, healthy or
+// not. Regions which need credentials only some composers have, like the
+// ones of GovCloud, can't fall back to other backends.
+func (p *Pool) RegionBackend(region string) (string, *ComposerClient, bool) {
+	for _, b := range p.backends {
+		if contains(b.Regions, region) {
+			return b.Name, b.Client, true
+		}
+	}
+	return "", nil, false
+}
+
+// Backends returns the backends of the pool, the default one first.
+func (p *Pool) Backends() []Backend {
+	var backends []Backend
+	for _, b := range p.backends {
+		backends = append(backends, b.Backend)
+	}
+	return backends
+}
+
+// Healthy returns the names of the backends which passed their last check.
+func (p *Pool) Healthy() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var names []string
+	for _, b := range p.backends {
+		if b.healthy {
+			names = append(names, b.Name)
+		}
+	}
+	return names
+}
+
+// RunHealthChecks checks whether every backend serves its openapi document
+// each interval, until ctx is done.
+func (p *Pool) RunHealthChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.CheckHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Pool) CheckHealth(ctx context.Context) {
+	for _, b := range p.backends {
+		err := checkBackend(ctx, b.Client)
+
+		p.mu.Lock()
+		if (err == nil) != b.healthy {
+			if err != nil {
+				logger.Module(logger.ModuleComposer).Warnf("Composer backend %s is unhealthy: %v", b.Name, err)
+			} else {
+				logger.Module(logger.ModuleComposer).Infof("Composer backend %s is healthy again", b.Name)
+			}
+		}
+		b.healthy = err == nil
+		p.mu.Unlock()
+	}
+}
+
+func checkBackend(ctx context.Context, cc *ComposerClient) error {
+	resp, err := cc.OpenAPI(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("composer responded with %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// BackendConfig is an additional composer backend, as configured.
+type BackendConfig struct {
+	Name       string
+	URL        string
+	Distros    []string
+	Regions    []string
+	MetricsURL string
+}
+
+// ParseBackends parses backends separated by semicolons, each a name=url pair
+// optionally followed by the distributions and regions it prefers and the url
+// of its metrics, e.g. "eu=https://composer-eu.example.com
+// distros=rhel-9,centos-9 regions=eu-west-1 metrics=https://composer-eu.example.com/metrics".
+func ParseBackends(backends string) ([]BackendConfig, error) {
+	var configs []BackendConfig
+	for _, entry := range strings.Split(backends, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		name, url, ok := strings.Cut(fields[0], "=")
+		if !ok || name == "" || url == "" {
+			return nil, fmt.Errorf("expected name=url, got %q", fields[0])
+		}
+		if name == DefaultBackend {
+			return nil, fmt.Errorf("the name %s is taken by the default composer", DefaultBackend)
+		}
+		conf := BackendConfig{Name: name, URL: url}
+		for _, f := range fields[1:] {
+			key, values, ok := strings.Cut(f, "=")
+			if !ok || values == "" {
+				return nil, fmt.Errorf("expected distros=..., regions=... or metrics=..., got %q", f)
+			}
+			switch key {
+			case "distros":
+				conf.Distros = strings.Split(values, ",")
+			case "regions":
+				conf.Regions = strings.Split(values, ",")
+			case "metrics":
+				conf.MetricsURL = values
+			default:
+				return nil, fmt.Errorf("unknown option %s of composer backend %s", key, name)
+			}
+		}
+		configs = append(configs, conf)
+	}
+	return configs, nil
+}
@@ -0,0 +1,96 @@
+package composer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+)
+
+func TestParseBackends(t *testing.T) {
+	backends, err := ParseBackends("")
+	require.NoError(t, err)
+	require.Empty(t, backends)
+
+	backends, err = ParseBackends("eu=https://eu.example.com distros=rhel-9,centos-9 regions=eu-west-1; spare=https://spare.example.com metrics=https://spare.example.com/metrics")
+	require.NoError(t, err)
+	require.Equal(t, []BackendConfig{
+		{Name: "eu", URL: "https://eu.example.com", Distros: []string{"rhel-9", "centos-9"}, Regions: []string{"eu-west-1"}},
+		{Name: "spare", URL: "https://spare.example.com", MetricsURL: "https://spare.example.com/metrics"},
+	}, backends)
+
+	_, err = ParseBackends("https://eu.example.com")
+	require.Error(t, err)
+	_, err = ParseBackends("default=https://eu.example.com")
+	require.Error(t, err)
+	_, err = ParseBackends("eu=https://eu.example.com arch=aarch64")
+	require.Error(t, err)
+}
+
+func TestPool(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	client := func(url string) *ComposerClient {
+		cc, err := NewClient(ComposerClientConfig{
+			ComposerURL:  url,
+			TokenURL:     url,
+			ClientId:     "id",
+			ClientSecret: "secret",
+			Retry:        common.RetryPolicy{Attempts: 1},
+		})
+		require.NoError(t, err)
+		return cc
+	}
+	def, eu, spare := client(healthy.URL), client(unhealthy.URL), client(healthy.URL)
+
+	_, err := NewPool(nil)
+	require.Error(t, err)
+	_, err = NewPool([]Backend{{Name: "a", Client: def}, {Name: "a", Client: eu}})
+	require.Error(t, err)
+
+	p, err := NewPool([]Backend{
+		{Name: DefaultBackend, Client: def},
+		{Name: "eu", Client: eu, Regions: []string{"eu-west-1"}},
+		{Name: "spare", Client: spare, Distros: []string{"rhel-10"}},
+	})
+	require.NoError(t, err)
+
+	name, cc := p.Route("rhel-9", "eu-west-1")
+	require.Equal(t, "eu", name)
+	require.Same(t, eu, cc)
+
+	// unhealthy backends don't get new composes
+	p.CheckHealth(context.Background())
+	require.Equal(t, []string{DefaultBackend, "spare"}, p.Healthy())
+	name, _ = p.Route("rhel-9", "eu-west-1")
+	require.Equal(t, DefaultBackend, name)
+	name, _ = p.Route("rhel-10", "")
+	require.Equal(t, "spare", name)
+
+	// unless they're the only ones which serve the region
+	name, cc, ok := p.RegionBackend("eu-west-1")
+	require.True(t, ok)
+	require.Equal(t, "eu", name)
+	require.Same(t, eu, cc)
+	_, _, ok = p.RegionBackend("us-gov-west-1")
+	require.False(t, ok)
+
+	// but are still asked about their composes
+	cc, err = p.Client("eu")
+	require.NoError(t, err)
+	require.Same(t, eu, cc)
+	cc, err = p.Client("")
+	require.NoError(t, err)
+	require.Same(t, def, cc)
+	_, err = p.Client("gone")
+	require.Error(t, err)
+}
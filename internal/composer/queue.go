@@ -0,0 +1,143 @@
+package composer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// ErrNoMetricsURL occurs when the queue of a composer is asked for, but the
+// url of its metrics isn't configured.
+var ErrNoMetricsURL = errors.New("the metrics url of composer isn't configured")
+
+// The gauges of composer the queue and the workers are read from. Jobs are of
+// types like osbuild:x86_64, jobs which depend on the architecture of the
+// worker have it appended.
+const (
+	pendingJobsMetric     = "image_builder_composer_pending_jobs"
+	runningJobsMetric     = "image_builder_composer_running_jobs"
+	workerHeartbeatMetric = "image_builder_composer_worker_heartbeat_timestamp_seconds"
+)
+
+// JobQueue is how many jobs of a type and architecture wait for a worker
+// and how many are worked on, summed over all tenants.
+type JobQueue struct {
+	Type    string
+	Arch    string
+	Pending int
+	Running int
+}
+
+// Worker is a worker which registered with composer.
+type Worker struct {
+	Id            string
+	Arch          string
+	LastHeartbeat time.Time
+}
+
+// QueueStatus is what composer reports about its jobs and workers.
+type QueueStatus struct {
+	Queues  []JobQueue
+	Workers []Worker
+}
+
+// ParseQueueStatus reads the queue and workers from composer's metrics, in
+// the prometheus text format. Metrics composer doesn't export are empty.
+func ParseQueueStatus(r io.Reader) (*QueueStatus, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the metrics of composer: %v", err)
+	}
+
+	queues := map[[2]string]*JobQueue{}
+	count := func(name string, add func(q *JobQueue, n int)) {
+		f, ok := families[name]
+		if !ok {
+			return
+		}
+		for _, m := range f.GetMetric() {
+			jobType, arch, _ := strings.Cut(label(m, "type"), ":")
+			key := [2]string{jobType, arch}
+			q, ok := queues[key]
+			if !ok {
+				q = &JobQueue{Type: jobType, Arch: arch}
+				queues[key] = q
+			}
+			add(q, int(value(m)))
+		}
+	}
+	count(pendingJobsMetric, func(q *JobQueue, n int) { q.Pending += n })
+	count(runningJobsMetric, func(q *JobQueue, n int) { q.Running += n })
+
+	var status QueueStatus
+	for _, q := range queues {
+		status.Queues = append(status.Queues, *q)
+	}
+	sort.Slice(status.Queues, func(i, j int) bool {
+		if status.Queues[i].Type != status.Queues[j].Type {
+			return status.Queues[i].Type < status.Queues[j].Type
+		}
+		return status.Queues[i].Arch < status.Queues[j].Arch
+	})
+
+	if f, ok := families[workerHeartbeatMetric]; ok {
+		for _, m := range f.GetMetric() {
+			sec, frac := math.Modf(value(m))
+			status.Workers = append(status.Workers, Worker{
+				Id:            label(m, "worker_id"),
+				Arch:          label(m, "arch"),
+				LastHeartbeat: time.Unix(int64(sec), int64(frac*1e9)).UTC(),
+			})
+		}
+	}
+	sort.Slice(status.Workers, func(i, j int) bool {
+		return status.Workers[i].Id < status.Workers[j].Id
+	})
+	return &status, nil
+}
+
+// value is the value of a gauge, metrics without a type are read as well.
+func value(m *dto.Metric) float64 {
+	if m.Gauge != nil {
+		return m.GetGauge().GetValue()
+	}
+	return m.GetUntyped().GetValue()
+}
+
+func label(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
+
+// QueueStatus returns the queue and the workers of composer, as its metrics
+// report them.
+func (cc *ComposerClient) QueueStatus(ctx context.Context) (*QueueStatus, error) {
+	if cc.metricsURL == "" {
+		return nil, ErrNoMetricsURL
+	}
+	resp, err := cc.request(ctx, "metrics", "GET", cc.metricsURL, map[string]string{"Accept": "text/plain"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("composer responded with %d to the request for its metrics", resp.StatusCode)
+	}
+	return ParseQueueStatus(resp.Body)
+}
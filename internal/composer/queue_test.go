@@ -0,0 +1,82 @@
+package composer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+)
+
+const composerMetrics = `# HELP image_builder_composer_pending_jobs Currently pending jobs
+# TYPE image_builder_composer_pending_jobs gauge
+image_builder_composer_pending_jobs{tenant="org1",type="osbuild:x86_64"} 3
+image_builder_composer_pending_jobs{tenant="org2",type="osbuild:x86_64"} 2
+image_builder_composer_pending_jobs{tenant="org1",type="osbuild:aarch64"} 1
+image_builder_composer_pending_jobs{tenant="org1",type="depsolve"} 0
+# HELP image_builder_composer_running_jobs Currently running jobs
+# TYPE image_builder_composer_running_jobs gauge
+image_builder_composer_running_jobs{tenant="org1",type="osbuild:x86_64"} 4
+# HELP image_builder_composer_worker_heartbeat_timestamp_seconds Last heartbeat of a worker
+# TYPE image_builder_composer_worker_heartbeat_timestamp_seconds gauge
+image_builder_composer_worker_heartbeat_timestamp_seconds{arch="x86_64",worker_id="w2"} 1.7000000005e+09
+image_builder_composer_worker_heartbeat_timestamp_seconds{arch="aarch64",worker_id="w1"} 1.7e+09
+# HELP go_goroutines Number of goroutines that currently exist.
+# TYPE go_goroutines gauge
+go_goroutines 42
+`
+
+func TestParseQueueStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		require.Equal(t, "Bearer", r.Header.Get("Authorization")[:6])
+		_, err := w.Write([]byte(composerMetrics))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	client := func(metricsURL string) *ComposerClient {
+		cc, err := NewClient(ComposerClientConfig{
+			ComposerURL:  srv.URL,
+			TokenURL:     srv.URL,
+			ClientId:     "id",
+			ClientSecret: "secret",
+			Retry:        common.RetryPolicy{Attempts: 1},
+			MetricsURL:   metricsURL,
+		})
+		require.NoError(t, err)
+		return cc
+	}
+
+	status, err := client(srv.URL + "/metrics").QueueStatus(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []JobQueue{
+		{Type: "depsolve"},
+		{Type: "osbuild", Arch: "aarch64", Pending: 1},
+		{Type: "osbuild", Arch: "x86_64", Pending: 5, Running: 4},
+	}, status.Queues)
+	require.Equal(t, []Worker{
+		{Id: "w1", Arch: "aarch64", LastHeartbeat: time.Unix(1700000000, 0).UTC()},
+		{Id: "w2", Arch: "x86_64", LastHeartbeat: time.Unix(1700000000, 500000000).UTC()},
+	}, status.Workers)
+
+	// composers which don't export the metrics have empty queues
+	status, err = ParseQueueStatus(bytes.NewReader(nil))
+	require.NoError(t, err)
+	require.Empty(t, status.Queues)
+	require.Empty(t, status.Workers)
+
+	_, err = client("").QueueStatus(context.Background())
+	require.True(t, errors.Is(err, ErrNoMetricsURL))
+	_, err = client(srv.URL + "/nope").QueueStatus(context.Background())
+	require.Error(t, err)
+}
@@ -0,0 +1,208 @@
+package composer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/osbuild/image-builder/internal/logger"
+)
+
+const (
+	// Access tokens are refreshed this long before they expire, so requests
+	// don't run into an expired token.
+	tokenRefreshMargin = time.Minute
+	// How often tokens without a known expiry are refreshed.
+	tokenRefreshInterval = 5 * time.Minute
+	// Wait before trying again after a refresh failed.
+	tokenRetryInterval = 10 * time.Second
+)
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// cachedToken is the access token as it's kept in the token cache file.
+type cachedToken struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func (cc *ComposerClient) token() string {
+	cc.tokenMu.RLock()
+	defer cc.tokenMu.RUnlock()
+	return cc.accessToken
+}
+
+// AcquireToken gets a new access token for composer.
+func (cc *ComposerClient) AcquireToken() error {
+	cc.tokenMu.Lock()
+	defer cc.tokenMu.Unlock()
+	return cc.refreshTokenLocked()
+}
+
+// refreshTokenIfStale gets a new access token unless another request already
+// replaced the stale one, so a burst of rejected requests causes a single
+// refresh.
+func (cc *ComposerClient) refreshTokenIfStale(stale string) error {
+	cc.tokenMu.Lock()
+	defer cc.tokenMu.Unlock()
+	if cc.accessToken != stale {
+		return nil
+	}
+	return cc.refreshTokenLocked()
+}
+
+func (cc *ComposerClient) refreshTokenLocked() error {
+	data := url.Values{}
+	if offlineToken := cc.offlineToken(); offlineToken != "" {
+		data.Set("grant_type", "refresh_token")
+		data.Set("client_id", cc.clientId)
+		data.Set("refresh_token", offlineToken)
+	}
+	if clientSecret := cc.clientSecret(); clientSecret != "" {
+		data.Set("grant_type", "client_credentials")
+		data.Set("client_id", cc.clientId)
+		data.Set("client_secret", clientSecret)
+	}
+
+	resp, err := cc.tokenClient.PostForm(cc.tokenURL, data)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Module(logger.ModuleComposer).Errorf("Error closing body after refreshing composer client token: %v", err)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("token endpoint responded with %d", resp.StatusCode)
+	}
+
+	var tr tokenResponse
+	err = json.NewDecoder(resp.Body).Decode(&tr)
+	if err != nil {
+		return err
+	}
+
+	cc.accessToken = tr.AccessToken
+	cc.tokenExpiry = time.Time{}
+	if tr.ExpiresIn > 0 {
+		cc.tokenExpiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	cc.storeCachedToken()
+	return nil
+}
+
+// untilRefresh returns how long the current token can be used before it
+// should be refreshed.
+func (cc *ComposerClient) untilRefresh(now time.Time) time.Duration {
+	cc.tokenMu.RLock()
+	defer cc.tokenMu.RUnlock()
+	if cc.accessToken == "" {
+		return 0
+	}
+	if cc.tokenExpiry.IsZero() {
+		return tokenRefreshInterval
+	}
+	return cc.tokenExpiry.Add(-tokenRefreshMargin).Sub(now)
+}
+
+// RunTokenRefresh refreshes the access token before it expires until ctx is
+// done, instead of waiting for composer to reject a request with it.
+func (cc *ComposerClient) RunTokenRefresh(ctx context.Context) {
+	for {
+		timer := time.NewTimer(cc.untilRefresh(time.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		err := cc.AcquireToken()
+		if err == nil {
+			continue
+		}
+		logger.Module(logger.ModuleComposer).Warnf("Unable to refresh the composer access token: %v", err)
+		timer = time.NewTimer(tokenRetryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// loadCachedToken picks up the token a previous instance stored, if it's
+// still valid for a while.
+func (cc *ComposerClient) loadCachedToken() {
+	if cc.tokenCacheFile == "" {
+		return
+	}
+	buf, err := os.ReadFile(filepath.Clean(cc.tokenCacheFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Module(logger.ModuleComposer).Warnf("Unable to read the composer token cache: %v", err)
+		}
+		return
+	}
+	var cached cachedToken
+	err = json.Unmarshal(buf, &cached)
+	if err != nil {
+		logger.Module(logger.ModuleComposer).Warnf("Unable to parse the composer token cache: %v", err)
+		return
+	}
+	if time.Until(cached.ExpiresAt) <= tokenRefreshMargin {
+		return
+	}
+
+	cc.tokenMu.Lock()
+	defer cc.tokenMu.Unlock()
+	cc.accessToken = cached.AccessToken
+	cc.tokenExpiry = cached.ExpiresAt
+}
+
+// storeCachedToken writes the token to the cache file, tokens without an
+// expiry aren't stored. Failing to do so only costs a refresh on restart.
+func (cc *ComposerClient) storeCachedToken() {
+	if cc.tokenCacheFile == "" || cc.tokenExpiry.IsZero() {
+		return
+	}
+	buf, err := json.Marshal(cachedToken{
+		AccessToken: cc.accessToken,
+		ExpiresAt:   cc.tokenExpiry,
+	})
+	if err != nil {
+		return
+	}
+
+	// written aside and renamed, so instances never read a partial token
+	err = writeFileAtomic(cc.tokenCacheFile, buf)
+	if err != nil {
+		logger.Module(logger.ModuleComposer).Warnf("Unable to write the composer token cache: %v", err)
+	}
+}
+
+func writeFileAtomic(path string, buf []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(buf)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,76 @@
+package composer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenRefresh(t *testing.T) {
+	var refreshes atomic.Int64
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := refreshes.Add(1)
+		require.NoError(t, json.NewEncoder(w).Encode(tokenResponse{
+			AccessToken: "token" + string(rune('0'+n)),
+			ExpiresIn:   900,
+		}))
+	}))
+	defer tokenSrv.Close()
+
+	conf := ComposerClientConfig{
+		TokenURL:       tokenSrv.URL,
+		ClientId:       "id",
+		ClientSecret:   "secret",
+		TokenCacheFile: filepath.Join(t.TempDir(), "token.json"),
+	}
+	cc, err := NewClient(conf)
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(0), cc.untilRefresh(time.Now()))
+
+	require.NoError(t, cc.AcquireToken())
+	require.Equal(t, "token1", cc.token())
+	now := time.Now()
+	require.WithinDuration(t, now.Add(14*time.Minute), now.Add(cc.untilRefresh(now)), time.Second)
+
+	// requests rejected with a token which was already replaced don't
+	// refresh it again
+	require.NoError(t, cc.refreshTokenIfStale("token0"))
+	require.Equal(t, int64(1), refreshes.Load())
+	require.NoError(t, cc.refreshTokenIfStale("token1"))
+	require.Equal(t, "token2", cc.token())
+
+	// restarted clients pick up the stored token
+	restarted, err := NewClient(conf)
+	require.NoError(t, err)
+	require.Equal(t, "token2", restarted.token())
+	require.Equal(t, int64(2), refreshes.Load())
+}
+
+func TestTokenRefreshRotatedSecret(t *testing.T) {
+	var secrets []string
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		secrets = append(secrets, r.PostForm.Get("client_secret"))
+		require.NoError(t, json.NewEncoder(w).Encode(tokenResponse{AccessToken: "token", ExpiresIn: 900}))
+	}))
+	defer tokenSrv.Close()
+
+	secret := "secret"
+	cc, err := NewClient(ComposerClientConfig{
+		TokenURL:         tokenSrv.URL,
+		ClientId:         "id",
+		ClientSecret:     secret,
+		ClientSecretFunc: func() string { return secret },
+	})
+	require.NoError(t, err)
+	require.NoError(t, cc.AcquireToken())
+	secret = "rotated"
+	require.NoError(t, cc.refreshTokenIfStale("token"))
+	require.Equal(t, []string{"secret", "rotated"}, secrets)
+}
@@ -2,40 +2,133 @@ package config
 
 import "strings"
 
-// Do not write this config to logs or stdout, it contains secrets!
+// Do not write this config to logs or stdout, it contains secrets! The fields
+// tagged secret are redacted by PrintConfig. The clients of the ones tagged
+// refresh read them again, so references to them are kept resolving.
 type ImageBuilderConfig struct {
-	ListenAddress        string `env:"LISTEN_ADDRESS"`
-	LogLevel             string `env:"LOG_LEVEL"`
-	LogGroup             string `env:"CW_LOG_GROUP"`
-	CwRegion             string `env:"CW_AWS_REGION"`
-	CwAccessKeyID        string `env:"CW_AWS_ACCESS_KEY_ID"`
-	CwSecretAccessKey    string `env:"CW_AWS_SECRET_ACCESS_KEY"`
-	ComposerURL          string `env:"COMPOSER_URL"`
-	ComposerTokenURL     string `env:"COMPOSER_TOKEN_URL"`
-	ComposerClientId     string `env:"COMPOSER_CLIENT_ID"`
-	ComposerOfflineToken string `env:"COMPOSER_OFFLINE_TOKEN"`
-	ComposerClientSecret string `env:"COMPOSER_CLIENT_SECRET"`
-	ComposerCA           string `env:"COMPOSER_CA_PATH"`
-	OsbuildRegion        string `env:"OSBUILD_AWS_REGION"`
-	OsbuildGCPRegion     string `env:"OSBUILD_GCP_REGION"`
-	OsbuildGCPBucket     string `env:"OSBUILD_GCP_BUCKET"`
-	DistributionsDir     string `env:"DISTRIBUTIONS_DIR"`
-	MigrationsDir        string `env:"MIGRATIONS_DIR"`
-	TernExecutable       string `env:"TERN_EXECUTABLE"`
-	TernMigrationsDir    string `env:"TERN_MIGRATIONS_DIR"`
-	PGHost               string `env:"PGHOST"`
-	PGPort               string `env:"PGPORT"`
-	PGDatabase           string `env:"PGDATABASE"`
-	PGUser               string `env:"PGUSER"`
-	PGPassword           string `env:"PGPASSWORD"`
-	PGSSLMode            string `env:"PGSSLMODE"`
-	QuotaFile            string `env:"QUOTA_FILE"`
-	AllowFile            string `env:"ALLOW_FILE"`
-	SplunkHost           string `env:"SPLUNK_HEC_HOST"`
-	SplunkPort           string `env:"SPLUNK_HEC_PORT"`
-	SplunkToken          string `env:"SPLUNK_HEC_TOKEN"`
-	ProvisioningURL      string `env:"PROVISIONING_URL"`
-	GlitchTipDSN         string `env:"GLITCHTIP_DSN"`
+	ListenAddress               string `env:"LISTEN_ADDRESS"`
+	LogLevel                    string `env:"LOG_LEVEL"`
+	LogModuleLevels             string `env:"LOG_MODULE_LEVELS"`
+	DiagnosticsAddress          string `env:"DIAGNOSTICS_ADDRESS"`
+	LogGroup                    string `env:"CW_LOG_GROUP"`
+	EMFNamespace                string `env:"CW_EMF_NAMESPACE"`
+	CwRegion                    string `env:"CW_AWS_REGION"`
+	CwAccessKeyID               string `env:"CW_AWS_ACCESS_KEY_ID"`
+	CwSecretAccessKey           string `env:"CW_AWS_SECRET_ACCESS_KEY" secret:""`
+	ComposerURL                 string `env:"COMPOSER_URL"`
+	ComposerTokenURL            string `env:"COMPOSER_TOKEN_URL"`
+	ComposerClientId            string `env:"COMPOSER_CLIENT_ID"`
+	ComposerOfflineToken        string `env:"COMPOSER_OFFLINE_TOKEN" secret:"" refresh:""`
+	ComposerClientSecret        string `env:"COMPOSER_CLIENT_SECRET" secret:"" refresh:""`
+	ComposerCA                  string `env:"COMPOSER_CA_PATH"`
+	ComposerCert                string `env:"COMPOSER_CERT_PATH"`
+	ComposerKey                 string `env:"COMPOSER_KEY_PATH"`
+	ComposerBackends            string `env:"COMPOSER_BACKENDS"`
+	ComposerHealthCheckInterval string `env:"COMPOSER_HEALTH_CHECK_INTERVAL"`
+	ComposerCapabilitiesRefresh string `env:"COMPOSER_CAPABILITIES_REFRESH_INTERVAL"`
+	ComposerMetricsURL          string `env:"COMPOSER_METRICS_URL"`
+	ComposerQueueInterval       string `env:"COMPOSER_QUEUE_INTERVAL"`
+	ComposerConnectTimeout      string `env:"COMPOSER_CONNECT_TIMEOUT"`
+	ComposerReadTimeout         string `env:"COMPOSER_READ_TIMEOUT"`
+	ComposerRequestTimeout      string `env:"COMPOSER_REQUEST_TIMEOUT"`
+	ComposerTokenConnectTimeout string `env:"COMPOSER_TOKEN_CONNECT_TIMEOUT"`
+	ComposerTokenReadTimeout    string `env:"COMPOSER_TOKEN_READ_TIMEOUT"`
+	ComposerTokenRequestTimeout string `env:"COMPOSER_TOKEN_REQUEST_TIMEOUT"`
+	ComposerTokenCacheFile      string `env:"COMPOSER_TOKEN_CACHE_FILE"`
+	OsbuildRegion               string `env:"OSBUILD_AWS_REGION"`
+	OsbuildGovRegion            string `env:"OSBUILD_AWS_GOV_REGION"`
+	OsbuildEncryptImages        string `env:"OSBUILD_AWS_ENCRYPT_IMAGES"`
+	OsbuildKMSKeyArn            string `env:"OSBUILD_AWS_KMS_KEY_ARN"`
+	OsbuildGCPRegion            string `env:"OSBUILD_GCP_REGION"`
+	OsbuildGCPBucket            string `env:"OSBUILD_GCP_BUCKET"`
+	DistributionsDir            string `env:"DISTRIBUTIONS_DIR"`
+	MigrationsDir               string `env:"MIGRATIONS_DIR"`
+	TernExecutable              string `env:"TERN_EXECUTABLE"`
+	TernMigrationsDir           string `env:"TERN_MIGRATIONS_DIR"`
+	PGHost                      string `env:"PGHOST"`
+	PGPort                      string `env:"PGPORT"`
+	PGDatabase                  string `env:"PGDATABASE"`
+	PGUser                      string `env:"PGUSER"`
+	PGPassword                  string `env:"PGPASSWORD" secret:"" refresh:""`
+	PGSSLMode                   string `env:"PGSSLMODE"`
+	QuotaFile                   string `env:"QUOTA_FILE"`
+	AllowFile                   string `env:"ALLOW_FILE"`
+	SplunkHost                  string `env:"SPLUNK_HEC_HOST"`
+	SplunkPort                  string `env:"SPLUNK_HEC_PORT"`
+	SplunkToken                 string `env:"SPLUNK_HEC_TOKEN" secret:""`
+	SplunkBatchSize             string `env:"SPLUNK_HEC_BATCH_SIZE"`
+	SplunkFlushInterval         string `env:"SPLUNK_HEC_FLUSH_INTERVAL"`
+	SplunkQueueSize             string `env:"SPLUNK_HEC_QUEUE_SIZE"`
+	ProvisioningURL             string `env:"PROVISIONING_URL"`
+	ProvisioningConnectTimeout  string `env:"PROVISIONING_CONNECT_TIMEOUT"`
+	ProvisioningReadTimeout     string `env:"PROVISIONING_READ_TIMEOUT"`
+	ProvisioningRequestTimeout  string `env:"PROVISIONING_REQUEST_TIMEOUT"`
+	RBACURL                     string `env:"RBAC_URL"`
+	GlitchTipDSN                string `env:"GLITCHTIP_DSN" secret:""`
+	ServiceAccountJWKS          string `env:"SERVICE_ACCOUNT_JWKS_URL"`
+	ServiceAccountIssuer        string `env:"SERVICE_ACCOUNT_ISSUER"`
+	ServiceAccountAudiences     string `env:"SERVICE_ACCOUNT_AUDIENCES"`
+	ServiceAccountEntitlements  string `env:"SERVICE_ACCOUNT_ENTITLEMENTS"`
+	AuthProvider                string `env:"AUTH_PROVIDER"`
+	OIDCIssuer                  string `env:"OIDC_ISSUER"`
+	OIDCJWKS                    string `env:"OIDC_JWKS_URL"`
+	OIDCOrgIdClaim              string `env:"OIDC_ORG_ID_CLAIM"`
+	OIDCAudiences               string `env:"OIDC_AUDIENCES"`
+	OIDCEntitlements            string `env:"OIDC_ENTITLEMENTS"`
+	OIDCEntitlementsClaim       string `env:"OIDC_ENTITLEMENTS_CLAIM"`
+	StandaloneOrgId             string `env:"STANDALONE_ORG_ID"`
+	StandaloneUsername          string `env:"STANDALONE_USERNAME"`
+	StandaloneUsersFile         string `env:"STANDALONE_USERS_FILE"`
+	RateLimitRequests           string `env:"RATE_LIMIT_REQUESTS"`
+	RateLimitInterval           string `env:"RATE_LIMIT_INTERVAL"`
+	RedisAddress                string `env:"REDIS_ADDRESS"`
+	RedisPassword               string `env:"REDIS_PASSWORD" secret:"" refresh:""`
+	ComposeQueueInterval        string `env:"COMPOSE_QUEUE_INTERVAL"`
+	WebhookInterval             string `env:"WEBHOOK_INTERVAL"`
+	StatusSyncInterval          string `env:"STATUS_SYNC_INTERVAL"`
+	OutboxInterval              string `env:"OUTBOX_INTERVAL"`
+	KafkaBrokers                string `env:"KAFKA_BROKERS"`
+	KafkaTopic                  string `env:"KAFKA_TOPIC"`
+	KafkaTLS                    string `env:"KAFKA_TLS"`
+	KafkaCA                     string `env:"KAFKA_CA_PATH"`
+	KafkaUsername               string `env:"KAFKA_SASL_USERNAME"`
+	KafkaPassword               string `env:"KAFKA_SASL_PASSWORD" secret:"" refresh:""`
+	KafkaMechanism              string `env:"KAFKA_SASL_MECHANISM"`
+	NotificationsTopic          string `env:"NOTIFICATIONS_TOPIC"`
+	InventoryTopic              string `env:"INVENTORY_TOPIC"`
+	SMTPAddress                 string `env:"SMTP_ADDRESS"`
+	SMTPUsername                string `env:"SMTP_USERNAME"`
+	SMTPPassword                string `env:"SMTP_PASSWORD" secret:"" refresh:""`
+	SMTPFrom                    string `env:"SMTP_FROM"`
+	EmailTemplatesDir           string `env:"EMAIL_TEMPLATES_DIR"`
+	EmailRateLimit              string `env:"EMAIL_RATE_LIMIT"`
+	ComposeStatusCacheTTL       string `env:"COMPOSE_STATUS_CACHE_TTL"`
+	RequestBodyLimit            string `env:"REQUEST_BODY_LIMIT"`
+	RequestBodyLimits           string `env:"REQUEST_BODY_LIMITS"`
+	RequestDeadline             string `env:"REQUEST_DEADLINE"`
+	RequestValidation           string `env:"REQUEST_VALIDATION"`
+	ResponseValidation          string `env:"RESPONSE_VALIDATION"`
+	ProvenanceBuilderId         string `env:"PROVENANCE_BUILDER_ID"`
+	ProvenanceSigningKey        string `env:"PROVENANCE_SIGNING_KEY_PATH"`
+	CosignKeyStore              string `env:"COSIGN_KEY_STORE" template:""`
+	CosignFulcioURL             string `env:"COSIGN_FULCIO_URL"`
+	CosignOIDCTokenPath         string `env:"COSIGN_OIDC_TOKEN_PATH"`
+	TrivyURL                    string `env:"TRIVY_URL"`
+	TrivyToken                  string `env:"TRIVY_TOKEN" secret:""`
+	KeyEncryptionKeys           string `env:"KEY_ENCRYPTION_KEYS" secret:""`
+	ComplianceExportWindow      string `env:"COMPLIANCE_EXPORT_WINDOW"`
+	ComplianceExportEndpoint    string `env:"COMPLIANCE_EXPORT_ENDPOINT"`
+	StreamAllowedOrigins        string `env:"STREAM_ALLOWED_ORIGINS"`
+	PolicyURL                   string `env:"POLICY_URL"`
+	PolicyPath                  string `env:"POLICY_PATH"`
+	ApprovalWebhookURL          string `env:"APPROVAL_WEBHOOK_URL"`
+	UnleashURL                  string `env:"UNLEASH_URL"`
+	UnleashToken                string `env:"UNLEASH_TOKEN" secret:""`
+	VaultAddress                string `env:"VAULT_ADDR"`
+	VaultToken                  string `env:"VAULT_TOKEN" secret:""`
+	VaultNamespace              string `env:"VAULT_NAMESPACE"`
+	SecretsAWSRegion            string `env:"SECRETS_AWS_REGION"`
+	SecretsRefreshInterval      string `env:"SECRETS_REFRESH_INTERVAL"`
 }
 
 func (ibc *ImageBuilderConfig) IsDebug() bool {
@@ -1,10 +1,16 @@
 package config
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/secrets"
 )
 
 func TestDefault(t *testing.T) {
@@ -58,3 +64,112 @@ func TestEnvPointerValues(t *testing.T) {
 	require.Empty(t, config.CwAccessKeyID)
 	require.Empty(t, config.CwSecretAccessKey)
 }
+
+func TestLoadConfigFromFile(t *testing.T) {
+	os.Clearenv()
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte("listen_address: localhost:8000\npgport: 5433\nkafka_tls: true\n"), 0600))
+	tomlFile := filepath.Join(dir, "config.toml")
+	require.NoError(t, os.WriteFile(tomlFile, []byte("listen_address = \"localhost:9000\"\npgport = 5434\n"), 0600))
+
+	config := ImageBuilderConfig{ListenAddress: "localhost", LogLevel: "DEBUG"}
+	require.NoError(t, LoadConfigFromFile(&config, yamlFile))
+	require.Equal(t, "localhost:8000", config.ListenAddress)
+	require.Equal(t, "5433", config.PGPort)
+	require.Equal(t, "true", config.KafkaTLS)
+	require.Equal(t, "DEBUG", config.LogLevel)
+
+	require.NoError(t, LoadConfigFromFile(&config, tomlFile))
+	require.Equal(t, "localhost:9000", config.ListenAddress)
+	require.Equal(t, "5434", config.PGPort)
+
+	// env variables win
+	os.Setenv("LISTEN_ADDRESS", "localhost:8086")
+	require.NoError(t, LoadConfigFromEnv(&config))
+	require.Equal(t, "localhost:8086", config.ListenAddress)
+	require.Equal(t, "5434", config.PGPort)
+}
+
+func TestLoadConfigFromFileInvalid(t *testing.T) {
+	dir := t.TempDir()
+	for content, msg := range map[string]string{
+		"listen_adress: localhost\nfoo: bar\n": "unknown keys: foo, listen_adress",
+		"pghost: [a, b]\n":                     "pghost has to be a string",
+		"pghost: \"\n":                         "Unable to parse",
+	} {
+		path := filepath.Join(dir, "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		var config ImageBuilderConfig
+		err := LoadConfigFromFile(&config, path)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), msg)
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	config := ImageBuilderConfig{
+		ListenAddress: "localhost:8086",
+		PGPassword:    "foobar",
+		SMTPPassword:  "",
+		RedisPassword: "vault:secret/data/image-builder#redis",
+	}
+	var buf bytes.Buffer
+	require.NoError(t, PrintConfig(&buf, &config))
+	require.Contains(t, buf.String(), "listen_address: localhost:8086\n")
+	require.Contains(t, buf.String(), "pgpassword: REDACTED\n")
+	require.Contains(t, buf.String(), "smtp_password: \"\"\n")
+	require.Contains(t, buf.String(), "redis_password: vault:secret/data/image-builder#redis\n")
+	require.NotContains(t, buf.String(), "foobar")
+
+	// the output is a valid config file
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0600))
+	var loaded ImageBuilderConfig
+	require.NoError(t, LoadConfigFromFile(&loaded, path))
+	require.Equal(t, "localhost:8086", loaded.ListenAddress)
+}
+
+type fakeStore map[string]string
+
+func (fs fakeStore) Lookup(ctx context.Context, path, key string) (string, error) {
+	value, ok := fs[path+"#"+key]
+	if !ok {
+		return "", fmt.Errorf("not found")
+	}
+	return value, nil
+}
+
+func TestResolveSecrets(t *testing.T) {
+	config := ImageBuilderConfig{
+		PGHost:        "localhost",
+		PGPassword:    "vault:secret/data/image-builder#pgpassword",
+		RedisPassword: "aws-sm:image-builder/redis#",
+	}
+	r := secrets.NewResolver(map[string]secrets.Store{
+		secrets.SchemeVault: fakeStore{"secret/data/image-builder#pgpassword": "foobar"},
+		secrets.SchemeAWS:   fakeStore{"image-builder/redis#": "redis"},
+	})
+	require.NoError(t, ResolveSecrets(context.Background(), &config, r))
+	require.Equal(t, "localhost", config.PGHost)
+	require.Equal(t, "foobar", config.PGPassword)
+	require.Equal(t, "redis", config.RedisPassword)
+	// only the secrets clients read again are kept to be refreshed
+	require.Equal(t, "foobar", r.Value("vault:secret/data/image-builder#pgpassword"))
+	config.SplunkToken = "aws-sm:image-builder/splunk-token"
+	r = secrets.NewResolver(map[string]secrets.Store{
+		secrets.SchemeAWS: fakeStore{"image-builder/splunk-token#": "splunk"},
+	})
+	require.NoError(t, ResolveSecrets(context.Background(), &config, r))
+	require.Equal(t, "splunk", config.SplunkToken)
+	require.Empty(t, r.Value("aws-sm:image-builder/splunk-token"))
+
+	// templates are left alone
+	config.CosignKeyStore = "vault:secret/data/image-builder/cosign/{org_id}"
+	require.NoError(t, ResolveSecrets(context.Background(), &config, r))
+	require.Equal(t, "vault:secret/data/image-builder/cosign/{org_id}", config.CosignKeyStore)
+
+	config.SMTPPassword = "vault:secret/data/image-builder#smtp"
+	err := ResolveSecrets(context.Background(), &config, r)
+	require.ErrorContains(t, err, "SMTP_PASSWORD")
+}
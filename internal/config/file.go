@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/osbuild/image-builder/internal/secrets"
+)
+
+const redacted = "REDACTED"
+
+// fileKey is the key of a field in config files, its env variable in lower
+// case, e.g. composer_url for COMPOSER_URL.
+func fileKey(field reflect.StructField) string {
+	return strings.ToLower(field.Tag.Get("env"))
+}
+
+// LoadConfigFromFile sets the fields of conf which are in the yaml or toml
+// file at path, toml if it ends in .toml. Keys are the env variables in
+// lower case, unknown keys are an error. Env variables are loaded on top
+// with LoadConfigFromEnv.
+func LoadConfigFromFile(conf *ImageBuilderConfig, path string) error {
+	raw, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+
+	values := map[string]interface{}{}
+	if filepath.Ext(path) == ".toml" {
+		err = toml.Unmarshal(raw, &values)
+	} else {
+		err = yaml.Unmarshal(raw, &values)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to parse config file %s: %v", path, err)
+	}
+
+	t := reflect.TypeOf(conf).Elem()
+	v := reflect.ValueOf(conf).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key := fileKey(t.Field(i))
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		delete(values, key)
+		switch value.(type) {
+		case string, bool, int, int64, uint64, float64:
+			v.Field(i).SetString(fmt.Sprint(value))
+		default:
+			return fmt.Errorf("Config file %s: %s has to be a string, a number or a boolean", path, key)
+		}
+	}
+
+	if len(values) > 0 {
+		var unknown []string
+		for k := range values {
+			unknown = append(unknown, k)
+		}
+		sort.Strings(unknown)
+		return fmt.Errorf("Config file %s has unknown keys: %s", path, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// PrintConfig writes conf as a yaml config file, with secrets redacted.
+// References to secrets are kept, they aren't secret themselves.
+func PrintConfig(w io.Writer, conf *ImageBuilderConfig) error {
+	t := reflect.TypeOf(conf).Elem()
+	v := reflect.ValueOf(conf).Elem()
+	values := map[string]string{}
+	for i := 0; i < v.NumField(); i++ {
+		value := v.Field(i).String()
+		if _, secret := t.Field(i).Tag.Lookup("secret"); secret && value != "" && !secrets.IsReference(value) {
+			value = redacted
+		}
+		values[fileKey(t.Field(i))] = value
+	}
+	enc := yaml.NewEncoder(w)
+	defer enc.Close()
+	return enc.Encode(values)
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/osbuild/image-builder/internal/secrets"
+)
+
+// ResolveSecrets replaces the values of conf which are references to
+// secrets, e.g. vault:secret/data/image-builder#pgpassword, with the secrets
+// they refer to. Fields tagged template are references with placeholders,
+// which are expanded and looked up when they're needed. Only the references
+// of fields tagged refresh are kept by r, to be resolved again by its Run,
+// the other values are never read again after startup.
+func ResolveSecrets(ctx context.Context, conf *ImageBuilderConfig, r *secrets.Resolver) error {
+	t := reflect.TypeOf(conf).Elem()
+	v := reflect.ValueOf(conf).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		ref := v.Field(i).String()
+		if _, ok := t.Field(i).Tag.Lookup("template"); ok || !secrets.IsReference(ref) {
+			continue
+		}
+		resolve := r.Lookup
+		if _, ok := t.Field(i).Tag.Lookup("refresh"); ok {
+			resolve = r.Resolve
+		}
+		value, err := resolve(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("%s: %v", t.Field(i).Tag.Get("env"), err)
+		}
+		v.Field(i).SetString(value)
+	}
+	return nil
+}
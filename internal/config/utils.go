@@ -46,6 +46,13 @@ func LoadConfigFromEnv(conf *ImageBuilderConfig) error {
 			conf.LogGroup = clowder.LoadedConfig.Logging.Cloudwatch.LogGroup
 		}
 
+		if clowder.LoadedConfig.InMemoryDb != nil {
+			conf.RedisAddress = fmt.Sprintf("%s:%d", clowder.LoadedConfig.InMemoryDb.Hostname, clowder.LoadedConfig.InMemoryDb.Port)
+			if clowder.LoadedConfig.InMemoryDb.Password != nil {
+				conf.RedisPassword = *clowder.LoadedConfig.InMemoryDb.Password
+			}
+		}
+
 		if endpoint, ok := clowder.DependencyEndpoints["provisioning-backend"]["api"]; ok {
 			conf.ProvisioningURL = fmt.Sprintf("http://%s:%d/api/provisioning/v1", endpoint.Hostname, endpoint.Port)
 		}
@@ -0,0 +1,87 @@
+// Package cosign signs container images the way cosign does and stores the
+// signatures in the registry next to the images, so they can be verified with
+// cosign verify, see
+// https://github.com/sigstore/cosign/blob/main/specs/SIGNATURE_SPEC.md.
+package cosign
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/osbuild/image-builder/internal/signing"
+)
+
+const (
+	SimpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+
+	signatureAnnotation   = "dev.cosignproject.cosign/signature"
+	certificateAnnotation = "dev.sigstore.cosign/certificate"
+	chainAnnotation       = "dev.sigstore.cosign/chain"
+)
+
+// Signature is a signature of an image manifest, and the certificate of the
+// key it was signed with if it was signed keyless.
+type Signature struct {
+	Payload     []byte
+	Signature   []byte
+	Certificate []byte
+	Chain       []byte
+}
+
+type simpleSigning struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]string `json:"optional"`
+}
+
+// Payload is the simple signing payload cosign signs, which vouches for the
+// manifest with digest of image.
+func Payload(image, digest string) ([]byte, error) {
+	repo, err := ParseImage(image)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := SignatureTag(digest); err != nil {
+		return nil, err
+	}
+	var p simpleSigning
+	p.Critical.Identity.DockerReference = repo.Name()
+	p.Critical.Image.DockerManifestDigest = digest
+	p.Critical.Type = "cosign container image signature"
+	return json.Marshal(p)
+}
+
+// SignatureTag is the tag cosign stores the signatures of the manifest with
+// digest at.
+func SignatureTag(digest string) (string, error) {
+	algorithm, hex, ok := strings.Cut(digest, ":")
+	if !ok || algorithm != "sha256" || len(hex) != 64 || strings.Trim(hex, "0123456789abcdef") != "" {
+		return "", fmt.Errorf("expected a sha256 digest, got %q", digest)
+	}
+	return fmt.Sprintf("%s-%s.sig", algorithm, hex), nil
+}
+
+// Sign signs the manifest with digest of image with key.
+func Sign(key crypto.Signer, image, digest string) (*Signature, error) {
+	payload, err := Payload(image, digest)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signing.Sign(key, payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Signature{
+		Payload:   payload,
+		Signature: sig,
+	}, nil
+}
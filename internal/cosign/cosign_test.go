@@ -0,0 +1,247 @@
+package cosign
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/signing"
+)
+
+const testDigest = "sha256:f5fbd0e5df5a33a2b1e0e5c6e52a7b3e6dba6c1e6a7f38ff4b0e0f1a6b5e7b2a"
+
+func TestParseImage(t *testing.T) {
+	for image, repo := range map[string]Repository{
+		"quay.io/myorg/edge":                 {Host: "quay.io", Path: "myorg/edge"},
+		"quay.io/myorg/edge:latest":          {Host: "quay.io", Path: "myorg/edge"},
+		"localhost:5000/edge:1.0":            {Host: "localhost:5000", Path: "edge"},
+		"quay.io/myorg/edge@" + testDigest:   {Host: "quay.io", Path: "myorg/edge"},
+		"myorg/edge":                         {Host: "index.docker.io", Path: "myorg/edge"},
+		"fedora:39":                          {Host: "index.docker.io", Path: "library/fedora"},
+		"docker.io/library/fedora":           {Host: "index.docker.io", Path: "library/fedora"},
+		"registry.example.com:8443/a/b/c:v1": {Host: "registry.example.com:8443", Path: "a/b/c"},
+	} {
+		parsed, err := ParseImage(image)
+		require.NoError(t, err)
+		require.Equal(t, repo, parsed, image)
+	}
+}
+
+func TestSignatureTag(t *testing.T) {
+	tag, err := SignatureTag(testDigest)
+	require.NoError(t, err)
+	require.Equal(t, "sha256-f5fbd0e5df5a33a2b1e0e5c6e52a7b3e6dba6c1e6a7f38ff4b0e0f1a6b5e7b2a.sig", tag)
+
+	for _, digest := range []string{"", "f5fbd0e5", "sha512:f5fbd0e5", "sha256:../../x"} {
+		_, err = SignatureTag(digest)
+		require.Error(t, err)
+	}
+}
+
+func TestSign(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	sig, err := Sign(key, "quay.io/myorg/edge:latest", testDigest)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"critical": {"identity": {"docker-reference": "quay.io/myorg/edge"}, "image": {"docker-manifest-digest": "`+
+		testDigest+`"}, "type": "cosign container image signature"}, "optional": null}`, string(sig.Payload))
+	require.NoError(t, signing.Verify(key.Public(), sig.Payload, sig.Signature))
+}
+
+// fakeRegistry is a registry which hands out tokens, like quay.io does.
+type fakeRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+}
+
+func newFakeRegistry(t *testing.T) (*fakeRegistry, *httptest.Server) {
+	reg := &fakeRegistry{
+		blobs:     map[string][]byte{},
+		manifests: map[string][]byte{},
+	}
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reg.mu.Lock()
+		defer reg.mu.Unlock()
+
+		if r.URL.Path == "/token" {
+			user, password, ok := r.BasicAuth()
+			if !ok || user != "robot" || password != "hunter2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			require.Equal(t, "repository:myorg/edge:pull,push", r.URL.Query().Get("scope"))
+			fmt.Fprint(w, `{"token": "registrytoken"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer registrytoken" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:myorg/edge:pull"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, "/v2/myorg/edge")
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		switch {
+		case r.Method == http.MethodHead && strings.HasPrefix(path, "/blobs/"):
+			if _, ok := reg.blobs[strings.TrimPrefix(path, "/blobs/")]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPost && path == "/blobs/uploads/":
+			w.Header().Set("Location", "/v2/myorg/edge/blobs/uploads/1?state=abc")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodPut && path == "/blobs/uploads/1":
+			require.Equal(t, "abc", r.URL.Query().Get("state"))
+			digest := r.URL.Query().Get("digest")
+			require.Equal(t, digestOf(body), digest)
+			reg.blobs[digest] = body
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet && strings.HasPrefix(path, "/manifests/"):
+			m, ok := reg.manifests[strings.TrimPrefix(path, "/manifests/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(m)
+		case r.Method == http.MethodPut && strings.HasPrefix(path, "/manifests/"):
+			require.Equal(t, ociManifestMediaType, r.Header.Get("Content-Type"))
+			var m manifest
+			require.NoError(t, json.Unmarshal(body, &m))
+			for _, d := range append(m.Layers, m.Config) {
+				require.Contains(t, reg.blobs, d.Digest)
+			}
+			reg.manifests[strings.TrimPrefix(path, "/manifests/")] = body
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	return reg, srv
+}
+
+func TestAttach(t *testing.T) {
+	reg, srv := newFakeRegistry(t)
+	defer srv.Close()
+	image := strings.TrimPrefix(srv.URL, "https://") + "/myorg/edge:latest"
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	sig, err := Sign(key, image, testDigest)
+	require.NoError(t, err)
+
+	_, err = (&Registry{Username: "robot", Password: "wrong", Client: srv.Client()}).Attach(context.Background(), image, testDigest, sig)
+	require.ErrorContains(t, err, "the registry responded with 401")
+
+	ref, err := (&Registry{Username: "robot", Password: "hunter2", Client: srv.Client()}).Attach(context.Background(), image, testDigest, sig)
+	require.NoError(t, err)
+	tag, err := SignatureTag(testDigest)
+	require.NoError(t, err)
+	require.Equal(t, strings.TrimPrefix(srv.URL, "https://")+"/myorg/edge:"+tag, ref)
+
+	// signing again keeps the signature which is there
+	other, err := Sign(key, image, testDigest)
+	require.NoError(t, err)
+	_, err = (&Registry{Username: "robot", Password: "hunter2", Client: srv.Client()}).Attach(context.Background(), image, testDigest, other)
+	require.NoError(t, err)
+
+	var m manifest
+	require.NoError(t, json.Unmarshal(reg.manifests[tag], &m))
+	require.Len(t, m.Layers, 2)
+	for i, s := range []*Signature{sig, other} {
+		layer := m.Layers[i]
+		require.Equal(t, SimpleSigningMediaType, layer.MediaType)
+		require.Equal(t, s.Payload, reg.blobs[layer.Digest])
+		raw, err := base64.StdEncoding.DecodeString(layer.Annotations[signatureAnnotation])
+		require.NoError(t, err)
+		require.NoError(t, signing.Verify(key.Public(), reg.blobs[layer.Digest], raw))
+	}
+}
+
+func TestFulcio(t *testing.T) {
+	ca, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caCert := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fulcio"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caCert, caCert, ca.Public(), ca)
+	require.NoError(t, err)
+
+	fulcio := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v2/signingCert", r.URL.Path)
+		var req fulcioRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.True(t, strings.HasPrefix(req.Credentials.OIDCIdentityToken, "eyJ"))
+
+		block, _ := pem.Decode([]byte(req.PublicKeyRequest.PublicKey.Content))
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		require.NoError(t, err)
+		proof, err := base64.StdEncoding.DecodeString(req.PublicKeyRequest.ProofOfPossession)
+		require.NoError(t, err)
+		require.NoError(t, signing.Verify(pub, []byte("system:serviceaccount:image-builder:image-builder"), proof))
+
+		der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(10 * time.Minute),
+		}, caCert, pub, ca)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(map[string]interface{}{
+			"signedCertificateEmbeddedSct": map[string]interface{}{
+				"chain": map[string]interface{}{
+					"certificates": []string{
+						string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+						string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})),
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+	}))
+	defer fulcio.Close()
+
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"iss": "https://kubernetes.default.svc", "sub": "system:serviceaccount:image-builder:image-builder"}`))
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("eyJhbGciOiJSUzI1NiJ9."+claims+".c2lnbmF0dXJl\n"), 0600))
+
+	f := &Fulcio{URL: fulcio.URL, TokenPath: tokenPath}
+	sig, err := f.Sign(context.Background(), "quay.io/myorg/edge", testDigest)
+	require.NoError(t, err)
+
+	block, _ := pem.Decode(sig.Certificate)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	require.NoError(t, signing.Verify(cert.PublicKey, sig.Payload, sig.Signature))
+	block, _ = pem.Decode(sig.Chain)
+	require.Equal(t, caDER, block.Bytes)
+
+	_, err = tokenSubject("not a token")
+	require.Error(t, err)
+}
@@ -0,0 +1,171 @@
+package cosign
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/osbuild/image-builder/internal/signing"
+)
+
+// Fulcio certifies short lived keys for the identity of an OIDC token, which
+// is what cosign calls keyless signing.
+type Fulcio struct {
+	URL string
+	// The OIDC token of the service is read from here every time it's
+	// needed, as tokens projected into pods are rotated.
+	TokenPath string
+	Client    *http.Client
+}
+
+type fulcioRequest struct {
+	Credentials struct {
+		OIDCIdentityToken string `json:"oidcIdentityToken"`
+	} `json:"credentials"`
+	PublicKeyRequest struct {
+		PublicKey struct {
+			Algorithm string `json:"algorithm"`
+			Content   string `json:"content"`
+		} `json:"publicKey"`
+		ProofOfPossession string `json:"proofOfPossession"`
+	} `json:"publicKeyRequest"`
+}
+
+type fulcioChain struct {
+	Chain struct {
+		Certificates []string `json:"certificates"`
+	} `json:"chain"`
+}
+
+type fulcioResponse struct {
+	SignedCertificateEmbeddedSct *fulcioChain `json:"signedCertificateEmbeddedSct"`
+	SignedCertificateDetachedSct *fulcioChain `json:"signedCertificateDetachedSct"`
+}
+
+// Sign signs the manifest with digest of image with a new key, certified
+// for the identity of the service.
+func (f *Fulcio) Sign(ctx context.Context, image, digest string) (*Signature, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	cert, chain, err := f.Certificate(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := Sign(key, image, digest)
+	if err != nil {
+		return nil, err
+	}
+	sig.Certificate = cert
+	sig.Chain = chain
+	return sig, nil
+}
+
+// Certificate requests a certificate for key, and returns it and the chain
+// it was issued by, PEM encoded.
+func (f *Fulcio) Certificate(ctx context.Context, key crypto.Signer) ([]byte, []byte, error) {
+	raw, err := os.ReadFile(filepath.Clean(f.TokenPath))
+	if err != nil {
+		return nil, nil, err
+	}
+	token := strings.TrimSpace(string(raw))
+	subject, err := tokenSubject(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof, err := signing.Sign(key, []byte(subject))
+	if err != nil {
+		return nil, nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var fr fulcioRequest
+	fr.Credentials.OIDCIdentityToken = token
+	fr.PublicKeyRequest.PublicKey.Algorithm = "ECDSA"
+	fr.PublicKeyRequest.PublicKey.Content = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	fr.PublicKeyRequest.ProofOfPossession = base64.StdEncoding.EncodeToString(proof)
+	body, err := json.Marshal(fr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(f.URL, "/")+"/api/v2/signingCert", bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, nil, fmt.Errorf("fulcio responded with %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	var result fulcioResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, nil, err
+	}
+	certs := result.SignedCertificateEmbeddedSct
+	if certs == nil {
+		certs = result.SignedCertificateDetachedSct
+	}
+	if certs == nil || len(certs.Chain.Certificates) == 0 {
+		return nil, nil, errors.New("fulcio didn't issue a certificate")
+	}
+	return []byte(certs.Chain.Certificates[0]), []byte(strings.Join(certs.Chain.Certificates[1:], "")), nil
+}
+
+// tokenSubject is the identity fulcio expects the proof of possession to
+// sign, the email of the token if it has one, its subject otherwise. The
+// token isn't verified, that's what fulcio is for.
+func tokenSubject(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("the OIDC token isn't a JWT")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("the OIDC token isn't a JWT: %v", err)
+	}
+	var claims struct {
+		Subject string `json:"sub"`
+		Email   string `json:"email"`
+	}
+	err = json.Unmarshal(raw, &claims)
+	if err != nil {
+		return "", fmt.Errorf("the OIDC token isn't a JWT: %v", err)
+	}
+	if claims.Email != "" {
+		return claims.Email, nil
+	}
+	if claims.Subject == "" {
+		return "", errors.New("the OIDC token has no subject")
+	}
+	return claims.Subject, nil
+}
@@ -0,0 +1,356 @@
+package cosign
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociConfigMediaType   = "application/vnd.oci.image.config.v1+json"
+)
+
+// Repository is a repository of a registry, e.g. quay.io and myorg/edge.
+type Repository struct {
+	Host string
+	Path string
+}
+
+// ParseImage parses the repository out of the name of an image, which may
+// carry a tag or a digest. Names without a registry are on docker hub, like
+// docker would have it.
+func ParseImage(image string) (Repository, error) {
+	name, _, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	host, path, ok := strings.Cut(name, "/")
+	if !ok || !(strings.ContainsAny(host, ".:") || host == "localhost") {
+		host, path = "index.docker.io", name
+		if !strings.Contains(path, "/") {
+			path = "library/" + path
+		}
+	} else if host == "docker.io" {
+		host = "index.docker.io"
+	}
+	if host == "" || path == "" {
+		return Repository{}, fmt.Errorf("invalid image name %q", image)
+	}
+	return Repository{Host: host, Path: path}, nil
+}
+
+// Name is the repository as cosign refers to it.
+func (r Repository) Name() string {
+	return r.Host + "/" + r.Path
+}
+
+func (r Repository) endpoint() string {
+	if r.Host == "index.docker.io" {
+		return "https://registry-1.docker.io"
+	}
+	return "https://" + r.Host
+}
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Size        int64             `json:"size"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Config        descriptor   `json:"config"`
+	Layers        []descriptor `json:"layers"`
+}
+
+// Registry pushes signatures to registries with the credentials of the org
+// whose images they sign.
+type Registry struct {
+	Username string
+	Password string
+	Client   *http.Client
+
+	token string
+}
+
+// Attach stores sig as a signature of the manifest with digest of image,
+// next to the signatures which are already there, and returns the reference
+// it's stored at.
+func (r *Registry) Attach(ctx context.Context, image, digest string, sig *Signature) (string, error) {
+	repo, err := ParseImage(image)
+	if err != nil {
+		return "", err
+	}
+	tag, err := SignatureTag(digest)
+	if err != nil {
+		return "", err
+	}
+
+	layer := descriptor{
+		MediaType: SimpleSigningMediaType,
+		Size:      int64(len(sig.Payload)),
+		Digest:    digestOf(sig.Payload),
+		Annotations: map[string]string{
+			signatureAnnotation: base64.StdEncoding.EncodeToString(sig.Signature),
+		},
+	}
+	if sig.Certificate != nil {
+		layer.Annotations[certificateAnnotation] = string(sig.Certificate)
+		layer.Annotations[chainAnnotation] = string(sig.Chain)
+	}
+	layers, err := r.signatureLayers(ctx, repo, tag)
+	if err != nil {
+		return "", err
+	}
+	var kept []descriptor
+	for _, l := range layers {
+		if l.Digest != layer.Digest || l.Annotations[signatureAnnotation] != layer.Annotations[signatureAnnotation] {
+			kept = append(kept, l)
+		}
+	}
+	layers = append(kept, layer)
+
+	var diffIds []string
+	for _, l := range layers {
+		diffIds = append(diffIds, l.Digest)
+	}
+	config, err := json.Marshal(map[string]interface{}{
+		"architecture": "",
+		"os":           "",
+		"config":       map[string]interface{}{},
+		"rootfs": map[string]interface{}{
+			"type":     "layers",
+			"diff_ids": diffIds,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	err = r.pushBlob(ctx, repo, sig.Payload)
+	if err != nil {
+		return "", err
+	}
+	err = r.pushBlob(ctx, repo, config)
+	if err != nil {
+		return "", err
+	}
+	m, err := json.Marshal(manifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestMediaType,
+		Config: descriptor{
+			MediaType: ociConfigMediaType,
+			Size:      int64(len(config)),
+			Digest:    digestOf(config),
+		},
+		Layers: layers,
+	})
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.do(ctx, repo, http.MethodPut, repo.endpoint()+"/v2/"+repo.Path+"/manifests/"+tag, ociManifestMediaType, m)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", registryError("pushing the signature manifest", resp)
+	}
+	return repo.Name() + ":" + tag, nil
+}
+
+// signatureLayers returns the signatures at tag, if there are any already.
+func (r *Registry) signatureLayers(ctx context.Context, repo Repository, tag string) ([]descriptor, error) {
+	resp, err := r.do(ctx, repo, http.MethodGet, repo.endpoint()+"/v2/"+repo.Path+"/manifests/"+tag, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, registryError("fetching the signatures", resp)
+	}
+	var m manifest
+	err = json.NewDecoder(resp.Body).Decode(&m)
+	if err != nil {
+		return nil, err
+	}
+	var layers []descriptor
+	for _, l := range m.Layers {
+		if l.MediaType == SimpleSigningMediaType {
+			layers = append(layers, l)
+		}
+	}
+	return layers, nil
+}
+
+func (r *Registry) pushBlob(ctx context.Context, repo Repository, blob []byte) error {
+	digest := digestOf(blob)
+	resp, err := r.do(ctx, repo, http.MethodHead, repo.endpoint()+"/v2/"+repo.Path+"/blobs/"+digest, "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	resp, err = r.do(ctx, repo, http.MethodPost, repo.endpoint()+"/v2/"+repo.Path+"/blobs/uploads/", "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return registryError("starting a blob upload", resp)
+	}
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return err
+	}
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+
+	resp, err = r.do(ctx, repo, http.MethodPut, location.String(), "application/octet-stream", blob)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return registryError("uploading a blob", resp)
+	}
+	return nil
+}
+
+// do sends a request to the registry, authenticating as the registry asks
+// to if it refuses it without.
+func (r *Registry) do(ctx context.Context, repo Repository, method, target, contentType string, body []byte) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if method == http.MethodGet {
+			req.Header.Set("Accept", ociManifestMediaType)
+		}
+		if r.token != "" {
+			req.Header.Set("Authorization", "Bearer "+r.token)
+		} else if r.Username != "" {
+			req.SetBasicAuth(r.Username, r.Password)
+		}
+		return r.client().Do(req)
+	}
+
+	resp, err := send()
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || r.token != "" {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	scheme, params := parseChallenge(challenge)
+	if !strings.EqualFold(scheme, "bearer") {
+		return nil, fmt.Errorf("the registry refused the credentials (%s)", challenge)
+	}
+	err = r.fetchToken(ctx, repo, params)
+	if err != nil {
+		return nil, err
+	}
+	return send()
+}
+
+func (r *Registry) fetchToken(ctx context.Context, repo Repository, params map[string]string) error {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Scheme == "" {
+		return fmt.Errorf("the registry asked for a token from an invalid realm %q", params["realm"])
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull,push", repo.Path))
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if r.Username != "" {
+		req.SetBasicAuth(r.Username, r.Password)
+	}
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return registryError("fetching a token", resp)
+	}
+	var result struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return err
+	}
+	r.token = result.Token
+	if r.token == "" {
+		r.token = result.AccessToken
+	}
+	if r.token == "" {
+		return fmt.Errorf("the registry didn't issue a token")
+	}
+	return nil
+}
+
+func (r *Registry) client() *http.Client {
+	if r.Client != nil {
+		return r.Client
+	}
+	return http.DefaultClient
+}
+
+// parseChallenge splits a WWW-Authenticate header, e.g. Bearer
+// realm="https://quay.io/v2/auth",service="quay.io",scope="repository:myorg/edge:pull,push".
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(challenge, " ")
+	params := map[string]string{}
+	for rest != "" {
+		var k, v string
+		k, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			v, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			v, rest, _ = strings.Cut(rest, ",")
+		}
+		if k != "" {
+			params[strings.ToLower(strings.TrimSpace(k))] = v
+		}
+	}
+	return scheme, params
+}
+
+func digestOf(blob []byte) string {
+	sum := sha256.Sum256(blob)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func registryError(doing string, resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s: the registry responded with %d: %s", doing, resp.StatusCode, bytes.TrimSpace(msg))
+}
@@ -4,16 +4,51 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/logger"
+	"github.com/osbuild/image-builder/internal/prometheus"
 )
 
 // ComposeNotFoundError occurs when no compose request is found for a user.
 var ComposeNotFoundError = errors.New("Compose not found")
 var CloneNotFoundError = errors.New("Clone not found")
+var ComposeReplicationNotFoundError = errors.New("Compose replication not found")
+var ComposeEncryptionNotFoundError = errors.New("Compose encryption not found")
+var APITokenNotFoundError = errors.New("API token not found")
+var QuotaNotFoundError = errors.New("Quota not found")
+var QueuedComposeNotFoundError = errors.New("Queued compose not found")
+var QuotaBoostNotFoundError = errors.New("Quota boost not found")
+var ComposeStatusNotFoundError = errors.New("Compose status not found")
+var ComposeNotPendingApprovalError = errors.New("Compose isn't pending approval")
+var WebhookNotFoundError = errors.New("Webhook not found")
+var LaunchNotFoundError = errors.New("Launch not found")
+var AWXSettingsNotFoundError = errors.New("AWX settings not found")
+var ComposeSignatureNotFoundError = errors.New("Compose signature not found")
+var ComposeSBOMNotFoundError = errors.New("Compose SBOM not found")
+var ArtifactSigningSettingsNotFoundError = errors.New("Artifact signing settings not found")
+var CommitSignatureNotFoundError = errors.New("Commit signature not found")
+var SigningKeyNotFoundError = errors.New("Signing key not found")
+var ComplianceExportSettingsNotFoundError = errors.New("Compliance export settings not found")
+var PulpSettingsNotFoundError = errors.New("Pulp settings not found")
+var IdempotencyKeyNotFoundError = errors.New("Idempotency key not found")
+
+// IdempotencyKeyExistsError occurs when a compose is stored with an
+// idempotency key another compose of the org already has.
+var IdempotencyKeyExistsError = errors.New("Idempotency key exists")
+
+var VulnerabilityScanNotFoundError = errors.New("Vulnerability scan not found")
+
+// SQLSTATE of inserting a row which violates a unique index
+const uniqueViolation = "23505"
 
 type dB struct {
 	Pool *pgxpool.Pool
@@ -24,25 +59,646 @@ type ComposeEntry struct {
 	Request   json.RawMessage
 	CreatedAt time.Time
 	ImageName *string
+	// Id of the job in composer, differs from Id if the compose was
+	// queued.
+	ComposerId uuid.UUID
+	// Name of the composer backend the compose was sent to, empty for the
+	// default one.
+	ComposerBackend string
+	OrgId           string
+}
+
+// ComposeCursor is the position of a compose in the composes of an org,
+// newest first, which a page of them continues after.
+type ComposeCursor struct {
+	CreatedAt time.Time
+	Id        uuid.UUID
+}
+
+// SupportComposeEntry is a compose including the details of who built it,
+// for support tooling which looks up composes across orgs.
+type SupportComposeEntry struct {
+	ComposeEntry
+	AccountNumber string
+	Email         *string
+	Deleted       bool
+}
+
+// CachedComposeStatus is the status composer last reported for a compose, as
+// it was returned. Fresh is set if it was refreshed within the max age it was
+// looked up with.
+type CachedComposeStatus struct {
+	Status      json.RawMessage
+	RefreshedAt time.Time
+	Fresh       bool
+}
+
+type ComposeEventEntry struct {
+	Status string
+	// Why a compose failed, if known.
+	Reason    *string
+	CreatedAt time.Time
 }
 
 type CloneEntry struct {
 	Id        uuid.UUID
 	Request   json.RawMessage
 	CreatedAt time.Time
+	// Compose it's a clone of and the composer backend of that compose,
+	// only set by GetClone and GetUnnotifiedClonesSince.
+	ComposeId       uuid.UUID
+	ComposerBackend string
+}
+
+// ComposeReplicationEntry is an additional region the AMI of a compose is
+// copied to once it succeeded.
+type ComposeReplicationEntry struct {
+	ComposeId         uuid.UUID
+	Region            string
+	ShareWithAccounts []string
+	// nil until composer accepted the clone
+	CloneId *uuid.UUID
+	// the status of the clone last reported, nil until it was queried
+	Status            json.RawMessage
+	StatusRefreshedAt *time.Time
+	Fresh             bool
+}
+
+// ComposeEncryptionEntry is the KMS key the AMI of a compose is copied with
+// once it succeeded, the copy is shared instead of the image composer
+// uploaded.
+type ComposeEncryptionEntry struct {
+	ComposeId         uuid.UUID
+	Region            string
+	KmsKeyId          string
+	ShareWithAccounts []string
+	// the image composer uploaded and its encrypted copy, nil until the
+	// copy was requested
+	SourceImageId *string
+	ImageId       *string
+	Status        string
+	LastError     *string
+}
+
+// LaunchEntry is a launch of a compose, the provisioning service tracks it
+// as the reservation.
+type LaunchEntry struct {
+	ReservationId int64
+	ComposeId     uuid.UUID
+	Provider      string
+	Request       json.RawMessage
+	CreatedAt     time.Time
+}
+
+type ArtifactEntry struct {
+	Filename string
+	// Only known for files which can be downloaded, not for cloud images
+	Size         *int64
+	Sha256       *string
+	CloudImageId *string
+	// ASCII armored detached signature, if the artifact was signed
+	Signature *string
+}
+
+// OrgAnalyticsEntry sums up the composes an org created in a period. The
+// artifacts are the ones of its composes which were stored, deleted composes
+// included.
+type OrgAnalyticsEntry struct {
+	OrgId         string
+	PeriodStart   time.Time
+	Builds        int
+	Succeeded     int
+	Failed        int
+	Artifacts     int
+	ArtifactBytes int64
+}
+
+// UsageReportEntry sums up the composes of an org which were created in a
+// month, with the image type and upload target of their first image request.
+// The build time of a compose lasts from its creation until its first
+// success or failure event, composes which haven't finished don't add to it.
+type UsageReportEntry struct {
+	OrgId         string
+	Month         time.Time
+	ImageType     string
+	UploadTarget  string
+	Builds        int
+	Succeeded     int
+	Failed        int
+	BuildSeconds  int64
+	ArtifactBytes int64
+}
+
+// BuildDurationEntry are the percentiles of how long the composes of a
+// distribution, image type and architecture took from their creation until
+// their first success event. The image type and architecture are the ones of
+// their first image request.
+type BuildDurationEntry struct {
+	Distribution string
+	ImageType    string
+	Arch         string
+	Builds       int
+	P50Seconds   float64
+	P95Seconds   float64
+}
+
+// CommitSignatureEntry is the signature of the ostree commit of a compose.
+type CommitSignatureEntry struct {
+	Commit    string
+	Signature []byte
+}
+
+// VulnerabilityScanEntry is the outcome of scanning the packages of a compose.
+type VulnerabilityScanEntry struct {
+	// name of the scanner, e.g. trivy
+	Scanner   string
+	Findings  json.RawMessage
+	ScannedAt time.Time
+}
+
+// APITokenEntry is an api token along with the org and user who created it,
+// requests authenticated with the token act on behalf of them.
+type APITokenEntry struct {
+	Id            uuid.UUID
+	OrgId         string
+	AccountNumber *string
+	Name          string
+	ReadOnly      bool
+	UserId        *string
+	// Username of the user
+	CreatedBy  *string
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+// QuotaEntry limits the builds of an org, nil limits aren't enforced.
+type QuotaEntry struct {
+	OrgId            string
+	BuildsPerDay     *int
+	BuildsPerMonth   *int
+	ConcurrentBuilds *int
+	UpdatedAt        time.Time
+}
+
+// BuildUsageEntry is how much of its quota an org used up, the builds
+// reserved by others included.
+type BuildUsageEntry struct {
+	BuildsToday      int
+	BuildsThisMonth  int
+	BuildsInProgress int
+	// whether composes of the org are waiting in the queue
+	Queued bool
+}
+
+// QuotaBoostEntry raises the limits of the quota of an org until it expires.
+type QuotaBoostEntry struct {
+	Id               uuid.UUID
+	OrgId            string
+	BuildsPerDay     int
+	BuildsPerMonth   int
+	ConcurrentBuilds int
+	Reason           string
+	CreatedBy        string
+	CreatedAt        time.Time
+	ExpiresAt        time.Time
+}
+
+// QueuedComposeEntry is a compose waiting for the org to have less builds in
+// progress, or for a second user to approve it. Composes which composer
+// refused once submitted, or which were rejected, have an error.
+type QueuedComposeEntry struct {
+	ComposeId       uuid.UUID
+	OrgId           string
+	ComposerRequest json.RawMessage
+	CreatedAt       time.Time
+	Error           *string
+	PendingApproval bool
+	RequestedBy     *string
+	ReviewedBy      *string
+}
+
+// ComposeRelations are the rows of a compose stored along with it: the
+// additional regions its AMI is copied to and the accounts they're shared
+// with, its encryption, its webhook, and the address its outcome is emailed
+// to. Each is optional.
+type ComposeRelations struct {
+	ReplicationRegions []string
+	ShareWithAccounts  []string
+	Encryption         *ComposeEncryptionEntry
+	Webhook            *WebhookEntry
+	NotifyEmail        *string
+	// IdempotencyKey is the key the compose was requested with, another
+	// compose of the org can't be stored with the same one.
+	IdempotencyKey *IdempotencyKeyEntry
+}
+
+// IdempotencyKeyEntry is the idempotency key of a compose, with the hash of
+// the request it was created for.
+type IdempotencyKeyEntry struct {
+	ComposeId   uuid.UUID
+	Key         string
+	RequestHash string
+}
+
+// SealedSecretEntry is a secret encrypted as a keystore envelope, e.g. the
+// secret of a webhook.
+type SealedSecretEntry struct {
+	KEKId            string
+	EncryptedDataKey []byte
+	Ciphertext       []byte
+}
+
+// AuditLogEntry records a mutating api call, who made it, on which resource
+// and with which outcome.
+type AuditLogEntry struct {
+	Id     int64
+	OrgId  string
+	UserId string
+	Email  string
+	Method string
+	// Route of the call, e.g. /api/image-builder/v1/composes/:composeId.
+	Path       string
+	ResourceId *string
+	// Hex encoded sha256 of the request body.
+	RequestDigest string
+	Status        int
+	RemoteIP      string
+	CreatedAt     time.Time
+}
+
+// WebhookEntry is an endpoint notified when composes and clones of an org
+// finish, or only those of ComposeId if it's set.
+type WebhookEntry struct {
+	Id        uuid.UUID
+	OrgId     string
+	ComposeId *uuid.UUID
+	URL       string
+	Secret    SealedSecretEntry
+	CreatedAt time.Time
+}
+
+// Statuses of webhook deliveries, pending ones are attempted again until
+// they're delivered or failed too often.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDeliveryEntry is an event sent, or still to be sent, to a webhook.
+type WebhookDeliveryEntry struct {
+	Id            uuid.UUID
+	WebhookId     uuid.UUID
+	Event         string
+	ResourceId    uuid.UUID
+	Payload       json.RawMessage
+	Status        string
+	Attempts      int
+	ResponseCode  *int
+	LastError     *string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time
+	// URL, secret and org of the webhook, only set by
+	// ClaimWebhookDeliveries.
+	URL    string
+	Secret SealedSecretEntry
+	OrgId  string
+}
+
+// Statuses of outbox entries, failed ones ran out of attempts.
+const (
+	OutboxPending    = "pending"
+	OutboxDispatched = "dispatched"
+	OutboxFailed     = "failed"
+)
+
+// Statuses of AWX jobs, pending ones failed to launch and are attempted again.
+const (
+	AWXJobPending  = "pending"
+	AWXJobLaunched = "launched"
+	AWXJobFailed   = "failed"
+)
+
+// The states of signing the container image of a compose, pending ones are
+// attempted again.
+const (
+	ComposeSignaturePending = "pending"
+	ComposeSignatureSigned  = "signed"
+	ComposeSignatureFailed  = "failed"
+)
+
+// The states of encrypting the AMI of a compose, pending and copying ones are
+// attempted again.
+const (
+	ComposeEncryptionPending = "pending"
+	ComposeEncryptionCopying = "copying"
+	ComposeEncryptionDone    = "done"
+	ComposeEncryptionFailed  = "failed"
+)
+
+// ComposeSignatureEntry is the cosign signature of the container image a
+// compose pushed.
+type ComposeSignatureEntry struct {
+	// where the signature is stored in the registry of the image, only set
+	// once it's signed
+	Reference *string
+	Status    string
+	Attempts  int
+	LastError *string
+}
+
+// AWXSettingsEntry is the AWX job template an org launches once one of its
+// composes succeeded.
+type AWXSettingsEntry struct {
+	OrgId         string
+	URL           string
+	Token         SealedSecretEntry
+	JobTemplateId int
+	UpdatedAt     time.Time
+}
+
+// ArtifactSigningSettingsEntry is how the artifacts of the composes of an org
+// are signed, with its artifact key or by its signing service. The token of
+// the service is its signing_service key.
+type ArtifactSigningSettingsEntry struct {
+	OrgId string
+	// Artifacts are signed with the artifact key if it's nil
+	ServiceURL *string
+	UpdatedAt  time.Time
+}
+
+// PlaintextArtifactSigningEntry is the private key and service token of
+// artifact signing settings stored before they were signing keys.
+type PlaintextArtifactSigningEntry struct {
+	OrgId string
+	// ASCII armored OpenPGP private key
+	PrivateKey   *string
+	ServiceToken *string
+}
+
+// PulpSettingsEntry is the Pulp instance the ostree commits of an org are
+// imported to by default.
+type PulpSettingsEntry struct {
+	OrgId         string
+	ServerAddress string
+	Repository    *string
+	Basepath      *string
+	UpdatedAt     time.Time
+}
+
+// ComplianceExportSettingsEntry is the bucket of an org its audit log and the
+// provenance of its composes are exported to.
+type ComplianceExportSettingsEntry struct {
+	OrgId  string
+	Bucket string
+	Region string
+	// Prepended to the keys of the exported objects, may be empty
+	Prefix string
+	// What the verification object in the bucket has to hold, it proves
+	// the org owns the bucket
+	VerificationToken string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// ComplianceExportEntry is a window of the audit log and of the composes of
+// an org which was exported, or is still to be.
+type ComplianceExportEntry struct {
+	OrgId       string
+	WindowStart time.Time
+	WindowEnd   time.Time
+	Attempts    int
+	// Why the last attempt failed
+	LastError       *string
+	AuditLogEntries int
+	Composes        int
+	ExportedAt      *time.Time
+}
+
+// SigningKeyEntry is a key an org signs with, the key itself is only stored
+// encrypted with envelope encryption.
+type SigningKeyEntry struct {
+	Id      uuid.UUID
+	OrgId   string
+	Purpose string
+	// Of OpenPGP keys
+	Fingerprint *string
+	PublicKey   *string
+	// Id of the key encryption key the data key is encrypted with
+	KEKId            string
+	EncryptedDataKey []byte
+	Ciphertext       []byte
+	CreatedAt        time.Time
+	// The key which replaced this one when it was rotated
+	ReplacedBy *uuid.UUID
+	RotatedAt  *time.Time
+	RevokedAt  *time.Time
+}
+
+// SigningKeyUsageEntry is something a key signed.
+type SigningKeyUsageEntry struct {
+	Id        int64
+	KeyId     uuid.UUID
+	Operation string
+	// e.g. the compose whose artifacts were signed
+	ResourceId *uuid.UUID
+	UsedAt     time.Time
+}
+
+// AWXJobEntry is the job launched for a compose, or the outcome of the last
+// attempt to launch it.
+type AWXJobEntry struct {
+	ComposeId     uuid.UUID
+	JobTemplateId int
+	Status        string
+	JobId         *int
+	Attempts      int
+	LastError     *string
+	CreatedAt     time.Time
+	LaunchedAt    *time.Time
+}
+
+// OrgEventEntry is an event of a compose or clone of an org, streamed to the
+// clients watching the org.
+type OrgEventEntry struct {
+	Id         int64
+	OrgId      string
+	Event      string
+	ResourceId uuid.UUID
+	Payload    json.RawMessage
+	CreatedAt  time.Time
+}
+
+// OutboxEntry is an event of a compose to dispatch to a sink. The id is
+// generated when it's inserted if it's left empty.
+type OutboxEntry struct {
+	Id        uuid.UUID
+	Sink      string
+	Event     string
+	ComposeId uuid.UUID
+	Payload   json.RawMessage
+	Attempts  int
+	CreatedAt time.Time
 }
 
 type DB interface {
-	InsertCompose(jobId uuid.UUID, accountNumber, email, orgId string, imageName *string, request json.RawMessage) error
+	// Ping checks that a connection to the database can be used.
+	Ping(ctx context.Context) error
+
+	InsertCompose(jobId uuid.UUID, accountNumber, email, orgId string, imageName *string, request json.RawMessage, outbox ...OutboxEntry) error
+	InsertComposeWithRelations(jobId uuid.UUID, accountNumber, email, orgId string, imageName *string, request json.RawMessage, relations *ComposeRelations, outbox ...OutboxEntry) error
+	GetIdempotencyKey(orgId, key string) (*IdempotencyKeyEntry, error)
 	GetComposes(orgId string, since time.Duration, limit, offset int, ignoreImageTypes []string) ([]ComposeEntry, int, error)
+	GetComposesAfter(orgId string, since time.Duration, limit int, after *ComposeCursor, ignoreImageTypes []string) ([]ComposeEntry, error)
 	GetCompose(jobId uuid.UUID, orgId string) (*ComposeEntry, error)
 	GetComposeImageType(jobId uuid.UUID, orgId string) (string, error)
 	CountComposesSince(orgId string, duration time.Duration) (int, error)
+	CountUnfinishedComposesSince(orgId string, duration time.Duration) (int, error)
+	GetUnfinishedComposesSince(orgId string, duration time.Duration) ([]ComposeEntry, error)
+	GetOrgsWithUnfinishedComposesSince(duration time.Duration) ([]string, error)
+	ClaimComposeStatusSync(jobId uuid.UUID, claimFor time.Duration) (bool, error)
 	DeleteCompose(jobId uuid.UUID, orgId string) error
+	GetComposeForSupport(jobId uuid.UUID) (*SupportComposeEntry, error)
+	SearchComposesForSupport(ami, imageName *string, limit int) ([]SupportComposeEntry, error)
+
+	InsertComposeEvent(jobId uuid.UUID, status string, reason *string, outbox ...OutboxEntry) (bool, error)
+	GetComposeEvents(jobId uuid.UUID) ([]ComposeEventEntry, error)
+	SetCachedComposeStatus(jobId uuid.UUID, status json.RawMessage) error
+	SetComposeBackend(jobId uuid.UUID, backend string) error
+	SetComposeNotifyEmail(jobId uuid.UUID, email string) error
+	GetComposeNotifyEmail(jobId uuid.UUID) (*string, error)
+	GetCachedComposeStatus(jobId uuid.UUID, maxAge time.Duration) (*CachedComposeStatus, error)
 
 	InsertClone(composeId, cloneId uuid.UUID, request json.RawMessage) error
 	GetClonesForCompose(composeId uuid.UUID, orgId string, limit, offset int) ([]CloneEntry, int, error)
 	GetClone(id uuid.UUID, orgId string) (*CloneEntry, error)
+	InsertComposeReplications(composeId uuid.UUID, regions, shareWithAccounts []string) error
+	GetComposeReplications(composeId uuid.UUID, maxAge time.Duration) ([]ComposeReplicationEntry, error)
+	ClaimComposeReplication(composeId uuid.UUID, region string) (bool, error)
+	SetComposeReplicationClone(composeId uuid.UUID, region string, cloneId *uuid.UUID) error
+	SetComposeReplicationStatus(composeId uuid.UUID, region string, status json.RawMessage) error
+	InsertComposeEncryption(encryption ComposeEncryptionEntry) error
+	GetComposeEncryption(composeId uuid.UUID) (*ComposeEncryptionEntry, error)
+	ClaimComposeEncryption(composeId uuid.UUID) (bool, error)
+	SetComposeEncryption(encryption ComposeEncryptionEntry) error
+	InsertLaunch(composeId uuid.UUID, reservationId int64, provider string, request json.RawMessage) error
+	GetLaunchesForCompose(composeId uuid.UUID, orgId string, limit, offset int) ([]LaunchEntry, int, error)
+	GetLaunch(reservationId int64, orgId string) (*LaunchEntry, error)
+
+	GetAWSShareAllowList(orgId string) ([]string, error)
+	GetIPAllowList(orgId string) ([]string, error)
+	SetIPAllowList(orgId string, cidrs []string) error
+	GetUploadTargetPolicy(orgId string) (json.RawMessage, error)
+	SetUploadTargetPolicy(orgId string, targets json.RawMessage) error
+
+	InsertComposeArtifacts(composeId uuid.UUID, artifacts []ArtifactEntry) error
+	GetComposeArtifacts(composeId uuid.UUID, orgId string) ([]ArtifactEntry, error)
+	SetComposeArtifactSignature(composeId uuid.UUID, filename, signature string) error
+	SetComposeSignatureResult(composeId uuid.UUID, status string, reference, lastError *string) error
+	GetComposeSignature(composeId uuid.UUID, orgId string) (*ComposeSignatureEntry, error)
+	InsertCommitSignature(composeId uuid.UUID, signature CommitSignatureEntry) error
+	GetCommitSignature(composeId uuid.UUID, orgId string) (*CommitSignatureEntry, error)
+	InsertVulnerabilityScan(composeId uuid.UUID, scan VulnerabilityScanEntry) error
+	GetVulnerabilityScan(composeId uuid.UUID, orgId string) (*VulnerabilityScanEntry, error)
+	InsertComposeSBOM(composeId uuid.UUID, format string, document json.RawMessage) error
+	GetComposeSBOM(composeId uuid.UUID, orgId, format string) (json.RawMessage, error)
+
+	InsertAPIToken(token APITokenEntry, tokenHash string) error
+	GetAPITokens(orgId string) ([]APITokenEntry, error)
+	// UseAPIToken returns the valid token with the hash and records that
+	// it was used.
+	UseAPIToken(tokenHash string) (*APITokenEntry, error)
+	RevokeAPIToken(id uuid.UUID, orgId string) error
+
+	GetQuota(orgId string) (*QuotaEntry, error)
+	SetQuota(quota QuotaEntry) error
+	InsertQuotaBoost(boost QuotaBoostEntry, expiresIn time.Duration) (*QuotaBoostEntry, error)
+	GetQuotaBoosts(orgId string) ([]QuotaBoostEntry, error)
+	DeleteQuotaBoost(id uuid.UUID, orgId string) error
+	ReserveBuilds(id uuid.UUID, orgId string, builds int, day, month, unfinished time.Duration) (*BuildUsageEntry, error)
+	ReleaseBuilds(id uuid.UUID) error
+
+	InsertQueuedCompose(jobId uuid.UUID, accountNumber, email, orgId string, imageName *string, request, composerRequest json.RawMessage, pendingApproval bool, relations *ComposeRelations, outbox ...OutboxEntry) error
+	GetQueuedCompose(jobId uuid.UUID) (*QueuedComposeEntry, error)
+	GetOrgsWithQueuedComposes() ([]string, error)
+	ClaimQueuedComposes(orgId string, limit int, concurrentBuilds *int, unfinished time.Duration) ([]QueuedComposeEntry, error)
+	CompleteQueuedCompose(jobId, composerId uuid.UUID) error
+	FailQueuedCompose(jobId uuid.UUID, reason string, outbox ...OutboxEntry) error
+	CountQueuedComposes(orgId string) (int, error)
+	ApproveQueuedCompose(jobId uuid.UUID, reviewer string) error
+	RejectQueuedCompose(jobId uuid.UUID, reviewer, reason string, outbox ...OutboxEntry) error
+
+	GetApprovalRequired(orgId string) (bool, error)
+	SetApprovalRequired(orgId string, required bool) error
+	GetSecretScanningMode(orgId string) (string, error)
+	SetSecretScanningMode(orgId, mode string) error
+	GetRepositorySignaturesRequired(orgId string) (bool, error)
+	SetRepositorySignaturesRequired(orgId string, required bool) error
+
+	GetStorageUsage(orgId string) (int64, error)
+	GetOrgAnalytics(orgId string, since, until time.Time, period string) ([]OrgAnalyticsEntry, error)
+	GetUsageReport(orgId string, since, until time.Time) ([]UsageReportEntry, error)
+	GetBuildDurations(since, until time.Time) ([]BuildDurationEntry, error)
+
+	InsertAuditLogEntry(entry AuditLogEntry) error
+	GetAuditLog(orgId string, limit, offset int) ([]AuditLogEntry, int, error)
+	GetAuditLogBetween(orgId string, since, until time.Time) ([]AuditLogEntry, error)
+
+	InsertWebhook(webhook WebhookEntry) error
+	GetWebhooks(orgId string) ([]WebhookEntry, error)
+	GetWebhook(id uuid.UUID, orgId string) (*WebhookEntry, error)
+	DeleteWebhook(id uuid.UUID, orgId string) error
+	GetOrgsWithWebhooks(since time.Duration) ([]string, error)
+	GetUnnotifiedClonesSince(orgId string, since time.Duration) ([]CloneEntry, error)
+	InsertWebhookDeliveries(composeId uuid.UUID, event string, resourceId uuid.UUID, payload json.RawMessage) (int, error)
+	ClaimWebhookDeliveries(limit int) ([]WebhookDeliveryEntry, error)
+	SetWebhookDeliveryResult(id uuid.UUID, status string, responseCode *int, lastError *string, retryIn time.Duration) error
+	GetWebhookDeliveries(webhookId uuid.UUID, orgId string, limit, offset int) ([]WebhookDeliveryEntry, int, error)
+
+	ClaimOutboxEntries(limit int) ([]OutboxEntry, error)
+	SetOutboxEntryResult(id uuid.UUID, status string, lastError *string, retryIn time.Duration) error
+	DeleteOutboxEntriesBefore(age time.Duration) (int, error)
+
+	GetAWXSettings(orgId string) (*AWXSettingsEntry, error)
+	SetAWXSettings(settings AWXSettingsEntry) error
+	DeleteAWXSettings(orgId string) error
+	SetAWXJobResult(composeId uuid.UUID, orgId string, jobTemplateId int, status string, jobId *int, lastError *string) error
+	GetAWXJobs(orgId string, limit, offset int) ([]AWXJobEntry, int, error)
+
+	GetArtifactSigningSettings(orgId string) (*ArtifactSigningSettingsEntry, error)
+	SetArtifactSigningSettings(settings ArtifactSigningSettingsEntry) error
+	DeleteArtifactSigningSettings(orgId string) error
+	GetPlaintextArtifactSigningSecrets() ([]PlaintextArtifactSigningEntry, error)
+	MoveArtifactSigningSecrets(orgId string, keys []SigningKeyEntry) error
+
+	GetPulpSettings(orgId string) (*PulpSettingsEntry, error)
+	SetPulpSettings(settings PulpSettingsEntry) error
+	DeletePulpSettings(orgId string) error
+
+	InsertSigningKey(key SigningKeyEntry) error
+	GetSigningKeys(orgId string) ([]SigningKeyEntry, error)
+	GetSigningKey(id uuid.UUID, orgId string) (*SigningKeyEntry, error)
+	GetSigningKeyInUse(orgId, purpose string) (*SigningKeyEntry, error)
+	RotateSigningKey(id uuid.UUID, orgId string, key SigningKeyEntry) error
+	RevokeSigningKey(id uuid.UUID, orgId string) error
+	InsertSigningKeyUsage(usage SigningKeyUsageEntry) error
+	GetSigningKeyUsage(keyId uuid.UUID, orgId string, limit, offset int) ([]SigningKeyUsageEntry, int, error)
+
+	GetComplianceExportSettings(orgId string) (*ComplianceExportSettingsEntry, error)
+	SetComplianceExportSettings(settings ComplianceExportSettingsEntry) error
+	DeleteComplianceExportSettings(orgId string) error
+	GetAllComplianceExportSettings() ([]ComplianceExportSettingsEntry, error)
+	GetComposesFinishedBetween(orgId string, since, until time.Time) ([]ComposeEntry, error)
+	ClaimComplianceExport(orgId string, windowStart, windowEnd time.Time, claimFor time.Duration) (bool, error)
+	SetComplianceExportResult(orgId string, windowStart time.Time, auditLogEntries, composes int, lastError *string, retryIn time.Duration) error
+	GetComplianceExports(orgId string, limit, offset int) ([]ComplianceExportEntry, int, error)
+
+	InsertOrgEvent(composeId uuid.UUID, event string, resourceId uuid.UUID, payload json.RawMessage) error
+	GetOrgEventsAfter(afterId int64, limit int) ([]OrgEventEntry, error)
+	GetLastOrgEventId() (int64, error)
+	DeleteOrgEventsBefore(age time.Duration) (int, error)
 }
 
 const (
@@ -51,7 +707,7 @@ const (
 		VALUES ($1, $2, CURRENT_TIMESTAMP, $3, $4, $5, $6)`
 
 	sqlGetComposes = `
-	        SELECT job_id, request, created_at, image_name
+	        SELECT job_id, request, created_at, image_name, COALESCE(composer_job_id, job_id), COALESCE(composer_backend, '')
 	        FROM composes
 		WHERE org_id = $1
 		AND CURRENT_TIMESTAMP - created_at <= $2
@@ -60,8 +716,29 @@ const (
 		ORDER BY created_at DESC
 		LIMIT $4 OFFSET $5`
 
+	sqlGetComposesAfter = `
+		SELECT job_id, request, created_at, image_name, COALESCE(composer_job_id, job_id), COALESCE(composer_backend, '')
+		FROM composes
+		WHERE org_id = $1
+		AND CURRENT_TIMESTAMP - created_at <= $2
+		AND ($3::text[] is NULL OR request->'image_requests'->0->>'image_type' <> ALL($3))
+		AND ($4::timestamp IS NULL OR (created_at, job_id) < ($4::timestamp, $5::uuid))
+		AND deleted = FALSE
+		ORDER BY created_at DESC, job_id DESC
+		LIMIT $6`
+
+	sqlSetComposeIdempotencyKey = `
+		UPDATE composes
+		SET idempotency_key = $2, idempotency_request_hash = $3
+		WHERE job_id = $1`
+
+	sqlGetIdempotencyKey = `
+		SELECT job_id, idempotency_key, idempotency_request_hash
+		FROM composes
+		WHERE org_id = $1 AND idempotency_key = $2`
+
 	sqlGetCompose = `
-		SELECT job_id, request, created_at, image_name
+		SELECT job_id, request, created_at, image_name, COALESCE(composer_job_id, job_id), COALESCE(composer_backend, '')
 		FROM composes
 		WHERE org_id=$1 AND job_id=$2 AND deleted=FALSE`
 
@@ -81,16 +758,171 @@ const (
 		FROM composes
 		WHERE org_id=$1 AND CURRENT_TIMESTAMP - created_at <= $2`
 
+	sqlCountUnfinishedComposesSince = `
+		SELECT COUNT(*)
+		FROM composes
+		WHERE org_id=$1 AND CURRENT_TIMESTAMP - created_at <= $2
+		AND NOT EXISTS (
+			SELECT 1
+			FROM compose_events
+			WHERE compose_events.compose_id = composes.job_id
+			AND compose_events.status IN ('success', 'failure'))
+		AND NOT EXISTS (
+			SELECT 1
+			FROM compose_queue
+			WHERE compose_queue.compose_id = composes.job_id
+			AND (claimed_at IS NULL OR CURRENT_TIMESTAMP - claimed_at > $3 OR error IS NOT NULL))`
+
+	sqlGetUnfinishedComposesSince = `
+		SELECT job_id, request, created_at, image_name, COALESCE(composer_job_id, job_id), COALESCE(composer_backend, '')
+		FROM composes
+		WHERE org_id=$1 AND CURRENT_TIMESTAMP - created_at <= $2
+		AND NOT EXISTS (
+			SELECT 1
+			FROM compose_events
+			WHERE compose_events.compose_id = composes.job_id
+			AND compose_events.status IN ('success', 'failure'))
+		AND NOT EXISTS (
+			SELECT 1
+			FROM compose_queue
+			WHERE compose_queue.compose_id = composes.job_id)
+		ORDER BY created_at`
+
+	sqlGetOrgsWithUnfinishedComposesSince = `
+		SELECT DISTINCT org_id
+		FROM composes
+		WHERE CURRENT_TIMESTAMP - created_at <= $1
+		AND NOT EXISTS (
+			SELECT 1
+			FROM compose_events
+			WHERE compose_events.compose_id = composes.job_id
+			AND compose_events.status IN ('success', 'failure'))
+		AND NOT EXISTS (
+			SELECT 1
+			FROM compose_queue
+			WHERE compose_queue.compose_id = composes.job_id)`
+
+	sqlClaimComposeStatusSync = `
+		UPDATE composes
+		SET status_synced_at=CURRENT_TIMESTAMP
+		WHERE job_id=$1
+		AND (status_synced_at IS NULL OR CURRENT_TIMESTAMP - status_synced_at > $2)
+		RETURNING job_id`
+
 	sqlDeleteCompose = `
 		UPDATE composes
 		SET deleted = TRUE
 		WHERE org_id=$1 AND job_id=$2
         `
 
+	sqlGetComposeForSupport = `
+		SELECT job_id, request, created_at, image_name, COALESCE(composer_job_id, job_id), COALESCE(composer_backend, ''), org_id, account_number, email, deleted
+		FROM composes
+		WHERE job_id=$1`
+
+	sqlSearchComposesForSupport = `
+		SELECT job_id, request, created_at, image_name, COALESCE(composer_job_id, job_id), COALESCE(composer_backend, ''), org_id, account_number, email, deleted
+		FROM composes
+		WHERE ($1::jsonpath IS NULL OR status @@ $1::jsonpath)
+		AND ($2::varchar IS NULL OR image_name = $2)
+		ORDER BY created_at DESC, job_id DESC
+		LIMIT $3`
+
+	sqlInsertComposeEvent = `
+		INSERT INTO compose_events(compose_id, status, reason, created_at)
+		SELECT $1, $2::varchar, $3, CURRENT_TIMESTAMP
+		WHERE $2::varchar IS DISTINCT FROM (
+			SELECT status
+			FROM compose_events
+			WHERE compose_id=$1
+			ORDER BY created_at DESC
+			LIMIT 1)`
+
+	sqlSetCachedComposeStatus = `
+		UPDATE composes
+		SET status=$2, status_refreshed_at=CURRENT_TIMESTAMP
+		WHERE job_id=$1`
+
+	sqlSetComposeBackend = `
+		UPDATE composes
+		SET composer_backend=$2
+		WHERE job_id=$1`
+
+	sqlSetComposeNotifyEmail = `
+		UPDATE composes
+		SET notify_email=$2
+		WHERE job_id=$1`
+
+	sqlGetComposeNotifyEmail = `
+		SELECT notify_email
+		FROM composes
+		WHERE job_id=$1`
+
+	sqlGetCachedComposeStatus = `
+		SELECT status, status_refreshed_at, CURRENT_TIMESTAMP - status_refreshed_at <= $2
+		FROM composes
+		WHERE job_id=$1 AND status IS NOT NULL`
+
+	sqlGetComposeEvents = `
+		SELECT status, reason, created_at
+		FROM compose_events
+		WHERE compose_id=$1
+		ORDER BY created_at`
+
 	sqlInsertClone = `
 		INSERT INTO clones(id, compose_id, request, created_at)
 		VALUES($1, $2, $3, CURRENT_TIMESTAMP)`
 
+	sqlInsertComposeReplication = `
+		INSERT INTO compose_replications(compose_id, region, share_with_accounts)
+		VALUES($1, $2, $3)
+		ON CONFLICT (compose_id, region) DO NOTHING`
+
+	sqlGetComposeReplications = `
+		SELECT compose_id, region, share_with_accounts, clone_id, status, status_refreshed_at,
+		       COALESCE(CURRENT_TIMESTAMP - status_refreshed_at <= $2, false)
+		FROM compose_replications
+		WHERE compose_id=$1
+		ORDER BY region`
+
+	sqlClaimComposeReplication = `
+		UPDATE compose_replications
+		SET claimed_at=CURRENT_TIMESTAMP
+		WHERE compose_id=$1 AND region=$2 AND clone_id IS NULL
+		AND (claimed_at IS NULL OR CURRENT_TIMESTAMP - claimed_at > $3)
+		RETURNING region`
+
+	sqlSetComposeReplicationClone = `
+		UPDATE compose_replications
+		SET clone_id=$3, claimed_at=NULL
+		WHERE compose_id=$1 AND region=$2`
+
+	sqlSetComposeReplicationStatus = `
+		UPDATE compose_replications
+		SET status=$3, status_refreshed_at=CURRENT_TIMESTAMP
+		WHERE compose_id=$1 AND region=$2`
+
+	sqlInsertComposeEncryption = `
+		INSERT INTO compose_encryptions(compose_id, region, kms_key_id, share_with_accounts)
+		VALUES($1, $2, $3, $4)`
+
+	sqlGetComposeEncryption = `
+		SELECT compose_id, region, kms_key_id, share_with_accounts, source_image_id, image_id, status, last_error
+		FROM compose_encryptions
+		WHERE compose_id=$1`
+
+	sqlClaimComposeEncryption = `
+		UPDATE compose_encryptions
+		SET claimed_at=CURRENT_TIMESTAMP
+		WHERE compose_id=$1 AND status IN ('pending', 'copying')
+		AND (claimed_at IS NULL OR CURRENT_TIMESTAMP - claimed_at > $2)
+		RETURNING compose_id`
+
+	sqlSetComposeEncryption = `
+		UPDATE compose_encryptions
+		SET source_image_id=$2, image_id=$3, status=$4, last_error=$5, claimed_at=NULL
+		WHERE compose_id=$1`
+
 	sqlGetClonesForCompose = `
 		SELECT clones.id, clones.request, clones.created_at
 		FROM clones
@@ -110,41 +942,3553 @@ const (
 			WHERE composes.org_id=$2)`
 
 	sqlGetClone = `
-		SELECT clones.id, clones.request, clones.created_at
+		SELECT clones.id, clones.request, clones.created_at, clones.compose_id, COALESCE(composes.composer_backend, '')
 		FROM clones
-		WHERE clones.id=$1 AND clones.compose_id in (
+		JOIN composes ON composes.job_id = clones.compose_id
+		WHERE clones.id=$1 AND composes.org_id=$2`
+
+	sqlInsertLaunch = `
+		INSERT INTO launches(reservation_id, compose_id, provider, request, created_at)
+		VALUES($1, $2, $3, $4, CURRENT_TIMESTAMP)`
+
+	sqlGetLaunchesForCompose = `
+		SELECT launches.reservation_id, launches.compose_id, launches.provider, launches.request, launches.created_at
+		FROM launches
+		JOIN composes ON composes.job_id = launches.compose_id
+		WHERE launches.compose_id=$1 AND composes.org_id=$2
+		ORDER BY launches.created_at DESC
+		LIMIT $3 OFFSET $4`
+
+	sqlCountLaunchesForCompose = `
+		SELECT COUNT(*)
+		FROM launches
+		JOIN composes ON composes.job_id = launches.compose_id
+		WHERE launches.compose_id=$1 AND composes.org_id=$2`
+
+	sqlGetLaunch = `
+		SELECT launches.reservation_id, launches.compose_id, launches.provider, launches.request, launches.created_at
+		FROM launches
+		JOIN composes ON composes.job_id = launches.compose_id
+		WHERE launches.reservation_id=$1 AND composes.org_id=$2`
+
+	sqlGetAWSShareAllowList = `
+		SELECT account_id
+		FROM aws_share_allowlist
+		WHERE org_id=$1
+		ORDER BY account_id`
+
+	sqlGetIPAllowList = `
+		SELECT cidr
+		FROM ip_allowlist
+		WHERE org_id=$1
+		ORDER BY cidr`
+
+	sqlDeleteIPAllowList = `
+		DELETE FROM ip_allowlist
+		WHERE org_id=$1`
+
+	sqlInsertIPAllowList = `
+		INSERT INTO ip_allowlist(org_id, cidr)
+		VALUES($1, $2)
+		ON CONFLICT DO NOTHING`
+
+	sqlInsertComposeArtifact = `
+		INSERT INTO compose_artifacts(compose_id, filename, size, sha256, cloud_image_id, signature)
+		VALUES($1, $2, $3, $4, $5, $6)
+		ON CONFLICT DO NOTHING`
+
+	sqlSetComposeArtifactSignature = `
+		UPDATE compose_artifacts
+		SET signature = $3
+		WHERE compose_id=$1 AND filename=$2`
+
+	sqlSetComposeSignatureResult = `
+		INSERT INTO compose_signatures(compose_id, status, reference, attempts, last_error, created_at)
+		VALUES($1, $2, $3, 1, $4, CURRENT_TIMESTAMP)
+		ON CONFLICT (compose_id) DO UPDATE
+		SET status = EXCLUDED.status,
+		    reference = EXCLUDED.reference,
+		    attempts = compose_signatures.attempts + 1,
+		    last_error = EXCLUDED.last_error
+		WHERE compose_signatures.status <> 'signed'`
+
+	sqlGetComposeSignature = `
+		SELECT compose_signatures.reference, compose_signatures.status, compose_signatures.attempts, compose_signatures.last_error
+		FROM compose_signatures
+		WHERE compose_signatures.compose_id=$1 AND $1 in (
 			SELECT composes.job_id
 			FROM composes
 			WHERE composes.org_id=$2)`
-)
 
-func InitDBConnectionPool(connStr string) (DB, error) {
-	dbConfig, err := pgxpool.ParseConfig(connStr)
+	sqlInsertCommitSignature = `
+		INSERT INTO compose_commit_signatures(compose_id, commit, signature, created_at)
+		VALUES($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT DO NOTHING`
+
+	sqlGetCommitSignature = `
+		SELECT compose_commit_signatures.commit, compose_commit_signatures.signature
+		FROM compose_commit_signatures
+		WHERE compose_commit_signatures.compose_id=$1 AND $1 in (
+			SELECT composes.job_id
+			FROM composes
+			WHERE composes.org_id=$2)`
+
+	sqlInsertVulnerabilityScan = `
+		INSERT INTO compose_vulnerability_scans(compose_id, scanner, findings, scanned_at)
+		VALUES($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT DO NOTHING`
+
+	sqlGetVulnerabilityScan = `
+		SELECT compose_vulnerability_scans.scanner, compose_vulnerability_scans.findings, compose_vulnerability_scans.scanned_at
+		FROM compose_vulnerability_scans
+		WHERE compose_vulnerability_scans.compose_id=$1 AND $1 in (
+			SELECT composes.job_id
+			FROM composes
+			WHERE composes.org_id=$2)`
+
+	sqlInsertComposeSBOM = `
+		INSERT INTO compose_sboms(compose_id, format, document, created_at)
+		VALUES($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT DO NOTHING`
+
+	sqlGetComposeSBOM = `
+		SELECT compose_sboms.document
+		FROM compose_sboms
+		WHERE compose_sboms.compose_id=$1 AND compose_sboms.format=$3 AND $1 in (
+			SELECT composes.job_id
+			FROM composes
+			WHERE composes.org_id=$2)`
+
+	sqlGetComposeArtifacts = `
+		SELECT compose_artifacts.filename, compose_artifacts.size, compose_artifacts.sha256, compose_artifacts.cloud_image_id, compose_artifacts.signature
+		FROM compose_artifacts
+		WHERE compose_artifacts.compose_id=$1 AND $1 in (
+			SELECT composes.job_id
+			FROM composes
+			WHERE composes.org_id=$2)
+		ORDER BY compose_artifacts.filename`
+
+	sqlInsertAPIToken = `
+		INSERT INTO api_tokens(id, org_id, account_number, name, token_hash, read_only, user_id, created_by, created_at, expires_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, CURRENT_TIMESTAMP, $9)`
+
+	sqlGetAPITokens = `
+		SELECT id, org_id, account_number, name, read_only, user_id, created_by, created_at, expires_at, last_used_at
+		FROM api_tokens
+		WHERE org_id=$1 AND revoked=FALSE
+		ORDER BY created_at DESC`
+
+	sqlUseAPIToken = `
+		UPDATE api_tokens
+		SET last_used_at = CURRENT_TIMESTAMP
+		WHERE token_hash=$1 AND revoked=FALSE AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		RETURNING id, org_id, account_number, name, read_only, user_id, created_by, created_at, expires_at, last_used_at`
+
+	sqlRevokeAPIToken = `
+		UPDATE api_tokens
+		SET revoked = TRUE
+		WHERE org_id=$1 AND id=$2 AND revoked=FALSE`
+
+	sqlGetQuota = `
+		SELECT org_id, builds_per_day, builds_per_month, concurrent_builds, updated_at
+		FROM quotas
+		WHERE org_id=$1`
+
+	sqlSetQuota = `
+		INSERT INTO quotas(org_id, builds_per_day, builds_per_month, concurrent_builds, updated_at)
+		VALUES($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		ON CONFLICT (org_id) DO UPDATE
+		SET builds_per_day = EXCLUDED.builds_per_day,
+		    builds_per_month = EXCLUDED.builds_per_month,
+		    concurrent_builds = EXCLUDED.concurrent_builds,
+		    updated_at = EXCLUDED.updated_at`
+
+	sqlGetUploadTargetPolicy = `
+		SELECT targets
+		FROM upload_target_policies
+		WHERE org_id=$1`
+
+	sqlSetUploadTargetPolicy = `
+		INSERT INTO upload_target_policies(org_id, targets, updated_at)
+		VALUES($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (org_id) DO UPDATE
+		SET targets = EXCLUDED.targets,
+		    updated_at = EXCLUDED.updated_at`
+
+	sqlInsertQuotaBoost = `
+		INSERT INTO quota_boosts(id, org_id, builds_per_day, builds_per_month, concurrent_builds, reason, created_by, created_at, expires_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP + $8)
+		RETURNING created_at, expires_at`
+
+	sqlGetQuotaBoosts = `
+		SELECT id, org_id, builds_per_day, builds_per_month, concurrent_builds, reason, created_by, created_at, expires_at
+		FROM quota_boosts
+		WHERE org_id=$1 AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY created_at`
+
+	sqlDeleteQuotaBoost = `
+		DELETE FROM quota_boosts
+		WHERE id=$1 AND org_id=$2`
+
+	sqlInsertQueuedCompose = `
+		INSERT INTO compose_queue(compose_id, org_id, composer_request, created_at, pending_approval, requested_by)
+		VALUES($1, $2, $3, CURRENT_TIMESTAMP, $4, $5)`
+
+	sqlGetQueuedCompose = `
+		SELECT compose_id, org_id, composer_request, created_at, error, pending_approval, requested_by, reviewed_by
+		FROM compose_queue
+		WHERE compose_id=$1`
+
+	sqlGetOrgsWithQueuedComposes = `
+		SELECT DISTINCT org_id
+		FROM compose_queue
+		WHERE error IS NULL AND NOT pending_approval`
+
+	// claims expire, so composes get retried if a replica died or
+	// composer couldn't be reached while submitting them
+	sqlClaimQueuedComposes = `
+		UPDATE compose_queue
+		SET claimed_at = CURRENT_TIMESTAMP
+		WHERE compose_id IN (
+			SELECT compose_id
+			FROM compose_queue
+			WHERE org_id=$1 AND error IS NULL AND NOT pending_approval
+			AND (claimed_at IS NULL OR CURRENT_TIMESTAMP - claimed_at > $3)
+			ORDER BY created_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED)
+		RETURNING compose_id, org_id, composer_request, created_at, error, pending_approval, requested_by, reviewed_by`
+
+	sqlSetComposerJobId = `
+		UPDATE composes
+		SET composer_job_id=$2
+		WHERE job_id=$1`
+
+	sqlDeleteQueuedCompose = `
+		DELETE FROM compose_queue
+		WHERE compose_id=$1`
+
+	sqlFailQueuedCompose = `
+		UPDATE compose_queue
+		SET error=$2, claimed_at=NULL
+		WHERE compose_id=$1`
+
+	sqlCountQueuedComposes = `
+		SELECT COUNT(*)
+		FROM compose_queue
+		WHERE org_id=$1 AND error IS NULL AND NOT pending_approval`
+
+	sqlApproveQueuedCompose = `
+		UPDATE compose_queue
+		SET pending_approval = FALSE, reviewed_by=$2
+		WHERE compose_id=$1 AND pending_approval`
+
+	sqlRejectQueuedCompose = `
+		UPDATE compose_queue
+		SET pending_approval = FALSE, reviewed_by=$2, error=$3
+		WHERE compose_id=$1 AND pending_approval`
+
+	sqlGetApprovalRequired = `
+		SELECT require_approval
+		FROM approval_settings
+		WHERE org_id=$1`
+
+	sqlSetApprovalRequired = `
+		INSERT INTO approval_settings(org_id, require_approval, updated_at)
+		VALUES($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (org_id) DO UPDATE
+		SET require_approval = EXCLUDED.require_approval,
+		    updated_at = EXCLUDED.updated_at`
+
+	sqlGetSecretScanningMode = `
+		SELECT mode
+		FROM secret_scanning_settings
+		WHERE org_id=$1`
+
+	sqlSetSecretScanningMode = `
+		INSERT INTO secret_scanning_settings(org_id, mode, updated_at)
+		VALUES($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (org_id) DO UPDATE
+		SET mode = EXCLUDED.mode,
+		    updated_at = EXCLUDED.updated_at`
+
+	sqlGetRepositorySignaturesRequired = `
+		SELECT require_signatures
+		FROM repository_signature_settings
+		WHERE org_id=$1`
+
+	sqlSetRepositorySignaturesRequired = `
+		INSERT INTO repository_signature_settings(org_id, require_signatures, updated_at)
+		VALUES($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (org_id) DO UPDATE
+		SET require_signatures = EXCLUDED.require_signatures,
+		    updated_at = EXCLUDED.updated_at`
+
+	sqlInsertAuditLogEntry = `
+		INSERT INTO audit_log(org_id, user_id, email, method, path, resource_id, request_digest, status, remote_ip, created_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, CURRENT_TIMESTAMP)`
+
+	sqlGetAuditLog = `
+		SELECT id, org_id, user_id, email, method, path, resource_id, request_digest, status, remote_ip, created_at
+		FROM audit_log
+		WHERE org_id=$1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3`
+
+	sqlCountAuditLog = `
+		SELECT COUNT(*)
+		FROM audit_log
+		WHERE org_id=$1`
+
+	sqlGetAuditLogBetween = `
+		SELECT id, org_id, user_id, email, method, path, resource_id, request_digest, status, remote_ip, created_at
+		FROM audit_log
+		WHERE ($1::varchar = '' OR org_id=$1) AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at, id`
+
+	sqlGetStorageUsage = `
+		SELECT COALESCE(SUM(compose_artifacts.size), 0)
+		FROM compose_artifacts
+		JOIN composes ON composes.job_id = compose_artifacts.compose_id
+		WHERE composes.org_id=$1 AND composes.deleted = FALSE`
+
+	sqlGetOrgAnalytics = `
+		SELECT composes.org_id, date_trunc($4::text, composes.created_at),
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE last_event.status = 'success'),
+		       COUNT(*) FILTER (WHERE last_event.status = 'failure'),
+		       COALESCE(SUM(artifacts.count), 0),
+		       COALESCE(SUM(artifacts.size), 0)
+		FROM composes
+		LEFT JOIN LATERAL (
+			SELECT status
+			FROM compose_events
+			WHERE compose_events.compose_id = composes.job_id
+			ORDER BY created_at DESC
+			LIMIT 1) last_event ON TRUE
+		LEFT JOIN (
+			SELECT compose_id, COUNT(*) AS count, SUM(size) AS size
+			FROM compose_artifacts
+			GROUP BY compose_id) artifacts ON artifacts.compose_id = composes.job_id
+		WHERE ($1::varchar = '' OR composes.org_id=$1) AND composes.created_at >= $2 AND composes.created_at < $3
+		GROUP BY 1, 2
+		ORDER BY 1, 2`
+
+	sqlGetUsageReport = `
+		SELECT composes.org_id, date_trunc('month', composes.created_at),
+		       COALESCE(request->'image_requests'->0->>'image_type', ''),
+		       COALESCE(request->'image_requests'->0->'upload_request'->>'type', ''),
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE last_event.status = 'success'),
+		       COUNT(*) FILTER (WHERE last_event.status = 'failure'),
+		       COALESCE(SUM(EXTRACT(EPOCH FROM finished.created_at - composes.created_at)), 0)::bigint,
+		       COALESCE(SUM(artifacts.size), 0)
+		FROM composes
+		LEFT JOIN LATERAL (
+			SELECT status
+			FROM compose_events
+			WHERE compose_events.compose_id = composes.job_id
+			ORDER BY created_at DESC
+			LIMIT 1) last_event ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT created_at
+			FROM compose_events
+			WHERE compose_events.compose_id = composes.job_id
+			AND compose_events.status IN ('success', 'failure')
+			ORDER BY created_at
+			LIMIT 1) finished ON TRUE
+		LEFT JOIN (
+			SELECT compose_id, SUM(size) AS size
+			FROM compose_artifacts
+			GROUP BY compose_id) artifacts ON artifacts.compose_id = composes.job_id
+		WHERE ($1::varchar = '' OR composes.org_id=$1) AND composes.created_at >= $2 AND composes.created_at < $3
+		GROUP BY 1, 2, 3, 4
+		ORDER BY 1, 2, 3, 4`
+
+	// builds start when composer got them, queued composes only then
+	sqlGetBuildDurations = `
+		SELECT COALESCE(composes.request->>'distribution', ''),
+		       COALESCE(composes.request->'image_requests'->0->>'image_type', ''),
+		       COALESCE(composes.request->'image_requests'->0->>'architecture', ''),
+		       COUNT(*),
+		       percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM finished.created_at - started.at)),
+		       percentile_cont(0.95) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM finished.created_at - started.at))
+		FROM compose_events finished
+		JOIN composes ON composes.job_id = finished.compose_id
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(MAX(submitted.created_at), composes.created_at) AS at
+			FROM compose_events submitted
+			WHERE submitted.compose_id = finished.compose_id
+			AND submitted.status = 'submitted'
+			AND submitted.created_at <= finished.created_at) started
+		WHERE finished.status = 'success' AND finished.created_at >= $1 AND finished.created_at < $2
+		AND NOT EXISTS (
+			SELECT 1
+			FROM compose_events earlier
+			WHERE earlier.compose_id = finished.compose_id
+			AND earlier.status = 'success'
+			AND earlier.created_at < finished.created_at)
+		GROUP BY 1, 2, 3
+		ORDER BY 1, 2, 3`
+
+	sqlInsertWebhook = `
+		INSERT INTO webhooks(id, org_id, compose_id, url, secret_kek_id, secret_data_key, secret_ciphertext, created_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP)`
+
+	sqlGetWebhooks = `
+		SELECT id, org_id, compose_id, url, secret_kek_id, secret_data_key, secret_ciphertext, created_at
+		FROM webhooks
+		WHERE org_id=$1
+		ORDER BY created_at DESC`
+
+	sqlGetWebhook = `
+		SELECT id, org_id, compose_id, url, secret_kek_id, secret_data_key, secret_ciphertext, created_at
+		FROM webhooks
+		WHERE id=$1 AND org_id=$2`
+
+	sqlDeleteWebhook = `
+		DELETE FROM webhooks
+		WHERE id=$1 AND org_id=$2`
+
+	sqlGetOrgsWithWebhooks = `
+		SELECT DISTINCT org_id
+		FROM webhooks
+		WHERE compose_id IS NULL OR CURRENT_TIMESTAMP - created_at <= $1`
+
+	sqlGetUnnotifiedClonesSince = `
+		SELECT clones.id, clones.request, clones.created_at, clones.compose_id, COALESCE(composes.composer_backend, '')
+		FROM clones
+		JOIN composes ON composes.job_id = clones.compose_id
+		WHERE composes.org_id=$1 AND CURRENT_TIMESTAMP - clones.created_at <= $2
+		AND EXISTS (
+			SELECT 1
+			FROM webhooks
+			WHERE webhooks.org_id = composes.org_id
+			AND (webhooks.compose_id IS NULL OR webhooks.compose_id = composes.job_id))
+		AND NOT EXISTS (
+			SELECT 1
+			FROM webhook_deliveries
+			WHERE webhook_deliveries.resource_id = clones.id)`
+
+	sqlGetWebhooksOfCompose = `
+		SELECT webhooks.id
+		FROM webhooks
+		JOIN composes ON composes.org_id = webhooks.org_id
+		WHERE composes.job_id=$1
+		AND (webhooks.compose_id IS NULL OR webhooks.compose_id = composes.job_id)`
+
+	sqlInsertWebhookDelivery = `
+		INSERT INTO webhook_deliveries(id, webhook_id, event, resource_id, payload, status, next_attempt_at, created_at)
+		VALUES($1, $2, $3, $4, $5, 'pending', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT (webhook_id, event, resource_id) DO NOTHING`
+
+	sqlClaimWebhookDeliveries = `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = CURRENT_TIMESTAMP + $2::interval
+		FROM webhooks
+		WHERE webhooks.id = webhook_deliveries.webhook_id
+		AND webhook_deliveries.id IN (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED)
+		RETURNING webhook_deliveries.id, webhook_deliveries.webhook_id, webhook_deliveries.event,
+			webhook_deliveries.resource_id, webhook_deliveries.payload, webhook_deliveries.status,
+			webhook_deliveries.attempts, webhook_deliveries.response_code, webhook_deliveries.last_error,
+			webhook_deliveries.next_attempt_at, webhook_deliveries.created_at, webhook_deliveries.delivered_at,
+			webhooks.url, webhooks.secret_kek_id, webhooks.secret_data_key, webhooks.secret_ciphertext,
+			webhooks.org_id`
+
+	sqlSetWebhookDeliveryResult = `
+		UPDATE webhook_deliveries
+		SET status=$2::varchar, attempts=attempts+1, response_code=$3, last_error=$4,
+		    next_attempt_at=CURRENT_TIMESTAMP + $5::interval,
+		    delivered_at=CASE WHEN $2::varchar = 'delivered' THEN CURRENT_TIMESTAMP END
+		WHERE id=$1`
+
+	sqlGetWebhookDeliveries = `
+		SELECT webhook_deliveries.id, webhook_deliveries.webhook_id, webhook_deliveries.event,
+			webhook_deliveries.resource_id, webhook_deliveries.payload, webhook_deliveries.status,
+			webhook_deliveries.attempts, webhook_deliveries.response_code, webhook_deliveries.last_error,
+			webhook_deliveries.next_attempt_at, webhook_deliveries.created_at, webhook_deliveries.delivered_at
+		FROM webhook_deliveries
+		JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+		WHERE webhook_deliveries.webhook_id=$1 AND webhooks.org_id=$2
+		ORDER BY webhook_deliveries.created_at DESC
+		LIMIT $3 OFFSET $4`
+
+	sqlCountWebhookDeliveries = `
+		SELECT COUNT(*)
+		FROM webhook_deliveries
+		JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+		WHERE webhook_deliveries.webhook_id=$1 AND webhooks.org_id=$2`
+
+	sqlInsertOutboxEntry = `
+		INSERT INTO outbox(id, sink, event, compose_id, payload, status, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, 'pending', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+
+	sqlClaimOutboxEntries = `
+		UPDATE outbox
+		SET next_attempt_at = CURRENT_TIMESTAMP + $2::interval
+		WHERE id IN (
+			SELECT id
+			FROM outbox
+			WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED)
+		RETURNING id, sink, event, compose_id, payload, attempts, created_at`
+
+	sqlSetOutboxEntryResult = `
+		UPDATE outbox
+		SET status=$2::varchar, attempts=attempts+1, last_error=$3,
+		    next_attempt_at=CURRENT_TIMESTAMP + $4::interval,
+		    dispatched_at=CASE WHEN $2::varchar = 'dispatched' THEN CURRENT_TIMESTAMP END
+		WHERE id=$1`
+
+	sqlDeleteOutboxEntriesBefore = `
+		DELETE FROM outbox
+		WHERE status <> 'pending' AND CURRENT_TIMESTAMP - created_at > $1`
+
+	sqlGetAWXSettings = `
+		SELECT org_id, url, token_kek_id, token_data_key, token_ciphertext, job_template_id, updated_at
+		FROM awx_settings
+		WHERE org_id=$1`
+
+	sqlSetAWXSettings = `
+		INSERT INTO awx_settings(org_id, url, token_kek_id, token_data_key, token_ciphertext, job_template_id, updated_at)
+		VALUES($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP)
+		ON CONFLICT (org_id) DO UPDATE
+		SET url = EXCLUDED.url,
+		    token = NULL,
+		    token_kek_id = EXCLUDED.token_kek_id,
+		    token_data_key = EXCLUDED.token_data_key,
+		    token_ciphertext = EXCLUDED.token_ciphertext,
+		    job_template_id = EXCLUDED.job_template_id,
+		    updated_at = EXCLUDED.updated_at`
+
+	sqlDeleteAWXSettings = `
+		DELETE FROM awx_settings
+		WHERE org_id=$1`
+
+	sqlGetArtifactSigningSettings = `
+		SELECT org_id, service_url, updated_at
+		FROM artifact_signing_settings
+		WHERE org_id=$1`
+
+	sqlSetArtifactSigningSettings = `
+		INSERT INTO artifact_signing_settings(org_id, service_url, updated_at)
+		VALUES($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (org_id) DO UPDATE
+		SET private_key = NULL,
+		    service_url = EXCLUDED.service_url,
+		    service_token = NULL,
+		    updated_at = EXCLUDED.updated_at`
+
+	sqlGetPlaintextArtifactSigningSecrets = `
+		SELECT org_id, private_key, service_token
+		FROM artifact_signing_settings
+		WHERE private_key IS NOT NULL OR service_token IS NOT NULL`
+
+	sqlDropArtifactSigningSecrets = `
+		UPDATE artifact_signing_settings
+		SET private_key=NULL, service_token=NULL
+		WHERE org_id=$1`
+
+	sqlDeleteArtifactSigningSettings = `
+		DELETE FROM artifact_signing_settings
+		WHERE org_id=$1`
+
+	sqlGetPulpSettings = `
+		SELECT org_id, server_address, repository, basepath, updated_at
+		FROM pulp_settings
+		WHERE org_id=$1`
+
+	sqlSetPulpSettings = `
+		INSERT INTO pulp_settings(org_id, server_address, repository, basepath, updated_at)
+		VALUES($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		ON CONFLICT (org_id) DO UPDATE
+		SET server_address = EXCLUDED.server_address,
+		    repository = EXCLUDED.repository,
+		    basepath = EXCLUDED.basepath,
+		    updated_at = EXCLUDED.updated_at`
+
+	sqlDeletePulpSettings = `
+		DELETE FROM pulp_settings
+		WHERE org_id=$1`
+
+	sqlInsertSigningKey = `
+		INSERT INTO signing_keys(id, org_id, purpose, fingerprint, public_key, kek_id, encrypted_data_key, ciphertext)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	sqlSigningKeyColumns = `
+		SELECT id, org_id, purpose, fingerprint, public_key, kek_id, encrypted_data_key, ciphertext, created_at, replaced_by, rotated_at, revoked_at
+		FROM signing_keys`
+
+	sqlGetSigningKeys = sqlSigningKeyColumns + `
+		WHERE org_id=$1
+		ORDER BY created_at DESC, id`
+
+	sqlGetSigningKey = sqlSigningKeyColumns + `
+		WHERE id=$1 AND org_id=$2`
+
+	sqlGetSigningKeyInUse = sqlSigningKeyColumns + `
+		WHERE org_id=$1 AND purpose=$2 AND replaced_by IS NULL AND revoked_at IS NULL`
+
+	sqlReplaceSigningKey = `
+		UPDATE signing_keys
+		SET replaced_by=$3, rotated_at=CURRENT_TIMESTAMP
+		WHERE id=$1 AND org_id=$2 AND replaced_by IS NULL AND revoked_at IS NULL`
+
+	sqlRevokeSigningKey = `
+		UPDATE signing_keys
+		SET revoked_at=CURRENT_TIMESTAMP
+		WHERE id=$1 AND org_id=$2 AND revoked_at IS NULL`
+
+	sqlInsertSigningKeyUsage = `
+		INSERT INTO signing_key_usage(key_id, operation, resource_id)
+		VALUES($1, $2, $3)`
+
+	sqlGetSigningKeyUsage = `
+		SELECT u.id, u.key_id, u.operation, u.resource_id, u.used_at
+		FROM signing_key_usage u
+		JOIN signing_keys k ON k.id = u.key_id
+		WHERE u.key_id=$1 AND k.org_id=$2
+		ORDER BY u.used_at DESC, u.id DESC
+		LIMIT $3 OFFSET $4`
+
+	sqlCountSigningKeyUsage = `
+		SELECT COUNT(*)
+		FROM signing_key_usage u
+		JOIN signing_keys k ON k.id = u.key_id
+		WHERE u.key_id=$1 AND k.org_id=$2`
+
+	sqlGetComplianceExportSettings = `
+		SELECT org_id, bucket, region, prefix, verification_token, created_at, updated_at
+		FROM compliance_export_settings
+		WHERE org_id=$1`
+
+	sqlSetComplianceExportSettings = `
+		INSERT INTO compliance_export_settings(org_id, bucket, region, prefix, verification_token, created_at, updated_at)
+		VALUES($1, $2, $3, $4, $5, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT (org_id) DO UPDATE
+		SET bucket = EXCLUDED.bucket,
+		    region = EXCLUDED.region,
+		    prefix = EXCLUDED.prefix,
+		    verification_token = EXCLUDED.verification_token,
+		    updated_at = EXCLUDED.updated_at`
+
+	sqlDeleteComplianceExportSettings = `
+		DELETE FROM compliance_export_settings
+		WHERE org_id=$1`
+
+	sqlGetAllComplianceExportSettings = `
+		SELECT org_id, bucket, region, prefix, verification_token, created_at, updated_at
+		FROM compliance_export_settings
+		ORDER BY org_id`
+
+	sqlGetComposesFinishedBetween = `
+		SELECT job_id, request, composes.created_at, image_name, COALESCE(composer_job_id, job_id), COALESCE(composer_backend, '')
+		FROM composes
+		JOIN compose_events ON compose_events.compose_id = composes.job_id
+		WHERE org_id=$1 AND compose_events.status = 'success'
+		AND compose_events.created_at >= $2 AND compose_events.created_at < $3
+		ORDER BY compose_events.created_at, job_id`
+
+	sqlClaimComplianceExport = `
+		INSERT INTO compliance_exports(org_id, window_start, window_end, claimed_until)
+		VALUES($1, $2, $3, CURRENT_TIMESTAMP + $4::interval)
+		ON CONFLICT (org_id, window_start) DO UPDATE
+		SET claimed_until = EXCLUDED.claimed_until
+		WHERE compliance_exports.exported_at IS NULL
+		AND compliance_exports.claimed_until <= CURRENT_TIMESTAMP`
+
+	sqlSetComplianceExportResult = `
+		UPDATE compliance_exports
+		SET attempts = attempts + 1, audit_log_entries = $3, composes = $4, last_error = $5::varchar,
+		    claimed_until = CURRENT_TIMESTAMP + $6::interval,
+		    exported_at = CASE WHEN $5::varchar IS NULL THEN CURRENT_TIMESTAMP END
+		WHERE org_id=$1 AND window_start=$2`
+
+	sqlGetComplianceExports = `
+		SELECT org_id, window_start, window_end, attempts, last_error, audit_log_entries, composes, exported_at
+		FROM compliance_exports
+		WHERE org_id=$1
+		ORDER BY window_start DESC
+		LIMIT $2 OFFSET $3`
+
+	sqlCountComplianceExports = `
+		SELECT COUNT(*)
+		FROM compliance_exports
+		WHERE org_id=$1`
+
+	sqlSetAWXJobResult = `
+		INSERT INTO awx_jobs(compose_id, org_id, job_template_id, status, job_id, attempts, last_error, created_at, launched_at)
+		VALUES($1, $2, $3, $4::varchar, $5, 1, $6, CURRENT_TIMESTAMP,
+		       CASE WHEN $4::varchar = 'launched' THEN CURRENT_TIMESTAMP END)
+		ON CONFLICT (compose_id) DO UPDATE
+		SET job_template_id = EXCLUDED.job_template_id,
+		    status = EXCLUDED.status,
+		    job_id = EXCLUDED.job_id,
+		    attempts = awx_jobs.attempts + 1,
+		    last_error = EXCLUDED.last_error,
+		    launched_at = EXCLUDED.launched_at`
+
+	sqlGetAWXJobs = `
+		SELECT compose_id, job_template_id, status, job_id, attempts, last_error, created_at, launched_at
+		FROM awx_jobs
+		WHERE org_id=$1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	sqlCountAWXJobs = `
+		SELECT COUNT(*)
+		FROM awx_jobs
+		WHERE org_id=$1`
+
+	sqlInsertOrgEvent = `
+		INSERT INTO org_events(org_id, event, resource_id, payload, created_at)
+		SELECT org_id, $2, $3, $4, CURRENT_TIMESTAMP
+		FROM composes
+		WHERE job_id=$1
+		ON CONFLICT (event, resource_id) DO NOTHING`
+
+	sqlGetOrgEventsAfter = `
+		SELECT id, org_id, event, resource_id, payload, created_at
+		FROM org_events
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2`
+
+	sqlGetLastOrgEventId = `
+		SELECT COALESCE(MAX(id), 0)
+		FROM org_events`
+
+	sqlDeleteOrgEventsBefore = `
+		DELETE FROM org_events
+		WHERE CURRENT_TIMESTAMP - created_at > $1`
+
+	// serializes checking the quota of an org with reserving builds and
+	// claiming queued composes, until the transaction ends
+	sqlLockOrgBuilds = `
+		SELECT pg_advisory_xact_lock(hashtext('builds/' || $1))`
+
+	sqlDeleteExpiredBuildReservations = `
+		DELETE FROM build_reservations
+		WHERE org_id=$1 AND expires_at < CURRENT_TIMESTAMP`
+
+	sqlCountBuildReservations = `
+		SELECT COALESCE(SUM(builds), 0)
+		FROM build_reservations
+		WHERE org_id=$1`
+
+	sqlHasQueuedComposes = `
+		SELECT EXISTS (
+			SELECT 1
+			FROM compose_queue
+			WHERE org_id=$1 AND error IS NULL AND NOT pending_approval)`
+
+	sqlInsertBuildReservation = `
+		INSERT INTO build_reservations(id, org_id, builds, expires_at)
+		VALUES($1, $2, $3, CURRENT_TIMESTAMP + $4)`
+
+	sqlDeleteBuildReservation = `
+		DELETE FROM build_reservations
+		WHERE id=$1`
+)
+
+// Time after which claimed composes which haven't been submitted can be
+// claimed again.
+const queueClaimExpiry = 5 * time.Minute
+
+// Time after which reserved builds which haven't been stored as composes
+// aren't counted anymore, longer than submitting a compose takes.
+const buildReservationExpiry = 5 * time.Minute
+
+// Time after which claimed webhook deliveries without a result can be
+// claimed again, longer than a delivery takes.
+const webhookClaimExpiry = time.Minute
+
+// Time after which claimed outbox entries without a result can be claimed
+// again, longer than dispatching a batch takes.
+const outboxClaimExpiry = 5 * time.Minute
+
+// How long a region of a compose stays claimed for its clone to be requested.
+const replicationClaimExpiry = 5 * time.Minute
+
+// how long a claimed encryption isn't worked on by anyone else
+const encryptionClaimExpiry = 5 * time.Minute
+
+// logQuery records the duration of the queries pgx logs and writes them to
+// the db module logger, at debug level as pgx logs every query at info. The
+// arguments are left out, they hold emails and token digests.
+func logQuery(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	if d, ok := data["time"].(time.Duration); ok {
+		prometheus.DBQueryDuration.WithLabelValues(msg).Observe(d.Seconds())
+	}
+
+	entry := logger.Module(logger.ModuleDB).WithContext(ctx)
+	logrusLevel := logrus.DebugLevel
+	switch level {
+	case pgx.LogLevelError:
+		logrusLevel = logrus.ErrorLevel
+	case pgx.LogLevelWarn:
+		logrusLevel = logrus.WarnLevel
+	}
+	if !entry.Logger.IsLevelEnabled(logrusLevel) {
+		return
+	}
+
+	fields := logrus.Fields{}
+	for k, v := range data {
+		if k != "args" {
+			fields[k] = v
+		}
+	}
+	entry.WithFields(fields).Log(logrusLevel, msg)
+}
+
+func InitDBConnectionPool(connStr string) (DB, error) {
+	return InitDBConnectionPoolWithPassword(connStr, nil)
+}
+
+// InitDBConnectionPoolWithPassword connects with the password returned by
+// password instead of the one of connStr, it's asked for each new
+// connection so rotated passwords are picked up.
+func InitDBConnectionPoolWithPassword(connStr string, password func() string) (DB, error) {
+	pool, err := connectPool(connStr, password)
+	if err != nil {
+		return nil, err
+	}
+	return &dB{pool}, nil
+}
+
+func connectPool(connStr string, password func() string) (*pgxpool.Pool, error) {
+	dbConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, err
+	}
+	dbConfig.ConnConfig.Logger = pgx.LoggerFunc(logQuery)
+	dbConfig.ConnConfig.LogLevel = pgx.LogLevelInfo
+	if password != nil {
+		dbConfig.BeforeConnect = func(ctx context.Context, cc *pgx.ConnConfig) error {
+			cc.Password = password()
+			return nil
+		}
+	}
+
+	return pgxpool.ConnectConfig(context.Background(), dbConfig)
+}
+
+func (db *dB) Ping(ctx context.Context) error {
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	return conn.Ping(ctx)
+}
+
+func (db *dB) InsertCompose(jobId uuid.UUID, accountNumber, email, orgId string, imageName *string, request json.RawMessage, outbox ...OutboxEntry) error {
+	return db.InsertComposeWithRelations(jobId, accountNumber, email, orgId, imageName, request, nil, outbox...)
+}
+
+// InsertComposeWithRelations stores a compose along with its relations, in
+// one transaction.
+func (db *dB) InsertComposeWithRelations(jobId uuid.UUID, accountNumber, email, orgId string, imageName *string, request json.RawMessage, relations *ComposeRelations, outbox ...OutboxEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	_, err = tx.Exec(ctx, sqlInsertCompose, jobId, request, accountNumber, email, orgId, imageName)
+	if err != nil {
+		return err
+	}
+	if relations != nil {
+		err = insertComposeRelations(ctx, tx, jobId, *relations)
+		if err != nil {
+			return err
+		}
+	}
+	err = insertOutboxEntries(ctx, tx, outbox)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetIdempotencyKey returns the idempotency key of the org a compose was
+// stored with, deleted composes included.
+func (db *dB) GetIdempotencyKey(orgId, key string) (*IdempotencyKeyEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var entry IdempotencyKeyEntry
+	err = conn.QueryRow(ctx, sqlGetIdempotencyKey, orgId, key).Scan(&entry.ComposeId, &entry.Key, &entry.RequestHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, IdempotencyKeyNotFoundError
+	} else if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (db *dB) GetCompose(jobId uuid.UUID, orgId string) (*ComposeEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	result := conn.QueryRow(ctx, sqlGetCompose, orgId, jobId)
+
+	var compose ComposeEntry
+	err = result.Scan(&compose.Id, &compose.Request, &compose.CreatedAt, &compose.ImageName, &compose.ComposerId, &compose.ComposerBackend)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ComposeNotFoundError
+		} else {
+			return nil, err
+		}
+	}
+	compose.OrgId = orgId
+
+	return &compose, nil
+}
+
+func (db *dB) GetComposeImageType(jobId uuid.UUID, orgId string) (string, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Release()
+
+	result := conn.QueryRow(ctx, sqlGetComposeImageType, orgId, jobId)
+
+	var imageType string
+	err = result.Scan(&imageType)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ComposeNotFoundError
+		} else {
+			return "", err
+		}
+	}
+
+	return imageType, nil
+}
+
+func (db *dB) GetComposes(orgId string, since time.Duration, limit, offset int, ignoreImageTypes []string) ([]ComposeEntry, int, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Release()
+	result, err := conn.Query(ctx, sqlGetComposes, orgId, since, ignoreImageTypes, limit, offset)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var composes []ComposeEntry
+	for result.Next() {
+		var jobId uuid.UUID
+		var request json.RawMessage
+		var createdAt time.Time
+		var imageName *string
+		var composerId uuid.UUID
+		var composerBackend string
+		err = result.Scan(&jobId, &request, &createdAt, &imageName, &composerId, &composerBackend)
+		if err != nil {
+			return nil, 0, err
+		}
+		composes = append(composes, ComposeEntry{
+			jobId,
+			request,
+			createdAt,
+			imageName,
+			composerId,
+			composerBackend,
+			orgId,
+		})
+	}
+	if err = result.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var count int
+	err = conn.QueryRow(ctx, sqlCountActiveComposesSince, orgId, since, ignoreImageTypes).Scan(&count)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return composes, count, nil
+}
+
+// GetComposesAfter returns a page of the composes of an org created within
+// since, newest first, starting after the cursor, or with the newest one if
+// it's nil. Unlike with offsets, pages don't shift as composes are created.
+func (db *dB) GetComposesAfter(orgId string, since time.Duration, limit int, after *ComposeCursor, ignoreImageTypes []string) ([]ComposeEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var createdAt *time.Time
+	var id *uuid.UUID
+	if after != nil {
+		createdAt = &after.CreatedAt
+		id = &after.Id
+	}
+	rows, err := conn.Query(ctx, sqlGetComposesAfter, orgId, since, ignoreImageTypes, createdAt, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var composes []ComposeEntry
+	for rows.Next() {
+		c := ComposeEntry{OrgId: orgId}
+		err = rows.Scan(&c.Id, &c.Request, &c.CreatedAt, &c.ImageName, &c.ComposerId, &c.ComposerBackend)
+		if err != nil {
+			return nil, err
+		}
+		composes = append(composes, c)
+	}
+	return composes, rows.Err()
+}
+
+func (db *dB) CountComposesSince(orgId string, duration time.Duration) (int, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	var count int
+	err = conn.QueryRow(ctx,
+		sqlCountComposesSince,
+		orgId, duration).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// CountUnfinishedComposesSince counts the composes of an org created within
+// the duration, which haven't been seen to succeed or fail yet. Queued
+// composes are counted once they are claimed to be submitted.
+func (db *dB) CountUnfinishedComposesSince(orgId string, duration time.Duration) (int, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	var count int
+	err = conn.QueryRow(ctx, sqlCountUnfinishedComposesSince, orgId, duration, queueClaimExpiry).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetUnfinishedComposesSince returns the composes CountUnfinishedComposesSince
+// counts.
+func (db *dB) GetUnfinishedComposesSince(orgId string, duration time.Duration) ([]ComposeEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetUnfinishedComposesSince, orgId, duration)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var composes []ComposeEntry
+	for rows.Next() {
+		var c ComposeEntry
+		err = rows.Scan(&c.Id, &c.Request, &c.CreatedAt, &c.ImageName, &c.ComposerId, &c.ComposerBackend)
+		if err != nil {
+			return nil, err
+		}
+		c.OrgId = orgId
+		composes = append(composes, c)
+	}
+	return composes, rows.Err()
+}
+
+// GetOrgsWithUnfinishedComposesSince returns the orgs with composes
+// GetUnfinishedComposesSince returns.
+func (db *dB) GetOrgsWithUnfinishedComposesSince(duration time.Duration) ([]string, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetOrgsWithUnfinishedComposesSince, duration)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []string
+	for rows.Next() {
+		var org string
+		err = rows.Scan(&org)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+// ClaimComposeStatusSync claims the refresh of the status of a compose by the
+// status sync of one replica. It returns false if it was claimed less than
+// claimFor ago.
+func (db *dB) ClaimComposeStatusSync(jobId uuid.UUID, claimFor time.Duration) (bool, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	var claimed uuid.UUID
+	err = conn.QueryRow(ctx, sqlClaimComposeStatusSync, jobId, claimFor).Scan(&claimed)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (db *dB) DeleteCompose(jobId uuid.UUID, orgId string) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlDeleteCompose, orgId, jobId)
+	if tag.RowsAffected() != 1 {
+		return ComposeNotFoundError
+	}
+
+	return err
+}
+
+// GetComposeForSupport returns a compose regardless of the org it belongs to
+// and whether it was deleted, it must only be used for support tooling.
+func (db *dB) GetComposeForSupport(jobId uuid.UUID) (*SupportComposeEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var compose SupportComposeEntry
+	err = conn.QueryRow(ctx, sqlGetComposeForSupport, jobId).Scan(&compose.Id, &compose.Request, &compose.CreatedAt,
+		&compose.ImageName, &compose.ComposerId, &compose.ComposerBackend, &compose.OrgId, &compose.AccountNumber, &compose.Email, &compose.Deleted)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ComposeNotFoundError
+		} else {
+			return nil, err
+		}
+	}
+
+	return &compose, nil
+}
+
+// amiPath matches the cached statuses of composes which were uploaded as ami,
+// a compose with several image requests has an upload status per image.
+func amiPath(ami string) string {
+	literal, _ := json.Marshal(ami)
+	return fmt.Sprintf(`$.image_status.upload_status.options.ami == %[1]s || $.image_statuses[*].upload_status.options.ami == %[1]s`, literal)
+}
+
+// SearchComposesForSupport returns the composes of any org, deleted or not,
+// which were uploaded as ami or are named imageName, newest first. Either may
+// be nil, but not both. Only the statuses composer reported to the service so
+// far are searched for the ami.
+func (db *dB) SearchComposesForSupport(ami, imageName *string, limit int) ([]SupportComposeEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var path *string
+	if ami != nil {
+		p := amiPath(*ami)
+		path = &p
+	}
+	rows, err := conn.Query(ctx, sqlSearchComposesForSupport, path, imageName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var composes []SupportComposeEntry
+	for rows.Next() {
+		var compose SupportComposeEntry
+		err = rows.Scan(&compose.Id, &compose.Request, &compose.CreatedAt, &compose.ImageName, &compose.ComposerId,
+			&compose.ComposerBackend, &compose.OrgId, &compose.AccountNumber, &compose.Email, &compose.Deleted)
+		if err != nil {
+			return nil, err
+		}
+		composes = append(composes, compose)
+	}
+	return composes, rows.Err()
+}
+
+// InsertComposeEvent records the status of a compose, unless it's the same
+// as the last recorded one. Returns whether the status was recorded, the
+// outbox entries are only inserted along with it.
+func (db *dB) InsertComposeEvent(jobId uuid.UUID, status string, reason *string, outbox ...OutboxEntry) (bool, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	tag, err := tx.Exec(ctx, sqlInsertComposeEvent, jobId, status, reason)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() != 1 {
+		return false, nil
+	}
+	err = insertOutboxEntries(ctx, tx, outbox)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (db *dB) GetComposeEvents(jobId uuid.UUID) ([]ComposeEventEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetComposeEvents, jobId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []ComposeEventEntry
+	for rows.Next() {
+		var e ComposeEventEntry
+		err = rows.Scan(&e.Status, &e.Reason, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (db *dB) SetCachedComposeStatus(jobId uuid.UUID, status json.RawMessage) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlSetCachedComposeStatus, jobId, status)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		return ComposeNotFoundError
+	}
+	return nil
+}
+
+func (db *dB) SetComposeBackend(jobId uuid.UUID, backend string) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlSetComposeBackend, jobId, backend)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		return ComposeNotFoundError
+	}
+	return nil
+}
+
+func (db *dB) SetComposeNotifyEmail(jobId uuid.UUID, email string) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlSetComposeNotifyEmail, jobId, email)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		return ComposeNotFoundError
+	}
+	return nil
+}
+
+// GetComposeNotifyEmail returns the address the outcome of a compose is
+// emailed to, nil if it isn't emailed.
+func (db *dB) GetComposeNotifyEmail(jobId uuid.UUID) (*string, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var email *string
+	err = conn.QueryRow(ctx, sqlGetComposeNotifyEmail, jobId).Scan(&email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ComposeNotFoundError
+	} else if err != nil {
+		return nil, err
+	}
+	return email, nil
+}
+
+func (db *dB) GetCachedComposeStatus(jobId uuid.UUID, maxAge time.Duration) (*CachedComposeStatus, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var cached CachedComposeStatus
+	err = conn.QueryRow(ctx, sqlGetCachedComposeStatus, jobId, maxAge).Scan(&cached.Status, &cached.RefreshedAt, &cached.Fresh)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ComposeStatusNotFoundError
+		}
+		return nil, err
+	}
+	return &cached, nil
+}
+
+func (db *dB) InsertClone(composeId, cloneId uuid.UUID, request json.RawMessage) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlInsertClone, cloneId, composeId, request)
+	return err
+}
+
+func (db *dB) InsertComposeReplications(composeId uuid.UUID, regions, shareWithAccounts []string) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	accounts, err := json.Marshal(shareWithAccounts)
+	if err != nil {
+		return err
+	}
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	for _, region := range regions {
+		_, err = tx.Exec(ctx, sqlInsertComposeReplication, composeId, region, accounts)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// GetComposeReplications returns the regions of a compose, their statuses
+// are fresh if they were refreshed within maxAge.
+func (db *dB) GetComposeReplications(composeId uuid.UUID, maxAge time.Duration) ([]ComposeReplicationEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetComposeReplications, composeId, maxAge)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var replications []ComposeReplicationEntry
+	for rows.Next() {
+		var r ComposeReplicationEntry
+		var accounts json.RawMessage
+		err = rows.Scan(&r.ComposeId, &r.Region, &accounts, &r.CloneId, &r.Status, &r.StatusRefreshedAt, &r.Fresh)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(accounts, &r.ShareWithAccounts)
+		if err != nil {
+			return nil, err
+		}
+		replications = append(replications, r)
+	}
+	return replications, rows.Err()
+}
+
+// ClaimComposeReplication claims a region of a compose which has no clone
+// yet, so no one else requests one until replicationClaimExpiry passed or
+// SetComposeReplicationClone was called. It returns false if the region
+// was cloned or is claimed already.
+func (db *dB) ClaimComposeReplication(composeId uuid.UUID, region string) (bool, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	var claimed string
+	err = conn.QueryRow(ctx, sqlClaimComposeReplication, composeId, region, replicationClaimExpiry).Scan(&claimed)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// SetComposeReplicationClone stores the clone of a claimed region and
+// releases the claim, a nil clone only releases it.
+func (db *dB) SetComposeReplicationClone(composeId uuid.UUID, region string, cloneId *uuid.UUID) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlSetComposeReplicationClone, composeId, region, cloneId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ComposeReplicationNotFoundError
+	}
+	return nil
+}
+
+func (db *dB) SetComposeReplicationStatus(composeId uuid.UUID, region string, status json.RawMessage) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlSetComposeReplicationStatus, composeId, region, status)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ComposeReplicationNotFoundError
+	}
+	return nil
+}
+
+func (db *dB) InsertComposeEncryption(encryption ComposeEncryptionEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	accounts, err := json.Marshal(encryption.ShareWithAccounts)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(ctx, sqlInsertComposeEncryption, encryption.ComposeId, encryption.Region, encryption.KmsKeyId, accounts)
+	return err
+}
+
+func (db *dB) GetComposeEncryption(composeId uuid.UUID) (*ComposeEncryptionEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var e ComposeEncryptionEntry
+	var accounts json.RawMessage
+	err = conn.QueryRow(ctx, sqlGetComposeEncryption, composeId).Scan(&e.ComposeId, &e.Region, &e.KmsKeyId, &accounts, &e.SourceImageId, &e.ImageId, &e.Status, &e.LastError)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ComposeEncryptionNotFoundError
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(accounts, &e.ShareWithAccounts)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ClaimComposeEncryption claims the encryption of a compose which isn't done
+// or failed, so no one else works on it until encryptionClaimExpiry passed or
+// SetComposeEncryption was called. It returns false if it's finished or
+// claimed already.
+func (db *dB) ClaimComposeEncryption(composeId uuid.UUID) (bool, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	var claimed uuid.UUID
+	err = conn.QueryRow(ctx, sqlClaimComposeEncryption, composeId, encryptionClaimExpiry).Scan(&claimed)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// SetComposeEncryption stores the images and the state of a claimed
+// encryption and releases the claim.
+func (db *dB) SetComposeEncryption(encryption ComposeEncryptionEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlSetComposeEncryption, encryption.ComposeId, encryption.SourceImageId, encryption.ImageId, encryption.Status, encryption.LastError)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ComposeEncryptionNotFoundError
+	}
+	return nil
+}
+
+func (db *dB) GetClonesForCompose(composeId uuid.UUID, orgId string, limit, offset int) ([]CloneEntry, int, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetClonesForCompose, composeId, orgId, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var clones []CloneEntry
+	for rows.Next() {
+		var id uuid.UUID
+		var request json.RawMessage
+		var createdAt time.Time
+		err = rows.Scan(&id, &request, &createdAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		clones = append(clones, CloneEntry{
+			Id:        id,
+			Request:   request,
+			CreatedAt: createdAt,
+		})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var count int
+	err = conn.QueryRow(ctx, sqlCountClonesForCompose, composeId, orgId).Scan(&count)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return clones, count, nil
+
+}
+
+func (db *dB) GetClone(id uuid.UUID, orgId string) (*CloneEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var clone CloneEntry
+	err = conn.QueryRow(ctx, sqlGetClone, id, orgId).Scan(&clone.Id, &clone.Request, &clone.CreatedAt, &clone.ComposeId, &clone.ComposerBackend)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, CloneNotFoundError
+		} else {
+			return nil, err
+		}
+	}
+
+	return &clone, nil
+}
+
+func (db *dB) InsertLaunch(composeId uuid.UUID, reservationId int64, provider string, request json.RawMessage) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlInsertLaunch, reservationId, composeId, provider, request)
+	return err
+}
+
+func (db *dB) GetLaunchesForCompose(composeId uuid.UUID, orgId string, limit, offset int) ([]LaunchEntry, int, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetLaunchesForCompose, composeId, orgId, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var launches []LaunchEntry
+	for rows.Next() {
+		var l LaunchEntry
+		err = rows.Scan(&l.ReservationId, &l.ComposeId, &l.Provider, &l.Request, &l.CreatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		launches = append(launches, l)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var count int
+	err = conn.QueryRow(ctx, sqlCountLaunchesForCompose, composeId, orgId).Scan(&count)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return launches, count, nil
+}
+
+func (db *dB) GetLaunch(reservationId int64, orgId string) (*LaunchEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var l LaunchEntry
+	err = conn.QueryRow(ctx, sqlGetLaunch, reservationId, orgId).Scan(&l.ReservationId, &l.ComposeId, &l.Provider, &l.Request, &l.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, LaunchNotFoundError
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+// GetAWSShareAllowList returns the aws accounts an organization is allowed to
+// share images with. An empty list means there are no restrictions.
+func (db *dB) GetAWSShareAllowList(orgId string) ([]string, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetAWSShareAllowList, orgId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []string
+	for rows.Next() {
+		var account string
+		err = rows.Scan(&account)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// GetIPAllowList returns the networks an organization allows composes to be
+// submitted from. An empty list means there are no restrictions.
+func (db *dB) GetIPAllowList(orgId string) ([]string, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetIPAllowList, orgId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cidrs []string
+	for rows.Next() {
+		var cidr string
+		err = rows.Scan(&cidr)
+		if err != nil {
+			return nil, err
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs, rows.Err()
+}
+
+// SetIPAllowList replaces the ip allow list of an organization.
+func (db *dB) SetIPAllowList(orgId string, cidrs []string) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	_, err = tx.Exec(ctx, sqlDeleteIPAllowList, orgId)
+	if err != nil {
+		return err
+	}
+	for _, cidr := range cidrs {
+		_, err = tx.Exec(ctx, sqlInsertIPAllowList, orgId, cidr)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (db *dB) InsertComposeArtifacts(composeId uuid.UUID, artifacts []ArtifactEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	for _, a := range artifacts {
+		_, err = tx.Exec(ctx, sqlInsertComposeArtifact, composeId, a.Filename, a.Size, a.Sha256, a.CloudImageId, a.Signature)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (db *dB) GetComposeArtifacts(composeId uuid.UUID, orgId string) ([]ArtifactEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetComposeArtifacts, composeId, orgId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var artifacts []ArtifactEntry
+	for rows.Next() {
+		var a ArtifactEntry
+		err = rows.Scan(&a.Filename, &a.Size, &a.Sha256, &a.CloudImageId, &a.Signature)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, a)
+	}
+	return artifacts, rows.Err()
+}
+
+func (db *dB) SetComposeArtifactSignature(composeId uuid.UUID, filename, signature string) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlSetComposeArtifactSignature, composeId, filename, signature)
+	return err
+}
+
+// SetComposeSignatureResult records an attempt to sign the image of a
+// compose, images which are signed already keep their signature.
+func (db *dB) SetComposeSignatureResult(composeId uuid.UUID, status string, reference, lastError *string) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlSetComposeSignatureResult, composeId, status, reference, lastError)
+	return err
+}
+
+func (db *dB) GetComposeSignature(composeId uuid.UUID, orgId string) (*ComposeSignatureEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var sig ComposeSignatureEntry
+	err = conn.QueryRow(ctx, sqlGetComposeSignature, composeId, orgId).Scan(&sig.Reference, &sig.Status, &sig.Attempts, &sig.LastError)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ComposeSignatureNotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sig, nil
+}
+
+func (db *dB) InsertCommitSignature(composeId uuid.UUID, signature CommitSignatureEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlInsertCommitSignature, composeId, signature.Commit, signature.Signature)
+	return err
+}
+
+func (db *dB) GetCommitSignature(composeId uuid.UUID, orgId string) (*CommitSignatureEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var signature CommitSignatureEntry
+	err = conn.QueryRow(ctx, sqlGetCommitSignature, composeId, orgId).Scan(&signature.Commit, &signature.Signature)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, CommitSignatureNotFoundError
+		}
+		return nil, err
+	}
+	return &signature, nil
+}
+
+func (db *dB) InsertVulnerabilityScan(composeId uuid.UUID, scan VulnerabilityScanEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlInsertVulnerabilityScan, composeId, scan.Scanner, scan.Findings)
+	return err
+}
+
+func (db *dB) GetVulnerabilityScan(composeId uuid.UUID, orgId string) (*VulnerabilityScanEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var scan VulnerabilityScanEntry
+	err = conn.QueryRow(ctx, sqlGetVulnerabilityScan, composeId, orgId).Scan(&scan.Scanner, &scan.Findings, &scan.ScannedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, VulnerabilityScanNotFoundError
+		}
+		return nil, err
+	}
+	return &scan, nil
+}
+
+func (db *dB) InsertComposeSBOM(composeId uuid.UUID, format string, document json.RawMessage) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlInsertComposeSBOM, composeId, format, document)
+	return err
+}
+
+func (db *dB) GetComposeSBOM(composeId uuid.UUID, orgId, format string) (json.RawMessage, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var document json.RawMessage
+	err = conn.QueryRow(ctx, sqlGetComposeSBOM, composeId, orgId, format).Scan(&document)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ComposeSBOMNotFoundError
+	}
+	return document, err
+}
+
+func (db *dB) InsertAPIToken(token APITokenEntry, tokenHash string) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlInsertAPIToken, token.Id, token.OrgId, token.AccountNumber, token.Name, tokenHash, token.ReadOnly, token.UserId, token.CreatedBy, token.ExpiresAt)
+	return err
+}
+
+func (db *dB) GetAPITokens(orgId string) ([]APITokenEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetAPITokens, orgId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []APITokenEntry
+	for rows.Next() {
+		var t APITokenEntry
+		err = rows.Scan(&t.Id, &t.OrgId, &t.AccountNumber, &t.Name, &t.ReadOnly, &t.UserId, &t.CreatedBy, &t.CreatedAt, &t.ExpiresAt, &t.LastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+func (db *dB) UseAPIToken(tokenHash string) (*APITokenEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var t APITokenEntry
+	err = conn.QueryRow(ctx, sqlUseAPIToken, tokenHash).Scan(&t.Id, &t.OrgId, &t.AccountNumber, &t.Name, &t.ReadOnly, &t.UserId, &t.CreatedBy, &t.CreatedAt, &t.ExpiresAt, &t.LastUsedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, APITokenNotFoundError
+		} else {
+			return nil, err
+		}
+	}
+	return &t, nil
+}
+
+func (db *dB) RevokeAPIToken(id uuid.UUID, orgId string) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlRevokeAPIToken, orgId, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		return APITokenNotFoundError
+	}
+	return nil
+}
+
+// GetUploadTargetPolicy returns the upload target policy of an org, nil if
+// it doesn't have one.
+func (db *dB) GetUploadTargetPolicy(orgId string) (json.RawMessage, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var targets json.RawMessage
+	err = conn.QueryRow(ctx, sqlGetUploadTargetPolicy, orgId).Scan(&targets)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return targets, nil
+}
+
+func (db *dB) SetUploadTargetPolicy(orgId string, targets json.RawMessage) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlSetUploadTargetPolicy, orgId, targets)
+	return err
+}
+
+func (db *dB) GetQuota(orgId string) (*QuotaEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var q QuotaEntry
+	err = conn.QueryRow(ctx, sqlGetQuota, orgId).Scan(&q.OrgId, &q.BuildsPerDay, &q.BuildsPerMonth, &q.ConcurrentBuilds, &q.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, QuotaNotFoundError
+		}
+		return nil, err
+	}
+	return &q, nil
+}
+
+// SetQuota creates or replaces the quota of an org.
+func (db *dB) SetQuota(quota QuotaEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlSetQuota, quota.OrgId, quota.BuildsPerDay, quota.BuildsPerMonth, quota.ConcurrentBuilds)
+	return err
+}
+
+// InsertQuotaBoost stores a boost which expires after expiresIn, the time of
+// the database is used so replicas agree on when it expires.
+func (db *dB) InsertQuotaBoost(boost QuotaBoostEntry, expiresIn time.Duration) (*QuotaBoostEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sqlInsertQuotaBoost, boost.Id, boost.OrgId, boost.BuildsPerDay, boost.BuildsPerMonth,
+		boost.ConcurrentBuilds, boost.Reason, boost.CreatedBy, expiresIn).Scan(&boost.CreatedAt, &boost.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &boost, nil
+}
+
+// GetQuotaBoosts returns the boosts of an org which haven't expired yet.
+func (db *dB) GetQuotaBoosts(orgId string) ([]QuotaBoostEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetQuotaBoosts, orgId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var boosts []QuotaBoostEntry
+	for rows.Next() {
+		var b QuotaBoostEntry
+		err = rows.Scan(&b.Id, &b.OrgId, &b.BuildsPerDay, &b.BuildsPerMonth, &b.ConcurrentBuilds, &b.Reason, &b.CreatedBy, &b.CreatedAt, &b.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		boosts = append(boosts, b)
+	}
+	return boosts, rows.Err()
+}
+
+func (db *dB) DeleteQuotaBoost(id uuid.UUID, orgId string) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlDeleteQuotaBoost, id, orgId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		return QuotaBoostNotFoundError
+	}
+	return nil
+}
+
+// InsertQueuedCompose stores a compose which hasn't been submitted to composer
+// yet, along with the request to submit and its relations, in one
+// transaction. Composes pending approval aren't submitted until they are
+// approved.
+func (db *dB) InsertQueuedCompose(jobId uuid.UUID, accountNumber, email, orgId string, imageName *string, request, composerRequest json.RawMessage, pendingApproval bool, relations *ComposeRelations, outbox ...OutboxEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	_, err = tx.Exec(ctx, sqlInsertCompose, jobId, request, accountNumber, email, orgId, imageName)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, sqlInsertQueuedCompose, jobId, orgId, composerRequest, pendingApproval, email)
+	if err != nil {
+		return err
+	}
+	if relations != nil {
+		err = insertComposeRelations(ctx, tx, jobId, *relations)
+		if err != nil {
+			return err
+		}
+	}
+	err = insertOutboxEntries(ctx, tx, outbox)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func insertComposeRelations(ctx context.Context, tx pgx.Tx, jobId uuid.UUID, r ComposeRelations) error {
+	if len(r.ReplicationRegions) > 0 {
+		accounts, err := json.Marshal(r.ShareWithAccounts)
+		if err != nil {
+			return err
+		}
+		for _, region := range r.ReplicationRegions {
+			_, err = tx.Exec(ctx, sqlInsertComposeReplication, jobId, region, accounts)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if e := r.Encryption; e != nil {
+		accounts, err := json.Marshal(e.ShareWithAccounts)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, sqlInsertComposeEncryption, jobId, e.Region, e.KmsKeyId, accounts)
+		if err != nil {
+			return err
+		}
+	}
+	if w := r.Webhook; w != nil {
+		_, err := tx.Exec(ctx, sqlInsertWebhook, w.Id, w.OrgId, jobId, w.URL,
+			w.Secret.KEKId, w.Secret.EncryptedDataKey, w.Secret.Ciphertext)
+		if err != nil {
+			return err
+		}
+	}
+	if r.NotifyEmail != nil {
+		_, err := tx.Exec(ctx, sqlSetComposeNotifyEmail, jobId, *r.NotifyEmail)
+		if err != nil {
+			return err
+		}
+	}
+	if k := r.IdempotencyKey; k != nil {
+		_, err := tx.Exec(ctx, sqlSetComposeIdempotencyKey, jobId, k.Key, k.RequestHash)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return IdempotencyKeyExistsError
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *dB) GetQueuedCompose(jobId uuid.UUID) (*QueuedComposeEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	q, err := scanQueuedCompose(conn.QueryRow(ctx, sqlGetQueuedCompose, jobId))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, QueuedComposeNotFoundError
+		}
+		return nil, err
+	}
+	return q, nil
+}
+
+func scanQueuedCompose(row pgx.Row) (*QueuedComposeEntry, error) {
+	var q QueuedComposeEntry
+	err := row.Scan(&q.ComposeId, &q.OrgId, &q.ComposerRequest, &q.CreatedAt, &q.Error, &q.PendingApproval, &q.RequestedBy, &q.ReviewedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+func (db *dB) GetOrgsWithQueuedComposes() ([]string, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetOrgsWithQueuedComposes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []string
+	for rows.Next() {
+		var org string
+		err = rows.Scan(&org)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+// countBuildsInProgress counts the unfinished composes of an org and the
+// builds reserved for it, with the builds of the org locked.
+func countBuildsInProgress(ctx context.Context, tx pgx.Tx, orgId string, unfinished time.Duration) (int, error) {
+	var count, reserved int
+	err := tx.QueryRow(ctx, sqlCountUnfinishedComposesSince, orgId, unfinished, queueClaimExpiry).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	err = tx.QueryRow(ctx, sqlCountBuildReservations, orgId).Scan(&reserved)
+	if err != nil {
+		return 0, err
+	}
+	return count + reserved, nil
+}
+
+// ReserveBuilds returns the usage of an org and reserves builds on top of it,
+// which are counted as composes of today and in progress until they're
+// released. The builds of an org are reserved one after the other, so
+// concurrent requests see each others reservations. Builds which exceed the
+// quota have to be released again.
+func (db *dB) ReserveBuilds(id uuid.UUID, orgId string, builds int, day, month, unfinished time.Duration) (*BuildUsageEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	_, err = tx.Exec(ctx, sqlLockOrgBuilds, orgId)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, sqlDeleteExpiredBuildReservations, orgId)
+	if err != nil {
+		return nil, err
+	}
+
+	var usage BuildUsageEntry
+	var reserved int
+	err = tx.QueryRow(ctx, sqlCountBuildReservations, orgId).Scan(&reserved)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(ctx, sqlCountComposesSince, orgId, day).Scan(&usage.BuildsToday)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(ctx, sqlCountComposesSince, orgId, month).Scan(&usage.BuildsThisMonth)
+	if err != nil {
+		return nil, err
+	}
+	usage.BuildsInProgress, err = countBuildsInProgress(ctx, tx, orgId, unfinished)
+	if err != nil {
+		return nil, err
+	}
+	usage.BuildsToday += reserved
+	usage.BuildsThisMonth += reserved
+	err = tx.QueryRow(ctx, sqlHasQueuedComposes, orgId).Scan(&usage.Queued)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, sqlInsertBuildReservation, id, orgId, builds, buildReservationExpiry)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// ReleaseBuilds deletes a reservation, once its builds are stored as
+// composes or were rejected.
+func (db *dB) ReleaseBuilds(id uuid.UUID) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlDeleteBuildReservation, id)
+	return err
+}
+
+// ClaimQueuedComposes returns up to limit of the oldest queued composes of an
+// org, which no one else is submitting. If the org has a concurrent build
+// limit, only as many are claimed as it has builds left. They're counted
+// with the other claims and reservations of the org locked, so the limit
+// holds across replicas.
+func (db *dB) ClaimQueuedComposes(orgId string, limit int, concurrentBuilds *int, unfinished time.Duration) ([]QueuedComposeEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	_, err = tx.Exec(ctx, sqlLockOrgBuilds, orgId)
+	if err != nil {
+		return nil, err
+	}
+	if concurrentBuilds != nil {
+		_, err = tx.Exec(ctx, sqlDeleteExpiredBuildReservations, orgId)
+		if err != nil {
+			return nil, err
+		}
+		inProgress, err := countBuildsInProgress(ctx, tx, orgId, unfinished)
+		if err != nil {
+			return nil, err
+		}
+		if free := *concurrentBuilds - inProgress; free < limit {
+			limit = free
+		}
+		if limit <= 0 {
+			return nil, nil
+		}
+	}
+
+	rows, err := tx.Query(ctx, sqlClaimQueuedComposes, orgId, limit, queueClaimExpiry)
+	if err != nil {
+		return nil, err
+	}
+	var queued []QueuedComposeEntry
+	for rows.Next() {
+		q, err := scanQueuedCompose(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		queued = append(queued, *q)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the returned rows aren't ordered
+	sort.Slice(queued, func(i, j int) bool {
+		return queued[i].CreatedAt.Before(queued[j].CreatedAt)
+	})
+	return queued, nil
+}
+
+// CompleteQueuedCompose removes a compose from the queue once composer
+// accepted it as composerId.
+func (db *dB) CompleteQueuedCompose(jobId, composerId uuid.UUID) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	tag, err := tx.Exec(ctx, sqlSetComposerJobId, jobId, composerId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		return ComposeNotFoundError
+	}
+	_, err = tx.Exec(ctx, sqlDeleteQueuedCompose, jobId)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// FailQueuedCompose keeps a compose which composer refused in the queue, so
+// its status reports the reason.
+func (db *dB) FailQueuedCompose(jobId uuid.UUID, reason string, outbox ...OutboxEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	tag, err := tx.Exec(ctx, sqlFailQueuedCompose, jobId, reason)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		return QueuedComposeNotFoundError
+	}
+	err = insertOutboxEntries(ctx, tx, outbox)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// CountQueuedComposes counts the composes of an org waiting to be submitted,
+// composes composer refused aren't waiting anymore, and composes pending
+// approval aren't waiting for build slots yet.
+func (db *dB) CountQueuedComposes(orgId string) (int, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	var count int
+	err = conn.QueryRow(ctx, sqlCountQueuedComposes, orgId).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ApproveQueuedCompose lets the queue submit a compose pending approval.
+func (db *dB) ApproveQueuedCompose(jobId uuid.UUID, reviewer string) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlApproveQueuedCompose, jobId, reviewer)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		return ComposeNotPendingApprovalError
+	}
+	return nil
+}
+
+// RejectQueuedCompose fails a compose pending approval, so it's never
+// submitted and its status reports the reason.
+func (db *dB) RejectQueuedCompose(jobId uuid.UUID, reviewer, reason string, outbox ...OutboxEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	tag, err := tx.Exec(ctx, sqlRejectQueuedCompose, jobId, reviewer, reason)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		return ComposeNotPendingApprovalError
+	}
+	err = insertOutboxEntries(ctx, tx, outbox)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetApprovalRequired returns whether the composes of an org need to be
+// approved, orgs which never changed the setting don't require it.
+func (db *dB) GetApprovalRequired(orgId string) (bool, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	var required bool
+	err = conn.QueryRow(ctx, sqlGetApprovalRequired, orgId).Scan(&required)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return required, nil
+}
+
+func (db *dB) SetApprovalRequired(orgId string, required bool) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlSetApprovalRequired, orgId, required)
+	return err
+}
+
+// GetSecretScanningMode returns what's done with compose requests of an org
+// which carry credentials, empty if the org didn't choose.
+func (db *dB) GetSecretScanningMode(orgId string) (string, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Release()
+
+	var mode string
+	err = conn.QueryRow(ctx, sqlGetSecretScanningMode, orgId).Scan(&mode)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return mode, nil
+}
+
+func (db *dB) SetSecretScanningMode(orgId, mode string) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlSetSecretScanningMode, orgId, mode)
+	return err
+}
+
+// GetRepositorySignaturesRequired returns whether the custom repositories of
+// the composes of an org have to be signed, false if the org didn't choose.
+func (db *dB) GetRepositorySignaturesRequired(orgId string) (bool, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	var required bool
+	err = conn.QueryRow(ctx, sqlGetRepositorySignaturesRequired, orgId).Scan(&required)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return required, nil
+}
+
+func (db *dB) SetRepositorySignaturesRequired(orgId string, required bool) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlSetRepositorySignaturesRequired, orgId, required)
+	return err
+}
+
+// GetStorageUsage returns the size of the artifacts of the composes of an org
+// which haven't been deleted, in bytes. Only artifacts which have been stored
+// are accounted for.
+func (db *dB) GetStorageUsage(orgId string) (int64, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	var size int64
+	err = conn.QueryRow(ctx, sqlGetStorageUsage, orgId).Scan(&size)
+	if err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// GetOrgAnalytics sums up the composes created between since and until per
+// org and period, which is day, week or month. All orgs are summed up if
+// orgId is empty. Composes count as succeeded or failed by their last status.
+func (db *dB) GetOrgAnalytics(orgId string, since, until time.Time, period string) ([]OrgAnalyticsEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetOrgAnalytics, orgId, since, until, period)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []OrgAnalyticsEntry
+	for rows.Next() {
+		var e OrgAnalyticsEntry
+		err = rows.Scan(&e.OrgId, &e.PeriodStart, &e.Builds, &e.Succeeded, &e.Failed, &e.Artifacts, &e.ArtifactBytes)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetUsageReport sums up the composes created between since and until per
+// org, month, image type and upload target. All orgs are summed up if orgId
+// is empty.
+func (db *dB) GetUsageReport(orgId string, since, until time.Time) ([]UsageReportEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetUsageReport, orgId, since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []UsageReportEntry
+	for rows.Next() {
+		var e UsageReportEntry
+		err = rows.Scan(&e.OrgId, &e.Month, &e.ImageType, &e.UploadTarget, &e.Builds, &e.Succeeded, &e.Failed,
+			&e.BuildSeconds, &e.ArtifactBytes)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetBuildDurations returns the percentiles of the build durations of the
+// composes of all orgs which first succeeded between since and until. A build
+// lasts from when composer got it, i.e. its creation or its submission from
+// the queue, until it was first seen to succeed.
+func (db *dB) GetBuildDurations(since, until time.Time) ([]BuildDurationEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetBuildDurations, since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []BuildDurationEntry
+	for rows.Next() {
+		var e BuildDurationEntry
+		err = rows.Scan(&e.Distribution, &e.ImageType, &e.Arch, &e.Builds, &e.P50Seconds, &e.P95Seconds)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (db *dB) InsertAuditLogEntry(entry AuditLogEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlInsertAuditLogEntry, entry.OrgId, entry.UserId, entry.Email, entry.Method, entry.Path, entry.ResourceId, entry.RequestDigest, entry.Status, entry.RemoteIP)
+	return err
+}
+
+// GetAuditLog returns a page of the audit log of an org, newest entries first,
+// along with the number of entries.
+func (db *dB) GetAuditLog(orgId string, limit, offset int) ([]AuditLogEntry, int, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetAuditLog, orgId, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	entries, err := scanAuditLog(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var count int
+	err = conn.QueryRow(ctx, sqlCountAuditLog, orgId).Scan(&count)
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, count, nil
+}
+
+// GetAuditLogBetween returns the audit log entries written in [since, until),
+// oldest first, of all orgs if orgId is empty.
+func (db *dB) GetAuditLogBetween(orgId string, since, until time.Time) ([]AuditLogEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetAuditLogBetween, orgId, since, until)
+	if err != nil {
+		return nil, err
+	}
+	return scanAuditLog(rows)
+}
+
+func scanAuditLog(rows pgx.Rows) ([]AuditLogEntry, error) {
+	defer rows.Close()
+
+	var entries []AuditLogEntry
+	for rows.Next() {
+		var e AuditLogEntry
+		err := rows.Scan(&e.Id, &e.OrgId, &e.UserId, &e.Email, &e.Method, &e.Path, &e.ResourceId, &e.RequestDigest, &e.Status, &e.RemoteIP, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (db *dB) InsertWebhook(webhook WebhookEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlInsertWebhook, webhook.Id, webhook.OrgId, webhook.ComposeId, webhook.URL,
+		webhook.Secret.KEKId, webhook.Secret.EncryptedDataKey, webhook.Secret.Ciphertext)
+	return err
+}
+
+// GetWebhooks returns the webhooks of an org, those of single composes
+// included, newest first.
+func (db *dB) GetWebhooks(orgId string) ([]WebhookEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetWebhooks, orgId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []WebhookEntry
+	for rows.Next() {
+		var w WebhookEntry
+		err = rows.Scan(&w.Id, &w.OrgId, &w.ComposeId, &w.URL, &w.Secret.KEKId, &w.Secret.EncryptedDataKey, &w.Secret.Ciphertext, &w.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+func (db *dB) GetWebhook(id uuid.UUID, orgId string) (*WebhookEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var w WebhookEntry
+	err = conn.QueryRow(ctx, sqlGetWebhook, id, orgId).Scan(&w.Id, &w.OrgId, &w.ComposeId, &w.URL, &w.Secret.KEKId, &w.Secret.EncryptedDataKey, &w.Secret.Ciphertext, &w.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, WebhookNotFoundError
+		}
+		return nil, err
+	}
+	return &w, nil
+}
+
+// DeleteWebhook removes a webhook along with its deliveries.
+func (db *dB) DeleteWebhook(id uuid.UUID, orgId string) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlDeleteWebhook, id, orgId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		return WebhookNotFoundError
+	}
+	return nil
+}
+
+// GetOrgsWithWebhooks returns the orgs with webhooks of their own, or of a
+// compose created since.
+func (db *dB) GetOrgsWithWebhooks(since time.Duration) ([]string, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetOrgsWithWebhooks, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []string
+	for rows.Next() {
+		var org string
+		err = rows.Scan(&org)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+// GetUnnotifiedClonesSince returns the clones of an org created since, which
+// webhooks are interested in and no event was stored for yet.
+func (db *dB) GetUnnotifiedClonesSince(orgId string, since time.Duration) ([]CloneEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetUnnotifiedClonesSince, orgId, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clones []CloneEntry
+	for rows.Next() {
+		var c CloneEntry
+		err = rows.Scan(&c.Id, &c.Request, &c.CreatedAt, &c.ComposeId, &c.ComposerBackend)
+		if err != nil {
+			return nil, err
+		}
+		clones = append(clones, c)
+	}
+	return clones, rows.Err()
+}
+
+// InsertWebhookDeliveries stores an event about a compose, or one of its
+// clones, for each webhook of the compose and of its org. Events which were
+// stored before are skipped. Returns the number of deliveries stored.
+func (db *dB) InsertWebhookDeliveries(composeId uuid.UUID, event string, resourceId uuid.UUID, payload json.RawMessage) (int, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	rows, err := tx.Query(ctx, sqlGetWebhooksOfCompose, composeId)
+	if err != nil {
+		return 0, err
+	}
+	var webhooks []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		err = rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		webhooks = append(webhooks, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	inserted := 0
+	for _, webhookId := range webhooks {
+		tag, err := tx.Exec(ctx, sqlInsertWebhookDelivery, uuid.New(), webhookId, event, resourceId, payload)
+		if err != nil {
+			return 0, err
+		}
+		inserted += int(tag.RowsAffected())
+	}
+	return inserted, tx.Commit(ctx)
+}
+
+// ClaimWebhookDeliveries returns up to limit of the pending deliveries which
+// are due, no one else attempts them until webhookClaimExpiry passed or their
+// result is set.
+func (db *dB) ClaimWebhookDeliveries(limit int) ([]WebhookDeliveryEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlClaimWebhookDeliveries, limit, webhookClaimExpiry)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDeliveryEntry
+	for rows.Next() {
+		var d WebhookDeliveryEntry
+		err = rows.Scan(&d.Id, &d.WebhookId, &d.Event, &d.ResourceId, &d.Payload, &d.Status, &d.Attempts, &d.ResponseCode, &d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.DeliveredAt, &d.URL, &d.Secret.KEKId, &d.Secret.EncryptedDataKey, &d.Secret.Ciphertext, &d.OrgId)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// SetWebhookDeliveryResult records an attempt of a delivery, pending
+// deliveries are attempted again in retryIn.
+func (db *dB) SetWebhookDeliveryResult(id uuid.UUID, status string, responseCode *int, lastError *string, retryIn time.Duration) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlSetWebhookDeliveryResult, id, status, responseCode, lastError, retryIn)
+	return err
+}
+
+// GetWebhookDeliveries returns a page of the deliveries of a webhook, newest
+// first, along with the number of deliveries.
+func (db *dB) GetWebhookDeliveries(webhookId uuid.UUID, orgId string, limit, offset int) ([]WebhookDeliveryEntry, int, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetWebhookDeliveries, webhookId, orgId, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDeliveryEntry
+	for rows.Next() {
+		var d WebhookDeliveryEntry
+		err = rows.Scan(&d.Id, &d.WebhookId, &d.Event, &d.ResourceId, &d.Payload, &d.Status, &d.Attempts, &d.ResponseCode, &d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var count int
+	err = conn.QueryRow(ctx, sqlCountWebhookDeliveries, webhookId, orgId).Scan(&count)
+	if err != nil {
+		return nil, 0, err
+	}
+	return deliveries, count, nil
+}
+
+// insertOutboxEntries adds the events of a state change to the outbox, in the
+// transaction making the change.
+func insertOutboxEntries(ctx context.Context, tx pgx.Tx, entries []OutboxEntry) error {
+	for _, e := range entries {
+		if e.Id == uuid.Nil {
+			e.Id = uuid.New()
+		}
+		_, err := tx.Exec(ctx, sqlInsertOutboxEntry, e.Id, e.Sink, e.Event, e.ComposeId, e.Payload)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClaimOutboxEntries returns up to limit pending outbox entries which are due,
+// oldest first. No one else claims them until outboxClaimExpiry passed or
+// their result is set.
+func (db *dB) ClaimOutboxEntries(limit int) ([]OutboxEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlClaimOutboxEntries, limit, outboxClaimExpiry)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []OutboxEntry
+	for rows.Next() {
+		var e OutboxEntry
+		err = rows.Scan(&e.Id, &e.Sink, &e.Event, &e.ComposeId, &e.Payload, &e.Attempts, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// SetOutboxEntryResult records an attempt to dispatch an entry, pending
+// entries are attempted again after retryIn.
+func (db *dB) SetOutboxEntryResult(id uuid.UUID, status string, lastError *string, retryIn time.Duration) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlSetOutboxEntryResult, id, status, lastError, retryIn)
+	return err
+}
+
+// DeleteOutboxEntriesBefore deletes the dispatched and failed entries older
+// than age and returns how many it deleted.
+func (db *dB) DeleteOutboxEntriesBefore(age time.Duration) (int, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlDeleteOutboxEntriesBefore, age)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func (db *dB) GetAWXSettings(orgId string) (*AWXSettingsEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var settings AWXSettingsEntry
+	err = conn.QueryRow(ctx, sqlGetAWXSettings, orgId).Scan(&settings.OrgId, &settings.URL, &settings.Token.KEKId, &settings.Token.EncryptedDataKey, &settings.Token.Ciphertext, &settings.JobTemplateId, &settings.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, AWXSettingsNotFoundError
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (db *dB) SetAWXSettings(settings AWXSettingsEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlSetAWXSettings, settings.OrgId, settings.URL,
+		settings.Token.KEKId, settings.Token.EncryptedDataKey, settings.Token.Ciphertext, settings.JobTemplateId)
+	return err
+}
+
+func (db *dB) DeleteAWXSettings(orgId string) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlDeleteAWXSettings, orgId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return AWXSettingsNotFoundError
+	}
+	return nil
+}
+
+func (db *dB) GetArtifactSigningSettings(orgId string) (*ArtifactSigningSettingsEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var settings ArtifactSigningSettingsEntry
+	err = conn.QueryRow(ctx, sqlGetArtifactSigningSettings, orgId).Scan(&settings.OrgId, &settings.ServiceURL, &settings.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ArtifactSigningSettingsNotFoundError
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (db *dB) SetArtifactSigningSettings(settings ArtifactSigningSettingsEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlSetArtifactSigningSettings, settings.OrgId, settings.ServiceURL)
+	return err
+}
+
+func (db *dB) DeleteArtifactSigningSettings(orgId string) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlDeleteArtifactSigningSettings, orgId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ArtifactSigningSettingsNotFoundError
+	}
+	return nil
+}
+
+// GetPlaintextArtifactSigningSecrets returns the private keys and service
+// tokens of the artifact signing settings stored before they were signing
+// keys.
+func (db *dB) GetPlaintextArtifactSigningSecrets() ([]PlaintextArtifactSigningEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetPlaintextArtifactSigningSecrets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []PlaintextArtifactSigningEntry
+	for rows.Next() {
+		var e PlaintextArtifactSigningEntry
+		err = rows.Scan(&e.OrgId, &e.PrivateKey, &e.ServiceToken)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// MoveArtifactSigningSecrets inserts the signing keys the private key and
+// service token of the artifact signing settings of an org became, and drops
+// them from the settings.
+func (db *dB) MoveArtifactSigningSecrets(orgId string, keys []SigningKeyEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	for _, key := range keys {
+		_, err = tx.Exec(ctx, sqlInsertSigningKey, key.Id, key.OrgId, key.Purpose, key.Fingerprint, key.PublicKey, key.KEKId, key.EncryptedDataKey, key.Ciphertext)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(ctx, sqlDropArtifactSigningSecrets, orgId)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (db *dB) GetPulpSettings(orgId string) (*PulpSettingsEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var settings PulpSettingsEntry
+	err = conn.QueryRow(ctx, sqlGetPulpSettings, orgId).Scan(&settings.OrgId, &settings.ServerAddress, &settings.Repository, &settings.Basepath, &settings.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, PulpSettingsNotFoundError
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (db *dB) SetPulpSettings(settings PulpSettingsEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlSetPulpSettings, settings.OrgId, settings.ServerAddress, settings.Repository, settings.Basepath)
+	return err
+}
+
+func (db *dB) DeletePulpSettings(orgId string) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlDeletePulpSettings, orgId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return PulpSettingsNotFoundError
+	}
+	return nil
+}
+
+func (db *dB) InsertSigningKey(key SigningKeyEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlInsertSigningKey, key.Id, key.OrgId, key.Purpose, key.Fingerprint, key.PublicKey, key.KEKId, key.EncryptedDataKey, key.Ciphertext)
+	return err
+}
+
+func scanSigningKey(row pgx.Row) (*SigningKeyEntry, error) {
+	var key SigningKeyEntry
+	err := row.Scan(&key.Id, &key.OrgId, &key.Purpose, &key.Fingerprint, &key.PublicKey, &key.KEKId, &key.EncryptedDataKey, &key.Ciphertext, &key.CreatedAt, &key.ReplacedBy, &key.RotatedAt, &key.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetSigningKeys returns the keys of an org, newest first.
+func (db *dB) GetSigningKeys(orgId string) ([]SigningKeyEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer conn.Release()
 
-	pool, err := pgxpool.ConnectConfig(context.Background(), dbConfig)
+	rows, err := conn.Query(ctx, sqlGetSigningKeys, orgId)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &dB{pool}, nil
+	var keys []SigningKeyEntry
+	for rows.Next() {
+		key, err := scanSigningKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
 }
 
-func (db *dB) InsertCompose(jobId uuid.UUID, accountNumber, email, orgId string, imageName *string, request json.RawMessage) error {
+func (db *dB) GetSigningKey(id uuid.UUID, orgId string) (*SigningKeyEntry, error) {
 	ctx := context.Background()
 	conn, err := db.Pool.Acquire(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer conn.Release()
 
-	_, err = conn.Exec(ctx, sqlInsertCompose, jobId, request, accountNumber, email, orgId, imageName)
-	return err
+	key, err := scanSigningKey(conn.QueryRow(ctx, sqlGetSigningKey, id, orgId))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, SigningKeyNotFoundError
+	}
+	return key, err
 }
 
-func (db *dB) GetCompose(jobId uuid.UUID, orgId string) (*ComposeEntry, error) {
+// GetSigningKeyInUse returns the key an org signs with for purpose, the one
+// which was neither rotated nor revoked.
+func (db *dB) GetSigningKeyInUse(orgId, purpose string) (*SigningKeyEntry, error) {
 	ctx := context.Background()
 	conn, err := db.Pool.Acquire(ctx)
 	if err != nil {
@@ -152,107 +4496,143 @@ func (db *dB) GetCompose(jobId uuid.UUID, orgId string) (*ComposeEntry, error) {
 	}
 	defer conn.Release()
 
-	result := conn.QueryRow(ctx, sqlGetCompose, orgId, jobId)
+	key, err := scanSigningKey(conn.QueryRow(ctx, sqlGetSigningKeyInUse, orgId, purpose))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, SigningKeyNotFoundError
+	}
+	return key, err
+}
 
-	var compose ComposeEntry
-	err = result.Scan(&compose.Id, &compose.Request, &compose.CreatedAt, &compose.ImageName)
+// RotateSigningKey replaces the key id, which has to be in use, by key.
+func (db *dB) RotateSigningKey(id uuid.UUID, orgId string, key SigningKeyEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ComposeNotFoundError
-		} else {
-			return nil, err
-		}
+		return err
 	}
+	defer conn.Release()
 
-	return &compose, nil
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	tag, err := tx.Exec(ctx, sqlReplaceSigningKey, id, orgId, key.Id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return SigningKeyNotFoundError
+	}
+	_, err = tx.Exec(ctx, sqlInsertSigningKey, key.Id, key.OrgId, key.Purpose, key.Fingerprint, key.PublicKey, key.KEKId, key.EncryptedDataKey, key.Ciphertext)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func (db *dB) GetComposeImageType(jobId uuid.UUID, orgId string) (string, error) {
+func (db *dB) RevokeSigningKey(id uuid.UUID, orgId string) error {
 	ctx := context.Background()
 	conn, err := db.Pool.Acquire(ctx)
 	if err != nil {
-		return "", err
+		return err
 	}
 	defer conn.Release()
 
-	result := conn.QueryRow(ctx, sqlGetComposeImageType, orgId, jobId)
+	tag, err := conn.Exec(ctx, sqlRevokeSigningKey, id, orgId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return SigningKeyNotFoundError
+	}
+	return nil
+}
 
-	var imageType string
-	err = result.Scan(&imageType)
+func (db *dB) InsertSigningKeyUsage(usage SigningKeyUsageEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return "", ComposeNotFoundError
-		} else {
-			return "", err
-		}
+		return err
 	}
+	defer conn.Release()
 
-	return imageType, nil
+	_, err = conn.Exec(ctx, sqlInsertSigningKeyUsage, usage.KeyId, usage.Operation, usage.ResourceId)
+	return err
 }
 
-func (db *dB) GetComposes(orgId string, since time.Duration, limit, offset int, ignoreImageTypes []string) ([]ComposeEntry, int, error) {
+// GetSigningKeyUsage returns a page of what a key signed, newest first,
+// along with the number of times it was used.
+func (db *dB) GetSigningKeyUsage(keyId uuid.UUID, orgId string, limit, offset int) ([]SigningKeyUsageEntry, int, error) {
 	ctx := context.Background()
 	conn, err := db.Pool.Acquire(ctx)
 	if err != nil {
 		return nil, 0, err
 	}
 	defer conn.Release()
-	result, err := conn.Query(ctx, sqlGetComposes, orgId, since, ignoreImageTypes, limit, offset)
 
+	rows, err := conn.Query(ctx, sqlGetSigningKeyUsage, keyId, orgId, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
+	defer rows.Close()
 
-	var composes []ComposeEntry
-	for result.Next() {
-		var jobId uuid.UUID
-		var request json.RawMessage
-		var createdAt time.Time
-		var imageName *string
-		err = result.Scan(&jobId, &request, &createdAt, &imageName)
+	var usage []SigningKeyUsageEntry
+	for rows.Next() {
+		var u SigningKeyUsageEntry
+		err = rows.Scan(&u.Id, &u.KeyId, &u.Operation, &u.ResourceId, &u.UsedAt)
 		if err != nil {
 			return nil, 0, err
 		}
-		composes = append(composes, ComposeEntry{
-			jobId,
-			request,
-			createdAt,
-			imageName,
-		})
+		usage = append(usage, u)
 	}
-	if err = result.Err(); err != nil {
+	if err = rows.Err(); err != nil {
 		return nil, 0, err
 	}
 
 	var count int
-	err = conn.QueryRow(ctx, sqlCountActiveComposesSince, orgId, since, ignoreImageTypes).Scan(&count)
+	err = conn.QueryRow(ctx, sqlCountSigningKeyUsage, keyId, orgId).Scan(&count)
 	if err != nil {
 		return nil, 0, err
 	}
-
-	return composes, count, nil
+	return usage, count, nil
 }
 
-func (db *dB) CountComposesSince(orgId string, duration time.Duration) (int, error) {
+func (db *dB) GetComplianceExportSettings(orgId string) (*ComplianceExportSettingsEntry, error) {
 	ctx := context.Background()
 	conn, err := db.Pool.Acquire(ctx)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	defer conn.Release()
 
-	var count int
-	err = conn.QueryRow(ctx,
-		sqlCountComposesSince,
-		orgId, duration).Scan(&count)
+	var settings ComplianceExportSettingsEntry
+	err = conn.QueryRow(ctx, sqlGetComplianceExportSettings, orgId).Scan(&settings.OrgId, &settings.Bucket, &settings.Region, &settings.Prefix, &settings.VerificationToken, &settings.CreatedAt, &settings.UpdatedAt)
 	if err != nil {
-		return 0, err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ComplianceExportSettingsNotFoundError
+		}
+		return nil, err
 	}
+	return &settings, nil
+}
 
-	return count, nil
+func (db *dB) SetComplianceExportSettings(settings ComplianceExportSettingsEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sqlSetComplianceExportSettings, settings.OrgId, settings.Bucket, settings.Region, settings.Prefix, settings.VerificationToken)
+	return err
 }
 
-func (db *dB) DeleteCompose(jobId uuid.UUID, orgId string) error {
+func (db *dB) DeleteComplianceExportSettings(orgId string) error {
 	ctx := context.Background()
 	conn, err := db.Pool.Acquire(ctx)
 	if err != nil {
@@ -260,15 +4640,141 @@ func (db *dB) DeleteCompose(jobId uuid.UUID, orgId string) error {
 	}
 	defer conn.Release()
 
-	tag, err := conn.Exec(ctx, sqlDeleteCompose, orgId, jobId)
-	if tag.RowsAffected() != 1 {
-		return ComposeNotFoundError
+	tag, err := conn.Exec(ctx, sqlDeleteComplianceExportSettings, orgId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ComplianceExportSettingsNotFoundError
+	}
+	return nil
+}
+
+func (db *dB) GetAllComplianceExportSettings() ([]ComplianceExportSettingsEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetAllComplianceExportSettings)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var settings []ComplianceExportSettingsEntry
+	for rows.Next() {
+		var s ComplianceExportSettingsEntry
+		err = rows.Scan(&s.OrgId, &s.Bucket, &s.Region, &s.Prefix, &s.VerificationToken, &s.CreatedAt, &s.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, s)
+	}
+	return settings, rows.Err()
+}
+
+// GetComposesFinishedBetween returns the composes of an org which succeeded
+// in [since, until), deleted ones included, in the order they finished.
+func (db *dB) GetComposesFinishedBetween(orgId string, since, until time.Time) ([]ComposeEntry, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetComposesFinishedBetween, orgId, since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var composes []ComposeEntry
+	for rows.Next() {
+		var c ComposeEntry
+		err = rows.Scan(&c.Id, &c.Request, &c.CreatedAt, &c.ImageName, &c.ComposerId, &c.ComposerBackend)
+		if err != nil {
+			return nil, err
+		}
+		c.OrgId = orgId
+		composes = append(composes, c)
+	}
+	return composes, rows.Err()
+}
+
+// ClaimComplianceExport claims a window of an org for claimFor, unless it was
+// exported already or another claim hasn't expired yet.
+func (db *dB) ClaimComplianceExport(orgId string, windowStart, windowEnd time.Time, claimFor time.Duration) (bool, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlClaimComplianceExport, orgId, windowStart, windowEnd, claimFor)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// SetComplianceExportResult records an attempt to export a window, failed
+// ones can be claimed again after retryIn.
+func (db *dB) SetComplianceExportResult(orgId string, windowStart time.Time, auditLogEntries, composes int, lastError *string, retryIn time.Duration) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
 	}
+	defer conn.Release()
 
+	_, err = conn.Exec(ctx, sqlSetComplianceExportResult, orgId, windowStart, auditLogEntries, composes, lastError, retryIn)
 	return err
 }
 
-func (db *dB) InsertClone(composeId, cloneId uuid.UUID, request json.RawMessage) error {
+// GetComplianceExports returns a page of the exports of an org, the latest
+// window first, along with the number of exports.
+func (db *dB) GetComplianceExports(orgId string, limit, offset int) ([]ComplianceExportEntry, int, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sqlGetComplianceExports, orgId, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var exports []ComplianceExportEntry
+	for rows.Next() {
+		var e ComplianceExportEntry
+		err = rows.Scan(&e.OrgId, &e.WindowStart, &e.WindowEnd, &e.Attempts, &e.LastError, &e.AuditLogEntries, &e.Composes, &e.ExportedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		exports = append(exports, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var count int
+	err = conn.QueryRow(ctx, sqlCountComplianceExports, orgId).Scan(&count)
+	if err != nil {
+		return nil, 0, err
+	}
+	return exports, count, nil
+}
+
+// SetAWXJobResult records an attempt to launch the job of a compose.
+func (db *dB) SetAWXJobResult(composeId uuid.UUID, orgId string, jobTemplateId int, status string, jobId *int, lastError *string) error {
 	ctx := context.Background()
 	conn, err := db.Pool.Acquire(ctx)
 	if err != nil {
@@ -276,11 +4782,12 @@ func (db *dB) InsertClone(composeId, cloneId uuid.UUID, request json.RawMessage)
 	}
 	defer conn.Release()
 
-	_, err = conn.Exec(ctx, sqlInsertClone, cloneId, composeId, request)
+	_, err = conn.Exec(ctx, sqlSetAWXJobResult, composeId, orgId, jobTemplateId, status, jobId, lastError)
 	return err
 }
 
-func (db *dB) GetClonesForCompose(composeId uuid.UUID, orgId string, limit, offset int) ([]CloneEntry, int, error) {
+// GetAWXJobs returns the jobs of an org, newest first, and how many there are.
+func (db *dB) GetAWXJobs(orgId string, limit, offset int) ([]AWXJobEntry, int, error) {
 	ctx := context.Background()
 	conn, err := db.Pool.Acquire(ctx)
 	if err != nil {
@@ -288,42 +4795,50 @@ func (db *dB) GetClonesForCompose(composeId uuid.UUID, orgId string, limit, offs
 	}
 	defer conn.Release()
 
-	rows, err := conn.Query(ctx, sqlGetClonesForCompose, composeId, orgId, limit, offset)
+	rows, err := conn.Query(ctx, sqlGetAWXJobs, orgId, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var clones []CloneEntry
+	var jobs []AWXJobEntry
 	for rows.Next() {
-		var id uuid.UUID
-		var request json.RawMessage
-		var createdAt time.Time
-		err = rows.Scan(&id, &request, &createdAt)
+		var j AWXJobEntry
+		err = rows.Scan(&j.ComposeId, &j.JobTemplateId, &j.Status, &j.JobId, &j.Attempts, &j.LastError, &j.CreatedAt, &j.LaunchedAt)
 		if err != nil {
 			return nil, 0, err
 		}
-		clones = append(clones, CloneEntry{
-			id,
-			request,
-			createdAt,
-		})
+		jobs = append(jobs, j)
 	}
 	if err = rows.Err(); err != nil {
 		return nil, 0, err
 	}
 
 	var count int
-	err = conn.QueryRow(ctx, sqlCountClonesForCompose, composeId, orgId).Scan(&count)
+	err = conn.QueryRow(ctx, sqlCountAWXJobs, orgId).Scan(&count)
 	if err != nil {
 		return nil, 0, err
 	}
+	return jobs, count, nil
+}
 
-	return clones, count, nil
+// InsertOrgEvent stores an event of a compose, or of one of its clones, for
+// the org of the compose. Events already stored for the resource are ignored.
+func (db *dB) InsertOrgEvent(composeId uuid.UUID, event string, resourceId uuid.UUID, payload json.RawMessage) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
 
+	_, err = conn.Exec(ctx, sqlInsertOrgEvent, composeId, event, resourceId, payload)
+	return err
 }
 
-func (db *dB) GetClone(id uuid.UUID, orgId string) (*CloneEntry, error) {
+// GetOrgEventsAfter returns the events of all orgs stored after the one with
+// afterId, oldest first.
+func (db *dB) GetOrgEventsAfter(afterId int64, limit int) ([]OrgEventEntry, error) {
 	ctx := context.Background()
 	conn, err := db.Pool.Acquire(ctx)
 	if err != nil {
@@ -331,15 +4846,52 @@ func (db *dB) GetClone(id uuid.UUID, orgId string) (*CloneEntry, error) {
 	}
 	defer conn.Release()
 
-	var clone CloneEntry
-	err = conn.QueryRow(ctx, sqlGetClone, id, orgId).Scan(&clone.Id, &clone.Request, &clone.CreatedAt)
+	rows, err := conn.Query(ctx, sqlGetOrgEventsAfter, afterId, limit)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, CloneNotFoundError
-		} else {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []OrgEventEntry
+	for rows.Next() {
+		var e OrgEventEntry
+		err = rows.Scan(&e.Id, &e.OrgId, &e.Event, &e.ResourceId, &e.Payload, &e.CreatedAt)
+		if err != nil {
 			return nil, err
 		}
+		events = append(events, e)
 	}
+	return events, rows.Err()
+}
 
-	return &clone, nil
+// GetLastOrgEventId returns the id of the last stored event, 0 if there are
+// none.
+func (db *dB) GetLastOrgEventId() (int64, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	var id int64
+	err = conn.QueryRow(ctx, sqlGetLastOrgEventId).Scan(&id)
+	return id, err
+}
+
+// DeleteOrgEventsBefore deletes the events older than age and returns how
+// many it deleted.
+func (db *dB) DeleteOrgEventsBefore(age time.Duration) (int, error) {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sqlDeleteOrgEventsBefore, age)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
 }
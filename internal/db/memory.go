@@ -0,0 +1,2636 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// memoryDB keeps everything in memory, for demos and tests of other projects
+// which don't want to run postgres. Its contents are gone once the process
+// exits, and it's only safe to use from a single replica.
+type memoryDB struct {
+	mu sync.Mutex
+
+	// in the order they were inserted, which breaks ties of timestamps
+	composes        []*memoryCompose
+	composesById    map[uuid.UUID]*memoryCompose
+	events          map[uuid.UUID][]ComposeEventEntry
+	clones          []CloneEntry
+	replications    []*memoryReplication
+	encryptions     map[uuid.UUID]*memoryEncryption
+	launches        []LaunchEntry
+	artifacts       map[uuid.UUID][]ArtifactEntry
+	signatures      map[uuid.UUID]ComposeSignatureEntry
+	commitSigs      map[uuid.UUID]CommitSignatureEntry
+	vulnScans       map[uuid.UUID]VulnerabilityScanEntry
+	sboms           map[uuid.UUID]map[string]json.RawMessage
+	awsShareAllow   map[string][]string
+	ipAllow         map[string][]string
+	targetPolicies  map[string]json.RawMessage
+	apiTokens       []*memoryAPIToken
+	quotas          map[string]QuotaEntry
+	quotaBoosts     []QuotaBoostEntry
+	reservations    []memoryReservation
+	queue           []*memoryQueuedCompose
+	approvals       map[string]bool
+	secretScanning  map[string]string
+	repoSignatures  map[string]bool
+	auditLog        []AuditLogEntry
+	webhooks        []WebhookEntry
+	deliveries      []*WebhookDeliveryEntry
+	outbox          []*memoryOutboxEntry
+	awxSettings     map[string]AWXSettingsEntry
+	signingSettings map[string]ArtifactSigningSettingsEntry
+	pulpSettings    map[string]PulpSettingsEntry
+	signingKeys     []SigningKeyEntry
+	keyUsage        []SigningKeyUsageEntry
+	exportSettings  map[string]ComplianceExportSettingsEntry
+	exports         []*memoryComplianceExport
+	awxJobs         []*memoryAWXJob
+	orgEvents       []OrgEventEntry
+	lastAuditLogId  int64
+	lastOrgEventId  int64
+}
+
+type memoryCompose struct {
+	SupportComposeEntry
+	composerId        *uuid.UUID
+	notifyEmail       *string
+	idempotencyKey    *IdempotencyKeyEntry
+	status            json.RawMessage
+	statusRefreshedAt time.Time
+	statusSyncedAt    *time.Time
+}
+
+type memoryReplication struct {
+	ComposeReplicationEntry
+	claimedAt *time.Time
+}
+
+type memoryEncryption struct {
+	ComposeEncryptionEntry
+	claimedAt *time.Time
+}
+
+type memoryAPIToken struct {
+	APITokenEntry
+	hash    string
+	revoked bool
+}
+
+type memoryQueuedCompose struct {
+	QueuedComposeEntry
+	claimedAt *time.Time
+}
+
+type memoryReservation struct {
+	id        uuid.UUID
+	orgId     string
+	builds    int
+	expiresAt time.Time
+}
+
+type memoryOutboxEntry struct {
+	OutboxEntry
+	status        string
+	nextAttemptAt time.Time
+}
+
+type memoryComplianceExport struct {
+	ComplianceExportEntry
+	claimedUntil time.Time
+}
+
+type memoryAWXJob struct {
+	AWXJobEntry
+	orgId string
+}
+
+// NewMemoryDB returns an empty database which lives in memory.
+func NewMemoryDB() DB {
+	return &memoryDB{
+		composesById:    map[uuid.UUID]*memoryCompose{},
+		events:          map[uuid.UUID][]ComposeEventEntry{},
+		encryptions:     map[uuid.UUID]*memoryEncryption{},
+		artifacts:       map[uuid.UUID][]ArtifactEntry{},
+		signatures:      map[uuid.UUID]ComposeSignatureEntry{},
+		commitSigs:      map[uuid.UUID]CommitSignatureEntry{},
+		vulnScans:       map[uuid.UUID]VulnerabilityScanEntry{},
+		sboms:           map[uuid.UUID]map[string]json.RawMessage{},
+		awsShareAllow:   map[string][]string{},
+		ipAllow:         map[string][]string{},
+		targetPolicies:  map[string]json.RawMessage{},
+		quotas:          map[string]QuotaEntry{},
+		approvals:       map[string]bool{},
+		secretScanning:  map[string]string{},
+		repoSignatures:  map[string]bool{},
+		awxSettings:     map[string]AWXSettingsEntry{},
+		signingSettings: map[string]ArtifactSigningSettingsEntry{},
+		pulpSettings:    map[string]PulpSettingsEntry{},
+		exportSettings:  map[string]ComplianceExportSettingsEntry{},
+	}
+}
+
+// now is the time as postgres stores it, in microseconds.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// page returns the bounds of LIMIT limit OFFSET offset of n rows.
+func page(n, limit, offset int) (int, int) {
+	if offset > n {
+		offset = n
+	}
+	end := offset + limit
+	if limit < 0 || end > n {
+		end = n
+	}
+	return offset, end
+}
+
+func (c *memoryCompose) entry() ComposeEntry {
+	e := c.ComposeEntry
+	e.ComposerId = c.Id
+	if c.composerId != nil {
+		e.ComposerId = *c.composerId
+	}
+	return e
+}
+
+// imageType is the type of the first image request, empty if there's none.
+func (c *memoryCompose) imageType() string {
+	var request struct {
+		ImageRequests []struct {
+			ImageType string `json:"image_type"`
+		} `json:"image_requests"`
+	}
+	if json.Unmarshal(c.Request, &request) != nil || len(request.ImageRequests) == 0 {
+		return ""
+	}
+	return request.ImageRequests[0].ImageType
+}
+
+func (c *memoryCompose) ignored(ignoreImageTypes []string) bool {
+	if len(ignoreImageTypes) == 0 {
+		return false
+	}
+	imageType := c.imageType()
+	if imageType == "" {
+		return true
+	}
+	for _, it := range ignoreImageTypes {
+		if it == imageType {
+			return true
+		}
+	}
+	return false
+}
+
+// finished reports whether the compose succeeded or failed, or is still
+// queued, which the queries of unfinished composes leave out.
+func (m *memoryDB) finished(c *memoryCompose) bool {
+	for _, e := range m.events[c.Id] {
+		if e.Status == "success" || e.Status == "failure" {
+			return true
+		}
+	}
+	return m.queued(c.Id) != nil
+}
+
+func (m *memoryDB) queued(id uuid.UUID) *memoryQueuedCompose {
+	for _, q := range m.queue {
+		if q.ComposeId == id {
+			return q
+		}
+	}
+	return nil
+}
+
+// composeOf returns the compose of an org, deleted ones included.
+func (m *memoryDB) composeOf(id uuid.UUID, orgId string) *memoryCompose {
+	c, ok := m.composesById[id]
+	if !ok || c.OrgId != orgId {
+		return nil
+	}
+	return c
+}
+
+// newestFirst sorts the composes by created_at DESC, job_id DESC.
+func newestFirst(composes []*memoryCompose) {
+	sort.SliceStable(composes, func(i, j int) bool {
+		if !composes[i].CreatedAt.Equal(composes[j].CreatedAt) {
+			return composes[i].CreatedAt.After(composes[j].CreatedAt)
+		}
+		return bytes.Compare(composes[i].Id[:], composes[j].Id[:]) > 0
+	})
+}
+
+func (m *memoryDB) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *memoryDB) insertCompose(jobId uuid.UUID, accountNumber, email, orgId string, imageName *string, request json.RawMessage, createdAt time.Time) error {
+	if _, ok := m.composesById[jobId]; ok {
+		return fmt.Errorf("duplicate key value violates unique constraint \"composes_pkey\"")
+	}
+	c := &memoryCompose{
+		SupportComposeEntry: SupportComposeEntry{
+			ComposeEntry: ComposeEntry{
+				Id:        jobId,
+				Request:   request,
+				CreatedAt: createdAt,
+				ImageName: imageName,
+				OrgId:     orgId,
+			},
+			AccountNumber: accountNumber,
+			Email:         &email,
+		},
+	}
+	m.composes = append(m.composes, c)
+	m.composesById[jobId] = c
+	return nil
+}
+
+func (m *memoryDB) insertOutboxEntries(entries []OutboxEntry) {
+	for _, e := range entries {
+		if e.Id == uuid.Nil {
+			e.Id = uuid.New()
+		}
+		e.Attempts = 0
+		e.CreatedAt = now()
+		m.outbox = append(m.outbox, &memoryOutboxEntry{
+			OutboxEntry:   e,
+			status:        OutboxPending,
+			nextAttemptAt: e.CreatedAt,
+		})
+	}
+}
+
+func (m *memoryDB) InsertCompose(jobId uuid.UUID, accountNumber, email, orgId string, imageName *string, request json.RawMessage, outbox ...OutboxEntry) error {
+	return m.InsertComposeWithRelations(jobId, accountNumber, email, orgId, imageName, request, nil, outbox...)
+}
+
+func (m *memoryDB) InsertComposeWithRelations(jobId uuid.UUID, accountNumber, email, orgId string, imageName *string, request json.RawMessage, relations *ComposeRelations, outbox ...OutboxEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err := m.checkComposeRelations(orgId, relations)
+	if err != nil {
+		return err
+	}
+	createdAt := now()
+	err = m.insertCompose(jobId, accountNumber, email, orgId, imageName, request, createdAt)
+	if err != nil {
+		return err
+	}
+	m.insertComposeRelations(jobId, createdAt, relations)
+	m.insertOutboxEntries(outbox)
+	return nil
+}
+
+// checkComposeRelations fails like the insert of relations which can't be
+// stored would, so nothing is stored then. It has to be called with the lock
+// held.
+func (m *memoryDB) checkComposeRelations(orgId string, relations *ComposeRelations) error {
+	if relations == nil {
+		return nil
+	}
+	if relations.Webhook != nil {
+		for _, w := range m.webhooks {
+			if w.Id == relations.Webhook.Id {
+				return fmt.Errorf("duplicate key value violates unique constraint \"webhooks_pkey\"")
+			}
+		}
+	}
+	if relations.IdempotencyKey != nil && m.idempotencyKey(orgId, relations.IdempotencyKey.Key) != nil {
+		return IdempotencyKeyExistsError
+	}
+	return nil
+}
+
+// insertComposeRelations has to be called with the lock held.
+func (m *memoryDB) insertComposeRelations(jobId uuid.UUID, createdAt time.Time, relations *ComposeRelations) {
+	if relations == nil {
+		return
+	}
+	m.insertComposeReplications(jobId, relations.ReplicationRegions, relations.ShareWithAccounts)
+	if relations.Encryption != nil {
+		e := *relations.Encryption
+		e.ComposeId = jobId
+		m.insertComposeEncryption(e)
+	}
+	if relations.Webhook != nil {
+		w := *relations.Webhook
+		w.ComposeId = &jobId
+		w.CreatedAt = createdAt
+		m.webhooks = append(m.webhooks, w)
+	}
+	if relations.NotifyEmail != nil {
+		notifyEmail := *relations.NotifyEmail
+		m.composesById[jobId].notifyEmail = &notifyEmail
+	}
+	if relations.IdempotencyKey != nil {
+		k := *relations.IdempotencyKey
+		k.ComposeId = jobId
+		m.composesById[jobId].idempotencyKey = &k
+	}
+}
+
+// idempotencyKey has to be called with the lock held.
+func (m *memoryDB) idempotencyKey(orgId, key string) *IdempotencyKeyEntry {
+	for _, c := range m.composes {
+		if c.OrgId == orgId && c.idempotencyKey != nil && c.idempotencyKey.Key == key {
+			return c.idempotencyKey
+		}
+	}
+	return nil
+}
+
+func (m *memoryDB) GetIdempotencyKey(orgId, key string) (*IdempotencyKeyEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := m.idempotencyKey(orgId, key)
+	if k == nil {
+		return nil, IdempotencyKeyNotFoundError
+	}
+	entry := *k
+	return &entry, nil
+}
+
+// activeComposes returns the composes of an org created within since which
+// weren't deleted or ignored, newest first.
+func (m *memoryDB) activeComposes(orgId string, since time.Duration, ignoreImageTypes []string) []*memoryCompose {
+	var composes []*memoryCompose
+	for _, c := range m.composes {
+		if c.OrgId == orgId && time.Since(c.CreatedAt) <= since && !c.Deleted && !c.ignored(ignoreImageTypes) {
+			composes = append(composes, c)
+		}
+	}
+	newestFirst(composes)
+	return composes
+}
+
+func (m *memoryDB) GetComposes(orgId string, since time.Duration, limit, offset int, ignoreImageTypes []string) ([]ComposeEntry, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	active := m.activeComposes(orgId, since, ignoreImageTypes)
+	start, end := page(len(active), limit, offset)
+	var composes []ComposeEntry
+	for _, c := range active[start:end] {
+		composes = append(composes, c.entry())
+	}
+	return composes, len(active), nil
+}
+
+func (m *memoryDB) GetComposesAfter(orgId string, since time.Duration, limit int, after *ComposeCursor, ignoreImageTypes []string) ([]ComposeEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var composes []ComposeEntry
+	for _, c := range m.activeComposes(orgId, since, ignoreImageTypes) {
+		if len(composes) == limit {
+			break
+		}
+		if after != nil {
+			if c.CreatedAt.After(after.CreatedAt) {
+				continue
+			}
+			if c.CreatedAt.Equal(after.CreatedAt) && bytes.Compare(c.Id[:], after.Id[:]) >= 0 {
+				continue
+			}
+		}
+		composes = append(composes, c.entry())
+	}
+	return composes, nil
+}
+
+func (m *memoryDB) GetCompose(jobId uuid.UUID, orgId string) (*ComposeEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.composeOf(jobId, orgId)
+	if c == nil || c.Deleted {
+		return nil, ComposeNotFoundError
+	}
+	compose := c.entry()
+	return &compose, nil
+}
+
+func (m *memoryDB) GetComposeImageType(jobId uuid.UUID, orgId string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.composeOf(jobId, orgId)
+	if c == nil {
+		return "", ComposeNotFoundError
+	}
+	imageType := c.imageType()
+	if imageType == "" {
+		return "", ComposeNotFoundError
+	}
+	return imageType, nil
+}
+
+func (m *memoryDB) CountComposesSince(orgId string, duration time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, c := range m.composes {
+		if c.OrgId == orgId && time.Since(c.CreatedAt) <= duration {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *memoryDB) unfinishedComposes(orgId string, duration time.Duration) []*memoryCompose {
+	var composes []*memoryCompose
+	for _, c := range m.composes {
+		if (orgId == "" || c.OrgId == orgId) && time.Since(c.CreatedAt) <= duration && !m.finished(c) {
+			composes = append(composes, c)
+		}
+	}
+	sort.SliceStable(composes, func(i, j int) bool {
+		return composes[i].CreatedAt.Before(composes[j].CreatedAt)
+	})
+	return composes
+}
+
+func (m *memoryDB) CountUnfinishedComposesSince(orgId string, duration time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.countUnfinished(orgId, duration), nil
+}
+
+// countUnfinished counts the unfinished composes of an org and the queued
+// ones which are claimed to be submitted.
+func (m *memoryDB) countUnfinished(orgId string, duration time.Duration) int {
+	count := len(m.unfinishedComposes(orgId, duration))
+	for _, q := range m.queue {
+		if q.OrgId == orgId && q.Error == nil && q.claimedAt != nil && time.Since(*q.claimedAt) <= queueClaimExpiry {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *memoryDB) GetUnfinishedComposesSince(orgId string, duration time.Duration) ([]ComposeEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var composes []ComposeEntry
+	for _, c := range m.unfinishedComposes(orgId, duration) {
+		composes = append(composes, c.entry())
+	}
+	return composes, nil
+}
+
+func (m *memoryDB) GetOrgsWithUnfinishedComposesSince(duration time.Duration) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var orgs []string
+	seen := map[string]bool{}
+	for _, c := range m.unfinishedComposes("", duration) {
+		if !seen[c.OrgId] {
+			seen[c.OrgId] = true
+			orgs = append(orgs, c.OrgId)
+		}
+	}
+	return orgs, nil
+}
+
+func (m *memoryDB) ClaimComposeStatusSync(jobId uuid.UUID, claimFor time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.composes {
+		if c.Id != jobId {
+			continue
+		}
+		if c.statusSyncedAt != nil && now().Sub(*c.statusSyncedAt) <= claimFor {
+			return false, nil
+		}
+		syncedAt := now()
+		c.statusSyncedAt = &syncedAt
+		return true, nil
+	}
+	return false, nil
+}
+
+func (m *memoryDB) DeleteCompose(jobId uuid.UUID, orgId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.composeOf(jobId, orgId)
+	if c == nil {
+		return ComposeNotFoundError
+	}
+	c.Deleted = true
+	return nil
+}
+
+func (m *memoryDB) GetComposeForSupport(jobId uuid.UUID) (*SupportComposeEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.composesById[jobId]
+	if !ok {
+		return nil, ComposeNotFoundError
+	}
+	compose := c.SupportComposeEntry
+	compose.ComposeEntry = c.entry()
+	return &compose, nil
+}
+
+// uploadedAs tells whether composer reported the compose to be uploaded as
+// ami, in the image status or in one of the image statuses.
+func (c *memoryCompose) uploadedAs(ami string) bool {
+	type imageStatus struct {
+		UploadStatus *struct {
+			Options struct {
+				Ami string `json:"ami"`
+			} `json:"options"`
+		} `json:"upload_status"`
+	}
+	var status struct {
+		ImageStatus   imageStatus   `json:"image_status"`
+		ImageStatuses []imageStatus `json:"image_statuses"`
+	}
+	if c.status == nil || json.Unmarshal(c.status, &status) != nil {
+		return false
+	}
+	for _, is := range append(status.ImageStatuses, status.ImageStatus) {
+		if is.UploadStatus != nil && is.UploadStatus.Options.Ami == ami {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memoryDB) SearchComposesForSupport(ami, imageName *string, limit int) ([]SupportComposeEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var composes []SupportComposeEntry
+	for _, c := range m.composesById {
+		if ami != nil && !c.uploadedAs(*ami) {
+			continue
+		}
+		if imageName != nil && (c.ImageName == nil || *c.ImageName != *imageName) {
+			continue
+		}
+		compose := c.SupportComposeEntry
+		compose.ComposeEntry = c.entry()
+		composes = append(composes, compose)
+	}
+	sort.SliceStable(composes, func(i, j int) bool {
+		if !composes[i].CreatedAt.Equal(composes[j].CreatedAt) {
+			return composes[i].CreatedAt.After(composes[j].CreatedAt)
+		}
+		return bytes.Compare(composes[i].Id[:], composes[j].Id[:]) > 0
+	})
+	if len(composes) > limit {
+		composes = composes[:limit]
+	}
+	return composes, nil
+}
+
+func (m *memoryDB) InsertComposeEvent(jobId uuid.UUID, status string, reason *string, outbox ...OutboxEntry) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := m.events[jobId]
+	if len(events) > 0 && events[len(events)-1].Status == status {
+		return false, nil
+	}
+	m.events[jobId] = append(events, ComposeEventEntry{Status: status, Reason: reason, CreatedAt: now()})
+	m.insertOutboxEntries(outbox)
+	return true, nil
+}
+
+func (m *memoryDB) GetComposeEvents(jobId uuid.UUID) ([]ComposeEventEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := append([]ComposeEventEntry(nil), m.events[jobId]...)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].CreatedAt.Before(events[j].CreatedAt)
+	})
+	return events, nil
+}
+
+func (m *memoryDB) SetCachedComposeStatus(jobId uuid.UUID, status json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.composesById[jobId]
+	if !ok {
+		return ComposeNotFoundError
+	}
+	c.status = status
+	c.statusRefreshedAt = now()
+	return nil
+}
+
+func (m *memoryDB) SetComposeBackend(jobId uuid.UUID, backend string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.composesById[jobId]
+	if !ok {
+		return ComposeNotFoundError
+	}
+	c.ComposerBackend = backend
+	return nil
+}
+
+func (m *memoryDB) SetComposeNotifyEmail(jobId uuid.UUID, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.composesById[jobId]
+	if !ok {
+		return ComposeNotFoundError
+	}
+	c.notifyEmail = &email
+	return nil
+}
+
+func (m *memoryDB) GetComposeNotifyEmail(jobId uuid.UUID) (*string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.composesById[jobId]
+	if !ok {
+		return nil, ComposeNotFoundError
+	}
+	return c.notifyEmail, nil
+}
+
+func (m *memoryDB) GetCachedComposeStatus(jobId uuid.UUID, maxAge time.Duration) (*CachedComposeStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.composesById[jobId]
+	if !ok || c.status == nil {
+		return nil, ComposeStatusNotFoundError
+	}
+	return &CachedComposeStatus{
+		Status:      c.status,
+		RefreshedAt: c.statusRefreshedAt,
+		Fresh:       time.Since(c.statusRefreshedAt) <= maxAge,
+	}, nil
+}
+
+func (m *memoryDB) InsertClone(composeId, cloneId uuid.UUID, request json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.composesById[composeId]; !ok {
+		return fmt.Errorf("insert or update on table \"clones\" violates foreign key constraint")
+	}
+	for _, c := range m.clones {
+		if c.Id == cloneId {
+			return fmt.Errorf("duplicate key value violates unique constraint \"clones_pkey\"")
+		}
+	}
+	m.clones = append(m.clones, CloneEntry{Id: cloneId, ComposeId: composeId, Request: request, CreatedAt: now()})
+	return nil
+}
+
+// clone returns c as it's returned by GetClone, with the backend of its
+// compose.
+func (m *memoryDB) clone(c CloneEntry) CloneEntry {
+	c.ComposerBackend = m.composesById[c.ComposeId].ComposerBackend
+	return c
+}
+
+func (m *memoryDB) GetClonesForCompose(composeId uuid.UUID, orgId string, limit, offset int) ([]CloneEntry, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.composeOf(composeId, orgId) == nil {
+		return nil, 0, nil
+	}
+	var all []CloneEntry
+	for _, c := range m.clones {
+		if c.ComposeId == composeId {
+			all = append(all, CloneEntry{Id: c.Id, Request: c.Request, CreatedAt: c.CreatedAt})
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].CreatedAt.After(all[j].CreatedAt)
+	})
+	start, end := page(len(all), limit, offset)
+	var clones []CloneEntry
+	clones = append(clones, all[start:end]...)
+	return clones, len(all), nil
+}
+
+func (m *memoryDB) GetClone(id uuid.UUID, orgId string) (*CloneEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.clones {
+		if c.Id == id && m.composeOf(c.ComposeId, orgId) != nil {
+			clone := m.clone(c)
+			return &clone, nil
+		}
+	}
+	return nil, CloneNotFoundError
+}
+
+func (m *memoryDB) InsertComposeReplications(composeId uuid.UUID, regions, shareWithAccounts []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.composesById[composeId]; !ok {
+		return fmt.Errorf("insert or update on table \"compose_replications\" violates foreign key constraint")
+	}
+	m.insertComposeReplications(composeId, regions, shareWithAccounts)
+	return nil
+}
+
+// insertComposeReplications has to be called with the lock held.
+func (m *memoryDB) insertComposeReplications(composeId uuid.UUID, regions, shareWithAccounts []string) {
+	for _, region := range regions {
+		if m.replication(composeId, region) != nil {
+			continue
+		}
+		m.replications = append(m.replications, &memoryReplication{
+			ComposeReplicationEntry: ComposeReplicationEntry{
+				ComposeId:         composeId,
+				Region:            region,
+				ShareWithAccounts: append([]string{}, shareWithAccounts...),
+			},
+		})
+	}
+}
+
+// replication has to be called with the lock held.
+func (m *memoryDB) replication(composeId uuid.UUID, region string) *memoryReplication {
+	for _, r := range m.replications {
+		if r.ComposeId == composeId && r.Region == region {
+			return r
+		}
+	}
+	return nil
+}
+
+func (m *memoryDB) GetComposeReplications(composeId uuid.UUID, maxAge time.Duration) ([]ComposeReplicationEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var replications []ComposeReplicationEntry
+	for _, r := range m.replications {
+		if r.ComposeId == composeId {
+			e := r.ComposeReplicationEntry
+			e.Fresh = e.StatusRefreshedAt != nil && time.Since(*e.StatusRefreshedAt) <= maxAge
+			replications = append(replications, e)
+		}
+	}
+	sort.SliceStable(replications, func(i, j int) bool {
+		return replications[i].Region < replications[j].Region
+	})
+	return replications, nil
+}
+
+func (m *memoryDB) ClaimComposeReplication(composeId uuid.UUID, region string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := m.replication(composeId, region)
+	if r == nil || r.CloneId != nil || (r.claimedAt != nil && time.Since(*r.claimedAt) <= replicationClaimExpiry) {
+		return false, nil
+	}
+	claimedAt := now()
+	r.claimedAt = &claimedAt
+	return true, nil
+}
+
+func (m *memoryDB) SetComposeReplicationClone(composeId uuid.UUID, region string, cloneId *uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := m.replication(composeId, region)
+	if r == nil {
+		return ComposeReplicationNotFoundError
+	}
+	r.CloneId = cloneId
+	r.claimedAt = nil
+	return nil
+}
+
+func (m *memoryDB) SetComposeReplicationStatus(composeId uuid.UUID, region string, status json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := m.replication(composeId, region)
+	if r == nil {
+		return ComposeReplicationNotFoundError
+	}
+	refreshedAt := now()
+	r.Status = status
+	r.StatusRefreshedAt = &refreshedAt
+	return nil
+}
+
+func (m *memoryDB) InsertComposeEncryption(encryption ComposeEncryptionEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.composesById[encryption.ComposeId]; !ok {
+		return fmt.Errorf("insert or update on table \"compose_encryptions\" violates foreign key constraint")
+	}
+	if _, ok := m.encryptions[encryption.ComposeId]; ok {
+		return fmt.Errorf("duplicate key value violates unique constraint \"compose_encryptions_pkey\"")
+	}
+	m.insertComposeEncryption(encryption)
+	return nil
+}
+
+// insertComposeEncryption has to be called with the lock held.
+func (m *memoryDB) insertComposeEncryption(encryption ComposeEncryptionEntry) {
+	m.encryptions[encryption.ComposeId] = &memoryEncryption{
+		ComposeEncryptionEntry: ComposeEncryptionEntry{
+			ComposeId:         encryption.ComposeId,
+			Region:            encryption.Region,
+			KmsKeyId:          encryption.KmsKeyId,
+			ShareWithAccounts: append([]string{}, encryption.ShareWithAccounts...),
+			Status:            ComposeEncryptionPending,
+		},
+	}
+}
+
+func (m *memoryDB) GetComposeEncryption(composeId uuid.UUID) (*ComposeEncryptionEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.encryptions[composeId]
+	if !ok {
+		return nil, ComposeEncryptionNotFoundError
+	}
+	entry := e.ComposeEncryptionEntry
+	return &entry, nil
+}
+
+func (m *memoryDB) ClaimComposeEncryption(composeId uuid.UUID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.encryptions[composeId]
+	if !ok || (e.Status != ComposeEncryptionPending && e.Status != ComposeEncryptionCopying) ||
+		(e.claimedAt != nil && time.Since(*e.claimedAt) <= encryptionClaimExpiry) {
+		return false, nil
+	}
+	claimedAt := now()
+	e.claimedAt = &claimedAt
+	return true, nil
+}
+
+func (m *memoryDB) SetComposeEncryption(encryption ComposeEncryptionEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.encryptions[encryption.ComposeId]
+	if !ok {
+		return ComposeEncryptionNotFoundError
+	}
+	e.SourceImageId = encryption.SourceImageId
+	e.ImageId = encryption.ImageId
+	e.Status = encryption.Status
+	e.LastError = encryption.LastError
+	e.claimedAt = nil
+	return nil
+}
+
+func (m *memoryDB) InsertLaunch(composeId uuid.UUID, reservationId int64, provider string, request json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.composesById[composeId]; !ok {
+		return fmt.Errorf("insert or update on table \"launches\" violates foreign key constraint")
+	}
+	for _, l := range m.launches {
+		if l.ReservationId == reservationId {
+			return fmt.Errorf("duplicate key value violates unique constraint \"launches_pkey\"")
+		}
+	}
+	m.launches = append(m.launches, LaunchEntry{
+		ReservationId: reservationId,
+		ComposeId:     composeId,
+		Provider:      provider,
+		Request:       request,
+		CreatedAt:     now(),
+	})
+	return nil
+}
+
+func (m *memoryDB) GetLaunchesForCompose(composeId uuid.UUID, orgId string, limit, offset int) ([]LaunchEntry, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.composeOf(composeId, orgId) == nil {
+		return nil, 0, nil
+	}
+	var all []LaunchEntry
+	for _, l := range m.launches {
+		if l.ComposeId == composeId {
+			all = append(all, l)
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].CreatedAt.After(all[j].CreatedAt)
+	})
+	start, end := page(len(all), limit, offset)
+	var launches []LaunchEntry
+	launches = append(launches, all[start:end]...)
+	return launches, len(all), nil
+}
+
+func (m *memoryDB) GetLaunch(reservationId int64, orgId string) (*LaunchEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, l := range m.launches {
+		if l.ReservationId == reservationId && m.composeOf(l.ComposeId, orgId) != nil {
+			launch := l
+			return &launch, nil
+		}
+	}
+	return nil, LaunchNotFoundError
+}
+
+func (m *memoryDB) GetAWSShareAllowList(orgId string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.awsShareAllow[orgId]...), nil
+}
+
+func (m *memoryDB) GetIPAllowList(orgId string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.ipAllow[orgId]...), nil
+}
+
+func (m *memoryDB) SetIPAllowList(orgId string, cidrs []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var list []string
+	seen := map[string]bool{}
+	for _, cidr := range cidrs {
+		if !seen[cidr] {
+			seen[cidr] = true
+			list = append(list, cidr)
+		}
+	}
+	sort.Strings(list)
+	if len(list) == 0 {
+		delete(m.ipAllow, orgId)
+	} else {
+		m.ipAllow[orgId] = list
+	}
+	return nil
+}
+
+func (m *memoryDB) GetUploadTargetPolicy(orgId string) (json.RawMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.targetPolicies[orgId], nil
+}
+
+func (m *memoryDB) SetUploadTargetPolicy(orgId string, targets json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.targetPolicies[orgId] = targets
+	return nil
+}
+
+func (m *memoryDB) InsertComposeArtifacts(composeId uuid.UUID, artifacts []ArtifactEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.composesById[composeId]; !ok && len(artifacts) > 0 {
+		return fmt.Errorf("insert or update on table \"compose_artifacts\" violates foreign key constraint")
+	}
+	stored := m.artifacts[composeId]
+	for _, a := range artifacts {
+		exists := false
+		for _, s := range stored {
+			if s.Filename == a.Filename {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			stored = append(stored, a)
+		}
+	}
+	sort.SliceStable(stored, func(i, j int) bool {
+		return stored[i].Filename < stored[j].Filename
+	})
+	m.artifacts[composeId] = stored
+	return nil
+}
+
+func (m *memoryDB) GetComposeArtifacts(composeId uuid.UUID, orgId string) ([]ArtifactEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.composeOf(composeId, orgId) == nil {
+		return nil, nil
+	}
+	return append([]ArtifactEntry(nil), m.artifacts[composeId]...), nil
+}
+
+func (m *memoryDB) SetComposeArtifactSignature(composeId uuid.UUID, filename, signature string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, a := range m.artifacts[composeId] {
+		if a.Filename == filename {
+			m.artifacts[composeId][i].Signature = &signature
+		}
+	}
+	return nil
+}
+
+func (m *memoryDB) SetComposeSignatureResult(composeId uuid.UUID, status string, reference, lastError *string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.composesById[composeId]; !ok {
+		return fmt.Errorf("insert or update on table \"compose_signatures\" violates foreign key constraint")
+	}
+	sig := m.signatures[composeId]
+	if sig.Status == ComposeSignatureSigned {
+		return nil
+	}
+	m.signatures[composeId] = ComposeSignatureEntry{
+		Reference: reference,
+		Status:    status,
+		Attempts:  sig.Attempts + 1,
+		LastError: lastError,
+	}
+	return nil
+}
+
+func (m *memoryDB) GetComposeSignature(composeId uuid.UUID, orgId string) (*ComposeSignatureEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sig, ok := m.signatures[composeId]
+	if !ok || m.composeOf(composeId, orgId) == nil {
+		return nil, ComposeSignatureNotFoundError
+	}
+	return &sig, nil
+}
+
+func (m *memoryDB) InsertCommitSignature(composeId uuid.UUID, signature CommitSignatureEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.composesById[composeId]; !ok {
+		return fmt.Errorf("insert or update on table \"compose_commit_signatures\" violates foreign key constraint")
+	}
+	if _, ok := m.commitSigs[composeId]; !ok {
+		m.commitSigs[composeId] = signature
+	}
+	return nil
+}
+
+func (m *memoryDB) GetCommitSignature(composeId uuid.UUID, orgId string) (*CommitSignatureEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	signature, ok := m.commitSigs[composeId]
+	if !ok || m.composeOf(composeId, orgId) == nil {
+		return nil, CommitSignatureNotFoundError
+	}
+	return &signature, nil
+}
+
+func (m *memoryDB) InsertVulnerabilityScan(composeId uuid.UUID, scan VulnerabilityScanEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.composesById[composeId]; !ok {
+		return fmt.Errorf("insert or update on table \"compose_vulnerability_scans\" violates foreign key constraint")
+	}
+	if _, ok := m.vulnScans[composeId]; !ok {
+		scan.ScannedAt = time.Now()
+		m.vulnScans[composeId] = scan
+	}
+	return nil
+}
+
+func (m *memoryDB) GetVulnerabilityScan(composeId uuid.UUID, orgId string) (*VulnerabilityScanEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	scan, ok := m.vulnScans[composeId]
+	if !ok || m.composeOf(composeId, orgId) == nil {
+		return nil, VulnerabilityScanNotFoundError
+	}
+	return &scan, nil
+}
+
+func (m *memoryDB) InsertComposeSBOM(composeId uuid.UUID, format string, document json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.composesById[composeId]; !ok {
+		return fmt.Errorf("insert or update on table \"compose_sboms\" violates foreign key constraint")
+	}
+	if m.sboms[composeId] == nil {
+		m.sboms[composeId] = map[string]json.RawMessage{}
+	}
+	if _, ok := m.sboms[composeId][format]; !ok {
+		m.sboms[composeId][format] = document
+	}
+	return nil
+}
+
+func (m *memoryDB) GetComposeSBOM(composeId uuid.UUID, orgId, format string) (json.RawMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	document, ok := m.sboms[composeId][format]
+	if !ok || m.composeOf(composeId, orgId) == nil {
+		return nil, ComposeSBOMNotFoundError
+	}
+	return document, nil
+}
+
+func (m *memoryDB) InsertAPIToken(token APITokenEntry, tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.apiTokens {
+		if t.Id == token.Id || t.hash == tokenHash {
+			return fmt.Errorf("duplicate key value violates unique constraint \"api_tokens_pkey\"")
+		}
+	}
+	token.CreatedAt = now()
+	m.apiTokens = append(m.apiTokens, &memoryAPIToken{APITokenEntry: token, hash: tokenHash})
+	return nil
+}
+
+func (m *memoryDB) GetAPITokens(orgId string) ([]APITokenEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tokens []APITokenEntry
+	for i := len(m.apiTokens) - 1; i >= 0; i-- {
+		t := m.apiTokens[i]
+		if t.OrgId == orgId && !t.revoked {
+			tokens = append(tokens, t.APITokenEntry)
+		}
+	}
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+	})
+	return tokens, nil
+}
+
+func (m *memoryDB) UseAPIToken(tokenHash string) (*APITokenEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.apiTokens {
+		if t.hash == tokenHash && !t.revoked && (t.ExpiresAt == nil || t.ExpiresAt.After(now())) {
+			usedAt := now()
+			t.LastUsedAt = &usedAt
+			token := t.APITokenEntry
+			return &token, nil
+		}
+	}
+	return nil, APITokenNotFoundError
+}
+
+func (m *memoryDB) RevokeAPIToken(id uuid.UUID, orgId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.apiTokens {
+		if t.Id == id && t.OrgId == orgId && !t.revoked {
+			t.revoked = true
+			return nil
+		}
+	}
+	return APITokenNotFoundError
+}
+
+func (m *memoryDB) GetQuota(orgId string) (*QuotaEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q, ok := m.quotas[orgId]
+	if !ok {
+		return nil, QuotaNotFoundError
+	}
+	return &q, nil
+}
+
+func (m *memoryDB) SetQuota(quota QuotaEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	quota.UpdatedAt = now()
+	m.quotas[quota.OrgId] = quota
+	return nil
+}
+
+func (m *memoryDB) InsertQuotaBoost(boost QuotaBoostEntry, expiresIn time.Duration) (*QuotaBoostEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	boost.CreatedAt = now()
+	boost.ExpiresAt = boost.CreatedAt.Add(expiresIn)
+	m.quotaBoosts = append(m.quotaBoosts, boost)
+	return &boost, nil
+}
+
+func (m *memoryDB) GetQuotaBoosts(orgId string) ([]QuotaBoostEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var boosts []QuotaBoostEntry
+	for _, b := range m.quotaBoosts {
+		if b.OrgId == orgId && b.ExpiresAt.After(time.Now()) {
+			boosts = append(boosts, b)
+		}
+	}
+	sort.SliceStable(boosts, func(i, j int) bool {
+		return boosts[i].CreatedAt.Before(boosts[j].CreatedAt)
+	})
+	return boosts, nil
+}
+
+func (m *memoryDB) DeleteQuotaBoost(id uuid.UUID, orgId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, b := range m.quotaBoosts {
+		if b.Id == id && b.OrgId == orgId {
+			m.quotaBoosts = append(m.quotaBoosts[:i], m.quotaBoosts[i+1:]...)
+			return nil
+		}
+	}
+	return QuotaBoostNotFoundError
+}
+
+// reservedBuilds drops the expired reservations and counts the builds
+// reserved for an org.
+func (m *memoryDB) reservedBuilds(orgId string) int {
+	reserved := 0
+	var reservations []memoryReservation
+	for _, r := range m.reservations {
+		if r.expiresAt.Before(time.Now()) {
+			continue
+		}
+		reservations = append(reservations, r)
+		if r.orgId == orgId {
+			reserved += r.builds
+		}
+	}
+	m.reservations = reservations
+	return reserved
+}
+
+func (m *memoryDB) ReserveBuilds(id uuid.UUID, orgId string, builds int, day, month, unfinished time.Duration) (*BuildUsageEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reserved := m.reservedBuilds(orgId)
+	usage := BuildUsageEntry{
+		BuildsToday:      reserved,
+		BuildsThisMonth:  reserved,
+		BuildsInProgress: reserved + m.countUnfinished(orgId, unfinished),
+	}
+	for _, c := range m.composes {
+		if c.OrgId != orgId {
+			continue
+		}
+		if time.Since(c.CreatedAt) <= day {
+			usage.BuildsToday++
+		}
+		if time.Since(c.CreatedAt) <= month {
+			usage.BuildsThisMonth++
+		}
+	}
+	for _, q := range m.queue {
+		if q.OrgId == orgId && q.Error == nil && !q.PendingApproval {
+			usage.Queued = true
+		}
+	}
+
+	m.reservations = append(m.reservations, memoryReservation{
+		id:        id,
+		orgId:     orgId,
+		builds:    builds,
+		expiresAt: now().Add(buildReservationExpiry),
+	})
+	return &usage, nil
+}
+
+func (m *memoryDB) ReleaseBuilds(id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, r := range m.reservations {
+		if r.id == id {
+			m.reservations = append(m.reservations[:i], m.reservations[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (m *memoryDB) InsertQueuedCompose(jobId uuid.UUID, accountNumber, email, orgId string, imageName *string, request, composerRequest json.RawMessage, pendingApproval bool, relations *ComposeRelations, outbox ...OutboxEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err := m.checkComposeRelations(orgId, relations)
+	if err != nil {
+		return err
+	}
+	createdAt := now()
+	err = m.insertCompose(jobId, accountNumber, email, orgId, imageName, request, createdAt)
+	if err != nil {
+		return err
+	}
+	requestedBy := email
+	m.queue = append(m.queue, &memoryQueuedCompose{
+		QueuedComposeEntry: QueuedComposeEntry{
+			ComposeId:       jobId,
+			OrgId:           orgId,
+			ComposerRequest: composerRequest,
+			CreatedAt:       createdAt,
+			PendingApproval: pendingApproval,
+			RequestedBy:     &requestedBy,
+		},
+	})
+	m.insertComposeRelations(jobId, createdAt, relations)
+	m.insertOutboxEntries(outbox)
+	return nil
+}
+
+func (m *memoryDB) GetQueuedCompose(jobId uuid.UUID) (*QueuedComposeEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q := m.queued(jobId)
+	if q == nil {
+		return nil, QueuedComposeNotFoundError
+	}
+	entry := q.QueuedComposeEntry
+	return &entry, nil
+}
+
+func (m *memoryDB) GetOrgsWithQueuedComposes() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var orgs []string
+	seen := map[string]bool{}
+	for _, q := range m.queue {
+		if q.Error == nil && !q.PendingApproval && !seen[q.OrgId] {
+			seen[q.OrgId] = true
+			orgs = append(orgs, q.OrgId)
+		}
+	}
+	return orgs, nil
+}
+
+func (m *memoryDB) ClaimQueuedComposes(orgId string, limit int, concurrentBuilds *int, unfinished time.Duration) ([]QueuedComposeEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if concurrentBuilds != nil {
+		inProgress := m.countUnfinished(orgId, unfinished) + m.reservedBuilds(orgId)
+		if free := *concurrentBuilds - inProgress; free < limit {
+			limit = free
+		}
+	}
+
+	var candidates []*memoryQueuedCompose
+	for _, q := range m.queue {
+		if q.OrgId == orgId && q.Error == nil && !q.PendingApproval && (q.claimedAt == nil || time.Since(*q.claimedAt) > queueClaimExpiry) {
+			candidates = append(candidates, q)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].CreatedAt.Before(candidates[j].CreatedAt)
+	})
+
+	var queued []QueuedComposeEntry
+	claimedAt := now()
+	for _, q := range candidates {
+		if len(queued) >= limit {
+			break
+		}
+		q.claimedAt = &claimedAt
+		queued = append(queued, q.QueuedComposeEntry)
+	}
+	return queued, nil
+}
+
+func (m *memoryDB) removeQueued(jobId uuid.UUID) {
+	for i, q := range m.queue {
+		if q.ComposeId == jobId {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m *memoryDB) CompleteQueuedCompose(jobId, composerId uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.composesById[jobId]
+	if !ok {
+		return ComposeNotFoundError
+	}
+	c.composerId = &composerId
+	m.removeQueued(jobId)
+	return nil
+}
+
+func (m *memoryDB) FailQueuedCompose(jobId uuid.UUID, reason string, outbox ...OutboxEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q := m.queued(jobId)
+	if q == nil {
+		return QueuedComposeNotFoundError
+	}
+	q.Error = &reason
+	q.claimedAt = nil
+	m.insertOutboxEntries(outbox)
+	return nil
+}
+
+func (m *memoryDB) CountQueuedComposes(orgId string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, q := range m.queue {
+		if q.OrgId == orgId && q.Error == nil && !q.PendingApproval {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *memoryDB) ApproveQueuedCompose(jobId uuid.UUID, reviewer string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q := m.queued(jobId)
+	if q == nil || !q.PendingApproval {
+		return ComposeNotPendingApprovalError
+	}
+	q.PendingApproval = false
+	q.ReviewedBy = &reviewer
+	return nil
+}
+
+func (m *memoryDB) RejectQueuedCompose(jobId uuid.UUID, reviewer, reason string, outbox ...OutboxEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q := m.queued(jobId)
+	if q == nil || !q.PendingApproval {
+		return ComposeNotPendingApprovalError
+	}
+	q.PendingApproval = false
+	q.ReviewedBy = &reviewer
+	q.Error = &reason
+	m.insertOutboxEntries(outbox)
+	return nil
+}
+
+func (m *memoryDB) GetApprovalRequired(orgId string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.approvals[orgId], nil
+}
+
+func (m *memoryDB) SetApprovalRequired(orgId string, required bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.approvals[orgId] = required
+	return nil
+}
+
+func (m *memoryDB) GetSecretScanningMode(orgId string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.secretScanning[orgId], nil
+}
+
+func (m *memoryDB) SetSecretScanningMode(orgId, mode string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secretScanning[orgId] = mode
+	return nil
+}
+
+func (m *memoryDB) GetRepositorySignaturesRequired(orgId string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.repoSignatures[orgId], nil
+}
+
+func (m *memoryDB) SetRepositorySignaturesRequired(orgId string, required bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.repoSignatures[orgId] = required
+	return nil
+}
+
+func (m *memoryDB) GetStorageUsage(orgId string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var size int64
+	for id, artifacts := range m.artifacts {
+		c := m.composeOf(id, orgId)
+		if c == nil || c.Deleted {
+			continue
+		}
+		for _, a := range artifacts {
+			if a.Size != nil {
+				size += *a.Size
+			}
+		}
+	}
+	return size, nil
+}
+
+// truncatePeriod truncates t to the start of its day, week or month, as
+// date_trunc does. Weeks start on mondays.
+func truncatePeriod(t time.Time, period string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch period {
+	case "week":
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case "month":
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+func (m *memoryDB) GetOrgAnalytics(orgId string, since, until time.Time, period string) ([]OrgAnalyticsEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	type key struct {
+		orgId string
+		start time.Time
+	}
+	sums := map[key]*OrgAnalyticsEntry{}
+	for id, c := range m.composesById {
+		if (orgId != "" && c.OrgId != orgId) || c.CreatedAt.Before(since) || !c.CreatedAt.Before(until) {
+			continue
+		}
+		k := key{c.OrgId, truncatePeriod(c.CreatedAt, period)}
+		e, ok := sums[k]
+		if !ok {
+			e = &OrgAnalyticsEntry{OrgId: k.orgId, PeriodStart: k.start}
+			sums[k] = e
+		}
+		e.Builds++
+		if events := m.events[id]; len(events) > 0 {
+			switch events[len(events)-1].Status {
+			case "success":
+				e.Succeeded++
+			case "failure":
+				e.Failed++
+			}
+		}
+		for _, a := range m.artifacts[id] {
+			e.Artifacts++
+			if a.Size != nil {
+				e.ArtifactBytes += *a.Size
+			}
+		}
+	}
+
+	var entries []OrgAnalyticsEntry
+	for _, e := range sums {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].OrgId != entries[j].OrgId {
+			return entries[i].OrgId < entries[j].OrgId
+		}
+		return entries[i].PeriodStart.Before(entries[j].PeriodStart)
+	})
+	return entries, nil
+}
+
+func (m *memoryDB) GetUsageReport(orgId string, since, until time.Time) ([]UsageReportEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	type key struct {
+		orgId        string
+		month        time.Time
+		imageType    string
+		uploadTarget string
+	}
+	sums := map[key]*UsageReportEntry{}
+	for id, c := range m.composesById {
+		if (orgId != "" && c.OrgId != orgId) || c.CreatedAt.Before(since) || !c.CreatedAt.Before(until) {
+			continue
+		}
+		var request struct {
+			ImageRequests []struct {
+				ImageType     string `json:"image_type"`
+				UploadRequest struct {
+					Type string `json:"type"`
+				} `json:"upload_request"`
+			} `json:"image_requests"`
+		}
+		// requests which aren't objects have no image type, as in postgres
+		_ = json.Unmarshal(c.Request, &request)
+		k := key{orgId: c.OrgId, month: truncatePeriod(c.CreatedAt, "month")}
+		if len(request.ImageRequests) > 0 {
+			k.imageType = request.ImageRequests[0].ImageType
+			k.uploadTarget = request.ImageRequests[0].UploadRequest.Type
+		}
+		e, ok := sums[k]
+		if !ok {
+			e = &UsageReportEntry{OrgId: k.orgId, Month: k.month, ImageType: k.imageType, UploadTarget: k.uploadTarget}
+			sums[k] = e
+		}
+		e.Builds++
+		events := m.events[id]
+		if len(events) > 0 {
+			switch events[len(events)-1].Status {
+			case "success":
+				e.Succeeded++
+			case "failure":
+				e.Failed++
+			}
+		}
+		for _, ev := range events {
+			if ev.Status == "success" || ev.Status == "failure" {
+				e.BuildSeconds += int64(ev.CreatedAt.Sub(c.CreatedAt) / time.Second)
+				break
+			}
+		}
+		for _, a := range m.artifacts[id] {
+			if a.Size != nil {
+				e.ArtifactBytes += *a.Size
+			}
+		}
+	}
+
+	var entries []UsageReportEntry
+	for _, e := range sums {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.OrgId != b.OrgId {
+			return a.OrgId < b.OrgId
+		}
+		if !a.Month.Equal(b.Month) {
+			return a.Month.Before(b.Month)
+		}
+		if a.ImageType != b.ImageType {
+			return a.ImageType < b.ImageType
+		}
+		return a.UploadTarget < b.UploadTarget
+	})
+	return entries, nil
+}
+
+func (m *memoryDB) GetBuildDurations(since, until time.Time) ([]BuildDurationEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	type key struct {
+		distribution string
+		imageType    string
+		arch         string
+	}
+	durations := map[key][]float64{}
+	for id, c := range m.composesById {
+		var finished *time.Time
+		for _, ev := range m.events[id] {
+			if ev.Status == "success" && (finished == nil || ev.CreatedAt.Before(*finished)) {
+				createdAt := ev.CreatedAt
+				finished = &createdAt
+			}
+		}
+		if finished == nil || finished.Before(since) || !finished.Before(until) {
+			continue
+		}
+		// builds start when composer got them, queued composes only then
+		started := c.CreatedAt
+		for _, ev := range m.events[id] {
+			if ev.Status == "submitted" && ev.CreatedAt.After(started) && !ev.CreatedAt.After(*finished) {
+				started = ev.CreatedAt
+			}
+		}
+		var request struct {
+			Distribution  string `json:"distribution"`
+			ImageRequests []struct {
+				ImageType    string `json:"image_type"`
+				Architecture string `json:"architecture"`
+			} `json:"image_requests"`
+		}
+		_ = json.Unmarshal(c.Request, &request)
+		k := key{distribution: request.Distribution}
+		if len(request.ImageRequests) > 0 {
+			k.imageType = request.ImageRequests[0].ImageType
+			k.arch = request.ImageRequests[0].Architecture
+		}
+		durations[k] = append(durations[k], finished.Sub(started).Seconds())
+	}
+
+	var entries []BuildDurationEntry
+	for k, d := range durations {
+		entries = append(entries, BuildDurationEntry{
+			Distribution: k.distribution,
+			ImageType:    k.imageType,
+			Arch:         k.arch,
+			Builds:       len(d),
+			P50Seconds:   percentile(d, 0.5),
+			P95Seconds:   percentile(d, 0.95),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Distribution != b.Distribution {
+			return a.Distribution < b.Distribution
+		}
+		if a.ImageType != b.ImageType {
+			return a.ImageType < b.ImageType
+		}
+		return a.Arch < b.Arch
+	})
+	return entries, nil
+}
+
+// percentile interpolates between the closest values like percentile_cont of
+// postgres does, values isn't empty.
+func percentile(values []float64, p float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	pos := p * float64(len(sorted)-1)
+	lower := int(pos)
+	if lower == len(sorted)-1 {
+		return sorted[lower]
+	}
+	return sorted[lower] + (pos-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+func (m *memoryDB) InsertAuditLogEntry(entry AuditLogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastAuditLogId++
+	entry.Id = m.lastAuditLogId
+	entry.CreatedAt = now()
+	m.auditLog = append(m.auditLog, entry)
+	return nil
+}
+
+func (m *memoryDB) GetAuditLog(orgId string, limit, offset int) ([]AuditLogEntry, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the ids grow with the time entries are written
+	var all []AuditLogEntry
+	for i := len(m.auditLog) - 1; i >= 0; i-- {
+		if m.auditLog[i].OrgId == orgId {
+			all = append(all, m.auditLog[i])
+		}
+	}
+	start, end := page(len(all), limit, offset)
+	var entries []AuditLogEntry
+	entries = append(entries, all[start:end]...)
+	return entries, len(all), nil
+}
+
+func (m *memoryDB) GetAuditLogBetween(orgId string, since, until time.Time) ([]AuditLogEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []AuditLogEntry
+	for _, e := range m.auditLog {
+		if (orgId == "" || e.OrgId == orgId) && !e.CreatedAt.Before(since) && e.CreatedAt.Before(until) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func (m *memoryDB) InsertWebhook(webhook WebhookEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, w := range m.webhooks {
+		if w.Id == webhook.Id {
+			return fmt.Errorf("duplicate key value violates unique constraint \"webhooks_pkey\"")
+		}
+	}
+	webhook.CreatedAt = now()
+	m.webhooks = append(m.webhooks, webhook)
+	return nil
+}
+
+func (m *memoryDB) GetWebhooks(orgId string) ([]WebhookEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var webhooks []WebhookEntry
+	for i := len(m.webhooks) - 1; i >= 0; i-- {
+		if m.webhooks[i].OrgId == orgId {
+			webhooks = append(webhooks, m.webhooks[i])
+		}
+	}
+	return webhooks, nil
+}
+
+func (m *memoryDB) webhook(id uuid.UUID, orgId string) *WebhookEntry {
+	for i := range m.webhooks {
+		if m.webhooks[i].Id == id && m.webhooks[i].OrgId == orgId {
+			return &m.webhooks[i]
+		}
+	}
+	return nil
+}
+
+func (m *memoryDB) GetWebhook(id uuid.UUID, orgId string) (*WebhookEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := m.webhook(id, orgId)
+	if w == nil {
+		return nil, WebhookNotFoundError
+	}
+	webhook := *w
+	return &webhook, nil
+}
+
+func (m *memoryDB) DeleteWebhook(id uuid.UUID, orgId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, w := range m.webhooks {
+		if w.Id == id && w.OrgId == orgId {
+			m.webhooks = append(m.webhooks[:i], m.webhooks[i+1:]...)
+			var deliveries []*WebhookDeliveryEntry
+			for _, d := range m.deliveries {
+				if d.WebhookId != id {
+					deliveries = append(deliveries, d)
+				}
+			}
+			m.deliveries = deliveries
+			return nil
+		}
+	}
+	return WebhookNotFoundError
+}
+
+func (m *memoryDB) GetOrgsWithWebhooks(since time.Duration) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var orgs []string
+	seen := map[string]bool{}
+	for _, w := range m.webhooks {
+		if (w.ComposeId == nil || time.Since(w.CreatedAt) <= since) && !seen[w.OrgId] {
+			seen[w.OrgId] = true
+			orgs = append(orgs, w.OrgId)
+		}
+	}
+	return orgs, nil
+}
+
+// webhooksOf returns the ids of the webhooks of a compose and of its org.
+func (m *memoryDB) webhooksOf(c *memoryCompose) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, w := range m.webhooks {
+		if w.OrgId == c.OrgId && (w.ComposeId == nil || *w.ComposeId == c.Id) {
+			ids = append(ids, w.Id)
+		}
+	}
+	return ids
+}
+
+func (m *memoryDB) GetUnnotifiedClonesSince(orgId string, since time.Duration) ([]CloneEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var clones []CloneEntry
+	for _, c := range m.clones {
+		compose := m.composeOf(c.ComposeId, orgId)
+		if compose == nil || time.Since(c.CreatedAt) > since || len(m.webhooksOf(compose)) == 0 {
+			continue
+		}
+		notified := false
+		for _, d := range m.deliveries {
+			if d.ResourceId == c.Id {
+				notified = true
+				break
+			}
+		}
+		if !notified {
+			clones = append(clones, m.clone(c))
+		}
+	}
+	return clones, nil
+}
+
+func (m *memoryDB) InsertWebhookDeliveries(composeId uuid.UUID, event string, resourceId uuid.UUID, payload json.RawMessage) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.composesById[composeId]
+	if !ok {
+		return 0, nil
+	}
+	inserted := 0
+	for _, webhookId := range m.webhooksOf(c) {
+		exists := false
+		for _, d := range m.deliveries {
+			if d.WebhookId == webhookId && d.Event == event && d.ResourceId == resourceId {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			continue
+		}
+		createdAt := now()
+		m.deliveries = append(m.deliveries, &WebhookDeliveryEntry{
+			Id:            uuid.New(),
+			WebhookId:     webhookId,
+			Event:         event,
+			ResourceId:    resourceId,
+			Payload:       payload,
+			Status:        WebhookDeliveryPending,
+			NextAttemptAt: createdAt,
+			CreatedAt:     createdAt,
+		})
+		inserted++
+	}
+	return inserted, nil
+}
+
+func (m *memoryDB) ClaimWebhookDeliveries(limit int) ([]WebhookDeliveryEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []*WebhookDeliveryEntry
+	for _, d := range m.deliveries {
+		if d.Status == WebhookDeliveryPending && !d.NextAttemptAt.After(time.Now()) {
+			due = append(due, d)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+
+	var deliveries []WebhookDeliveryEntry
+	for _, d := range due {
+		if len(deliveries) == limit {
+			break
+		}
+		d.NextAttemptAt = now().Add(webhookClaimExpiry)
+		claimed := *d
+		for _, w := range m.webhooks {
+			if w.Id == d.WebhookId {
+				claimed.URL = w.URL
+				claimed.Secret = w.Secret
+				claimed.OrgId = w.OrgId
+			}
+		}
+		deliveries = append(deliveries, claimed)
+	}
+	return deliveries, nil
+}
+
+func (m *memoryDB) SetWebhookDeliveryResult(id uuid.UUID, status string, responseCode *int, lastError *string, retryIn time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, d := range m.deliveries {
+		if d.Id != id {
+			continue
+		}
+		d.Status = status
+		d.Attempts++
+		d.ResponseCode = responseCode
+		d.LastError = lastError
+		d.NextAttemptAt = now().Add(retryIn)
+		d.DeliveredAt = nil
+		if status == WebhookDeliveryDelivered {
+			deliveredAt := now()
+			d.DeliveredAt = &deliveredAt
+		}
+	}
+	return nil
+}
+
+func (m *memoryDB) GetWebhookDeliveries(webhookId uuid.UUID, orgId string, limit, offset int) ([]WebhookDeliveryEntry, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.webhook(webhookId, orgId) == nil {
+		return nil, 0, nil
+	}
+	var all []WebhookDeliveryEntry
+	for i := len(m.deliveries) - 1; i >= 0; i-- {
+		if m.deliveries[i].WebhookId == webhookId {
+			all = append(all, *m.deliveries[i])
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].CreatedAt.After(all[j].CreatedAt)
+	})
+	start, end := page(len(all), limit, offset)
+	var deliveries []WebhookDeliveryEntry
+	deliveries = append(deliveries, all[start:end]...)
+	return deliveries, len(all), nil
+}
+
+func (m *memoryDB) ClaimOutboxEntries(limit int) ([]OutboxEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []OutboxEntry
+	for _, e := range m.outbox {
+		if len(entries) == limit {
+			break
+		}
+		if e.status != OutboxPending || e.nextAttemptAt.After(time.Now()) {
+			continue
+		}
+		e.nextAttemptAt = now().Add(outboxClaimExpiry)
+		entries = append(entries, e.OutboxEntry)
+	}
+	return entries, nil
+}
+
+func (m *memoryDB) SetOutboxEntryResult(id uuid.UUID, status string, lastError *string, retryIn time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.outbox {
+		if e.Id == id {
+			e.status = status
+			e.Attempts++
+			e.nextAttemptAt = now().Add(retryIn)
+		}
+	}
+	return nil
+}
+
+func (m *memoryDB) DeleteOutboxEntriesBefore(age time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var kept []*memoryOutboxEntry
+	for _, e := range m.outbox {
+		if e.status == OutboxPending || time.Since(e.CreatedAt) <= age {
+			kept = append(kept, e)
+		}
+	}
+	deleted := len(m.outbox) - len(kept)
+	m.outbox = kept
+	return deleted, nil
+}
+
+func (m *memoryDB) GetAWXSettings(orgId string) (*AWXSettingsEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	settings, ok := m.awxSettings[orgId]
+	if !ok {
+		return nil, AWXSettingsNotFoundError
+	}
+	return &settings, nil
+}
+
+func (m *memoryDB) SetAWXSettings(settings AWXSettingsEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	settings.UpdatedAt = now()
+	m.awxSettings[settings.OrgId] = settings
+	return nil
+}
+
+func (m *memoryDB) DeleteAWXSettings(orgId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.awxSettings[orgId]; !ok {
+		return AWXSettingsNotFoundError
+	}
+	delete(m.awxSettings, orgId)
+	return nil
+}
+
+func (m *memoryDB) GetArtifactSigningSettings(orgId string) (*ArtifactSigningSettingsEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	settings, ok := m.signingSettings[orgId]
+	if !ok {
+		return nil, ArtifactSigningSettingsNotFoundError
+	}
+	return &settings, nil
+}
+
+func (m *memoryDB) SetArtifactSigningSettings(settings ArtifactSigningSettingsEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	settings.UpdatedAt = now()
+	m.signingSettings[settings.OrgId] = settings
+	return nil
+}
+
+func (m *memoryDB) DeleteArtifactSigningSettings(orgId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.signingSettings[orgId]; !ok {
+		return ArtifactSigningSettingsNotFoundError
+	}
+	delete(m.signingSettings, orgId)
+	return nil
+}
+
+// The memory db never stored private keys and tokens in the settings.
+func (m *memoryDB) GetPlaintextArtifactSigningSecrets() ([]PlaintextArtifactSigningEntry, error) {
+	return nil, nil
+}
+
+func (m *memoryDB) MoveArtifactSigningSecrets(orgId string, keys []SigningKeyEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		err := m.insertSigningKey(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memoryDB) GetPulpSettings(orgId string) (*PulpSettingsEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	settings, ok := m.pulpSettings[orgId]
+	if !ok {
+		return nil, PulpSettingsNotFoundError
+	}
+	return &settings, nil
+}
+
+func (m *memoryDB) SetPulpSettings(settings PulpSettingsEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	settings.UpdatedAt = now()
+	m.pulpSettings[settings.OrgId] = settings
+	return nil
+}
+
+func (m *memoryDB) DeletePulpSettings(orgId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.pulpSettings[orgId]; !ok {
+		return PulpSettingsNotFoundError
+	}
+	delete(m.pulpSettings, orgId)
+	return nil
+}
+
+// insertSigningKey has to be called with the lock held.
+func (m *memoryDB) insertSigningKey(key SigningKeyEntry) error {
+	for _, k := range m.signingKeys {
+		if k.Id == key.Id {
+			return fmt.Errorf("duplicate key value violates unique constraint \"signing_keys_pkey\"")
+		}
+		if k.OrgId == key.OrgId && k.Purpose == key.Purpose && k.ReplacedBy == nil && k.RevokedAt == nil {
+			return fmt.Errorf("duplicate key value violates unique constraint \"signing_keys_in_use_idx\"")
+		}
+	}
+	key.CreatedAt = now()
+	key.ReplacedBy = nil
+	key.RotatedAt = nil
+	key.RevokedAt = nil
+	m.signingKeys = append(m.signingKeys, key)
+	return nil
+}
+
+func (m *memoryDB) InsertSigningKey(key SigningKeyEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.insertSigningKey(key)
+}
+
+func (m *memoryDB) GetSigningKeys(orgId string) ([]SigningKeyEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var keys []SigningKeyEntry
+	for i := len(m.signingKeys) - 1; i >= 0; i-- {
+		if m.signingKeys[i].OrgId == orgId {
+			keys = append(keys, m.signingKeys[i])
+		}
+	}
+	return keys, nil
+}
+
+func (m *memoryDB) GetSigningKey(id uuid.UUID, orgId string) (*SigningKeyEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, k := range m.signingKeys {
+		if k.Id == id && k.OrgId == orgId {
+			return &k, nil
+		}
+	}
+	return nil, SigningKeyNotFoundError
+}
+
+func (m *memoryDB) GetSigningKeyInUse(orgId, purpose string) (*SigningKeyEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, k := range m.signingKeys {
+		if k.OrgId == orgId && k.Purpose == purpose && k.ReplacedBy == nil && k.RevokedAt == nil {
+			return &k, nil
+		}
+	}
+	return nil, SigningKeyNotFoundError
+}
+
+func (m *memoryDB) RotateSigningKey(id uuid.UUID, orgId string, key SigningKeyEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.signingKeys {
+		k := &m.signingKeys[i]
+		if k.Id != id || k.OrgId != orgId || k.ReplacedBy != nil || k.RevokedAt != nil {
+			continue
+		}
+		rotatedAt := now()
+		k.ReplacedBy = &key.Id
+		k.RotatedAt = &rotatedAt
+		err := m.insertSigningKey(key)
+		if err != nil {
+			k.ReplacedBy = nil
+			k.RotatedAt = nil
+		}
+		return err
+	}
+	return SigningKeyNotFoundError
+}
+
+func (m *memoryDB) RevokeSigningKey(id uuid.UUID, orgId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.signingKeys {
+		k := &m.signingKeys[i]
+		if k.Id == id && k.OrgId == orgId && k.RevokedAt == nil {
+			revokedAt := now()
+			k.RevokedAt = &revokedAt
+			return nil
+		}
+	}
+	return SigningKeyNotFoundError
+}
+
+func (m *memoryDB) InsertSigningKeyUsage(usage SigningKeyUsageEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, k := range m.signingKeys {
+		if k.Id == usage.KeyId {
+			usage.Id = int64(len(m.keyUsage) + 1)
+			usage.UsedAt = now()
+			m.keyUsage = append(m.keyUsage, usage)
+			return nil
+		}
+	}
+	return fmt.Errorf("insert or update on table \"signing_key_usage\" violates foreign key constraint")
+}
+
+func (m *memoryDB) GetSigningKeyUsage(keyId uuid.UUID, orgId string, limit, offset int) ([]SigningKeyUsageEntry, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	owned := false
+	for _, k := range m.signingKeys {
+		if k.Id == keyId && k.OrgId == orgId {
+			owned = true
+		}
+	}
+	var all []SigningKeyUsageEntry
+	for i := len(m.keyUsage) - 1; owned && i >= 0; i-- {
+		if m.keyUsage[i].KeyId == keyId {
+			all = append(all, m.keyUsage[i])
+		}
+	}
+	start, end := page(len(all), limit, offset)
+	var usage []SigningKeyUsageEntry
+	usage = append(usage, all[start:end]...)
+	return usage, len(all), nil
+}
+
+func (m *memoryDB) GetComplianceExportSettings(orgId string) (*ComplianceExportSettingsEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	settings, ok := m.exportSettings[orgId]
+	if !ok {
+		return nil, ComplianceExportSettingsNotFoundError
+	}
+	return &settings, nil
+}
+
+func (m *memoryDB) SetComplianceExportSettings(settings ComplianceExportSettingsEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	settings.UpdatedAt = now()
+	settings.CreatedAt = settings.UpdatedAt
+	if existing, ok := m.exportSettings[settings.OrgId]; ok {
+		settings.CreatedAt = existing.CreatedAt
+	}
+	m.exportSettings[settings.OrgId] = settings
+	return nil
+}
+
+func (m *memoryDB) DeleteComplianceExportSettings(orgId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.exportSettings[orgId]; !ok {
+		return ComplianceExportSettingsNotFoundError
+	}
+	delete(m.exportSettings, orgId)
+	return nil
+}
+
+func (m *memoryDB) GetAllComplianceExportSettings() ([]ComplianceExportSettingsEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var settings []ComplianceExportSettingsEntry
+	for _, s := range m.exportSettings {
+		settings = append(settings, s)
+	}
+	sort.Slice(settings, func(i, j int) bool {
+		return settings[i].OrgId < settings[j].OrgId
+	})
+	return settings, nil
+}
+
+func (m *memoryDB) GetComposesFinishedBetween(orgId string, since, until time.Time) ([]ComposeEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	type finished struct {
+		compose ComposeEntry
+		at      time.Time
+	}
+	var composes []finished
+	for _, c := range m.composes {
+		if c.OrgId != orgId {
+			continue
+		}
+		for _, e := range m.events[c.Id] {
+			if e.Status == "success" && !e.CreatedAt.Before(since) && e.CreatedAt.Before(until) {
+				composes = append(composes, finished{c.entry(), e.CreatedAt})
+			}
+		}
+	}
+	sort.SliceStable(composes, func(i, j int) bool {
+		if !composes[i].at.Equal(composes[j].at) {
+			return composes[i].at.Before(composes[j].at)
+		}
+		return composes[i].compose.Id.String() < composes[j].compose.Id.String()
+	})
+	var entries []ComposeEntry
+	for _, c := range composes {
+		entries = append(entries, c.compose)
+	}
+	return entries, nil
+}
+
+func (m *memoryDB) ClaimComplianceExport(orgId string, windowStart, windowEnd time.Time, claimFor time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.exports {
+		if e.OrgId == orgId && e.WindowStart.Equal(windowStart) {
+			if e.ExportedAt != nil || e.claimedUntil.After(now()) {
+				return false, nil
+			}
+			e.claimedUntil = now().Add(claimFor)
+			return true, nil
+		}
+	}
+	m.exports = append(m.exports, &memoryComplianceExport{
+		ComplianceExportEntry: ComplianceExportEntry{
+			OrgId:       orgId,
+			WindowStart: windowStart,
+			WindowEnd:   windowEnd,
+		},
+		claimedUntil: now().Add(claimFor),
+	})
+	return true, nil
+}
+
+func (m *memoryDB) SetComplianceExportResult(orgId string, windowStart time.Time, auditLogEntries, composes int, lastError *string, retryIn time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.exports {
+		if e.OrgId == orgId && e.WindowStart.Equal(windowStart) {
+			e.Attempts++
+			e.AuditLogEntries = auditLogEntries
+			e.Composes = composes
+			e.LastError = lastError
+			e.claimedUntil = now().Add(retryIn)
+			e.ExportedAt = nil
+			if lastError == nil {
+				exportedAt := now()
+				e.ExportedAt = &exportedAt
+			}
+		}
+	}
+	return nil
+}
+
+func (m *memoryDB) GetComplianceExports(orgId string, limit, offset int) ([]ComplianceExportEntry, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var all []ComplianceExportEntry
+	for _, e := range m.exports {
+		if e.OrgId == orgId {
+			all = append(all, e.ComplianceExportEntry)
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].WindowStart.After(all[j].WindowStart)
+	})
+	start, end := page(len(all), limit, offset)
+	var exports []ComplianceExportEntry
+	exports = append(exports, all[start:end]...)
+	return exports, len(all), nil
+}
+
+func (m *memoryDB) SetAWXJobResult(composeId uuid.UUID, orgId string, jobTemplateId int, status string, jobId *int, lastError *string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var launchedAt *time.Time
+	if status == AWXJobLaunched {
+		t := now()
+		launchedAt = &t
+	}
+	for _, j := range m.awxJobs {
+		if j.ComposeId == composeId {
+			j.JobTemplateId = jobTemplateId
+			j.Status = status
+			j.JobId = jobId
+			j.Attempts++
+			j.LastError = lastError
+			j.LaunchedAt = launchedAt
+			return nil
+		}
+	}
+	m.awxJobs = append(m.awxJobs, &memoryAWXJob{
+		AWXJobEntry: AWXJobEntry{
+			ComposeId:     composeId,
+			JobTemplateId: jobTemplateId,
+			Status:        status,
+			JobId:         jobId,
+			Attempts:      1,
+			LastError:     lastError,
+			CreatedAt:     now(),
+			LaunchedAt:    launchedAt,
+		},
+		orgId: orgId,
+	})
+	return nil
+}
+
+func (m *memoryDB) GetAWXJobs(orgId string, limit, offset int) ([]AWXJobEntry, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var all []AWXJobEntry
+	for i := len(m.awxJobs) - 1; i >= 0; i-- {
+		if m.awxJobs[i].orgId == orgId {
+			all = append(all, m.awxJobs[i].AWXJobEntry)
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].CreatedAt.After(all[j].CreatedAt)
+	})
+	start, end := page(len(all), limit, offset)
+	var jobs []AWXJobEntry
+	jobs = append(jobs, all[start:end]...)
+	return jobs, len(all), nil
+}
+
+func (m *memoryDB) InsertOrgEvent(composeId uuid.UUID, event string, resourceId uuid.UUID, payload json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.composesById[composeId]
+	if !ok {
+		return nil
+	}
+	for _, e := range m.orgEvents {
+		if e.Event == event && e.ResourceId == resourceId {
+			return nil
+		}
+	}
+	m.lastOrgEventId++
+	m.orgEvents = append(m.orgEvents, OrgEventEntry{
+		Id:         m.lastOrgEventId,
+		OrgId:      c.OrgId,
+		Event:      event,
+		ResourceId: resourceId,
+		Payload:    payload,
+		CreatedAt:  now(),
+	})
+	return nil
+}
+
+func (m *memoryDB) GetOrgEventsAfter(afterId int64, limit int) ([]OrgEventEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var events []OrgEventEntry
+	for _, e := range m.orgEvents {
+		if len(events) == limit {
+			break
+		}
+		if e.Id > afterId {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (m *memoryDB) GetLastOrgEventId() (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.orgEvents) == 0 {
+		return 0, nil
+	}
+	return m.orgEvents[len(m.orgEvents)-1].Id, nil
+}
+
+func (m *memoryDB) DeleteOrgEventsBefore(age time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var kept []OrgEventEntry
+	for _, e := range m.orgEvents {
+		if time.Since(e.CreatedAt) <= age {
+			kept = append(kept, e)
+		}
+	}
+	deleted := len(m.orgEvents) - len(kept)
+	m.orgEvents = kept
+	return deleted, nil
+}
@@ -0,0 +1,293 @@
+package db
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryComposes(t *testing.T) {
+	d := NewMemoryDB()
+	name := "my-image"
+	aws := json.RawMessage(`{"image_requests": [{"image_type": "aws"}]}`)
+	guest := json.RawMessage(`{"image_requests": [{"image_type": "guest-image"}]}`)
+
+	var ids []uuid.UUID
+	for i := 0; i < 3; i++ {
+		id := uuid.New()
+		ids = append(ids, id)
+		require.NoError(t, d.InsertCompose(id, "0000001", "user@example.com", "000001", &name, aws))
+		time.Sleep(time.Millisecond)
+	}
+	require.Error(t, d.InsertCompose(ids[0], "0000001", "user@example.com", "000001", &name, aws))
+	require.NoError(t, d.InsertCompose(uuid.New(), "0000002", "user@example.com", "000002", nil, guest))
+
+	composes, count, err := d.GetComposes("000001", 14*24*time.Hour, 2, 0, nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+	require.Len(t, composes, 2)
+	require.Equal(t, ids[2], composes[0].Id)
+	require.Equal(t, ids[2], composes[0].ComposerId)
+	require.Equal(t, "000001", composes[0].OrgId)
+
+	composes, err = d.GetComposesAfter("000001", 14*24*time.Hour, 10, &ComposeCursor{CreatedAt: composes[1].CreatedAt, Id: composes[1].Id}, nil)
+	require.NoError(t, err)
+	require.Len(t, composes, 1)
+	require.Equal(t, ids[0], composes[0].Id)
+
+	_, count, err = d.GetComposes("000001", 14*24*time.Hour, 10, 0, []string{"aws"})
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	imageType, err := d.GetComposeImageType(ids[0], "000001")
+	require.NoError(t, err)
+	require.Equal(t, "aws", imageType)
+
+	require.NoError(t, d.DeleteCompose(ids[0], "000001"))
+	require.ErrorIs(t, d.DeleteCompose(ids[0], "000002"), ComposeNotFoundError)
+	_, err = d.GetCompose(ids[0], "000001")
+	require.ErrorIs(t, err, ComposeNotFoundError)
+	support, err := d.GetComposeForSupport(ids[0])
+	require.NoError(t, err)
+	require.True(t, support.Deleted)
+	count, err = d.CountComposesSince("000001", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	// composes are unfinished until they succeed or fail
+	inserted, err := d.InsertComposeEvent(ids[1], "building", nil)
+	require.NoError(t, err)
+	require.True(t, inserted)
+	inserted, err = d.InsertComposeEvent(ids[1], "building", nil)
+	require.NoError(t, err)
+	require.False(t, inserted)
+	_, err = d.InsertComposeEvent(ids[1], "success", nil, OutboxEntry{Sink: "kafka", Event: "compose.succeeded", ComposeId: ids[1]})
+	require.NoError(t, err)
+	unfinished, err := d.CountUnfinishedComposesSince("000001", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 2, unfinished)
+	claimed, err := d.ClaimComposeStatusSync(ids[2], time.Minute)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	claimed, err = d.ClaimComposeStatusSync(ids[2], time.Minute)
+	require.NoError(t, err)
+	require.False(t, claimed)
+	events, err := d.GetComposeEvents(ids[1])
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	entries, err := d.ClaimOutboxEntries(10)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	entries, err = d.ClaimOutboxEntries(10)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	_, err = d.GetCachedComposeStatus(ids[1], time.Minute)
+	require.ErrorIs(t, err, ComposeStatusNotFoundError)
+	require.NoError(t, d.SetCachedComposeStatus(ids[1], json.RawMessage(`{"status": "success"}`)))
+	cached, err := d.GetCachedComposeStatus(ids[1], time.Minute)
+	require.NoError(t, err)
+	require.True(t, cached.Fresh)
+}
+
+func TestMemoryQueueRelations(t *testing.T) {
+	d := NewMemoryDB()
+	composeId := uuid.New()
+	webhook := WebhookEntry{Id: uuid.New(), OrgId: "000001", URL: "https://example.com/compose"}
+	notifyEmail := "notify@example.com"
+	require.NoError(t, d.InsertQueuedCompose(composeId, "0000001", "user@example.com", "000001", nil, json.RawMessage(`{}`), json.RawMessage(`{}`), false, &ComposeRelations{
+		ReplicationRegions: []string{"eu-west-1"},
+		ShareWithAccounts:  []string{"123456789012"},
+		Encryption:         &ComposeEncryptionEntry{Region: "us-east-1", KmsKeyId: "arn:aws:kms:us-east-1:123456789012:key/1"},
+		Webhook:            &webhook,
+		NotifyEmail:        &notifyEmail,
+	}))
+
+	replications, err := d.GetComposeReplications(composeId, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, replications, 1)
+	require.Equal(t, "eu-west-1", replications[0].Region)
+	encryption, err := d.GetComposeEncryption(composeId)
+	require.NoError(t, err)
+	require.Equal(t, composeId, encryption.ComposeId)
+	hook, err := d.GetWebhook(webhook.Id, "000001")
+	require.NoError(t, err)
+	require.Equal(t, composeId, *hook.ComposeId)
+	email, err := d.GetComposeNotifyEmail(composeId)
+	require.NoError(t, err)
+	require.Equal(t, notifyEmail, *email)
+
+	// the webhook exists already, so the compose isn't queued either
+	other := uuid.New()
+	require.Error(t, d.InsertQueuedCompose(other, "0000001", "user@example.com", "000001", nil, json.RawMessage(`{}`), json.RawMessage(`{}`), false, &ComposeRelations{Webhook: &webhook}))
+	_, err = d.GetQueuedCompose(other)
+	require.ErrorIs(t, err, QueuedComposeNotFoundError)
+}
+
+func TestMemoryIdempotencyKeys(t *testing.T) {
+	d := NewMemoryDB()
+	composeId := uuid.New()
+	key := IdempotencyKeyEntry{Key: "key", RequestHash: "hash"}
+	require.NoError(t, d.InsertComposeWithRelations(composeId, "0000001", "user@example.com", "000001", nil, json.RawMessage(`{}`), &ComposeRelations{IdempotencyKey: &key}))
+	entry, err := d.GetIdempotencyKey("000001", "key")
+	require.NoError(t, err)
+	require.Equal(t, IdempotencyKeyEntry{ComposeId: composeId, Key: "key", RequestHash: "hash"}, *entry)
+	_, err = d.GetIdempotencyKey("000002", "key")
+	require.ErrorIs(t, err, IdempotencyKeyNotFoundError)
+
+	other := uuid.New()
+	require.ErrorIs(t, d.InsertQueuedCompose(other, "0000001", "user@example.com", "000001", nil, json.RawMessage(`{}`), json.RawMessage(`{}`), false, &ComposeRelations{IdempotencyKey: &key}), IdempotencyKeyExistsError)
+	_, err = d.GetCompose(other, "000001")
+	require.ErrorIs(t, err, ComposeNotFoundError)
+	require.NoError(t, d.InsertQueuedCompose(other, "0000002", "user@example.com", "000002", nil, json.RawMessage(`{}`), json.RawMessage(`{}`), false, &ComposeRelations{IdempotencyKey: &key}))
+}
+
+func TestMemoryQueue(t *testing.T) {
+	d := NewMemoryDB()
+	queued := uuid.New()
+	pending := uuid.New()
+	require.NoError(t, d.InsertQueuedCompose(queued, "0000001", "user@example.com", "000001", nil, json.RawMessage(`{}`), json.RawMessage(`{}`), false, nil))
+	require.NoError(t, d.InsertQueuedCompose(pending, "0000001", "user@example.com", "000001", nil, json.RawMessage(`{}`), json.RawMessage(`{}`), true, nil))
+
+	count, err := d.CountQueuedComposes("000001")
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	unfinished, err := d.CountUnfinishedComposesSince("000001", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 0, unfinished)
+
+	claimed, err := d.ClaimQueuedComposes("000001", 10, nil, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, queued, claimed[0].ComposeId)
+	claimed, err = d.ClaimQueuedComposes("000001", 10, nil, time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+	unfinished, err = d.CountUnfinishedComposesSince("000001", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 1, unfinished)
+
+	composerId := uuid.New()
+	require.NoError(t, d.CompleteQueuedCompose(queued, composerId))
+	compose, err := d.GetCompose(queued, "000001")
+	require.NoError(t, err)
+	require.Equal(t, composerId, compose.ComposerId)
+	_, err = d.GetQueuedCompose(queued)
+	require.ErrorIs(t, err, QueuedComposeNotFoundError)
+
+	require.NoError(t, d.RejectQueuedCompose(pending, "reviewer@example.com", "not now"))
+	require.ErrorIs(t, d.ApproveQueuedCompose(pending, "reviewer@example.com"), ComposeNotPendingApprovalError)
+	q, err := d.GetQueuedCompose(pending)
+	require.NoError(t, err)
+	require.Equal(t, "not now", *q.Error)
+	require.Equal(t, "user@example.com", *q.RequestedBy)
+}
+
+func TestMemoryBuildReservations(t *testing.T) {
+	d := NewMemoryDB()
+	queued := uuid.New()
+	require.NoError(t, d.InsertQueuedCompose(queued, "0000001", "user@example.com", "000001", nil, json.RawMessage(`{}`), json.RawMessage(`{}`), false, nil))
+
+	reservation := uuid.New()
+	usage, err := d.ReserveBuilds(reservation, "000001", 2, time.Hour, time.Hour, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, &BuildUsageEntry{BuildsToday: 1, BuildsThisMonth: 1, Queued: true}, usage)
+	usage, err = d.ReserveBuilds(uuid.New(), "000001", 1, time.Hour, time.Hour, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, &BuildUsageEntry{BuildsToday: 3, BuildsThisMonth: 3, BuildsInProgress: 2, Queued: true}, usage)
+
+	limit := 3
+	claimed, err := d.ClaimQueuedComposes("000001", 10, &limit, time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+	require.NoError(t, d.ReleaseBuilds(reservation))
+	claimed, err = d.ClaimQueuedComposes("000001", 10, &limit, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+}
+
+func TestMemoryWebhooks(t *testing.T) {
+	d := NewMemoryDB()
+	composeId := uuid.New()
+	cloneId := uuid.New()
+	require.NoError(t, d.InsertCompose(composeId, "0000001", "user@example.com", "000001", nil, json.RawMessage(`{}`)))
+	require.NoError(t, d.InsertClone(composeId, cloneId, json.RawMessage(`{}`)))
+
+	clones, err := d.GetUnnotifiedClonesSince("000001", time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, clones)
+
+	webhook := WebhookEntry{Id: uuid.New(), OrgId: "000001", URL: "https://example.com/hook"}
+	require.NoError(t, d.InsertWebhook(webhook))
+	clones, err = d.GetUnnotifiedClonesSince("000001", time.Hour)
+	require.NoError(t, err)
+	require.Len(t, clones, 1)
+
+	inserted, err := d.InsertWebhookDeliveries(composeId, "clone.succeeded", cloneId, json.RawMessage(`{}`))
+	require.NoError(t, err)
+	require.Equal(t, 1, inserted)
+	inserted, err = d.InsertWebhookDeliveries(composeId, "clone.succeeded", cloneId, json.RawMessage(`{}`))
+	require.NoError(t, err)
+	require.Equal(t, 0, inserted)
+	clones, err = d.GetUnnotifiedClonesSince("000001", time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, clones)
+
+	deliveries, err := d.ClaimWebhookDeliveries(10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, webhook.URL, deliveries[0].URL)
+	require.NoError(t, d.SetWebhookDeliveryResult(deliveries[0].Id, WebhookDeliveryDelivered, nil, nil, 0))
+	deliveries, count, err := d.GetWebhookDeliveries(webhook.Id, "000001", 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Equal(t, WebhookDeliveryDelivered, deliveries[0].Status)
+	require.NotNil(t, deliveries[0].DeliveredAt)
+
+	require.NoError(t, d.DeleteWebhook(webhook.Id, "000001"))
+	_, count, err = d.GetWebhookDeliveries(webhook.Id, "000001", 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+}
+
+func TestMemoryOrgEvents(t *testing.T) {
+	d := NewMemoryDB()
+	composeId := uuid.New()
+	require.NoError(t, d.InsertCompose(composeId, "0000001", "user@example.com", "000001", nil, json.RawMessage(`{}`)))
+
+	require.NoError(t, d.InsertOrgEvent(composeId, "compose.succeeded", composeId, json.RawMessage(`{}`)))
+	require.NoError(t, d.InsertOrgEvent(composeId, "compose.succeeded", composeId, json.RawMessage(`{}`)))
+	last, err := d.GetLastOrgEventId()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), last)
+
+	events, err := d.GetOrgEventsAfter(0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, "000001", events[0].OrgId)
+	deleted, err := d.DeleteOrgEventsBefore(0)
+	require.NoError(t, err)
+	require.Equal(t, 1, deleted)
+}
+
+func TestTruncatePeriod(t *testing.T) {
+	// a wednesday
+	ts := time.Date(2024, 5, 15, 13, 4, 5, 0, time.UTC)
+	require.Equal(t, time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), truncatePeriod(ts, "day"))
+	require.Equal(t, time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC), truncatePeriod(ts, "week"))
+	require.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), truncatePeriod(ts, "month"))
+	// weeks start on mondays, sundays are their last day
+	require.Equal(t, time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC), truncatePeriod(time.Date(2024, 5, 19, 23, 0, 0, 0, time.UTC), "week"))
+	require.Equal(t, time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC), truncatePeriod(time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC), "week"))
+}
+
+func TestPercentile(t *testing.T) {
+	require.Equal(t, 5.0, percentile([]float64{5}, 0.95))
+	require.Equal(t, 20.0, percentile([]float64{30, 10, 20}, 0.5))
+	require.InDelta(t, 29.0, percentile([]float64{30, 10, 20}, 0.95), 1e-9)
+	require.Equal(t, 15.0, percentile([]float64{10, 20}, 0.5))
+}
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS aws_share_allowlist(
+       org_id varchar NOT NULL,
+       account_id varchar(12) NOT NULL,
+
+       PRIMARY KEY (org_id, account_id)
+);
@@ -0,0 +1,11 @@
+-- cloud images are only known by their identifier, their size and checksum
+-- aren't
+CREATE TABLE IF NOT EXISTS compose_artifacts(
+       compose_id uuid NOT NULL REFERENCES composes(job_id) ON DELETE CASCADE,
+       filename varchar NOT NULL,
+       size bigint,
+       sha256 varchar(64),
+       cloud_image_id varchar,
+
+       PRIMARY KEY (compose_id, filename)
+);
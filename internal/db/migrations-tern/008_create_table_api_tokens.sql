@@ -0,0 +1,18 @@
+-- api tokens act on behalf of the org and user who created them, what the
+-- user is allowed to do is looked up on every use
+CREATE TABLE IF NOT EXISTS api_tokens(
+       id uuid PRIMARY KEY,
+       org_id varchar NOT NULL,
+       account_number varchar,
+       name varchar NOT NULL,
+       token_hash varchar(64) NOT NULL UNIQUE,
+       read_only boolean NOT NULL DEFAULT FALSE,
+       user_id varchar,
+       created_by varchar,
+       created_at timestamp NOT NULL,
+       expires_at timestamp,
+       last_used_at timestamp,
+       revoked boolean NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS api_tokens_org_id_idx ON api_tokens(org_id);
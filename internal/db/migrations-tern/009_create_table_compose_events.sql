@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS compose_events(
+       compose_id uuid NOT NULL REFERENCES composes(job_id) ON DELETE CASCADE,
+       status varchar NOT NULL,
+       created_at timestamp NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS compose_events_compose_id_idx ON compose_events(compose_id, created_at);
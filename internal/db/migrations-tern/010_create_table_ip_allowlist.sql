@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS ip_allowlist(
+       org_id varchar NOT NULL,
+       cidr varchar NOT NULL,
+
+       PRIMARY KEY (org_id, cidr)
+);
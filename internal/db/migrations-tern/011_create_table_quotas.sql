@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS quotas(
+       org_id varchar PRIMARY KEY,
+       builds_per_day integer,
+       builds_per_month integer,
+       concurrent_builds integer,
+       updated_at timestamp NOT NULL
+);
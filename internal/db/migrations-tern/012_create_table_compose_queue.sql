@@ -0,0 +1,27 @@
+-- composes which were queued get submitted to composer later, under a
+-- different id
+ALTER TABLE composes ADD COLUMN IF NOT EXISTS composer_job_id uuid;
+
+CREATE TABLE IF NOT EXISTS compose_queue(
+       compose_id uuid PRIMARY KEY REFERENCES composes(job_id) ON DELETE CASCADE,
+       org_id varchar NOT NULL,
+       composer_request jsonb NOT NULL,
+       created_at timestamp NOT NULL,
+       claimed_at timestamp,
+       error varchar
+);
+
+CREATE INDEX IF NOT EXISTS compose_queue_org_id_created_at_idx ON compose_queue(org_id, created_at);
+
+-- builds which passed the quota of an org but aren't stored as composes yet,
+-- they're counted against the quota while composer is asked to build them.
+-- Reservations are deleted once the composes are stored, they expire in case
+-- a replica died before.
+CREATE TABLE IF NOT EXISTS build_reservations(
+       id uuid PRIMARY KEY,
+       org_id varchar NOT NULL,
+       builds integer NOT NULL,
+       expires_at timestamp NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS build_reservations_org_id_idx ON build_reservations(org_id);
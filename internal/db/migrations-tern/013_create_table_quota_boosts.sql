@@ -0,0 +1,14 @@
+-- temporary increases of the quota of an org, granted by support
+CREATE TABLE IF NOT EXISTS quota_boosts(
+       id uuid PRIMARY KEY,
+       org_id varchar NOT NULL,
+       builds_per_day integer NOT NULL DEFAULT 0,
+       builds_per_month integer NOT NULL DEFAULT 0,
+       concurrent_builds integer NOT NULL DEFAULT 0,
+       reason varchar NOT NULL,
+       created_by varchar NOT NULL,
+       created_at timestamp NOT NULL,
+       expires_at timestamp NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS quota_boosts_org_id_expires_at_idx ON quota_boosts(org_id, expires_at);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS upload_target_policies(
+       org_id varchar PRIMARY KEY,
+       targets jsonb NOT NULL,
+       updated_at timestamp NOT NULL
+);
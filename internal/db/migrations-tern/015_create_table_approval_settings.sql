@@ -0,0 +1,11 @@
+-- orgs can require composes to be approved by a second user, those composes
+-- wait in the queue until they are approved or rejected
+CREATE TABLE IF NOT EXISTS approval_settings(
+       org_id varchar PRIMARY KEY,
+       require_approval boolean NOT NULL,
+       updated_at timestamp NOT NULL
+);
+
+ALTER TABLE compose_queue ADD COLUMN IF NOT EXISTS pending_approval boolean NOT NULL DEFAULT FALSE;
+ALTER TABLE compose_queue ADD COLUMN IF NOT EXISTS requested_by varchar;
+ALTER TABLE compose_queue ADD COLUMN IF NOT EXISTS reviewed_by varchar;
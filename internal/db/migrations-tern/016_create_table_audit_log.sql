@@ -0,0 +1,28 @@
+-- mutating api calls, rows can't be changed or removed once written
+CREATE TABLE IF NOT EXISTS audit_log(
+       id bigserial PRIMARY KEY,
+       org_id varchar NOT NULL,
+       user_id varchar NOT NULL,
+       email varchar NOT NULL,
+       method varchar NOT NULL,
+       path varchar NOT NULL,
+       resource_id varchar,
+       request_digest varchar NOT NULL,
+       status integer NOT NULL,
+       remote_ip varchar NOT NULL,
+       created_at timestamp NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_org_id_created_at_idx ON audit_log(org_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON audit_log(created_at);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+       RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+       BEFORE UPDATE OR DELETE ON audit_log
+       FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
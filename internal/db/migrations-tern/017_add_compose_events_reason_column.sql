@@ -0,0 +1 @@
+ALTER TABLE compose_events ADD COLUMN IF NOT EXISTS reason varchar;
@@ -0,0 +1,2 @@
+ALTER TABLE composes ADD COLUMN IF NOT EXISTS status jsonb;
+ALTER TABLE composes ADD COLUMN IF NOT EXISTS status_refreshed_at timestamp;
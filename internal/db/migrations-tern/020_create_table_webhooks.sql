@@ -0,0 +1,36 @@
+-- endpoints notified when composes and clones of an org finish, or only
+-- those of one compose if compose_id is set. The secret signs the events, it's
+-- encrypted with the keystore like the keys of orgs.
+CREATE TABLE IF NOT EXISTS webhooks(
+       id uuid PRIMARY KEY,
+       org_id varchar NOT NULL,
+       compose_id uuid REFERENCES composes(job_id) ON DELETE CASCADE,
+       url varchar NOT NULL,
+       secret_kek_id varchar NOT NULL,
+       secret_data_key bytea NOT NULL,
+       secret_ciphertext bytea NOT NULL,
+       created_at timestamp NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_org_id_idx ON webhooks(org_id);
+
+-- each event is delivered once to each webhook, pending deliveries are
+-- attempted again once next_attempt_at passed
+CREATE TABLE IF NOT EXISTS webhook_deliveries(
+       id uuid PRIMARY KEY,
+       webhook_id uuid NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+       event varchar NOT NULL,
+       resource_id uuid NOT NULL,
+       payload jsonb NOT NULL,
+       status varchar NOT NULL,
+       attempts integer NOT NULL DEFAULT 0,
+       response_code integer,
+       last_error varchar,
+       next_attempt_at timestamp NOT NULL,
+       created_at timestamp NOT NULL,
+       delivered_at timestamp,
+       UNIQUE (webhook_id, event, resource_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_pending_idx ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx ON webhook_deliveries(webhook_id, created_at);
@@ -0,0 +1,3 @@
+-- address the outcome of the compose is emailed to, on deployments with smtp
+-- configured
+ALTER TABLE composes ADD COLUMN IF NOT EXISTS notify_email varchar;
@@ -0,0 +1,20 @@
+-- events of compose state changes, one row per sink they go to. They're
+-- written in the same transaction as the change and dispatched in the
+-- background, so they aren't lost if the service dies in between. Dispatched
+-- rows are kept for a while for support.
+CREATE TABLE IF NOT EXISTS outbox(
+       id uuid PRIMARY KEY,
+       sink varchar NOT NULL,
+       event varchar NOT NULL,
+       compose_id uuid NOT NULL,
+       payload jsonb NOT NULL,
+       status varchar NOT NULL,
+       attempts integer NOT NULL DEFAULT 0,
+       last_error varchar,
+       next_attempt_at timestamp NOT NULL,
+       created_at timestamp NOT NULL,
+       dispatched_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS outbox_created_at_idx ON outbox(created_at);
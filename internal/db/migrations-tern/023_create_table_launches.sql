@@ -0,0 +1,11 @@
+-- instances of composes launched through the provisioning service, which
+-- tracks the launch as a reservation
+CREATE TABLE IF NOT EXISTS launches(
+       reservation_id bigint PRIMARY KEY,
+       compose_id uuid NOT NULL REFERENCES composes(job_id) ON DELETE CASCADE,
+       provider varchar NOT NULL,
+       request jsonb NOT NULL,
+       created_at timestamp NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS launches_compose_id_idx ON launches(compose_id, created_at);
@@ -0,0 +1,28 @@
+-- the AWX or Ansible Controller job template an org launches once one of its
+-- composes succeeded. The token authenticates the launches, it's encrypted
+-- with the keystore like the keys of orgs.
+CREATE TABLE IF NOT EXISTS awx_settings(
+       org_id varchar PRIMARY KEY,
+       url varchar NOT NULL,
+       token_kek_id varchar NOT NULL,
+       token_data_key bytea NOT NULL,
+       token_ciphertext bytea NOT NULL,
+       job_template_id integer NOT NULL,
+       updated_at timestamp NOT NULL
+);
+
+-- the job launched for a compose, or the outcome of the last attempt to
+-- launch it
+CREATE TABLE IF NOT EXISTS awx_jobs(
+       compose_id uuid PRIMARY KEY REFERENCES composes(job_id) ON DELETE CASCADE,
+       org_id varchar NOT NULL,
+       job_template_id integer NOT NULL,
+       status varchar NOT NULL,
+       job_id integer,
+       attempts integer NOT NULL DEFAULT 0,
+       last_error varchar,
+       created_at timestamp NOT NULL,
+       launched_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS awx_jobs_org_id_idx ON awx_jobs(org_id, created_at);
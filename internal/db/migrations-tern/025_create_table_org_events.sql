@@ -0,0 +1,15 @@
+-- events of the composes and clones of an org, streamed to the clients
+-- connected to the websocket of the org. The serial id orders them across
+-- the replicas, which each stream the ones after the last they saw. They're
+-- only kept for a day.
+CREATE TABLE IF NOT EXISTS org_events(
+       id bigserial PRIMARY KEY,
+       org_id varchar NOT NULL,
+       event varchar NOT NULL,
+       resource_id uuid NOT NULL,
+       payload jsonb NOT NULL,
+       created_at timestamp NOT NULL,
+       UNIQUE (event, resource_id)
+);
+
+CREATE INDEX IF NOT EXISTS org_events_created_at_idx ON org_events(created_at);
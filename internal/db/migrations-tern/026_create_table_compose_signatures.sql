@@ -0,0 +1,11 @@
+-- cosign signatures of the container images composes pushed, stored in the
+-- registry of the image at reference. Images are signed in the background
+-- and retried, the reference is only set once the image is signed.
+CREATE TABLE IF NOT EXISTS compose_signatures(
+       compose_id uuid PRIMARY KEY REFERENCES composes(job_id) ON DELETE CASCADE,
+       status varchar NOT NULL,
+       reference varchar,
+       attempts integer NOT NULL DEFAULT 0,
+       last_error varchar,
+       created_at timestamp NOT NULL
+);
@@ -0,0 +1,10 @@
+-- software bills of materials of composes, generated from the package list
+-- of the compose the first time they're asked for
+CREATE TABLE IF NOT EXISTS compose_sboms(
+       compose_id uuid NOT NULL REFERENCES composes(job_id) ON DELETE CASCADE,
+       format varchar NOT NULL,
+       document jsonb NOT NULL,
+       created_at timestamp NOT NULL,
+
+       PRIMARY KEY (compose_id, format)
+);
@@ -0,0 +1,12 @@
+-- how an org signs the artifacts of its composes, with its private key or by
+-- its signing service. Both are needed to sign, so they're stored as is.
+CREATE TABLE IF NOT EXISTS artifact_signing_settings(
+       org_id varchar PRIMARY KEY,
+       private_key varchar,
+       service_url varchar,
+       service_token varchar,
+       updated_at timestamp NOT NULL
+);
+
+-- ASCII armored detached signature of the artifact
+ALTER TABLE compose_artifacts ADD COLUMN IF NOT EXISTS signature varchar;
@@ -0,0 +1,7 @@
+-- binary OpenPGP signatures of the ostree commits edge-commit composes built
+CREATE TABLE IF NOT EXISTS compose_commit_signatures(
+       compose_id uuid PRIMARY KEY REFERENCES composes(job_id) ON DELETE CASCADE,
+       commit varchar(64) NOT NULL,
+       signature bytea NOT NULL,
+       created_at timestamp NOT NULL
+);
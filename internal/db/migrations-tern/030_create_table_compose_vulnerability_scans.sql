@@ -0,0 +1,8 @@
+-- findings of the scanner the packages of successful composes were submitted
+-- to, kept as the scanner reported them at the time
+CREATE TABLE IF NOT EXISTS compose_vulnerability_scans(
+       compose_id uuid PRIMARY KEY REFERENCES composes(job_id) ON DELETE CASCADE,
+       scanner varchar NOT NULL,
+       findings jsonb NOT NULL,
+       scanned_at timestamp NOT NULL
+);
@@ -0,0 +1,7 @@
+-- whether compose requests with credentials in their customizations are only
+-- warned about or rejected, orgs without settings are warned
+CREATE TABLE IF NOT EXISTS secret_scanning_settings(
+       org_id varchar PRIMARY KEY,
+       mode varchar NOT NULL,
+       updated_at timestamp NOT NULL
+);
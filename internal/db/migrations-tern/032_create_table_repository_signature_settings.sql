@@ -0,0 +1,7 @@
+-- orgs can require the custom repositories of their composes to check gpg
+-- signatures and to have metadata signed by the keys they come with
+CREATE TABLE IF NOT EXISTS repository_signature_settings(
+       org_id varchar PRIMARY KEY,
+       require_signatures boolean NOT NULL,
+       updated_at timestamp NOT NULL
+);
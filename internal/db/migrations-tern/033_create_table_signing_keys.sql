@@ -0,0 +1,32 @@
+-- signing keys of orgs, encrypted with a data key of their own which is in
+-- turn encrypted with a key encryption key of the service
+CREATE TABLE IF NOT EXISTS signing_keys(
+       id uuid PRIMARY KEY,
+       org_id varchar NOT NULL,
+       purpose varchar NOT NULL,
+       fingerprint varchar,
+       public_key varchar,
+       kek_id varchar NOT NULL,
+       encrypted_data_key bytea NOT NULL,
+       ciphertext bytea NOT NULL,
+       created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+       -- rotating inserts the new key after marking the old one
+       replaced_by uuid REFERENCES signing_keys(id) DEFERRABLE INITIALLY DEFERRED,
+       rotated_at timestamp,
+       revoked_at timestamp
+);
+
+-- an org signs with a single key of each purpose
+CREATE UNIQUE INDEX IF NOT EXISTS signing_keys_in_use_idx ON signing_keys(org_id, purpose)
+       WHERE replaced_by IS NULL AND revoked_at IS NULL;
+
+-- what the keys signed, e.g. the artifacts of a compose
+CREATE TABLE IF NOT EXISTS signing_key_usage(
+       id bigserial PRIMARY KEY,
+       key_id uuid NOT NULL REFERENCES signing_keys(id),
+       operation varchar NOT NULL,
+       resource_id uuid,
+       used_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS signing_key_usage_key_id_idx ON signing_key_usage(key_id, used_at);
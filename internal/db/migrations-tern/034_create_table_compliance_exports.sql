@@ -0,0 +1,29 @@
+-- the bucket the audit log and the provenance of the composes of an org are
+-- exported to. The verification object in the bucket has to hold the token
+-- before anything is exported to it, so orgs can't have the service write
+-- into the buckets of others.
+CREATE TABLE IF NOT EXISTS compliance_export_settings(
+       org_id varchar PRIMARY KEY,
+       bucket varchar NOT NULL,
+       region varchar NOT NULL,
+       prefix varchar NOT NULL,
+       verification_token varchar NOT NULL,
+       created_at timestamp NOT NULL,
+       updated_at timestamp NOT NULL
+);
+
+-- the windows exported for an org. A window is claimed by the instance
+-- exporting it until claimed_until, failed exports are retried once their
+-- claim expired.
+CREATE TABLE IF NOT EXISTS compliance_exports(
+       org_id varchar NOT NULL,
+       window_start timestamp NOT NULL,
+       window_end timestamp NOT NULL,
+       attempts integer NOT NULL DEFAULT 0,
+       last_error varchar,
+       audit_log_entries integer NOT NULL DEFAULT 0,
+       composes integer NOT NULL DEFAULT 0,
+       claimed_until timestamp NOT NULL,
+       exported_at timestamp,
+       PRIMARY KEY(org_id, window_start)
+);
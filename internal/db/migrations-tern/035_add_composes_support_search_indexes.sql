@@ -0,0 +1,4 @@
+-- support looks composes up by their image name and by the ami they were
+-- uploaded as, regardless of the org they belong to
+CREATE INDEX IF NOT EXISTS composes_image_name_idx ON composes(image_name);
+CREATE INDEX IF NOT EXISTS composes_status_idx ON composes USING GIN (status jsonb_path_ops);
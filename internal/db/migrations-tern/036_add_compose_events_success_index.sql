@@ -0,0 +1,2 @@
+-- the build duration statistics look the composes up by when they succeeded
+CREATE INDEX IF NOT EXISTS compose_events_success_created_at_idx ON compose_events(created_at) WHERE status = 'success';
@@ -0,0 +1,10 @@
+-- the Pulp instance the ostree commits of an org are imported to, unless the
+-- compose request names another one. The build system authenticates against
+-- it with its own credentials.
+CREATE TABLE IF NOT EXISTS pulp_settings(
+       org_id varchar PRIMARY KEY,
+       server_address varchar NOT NULL,
+       repository varchar,
+       basepath varchar,
+       updated_at timestamp NOT NULL
+);
@@ -0,0 +1,16 @@
+-- the additional regions the AMI of an aws compose is copied to once it
+-- succeeded, with the accounts it's shared with as they were resolved when
+-- the compose was submitted. A region is claimed before its clone is
+-- requested, so it's only cloned once. The clone status is cached like the
+-- status of the compose.
+CREATE TABLE IF NOT EXISTS compose_replications(
+       compose_id uuid NOT NULL REFERENCES composes(job_id) ON DELETE CASCADE,
+       region varchar NOT NULL,
+       share_with_accounts jsonb NOT NULL,
+       clone_id uuid,
+       claimed_at timestamp,
+       status jsonb,
+       status_refreshed_at timestamp,
+
+       PRIMARY KEY (compose_id, region)
+);
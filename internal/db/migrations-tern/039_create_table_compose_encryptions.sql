@@ -0,0 +1,16 @@
+-- the key the AMI of an aws compose is encrypted with once it succeeded, with
+-- the accounts the encrypted copy is shared with as they were resolved when
+-- the compose was submitted. Composer doesn't share the unencrypted image,
+-- it's deleted once the copy is shared. An encryption is claimed before it's
+-- worked on, so the image is only copied once.
+CREATE TABLE IF NOT EXISTS compose_encryptions(
+       compose_id uuid PRIMARY KEY REFERENCES composes(job_id) ON DELETE CASCADE,
+       region varchar NOT NULL,
+       kms_key_id varchar NOT NULL,
+       share_with_accounts jsonb NOT NULL,
+       source_image_id varchar,
+       image_id varchar,
+       status varchar NOT NULL DEFAULT 'pending',
+       last_error varchar,
+       claimed_at timestamp
+);
@@ -0,0 +1,3 @@
+-- when the status sync of a replica last claimed the refresh of a compose,
+-- so the replicas don't all ask composer about the same composes
+ALTER TABLE composes ADD COLUMN IF NOT EXISTS status_synced_at timestamp;
@@ -0,0 +1,8 @@
+-- the key a compose was requested with, and the hash of its request, so a
+-- retried request returns the compose instead of building it again
+ALTER TABLE composes ADD COLUMN IF NOT EXISTS idempotency_key varchar(255);
+ALTER TABLE composes ADD COLUMN IF NOT EXISTS idempotency_request_hash varchar(64);
+
+-- an org requests a single compose with each key
+CREATE UNIQUE INDEX IF NOT EXISTS composes_idempotency_key_idx ON composes(org_id, idempotency_key)
+       WHERE idempotency_key IS NOT NULL;
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	sqlInsertSeedCompose = `
+		INSERT INTO composes(job_id, request, created_at, account_number, email, org_id, image_name, deleted, status, status_refreshed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, CASE WHEN $9::jsonb IS NULL THEN NULL ELSE $3::timestamp END)`
+
+	sqlInsertSeedComposeEvent = `
+		INSERT INTO compose_events(compose_id, status, reason, created_at)
+		VALUES ($1, $2, $3, $4)`
+
+	sqlInsertSeedQueuedCompose = `
+		INSERT INTO compose_queue(compose_id, org_id, composer_request, created_at, pending_approval, requested_by)
+		VALUES($1, $2, $3, $4, $5, $6)`
+
+	sqlInsertSeedClone = `
+		INSERT INTO clones(id, compose_id, request, created_at)
+		VALUES($1, $2, $3, $4)`
+)
+
+// SeedComposeEntry is a compose of a development database, with what
+// happened to it since CreatedAt.
+type SeedComposeEntry struct {
+	Id            uuid.UUID
+	OrgId         string
+	AccountNumber string
+	Email         string
+	ImageName     *string
+	Request       json.RawMessage
+	CreatedAt     time.Time
+	Deleted       bool
+
+	// The last status of composer, finished composes are served from it.
+	Status json.RawMessage
+	Events []ComposeEventEntry
+	Clones []SeedCloneEntry
+
+	// Composes with a composer request wait in the queue, for composer or
+	// for an approval.
+	ComposerRequest json.RawMessage
+	PendingApproval bool
+}
+
+type SeedCloneEntry struct {
+	Id        uuid.UUID
+	Request   json.RawMessage
+	CreatedAt time.Time
+}
+
+// Seeder backdates composes, for development databases. It isn't part of DB
+// so the services can't rewrite the history of composes.
+type Seeder interface {
+	InsertSeedCompose(compose SeedComposeEntry) error
+}
+
+func InitSeederConnectionPool(connStr string) (Seeder, error) {
+	pool, err := connectPool(connStr, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &dB{pool}, nil
+}
+
+func (db *dB) InsertSeedCompose(compose SeedComposeEntry) error {
+	ctx := context.Background()
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var status interface{}
+	if compose.Status != nil {
+		status = compose.Status
+	}
+	_, err = tx.Exec(ctx, sqlInsertSeedCompose, compose.Id, compose.Request, compose.CreatedAt, compose.AccountNumber,
+		compose.Email, compose.OrgId, compose.ImageName, compose.Deleted, status)
+	if err != nil {
+		return err
+	}
+	for _, e := range compose.Events {
+		_, err = tx.Exec(ctx, sqlInsertSeedComposeEvent, compose.Id, e.Status, e.Reason, e.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	if compose.ComposerRequest != nil {
+		_, err = tx.Exec(ctx, sqlInsertSeedQueuedCompose, compose.Id, compose.OrgId, compose.ComposerRequest,
+			compose.CreatedAt, compose.PendingApproval, compose.Email)
+		if err != nil {
+			return err
+		}
+	}
+	for _, c := range compose.Clones {
+		_, err = tx.Exec(ctx, sqlInsertSeedClone, c.Id, compose.Id, c.Request, c.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
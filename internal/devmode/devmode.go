@@ -0,0 +1,351 @@
+// Package devmode fakes the services image builder depends on, composer, its
+// token endpoint and provisioning, so the service runs on a laptop with only
+// postgres.
+//
+// Composes of the fake go through the statuses of real ones in BuildTime and
+// always succeed, clones succeed right away. Everything is kept in memory.
+package devmode
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/provisioning"
+)
+
+const (
+	DefaultBuildTime = 30 * time.Second
+
+	// the account provisioning knows for every source
+	AWSAccountId = "123456789012"
+
+	composerPrefix = "/api/image-builder-composer/v2"
+)
+
+type Fakes struct {
+	// How long composes take, DefaultBuildTime if 0.
+	BuildTime time.Duration
+
+	mu       sync.Mutex
+	composes map[uuid.UUID]fakeCompose
+	clones   map[uuid.UUID]composer.AWSEC2CloneCompose
+}
+
+type fakeCompose struct {
+	created    time.Time
+	imageType  composer.ImageTypes
+	uploadType composer.UploadTypes
+}
+
+// Server serves the fakes, the urls are the ones to configure image builder
+// with.
+type Server struct {
+	ComposerURL     string
+	TokenURL        string
+	ProvisioningURL string
+
+	srv *http.Server
+}
+
+func New() *Fakes {
+	return &Fakes{
+		composes: map[uuid.UUID]fakeCompose{},
+		clones:   map[uuid.UUID]composer.AWSEC2CloneCompose{},
+	}
+}
+
+// Start serves the fakes on a free port of localhost.
+func (f *Fakes) Start() (*Server, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	base := "http://" + l.Addr().String()
+	s := &Server{
+		ComposerURL:     base,
+		TokenURL:        base + "/token",
+		ProvisioningURL: base + "/api/provisioning/v1",
+		srv:             &http.Server{Handler: f.Handler(), ReadHeaderTimeout: 5 * time.Second},
+	}
+	go func() {
+		err := s.srv.Serve(l)
+		if err != http.ErrServerClosed {
+			logrus.Errorf("Fake services stopped: %v", err)
+		}
+	}()
+	return s, nil
+}
+
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
+
+func (f *Fakes) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", f.token)
+	mux.HandleFunc(composerPrefix+"/", f.composer)
+	mux.HandleFunc("/api/provisioning/v1/sources/", f.uploadInfo)
+	return mux
+}
+
+func (f *Fakes) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "devmode",
+		"expires_in":   3600,
+	})
+}
+
+func (f *Fakes) uploadInfo(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasSuffix(r.URL.Path, "/upload_info") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	accountId := AWSAccountId
+	writeJSON(w, http.StatusOK, provisioning.V1SourceUploadInfoResponse{
+		Aws: &struct {
+			AccountId *string `json:"account_id,omitempty"`
+		}{AccountId: &accountId},
+	})
+}
+
+func (f *Fakes) composer(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, composerPrefix+"/"), "/")
+	route := r.Method + " " + parts[0]
+	if len(parts) > 2 {
+		route += " " + parts[2]
+	}
+	var id uuid.UUID
+	if len(parts) > 1 {
+		var err error
+		id, err = uuid.Parse(parts[1])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "IMAGE-BUILDER-COMPOSER-2", "Invalid format for parameter id")
+			return
+		}
+	}
+
+	switch {
+	case route == "GET openapi":
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(composer.OpenAPIDocument)
+	case route == "POST compose":
+		f.compose(w, r)
+	case route == "GET composes" && len(parts) == 2:
+		f.composeStatus(w, id)
+	case route == "GET composes metadata":
+		f.composeMetadata(w, id)
+	case route == "POST composes clone":
+		f.clone(w, r, id)
+	case route == "GET clones":
+		f.cloneStatus(w, id)
+	default:
+		writeError(w, http.StatusNotFound, "IMAGE-BUILDER-COMPOSER-21", "Requested resource doesn't exist")
+	}
+}
+
+func (f *Fakes) compose(w http.ResponseWriter, r *http.Request) {
+	var cr composer.ComposeRequest
+	err := json.NewDecoder(r.Body).Decode(&cr)
+	if err != nil || cr.ImageRequest == nil {
+		writeError(w, http.StatusBadRequest, "IMAGE-BUILDER-COMPOSER-30", "Failed to decode the compose request")
+		return
+	}
+	id := uuid.New()
+	f.mu.Lock()
+	f.composes[id] = fakeCompose{
+		created:    time.Now(),
+		imageType:  cr.ImageRequest.ImageType,
+		uploadType: uploadTypeOf(cr.ImageRequest.ImageType),
+	}
+	f.mu.Unlock()
+	writeJSON(w, http.StatusCreated, composer.ComposeId{
+		Href: composerPrefix + "/compose",
+		Id:   id,
+		Kind: "ComposeId",
+	})
+}
+
+func (f *Fakes) lookup(w http.ResponseWriter, id uuid.UUID) (fakeCompose, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.composes[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "IMAGE-BUILDER-COMPOSER-15", fmt.Sprintf("Compose with given id not found: %s", id))
+	}
+	return c, ok
+}
+
+func (f *Fakes) composeStatus(w http.ResponseWriter, id uuid.UUID) {
+	c, ok := f.lookup(w, id)
+	if !ok {
+		return
+	}
+	buildTime := f.BuildTime
+	if buildTime == 0 {
+		buildTime = DefaultBuildTime
+	}
+
+	status := composer.ComposeStatus{
+		Href:   composerPrefix + "/composes/" + id.String(),
+		Id:     id.String(),
+		Kind:   "ComposeStatus",
+		Status: composer.ComposeStatusValuePending,
+	}
+	elapsed := time.Since(c.created)
+	switch {
+	case elapsed < buildTime/10:
+		status.ImageStatus.Status = composer.ImageStatusValuePending
+	case elapsed < buildTime*8/10:
+		status.ImageStatus.Status = composer.ImageStatusValueBuilding
+	case elapsed < buildTime:
+		status.ImageStatus.Status = composer.ImageStatusValueUploading
+		status.ImageStatus.UploadStatus = uploadStatus(c.uploadType, composer.Running)
+	default:
+		status.Status = composer.ComposeStatusValueSuccess
+		status.ImageStatus.Status = composer.ImageStatusValueSuccess
+		status.ImageStatus.UploadStatus = uploadStatus(c.uploadType, composer.Success)
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (f *Fakes) composeMetadata(w http.ResponseWriter, id uuid.UUID) {
+	_, ok := f.lookup(w, id)
+	if !ok {
+		return
+	}
+	packages := []composer.PackageMetadata{
+		{Type: "rpm", Name: "bash", Version: "5.1.8", Release: "9.el9", Arch: "x86_64", Sigmd5: "fake"},
+		{Type: "rpm", Name: "kernel", Version: "5.14.0", Release: "427.el9", Arch: "x86_64", Sigmd5: "fake"},
+	}
+	writeJSON(w, http.StatusOK, composer.ComposeMetadata{
+		Href:     composerPrefix + "/composes/" + id.String() + "/metadata",
+		Id:       id.String(),
+		Kind:     "ComposeMetadata",
+		Packages: &packages,
+	})
+}
+
+func (f *Fakes) clone(w http.ResponseWriter, r *http.Request, composeId uuid.UUID) {
+	_, ok := f.lookup(w, composeId)
+	if !ok {
+		return
+	}
+	var clone composer.AWSEC2CloneCompose
+	err := json.NewDecoder(r.Body).Decode(&clone)
+	if err != nil || clone.Region == "" {
+		writeError(w, http.StatusBadRequest, "IMAGE-BUILDER-COMPOSER-30", "Failed to decode the clone request")
+		return
+	}
+	id := uuid.New()
+	f.mu.Lock()
+	f.clones[id] = clone
+	f.mu.Unlock()
+	writeJSON(w, http.StatusCreated, composer.CloneComposeResponse{
+		Href: composerPrefix + "/composes/" + composeId.String() + "/clone",
+		Id:   id,
+		Kind: "CloneComposeId",
+	})
+}
+
+func (f *Fakes) cloneStatus(w http.ResponseWriter, id uuid.UUID) {
+	f.mu.Lock()
+	clone, ok := f.clones[id]
+	f.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "IMAGE-BUILDER-COMPOSER-34", fmt.Sprintf("Clone with given id not found: %s", id))
+		return
+	}
+	status := composer.CloneStatus{
+		Href:   composerPrefix + "/clones/" + id.String(),
+		Id:     id.String(),
+		Kind:   "CloneComposeStatus",
+		Status: composer.Success,
+		Type:   composer.UploadTypesAws,
+	}
+	err := status.Options.FromAWSEC2UploadStatus(composer.AWSEC2UploadStatus{
+		Ami:    fakeAMI(id),
+		Region: clone.Region,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "IMAGE-BUILDER-COMPOSER-10", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// uploadTypeOf is the upload target composer picks for the image types
+// image builder asks for.
+func uploadTypeOf(it composer.ImageTypes) composer.UploadTypes {
+	switch {
+	case strings.HasPrefix(string(it), "aws"):
+		return composer.UploadTypesAws
+	case strings.HasPrefix(string(it), "azure"):
+		return composer.UploadTypesAzure
+	case strings.HasPrefix(string(it), "gcp"):
+		return composer.UploadTypesGcp
+	case it == composer.ImageTypesOci:
+		return composer.UploadTypesOciObjectstorage
+	}
+	return composer.UploadTypesAwsS3
+}
+
+func uploadStatus(ut composer.UploadTypes, value composer.UploadStatusValue) *composer.UploadStatus {
+	us := &composer.UploadStatus{Status: value, Type: ut}
+	if value != composer.Success {
+		return us
+	}
+	var err error
+	switch ut {
+	case composer.UploadTypesAws:
+		err = us.Options.FromAWSEC2UploadStatus(composer.AWSEC2UploadStatus{Ami: fakeAMI(uuid.New()), Region: "us-east-1"})
+	case composer.UploadTypesAzure:
+		err = us.Options.FromAzureUploadStatus(composer.AzureUploadStatus{ImageName: "composer-api-" + uuid.NewString()})
+	case composer.UploadTypesGcp:
+		err = us.Options.FromGCPUploadStatus(composer.GCPUploadStatus{ImageName: "composer-api-" + uuid.NewString(), ProjectId: "devmode"})
+	case composer.UploadTypesOciObjectstorage:
+		err = us.Options.FromOCIUploadStatus(composer.OCIUploadStatus{Url: "https://objectstorage.us-phoenix-1.oraclecloud.com/devmode"})
+	default:
+		err = us.Options.FromAWSS3UploadStatus(composer.AWSS3UploadStatus{Url: "https://image-builder-service-production.s3.amazonaws.com/devmode"})
+	}
+	if err != nil {
+		// the options are plain structs, marshalling them can't fail
+		panic(err)
+	}
+	return us
+}
+
+func fakeAMI(id uuid.UUID) string {
+	return "ami-" + strings.ReplaceAll(id.String(), "-", "")[:17]
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		logrus.Errorf("Unable to write the response of a fake: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, id, reason string) {
+	writeJSON(w, code, composer.Error{
+		Code:   id,
+		Href:   "/api/image-builder-composer/v2/errors/" + strings.TrimPrefix(id, "IMAGE-BUILDER-COMPOSER-"),
+		Id:     strings.TrimPrefix(id, "IMAGE-BUILDER-COMPOSER-"),
+		Kind:   "Error",
+		Reason: reason,
+	})
+}
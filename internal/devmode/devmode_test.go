@@ -0,0 +1,125 @@
+package devmode
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redhatinsights/identity"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/provisioning"
+	"github.com/osbuild/image-builder/pkg/tutils"
+)
+
+func startFakes(t *testing.T, buildTime time.Duration) (*composer.ComposerClient, *Server) {
+	f := New()
+	f.BuildTime = buildTime
+	srv, err := f.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, srv.Close()) })
+
+	cc, err := composer.NewClient(composer.ComposerClientConfig{
+		ComposerURL:  srv.ComposerURL,
+		TokenURL:     srv.TokenURL,
+		ClientId:     "devmode",
+		OfflineToken: "devmode",
+	})
+	require.NoError(t, err)
+	return cc, srv
+}
+
+func composeStatus(t *testing.T, cc *composer.ComposerClient, id uuid.UUID) composer.ComposeStatus {
+	resp, err := cc.ComposeStatus(context.Background(), id)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var status composer.ComposeStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	return status
+}
+
+func TestFakeCompose(t *testing.T) {
+	cc, _ := startFakes(t, time.Hour)
+	ctx := context.Background()
+
+	require.NoError(t, cc.RefreshCapabilities(ctx))
+	require.NotNil(t, cc.Capabilities())
+
+	resp, err := cc.Compose(ctx, composer.ComposeRequest{
+		Distribution: "rhel-9",
+		ImageRequest: &composer.ImageRequest{Architecture: "x86_64", ImageType: composer.ImageTypesAws},
+	})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var composeId composer.ComposeId
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&composeId))
+
+	status := composeStatus(t, cc, composeId.Id)
+	require.Equal(t, composer.ComposeStatusValuePending, status.Status)
+	require.Equal(t, composer.ImageStatusValuePending, status.ImageStatus.Status)
+
+	body := composer.CloneComposeBody{}
+	require.NoError(t, body.FromAWSEC2CloneCompose(composer.AWSEC2CloneCompose{Region: "eu-central-1"}))
+	resp, err = cc.CloneCompose(ctx, composeId.Id, body)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var cloneId composer.CloneComposeResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&cloneId))
+
+	resp, err = cc.CloneStatus(ctx, cloneId.Id)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var cloneStatus composer.CloneStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&cloneStatus))
+	require.Equal(t, composer.Success, cloneStatus.Status)
+	ec2, err := cloneStatus.Options.AsAWSEC2UploadStatus()
+	require.NoError(t, err)
+	require.Equal(t, "eu-central-1", ec2.Region)
+
+	resp, err = cc.ComposeStatus(ctx, uuid.New())
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestFakeComposeFinishes(t *testing.T) {
+	cc, _ := startFakes(t, time.Millisecond)
+	resp, err := cc.Compose(context.Background(), composer.ComposeRequest{
+		Distribution: "rhel-9",
+		ImageRequest: &composer.ImageRequest{Architecture: "x86_64", ImageType: composer.ImageTypesGuestImage},
+	})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var composeId composer.ComposeId
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&composeId))
+
+	time.Sleep(2 * time.Millisecond)
+	status := composeStatus(t, cc, composeId.Id)
+	require.Equal(t, composer.ComposeStatusValueSuccess, status.Status)
+	require.Equal(t, composer.UploadTypesAwsS3, status.ImageStatus.UploadStatus.Type)
+	s3, err := status.ImageStatus.UploadStatus.Options.AsAWSS3UploadStatus()
+	require.NoError(t, err)
+	require.NotEmpty(t, s3.Url)
+}
+
+func TestFakeUploadInfo(t *testing.T) {
+	_, srv := startFakes(t, 0)
+	pc, err := provisioning.NewClient(provisioning.ProvisioningClientConfig{URL: srv.ProvisioningURL})
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), identity.IDHeaderKey, tutils.AuthString0)
+	resp, err := pc.GetUploadInfo(ctx, "1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var info provisioning.V1SourceUploadInfoResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	require.Equal(t, AWSAccountId, *info.Aws.AccountId)
+}
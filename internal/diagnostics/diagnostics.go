@@ -0,0 +1,97 @@
+// Package diagnostics serves the runtime profiles and statistics of the
+// service, to diagnose memory growth and goroutine leaks of its background
+// workers in production. The endpoints are unauthenticated and have to be
+// served on an address that isn't exposed outside of the cluster.
+package diagnostics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	rpprof "runtime/pprof"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type GCStats struct {
+	NumGC        int64           `json:"num_gc"`
+	LastGC       time.Time       `json:"last_gc"`
+	PauseTotal   time.Duration   `json:"pause_total_ns"`
+	RecentPauses []time.Duration `json:"recent_pauses_ns"`
+	HeapAlloc    uint64          `json:"heap_alloc_bytes"`
+	HeapInuse    uint64          `json:"heap_inuse_bytes"`
+	HeapObjects  uint64          `json:"heap_objects"`
+	Sys          uint64          `json:"sys_bytes"`
+	NextGC       uint64          `json:"next_gc_bytes"`
+	NumGoroutine int             `json:"num_goroutine"`
+	GOMAXPROCS   int             `json:"gomaxprocs"`
+}
+
+// Handler serves the pprof profiles under /debug/pprof/, the statistics of
+// the garbage collector and heap under /debug/gcstats and the stacks of all
+// goroutines under /debug/goroutines.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/gcstats", gcStats)
+	mux.HandleFunc("/debug/goroutines", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		// debug=2 dumps the stacks in the format of a panic
+		err := rpprof.Lookup("goroutine").WriteTo(w, 2)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	return mux
+}
+
+func gcStats(w http.ResponseWriter, r *http.Request) {
+	var gc debug.GCStats
+	debug.ReadGCStats(&gc)
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := GCStats{
+		NumGC:        gc.NumGC,
+		LastGC:       gc.LastGC,
+		PauseTotal:   gc.PauseTotal,
+		RecentPauses: gc.Pause,
+		HeapAlloc:    mem.HeapAlloc,
+		HeapInuse:    mem.HeapInuse,
+		HeapObjects:  mem.HeapObjects,
+		Sys:          mem.Sys,
+		NextGC:       mem.NextGC,
+		NumGoroutine: runtime.NumGoroutine(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+	}
+	if len(stats.RecentPauses) > 10 {
+		stats.RecentPauses = stats.RecentPauses[:10]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(stats)
+	if err != nil {
+		logrus.Errorf("Unable to encode gc stats: %v", err)
+	}
+}
+
+// Serve serves the diagnostics endpoints on address until it fails.
+func Serve(address string) {
+	srv := &http.Server{
+		Addr:              address,
+		Handler:           Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	logrus.Infof("Serving diagnostics on %s", address)
+	err := srv.ListenAndServe()
+	if err != nil {
+		logrus.Errorf("Diagnostics server failed: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/debug/gcstats")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var stats GCStats
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+	require.Greater(t, stats.NumGoroutine, 0)
+	require.Greater(t, stats.Sys, uint64(0))
+	require.LessOrEqual(t, len(stats.RecentPauses), 10)
+
+	for _, path := range []string{"/debug/goroutines", "/debug/pprof/", "/debug/pprof/heap"} {
+		resp, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode, path)
+	}
+}
@@ -0,0 +1,181 @@
+// Package ec2 encrypts the AMIs composer uploaded by copying them. Only
+// what's needed for that is implemented.
+package ec2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/osbuild/image-builder/internal/common"
+)
+
+var defaultTimeouts = common.HTTPTimeouts{
+	Connect: 30 * time.Second,
+	Read:    time.Minute,
+	Request: time.Minute,
+}
+
+// States of images as EC2 reports them.
+const (
+	ImageStatePending   = "pending"
+	ImageStateAvailable = "available"
+	ImageStateFailed    = "failed"
+)
+
+type Config struct {
+	// Optional, e.g. of a mock, all regions are requested from it then.
+	// Defaults to the regional endpoint.
+	Endpoint string
+	// The default credential chain if nil, e.g. the role of the pod. They
+	// have to be of the account composer uploads the images into.
+	Credentials *credentials.Credentials
+	// Defaults to a request timeout of a minute.
+	Timeouts common.HTTPTimeouts
+}
+
+type Client struct {
+	sess *session.Session
+}
+
+// Image is an AMI along with the snapshots backing it.
+type Image struct {
+	Id          string
+	State       string
+	SnapshotIds []string
+}
+
+func NewClient(conf Config) (*Client, error) {
+	if conf.Timeouts == (common.HTTPTimeouts{}) {
+		conf.Timeouts = defaultTimeouts
+	}
+	awsConf := aws.NewConfig().WithHTTPClient(common.NewHTTPClient(conf.Timeouts, nil))
+	if conf.Credentials != nil {
+		awsConf = awsConf.WithCredentials(conf.Credentials)
+	}
+	if conf.Endpoint != "" {
+		awsConf = awsConf.WithEndpoint(conf.Endpoint)
+	}
+	sess, err := session.NewSession(awsConf)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		sess: sess,
+	}, nil
+}
+
+func (c *Client) client(region string) *ec2.EC2 {
+	return ec2.New(c.sess, aws.NewConfig().WithRegion(region))
+}
+
+// CopyImage copies an AMI within its region, the snapshots of the copy are
+// encrypted with the KMS key. The client token makes retries return the
+// copy of the first request instead of copying it again. The copy is
+// pending until its snapshots are encrypted.
+func (c *Client) CopyImage(ctx context.Context, region, imageId, name, kmsKeyId, clientToken string) (string, error) {
+	out, err := c.client(region).CopyImageWithContext(ctx, &ec2.CopyImageInput{
+		SourceImageId: aws.String(imageId),
+		SourceRegion:  aws.String(region),
+		Name:          aws.String(name),
+		Encrypted:     aws.Bool(true),
+		KmsKeyId:      aws.String(kmsKeyId),
+		ClientToken:   aws.String(clientToken),
+	})
+	if err != nil {
+		return "", requestError("CopyImage", region, err)
+	}
+	return aws.StringValue(out.ImageId), nil
+}
+
+// DescribeImage returns the state and snapshots of an AMI, nil if it
+// doesn't exist (anymore).
+func (c *Client) DescribeImage(ctx context.Context, region, imageId string) (*Image, error) {
+	out, err := c.client(region).DescribeImagesWithContext(ctx, &ec2.DescribeImagesInput{
+		ImageIds: aws.StringSlice([]string{imageId}),
+	})
+	if errorCode(err) == "InvalidAMIID.NotFound" {
+		return nil, nil
+	} else if err != nil {
+		return nil, requestError("DescribeImages", region, err)
+	}
+	if len(out.Images) == 0 {
+		return nil, nil
+	}
+	image := &Image{
+		Id:    aws.StringValue(out.Images[0].ImageId),
+		State: aws.StringValue(out.Images[0].State),
+	}
+	for _, bdm := range out.Images[0].BlockDeviceMappings {
+		if bdm.Ebs != nil && aws.StringValue(bdm.Ebs.SnapshotId) != "" {
+			image.SnapshotIds = append(image.SnapshotIds, *bdm.Ebs.SnapshotId)
+		}
+	}
+	return image, nil
+}
+
+// ShareImage allows accounts to launch an AMI, they also need to be allowed
+// to use the key it's encrypted with.
+func (c *Client) ShareImage(ctx context.Context, region, imageId string, accounts []string) error {
+	var permissions []*ec2.LaunchPermission
+	for _, account := range accounts {
+		permissions = append(permissions, &ec2.LaunchPermission{UserId: aws.String(account)})
+	}
+	_, err := c.client(region).ModifyImageAttributeWithContext(ctx, &ec2.ModifyImageAttributeInput{
+		ImageId:          aws.String(imageId),
+		Attribute:        aws.String("launchPermission"),
+		LaunchPermission: &ec2.LaunchPermissionModifications{Add: permissions},
+	})
+	if err != nil {
+		return requestError("ModifyImageAttribute", region, err)
+	}
+	return nil
+}
+
+// DeleteImage deregisters an AMI and deletes the snapshots backing it.
+// Images which are gone already are skipped, so it can be retried.
+func (c *Client) DeleteImage(ctx context.Context, region, imageId string) error {
+	image, err := c.DescribeImage(ctx, region, imageId)
+	if err != nil {
+		return err
+	}
+	if image == nil {
+		return nil
+	}
+	client := c.client(region)
+	_, err = client.DeregisterImageWithContext(ctx, &ec2.DeregisterImageInput{ImageId: aws.String(imageId)})
+	if err != nil {
+		return requestError("DeregisterImage", region, err)
+	}
+	for _, snapshotId := range image.SnapshotIds {
+		_, err = client.DeleteSnapshotWithContext(ctx, &ec2.DeleteSnapshotInput{SnapshotId: aws.String(snapshotId)})
+		if err != nil && errorCode(err) != "InvalidSnapshot.NotFound" {
+			return requestError("DeleteSnapshot", region, err)
+		}
+	}
+	return nil
+}
+
+// errorCode is the code of the error EC2 responded with, empty if it didn't.
+func errorCode(err error) string {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return awsErr.Code()
+	}
+	return ""
+}
+
+func requestError(action, region string, err error) error {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return fmt.Errorf("%s in %s failed with %s: %s", action, region, awsErr.Code(), awsErr.Message())
+	}
+	return fmt.Errorf("%s in %s failed: %w", action, region, err)
+}
@@ -0,0 +1,97 @@
+package ec2
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptImage(t *testing.T) {
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/"))
+		require.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/ec2/")
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "2016-11-15", r.Form.Get("Version"))
+		action := r.Form.Get("Action")
+		calls = append(calls, action)
+
+		switch action {
+		case "CopyImage":
+			if r.Form.Get("SourceImageId") == "ami-gone" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `<Response><Errors><Error><Code>InvalidAMIID.NotFound</Code><Message>nope</Message></Error></Errors></Response>`)
+				return
+			}
+			require.Equal(t, "ami-plain", r.Form.Get("SourceImageId"))
+			require.Equal(t, "eu-west-1", r.Form.Get("SourceRegion"))
+			require.Equal(t, "true", r.Form.Get("Encrypted"))
+			require.Equal(t, "arn:aws:kms:eu-west-1:123456789012:key/k", r.Form.Get("KmsKeyId"))
+			require.Equal(t, "token", r.Form.Get("ClientToken"))
+			fmt.Fprint(w, `<CopyImageResponse><requestId>r</requestId><imageId>ami-encrypted</imageId></CopyImageResponse>`)
+		case "DescribeImages":
+			if r.Form.Get("ImageId.1") == "ami-gone" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `<Response><Errors><Error><Code>InvalidAMIID.NotFound</Code><Message>The image id '[ami-gone]' does not exist</Message></Error></Errors></Response>`)
+				return
+			}
+			fmt.Fprintf(w, `<DescribeImagesResponse><imagesSet><item><imageId>%s</imageId><imageState>available</imageState>
+<blockDeviceMapping><item><deviceName>/dev/sda1</deviceName><ebs><snapshotId>snap-1</snapshotId></ebs></item><item><deviceName>/dev/sdb</deviceName><virtualName>ephemeral0</virtualName></item></blockDeviceMapping>
+</item></imagesSet></DescribeImagesResponse>`, r.Form.Get("ImageId.1"))
+		case "ModifyImageAttribute":
+			require.Equal(t, "launchPermission", r.Form.Get("Attribute"))
+			require.Equal(t, "000000000001", r.Form.Get("LaunchPermission.Add.1.UserId"))
+			require.Equal(t, "000000000002", r.Form.Get("LaunchPermission.Add.2.UserId"))
+			fmt.Fprint(w, `<ModifyImageAttributeResponse><return>true</return></ModifyImageAttributeResponse>`)
+		case "DeregisterImage":
+			require.Equal(t, "ami-plain", r.Form.Get("ImageId"))
+			fmt.Fprint(w, `<DeregisterImageResponse><return>true</return></DeregisterImageResponse>`)
+		case "DeleteSnapshot":
+			require.Equal(t, "snap-1", r.Form.Get("SnapshotId"))
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `<Response><Errors><Error><Code>InvalidSnapshot.NotFound</Code><Message>gone</Message></Error></Errors></Response>`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `<Response><Errors><Error><Code>InvalidAction</Code><Message>nope</Message></Error></Errors></Response>`)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(Config{
+		Endpoint:    srv.URL,
+		Credentials: credentials.NewStaticCredentials("key", "secret", ""),
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	id, err := client.CopyImage(ctx, "eu-west-1", "ami-plain", "copy", "arn:aws:kms:eu-west-1:123456789012:key/k", "token")
+	require.NoError(t, err)
+	require.Equal(t, "ami-encrypted", id)
+
+	image, err := client.DescribeImage(ctx, "eu-west-1", "ami-encrypted")
+	require.NoError(t, err)
+	require.Equal(t, &Image{Id: "ami-encrypted", State: ImageStateAvailable, SnapshotIds: []string{"snap-1"}}, image)
+	image, err = client.DescribeImage(ctx, "eu-west-1", "ami-gone")
+	require.NoError(t, err)
+	require.Nil(t, image)
+
+	require.NoError(t, client.ShareImage(ctx, "eu-west-1", "ami-encrypted", []string{"000000000001", "000000000002"}))
+
+	// snapshots which are gone already don't fail the deletion
+	calls = nil
+	require.NoError(t, client.DeleteImage(ctx, "eu-west-1", "ami-plain"))
+	require.Equal(t, []string{"DescribeImages", "DeregisterImage", "DeleteSnapshot"}, calls)
+	calls = nil
+	require.NoError(t, client.DeleteImage(ctx, "eu-west-1", "ami-gone"))
+	require.Equal(t, []string{"DescribeImages"}, calls)
+
+	_, err = client.CopyImage(ctx, "eu-west-1", "ami-gone", "copy", "arn:aws:kms:eu-west-1:123456789012:key/k", "token")
+	require.ErrorContains(t, err, "CopyImage in eu-west-1 failed with InvalidAMIID.NotFound: nope")
+}
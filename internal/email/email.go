@@ -0,0 +1,183 @@
+// Package email sends notifications by SMTP, for deployments without the
+// console notifications service.
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/osbuild/image-builder/internal/ratelimit"
+)
+
+// Default number of emails sent to one address per hour.
+const DefaultRateLimit = 20
+
+// The default templates, which TemplatesDir can override. A template renders
+// the subject on its first line, followed by an empty line and the body.
+const defaultTemplates = `
+{{define "compose-succeeded"}}Image {{.Name}} is ready
+
+Your image {{.Name}} finished building.
+
+Compose: {{.ComposeId}}
+{{end}}
+{{define "compose-failed"}}Image {{.Name}} failed to build
+
+Your image {{.Name}} failed to build{{if .Reason}}: {{.Reason}}{{end}}.
+
+Compose: {{.ComposeId}}
+{{end}}
+`
+
+type Config struct {
+	// host:port of the SMTP server, which has to support STARTTLS if
+	// Username is set.
+	Address  string
+	Username string
+	Password string
+	// Optional, asked for the password of each email instead, so a rotated
+	// one is picked up.
+	PasswordFunc func() string
+	From         string
+	// Directory with *.tmpl files defining templates which replace the
+	// defaults of the same name.
+	TemplatesDir string
+	// Number of emails sent to one address per hour, further emails are
+	// dropped.
+	RateLimit int
+}
+
+type Mailer struct {
+	addr      string
+	auth      func() smtp.Auth
+	from      mail.Address
+	templates *template.Template
+	limiter   ratelimit.Limiter
+	// replaced in tests
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// RateLimitedError is returned for emails to an address which was sent
+// too many already.
+var RateLimitedError = errors.New("too many emails sent to the address")
+
+func NewMailer(conf Config) (*Mailer, error) {
+	host, _, err := net.SplitHostPort(conf.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp address %q: %w", conf.Address, err)
+	}
+	from, err := mail.ParseAddress(conf.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %w", conf.From, err)
+	}
+
+	templates, err := template.New("").Parse(defaultTemplates)
+	if err != nil {
+		return nil, err
+	}
+	if conf.TemplatesDir != "" {
+		templates, err = templates.ParseGlob(filepath.Join(conf.TemplatesDir, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.RateLimit <= 0 {
+		conf.RateLimit = DefaultRateLimit
+	}
+
+	m := &Mailer{
+		addr:      conf.Address,
+		from:      *from,
+		templates: templates,
+		limiter: ratelimit.NewMemoryLimiter(ratelimit.Config{
+			Rate:  float64(conf.RateLimit) / time.Hour.Seconds(),
+			Burst: conf.RateLimit,
+		}),
+		send: smtp.SendMail,
+	}
+	if conf.Username != "" {
+		password := conf.PasswordFunc
+		if password == nil {
+			password = func() string { return conf.Password }
+		}
+		m.auth = func() smtp.Auth {
+			return smtp.PlainAuth("", conf.Username, password(), host)
+		}
+	}
+	return m, nil
+}
+
+// Send renders a template with data and sends it to an address, unless the
+// rate limit of the address was reached.
+func (m *Mailer) Send(ctx context.Context, to, name string, data interface{}) error {
+	rcpt, err := mail.ParseAddress(to)
+	if err != nil {
+		return err
+	}
+	res, err := m.limiter.Take(ctx, strings.ToLower(rcpt.Address))
+	if err != nil {
+		return err
+	}
+	if !res.Allowed {
+		return RateLimitedError
+	}
+
+	msg, err := m.message(rcpt, name, data, time.Now())
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.auth != nil {
+		auth = m.auth()
+	}
+	return m.send(m.addr, auth, m.from.Address, []string{rcpt.Address}, msg)
+}
+
+// message renders an email, the headers are written here, the template only
+// contributes the subject so it can't add headers of its own.
+func (m *Mailer) message(to *mail.Address, name string, data interface{}, now time.Time) ([]byte, error) {
+	var rendered bytes.Buffer
+	err := m.templates.ExecuteTemplate(&rendered, name, data)
+	if err != nil {
+		return nil, err
+	}
+	subject, body, _ := strings.Cut(strings.TrimLeft(rendered.String(), "\n"), "\n")
+	body = strings.TrimLeft(body, "\n")
+
+	id := make([]byte, 16)
+	_, err = rand.Read(id)
+	if err != nil {
+		return nil, err
+	}
+	_, domain, _ := strings.Cut(m.from.Address, "@")
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", to.String())
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	msg.WriteString("\r\n")
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		// lines starting with a dot are escaped by SendMail
+		msg.WriteString(line)
+		msg.WriteString("\r\n")
+	}
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,101 @@
+package email
+
+import (
+	"context"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type sent struct {
+	from string
+	to   []string
+	msg  string
+}
+
+func testMailer(t *testing.T, conf Config) (*Mailer, *[]sent) {
+	m, err := NewMailer(conf)
+	require.NoError(t, err)
+	var mails []sent
+	m.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		require.Equal(t, conf.Address, addr)
+		mails = append(mails, sent{from, to, string(msg)})
+		return nil
+	}
+	return m, &mails
+}
+
+func TestSend(t *testing.T) {
+	m, mails := testMailer(t, Config{
+		Address: "smtp.example.com:25",
+		From:    "Image Builder <image-builder@example.com>",
+	})
+
+	data := map[string]string{"Name": "my-image\r\nBcc: someone@example.com", "ComposeId": "1234", "Reason": "osbuild failed"}
+	require.NoError(t, m.Send(context.Background(), "user@example.com", "compose-failed", data))
+	require.Len(t, *mails, 1)
+	mail := (*mails)[0]
+	require.Equal(t, "image-builder@example.com", mail.from)
+	require.Equal(t, []string{"user@example.com"}, mail.to)
+
+	headers, body, found := strings.Cut(mail.msg, "\r\n\r\n")
+	require.True(t, found)
+	require.Contains(t, headers, "From: \"Image Builder\" <image-builder@example.com>\r\n")
+	require.Contains(t, headers, "To: <user@example.com>\r\n")
+	// the template can't add headers, the subject is its first line
+	require.NotContains(t, headers, "Bcc")
+	require.Contains(t, headers, "Subject: Image my-image\r\n")
+	require.Contains(t, body, "failed to build")
+	require.Contains(t, body, ": osbuild failed.\r\n")
+	require.Contains(t, body, "Compose: 1234\r\n")
+
+	require.Error(t, m.Send(context.Background(), "not an address", "compose-failed", data))
+	require.Error(t, m.Send(context.Background(), "user@example.com", "unknown", data))
+}
+
+func TestSendRateLimit(t *testing.T) {
+	m, mails := testMailer(t, Config{
+		Address:   "smtp.example.com:25",
+		From:      "image-builder@example.com",
+		RateLimit: 2,
+	})
+
+	data := map[string]string{"Name": "my-image", "ComposeId": "1234"}
+	require.NoError(t, m.Send(context.Background(), "user@example.com", "compose-succeeded", data))
+	require.NoError(t, m.Send(context.Background(), "User@Example.com", "compose-succeeded", data))
+	require.ErrorIs(t, m.Send(context.Background(), "user@example.com", "compose-succeeded", data), RateLimitedError)
+	require.NoError(t, m.Send(context.Background(), "other@example.com", "compose-succeeded", data))
+	require.Len(t, *mails, 3)
+	require.Contains(t, (*mails)[0].msg, "Subject: Image my-image is ready\r\n")
+}
+
+func TestTemplatesDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "succeeded.tmpl"),
+		[]byte(`{{define "compose-succeeded"}}Done: {{.Name}}
+
+Get it while it's hot.
+{{end}}`), 0600))
+
+	m, mails := testMailer(t, Config{
+		Address:      "smtp.example.com:25",
+		From:         "image-builder@example.com",
+		TemplatesDir: dir,
+	})
+	data := map[string]string{"Name": "my-image", "ComposeId": "1234"}
+	require.NoError(t, m.Send(context.Background(), "user@example.com", "compose-succeeded", data))
+	require.NoError(t, m.Send(context.Background(), "user@example.com", "compose-failed", data))
+	require.Contains(t, (*mails)[0].msg, "Subject: Done: my-image\r\n")
+	require.Contains(t, (*mails)[0].msg, "\r\n\r\nGet it while it's hot.\r\n")
+	// the other defaults are kept
+	require.Contains(t, (*mails)[1].msg, "Subject: Image my-image failed to build\r\n")
+
+	_, err := NewMailer(Config{Address: "smtp.example.com", From: "image-builder@example.com"})
+	require.Error(t, err)
+	_, err = NewMailer(Config{Address: "smtp.example.com:25", From: "not an address"})
+	require.Error(t, err)
+}
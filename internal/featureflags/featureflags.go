@@ -0,0 +1,296 @@
+// Package featureflags evaluates the feature flags of an Unleash server, so
+// features can be rolled out to some organizations before they are
+// generally available.
+//
+// The client fetches the flags from the client API of the server and
+// evaluates them locally, like the Unleash SDKs do. The default, userWithId
+// and flexibleRollout strategies are supported, along with IN and NOT_IN
+// constraints on the orgId and userId context fields. Strategies the client
+// doesn't know never match.
+package featureflags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Context is what flags are evaluated against.
+type Context struct {
+	OrgId  string
+	UserId string
+}
+
+type Flags interface {
+	// Lookup returns whether a flag is enabled in c, and whether the flag
+	// is defined at all.
+	Lookup(name string, c Context) (enabled bool, defined bool)
+}
+
+type UnleashConfig struct {
+	// Address of the client API, e.g. https://unleash.example.com/api.
+	URL string
+	// Client API token.
+	Token string
+	// Name of the application the flags are requested for.
+	AppName string
+}
+
+type UnleashClient struct {
+	url     string
+	token   string
+	appName string
+	client  *http.Client
+
+	mu       sync.RWMutex
+	features map[string]feature
+}
+
+type feature struct {
+	Name       string     `json:"name"`
+	Enabled    bool       `json:"enabled"`
+	Strategies []strategy `json:"strategies"`
+}
+
+type strategy struct {
+	Name        string            `json:"name"`
+	Parameters  map[string]string `json:"parameters"`
+	Constraints []constraint      `json:"constraints"`
+}
+
+type constraint struct {
+	ContextName string   `json:"contextName"`
+	Operator    string   `json:"operator"`
+	Values      []string `json:"values"`
+	Inverted    bool     `json:"inverted"`
+}
+
+func NewUnleashClient(conf UnleashConfig) (*UnleashClient, error) {
+	if conf.URL == "" {
+		return nil, fmt.Errorf("Client needs the url of the unleash server")
+	}
+	if conf.AppName == "" {
+		return nil, fmt.Errorf("Client needs the name of the application")
+	}
+
+	return &UnleashClient{
+		url:     strings.TrimSuffix(conf.URL, "/"),
+		token:   conf.Token,
+		appName: conf.AppName,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		features: map[string]feature{},
+	}, nil
+}
+
+// Refresh fetches the flags from the server. The flags fetched last are kept
+// if the server can't be reached.
+func (uc *UnleashClient) Refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uc.url+"/client/features", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("UNLEASH-APPNAME", uc.appName)
+	if uc.token != "" {
+		req.Header.Set("Authorization", uc.token)
+	}
+
+	resp, err := uc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unleash responded with %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Features []feature `json:"features"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return err
+	}
+
+	features := map[string]feature{}
+	for _, f := range result.Features {
+		features[f.Name] = f
+	}
+	uc.mu.Lock()
+	uc.features = features
+	uc.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the flags every interval until ctx is done.
+func (uc *UnleashClient) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := uc.Refresh(ctx)
+			if err != nil {
+				logrus.Warnf("Unable to refresh feature flags: %v", err)
+			}
+		}
+	}
+}
+
+func (uc *UnleashClient) Lookup(name string, c Context) (bool, bool) {
+	uc.mu.RLock()
+	f, ok := uc.features[name]
+	uc.mu.RUnlock()
+	if !ok {
+		return false, false
+	}
+	return f.enabled(c), true
+}
+
+func (f feature) enabled(c Context) bool {
+	if !f.Enabled {
+		return false
+	}
+	// flags without strategies are on for everyone
+	if len(f.Strategies) == 0 {
+		return true
+	}
+	for _, s := range f.Strategies {
+		if s.matches(f.Name, c) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s strategy) matches(flag string, c Context) bool {
+	for _, con := range s.Constraints {
+		if !con.matches(c) {
+			return false
+		}
+	}
+
+	switch s.Name {
+	case "default":
+		return true
+	case "userWithId":
+		for _, id := range strings.Split(s.Parameters["userIds"], ",") {
+			if c.UserId != "" && strings.TrimSpace(id) == c.UserId {
+				return true
+			}
+		}
+		return false
+	case "flexibleRollout":
+		rollout, err := strconv.Atoi(s.Parameters["rollout"])
+		if err != nil {
+			return false
+		}
+		// organizations are what features are rolled out to
+		id := c.OrgId
+		if s.Parameters["stickiness"] == "userId" {
+			id = c.UserId
+		}
+		if id == "" {
+			return false
+		}
+		groupId := s.Parameters["groupId"]
+		if groupId == "" {
+			groupId = flag
+		}
+		return normalizedHash(groupId, id) <= uint32(rollout)
+	default:
+		return false
+	}
+}
+
+func (con constraint) matches(c Context) bool {
+	var value string
+	switch con.ContextName {
+	case "orgId":
+		value = c.OrgId
+	case "userId":
+		value = c.UserId
+	}
+
+	in := false
+	for _, v := range con.Values {
+		if v == value {
+			in = true
+			break
+		}
+	}
+
+	var match bool
+	switch con.Operator {
+	case "IN":
+		match = in
+	case "NOT_IN":
+		match = !in
+	default:
+		return false
+	}
+	return match != con.Inverted
+}
+
+// normalizedHash maps an id to 1-100 the way the Unleash SDKs do, so
+// percentages roll out to the same organizations in all clients.
+func normalizedHash(groupId, id string) uint32 {
+	return murmur3([]byte(groupId+":"+id), 0)%100 + 1
+}
+
+// murmur3 is the 32 bit x86 variant of MurmurHash3.
+func murmur3(data []byte, seed uint32) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	h := seed
+	blocks := len(data) / 4
+	for i := 0; i < blocks; i++ {
+		k := uint32(data[i*4]) | uint32(data[i*4+1])<<8 | uint32(data[i*4+2])<<16 | uint32(data[i*4+3])<<24
+		k *= c1
+		k = k<<15 | k>>17
+		k *= c2
+		h ^= k
+		h = h<<13 | h>>19
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	tail := data[blocks*4:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = k<<15 | k>>17
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
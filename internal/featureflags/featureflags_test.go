@@ -0,0 +1,85 @@
+package featureflags
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMurmur3(t *testing.T) {
+	require.Equal(t, uint32(0), murmur3([]byte(""), 0))
+	require.Equal(t, uint32(0x248bfa47), murmur3([]byte("hello"), 0))
+	require.Equal(t, uint32(0x149bbb7f), murmur3([]byte("hello, world"), 0))
+	require.Equal(t, uint32(0x2e4ff723), murmur3([]byte("The quick brown fox jumps over the lazy dog"), 0))
+
+	// same as the Unleash SDKs
+	require.Equal(t, uint32(73), normalizedHash("gr1", "123"))
+	require.Equal(t, uint32(25), normalizedHash("groupX", "999"))
+}
+
+func TestUnleashClient(t *testing.T) {
+	features := `{"version": 1, "features": [
+		{"name": "everyone", "enabled": true, "strategies": [{"name": "default"}]},
+		{"name": "no-strategies", "enabled": true},
+		{"name": "disabled", "enabled": false, "strategies": [{"name": "default"}]},
+		{"name": "orgs", "enabled": true, "strategies": [
+			{"name": "default", "constraints": [{"contextName": "orgId", "operator": "IN", "values": ["000001", "000002"]}]}
+		]},
+		{"name": "not-orgs", "enabled": true, "strategies": [
+			{"name": "default", "constraints": [{"contextName": "orgId", "operator": "IN", "values": ["000001"], "inverted": true}]}
+		]},
+		{"name": "users", "enabled": true, "strategies": [{"name": "userWithId", "parameters": {"userIds": "alice, bob"}}]},
+		{"name": "rollout", "enabled": true, "strategies": [
+			{"name": "flexibleRollout", "parameters": {"rollout": "50", "stickiness": "default", "groupId": "rollout"}}
+		]},
+		{"name": "unknown", "enabled": true, "strategies": [{"name": "gradualRolloutSessionId"}]}
+	]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/client/features", r.URL.Path)
+		require.Equal(t, "token", r.Header.Get("Authorization"))
+		require.Equal(t, "image-builder", r.Header.Get("UNLEASH-APPNAME"))
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(features))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	uc, err := NewUnleashClient(UnleashConfig{URL: srv.URL + "/api/", Token: "token", AppName: "image-builder"})
+	require.NoError(t, err)
+
+	// nothing is defined before the flags are fetched
+	_, defined := uc.Lookup("everyone", Context{OrgId: "000000"})
+	require.False(t, defined)
+	require.NoError(t, uc.Refresh(context.Background()))
+
+	lookup := func(name string, c Context) bool {
+		enabled, defined := uc.Lookup(name, c)
+		require.True(t, defined, name)
+		return enabled
+	}
+	org0 := Context{OrgId: "000000", UserId: "alice"}
+	org1 := Context{OrgId: "000001", UserId: "carol"}
+	require.True(t, lookup("everyone", org0))
+	require.True(t, lookup("no-strategies", org0))
+	require.False(t, lookup("disabled", org0))
+	require.False(t, lookup("orgs", org0))
+	require.True(t, lookup("orgs", org1))
+	require.True(t, lookup("not-orgs", org0))
+	require.False(t, lookup("not-orgs", org1))
+	require.True(t, lookup("users", org0))
+	require.False(t, lookup("users", org1))
+	require.False(t, lookup("unknown", org0))
+	require.Equal(t, normalizedHash("rollout", "000000") <= 50, lookup("rollout", org0))
+	require.False(t, lookup("rollout", Context{}))
+
+	_, defined = uc.Lookup("undefined", org0)
+	require.False(t, defined)
+
+	// the last flags are kept if the server fails
+	features = `{`
+	require.Error(t, uc.Refresh(context.Background()))
+	require.True(t, lookup("everyone", org0))
+}
@@ -0,0 +1,317 @@
+// Package gpg makes detached OpenPGP signatures of the artifacts of composes,
+// with the key of an org or by the signing service of an org, and verifies
+// the signatures of the metadata of repositories.
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+
+	"github.com/osbuild/image-builder/internal/common"
+)
+
+// Key is the private key of an org.
+type Key struct {
+	entity *openpgp.Entity
+}
+
+// ParseKey parses an ASCII armored private key, which has to be able to sign
+// and can't be protected by a passphrase. RSA, DSA, ECDSA and EdDSA keys
+// are supported.
+func ParseKey(armored string) (*Key, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+	if err != nil {
+		return nil, fmt.Errorf("reading the key failed: %w", err)
+	}
+	if len(entities) != 1 {
+		return nil, fmt.Errorf("expected a single key, got %d", len(entities))
+	}
+	k := &Key{entities[0]}
+	if k.entity.PrivateKey == nil {
+		return nil, fmt.Errorf("key %s is a public key", k.Fingerprint())
+	}
+	// encrypted or unusable keys only show themselves when signing
+	_, err = k.Sign(strings.NewReader(""))
+	if err != nil {
+		return nil, fmt.Errorf("key %s can't sign: %w", k.Fingerprint(), err)
+	}
+	return k, nil
+}
+
+// Fingerprint is the fingerprint of the primary key as gpg shows it, in
+// upper case hex.
+func (k *Key) Fingerprint() string {
+	return fmt.Sprintf("%X", k.entity.PrimaryKey.Fingerprint)
+}
+
+// PublicKey is the ASCII armored public key signatures are verified with.
+func (k *Key) PublicKey() (string, error) {
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return "", err
+	}
+	err = k.entity.Serialize(w)
+	if err != nil {
+		return "", err
+	}
+	err = w.Close()
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Sign returns the ASCII armored detached signature of what r reads.
+func (k *Key) Sign(r io.Reader) (string, error) {
+	var buf bytes.Buffer
+	err := openpgp.ArmoredDetachSign(&buf, k.entity, r, &packet.Config{})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// SignBinary returns the binary detached signature of what r reads, as
+// ostree stores them.
+func (k *Key) SignBinary(r io.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	err := openpgp.DetachSign(&buf, k.entity, r, &packet.Config{})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Artifact is an artifact of a compose and where it's downloaded from.
+type Artifact struct {
+	ComposeId string `json:"compose_id"`
+	Filename  string `json:"filename"`
+	Size      int64  `json:"size"`
+	Sha256    string `json:"sha256"`
+	URL       string `json:"url"`
+}
+
+// Service is the signing service of an org, which is given the artifact and
+// responds with its signature.
+type Service struct {
+	// https url the artifacts are posted to
+	URL string
+	// Optional bearer token of the service
+	Token string
+}
+
+// Keys and repomd.xml files are way smaller than this.
+const maxMetadataSize = 16 << 20
+
+// Default timeouts, the artifacts are images which take a while to download.
+var defaultTimeouts = common.HTTPTimeouts{
+	Connect: 30 * time.Second,
+	Read:    time.Minute,
+	Request: 30 * time.Minute,
+}
+
+type Client struct {
+	client *http.Client
+	// for the urls users pick, like the ones of repositories and their keys
+	public *http.Client
+}
+
+type Config struct {
+	// Defaults to a request timeout of 30 minutes.
+	Timeouts common.HTTPTimeouts
+	// Optional, e.g. for signing services with certificates of a private CA.
+	TLSConfig *tls.Config
+	// Optional, replaces the transport which only connects to public
+	// addresses, e.g. for tests with a repository on the loopback address.
+	PublicTransport http.RoundTripper
+}
+
+// NewClient returns a client which downloads the artifacts of composes from
+// wherever composer uploaded them, and only connects to public addresses for
+// the urls of users.
+func NewClient(conf Config) *Client {
+	if conf.Timeouts == (common.HTTPTimeouts{}) {
+		conf.Timeouts = defaultTimeouts
+	}
+	public := common.NewPublicHTTPClient(conf.Timeouts, conf.TLSConfig)
+	if conf.PublicTransport != nil {
+		public.Transport = conf.PublicTransport
+	}
+	return &Client{
+		client: common.NewHTTPClient(conf.Timeouts, conf.TLSConfig),
+		public: public,
+	}
+}
+
+// validatePublicURL rejects urls of users which are known not to be public up
+// front, the addresses names resolve to are checked when they're dialed.
+func validatePublicURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%s isn't an http(s) url", rawURL)
+	}
+	return common.ValidatePublicHost(u.Hostname())
+}
+
+// Download downloads what's at url, the caller has to close it.
+func (c *Client) Download(ctx context.Context, url string) (io.ReadCloser, error) {
+	return c.download(ctx, c.client, url)
+}
+
+func (c *Client) download(ctx context.Context, client *http.Client, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("downloading failed with %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// Sign downloads the artifact and signs it with key. The signature is only
+// returned if the download matches the checksum of the artifact.
+func (c *Client) Sign(ctx context.Context, key *Key, a Artifact) (string, error) {
+	body, err := c.Download(ctx, a.URL)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", a.Filename, err)
+	}
+	defer body.Close()
+
+	hash := sha256.New()
+	signature, err := key.Sign(io.TeeReader(body, hash))
+	if err != nil {
+		return "", err
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != a.Sha256 {
+		return "", fmt.Errorf("the sha256 of the download of %s is %s instead of %s", a.Filename, sum, a.Sha256)
+	}
+	return signature, nil
+}
+
+// SignWithService asks the signing service to sign the artifact. The org
+// picks the url of the service, so it has to be a public host, and what it
+// responds with on errors isn't passed on.
+func (c *Client) SignWithService(ctx context.Context, s Service, a Artifact) (string, error) {
+	err := validatePublicURL(s.URL)
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.public.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("the signing service responded with %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Signature string `json:"signature"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return "", err
+	}
+	block, err := armor.Decode(strings.NewReader(result.Signature))
+	if err != nil || block.Type != openpgp.SignatureType {
+		return "", fmt.Errorf("the signing service didn't respond with an armored signature")
+	}
+	return result.Signature, nil
+}
+
+// VerifyRepository checks that repodata/repomd.xml below baseurl is signed by
+// one of keys. Keys are ASCII armored public keys or http(s) urls of them.
+// Users pick the urls, so only public hosts are downloaded from.
+func (c *Client) VerifyRepository(ctx context.Context, baseurl string, keys []string) error {
+	var keyring openpgp.EntityList
+	for _, k := range keys {
+		armored := k
+		if strings.HasPrefix(k, "http://") || strings.HasPrefix(k, "https://") {
+			raw, err := c.get(ctx, k)
+			if err != nil {
+				return fmt.Errorf("downloading the key %s failed: %w", k, err)
+			}
+			armored = string(raw)
+		}
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+		if err != nil {
+			return fmt.Errorf("reading the key failed: %w", err)
+		}
+		keyring = append(keyring, entities...)
+	}
+	if len(keyring) == 0 {
+		return fmt.Errorf("no keys to verify the metadata with")
+	}
+
+	repomdURL := strings.TrimSuffix(baseurl, "/") + "/repodata/repomd.xml"
+	repomd, err := c.get(ctx, repomdURL)
+	if err != nil {
+		return fmt.Errorf("downloading %s failed: %w", repomdURL, err)
+	}
+	signature, err := c.get(ctx, repomdURL+".asc")
+	if err != nil {
+		return fmt.Errorf("downloading the signature of %s failed: %w", repomdURL, err)
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte("-----BEGIN")) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(repomd), bytes.NewReader(signature), nil)
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(repomd), bytes.NewReader(signature), nil)
+	}
+	if err != nil {
+		return fmt.Errorf("the signature of %s doesn't verify: %w", repomdURL, err)
+	}
+	return nil
+}
+
+// get downloads small files like keys and repository metadata from public
+// hosts.
+func (c *Client) get(ctx context.Context, url string) ([]byte, error) {
+	err := validatePublicURL(url)
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.download(ctx, c.public, url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(io.LimitReader(body, maxMetadataSize))
+}
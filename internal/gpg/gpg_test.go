@@ -0,0 +1,200 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(t *testing.T) (string, *openpgp.Entity) {
+	entity, err := openpgp.NewEntity("Image Builder Test", "", "test@example.com", nil)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.SerializePrivate(w, nil))
+	require.NoError(t, w.Close())
+	return buf.String(), entity
+}
+
+func TestParseKey(t *testing.T) {
+	armored, entity := newKey(t)
+	key, err := ParseKey(armored)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint), key.Fingerprint())
+	require.Len(t, key.Fingerprint(), 40)
+
+	public, err := key.PublicKey()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(public, "-----BEGIN PGP PUBLIC KEY BLOCK-----"))
+	require.NotContains(t, public, "PRIVATE")
+
+	_, err = ParseKey(public)
+	require.ErrorContains(t, err, "is a public key")
+	_, err = ParseKey("not a key")
+	require.ErrorContains(t, err, "reading the key failed")
+
+	// EdDSA keys sign as well
+	eddsa, err := openpgp.NewEntity("Image Builder Test", "", "test@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, eddsa.SerializePrivate(w, nil))
+	require.NoError(t, w.Close())
+	_, err = ParseKey(buf.String())
+	require.NoError(t, err)
+
+	_, other := newKey(t)
+	buf.Reset()
+	w, err = armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.SerializePrivate(w, nil))
+	require.NoError(t, other.SerializePrivate(w, nil))
+	require.NoError(t, w.Close())
+	_, err = ParseKey(buf.String())
+	require.ErrorContains(t, err, "expected a single key, got 2")
+}
+
+func TestSign(t *testing.T) {
+	armored, _ := newKey(t)
+	key, err := ParseKey(armored)
+	require.NoError(t, err)
+	public, err := key.PublicKey()
+	require.NoError(t, err)
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(public))
+	require.NoError(t, err)
+
+	content := []byte("a qcow2 image")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	defer srv.Close()
+	sum := sha256.Sum256(content)
+	artifact := Artifact{Filename: "disk.qcow2", Size: int64(len(content)), Sha256: hex.EncodeToString(sum[:]), URL: srv.URL}
+
+	signature, err := NewClient(Config{}).Sign(context.Background(), key, artifact)
+	require.NoError(t, err)
+	_, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(content), strings.NewReader(signature), nil)
+	require.NoError(t, err)
+
+	artifact.Sha256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	_, err = NewClient(Config{}).Sign(context.Background(), key, artifact)
+	require.ErrorContains(t, err, "the sha256 of the download of disk.qcow2 is")
+}
+
+func TestSignWithService(t *testing.T) {
+	armored, _ := newKey(t)
+	key, err := ParseKey(armored)
+	require.NoError(t, err)
+	signature, err := key.Sign(strings.NewReader("an iso"))
+	require.NoError(t, err)
+
+	artifact := Artifact{ComposeId: "1a5d8a50-6a1e-4a56-8c2d-1e6f0c8b3c4d", Filename: "installer.iso", Size: 6, Sha256: "abc", URL: "https://s3.example.com/installer.iso"}
+	respond := signature
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer servicetoken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte("details of the service"))
+			return
+		}
+		var a Artifact
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&a))
+		require.Equal(t, artifact, a)
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]string{"signature": respond}))
+	}))
+	defer srv.Close()
+	client := NewClient(Config{PublicTransport: transportTo(srv)})
+	// the certificate of the test server is valid for example.com
+	serviceURL := "https://example.com/sign"
+
+	got, err := client.SignWithService(context.Background(), Service{URL: serviceURL, Token: "servicetoken"}, artifact)
+	require.NoError(t, err)
+	require.Equal(t, signature, got)
+
+	_, err = client.SignWithService(context.Background(), Service{URL: serviceURL}, artifact)
+	require.EqualError(t, err, "the signing service responded with 401")
+
+	respond = "not a signature"
+	_, err = client.SignWithService(context.Background(), Service{URL: serviceURL, Token: "servicetoken"}, artifact)
+	require.ErrorContains(t, err, "didn't respond with an armored signature")
+
+	// only public hosts are given the token
+	_, err = client.SignWithService(context.Background(), Service{URL: srv.URL, Token: "servicetoken"}, artifact)
+	require.ErrorContains(t, err, "isn't a public address")
+	_, err = client.SignWithService(context.Background(), Service{URL: "https://localhost/sign", Token: "servicetoken"}, artifact)
+	require.ErrorContains(t, err, "isn't a public host")
+}
+
+func TestVerifyRepository(t *testing.T) {
+	armored, entity := newKey(t)
+	key, err := ParseKey(armored)
+	require.NoError(t, err)
+	public, err := key.PublicKey()
+	require.NoError(t, err)
+	_, other := newKey(t)
+
+	repomd := []byte(`<?xml version="1.0" encoding="UTF-8"?><repomd/>`)
+	var signature bytes.Buffer
+	require.NoError(t, openpgp.ArmoredDetachSign(&signature, entity, bytes.NewReader(repomd), nil))
+	var otherSignature bytes.Buffer
+	require.NoError(t, openpgp.DetachSign(&otherSignature, other, bytes.NewReader(repomd), nil))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/el9/repodata/repomd.xml", "/other/repodata/repomd.xml":
+			_, _ = w.Write(repomd)
+		case "/el9/repodata/repomd.xml.asc":
+			_, _ = w.Write(signature.Bytes())
+		case "/other/repodata/repomd.xml.asc":
+			_, _ = w.Write(otherSignature.Bytes())
+		case "/RPM-GPG-KEY":
+			_, _ = w.Write([]byte(public))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient(Config{PublicTransport: transportTo(srv)})
+	repoURL := "http://repo.example.com"
+	require.NoError(t, client.VerifyRepository(context.Background(), repoURL+"/el9/", []string{public}))
+	require.NoError(t, client.VerifyRepository(context.Background(), repoURL+"/el9", []string{repoURL + "/RPM-GPG-KEY"}))
+
+	err = client.VerifyRepository(context.Background(), repoURL+"/other", []string{public})
+	require.ErrorContains(t, err, fmt.Sprintf("the signature of %s/other/repodata/repomd.xml doesn't verify", repoURL))
+	err = client.VerifyRepository(context.Background(), repoURL+"/unsigned", []string{public})
+	require.ErrorContains(t, err, "downloading "+repoURL+"/unsigned/repodata/repomd.xml failed: downloading failed with 404")
+	err = client.VerifyRepository(context.Background(), repoURL+"/el9", []string{repoURL + "/missing-key"})
+	require.ErrorContains(t, err, "downloading the key "+repoURL+"/missing-key failed")
+	err = client.VerifyRepository(context.Background(), repoURL+"/el9", []string{"not a key"})
+	require.ErrorContains(t, err, "reading the key failed")
+
+	// only public hosts are downloaded from
+	err = client.VerifyRepository(context.Background(), srv.URL+"/el9", []string{public})
+	require.ErrorContains(t, err, "isn't a public address")
+	err = client.VerifyRepository(context.Background(), repoURL+"/el9", []string{"http://169.254.169.254/RPM-GPG-KEY"})
+	require.ErrorContains(t, err, "isn't a public address")
+}
+
+// transportTo connects to srv whatever the host of the url is, as the public
+// client doesn't connect to the loopback address.
+func transportTo(srv *httptest.Server) *http.Transport {
+	transport := srv.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+	return transport
+}
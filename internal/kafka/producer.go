@@ -0,0 +1,405 @@
+// Package kafka publishes messages to one topic in batches, with the
+// synchronous producer of sarama.
+package kafka
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/sirupsen/logrus"
+	"github.com/xdg-go/scram"
+
+	"github.com/osbuild/image-builder/internal/prometheus"
+)
+
+const (
+	// Default number of messages sent in one request.
+	DefaultBatchSize = 100
+	// Default interval queued messages are sent in.
+	DefaultFlushInterval = time.Second
+	// Default number of messages queued for sending.
+	DefaultQueueSize = 10000
+	// Default timeout of connecting to and waiting for brokers.
+	DefaultTimeout = 10 * time.Second
+
+	// attempts to send a batch before it is dropped, metadata is fetched
+	// again before every retry
+	sendAttempts = 3
+)
+
+// SASL mechanisms the producer authenticates with.
+const (
+	MechanismPlain       = sarama.SASLTypePlaintext
+	MechanismSCRAMSHA256 = sarama.SASLTypeSCRAMSHA256
+	MechanismSCRAMSHA512 = sarama.SASLTypeSCRAMSHA512
+)
+
+type Config struct {
+	// Bootstrap brokers as host:port, the partition leaders are discovered
+	// from them.
+	Brokers  []string
+	Topic    string
+	ClientId string
+
+	// Connect with TLS, verified against CA if it's set or the system roots
+	// otherwise.
+	TLS bool
+	CA  string
+	// SASL credentials, not authenticated if Username is empty. Mechanism is
+	// PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512, PLAIN if it's empty.
+	Username  string
+	Password  string
+	Mechanism string
+	// Optional, asked for the password whenever the producer connects
+	// instead of Password, so a rotated one is picked up.
+	PasswordFunc func() string
+
+	// Number of messages sent in one request.
+	BatchSize int
+	// Interval queued messages are sent in, unless a batch fills up before.
+	FlushInterval time.Duration
+	// Number of messages queued for sending. Messages published while the
+	// queue is full are dropped rather than blocking the caller.
+	QueueSize int
+	Timeout   time.Duration
+}
+
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+type Message struct {
+	// Messages with the same key go to the same partition, messages without
+	// one are spread across partitions.
+	Key     []byte
+	Value   []byte
+	Headers []Header
+	Time    time.Time
+}
+
+type Producer struct {
+	conf      Config
+	tlsConfig *tls.Config
+
+	messages chan Message
+	dropped  atomic.Int64
+
+	// serializes SendMessages, which the queue and synchronous callers share
+	mu       sync.Mutex
+	producer sarama.SyncProducer
+}
+
+func NewProducer(conf Config) (*Producer, error) {
+	p, err := newProducer(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	ticker := time.NewTicker(p.conf.FlushInterval)
+	p.messages = make(chan Message, p.conf.QueueSize)
+
+	go p.flushMessages(ticker.C)
+
+	return p, nil
+}
+
+func newProducer(conf Config) (*Producer, error) {
+	if len(conf.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka: no brokers configured")
+	}
+	if conf.Topic == "" {
+		return nil, fmt.Errorf("kafka: no topic configured")
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = DefaultBatchSize
+	}
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = DefaultFlushInterval
+	}
+	if conf.QueueSize <= 0 {
+		conf.QueueSize = DefaultQueueSize
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = DefaultTimeout
+	}
+	switch conf.Mechanism {
+	case "":
+		conf.Mechanism = MechanismPlain
+	case MechanismPlain, MechanismSCRAMSHA256, MechanismSCRAMSHA512:
+	default:
+		return nil, fmt.Errorf("kafka: unsupported sasl mechanism %s", conf.Mechanism)
+	}
+
+	p := &Producer{
+		conf: conf,
+	}
+
+	if conf.TLS {
+		p.tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		if conf.CA != "" {
+			ca, err := os.ReadFile(conf.CA)
+			if err != nil {
+				return nil, fmt.Errorf("kafka: unable to read ca: %w", err)
+			}
+			p.tlsConfig.RootCAs = x509.NewCertPool()
+			if !p.tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("kafka: no certificates found in %s", conf.CA)
+			}
+		}
+	}
+	return p, nil
+}
+
+// saramaConfig is the configuration of the sarama producer, which waits for
+// all in-sync replicas to acknowledge the messages.
+func (p *Producer) saramaConfig() *sarama.Config {
+	sc := sarama.NewConfig()
+	if p.conf.ClientId != "" {
+		sc.ClientID = p.conf.ClientId
+	}
+	// record headers and SaslAuthenticate requests
+	sc.Version = sarama.V1_0_0_0
+	sc.Metadata.Full = false
+	sc.Net.DialTimeout = p.conf.Timeout
+	sc.Net.ReadTimeout = 2 * p.conf.Timeout
+	sc.Net.WriteTimeout = 2 * p.conf.Timeout
+	if p.tlsConfig != nil {
+		sc.Net.TLS.Enable = true
+		sc.Net.TLS.Config = p.tlsConfig
+	}
+	if p.conf.Username != "" {
+		sc.Net.SASL.Enable = true
+		sc.Net.SASL.Handshake = true
+		sc.Net.SASL.Version = sarama.SASLHandshakeV1
+		sc.Net.SASL.Mechanism = sarama.SASLMechanism(p.conf.Mechanism)
+		sc.Net.SASL.User = p.conf.Username
+		sc.Net.SASL.Password = p.password()
+		switch p.conf.Mechanism {
+		case MechanismSCRAMSHA256:
+			sc.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{hash: sha256.New} }
+		case MechanismSCRAMSHA512:
+			sc.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{hash: sha512.New} }
+		}
+	}
+	sc.Producer.RequiredAcks = sarama.WaitForAll
+	sc.Producer.Timeout = p.conf.Timeout
+	sc.Producer.Return.Successes = true
+	sc.Producer.Retry.Max = sendAttempts - 1
+	sc.Producer.Retry.Backoff = 500 * time.Millisecond
+	sc.Producer.Flush.MaxMessages = p.conf.BatchSize
+	// the partitions the Java client puts keys in, so consumers and other
+	// producers agree on where the messages of a key go
+	sc.Producer.Partitioner = sarama.NewCustomPartitioner(
+		sarama.WithAbsFirst(),
+		sarama.WithCustomHashFunction(newMurmur2),
+	)
+	return sc
+}
+
+func (p *Producer) password() string {
+	if p.conf.PasswordFunc != nil {
+		return p.conf.PasswordFunc()
+	}
+	return p.conf.Password
+}
+
+func (p *Producer) flushMessages(ticker <-chan time.Time) {
+	var messages []Message
+	flush := func() {
+		if dropped := p.dropped.Swap(0); dropped > 0 {
+			prometheus.EventsPublished.WithLabelValues("dropped").Add(float64(dropped))
+			logrus.Warnf("Dropped %d kafka messages, the producer queue was full", dropped)
+		}
+		if len(messages) == 0 {
+			return
+		}
+		err := p.SendMessages(messages)
+		if err != nil {
+			prometheus.EventsPublished.WithLabelValues("failed").Add(float64(len(messages)))
+			logrus.Errorf("Unable to send %d kafka messages: %v", len(messages), err)
+		} else {
+			prometheus.EventsPublished.WithLabelValues("published").Add(float64(len(messages)))
+		}
+		messages = nil
+	}
+
+	for {
+		select {
+		case m := <-p.messages:
+			messages = append(messages, m)
+			if len(messages) >= p.conf.BatchSize {
+				flush()
+			}
+		case <-ticker:
+			flush()
+		}
+	}
+}
+
+// Publish queues a message for sending, or drops it if the queue is full.
+func (p *Producer) Publish(msg Message) {
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	select {
+	case p.messages <- msg:
+	default:
+		p.dropped.Add(1)
+	}
+}
+
+// SendMessages sends messages to the leaders of their partitions and waits
+// for all in-sync replicas to acknowledge them. The producer connects on
+// first use, and again after an error, with the password of the moment.
+func (p *Producer) SendMessages(messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.producer == nil {
+		producer, err := sarama.NewSyncProducer(p.conf.Brokers, p.saramaConfig())
+		if err != nil {
+			return err
+		}
+		p.producer = producer
+	}
+
+	msgs := make([]*sarama.ProducerMessage, 0, len(messages))
+	for _, m := range messages {
+		msgs = append(msgs, p.producerMessage(m))
+	}
+	err := p.producer.SendMessages(msgs)
+	if err != nil {
+		p.reset()
+		return err
+	}
+	return nil
+}
+
+func (p *Producer) producerMessage(m Message) *sarama.ProducerMessage {
+	pm := &sarama.ProducerMessage{
+		Topic:     p.conf.Topic,
+		Value:     sarama.ByteEncoder(m.Value),
+		Timestamp: m.Time,
+	}
+	if m.Key != nil {
+		pm.Key = sarama.ByteEncoder(m.Key)
+	}
+	for _, h := range m.Headers {
+		pm.Headers = append(pm.Headers, sarama.RecordHeader{Key: []byte(h.Key), Value: h.Value})
+	}
+	return pm
+}
+
+// reset closes the producer, it's created again by the next SendMessages.
+func (p *Producer) reset() {
+	if p.producer == nil {
+		return
+	}
+	err := p.producer.Close()
+	if err != nil {
+		logrus.Warnf("Error closing the kafka producer: %v", err)
+	}
+	p.producer = nil
+}
+
+// scramClient is the SCRAM exchange of xdg-go/scram as sarama drives it.
+type scramClient struct {
+	hash func() hash.Hash
+	conv *scram.ClientConversation
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := scram.HashGeneratorFcn(c.hash).NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.conv = client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.conv.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.conv.Done()
+}
+
+// murmur2 is the hash of the default partitioner of Kafka.
+type murmur2 struct {
+	data []byte
+}
+
+func newMurmur2() hash.Hash32 {
+	return &murmur2{}
+}
+
+func (m *murmur2) Write(p []byte) (int, error) {
+	m.data = append(m.data, p...)
+	return len(p), nil
+}
+
+func (m *murmur2) Sum(b []byte) []byte {
+	return binary.BigEndian.AppendUint32(b, m.Sum32())
+}
+
+func (m *murmur2) Reset() {
+	m.data = nil
+}
+
+func (m *murmur2) Size() int {
+	return 4
+}
+
+func (m *murmur2) BlockSize() int {
+	return 4
+}
+
+func (m *murmur2) Sum32() uint32 {
+	const (
+		seed = 0x9747b28c
+		mul  = 0x5bd1e995
+		r    = 24
+	)
+	data := m.data
+	length := len(data)
+	h := uint32(seed) ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= mul
+		k ^= k >> r
+		k *= mul
+		h *= mul
+		h ^= k
+	}
+	tail := data[length&^3:]
+	switch len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= mul
+	}
+	h ^= h >> 13
+	h *= mul
+	h ^= h >> 15
+	return h
+}
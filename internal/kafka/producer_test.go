@@ -0,0 +1,228 @@
+package kafka
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/require"
+	"github.com/xdg-go/scram"
+)
+
+// newMockBroker is a single broker leading both partitions of a topic,
+// produce requests are answered with the responses in turn, the last one
+// repeatedly.
+func newMockBroker(t *testing.T, topic string, produce ...interface{}) *sarama.MockBroker {
+	b := sarama.NewMockBroker(t, 1)
+	t.Cleanup(b.Close)
+	if len(produce) == 0 {
+		produce = append(produce, sarama.NewMockProduceResponse(t))
+	}
+	b.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(b.Addr(), b.BrokerID()).
+			SetLeader(topic, 0, b.BrokerID()).
+			SetLeader(topic, 1, b.BrokerID()),
+		"ProduceRequest":          sarama.NewMockSequence(produce...),
+		"SaslHandshakeRequest":    sarama.NewMockSaslHandshakeResponse(t).SetEnabledMechanisms([]string{MechanismPlain}),
+		"SaslAuthenticateRequest": sarama.NewMockSaslAuthenticateResponse(t),
+	})
+	return b
+}
+
+func produceRequests(b *sarama.MockBroker) int {
+	n := 0
+	for _, rr := range b.History() {
+		if _, ok := rr.Request.(*sarama.ProduceRequest); ok {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSendMessages(t *testing.T) {
+	b := newMockBroker(t, "events")
+	p, err := newProducer(Config{
+		Brokers:  []string{b.Addr()},
+		Topic:    "events",
+		ClientId: "image-builder",
+	})
+	require.NoError(t, err)
+
+	now := time.UnixMilli(1700000000000)
+	messages := []Message{
+		{Key: []byte("a"), Value: []byte("first"), Time: now, Headers: []Header{{"content-type", []byte("application/json")}}},
+		{Key: []byte("d"), Value: []byte("second"), Time: now.Add(time.Second)},
+		{Key: []byte("a"), Value: []byte("third"), Time: now.Add(2 * time.Second)},
+	}
+	require.NoError(t, p.SendMessages(messages))
+	require.NotZero(t, produceRequests(b))
+
+	// errors of the broker are retried
+	b = newMockBroker(t, "events",
+		sarama.NewMockProduceResponse(t).
+			SetError("events", 0, sarama.ErrNotLeaderForPartition).
+			SetError("events", 1, sarama.ErrNotLeaderForPartition),
+		sarama.NewMockProduceResponse(t),
+	)
+	p, err = newProducer(Config{Brokers: []string{b.Addr()}, Topic: "events"})
+	require.NoError(t, err)
+	require.NoError(t, p.SendMessages(messages[1:2]))
+	require.Equal(t, 2, produceRequests(b))
+}
+
+func TestProducerMessage(t *testing.T) {
+	p, err := newProducer(Config{Brokers: []string{"127.0.0.1:1"}, Topic: "events"})
+	require.NoError(t, err)
+
+	now := time.UnixMilli(1700000000000)
+	pm := p.producerMessage(Message{
+		Key:     []byte("a"),
+		Value:   []byte("first"),
+		Time:    now,
+		Headers: []Header{{"content-type", []byte("application/json")}},
+	})
+	require.Equal(t, &sarama.ProducerMessage{
+		Topic:     "events",
+		Key:       sarama.ByteEncoder("a"),
+		Value:     sarama.ByteEncoder("first"),
+		Timestamp: now,
+		Headers:   []sarama.RecordHeader{{Key: []byte("content-type"), Value: []byte("application/json")}},
+	}, pm)
+
+	// messages without a key are spread across partitions
+	require.Nil(t, p.producerMessage(Message{Value: []byte("event")}).Key)
+}
+
+func TestSendMessagesSASL(t *testing.T) {
+	b := newMockBroker(t, "events")
+	p, err := newProducer(Config{
+		Brokers:  []string{b.Addr()},
+		Topic:    "events",
+		Username: "user",
+		Password: "secret",
+	})
+	require.NoError(t, err)
+	require.NoError(t, p.SendMessages([]Message{{Value: []byte("event"), Time: time.Now()}}))
+
+	var auth *sarama.SaslAuthenticateRequest
+	for _, rr := range b.History() {
+		if req, ok := rr.Request.(*sarama.SaslAuthenticateRequest); ok {
+			auth = req
+		}
+	}
+	require.NotNil(t, auth)
+	require.Equal(t, "\x00user\x00secret", string(auth.SaslAuthBytes))
+
+	// the broker refusing the credentials fails the send
+	b.SetHandlerByMap(map[string]sarama.MockResponse{
+		"SaslHandshakeRequest":    sarama.NewMockSaslHandshakeResponse(t).SetEnabledMechanisms([]string{MechanismPlain}),
+		"SaslAuthenticateRequest": sarama.NewMockSaslAuthenticateResponse(t).SetError(sarama.ErrSASLAuthenticationFailed),
+	})
+	p.reset()
+	require.Error(t, p.SendMessages([]Message{{Value: []byte("event"), Time: time.Now()}}))
+
+	_, err = newProducer(Config{Brokers: []string{"127.0.0.1:1"}, Topic: "events", Mechanism: "GSSAPI"})
+	require.ErrorContains(t, err, "unsupported sasl mechanism")
+}
+
+// A rotated password is used once the producer connects again.
+func TestPasswordFunc(t *testing.T) {
+	password := "first"
+	p, err := newProducer(Config{
+		Brokers:      []string{"127.0.0.1:1"},
+		Topic:        "events",
+		Username:     "user",
+		PasswordFunc: func() string { return password },
+	})
+	require.NoError(t, err)
+	require.Equal(t, "first", p.saramaConfig().Net.SASL.Password)
+	password = "second"
+	require.Equal(t, "second", p.saramaConfig().Net.SASL.Password)
+}
+
+func TestSCRAMClient(t *testing.T) {
+	for mechanism, h := range map[string]func() hash.Hash{
+		MechanismSCRAMSHA256: sha256.New,
+		MechanismSCRAMSHA512: sha512.New,
+	} {
+		p, err := newProducer(Config{Brokers: []string{"127.0.0.1:1"}, Topic: "events", Username: "user", Password: "pencil", Mechanism: mechanism})
+		require.NoError(t, err)
+		sc := p.saramaConfig()
+		require.Equal(t, sarama.SASLMechanism(mechanism), sc.Net.SASL.Mechanism)
+
+		// the server side of the exchange knows the password as well
+		kf := scram.KeyFactors{Salt: "salt", Iters: 4096}
+		client, err := scram.HashGeneratorFcn(h).NewClient("user", "pencil", "")
+		require.NoError(t, err)
+		credentials := client.GetStoredCredentials(kf)
+		server, err := scram.HashGeneratorFcn(h).NewServer(func(string) (scram.StoredCredentials, error) {
+			return credentials, nil
+		})
+		require.NoError(t, err)
+		serverConv := server.NewConversation()
+
+		c := sc.Net.SASL.SCRAMClientGeneratorFunc()
+		require.NoError(t, c.Begin("user", "pencil", ""))
+		msg, err := c.Step("")
+		require.NoError(t, err)
+		for !c.Done() {
+			msg, err = serverConv.Step(msg)
+			require.NoError(t, err, mechanism)
+			msg, err = c.Step(msg)
+			require.NoError(t, err, mechanism)
+		}
+		require.True(t, serverConv.Valid(), mechanism)
+
+		// a client with the wrong password is refused
+		c = sc.Net.SASL.SCRAMClientGeneratorFunc()
+		require.NoError(t, c.Begin("user", "wrong", ""))
+		serverConv = server.NewConversation()
+		msg, err = c.Step("")
+		require.NoError(t, err)
+		msg, err = serverConv.Step(msg)
+		require.NoError(t, err)
+		msg, err = c.Step(msg)
+		require.NoError(t, err)
+		_, err = serverConv.Step(msg)
+		require.Error(t, err, mechanism)
+	}
+}
+
+// Keys hash to the partitions the Java client puts them in.
+func TestPartition(t *testing.T) {
+	for key, hash := range map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	} {
+		m := newMurmur2()
+		_, err := m.Write([]byte(key))
+		require.NoError(t, err)
+		require.Equal(t, hash, int32(m.Sum32()), key)
+	}
+
+	p, err := newProducer(Config{Brokers: []string{"127.0.0.1:1"}, Topic: "events"})
+	require.NoError(t, err)
+	partitioner := p.saramaConfig().Producer.Partitioner("events")
+	partition, err := partitioner.Partition(p.producerMessage(Message{Key: []byte("21")}), 3)
+	require.NoError(t, err)
+	// toPositive(-973932308) % 3
+	require.Equal(t, int32(1173551340%3), partition)
+}
+
+func TestPublishDrops(t *testing.T) {
+	p, err := newProducer(Config{Brokers: []string{"127.0.0.1:1"}, Topic: "events", QueueSize: 1})
+	require.NoError(t, err)
+	p.messages = make(chan Message, p.conf.QueueSize)
+	p.Publish(Message{Value: []byte("queued")})
+	p.Publish(Message{Value: []byte("dropped")})
+	require.Equal(t, int64(1), p.dropped.Load())
+	require.False(t, (<-p.messages).Time.IsZero())
+}
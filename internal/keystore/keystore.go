@@ -0,0 +1,142 @@
+// Package keystore encrypts the signing keys of orgs with envelope
+// encryption. Every key is encrypted with a data key of its own, which is in
+// turn encrypted with a key encryption key of the service. Only the
+// encrypted data key is stored next to the key, so rotating the key
+// encryption key doesn't touch the keys themselves.
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Envelope is a key as it's stored.
+type Envelope struct {
+	// Id of the key encryption key the data key is encrypted with
+	KeyId string
+	// The data key, encrypted with the key encryption key
+	DataKey []byte
+	// The key, encrypted with the data key
+	Ciphertext []byte
+}
+
+// Keystore holds the key encryption keys, the current one encrypts, all of
+// them decrypt.
+type Keystore struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// Parse reads key encryption keys separated by commas, each an id and the
+// base64 encoded 32 bytes of an AES key separated by a colon, e.g.
+// "2024-02:<key>,2023-06:<key>". The first key is the current one, the
+// others are kept to decrypt envelopes until they're encrypted again.
+func Parse(s string) (*Keystore, error) {
+	store := &Keystore{
+		keys: map[string]cipher.AEAD{},
+	}
+	for _, entry := range strings.Split(s, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("expected a key encryption key as id:key, got %q", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key encryption key %s isn't base64 encoded: %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key encryption key %s has %d bytes instead of 32", id, len(key))
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := store.keys[id]; ok {
+			return nil, fmt.Errorf("key encryption key %s is given twice", id)
+		}
+		store.keys[id] = aead
+		if store.current == "" {
+			store.current = id
+		}
+	}
+	return store, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext and prepends the random nonce.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// Encrypt encrypts plaintext with a new data key. The additional data, e.g.
+// the id of the key, isn't encrypted but has to be the same to decrypt,
+// which keeps envelopes from being swapped.
+func (k *Keystore) Encrypt(plaintext, additionalData []byte) (Envelope, error) {
+	dataKey := make([]byte, 32)
+	_, err := rand.Read(dataKey)
+	if err != nil {
+		return Envelope{}, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return Envelope{}, err
+	}
+	ciphertext, err := seal(aead, plaintext, additionalData)
+	if err != nil {
+		return Envelope{}, err
+	}
+	encryptedDataKey, err := seal(k.keys[k.current], dataKey, additionalData)
+	if err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{
+		KeyId:      k.current,
+		DataKey:    encryptedDataKey,
+		Ciphertext: ciphertext,
+	}, nil
+}
+
+// Decrypt returns the plaintext of an envelope made by Encrypt.
+func (k *Keystore) Decrypt(e Envelope, additionalData []byte) ([]byte, error) {
+	kek, ok := k.keys[e.KeyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown key encryption key %s", e.KeyId)
+	}
+	dataKey, err := open(kek, e.DataKey, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting the data key failed: %w", err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(aead, e.Ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting the key failed: %w", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,104 @@
+// Package kms checks that KMS keys of orgs can be used to encrypt images.
+// Only what's needed for that is implemented.
+package kms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+
+	"github.com/osbuild/image-builder/internal/common"
+)
+
+var defaultTimeouts = common.HTTPTimeouts{
+	Connect: 30 * time.Second,
+	Read:    time.Minute,
+	Request: time.Minute,
+}
+
+type Config struct {
+	// Optional, e.g. of a mock, all regions are requested from it then.
+	// Defaults to the regional endpoint.
+	Endpoint string
+	// The default credential chain if nil, e.g. the role of the pod.
+	Credentials *credentials.Credentials
+	// Defaults to a request timeout of a minute.
+	Timeouts common.HTTPTimeouts
+}
+
+type Client struct {
+	sess *session.Session
+}
+
+// Key is a key which can be used to encrypt images.
+type Key struct {
+	// the ARN of the key, also if it was named by an alias
+	Arn    string
+	Region string
+}
+
+func NewClient(conf Config) (*Client, error) {
+	if conf.Timeouts == (common.HTTPTimeouts{}) {
+		conf.Timeouts = defaultTimeouts
+	}
+	awsConf := aws.NewConfig().WithHTTPClient(common.NewHTTPClient(conf.Timeouts, nil))
+	if conf.Credentials != nil {
+		awsConf = awsConf.WithCredentials(conf.Credentials)
+	}
+	if conf.Endpoint != "" {
+		awsConf = awsConf.WithEndpoint(conf.Endpoint)
+	}
+	sess, err := session.NewSession(awsConf)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		sess: sess,
+	}, nil
+}
+
+// CheckKey makes sure the key named by an ARN or alias ARN is an enabled
+// symmetric encryption key, which the credentials of the client are allowed
+// to generate data keys with. That's what encrypting a snapshot needs.
+func (c *Client) CheckKey(ctx context.Context, region, keyId string) (*Key, error) {
+	client := kms.New(c.sess, aws.NewConfig().WithRegion(region))
+	described, err := client.DescribeKeyWithContext(ctx, &kms.DescribeKeyInput{
+		KeyId: aws.String(keyId),
+	})
+	if err != nil {
+		return nil, requestError("DescribeKey", keyId, err)
+	}
+	md := described.KeyMetadata
+	if state := aws.StringValue(md.KeyState); state != kms.KeyStateEnabled {
+		return nil, fmt.Errorf("key %s is %s", keyId, strings.ToLower(state))
+	}
+	if aws.StringValue(md.KeyUsage) != kms.KeyUsageTypeEncryptDecrypt || aws.StringValue(md.KeySpec) != kms.KeySpecSymmetricDefault {
+		return nil, fmt.Errorf("key %s isn't a symmetric encryption key", keyId)
+	}
+
+	// the data key itself isn't needed, only whether it can be generated
+	_, err = client.GenerateDataKeyWithoutPlaintextWithContext(ctx, &kms.GenerateDataKeyWithoutPlaintextInput{
+		KeyId:   md.Arn,
+		KeySpec: aws.String(kms.DataKeySpecAes256),
+	})
+	if err != nil {
+		return nil, requestError("GenerateDataKeyWithoutPlaintext", aws.StringValue(md.Arn), err)
+	}
+	return &Key{Arn: aws.StringValue(md.Arn), Region: region}, nil
+}
+
+func requestError(action, keyId string, err error) error {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return fmt.Errorf("%s of key %s failed with %s: %s", action, keyId, awsErr.Code(), awsErr.Message())
+	}
+	return fmt.Errorf("%s of key %s failed: %w", action, keyId, err)
+}
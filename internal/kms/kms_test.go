@@ -0,0 +1,73 @@
+package kms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/require"
+)
+
+const keyArn = "arn:aws:kms:eu-west-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+
+func TestCheckKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/"))
+		require.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/kms/")
+		var params map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.DescribeKey":
+			switch params["KeyId"] {
+			case "arn:aws:kms:eu-west-1:123456789012:alias/images":
+				fmt.Fprintf(w, `{"KeyMetadata":{"Arn":%q,"KeyState":"Enabled","KeyUsage":"ENCRYPT_DECRYPT","KeySpec":"SYMMETRIC_DEFAULT"}}`, keyArn)
+			case "arn:aws:kms:eu-west-1:123456789012:alias/disabled":
+				fmt.Fprint(w, `{"KeyMetadata":{"Arn":"arn:aws:kms:eu-west-1:123456789012:key/disabled","KeyState":"Disabled","KeyUsage":"ENCRYPT_DECRYPT","KeySpec":"SYMMETRIC_DEFAULT"}}`)
+			case "arn:aws:kms:eu-west-1:123456789012:alias/signing":
+				fmt.Fprint(w, `{"KeyMetadata":{"Arn":"arn:aws:kms:eu-west-1:123456789012:key/signing","KeyState":"Enabled","KeyUsage":"SIGN_VERIFY","KeySpec":"RSA_2048"}}`)
+			case "arn:aws:kms:eu-west-1:123456789012:alias/denied":
+				fmt.Fprint(w, `{"KeyMetadata":{"Arn":"arn:aws:kms:eu-west-1:123456789012:key/denied","KeyState":"Enabled","KeyUsage":"ENCRYPT_DECRYPT","KeySpec":"SYMMETRIC_DEFAULT"}}`)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"__type":"NotFoundException","message":"Alias is not found."}`)
+			}
+		case "TrentService.GenerateDataKeyWithoutPlaintext":
+			require.Equal(t, "AES_256", params["KeySpec"])
+			if params["KeyId"] != keyArn {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"__type":"com.amazonaws.kms#AccessDeniedException","message":"not authorized"}`)
+				return
+			}
+			fmt.Fprint(w, `{"CiphertextBlob":"AAAA","KeyId":"x"}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(Config{
+		Endpoint:    srv.URL,
+		Credentials: credentials.NewStaticCredentials("key", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	key, err := client.CheckKey(context.Background(), "eu-west-1", "arn:aws:kms:eu-west-1:123456789012:alias/images")
+	require.NoError(t, err)
+	require.Equal(t, &Key{Arn: keyArn, Region: "eu-west-1"}, key)
+
+	_, err = client.CheckKey(context.Background(), "eu-west-1", "arn:aws:kms:eu-west-1:123456789012:alias/disabled")
+	require.ErrorContains(t, err, "is disabled")
+	_, err = client.CheckKey(context.Background(), "eu-west-1", "arn:aws:kms:eu-west-1:123456789012:alias/signing")
+	require.ErrorContains(t, err, "isn't a symmetric encryption key")
+	_, err = client.CheckKey(context.Background(), "eu-west-1", "arn:aws:kms:eu-west-1:123456789012:alias/denied")
+	require.ErrorContains(t, err, "GenerateDataKeyWithoutPlaintext of key arn:aws:kms:eu-west-1:123456789012:key/denied failed with AccessDeniedException: not authorized")
+	_, err = client.CheckKey(context.Background(), "eu-west-1", "arn:aws:kms:eu-west-1:123456789012:alias/nope")
+	require.ErrorContains(t, err, "failed with NotFoundException")
+}
@@ -86,27 +86,35 @@ func (f *Formatter) Format(entry *logrus.Entry) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
-func ConfigLogger(log *logrus.Logger, level string) error {
-
-	// avoid configuring standard logger multiple times to avoid duplicate hooks
-	if stdLoggerConfigd && log == logrus.StandardLogger() {
-		return nil
-	}
-
+// parseLevel parses the LOG_LEVEL values, anything else is info.
+func parseLevel(level string) logrus.Level {
 	switch strings.ToUpper(level) {
 	case "TRACE":
-		logLevel = logrus.TraceLevel
+		return logrus.TraceLevel
 	case "DEBUG":
-		logLevel = logrus.DebugLevel
+		return logrus.DebugLevel
 	case "ERROR":
-		logLevel = logrus.ErrorLevel
+		return logrus.ErrorLevel
 	case "INFO":
 		fallthrough
 	default:
-		logLevel = logrus.InfoLevel
+		return logrus.InfoLevel
 	}
+}
 
-	log.SetLevel(logLevel)
+func ConfigLogger(log *logrus.Logger, level string) error {
+
+	// avoid configuring standard logger multiple times to avoid duplicate hooks
+	if stdLoggerConfigd && log == logrus.StandardLogger() {
+		return nil
+	}
+
+	logLevel = parseLevel(level)
+	if log == logrus.StandardLogger() {
+		SetLevel(logLevel)
+	} else {
+		log.SetLevel(logLevel)
+	}
 	log.SetOutput(os.Stdout)
 	log.SetReportCaller(true)
 	log.SetFormatter(&logrus.TextFormatter{
@@ -134,8 +142,8 @@ func AddCloudWatchHook(log *logrus.Logger, key, secret, region, group string) er
 	return nil
 }
 
-func AddSplunkHook(log *logrus.Logger, host, port, token string) error {
-	hook, err := NewSplunkHook(host, port, token, "image-builder")
+func AddSplunkHook(log *logrus.Logger, conf SplunkConfig) error {
+	hook, err := NewSplunkHook(conf, "image-builder")
 	if err != nil {
 		return err
 	}
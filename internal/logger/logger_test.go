@@ -2,6 +2,7 @@ package logger
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -90,7 +91,77 @@ func TestSplunkLogger(t *testing.T) {
 		require.Equal(t, "message", sp.Event.Message)
 		ch <- true
 	}))
-	sl := NewSplunkLogger(srv.URL, "", "image-builder", "test-host")
+	sl := NewSplunkLogger(srv.URL, "image-builder", "test-host", SplunkConfig{})
 	require.NoError(t, sl.LogWithTime(time.Now(), "message"))
 	require.True(t, <-ch)
 }
+
+func TestSplunkLoggerBatching(t *testing.T) {
+	batches := make(chan []SplunkPayload, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []SplunkPayload
+		decoder := json.NewDecoder(r.Body)
+		for decoder.More() {
+			var sp SplunkPayload
+			require.NoError(t, decoder.Decode(&sp))
+			batch = append(batch, sp)
+		}
+		batches <- batch
+	}))
+	defer srv.Close()
+
+	// full batches are sent right away
+	sl := NewSplunkLogger(srv.URL, "image-builder", "test-host", SplunkConfig{
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+	sl.Log(time.Now(), SplunkEvent{Message: "one", Level: "info", Fields: map[string]interface{}{"request_id": "abc"}})
+	sl.Log(time.Now(), SplunkEvent{Message: "two"})
+	select {
+	case batch := <-batches:
+		require.Len(t, batch, 2)
+		require.Equal(t, "one", batch[0].Event.Message)
+		require.Equal(t, "info", batch[0].Event.Level)
+		require.Equal(t, "abc", batch[0].Event.Fields["request_id"])
+		require.Equal(t, "image-builder", batch[1].Event.Ident)
+	case <-time.After(10 * time.Second):
+		t.Fatal("batch wasn't sent")
+	}
+}
+
+func TestSplunkHook(t *testing.T) {
+	payloads := make(chan SplunkPayload, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var sp SplunkPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&sp))
+		payloads <- sp
+	}))
+	defer srv.Close()
+
+	log := CreateLogger()
+	log.AddHook(&SplunkHook{
+		sl: NewSplunkLogger(srv.URL, "image-builder", "test-host", SplunkConfig{BatchSize: 1}),
+	})
+	log.WithField("org_id", "000000").WithError(fmt.Errorf("failed")).Warn("message")
+
+	select {
+	case sp := <-payloads:
+		require.Equal(t, "message", sp.Event.Message)
+		require.Equal(t, "warning", sp.Event.Level)
+		require.Equal(t, "000000", sp.Event.Fields["org_id"])
+		require.Equal(t, "failed", sp.Event.Fields["error"])
+	case <-time.After(10 * time.Second):
+		t.Fatal("event wasn't sent")
+	}
+}
+
+func TestSplunkLoggerDropsEvents(t *testing.T) {
+	sl := &SplunkLogger{
+		payloads: make(chan *SplunkPayload, 1),
+	}
+	sl.Log(time.Now(), SplunkEvent{Message: "one"})
+	sl.Log(time.Now(), SplunkEvent{Message: "two"})
+	sl.Log(time.Now(), SplunkEvent{Message: "three"})
+	require.Len(t, sl.payloads, 1)
+	require.Equal(t, int64(2), sl.Dropped())
+}
@@ -0,0 +1,201 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Modules whose level can be set apart from the global one, e.g. to debug
+// the queries of the service without the debug logs of every request.
+const (
+	ModuleDB       = "db"
+	ModuleComposer = "composer"
+	ModuleAuth     = "auth"
+)
+
+type module struct {
+	logger *logrus.Logger
+	// nil if the module follows the global level
+	level *logrus.Level
+}
+
+var (
+	modulesMu sync.Mutex
+	modules   = map[string]*module{}
+)
+
+func init() {
+	for _, name := range []string{ModuleDB, ModuleComposer, ModuleAuth} {
+		modules[name] = &module{
+			logger: &logrus.Logger{
+				Out:          stdWriter{},
+				Formatter:    stdFormatter{},
+				Hooks:        logrus.LevelHooks{},
+				ReportCaller: true,
+				Level:        logrus.InfoLevel,
+				ExitFunc:     os.Exit,
+			},
+		}
+		modules[name].logger.AddHook(stdHooks{})
+	}
+}
+
+// Module loggers write through the standard logger, so they pick up its
+// output, formatter and hooks regardless of when those are configured.
+type stdWriter struct{}
+
+func (stdWriter) Write(p []byte) (int, error) {
+	return logrus.StandardLogger().Out.Write(p)
+}
+
+type stdFormatter struct{}
+
+func (stdFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	return logrus.StandardLogger().Formatter.Format(entry)
+}
+
+type stdHooks struct{}
+
+func (stdHooks) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (stdHooks) Fire(entry *logrus.Entry) error {
+	for _, hook := range logrus.StandardLogger().Hooks[entry.Level] {
+		if err := hook.Fire(entry); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to fire hook: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// Module returns the logger of a module, its entries are marked with the
+// module they belong to.
+func Module(name string) *logrus.Entry {
+	modulesMu.Lock()
+	m, ok := modules[name]
+	modulesMu.Unlock()
+	if !ok {
+		return logrus.WithField("module", name)
+	}
+	return m.logger.WithField("module", name)
+}
+
+// Level returns the global level and the levels of the modules.
+func Level() (logrus.Level, map[string]logrus.Level) {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+
+	levels := map[string]logrus.Level{}
+	for name, m := range modules {
+		levels[name] = m.logger.GetLevel()
+	}
+	return logrus.GetLevel(), levels
+}
+
+// Modules returns the names of the modules, sorted.
+func Modules() []string {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+
+	var names []string
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetLevel changes the global level, modules without a level of their own
+// follow it.
+func SetLevel(level logrus.Level) {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+
+	logrus.SetLevel(level)
+	for _, m := range modules {
+		if m.level == nil {
+			m.logger.SetLevel(level)
+		}
+	}
+}
+
+// SetModuleLevel changes the level of a module, or makes it follow the
+// global level again if level is nil.
+func SetModuleLevel(name string, level *logrus.Level) error {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+
+	m, ok := modules[name]
+	if !ok {
+		return fmt.Errorf("unknown log module %q", name)
+	}
+	m.level = level
+	if level == nil {
+		m.logger.SetLevel(logrus.GetLevel())
+	} else {
+		m.logger.SetLevel(*level)
+	}
+	return nil
+}
+
+// SetModuleLevels sets the levels of modules from a comma separated list of
+// module=level pairs, e.g. "db=debug,auth=warn".
+func SetModuleLevels(levels string) error {
+	for _, pair := range strings.Split(levels, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("expected module=level, got %q", pair)
+		}
+		level, err := logrus.ParseLevel(strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+		err = SetModuleLevel(strings.TrimSpace(name), &level)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReloadLevels replaces the configured global level and the levels of all
+// modules, modules which aren't in moduleLevels follow the global level again.
+func ReloadLevels(level, moduleLevels string) error {
+	logLevel = parseLevel(level)
+	SetLevel(logLevel)
+	for _, name := range Modules() {
+		err := SetModuleLevel(name, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return SetModuleLevels(moduleLevels)
+}
+
+// ToggleDebugOnSignal switches the global level between debug and the
+// configured one whenever the process receives SIGUSR1.
+func ToggleDebugOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		for range signals {
+			level := logrus.DebugLevel
+			if logrus.GetLevel() == logrus.DebugLevel {
+				level = logLevel
+			}
+			SetLevel(level)
+			logrus.Warnf("Log level set to %s on SIGUSR1", level)
+		}
+	}()
+}
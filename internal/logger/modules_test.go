@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleLevels(t *testing.T) {
+	var buf bytes.Buffer
+	out := logrus.StandardLogger().Out
+	logrus.SetOutput(&buf)
+	defer logrus.SetOutput(out)
+	defer SetLevel(logrus.GetLevel())
+	defer func() {
+		for _, name := range Modules() {
+			require.NoError(t, SetModuleLevel(name, nil))
+		}
+	}()
+
+	SetLevel(logrus.InfoLevel)
+	Module(ModuleDB).Debug("hidden")
+	require.Empty(t, buf.String())
+
+	require.NoError(t, SetModuleLevels("db=debug, auth=error"))
+	Module(ModuleDB).Debug("query")
+	require.Contains(t, buf.String(), "module=db")
+	require.Contains(t, buf.String(), "msg=query")
+	buf.Reset()
+	Module(ModuleAuth).Warn("hidden")
+	Module(ModuleComposer).Debug("hidden")
+	require.Empty(t, buf.String())
+
+	// modules without a level of their own follow the global level
+	SetLevel(logrus.DebugLevel)
+	Module(ModuleComposer).Debug("request")
+	require.Contains(t, buf.String(), "module=composer")
+	_, levels := Level()
+	require.Equal(t, logrus.DebugLevel, levels[ModuleDB])
+	require.Equal(t, logrus.ErrorLevel, levels[ModuleAuth])
+	require.NoError(t, SetModuleLevel(ModuleAuth, nil))
+	_, levels = Level()
+	require.Equal(t, logrus.DebugLevel, levels[ModuleAuth])
+
+	require.Error(t, SetModuleLevels("db"))
+	require.Error(t, SetModuleLevels("db=verbose"))
+	require.Error(t, SetModuleLevels("unknown=debug"))
+}
+
+func TestReloadLevels(t *testing.T) {
+	defer SetLevel(logrus.GetLevel())
+	defer func() {
+		for _, name := range Modules() {
+			require.NoError(t, SetModuleLevel(name, nil))
+		}
+	}()
+
+	require.NoError(t, ReloadLevels("INFO", "db=debug,auth=error"))
+	require.NoError(t, ReloadLevels("ERROR", "auth=debug"))
+	level, levels := Level()
+	require.Equal(t, logrus.ErrorLevel, level)
+	require.Equal(t, logrus.ErrorLevel, levels[ModuleDB])
+	require.Equal(t, logrus.DebugLevel, levels[ModuleAuth])
+	require.Equal(t, logrus.ErrorLevel, logLevel)
+
+	require.Error(t, ReloadLevels("INFO", "unknown=debug"))
+}
@@ -11,25 +11,40 @@ type SplunkHook struct {
 	sl *SplunkLogger
 }
 
-func NewSplunkHook(host, port, token, source string) (*SplunkHook, error) {
-	url := fmt.Sprintf("https://%s:%s/services/collector/event", host, port)
+func NewSplunkHook(conf SplunkConfig, source string) (*SplunkHook, error) {
+	url := fmt.Sprintf("https://%s:%s/services/collector/event", conf.Host, conf.Port)
 	hostname, err := os.Hostname()
 	if err != nil {
 		return nil, err
 	}
 
 	return &SplunkHook{
-		sl: NewSplunkLogger(url, token, source, hostname),
+		sl: NewSplunkLogger(url, source, hostname, conf),
 	}, nil
 }
 
+// Fire sends the fields of entries along with their message, so they can be
+// searched on in splunk.
 func (sh *SplunkHook) Fire(entry *logrus.Entry) error {
-	msg, err := entry.String()
-	if err != nil {
-		return err
+	fields := map[string]interface{}{}
+	for k, v := range entry.Data {
+		switch v := v.(type) {
+		case error:
+			fields[k] = v.Error()
+		default:
+			fields[k] = v
+		}
+	}
+	if entry.HasCaller() {
+		fields["caller"] = entry.Caller.Function
 	}
 
-	return sh.sl.LogWithTime(entry.Time, msg)
+	sh.sl.Log(entry.Time, SplunkEvent{
+		Message: entry.Message,
+		Level:   entry.Level.String(),
+		Fields:  fields,
+	})
+	return nil
 }
 
 func (sh *SplunkHook) Levels() []logrus.Level {
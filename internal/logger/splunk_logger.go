@@ -6,25 +6,46 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
 )
 
 const (
-	PayloadsChannelSize = 1000
-	// in seconds, how often batched events should be sent
-	SendFrequency = 5
+	// Default number of events sent in one request.
+	DefaultBatchSize = 1000
+	// Default interval queued events are sent in.
+	DefaultFlushInterval = 5 * time.Second
+	// Default number of events queued for sending.
+	DefaultQueueSize = 10000
 )
 
+type SplunkConfig struct {
+	Host  string
+	Port  string
+	Token string
+
+	// Number of events sent in one request.
+	BatchSize int
+	// Interval queued events are sent in, unless a batch fills up before.
+	FlushInterval time.Duration
+	// Number of events queued for sending. Sending batches is retried,
+	// events logged while the queue is full are dropped rather than blocking
+	// the caller, the number dropped is logged with the next batch.
+	QueueSize int
+}
+
 type SplunkLogger struct {
-	client   *http.Client
-	url      string
-	token    string
-	source   string
-	hostname string
+	client    *http.Client
+	url       string
+	token     string
+	source    string
+	hostname  string
+	batchSize int
 
 	payloads chan *SplunkPayload
+	dropped  atomic.Int64
 }
 
 type SplunkPayload struct {
@@ -35,22 +56,35 @@ type SplunkPayload struct {
 }
 
 type SplunkEvent struct {
-	Message string `json:"message"`
-	Ident   string `json:"ident"`
-	Host    string `json:"host"`
+	Message string                 `json:"message"`
+	Ident   string                 `json:"ident"`
+	Host    string                 `json:"host"`
+	Level   string                 `json:"level,omitempty"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
 }
 
-func NewSplunkLogger(url, token, source, hostname string) *SplunkLogger {
+func NewSplunkLogger(url, source, hostname string, conf SplunkConfig) *SplunkLogger {
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = DefaultBatchSize
+	}
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = DefaultFlushInterval
+	}
+	if conf.QueueSize <= 0 {
+		conf.QueueSize = DefaultQueueSize
+	}
+
 	sl := &SplunkLogger{
-		client:   retryablehttp.NewClient().StandardClient(),
-		url:      url,
-		token:    token,
-		source:   source,
-		hostname: hostname,
+		client:    retryablehttp.NewClient().StandardClient(),
+		url:       url,
+		token:     conf.Token,
+		source:    source,
+		hostname:  hostname,
+		batchSize: conf.BatchSize,
 	}
 
-	ticker := time.NewTicker(time.Second * SendFrequency)
-	sl.payloads = make(chan *SplunkPayload, PayloadsChannelSize)
+	ticker := time.NewTicker(conf.FlushInterval)
+	sl.payloads = make(chan *SplunkPayload, conf.QueueSize)
 
 	go sl.flushPayloads(ticker.C)
 
@@ -59,25 +93,31 @@ func NewSplunkLogger(url, token, source, hostname string) *SplunkLogger {
 
 func (sl *SplunkLogger) flushPayloads(ticker <-chan time.Time) {
 	var payloads []*SplunkPayload
+	flush := func() {
+		if dropped := sl.dropped.Swap(0); dropped > 0 {
+			payloads = append(payloads, sl.payload(time.Now(), SplunkEvent{
+				Message: fmt.Sprintf("Dropped %d log events, the splunk logger queue was full", dropped),
+				Level:   "warning",
+			}))
+		}
+		err := sl.SendPayloads(payloads)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Splunk logger unable to send %d payloads: %v\n", len(payloads), err)
+		}
+		payloads = nil
+	}
+
 	for {
 		select {
 		case p := <-sl.payloads:
 			if p != nil {
 				payloads = append(payloads, p)
 			}
-			if len(payloads) == PayloadsChannelSize {
-				err := sl.SendPayloads(payloads)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Splunk logger unable to send payloads: %v", err)
-				}
-				payloads = nil
+			if len(payloads) >= sl.batchSize {
+				flush()
 			}
 		case <-ticker:
-			err := sl.SendPayloads(payloads)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Splunk logger unable to send payloads: %v", err)
-			}
-			payloads = nil
+			flush()
 		}
 	}
 }
@@ -128,20 +168,33 @@ func (sl *SplunkLogger) SendPayloads(payloads []*SplunkPayload) error {
 	return nil
 }
 
-func (sl *SplunkLogger) LogWithTime(t time.Time, msg string) error {
-	sp := SplunkPayload{
-		Time: t.Unix(),
-		Host: sl.hostname,
-		Event: SplunkEvent{
-			Message: msg,
-			Ident:   sl.source,
-			Host:    sl.hostname,
-		},
+func (sl *SplunkLogger) payload(t time.Time, event SplunkEvent) *SplunkPayload {
+	event.Ident = sl.source
+	event.Host = sl.hostname
+	return &SplunkPayload{
+		Time:  t.Unix(),
+		Host:  sl.hostname,
+		Event: event,
 	}
+}
+
+// Log queues an event for sending, or drops it if the queue is full.
+func (sl *SplunkLogger) Log(t time.Time, event SplunkEvent) {
 	select {
-	case sl.payloads <- &sp:
+	case sl.payloads <- sl.payload(t, event):
 	default:
-		return fmt.Errorf("Error queueing splunk payload, channel full")
+		sl.dropped.Add(1)
 	}
+}
+
+func (sl *SplunkLogger) LogWithTime(t time.Time, msg string) error {
+	sl.Log(t, SplunkEvent{
+		Message: msg,
+	})
 	return nil
 }
+
+// Dropped returns the number of events dropped since the last batch was sent.
+func (sl *SplunkLogger) Dropped() int64 {
+	return sl.dropped.Load()
+}
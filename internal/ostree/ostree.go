@@ -0,0 +1,124 @@
+// Package ostree reads commits out of the tarballs of edge-commit images and
+// writes the detached metadata their signatures are stored in.
+package ostree
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var commitRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ObjectPath is where the object with checksum is stored in a repo, with
+// extension given by its type, e.g. commit or commitmeta.
+func ObjectPath(checksum, extension string) string {
+	return fmt.Sprintf("objects/%s/%s.%s", checksum[:2], checksum[2:], extension)
+}
+
+// ReadCommit finds the object of commit in the tarball of a repo, which may
+// be compressed with gzip, and checks that it matches its checksum.
+func ReadCommit(r io.Reader, commit string) ([]byte, error) {
+	if !commitRegex.MatchString(commit) {
+		return nil, fmt.Errorf("invalid commit %q", commit)
+	}
+
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	var tr *tar.Reader
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		tr = tar.NewReader(zr)
+	} else {
+		tr = tar.NewReader(br)
+	}
+
+	path := ObjectPath(commit, "commit")
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("commit %s not found in the tarball", commit)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || !strings.HasSuffix(strings.TrimPrefix(hdr.Name, "./"), path) {
+			continue
+		}
+		// commits are small, anything else isn't one
+		object, err := io.ReadAll(io.LimitReader(tr, 1<<20))
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(object)
+		if hex.EncodeToString(sum[:]) != commit {
+			return nil, fmt.Errorf("the object of commit %s doesn't match its checksum", commit)
+		}
+		return object, nil
+	}
+}
+
+// CommitMeta is the detached metadata of a commit with the binary OpenPGP
+// signatures of it, the a{sv} GVariant ostree reads from the commitmeta
+// object of the commit.
+func CommitMeta(signatures [][]byte) []byte {
+	// aay, the signatures followed by the offsets of their ends
+	var sigs []byte
+	var ends []int
+	for _, s := range signatures {
+		sigs = append(sigs, s...)
+		ends = append(ends, len(sigs))
+	}
+	sigs = appendOffsets(sigs, ends)
+
+	// the v holding the aay, followed by its type
+	variant := append(append(sigs, 0), "aay"...)
+
+	// the {sv} entry, the key is padded to the alignment of the variant
+	// and the end of the key follows the entry
+	entry := append([]byte("ostree.gpgsigs"), 0)
+	keyEnd := len(entry)
+	for len(entry)%8 != 0 {
+		entry = append(entry, 0)
+	}
+	entry = appendOffsets(append(entry, variant...), []int{keyEnd})
+
+	// a{sv} with the single entry
+	return appendOffsets(entry, []int{len(entry)})
+}
+
+// appendOffsets appends the framing offsets of a container, in the least
+// amount of bytes which can address the whole container including them.
+func appendOffsets(content []byte, offsets []int) []byte {
+	if len(content) == 0 && len(offsets) == 0 {
+		return content
+	}
+	size := 1
+	for ; size < 8; size *= 2 {
+		if uint64(len(content)+len(offsets)*size) < uint64(1)<<(8*size) {
+			break
+		}
+	}
+	var buf [8]byte
+	for _, o := range offsets {
+		binary.LittleEndian.PutUint64(buf[:], uint64(o))
+		content = append(content, buf[:size]...)
+	}
+	return content
+}
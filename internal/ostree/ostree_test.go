@@ -0,0 +1,77 @@
+package ostree
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func tarball(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "repo/objects/", Typeflag: tar.TypeDir, Mode: 0755}))
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}))
+		_, err := tw.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func TestReadCommit(t *testing.T) {
+	object := []byte("a commit variant")
+	sum := sha256.Sum256(object)
+	commit := hex.EncodeToString(sum[:])
+	require.Equal(t, "objects/"+commit[:2]+"/"+commit[2:]+".commit", ObjectPath(commit, "commit"))
+
+	tarred := tarball(t, map[string][]byte{
+		"repo/config":                          []byte("[core]\nmode=archive-z2\n"),
+		"repo/" + ObjectPath(commit, "commit"): object,
+	})
+	read, err := ReadCommit(bytes.NewReader(tarred), commit)
+	require.NoError(t, err)
+	require.Equal(t, object, read)
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, err = zw.Write(tarred)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	read, err = ReadCommit(&gz, commit)
+	require.NoError(t, err)
+	require.Equal(t, object, read)
+
+	other := sha256.Sum256([]byte("another commit"))
+	_, err = ReadCommit(bytes.NewReader(tarred), hex.EncodeToString(other[:]))
+	require.ErrorContains(t, err, "not found in the tarball")
+
+	tampered := tarball(t, map[string][]byte{
+		"./repo/" + ObjectPath(commit, "commit"): []byte("a tampered variant"),
+	})
+	_, err = ReadCommit(bytes.NewReader(tampered), commit)
+	require.ErrorContains(t, err, "doesn't match its checksum")
+
+	_, err = ReadCommit(bytes.NewReader(tarred), "../../etc/passwd")
+	require.ErrorContains(t, err, "invalid commit")
+}
+
+func TestCommitMeta(t *testing.T) {
+	require.Equal(t, append([]byte("ostree.gpgsigs\x00\x00"), "ab\x02\x00aay\x0f\x18"...), CommitMeta([][]byte{[]byte("ab")}))
+
+	// larger signatures need offsets of two bytes
+	sig := bytes.Repeat([]byte{0xc2}, 600)
+	meta := CommitMeta([][]byte{sig})
+	require.Len(t, meta, 16+600+2+4+2+2)
+	require.Equal(t, sig, meta[16:616])
+	require.Equal(t, uint16(600), binary.LittleEndian.Uint16(meta[616:618]))
+	require.Equal(t, []byte("\x00aay"), meta[618:622])
+	require.Equal(t, uint16(15), binary.LittleEndian.Uint16(meta[622:624]))
+	require.Equal(t, uint16(624), binary.LittleEndian.Uint16(meta[624:626]))
+}
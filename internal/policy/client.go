@@ -0,0 +1,105 @@
+// Package policy evaluates requests against the policies of an Open Policy
+// Agent server, which operators run next to the service and load their rego
+// policies or bundles into.
+//
+// A policy decides on a document with `deny`, a set of messages explaining
+// why the input is rejected, optionally `request`, a replacement of the
+// request the service continues with, and `require_approval`, whether a
+// second user has to approve the request, e.g.
+//
+//	package imagebuilder.compose
+//
+//	deny contains msg if {
+//		input.request.image_requests[_].upload_request.options.share_with_accounts
+//		msg := "images can not be shared with other accounts"
+//	}
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type PolicyClientConfig struct {
+	// Address of the OPA server.
+	URL string
+	// Package the decision is looked up in, e.g. "imagebuilder/compose".
+	Path string
+}
+
+type PolicyClient struct {
+	url    string
+	client *http.Client
+}
+
+type Decision struct {
+	Deny []string `json:"deny"`
+	// The request to continue with, nil if the policy doesn't change it.
+	Request json.RawMessage `json:"request,omitempty"`
+	// Whether a second user has to approve the request.
+	RequireApproval bool `json:"require_approval"`
+}
+
+func NewClient(conf PolicyClientConfig) (*PolicyClient, error) {
+	if conf.URL == "" {
+		return nil, fmt.Errorf("Client needs the url of the policy server")
+	}
+	if conf.Path == "" {
+		return nil, fmt.Errorf("Client needs the path of the policy")
+	}
+
+	pc := PolicyClient{
+		url: fmt.Sprintf("%s/v1/data/%s", strings.TrimSuffix(conf.URL, "/"), strings.Trim(conf.Path, "/")),
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+	return &pc, nil
+}
+
+// Evaluate asks the policy server for its decision on input. It fails if the
+// policy isn't loaded, so a missing policy isn't taken for allowing
+// everything.
+func (pc *PolicyClient) Evaluate(ctx context.Context, input interface{}) (*Decision, error) {
+	body, err := json.Marshal(struct {
+		Input interface{} `json:"input"`
+	}{
+		Input: input,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pc.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := pc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("policy server returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Result *Decision `json:"result"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+	if result.Result == nil {
+		return nil, fmt.Errorf("policy %s is undefined", pc.url)
+	}
+	return result.Result, nil
+}
@@ -0,0 +1,63 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		var body struct {
+			Input struct {
+				Org string `json:"org"`
+			} `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/data/imagebuilder/compose":
+			result := map[string]interface{}{"deny": []string{}}
+			if body.Input.Org == "denied" {
+				result["deny"] = []string{"not allowed"}
+			}
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"result": result}))
+		case "/v1/data/missing":
+			// opa omits the result of undefined documents
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{}))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(PolicyClientConfig{Path: "imagebuilder/compose"})
+	require.Error(t, err)
+
+	pc, err := NewClient(PolicyClientConfig{URL: srv.URL + "/", Path: "/imagebuilder/compose"})
+	require.NoError(t, err)
+	decision, err := pc.Evaluate(context.Background(), map[string]string{"org": "allowed"})
+	require.NoError(t, err)
+	require.Empty(t, decision.Deny)
+	require.Nil(t, decision.Request)
+
+	decision, err = pc.Evaluate(context.Background(), map[string]string{"org": "denied"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"not allowed"}, decision.Deny)
+
+	pc, err = NewClient(PolicyClientConfig{URL: srv.URL, Path: "missing"})
+	require.NoError(t, err)
+	_, err = pc.Evaluate(context.Background(), nil)
+	require.ErrorContains(t, err, "is undefined")
+
+	pc, err = NewClient(PolicyClientConfig{URL: srv.URL, Path: "other"})
+	require.NoError(t, err)
+	_, err = pc.Evaluate(context.Background(), nil)
+	require.ErrorContains(t, err, "status 404")
+}
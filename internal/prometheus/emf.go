@@ -0,0 +1,181 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+)
+
+// EMFEmitter writes key metrics as CloudWatch Embedded Metric Format records
+// to the log, for deployments which ship their logs to CloudWatch but can't
+// have the metrics endpoint scraped. CloudWatch extracts the metrics from the
+// records, which the CloudWatch formatter writes as json with the fields of
+// the entries at the top level.
+//
+// Every interval, the requests by status class, the composes by outcome and
+// the number and mean duration of the database queries since the previous
+// interval are emitted.
+type EMFEmitter struct {
+	namespace string
+	gatherer  prometheus.Gatherer
+	log       *logrus.Logger
+
+	// values of the counters at the previous interval
+	last map[string]float64
+}
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+type emfMetadata struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+func NewEMFEmitter(namespace string, log *logrus.Logger) *EMFEmitter {
+	return &EMFEmitter{
+		namespace: namespace,
+		gatherer:  prometheus.DefaultGatherer,
+		log:       log,
+		last:      map[string]float64{},
+	}
+}
+
+// Run emits the metrics every interval until ctx is done.
+func (e *EMFEmitter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := e.Emit(time.Now())
+			if err != nil {
+				e.log.Warnf("Unable to emit EMF metrics: %v", err)
+			}
+		}
+	}
+}
+
+// Emit writes the records of the metrics gathered now.
+func (e *EMFEmitter) Emit(now time.Time) error {
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	byName := map[string]*dto.MetricFamily{}
+	for _, f := range families {
+		byName[f.GetName()] = f
+	}
+	name := func(metric string) string {
+		return fmt.Sprintf("%s_%s_%s", namespace, subsystem, metric)
+	}
+
+	requests := map[string]float64{}
+	if f, ok := byName[name("request_count")]; ok {
+		for _, m := range f.GetMetric() {
+			code := label(m, "code")
+			class := "unknown"
+			if len(code) == 3 {
+				class = code[:1] + "xx"
+			}
+			requests[class] += e.delta(f.GetName(), m, m.GetCounter().GetValue())
+		}
+	}
+	for _, class := range sortedKeys(requests) {
+		e.emit(now, "StatusClass", class, map[string]float64{"Requests": requests[class]}, "Count")
+	}
+
+	outcomes := map[string]float64{}
+	if f, ok := byName[name("compose_outcomes_total")]; ok {
+		for _, m := range f.GetMetric() {
+			outcomes[label(m, "status")] += e.delta(f.GetName(), m, m.GetCounter().GetValue())
+		}
+	}
+	for _, status := range sortedKeys(outcomes) {
+		e.emit(now, "Status", status, map[string]float64{"ComposeOutcomes": outcomes[status]}, "Count")
+	}
+
+	if f, ok := byName[name("db_query_duration_seconds")]; ok {
+		var count, sum float64
+		for _, m := range f.GetMetric() {
+			count += e.delta(f.GetName()+"_count", m, float64(m.GetHistogram().GetSampleCount()))
+			sum += e.delta(f.GetName()+"_sum", m, m.GetHistogram().GetSampleSum())
+		}
+		if count > 0 {
+			e.emit(now, "", "", map[string]float64{"DBQueries": count}, "Count")
+			e.emit(now, "", "", map[string]float64{"DBQueryLatency": sum / count * 1000}, "Milliseconds")
+		}
+	}
+	return nil
+}
+
+// delta returns how much a counter grew since the previous interval.
+func (e *EMFEmitter) delta(family string, m *dto.Metric, value float64) float64 {
+	var labels []string
+	for _, l := range m.GetLabel() {
+		labels = append(labels, l.GetName()+"="+l.GetValue())
+	}
+	key := family + "{" + strings.Join(labels, ",") + "}"
+	d := value - e.last[key]
+	e.last[key] = value
+	// counters reset when the process restarts
+	if d < 0 {
+		return value
+	}
+	return d
+}
+
+func (e *EMFEmitter) emit(now time.Time, dimension, value string, metrics map[string]float64, unit string) {
+	directive := emfDirective{
+		Namespace:  e.namespace,
+		Dimensions: [][]string{},
+	}
+	fields := logrus.Fields{}
+	if dimension != "" {
+		directive.Dimensions = append(directive.Dimensions, []string{dimension})
+		fields[dimension] = value
+	}
+	for _, name := range sortedKeys(metrics) {
+		directive.Metrics = append(directive.Metrics, emfMetric{Name: name, Unit: unit})
+		fields[name] = metrics[name]
+	}
+	fields["_aws"] = emfMetadata{
+		Timestamp:         now.UnixMilli(),
+		CloudWatchMetrics: []emfDirective{directive},
+	}
+	e.log.WithFields(fields).Info("EMF metrics")
+}
+
+func label(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]float64) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
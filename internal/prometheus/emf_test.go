@@ -0,0 +1,78 @@
+package prometheus
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEMFEmitter(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "request_count",
+		Namespace: namespace,
+		Subsystem: subsystem,
+	}, []string{"method", "path", "code"})
+	queries := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:      "db_query_duration_seconds",
+		Namespace: namespace,
+		Subsystem: subsystem,
+	}, []string{"operation"})
+	registry.MustRegister(requests, queries)
+
+	var buf bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&buf)
+	log.SetFormatter(&logrus.JSONFormatter{})
+	e := NewEMFEmitter("image-builder", log)
+	e.gatherer = registry
+
+	records := func() []map[string]interface{} {
+		var records []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var r map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(line), &r))
+			records = append(records, r)
+		}
+		buf.Reset()
+		return records
+	}
+
+	requests.WithLabelValues("GET", "/composes", "200").Add(3)
+	requests.WithLabelValues("POST", "/compose", "201").Inc()
+	requests.WithLabelValues("POST", "/compose", "500").Inc()
+	queries.WithLabelValues("Query").Observe(0.01)
+	queries.WithLabelValues("Exec").Observe(0.03)
+	now := time.Now()
+	require.NoError(t, e.Emit(now))
+
+	r := records()
+	require.Len(t, r, 4)
+	require.Equal(t, "2xx", r[0]["StatusClass"])
+	require.Equal(t, float64(4), r[0]["Requests"])
+	require.Equal(t, "5xx", r[1]["StatusClass"])
+	require.Equal(t, float64(1), r[1]["Requests"])
+	require.Equal(t, float64(2), r[2]["DBQueries"])
+	require.InDelta(t, 20, r[3]["DBQueryLatency"], 0.001)
+
+	aws := r[0]["_aws"].(map[string]interface{})
+	require.Equal(t, float64(now.UnixMilli()), aws["Timestamp"])
+	directive := aws["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, "image-builder", directive["Namespace"])
+	require.Equal(t, []interface{}{[]interface{}{"StatusClass"}}, directive["Dimensions"])
+	require.Equal(t, []interface{}{map[string]interface{}{"Name": "Requests", "Unit": "Count"}}, directive["Metrics"])
+
+	// only what happened since the previous interval is emitted
+	requests.WithLabelValues("GET", "/composes", "200").Inc()
+	require.NoError(t, e.Emit(now))
+	r = records()
+	require.Len(t, r, 2)
+	require.Equal(t, float64(1), r[0]["Requests"])
+	require.Equal(t, float64(0), r[1]["Requests"])
+}
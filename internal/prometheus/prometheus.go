@@ -55,6 +55,150 @@ var (
 	}, []string{"method", "path", "code"})
 )
 
+// Requests rejected by authentication or authorization, labelled by the
+// reason only, the audit log holds the details of each rejection.
+var (
+	AuthRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "auth_rejections_total",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "total number of requests rejected by authentication or authorization",
+	}, []string{"reason"})
+)
+
+// Composes by what they build, and the outcome of those which finished. The
+// outcome is recorded once the final status is seen, when users or the compose
+// queue poll the status of a compose. The duration includes the time composes
+// were queued.
+var (
+	Composes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "composes_total",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "total number of composes",
+	}, []string{"distribution", "image_type", "upload_target"})
+
+	ComposeOutcomes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "compose_outcomes_total",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "total number of finished composes",
+	}, []string{"distribution", "image_type", "upload_target", "status"})
+
+	ComposeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:      "compose_duration_seconds",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "Duration of composes from the request to the final status.",
+		Buckets:   []float64{60, 120, 300, 600, 900, 1200, 1800, 2700, 3600, 5400, 7200, 10800, 21600},
+	}, []string{"distribution", "image_type", "upload_target", "status"})
+)
+
+// Duration of database queries as pgx reports them, by the kind of query:
+// Exec, Query or SendBatch.
+var (
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:      "db_query_duration_seconds",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "Duration of database queries.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"operation"})
+)
+
+// Retries of requests to other services, by service and what went wrong:
+// error for connection errors, or the status class, e.g. 5xx. Calls which
+// gave up after the last attempt are counted as exhausted.
+var (
+	OutboundRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "outbound_retries_total",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "Retried requests to other services.",
+	}, []string{"client", "reason"})
+
+	OutboundRetriesExhausted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "outbound_retries_exhausted_total",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "Requests to other services which failed after the last retry.",
+	}, []string{"client"})
+
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "webhook_deliveries_total",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "Attempts to deliver webhook events, by the status of the delivery afterwards.",
+	}, []string{"status"})
+
+	EventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "events_published_total",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "Events sent to kafka, by whether they were published, failed or dropped because the queue was full.",
+	}, []string{"status"})
+
+	OutboxDispatches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "outbox_dispatches_total",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "Attempts to dispatch outbox entries, by sink and the status of the entry afterwards.",
+	}, []string{"sink", "status"})
+
+	DeprecatedUses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "deprecated_uses_total",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "Requests using deprecated parts of the api, by what they used.",
+	}, []string{"deprecation"})
+
+	SchemaViolations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "schema_violations_total",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "Requests and responses which don't match the api specification, by direction and operation.",
+	}, []string{"direction", "operation"})
+)
+
+// The queue and the workers of composer backends, as their metrics report
+// them. Jobs which don't depend on the architecture have an empty arch.
+var (
+	ComposerPendingJobs = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "composer_pending_jobs",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "Jobs waiting for a worker, by composer backend, job type and architecture.",
+	}, []string{"backend", "type", "arch"})
+
+	ComposerRunningJobs = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "composer_running_jobs",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "Jobs worked on, by composer backend, job type and architecture.",
+	}, []string{"backend", "type", "arch"})
+
+	ComposerWorkers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "composer_workers",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "Workers registered with composer, by composer backend and architecture.",
+	}, []string{"backend", "arch"})
+
+	ComposerWorkerHeartbeatAge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "composer_worker_heartbeat_age_seconds",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "Age of the oldest heartbeat of the workers of an architecture, by composer backend.",
+	}, []string{"backend", "arch"})
+
+	ComposerQueueErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name:      "composer_queue_errors_total",
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Help:      "Failures to read the queue of a composer backend.",
+	}, []string{"backend"})
+)
+
 func pathLabel(path string) string {
 	r := regexp.MustCompile(":(.*)")
 	segments := strings.Split(path, "/")
@@ -3,52 +3,119 @@
 package provisioning
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 
 	"github.com/redhatinsights/identity"
+
+	"github.com/osbuild/image-builder/internal/common"
 )
 
 type ProvisioningClient struct {
 	url    string
 	client *http.Client
+	retry  common.RetryPolicy
 }
 
 type ProvisioningClientConfig struct {
 	URL string
+
+	// Retries of idempotent requests, defaults to common.DefaultRetryPolicy.
+	Retry common.RetryPolicy
+
+	Timeouts common.HTTPTimeouts
 }
 
 func NewClient(conf ProvisioningClientConfig) (*ProvisioningClient, error) {
 	pc := ProvisioningClient{
 		url:    conf.URL,
-		client: &http.Client{},
+		client: common.NewHTTPClient(conf.Timeouts, nil),
+		retry:  conf.Retry,
+	}
+	if pc.retry.Attempts <= 0 {
+		pc.retry = common.DefaultRetryPolicy
 	}
 
 	return &pc, nil
 }
 
-func (pc *ProvisioningClient) request(method, url string, headers map[string]string, body io.Reader) (*http.Response, error) {
-	req, err := http.NewRequest(method, url, body)
+// request sends a request to provisioning, requests without a body which can
+// be repeated are retried.
+func (pc *ProvisioningClient) request(ctx context.Context, method, url string, headers map[string]string, body io.Reader) (*http.Response, error) {
+	policy := pc.retry
+	if body != nil || !common.Idempotent(method) {
+		policy.Attempts = 1
+	}
+	return policy.Retry(ctx, "provisioning", func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, url, body)
+		if err != nil {
+			return nil, common.Permanent(err)
+		}
+
+		for k, v := range headers {
+			req.Header.Add(k, v)
+		}
+		common.SetRequestIdHeaders(ctx, req.Header)
+
+		return pc.client.Do(req)
+	})
+}
+
+func (pc *ProvisioningClient) GetUploadInfo(ctx context.Context, sourceID string) (*http.Response, error) {
+	id, ok := identity.GetIdentityHeader(ctx)
+	if !ok {
+		return nil, fmt.Errorf("Unable to get identity from context")
+	}
+
+	return pc.request(ctx, "GET", fmt.Sprintf("%s/sources/%s/upload_info", pc.url, sourceID), map[string]string{
+		"x-rh-identity": id,
+	}, nil)
+}
+
+// CreateReservation asks provisioning to launch instances of an image on aws,
+// azure or gcp, reservation being the request body of that provider.
+func (pc *ProvisioningClient) CreateReservation(ctx context.Context, provider string, reservation interface{}) (*http.Response, error) {
+	id, ok := identity.GetIdentityHeader(ctx)
+	if !ok {
+		return nil, fmt.Errorf("Unable to get identity from context")
+	}
+
+	body, err := json.Marshal(reservation)
 	if err != nil {
 		return nil, err
 	}
+	return pc.request(ctx, "POST", fmt.Sprintf("%s/reservations/%s", pc.url, provider), map[string]string{
+		"x-rh-identity": id,
+		"content-type":  "application/json",
+	}, bytes.NewReader(body))
+}
 
-	for k, v := range headers {
-		req.Header.Add(k, v)
+// GetReservation returns the status of a reservation, which is the same for
+// all providers.
+func (pc *ProvisioningClient) GetReservation(ctx context.Context, reservationID int64) (*http.Response, error) {
+	id, ok := identity.GetIdentityHeader(ctx)
+	if !ok {
+		return nil, fmt.Errorf("Unable to get identity from context")
 	}
 
-	return pc.client.Do(req)
+	return pc.request(ctx, "GET", fmt.Sprintf("%s/reservations/%d", pc.url, reservationID), map[string]string{
+		"x-rh-identity": id,
+	}, nil)
 }
 
-func (pc *ProvisioningClient) GetUploadInfo(ctx context.Context, sourceID string) (*http.Response, error) {
+// GetProviderReservation returns the details of a reservation specific to its
+// provider, including the launched instances.
+func (pc *ProvisioningClient) GetProviderReservation(ctx context.Context, provider string, reservationID int64) (*http.Response, error) {
 	id, ok := identity.GetIdentityHeader(ctx)
 	if !ok {
 		return nil, fmt.Errorf("Unable to get identity from context")
 	}
 
-	return pc.request("GET", fmt.Sprintf("%s/sources/%s/upload_info", pc.url, sourceID), map[string]string{
+	return pc.request(ctx, "GET", fmt.Sprintf("%s/reservations/%s/%d", pc.url, provider, reservationID), map[string]string{
 		"x-rh-identity": id,
 	}, nil)
 }
@@ -0,0 +1,124 @@
+// Package ratelimit implements token bucket rate limiting. Every key gets a
+// bucket holding up to Burst tokens, which refills at Rate tokens per second.
+// Each request takes a token, it is rejected if the bucket is empty.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// Tokens added to a bucket per second.
+	Rate float64
+	// Capacity of a bucket.
+	Burst int
+}
+
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Tokens left after the request.
+	Remaining int
+	// Time until the bucket is full again.
+	Reset time.Duration
+	// Time until a token is available again, zero if the request was
+	// allowed.
+	RetryAfter time.Duration
+}
+
+type Limiter interface {
+	Take(ctx context.Context, key string) (Result, error)
+}
+
+// result derives the result of a request from the tokens left in the bucket
+// after taking one, or the tokens in it if there weren't enough.
+func (c Config) result(allowed bool, tokens float64) Result {
+	r := Result{
+		Allowed:   allowed,
+		Limit:     c.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(c.Burst) - tokens) / c.Rate),
+	}
+	if !allowed {
+		r.RetryAfter = seconds((1 - tokens) / c.Rate)
+	}
+	return r
+}
+
+// refill returns the tokens a bucket holds after elapsed.
+func (c Config) refill(tokens float64, elapsed time.Duration) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(c.Burst), tokens+elapsed.Seconds()*c.Rate)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// memoryLimiter keeps the buckets in memory, every replica of the service
+// limits requests on its own.
+type memoryLimiter struct {
+	conf Config
+	now  func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+func NewMemoryLimiter(conf Config) Limiter {
+	return &memoryLimiter{
+		conf:    conf,
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+func (l *memoryLimiter) Take(_ context.Context, key string) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{
+			tokens: float64(l.conf.Burst),
+			last:   now,
+		}
+		l.buckets[key] = b
+	}
+	b.tokens = l.conf.refill(b.tokens, now.Sub(b.last))
+	b.last = now
+
+	if b.tokens < 1 {
+		return l.conf.result(false, b.tokens), nil
+	}
+	b.tokens--
+	return l.conf.result(true, b.tokens), nil
+}
+
+// prune drops buckets which are full again, they are indistinguishable from
+// new ones.
+func (l *memoryLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+	for key, b := range l.buckets {
+		if l.conf.refill(b.tokens, now.Sub(b.last)) >= float64(l.conf.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewMemoryLimiter(Config{Rate: 1, Burst: 3}).(*memoryLimiter)
+	l.now = func() time.Time { return now }
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		r, err := l.Take(context.Background(), "org1")
+		require.NoError(t, err)
+		require.True(t, r.Allowed)
+		require.Equal(t, 3, r.Limit)
+		require.Equal(t, remaining, r.Remaining)
+		require.Zero(t, r.RetryAfter)
+	}
+
+	r, err := l.Take(context.Background(), "org1")
+	require.NoError(t, err)
+	require.False(t, r.Allowed)
+	require.Equal(t, 0, r.Remaining)
+	require.Equal(t, time.Second, r.RetryAfter)
+	require.Equal(t, 3*time.Second, r.Reset)
+
+	// buckets are per key
+	r, err = l.Take(context.Background(), "org2")
+	require.NoError(t, err)
+	require.True(t, r.Allowed)
+
+	// refills at the configured rate
+	now = now.Add(1500 * time.Millisecond)
+	r, err = l.Take(context.Background(), "org1")
+	require.NoError(t, err)
+	require.True(t, r.Allowed)
+	require.Equal(t, 0, r.Remaining)
+	r, err = l.Take(context.Background(), "org1")
+	require.NoError(t, err)
+	require.False(t, r.Allowed)
+	require.Equal(t, 500*time.Millisecond, r.RetryAfter)
+
+	// full buckets get pruned
+	now = now.Add(time.Hour)
+	_, err = l.Take(context.Background(), "org3")
+	require.NoError(t, err)
+	require.Len(t, l.buckets, 1)
+}
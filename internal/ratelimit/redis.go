@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "image-builder:ratelimit:"
+
+// Refills and takes a token from the bucket in KEYS[1], atomically, using
+// the clock of the redis server so replicas with skewed clocks agree. Returns
+// whether a token was taken and the tokens left.
+var takeScript = redis.NewScript(`
+redis.replicate_commands()
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('EXPIRE', KEYS[1], math.ceil(burst / rate) + 1)
+return {allowed, tostring(tokens)}
+`)
+
+type RedisConfig struct {
+	// host:port of the redis server
+	Address  string
+	Password string
+	// Optional, asked for the password on each new connection instead, so a
+	// rotated one is picked up.
+	PasswordFunc func() string
+}
+
+// redisLimiter keeps the buckets in redis, so all replicas of the service
+// share them.
+type redisLimiter struct {
+	conf   Config
+	client *redis.Client
+}
+
+func NewRedisLimiter(conf Config, rc RedisConfig) Limiter {
+	passwd := rc.PasswordFunc
+	if passwd == nil {
+		passwd = func() string { return rc.Password }
+	}
+	timeout := 2 * time.Second
+	return &redisLimiter{
+		conf: conf,
+		client: redis.NewClient(&redis.Options{
+			Addr: rc.Address,
+			CredentialsProvider: func() (string, string) {
+				return "", passwd()
+			},
+			DialTimeout:  timeout,
+			ReadTimeout:  timeout,
+			WriteTimeout: timeout,
+		}),
+	}
+}
+
+func (l *redisLimiter) Take(ctx context.Context, key string) (Result, error) {
+	values, err := takeScript.Run(ctx, l.client, []string{redisKeyPrefix + key},
+		strconv.FormatFloat(l.conf.Rate, 'f', -1, 64), l.conf.Burst).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(values) != 2 {
+		return Result{}, fmt.Errorf("unexpected reply from redis: %v", values)
+	}
+	allowed, ok := values[0].(int64)
+	if !ok {
+		return Result{}, fmt.Errorf("unexpected reply from redis: %v", values)
+	}
+	tokensStr, ok := values[1].(string)
+	if !ok {
+		return Result{}, fmt.Errorf("unexpected reply from redis: %v", values)
+	}
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	return l.conf.result(allowed == 1, tokens), nil
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisLimiter(t *testing.T) {
+	m := miniredis.RunT(t)
+	m.RequireAuth("secret")
+	now := time.Unix(1700000000, 0)
+	m.SetTime(now)
+	l := NewRedisLimiter(Config{Rate: 0.5, Burst: 2}, RedisConfig{Address: m.Addr(), Password: "secret"})
+
+	r, err := l.Take(context.Background(), "org1")
+	require.NoError(t, err)
+	require.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 2 * time.Second}, r)
+	r, err = l.Take(context.Background(), "org1")
+	require.NoError(t, err)
+	require.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 4 * time.Second}, r)
+	r, err = l.Take(context.Background(), "org1")
+	require.NoError(t, err)
+	require.False(t, r.Allowed)
+	require.Equal(t, 2*time.Second, r.RetryAfter)
+	// the buckets expire once they're full again
+	require.Equal(t, 5*time.Second, m.TTL("image-builder:ratelimit:org1"))
+
+	// other keys have their own bucket
+	r, err = l.Take(context.Background(), "org2")
+	require.NoError(t, err)
+	require.True(t, r.Allowed)
+
+	// the clock of redis refills the bucket
+	m.SetTime(now.Add(2 * time.Second))
+	r, err = l.Take(context.Background(), "org1")
+	require.NoError(t, err)
+	require.True(t, r.Allowed)
+	require.Equal(t, 0, r.Remaining)
+}
+
+func TestRedisLimiterPassword(t *testing.T) {
+	m := miniredis.RunT(t)
+	m.RequireAuth("secret")
+
+	l := NewRedisLimiter(Config{Rate: 1, Burst: 1}, RedisConfig{Address: m.Addr(), Password: "wrong"})
+	_, err := l.Take(context.Background(), "org1")
+	require.Error(t, err)
+
+	password := "secret"
+	l = NewRedisLimiter(Config{Rate: 1, Burst: 1}, RedisConfig{Address: m.Addr(), Password: "wrong", PasswordFunc: func() string { return password }})
+	_, err = l.Take(context.Background(), "org1")
+	require.NoError(t, err)
+
+	// requests fail while redis is down
+	m.Close()
+	_, err = l.Take(context.Background(), "org1")
+	require.Error(t, err)
+}
@@ -0,0 +1,106 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/redhatinsights/identity"
+)
+
+// Permissions are cached per identity, so not every request results in a
+// call to the rbac service.
+const permissionsCacheTTL = time.Minute
+
+type RBACClient struct {
+	url    string
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cachedPermissions
+}
+
+type RBACClientConfig struct {
+	URL string
+}
+
+type cachedPermissions struct {
+	permissions []string
+	fetchedAt   time.Time
+}
+
+func NewClient(conf RBACClientConfig) (*RBACClient, error) {
+	rc := RBACClient{
+		url: conf.URL,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		cache: map[string]cachedPermissions{},
+	}
+
+	return &rc, nil
+}
+
+// Permissions returns the image-builder permissions, e.g.
+// "image-builder:composes:read", of the identity in the context.
+func (rc *RBACClient) Permissions(ctx context.Context) ([]string, error) {
+	id, ok := identity.GetIdentityHeader(ctx)
+	if !ok {
+		return nil, fmt.Errorf("Unable to get identity from context")
+	}
+
+	rc.mu.Lock()
+	cached, ok := rc.cache[id]
+	rc.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < permissionsCacheTTL {
+		return cached.permissions, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/access/?application=image-builder&limit=1000", rc.url), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("x-rh-identity", id)
+
+	resp, err := rc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rbac service returned status %d", resp.StatusCode)
+	}
+
+	var access struct {
+		Data []struct {
+			Permission string `json:"permission"`
+		} `json:"data"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&access)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := []string{}
+	for _, a := range access.Data {
+		permissions = append(permissions, a.Permission)
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	// drop expired entries so the cache doesn't grow unbounded
+	for k, v := range rc.cache {
+		if time.Since(v.fetchedAt) >= permissionsCacheTTL {
+			delete(rc.cache, k)
+		}
+	}
+	rc.cache[id] = cachedPermissions{
+		permissions: permissions,
+		fetchedAt:   time.Now(),
+	}
+	return permissions, nil
+}
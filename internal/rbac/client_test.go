@@ -0,0 +1,53 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/redhatinsights/identity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissions(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		require.Equal(t, "/access/", r.URL.Path)
+		require.Equal(t, "image-builder", r.URL.Query().Get("application"))
+		if r.Header.Get("x-rh-identity") != "dXNlcjE=" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"permission": "image-builder:composes:read", "resourceDefinitions": []interface{}{}},
+				{"permission": "image-builder:composes:write", "resourceDefinitions": []interface{}{}},
+			},
+		})
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	rc, err := NewClient(RBACClientConfig{URL: srv.URL})
+	require.NoError(t, err)
+
+	_, err = rc.Permissions(context.Background())
+	require.Error(t, err)
+
+	// the permissions are only fetched once
+	ctx := context.WithValue(context.Background(), identity.IDHeaderKey, "dXNlcjE=")
+	for i := 0; i < 2; i++ {
+		permissions, err := rc.Permissions(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"image-builder:composes:read", "image-builder:composes:write"}, permissions)
+	}
+	require.Equal(t, 1, requests)
+
+	ctx = context.WithValue(context.Background(), identity.IDHeaderKey, "dXNlcjI=")
+	_, err = rc.Permissions(ctx)
+	require.Error(t, err)
+}
@@ -0,0 +1,121 @@
+// Package s3 puts objects into S3 buckets, which can belong to other
+// accounts. Only what's needed to write exports and to check the buckets
+// they're written to is implemented.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/osbuild/image-builder/internal/common"
+)
+
+var defaultTimeouts = common.HTTPTimeouts{
+	Connect: 30 * time.Second,
+	Read:    time.Minute,
+	Request: 5 * time.Minute,
+}
+
+type Config struct {
+	// Optional, e.g. of an S3 compatible store, objects are put into
+	// <endpoint>/<bucket>/<key> then. Defaults to the regional endpoint of
+	// the bucket.
+	Endpoint string
+	// The default credential chain if nil, e.g. the role of the pod.
+	Credentials *credentials.Credentials
+	// Defaults to a request timeout of 5 minutes.
+	Timeouts common.HTTPTimeouts
+}
+
+type Client struct {
+	sess *session.Session
+}
+
+func NewClient(conf Config) (*Client, error) {
+	if conf.Timeouts == (common.HTTPTimeouts{}) {
+		conf.Timeouts = defaultTimeouts
+	}
+	awsConf := aws.NewConfig().WithHTTPClient(common.NewHTTPClient(conf.Timeouts, nil))
+	if conf.Credentials != nil {
+		awsConf = awsConf.WithCredentials(conf.Credentials)
+	}
+	if conf.Endpoint != "" {
+		awsConf = awsConf.WithEndpoint(conf.Endpoint).WithS3ForcePathStyle(true)
+	}
+	sess, err := session.NewSession(awsConf)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		sess: sess,
+	}, nil
+}
+
+// client is the S3 client of the region of a bucket, buckets of other
+// regions can only be written to through their regional endpoint.
+func (c *Client) client(region string) *s3.S3 {
+	return s3.New(c.sess, aws.NewConfig().WithRegion(region))
+}
+
+// the verification objects the exports check are small
+const maxObjectSize = 4096
+
+// PutObject writes body to the key of a bucket. The owner of the bucket gets
+// full control of the object, so it can be read by the account of the bucket
+// when it's written by another one.
+func (c *Client) PutObject(ctx context.Context, region, bucket, key string, body []byte, contentType string) error {
+	digest := md5.Sum(body) // #nosec G401 -- S3 checks the integrity of uploads with it
+	_, err := c.client(region).PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String(contentType),
+		ContentMD5:  aws.String(base64.StdEncoding.EncodeToString(digest[:])),
+		ACL:         aws.String(s3.ObjectCannedACLBucketOwnerFullControl),
+	})
+	if err != nil {
+		return responseError(err, fmt.Sprintf("putting %s into bucket %s", key, bucket))
+	}
+	return nil
+}
+
+// GetObject reads a small object, of at most 4 KiB, from the key of a bucket.
+func (c *Client) GetObject(ctx context.Context, region, bucket, key string) ([]byte, error) {
+	out, err := c.client(region).GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, responseError(err, fmt.Sprintf("getting %s from bucket %s", key, bucket))
+	}
+	defer out.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(out.Body, maxObjectSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxObjectSize {
+		return nil, fmt.Errorf("%s in bucket %s is larger than %d bytes", key, bucket, maxObjectSize)
+	}
+	return body, nil
+}
+
+// responseError is the error S3 responded with to what was done.
+func responseError(err error, what string) error {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return fmt.Errorf("%s failed with %s: %s", what, awsErr.Code(), awsErr.Message())
+	}
+	return fmt.Errorf("%s failed: %w", what, err)
+}
@@ -0,0 +1,76 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPutObject(t *testing.T) {
+	objects := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/"))
+		require.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/s3/")
+		require.NotEmpty(t, r.Header.Get("X-Amz-Content-Sha256"))
+		require.Equal(t, "bucket-owner-full-control", r.Header.Get("X-Amz-Acl"))
+		if !strings.HasPrefix(r.URL.Path, "/evidence/") {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		objects[r.URL.Path] = r.Header.Get("Content-Type") + " " + string(body)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(Config{
+		Endpoint:    srv.URL + "/",
+		Credentials: credentials.NewStaticCredentials("key", "secret", ""),
+	})
+	require.NoError(t, err)
+	err = client.PutObject(context.Background(), "eu-west-1", "evidence", "exports/20260101T000000Z/manifest.json", []byte(`{}`), "application/json")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"/evidence/exports/20260101T000000Z/manifest.json": "application/json {}"}, objects)
+
+	err = client.PutObject(context.Background(), "eu-west-1", "other", "manifest.json", []byte(`{}`), "application/json")
+	require.ErrorContains(t, err, "putting manifest.json into bucket other failed with AccessDenied: Access Denied")
+}
+
+func TestGetObject(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/"))
+		switch r.URL.Path {
+		case "/evidence/image-builder-verification":
+			_, _ = w.Write([]byte("token\n"))
+		case "/evidence/large":
+			_, _ = w.Write([]byte(strings.Repeat("a", 5000)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(Config{
+		Endpoint:    srv.URL,
+		Credentials: credentials.NewStaticCredentials("key", "secret", ""),
+	})
+	require.NoError(t, err)
+	body, err := client.GetObject(context.Background(), "eu-west-1", "evidence", "image-builder-verification")
+	require.NoError(t, err)
+	require.Equal(t, "token\n", string(body))
+
+	_, err = client.GetObject(context.Background(), "eu-west-1", "evidence", "large")
+	require.ErrorContains(t, err, "larger than 4096 bytes")
+	_, err = client.GetObject(context.Background(), "eu-west-1", "evidence", "missing")
+	require.ErrorContains(t, err, "getting missing from bucket evidence failed with NoSuchKey")
+}
@@ -0,0 +1,254 @@
+// Package sbom writes the packages an image was built from as a software
+// bill of materials, in SPDX 2.3 or CycloneDX 1.5 JSON.
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	FormatSPDX      = "spdx"
+	FormatCycloneDX = "cyclonedx"
+)
+
+// MediaType is the content type of documents of format.
+func MediaType(format string) string {
+	switch format {
+	case FormatSPDX:
+		return "application/spdx+json"
+	case FormatCycloneDX:
+		return "application/vnd.cyclonedx+json"
+	}
+	return "application/json"
+}
+
+// Package is an rpm, or another kind of package, of an image.
+type Package struct {
+	Type      string
+	Name      string
+	Epoch     string
+	Version   string
+	Release   string
+	Arch      string
+	Sigmd5    string
+	Signature string
+}
+
+// EVR is the epoch, version and release of the package as rpm writes them,
+// the epoch being left out if there is none.
+func (p Package) EVR() string {
+	if p.Epoch == "" || p.Epoch == "0" {
+		return fmt.Sprintf("%s-%s", p.Version, p.Release)
+	}
+	return fmt.Sprintf("%s:%s-%s", p.Epoch, p.Version, p.Release)
+}
+
+// PURL is the package url of the package, see
+// https://github.com/package-url/purl-spec.
+func (p Package) PURL() string {
+	qualifiers := url.Values{"arch": {p.Arch}}
+	if p.Epoch != "" && p.Epoch != "0" {
+		qualifiers.Set("epoch", p.Epoch)
+	}
+	return fmt.Sprintf("pkg:%s/%s@%s-%s?%s", p.Type, url.PathEscape(p.Name), url.PathEscape(p.Version), url.PathEscape(p.Release), qualifiers.Encode())
+}
+
+// Document describes an image and its packages.
+type Document struct {
+	// Name of the image.
+	Name string
+	// A unique URI of the document, SPDX documents need one.
+	Namespace string
+	Created   time.Time
+	Packages  []Package
+}
+
+// marshal leaves the & of purls with qualifiers as they are, rather than
+// escaping them like json.Marshal does.
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID                string            `json:"SPDXID"`
+	Name                  string            `json:"name"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementId      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// SPDX writes doc as an SPDX 2.3 document, see https://spdx.github.io/spdx-spec/v2.3/.
+func SPDX(doc Document) ([]byte, error) {
+	d := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              doc.Name,
+		DocumentNamespace: doc.Namespace,
+		CreationInfo: spdxCreationInfo{
+			Created:  doc.Created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: image-builder"},
+		},
+		Packages: []spdxPackage{
+			{
+				SPDXID:                "SPDXRef-Image",
+				Name:                  doc.Name,
+				DownloadLocation:      "NOASSERTION",
+				PrimaryPackagePurpose: "OPERATING-SYSTEM",
+			},
+		},
+		Relationships: []spdxRelationship{
+			{
+				SPDXElementId:      "SPDXRef-DOCUMENT",
+				RelationshipType:   "DESCRIBES",
+				RelatedSPDXElement: "SPDXRef-Image",
+			},
+		},
+	}
+	for i, p := range doc.Packages {
+		id := fmt.Sprintf("SPDXRef-Package-%d", i)
+		d.Packages = append(d.Packages, spdxPackage{
+			SPDXID:           id,
+			Name:             p.Name,
+			VersionInfo:      p.EVR(),
+			DownloadLocation: "NOASSERTION",
+			ExternalRefs: []spdxExternalRef{
+				{
+					ReferenceCategory: "PACKAGE-MANAGER",
+					ReferenceType:     "purl",
+					ReferenceLocator:  p.PURL(),
+				},
+			},
+		})
+		d.Relationships = append(d.Relationships, spdxRelationship{
+			SPDXElementId:      "SPDXRef-Image",
+			RelationshipType:   "CONTAINS",
+			RelatedSPDXElement: id,
+		})
+	}
+	return marshal(d)
+}
+
+type cycloneDXDocument struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string `json:"timestamp"`
+	Tools     struct {
+		Components []cycloneDXComponent `json:"components"`
+	} `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXComponent struct {
+	Type       string              `json:"type"`
+	BOMRef     string              `json:"bom-ref,omitempty"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	PURL       string              `json:"purl,omitempty"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// CycloneDX writes doc as a CycloneDX 1.5 document, see
+// https://cyclonedx.org/docs/1.5/json/.
+func CycloneDX(doc Document) ([]byte, error) {
+	d := cycloneDXDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + uuid.NewString(),
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: doc.Created.UTC().Format(time.RFC3339),
+			Component: cycloneDXComponent{
+				Type:   "operating-system",
+				BOMRef: "image",
+				Name:   doc.Name,
+			},
+		},
+		Components: []cycloneDXComponent{},
+	}
+	d.Metadata.Tools.Components = []cycloneDXComponent{
+		{Type: "application", Name: "image-builder"},
+	}
+
+	image := cycloneDXDependency{Ref: "image", DependsOn: []string{}}
+	for _, p := range doc.Packages {
+		purl := p.PURL()
+		c := cycloneDXComponent{
+			Type:    "library",
+			BOMRef:  purl,
+			Name:    p.Name,
+			Version: p.EVR(),
+			PURL:    purl,
+		}
+		if p.Sigmd5 != "" {
+			c.Properties = append(c.Properties, cycloneDXProperty{Name: "rpm:sigmd5", Value: p.Sigmd5})
+		}
+		if p.Signature != "" {
+			c.Properties = append(c.Properties, cycloneDXProperty{Name: "rpm:signature", Value: p.Signature})
+		}
+		d.Components = append(d.Components, c)
+		image.DependsOn = append(image.DependsOn, purl)
+	}
+	d.Dependencies = []cycloneDXDependency{image}
+	return marshal(d)
+}
@@ -0,0 +1,84 @@
+package sbom
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testDocument = Document{
+	Name:      "my-image",
+	Namespace: "https://console.redhat.com/api/image-builder/composes/1a5d8a50-6a1e-4a56-8c2d-1e6f0c8b3c4d/sbom",
+	Created:   time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	Packages: []Package{
+		{Type: "rpm", Name: "bash", Epoch: "0", Version: "5.1.8", Release: "6.el9", Arch: "x86_64", Sigmd5: "2ee0ba6e"},
+		{Type: "rpm", Name: "gcc-c++", Epoch: "1", Version: "11.4.1", Release: "2.el9", Arch: "x86_64", Signature: "RSA/SHA256, Key ID 199e2f91fd431d51"},
+	},
+}
+
+func TestPackage(t *testing.T) {
+	require.Equal(t, "5.1.8-6.el9", testDocument.Packages[0].EVR())
+	require.Equal(t, "pkg:rpm/bash@5.1.8-6.el9?arch=x86_64", testDocument.Packages[0].PURL())
+	require.Equal(t, "1:11.4.1-2.el9", testDocument.Packages[1].EVR())
+	require.Equal(t, "pkg:rpm/gcc-c++@11.4.1-2.el9?arch=x86_64&epoch=1", testDocument.Packages[1].PURL())
+}
+
+func TestSPDX(t *testing.T) {
+	raw, err := SPDX(testDocument)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"spdxVersion": "SPDX-2.3",
+		"dataLicense": "CC0-1.0",
+		"SPDXID": "SPDXRef-DOCUMENT",
+		"name": "my-image",
+		"documentNamespace": "https://console.redhat.com/api/image-builder/composes/1a5d8a50-6a1e-4a56-8c2d-1e6f0c8b3c4d/sbom",
+		"creationInfo": {"created": "2024-03-01T12:00:00Z", "creators": ["Tool: image-builder"]},
+		"packages": [
+			{"SPDXID": "SPDXRef-Image", "name": "my-image", "downloadLocation": "NOASSERTION", "filesAnalyzed": false, "primaryPackagePurpose": "OPERATING-SYSTEM"},
+			{"SPDXID": "SPDXRef-Package-0", "name": "bash", "versionInfo": "5.1.8-6.el9", "downloadLocation": "NOASSERTION", "filesAnalyzed": false,
+				"externalRefs": [{"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:rpm/bash@5.1.8-6.el9?arch=x86_64"}]},
+			{"SPDXID": "SPDXRef-Package-1", "name": "gcc-c++", "versionInfo": "1:11.4.1-2.el9", "downloadLocation": "NOASSERTION", "filesAnalyzed": false,
+				"externalRefs": [{"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:rpm/gcc-c++@11.4.1-2.el9?arch=x86_64&epoch=1"}]}
+		],
+		"relationships": [
+			{"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-Image"},
+			{"spdxElementId": "SPDXRef-Image", "relationshipType": "CONTAINS", "relatedSpdxElement": "SPDXRef-Package-0"},
+			{"spdxElementId": "SPDXRef-Image", "relationshipType": "CONTAINS", "relatedSpdxElement": "SPDXRef-Package-1"}
+		]
+	}`, string(raw))
+}
+
+func TestCycloneDX(t *testing.T) {
+	raw, err := CycloneDX(testDocument)
+	require.NoError(t, err)
+
+	var d map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &d))
+	require.True(t, strings.HasPrefix(d["serialNumber"].(string), "urn:uuid:"))
+	delete(d, "serialNumber")
+	withoutSerial, err := json.Marshal(d)
+	require.NoError(t, err)
+
+	require.JSONEq(t, `{
+		"bomFormat": "CycloneDX",
+		"specVersion": "1.5",
+		"version": 1,
+		"metadata": {
+			"timestamp": "2024-03-01T12:00:00Z",
+			"tools": {"components": [{"type": "application", "name": "image-builder"}]},
+			"component": {"type": "operating-system", "bom-ref": "image", "name": "my-image"}
+		},
+		"components": [
+			{"type": "library", "bom-ref": "pkg:rpm/bash@5.1.8-6.el9?arch=x86_64", "name": "bash", "version": "5.1.8-6.el9",
+				"purl": "pkg:rpm/bash@5.1.8-6.el9?arch=x86_64", "properties": [{"name": "rpm:sigmd5", "value": "2ee0ba6e"}]},
+			{"type": "library", "bom-ref": "pkg:rpm/gcc-c++@11.4.1-2.el9?arch=x86_64&epoch=1", "name": "gcc-c++", "version": "1:11.4.1-2.el9",
+				"purl": "pkg:rpm/gcc-c++@11.4.1-2.el9?arch=x86_64&epoch=1", "properties": [{"name": "rpm:signature", "value": "RSA/SHA256, Key ID 199e2f91fd431d51"}]}
+		],
+		"dependencies": [
+			{"ref": "image", "dependsOn": ["pkg:rpm/bash@5.1.8-6.el9?arch=x86_64", "pkg:rpm/gcc-c++@11.4.1-2.el9?arch=x86_64&epoch=1"]}
+		]
+	}`, string(withoutSerial))
+}
@@ -0,0 +1,83 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+)
+
+type AWSConfig struct {
+	Region string
+	// The regional endpoint if empty.
+	Endpoint string
+	// The default credential chain if nil, e.g. the role of the pod.
+	Credentials *credentials.Credentials
+}
+
+// AWSSecretsManager reads secrets by their name or arn. Secrets which are
+// json objects can be referred to by key, e.g. image-builder/db#password,
+// others only as a whole.
+type AWSSecretsManager struct {
+	client *secretsmanager.SecretsManager
+}
+
+func NewAWSSecretsManager(conf AWSConfig) (*AWSSecretsManager, error) {
+	if conf.Region == "" {
+		return nil, fmt.Errorf("aws region of the secrets manager is missing")
+	}
+	awsConf := aws.NewConfig().WithRegion(conf.Region).WithHTTPClient(&http.Client{
+		Timeout: 10 * time.Second,
+	})
+	if conf.Credentials != nil {
+		awsConf = awsConf.WithCredentials(conf.Credentials)
+	}
+	if conf.Endpoint != "" {
+		awsConf = awsConf.WithEndpoint(conf.Endpoint)
+	}
+	sess, err := session.NewSession(awsConf)
+	if err != nil {
+		return nil, err
+	}
+	return &AWSSecretsManager{
+		client: secretsmanager.New(sess),
+	}, nil
+}
+
+func (sm *AWSSecretsManager) Lookup(ctx context.Context, path, key string) (string, error) {
+	secret, err := sm.client.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(path),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if secret.SecretString == nil {
+		return "", fmt.Errorf("secret %s is binary, only strings are supported", path)
+	}
+	if key == "" {
+		return *secret.SecretString, nil
+	}
+
+	var values map[string]json.RawMessage
+	err = json.Unmarshal([]byte(*secret.SecretString), &values)
+	if err != nil {
+		return "", fmt.Errorf("secret %s is not a json object, it can't be referred to by key", path)
+	}
+	raw, ok := values[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %s", path, key)
+	}
+	var value string
+	err = json.Unmarshal(raw, &value)
+	if err != nil {
+		return "", fmt.Errorf("key %s of secret %s is not a string", key, path)
+	}
+	return value, nil
+}
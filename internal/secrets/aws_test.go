@@ -0,0 +1,60 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSSecretsManager(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/"))
+		require.Contains(t, r.Header.Get("Authorization"), "/us-east-1/secretsmanager/")
+
+		var body struct {
+			SecretId string
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		switch body.SecretId {
+		case "image-builder/db":
+			fmt.Fprint(w, `{"SecretString": "{\"password\": \"foobar\"}"}`)
+		case "image-builder/token":
+			fmt.Fprint(w, `{"SecretString": "token"}`)
+		case "image-builder/cert":
+			fmt.Fprint(w, `{"SecretBinary": "Zm9vYmFy"}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type": "ResourceNotFoundException", "message": "Secrets Manager can't find the specified secret."}`)
+		}
+	}))
+	defer srv.Close()
+
+	sm, err := NewAWSSecretsManager(AWSConfig{
+		Region:      "us-east-1",
+		Endpoint:    srv.URL,
+		Credentials: credentials.NewStaticCredentials("key", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	value, err := sm.Lookup(context.Background(), "image-builder/db", "password")
+	require.NoError(t, err)
+	require.Equal(t, "foobar", value)
+	value, err = sm.Lookup(context.Background(), "image-builder/token", "")
+	require.NoError(t, err)
+	require.Equal(t, "token", value)
+
+	_, err = sm.Lookup(context.Background(), "image-builder/token", "password")
+	require.ErrorContains(t, err, "not a json object")
+	_, err = sm.Lookup(context.Background(), "image-builder/cert", "")
+	require.ErrorContains(t, err, "binary")
+	_, err = sm.Lookup(context.Background(), "image-builder/unknown", "")
+	require.ErrorContains(t, err, "ResourceNotFoundException")
+}
@@ -0,0 +1,157 @@
+// Package secrets resolves references to secrets in config values, e.g.
+// vault:secret/data/image-builder#pgpassword, from the secret stores they
+// point to.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Schemes of the references of the stores.
+const (
+	SchemeVault = "vault"
+	SchemeAWS   = "aws-sm"
+)
+
+// Store looks up the key of the secret at path, or the whole secret if key
+// is empty and the store supports it.
+type Store interface {
+	Lookup(ctx context.Context, path, key string) (string, error)
+}
+
+// IsReference reports whether value refers to a secret in a store.
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, SchemeVault+":") || strings.HasPrefix(value, SchemeAWS+":")
+}
+
+// parseReference splits scheme:path#key.
+func parseReference(ref string) (scheme, path, key string, err error) {
+	scheme, rest, ok := strings.Cut(ref, ":")
+	if !ok || rest == "" {
+		return "", "", "", fmt.Errorf("expected scheme:path#key, got %q", ref)
+	}
+	path, key, _ = strings.Cut(rest, "#")
+	if path == "" {
+		return "", "", "", fmt.Errorf("secret reference %q has no path", ref)
+	}
+	return scheme, path, key, nil
+}
+
+// OrgReference expands the {org_id} in the path of template, e.g.
+// vault:secret/data/image-builder/orgs/{org_id}, to refer to key of the
+// secret of an org. Org ids which aren't plain names are refused, they
+// could point anywhere in the store.
+func OrgReference(template, orgId, key string) (string, error) {
+	if orgId == "" || strings.Trim(orgId, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-") != "" {
+		return "", fmt.Errorf("org id %q can't be part of a secret reference", orgId)
+	}
+	if !strings.Contains(template, "{org_id}") {
+		return "", fmt.Errorf("secret reference %q has no {org_id}", template)
+	}
+	return strings.ReplaceAll(template, "{org_id}", orgId) + "#" + key, nil
+}
+
+// Resolver resolves references and keeps the values, so they can be
+// resolved again periodically with Run, e.g. after a rotation.
+type Resolver struct {
+	stores map[string]Store
+
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+// NewResolver resolves references with the stores by their scheme, those
+// of schemes without a store are an error.
+func NewResolver(stores map[string]Store) *Resolver {
+	return &Resolver{
+		stores: stores,
+		values: map[string]string{},
+	}
+}
+
+func (r *Resolver) lookup(ctx context.Context, ref string) (string, error) {
+	scheme, path, key, err := parseReference(ref)
+	if err != nil {
+		return "", err
+	}
+	store, ok := r.stores[scheme]
+	if !ok {
+		return "", fmt.Errorf("no secret store configured for %q", ref)
+	}
+	value, err := store.Lookup(ctx, path, key)
+	if err != nil {
+		return "", fmt.Errorf("Unable to resolve %q: %v", ref, err)
+	}
+	return value, nil
+}
+
+// Resolve looks up ref and keeps its value for Value.
+func (r *Resolver) Resolve(ctx context.Context, ref string) (string, error) {
+	value, err := r.lookup(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values[ref] = value
+	return value, nil
+}
+
+// Lookup looks up ref without keeping its value, for secrets which are only
+// needed now and then.
+func (r *Resolver) Lookup(ctx context.Context, ref string) (string, error) {
+	return r.lookup(ctx, ref)
+}
+
+// Value returns the last value ref resolved to, empty if it wasn't
+// resolved yet.
+func (r *Resolver) Value(ref string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.values[ref]
+}
+
+// Refresh resolves all references again. References which can't be
+// resolved keep their previous value.
+func (r *Resolver) Refresh(ctx context.Context) {
+	r.mu.RLock()
+	var refs []string
+	for ref := range r.values {
+		refs = append(refs, ref)
+	}
+	r.mu.RUnlock()
+
+	for _, ref := range refs {
+		value, err := r.lookup(ctx, ref)
+		if err != nil {
+			logrus.Errorf("Unable to refresh secret, keeping the previous value: %v", err)
+			continue
+		}
+		r.mu.Lock()
+		if r.values[ref] != value {
+			logrus.Infof("Secret %s changed", ref)
+			r.values[ref] = value
+		}
+		r.mu.Unlock()
+	}
+}
+
+// Run refreshes the references every interval until ctx is done.
+func (r *Resolver) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Refresh(ctx)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore map[string]string
+
+func (fs fakeStore) Lookup(ctx context.Context, path, key string) (string, error) {
+	value, ok := fs[path+"#"+key]
+	if !ok {
+		return "", fmt.Errorf("not found")
+	}
+	return value, nil
+}
+
+func TestIsReference(t *testing.T) {
+	require.True(t, IsReference("vault:secret/data/image-builder#pgpassword"))
+	require.True(t, IsReference("aws-sm:image-builder/db"))
+	require.False(t, IsReference("foobar"))
+	require.False(t, IsReference("https://vault.example.com"))
+}
+
+func TestResolver(t *testing.T) {
+	store := fakeStore{"secret/data/ib#pgpassword": "foobar"}
+	r := NewResolver(map[string]Store{SchemeVault: store})
+
+	value, err := r.Resolve(context.Background(), "vault:secret/data/ib#pgpassword")
+	require.NoError(t, err)
+	require.Equal(t, "foobar", value)
+	require.Equal(t, "foobar", r.Value("vault:secret/data/ib#pgpassword"))
+
+	_, err = r.Resolve(context.Background(), "aws-sm:ib/db#password")
+	require.ErrorContains(t, err, "no secret store")
+	_, err = r.Resolve(context.Background(), "vault:#pgpassword")
+	require.ErrorContains(t, err, "no path")
+	_, err = r.Resolve(context.Background(), "vault:secret/data/ib#unknown")
+	require.Error(t, err)
+
+	// rotated secrets are picked up, vanished ones keep their value
+	store["secret/data/ib#pgpassword"] = "rotated"
+	r.Refresh(context.Background())
+	require.Equal(t, "rotated", r.Value("vault:secret/data/ib#pgpassword"))
+	delete(store, "secret/data/ib#pgpassword")
+	r.Refresh(context.Background())
+	require.Equal(t, "rotated", r.Value("vault:secret/data/ib#pgpassword"))
+
+	// looked up secrets aren't kept
+	store["secret/data/ib/orgs/000000#private_key"] = "key"
+	ref, err := OrgReference("vault:secret/data/ib/orgs/{org_id}", "000000", "private_key")
+	require.NoError(t, err)
+	value, err = r.Lookup(context.Background(), ref)
+	require.NoError(t, err)
+	require.Equal(t, "key", value)
+	require.Empty(t, r.Value(ref))
+}
+
+func TestOrgReference(t *testing.T) {
+	ref, err := OrgReference("aws-sm:image-builder/{org_id}/cosign", "org_1-a", "private_key")
+	require.NoError(t, err)
+	require.Equal(t, "aws-sm:image-builder/org_1-a/cosign#private_key", ref)
+
+	for _, orgId := range []string{"", "../000000", "000000#pgpassword", "000 000"} {
+		_, err = OrgReference("vault:secret/data/ib/orgs/{org_id}", orgId, "private_key")
+		require.Error(t, err, orgId)
+	}
+	_, err = OrgReference("vault:secret/data/ib/cosign", "000000", "private_key")
+	require.ErrorContains(t, err, "has no {org_id}")
+}
@@ -0,0 +1,79 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+type VaultConfig struct {
+	// e.g. https://vault.example.com:8200
+	Address string
+	Token   string
+	// Enterprise namespace, none if empty.
+	Namespace string
+}
+
+// Vault reads secrets of the kv secrets engine, the path is the one of the
+// api, e.g. secret/data/image-builder for version 2 of the engine.
+type Vault struct {
+	client *vault.Client
+}
+
+func NewVault(conf VaultConfig) (*Vault, error) {
+	if conf.Address == "" {
+		return nil, fmt.Errorf("vault address is missing")
+	}
+	vc := vault.DefaultConfig()
+	if vc.Error != nil {
+		return nil, vc.Error
+	}
+	vc.Address = conf.Address
+	vc.Timeout = 10 * time.Second
+	client, err := vault.NewClient(vc)
+	if err != nil {
+		return nil, err
+	}
+	client.SetToken(conf.Token)
+	if conf.Namespace != "" {
+		client.SetNamespace(conf.Namespace)
+	}
+	return &Vault{
+		client: client,
+	}, nil
+}
+
+func (v *Vault) Lookup(ctx context.Context, path, key string) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("vault secrets need a key, e.g. %s#password", path)
+	}
+
+	secret, err := v.client.Logical().ReadWithContext(ctx, strings.TrimPrefix(path, "/"))
+	if err != nil {
+		return "", err
+	}
+	if secret == nil || secret.Data == nil {
+		return "", fmt.Errorf("secret %s not found", path)
+	}
+
+	// version 2 of the kv engine nests the secret in data.data
+	data := secret.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, metadata := data["metadata"]; metadata {
+			data = nested
+		}
+	}
+
+	raw, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %s", path, key)
+	}
+	value, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("key %s of secret %s is not a string", key, path)
+	}
+	return value, nil
+}
@@ -0,0 +1,53 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVault(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" || r.Header.Get("X-Vault-Namespace") != "ib" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/image-builder":
+			fmt.Fprint(w, `{"data": {"data": {"pgpassword": "kv2"}, "metadata": {"version": 3}}}`)
+		case "/v1/kv/image-builder":
+			fmt.Fprint(w, `{"data": {"pgpassword": "kv1", "port": 5432}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	v, err := NewVault(VaultConfig{Address: srv.URL + "/", Token: "token", Namespace: "ib"})
+	require.NoError(t, err)
+
+	value, err := v.Lookup(context.Background(), "secret/data/image-builder", "pgpassword")
+	require.NoError(t, err)
+	require.Equal(t, "kv2", value)
+	value, err = v.Lookup(context.Background(), "kv/image-builder", "pgpassword")
+	require.NoError(t, err)
+	require.Equal(t, "kv1", value)
+
+	_, err = v.Lookup(context.Background(), "kv/image-builder", "port")
+	require.ErrorContains(t, err, "not a string")
+	_, err = v.Lookup(context.Background(), "kv/image-builder", "")
+	require.ErrorContains(t, err, "need a key")
+	_, err = v.Lookup(context.Background(), "kv/image-builder", "unknown")
+	require.ErrorContains(t, err, "no key unknown")
+	_, err = v.Lookup(context.Background(), "kv/unknown", "pgpassword")
+	require.ErrorContains(t, err, "not found")
+
+	v, err = NewVault(VaultConfig{Address: srv.URL, Token: "wrong", Namespace: "ib"})
+	require.NoError(t, err)
+	_, err = v.Lookup(context.Background(), "kv/image-builder", "pgpassword")
+	require.ErrorContains(t, err, "403")
+}
@@ -0,0 +1,113 @@
+// Package secretscan looks for credentials in the user supplied parts of
+// compose requests, which would otherwise be baked into every instance of an
+// image.
+package secretscan
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type rule struct {
+	name        string
+	description string
+	re          *regexp.Regexp
+}
+
+// The rules match what the credentials look like on their own, without
+// relying on the names of the fields they're in.
+var rules = []rule{
+	{"private-key", "a private key", regexp.MustCompile(`-----BEGIN[A-Z ]*PRIVATE KEY( BLOCK)?-----`)},
+	{"aws-access-key-id", "an AWS access key id", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"aws-secret-access-key", "an AWS secret access key", regexp.MustCompile(`(?i)aws.{0,20}secret.{0,20}[=:"'\s][A-Za-z0-9/+]{40}\b`)},
+	{"github-token", "a GitHub token", regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{82})\b`)},
+	{"gitlab-token", "a GitLab token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20}\b`)},
+	{"slack-token", "a Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{"google-api-key", "a Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+}
+
+// Finding is a credential found in a document. The credential itself isn't
+// kept, so findings can be logged and returned.
+type Finding struct {
+	// JSON pointer to the value, e.g. /customizations/users/0/ssh_key
+	Path string
+	// Name of the rule which matched, e.g. private-key
+	Rule string
+	// What was found, e.g. a private key
+	Description string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s at %s", f.Description, f.Path)
+}
+
+// Scan returns the credentials in the string values of v once it's encoded
+// as JSON, ordered by their path. Values below the paths in skip aren't
+// scanned.
+func Scan(v interface{}, skip ...string) ([]Finding, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	err = json.Unmarshal(raw, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	walk(doc, "", skip, &findings)
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Path < findings[j].Path
+	})
+	return findings, nil
+}
+
+func walk(v interface{}, path string, skip []string, findings *[]Finding) {
+	for _, s := range skip {
+		if path == s {
+			return
+		}
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			walk(child, path+"/"+escape(k), skip, findings)
+		}
+	case []interface{}:
+		for i, child := range v {
+			walk(child, fmt.Sprintf("%s/%d", path, i), skip, findings)
+		}
+	case string:
+		for _, r := range rules {
+			if r.re.MatchString(v) {
+				*findings = append(*findings, Finding{path, r.name, r.description})
+			}
+		}
+		if urlWithPassword(v) {
+			*findings = append(*findings, Finding{path, "url-password", "a url with a password"})
+		}
+	}
+}
+
+// urlWithPassword tells whether s is a url which carries a password, e.g. of
+// a repository.
+func urlWithPassword(s string) bool {
+	if !strings.Contains(s, "://") {
+		return false
+	}
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil || u.User == nil {
+		return false
+	}
+	password, ok := u.User.Password()
+	return ok && password != ""
+}
+
+func escape(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
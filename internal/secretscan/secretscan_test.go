@@ -0,0 +1,57 @@
+package secretscan
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScan(t *testing.T) {
+	// the credentials are put together here, so they don't trip the
+	// scanners of the repository
+	privateKey := "-----BEGIN " + "OPENSSH PRIVATE KEY-----\nb3BlbnNzaC1rZXktdjEAAAAA\n-----END OPENSSH PRIVATE KEY-----"
+	githubToken := "ghp_" + strings.Repeat("x1", 18)
+	awsKeyId := "AKIA" + "IOSFODNN7EXAMPLE"
+
+	findings, err := Scan(map[string]interface{}{
+		"customizations": map[string]interface{}{
+			"users": []map[string]string{
+				{"name": "user1", "ssh_key": "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGn3 user1@example.com"},
+				{"name": "user2", "ssh_key": privateKey},
+			},
+			"payload_repositories": []map[string]string{
+				{"baseurl": "https://user:" + "hunter2@repo.example.com/el9"},
+				{"baseurl": "https://token@repo.example.com/el9"},
+				{"baseurl": "https://repo.example.com/el9?token=" + githubToken},
+			},
+			"subscription": map[string]string{
+				"activation-key": "ak_" + awsKeyId,
+			},
+			"packages": []string{"postgresql", "aws-cli"},
+		},
+		"image_description": "built with " + awsKeyId + " and -----BEGIN PUBLIC KEY-----",
+	}, "/customizations/subscription")
+	require.NoError(t, err)
+	require.Equal(t, []Finding{
+		{"/customizations/payload_repositories/0/baseurl", "url-password", "a url with a password"},
+		{"/customizations/payload_repositories/2/baseurl", "github-token", "a GitHub token"},
+		{"/customizations/users/1/ssh_key", "private-key", "a private key"},
+		{"/image_description", "aws-access-key-id", "an AWS access key id"},
+	}, findings)
+	require.Equal(t, "a private key at /customizations/users/1/ssh_key", findings[2].String())
+
+	findings, err = Scan(map[string]string{
+		"script": "export AWS_SECRET_ACCESS_KEY=" + strings.Repeat("wJalrXUtnFEMI/K7MDENG", 2)[:40],
+		"a/b":    "xoxb-" + "123456789012-abcdefgh",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Finding{
+		{"/a~1b", "slack-token", "a Slack token"},
+		{"/script", "aws-secret-access-key", "an AWS secret access key"},
+	}, findings)
+
+	findings, err = Scan(map[string]interface{}{"count": 3, "enabled": true, "name": "my-image"})
+	require.NoError(t, err)
+	require.Empty(t, findings)
+}
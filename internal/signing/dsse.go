@@ -0,0 +1,71 @@
+package signing
+
+import (
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Envelope is a DSSE envelope, in which in-toto attestations are signed, see
+// https://github.com/secure-systems-lab/dsse.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+type Signature struct {
+	KeyId string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// pae is the pre-authentication encoding of DSSE, which is what's signed.
+func pae(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// SignEnvelope wraps payload in an envelope signed by signer.
+func SignEnvelope(signer crypto.Signer, payloadType string, payload []byte) (*Envelope, error) {
+	keyId, err := KeyId(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	sig, err := Sign(signer, pae(payloadType, payload))
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{
+		PayloadType: payloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []Signature{
+			{KeyId: keyId, Sig: base64.StdEncoding.EncodeToString(sig)},
+		},
+	}, nil
+}
+
+// Open returns the payload of the envelope if one of its signatures is by
+// pub.
+func (e *Envelope) Open(pub crypto.PublicKey) ([]byte, error) {
+	keyId, err := KeyId(pub)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := base64.StdEncoding.DecodeString(e.Payload)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range e.Signatures {
+		if s.KeyId != "" && s.KeyId != keyId {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err != nil {
+			continue
+		}
+		if Verify(pub, pae(e.PayloadType, payload), sig) == nil {
+			return payload, nil
+		}
+	}
+	return nil, errors.New("the envelope isn't signed by the key")
+}
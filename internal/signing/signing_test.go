@@ -0,0 +1,81 @@
+package signing
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testKeys(t *testing.T) map[string][]byte {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return map[string][]byte{
+		"ed25519": pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDER}),
+		"ecdsa":   pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}),
+		"rsa":     pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+	}
+}
+
+func TestSignEnvelope(t *testing.T) {
+	for name, raw := range testKeys(t) {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "key.pem")
+			require.NoError(t, os.WriteFile(path, raw, 0600))
+			signer, err := LoadKey(path)
+			require.NoError(t, err)
+
+			env, err := SignEnvelope(signer, "application/vnd.in-toto+json", []byte(`{"_type": "https://in-toto.io/Statement/v1"}`))
+			require.NoError(t, err)
+			keyId, err := KeyId(signer.Public())
+			require.NoError(t, err)
+			require.Equal(t, keyId, env.Signatures[0].KeyId)
+
+			payload, err := env.Open(signer.Public())
+			require.NoError(t, err)
+			require.JSONEq(t, `{"_type": "https://in-toto.io/Statement/v1"}`, string(payload))
+
+			// the payload type is signed too
+			env.PayloadType = "application/json"
+			_, err = env.Open(signer.Public())
+			require.Error(t, err)
+			env.PayloadType = "application/vnd.in-toto+json"
+
+			env.Payload = base64.StdEncoding.EncodeToString([]byte(`{"_type": "forged"}`))
+			_, err = env.Open(signer.Public())
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	_, err := ParseKey([]byte("not a key"))
+	require.Error(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	pub, err := x509.MarshalPKIXPublicKey(ecKey.Public())
+	require.NoError(t, err)
+	_, err = ParseKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+	require.ErrorContains(t, err, "expected a private key")
+}
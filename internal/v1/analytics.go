@@ -0,0 +1,138 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+// how long recording the artifacts of a compose may take, images uploaded to
+// aws.s3 are downloaded to measure them
+const artifactRecordingTimeout = 30 * time.Minute
+
+// how far back analytics go if no since is given
+const defaultAnalyticsRange = 30 * 24 * time.Hour
+
+var analyticsPeriods = []string{"day", "week", "month"}
+
+// SupportOrgAnalytics sums up the composes an org created in a period.
+type SupportOrgAnalytics struct {
+	OrgId       string `json:"org_id"`
+	PeriodStart string `json:"period_start"`
+	Builds      int    `json:"builds"`
+	Succeeded   int    `json:"succeeded"`
+	Failed      int    `json:"failed"`
+	// Of the composes which finished, null if none did
+	SuccessRate *float64 `json:"success_rate"`
+	Artifacts   int      `json:"artifacts"`
+	// Size of the artifacts of the composes, deleted ones included
+	StorageBytes int64 `json:"storage_bytes"`
+}
+
+type SupportAnalyticsResponse struct {
+	Period string                `json:"period"`
+	Since  string                `json:"since"`
+	Until  string                `json:"until"`
+	Data   []SupportOrgAnalytics `json:"data"`
+}
+
+// recordArtifacts stores the artifacts of a successful compose, so the storage
+// its org consumes is accounted for.
+func (s *Server) recordArtifacts(event outboxEvent) error {
+	compose, err := s.db.GetCompose(event.ComposeId, event.OrgId)
+	if errors.Is(err, db.ComposeNotFoundError) {
+		// deleted before its artifacts were recorded
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), artifactRecordingTimeout)
+	defer cancel()
+	artifacts, err := s.composeArtifacts(ctx, compose)
+	if err != nil {
+		return err
+	}
+	logrus.Debugf("Recorded %d artifacts of compose %v", len(artifacts), event.ComposeId)
+	return nil
+}
+
+// GetSupportAnalytics sums up the builds, their success rate and the storage
+// their artifacts consume per org and period, for capacity planning.
+func (h *Handlers) GetSupportAnalytics(ctx echo.Context) error {
+	until := time.Now()
+	var err error
+	if ctx.QueryParam("until") != "" {
+		until, err = time.Parse(time.RFC3339, ctx.QueryParam("until"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid until, expected a RFC3339 timestamp")
+		}
+	}
+	since := until.Add(-defaultAnalyticsRange)
+	if ctx.QueryParam("since") != "" {
+		since, err = time.Parse(time.RFC3339, ctx.QueryParam("since"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid since, expected a RFC3339 timestamp")
+		}
+	}
+	if !since.Before(until) {
+		return echo.NewHTTPError(http.StatusBadRequest, "since has to be before until")
+	}
+	period := ctx.QueryParam("period")
+	if period == "" {
+		period = "day"
+	}
+	if !containsString(analyticsPeriods, period) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid period, expected day, week or month")
+	}
+
+	idh, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+	orgId := ctx.QueryParam("org_id")
+	ctx.Logger().Infof("Associate %s looked up the analytics of %q from %v to %v", idh.Identity.Associate.Email, orgId, since, until)
+
+	entries, err := h.server.db.GetOrgAnalytics(orgId, since.UTC(), until.UTC(), period)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	data := []SupportOrgAnalytics{}
+	for _, e := range entries {
+		a := SupportOrgAnalytics{
+			OrgId:        e.OrgId,
+			PeriodStart:  e.PeriodStart.Format(time.RFC3339),
+			Builds:       e.Builds,
+			Succeeded:    e.Succeeded,
+			Failed:       e.Failed,
+			Artifacts:    e.Artifacts,
+			StorageBytes: e.ArtifactBytes,
+		}
+		if finished := e.Succeeded + e.Failed; finished > 0 {
+			rate := float64(e.Succeeded) / float64(finished)
+			a.SuccessRate = &rate
+		}
+		data = append(data, a)
+	}
+	return ctx.JSON(http.StatusOK, SupportAnalyticsResponse{
+		Period: period,
+		Since:  since.UTC().Format(time.RFC3339),
+		Until:  until.UTC().Format(time.RFC3339),
+		Data:   data,
+	})
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -13,6 +13,7 @@ import (
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/deepmap/oapi-codegen/pkg/runtime"
 	openapi_types "github.com/deepmap/oapi-codegen/pkg/types"
@@ -20,6 +21,32 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// Defines values for AWXJobStatus.
+const (
+	AWXJobStatusFailed   AWXJobStatus = "failed"
+	AWXJobStatusLaunched AWXJobStatus = "launched"
+	AWXJobStatusPending  AWXJobStatus = "pending"
+)
+
+// Defines values for ComplianceExportStatus.
+const (
+	ComplianceExportStatusExported ComplianceExportStatus = "exported"
+	ComplianceExportStatusPending  ComplianceExportStatus = "pending"
+)
+
+// Defines values for ContainerUploadRequestOptionsSign.
+const (
+	Key     ContainerUploadRequestOptionsSign = "key"
+	Keyless ContainerUploadRequestOptionsSign = "keyless"
+)
+
+// Defines values for ContainerUploadStatusSignatureStatus.
+const (
+	ContainerUploadStatusSignatureStatusFailed  ContainerUploadStatusSignatureStatus = "failed"
+	ContainerUploadStatusSignatureStatusPending ContainerUploadStatusSignatureStatus = "pending"
+	ContainerUploadStatusSignatureStatusSigned  ContainerUploadStatusSignatureStatus = "signed"
+)
+
 // Defines values for CustomizationsPartitioningMode.
 const (
 	AutoLvm CustomizationsPartitioningMode = "auto-lvm"
@@ -78,12 +105,14 @@ const (
 
 // Defines values for ImageStatusStatus.
 const (
-	ImageStatusStatusBuilding    ImageStatusStatus = "building"
-	ImageStatusStatusFailure     ImageStatusStatus = "failure"
-	ImageStatusStatusPending     ImageStatusStatus = "pending"
-	ImageStatusStatusRegistering ImageStatusStatus = "registering"
-	ImageStatusStatusSuccess     ImageStatusStatus = "success"
-	ImageStatusStatusUploading   ImageStatusStatus = "uploading"
+	ImageStatusStatusBuilding        ImageStatusStatus = "building"
+	ImageStatusStatusFailure         ImageStatusStatus = "failure"
+	ImageStatusStatusPending         ImageStatusStatus = "pending"
+	ImageStatusStatusPendingApproval ImageStatusStatus = "pending_approval"
+	ImageStatusStatusQueued          ImageStatusStatus = "queued"
+	ImageStatusStatusRegistering     ImageStatusStatus = "registering"
+	ImageStatusStatusSuccess         ImageStatusStatus = "success"
+	ImageStatusStatusUploading       ImageStatusStatus = "uploading"
 )
 
 // Defines values for ImageTypes.
@@ -92,10 +121,12 @@ const (
 	ImageTypesAws               ImageTypes = "aws"
 	ImageTypesAzure             ImageTypes = "azure"
 	ImageTypesEdgeCommit        ImageTypes = "edge-commit"
+	ImageTypesEdgeContainer     ImageTypes = "edge-container"
 	ImageTypesEdgeInstaller     ImageTypes = "edge-installer"
 	ImageTypesGcp               ImageTypes = "gcp"
 	ImageTypesGuestImage        ImageTypes = "guest-image"
 	ImageTypesImageInstaller    ImageTypes = "image-installer"
+	ImageTypesLiveInstaller     ImageTypes = "live-installer"
 	ImageTypesOci               ImageTypes = "oci"
 	ImageTypesRhelEdgeCommit    ImageTypes = "rhel-edge-commit"
 	ImageTypesRhelEdgeInstaller ImageTypes = "rhel-edge-installer"
@@ -105,6 +136,49 @@ const (
 	ImageTypesWsl               ImageTypes = "wsl"
 )
 
+// Defines values for LaunchStatusStatus.
+const (
+	LaunchStatusStatusFailure LaunchStatusStatus = "failure"
+	LaunchStatusStatusPending LaunchStatusStatus = "pending"
+	LaunchStatusStatusSuccess LaunchStatusStatus = "success"
+)
+
+// Defines values for OrgEventEvent.
+const (
+	OrgEventEventCloneCreated    OrgEventEvent = "clone_created"
+	OrgEventEventCloneFinished   OrgEventEvent = "clone_finished"
+	OrgEventEventComposeCreated  OrgEventEvent = "compose_created"
+	OrgEventEventComposeFinished OrgEventEvent = "compose_finished"
+)
+
+// Defines values for SecretScanningSettingsMode.
+const (
+	Reject SecretScanningSettingsMode = "reject"
+	Warn   SecretScanningSettingsMode = "warn"
+)
+
+// Defines values for SigningKeyStatus.
+const (
+	InUse   SigningKeyStatus = "in_use"
+	Revoked SigningKeyStatus = "revoked"
+	Rotated SigningKeyStatus = "rotated"
+)
+
+// Defines values for SigningKeyPurpose.
+const (
+	SigningKeyPurposeArtifact       SigningKeyPurpose = "artifact"
+	SigningKeyPurposeCommit         SigningKeyPurpose = "commit"
+	SigningKeyPurposeSigningService SigningKeyPurpose = "signing_service"
+	SigningKeyPurposeWebhook        SigningKeyPurpose = "webhook"
+)
+
+// Defines values for SigningKeyUsageOperation.
+const (
+	SignArtifact     SigningKeyUsageOperation = "sign_artifact"
+	SignCommit       SigningKeyUsageOperation = "sign_commit"
+	SignWebhookEvent SigningKeyUsageOperation = "sign_webhook_event"
+)
+
 // Defines values for UploadStatusStatus.
 const (
 	UploadStatusStatusFailure UploadStatusStatus = "failure"
@@ -118,8 +192,45 @@ const (
 	UploadTypesAws              UploadTypes = "aws"
 	UploadTypesAwsS3            UploadTypes = "aws.s3"
 	UploadTypesAzure            UploadTypes = "azure"
+	UploadTypesContainer        UploadTypes = "container"
 	UploadTypesGcp              UploadTypes = "gcp"
 	UploadTypesOciObjectstorage UploadTypes = "oci.objectstorage"
+	UploadTypesPulpOstree       UploadTypes = "pulp.ostree"
+)
+
+// Defines values for VulnerabilitySeverity.
+const (
+	Critical VulnerabilitySeverity = "critical"
+	High     VulnerabilitySeverity = "high"
+	Low      VulnerabilitySeverity = "low"
+	Medium   VulnerabilitySeverity = "medium"
+	Unknown  VulnerabilitySeverity = "unknown"
+)
+
+// Defines values for WebhookDeliveryEvent.
+const (
+	WebhookDeliveryEventCloneFinished             WebhookDeliveryEvent = "clone_finished"
+	WebhookDeliveryEventComposeFinished           WebhookDeliveryEvent = "compose_finished"
+	WebhookDeliveryEventVulnerabilityScanFinished WebhookDeliveryEvent = "vulnerability_scan_finished"
+)
+
+// Defines values for WebhookDeliveryStatus.
+const (
+	Delivered WebhookDeliveryStatus = "delivered"
+	Failed    WebhookDeliveryStatus = "failed"
+	Pending   WebhookDeliveryStatus = "pending"
+)
+
+// Defines values for ExportComposesParamsFormat.
+const (
+	ExportComposesParamsFormatCsv  ExportComposesParamsFormat = "csv"
+	ExportComposesParamsFormatJson ExportComposesParamsFormat = "json"
+)
+
+// Defines values for GetComposeSBOMParamsFormat.
+const (
+	Cyclonedx GetComposeSBOMParamsFormat = "cyclonedx"
+	Spdx      GetComposeSBOMParamsFormat = "spdx"
 )
 
 // Defines values for GetPackagesParamsArchitecture.
@@ -128,6 +239,65 @@ const (
 	GetPackagesParamsArchitectureX8664   GetPackagesParamsArchitecture = "x86_64"
 )
 
+// Defines values for GetUsageReportParamsFormat.
+const (
+	GetUsageReportParamsFormatCsv  GetUsageReportParamsFormat = "csv"
+	GetUsageReportParamsFormatJson GetUsageReportParamsFormat = "json"
+)
+
+// Defines values for GetBuildDurationsParamsWindow.
+const (
+	N1d  GetBuildDurationsParamsWindow = "1d"
+	N30d GetBuildDurationsParamsWindow = "30d"
+	N7d  GetBuildDurationsParamsWindow = "7d"
+	N90d GetBuildDurationsParamsWindow = "90d"
+)
+
+// APIToken defines model for APIToken.
+type APIToken struct {
+	CreatedAt string `json:"created_at"`
+
+	// CreatedBy username of the user who created the token
+	CreatedBy  *string            `json:"created_by,omitempty"`
+	ExpiresAt  *string            `json:"expires_at,omitempty"`
+	Id         openapi_types.UUID `json:"id"`
+	LastUsedAt *string            `json:"last_used_at,omitempty"`
+	Name       string             `json:"name"`
+	ReadOnly   bool               `json:"read_only"`
+}
+
+// APITokenCreated defines model for APITokenCreated.
+type APITokenCreated struct {
+	CreatedAt string `json:"created_at"`
+
+	// CreatedBy username of the user who created the token
+	CreatedBy  *string            `json:"created_by,omitempty"`
+	ExpiresAt  *string            `json:"expires_at,omitempty"`
+	Id         openapi_types.UUID `json:"id"`
+	LastUsedAt *string            `json:"last_used_at,omitempty"`
+	Name       string             `json:"name"`
+	ReadOnly   bool               `json:"read_only"`
+
+	// Token The token itself, it can not be retrieved again.
+	Token string `json:"token"`
+}
+
+// APITokenRequest defines model for APITokenRequest.
+type APITokenRequest struct {
+	// ExpiresAt The token can't be used after this time, it doesn't expire if
+	// unset.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Name      string     `json:"name"`
+
+	// ReadOnly Read-only tokens can only be used for GET requests.
+	ReadOnly *bool `json:"read_only,omitempty"`
+}
+
+// APITokensResponse defines model for APITokensResponse.
+type APITokensResponse struct {
+	Data []APIToken `json:"data"`
+}
+
 // AWSEC2Clone defines model for AWSEC2Clone.
 type AWSEC2Clone struct {
 	// Region A region as described in
@@ -150,6 +320,28 @@ type AWSS3UploadStatus struct {
 
 // AWSUploadRequestOptions defines model for AWSUploadRequestOptions.
 type AWSUploadRequestOptions struct {
+	// Encrypted Encrypt the snapshot backing the AMI. Defaults to true when
+	// kms_key_arn is set, or when the service has a default key for
+	// the region. Encrypted images can't be copied into other regions
+	// or cloned.
+	Encrypted *bool `json:"encrypted,omitempty"`
+
+	// KmsKeyArn ARN of a customer managed KMS key, or its alias, which is used
+	// to encrypt the snapshot backing the AMI. The AMI is copied with
+	// the key once it's uploaded, only the copy is shared. The key has
+	// to live in the region the image is uploaded to, and its policy
+	// has to allow the service and the accounts the image is shared
+	// with to use it. Requests with a key the service can't use are
+	// rejected.
+	KmsKeyArn *string `json:"kms_key_arn,omitempty"`
+
+	// Partition The AWS partition the image is uploaded to. Images in the
+	// aws-us-gov partition can only be shared with GovCloud accounts.
+	Partition *string `json:"partition,omitempty"`
+
+	// Regions Additional regions the AMI is copied to once the compose has
+	// finished. The copies show up as clones of the compose.
+	Regions           *[]string `json:"regions,omitempty"`
 	ShareWithAccounts *[]string `json:"share_with_accounts,omitempty"`
 	ShareWithSources  *[]string `json:"share_with_sources,omitempty"`
 }
@@ -160,6 +352,68 @@ type AWSUploadStatus struct {
 	Region string `json:"region"`
 }
 
+// AWXJob defines model for AWXJob.
+type AWXJob struct {
+	Attempts  int                `json:"attempts"`
+	ComposeId openapi_types.UUID `json:"compose_id"`
+	CreatedAt string             `json:"created_at"`
+
+	// Error why the last attempt failed
+	Error *string `json:"error,omitempty"`
+
+	// JobId id of the job on the controller
+	JobId         *int    `json:"job_id,omitempty"`
+	JobTemplateId int     `json:"job_template_id"`
+	LaunchedAt    *string `json:"launched_at,omitempty"`
+
+	// Status Pending jobs are attempted to be launched again, failed ones were
+	// given up on.
+	Status AWXJobStatus `json:"status"`
+}
+
+// AWXJobStatus Pending jobs are attempted to be launched again, failed ones were
+// given up on.
+type AWXJobStatus string
+
+// AWXJobsResponse defines model for AWXJobsResponse.
+type AWXJobsResponse struct {
+	Data  []AWXJob `json:"data"`
+	Links struct {
+		First string `json:"first"`
+		Last  string `json:"last"`
+	} `json:"links"`
+	Meta struct {
+		Count int `json:"count"`
+	} `json:"meta"`
+}
+
+// AWXSettings defines model for AWXSettings.
+type AWXSettings struct {
+	JobTemplateId int    `json:"job_template_id"`
+	UpdatedAt     string `json:"updated_at"`
+	Url           string `json:"url"`
+}
+
+// AWXSettingsRequest defines model for AWXSettingsRequest.
+type AWXSettingsRequest struct {
+	JobTemplateId int `json:"job_template_id"`
+
+	// Token OAuth2 token of a user allowed to launch the job template, it can
+	// not be retrieved again.
+	Token string `json:"token"`
+
+	// Url https url of the controller
+	Url string `json:"url"`
+}
+
+// ApprovalSettings defines model for ApprovalSettings.
+type ApprovalSettings struct {
+	// RequireApproval Whether composes have to be approved by a second user before
+	// they are built. The compose policy of the service can require
+	// approvals for some composes regardless of this setting.
+	RequireApproval bool `json:"require_approval"`
+}
+
 // ArchitectureItem defines model for ArchitectureItem.
 type ArchitectureItem struct {
 	Arch       string   `json:"arch"`
@@ -172,6 +426,65 @@ type ArchitectureItem struct {
 // Architectures defines model for Architectures.
 type Architectures = []ArchitectureItem
 
+// ArtifactSigningSettings defines model for ArtifactSigningSettings.
+type ArtifactSigningSettings struct {
+	// Fingerprint fingerprint of the artifact key, it takes precedence over the
+	// signing service
+	Fingerprint *string `json:"fingerprint,omitempty"`
+
+	// PublicKey ASCII armored public key the signatures are verified with
+	PublicKey  *string `json:"public_key,omitempty"`
+	ServiceUrl *string `json:"service_url,omitempty"`
+	UpdatedAt  string  `json:"updated_at"`
+}
+
+// ArtifactSigningSettingsRequest Either a private key or the url of a signing service.
+type ArtifactSigningSettingsRequest struct {
+	// PrivateKey ASCII armored OpenPGP private key without a passphrase, it can not
+	// be retrieved again. RSA, DSA, ECDSA and EdDSA keys are supported.
+	PrivateKey *string `json:"private_key,omitempty"`
+
+	// ServiceToken Bearer token of the signing service, it can not be retrieved
+	// again.
+	ServiceToken *string `json:"service_token,omitempty"`
+
+	// ServiceUrl https url the artifacts to sign are posted to, it responds with
+	// their signature
+	ServiceUrl *string `json:"service_url,omitempty"`
+}
+
+// AuditLogEntry defines model for AuditLogEntry.
+type AuditLogEntry struct {
+	CreatedAt string `json:"created_at"`
+	Email     string `json:"email"`
+	Id        int64  `json:"id"`
+	Method    string `json:"method"`
+
+	// Path route of the call
+	Path     string `json:"path"`
+	RemoteIp string `json:"remote_ip"`
+
+	// RequestDigest hex encoded sha256 of the request body
+	RequestDigest string `json:"request_digest"`
+
+	// ResourceId Id of the resource the call created, or the id in its path.
+	ResourceId *string `json:"resource_id,omitempty"`
+	Status     int     `json:"status"`
+	UserId     string  `json:"user_id"`
+}
+
+// AuditLogResponse defines model for AuditLogResponse.
+type AuditLogResponse struct {
+	Data  []AuditLogEntry `json:"data"`
+	Links struct {
+		First string `json:"first"`
+		Last  string `json:"last"`
+	} `json:"links"`
+	Meta struct {
+		Count int `json:"count"`
+	} `json:"meta"`
+}
+
 // AzureUploadRequestOptions defines model for AzureUploadRequestOptions.
 type AzureUploadRequestOptions struct {
 	// ImageName Name of the created image.
@@ -202,6 +515,56 @@ type AzureUploadStatus struct {
 	ImageName string `json:"image_name"`
 }
 
+// BatchComposeRequest defines model for BatchComposeRequest.
+type BatchComposeRequest struct {
+	Composes []ComposeRequest `json:"composes"`
+}
+
+// BatchComposeResponse defines model for BatchComposeResponse.
+type BatchComposeResponse struct {
+	Results []BatchComposeResult `json:"results"`
+}
+
+// BatchComposeResult defines model for BatchComposeResult.
+type BatchComposeResult struct {
+	Error *HTTPError `json:"error,omitempty"`
+
+	// Id id of the compose, if it was submitted
+	Id *openapi_types.UUID `json:"id,omitempty"`
+
+	// Index position of the compose request in the batch
+	Index int `json:"index"`
+
+	// Warnings Problems with the compose request which didn't stop it from being
+	// built, e.g. credentials found in its customizations.
+	Warnings *ComposeWarnings `json:"warnings,omitempty"`
+}
+
+// BuildDurationStats The image type and architecture of a build are the ones of its first
+// image request.
+type BuildDurationStats struct {
+	Architecture string `json:"architecture"`
+
+	// Builds How many builds the percentiles are computed from
+	Builds       int    `json:"builds"`
+	Distribution string `json:"distribution"`
+	ImageType    string `json:"image_type"`
+
+	// P50Seconds Median of the build durations in seconds
+	P50Seconds float64 `json:"p50_seconds"`
+
+	// P95Seconds 95th percentile of the build durations in seconds
+	P95Seconds float64 `json:"p95_seconds"`
+}
+
+// BuildDurations defines model for BuildDurations.
+type BuildDurations struct {
+	Data   []BuildDurationStats `json:"data"`
+	Since  time.Time            `json:"since"`
+	Until  time.Time            `json:"until"`
+	Window string               `json:"window"`
+}
+
 // CloneRequest defines model for CloneRequest.
 type CloneRequest struct {
 	union json.RawMessage
@@ -233,6 +596,126 @@ type ClonesResponseItem struct {
 	Request   CloneRequest       `json:"request"`
 }
 
+// ComplianceExport defines model for ComplianceExport.
+type ComplianceExport struct {
+	Attempts        int `json:"attempts"`
+	AuditLogEntries int `json:"audit_log_entries"`
+
+	// Composes number of composes whose provenance was exported
+	Composes int `json:"composes"`
+
+	// Error why the last attempt failed
+	Error      *string `json:"error,omitempty"`
+	ExportedAt *string `json:"exported_at,omitempty"`
+
+	// Status Pending exports are attempted again until they succeeded.
+	Status      ComplianceExportStatus `json:"status"`
+	WindowEnd   string                 `json:"window_end"`
+	WindowStart string                 `json:"window_start"`
+}
+
+// ComplianceExportStatus Pending exports are attempted again until they succeeded.
+type ComplianceExportStatus string
+
+// ComplianceExportSettings defines model for ComplianceExportSettings.
+type ComplianceExportSettings struct {
+	Bucket    string `json:"bucket"`
+	Prefix    string `json:"prefix"`
+	Region    string `json:"region"`
+	UpdatedAt string `json:"updated_at"`
+
+	// VerificationKey Key of the object in the bucket which has to hold the
+	// verification token, nothing is exported to the bucket until it
+	// does. It's checked before every export.
+	VerificationKey string `json:"verification_key"`
+
+	// VerificationToken Proves the organization owns the bucket. It's kept as long as
+	// the bucket, region and prefix stay the same.
+	VerificationToken string `json:"verification_token"`
+}
+
+// ComplianceExportSettingsRequest defines model for ComplianceExportSettingsRequest.
+type ComplianceExportSettingsRequest struct {
+	// Bucket Name of the S3 bucket, bucket names with dots aren't supported.
+	Bucket string `json:"bucket"`
+
+	// Prefix Prepended to the keys of the exported objects as is, so it usually
+	// ends with a slash.
+	Prefix *string `json:"prefix,omitempty"`
+	Region string  `json:"region"`
+}
+
+// ComplianceExportsResponse defines model for ComplianceExportsResponse.
+type ComplianceExportsResponse struct {
+	Data  []ComplianceExport `json:"data"`
+	Links struct {
+		First string `json:"first"`
+		Last  string `json:"last"`
+	} `json:"links"`
+	Meta struct {
+		Count int `json:"count"`
+	} `json:"meta"`
+}
+
+// ComposeArtifact defines model for ComposeArtifact.
+type ComposeArtifact struct {
+	// CloudImageId Identifier of the image in the cloud the artifact was imported
+	// into, e.g. the AMI for aws or the image name for gcp and azure.
+	CloudImageId *string `json:"cloud_image_id,omitempty"`
+
+	// Filename Name of the object in the bucket for aws.s3 composes, the
+	// identifier of the cloud image otherwise.
+	Filename string `json:"filename"`
+
+	// Sha256 Checksum of the artifact, only known for aws.s3 composes.
+	Sha256 *string `json:"sha256,omitempty"`
+
+	// Signature ASCII armored detached OpenPGP signature of the artifact, if the
+	// organization has its artifacts signed. It's added shortly after
+	// the compose succeeded.
+	Signature *string `json:"signature,omitempty"`
+
+	// Size Size of the artifact in bytes, only known for aws.s3 composes.
+	Size *int64 `json:"size,omitempty"`
+}
+
+// ComposeArtifactsResponse defines model for ComposeArtifactsResponse.
+type ComposeArtifactsResponse struct {
+	Data []ComposeArtifact `json:"data"`
+}
+
+// ComposeEvent defines model for ComposeEvent.
+type ComposeEvent struct {
+	CreatedAt string `json:"created_at"`
+
+	// Reason why the compose failed, if known
+	Reason *string `json:"reason,omitempty"`
+
+	// Status 'created', 'queued', 'pending_approval' or 'submitted' for
+	// composes which were queued, or a status of the image build.
+	Status string `json:"status"`
+}
+
+// ComposeEvents defines model for ComposeEvents.
+type ComposeEvents struct {
+	Data []ComposeEvent `json:"data"`
+}
+
+// ComposeExportItem defines model for ComposeExportItem.
+type ComposeExportItem struct {
+	CreatedAt    string             `json:"created_at"`
+	Distribution string             `json:"distribution"`
+	Id           openapi_types.UUID `json:"id"`
+	ImageName    *string            `json:"image_name,omitempty"`
+	ImageType    string             `json:"image_type"`
+
+	// Status status composer last reported, empty if it was never asked
+	Status string `json:"status"`
+
+	// Targets upload targets of the image requests
+	Targets []string `json:"targets"`
+}
+
 // ComposeMetadata defines model for ComposeMetadata.
 type ComposeMetadata struct {
 	// OstreeCommit ID (hash) of the built commit
@@ -242,6 +725,11 @@ type ComposeMetadata struct {
 	Packages *[]PackageMetadata `json:"packages,omitempty"`
 }
 
+// ComposeRejection defines model for ComposeRejection.
+type ComposeRejection struct {
+	Reason string `json:"reason"`
+}
+
 // ComposeRequest defines model for ComposeRequest.
 type ComposeRequest struct {
 	Customizations *Customizations `json:"customizations,omitempty"`
@@ -256,17 +744,30 @@ type ComposeRequest struct {
 
 	// ImageRequests Array of exactly one image request. Having more image requests in one compose is currently not supported.
 	ImageRequests []ImageRequest `json:"image_requests"`
+
+	// NotifyEmail Address the outcome of the compose is emailed to, only supported on deployments with email
+	// notifications configured.
+	NotifyEmail *string         `json:"notify_email,omitempty"`
+	Webhook     *WebhookRequest `json:"webhook,omitempty"`
 }
 
 // ComposeResponse defines model for ComposeResponse.
 type ComposeResponse struct {
 	Id openapi_types.UUID `json:"id"`
+
+	// Warnings Problems with the compose request which didn't stop it from being
+	// built, e.g. credentials found in its customizations.
+	Warnings *ComposeWarnings `json:"warnings,omitempty"`
 }
 
 // ComposeStatus defines model for ComposeStatus.
 type ComposeStatus struct {
-	ImageStatus ImageStatus    `json:"image_status"`
-	Request     ComposeRequest `json:"request"`
+	// CloneStatuses Statuses of the copies of the image into the additional regions
+	// requested in the upload options, sorted by region.
+	// Regions which aren't being copied to yet are pending.
+	CloneStatuses *[]UploadStatus `json:"clone_statuses,omitempty"`
+	ImageStatus   ImageStatus     `json:"image_status"`
+	Request       ComposeRequest  `json:"request"`
 }
 
 // ComposeStatusError defines model for ComposeStatusError.
@@ -276,6 +777,10 @@ type ComposeStatusError struct {
 	Reason  string       `json:"reason"`
 }
 
+// ComposeWarnings Problems with the compose request which didn't stop it from being
+// built, e.g. credentials found in its customizations.
+type ComposeWarnings = []string
+
 // ComposesResponse defines model for ComposesResponse.
 type ComposesResponse struct {
 	Data  []ComposesResponseItem `json:"data"`
@@ -296,6 +801,55 @@ type ComposesResponseItem struct {
 	Request   ComposeRequest     `json:"request"`
 }
 
+// ContainerUploadRequestOptions Push the resulting container image to a container registry. Only available
+// for image types which produce a container. The image is pushed with the
+// registry credentials of the build system.
+type ContainerUploadRequestOptions struct {
+	// Name Fully qualified name of the image to push, including the registry
+	Name string `json:"name"`
+
+	// Sign Sign the pushed image with cosign once the compose finished, with the key of
+	// the organization in the key store of the service, or keyless with a short lived
+	// certificate of the identity of the service. The signature is pushed with the
+	// registry credentials of the organization in the key store.
+	Sign *ContainerUploadRequestOptionsSign `json:"sign,omitempty"`
+
+	// Tag Tag of the image to push, defaults to latest
+	Tag *string `json:"tag,omitempty"`
+}
+
+// ContainerUploadRequestOptionsSign Sign the pushed image with cosign once the compose finished, with the key of
+// the organization in the key store of the service, or keyless with a short lived
+// certificate of the identity of the service. The signature is pushed with the
+// registry credentials of the organization in the key store.
+type ContainerUploadRequestOptionsSign string
+
+// ContainerUploadStatus defines model for ContainerUploadStatus.
+type ContainerUploadStatus struct {
+	// Digest Digest of the manifest of the pushed image
+	Digest string `json:"digest"`
+
+	// Signature Where the cosign signature of the image is stored, if it was asked to be signed
+	// and has been signed
+	Signature *string `json:"signature,omitempty"`
+
+	// SignatureError why the last attempt to sign the image failed
+	SignatureError *string `json:"signature_error,omitempty"`
+
+	// SignatureStatus Whether the image has been signed, if it was asked to be signed. Images are
+	// signed in the background, pending ones are attempted again, failed ones were
+	// given up on.
+	SignatureStatus *ContainerUploadStatusSignatureStatus `json:"signature_status,omitempty"`
+
+	// Url Fully qualified name of the pushed image, including the tag
+	Url string `json:"url"`
+}
+
+// ContainerUploadStatusSignatureStatus Whether the image has been signed, if it was asked to be signed. Images are
+// signed in the background, pending ones are attempted again, failed ones were
+// given up on.
+type ContainerUploadStatusSignatureStatus string
+
 // CustomRepository Repository configuration for custom repositories.
 // At least one of the 'baseurl', 'mirrorlist', 'metalink' properties must
 // be specified. If more of them are specified, the order of precedence is
@@ -321,8 +875,13 @@ type CustomRepository struct {
 type Customizations struct {
 	CustomRepositories *[]CustomRepository `json:"custom_repositories,omitempty"`
 	Filesystem         *[]Filesystem       `json:"filesystem,omitempty"`
-	Openscap           *OpenSCAP           `json:"openscap,omitempty"`
-	Packages           *[]string           `json:"packages,omitempty"`
+
+	// Installer Customizations for the installer of the image-installer and
+	// edge-installer image types. They are written to an embedded kickstart
+	// file.
+	Installer *Installer `json:"installer,omitempty"`
+	Openscap  *OpenSCAP  `json:"openscap,omitempty"`
+	Packages  *[]string  `json:"packages,omitempty"`
 
 	// PartitioningMode Select how the disk image will be partitioned. 'auto-lvm' will use raw unless
 	// there are one or more mountpoints in which case it will use LVM. 'lvm' always
@@ -342,6 +901,23 @@ type Customizations struct {
 // even when there are one or more mountpoints.
 type CustomizationsPartitioningMode string
 
+// DSSEEnvelope A DSSE envelope, see https://github.com/secure-systems-lab/dsse
+type DSSEEnvelope struct {
+	// Payload base64 encoded statement
+	Payload     string          `json:"payload"`
+	PayloadType string          `json:"payloadType"`
+	Signatures  []DSSESignature `json:"signatures"`
+}
+
+// DSSESignature defines model for DSSESignature.
+type DSSESignature struct {
+	// Keyid sha256 of the public key in PKIX encoding, hex encoded
+	Keyid string `json:"keyid"`
+
+	// Sig base64 encoded signature
+	Sig string `json:"sig"`
+}
+
 // DistributionItem defines model for DistributionItem.
 type DistributionItem struct {
 	Description string `json:"description"`
@@ -393,23 +969,68 @@ type GCPUploadStatus struct {
 	ProjectId string `json:"project_id"`
 }
 
+// GraphQLError defines model for GraphQLError.
+type GraphQLError struct {
+	Message string `json:"message"`
+
+	// Path the keys and indexes leading to the field
+	Path *[]interface{} `json:"path,omitempty"`
+}
+
+// GraphQLResponse defines model for GraphQLResponse.
+type GraphQLResponse struct {
+	Data   *map[string]interface{} `json:"data"`
+	Errors *[]GraphQLError         `json:"errors,omitempty"`
+}
+
 // HTTPError defines model for HTTPError.
 type HTTPError struct {
-	Detail string `json:"detail"`
-	Title  string `json:"title"`
+	// Code Machine readable reason of the error, only set for some errors.
+	Code   *string `json:"code,omitempty"`
+	Detail string  `json:"detail"`
+	Title  string  `json:"title"`
 }
 
-// HTTPErrorList defines model for HTTPErrorList.
+// HTTPErrorList Problem details (RFC 7807) of a failed request, served as application/problem+json. The errors
+// repeat the problem in the format of before, for existing clients.
 type HTTPErrorList struct {
+	// Code Machine readable reason of the error, which doesn't change, e.g. UPLOAD_TARGET_NOT_ALLOWED, or
+	// the phrase of the status, e.g. NOT_FOUND, for errors without a specific one.
+	Code string `json:"code"`
+
+	// Detail what went wrong, for humans
+	Detail string      `json:"detail"`
 	Errors []HTTPError `json:"errors"`
+
+	// InvalidParams the parts of the request which failed validation
+	InvalidParams *[]InvalidParam `json:"invalid_params,omitempty"`
+
+	// RequestId id of the request, to give to support
+	RequestId *string `json:"request_id,omitempty"`
+	Status    int     `json:"status"`
+
+	// Title the phrase of the status
+	Title string `json:"title"`
+
+	// Type always about:blank, the code tells problems apart
+	Type string `json:"type"`
+}
+
+// IPAllowList defines model for IPAllowList.
+type IPAllowList struct {
+	// Cidrs Networks in CIDR notation, single addresses are accepted as well.
+	Cidrs []string `json:"cidrs"`
 }
 
 // ImageRequest defines model for ImageRequest.
 type ImageRequest struct {
 	// Architecture CPU architecture of the image, x86_64 and aarch64 are currently supported.
 	Architecture ImageRequestArchitecture `json:"architecture"`
-	ImageType    ImageTypes               `json:"image_type"`
-	Ostree       *OSTree                  `json:"ostree,omitempty"`
+
+	// ImageType The aliases ami, rhel-edge-commit, rhel-edge-installer and vhd are deprecated, requests using them get a
+	// Deprecation header.
+	ImageType ImageTypes `json:"image_type"`
+	Ostree    *OSTree    `json:"ostree,omitempty"`
 
 	// Size Size of image, in bytes. When set to 0 the image size is a minimum
 	// defined by the image type.
@@ -422,22 +1043,141 @@ type ImageRequestArchitecture string
 
 // ImageStatus defines model for ImageStatus.
 type ImageStatus struct {
-	Error        *ComposeStatusError `json:"error,omitempty"`
-	Status       ImageStatusStatus   `json:"status"`
-	UploadStatus *UploadStatus       `json:"upload_status,omitempty"`
+	Error *ComposeStatusError `json:"error,omitempty"`
+
+	// Status Composes are 'queued' while the organization has as many builds
+	// in progress as its quota allows, they are built once others
+	// finish. Composes of organizations which require approvals are
+	// 'pending_approval' until a second user approves or rejects them.
+	Status       ImageStatusStatus `json:"status"`
+	UploadStatus *UploadStatus     `json:"upload_status,omitempty"`
 }
 
-// ImageStatusStatus defines model for ImageStatus.Status.
+// ImageStatusStatus Composes are 'queued' while the organization has as many builds
+// in progress as its quota allows, they are built once others
+// finish. Composes of organizations which require approvals are
+// 'pending_approval' until a second user approves or rejects them.
 type ImageStatusStatus string
 
-// ImageTypes defines model for ImageTypes.
+// ImageTypes The aliases ami, rhel-edge-commit, rhel-edge-installer and vhd are deprecated, requests using them get a
+// Deprecation header.
 type ImageTypes string
 
+// Installer Customizations for the installer of the image-installer and
+// edge-installer image types. They are written to an embedded kickstart
+// file.
+type Installer struct {
+	// SudoNopasswd Users and groups (prefixed with %) which are allowed to use sudo
+	// without a password.
+	SudoNopasswd *[]string `json:"sudo-nopasswd,omitempty"`
+
+	// Unattended Install the embedded payload without user interaction.
+	Unattended *bool `json:"unattended,omitempty"`
+}
+
+// InvalidParam defines model for InvalidParam.
+type InvalidParam struct {
+	// Name the parameter, or body for the request body
+	Name string `json:"name"`
+
+	// Pointer JSON pointer to the invalid value in the request body
+	Pointer *string `json:"pointer,omitempty"`
+	Reason  string  `json:"reason"`
+}
+
+// LaunchInstance defines model for LaunchInstance.
+type LaunchInstance struct {
+	Id          string  `json:"id"`
+	PrivateIpv4 *string `json:"private_ipv4,omitempty"`
+	PublicDns   *string `json:"public_dns,omitempty"`
+	PublicIpv4  *string `json:"public_ipv4,omitempty"`
+}
+
+// LaunchProvider Cloud the instances are launched in: aws, azure or gcp
+type LaunchProvider = string
+
+// LaunchRequest defines model for LaunchRequest.
+type LaunchRequest struct {
+	Amount *int `json:"amount,omitempty"`
+
+	// InstanceType Instance type on aws, instance size on azure, machine type on gcp
+	InstanceType *string `json:"instance_type,omitempty"`
+
+	// LaunchTemplateId Launch template of aws and gcp
+	LaunchTemplateId *string `json:"launch_template_id,omitempty"`
+
+	// Name Name of the instances, suffixed by the provider
+	Name *string `json:"name,omitempty"`
+
+	// Poweroff Power the instances off once they were launched
+	Poweroff *bool `json:"poweroff,omitempty"`
+
+	// PubkeyId Id of the public key of the provisioning service which is added
+	// to the instances
+	PubkeyId int64 `json:"pubkey_id"`
+
+	// Region Region on aws, location on azure, zone on gcp. The provisioning
+	// service picks the default of the provider if unset.
+	Region *string `json:"region,omitempty"`
+
+	// SourceId Source of the cloud account the instances are launched in
+	SourceId string `json:"source_id"`
+}
+
+// LaunchResponse defines model for LaunchResponse.
+type LaunchResponse struct {
+	ReservationId int64 `json:"reservation_id"`
+}
+
+// LaunchStatus defines model for LaunchStatus.
+type LaunchStatus struct {
+	Error     *string           `json:"error,omitempty"`
+	Instances *[]LaunchInstance `json:"instances,omitempty"`
+
+	// Provider Cloud the instances are launched in: aws, azure or gcp
+	Provider      LaunchProvider     `json:"provider"`
+	ReservationId int64              `json:"reservation_id"`
+	Status        LaunchStatusStatus `json:"status"`
+
+	// Step Step of the provisioning service the launch is at
+	Step      *int    `json:"step,omitempty"`
+	StepTitle *string `json:"step_title,omitempty"`
+	Steps     *int    `json:"steps,omitempty"`
+}
+
+// LaunchStatusStatus defines model for LaunchStatus.Status.
+type LaunchStatusStatus string
+
+// LaunchesResponse defines model for LaunchesResponse.
+type LaunchesResponse struct {
+	Data  []LaunchesResponseItem `json:"data"`
+	Links struct {
+		First string `json:"first"`
+		Last  string `json:"last"`
+	} `json:"links"`
+	Meta struct {
+		Count int `json:"count"`
+	} `json:"meta"`
+}
+
+// LaunchesResponseItem defines model for LaunchesResponseItem.
+type LaunchesResponseItem struct {
+	// ComposeId UUID of the compose which was launched
+	ComposeId openapi_types.UUID `json:"compose_id"`
+	CreatedAt string             `json:"created_at"`
+
+	// Provider Cloud the instances are launched in: aws, azure or gcp
+	Provider      LaunchProvider `json:"provider"`
+	Request       LaunchRequest  `json:"request"`
+	ReservationId int64          `json:"reservation_id"`
+}
+
 // OCIUploadRequestOptions defines model for OCIUploadRequestOptions.
 type OCIUploadRequestOptions = map[string]interface{}
 
 // OCIUploadStatus defines model for OCIUploadStatus.
 type OCIUploadStatus struct {
+	// Url Pre-authenticated request URL of the image in the Object Storage bucket.
 	Url string `json:"url"`
 }
 
@@ -454,7 +1194,14 @@ type OSTree struct {
 	// Rhsm Determines whether a valid subscription manager (candlepin) identity is required to
 	// access this repository. Consumer certificates will be used as client certificates when
 	// fetching metadata and content.
-	Rhsm *bool   `json:"rhsm,omitempty"`
+	Rhsm *bool `json:"rhsm,omitempty"`
+
+	// Sign Sign the commit with the private key of the artifact signing
+	// settings of the organization once it's built, so devices can
+	// verify updates with the public key of the settings. Only edge
+	// commits uploaded to aws.s3 can be signed, the signature is
+	// returned by /composes/{composeId}/commit-signature.
+	Sign *bool   `json:"sign,omitempty"`
 	Url  *string `json:"url,omitempty"`
 }
 
@@ -463,6 +1210,25 @@ type OpenSCAP struct {
 	ProfileId string `json:"profile_id"`
 }
 
+// OrgEvent defines model for OrgEvent.
+type OrgEvent struct {
+	CloneId   *openapi_types.UUID `json:"clone_id,omitempty"`
+	ComposeId openapi_types.UUID  `json:"compose_id"`
+
+	// Data The status of the compose or clone, 'created', 'success' or
+	// 'failure', with the reason of failures and the labels of created
+	// composes.
+	Data  map[string]interface{} `json:"data"`
+	Event OrgEventEvent          `json:"event"`
+
+	// Id increases with every event of the service
+	Id   int64  `json:"id"`
+	Time string `json:"time"`
+}
+
+// OrgEventEvent defines model for OrgEvent.Event.
+type OrgEventEvent string
+
 // Package defines model for Package.
 type Package struct {
 	Name    string `json:"name"`
@@ -493,25 +1259,194 @@ type PackagesResponse struct {
 	} `json:"meta"`
 }
 
-// Readiness defines model for Readiness.
-type Readiness struct {
-	Readiness string `json:"readiness"`
+// ProvenanceStatement An in-toto statement, see https://in-toto.io/Statement/v1, with a
+// predicate of type https://slsa.dev/provenance/v1
+type ProvenanceStatement struct {
+	Type          string                 `json:"_type"`
+	Predicate     map[string]interface{} `json:"predicate"`
+	PredicateType string                 `json:"predicateType"`
+	Subject       []ProvenanceSubject    `json:"subject"`
 }
 
-// Repository defines model for Repository.
-type Repository struct {
-	Baseurl  *string `json:"baseurl,omitempty"`
-	CheckGpg *bool   `json:"check_gpg,omitempty"`
+// ProvenanceSubject defines model for ProvenanceSubject.
+type ProvenanceSubject struct {
+	Digest map[string]string `json:"digest"`
+	Name   string            `json:"name"`
+}
 
-	// CheckRepoGpg Enables gpg verification of the repository metadata
-	CheckRepoGpg *bool   `json:"check_repo_gpg,omitempty"`
-	Gpgkey       *string `json:"gpgkey,omitempty"`
+// PulpOSTreeUploadRequestOptions Import the ostree commit into a Pulp instance. Options which are not
+// set are taken from the Pulp settings of the organization, the basepath
+// has to be set by either. The build system authenticates against Pulp
+// with the credentials it is configured with.
+type PulpOSTreeUploadRequestOptions struct {
+	// Basepath Base path of the distribution serving the repository
+	Basepath *string `json:"basepath,omitempty"`
+
+	// Repository Name of the repository to import the commit to, it gets created if
+	// it does not exist yet.
+	Repository *string `json:"repository,omitempty"`
+
+	// ServerAddress URL of the Pulp API server
+	ServerAddress *string `json:"server_address,omitempty"`
+}
+
+// PulpOSTreeUploadStatus defines model for PulpOSTreeUploadStatus.
+type PulpOSTreeUploadStatus struct {
+	// RepoUrl URL of the repository the commit was imported to.
+	RepoUrl string `json:"repo_url"`
+}
+
+// PulpSettings defines model for PulpSettings.
+type PulpSettings struct {
+	Basepath      *string `json:"basepath,omitempty"`
+	Repository    *string `json:"repository,omitempty"`
+	ServerAddress string  `json:"server_address"`
+	UpdatedAt     string  `json:"updated_at"`
+}
+
+// PulpSettingsRequest defines model for PulpSettingsRequest.
+type PulpSettingsRequest struct {
+	Basepath   *string `json:"basepath,omitempty"`
+	Repository *string `json:"repository,omitempty"`
+
+	// ServerAddress https url of the Pulp API server
+	ServerAddress string `json:"server_address"`
+}
+
+// Readiness defines model for Readiness.
+type Readiness struct {
+	// Checks Outcome of each dependency check, "ok" or what went wrong. Only
+	// set if the service isn't ready.
+	Checks    *map[string]string `json:"checks,omitempty"`
+	Readiness string             `json:"readiness"`
+}
+
+// Repository defines model for Repository.
+type Repository struct {
+	Baseurl  *string `json:"baseurl,omitempty"`
+	CheckGpg *bool   `json:"check_gpg,omitempty"`
+
+	// CheckRepoGpg Enables gpg verification of the repository metadata
+	CheckRepoGpg *bool   `json:"check_repo_gpg,omitempty"`
+	Gpgkey       *string `json:"gpgkey,omitempty"`
 	IgnoreSsl    *bool   `json:"ignore_ssl,omitempty"`
 	Metalink     *string `json:"metalink,omitempty"`
 	Mirrorlist   *string `json:"mirrorlist,omitempty"`
 	Rhsm         bool    `json:"rhsm"`
 }
 
+// RepositorySignatureSettings defines model for RepositorySignatureSettings.
+type RepositorySignatureSettings struct {
+	// RequireSignatures Whether the payload and custom repositories of composes have to
+	// set check_gpg and check_repo_gpg, and come with gpg keys which
+	// verify the signature of their repodata/repomd.xml. Compose
+	// requests with other repositories fail with 400.
+	RequireSignatures bool `json:"require_signatures"`
+}
+
+// SecretScanningSettings defines model for SecretScanningSettings.
+type SecretScanningSettings struct {
+	// Mode What happens to compose requests with credentials, like private
+	// keys or access tokens, in their customizations. With warn they
+	// are built and the response lists the credentials found, with
+	// reject they fail with 400. Organizations are warned by default.
+	Mode SecretScanningSettingsMode `json:"mode"`
+}
+
+// SecretScanningSettingsMode What happens to compose requests with credentials, like private
+// keys or access tokens, in their customizations. With warn they
+// are built and the response lists the credentials found, with
+// reject they fail with 400. Organizations are warned by default.
+type SecretScanningSettingsMode string
+
+// SigningKey defines model for SigningKey.
+type SigningKey struct {
+	CreatedAt time.Time `json:"created_at"`
+
+	// Fingerprint Fingerprint of OpenPGP keys
+	Fingerprint *string            `json:"fingerprint,omitempty"`
+	Id          openapi_types.UUID `json:"id"`
+
+	// PublicKey ASCII armored public key of OpenPGP keys
+	PublicKey *string `json:"public_key,omitempty"`
+
+	// Purpose What the key signs, artifacts and ostree commits of composes, or
+	// the events posted to webhooks. A signing_service key is the token
+	// the signing service of the artifact signing settings is called with.
+	Purpose SigningKeyPurpose `json:"purpose"`
+
+	// ReplacedBy Id of the key which replaced this one
+	ReplacedBy *openapi_types.UUID `json:"replaced_by,omitempty"`
+	RevokedAt  *time.Time          `json:"revoked_at,omitempty"`
+	RotatedAt  *time.Time          `json:"rotated_at,omitempty"`
+	Status     SigningKeyStatus    `json:"status"`
+}
+
+// SigningKeyStatus defines model for SigningKey.Status.
+type SigningKeyStatus string
+
+// SigningKeyMaterial defines model for SigningKeyMaterial.
+type SigningKeyMaterial struct {
+	// PrivateKey ASCII armored OpenPGP private key, of artifact and commit keys.
+	// It can't be protected by a passphrase.
+	PrivateKey *string `json:"private_key,omitempty"`
+
+	// Secret HMAC secret of webhook keys, of at least 16 characters, or the
+	// bearer token of signing_service keys
+	Secret *string `json:"secret,omitempty"`
+}
+
+// SigningKeyPurpose What the key signs, artifacts and ostree commits of composes, or
+// the events posted to webhooks. A signing_service key is the token
+// the signing service of the artifact signing settings is called with.
+type SigningKeyPurpose string
+
+// SigningKeyRequest defines model for SigningKeyRequest.
+type SigningKeyRequest struct {
+	// PrivateKey ASCII armored OpenPGP private key, of artifact and commit keys.
+	// It can't be protected by a passphrase.
+	PrivateKey *string `json:"private_key,omitempty"`
+
+	// Purpose What the key signs, artifacts and ostree commits of composes, or
+	// the events posted to webhooks. A signing_service key is the token
+	// the signing service of the artifact signing settings is called with.
+	Purpose SigningKeyPurpose `json:"purpose"`
+
+	// Secret HMAC secret of webhook keys, of at least 16 characters, or the
+	// bearer token of signing_service keys
+	Secret *string `json:"secret,omitempty"`
+}
+
+// SigningKeyUsage defines model for SigningKeyUsage.
+type SigningKeyUsage struct {
+	Operation SigningKeyUsageOperation `json:"operation"`
+
+	// ResourceId The compose whose artifact or commit was signed, or the compose
+	// or clone of the webhook event
+	ResourceId *openapi_types.UUID `json:"resource_id,omitempty"`
+	UsedAt     time.Time           `json:"used_at"`
+}
+
+// SigningKeyUsageOperation defines model for SigningKeyUsage.Operation.
+type SigningKeyUsageOperation string
+
+// SigningKeyUsageResponse defines model for SigningKeyUsageResponse.
+type SigningKeyUsageResponse struct {
+	Data  []SigningKeyUsage `json:"data"`
+	Links struct {
+		First string `json:"first"`
+		Last  string `json:"last"`
+	} `json:"links"`
+	Meta struct {
+		Count int `json:"count"`
+	} `json:"meta"`
+}
+
+// SigningKeysResponse defines model for SigningKeysResponse.
+type SigningKeysResponse struct {
+	Data []SigningKey `json:"data"`
+}
+
 // Subscription defines model for Subscription.
 type Subscription struct {
 	ActivationKey string `json:"activation-key"`
@@ -550,9 +1485,82 @@ type UploadStatus_Options struct {
 // UploadStatusStatus defines model for UploadStatus.Status.
 type UploadStatusStatus string
 
+// UploadTargetPolicy defines model for UploadTargetPolicy.
+type UploadTargetPolicy struct {
+	Targets []UploadTargetRule `json:"targets"`
+}
+
+// UploadTargetRule defines model for UploadTargetRule.
+type UploadTargetRule struct {
+	// Regions Regions images may be uploaded to, any region if empty. Only
+	// enforced for targets with a known region, i.e. aws, aws.s3,
+	// gcp and azure galleries.
+	Regions *[]string   `json:"regions,omitempty"`
+	Type    UploadTypes `json:"type"`
+}
+
 // UploadTypes defines model for UploadTypes.
 type UploadTypes string
 
+// Usage defines model for Usage.
+type Usage struct {
+	// BuildsInProgress Builds which haven't finished yet
+	BuildsInProgress int `json:"builds_in_progress"`
+
+	// BuildsQueued Builds waiting for builds in progress to finish, as the
+	// concurrent build limit has been reached
+	BuildsQueued int `json:"builds_queued"`
+
+	// BuildsThisMonth Builds started since the start of the month in UTC
+	BuildsThisMonth int `json:"builds_this_month"`
+
+	// BuildsToday Builds started since the start of the day in UTC
+	BuildsToday int `json:"builds_today"`
+
+	// Quota Limits which aren't set aren't enforced.
+	Quota UsageQuota `json:"quota"`
+
+	// StorageBytes Size of the artifacts of all images in bytes
+	StorageBytes int64 `json:"storage_bytes"`
+}
+
+// UsageQuota Limits which aren't set aren't enforced.
+type UsageQuota struct {
+	BuildsPerDay     *int `json:"builds_per_day,omitempty"`
+	BuildsPerMonth   *int `json:"builds_per_month,omitempty"`
+	ConcurrentBuilds *int `json:"concurrent_builds,omitempty"`
+
+	// DailyReset Time the daily build count resets
+	DailyReset string `json:"daily_reset"`
+
+	// MonthlyReset Time the monthly build count resets
+	MonthlyReset string `json:"monthly_reset"`
+}
+
+// UsageReport defines model for UsageReport.
+type UsageReport struct {
+	Data []UsageReportEntry `json:"data"`
+	From string             `json:"from"`
+	To   string             `json:"to"`
+}
+
+// UsageReportEntry Builds are accounted for in the month they were started, deleted
+// builds included.
+type UsageReportEntry struct {
+	// BuildMinutes Minutes from starting the builds until they finished, builds which
+	// haven't finished yet aren't accounted for
+	BuildMinutes float64 `json:"build_minutes"`
+	Builds       int     `json:"builds"`
+	Failed       int     `json:"failed"`
+	ImageType    string  `json:"image_type"`
+	Month        string  `json:"month"`
+
+	// StorageBytes Size of the artifacts of the builds in bytes
+	StorageBytes int64  `json:"storage_bytes"`
+	Succeeded    int    `json:"succeeded"`
+	UploadTarget string `json:"upload_target"`
+}
+
 // User defines model for User.
 type User struct {
 	Name   string `json:"name"`
@@ -564,6 +1572,123 @@ type Version struct {
 	Version string `json:"version"`
 }
 
+// VulnerabilitiesResponse defines model for VulnerabilitiesResponse.
+type VulnerabilitiesResponse struct {
+	// Data The findings, the most severe first
+	Data      []Vulnerability `json:"data"`
+	ScannedAt time.Time       `json:"scanned_at"`
+	Scanner   string          `json:"scanner"`
+
+	// Summary Number of findings of each severity
+	Summary VulnerabilitySummary `json:"summary"`
+}
+
+// Vulnerability defines model for Vulnerability.
+type Vulnerability struct {
+	// FixedVersion Version which fixes the vulnerability, if there's one
+	FixedVersion     *string               `json:"fixed_version,omitempty"`
+	Id               string                `json:"id"`
+	InstalledVersion string                `json:"installed_version"`
+	Package          string                `json:"package"`
+	Severity         VulnerabilitySeverity `json:"severity"`
+	Title            *string               `json:"title,omitempty"`
+
+	// Url Link to the advisory
+	Url *string `json:"url,omitempty"`
+}
+
+// VulnerabilitySeverity defines model for Vulnerability.Severity.
+type VulnerabilitySeverity string
+
+// VulnerabilitySummary Number of findings of each severity
+type VulnerabilitySummary struct {
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Low      int `json:"low"`
+	Medium   int `json:"medium"`
+	Unknown  int `json:"unknown"`
+}
+
+// Webhook defines model for Webhook.
+type Webhook struct {
+	// ComposeId The compose the webhook was registered for, it receives the
+	// events of all composes of the organization otherwise.
+	ComposeId *openapi_types.UUID `json:"compose_id,omitempty"`
+	CreatedAt string              `json:"created_at"`
+	Id        openapi_types.UUID  `json:"id"`
+	Url       string              `json:"url"`
+}
+
+// WebhookDeliveriesResponse defines model for WebhookDeliveriesResponse.
+type WebhookDeliveriesResponse struct {
+	Data  []WebhookDelivery `json:"data"`
+	Links struct {
+		First string `json:"first"`
+		Last  string `json:"last"`
+	} `json:"links"`
+	Meta struct {
+		Count int `json:"count"`
+	} `json:"meta"`
+}
+
+// WebhookDelivery defines model for WebhookDelivery.
+type WebhookDelivery struct {
+	Attempts    int     `json:"attempts"`
+	CreatedAt   string  `json:"created_at"`
+	DeliveredAt *string `json:"delivered_at,omitempty"`
+
+	// Error why the last attempt failed
+	Error *string              `json:"error,omitempty"`
+	Event WebhookDeliveryEvent `json:"event"`
+
+	// Id also sent in the X-Image-Builder-Delivery header
+	Id openapi_types.UUID `json:"id"`
+
+	// NextAttemptAt when a pending delivery is attempted again
+	NextAttemptAt *string `json:"next_attempt_at,omitempty"`
+
+	// ResourceId id of the compose or clone which finished
+	ResourceId openapi_types.UUID `json:"resource_id"`
+
+	// ResponseCode status the webhook responded with to the last attempt
+	ResponseCode *int `json:"response_code,omitempty"`
+
+	// Status Pending deliveries are attempted again, failed ones were given up
+	// on.
+	Status WebhookDeliveryStatus `json:"status"`
+}
+
+// WebhookDeliveryEvent defines model for WebhookDelivery.Event.
+type WebhookDeliveryEvent string
+
+// WebhookDeliveryStatus Pending deliveries are attempted again, failed ones were given up
+// on.
+type WebhookDeliveryStatus string
+
+// WebhookRequest defines model for WebhookRequest.
+type WebhookRequest struct {
+	// Secret Key of the HMAC-SHA256 signature of the events, it can not be
+	// retrieved again.
+	Secret string `json:"secret"`
+
+	// Url https url the events are posted to, its host has to resolve to public addresses
+	Url string `json:"url"`
+}
+
+// WebhooksResponse defines model for WebhooksResponse.
+type WebhooksResponse struct {
+	Data []Webhook `json:"data"`
+}
+
+// GetAuditLogParams defines parameters for GetAuditLog.
+type GetAuditLogParams struct {
+	// Limit max amount of entries, default 100
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Offset entries page offset, default 0
+	Offset *int `form:"offset,omitempty" json:"offset,omitempty"`
+}
+
 // GetComposesParams defines parameters for GetComposes.
 type GetComposesParams struct {
 	// Limit max amount of composes, default 100
@@ -576,6 +1701,26 @@ type GetComposesParams struct {
 	IgnoreImageTypes *[]ImageTypes `form:"ignoreImageTypes,omitempty" json:"ignoreImageTypes,omitempty"`
 }
 
+// ExportComposesParams defines parameters for ExportComposes.
+type ExportComposesParams struct {
+	// Format format of the export, default csv
+	Format *ExportComposesParamsFormat `form:"format,omitempty" json:"format,omitempty"`
+
+	// IgnoreImageTypes Filter the composes on image type. The filter is optional and can be specified multiple times.
+	IgnoreImageTypes *[]ImageTypes `form:"ignoreImageTypes,omitempty" json:"ignoreImageTypes,omitempty"`
+}
+
+// ExportComposesParamsFormat defines parameters for ExportComposes.
+type ExportComposesParamsFormat string
+
+// GetComposeStatusParams defines parameters for GetComposeStatus.
+type GetComposeStatusParams struct {
+	// Wait How long to wait for the status to change, as a duration like 30s
+	// or 2m, at most 2m. Statuses of finished composes are returned
+	// right away.
+	Wait *string `form:"wait,omitempty" json:"wait,omitempty"`
+}
+
 // GetComposeClonesParams defines parameters for GetComposeClones.
 type GetComposeClonesParams struct {
 	// Limit max amount of clones, default 100
@@ -585,6 +1730,52 @@ type GetComposeClonesParams struct {
 	Offset *int `form:"offset,omitempty" json:"offset,omitempty"`
 }
 
+// GetComposeLaunchesParams defines parameters for GetComposeLaunches.
+type GetComposeLaunchesParams struct {
+	// Limit max amount of launches, default 100
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Offset launches page offset, default 0
+	Offset *int `form:"offset,omitempty" json:"offset,omitempty"`
+}
+
+// GetComposeProvenanceParams defines parameters for GetComposeProvenance.
+type GetComposeProvenanceParams struct {
+	// Signed Return the provenance in a DSSE envelope signed by the key of
+	// the service
+	Signed *bool `form:"signed,omitempty" json:"signed,omitempty"`
+}
+
+// GetComposeSBOMParams defines parameters for GetComposeSBOM.
+type GetComposeSBOMParams struct {
+	// Format SPDX 2.3 or CycloneDX 1.5 JSON
+	Format *GetComposeSBOMParamsFormat `form:"format,omitempty" json:"format,omitempty"`
+}
+
+// GetComposeSBOMParamsFormat defines parameters for GetComposeSBOM.
+type GetComposeSBOMParamsFormat string
+
+// QueryGraphQLParams defines parameters for QueryGraphQL.
+type QueryGraphQLParams struct {
+	// Query the graphql document
+	Query string `form:"query" json:"query"`
+
+	// Variables the variables of the query, as a json object
+	Variables *string `form:"variables,omitempty" json:"variables,omitempty"`
+
+	// OperationName the operation to run of a document with several
+	OperationName *string `form:"operationName,omitempty" json:"operationName,omitempty"`
+}
+
+// GetSigningKeyUsageParams defines parameters for GetSigningKeyUsage.
+type GetSigningKeyUsageParams struct {
+	// Limit max amount of entries, default 100
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Offset entries page offset, default 0
+	Offset *int `form:"offset,omitempty" json:"offset,omitempty"`
+}
+
 // GetPackagesParams defines parameters for GetPackages.
 type GetPackagesParams struct {
 	// Distribution distribution to look up packages for
@@ -606,12 +1797,111 @@ type GetPackagesParams struct {
 // GetPackagesParamsArchitecture defines parameters for GetPackages.
 type GetPackagesParamsArchitecture string
 
+// GetUsageReportParams defines parameters for GetUsageReport.
+type GetUsageReportParams struct {
+	// From first month of the report, defaults to 11 months before to
+	From *string `form:"from,omitempty" json:"from,omitempty"`
+
+	// To last month of the report, defaults to the current month
+	To *string `form:"to,omitempty" json:"to,omitempty"`
+
+	// Format format of the report, default json
+	Format *GetUsageReportParamsFormat `form:"format,omitempty" json:"format,omitempty"`
+}
+
+// GetUsageReportParamsFormat defines parameters for GetUsageReport.
+type GetUsageReportParamsFormat string
+
+// GetAWXJobsParams defines parameters for GetAWXJobs.
+type GetAWXJobsParams struct {
+	// Limit max amount of jobs, default 100
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Offset jobs page offset, default 0
+	Offset *int `form:"offset,omitempty" json:"offset,omitempty"`
+}
+
+// GetComplianceExportsParams defines parameters for GetComplianceExports.
+type GetComplianceExportsParams struct {
+	// Limit max amount of exports, default 100
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Offset exports page offset, default 0
+	Offset *int `form:"offset,omitempty" json:"offset,omitempty"`
+}
+
+// GetBuildDurationsParams defines parameters for GetBuildDurations.
+type GetBuildDurationsParams struct {
+	// Window how far back builds are accounted for, default 30d
+	Window *GetBuildDurationsParamsWindow `form:"window,omitempty" json:"window,omitempty"`
+}
+
+// GetBuildDurationsParamsWindow defines parameters for GetBuildDurations.
+type GetBuildDurationsParamsWindow string
+
+// GetWebhookDeliveriesParams defines parameters for GetWebhookDeliveries.
+type GetWebhookDeliveriesParams struct {
+	// Limit max amount of deliveries, default 100
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Offset deliveries page offset, default 0
+	Offset *int `form:"offset,omitempty" json:"offset,omitempty"`
+}
+
 // ComposeImageJSONRequestBody defines body for ComposeImage for application/json ContentType.
 type ComposeImageJSONRequestBody = ComposeRequest
 
+// ComposeImageBatchJSONRequestBody defines body for ComposeImageBatch for application/json ContentType.
+type ComposeImageBatchJSONRequestBody = BatchComposeRequest
+
 // CloneComposeJSONRequestBody defines body for CloneCompose for application/json ContentType.
 type CloneComposeJSONRequestBody = CloneRequest
 
+// LaunchComposeJSONRequestBody defines body for LaunchCompose for application/json ContentType.
+type LaunchComposeJSONRequestBody = LaunchRequest
+
+// RejectComposeJSONRequestBody defines body for RejectCompose for application/json ContentType.
+type RejectComposeJSONRequestBody = ComposeRejection
+
+// CreateSigningKeyJSONRequestBody defines body for CreateSigningKey for application/json ContentType.
+type CreateSigningKeyJSONRequestBody = SigningKeyRequest
+
+// RotateSigningKeyJSONRequestBody defines body for RotateSigningKey for application/json ContentType.
+type RotateSigningKeyJSONRequestBody = SigningKeyMaterial
+
+// UpdateApprovalSettingsJSONRequestBody defines body for UpdateApprovalSettings for application/json ContentType.
+type UpdateApprovalSettingsJSONRequestBody = ApprovalSettings
+
+// UpdateArtifactSigningSettingsJSONRequestBody defines body for UpdateArtifactSigningSettings for application/json ContentType.
+type UpdateArtifactSigningSettingsJSONRequestBody = ArtifactSigningSettingsRequest
+
+// UpdateAWXSettingsJSONRequestBody defines body for UpdateAWXSettings for application/json ContentType.
+type UpdateAWXSettingsJSONRequestBody = AWXSettingsRequest
+
+// UpdateComplianceExportSettingsJSONRequestBody defines body for UpdateComplianceExportSettings for application/json ContentType.
+type UpdateComplianceExportSettingsJSONRequestBody = ComplianceExportSettingsRequest
+
+// UpdateIPAllowListJSONRequestBody defines body for UpdateIPAllowList for application/json ContentType.
+type UpdateIPAllowListJSONRequestBody = IPAllowList
+
+// UpdatePulpSettingsJSONRequestBody defines body for UpdatePulpSettings for application/json ContentType.
+type UpdatePulpSettingsJSONRequestBody = PulpSettingsRequest
+
+// UpdateRepositorySignatureSettingsJSONRequestBody defines body for UpdateRepositorySignatureSettings for application/json ContentType.
+type UpdateRepositorySignatureSettingsJSONRequestBody = RepositorySignatureSettings
+
+// UpdateSecretScanningSettingsJSONRequestBody defines body for UpdateSecretScanningSettings for application/json ContentType.
+type UpdateSecretScanningSettingsJSONRequestBody = SecretScanningSettings
+
+// UpdateUploadTargetPolicyJSONRequestBody defines body for UpdateUploadTargetPolicy for application/json ContentType.
+type UpdateUploadTargetPolicyJSONRequestBody = UploadTargetPolicy
+
+// CreateAPITokenJSONRequestBody defines body for CreateAPIToken for application/json ContentType.
+type CreateAPITokenJSONRequestBody = APITokenRequest
+
+// CreateWebhookJSONRequestBody defines body for CreateWebhook for application/json ContentType.
+type CreateWebhookJSONRequestBody = WebhookRequest
+
 // AsAWSEC2Clone returns the union data inside the CloneRequest as a AWSEC2Clone
 func (t CloneRequest) AsAWSEC2Clone() (AWSEC2Clone, error) {
 	var body AWSEC2Clone
@@ -778,6 +2068,58 @@ func (t *UploadRequest_Options) MergeOCIUploadRequestOptions(v OCIUploadRequestO
 	return err
 }
 
+// AsPulpOSTreeUploadRequestOptions returns the union data inside the UploadRequest_Options as a PulpOSTreeUploadRequestOptions
+func (t UploadRequest_Options) AsPulpOSTreeUploadRequestOptions() (PulpOSTreeUploadRequestOptions, error) {
+	var body PulpOSTreeUploadRequestOptions
+	err := json.Unmarshal(t.union, &body)
+	return body, err
+}
+
+// FromPulpOSTreeUploadRequestOptions overwrites any union data inside the UploadRequest_Options as the provided PulpOSTreeUploadRequestOptions
+func (t *UploadRequest_Options) FromPulpOSTreeUploadRequestOptions(v PulpOSTreeUploadRequestOptions) error {
+	b, err := json.Marshal(v)
+	t.union = b
+	return err
+}
+
+// MergePulpOSTreeUploadRequestOptions performs a merge with any union data inside the UploadRequest_Options, using the provided PulpOSTreeUploadRequestOptions
+func (t *UploadRequest_Options) MergePulpOSTreeUploadRequestOptions(v PulpOSTreeUploadRequestOptions) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	merged, err := runtime.JsonMerge(b, t.union)
+	t.union = merged
+	return err
+}
+
+// AsContainerUploadRequestOptions returns the union data inside the UploadRequest_Options as a ContainerUploadRequestOptions
+func (t UploadRequest_Options) AsContainerUploadRequestOptions() (ContainerUploadRequestOptions, error) {
+	var body ContainerUploadRequestOptions
+	err := json.Unmarshal(t.union, &body)
+	return body, err
+}
+
+// FromContainerUploadRequestOptions overwrites any union data inside the UploadRequest_Options as the provided ContainerUploadRequestOptions
+func (t *UploadRequest_Options) FromContainerUploadRequestOptions(v ContainerUploadRequestOptions) error {
+	b, err := json.Marshal(v)
+	t.union = b
+	return err
+}
+
+// MergeContainerUploadRequestOptions performs a merge with any union data inside the UploadRequest_Options, using the provided ContainerUploadRequestOptions
+func (t *UploadRequest_Options) MergeContainerUploadRequestOptions(v ContainerUploadRequestOptions) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	merged, err := runtime.JsonMerge(b, t.union)
+	t.union = merged
+	return err
+}
+
 func (t UploadRequest_Options) MarshalJSON() ([]byte, error) {
 	b, err := t.union.MarshalJSON()
 	return b, err
@@ -918,6 +2260,58 @@ func (t *UploadStatus_Options) MergeOCIUploadStatus(v OCIUploadStatus) error {
 	return err
 }
 
+// AsPulpOSTreeUploadStatus returns the union data inside the UploadStatus_Options as a PulpOSTreeUploadStatus
+func (t UploadStatus_Options) AsPulpOSTreeUploadStatus() (PulpOSTreeUploadStatus, error) {
+	var body PulpOSTreeUploadStatus
+	err := json.Unmarshal(t.union, &body)
+	return body, err
+}
+
+// FromPulpOSTreeUploadStatus overwrites any union data inside the UploadStatus_Options as the provided PulpOSTreeUploadStatus
+func (t *UploadStatus_Options) FromPulpOSTreeUploadStatus(v PulpOSTreeUploadStatus) error {
+	b, err := json.Marshal(v)
+	t.union = b
+	return err
+}
+
+// MergePulpOSTreeUploadStatus performs a merge with any union data inside the UploadStatus_Options, using the provided PulpOSTreeUploadStatus
+func (t *UploadStatus_Options) MergePulpOSTreeUploadStatus(v PulpOSTreeUploadStatus) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	merged, err := runtime.JsonMerge(b, t.union)
+	t.union = merged
+	return err
+}
+
+// AsContainerUploadStatus returns the union data inside the UploadStatus_Options as a ContainerUploadStatus
+func (t UploadStatus_Options) AsContainerUploadStatus() (ContainerUploadStatus, error) {
+	var body ContainerUploadStatus
+	err := json.Unmarshal(t.union, &body)
+	return body, err
+}
+
+// FromContainerUploadStatus overwrites any union data inside the UploadStatus_Options as the provided ContainerUploadStatus
+func (t *UploadStatus_Options) FromContainerUploadStatus(v ContainerUploadStatus) error {
+	b, err := json.Marshal(v)
+	t.union = b
+	return err
+}
+
+// MergeContainerUploadStatus performs a merge with any union data inside the UploadStatus_Options, using the provided ContainerUploadStatus
+func (t *UploadStatus_Options) MergeContainerUploadStatus(v ContainerUploadStatus) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	merged, err := runtime.JsonMerge(b, t.union)
+	t.union = merged
+	return err
+}
+
 func (t UploadStatus_Options) MarshalJSON() ([]byte, error) {
 	b, err := t.union.MarshalJSON()
 	return b, err
@@ -933,6 +2327,9 @@ type ServerInterface interface {
 	// get the architectures and their image types available for a given distribution
 	// (GET /architectures/{distribution})
 	GetArchitectures(ctx echo.Context, distribution Distributions) error
+	// get the audit log of the organization
+	// (GET /audit)
+	GetAuditLog(ctx echo.Context, params GetAuditLogParams) error
 	// get status of a compose clone
 	// (GET /clones/{id})
 	GetCloneStatus(ctx echo.Context, id openapi_types.UUID) error
@@ -942,24 +2339,84 @@ type ServerInterface interface {
 	// get a collection of previous compose requests for the logged in user
 	// (GET /composes)
 	GetComposes(ctx echo.Context, params GetComposesParams) error
+	// compose several images in one request
+	// (POST /composes/batch)
+	ComposeImageBatch(ctx echo.Context) error
+	// export the compose history of the organization
+	// (GET /composes/export)
+	ExportComposes(ctx echo.Context, params ExportComposesParams) error
 	// delete a compose
 	// (DELETE /composes/{composeId})
 	DeleteCompose(ctx echo.Context, composeId openapi_types.UUID) error
 	// get status of an image compose
 	// (GET /composes/{composeId})
-	GetComposeStatus(ctx echo.Context, composeId openapi_types.UUID) error
+	GetComposeStatus(ctx echo.Context, composeId openapi_types.UUID, params GetComposeStatusParams) error
+	// approve a compose pending approval
+	// (POST /composes/{composeId}/approve)
+	ApproveCompose(ctx echo.Context, composeId openapi_types.UUID) error
+	// get the artifacts of a compose
+	// (GET /composes/{composeId}/artifacts)
+	GetComposeArtifacts(ctx echo.Context, composeId openapi_types.UUID) error
 	// clone a compose
 	// (POST /composes/{composeId}/clone)
 	CloneCompose(ctx echo.Context, composeId openapi_types.UUID) error
 	// get clones of a compose
 	// (GET /composes/{composeId}/clones)
 	GetComposeClones(ctx echo.Context, composeId openapi_types.UUID, params GetComposeClonesParams) error
+	// get the detached metadata with the signature of an ostree commit
+	// (GET /composes/{composeId}/commit-signature)
+	GetComposeCommitSignature(ctx echo.Context, composeId openapi_types.UUID) error
+	// get the status history of a compose
+	// (GET /composes/{composeId}/events)
+	GetComposeEvents(ctx echo.Context, composeId openapi_types.UUID) error
+	// launch instances of a compose
+	// (POST /composes/{composeId}/launch)
+	LaunchCompose(ctx echo.Context, composeId openapi_types.UUID) error
+	// get launches of a compose
+	// (GET /composes/{composeId}/launches)
+	GetComposeLaunches(ctx echo.Context, composeId openapi_types.UUID, params GetComposeLaunchesParams) error
 	// get metadata of an image compose
 	// (GET /composes/{composeId}/metadata)
 	GetComposeMetadata(ctx echo.Context, composeId openapi_types.UUID) error
+	// get the provenance of a compose
+	// (GET /composes/{composeId}/provenance)
+	GetComposeProvenance(ctx echo.Context, composeId openapi_types.UUID, params GetComposeProvenanceParams) error
+	// reject a compose pending approval
+	// (POST /composes/{composeId}/reject)
+	RejectCompose(ctx echo.Context, composeId openapi_types.UUID) error
+	// get the software bill of materials of a compose
+	// (GET /composes/{composeId}/sbom)
+	GetComposeSBOM(ctx echo.Context, composeId openapi_types.UUID, params GetComposeSBOMParams) error
+	// get the vulnerabilities found in the packages of a compose
+	// (GET /composes/{composeId}/vulnerabilities)
+	GetComposeVulnerabilities(ctx echo.Context, composeId openapi_types.UUID) error
 	// get the distributions available to this user
 	// (GET /distributions)
 	GetDistributions(ctx echo.Context) error
+	// query the composes and clones of the organization with graphql
+	// (GET /graphql)
+	QueryGraphQL(ctx echo.Context, params QueryGraphQLParams) error
+	// get the signing keys of the organization
+	// (GET /keys)
+	GetSigningKeys(ctx echo.Context) error
+	// upload a signing key
+	// (POST /keys)
+	CreateSigningKey(ctx echo.Context) error
+	// get a signing key
+	// (GET /keys/{id})
+	GetSigningKey(ctx echo.Context, id openapi_types.UUID) error
+	// revoke a signing key
+	// (POST /keys/{id}/revoke)
+	RevokeSigningKey(ctx echo.Context, id openapi_types.UUID) error
+	// replace a signing key by a new one
+	// (POST /keys/{id}/rotate)
+	RotateSigningKey(ctx echo.Context, id openapi_types.UUID) error
+	// get what a signing key signed
+	// (GET /keys/{id}/usage)
+	GetSigningKeyUsage(ctx echo.Context, id openapi_types.UUID, params GetSigningKeyUsageParams) error
+	// get status of a launch
+	// (GET /launches/{reservationId})
+	GetLaunchStatus(ctx echo.Context, reservationId int64) error
 	// get the openapi json specification
 	// (GET /openapi.json)
 	GetOpenapiJson(ctx echo.Context) error
@@ -975,9 +2432,114 @@ type ServerInterface interface {
 	// return the readiness
 	// (GET /ready)
 	GetReadiness(ctx echo.Context) error
+	// get the usage report of the organization
+	// (GET /reports/usage)
+	GetUsageReport(ctx echo.Context, params GetUsageReportParams) error
+	// get the approval settings of the organization
+	// (GET /settings/approvals)
+	GetApprovalSettings(ctx echo.Context) error
+	// replace the approval settings of the organization
+	// (PUT /settings/approvals)
+	UpdateApprovalSettings(ctx echo.Context) error
+	// stop signing the artifacts of the organization
+	// (DELETE /settings/artifact-signing)
+	DeleteArtifactSigningSettings(ctx echo.Context) error
+	// get the artifact signing settings of the organization
+	// (GET /settings/artifact-signing)
+	GetArtifactSigningSettings(ctx echo.Context) error
+	// replace the artifact signing settings of the organization
+	// (PUT /settings/artifact-signing)
+	UpdateArtifactSigningSettings(ctx echo.Context) error
+	// remove the awx job template of the organization
+	// (DELETE /settings/awx)
+	DeleteAWXSettings(ctx echo.Context) error
+	// get the awx job template of the organization
+	// (GET /settings/awx)
+	GetAWXSettings(ctx echo.Context) error
+	// replace the awx job template of the organization
+	// (PUT /settings/awx)
+	UpdateAWXSettings(ctx echo.Context) error
+	// get the awx jobs launched for composes
+	// (GET /settings/awx/jobs)
+	GetAWXJobs(ctx echo.Context, params GetAWXJobsParams) error
+	// stop exporting the audit log and provenance
+	// (DELETE /settings/compliance-export)
+	DeleteComplianceExportSettings(ctx echo.Context) error
+	// get the compliance export settings of the organization
+	// (GET /settings/compliance-export)
+	GetComplianceExportSettings(ctx echo.Context) error
+	// replace the compliance export settings of the organization
+	// (PUT /settings/compliance-export)
+	UpdateComplianceExportSettings(ctx echo.Context) error
+	// get the exported windows
+	// (GET /settings/compliance-export/exports)
+	GetComplianceExports(ctx echo.Context, params GetComplianceExportsParams) error
+	// get the ip allow list of the organization
+	// (GET /settings/ip-allowlist)
+	GetIPAllowList(ctx echo.Context) error
+	// replace the ip allow list of the organization
+	// (PUT /settings/ip-allowlist)
+	UpdateIPAllowList(ctx echo.Context) error
+	// remove the pulp settings of the organization
+	// (DELETE /settings/pulp)
+	DeletePulpSettings(ctx echo.Context) error
+	// get the pulp settings of the organization
+	// (GET /settings/pulp)
+	GetPulpSettings(ctx echo.Context) error
+	// replace the pulp settings of the organization
+	// (PUT /settings/pulp)
+	UpdatePulpSettings(ctx echo.Context) error
+	// get the repository signature settings of the organization
+	// (GET /settings/repository-signatures)
+	GetRepositorySignatureSettings(ctx echo.Context) error
+	// replace the repository signature settings of the organization
+	// (PUT /settings/repository-signatures)
+	UpdateRepositorySignatureSettings(ctx echo.Context) error
+	// get the secret scanning settings of the organization
+	// (GET /settings/secret-scanning)
+	GetSecretScanningSettings(ctx echo.Context) error
+	// replace the secret scanning settings of the organization
+	// (PUT /settings/secret-scanning)
+	UpdateSecretScanningSettings(ctx echo.Context) error
+	// get the upload target policy of the organization
+	// (GET /settings/upload-targets)
+	GetUploadTargetPolicy(ctx echo.Context) error
+	// replace the upload target policy of the organization
+	// (PUT /settings/upload-targets)
+	UpdateUploadTargetPolicy(ctx echo.Context) error
+	// get statistics of how long builds take
+	// (GET /stats/build-durations)
+	GetBuildDurations(ctx echo.Context, params GetBuildDurationsParams) error
+	// get the api tokens of the organization
+	// (GET /tokens)
+	GetAPITokens(ctx echo.Context) error
+	// create an api token
+	// (POST /tokens)
+	CreateAPIToken(ctx echo.Context) error
+	// revoke an api token
+	// (DELETE /tokens/{id})
+	RevokeAPIToken(ctx echo.Context, id openapi_types.UUID) error
+	// get the quota and current usage of the organization
+	// (GET /usage)
+	GetUsage(ctx echo.Context) error
 	// get the service version
 	// (GET /version)
 	GetVersion(ctx echo.Context) error
+	// get the webhooks of the organization
+	// (GET /webhooks)
+	GetWebhooks(ctx echo.Context) error
+	// register a webhook
+	// (POST /webhooks)
+	CreateWebhook(ctx echo.Context) error
+	// remove a webhook
+	// (DELETE /webhooks/{id})
+	DeleteWebhook(ctx echo.Context, id openapi_types.UUID) error
+	// get the deliveries of a webhook
+	// (GET /webhooks/{id}/deliveries)
+	GetWebhookDeliveries(ctx echo.Context, id openapi_types.UUID, params GetWebhookDeliveriesParams) error
+	// stream the events of the composes and clones of the organization
+	// (GET /ws)
+	StreamEvents(ctx echo.Context) error
 }
 
 // ServerInterfaceWrapper converts echo contexts to parameters.
@@ -1001,11 +2563,36 @@ func (w *ServerInterfaceWrapper) GetArchitectures(ctx echo.Context) error {
 	return err
 }
 
-// GetCloneStatus converts echo context to params.
-func (w *ServerInterfaceWrapper) GetCloneStatus(ctx echo.Context) error {
+// GetAuditLog converts echo context to params.
+func (w *ServerInterfaceWrapper) GetAuditLog(ctx echo.Context) error {
 	var err error
-	// ------------- Path parameter "id" -------------
-	var id openapi_types.UUID
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetAuditLogParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "offset" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "offset", ctx.QueryParams(), &params.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter offset: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetAuditLog(ctx, params)
+	return err
+}
+
+// GetCloneStatus converts echo context to params.
+func (w *ServerInterfaceWrapper) GetCloneStatus(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id openapi_types.UUID
 
 	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
 	if err != nil {
@@ -1058,6 +2645,40 @@ func (w *ServerInterfaceWrapper) GetComposes(ctx echo.Context) error {
 	return err
 }
 
+// ComposeImageBatch converts echo context to params.
+func (w *ServerInterfaceWrapper) ComposeImageBatch(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ComposeImageBatch(ctx)
+	return err
+}
+
+// ExportComposes converts echo context to params.
+func (w *ServerInterfaceWrapper) ExportComposes(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ExportComposesParams
+	// ------------- Optional query parameter "format" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "format", ctx.QueryParams(), &params.Format)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter format: %s", err))
+	}
+
+	// ------------- Optional query parameter "ignoreImageTypes" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "ignoreImageTypes", ctx.QueryParams(), &params.IgnoreImageTypes)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter ignoreImageTypes: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ExportComposes(ctx, params)
+	return err
+}
+
 // DeleteCompose converts echo context to params.
 func (w *ServerInterfaceWrapper) DeleteCompose(ctx echo.Context) error {
 	var err error
@@ -1085,8 +2706,49 @@ func (w *ServerInterfaceWrapper) GetComposeStatus(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter composeId: %s", err))
 	}
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetComposeStatusParams
+	// ------------- Optional query parameter "wait" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "wait", ctx.QueryParams(), &params.Wait)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter wait: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetComposeStatus(ctx, composeId, params)
+	return err
+}
+
+// ApproveCompose converts echo context to params.
+func (w *ServerInterfaceWrapper) ApproveCompose(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "composeId" -------------
+	var composeId openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "composeId", runtime.ParamLocationPath, ctx.Param("composeId"), &composeId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter composeId: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ApproveCompose(ctx, composeId)
+	return err
+}
+
+// GetComposeArtifacts converts echo context to params.
+func (w *ServerInterfaceWrapper) GetComposeArtifacts(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "composeId" -------------
+	var composeId openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "composeId", runtime.ParamLocationPath, ctx.Param("composeId"), &composeId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter composeId: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.GetComposeStatus(ctx, composeId)
+	err = w.Handler.GetComposeArtifacts(ctx, composeId)
 	return err
 }
 
@@ -1138,8 +2800,8 @@ func (w *ServerInterfaceWrapper) GetComposeClones(ctx echo.Context) error {
 	return err
 }
 
-// GetComposeMetadata converts echo context to params.
-func (w *ServerInterfaceWrapper) GetComposeMetadata(ctx echo.Context) error {
+// GetComposeCommitSignature converts echo context to params.
+func (w *ServerInterfaceWrapper) GetComposeCommitSignature(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "composeId" -------------
 	var composeId openapi_types.UUID
@@ -1150,129 +2812,853 @@ func (w *ServerInterfaceWrapper) GetComposeMetadata(ctx echo.Context) error {
 	}
 
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.GetComposeMetadata(ctx, composeId)
+	err = w.Handler.GetComposeCommitSignature(ctx, composeId)
 	return err
 }
 
-// GetDistributions converts echo context to params.
-func (w *ServerInterfaceWrapper) GetDistributions(ctx echo.Context) error {
+// GetComposeEvents converts echo context to params.
+func (w *ServerInterfaceWrapper) GetComposeEvents(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "composeId" -------------
+	var composeId openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "composeId", runtime.ParamLocationPath, ctx.Param("composeId"), &composeId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter composeId: %s", err))
+	}
 
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.GetDistributions(ctx)
+	err = w.Handler.GetComposeEvents(ctx, composeId)
 	return err
 }
 
-// GetOpenapiJson converts echo context to params.
-func (w *ServerInterfaceWrapper) GetOpenapiJson(ctx echo.Context) error {
+// LaunchCompose converts echo context to params.
+func (w *ServerInterfaceWrapper) LaunchCompose(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "composeId" -------------
+	var composeId openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "composeId", runtime.ParamLocationPath, ctx.Param("composeId"), &composeId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter composeId: %s", err))
+	}
 
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.GetOpenapiJson(ctx)
+	err = w.Handler.LaunchCompose(ctx, composeId)
 	return err
 }
 
-// GetOscapProfiles converts echo context to params.
-func (w *ServerInterfaceWrapper) GetOscapProfiles(ctx echo.Context) error {
+// GetComposeLaunches converts echo context to params.
+func (w *ServerInterfaceWrapper) GetComposeLaunches(ctx echo.Context) error {
 	var err error
-	// ------------- Path parameter "distribution" -------------
-	var distribution Distributions
+	// ------------- Path parameter "composeId" -------------
+	var composeId openapi_types.UUID
 
-	err = runtime.BindStyledParameterWithLocation("simple", false, "distribution", runtime.ParamLocationPath, ctx.Param("distribution"), &distribution)
+	err = runtime.BindStyledParameterWithLocation("simple", false, "composeId", runtime.ParamLocationPath, ctx.Param("composeId"), &composeId)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter distribution: %s", err))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter composeId: %s", err))
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetComposeLaunchesParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "offset" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "offset", ctx.QueryParams(), &params.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter offset: %s", err))
 	}
 
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.GetOscapProfiles(ctx, distribution)
+	err = w.Handler.GetComposeLaunches(ctx, composeId, params)
 	return err
 }
 
-// GetOscapCustomizations converts echo context to params.
-func (w *ServerInterfaceWrapper) GetOscapCustomizations(ctx echo.Context) error {
+// GetComposeMetadata converts echo context to params.
+func (w *ServerInterfaceWrapper) GetComposeMetadata(ctx echo.Context) error {
 	var err error
-	// ------------- Path parameter "distribution" -------------
-	var distribution Distributions
+	// ------------- Path parameter "composeId" -------------
+	var composeId openapi_types.UUID
 
-	err = runtime.BindStyledParameterWithLocation("simple", false, "distribution", runtime.ParamLocationPath, ctx.Param("distribution"), &distribution)
+	err = runtime.BindStyledParameterWithLocation("simple", false, "composeId", runtime.ParamLocationPath, ctx.Param("composeId"), &composeId)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter distribution: %s", err))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter composeId: %s", err))
 	}
 
-	// ------------- Path parameter "profile" -------------
-	var profile DistributionProfileItem
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetComposeMetadata(ctx, composeId)
+	return err
+}
 
-	err = runtime.BindStyledParameterWithLocation("simple", false, "profile", runtime.ParamLocationPath, ctx.Param("profile"), &profile)
+// GetComposeProvenance converts echo context to params.
+func (w *ServerInterfaceWrapper) GetComposeProvenance(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "composeId" -------------
+	var composeId openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "composeId", runtime.ParamLocationPath, ctx.Param("composeId"), &composeId)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter profile: %s", err))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter composeId: %s", err))
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetComposeProvenanceParams
+	// ------------- Optional query parameter "signed" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "signed", ctx.QueryParams(), &params.Signed)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter signed: %s", err))
 	}
 
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.GetOscapCustomizations(ctx, distribution, profile)
+	err = w.Handler.GetComposeProvenance(ctx, composeId, params)
 	return err
 }
 
-// GetPackages converts echo context to params.
-func (w *ServerInterfaceWrapper) GetPackages(ctx echo.Context) error {
+// RejectCompose converts echo context to params.
+func (w *ServerInterfaceWrapper) RejectCompose(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "composeId" -------------
+	var composeId openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "composeId", runtime.ParamLocationPath, ctx.Param("composeId"), &composeId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter composeId: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RejectCompose(ctx, composeId)
+	return err
+}
+
+// GetComposeSBOM converts echo context to params.
+func (w *ServerInterfaceWrapper) GetComposeSBOM(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "composeId" -------------
+	var composeId openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "composeId", runtime.ParamLocationPath, ctx.Param("composeId"), &composeId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter composeId: %s", err))
+	}
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params GetPackagesParams
-	// ------------- Required query parameter "distribution" -------------
+	var params GetComposeSBOMParams
+	// ------------- Optional query parameter "format" -------------
 
-	err = runtime.BindQueryParameter("form", true, true, "distribution", ctx.QueryParams(), &params.Distribution)
+	err = runtime.BindQueryParameter("form", true, false, "format", ctx.QueryParams(), &params.Format)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter distribution: %s", err))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter format: %s", err))
 	}
 
-	// ------------- Required query parameter "architecture" -------------
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetComposeSBOM(ctx, composeId, params)
+	return err
+}
 
-	err = runtime.BindQueryParameter("form", true, true, "architecture", ctx.QueryParams(), &params.Architecture)
+// GetComposeVulnerabilities converts echo context to params.
+func (w *ServerInterfaceWrapper) GetComposeVulnerabilities(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "composeId" -------------
+	var composeId openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "composeId", runtime.ParamLocationPath, ctx.Param("composeId"), &composeId)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter architecture: %s", err))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter composeId: %s", err))
 	}
 
-	// ------------- Required query parameter "search" -------------
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetComposeVulnerabilities(ctx, composeId)
+	return err
+}
 
-	err = runtime.BindQueryParameter("form", true, true, "search", ctx.QueryParams(), &params.Search)
+// GetDistributions converts echo context to params.
+func (w *ServerInterfaceWrapper) GetDistributions(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetDistributions(ctx)
+	return err
+}
+
+// QueryGraphQL converts echo context to params.
+func (w *ServerInterfaceWrapper) QueryGraphQL(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params QueryGraphQLParams
+	// ------------- Required query parameter "query" -------------
+
+	err = runtime.BindQueryParameter("form", true, true, "query", ctx.QueryParams(), &params.Query)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter search: %s", err))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter query: %s", err))
 	}
 
-	// ------------- Optional query parameter "limit" -------------
+	// ------------- Optional query parameter "variables" -------------
 
-	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	err = runtime.BindQueryParameter("form", true, false, "variables", ctx.QueryParams(), &params.Variables)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter variables: %s", err))
 	}
 
-	// ------------- Optional query parameter "offset" -------------
+	// ------------- Optional query parameter "operationName" -------------
 
-	err = runtime.BindQueryParameter("form", true, false, "offset", ctx.QueryParams(), &params.Offset)
+	err = runtime.BindQueryParameter("form", true, false, "operationName", ctx.QueryParams(), &params.OperationName)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter offset: %s", err))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter operationName: %s", err))
 	}
 
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.GetPackages(ctx, params)
+	err = w.Handler.QueryGraphQL(ctx, params)
 	return err
 }
 
-// GetReadiness converts echo context to params.
-func (w *ServerInterfaceWrapper) GetReadiness(ctx echo.Context) error {
+// GetSigningKeys converts echo context to params.
+func (w *ServerInterfaceWrapper) GetSigningKeys(ctx echo.Context) error {
 	var err error
 
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.GetReadiness(ctx)
+	err = w.Handler.GetSigningKeys(ctx)
 	return err
 }
 
-// GetVersion converts echo context to params.
-func (w *ServerInterfaceWrapper) GetVersion(ctx echo.Context) error {
+// CreateSigningKey converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateSigningKey(ctx echo.Context) error {
 	var err error
 
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.GetVersion(ctx)
+	err = w.Handler.CreateSigningKey(ctx)
+	return err
+}
+
+// GetSigningKey converts echo context to params.
+func (w *ServerInterfaceWrapper) GetSigningKey(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetSigningKey(ctx, id)
+	return err
+}
+
+// RevokeSigningKey converts echo context to params.
+func (w *ServerInterfaceWrapper) RevokeSigningKey(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RevokeSigningKey(ctx, id)
+	return err
+}
+
+// RotateSigningKey converts echo context to params.
+func (w *ServerInterfaceWrapper) RotateSigningKey(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RotateSigningKey(ctx, id)
+	return err
+}
+
+// GetSigningKeyUsage converts echo context to params.
+func (w *ServerInterfaceWrapper) GetSigningKeyUsage(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetSigningKeyUsageParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "offset" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "offset", ctx.QueryParams(), &params.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter offset: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetSigningKeyUsage(ctx, id, params)
+	return err
+}
+
+// GetLaunchStatus converts echo context to params.
+func (w *ServerInterfaceWrapper) GetLaunchStatus(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "reservationId" -------------
+	var reservationId int64
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "reservationId", runtime.ParamLocationPath, ctx.Param("reservationId"), &reservationId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter reservationId: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetLaunchStatus(ctx, reservationId)
+	return err
+}
+
+// GetOpenapiJson converts echo context to params.
+func (w *ServerInterfaceWrapper) GetOpenapiJson(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetOpenapiJson(ctx)
+	return err
+}
+
+// GetOscapProfiles converts echo context to params.
+func (w *ServerInterfaceWrapper) GetOscapProfiles(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "distribution" -------------
+	var distribution Distributions
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "distribution", runtime.ParamLocationPath, ctx.Param("distribution"), &distribution)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter distribution: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetOscapProfiles(ctx, distribution)
+	return err
+}
+
+// GetOscapCustomizations converts echo context to params.
+func (w *ServerInterfaceWrapper) GetOscapCustomizations(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "distribution" -------------
+	var distribution Distributions
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "distribution", runtime.ParamLocationPath, ctx.Param("distribution"), &distribution)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter distribution: %s", err))
+	}
+
+	// ------------- Path parameter "profile" -------------
+	var profile DistributionProfileItem
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "profile", runtime.ParamLocationPath, ctx.Param("profile"), &profile)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter profile: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetOscapCustomizations(ctx, distribution, profile)
+	return err
+}
+
+// GetPackages converts echo context to params.
+func (w *ServerInterfaceWrapper) GetPackages(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetPackagesParams
+	// ------------- Required query parameter "distribution" -------------
+
+	err = runtime.BindQueryParameter("form", true, true, "distribution", ctx.QueryParams(), &params.Distribution)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter distribution: %s", err))
+	}
+
+	// ------------- Required query parameter "architecture" -------------
+
+	err = runtime.BindQueryParameter("form", true, true, "architecture", ctx.QueryParams(), &params.Architecture)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter architecture: %s", err))
+	}
+
+	// ------------- Required query parameter "search" -------------
+
+	err = runtime.BindQueryParameter("form", true, true, "search", ctx.QueryParams(), &params.Search)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter search: %s", err))
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "offset" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "offset", ctx.QueryParams(), &params.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter offset: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetPackages(ctx, params)
+	return err
+}
+
+// GetReadiness converts echo context to params.
+func (w *ServerInterfaceWrapper) GetReadiness(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetReadiness(ctx)
+	return err
+}
+
+// GetUsageReport converts echo context to params.
+func (w *ServerInterfaceWrapper) GetUsageReport(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetUsageReportParams
+	// ------------- Optional query parameter "from" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "from", ctx.QueryParams(), &params.From)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter from: %s", err))
+	}
+
+	// ------------- Optional query parameter "to" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "to", ctx.QueryParams(), &params.To)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter to: %s", err))
+	}
+
+	// ------------- Optional query parameter "format" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "format", ctx.QueryParams(), &params.Format)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter format: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetUsageReport(ctx, params)
+	return err
+}
+
+// GetApprovalSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) GetApprovalSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetApprovalSettings(ctx)
+	return err
+}
+
+// UpdateApprovalSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdateApprovalSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.UpdateApprovalSettings(ctx)
+	return err
+}
+
+// DeleteArtifactSigningSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteArtifactSigningSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.DeleteArtifactSigningSettings(ctx)
+	return err
+}
+
+// GetArtifactSigningSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) GetArtifactSigningSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetArtifactSigningSettings(ctx)
+	return err
+}
+
+// UpdateArtifactSigningSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdateArtifactSigningSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.UpdateArtifactSigningSettings(ctx)
+	return err
+}
+
+// DeleteAWXSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteAWXSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.DeleteAWXSettings(ctx)
+	return err
+}
+
+// GetAWXSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) GetAWXSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetAWXSettings(ctx)
+	return err
+}
+
+// UpdateAWXSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdateAWXSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.UpdateAWXSettings(ctx)
+	return err
+}
+
+// GetAWXJobs converts echo context to params.
+func (w *ServerInterfaceWrapper) GetAWXJobs(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetAWXJobsParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "offset" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "offset", ctx.QueryParams(), &params.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter offset: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetAWXJobs(ctx, params)
+	return err
+}
+
+// DeleteComplianceExportSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteComplianceExportSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.DeleteComplianceExportSettings(ctx)
+	return err
+}
+
+// GetComplianceExportSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) GetComplianceExportSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetComplianceExportSettings(ctx)
+	return err
+}
+
+// UpdateComplianceExportSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdateComplianceExportSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.UpdateComplianceExportSettings(ctx)
+	return err
+}
+
+// GetComplianceExports converts echo context to params.
+func (w *ServerInterfaceWrapper) GetComplianceExports(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetComplianceExportsParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "offset" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "offset", ctx.QueryParams(), &params.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter offset: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetComplianceExports(ctx, params)
+	return err
+}
+
+// GetIPAllowList converts echo context to params.
+func (w *ServerInterfaceWrapper) GetIPAllowList(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetIPAllowList(ctx)
+	return err
+}
+
+// UpdateIPAllowList converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdateIPAllowList(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.UpdateIPAllowList(ctx)
+	return err
+}
+
+// DeletePulpSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) DeletePulpSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.DeletePulpSettings(ctx)
+	return err
+}
+
+// GetPulpSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) GetPulpSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetPulpSettings(ctx)
+	return err
+}
+
+// UpdatePulpSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdatePulpSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.UpdatePulpSettings(ctx)
+	return err
+}
+
+// GetRepositorySignatureSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) GetRepositorySignatureSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetRepositorySignatureSettings(ctx)
+	return err
+}
+
+// UpdateRepositorySignatureSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdateRepositorySignatureSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.UpdateRepositorySignatureSettings(ctx)
+	return err
+}
+
+// GetSecretScanningSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) GetSecretScanningSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetSecretScanningSettings(ctx)
+	return err
+}
+
+// UpdateSecretScanningSettings converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdateSecretScanningSettings(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.UpdateSecretScanningSettings(ctx)
+	return err
+}
+
+// GetUploadTargetPolicy converts echo context to params.
+func (w *ServerInterfaceWrapper) GetUploadTargetPolicy(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetUploadTargetPolicy(ctx)
+	return err
+}
+
+// UpdateUploadTargetPolicy converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdateUploadTargetPolicy(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.UpdateUploadTargetPolicy(ctx)
+	return err
+}
+
+// GetBuildDurations converts echo context to params.
+func (w *ServerInterfaceWrapper) GetBuildDurations(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetBuildDurationsParams
+	// ------------- Optional query parameter "window" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "window", ctx.QueryParams(), &params.Window)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter window: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetBuildDurations(ctx, params)
+	return err
+}
+
+// GetAPITokens converts echo context to params.
+func (w *ServerInterfaceWrapper) GetAPITokens(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetAPITokens(ctx)
+	return err
+}
+
+// CreateAPIToken converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateAPIToken(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.CreateAPIToken(ctx)
+	return err
+}
+
+// RevokeAPIToken converts echo context to params.
+func (w *ServerInterfaceWrapper) RevokeAPIToken(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RevokeAPIToken(ctx, id)
+	return err
+}
+
+// GetUsage converts echo context to params.
+func (w *ServerInterfaceWrapper) GetUsage(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetUsage(ctx)
+	return err
+}
+
+// GetVersion converts echo context to params.
+func (w *ServerInterfaceWrapper) GetVersion(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetVersion(ctx)
+	return err
+}
+
+// GetWebhooks converts echo context to params.
+func (w *ServerInterfaceWrapper) GetWebhooks(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetWebhooks(ctx)
+	return err
+}
+
+// CreateWebhook converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateWebhook(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.CreateWebhook(ctx)
+	return err
+}
+
+// DeleteWebhook converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteWebhook(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.DeleteWebhook(ctx, id)
+	return err
+}
+
+// GetWebhookDeliveries converts echo context to params.
+func (w *ServerInterfaceWrapper) GetWebhookDeliveries(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetWebhookDeliveriesParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "offset" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "offset", ctx.QueryParams(), &params.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter offset: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetWebhookDeliveries(ctx, id, params)
+	return err
+}
+
+// StreamEvents converts echo context to params.
+func (w *ServerInterfaceWrapper) StreamEvents(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.StreamEvents(ctx)
 	return err
 }
 
@@ -1305,152 +3691,449 @@ func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL
 	}
 
 	router.GET(baseURL+"/architectures/:distribution", wrapper.GetArchitectures)
+	router.GET(baseURL+"/audit", wrapper.GetAuditLog)
 	router.GET(baseURL+"/clones/:id", wrapper.GetCloneStatus)
 	router.POST(baseURL+"/compose", wrapper.ComposeImage)
 	router.GET(baseURL+"/composes", wrapper.GetComposes)
+	router.POST(baseURL+"/composes/batch", wrapper.ComposeImageBatch)
+	router.GET(baseURL+"/composes/export", wrapper.ExportComposes)
 	router.DELETE(baseURL+"/composes/:composeId", wrapper.DeleteCompose)
 	router.GET(baseURL+"/composes/:composeId", wrapper.GetComposeStatus)
+	router.POST(baseURL+"/composes/:composeId/approve", wrapper.ApproveCompose)
+	router.GET(baseURL+"/composes/:composeId/artifacts", wrapper.GetComposeArtifacts)
 	router.POST(baseURL+"/composes/:composeId/clone", wrapper.CloneCompose)
 	router.GET(baseURL+"/composes/:composeId/clones", wrapper.GetComposeClones)
+	router.GET(baseURL+"/composes/:composeId/commit-signature", wrapper.GetComposeCommitSignature)
+	router.GET(baseURL+"/composes/:composeId/events", wrapper.GetComposeEvents)
+	router.POST(baseURL+"/composes/:composeId/launch", wrapper.LaunchCompose)
+	router.GET(baseURL+"/composes/:composeId/launches", wrapper.GetComposeLaunches)
 	router.GET(baseURL+"/composes/:composeId/metadata", wrapper.GetComposeMetadata)
+	router.GET(baseURL+"/composes/:composeId/provenance", wrapper.GetComposeProvenance)
+	router.POST(baseURL+"/composes/:composeId/reject", wrapper.RejectCompose)
+	router.GET(baseURL+"/composes/:composeId/sbom", wrapper.GetComposeSBOM)
+	router.GET(baseURL+"/composes/:composeId/vulnerabilities", wrapper.GetComposeVulnerabilities)
 	router.GET(baseURL+"/distributions", wrapper.GetDistributions)
+	router.GET(baseURL+"/graphql", wrapper.QueryGraphQL)
+	router.GET(baseURL+"/keys", wrapper.GetSigningKeys)
+	router.POST(baseURL+"/keys", wrapper.CreateSigningKey)
+	router.GET(baseURL+"/keys/:id", wrapper.GetSigningKey)
+	router.POST(baseURL+"/keys/:id/revoke", wrapper.RevokeSigningKey)
+	router.POST(baseURL+"/keys/:id/rotate", wrapper.RotateSigningKey)
+	router.GET(baseURL+"/keys/:id/usage", wrapper.GetSigningKeyUsage)
+	router.GET(baseURL+"/launches/:reservationId", wrapper.GetLaunchStatus)
 	router.GET(baseURL+"/openapi.json", wrapper.GetOpenapiJson)
 	router.GET(baseURL+"/oscap/:distribution/profiles", wrapper.GetOscapProfiles)
 	router.GET(baseURL+"/oscap/:distribution/:profile/customizations", wrapper.GetOscapCustomizations)
 	router.GET(baseURL+"/packages", wrapper.GetPackages)
 	router.GET(baseURL+"/ready", wrapper.GetReadiness)
+	router.GET(baseURL+"/reports/usage", wrapper.GetUsageReport)
+	router.GET(baseURL+"/settings/approvals", wrapper.GetApprovalSettings)
+	router.PUT(baseURL+"/settings/approvals", wrapper.UpdateApprovalSettings)
+	router.DELETE(baseURL+"/settings/artifact-signing", wrapper.DeleteArtifactSigningSettings)
+	router.GET(baseURL+"/settings/artifact-signing", wrapper.GetArtifactSigningSettings)
+	router.PUT(baseURL+"/settings/artifact-signing", wrapper.UpdateArtifactSigningSettings)
+	router.DELETE(baseURL+"/settings/awx", wrapper.DeleteAWXSettings)
+	router.GET(baseURL+"/settings/awx", wrapper.GetAWXSettings)
+	router.PUT(baseURL+"/settings/awx", wrapper.UpdateAWXSettings)
+	router.GET(baseURL+"/settings/awx/jobs", wrapper.GetAWXJobs)
+	router.DELETE(baseURL+"/settings/compliance-export", wrapper.DeleteComplianceExportSettings)
+	router.GET(baseURL+"/settings/compliance-export", wrapper.GetComplianceExportSettings)
+	router.PUT(baseURL+"/settings/compliance-export", wrapper.UpdateComplianceExportSettings)
+	router.GET(baseURL+"/settings/compliance-export/exports", wrapper.GetComplianceExports)
+	router.GET(baseURL+"/settings/ip-allowlist", wrapper.GetIPAllowList)
+	router.PUT(baseURL+"/settings/ip-allowlist", wrapper.UpdateIPAllowList)
+	router.DELETE(baseURL+"/settings/pulp", wrapper.DeletePulpSettings)
+	router.GET(baseURL+"/settings/pulp", wrapper.GetPulpSettings)
+	router.PUT(baseURL+"/settings/pulp", wrapper.UpdatePulpSettings)
+	router.GET(baseURL+"/settings/repository-signatures", wrapper.GetRepositorySignatureSettings)
+	router.PUT(baseURL+"/settings/repository-signatures", wrapper.UpdateRepositorySignatureSettings)
+	router.GET(baseURL+"/settings/secret-scanning", wrapper.GetSecretScanningSettings)
+	router.PUT(baseURL+"/settings/secret-scanning", wrapper.UpdateSecretScanningSettings)
+	router.GET(baseURL+"/settings/upload-targets", wrapper.GetUploadTargetPolicy)
+	router.PUT(baseURL+"/settings/upload-targets", wrapper.UpdateUploadTargetPolicy)
+	router.GET(baseURL+"/stats/build-durations", wrapper.GetBuildDurations)
+	router.GET(baseURL+"/tokens", wrapper.GetAPITokens)
+	router.POST(baseURL+"/tokens", wrapper.CreateAPIToken)
+	router.DELETE(baseURL+"/tokens/:id", wrapper.RevokeAPIToken)
+	router.GET(baseURL+"/usage", wrapper.GetUsage)
 	router.GET(baseURL+"/version", wrapper.GetVersion)
+	router.GET(baseURL+"/webhooks", wrapper.GetWebhooks)
+	router.POST(baseURL+"/webhooks", wrapper.CreateWebhook)
+	router.DELETE(baseURL+"/webhooks/:id", wrapper.DeleteWebhook)
+	router.GET(baseURL+"/webhooks/:id/deliveries", wrapper.GetWebhookDeliveries)
+	router.GET(baseURL+"/ws", wrapper.StreamEvents)
 
 }
 
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/+x9a3PquLLoX1FxdtWaueH9TqqmziGEJCSQF+Q5rJMjbGEr2JIjyRAyN//9liTb2GAC",
-	"mVlr9t63znxYY2yp1epu9Ust5Y+MQV2PEkQEzxz8keGGjVyoHlv3g0673HYoQfKnx6iHmMBIfWTIwpTI",
-	"JxNxg2FPqJ+ZFtBfAORAfxkjE2AyIrYQHj8oFExq8Dyc8zx04TsleYO6BT1UwYECcVG45Yid+NhEBZ9j",
-	"YuU0RJ6DM4gdOMYOFovcOyWI523hOv9hUGIgT/Cw4Yhkshmx8FDmIMMFw8TKfGQz3IYMPc+xsJ+hYVA/",
-	"mPAK+gRAxuAC0Alo3Q9A0BJ0j/jXZtRt9denY1DCqYPC8XPQwVDPQaGM3qDrOShz8HumVK5Ua/VGc79Y",
-	"Kme+ZzNYIFeh60EhEJOo/vfvxdz+9z9K5Y9/pE3XhW9d3alULEbf1eRWqMGpzwzN1VUMEkOvDZGAmc34",
-	"BL/6KBhUMB99fGQzDL36mCFTggxk5nvUk45fkCEkqNb9YFC59RwKzRv06iMuLhVL4gOnth4IKHy+Lp8+",
-	"c1JwXkFINtqAzSZckqNskKldGPl1av59TNtMkE3khi5OoCJf5IpGs1Js7FcajVptv2ZWx2lyulQky87I",
-	"z80RF7nSeocVDspxs58KFjNsLJAhfKZmmYI6M+zk8G/N+nO9moYsdqGFnuVr1TWi8rLvq0Hn5bSuqwuQ",
-	"IY9yLCgL0EjqoUPIEYg3ARPKgLARsPAMEWBiCXnsC6VqiQlgbJ75TEwA/sHQJHOQ+Y/CUs8XAiVfuAkH",
-	"WKxjuEpoSaUkAVbmsI36SYp9htYaz1LI13r3GdptkWqcCXTROp0voIukrpeUNRiCQqp22T4/In2fCzBG",
-	"FiZALjkAgYOk8gWUAeK7Y8SyABEz+TEbfJKNfGIixg3KUFbxyIULYFAiICaAEmcRdOFhH56NdeFZ4CGG",
-	"qcmzEpa98GxEeH5EhjYCggroAAcRS9gAc+BgF0vUBQX1IjBsyKAhIeeTdiXTw8R/68r5ZZSF6CkImYN6",
-	"MZtxMQl/lrIxO/PLf/8Oc++t3JM0N//49f8mfi8fn0ejfO77/4m9+P6PX9MXvNZdzxajvvc5S8K2QLUF",
-	"cxsxpD4oHgFuU98xwRgBX0kCMlcnPKS+AclNAOZEjZjmG2iMsLmOTvcoRCZARdhQgDl2HDUu11SXiDoz",
-	"jZtABBKhOM79cQRL+hD5ETmigFABPEZn2EQABs2fsSnZHO8gX81tRIK2mFgAggjT1Zlq1Z82tyTITTNM",
-	"oLoToe/XcEuOlAXQ4VR24r6ERlMnLclkappgYji++rxxllVUM5vjspGD43I1V62WKrn9olHL1UvlSrGO",
-	"msV9lK59w/E+Y3DAuB0mD4a2WnVkCtCb50BMOLDpfEQEBRNMTIDlbBQMpajAFWUCOgcrPqOLDUY5nQjl",
-	"MiKS83kByvYFaAg8QzkTM2RI/VyY+MSELiICOnzta86m85ygOTl0Ts8ihT0RDT5jzKoAfo09NaOBJrVx",
-	"PVcyKpNc1YTFHKyXy7niuFgvliv7ZsNsbLXpKwoi1a4stf8mjySp9ZcouoscDhTg52jEAKShoOKiwPTI",
-	"EShBl5PMwe9bTFsspvr4vgTDPUp4SpilBTaxypH0J3OouT/OlcpmJQertXquWq7Xa7VqtVgsFjPZzIQy",
-	"F4rMQcb3sbl9pmYmQoVvxsWEAu5sw5PANllxuYJSeDfBTBN1OfEC9HBBsSQ39rFjIlaYlfTAHPH/VAbw",
-	"t1Jx5BeL5TqdTDgSvxXTNIEDfwToUnErVfUkggHTJMhFmp7JuasoIua5YyKQhdgaeN1uHe5KMzVISOis",
-	"5uE6s9M944AEqVrz9napNz3IEBEgaB65U0rIt8piNhP4Xc9QpAYsevStUNhyKW6Vy3DZpiyEbHzWS6gJ",
-	"LBX9dKs+EjBcF0niUS4YQs8GdV0sUq3OLzbk9q8huaToKRrK5inz86AxhVZarHClvwAH81BJS4V/0bm7",
-	"ae0aCQQwoumkhQPrKlDTIKYEoWliiRV0rmLEmECHo+yqcPlcUBe/w8hr/5RtydYf2Uw8AtrW+yjWli/j",
-	"uAQZ4xqhv1A+8lHse8JdLteKG4PDdYsTQJN+bRJMPDGzCiYQvLQUVZifQm/QEM4CUBJ6KkGnPDiFMykC",
-	"LmUrnzhQsQeKFivmwPCZXL/OQll57nseZSJ0pXeSHjW/aFElck8qrlj++GrKKMHlNdp8/0woPzepf85C",
-	"atifuxw8+rqVZAGgL2iv5IpLd1kCBJZA11DvMEZZioFHAmJHPkZqd9UISaCQ6/WylWRR4xgCP8y/WAH3",
-	"vx7Gv5yHkcahdWR+iPFPqt4/7RtsWV2fOwTKQsUyamuKe/kNGJRMsOUzZc5Uak+bw0TKLz8iLQEcBLlQ",
-	"KjtwFL6NIUc+c75lwTcXy5UsDb/6hQSUbPgGljQGrs/FiMhYz0MGnmAZvXYn2jRoiC6ALPY5q0ahzERM",
-	"NvAYMpCJiCFtxYioRAh0EYBcORzIBHBMZygPuqY0JiHBtPVI8jpAfCVnHUbEhknyDJk21NGwQYlARBSk",
-	"BSgwGznNQrOgM7MFCYjyAuWFRK57KSYM75KCNWxkTJ8tz4oJzZhSB0Gy/Cw5srkNInDsIDP94wQ7aKNM",
-	"Wp41RSlScnJ1AqZoEWWWOLYICJ0/nVRQZA5lKQ/akMhYHgLLs1RXygAEtze95JZSTv532DnpXoCrkytw",
-	"dXvY67bBeecRHPYu2+fq84iMiHvdvTg8aRkDgx52Wke9SfPxdIrez+rQdPqP8wY8Oek6Z9ARzbOX8lvh",
-	"sHy+Z3cnXf/tRHh3Lw00Ir0b6+i2UX+Bw5p3d1Rzj/tnFW+KCLopGEP39fV6erG45vZDmV4/zDvvt4Nx",
-	"qX3Rb0/aJ9b0oXldHpH3pynrGm12XLwuz9n52IG+ad/u4TtIWkfcLTUfO698XGvdVhqmuGX9yvWjeW/t",
-	"3+w94KvJXfNmRM4PX4bFyuzu8NLsD/hjZb8H26Te9UqXM6/Z7dBCF3XuHkuvbvvyqgXPi+Oz04o/sapt",
-	"H0353nAwIvPr+yFq9978p179sv9AL6/O57P+9eRtbJUejpoz/6l4Ll4KxsVp+Q36xTeXt/z90zMPTWeX",
-	"VzdvzogsXsXL4mnC6B1Gxwtv/mTNrueCkH6zYA06fuHsbsgei7Wy27kdNtrGuFGdGqfHw+NJf+qQ6Ulh",
-	"RIqT22rrBtaK1dPK20txKsaoMjs3rh7o1aV/fnjHTwezYvH25LG1uEL+Yq/ZMG4Ljx2735hWBnfnLyNS",
-	"R90na4H7l8W5U3o8Obo5N3xnPuX7rT3fmVolOhxXeeXdfZpdFRsndPh2Xy2/wPPa/WDvwn5CaESa9eID",
-	"vbPHRuncG+y9TJ7oC2cd8dS8Gt8+7T3Ojps3HjPvW+zldHw2LZ95N+ett6H9xq9b/NA+KY1Isee/le9h",
-	"/7Bolbu1K6NvnhWM1xdabBoGezl88PHbPcM17O/3H7zm67AwGbxfuNzsWqRZeH06HxHcvPadid9o+K/2",
-	"fWEuymNBsLBu+OuL/db3Xx5vq0/jqj0Vx037/Lbw8NColl/tXu183rppXbcOR0QcHZ883d/MDLdjnR/1",
-	"S+eDVvPJvZuOK2d2b9gv9R4OF/C+ZBvEaYXvjdOzGXTvXsx2bTYihmvs4euzy8PD/mG71aoe404HndZd",
-	"Zh+fNvw7ft3r98vFx5rxZJO3x+Zxy1VrqH0ybx6359PuiBzOuyfH1/Ss3eLtw8PHdmveaZ9anfZxtdVq",
-	"W9PrZe+9i8dWoXH46FnOYtB6ejy1Xxbn9ogU9ib196vJ3Wx8Wi52XivTbuPy+PCiSHoPe4e3JdefDfZe",
-	"h/6gct9jhxW3cuI7wju/6Zyd94Rb6xyNSImdvD+06LC08PYfu81e68jst9uXi5fWC6f3t83G463f3iuM",
-	"yQsbopty7+ayPVlctRv1+/1mDV/ejYhbG+yN+fXRvNEu95hjtvrV/pFPF0+lARYn8Kl6ft27E3vDDixV",
-	"MX8cnLRf3mnj6rF5Vzm7nNaKI2K93lvN8kVh7JY774PGsFm57xyNS87spdp1Zm9W9/UcWaXS+8Pjm8se",
-	"B09nZ+3J7H2y51wM6v6bdToiL2+Fs+LCeSr38PiE1U9arcXl/u09az0N5oN+sWO8DJvzTpu8TQdH/uLV",
-	"vZ/fzS4OH/xO9655iSqPI9LHt6XJ2UWTm40jjx+/1fp7Dybpk+vB3il7GV6dH1Xce+a0TNIZ2ubjXfPl",
-	"aerd20cLXins76PLEbGnRdYji+LLxXwK/UkB3zYvjfrDrD996d30z6za7f7d+eLMv78X7/MH8tK/qN3f",
-	"HB++nlf5E3X7/RGZiPHwtLRXW4xv7gutyuxwDN9u7suicft+8WK8o+ngqYNh72K/Vzg1ztrdm9L1cbPe",
-	"LB+ZLadzvG+OyLRsXePHwXULwrPi2Vnr/XR2M7056/Ws8/Lj9SM+vbhblEXlbHE84Qy6tfmgfX85sa9Q",
-	"d9E7HD6djciMeRfO1RhN+HC/1hhOyocXXd96f2Lt2t3b0eB8+mTd2KW7k9mge03ai/fp9aLeuS2/Xnn4",
-	"vrYvdZR91X14YufUOK+c9wb7Bfx+dj28ccRLv/XbiPx2NRk2RkRZl87F0Wem5wv71qvxSazsIfCBkg54",
-	"6GNof4nnJ8ikDHqMSo82T5lVCPv9p7Ssv+nvuUpZu+SQGfZv0a7wNjdj6ZStIxHhID/nDUQE5Wr8/2RI",
-	"enrot2aOC4agGxsZyn/rVf1G4XcIOboc7IDLRvfDY5gyLBbpQR7nzvMMMTxZpHk2KcFxWiC+luBJSwA9",
-	"r+6D7xb9rTrbKQIivS++4EHUsRPY42WXZBaj3FyHTz1EuAG9bUAvPUQG7dbVahIv5pp5lAuLIf7qfL4G",
-	"EjU9aVU9HmRCJeAwsZ5daqbseA+QgwwBbDpXfr6J+TRIEYV7mhEQGSp8g76gOWfmftPffY4Ag3PgEwdx",
-	"HQ8wpAIIFaIwHVi4MnT0KCY64TS3sWEDA3IEsFjC6d318+Cbgg2dOVzwEfE54vJ9FqAZInr7czkEoQC9",
-	"CQbj8PPgG4Pzb0D1lJhF6PMRSQOyAc9gK4v4rtqDgnMZ087cTDYTUiAm5fG07MKh0PxzYvy5AMe34rZB",
-	"GsTbfmQzPkcsJXeoUsR0AtRnvZMNg9ATMWBAAqAZbg/qgHCBiSWJhxlgSL4SeBZsx3O1OTgYnMqgg++a",
-	"K7zlWsVsTy/Hs7bpyYONCdwbZIJTKECHCMQ8hqWwYeK/gV9uTju9X0EzX/1MWy4BycAz16xuTcUQndyN",
-	"I/R9y5SuGJUqKpxZKHlvhmFOnimz8pxboYUKguFnT/d5hoRz/Dz2ys1nRGxIDGRmsl/uamPL/hPdpJ1g",
-	"LjIxZIs/0d3FBLvQ2bWngfkXmj5zxGaIPTulr3SaUzblQhmqv9KzvHNPH+/aFDV3bWljD8JdG2PuPtNd",
-	"G1Puebu29QycM/nOLOMCEhMyc/f22PpK22fLx6l6O2UlxjPTSbXZC9RmAFkX48GUUrzdt0w2aYIUO5Dc",
-	"vdqIHHScBC6Bfte2PUg7h7s7PA9ayggAF1u2UBs/NpwhAA0DcQ4EHRGGJCxDIDMJNj8iI3Kz4WNUJCJ9",
-	"C6lrAZEDOBhpayFfHyvneg1o3PoqrZvJBg85DWMRvahGT7XoqR49NaKnCMR+9LAKa78YPZWiJ7mQtW+u",
-	"sAgeJZAwMGjEnpux51ibanGr4PHtIrfKUcw13zCXDKdznSRU7M3/OenbJHbHCf85aXhdTJ45fk/BW74N",
-	"M9VLD1w6geOFUEWjkXEtl6qNarNSrzazmbecRXMBBj4mol5V/m7knq3sp8wg22qSY52zS4TTrPJJ++ov",
-	"lXunc24GHWyCE0otB4XnCLjK6EooQX2Z3moFbep6vkDggpoo8saFnR+RDjRsoGeoUvlRASmMMvYspHd4",
-	"WEFOMA/u1Pg6QOTS8z0YEQBy4JuUn4M/kAuxg82PbwegRYD6JZ0/ppa/VBwMeQxxKTbLsQwJAqxMKg+O",
-	"KQMBd7LgG3Swgf4r+J03qPstH4wsjTM2UEv3+yIOeugAxKax3UWOSlc/Bz3vv6DncY+KvBV0CvvEUVKe",
-	"7FepEcxf9c1rvFZIYLqY8FQamNSFmBz8of8vBxzaCJyAgY8FAvot+MVj2IVs8ev64I6jB5QM12684j4U",
-	"Qd9VilgKV4WCDHu+reEEQHeid/6TO0CfCSfmuoeU5LAAmiw0tJDKq0dalNityUYmm1mRil1ZmMlmNPPW",
-	"iS09cU3m+MsffywiUhw/rghR7ZlJ+M+rpX+QG4iYkIjcmEFs5irFSq1U2aoGY+Cy22oaT4fDq09rA9JJ",
-	"h4WDthcE6GbZENL3+Hi9IFmWHBOpZN3O0fQS+22HGQLAEoVE6crXKpjiRy7WzUD76jZxKCNU0ooFWaCz",
-	"ifrohk7vqeTEshZnpQ4nCg/DLGTQK9XHWJ7S2KkMZaiOc3xkg5q1rQmtwVC2+shm0l2AQeACBDMNTX8e",
-	"qIpkjoQ0gsV4gbXsIB0aoGJD3x0RE00wQSYYL2LtlF1LqpVqeb+6X2+U9+ubfAhds/28YwlAwg9IPQQT",
-	"cTxB5rVx0pZXvOYnXdh3rFCIV/FINkQgQynhvvLjpTMKsaOx9RAxpXxkM8pb1I8aa/3MkIW5QEqIvser",
-	"upfQ1iQtmPVuNU8JXblK2wBEtCaH4SGrcE5wLjFQ5fFSAkwL5aJqSfULEy6g4yDpGlqGjFgtyYpI0+oC",
-	"nHgrasg4fMY9GymgwVOOzmQgPeeOHFCfMJNxQXLM5as4yJltpi7Jy3Z35+ONUdufcrgxWL4pZUEqfA5g",
-	"r57qvb3p6TRuFuCJXMRZoEMR5WKCCRKGLX3UAEoedF1PRX3KNfkfnzn/IzvI1Q85mCPHyY6IzgsnztJI",
-	"YG5QhaoOSeXTT/PqmuMUtauLIRCWLgSAQUEt+CWQ5gNQLNeL1XHZhHW0X6uOzUp13Bw3y7BZqaEabDTM",
-	"8rhenEzgr1ldRzFmkBh2zsFTBBiaIKZKYZbwpBgsK1OkPPy6cihivUV6/fJkPfW4Qzebu+tUOEICMRcT",
-	"xMHcRgEpdECSOOfjQgItxMAvBiSmgzxMfgXYRERgsYhX86iEQJgbWKs/oYT7Kn8shWmCDSjkuHGuQg4M",
-	"B6si8UQbG5ERiWQn4ru0iqEgxdkfq63ZuATW5T3cf1mT+CgZtuJpfSEvudX3CgdIW4lB0fU6Yht37bjv",
-	"yrBg++IP8tFh++/L0TZXrIcHYddGRR7d8OWTiju1m5k+CWy5Zm3TJwJDf2qDj57yYYYYx7sUpQa2OqBO",
-	"2G2JbjY85xrgGKPbjypcDZn+E2pVw93FDbWq+lc8oZTP5/N/pYL18wFLO4/471PXmoLMDZIOlPSPUi7J",
-	"iH3adugtbJo+RrysdHtV5V8sqtxeV/Dl0kkTTaDviCiUStqrjiqj5KqCUdUdSAMhDVR0DjgqnQ2NxAa7",
-	"sCyrXI+KLEIZeubcSUf6f0tHUj2LLdUfqlmazA5Wtq9XjI0h8EzxOBfwK5Ed4chgSKhPMUw9yPmcstQS",
-	"cCm+udR1sL4MUkvICceWvXJ/h2A+SpMyyixIgtKWRIdysVqslKvZtIMTtrF9IeiwADpg4kBLxsmq3sI2",
-	"9BlzHZ/pw+gqr54NSivUhr2uoQAoWEvdYEIrEfOmKemN03UKxv3FvGR2jJBbNXmCTtlVpicGjXEwxow0",
-	"wUrG6OsH75aRFSSL3c7kpoZmH9mt/TbcWbOt56YNh60jbrz0YlvPTeGnOoO8S6JI9w4yReleVUj4zTzb",
-	"FM/GWLbzMepkMmF3Vu3YYzW1+wXW7NhjNcpXrPhqKof5hAT5mo3u8p9la3SObJW/ET835Gh08iXM1MA5",
-	"z/OKzrbktURwQZn0gdOwVvVBGyOipV7yOWKl1PsuuP28ZlI4t3OMQ9BqtVqHlYt32C7tWtMTwksT67tl",
-	"4JHEd+eIJGz4/eNDGaEJTSvZ03tewV6QI7V8bFs/OtupvFUDBTGKJlmm5UHDRqCcL2aCqDlyaebzeR6q",
-	"z8qPCPryQq/b7lwMOrlyvqguJ4sl+XVuLheVE2jMYrHUQaaUL4blkdDDmYNMJV/MS2p7UNiKOIV4HpUX",
-	"/ogHCB+ygYX0lQoe0iekumbmIHOCRPI6IZUEgi4SqtTt91WqJa5ImlAWmEpBgUPpFPgeCO6Tc1AiUc/X",
-	"kje64AAT5YEIOwwgD1ZPpy75qo2sXmRfPJz88V1dkaNCTUWtcrEYS88ps+Z5TuAgF16Cw5i7jZUkoBK5",
-	"JNEgCEsENxAnrOLADEDOqYGXVyYBEe4jVIuVH4Zyco8oBeWwHIJQsVYSASgDrz5iC521SvDrI55PkSKn",
-	"99A3TDY2wxhpNtUBKeAFdQMCL/yBzbhUr9RLKCWrCB7dmLAm9+rSgkGojj+V+q4pYSlIIIAtKJBDp0pw",
-	"dKYxXW5/+MUjP1O4V7YX1gQlTpQU7ic4EZyO1xzRzNSvlKqnPIWT0Yn6YLshycVg5ya8eytQ2YfUXPyw",
-	"+a8dX12jQHDAO9qcU+skugtgXRQ+1rhV+vHYBlm1NIYFFLUhl9xhAplauxT/Pu2ibjIJ8AiYJpWNCx0p",
-	"6iFC/yrqbpuWS8poXK75Z3Y3PMq9Tfm48A1AVXellFDQKwuCmBeUlI5Qakhp5aUeUgnETFz1RHGyuiXD",
-	"hW/YlT6m/qW3ieM3SsSSfRsWJgeeFHmdnFzitAkj3S4dpTgKxV1QOMZOGLhH2FAS39ZWVUAT3QxzQMM8",
-	"gNoK0XtaUX0OcH1HYM9BQGAXBQF+2hx0xiu2nRqfze43e0T1AStFFT9Tma9dF/GpsxIJ8bpal8rccZAR",
-	"phM9hmaY+nx1VS9v2HSoZakbflV9VXKVFP4InrrappvIQQKlbcDJ93xpSrJx5uvNMS7kv0HJHp1DZnLw",
-	"6lMBNUOTq1ADDKiSSSf8SirpfIUaGtclSipdusUnCWXUiAbepBwGy1tHfq5IfGLgA+ruYuJXJ/axm18V",
-	"kSHFl4ok4292qTbJp/Y/Nzss+gqwpTzkwSVxFksFpcr61I0TcM6/xZTVepWScpQwsdIkVw2zFNzdqSzN",
-	"WugS/wuR+yc5b8l7yT5z3SRLCJpHtPkbfbbEZYUbXGxMrKTHlvRAlBMe00GfSy/fGDjdIOEzIsXXiR2A",
-	"iC6e40G4P0cMhagE4VowRoqoLrWZXhtfFtcwigxQoJN/KdHNbvHXFNL/dG9Nk+5v89V+qhOTvFHzE5MV",
-	"CPu6yYokaac148aKO1JXTVRio5bC7tY9qhr50oqIRvssBfHP1OM/11NZ3qa4mfHuss0q65dlcGn+ipQB",
-	"c/Vk2KYALplj/IkzTz/dlDL/VqS2N51wkjYkOo6WBwPqopW2kKkbwoODaFnA6YgIfTEzF7GTbQZlesIq",
-	"Upb6OYEm+AUR3/0V6DkkcnoSEenTpOcKV7CJsoJqkGAamlFBMjwfEnMTny51uzMe5JP/ApdWi1vWOMAC",
-	"A6pzBtTwXQk3faYB/kAOE50FCnd1BbR4VDDzXc+XG9BbSewXwtOTnxJAdrwKG/5Ngrp6/vNTcY3OgEZ3",
-	"uIWJ4LXky2bJWcrK1iOlwV3jqjxeINejDLIFQMRUR9uAi6AKH8FYrgWXzpAJOKUknxLR/G07GBtF4I9g",
-	"uh+F9etfPxWJlctEfqbuXrlpNk0WksgHfzHI90y1FxKFTQQhE5kAOUiuLL5ZGlbAbZIElQsKCPhvKBXZ",
-	"z/7QQzAtXeYiGEazdbIwVXCSgm7Q+YdgmjiLrSU5fmnLJiENi0S/tCkZ24qMLvibULbByf17mJI4svQ1",
-	"BFdOx3ziy+1+lmkdwQiRELnNCHEUVPNuRuWLIVI4+D87SIqI8P9FmLRWYf1prjdajv8+G83KJ2II6nzR",
-	"Jh2yLCT+ibReDpLqEi4/xg2VdhWDStx4k0KsziU13gxNXHhse1lyvzb9u1g1/k+afDhEqnytophuq9db",
-	"RbWTWt/rEpvUenlVEvbJ93wx8/H94/8FAAD//+0thmWScQAA",
+	"H4sIAAAAAAAC/+x9i3ITO7bor+j4zi32vvidd6qmzjVJgEBCQhwIMOZk5G7ZFmlLTUsdx+zLv9/Ss9Xd",
+	"arsDAfaemVOnZhO3HktLS0tL6/lHI6DzmBJEOGvs/9FgwQzNofzn4Pz4kt4gIv4dJzRGCcdIfgkSBDkK",
+	"ryEXf/FljBr7DcYTTKaNr037ebwUn0PEggTHHFPS2G+kDCUEzhGgE8BnCIi/wWJGge4kf+Ry2mZ5ZHQX",
+	"4wSxqolxKH6e0GQuGjTSFIe+USLI+HXKqhcgAPR+SBAMrymJls7XMaURgqTxVX7/nOIEhY39fzTk3HIk",
+	"t1/TRd5HCxwdf0IBF1MYrB+oZmIiGEVnk8b+P/5o/C1Bk8Z+4391sk3r6B3rmI6Nr83ifnGzjfm9uDSo",
+	"BpgzFE2aAHMQQAII5WCMQIJ4gtEtCgGcQkzaZVwWlqzmKa/qo7OuC/Q5RYyXiSq/t1WQBpA8ksCJ/QNw",
+	"wlEC+AwzwPEcyQWEFDHRRo0H8GREUsIQb48ESaE7OI8jAVy/299qdXutbu+y292X//+h0cyoJ4QctcSo",
+	"PhIyFJINF+BWjGMUYSI6zOHdCSJTPmvs97rdZmOOif27uYauQjSBacQb+xMYMdQsoOICwbAlmiqEMLlh",
+	"8m+DlAlNwLOjS5AoTDO18DXUKhe0ih7ZBWIxJQyVdy6EHIr/Yo7m8oeaZGomg0kClyWI5KiScq6GRwf9",
+	"g4gSz9wJmkq8FClmANQXABlQX8YoBJiMyIzzmO13OiENWBsuWBvO4RdK2gGdd9RUnQhyxHjnDUPJsxSH",
+	"qJMyTKYtNSJrwVuIIzjGEebL1hdKEGvP+Dz6XwElAYo5Mw1HXh7GZjBB1wvMZ9cwCGiqGW8BfAIkVgSb",
+	"HFwNgW4Jjg/Z/VZ0PDgtLyeghNEImflbMMJQrSF/Rv7R6PU3Nre2d3b3ur2+IA+7xTHkHCUC1P/5R7e1",
+	"9/GPXv/r33zLncO7Y9VJHoT8luewwWiaBIYX5CHITV2aIjdms5ES/DlFelKepKhIWZpmvNR+NRxuvIkj",
+	"CkPNqM7klrgTe1sPOeQpK9NnmkQemAsAiUYV0FTBkp8FkSBZxvq6yFPSkfok71VGYMxmgrnD4AaTqfxx",
+	"cHrcBoeK5zDAKRAoA4sZIiNyM2fXN2h5DRMCMAMM8SagifyoBkTJLQ4QmEEGINCMC9ygpWBCIyKaKGS3",
+	"wZEBEeA5nCKWcfKAxlj8TDgFlM9QAuz5oQkIxLkP/Tys2XAA9Byii1fi/EAQpIzTOUrAHBI4RSF4eToU",
+	"UMrFYM6APABNsJjhYCYWKvjoSMCDamHvUv1D9NSLEQSt1i+QIfgCwPwRA6ncThQ2FcsWDQIaLyVyxUkI",
+	"1WCi0wwyCUKEbxHACt+ap4l/SiwCnA0JOG0CSEK5oJhGOFiOiNgYTgGMIrrI7ZhoKP42LCg/poJlRMQy",
+	"RP+UCfjbQNMhk+sDUMLpjqr2VLSGCRqRBAlKNruX3ZUwIftwwfZv5mw/ZS0EGW/19l1es3+Dlh3xAxwH",
+	"YavXh+PWxmYQtra20aSVNYRjH8uJYcIxt9eCvk0bcMEaTY9gIfir7VKJ2zY4VnSrtmJE4IK1Utaa0lun",
+	"t3sZKyQqVD2jtwcRTUOLb4USh4v+Bhfs/2Vj/u5lpvpgeEg9DCUAMDKHx1CnQ5ScKkpUVDePKUOKyiaY",
+	"YDYzxCdbCxqgC5DG4rqRZ5AZkV13bRfvCrOTffFz2logsaurOXd2OWx0a/Dxysuzzo11/2vj591O1Zy/",
+	"6l6Bc5wDRfzQ6ga7G92dvY2dna2tva1wc1xNQ/nO2XatE/HFvM3VN+i7F3TsAZhzNI+5iyNMOJqiRPTS",
+	"NHVd8wW35gGKkoQm5UOymCluJd5/QMMDJhBHyDvJJzrW8OSHwaE5CZ/oGGiWEVDCExpFKGk0PesTY4n5",
+	"hCimBy03imBKgln1spilhTxA54iE4j76RMdMMF6zNnXkxwiYgdU7rqnXDOShXiDBqaf4FhFx2inR55qk",
+	"c7HfsRq7kUEn3kgKZx/XEYuzq2UU2PU0M9pY/zqW1PVgbxE5Wvkl0mxEmNx4Tt0EJ4znj04HxrgjL4zW",
+	"OMVRiJLOba/DEOeYTFkHLu46Yl/+O8JzzP/e647Sbre/TScThvjfu1XqiQeco9dde6jVsvTMPrTPEYdl",
+	"bEj+6yPlEhmkxDduoZmcxKC+6b7/3g31Ussw1DpYaRyuYhe1ZXQfETtjVxCsAd7RfEB7XZ87q9Hv/bUL",
+	"nGOC5+ncVSU4i61Q9pwNUj7ray2KlIql6k1KhopRqANuGZuZ1GiFRqRCLTQiRYVHf3OtxkPjPA+jfMiC",
+	"NIkyUcPhqtl5MA9euLhr61/FYzcPRr+7udustalG3VhEtXc/4zihtzCqpkg9/jXULcvLvJoh+czR7JGB",
+	"GbxFmlWrXigE4yWAgKGAklDt1BhNqGDVfIaWkssLVsCNyCZH0mK/wZ4jlQMN1YgYsJjUEzE6RxkcCZrC",
+	"JIwQ07KeevKJddbSIZVW7kVgEswwRwFPEykFeSSFJJjl+d/d7vb19qaPV0qmeC1+Zjmun/X9HNBF39e1",
+	"yPITFFOGOU3MTZLbsyeQIeA2kegTWFZXZ4jFyOOUS50TCQF01ik0p7UupAszwXKtekxiKY+AwhrWYZ/V",
+	"vyeLe+ZB3yDheAIDPsRTgsm0+nxMMJmiJE4w8Sh6nY+GiqEeWD3YMQcc3iAG4gQFKETiPUNvpRIYjQhT",
+	"kxvKLzw7N570D7cGewe9o+7TzZ3B7pPtg83D/lHvaXew92T3YOdw+2jr6eZgw/umTMcRDoSuwfP4Gh4c",
+	"HwOYzKl476mW2dMYTwmU6JaH9hYleGI0BL6JNOjX/ktpzV1WZG9r7ib/ltW9pwp6Jix5GgRxgm8h14oP",
+	"dUQ0R4egsD/6EZwjD929DqbPYkTOn53nZhRopSkXcEDG4lkCGXLtGiPiucHAxXDQBIfif44ODocDeYCP",
+	"QvGvG7RUG8fSOKaJVWY4F8321tbG9toLz+xrxfX8BMFEULG5ng3pONiqNM+MyHdcxAV6q7qQ3ZMoVUoC",
+	"OImYmDKutU+Yg0RK5iHLNGA4yc5A4UCae1x8dy/yjp2p3pVepuw0xPyETo8IT5b3tl+iOcRRHQMjJty9",
+	"lhwhbI74jIb5m+j8bHjpV1fxWRn1CU25tZIGMIoazbUvAnORd/b1v47DjtTd+PUAcyrknLjC0imZwHWI",
+	"p5oXFCgD3QnlKBXKMTaD/a1tA6vuCcY0XPrnVaoU79v6OMyGUc3s+o2FuGm4ChY6Y6XthHzWrrC22Dez",
+	"RV6/65WbhZCVf0FUcFUl9uvWhlrsluv9LGHQee5mmM+/dx3CfagHbu4c/IB3LhQT/IC37Ypx//rv2S9p",
+	"gurZdZR0Z4zN+aPyyvGiMM4Tsn17RE5TcQDRFBOjqY8Q5ygRR4ek8zFKmgCRMP+xqT+JRikJUcICmiBl",
+	"U5jDpXyMQaz126oLM31Y0+nCmiBGCaYhk2d1toxniAiNtzLjcxiBSHJ0gBmQe6weoNtdEMxgAgMxctFm",
+	"cIJJeidV8IXbt2Rdz5Tqv/3PP2Dry6D1QVgo//b7/8v9nf3zejRqtz7+H+eHj3/7fSXrmiY0jVdviWkL",
+	"ZFthMkuQY1xgM5pGobTZaxtDccGXNA0gudDDPJMz+hjcCmZ6aICxrBRysMBRZH0FOJWARrcKNo4IJFzu",
+	"OEvHdixhdm6PyCGVood43OEQAaibXwuFaJLrIH6SRkLVVggxEFhIiytVSnTf2vJDVq0wB2otRF+VYMvP",
+	"1AQwYvI9ztJEPs19ixZoChVOMAmiNESrVrmJtsLdcT9owXF/s7W52dto7XWDrdZ2r7/R3Ua73T3kf6ea",
+	"+VZtsN64GosHlzN56siN8JCJICYMzOhCmhonWJoPjbVRMipwThMOo/2Cm8EcBwlldMKlvIZIK2UdKNp3",
+	"YMDxLWqFOEGBeMl2JikJ4RwRDiNW+tqa0UWL05aYuqVW4dkei4NVG1MkwPttz1awgyZb4+1WL9iYtDZD",
+	"2G3B7X6/1R13t7v9jb1wJ9xZe/EUGIT30ZVx/yrbTp7rZyDOly2sGeBqMJwBfCA8gTyYHSgJsdIdy8iS",
+	"tWWNwoA5615fMWn9V2+NasNO/bEEbJVUlCAm3BdqA1sYVRiI1ylczBQeoET3EkjWCLUKjueXl+dHsqF9",
+	"XVQZmzRWmgBPxBldQCYofo45VzaZdTYzTEJ0V55AKowE48xPY8V4zQvGYsXep84CJsRoemrQx5VpXiJa",
+	"CZ/ErpADD9MECrjEIWF+j0DF4wREJY2bUjdIgVI+UsUSjA0bcwakhDgiagS9VJ82wh2zrk5SzuoB+Tld",
+	"CAeUpQJLGehjlASIcBxp9ZDAWyrEoUlC59ngDrpdRWMeomSGotbeai1pvofyiCg1j7e610rz7FnFKQox",
+	"tNSiMBzqvZLOEaan60pJ03HksC0lM8qp9raqp9rb4jMHQw8zZ9HL0EVnDlPN/Obbfc3jJ78EL7d1qZl9",
+	"54POczQ8rzqGSYBymoqVzqypQG/95gtMQrrIU9JGN1x7K+l+Bjwzr3kZeVAnfT6dG4oSVMcR2vEXla7H",
+	"epiquwMX9DS9/gYSLiQttLs3bvX64UYLbm5ttzb729tbW5ub3W63u57hlpUGFpSHMl/nB6tSzH/vE9/c",
+	"xj/glb966L/8Q9+zP1Ville8f/MmE/BjmCDC7e2sfzXKve91mKnpdpNkR3EtXVo50Kc9yzmGmFFLyjAh",
+	"MUQYkgAd3cU04fd1KpJKpOuITq8R4casV+l75LmAjD5kkplIFzNpZxU2WiIgk3IYulOmAe+F/TDOSGaK",
+	"b/UNUv2L7kHSbgAkHwbSrszSIEAo00aUvIDsUj9W3gzXiIReGPVnxmFSw3iVa50b2+85VN5tZ2u9t0uB",
+	"vKpNluM0uEF+vMcJmuA776fM0e5+RrxmQxkJA3nJ+w1hL5G18qsFWTldgqo9mbX374xGobKPugMrW1NT",
+	"vI9ngkJwRseikzOYog/MRySkiLXBsfBkDmYouEGhdksA6BYlSz1A8Wmd5/u5v1ouRD66z6GiwnZ2Lk6j",
+	"kqdpMoUEf1ELpAvCnHVowG9QzAFkIKJCtcBGJGvRtEEjJARqZwHjUBty4Rx5jQwFulVDZU6SlkY8++pd",
+	"31qfoirC/TYHo4y4q5WZww2LIfVfIJQL2g08pIqvCNfvgpU0owH9rxYSyhoSFJW4Gzkl7kZz1UErbj4S",
+	"3CkjWmmx1XBbglbIk7EzmDUBo+IJnbIURtFyRJAxWAoFUQTZbDUF52Hf6vVretlaV/f8ABv9+5JUHaJ4",
+	"MCGzMO4P9ZYM7GQttXUd9Z8f6j55z0n/+mKpuhSN94cHDhGpcK2ewn4jLSIcTzBKzDnTARPECKVpmHfd",
+	"EUISnquzOCKYcNoEqD1t2yAF4UwFF8yaduV4Mk5YfJkGsdLwCN1pKZikruP7BEdovSnNe51q8Npsw0qC",
+	"TXWj4hIu1PLVCmQ40wKXgiWMYJK0YIxbdR6crRCzm3alM5sywZdXdiBuaZbOi85UOv7ohtAF8a2uxL43",
+	"xt1gc7O/tzsJekFvcw9OxpPNYHdvb3sy3utv9ncg2uyhze3NvfHexmYAN/e29vZ6453drf54d8tv3TFe",
+	"Iev8fELEYTBzHH5sz/Ky8ETtS04QEHKQDPOyDixiBBRqiQCGyouBJjxaqphiJRWY11ZBKvas5ItnEUP8",
+	"pQSgoKvxkiNWbwPWepqUGI8mcc85f8gLwRm2fhyv7nh0iwi/t09OgiCjpPoZZbZKvaAkHUjcrvYJyY/1",
+	"SMPwqAkefU5Rqv6l3z7Wq/WRYFKPrOr9kQp1dB6IQvIWURVAjSFt4BCoSfMMU95GxaMmf1SPrdW3jH0E",
+	"eZ7NBs/sYTZb7dm9d1peoxV6j9XbXdRyf6vGIm9KW6MYr00oei8NE1dv+ASp+60JxIt06ZhpiHgcAchu",
+	"/E97DpMp8tk4lM0W6O950jHB/Y3aoWdeRcwq9beBq7mO0k4Rh4aq8rtMGU8Qug7ofI6513792wyy2e+u",
+	"ap8D3dzrKhfcwKlPVXOuvoAIM2PuFY/aV0dvLwZ1va/1GHY5Pgx65W5pBRQ/aGot2iYN63KcOxyhScCK",
+	"GDBr0xwEJkiarnWARimnxLqkEiXrpQTi46oVfMvrUYVW64t2PTvJt/Yc9FW9D522LDu+OUJwkXy6lP5C",
+	"h873vDvpVnctyyiNJmRFT4KPimHsMS2LOCa9A7qDgZA7KCmc7TZ4Dm8FEQspKP9JGr1EB3PtYQaCNEkQ",
+	"ESMRWnqI16J/uT6v/b63ynzvD50llOPJ8tr60pbilRPEtM4m5QF1fNmyJcnO2rdYikt2VSLsMkRxRJdz",
+	"sQT1eJfNZZiSVakIJk0meJomZZVEylDyf6vDh7Y2fRpONJ5RerMOk1eqWZUO3Mt1LamsPKOrjVhr78MH",
+	"M9aH7gVQ5cgizRPX6vbwMe2h/pJtfYyzvwx/1HodWApxHxGNM5k9QjbSVyaVM0hFj6SW8VL3aY/Iheqc",
+	"sVmVhkIctCxOfom4cm5Xwt89DlHOtcejK1GbnUkWaw9kNlRd80vBEcfrHOQ4I6tmpe08MnaLgviIOMSR",
+	"+KeVwso2j+zKq+FKba6mDIArh0pLqt5xhOb6wPv8VdS2hjgU28o4jYUUJrwp1B6PiJQxtPohSJB8v6tw",
+	"uJRYj/L8tVbYfkf3kIs9gRx08h07gsewTrfD2EwrfNfGoWkcPPBz7T9m4j+7Pm6dofhBLLhr3kMPxGHW",
+	"WHWlGzlKqlzgCyc+ZTPjUJ1GXLFpPYLxAKMAOj8KTs94smyDMyEz6GxdERqRCbVdlrGVtOOEhmmA3DFU",
+	"VK1NAxOnbGYyuUjtkpkix0By7klsyTia+1zK/CrIp2kULcHnFEYqPNDNkWhXKeBoOq8bkxJIgFIQbj6n",
+	"cNnGtDNf0mTaQaH0GnWTdvkc4dvX+53Wx//ztyptnU/HNVUXr0aRAlUiKqCiSznjjEk208x4uAwVnCid",
+	"W05xh4n9zjjN9H02Io4m4qOMWDb2G6HGk0mTwhEJUKJlwQyZcr94MUha7XimWLzvrq8EO29MV/eABttr",
+	"R+dw6vF4hNMKggid5F0q11uOFOxP3u2/dgMh2q2Pf3Sbvf6OL5db3YyBhQNeJRxWRZYdyt/NUueQ4Inz",
+	"t0tnBZJXOvD9ydZkHHbRVjjZghsbsD/uoS7aCrbRVh/ujDfQdjiG20EPbcOdycbuZLI57qLupAe3x1to",
+	"Z9yH91RVX1mXe03wJeW0ZSOSFkLXgVfqhHTkv1JJj4gwdAiF9RghYn9cc7b31dpb37v2NsPTleu/vo83",
+	"i4kSzZBQ7d+SzVCldDNZE7LRClhajVibv0tmJlO/Zc7NwY3w2Cdh00j8ylvY4y3zjdlz1ISrcudU5MRY",
+	"dTG4h6F4L3A4XU82ljXUSZOhD6z3yEup18leUFpG9s2+yBWvFHeyEppz6RXaIzLgIEKCniixK340hgyl",
+	"SSQU83MsaDHCjMu/EIdCtHoEMjYD5ilTEd8sRoHEXxscT5RCRY04l3tsPzc1Mw+VPc9JMoC1pwgT+Bfu",
+	"I1i+O+GY3qI2OA7FATc48137GvBC/jATUxOEpJ2gcAZVPI0QRBDhnRAz3hF+3bud3Y7yOO+IgSjrUNbJ",
+	"5R3LRL8E13lmSP+d62k89aUyNp/FjlS3QUSIVaH/o2ttLQEzjadet6Zn58/krWli0yT7sIpReVoxy+hk",
+	"2QYHkIgzDsE0npqUAxC8uTjJ56Zrif97cvTs+BUQBsTzN09Ojg/Ay6P34MnJ2cFL+XlERmT++vjVk2eD",
+	"YBjQJ0eDw5PJ7vvnN+jLi20YRqfvFzvw2bPj6AWM+O6LT/27zpP+y8ez48lxeveMx28/7aARObmYHr7Z",
+	"2f4EL7fit4db86enLzbiG0TQRSe4nH/+/Prm1fI1m73r09fvFkdf3gzHvYNXpweTg2fTm3e7r/sj8uXD",
+	"TXIcHCRPu6/7i+TlOIJpOHvzGL+FZHDI5r3d90ef2Xhr8GZjJ+RvktON1+/Dq+nexeN3+HzydvdiRF4+",
+	"+XTZ3bh9++QsPB2y9xt7J/CAbB/HvbPbePf4iHaO0dHb973P84Oz8wF82R2/eL6RTqabBym6YY8vhyOy",
+	"eH11iQ5O7tIPJ9tnp+/o2fnLxe3p68ndeNp7d7h7m37ovuSfOsGr5/07mHbv5myQ7j1/EaOb27Pzi7to",
+	"RJaf+aflh0lC32L0dBkvPkxvXy84Iae7nenwKO28eHuZvO9u9edHby53DoLxzuZN8Pzp5dPJ6U1Ebp51",
+	"RqQ7ebM5uIBb3c3nG3efujd8jDZuXwbn7+j5WfryyVv2fHjb7b559n6wPEfp8vHuTvCm8/5odrpzszF8",
+	"+/LTiGyj4w/TJT496y6i3vtnhxcvgzRa3LC9weM0upn26OV4k218mX+4Pe/uPKOXd1eb/U/w5dbV8PGr",
+	"2QeERmR3u/uOvp2Ng97LePj40+QD/cSSI/5h93z85sPj97dPdy/iJLwaJJ+ej1/c9F/EFy8Hd5ezO/Z6",
+	"wJ7MnvVGpHuS3vWv4OmT7rR/vHUenIYvOsHnT7S7GwTJpyfvUnx3leAtnO6dvot3P192JsMvr+YsPJ6S",
+	"3c7nDy9HBO++TqNJurOTfp5ddRa8P+YE8+kF+/xpdneafnr/ZvPDeHN2w5/uzl6+6bx7t7PZ/zw72Xq5",
+	"GFwMXg+ejAg/fPrsw9XFbTA/mr48PO29HA52P8zf3ow3XsxOLk97J++eLOFVbxaQaGB+D56/uIXzt5/C",
+	"g63bEQnmwWP8+sXZkyenTw4Gg82n+OgIPd+eJ7Onz3fSt+z1yelpv/t+K/gwI3fvd58O5vIMHTxb7D49",
+	"WNwcj8iTxfGzp6/pi4MBO3jy5P3BYHF08Hx6dPB0czA4mN68zno/fvV+0Nl58j6eRsvh4MP757NPy5ez",
+	"Eek8nmx/OZ+8vR0/73ePPm/cHO+cPX3yqktO3j1+8qY3T2+Hjz9fpsONq5PkycZ841ka8fjlxdGLlyd8",
+	"vnV0OCK95NmXdwN62VvGe++Pd08Gh+HpwcHZ8tPgE6NXb3Z33r9JDx53xuRTcoku+icXZweT5fnBzvbV",
+	"3u4WPns7IvOt4eMxe3242DnonyRRODjdPD1M6fJDb4j5M/hh8+Xrk7f88eUR7G1i9n747ODTF7pz/n73",
+	"7caLs5ut7ohMP19Nd/uvOuN5/+jLcOdyd+Pq6HDci24/bR5Ht3fT488v0bTX+/Lu/d08eT/88OLFweT2",
+	"y+Rx9Gq4nd5Nn4/Ip7vOi+4y+tA/weNnyfazwWB5tvfmKhl8GC6Gp92j4NPl7uLogNzdDA/T5ef51eLt",
+	"7asn79Kj47e7Z2jj/Yic4je9yYtXuyzcOYzZ07ut08fvQnJKXg8fP08+XZ6/PNyYXyXRICRHl7Pw/dvd",
+	"Tx9u4qvZ4ZJtdPb20NmIzG66yQlZdj+9WtzAdNLBb3bPgu13t6c3n04uTl9Mt97svX25fJFeXfEvi3fk",
+	"0+mrrauLp08+v9xkH+j89HREJnx8+bz3eGs5vrjqDDZun4zh3cVVn++8+fLqU/AF3Qw/HGF48mrvpPM8",
+	"eHFwfNF7/XR3e7d/GA6io6d74Yjc9Kev8fvh6wGEL7ovXgy+PL+9uLl4cXIyfdl///o9fv7q7bLPN14s",
+	"n05YAudbi+HB1dlkdo6OlydPLj+8GJHbJH4VnY/RhF3ube1cTvpPXh2n0y8fkoOtt3eHw5c3H6YXs97b",
+	"Z7fD49fkYPnl5vVy++hN//N5jK+29gSPmp0fv/uQvKTBy42XJ8O9Dv7y4vXlRcQ/nQ7+PiJ/P59c7oyI",
+	"vF2OXh2uunrukUO0qHfNmhkZKK9UMzKGkpdYe4JCmsA4oUJ6awtZ0PT7b3Gz/l19b230lZpNREH93Ua7",
+	"rRMzMqGsDISFQXxuB4hwyuT8/50gIemhv++2GE8QnDszQ/G/25vqFwmfSMV1NqwBS6X4ESeYJpgv/cpr",
+	"xqJr6ZZbs8pDtUDsmkV9ZtPrYs6xehrdorDtIRAhfSkVVO1hn2Zd8ra//m55fEwYhzIv3zoThm34tdmg",
+	"MSIsgPG6TsLPa3gwOC+a/B2BLqaMTxPEPkd1MwwLm7knAb3N3Sx8fOY09DlyoQgFXETJy9eB8MSzii6V",
+	"S8EOIh4Yj2DKaSu6nT9S31OGQAIXICURYuoVkcgE2ephk6jnyFwokWOKiTLuKtVkAGXi7Wyck7enbfBI",
+	"jg2jBVyyEZEGtJO3p03hg09sbnY9BaEA3fEEuuO3waMELh4B2VNAZsFnI+IbpALO/Fs3gYtGsxHdzmUs",
+	"hsKA95kbw6XQC30b8a8mezcFwLqRhm5bnf/Ho3aQDiV0AuRnlUHDSSgfQCIMkzotgXpGLvUTHCcyGxaS",
+	"GQ9UGhAm3ViHw+fSS722SZGhpLxanzPK4XB4dERuUURjn1clEN8B0g2agCEEzO0wxXyWjuXrk6EgTVBL",
+	"MQPWiuC4EzKGyqna1EaWJxJP1O3NLD8UhxwJO32jmhouy8HJcRxpK37nloRtTFqccvr4E6NkpQapPjEJ",
+	"dAxNt7UOUy6kFu5GbuKPFXsydPWHeSTeoKXPxzqfU8vJKYgJOH95/E4hF5NpEzipuCrwsn6HLHzrVEEK",
+	"XDWqd7WOa4PfkFXprnOBQvAccnBEuEwBKdidSPoDfrt4fnTyO9htb9YrxSMD4Xc362mw83kM1y3pPKHi",
+	"ajUrM7zvLgjCyTVNpm3Gpkay0kqc61j1uYaEMXw9jvu714jMIAnkft236wxPZ9/QDQukzlGIYbL8hu4y",
+	"5TCM6vYMMLtH02thg0HJddS7T6cFTW4YV4FT39GzX7tnius2Rbt1W85wDGHdxpjNr2ndxpTFcd22cYBb",
+	"Iau9ZYxDEsIkrN8eT+/T9nqaYq/k4DmJrpdEnsWd6Itbj6wS9kJPut76vj1VnMAjibhNWTVwIq+hC4uW",
+	"MBz3cJQYrzfWBgOVCnqOpzMu3fxk5mgYBNKXjgoTpRgr4CjMD9sWys2Lio82PZK4agSvBURMEGHEbJ2Y",
+	"p/JRWBrUlf8k12009T9aaoxlo+nwY/WvLfuvbfuvHfsvO8Se/UdxrL2u/VfP/kscZPWmbO1m/xSDmAft",
+	"jvPvXeffTpvN7lrCY+tJrrijqqJQAjBz86073v/3pr4qsnuae/flL945Jtf+SBXmRKpkL0cbq+Kq0vu9",
+	"zZ3N3Y1tkYj1rjWlLQ1BqiJUxIvLPhAKvj23MFl7JTudmxnAvlv52cF5vRyKteqtmZ27hREOwTNKp5Fb",
+	"G4mqYj7a9qhdUA9Urh7wiobIcXxoj8gRDGZArVCaoGzqRGgtTTZcTE8iPVLa4K2cXyk2pPlxf0QAaIFH",
+	"gn72/5Aerjj8+mgfDIjydwXQutJCGYSQICZdYu1cgRgCFBbVBk9pAvTuNMEjGOEAud6wj9p6Zu0gMVD9",
+	"7gmDmtoWnfLPPV+2ZKBcC8bx/4VxzGLK21PdyfRxQZJvqftiQ69f9m0ruAooCOeYMC8OQjqHmOz/of4r",
+	"JhTeIs/AMMUcAfUr+C1O8Bwmy9/Lk0eRmtBUPNVuI5DrvkWMTCWsEgQZbVSCCQgzpvTzzlsuVxEnZqqH",
+	"U5YKkqUazWC5XNMJJfsl2mg0GwWqqLuFjWZDbV4Z2Y1mQ6PZ/fHhSytZxvFw6ffkw1iMf13MJQRZgEgI",
+	"CW+NE4jD1kZ3Y6u3sZYNOsM112Xze5bAePb6pMJdd44YEzB71aDexNM2hh5Kd9gQ3SEmDPHKs4DqSwJF",
+	"oXNvrXs6Gyg+ZvCud2/1R6GIfW02SBpJnz7zdwkr0k+lviYgh8Tyar42G1mmPo+fqE9reAqDGSYIJAiG",
+	"AlSg/JwN35cAmrAGxLNqHArywklsvDk/ORscXl8OLp4dXV6/Oru8HpycnF0dHfqoUflo+48M5hFa75it",
+	"mtmRProIOMGMV3pmA9WDgd8unh6And3uzu8qF5/2mtFuoU15J6AQQAZcRU+sRpFKHuWSp9AhJNsYQVWI",
+	"UTcy3jvqthSzqFwkTYlLdIeZ8haNMMoK7j3Yxmk/c11sOJhBMkXaq7xyr5rAVMVUhQnMkMrlSfcW7Z+e",
+	"vXl1qNch1+/UNTC3utDKPhCVFH25RJZgJNLJJpRMFRizdA6JN1vgPU9aLuNl2awgBbDrGCZwzvy8KYZJ",
+	"FgyZ9/rXNCbHMBll6kU+qXnPxbT+2jBymjXl4CxtcyqfmeK/+u22OgzZU8TJHFPP+j2kkyOCpzQZ4zD0",
+	"V1DnS59mWNkShDNTyvfHESQ3Te3SKF6FKIqYOXTiuKqETNmETre1N5uJLdX8xYKvibGpzqSlKsF4js8H",
+	"4tFk2E7hCOPQp7V/hbjU8ggecXB8eCEkH0kRTcAwkXKwEhSNk18QIOXjJ3z6oqgy4qO312932/12t9Pf",
+	"vHe54wIuFOy+Oz0XiXe/gMxixtJCPojzN6U8qdahsgmUmVfl2lB2V4mdLLSwmN/H6D+NeVj38j6i88HW",
+	"a+OeLmVNI2E1lEHEa22Gw0vRam02Bussqd62bSCTTYsbmFPQdXNniw7ixQ50vbURCdEEExVOxnP5Zwt8",
+	"eLO/t7m3vdPf2656JKs4teua8Ra5h663EpSTpjQXw12Yp5LWqmThWkmMPWFjK4LndWt17kyeBcHAI1T2",
+	"pJeFpZmbNlekjxHMaCqfeTqxx+eUcqh0Kyoxi1MhTUUgyNeJLXXbBhYKOsnNaIJCNIJBVi5N+g17skGo",
+	"7Gj5Wm3qK5L5bFQRZBnqOs+fGplWRKpdxcWldi/zGHbyQKhdVP9WQQgoUX8p9GX9crXXMq6VzeRJRScp",
+	"pF5AYj640Z+P4qOhqUtTla2cuVnW2xYkMMdNINV3KJyilgr8d3+xfgaSJ93OVDbnEMUJClQxGBsRLWv0",
+	"SyyDKeJC9XCom0lCQjBESR7/Kv2xTCokfs0gyP7SkQzmBwtOo9mYBrH4XzG5fRfK/+ZaiUCU3A80wI1m",
+	"45bFM5Sg7F8tegsbzcaCiTtQF9kt4CX3kzvk7czvT37sOmnco5RX3nnFFrrL9sK9NPJbNCKFbct4JJPy",
+	"vDqYiwRzjoiM3BKamzGSKXhucHAj8z2Kcxp5y4OxNKQtQmPI2MKXMlUqVwStaHv7bypvnFF4/O/fnfQK",
+	"ji42lVl+QjoimaAt56BJWFKK/O/FDKFIFwHq3c9zKyVQrDz0lerX+6VeGQYn2s5sHwBKkUw4SqDMN1FZ",
+	"mbHM6F0ht8Tp/WFpWuCGcyQLxNBElnWyJLGu1pPU5CJPvMiL4dkroL8apYIW/oX4njoV73MzOOrkfNR8",
+	"p9sp3IMrUgjVMgs7YcknsjKq3B6dWHt1Bmnc6toy5KKAPZp4419NqTsc3276FTSq6GBI2KrPFd39Eftq",
+	"KeeqaIZnYw5s8jasl6sualtCGpN9kauqqZKxAZWdLf8c8GeVVzNX1puAcxNtayv296Q4rUrdbqtKdivq",
+	"3hp4r/1PHLN7ql4AJWoRppOS9ihRq2qCudYBmMbTIC48tflGm81VWTZPYLNYarFsb8HiINvYKrtSSbLQ",
+	"rCuIVzk6VOeus1vWBCydKLanZdXY7Hg+7+am99AuUEInk9xeeC+Kc9GyQCx0MrExn0uVB8upHF6OE4nT",
+	"8Q1arikG5/i/0Em2Hqbc9qx9QbF2rDO6jQineeDq5VRzk3cWg5imMq+sJp6IauEio5svlBh6UeorF84R",
+	"MYDG4qKTsGkE55YVCg4/ASlhiJeTlmQ5RO9TC2ooP+VzFFrT06rTXqdSU1EQtGC42+t7exiesKKsC0pu",
+	"oVP/ycIiQLl/grzCgBlHXPsA8tRT0RirrfoqXCMe6SB2+PL6kSwX/9osLqxelcpM6PcELJYeJx+9Oizk",
+	"qYM25CheeVBVqGhK9GnlFdCh+NoqwpxKcLqjxuNv7PdGBWSsRvKGAuKcPWi6Lxo16YNl5SgO96OzcnTq",
+	"5Bjt6GP/I3N4PAQgf/mMH97d/+bSELqdyTwpYlOzC/d7K0N8D0eqpdvKi4XfxsnWHelcuQnnfFfmKDk7",
+	"OK5yLilRkm1bdYV4w7rPE9SCKZ8hwmV6CmsZE7Gr3rTKZ3JGMOQ0gVOTmbhOQnoxv+8IaI2ph+6kS54X",
+	"7IEET1KaDLdniDeBcm+SbitggngwM0lakPD1OBbZtZH2yPhnmkT/FB0Y4kbX3hwRTbpuZUox2FxnYpRW",
+	"0orcu6owiuchowKDkSlLrnQo4DfNqPZBt7/d3Rz3Q7iN9rY2x+HG5nh3vNuHuxtbaAvu7IT98XZ3MoG/",
+	"6wSu4wSSYNaK8A0CCZqgRIaFZ+MJ3UwWpS3UIL8XZLdyC/8zdVJ2Z67RbcbmnmQaiKNkjmWqAp0/AWon",
+	"p1zVzDkkcIoS8FsASRihGJPfs0wpTmS7dDI0/oalWGxKWCqjIrK0Kyy/q5Bpe2yhzQyREbG0Y/ddvIYM",
+	"IXn1HGvz0uhtt6lmctXpC7mZdb31ETGp4b3JXeTjBou80TqTGKMgREKsYSIcRBfZWAJVxMHJVFZ+xJh5",
+	"dKYisbUyk/AcC32mrtgplWQ6ObSiaZPughcy1ggjOU8TbaHIrtw/bDXwrx01est2q0KrPv01aq3bYK0S",
+	"I7F+ywX1yD1cyNe6yZgJvAwumVYlm5aJCWvmzMrfw2ub13FfKWvE8+mhbZmnRJV4agI3N7UWzR9Jb4JH",
+	"Wjx/5ORTyhwW9MfMjzeCY6SyFukBs9TVOVLIsIgMCs0bweBDD+Dcrya1k/hJYthpIv+2Dbz2QZ9xnYgh",
+	"mDlGusqMgKiQv6new16WtKuXlVAtuyA7yP6OIKczBldrUz0ljefCR7C2FtK0d2arTrcsNKDeWVFMK76s",
+	"SAUnQ3L9i8DTebhV9SmLdKr0PvCV+WG4jnJWfm0a7JhuGbiqamPDwujg7aHebmbTf8BzzQS7VjzA1F+u",
+	"d3m73W5/z7Ns9YS92jP+dZ5fHmDObTW3oY1ULEu+BOgIxCyeMR9BqT+LFEt2nM5tT/NlOCJxgsIsF90y",
+	"zrqyiMF2iG47WWG5zm3PY/7yFHFdM73f8KABWXdPlVBle15WwuFfS0V5dzlu7YOX7VNqIVrpYWPcIMxM",
+	"xQU4f6+jjAzWqjR2fkT6uLHB2R+2bsv3F1jxiWZlH+ZVVWQqoiOrs36dp1GsHpH1UnkeS+d0JVFLnx4j",
+	"ncsczxCI8axarw30OPl8+FI4l39weIOIyuwrRpSdVwnuTZ3qjSHh9zwiujjeWIrhQmBWT0WltneTeAL3",
+	"nc5UFjjG5YTKXCwHdnNCiiW5mcdNXIg3KZjfC1skuQDik1mKy4OV0GMTgNqI+FzFnnCKOtUloZMVudpc",
+	"k1LWTiALZzuot47TpliuLFChpT2AJyOCufSNFVumXHHBsmzMqHrL6nhQ7aLn0X5lKhK57YPzY6D6NJoe",
+	"jhSnUdzORxqsTh3ytQaxV2l7ZMo0r+7EgdrFqvNIdapkAU6L2KpajvzBpoyT+95YXw5CQ1l1rlcUw3Ro",
+	"dg1Z1djY+5XGLKyiMNzawonuwr6xWKKz+KqzlisisLX27JWOw9r+646HJBSQJtF3HhIXkO7mbvN+u+Hb",
+	"gAsZRqKBLsh+slLZve7R/KrPsjISSMTfhaouJAmWqlZpE4wa9GbUEI/qgou70r+oqwXnnpUAS/f+BMFw",
+	"WfE+Ttw1rTt1pqkfOS5ZrM8e+Z3JI9fnT7p3isjV3gJHMl0kk5kac4Voy0zRKAArlFNZ+sgSzHhKaIKu",
+	"GYv8QP8nRZZXa7wmy5VstppmbYqT6ptDj3idz9RSneXXuL5JLXA5RWyuLrcMhOdUnWFLuKprjk6b6jfB",
+	"KaTspvOGajHTam/zulU6sQl9YioIsyP+MQ/bd/PI+hLbciRaWSVdjvMQC3Wc+rjZ7VZ67uVZRglnvn0Y",
+	"oiBBfBhAIjTY1VvgT3B1JdjhDMYxIlImLtTU0OtxJNwmkGYQrUwfEYlAYSUxmQhuEJHeVRpthXIa4EqM",
+	"J2rRiO/LEcmctY2eMtG6Gplnl5Uk7IlK1izgEmiXljExVAHD4Czn2i1dT6FRkGtulfcJFp+lOkli9uP6",
+	"ePXQ/3IcKlvCS7RcV8rBHlEhurS0htFTvJRMZVYcn27iafZRkKopk6lzTH1rrQjtXuhN1Jsv0Jm3bKyb",
+	"Pk4TQV1r83NZDJ7rDkp0imCAwuvxcpW/mIDE+PGrDspaRQmqYxNP0C29uecGJZTfe1PL/jeYXKdSi6mH",
+	"a1hg1tOi9rdSuKoo2reSUk8hRwmGkc+SozxVa9CC2X3H0NYU22KtbJr9igePoJD2iBxzYdmSVZhAnFCO",
+	"Al2zSflgq4C3qtKrkvGVgXp+OjgA6qOYXdftkjMqcExy8d62CN5MYMBRwpq6ALHIGQ4T6ZoslAx0YmyD",
+	"10YuFANJiNYV4VuB7/PsIHj4saFjMTNrOrVrBQJz+pPcTZjFmEoLBgMxZbqkv0aCzCPjWQ/Ais/KNash",
+	"dCsrDVdYTDO9CxY20CjKqTxsuIXupYwqKqBBg6SzqDkAeS1EGerc11sUnU0a+/+oy00slX9tlsj8mzlT",
+	"0TCpfy+ft4+5ZbxhXuuR+De0CdI0+gSCrh0cyr8tIuVfGpvXcue9GEzQCh/Ry5wzkfhfu9HCEJkpKYwB",
+	"WkcBBEb+MeZKQyfm1El4RqQO603ZvXhoAe8Z4rKRVvM8uQMPZBgq7usPMBAJtlPPhS4VIPwAR74HgeAv",
+	"78GXbTV7cOKpX2d5WEh3mp8eisSj8jS09L2dy2WiLkf5yTmXJujJRyJCedDyaiHKSghff0wYns54PqI6",
+	"Z2hyHuqu6j7Xod/d7G70N71++7NgvRpCmRVgBCYRnJrYr2QWiH+a4Er1/JPaf5PpQSZ41XHySGsyjvWC",
+	"CpraqiUpBVkZg64nVls8tR1Ermd5Lp6axU3PTersoLMZvrOVDzj23E/WwgPJssbtO7gaek1EX5tr+w03",
+	"vqlnVXqwtTOKOI5v6lnlM7qu3xor2rruq8vpSXmjTsi96q1j7v0+F2bXqwmmyibi0Asl6D70Youf1qaT",
+	"mj2KWaDuQRc1exT9gu9LBzW7+autyX0vPy9XR5wnqdQe+UvSfScN2ddokZgs8VzKcvPnNMKBR2fiFMm/",
+	"RxleNeZFGqF8bo7+utQcZrpqWneG9ig6p34LuKk9jFUpsjlcSs/YzNNTaCdNrWJhikDzmC+NeQKJApaB",
+	"9o3WEJqyhzeELoju2AS4jdo6SlN6jjZHZBrEKq+HDNucyuhsjKpL26K0tUBVgWYZJrc8KfUfhN+swLzJ",
+	"K5CP41dRkyaaX61bxdu31QhMec5LJUkUt9UTWj5G9QnyEn7FE00lo7jG5NrkovDY8GUbLT8IBbXQcRgX",
+	"SGEP9/oo6pF1ZofKQSGW2a0EKagewM2LwameqAkgUxqNgBKdx0V1AFIyz4roJcJqpmsNVkLFZ5hdzynx",
+	"uiwoMGQAv8wpbip/yl9sQUfRWcD65vJg5Uw0hMtvnSSEy1VTyHQhaylUbPxr2VLyUkk81yohamV2GVc7",
+	"wkyCX33anXSq941oUQAXcOPblKaPMIs0VVyN96hlq/ckS5VKp1wBde2VI/5p2JTX4UUBEqPkWm9vJQGI",
+	"NpbSyq0ycr5WHfzNQoij5XWCmE9JeInnSNMLjnSCGaBiYmWPfF6tfre/2er2Wt3+Zbe7L///g5c5CqBr",
+	"TKrb1Zu23+r2Vk1beiJmyy5CVLndwpiX8O98xzojHRFeUZ0mofP8baNx60Mnp56mvfX+a3IS2X2F62cJ",
+	"2iqGo5OEiU3SF7AOy1L8LAt215ypCUIUIelfb9mzTLJdfSxEkv3Uy11O1Qfl7CYnMM5femyVg0gZwGx5",
+	"5bFz/YyI7/4xJza3sLyeLqTpOHIUbySdj91j6j91usip91s+DdjatBGWBdQilm9l0w4u78OmdZg0CqsW",
+	"q7MrKWGtxnpL5kXF1H05vfSYdiNcWOwONAukVY/1o6Q6osFNRYAS/yaw2XVJ48TYrJUwCAaDweDJxqsv",
+	"8KBX1wXVjOcD9m0WOJCHt3ZEgWkoXiJv04igBI5xhMU461V7ZQX6BMvnlEpDBuaUibvxVnAGo9GsxUZd",
+	"SLw8lAlb/33tjbJPkt8YnuBbb/IeJ1SlNqRD3af0/NMz5+DOpnA0q/mFezTldyi8djY3vwOaHEw+UHyH",
+	"lE3r1h21qZ28EvTImISdFBf7G+1ue6fV22mjaK/afJ71OHh71Op3+xutbn9329tBZ8PKwe2ZcbtqxjgL",
+	"M8q6yWJqLGpFeOxlnJLqNA5t6FaCOQ5kHRddR2aOQpyKezKiC5lCWz4k/SqAitTBFYWrTzC5MamdYHiL",
+	"mfJQrmPD1sv1Yc5ZV4lahhnBFhya5aUl4+H06bRegna0Um5ggyovX5fY834RePR+0Jj2fjNor2G3uP8O",
+	"ZszySltb75VqwLUKukY9YQg0+nIlOUhP8AQFCN8i/ejUNmj9EAqcpIvluFpxIhfYWPq/N3FBTSeXyjjX",
+	"ElUqtfkaZwqN4UMk8v4l+MFizvLjLn+EaVHva03jXmhX+ANsjA8Lyl/e2Fjc/BIckHOhKKwQxtccFI2+",
+	"6gY2BVIxTfhSRxQzDjQEwIqd5VGqwohLQcPOD7lr+1qIDvcPIVaVGRHh5sX2riXTk7aeKIJrGbzqHKF1",
+	"eA9Bd/xar1mjrYgcRIT7ktKtg9BMIbMdyW4oVJFEjft6aWT5xotR4lbqsfir4emm2NO1PxW+jkp32b7q",
+	"EZqEmpyWiGBNqqlCHpQ8hrDJx51HUlMTlpDWmHpnqzpeaTwiJgdmOYeVJe3sQVTPi84Ef7sb4XjU2fNW",
+	"9z74tmCTKt+2l1kGCeHm1ho+H4hakUV3Ze0AJm/lABIZijWWXso8wejW4FYhLxd0sp13a9tu1hT5ssCT",
+	"bHq5ndYHrSnTNc8oU4pnaXJnNFK58rUbqc3M7o1TCXAu9kndE7kb5P6RK+pq1+hesY0PfJvXdfT4Kt8Q",
+	"E+qrTGzyqcmCQ5FwTnBqx1kHbnmrBEhDrp7yjUEsFP6g3+5q6SZD8mKxaEP5WQYf6L6sc3J8cPRqeNQS",
+	"OfBnfB45z4LGsbsHTloG++Zp9NpdUwUaxrix3xDvnl5DFaKRSMvlcGWdP9ygx6+igdaiWD+v47Cx33iG",
+	"+MDtJ0fUOWuZtDXnseaOKlV5im9yCiLB4dIYwFuIZX0ZAAsD+6qMYiIdZ6SmRuPWnaLhbqryDVGEcJ+C",
+	"b8JK9jHj1xJb/W7Xydck/umWUvmkk93WmyuPQElyhWsUmErIFcgxrvs4AZAxGmAVC5rlfxZ7v9ndWAGy",
+	"W/2lPuj5wjQe0E3tPcEAi/X3xOX5OUUyEBOzXJStPI5WCSJIT+sN/Yt2VuqgqKropBy8A9MQc4eui9Zi",
+	"niZEXb/zlENVzgaKahxOEbHCM2oOQ9QEBAnjrUijnTAuqlNTMlUX9mJGZRuVKT0Dn6rwOXUblM+XAPSE",
+	"TtcdrTm8AyqTrwAOEZ5gxJo2y2mv2zXnRSI9OzBScm+4JyNLA9ztOomA1V8rMgF/bRaB0mCAWGyQehRk",
+	"IFUBpNr5IXIh6Hog+KEHVe+EvYq8Z1UvVRGs6AEiOq0iaPPdR0+KTqV4yTp/4PBrJbVmGYygEkd9dHQg",
+	"PgyNHLWSlFQ0hxzJZEfiFCiVt4fj4nAln83lsF37plwvOv/QPS6UWSjtr4sUz6bmdkK/EWQXvZnqJynD",
+	"UF8pL9PHVDXI76KOdjvWH7WI8YSGywdbv54iK3hSwoCpMmYKuuhMDhryMil8Le1W7+GhrT6QBqNC5tUG",
+	"QnUbdn/+bei+HPXmictxDiNB8ij8c17T627nPM26dM5WyY0Hps297rUs1ubXXmwGjp93szXLoYeR8Ze2",
+	"0FDilkYCyiomm2EGqHG/lnFgOqGhKWIK5mnEcRwhwPHcOqd51qDCvJ0yM+5q6pV8y9WYKjzDfiRzNyS3",
+	"+gI3wnaQEahST0l4ji7h1KN0QvAGiE9ZRL2aogkYIiHAMuXq8aT1ihLUOoVcPXpkCcopMkUM87gsXnsC",
+	"1o3upr9SiJnPRJIxOEfa/ww4YUESREzykHjuMXF7RREKTJKAOEG3mKasHJ9s6pFEdDqVWeulgJxnA52x",
+	"nKby1jP7It5/nIJ+VxGxKcto1xOUq+NIIxIsVnKX5XByr4U2GERRGXpZYk0EqaNQl7CUXqCYiRytc8yl",
+	"OwmeOCicjwhmtmAKcT6owfQ16EZTpxFniqpsEUuW1Y2QAwlVmgDyzFhicn1VhL1oLQUzlYrRAmjmtEFh",
+	"coYR0Q3EywVzExEOaBJmZYQMHnxPD1fYeCL378dIHHJsn9jx88SIPAgreINrSJOb4kgU/e7OTweI0Sxd",
+	"lAUsoGkU6pheSyTrZZ4HgbD5s8QoyVFywpMkf4MQzJn/sOvz9sskLQG7Kpmnt82IXuhOOxD5hSvD5zKH",
+	"Vkrs0grcFt0ZZ0Lva3Eok5iwguAwyYwJvU3hxcuM/7J7FdiUEHNVm1Y9YAWvS6QnHyZTHy85khDVlfiy",
+	"qsJidrWaTLYK2G2FZKL6+aWrhupmLWDyL7m3PrPEf0StOvyhFgR60xUF+GttiB/QHe+ITclNUJKAVjyo",
+	"mNG8aWev/DFSRJQz3c0wkwmPqjUvniTeiqYixJEv4bv4nWUv/2ZuPpmMnXEs7xBZ+4cuYBLqCpq+U6MG",
+	"1Ahs+DerEGD5srBuBWsGkkB+BVOwigtD17qLTRmDuVqQ83KdIaHGjRFRbrA6c4EaSou0ki1bJ1kQpmqB",
+	"AEUwZoJrG0FJdZNDECDT26uE6hVq0Vzl03Uc5TldAKmHFakYIOZWas20W6aMOBQbaKGUuXY2ukwG2Pfn",
+	"TQC5cizsz9tAza2Yp/XtDdwaq2YNI5KIiE8AF3BZfdwFZA2/5my7y36yIiyP3xWKFWua/bd7JLHKM9P4",
+	"uoYgtYbVHm2PVtUynZ+sXK1ifR1dWbf6GTdQDXIc0NaFcwoCe0sNi+/hiLCIcseI41QLtnlqNBy2vp6U",
+	"oBYzapOAodCp+JtnHBrEjKfW3yVBigYFf6oN+5VMIHfBQWYQ9GvlaxegjCTGy+zMKxWFAHHv14Koclwa",
+	"XyVbQTrPa9TPzi3u71Bxak2sRS1bZz6ADuig5UkaZdLAsXqBOJGzI6KLpMgC0XRB9Bd52OGkIDkDGyth",
+	"bmEslBUiPESIAiott1LKsHSu5APx7LBuT6KDW3xlMiJ618ZRBp/N5qszXOu6iwkygyl6GBFVa2eCM62I",
+	"aioZupLrRQ/5RhMhwU69dJt9BzMt2ek4bgm0EnowZw5WpdJAx3wGQhWzRrwZmJ73ZlWZzTzb0X83vmWx",
+	"V8dAlB0UyRg2C8DIF0ocQUzu+UZ5o7zCsyMfVno35M6eFSUqj7YyLFZrVSPpsgezoytLHZmh7GkCj+CC",
+	"PXIes0BHd0ZCyRjLh5OYquJtL6f51gvVWKv/ZGT5A+yqYqH1rKpiSwhaWNz8RHOqAnLFWVFkkDem5vVV",
+	"Yoj61Lv+VnKcn3R9dtXR1Fx0Aj+1x4/lyiv4qjob38xUNQh/Mo7aXGM6lUD/csOpQt2/hEOQoqI6l4sm",
+	"9jLjt5RU78wUSsjVkulUpzniECi3WsdiMJfaW1n+rqX/tMdH3g//VLk62mLKf5qyezqtBhUxu7I+pk48",
+	"WnSDNhX12uBYvNyEBoYBUYpSwcE6IlhkI5AKO8AX1EkHWhhBNkS6fYJYYQ3qcztb6T+NucvJbF6E6TJD",
+	"ATaJFQGb0URcfCvE1tUS24Ec0abkrsdi3HlcNqOgc9H6FxbhaMAR1ynU82fMzjPGBHpDFaseUasI2y/H",
+	"/YQHXlnic/N1KnqTiTskyVVIg0IgkHXgbUVOK6/ljhkk+Yy0K7iHigqoxTOYUW1mb05Zs4HPEppOZ01A",
+	"o9Dq2puCZhlCupaoePuICCVoSvmoONK8vtRG/Ws9qXoMyRHUKfVXZcTOi371OTxSi73v8ft3eyJpNFWc",
+	"ML0JBVOJo+T8JefLEAOhXGWjrzhCZejr3LGqgvcKLSe7YSvK61NbXV9X02L5ktICihGx5gKVKUwmBqMJ",
+	"mAZxpjrFxNFG6DwhahEqMqo9Ipe5Yv48gdKRRZownErcBgAfwL5DpOqCf+uTTuPv3+BNV6ifvvZRZyni",
+	"pz7qDJTVQqo2jVhyEXpTUz42f7J8pF3/TH3Ta890/b73nin7/80vPgvGX+vNZ8D+1a8+i75/iXefoaY6",
+	"Lz9L+uU7yqGpWqdo7lQf9p4i00AdjKJNsvp02LLG9zoddrZVsSH/upKTRdqKzZ9nbYqbbz75zceVNJBV",
+	"dl3PSz1lc9UjYngyHIBsJAHCjC6U3L3WAqRYsNQE7GdiPEqa9nWdi2+A0rDPgCpm2tTVpVTpY8vTiS5E",
+	"KkdQqDBuIaWY+wR8ouM2GOr3ulkZ025WuowUdsxF3vL/ldJPdiyyErTffG3kkPznvjgU2RShxgRAcDgc",
+	"HgFEblFEY2Q0JdqeqpA6Ig5WKx1cVE8/P9dh+KWaXN97kutlvfbVoV6X/llg5UgjRWR99gtW+WNWej49",
+	"HFta+2zS++YAJC327MakEBqn3D0cUstPqK00c4OW/iffg5rG1hnlf6AxfgZZLk1jxvuipdTgaIQ4lkLv",
+	"uzO/5XVudl3zrPLNeSG/5xxrsMr44pjxEgSZW9Lxk/bZlRyYjQgRLsSKcfvcalSHsltNpnAZkSq3GgXf",
+	"t74Y9er/HayAxmFe7029QIdf6M9jiOI//jwP6M+jkPpt7jxsTOdrJb91MpajinKYXCa7SQUSoxO+kNUh",
+	"hWMLnYC5rt7FlOkkpEEqRUrMwBQRwQ40iwDaoIPnCGD+KHfHWG9fOM8PkbnKGtML5Gtcf5+cnX6zYCY6",
+	"/+lFsuH54TvQb2+Iu+dgKU2Fh+9Ar70FXgzPXn1LFASLwzsnDEL/Gaixw7vGxwpWWJsfiRE9B6ycfc3t",
+	"dEvCtoWhRm/vEdQ7ul5D/e8grlRpxCvPdF1J5TafpnctK1qYeopuxyXQSWmV+t5oui3LUjry8tOzWSwR",
+	"LgQySrSXnu0uFqgmEA7F6w26GVeyOUrFEDco5gCqiASdYVwFOQkVu1qUYHGrmVQhrfF3mYMLuP93cuir",
+	"yg5dcUTcXLN8pqjhT2oONpKNNAgroq04vMXtrz47+XOcC41elZEhn/TqB+5mbqJVezmw5oDcIlRKCvlA",
+	"ERzAlr5ugyGdo0JbZV8WvwQymptRcaKxjsiey7gcQjkIaKIWHJrMijkwwW/i0vwdqDXkkksJQBQX+LcL",
+	"geGzErpt/i1Os31SlDhNYDz7HFVfGilRpksYttSSVQeVJkxsHYDgFqNFPhOI9gjX3gXKAUH9pr2rMAMT",
+	"xKU3RT5wVl0cekvFG3lEAADKCfa1mBP8oX4BdrrfpJlkHxwTDv4urClNbc4wP3WboBi3uQ/+MZS7818f",
+	"f98H/9BXw399/K/C4L/hcB8cH/7X7/tZYXvdQCzE/Sz+Vh+/OjDrXhnUuof9UxYzENfEPlAQ2QlsMk3z",
+	"xXbSuNqXQqf9VaF7H+SelDlwa6BKYkO0tbjwrEYNDf4ozlwAU9dmMF/dRE6miUyMoNbhmU3AUYm5LE13",
+	"/udvRVsZPBcW9+vahYsepR91kbfc7F8FfR+48Yn61N8v/FurgdyjY5VImPCEsljqDNrOdI5DUaLEN+Wn",
+	"kIlWcupAujw1Fb8zwI2IY/pVSizVTo6l3paZO5EqHSr4s6i1LRmHAG9ETLZTE8HZ63a72m+MNdXKdGCu",
+	"E2YiKVjJh5DdjIhvniwmtGuX2QQTjIR75Bgt5Y0ixGCYoBGRNOhNuCEZzjPB8V6frJMTBRyGOZpXdMV7",
+	"0PxZLQmufYdK6QMmWJVe1bSiebLUFXySisexq8YrQGG7N+49s8WS1BWmRIk4ZtVqt3R6hIrJ7QivdFWT",
+	"SgB+pAyrt7aGJ4awieexbJW8+ewxmsh0nSE38Yem9nCk5d6fvgx18rJUOQXhQX3OJVQQhyxzhy4FiUoU",
+	"GAlCihOiHnYtXZhoWB5QVveXw6oDru0jch6VCyj39KNccHpAE5CgW3qDwnzCAcUPzUxzhqJbxe2M4n1N",
+	"PLtT1vpHit6+6tkVtiJt86mycrhN/EkUmhXWjCGnMn+r6Lp6WxAJkmUs7hJgYrlUbiftRyvnFrfAYHhw",
+	"fAxgMqcJCq0/epyIgsxqV4zXOoc3aETiBAUoRNJEc6v1Ao552BbfN4tkSCZSYm2gE0iPiJ1bJa9mbuJt",
+	"m3RbujGZmgrWAz23XnmJ2czk0dKlw6Z0q5V9iqnzX6Jly3qZ6/z5kgZlaR0IGCbTSC1K5eQaEXFXycor",
+	"cZoUorMNdUtrTBzBAAHsVb8eSIEno6IflBQqm+AXpYRyVljB4ARmFzIOStDcL80peYPynPYXGUKge4Q0",
+	"jSm4BP0BGImHXVE1qcR2SbCOmdmw92Le2xVss75qzZ3pXz6h7XpCXmvf/4k6siIRFLMSVhNJR93K1Tb0",
+	"U5jcmEsHquxgCZ1jmYPGhmSkTDFBMQ+AZCnukzZ4IyO5objJF+aw2fj3zGFKCjDyYtIXg2LkcjIywdM0",
+	"kTUpl6uvGKnkhjIPq9/ULpb5H7L/brLXUtyfj+x/oRnb3GkGN36Wrb6uOY1SoFjl0SKlDHMgrSCi74qx",
+	"CB8Sp83RSUpVpRVdlCAVhfo8Vh5e3xGSsH3nEdKQ/olO0o+Uwk61nfDPJ4Zplvynk7/+w00yblJ6Olcy",
+	"FvX0yHGWHC8ospmU6eKc6yMazVuJqRoc8pkvmU/hHb/ueS7rVv+Fb93mfwqH/Ox4kQLx1K8fkjLnjz+/",
+	"fC6dPfKH14lxtlFgnT+caLPjFXVNnKSUOlhG6putF4YWpb1xjUIVMiJZpJqT+ckmoVNjro3sV+E+9ymd",
+	"Uoyos+aV6tDIHErqcYRef2OzRqX0nxAKVe1QalCsG/wQfywX097sSqxER4ogdTm0tllylZLhTLV7wXRF",
+	"se/A5VpXtsTeWZhlph2/ClbDr8wvOr2wmlmQAZwyW9r0o1ovC2BcKO0mOMYER6trdZyJjuem4U/yDNHz",
+	"1fMPMauwT21bAqxUvkSccj8+M98FO1xVETHBX7D0LICAo3lME5gsASJhTDHhYI4g4boyToLmMl0lo5S0",
+	"PZlBf1oNu0oS+EMv92snX2FhLUkc5Jv/SM/1/ExeWsgDD2R2aZDGIXRzBwIiWT1AkQobq6YGT7UJHyVI",
+	"tY9G4F+QKkqSl7CROhkDJjjSQRqqdGkJLQmd+2803flBINW8QOUPV5RsPN5WEem5aXOfspROMUozh9j8",
+	"Cpnz52yKW8vkngC6XVcCaJzC73a3r6UgAUXX7c1aGfItIAa4aoAYEuN+n0dC/s1iJv/VjxaLhH+JV4s5",
+	"PPWqJdnj+NcrNSplI6WPWMFLLhAMMUHsh15z2SRe0dB+bDa2uhs/Z1bX3175eYm/Mr+Nkg7HhhE78CoM",
+	"i2cbW6OzGaZzVQfKeFr5PDd0/o8YJWBOibCTj5dOAlPlAqotixwmU3EMrQAwxyTlSPueccGrshIENJFj",
+	"wBtERiSN9QsTJ5mVR9U9EYnqprKyUlbymYExDG4q3pD64S8wsLb8iYyekuvK3pK5GiiSy/Z6qg0zhas4",
+	"rYoIUle0T6fU7/Y3W11dCJqjRPT+n9Eo/GPza0v8p//1b3VUSNJ5by3EfGZTy6rGFfByugraXv97oc3X",
+	"lylAKh9T3xJapfuZW1T/GbDb7w+qumcJU4fUvrO2itI/OccsO2OgdMR+ekC7PMzqCDilnQSvZzEkYC4P",
+	"xQwSsLGt21VI+mqZihCq68EYA23HhGmuFD0HutFQ9/qRt0ZpLt9NrdtYO3PVG7jYrtK3K/Us/I18bXnX",
+	"/vDmKf+yf140dR20S/LST9B1W2DMH/fYhjxd6juqpRWwq4oVKTeCfNJ/6wWJyfTaXPlV7n3VBYuMp57W",
+	"eq85AOUCRr9IxT3I/DgSVJmek3EaGxyVs7b7zslKy5ROuwM+B3TR15kaaH7MQok2j/egArQNXiHMZ9qZ",
+	"0XF9BERHm3F6g0iWrMX6naiNdusVSfP68lEWBFmikxH5HkIR/PFeVPIw57ViSh+3rPLO+ZOTZ7GWQAn+",
+	"Ndy8UDYLsntSZ8FlW/mla/uKVBUBQoUhN+dsDbNkt6VM0k2giFqno4Y5wvbNSRNlMC6StyB7k/XZjg50",
+	"GKC0SFMyzR4JZpGqgzPpiIxRQOdF1lkBTjN38NT5LUKGOfNx3ab2xDWMRkWySacTnflTnjiJY0MTWrep",
+	"fV/0kZYWd98p1Jf1ioP4A+5s/2z38vL9JRwhd5Gv5g6/0AtFc/o0iapDL3Jixv34RF7cWNzlJQyvMHD1",
+	"7q8iALyiIrOc1NtH4rBj12+zhEOhvVcoXNzl+32LHCA6DK7eif0bEIaFCWqQcjqXvcF5BLl4e+bn0eZ0",
+	"WTs6cKtD+dhiZuMGl5YjKW2ZCI1bGZqyfg8f5jA60/iu5MXdr7+F70Mj9i6uSSDeK/jAjC7D7NxRrMuk",
+	"oQOZPF2GGhVJYUR8tMAcnZhpb7I16hyMbET+qWKGdZJHW1cB3fEEZkGBmqoMbDMoFT5xQucxV4Entimg",
+	"RIO84k4qUNwPuIeu3v3qu2c1uefumxLp/6Irpv69Uovmi9dJ5xMd14voEw0t4avK37L+mrKtZh+krjYT",
+	"UUekCETe+a9ZFANpygOnxrrQc44I5GJZ3Mn1XpUXTzHPF2JVa5S+eYOWWN6vNmZJFP9LGLL0Fqy0Yyl6",
+	"ZatZuMNrXcIqELL4OcKQBKiVVWJfLSUd2C6qTvZfR2TiM2kFYbpMu19for5ZjYmsGR/RqfGcMEl26whJ",
+	"ww0wToMbxD1DedOtjohz/stykXgiGdBFBpnqZE732J8Hy9XonbMi37RqqxfzZ5CU1pGGU+WoAvh7SUoK",
+	"S6wuXTh1EHNaCmTT/yg6a8oQlAUmIV3IsCyV11CK3JhLSSeGTESRyZo1iszt/aH78RnKFiXDyZWRj8Fb",
+	"mZNGe6tqytbSkzRi59Kd6Vxg8tikPEteoaU2k/pabey1eA/qoUSpLjlSroGrlrCJgUZEmxMhWZZ2UIyF",
+	"ebXctvKc/Jh0qr7pfpFEd58T64p3607vLxL2DP0naKoVa3GCJvjONbatEAHve6xX36Ed9Z960qE6dbmS",
+	"IYaCpawoMyAiQdvZz/KqEKLqfWRBoEXBmtfGPaVAveRfHoqhGeu/RsnG4pasq+GRI+Eq+dDyR015BWrG",
+	"cUsy8wgzXouACeILKsKVV8ouc7gUrx2WjueYy+S7QsOez+2da6A08JAsFzMkK2crQnarW1dQ8vH5QCxA",
+	"sosfuDvuNJ79wLG+FCPVwLcVuTbfYLsurvThb63SIn/eDbUGv+6lVMD1L7yHnO0Uk0NMrAbCHpQV91AN",
+	"gsgd1jiN4vUvtvM0iv9Cim0Bri3bVVezLTCxVhZfy8vyU8sh3HqVlc8yUQgmkzrHS3vlOGVdoritB8t5",
+	"IlUwsRp79jCOs+48nv3I4fWvQRW2tEUdkljBXUtb8PDs1Z3iFz0E1hGAy2c9xPCL+Kx0601ELqoEMVZP",
+	"z1uDHnLMNSvHnBWxZqu9rk0Hm+zqZxzhVdN6PbNNc8e3YI2j3co+33C01mHq4U/aWiT9vBN3z/1yD+B9",
+	"9s4l/W/Yv9xRUGnjWjLnt3bWq0xyJZsOdcufQf8VM3pQqZYBzDLWUX1V828g+BVY+QGpUFYg5OeRef1t",
+	"cSm85ha5xH2/bcrRtZLEWkoSq6evyQlvLCvHoDRPKhmksjvPtQu635hnXsQ2N5dICX+s2uu3sP1kXsEj",
+	"op/BMY1wsDQ1vjQsVdEccpRL2eZc9vuRh9Ezmy/+yUWiXk2Vv7un6TccwAosPPzhq0LAzzt49bbAPXT+",
+	"7fiF4p3e5lpiXX0CkUefQ8460kOkFaZJKXa8+uDPUYghsed9b4vPQIySABGOI1uXVKphnXgwcfRFXlUX",
+	"EqYiuaSCS6l2TZBYKsZSwV5OxZdYuJS6cYCl2BY3braZZYnVMJSMRtovRikA2+ApxBEKTWvtnqmLuCvz",
+	"sbb5aBTIkPEppSFAjOM55PnVqwRIcjSwkI4O8Kaq8ozMEHto92GNyllMMYGJjF1zwM3Dmul7N7phhcZX",
+	"rbwiOEp1M7FRPfHHjvgf9fteN/zZQVIFJFWaQATCLU0LqgG1ieaXxEOpXag+5SYdCmYcByxHY3rzBWWp",
+	"cy3tg/UOMoyx8inMGV10dQSdhqOo4hFBBDN4i1TGckSsq3LmoVjMpa0KAxh3RWmEbYryAIioAy5Wpyet",
+	"csc5P75Uy/qRDidmkpUuJxZllZFYFqf3Sq+tUjTLWvqUTFsRlrUWzGDezTCZoE0JZWXRVrn9xwgmKNGd",
+	"MWEcQZnjCKZ8hgiXGCJTcIshGA7P2kDrXEQdlqyFcfXHgglyQMU0MxhNskRXutioIRmsI3aVkBejZI4Z",
+	"07V/kFP6J6vDlRmydWmXgcXfiIiFEcqlBKizRM4hkT6OtlV1umuznz/KF1EP/4tSXZvp1VrDlbQq8/kF",
+	"tqFLtupXIK5z09plIzaTc5VeXUWkOaiumWcrg01mKRGD/KnT2/5pzAImfVZ+u8ppXksbWj/poro/P6dU",
+	"1bkvs52CiV22ZyJQRx1TRoVs0nS+AUxAnNCpVFF64/aBDNsfEevGzFaF5Dd+dCC2D/MKIQL6VDfx8f+s",
+	"lQmaz6UDLMvhtyhhOJfJLD9tpoNR7kSmvQc3b+2nH1fST0/hYzclEKuUSeVWHVNtoZ6nSL40wyrqpEwp",
+	"JBhHMo+3uEd1kQVjpZeSC05sRQghsRDK15X+uDIQ/0BsmzlWSSQWc35sr8JVtTRyoVEmTiuYcR6zLB+W",
+	"EjoSFCAsBTyi6md4AhlEBIMqDuOb3dRAZW1wJAZQmBcIshn0mIq7cxOBqV2yJTZAscJGqbpGpXygkfuD",
+	"xAM9+i+SDszaqulFZww3J+OXByhQcwBX6ToUtAAaqs7zDo+wUqRqYSVnWf+mKf2SPX/EuyZEQvxOUAiW",
+	"SBYAA2FC49jPCpRnQUZMNQUgsw1S/BFg/Uf8uY/44xJAyQ1iFX109ObWKYPs1AZiiPBSxIr6kVOXoFRg",
+	"yojc2xtRjKNhWxWZogntMFvFt5CcrQJqh6msS/wny3SdQfyrPSwd3P1LOFmWKKuG1OFsx5+UJXgpvcQh",
+	"qnnBm3iawBCZopqE6KKa5tQzauIN9CXiMI1SLMuaYnmqLhnhtsbmDMYxImJwNCJnyVTKSVKfKdJDgTli",
+	"4m1h5SetR1d5xfLgKl32iCiWFUTYufYSpBsqZ7tcmAWnIJD1jNO4DZ4kdMFQojUzUqtnesqp9ZzaGQnQ",
+	"BE+xX0Mz5AmCcwV2UYDuPaAcZHBWXZXfYkhWB5J7HRY2FzAJLSZTYPdAo/7X+v5ouVUXUnEhnkESspnU",
+	"Cf+ixI6KuAUBGFK3MBl4VbrHUuiZwHX5FNWtOKlgUV5R6jpMk6ix3+jAGHekZqGlo6I7t73G1+bK7+1u",
+	"4+vHr/9/AB4L5iHzpwEA",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -0,0 +1,203 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/redhatinsights/identity"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+const (
+	approvalEventRequested = "approval_requested"
+	approvalEventApproved  = "compose_approved"
+	approvalEventRejected  = "compose_rejected"
+)
+
+// approvalEvent is sent to the approval webhook, so approvers learn about
+// composes waiting for them and requesters about the decision.
+type approvalEvent struct {
+	Event     string    `json:"event"`
+	ComposeId uuid.UUID `json:"compose_id"`
+	OrgId     string    `json:"org_id"`
+	// The user who requested the compose, or who approved or rejected it.
+	User   string  `json:"user"`
+	Reason *string `json:"reason,omitempty"`
+}
+
+var approvalWebhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// notifyApproval sends the event to the approval webhook in the background,
+// the approval doesn't depend on the notification arriving.
+func (s *Server) notifyApproval(event approvalEvent) {
+	if s.approvalWebhook == "" {
+		return
+	}
+	go func() {
+		err := s.sendApprovalEvent(event)
+		if err != nil {
+			logrus.Errorf("Error sending %s event of compose %v: %v", event.Event, event.ComposeId, err)
+		}
+	}()
+}
+
+func (s *Server) sendApprovalEvent(event approvalEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	resp, err := approvalWebhookClient.Post(s.approvalWebhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp.Body)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook responded with %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// reviewCompose looks up a compose pending approval and makes sure the user
+// reviewing it is not the one who requested it.
+func (h *Handlers) reviewCompose(ctx echo.Context, idHeader *identity.XRHID, composeId uuid.UUID) (string, error) {
+	_, err := h.getComposeByIdAndOrgId(ctx, composeId)
+	if err != nil {
+		return "", err
+	}
+
+	queued, err := h.server.db.GetQueuedCompose(composeId)
+	if errors.Is(err, db.QueuedComposeNotFoundError) {
+		return "", echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Compose %v isn't pending approval", composeId))
+	} else if err != nil {
+		ctx.Logger().Errorf("Error querying the queue for compose %v: %v", composeId, err)
+		return "", echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the compose")
+	}
+	if !queued.PendingApproval {
+		return "", echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Compose %v isn't pending approval", composeId))
+	}
+
+	reviewer := idHeader.Identity.User.Email
+	if reviewer == "" {
+		return "", auditRejection(ctx, reasonSelfApproval, echo.NewHTTPError(http.StatusForbidden, "Composes can only be reviewed by users"))
+	}
+	if queued.RequestedBy != nil && *queued.RequestedBy == reviewer {
+		return "", auditRejection(ctx, reasonSelfApproval, echo.NewHTTPError(http.StatusForbidden, "Composes can't be reviewed by the user who requested them"))
+	}
+	return reviewer, nil
+}
+
+func (h *Handlers) ApproveCompose(ctx echo.Context, composeId uuid.UUID) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	reviewer, err := h.reviewCompose(ctx, idHeader, composeId)
+	if err != nil {
+		return err
+	}
+
+	err = h.server.db.ApproveQueuedCompose(composeId, reviewer)
+	if errors.Is(err, db.ComposeNotPendingApprovalError) {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Compose %v isn't pending approval", composeId))
+	} else if err != nil {
+		ctx.Logger().Errorf("Error approving compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong approving the compose")
+	}
+
+	h.server.recordComposeEvent(composeId, composeEventQueued, nil)
+	logAction(ctx, "approve_compose", logrus.Fields{"compose_id": composeId, "reviewer": reviewer}, "Compose approved")
+	h.server.notifyApproval(approvalEvent{
+		Event:     approvalEventApproved,
+		ComposeId: composeId,
+		OrgId:     idHeader.Identity.OrgID,
+		User:      reviewer,
+	})
+	return h.GetComposeStatus(ctx, composeId, GetComposeStatusParams{})
+}
+
+func (h *Handlers) RejectCompose(ctx echo.Context, composeId uuid.UUID) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	var rejection ComposeRejection
+	err = ctx.Bind(&rejection)
+	if err != nil {
+		return err
+	}
+
+	reviewer, err := h.reviewCompose(ctx, idHeader, composeId)
+	if err != nil {
+		return err
+	}
+
+	reason := fmt.Sprintf("Rejected by %s: %s", reviewer, rejection.Reason)
+	err = h.server.db.RejectQueuedCompose(composeId, reviewer, reason, h.server.composeFinishedOutbox(composeId, idHeader.Identity.OrgID, composeEventFailure, &reason, nil)...)
+	if errors.Is(err, db.ComposeNotPendingApprovalError) {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Compose %v isn't pending approval", composeId))
+	} else if err != nil {
+		ctx.Logger().Errorf("Error rejecting compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong rejecting the compose")
+	}
+
+	h.server.recordComposeEvent(composeId, composeEventFailure, &reason)
+	logAction(ctx, "reject_compose", logrus.Fields{"compose_id": composeId, "reviewer": reviewer, "reason": rejection.Reason}, "Compose rejected")
+	h.server.notifyApproval(approvalEvent{
+		Event:     approvalEventRejected,
+		ComposeId: composeId,
+		OrgId:     idHeader.Identity.OrgID,
+		User:      reviewer,
+		Reason:    &rejection.Reason,
+	})
+	return h.GetComposeStatus(ctx, composeId, GetComposeStatusParams{})
+}
+
+func (h *Handlers) GetApprovalSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	required, err := h.server.db.GetApprovalRequired(idHeader.Identity.OrgID)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying approval settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the approval settings")
+	}
+	return ctx.JSON(http.StatusOK, ApprovalSettings{
+		RequireApproval: required,
+	})
+}
+
+func (h *Handlers) UpdateApprovalSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	var req ApprovalSettings
+	err = ctx.Bind(&req)
+	if err != nil {
+		return err
+	}
+
+	err = h.server.db.SetApprovalRequired(idHeader.Identity.OrgID, req.RequireApproval)
+	if err != nil {
+		ctx.Logger().Errorf("Error updating approval settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong updating the approval settings")
+	}
+
+	logAction(ctx, "update_approval_settings", logrus.Fields{"org_id": idHeader.Identity.OrgID, "require_approval": req.RequireApproval}, "Approval settings updated")
+	return h.GetApprovalSettings(ctx)
+}
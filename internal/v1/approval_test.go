@@ -0,0 +1,42 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+)
+
+func TestSendApprovalEvent(t *testing.T) {
+	var received []approvalEvent
+	status := http.StatusNoContent
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var event approvalEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		received = append(received, event)
+		w.WriteHeader(status)
+	}))
+	defer webhook.Close()
+
+	s := &Server{
+		approvalWebhook: webhook.URL,
+	}
+	event := approvalEvent{
+		Event:     approvalEventRejected,
+		ComposeId: uuid.New(),
+		OrgId:     "000000",
+		User:      "approver@example.com",
+		Reason:    common.ToPtr("not allowed"),
+	}
+	require.NoError(t, s.sendApprovalEvent(event))
+	require.Equal(t, []approvalEvent{event}, received)
+
+	status = http.StatusInternalServerError
+	require.EqualError(t, s.sendApprovalEvent(event), "webhook responded with 500")
+}
@@ -0,0 +1,452 @@
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/gpg"
+)
+
+// how long signing the artifacts of a compose may take, artifacts signed
+// with the key of the org are downloaded first
+const artifactSigningTimeout = 30 * time.Minute
+
+// signedArtifact tells whether an artifact is signed, only disk images and
+// installers are.
+func signedArtifact(filename string) bool {
+	switch filepath.Ext(filename) {
+	case ".qcow2", ".iso":
+		return true
+	}
+	return false
+}
+
+// validateSigningServiceURL rejects urls which are known not to be public up
+// front, the addresses names resolve to are checked when artifacts are signed.
+func validateSigningServiceURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "The signing service url has to be an absolute https url")
+	}
+	err = common.ValidatePublicHost(u.Hostname())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("The signing service url has to be public: %v", err))
+	}
+	return nil
+}
+
+// artifactSigningSettings responds with the settings and the artifact key in
+// use, which is what signs if there is one.
+func (s *Server) artifactSigningSettings(settings *db.ArtifactSigningSettingsEntry) (ArtifactSigningSettings, error) {
+	resp := ArtifactSigningSettings{
+		ServiceUrl: settings.ServiceURL,
+		UpdatedAt:  settings.UpdatedAt.Format(time.RFC3339),
+	}
+	key, err := s.db.GetSigningKeyInUse(settings.OrgId, string(SigningKeyPurposeArtifact))
+	if errors.Is(err, db.SigningKeyNotFoundError) {
+		return resp, nil
+	} else if err != nil {
+		return resp, err
+	}
+	resp.Fingerprint = key.Fingerprint
+	resp.PublicKey = key.PublicKey
+	return resp, nil
+}
+
+// replaceSigningKey has key be the key of its purpose an org signs with. The
+// key in use is rotated, or revoked if key is nil.
+func (s *Server) replaceSigningKey(orgId string, purpose SigningKeyPurpose, key *db.SigningKeyEntry) error {
+	inUse, err := s.db.GetSigningKeyInUse(orgId, string(purpose))
+	if errors.Is(err, db.SigningKeyNotFoundError) {
+		if key == nil {
+			return nil
+		}
+		return s.db.InsertSigningKey(*key)
+	} else if err != nil {
+		return err
+	}
+	if key == nil {
+		return s.db.RevokeSigningKey(inUse.Id, orgId)
+	}
+	return s.db.RotateSigningKey(inUse.Id, orgId, *key)
+}
+
+func (h *Handlers) GetArtifactSigningSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	settings, err := h.server.db.GetArtifactSigningSettings(idHeader.Identity.OrgID)
+	if errors.Is(err, db.ArtifactSigningSettingsNotFoundError) {
+		return echo.NewHTTPError(http.StatusNotFound, "Artifacts aren't signed")
+	} else if err != nil {
+		ctx.Logger().Errorf("Error querying artifact signing settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the artifact signing settings")
+	}
+	resp, err := h.server.artifactSigningSettings(settings)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying the artifact key of org %s: %v", idHeader.Identity.OrgID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the artifact key")
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// UpdateArtifactSigningSettings stores the private key as the artifact key of
+// the org and the token of the signing service as its signing_service key,
+// the settings only keep the url of the service. The key which isn't used
+// with the new settings is revoked.
+func (h *Handlers) UpdateArtifactSigningSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	var req ArtifactSigningSettingsRequest
+	err = ctx.Bind(&req)
+	if err != nil {
+		return err
+	}
+	if (req.PrivateKey == nil) == (req.ServiceUrl == nil) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Either a private key or the url of a signing service has to be set")
+	}
+	if req.ServiceToken != nil && req.ServiceUrl == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "A service token needs the url of a signing service")
+	}
+	if req.PrivateKey != nil || req.ServiceToken != nil {
+		err = h.checkKeystore()
+		if err != nil {
+			return err
+		}
+	}
+	orgId := idHeader.Identity.OrgID
+	fields := logrus.Fields{"org_id": orgId}
+	var artifactKey, tokenKey *db.SigningKeyEntry
+	if req.PrivateKey != nil {
+		artifactKey, err = h.server.newSigningKey(orgId, SigningKeyPurposeArtifact, SigningKeyMaterial{PrivateKey: req.PrivateKey})
+		if err != nil {
+			return err
+		}
+		fields["fingerprint"] = *artifactKey.Fingerprint
+	} else {
+		err = validateSigningServiceURL(*req.ServiceUrl)
+		if err != nil {
+			return err
+		}
+		fields["service_url"] = *req.ServiceUrl
+		if req.ServiceToken != nil {
+			tokenKey, err = h.server.newSigningKey(orgId, SigningKeyPurposeSigningService, SigningKeyMaterial{Secret: req.ServiceToken})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = h.server.replaceSigningKey(orgId, SigningKeyPurposeArtifact, artifactKey)
+	if err == nil {
+		err = h.server.replaceSigningKey(orgId, SigningKeyPurposeSigningService, tokenKey)
+	}
+	if err == nil {
+		err = h.server.db.SetArtifactSigningSettings(db.ArtifactSigningSettingsEntry{
+			OrgId:      orgId,
+			ServiceURL: req.ServiceUrl,
+		})
+	}
+	if err != nil {
+		ctx.Logger().Errorf("Error updating artifact signing settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong updating the artifact signing settings")
+	}
+
+	logAction(ctx, "update_artifact_signing_settings", fields, "Artifact signing settings updated")
+	return h.GetArtifactSigningSettings(ctx)
+}
+
+// DeleteArtifactSigningSettings revokes the keys the settings sign with, so
+// artifacts aren't signed anymore.
+func (h *Handlers) DeleteArtifactSigningSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	orgId := idHeader.Identity.OrgID
+	_, err = h.server.db.GetArtifactSigningSettings(orgId)
+	if err == nil {
+		err = h.server.replaceSigningKey(orgId, SigningKeyPurposeArtifact, nil)
+	}
+	if err == nil {
+		err = h.server.replaceSigningKey(orgId, SigningKeyPurposeSigningService, nil)
+	}
+	if err == nil {
+		err = h.server.db.DeleteArtifactSigningSettings(orgId)
+	}
+	if errors.Is(err, db.ArtifactSigningSettingsNotFoundError) {
+		return echo.NewHTTPError(http.StatusNotFound, "Artifacts aren't signed")
+	} else if err != nil {
+		ctx.Logger().Errorf("Error deleting artifact signing settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong deleting the artifact signing settings")
+	}
+
+	logAction(ctx, "delete_artifact_signing_settings", logrus.Fields{"org_id": idHeader.Identity.OrgID}, "Artifact signing settings deleted")
+	return ctx.NoContent(http.StatusOK)
+}
+
+// composeArtifacts returns the artifacts of a successful compose, which are
+// derived from its upload status and stored if they weren't yet. Composer
+// doesn't measure them, so images uploaded to aws.s3 are downloaded once for
+// their size and checksum, and signed in the same pass if their org signs
+// with an artifact key. Images imported into a cloud are only known by their
+// identifier.
+func (s *Server) composeArtifacts(ctx context.Context, compose *db.ComposeEntry) ([]db.ArtifactEntry, error) {
+	artifacts, err := s.db.GetComposeArtifacts(compose.Id, compose.OrgId)
+	if err != nil || len(artifacts) > 0 {
+		return artifacts, err
+	}
+
+	cc, err := s.composerOf(compose)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cc.ComposeStatus(ctx, compose.ComposerId)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("querying the status of compose %v failed with %d: %s", compose.Id, resp.StatusCode, body)
+	}
+	var cloudStat composer.ComposeStatus
+	err = json.NewDecoder(resp.Body).Decode(&cloudStat)
+	if err != nil {
+		return nil, err
+	}
+	us := cloudStat.ImageStatus.UploadStatus
+	if cloudStat.ImageStatus.Status != composer.ImageStatusValueSuccess || us == nil {
+		return nil, nil
+	}
+
+	var key *orgGPGKey
+	if us.Type == composer.UploadTypesAwsS3 {
+		s3Status, err := us.Options.AsAWSS3UploadStatus()
+		if err != nil {
+			return nil, err
+		}
+		key, err = s.managedGPGKey(compose.OrgId, SigningKeyPurposeArtifact)
+		if err != nil {
+			return nil, err
+		}
+		artifact, err := s.downloadArtifact(ctx, s3Status.Url, key)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, *artifact)
+	} else if cloudImageId := uploadStatusCloudImageId(us); cloudImageId != nil {
+		// encrypted AMIs are recorded once their copy is shared
+		encrypted, err := s.encryptedImage(compose.Id)
+		if err != nil {
+			return nil, err
+		}
+		if encrypted != nil {
+			cloudImageId = encrypted
+		}
+		artifacts = append(artifacts, db.ArtifactEntry{
+			Filename:     *cloudImageId,
+			CloudImageId: cloudImageId,
+		})
+	} else {
+		return nil, nil
+	}
+	err = s.db.InsertComposeArtifacts(compose.Id, artifacts)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range artifacts {
+		if a.Signature != nil {
+			logrus.Infof("Signed artifact %s of compose %v", a.Filename, compose.Id)
+			s.recordKeyUsage(key.id, SignArtifact, compose.Id)
+		}
+	}
+	return artifacts, nil
+}
+
+// downloadArtifact downloads the image behind the download url of an aws.s3
+// upload for its size and checksum, and signs it on the way if key isn't nil
+// and it's an artifact which is signed. It's named like the object in the
+// bucket. The url is presigned and thus not part of the errors.
+func (s *Server) downloadArtifact(ctx context.Context, downloadURL string, key *orgGPGKey) (*db.ArtifactEntry, error) {
+	u, err := url.Parse(downloadURL)
+	if err != nil {
+		return nil, errors.New("the download url is invalid")
+	}
+	filename := path.Base(u.Path)
+	if filename == "." || filename == "/" {
+		return nil, errors.New("the download url doesn't name a file")
+	}
+
+	body, err := s.gpgClient.Download(ctx, downloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	defer body.Close()
+	hash := sha256.New()
+	counted := &countingReader{r: io.TeeReader(body, hash)}
+	artifact := &db.ArtifactEntry{Filename: filename}
+	if key != nil && signedArtifact(filename) {
+		signature, err := key.Sign(counted)
+		if err != nil {
+			return nil, fmt.Errorf("signing %s failed: %w", filename, err)
+		}
+		artifact.Signature = &signature
+	} else {
+		_, err = io.Copy(io.Discard, counted)
+		if err != nil {
+			return nil, fmt.Errorf("downloading %s failed: %w", filename, err)
+		}
+	}
+	artifact.Size = &counted.n
+	artifact.Sha256 = common.ToPtr(hex.EncodeToString(hash.Sum(nil)))
+	return artifact, nil
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// artifactSigner is how an org signs its artifacts, with a key or by its
+// signing service.
+type artifactSigner struct {
+	key     *orgGPGKey
+	service *gpg.Service
+	// the signing_service key the token of the service is
+	tokenId *uuid.UUID
+}
+
+// artifactSigner returns how an org signs its artifacts, nil if it doesn't.
+// Its artifact key takes precedence over the signing service of its artifact
+// signing settings.
+func (s *Server) artifactSigner(orgId string) (*artifactSigner, error) {
+	key, err := s.managedGPGKey(orgId, SigningKeyPurposeArtifact)
+	if err != nil {
+		return nil, err
+	}
+	if key != nil {
+		return &artifactSigner{key: key}, nil
+	}
+
+	settings, err := s.db.GetArtifactSigningSettings(orgId)
+	if errors.Is(err, db.ArtifactSigningSettingsNotFoundError) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if settings.ServiceURL == nil {
+		// its artifact key was revoked
+		return nil, nil
+	}
+	signer := &artifactSigner{service: &gpg.Service{URL: *settings.ServiceURL}}
+	token, plaintext, err := s.signingKeyInUse(orgId, SigningKeyPurposeSigningService)
+	if err != nil {
+		return nil, err
+	}
+	if token != nil {
+		signer.service.Token = string(plaintext)
+		signer.tokenId = &token.Id
+	}
+	return signer, nil
+}
+
+// signArtifacts signs the qcow2 and iso artifacts of a successful compose,
+// if its org has them signed, and the ostree commit of edge composes which
+// asked for it. Only images uploaded to aws.s3 can be
+// downloaded, and thus signed. Artifacts which are signed already, with the
+// artifact key while they were measured or when this is retried, are kept.
+func (s *Server) signArtifacts(event outboxEvent) error {
+	if event.UploadStatus == nil || event.UploadStatus.Type != UploadTypesAwsS3 {
+		return nil
+	}
+	signer, err := s.artifactSigner(event.OrgId)
+	if err != nil {
+		return err
+	}
+	commitKey, err := s.commitSigningKey(event.OrgId)
+	if err != nil {
+		return err
+	}
+	if signer == nil && commitKey == nil {
+		return nil
+	}
+	s3Status, err := event.UploadStatus.Options.AsAWSS3UploadStatus()
+	if err != nil {
+		return err
+	}
+
+	compose, err := s.db.GetCompose(event.ComposeId, event.OrgId)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), artifactSigningTimeout)
+	defer cancel()
+	artifacts, err := s.composeArtifacts(ctx, compose)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range artifacts {
+		if signer == nil || a.Sha256 == nil || !signedArtifact(a.Filename) || a.Signature != nil {
+			continue
+		}
+		artifact := gpg.Artifact{
+			ComposeId: event.ComposeId.String(),
+			Filename:  a.Filename,
+			Size:      *a.Size,
+			Sha256:    *a.Sha256,
+			URL:       s3Status.Url,
+		}
+		var signature string
+		if signer.key != nil {
+			signature, err = s.gpgClient.Sign(ctx, signer.key.Key, artifact)
+		} else {
+			signature, err = s.gpgClient.SignWithService(ctx, *signer.service, artifact)
+		}
+		if err != nil {
+			return fmt.Errorf("signing %s failed: %w", a.Filename, err)
+		}
+		err = s.db.SetComposeArtifactSignature(event.ComposeId, a.Filename, signature)
+		if err != nil {
+			return err
+		}
+		if signer.key != nil {
+			s.recordKeyUsage(signer.key.id, SignArtifact, event.ComposeId)
+		} else {
+			s.recordKeyUsage(signer.tokenId, SignArtifact, event.ComposeId)
+		}
+		logrus.Infof("Signed artifact %s of compose %v", a.Filename, event.ComposeId)
+	}
+	return s.signCommit(ctx, commitKey, compose, s3Status.Url)
+}
@@ -0,0 +1,19 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedArtifact(t *testing.T) {
+	for filename, signed := range map[string]bool{
+		"disk.qcow2":    true,
+		"installer.iso": true,
+		"image.raw":     false,
+		"image.vhd":     false,
+		"qcow2":         false,
+	} {
+		require.Equal(t, signed, signedArtifact(filename), filename)
+	}
+}
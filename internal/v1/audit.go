@@ -0,0 +1,102 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redhatinsights/identity"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/prometheus"
+)
+
+// Reasons requests get rejected for, used as the label of the
+// auth_rejections_total counter.
+const (
+	reasonAuthenticationFailed = "authentication_failed"
+	reasonInvalidAPIToken      = "invalid_api_token"
+	reasonReadOnlyAPIToken     = "read_only_api_token"
+	reasonInvalidIdentity      = "invalid_identity"
+	reasonUnsupportedAccount   = "unsupported_account"
+	reasonNotEntitled          = "not_entitled"
+	reasonRoleDenied           = "role_denied"
+	reasonSystemDenied         = "system_identity_denied"
+	reasonIPNotAllowed         = "ip_not_allowed"
+	reasonQuotaExceeded        = "quota_exceeded"
+	reasonPolicyDenied         = "policy_denied"
+	reasonUploadTargetDenied   = "upload_target_not_allowed"
+	reasonSelfApproval         = "self_approval"
+)
+
+// logRejection counts the rejection and writes an audit log entry. Unlike the
+// metric, the entry identifies the caller as far as it is known.
+func logRejection(ctx echo.Context, reason string, status int, message string) {
+	prometheus.AuthRejections.WithLabelValues(reason).Inc()
+
+	fields := logrus.Fields{
+		"audit":     true,
+		"reason":    reason,
+		"status":    status,
+		"method":    ctx.Request().Method,
+		"path":      ctx.Request().URL.Path,
+		"remote_ip": ctx.RealIP(),
+	}
+	if id, ok := identity.Get(ctx.Request().Context()); ok {
+		fields["org_id"] = id.Identity.OrgID
+		fields["identity_type"] = id.Identity.Type
+	}
+	logrus.WithContext(ctx.Request().Context()).WithFields(fields).Warnf("Rejected request: %s", message)
+}
+
+// logAction writes an audit log entry for a change associates made through
+// the internal endpoints.
+func logAction(ctx echo.Context, action string, fields logrus.Fields, message string) {
+	entry := logrus.Fields{
+		"audit":     true,
+		"action":    action,
+		"method":    ctx.Request().Method,
+		"path":      ctx.Request().URL.Path,
+		"remote_ip": ctx.RealIP(),
+	}
+	if id, ok := identity.Get(ctx.Request().Context()); ok {
+		entry["associate"] = id.Identity.Associate.Email
+	}
+	for k, v := range fields {
+		entry[k] = v
+	}
+	logrus.WithContext(ctx.Request().Context()).WithFields(entry).Info(message)
+}
+
+// auditRejection records the rejection of a request and returns err, so it
+// can wrap the error a middleware or handler returns. Internal errors aren't
+// rejections and are returned as is.
+func auditRejection(ctx echo.Context, reason string, err error) error {
+	status := http.StatusInternalServerError
+	message := fmt.Sprintf("%v", err)
+	if he, ok := err.(*echo.HTTPError); ok {
+		status = he.Code
+		message = fmt.Sprintf("%v", he.Message)
+	}
+	if status < http.StatusInternalServerError {
+		logRejection(ctx, reason, status, message)
+	}
+	return err
+}
+
+// auditedBasePolicy applies identity.BasePolicy, which writes its error
+// responses itself, and records the requests it rejects.
+func auditedBasePolicy(nextHandler echo.HandlerFunc) echo.HandlerFunc {
+	basePolicy := echo.WrapMiddleware(identity.BasePolicy)
+	return func(ctx echo.Context) error {
+		passed := false
+		err := basePolicy(func(ctx echo.Context) error {
+			passed = true
+			return nextHandler(ctx)
+		})(ctx)
+		if !passed {
+			logRejection(ctx, reasonInvalidIdentity, ctx.Response().Status, "identity header rejected by the base policy")
+		}
+		return err
+	}
+}
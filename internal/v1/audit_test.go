@@ -0,0 +1,110 @@
+package v1
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/prometheus"
+)
+
+func TestAuditRejections(t *testing.T) {
+	hook := logrustest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	s := &Server{
+		auth: NewIdentityHeaderAuthenticator(ServiceAccountConfig{}),
+	}
+	run := func(header string) error {
+		req := httptest.NewRequest(http.MethodGet, "/api/image-builder/v1/version", nil)
+		if header != "" {
+			req.Header.Set("X-Rh-Identity", base64.StdEncoding.EncodeToString([]byte(header)))
+		}
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		return s.authenticate(auditedBasePolicy(func(ctx echo.Context) error {
+			return ctx.NoContent(http.StatusOK)
+		}))(ctx)
+	}
+
+	counter := func(reason string) float64 {
+		return testutil.ToFloat64(prometheus.AuthRejections.WithLabelValues(reason))
+	}
+	invalidIdentity := counter(reasonInvalidIdentity)
+	authenticationFailed := counter(reasonAuthenticationFailed)
+
+	require.Error(t, run(""))
+	require.Equal(t, authenticationFailed+1, counter(reasonAuthenticationFailed))
+
+	require.Error(t, run("not json"))
+	require.Equal(t, invalidIdentity+1, counter(reasonInvalidIdentity))
+
+	// rejected by the base policy, which writes the response itself
+	require.NoError(t, run(`{"identity": {"type": "User"}}`))
+	require.Equal(t, invalidIdentity+2, counter(reasonInvalidIdentity))
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	require.Equal(t, logrus.WarnLevel, entry.Level)
+	require.Equal(t, reasonInvalidIdentity, entry.Data["reason"])
+	require.Equal(t, http.StatusBadRequest, entry.Data["status"])
+	require.Equal(t, "User", entry.Data["identity_type"])
+
+	hook.Reset()
+	require.NoError(t, run(`{"identity": {"type": "User", "org_id": "000000", "internal": {"org_id": "000000"}}}`))
+	require.Empty(t, hook.AllEntries())
+	require.Equal(t, invalidIdentity+2, counter(reasonInvalidIdentity))
+}
+
+func TestAuditRejectionInternalError(t *testing.T) {
+	hook := logrustest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	ctx := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	before := testutil.ToFloat64(prometheus.AuthRejections.WithLabelValues(reasonQuotaExceeded))
+
+	err := echo.NewHTTPError(http.StatusInternalServerError, "database is down")
+	require.Equal(t, err, auditRejection(ctx, reasonQuotaExceeded, err))
+	plainErr := errors.New("something broke")
+	require.Equal(t, plainErr, auditRejection(ctx, reasonQuotaExceeded, plainErr))
+	require.Empty(t, hook.AllEntries())
+
+	err = echo.NewHTTPError(http.StatusForbidden, "Quota exceeded for user")
+	require.Equal(t, err, auditRejection(ctx, reasonQuotaExceeded, err))
+	require.Len(t, hook.AllEntries(), 1)
+	require.Equal(t, before+1, testutil.ToFloat64(prometheus.AuthRejections.WithLabelValues(reasonQuotaExceeded)))
+}
+
+func TestLogAction(t *testing.T) {
+	hook := logrustest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	s := &Server{
+		auth: NewIdentityHeaderAuthenticator(ServiceAccountConfig{}),
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/image-builder/internal/quotas/000000/boosts", nil)
+	req.Header.Set("X-Rh-Identity", base64.StdEncoding.EncodeToString([]byte(`{"identity": {"type": "Associate", "associate": {"email": "support@example.com"}}}`)))
+	ctx := echo.New().NewContext(req, httptest.NewRecorder())
+	err := s.authenticate(func(ctx echo.Context) error {
+		logAction(ctx, "create_quota_boost", logrus.Fields{"org_id": "000000"}, "Boosted the quota of org 000000")
+		return nil
+	})(ctx)
+	require.NoError(t, err)
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	require.Equal(t, logrus.InfoLevel, entry.Level)
+	require.Equal(t, true, entry.Data["audit"])
+	require.Equal(t, "create_quota_boost", entry.Data["action"])
+	require.Equal(t, "support@example.com", entry.Data["associate"])
+	require.Equal(t, "000000", entry.Data["org_id"])
+	require.Equal(t, http.MethodPost, entry.Data["method"])
+}
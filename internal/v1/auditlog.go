@@ -0,0 +1,204 @@
+package v1
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redhatinsights/identity"
+
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+// Key of the id of the resource a handler created, for the audit log.
+const auditResourceKey = "audit_resource_id"
+
+// setAuditResource records the id of the resource a request created, calls
+// on existing resources are recorded with the id in their path.
+func setAuditResource(ctx echo.Context, id fmt.Stringer) {
+	ctx.Set(auditResourceKey, id.String())
+}
+
+// recordAuditLog writes every mutating call, along with its outcome, to the
+// audit log. It has to run after the body is limited, as the body is read to
+// digest it. Calls rejected before, e.g. by authentication or rate limiting,
+// are only recorded in the log of the service.
+func (s *Server) recordAuditLog(nextHandler echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		request := ctx.Request()
+		if request.Method != http.MethodPost && request.Method != http.MethodPut &&
+			request.Method != http.MethodPatch && request.Method != http.MethodDelete {
+			return nextHandler(ctx)
+		}
+
+		digest := sha256.New()
+		if request.Body != nil && request.Body != http.NoBody {
+			body, err := io.ReadAll(request.Body)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("cannot read request body: %v", err))
+			}
+			digest.Write(body)
+			request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		err := nextHandler(ctx)
+
+		status := ctx.Response().Status
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			status = httpErr.Code
+		} else if err != nil {
+			status = http.StatusInternalServerError
+		}
+
+		entry := db.AuditLogEntry{
+			Method:        request.Method,
+			Path:          ctx.Path(),
+			RequestDigest: hex.EncodeToString(digest.Sum(nil)),
+			Status:        status,
+			RemoteIP:      ctx.RealIP(),
+		}
+		if id, ok := identity.Get(request.Context()); ok {
+			entry.OrgId = id.Identity.OrgID
+			entry.UserId = id.Identity.User.UserID
+			entry.Email = id.Identity.User.Email
+			if id.Identity.Type == "Associate" {
+				// support acts on the org in the path
+				entry.OrgId = ctx.Param("orgId")
+				entry.Email = id.Identity.Associate.Email
+			}
+		}
+		if resource, ok := ctx.Get(auditResourceKey).(string); ok {
+			entry.ResourceId = &resource
+		} else if values := ctx.ParamValues(); len(values) > 0 {
+			entry.ResourceId = &values[len(values)-1]
+		}
+
+		// the call already happened, so it isn't failed afterwards
+		dbErr := s.db.InsertAuditLogEntry(entry)
+		if dbErr != nil {
+			ctx.Logger().Errorf("Error writing the audit log entry of %s %s: %v", entry.Method, entry.Path, dbErr)
+		}
+		return err
+	}
+}
+
+func auditLogEntry(e db.AuditLogEntry) AuditLogEntry {
+	return AuditLogEntry{
+		Id:            e.Id,
+		UserId:        e.UserId,
+		Email:         e.Email,
+		Method:        e.Method,
+		Path:          e.Path,
+		ResourceId:    e.ResourceId,
+		RequestDigest: e.RequestDigest,
+		Status:        e.Status,
+		RemoteIp:      e.RemoteIP,
+		CreatedAt:     e.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func (h *Handlers) GetAuditLog(ctx echo.Context, params GetAuditLogParams) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	limit := 100
+	if params.Limit != nil && *params.Limit > 0 {
+		limit = *params.Limit
+	}
+	offset := 0
+	if params.Offset != nil {
+		offset = *params.Offset
+	}
+
+	entries, count, err := h.server.db.GetAuditLog(idHeader.Identity.OrgID, limit, offset)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying the audit log: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the audit log")
+	}
+
+	data := []AuditLogEntry{}
+	for _, e := range entries {
+		data = append(data, auditLogEntry(e))
+	}
+
+	lastOffset := count - 1
+	if lastOffset < 0 {
+		lastOffset = 0
+	}
+
+	return ctx.JSON(http.StatusOK, AuditLogResponse{
+		Meta: struct {
+			Count int `json:"count"`
+		}{
+			count,
+		},
+		Links: struct {
+			First string `json:"first"`
+			Last  string `json:"last"`
+		}{
+			fmt.Sprintf("%v/v%v/audit?offset=0&limit=%v",
+				RoutePrefix(), h.server.spec.Info.Version, limit),
+			fmt.Sprintf("%v/v%v/audit?offset=%v&limit=%v",
+				RoutePrefix(), h.server.spec.Info.Version, lastOffset, limit),
+		},
+		Data: data,
+	})
+}
+
+type SupportAuditLogEntry struct {
+	AuditLogEntry
+	OrgId string `json:"org_id"`
+}
+
+// ExportSupportAuditLog streams the audit log entries written between the
+// since and until parameters as json lines, of all orgs unless the org_id
+// parameter is set. Until defaults to now.
+func (h *Handlers) ExportSupportAuditLog(ctx echo.Context) error {
+	since, err := time.Parse(time.RFC3339, ctx.QueryParam("since"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid since, expected a RFC3339 timestamp")
+	}
+	until := time.Now()
+	if ctx.QueryParam("until") != "" {
+		until, err = time.Parse(time.RFC3339, ctx.QueryParam("until"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid until, expected a RFC3339 timestamp")
+		}
+	}
+
+	idh, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+	orgId := ctx.QueryParam("org_id")
+	ctx.Logger().Infof("Associate %s exported the audit log of %q from %v to %v", idh.Identity.Associate.Email, orgId, since, until)
+
+	entries, err := h.server.db.GetAuditLogBetween(orgId, since.UTC(), until.UTC())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	ctx.Response().Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	ctx.Response().WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(ctx.Response())
+	for _, e := range entries {
+		err = encoder.Encode(SupportAuditLogEntry{
+			AuditLogEntry: auditLogEntry(e),
+			OrgId:         e.OrgId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package v1
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redhatinsights/identity"
+
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/logger"
+)
+
+// Authenticator establishes who the caller of a request is. The identity is
+// passed around as an identity header, regardless of whether it was set by
+// the console gateway or derived from other credentials, so handlers and the
+// services requests get forwarded to don't depend on the authenticator in
+// use.
+type Authenticator interface {
+	IdentityHeader(r *http.Request) (string, error)
+}
+
+// Claim of Red Hat SSO service account tokens holding the organization the
+// service account belongs to.
+const serviceAccountOrgIdClaim = "rh-org-id"
+
+// Prefix of api tokens, distinguishes them from SSO bearer tokens.
+const apiTokenPrefix = "ibt_"
+
+// Key of the id of the api token a request was authenticated with in the
+// echo context.
+const apiTokenIdKey = "apiTokenId"
+
+type ServiceAccountConfig struct {
+	// Url of the json web key set of the SSO, bearer tokens are not
+	// accepted if empty. Nor are they without audiences.
+	JWKSURL string
+	Issuer  string
+	TokenClaimsConfig
+}
+
+// identityHeaderAuthenticator trusts the identity header set by the console
+// gateway. If configured, it also accepts SSO service account bearer tokens
+// for requests without one, as CI systems calling the api directly send.
+type identityHeaderAuthenticator struct {
+	serviceAccounts *tokenValidator
+}
+
+func NewIdentityHeaderAuthenticator(conf ServiceAccountConfig) Authenticator {
+	var a identityHeaderAuthenticator
+	if conf.JWKSURL != "" {
+		a.serviceAccounts = newTokenValidator(conf.Issuer, conf.JWKSURL, conf.TokenClaimsConfig)
+	}
+	return &a
+}
+
+func (a *identityHeaderAuthenticator) IdentityHeader(r *http.Request) (string, error) {
+	rawHeaders := r.Header["X-Rh-Identity"]
+	if len(rawHeaders) == 1 {
+		return rawHeaders[0], nil
+	}
+
+	if token, ok := bearerToken(r); ok && len(rawHeaders) == 0 && a.serviceAccounts != nil {
+		return a.serviceAccounts.identityHeaderFromToken(token, serviceAccountOrgIdClaim, "ServiceAccount")
+	}
+
+	return "", echo.NewHTTPError(http.StatusBadRequest, "missing x-rh-identity header")
+}
+
+type OIDCConfig struct {
+	Issuer string
+	// Discovered from the issuer if empty.
+	JWKSURL string
+	// Claim holding the organization, or tenant, of the caller.
+	OrgIdClaim string
+	TokenClaimsConfig
+}
+
+// oidcAuthenticator validates bearer tokens of a generic OIDC provider, for
+// deployments outside of console.redhat.com. Identity headers sent by the
+// caller are ignored, as there is no gateway vouching for them.
+type oidcAuthenticator struct {
+	tokens     *tokenValidator
+	orgIdClaim string
+}
+
+func NewOIDCAuthenticator(conf OIDCConfig) (Authenticator, error) {
+	jwksURL := conf.JWKSURL
+	if jwksURL == "" {
+		var err error
+		jwksURL, err = discoverJWKSURL(conf.Issuer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(conf.Audiences) == 0 {
+		return nil, fmt.Errorf("bearer tokens of %s need the clients they're issued for", conf.Issuer)
+	}
+
+	orgIdClaim := conf.OrgIdClaim
+	if orgIdClaim == "" {
+		orgIdClaim = "org_id"
+	}
+
+	return &oidcAuthenticator{
+		tokens:     newTokenValidator(conf.Issuer, jwksURL, conf.TokenClaimsConfig),
+		orgIdClaim: orgIdClaim,
+	}, nil
+}
+
+func (a *oidcAuthenticator) IdentityHeader(r *http.Request) (string, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "missing bearer token")
+	}
+	return a.tokens.identityHeaderFromToken(token, a.orgIdClaim, "User")
+}
+
+func newAPIToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Only the hash of api tokens is stored, they are random enough that a salt
+// wouldn't add anything.
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func authenticatedWithAPIToken(ctx echo.Context) bool {
+	return ctx.Get(apiTokenIdKey) != nil
+}
+
+// apiTokenIdentityHeader returns an identity header of the user who created
+// the api token. It only holds the org and the user, not their roles, so
+// rbac decides what the user may do whenever the token is used. Whether the
+// org is entitled to rhel is derived from its account number, as the
+// entitlements of the gateway aren't available.
+func (s *Server) apiTokenIdentityHeader(ctx echo.Context, token string) (string, error) {
+	entry, err := s.db.UseAPIToken(hashAPIToken(token))
+	if errors.Is(err, db.APITokenNotFoundError) {
+		return "", auditRejection(ctx, reasonInvalidAPIToken, echo.NewHTTPError(http.StatusUnauthorized, "invalid api token"))
+	} else if err != nil {
+		return "", echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong validating the api token").SetInternal(err)
+	}
+
+	method := ctx.Request().Method
+	if entry.ReadOnly && method != http.MethodGet && method != http.MethodHead {
+		return "", auditRejection(ctx, reasonReadOnlyAPIToken, echo.NewHTTPError(http.StatusForbidden, "read-only api tokens can only be used for GET requests"))
+	}
+
+	id := identity.XRHID{
+		Identity: identity.Identity{
+			OrgID: entry.OrgId,
+			Internal: identity.Internal{
+				OrgID: entry.OrgId,
+			},
+			Type: "User",
+		},
+		Entitlements: map[string]identity.ServiceDetails{
+			"rhel": {
+				IsEntitled: entry.AccountNumber != nil,
+			},
+		},
+	}
+	if entry.AccountNumber != nil {
+		id.Identity.AccountNumber = *entry.AccountNumber
+	}
+	if entry.UserId != nil {
+		id.Identity.User.UserID = *entry.UserId
+	}
+	if entry.CreatedBy != nil {
+		id.Identity.User.Username = *entry.CreatedBy
+	}
+	idHeader, err := json.Marshal(id)
+	if err != nil {
+		return "", err
+	}
+
+	ctx.Set(apiTokenIdKey, entry.Id)
+	return base64.StdEncoding.EncodeToString(idHeader), nil
+}
+
+// authenticate stores the identity of the caller in the request context, in
+// the same way identity.Extractor does. Api tokens are accepted independently
+// of the authenticator.
+func (s *Server) authenticate(nextHandler echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		request := ctx.Request()
+
+		var rawHeader string
+		var err error
+		if token, ok := bearerToken(request); ok && strings.HasPrefix(token, apiTokenPrefix) {
+			rawHeader, err = s.apiTokenIdentityHeader(ctx, token)
+		} else {
+			rawHeader, err = s.auth.IdentityHeader(request)
+			if err != nil {
+				err = auditRejection(ctx, reasonAuthenticationFailed, err)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		id, err := parseIdentityHeader(rawHeader)
+		if err != nil {
+			return auditRejection(ctx, reasonInvalidIdentity, err)
+		}
+
+		logger.Module(logger.ModuleAuth).WithContext(request.Context()).Debugf("Authenticated %s identity %s of org %s",
+			id.Identity.Type, id.Identity.User.Username, id.Identity.OrgID)
+
+		c := context.WithValue(request.Context(), identity.Key, id)
+		c = context.WithValue(c, identity.IDHeaderKey, rawHeader)
+		ctx.SetRequest(request.WithContext(c))
+		return nextHandler(ctx)
+	}
+}
@@ -0,0 +1,360 @@
+package v1
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/redhatinsights/identity"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+type testIssuer struct {
+	srv  *httptest.Server
+	key  *rsa.PrivateKey
+	kid  string
+	name string
+}
+
+// newTestIssuer serves the openid configuration and key set of a fake SSO
+func newTestIssuer(t *testing.T) *testIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ti := &testIssuer{
+		key: key,
+		kid: "key1",
+	}
+	ti.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			err := json.NewEncoder(w).Encode(map[string]string{
+				"issuer":   ti.name,
+				"jwks_uri": ti.srv.URL + "/certs",
+			})
+			require.NoError(t, err)
+		case "/certs":
+			err := json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys": []map[string]string{
+					{
+						"kid": ti.kid,
+						"kty": "RSA",
+						"use": "sig",
+						"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+						"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+					},
+				},
+			})
+			require.NoError(t, err)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	ti.name = ti.srv.URL
+	return ti
+}
+
+func (ti *testIssuer) sign(t *testing.T, k *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(k)
+	require.NoError(t, err)
+	return signed
+}
+
+func (ti *testIssuer) claims(orgIdClaim string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":                ti.name,
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"aud":                []string{"account", "api.console"},
+		"sub":                "f4c3a1e2",
+		orgIdClaim:           "000123",
+		"preferred_username": "ci",
+	}
+}
+
+func noExpiry(claims jwt.MapClaims) jwt.MapClaims {
+	delete(claims, "exp")
+	return claims
+}
+
+func decodeIdentityHeader(t *testing.T, raw string) identity.XRHID {
+	decoded, err := base64.StdEncoding.DecodeString(raw)
+	require.NoError(t, err)
+	var id identity.XRHID
+	require.NoError(t, json.Unmarshal(decoded, &id))
+	return id
+}
+
+func requireUnauthorized(t *testing.T, err error) {
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, err.(*echo.HTTPError).Code)
+}
+
+func TestIdentityHeaderAuthenticator(t *testing.T) {
+	ti := newTestIssuer(t)
+	defer ti.srv.Close()
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	auth := NewIdentityHeaderAuthenticator(ServiceAccountConfig{
+		JWKSURL: ti.srv.URL + "/certs",
+		Issuer:  ti.name,
+		TokenClaimsConfig: TokenClaimsConfig{
+			Audiences:    []string{"api.console"},
+			Entitlements: []string{"insights"},
+		},
+	})
+	request := func(header http.Header) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header = header
+		return req
+	}
+
+	t.Run("ServiceAccountToken", func(t *testing.T) {
+		raw, err := auth.IdentityHeader(request(http.Header{
+			"Authorization": {"Bearer " + ti.sign(t, ti.key, ti.kid, ti.claims("rh-org-id"))},
+		}))
+		require.NoError(t, err)
+		id := decodeIdentityHeader(t, raw)
+		require.Equal(t, "000123", id.Identity.OrgID)
+		require.Equal(t, "000123", id.Identity.Internal.OrgID)
+		require.Equal(t, "ServiceAccount", id.Identity.Type)
+		require.Equal(t, "ci", id.Identity.User.Username)
+		require.Equal(t, "f4c3a1e2", id.Identity.User.UserID)
+		require.False(t, id.Identity.User.OrgAdmin)
+		require.Equal(t, map[string]identity.ServiceDetails{
+			"rhel":     {IsEntitled: false},
+			"insights": {IsEntitled: true},
+		}, id.Entitlements)
+	})
+
+	t.Run("AuthorizedParty", func(t *testing.T) {
+		claims := ti.claims("rh-org-id")
+		delete(claims, "aud")
+		claims["azp"] = "api.console"
+		_, err := auth.IdentityHeader(request(http.Header{
+			"Authorization": {"Bearer " + ti.sign(t, ti.key, ti.kid, claims)},
+		}))
+		require.NoError(t, err)
+	})
+
+	t.Run("NoAudiences", func(t *testing.T) {
+		_, err := NewIdentityHeaderAuthenticator(ServiceAccountConfig{
+			JWKSURL: ti.srv.URL + "/certs",
+			Issuer:  ti.name,
+		}).IdentityHeader(request(http.Header{
+			"Authorization": {"Bearer " + ti.sign(t, ti.key, ti.kid, ti.claims("rh-org-id"))},
+		}))
+		requireUnauthorized(t, err)
+	})
+
+	t.Run("IdentityHeaderTakesPrecedence", func(t *testing.T) {
+		raw, err := auth.IdentityHeader(request(http.Header{
+			"Authorization": {"Bearer invalid"},
+			"X-Rh-Identity": {"e30="},
+		}))
+		require.NoError(t, err)
+		require.Equal(t, "e30=", raw)
+	})
+
+	t.Run("NoCredentials", func(t *testing.T) {
+		_, err := auth.IdentityHeader(request(http.Header{}))
+		require.Error(t, err)
+		require.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+	})
+
+	t.Run("ServiceAccountsDisabled", func(t *testing.T) {
+		_, err := NewIdentityHeaderAuthenticator(ServiceAccountConfig{}).IdentityHeader(request(http.Header{
+			"Authorization": {"Bearer " + ti.sign(t, ti.key, ti.kid, ti.claims("rh-org-id"))},
+		}))
+		require.Error(t, err)
+		require.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+	})
+
+	expired := ti.claims("rh-org-id")
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	wrongIssuer := ti.claims("rh-org-id")
+	wrongIssuer["iss"] = "https://sso.example.com/realms/other"
+	wrongAudience := ti.claims("rh-org-id")
+	wrongAudience["aud"] = "other-service"
+	wrongAudience["azp"] = "other-service"
+	noSubject := ti.claims("rh-org-id")
+	delete(noSubject, "sub")
+
+	for name, token := range map[string]string{
+		"WrongKey":      ti.sign(t, otherKey, ti.kid, ti.claims("rh-org-id")),
+		"UnknownKid":    ti.sign(t, ti.key, "key2", ti.claims("rh-org-id")),
+		"Expired":       ti.sign(t, ti.key, ti.kid, expired),
+		"NoExpiry":      ti.sign(t, ti.key, ti.kid, noExpiry(ti.claims("rh-org-id"))),
+		"WrongIssuer":   ti.sign(t, ti.key, ti.kid, wrongIssuer),
+		"WrongAudience": ti.sign(t, ti.key, ti.kid, wrongAudience),
+		"NoSubject":     ti.sign(t, ti.key, ti.kid, noSubject),
+		"NoOrg":         ti.sign(t, ti.key, ti.kid, ti.claims("org_id")),
+		"Garbage":       "not.a.token",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := auth.IdentityHeader(request(http.Header{"Authorization": {"Bearer " + token}}))
+			requireUnauthorized(t, err)
+		})
+	}
+}
+
+func TestOIDCAuthenticator(t *testing.T) {
+	ti := newTestIssuer(t)
+	defer ti.srv.Close()
+
+	_, err := NewOIDCAuthenticator(OIDCConfig{
+		Issuer:            ti.srv.URL + "/unknown",
+		TokenClaimsConfig: TokenClaimsConfig{Audiences: []string{"api.console"}},
+	})
+	require.Error(t, err)
+	_, err = NewOIDCAuthenticator(OIDCConfig{
+		Issuer: ti.name,
+	})
+	require.Error(t, err)
+
+	// the key set is discovered from the issuer
+	auth, err := NewOIDCAuthenticator(OIDCConfig{
+		Issuer:     ti.name,
+		OrgIdClaim: "tenant",
+		TokenClaimsConfig: TokenClaimsConfig{
+			Audiences:         []string{"api.console"},
+			EntitlementsClaim: "services",
+		},
+	})
+	require.NoError(t, err)
+
+	claims := ti.claims("tenant")
+	claims["services"] = []string{"rhel", "ansible"}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+ti.sign(t, ti.key, ti.kid, claims))
+	raw, err := auth.IdentityHeader(req)
+	require.NoError(t, err)
+	id := decodeIdentityHeader(t, raw)
+	require.Equal(t, "000123", id.Identity.OrgID)
+	require.Equal(t, "User", id.Identity.Type)
+	require.Equal(t, "f4c3a1e2", id.Identity.User.UserID)
+	require.Equal(t, map[string]identity.ServiceDetails{
+		"rhel":    {IsEntitled: true},
+		"ansible": {IsEntitled: true},
+	}, id.Entitlements)
+
+	// callers without the claim aren't entitled
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+ti.sign(t, ti.key, ti.kid, ti.claims("tenant")))
+	raw, err = auth.IdentityHeader(req)
+	require.NoError(t, err)
+	require.False(t, decodeIdentityHeader(t, raw).Entitlements["rhel"].IsEntitled)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+ti.sign(t, ti.key, ti.kid, noExpiry(ti.claims("tenant"))))
+	_, err = auth.IdentityHeader(req)
+	requireUnauthorized(t, err)
+
+	// identity headers aren't trusted
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Rh-Identity", "e30=")
+	_, err = auth.IdentityHeader(req)
+	requireUnauthorized(t, err)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+ti.sign(t, ti.key, ti.kid, ti.claims("rh-org-id")))
+	_, err = auth.IdentityHeader(req)
+	requireUnauthorized(t, err)
+}
+
+func TestAuthenticate(t *testing.T) {
+	s := &Server{
+		auth: NewIdentityHeaderAuthenticator(ServiceAccountConfig{}),
+	}
+	run := func(header string) (*identity.XRHID, string, error) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Rh-Identity", header)
+		ctx := echo.New().NewContext(req, httptest.NewRecorder())
+
+		var id identity.XRHID
+		var raw string
+		err := s.authenticate(func(ctx echo.Context) error {
+			var ok bool
+			id, ok = identity.Get(ctx.Request().Context())
+			require.True(t, ok)
+			raw, ok = identity.GetIdentityHeader(ctx.Request().Context())
+			require.True(t, ok)
+			return nil
+		})(ctx)
+		return &id, raw, err
+	}
+
+	header := base64.StdEncoding.EncodeToString([]byte(`{"identity": {"type": "User", "internal": {"org_id": "000123"}}}`))
+	id, raw, err := run(header)
+	require.NoError(t, err)
+	require.Equal(t, header, raw)
+	require.Equal(t, "000123", id.Identity.OrgID)
+
+	_, _, err = run("notbase64")
+	require.Error(t, err)
+	_, _, err = run(base64.StdEncoding.EncodeToString([]byte("not json")))
+	require.Error(t, err)
+}
+
+func TestAPITokenIdentityHeader(t *testing.T) {
+	s := &Server{
+		db: db.NewMemoryDB(),
+	}
+	run := func(token string) (identity.XRHID, error) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		ctx := echo.New().NewContext(req, httptest.NewRecorder())
+		raw, err := s.apiTokenIdentityHeader(ctx, token)
+		if err != nil {
+			return identity.XRHID{}, err
+		}
+		return decodeIdentityHeader(t, raw), nil
+	}
+
+	require.NoError(t, s.db.InsertAPIToken(db.APITokenEntry{
+		Id:            uuid.New(),
+		OrgId:         "000123",
+		AccountNumber: common.ToPtr("500123"),
+		Name:          "ci",
+		UserId:        common.ToPtr("f4c3a1e2"),
+		CreatedBy:     common.ToPtr("alice"),
+	}, hashAPIToken(apiTokenPrefix+"valid")))
+	require.NoError(t, s.db.InsertAPIToken(db.APITokenEntry{
+		Id:        uuid.New(),
+		OrgId:     "000123",
+		Name:      "expired",
+		ExpiresAt: common.ToPtr(time.Now().Add(-time.Minute)),
+	}, hashAPIToken(apiTokenPrefix+"expired")))
+
+	// only the org and user are passed on, rbac decides about their roles
+	id, err := run(apiTokenPrefix + "valid")
+	require.NoError(t, err)
+	require.Equal(t, "000123", id.Identity.OrgID)
+	require.Equal(t, "500123", id.Identity.AccountNumber)
+	require.Equal(t, "User", id.Identity.Type)
+	require.Equal(t, identity.User{Username: "alice", UserID: "f4c3a1e2"}, id.Identity.User)
+	require.True(t, id.Entitlements["rhel"].IsEntitled)
+
+	_, err = run(apiTokenPrefix + "expired")
+	requireUnauthorized(t, err)
+
+	tokens, err := s.db.GetAPITokens("000123")
+	require.NoError(t, err)
+	for _, token := range tokens {
+		require.Equal(t, token.Name == "ci", token.LastUsedAt != nil)
+	}
+}
@@ -0,0 +1,257 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/awx"
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+// awxExtraVars are the extra variables of the jobs launched for composes,
+// under a single key so they don't clash with the variables of the template.
+type awxExtraVars struct {
+	ImageBuilder awxCompose `json:"image_builder"`
+}
+
+type awxCompose struct {
+	ComposeId    uuid.UUID     `json:"compose_id"`
+	OrgId        string        `json:"org_id"`
+	ImageName    *string       `json:"image_name,omitempty"`
+	Distribution string        `json:"distribution"`
+	ImageType    string        `json:"image_type"`
+	UploadStatus *UploadStatus `json:"upload_status,omitempty"`
+}
+
+// validateAWXURL rejects urls which are known not to be public up front, the
+// addresses names resolve to are checked when jobs are launched.
+func validateAWXURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "The awx url has to be an absolute https url")
+	}
+	err = common.ValidatePublicHost(u.Hostname())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("The awx url has to be public: %v", err))
+	}
+	return nil
+}
+
+func (h *Handlers) GetAWXSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	settings, err := h.server.db.GetAWXSettings(idHeader.Identity.OrgID)
+	if errors.Is(err, db.AWXSettingsNotFoundError) {
+		return echo.NewHTTPError(http.StatusNotFound, "No awx job template is configured")
+	} else if err != nil {
+		ctx.Logger().Errorf("Error querying awx settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the awx settings")
+	}
+	return ctx.JSON(http.StatusOK, AWXSettings{
+		Url:           settings.URL,
+		JobTemplateId: settings.JobTemplateId,
+		UpdatedAt:     settings.UpdatedAt.Format(time.RFC3339),
+	})
+}
+
+func (h *Handlers) UpdateAWXSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	var req AWXSettingsRequest
+	err = ctx.Bind(&req)
+	if err != nil {
+		return err
+	}
+	err = validateAWXURL(req.Url)
+	if err != nil {
+		return err
+	}
+	if h.server.keystore == nil {
+		return echo.NewHTTPError(http.StatusNotImplemented, "AWX settings can't be stored, no key encryption key is configured")
+	}
+	token, err := h.server.sealSecret([]byte(req.Token), awxTokenAdditionalData(idHeader.Identity.OrgID))
+	if err != nil {
+		ctx.Logger().Errorf("Error encrypting the awx token: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong updating the awx settings")
+	}
+
+	err = h.server.db.SetAWXSettings(db.AWXSettingsEntry{
+		OrgId:         idHeader.Identity.OrgID,
+		URL:           req.Url,
+		Token:         token,
+		JobTemplateId: req.JobTemplateId,
+	})
+	if err != nil {
+		ctx.Logger().Errorf("Error updating awx settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong updating the awx settings")
+	}
+
+	logAction(ctx, "update_awx_settings", logrus.Fields{"org_id": idHeader.Identity.OrgID, "url": req.Url, "job_template_id": req.JobTemplateId}, "AWX settings updated")
+	return h.GetAWXSettings(ctx)
+}
+
+func (h *Handlers) DeleteAWXSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = h.server.db.DeleteAWXSettings(idHeader.Identity.OrgID)
+	if errors.Is(err, db.AWXSettingsNotFoundError) {
+		return echo.NewHTTPError(http.StatusNotFound, "No awx job template is configured")
+	} else if err != nil {
+		ctx.Logger().Errorf("Error deleting awx settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong deleting the awx settings")
+	}
+
+	logAction(ctx, "delete_awx_settings", logrus.Fields{"org_id": idHeader.Identity.OrgID}, "AWX settings deleted")
+	return ctx.NoContent(http.StatusOK)
+}
+
+func (h *Handlers) GetAWXJobs(ctx echo.Context, params GetAWXJobsParams) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	limit := 100
+	if params.Limit != nil && *params.Limit > 0 {
+		limit = *params.Limit
+	}
+	offset := 0
+	if params.Offset != nil {
+		offset = *params.Offset
+	}
+
+	entries, count, err := h.server.db.GetAWXJobs(idHeader.Identity.OrgID, limit, offset)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying awx jobs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the awx jobs")
+	}
+
+	data := []AWXJob{}
+	for _, e := range entries {
+		j := AWXJob{
+			ComposeId:     e.ComposeId,
+			JobTemplateId: e.JobTemplateId,
+			Status:        AWXJobStatus(e.Status),
+			JobId:         e.JobId,
+			Attempts:      e.Attempts,
+			Error:         e.LastError,
+			CreatedAt:     e.CreatedAt.Format(time.RFC3339),
+		}
+		if e.LaunchedAt != nil {
+			j.LaunchedAt = common.ToPtr(e.LaunchedAt.Format(time.RFC3339))
+		}
+		data = append(data, j)
+	}
+
+	lastOffset := count - 1
+	if lastOffset < 0 {
+		lastOffset = 0
+	}
+
+	spec, err := GetSwagger()
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, AWXJobsResponse{
+		Meta: struct {
+			Count int `json:"count"`
+		}{
+			count,
+		},
+		Links: struct {
+			First string `json:"first"`
+			Last  string `json:"last"`
+		}{
+			fmt.Sprintf("%v/v%v/settings/awx/jobs?offset=%v&limit=%v",
+				RoutePrefix(), spec.Info.Version, 0, limit),
+			fmt.Sprintf("%v/v%v/settings/awx/jobs?offset=%v&limit=%v",
+				RoutePrefix(), spec.Info.Version, lastOffset, limit),
+		},
+		Data: data,
+	})
+}
+
+// launchAWXJob launches the job template of the org of a successful compose,
+// if it has one, and records the outcome as the job of the compose. The job
+// is marked as failed if this was the last attempt.
+func (s *Server) launchAWXJob(event outboxEvent, lastAttempt bool) error {
+	settings, err := s.db.GetAWXSettings(event.OrgId)
+	if errors.Is(err, db.AWXSettingsNotFoundError) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	token, err := s.openSecret(settings.Token, awxTokenAdditionalData(settings.OrgId))
+	if err != nil {
+		return fmt.Errorf("unable to decrypt the awx token of org %s: %w", settings.OrgId, err)
+	}
+
+	// the job waits for the encrypted copy of the AMI
+	us, err := s.encryptedUploadStatus(event.ComposeId, event.UploadStatus)
+	if err != nil {
+		return err
+	}
+	vars := awxExtraVars{
+		ImageBuilder: awxCompose{
+			ComposeId:    event.ComposeId,
+			OrgId:        event.OrgId,
+			UploadStatus: us,
+		},
+	}
+	compose, err := s.db.GetCompose(event.ComposeId, event.OrgId)
+	if err != nil {
+		return err
+	}
+	vars.ImageBuilder.ImageName = compose.ImageName
+	var cr ComposeRequest
+	// composes with requests which can't be read still get their job
+	_ = json.Unmarshal(compose.Request, &cr)
+	vars.ImageBuilder.Distribution, vars.ImageBuilder.ImageType, _ = composeLabels(cr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	jobId, launchErr := s.awxClient.Launch(ctx, awx.JobTemplate{
+		URL:   settings.URL,
+		Token: string(token),
+		Id:    settings.JobTemplateId,
+	}, vars)
+
+	status := db.AWXJobLaunched
+	var job *int
+	var lastError *string
+	if launchErr != nil {
+		status = db.AWXJobPending
+		if lastAttempt {
+			status = db.AWXJobFailed
+		}
+		lastError = common.ToPtr(launchErr.Error())
+	} else {
+		job = &jobId
+		logrus.Infof("Launched awx job %d of compose %v", jobId, event.ComposeId)
+	}
+	err = s.db.SetAWXJobResult(event.ComposeId, event.OrgId, settings.JobTemplateId, status, job, lastError)
+	if err != nil {
+		logrus.Errorf("Error storing the awx job of compose %v: %v", event.ComposeId, err)
+	}
+	return launchErr
+}
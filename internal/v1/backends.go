@@ -0,0 +1,70 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+// composerOf returns the client of the composer backend which owns a compose.
+func (s *Server) composerOf(compose *db.ComposeEntry) (*composer.ComposerClient, error) {
+	return s.composerOfBackend(compose.Id.String(), compose.ComposerBackend)
+}
+
+func (s *Server) composerOfBackend(id, backend string) (*composer.ComposerClient, error) {
+	cc, err := s.composers.Client(backend)
+	if err != nil {
+		logrus.Errorf("Unable to reach the composer backend of %s: %v", id, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "The build system of this compose is not available")
+	}
+	return cc, nil
+}
+
+// routeCompose picks the composer backend of a new compose. The empty name is
+// returned for the default backend, which composes don't need to be marked
+// with. GovCloud composes always go to the backend which lists their region.
+func (s *Server) routeCompose(cr composer.ComposeRequest) (string, *composer.ComposerClient) {
+	region := composeRegion(cr)
+	// only the composers listing a GovCloud region have credentials for it
+	if awsPartitionOfRegion(region) == AWSPartitionGovCloud {
+		if name, cc, ok := s.composers.RegionBackend(region); ok {
+			if name == composer.DefaultBackend {
+				name = ""
+			}
+			return name, cc
+		}
+	}
+	name, cc := s.composers.Route(cr.Distribution, region)
+	if name == composer.DefaultBackend {
+		name = ""
+	}
+	return name, cc
+}
+
+// composeRegion returns the region or location the image is uploaded to, if
+// the upload target has one.
+func composeRegion(cr composer.ComposeRequest) string {
+	if cr.ImageRequest == nil || cr.ImageRequest.UploadOptions == nil {
+		return ""
+	}
+	buf, err := cr.ImageRequest.UploadOptions.MarshalJSON()
+	if err != nil {
+		return ""
+	}
+	var options struct {
+		Region   string `json:"region"`
+		Location string `json:"location"`
+	}
+	if json.Unmarshal(buf, &options) != nil {
+		return ""
+	}
+	if options.Region != "" {
+		return options.Region
+	}
+	return options.Location
+}
@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/osbuild/image-builder/internal/common"
+)
+
+// most compose requests in a batch, the spec limits it as well
+const maxBatchComposes = 20
+
+// ComposeImageBatch composes several images. All requests are checked before
+// any is submitted, so an invalid one doesn't leave the client with half a
+// batch. Errors submitting a compose are only its own, the others are still
+// submitted.
+func (h *Handlers) ComposeImageBatch(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	var batch BatchComposeRequest
+	err = ctx.Bind(&batch)
+	if err != nil {
+		return err
+	}
+	if len(batch.Composes) == 0 || len(batch.Composes) > maxBatchComposes {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("A batch has to have between 1 and %d compose requests", maxBatchComposes))
+	}
+
+	queue, release, err := h.server.checkComposeQuota(ctx, idHeader.Identity.OrgID, len(batch.Composes))
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	prepared := make([]*preparedCompose, len(batch.Composes))
+	invalid := []BatchComposeResult{}
+	for i, cr := range batch.Composes {
+		pc, err := h.prepareCompose(ctx, idHeader, cr)
+		if err != nil {
+			he, ok := err.(*echo.HTTPError)
+			if !ok || he.Code >= http.StatusInternalServerError {
+				return err
+			}
+			invalid = append(invalid, BatchComposeResult{
+				Index: i,
+				Error: common.ToPtr(httpErrorOf(he)),
+			})
+			continue
+		}
+		prepared[i] = pc
+	}
+	if len(invalid) > 0 {
+		return ctx.JSON(http.StatusBadRequest, BatchComposeResponse{
+			Results: invalid,
+		})
+	}
+
+	status := http.StatusCreated
+	results := []BatchComposeResult{}
+	for i, pc := range prepared {
+		composeId, err := h.submitCompose(ctx, idHeader, pc, queue)
+		if err != nil {
+			he, ok := err.(*echo.HTTPError)
+			if !ok {
+				ctx.Logger().Errorf("Error submitting compose %d of a batch: %v", i, err)
+				he = echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong submitting the compose")
+			}
+			results = append(results, BatchComposeResult{
+				Index: i,
+				Error: common.ToPtr(httpErrorOf(he)),
+			})
+			status = http.StatusMultiStatus
+			continue
+		}
+		result := BatchComposeResult{
+			Index: i,
+			Id:    &composeId,
+		}
+		if len(pc.warnings) > 0 {
+			result.Warnings = &pc.warnings
+		}
+		results = append(results, result)
+	}
+	ctx.Logger().Infof("Submitted a batch of %d composes of org %s", len(results), idHeader.Identity.OrgID)
+	return ctx.JSON(status, BatchComposeResponse{
+		Results: results,
+	})
+}
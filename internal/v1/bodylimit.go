@@ -0,0 +1,184 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	bytesize "github.com/labstack/gommon/bytes"
+)
+
+const (
+	defaultMaxBodySize     = 1 << 20
+	defaultMaxJSONDepth    = 32
+	defaultMaxJSONElements = 5000
+)
+
+type RequestLimits struct {
+	// Maximum size of request bodies in bytes, defaults to 1MiB.
+	MaxBodySize int64
+	// Overrides MaxBodySize for some operations, keyed by their
+	// operationId.
+	OperationBodySizes map[string]int64
+	// Maximum nesting of arrays and objects, defaults to 32.
+	MaxJSONDepth int
+	// Maximum number of elements of an array, or members of an object,
+	// defaults to 5000.
+	MaxJSONElements int
+}
+
+func (l RequestLimits) withDefaults() RequestLimits {
+	if l.MaxBodySize == 0 {
+		l.MaxBodySize = defaultMaxBodySize
+	}
+	if l.MaxJSONDepth == 0 {
+		l.MaxJSONDepth = defaultMaxJSONDepth
+	}
+	if l.MaxJSONElements == 0 {
+		l.MaxJSONElements = defaultMaxJSONElements
+	}
+	operations := map[string]int64{}
+	for op, size := range l.OperationBodySizes {
+		operations[strings.ToLower(op)] = size
+	}
+	l.OperationBodySizes = operations
+	return l
+}
+
+func (l RequestLimits) bodySize(operationId string) int64 {
+	if size, ok := l.OperationBodySizes[operationId]; ok {
+		return size
+	}
+	return l.MaxBodySize
+}
+
+// ParseBodySizes parses a comma separated list of operationId=size pairs,
+// sizes can have units, e.g. "composeImage=4MiB,cloneCompose=16KiB".
+func ParseBodySizes(s string) (map[string]int64, error) {
+	sizes := map[string]int64{}
+	if s == "" {
+		return sizes, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		op, size, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || op == "" {
+			return nil, fmt.Errorf("invalid body size %q, expected operationId=size", pair)
+		}
+		n, err := bytesize.Parse(size)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body size of %s: %v", op, err)
+		}
+		sizes[op] = n
+	}
+	return sizes, nil
+}
+
+// limitRequestBody rejects request bodies which are too large, or too deeply
+// nested, before the request validation decodes them.
+func (s *Server) limitRequestBody(nextHandler echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		request := ctx.Request()
+		if request.Body == nil || request.Body == http.NoBody {
+			return nextHandler(ctx)
+		}
+
+		var opId string
+		route, _, err := s.router.FindRoute(request)
+		if err == nil {
+			opId = operationId(route)
+		}
+		maxSize := s.requestLimits.bodySize(opId)
+		if request.ContentLength > maxSize {
+			return bodyTooLarge(maxSize)
+		}
+
+		// the body is checked while it's read, and buffered for the
+		// handlers
+		var buf bytes.Buffer
+		body := io.TeeReader(http.MaxBytesReader(ctx.Response(), request.Body, maxSize), &buf)
+		err = checkJSONLimits(body, s.requestLimits.MaxJSONDepth, s.requestLimits.MaxJSONElements)
+		if err == nil {
+			_, err = io.Copy(io.Discard, body)
+		}
+		if err == nil && !utf8.Valid(buf.Bytes()) {
+			err = errors.New("invalid UTF-8")
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return bodyTooLarge(maxSize)
+		} else if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("cannot parse request body: %v", err))
+		}
+
+		request.Body = io.NopCloser(&buf)
+		return nextHandler(ctx)
+	}
+}
+
+func bodyTooLarge(maxSize int64) error {
+	return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds the limit of %s", bytesize.Format(maxSize)))
+}
+
+// checkJSONLimits reads a json value token by token, so deeply nested or
+// large documents are rejected without decoding them. Anything but
+// whitespace after the value is an error.
+func checkJSONLimits(r io.Reader, maxDepth, maxElements int) error {
+	type container struct {
+		object bool
+		// tokens directly within the container, objects hold keys and
+		// values
+		tokens int
+	}
+	var stack []container
+	var values int
+
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			if len(stack) > 0 {
+				return io.ErrUnexpectedEOF
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		delim, isDelim := token.(json.Delim)
+		if isDelim && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		if len(stack) == 0 {
+			values++
+			if values > 1 {
+				return errors.New("unexpected data after the json value")
+			}
+		} else {
+			top := &stack[len(stack)-1]
+			top.tokens++
+			elements := top.tokens
+			if top.object {
+				elements = (top.tokens + 1) / 2
+			}
+			if elements > maxElements {
+				return fmt.Errorf("more than %d elements in an array or object", maxElements)
+			}
+		}
+
+		if isDelim {
+			if len(stack) >= maxDepth {
+				return fmt.Errorf("arrays and objects are nested deeper than %d levels", maxDepth)
+			}
+			stack = append(stack, container{object: delim == '{'})
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package v1
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	legacyrouter "github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckJSONLimits(t *testing.T) {
+	check := func(doc string) error {
+		return checkJSONLimits(strings.NewReader(doc), 3, 4)
+	}
+	require.NoError(t, check(``))
+	require.NoError(t, check(`{"a": [1, 2, 3, 4], "b": {"c": [], "d": "e"}}`))
+	require.NoError(t, check(`[[["a", "b", "c", "d"]]]`))
+	require.NoError(t, check(`{"a": 1, "b": 2, "c": 3, "d": [1]}`))
+
+	require.ErrorContains(t, check(`[[[[]]]]`), "nested deeper than 3 levels")
+	require.ErrorContains(t, check(`{"a": {"b": {"c": {}}}}`), "nested deeper than 3 levels")
+	require.ErrorContains(t, check(`[1, 2, 3, 4, 5]`), "more than 4 elements")
+	require.ErrorContains(t, check(`{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5}`), "more than 4 elements")
+	require.ErrorContains(t, check(`{"a": [{}, {}, {}, {}, []]}`), "more than 4 elements")
+	require.Error(t, check(`{"a": `))
+	require.Error(t, check(`{"a" 1}`))
+}
+
+func TestParseBodySizes(t *testing.T) {
+	sizes, err := ParseBodySizes("")
+	require.NoError(t, err)
+	require.Empty(t, sizes)
+
+	sizes, err = ParseBodySizes("composeImage=4MiB, cloneCompose=16KiB")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"composeImage": 4 << 20, "cloneCompose": 16 << 10}, sizes)
+
+	_, err = ParseBodySizes("composeImage")
+	require.Error(t, err)
+	_, err = ParseBodySizes("composeImage=lots")
+	require.Error(t, err)
+}
+
+func TestLimitRequestBody(t *testing.T) {
+	spec, err := GetSwagger()
+	require.NoError(t, err)
+	spec.AddServer(&openapi3.Server{URL: fmt.Sprintf("%s/v%s", RoutePrefix(), spec.Info.Version)})
+	router, err := legacyrouter.NewRouter(spec)
+	require.NoError(t, err)
+	s := &Server{
+		router: router,
+		requestLimits: RequestLimits{
+			MaxBodySize: 64,
+			OperationBodySizes: map[string]int64{
+				"composeImage": 128,
+			},
+			MaxJSONElements: 10,
+		}.withDefaults(),
+	}
+
+	run := func(path, body string, contentLength bool) (int, string) {
+		req := httptest.NewRequest(http.MethodPost, RoutePrefix()+"/v1"+path, strings.NewReader(body))
+		if !contentLength {
+			req.ContentLength = -1
+		}
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		var received string
+		err := s.limitRequestBody(func(ctx echo.Context) error {
+			buf, err := io.ReadAll(ctx.Request().Body)
+			require.NoError(t, err)
+			received = string(buf)
+			return ctx.NoContent(http.StatusOK)
+		})(ctx)
+		if err != nil {
+			return err.(*echo.HTTPError).Code, ""
+		}
+		return rec.Code, received
+	}
+
+	body := `{"distribution": "rhel-9", "image_requests": []}`
+	code, received := run("/compose", body, true)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, body, received)
+
+	long := fmt.Sprintf(`{"distribution": "%s"}`, strings.Repeat("a", 100))
+	code, _ = run("/compose", long, true)
+	require.Equal(t, http.StatusOK, code)
+	code, _ = run("/compose", long+strings.Repeat(" ", 100), true)
+	require.Equal(t, http.StatusRequestEntityTooLarge, code)
+	// bodies without a content length are cut off while reading them
+	code, _ = run("/compose", long+strings.Repeat(" ", 100), false)
+	require.Equal(t, http.StatusRequestEntityTooLarge, code)
+
+	// other operations use the default
+	code, _ = run("/tokens", long, true)
+	require.Equal(t, http.StatusRequestEntityTooLarge, code)
+	code, _ = run("/tokens", long, false)
+	require.Equal(t, http.StatusRequestEntityTooLarge, code)
+
+	code, _ = run("/compose", `{"packages": [1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11]}`, true)
+	require.Equal(t, http.StatusBadRequest, code)
+}
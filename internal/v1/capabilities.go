@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/osbuild/image-builder/internal/composer"
+)
+
+// composerImageType returns the composer image type an image type is built
+// as, regardless of the upload target.
+func composerImageType(it ImageTypes) (composer.ImageTypes, bool) {
+	switch it {
+	case ImageTypesAws, ImageTypesAmi:
+		return composer.ImageTypesAws, true
+	case ImageTypesAzure, ImageTypesVhd:
+		return composer.ImageTypesAzure, true
+	case ImageTypesGcp:
+		return composer.ImageTypesGcp, true
+	case ImageTypesOci:
+		return composer.ImageTypesOci, true
+	case ImageTypesEdgeContainer:
+		return composer.ImageTypesEdgeContainer, true
+	}
+	cit, err := downloadImageType(it)
+	return cit, err == nil
+}
+
+// supportedImageTypes drops the image types the default composer doesn't
+// build, so the distributions matrix follows the deployed composer. Nothing
+// is dropped until its capabilities were detected.
+func (s *Server) supportedImageTypes(imageTypes []string) []string {
+	caps := s.cClient.Capabilities()
+	if caps == nil {
+		return imageTypes
+	}
+	supported := []string{}
+	for _, it := range imageTypes {
+		cit, ok := composerImageType(ImageTypes(it))
+		if ok && caps.ImageTypes[string(cit)] {
+			supported = append(supported, it)
+		}
+	}
+	return supported
+}
+
+// checkCapabilities rejects compose requests the composer they're sent to
+// would refuse, as it lacks the image type, upload target or customizations.
+func checkCapabilities(cc *composer.ComposerClient, uploadType UploadTypes, cr composer.ComposeRequest) error {
+	caps := cc.Capabilities()
+	if caps == nil {
+		return nil
+	}
+	if !caps.ImageTypes[string(cr.ImageRequest.ImageType)] {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("Image type %s is not supported by the build system", cr.ImageRequest.ImageType))
+	}
+	if !caps.UploadTypes[string(uploadType)] {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("Upload target %s is not supported by the build system", uploadType))
+	}
+	unsupported, err := caps.UnsupportedCustomizations(cr.Customizations)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if len(unsupported) > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("Customizations %s are not supported by the build system", strings.Join(unsupported, ", ")))
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/composer"
+)
+
+func TestCapabilities(t *testing.T) {
+	doc, err := os.ReadFile("../composer/openapi.v2.yml")
+	require.NoError(t, err)
+	// a composer which doesn't build wsl images yet
+	doc = []byte(strings.Replace(string(doc), "        - wsl\n", "", 1))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]string{"access_token": "token"}))
+			return
+		}
+		require.Equal(t, "/api/image-builder-composer/v2/openapi", r.URL.Path)
+		_, _ = w.Write(doc)
+	}))
+	defer srv.Close()
+
+	cc, err := composer.NewClient(composer.ComposerClientConfig{
+		ComposerURL:  srv.URL,
+		TokenURL:     srv.URL + "/token",
+		ClientId:     "id",
+		ClientSecret: "secret",
+	})
+	require.NoError(t, err)
+	s := &Server{cClient: cc}
+
+	cr := composer.ComposeRequest{
+		Distribution: "rhel-9",
+		ImageRequest: &composer.ImageRequest{ImageType: composer.ImageTypesWsl},
+	}
+	imageTypes := []string{"ami", "guest-image", "wsl"}
+
+	// nothing is filtered until the capabilities are known
+	require.Equal(t, imageTypes, s.supportedImageTypes(imageTypes))
+	require.NoError(t, checkCapabilities(cc, UploadTypesAwsS3, cr))
+
+	require.NoError(t, cc.RefreshCapabilities(context.Background()))
+	require.Equal(t, []string{"ami", "guest-image"}, s.supportedImageTypes(imageTypes))
+	require.ErrorContains(t, checkCapabilities(cc, UploadTypesAwsS3, cr), "Image type wsl is not supported")
+
+	cr.ImageRequest.ImageType = composer.ImageTypesGuestImage
+	cr.Customizations = &composer.Customizations{Packages: common.ToPtr([]string{"vim"})}
+	require.NoError(t, checkCapabilities(cc, UploadTypesAwsS3, cr))
+	require.ErrorContains(t, checkCapabilities(cc, UploadTypes("ftp"), cr), "Upload target ftp is not supported")
+}
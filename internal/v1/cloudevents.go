@@ -0,0 +1,95 @@
+package v1
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/kafka"
+)
+
+// The lifecycle events of composes are published as CloudEvents in the
+// structured JSON format, the types and source follow the conventions of the
+// console event bus.
+const (
+	cloudEventSource           = "urn:redhat:source:console:app:image-builder"
+	cloudEventComposeCreated   = "com.redhat.console.image-builder.compose.created"
+	cloudEventComposeSucceeded = "com.redhat.console.image-builder.compose.succeeded"
+	cloudEventComposeFailed    = "com.redhat.console.image-builder.compose.failed"
+
+	cloudEventContentType = "application/cloudevents+json"
+)
+
+// EventPublisher sends events and waits for them to be acknowledged, it's
+// implemented by kafka.Producer.
+type EventPublisher interface {
+	SendMessages(msgs []kafka.Message) error
+}
+
+type cloudEvent struct {
+	SpecVersion     string `json:"specversion"`
+	Id              string `json:"id"`
+	Source          string `json:"source"`
+	Type            string `json:"type"`
+	Subject         string `json:"subject"`
+	Time            string `json:"time"`
+	DataContentType string `json:"datacontenttype"`
+	// extension attribute routing events of an org
+	RedHatOrgId string           `json:"redhatorgid"`
+	Data        composeEventData `json:"data"`
+}
+
+type composeEventData struct {
+	ComposeId uuid.UUID `json:"compose_id"`
+	OrgId     string    `json:"org_id"`
+	// created, success or failure
+	Status       string  `json:"status"`
+	Reason       *string `json:"reason,omitempty"`
+	ImageName    *string `json:"image_name,omitempty"`
+	Distribution string  `json:"distribution,omitempty"`
+	ImageType    string  `json:"image_type,omitempty"`
+	UploadTarget string  `json:"upload_target,omitempty"`
+}
+
+// publishComposeFinished publishes the outcome of a compose.
+func (s *Server) publishComposeFinished(data composeEventData, t time.Time) error {
+	eventType := cloudEventComposeFailed
+	if data.Status == string(composer.ImageStatusValueSuccess) {
+		eventType = cloudEventComposeSucceeded
+	}
+	return s.publishComposeEvent(eventType, data, t)
+}
+
+// publishComposeEvent sends an event keyed by the compose, so the events of
+// a compose stay in order, and waits for the brokers to acknowledge it.
+func (s *Server) publishComposeEvent(eventType string, data composeEventData, t time.Time) error {
+	if s.events == nil {
+		return nil
+	}
+	value, err := json.Marshal(cloudEvent{
+		SpecVersion:     "1.0",
+		Id:              uuid.New().String(),
+		Source:          cloudEventSource,
+		Type:            eventType,
+		Subject:         data.ComposeId.String(),
+		Time:            t.UTC().Format(time.RFC3339Nano),
+		DataContentType: "application/json",
+		RedHatOrgId:     data.OrgId,
+		Data:            data,
+	})
+	if err != nil {
+		return err
+	}
+	return s.events.SendMessages([]kafka.Message{
+		{
+			Key:   []byte(data.ComposeId.String()),
+			Value: value,
+			Headers: []kafka.Header{
+				{Key: "content-type", Value: []byte(cloudEventContentType)},
+			},
+			Time: t,
+		},
+	})
+}
@@ -0,0 +1,84 @@
+package v1
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/kafka"
+)
+
+type fakePublisher struct {
+	messages []kafka.Message
+}
+
+func (p *fakePublisher) SendMessages(msgs []kafka.Message) error {
+	p.messages = append(p.messages, msgs...)
+	return nil
+}
+
+func TestPublishComposeEvents(t *testing.T) {
+	events := &fakePublisher{}
+	s := &Server{events: events}
+
+	id := uuid.New()
+	outbox := s.composeCreatedOutbox(id, "000000", ComposeRequest{
+		Distribution: "rhel-9",
+		ImageName:    common.ToPtr("my-image"),
+		ImageRequests: []ImageRequest{
+			{
+				ImageType:     ImageTypesGuestImage,
+				UploadRequest: UploadRequest{Type: UploadTypesAwsS3},
+			},
+		},
+	})
+	outbox = append(outbox, s.composeFinishedOutbox(id, "000000", "failure", common.ToPtr("osbuild failed"), nil)...)
+	for _, e := range outbox {
+		if e.Sink == outboxSinkEvents {
+			require.NoError(t, s.dispatchOutboxEntry(e))
+		}
+	}
+	require.Len(t, events.messages, 2)
+
+	for _, m := range events.messages {
+		require.Equal(t, id.String(), string(m.Key))
+		require.Equal(t, []kafka.Header{{Key: "content-type", Value: []byte("application/cloudevents+json")}}, m.Headers)
+	}
+
+	var created cloudEvent
+	require.NoError(t, json.Unmarshal(events.messages[0].Value, &created))
+	require.Equal(t, "1.0", created.SpecVersion)
+	require.Equal(t, cloudEventSource, created.Source)
+	require.Equal(t, cloudEventComposeCreated, created.Type)
+	require.Equal(t, id.String(), created.Subject)
+	require.Equal(t, "000000", created.RedHatOrgId)
+	require.Equal(t, composeEventData{
+		ComposeId:    id,
+		OrgId:        "000000",
+		Status:       "created",
+		ImageName:    common.ToPtr("my-image"),
+		Distribution: "rhel-9",
+		ImageType:    "guest-image",
+		UploadTarget: "aws.s3",
+	}, created.Data)
+
+	var failed cloudEvent
+	require.NoError(t, json.Unmarshal(events.messages[1].Value, &failed))
+	require.Equal(t, cloudEventComposeFailed, failed.Type)
+	require.NotEqual(t, created.Id, failed.Id)
+	require.Equal(t, "osbuild failed", *failed.Data.Reason)
+
+	require.NoError(t, s.publishComposeFinished(composeEventData{ComposeId: id, OrgId: "000000", Status: "success"}, time.Now()))
+	var succeeded cloudEvent
+	require.NoError(t, json.Unmarshal(events.messages[2].Value, &succeeded))
+	require.Equal(t, cloudEventComposeSucceeded, succeeded.Type)
+
+	// nothing is published without a publisher
+	s.events = nil
+	require.NoError(t, s.publishComposeFinished(composeEventData{ComposeId: id, OrgId: "000000", Status: "success"}, time.Now()))
+	require.Len(t, events.messages, 3)
+}
@@ -0,0 +1,502 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/signing"
+)
+
+const (
+	defaultComplianceExportWindow   = 24 * time.Hour
+	defaultComplianceExportInterval = 5 * time.Minute
+	// how long an instance has to export a window before another one may
+	// try, and how long exporting a window may take
+	complianceExportClaim   = 30 * time.Minute
+	complianceExportTimeout = 20 * time.Minute
+	// windows exported per org and run, more are caught up with on the
+	// following runs
+	maxComplianceExportWindows = 7
+	// version of the layout of the exported objects, see HACKING.md
+	complianceExportSchemaVersion = 1
+	complianceExportTimeFormat    = "20060102T150405Z"
+	// the object under the prefix of the bucket of an org which has to hold
+	// its verification token
+	complianceVerificationObject = "image-builder-verification"
+)
+
+var (
+	// bucket names with dots can't be used with the virtual hosted
+	// endpoints of S3 over https
+	complianceBucketRE = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,61}[a-z0-9]$`)
+	complianceRegionRE = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+	complianceKeyRE    = regexp.MustCompile(`^[a-zA-Z0-9!_.*'()/-]*$`)
+)
+
+// ObjectStore puts the compliance exports into the buckets of orgs, and
+// reads the verification objects of the buckets.
+type ObjectStore interface {
+	PutObject(ctx context.Context, region, bucket, key string, body []byte, contentType string) error
+	GetObject(ctx context.Context, region, bucket, key string) ([]byte, error)
+}
+
+type ComplianceExportConfig struct {
+	// Puts the exports into the buckets of orgs, nothing is exported if
+	// nil.
+	Store ObjectStore
+	// Length of the exported windows, defaults to a day. Windows are
+	// aligned to multiples of it since the zero time, so days start at
+	// midnight UTC.
+	Window time.Duration
+	// How often the windows which passed are looked for, zero is replaced
+	// by the default.
+	Interval time.Duration
+}
+
+type complianceExportObject struct {
+	Key     string `json:"key"`
+	Records int    `json:"records"`
+	Size    int    `json:"size"`
+	Sha256  string `json:"sha256"`
+}
+
+// complianceExportManifest is written after the other objects of a window,
+// a window without one wasn't exported completely.
+type complianceExportManifest struct {
+	SchemaVersion int                      `json:"schema_version"`
+	OrgId         string                   `json:"org_id"`
+	WindowStart   time.Time                `json:"window_start"`
+	WindowEnd     time.Time                `json:"window_end"`
+	ExportedAt    time.Time                `json:"exported_at"`
+	Builder       string                   `json:"builder"`
+	Objects       []complianceExportObject `json:"objects"`
+}
+
+// complianceProvenance is a line of provenance.jsonl.
+type complianceProvenance struct {
+	ComposeId uuid.UUID           `json:"compose_id"`
+	Statement provenanceStatement `json:"statement"`
+	// DSSE envelope of the statement, if the service signs provenance
+	Envelope *signing.Envelope `json:"envelope,omitempty"`
+}
+
+func (h *Handlers) GetComplianceExportSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	settings, err := h.server.db.GetComplianceExportSettings(idHeader.Identity.OrgID)
+	if errors.Is(err, db.ComplianceExportSettingsNotFoundError) {
+		return echo.NewHTTPError(http.StatusNotFound, "Nothing is exported")
+	} else if err != nil {
+		ctx.Logger().Errorf("Error querying compliance export settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the compliance export settings")
+	}
+	return ctx.JSON(http.StatusOK, ComplianceExportSettings{
+		Bucket:            settings.Bucket,
+		Region:            settings.Region,
+		Prefix:            settings.Prefix,
+		VerificationKey:   settings.Prefix + complianceVerificationObject,
+		VerificationToken: settings.VerificationToken,
+		UpdatedAt:         settings.UpdatedAt.Format(time.RFC3339),
+	})
+}
+
+func newComplianceVerificationToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// complianceVerificationToken returns the verification token of settings,
+// which is kept as long as they name the same bucket and prefix. Anything
+// else has to be verified again.
+func (s *Server) complianceVerificationToken(settings db.ComplianceExportSettingsEntry) (string, error) {
+	existing, err := s.db.GetComplianceExportSettings(settings.OrgId)
+	if errors.Is(err, db.ComplianceExportSettingsNotFoundError) {
+		return newComplianceVerificationToken()
+	} else if err != nil {
+		return "", err
+	}
+	if existing.Bucket != settings.Bucket || existing.Region != settings.Region || existing.Prefix != settings.Prefix {
+		return newComplianceVerificationToken()
+	}
+	return existing.VerificationToken, nil
+}
+
+// UpdateComplianceExportSettings stores the bucket of an org. Nothing is
+// exported to it until the org put its verification token into the
+// verification object of the bucket, otherwise an org could have the
+// service write into the buckets of others.
+func (h *Handlers) UpdateComplianceExportSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+	if h.server.complianceExport.Store == nil {
+		return echo.NewHTTPError(http.StatusNotImplemented, "This service doesn't export the audit log and provenance")
+	}
+
+	var req ComplianceExportSettingsRequest
+	err = ctx.Bind(&req)
+	if err != nil {
+		return err
+	}
+	if !complianceBucketRE.MatchString(req.Bucket) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid bucket %q, bucket names with dots aren't supported", req.Bucket))
+	}
+	if !complianceRegionRE.MatchString(req.Region) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid region %q", req.Region))
+	}
+	prefix := ""
+	if req.Prefix != nil {
+		prefix = *req.Prefix
+	}
+	if !complianceKeyRE.MatchString(prefix) || strings.HasPrefix(prefix, "/") {
+		return echo.NewHTTPError(http.StatusBadRequest, "The prefix can only contain letters, digits and !_.*'()/- and can't start with a slash")
+	}
+
+	settings := db.ComplianceExportSettingsEntry{
+		OrgId:  idHeader.Identity.OrgID,
+		Bucket: req.Bucket,
+		Region: req.Region,
+		Prefix: prefix,
+	}
+	settings.VerificationToken, err = h.server.complianceVerificationToken(settings)
+	if err == nil {
+		err = h.server.db.SetComplianceExportSettings(settings)
+	}
+	if err != nil {
+		ctx.Logger().Errorf("Error updating compliance export settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong updating the compliance export settings")
+	}
+
+	logAction(ctx, "update_compliance_export_settings", logrus.Fields{"org_id": idHeader.Identity.OrgID, "bucket": req.Bucket, "region": req.Region, "prefix": prefix}, "Compliance export settings updated")
+	return h.GetComplianceExportSettings(ctx)
+}
+
+func (h *Handlers) DeleteComplianceExportSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = h.server.db.DeleteComplianceExportSettings(idHeader.Identity.OrgID)
+	if errors.Is(err, db.ComplianceExportSettingsNotFoundError) {
+		return echo.NewHTTPError(http.StatusNotFound, "Nothing is exported")
+	} else if err != nil {
+		ctx.Logger().Errorf("Error deleting compliance export settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong deleting the compliance export settings")
+	}
+
+	logAction(ctx, "delete_compliance_export_settings", logrus.Fields{"org_id": idHeader.Identity.OrgID}, "Compliance export settings deleted")
+	return ctx.NoContent(http.StatusOK)
+}
+
+func (h *Handlers) GetComplianceExports(ctx echo.Context, params GetComplianceExportsParams) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	limit := 100
+	if params.Limit != nil && *params.Limit > 0 {
+		limit = *params.Limit
+	}
+	offset := 0
+	if params.Offset != nil {
+		offset = *params.Offset
+	}
+
+	entries, count, err := h.server.db.GetComplianceExports(idHeader.Identity.OrgID, limit, offset)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying compliance exports: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the compliance exports")
+	}
+
+	data := []ComplianceExport{}
+	for _, e := range entries {
+		export := ComplianceExport{
+			WindowStart:     e.WindowStart.Format(time.RFC3339),
+			WindowEnd:       e.WindowEnd.Format(time.RFC3339),
+			Status:          ComplianceExportStatusPending,
+			Attempts:        e.Attempts,
+			Error:           e.LastError,
+			AuditLogEntries: e.AuditLogEntries,
+			Composes:        e.Composes,
+		}
+		if e.ExportedAt != nil {
+			export.Status = ComplianceExportStatusExported
+			export.ExportedAt = common.ToPtr(e.ExportedAt.Format(time.RFC3339))
+		}
+		data = append(data, export)
+	}
+
+	lastOffset := count - 1
+	if lastOffset < 0 {
+		lastOffset = 0
+	}
+
+	return ctx.JSON(http.StatusOK, ComplianceExportsResponse{
+		Meta: struct {
+			Count int `json:"count"`
+		}{
+			count,
+		},
+		Links: struct {
+			First string `json:"first"`
+			Last  string `json:"last"`
+		}{
+			fmt.Sprintf("%v/v%v/settings/compliance-export/exports?offset=%v&limit=%v",
+				RoutePrefix(), h.server.spec.Info.Version, 0, limit),
+			fmt.Sprintf("%v/v%v/settings/compliance-export/exports?offset=%v&limit=%v",
+				RoutePrefix(), h.server.spec.Info.Version, lastOffset, limit),
+		},
+		Data: data,
+	})
+}
+
+func (s *Server) complianceExportWindow() time.Duration {
+	if s.complianceExport.Window <= 0 {
+		return defaultComplianceExportWindow
+	}
+	return s.complianceExport.Window
+}
+
+// RunComplianceExports exports the windows which passed for all orgs with
+// compliance export settings until ctx is done. The instances of the
+// service claim the windows they export, so each is exported once.
+func (s *Server) RunComplianceExports(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.exportCompliance(time.Now().UTC())
+		}
+	}
+}
+
+func (s *Server) exportCompliance(now time.Time) {
+	all, err := s.db.GetAllComplianceExportSettings()
+	if err != nil {
+		logrus.Errorf("Error querying compliance export settings: %v", err)
+		return
+	}
+	for _, settings := range all {
+		err = s.exportComplianceWindows(settings, now)
+		if err != nil {
+			logrus.Errorf("Error exporting the audit log and provenance of org %s: %v", settings.OrgId, err)
+		}
+	}
+}
+
+// exportComplianceWindows exports the windows of an org which passed in
+// order, starting after the last exported one or with the window its
+// settings were saved in first. A window which failed to export is retried
+// before the ones after it.
+func (s *Server) exportComplianceWindows(settings db.ComplianceExportSettingsEntry, now time.Time) error {
+	window := s.complianceExportWindow()
+	start := settings.CreatedAt.UTC().Truncate(window)
+	attempts := 0
+	latest, _, err := s.db.GetComplianceExports(settings.OrgId, 1, 0)
+	if err != nil {
+		return err
+	}
+	if len(latest) > 0 {
+		start = latest[0].WindowEnd.UTC()
+		if latest[0].ExportedAt == nil {
+			start = latest[0].WindowStart.UTC()
+			attempts = latest[0].Attempts
+		}
+	}
+
+	for i := 0; i < maxComplianceExportWindows; i++ {
+		end := start.Truncate(window).Add(window)
+		if end.After(now) {
+			return nil
+		}
+		claimed, err := s.db.ClaimComplianceExport(settings.OrgId, start, end, complianceExportClaim)
+		if err != nil || !claimed {
+			return err
+		}
+
+		auditLogEntries, composes, err := s.exportComplianceWindow(settings, start, end)
+		if err != nil {
+			lastError := err.Error()
+			dbErr := s.db.SetComplianceExportResult(settings.OrgId, start, 0, 0, &lastError, retryDelay(attempts+1))
+			if dbErr != nil {
+				logrus.Errorf("Error recording the failed compliance export of org %s from %v: %v", settings.OrgId, start, dbErr)
+			}
+			return err
+		}
+		err = s.db.SetComplianceExportResult(settings.OrgId, start, auditLogEntries, composes, nil, 0)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("Exported %d audit log entries and the provenance of %d composes of org %s from %v to %v", auditLogEntries, composes, settings.OrgId, start, end)
+		start = end
+		attempts = 0
+	}
+	return nil
+}
+
+// verifyComplianceBucket makes sure the bucket of an org holds its
+// verification token. Only who can write into the bucket can put it there,
+// the service itself never writes that key.
+func (s *Server) verifyComplianceBucket(ctx context.Context, settings db.ComplianceExportSettingsEntry) error {
+	key := settings.Prefix + complianceVerificationObject
+	body, err := s.complianceExport.Store.GetObject(ctx, settings.Region, settings.Bucket, key)
+	if err != nil {
+		return fmt.Errorf("reading the verification object failed: %w", err)
+	}
+	if subtle.ConstantTimeCompare(bytes.TrimSpace(body), []byte(settings.VerificationToken)) != 1 {
+		return fmt.Errorf("%s in bucket %s doesn't hold the verification token of the settings", key, settings.Bucket)
+	}
+	return nil
+}
+
+// exportComplianceWindow puts the audit log entries of an org written in
+// [start, end), and the provenance of its composes which succeeded then,
+// into its bucket once it verified the bucket. It returns how many of each
+// were exported.
+func (s *Server) exportComplianceWindow(settings db.ComplianceExportSettingsEntry, start, end time.Time) (int, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), complianceExportTimeout)
+	defer cancel()
+
+	err := s.verifyComplianceBucket(ctx, settings)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	entries, err := s.db.GetAuditLogBetween(settings.OrgId, start, end)
+	if err != nil {
+		return 0, 0, err
+	}
+	var auditLog bytes.Buffer
+	encoder := json.NewEncoder(&auditLog)
+	for _, e := range entries {
+		err = encoder.Encode(auditLogEntry(e))
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	composes, err := s.db.GetComposesFinishedBetween(settings.OrgId, start, end)
+	if err != nil {
+		return 0, 0, err
+	}
+	var provenance bytes.Buffer
+	encoder = json.NewEncoder(&provenance)
+	for i := range composes {
+		record, err := s.complianceProvenance(ctx, &composes[i])
+		if err != nil {
+			return 0, 0, err
+		}
+		err = encoder.Encode(record)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	prefix := fmt.Sprintf("%s%s_%s/", settings.Prefix, start.Format(complianceExportTimeFormat), end.Format(complianceExportTimeFormat))
+	manifest := complianceExportManifest{
+		SchemaVersion: complianceExportSchemaVersion,
+		OrgId:         settings.OrgId,
+		WindowStart:   start,
+		WindowEnd:     end,
+		Builder:       s.provenanceBuilderId(),
+	}
+	for _, o := range []struct {
+		name    string
+		body    []byte
+		records int
+	}{
+		{"audit-log.jsonl", auditLog.Bytes(), len(entries)},
+		{"provenance.jsonl", provenance.Bytes(), len(composes)},
+	} {
+		key := prefix + o.name
+		err = s.complianceExport.Store.PutObject(ctx, settings.Region, settings.Bucket, key, o.body, "application/x-ndjson")
+		if err != nil {
+			return 0, 0, err
+		}
+		sum := sha256.Sum256(o.body)
+		manifest.Objects = append(manifest.Objects, complianceExportObject{
+			Key:     key,
+			Records: o.records,
+			Size:    len(o.body),
+			Sha256:  hex.EncodeToString(sum[:]),
+		})
+	}
+
+	manifest.ExportedAt = time.Now().UTC()
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return 0, 0, err
+	}
+	err = s.complianceExport.Store.PutObject(ctx, settings.Region, settings.Bucket, prefix+"manifest.json", raw, "application/json")
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(entries), len(composes), nil
+}
+
+// complianceProvenance is the provenance of a compose as it's exported. The
+// provenance of composes composer doesn't know anymore is exported without
+// their packages.
+func (s *Server) complianceProvenance(ctx context.Context, compose *db.ComposeEntry) (*complianceProvenance, error) {
+	artifacts, err := s.db.GetComposeArtifacts(compose.Id, compose.OrgId)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.db.GetComposeEvents(compose.Id)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := s.composerMetadata(ctx, compose)
+	if err != nil {
+		logrus.Warnf("Exporting the provenance of compose %v without its packages: %v", compose.Id, err)
+		metadata = &composer.ComposeMetadata{}
+	}
+
+	record := &complianceProvenance{
+		ComposeId: compose.Id,
+		Statement: newProvenanceStatement(s.provenanceBuilderId(), compose, artifacts, metadata, events),
+	}
+	if s.provenance.Signer != nil {
+		payload, err := json.Marshal(record.Statement)
+		if err != nil {
+			return nil, err
+		}
+		record.Envelope, err = signing.SignEnvelope(s.provenance.Signer, inTotoPayloadType, payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
@@ -0,0 +1,142 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/prometheus"
+)
+
+// how long reading the queue of a composer backend may take
+const composerQueueTimeout = 30 * time.Second
+
+// SupportComposerQueue is the queue and the workers of a composer backend.
+// Error says why they couldn't be read.
+type SupportComposerQueue struct {
+	Backend string                  `json:"backend"`
+	Error   string                  `json:"error,omitempty"`
+	Queues  []SupportJobQueue       `json:"queues"`
+	Workers []SupportComposerWorker `json:"workers"`
+}
+
+type SupportJobQueue struct {
+	Type    string `json:"type"`
+	Arch    string `json:"arch,omitempty"`
+	Pending int    `json:"pending"`
+	Running int    `json:"running"`
+}
+
+type SupportComposerWorker struct {
+	Id                  string  `json:"id"`
+	Arch                string  `json:"arch"`
+	LastHeartbeat       string  `json:"last_heartbeat"`
+	HeartbeatAgeSeconds float64 `json:"heartbeat_age_seconds"`
+}
+
+type SupportComposerQueuesResponse struct {
+	Data []SupportComposerQueue `json:"data"`
+}
+
+// composerQueue reads the queue and the workers of a backend, nil if the
+// url of its metrics isn't configured.
+func composerQueue(ctx context.Context, b composer.Backend, now time.Time) *SupportComposerQueue {
+	queue := SupportComposerQueue{
+		Backend: b.Name,
+		Queues:  []SupportJobQueue{},
+		Workers: []SupportComposerWorker{},
+	}
+	ctx, cancel := context.WithTimeout(ctx, composerQueueTimeout)
+	defer cancel()
+	status, err := b.Client.QueueStatus(ctx)
+	if errors.Is(err, composer.ErrNoMetricsURL) {
+		return nil
+	} else if err != nil {
+		queue.Error = err.Error()
+		return &queue
+	}
+	for _, q := range status.Queues {
+		queue.Queues = append(queue.Queues, SupportJobQueue(q))
+	}
+	for _, w := range status.Workers {
+		queue.Workers = append(queue.Workers, SupportComposerWorker{
+			Id:                  w.Id,
+			Arch:                w.Arch,
+			LastHeartbeat:       w.LastHeartbeat.Format(time.RFC3339),
+			HeartbeatAgeSeconds: now.Sub(w.LastHeartbeat).Seconds(),
+		})
+	}
+	return &queue
+}
+
+// composerQueues reads the queues of the backends with a metrics url.
+func (s *Server) composerQueues(ctx context.Context) []SupportComposerQueue {
+	now := time.Now()
+	queues := []SupportComposerQueue{}
+	for _, b := range s.composers.Backends() {
+		if q := composerQueue(ctx, b, now); q != nil {
+			queues = append(queues, *q)
+		}
+	}
+	return queues
+}
+
+// recordComposerQueues sets the gauges of the queues and the workers of the
+// backends. The gauges of a backend are left alone if its queue couldn't be
+// read, so they don't drop to zero when composer is unavailable.
+func recordComposerQueues(queues []SupportComposerQueue) {
+	for _, q := range queues {
+		if q.Error != "" {
+			logrus.Warnf("Unable to read the queue of composer backend %s: %s", q.Backend, q.Error)
+			prometheus.ComposerQueueErrors.WithLabelValues(q.Backend).Inc()
+			continue
+		}
+		backend := map[string]string{"backend": q.Backend}
+		prometheus.ComposerPendingJobs.DeletePartialMatch(backend)
+		prometheus.ComposerRunningJobs.DeletePartialMatch(backend)
+		prometheus.ComposerWorkers.DeletePartialMatch(backend)
+		prometheus.ComposerWorkerHeartbeatAge.DeletePartialMatch(backend)
+		for _, jq := range q.Queues {
+			prometheus.ComposerPendingJobs.WithLabelValues(q.Backend, jq.Type, jq.Arch).Set(float64(jq.Pending))
+			prometheus.ComposerRunningJobs.WithLabelValues(q.Backend, jq.Type, jq.Arch).Set(float64(jq.Running))
+		}
+		oldest := map[string]float64{}
+		for _, w := range q.Workers {
+			prometheus.ComposerWorkers.WithLabelValues(q.Backend, w.Arch).Inc()
+			if age, ok := oldest[w.Arch]; !ok || w.HeartbeatAgeSeconds > age {
+				oldest[w.Arch] = w.HeartbeatAgeSeconds
+			}
+		}
+		for arch, age := range oldest {
+			prometheus.ComposerWorkerHeartbeatAge.WithLabelValues(q.Backend, arch).Set(age)
+		}
+	}
+}
+
+// RunComposerQueueMonitor records the queues and the workers of the composer
+// backends in the gauges every interval, until ctx is done.
+func (s *Server) RunComposerQueueMonitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		recordComposerQueues(s.composerQueues(ctx))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetSupportComposerQueues returns the queues and the workers of the composer
+// backends, so slow builds can be told apart from a lack of workers.
+func (h *Handlers) GetSupportComposerQueues(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, SupportComposerQueuesResponse{
+		Data: h.server.composerQueues(ctx.Request().Context()),
+	})
+}
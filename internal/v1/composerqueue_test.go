@@ -0,0 +1,81 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/prometheus"
+)
+
+func TestComposerQueues(t *testing.T) {
+	heartbeat := time.Now().Add(-time.Minute).Unix()
+	var available atomic.Bool
+	available.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `image_builder_composer_pending_jobs{tenant="org1",type="osbuild:x86_64"} 7
+image_builder_composer_running_jobs{tenant="org1",type="osbuild:x86_64"} 2
+image_builder_composer_worker_heartbeat_timestamp_seconds{arch="x86_64",worker_id="w1"} %d
+image_builder_composer_worker_heartbeat_timestamp_seconds{arch="x86_64",worker_id="w2"} %d
+`, heartbeat, heartbeat-60)
+	}))
+	defer srv.Close()
+
+	client := func(metricsURL string) *composer.ComposerClient {
+		cc, err := composer.NewClient(composer.ComposerClientConfig{
+			ComposerURL:  srv.URL,
+			TokenURL:     srv.URL,
+			ClientId:     "id",
+			ClientSecret: "secret",
+			Retry:        common.RetryPolicy{Attempts: 1},
+			MetricsURL:   metricsURL,
+		})
+		require.NoError(t, err)
+		return cc
+	}
+	pool, err := composer.NewPool([]composer.Backend{
+		{Name: composer.DefaultBackend, Client: client("")},
+		{Name: "queue-test", Client: client(srv.URL + "/metrics")},
+	})
+	require.NoError(t, err)
+	s := &Server{composers: pool}
+
+	// backends without a metrics url aren't listed
+	queues := s.composerQueues(context.Background())
+	require.Len(t, queues, 1)
+	require.Equal(t, "queue-test", queues[0].Backend)
+	require.Empty(t, queues[0].Error)
+	require.Equal(t, []SupportJobQueue{{Type: "osbuild", Arch: "x86_64", Pending: 7, Running: 2}}, queues[0].Queues)
+	require.Len(t, queues[0].Workers, 2)
+	require.Equal(t, "w1", queues[0].Workers[0].Id)
+	require.InDelta(t, 60, queues[0].Workers[0].HeartbeatAgeSeconds, 5)
+
+	recordComposerQueues(queues)
+	require.Equal(t, 7.0, testutil.ToFloat64(prometheus.ComposerPendingJobs.WithLabelValues("queue-test", "osbuild", "x86_64")))
+	require.Equal(t, 2.0, testutil.ToFloat64(prometheus.ComposerRunningJobs.WithLabelValues("queue-test", "osbuild", "x86_64")))
+	require.Equal(t, 2.0, testutil.ToFloat64(prometheus.ComposerWorkers.WithLabelValues("queue-test", "x86_64")))
+	require.InDelta(t, 120, testutil.ToFloat64(prometheus.ComposerWorkerHeartbeatAge.WithLabelValues("queue-test", "x86_64")), 5)
+
+	// the gauges are kept while composer is unavailable
+	available.Store(false)
+	errors := testutil.ToFloat64(prometheus.ComposerQueueErrors.WithLabelValues("queue-test"))
+	queues = s.composerQueues(context.Background())
+	require.Len(t, queues, 1)
+	require.NotEmpty(t, queues[0].Error)
+	recordComposerQueues(queues)
+	require.Equal(t, errors+1, testutil.ToFloat64(prometheus.ComposerQueueErrors.WithLabelValues("queue-test")))
+	require.Equal(t, 2.0, testutil.ToFloat64(prometheus.ComposerWorkers.WithLabelValues("queue-test", "x86_64")))
+}
@@ -0,0 +1,221 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/cosign"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/signing"
+)
+
+// CosignConfig signs the container images composes push, if they ask for
+// it.
+type CosignConfig struct {
+	// Looks up a key of the secret of an org in the key store, the
+	// private_key images are signed with and the registry_username and
+	// registry_password signatures are pushed with. Images can't be signed
+	// if nil.
+	OrgSecret func(ctx context.Context, orgId, key string) (string, error)
+	// Certifies the short lived keys of keyless signatures, images can't
+	// be signed keyless if nil.
+	Fulcio *cosign.Fulcio
+	// Talks to the registries, defaults to a client which only connects to
+	// public addresses.
+	Client *http.Client
+}
+
+// Users name the images, and so the registries which are given the
+// credentials of the org, hence only public addresses are connected to.
+var registryClient = common.NewPublicHTTPClient(common.HTTPTimeouts{
+	Connect: 10 * time.Second,
+	Read:    30 * time.Second,
+	Request: time.Minute,
+}, nil)
+
+// checkSign refuses compose requests asking for a signature this service
+// can't make.
+func (s *Server) checkSign(sign *ContainerUploadRequestOptionsSign) error {
+	if sign == nil {
+		return nil
+	}
+	if s.cosign.OrgSecret == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "This service doesn't sign container images")
+	}
+	if *sign == Keyless && s.cosign.Fulcio == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "This service doesn't sign container images keyless")
+	}
+	return nil
+}
+
+// validateRegistryHost rejects images whose registry is known not to be
+// public, the credentials of the org aren't pushed there.
+func validateRegistryHost(image string) error {
+	repo, err := cosign.ParseImage(image)
+	if err != nil {
+		return err
+	}
+	host := repo.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	err = common.ValidatePublicHost(host)
+	if err != nil {
+		return fmt.Errorf("the registry of the image has to be public: %w", err)
+	}
+	return nil
+}
+
+// signContainerImage signs the image a successful compose pushed, if it
+// asked for it, and records the outcome. The signing is marked as failed if
+// this was the last attempt.
+func (s *Server) signContainerImage(event outboxEvent, lastAttempt bool) error {
+	if event.UploadStatus == nil || event.UploadStatus.Type != UploadTypesContainer {
+		return nil
+	}
+	compose, err := s.db.GetCompose(event.ComposeId, event.OrgId)
+	if err != nil {
+		return err
+	}
+	var cr ComposeRequest
+	err = json.Unmarshal(compose.Request, &cr)
+	if err != nil {
+		return err
+	}
+	if len(cr.ImageRequests) == 0 || cr.ImageRequests[0].UploadRequest.Type != UploadTypesContainer {
+		return nil
+	}
+	uo, err := cr.ImageRequests[0].UploadRequest.Options.AsContainerUploadRequestOptions()
+	if err != nil || uo.Sign == nil {
+		return nil
+	}
+	sig, err := s.db.GetComposeSignature(event.ComposeId, event.OrgId)
+	if err == nil && sig.Status == db.ComposeSignatureSigned {
+		return nil
+	} else if err != nil && !errors.Is(err, db.ComposeSignatureNotFoundError) {
+		return err
+	}
+	status, err := event.UploadStatus.Options.AsContainerUploadStatus()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	reference, signErr := s.signImage(ctx, event.OrgId, *uo.Sign, status.Url, status.Digest)
+
+	result := db.ComposeSignatureSigned
+	var ref, lastError *string
+	if signErr != nil {
+		result = db.ComposeSignaturePending
+		if lastAttempt {
+			result = db.ComposeSignatureFailed
+		}
+		lastError = common.ToPtr(signErr.Error())
+	} else {
+		ref = &reference
+		logrus.Infof("Signed the image of compose %v", event.ComposeId)
+	}
+	err = s.db.SetComposeSignatureResult(event.ComposeId, result, ref, lastError)
+	if err != nil {
+		logrus.Errorf("Error storing the signature of compose %v: %v", event.ComposeId, err)
+		if signErr == nil {
+			return err
+		}
+	}
+	return signErr
+}
+
+// containerSignatureStatus adds whether the image of a compose which asked
+// for it is signed, and where the signature is stored, to the upload status.
+func (h *Handlers) containerSignatureStatus(ctx echo.Context, composeEntry *db.ComposeEntry, cr ComposeRequest, us *UploadStatus) error {
+	if len(cr.ImageRequests) == 0 || cr.ImageRequests[0].UploadRequest.Type != UploadTypesContainer || us == nil || us.Type != UploadTypesContainer {
+		return nil
+	}
+	uo, err := cr.ImageRequests[0].UploadRequest.Options.AsContainerUploadRequestOptions()
+	if err != nil || uo.Sign == nil {
+		return nil
+	}
+	status, err := us.Options.AsContainerUploadStatus()
+	if err != nil {
+		return err
+	}
+
+	sigStatus := ContainerUploadStatusSignatureStatusPending
+	sig, err := h.server.db.GetComposeSignature(composeEntry.Id, composeEntry.OrgId)
+	if err == nil {
+		sigStatus = ContainerUploadStatusSignatureStatus(sig.Status)
+		status.Signature = sig.Reference
+		status.SignatureError = sig.LastError
+	} else if !errors.Is(err, db.ComposeSignatureNotFoundError) {
+		ctx.Logger().Errorf("Error querying the signature of compose %v: %v", composeEntry.Id, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the signature of this compose")
+	}
+	status.SignatureStatus = &sigStatus
+	return us.Options.FromContainerUploadStatus(status)
+}
+
+// signImage signs the manifest with digest of image and pushes the
+// signature to the registry of the image, with the keys and credentials of
+// the org.
+func (s *Server) signImage(ctx context.Context, orgId string, sign ContainerUploadRequestOptionsSign, image, digest string) (string, error) {
+	if err := s.checkSign(&sign); err != nil {
+		return "", err
+	}
+	err := validateRegistryHost(image)
+	if err != nil {
+		return "", err
+	}
+
+	var sig *cosign.Signature
+	switch sign {
+	case Key:
+		raw, err := s.cosign.OrgSecret(ctx, orgId, "private_key")
+		if err != nil {
+			return "", err
+		}
+		key, err := signing.ParseKey([]byte(raw))
+		if err != nil {
+			return "", err
+		}
+		sig, err = cosign.Sign(key, image, digest)
+		if err != nil {
+			return "", err
+		}
+	case Keyless:
+		sig, err = s.cosign.Fulcio.Sign(ctx, image, digest)
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Unknown signing method")
+	}
+
+	username, err := s.cosign.OrgSecret(ctx, orgId, "registry_username")
+	if err != nil {
+		return "", err
+	}
+	password, err := s.cosign.OrgSecret(ctx, orgId, "registry_password")
+	if err != nil {
+		return "", err
+	}
+	client := s.cosign.Client
+	if client == nil {
+		client = registryClient
+	}
+	registry := &cosign.Registry{
+		Username: username,
+		Password: password,
+		Client:   client,
+	}
+	return registry.Attach(ctx, image, digest, sig)
+}
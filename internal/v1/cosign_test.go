@@ -0,0 +1,34 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/cosign"
+)
+
+func TestCheckSign(t *testing.T) {
+	requireBadRequest := func(err error) {
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		require.Equal(t, http.StatusBadRequest, httpErr.Code)
+	}
+
+	s := &Server{}
+	require.NoError(t, s.checkSign(nil))
+	requireBadRequest(s.checkSign(common.ToPtr(Key)))
+
+	s.cosign.OrgSecret = func(ctx context.Context, orgId, key string) (string, error) {
+		return "", nil
+	}
+	require.NoError(t, s.checkSign(common.ToPtr(Key)))
+	requireBadRequest(s.checkSign(common.ToPtr(Keyless)))
+
+	s.cosign.Fulcio = &cosign.Fulcio{URL: "https://fulcio.sigstore.dev"}
+	require.NoError(t, s.checkSign(common.ToPtr(Keyless)))
+}
@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redhatinsights/identity"
+)
+
+// maxIdentityHeaderSize is the size of the base64 encoded identity header,
+// real ones are a few hundred bytes.
+const maxIdentityHeaderSize = 16 << 10
+
+// identityLimits apply to the json of identity headers, which are far
+// smaller and flatter than request bodies.
+var identityLimits = jsonLimits{maxDepth: 8, maxElements: 256}
+
+type jsonLimits struct {
+	maxDepth    int
+	maxElements int
+}
+
+func (l RequestLimits) json() jsonLimits {
+	return jsonLimits{maxDepth: l.MaxJSONDepth, maxElements: l.MaxJSONElements}
+}
+
+// checkJSON tells whether raw is a single json value of valid UTF-8 within
+// the limits. encoding/json silently replaces invalid UTF-8, so what was
+// validated and what's stored could differ otherwise.
+func checkJSON(raw []byte, limits jsonLimits) error {
+	if !utf8.Valid(raw) {
+		return errors.New("invalid UTF-8")
+	}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return errors.New("no json value")
+	}
+	return checkJSONLimits(bytes.NewReader(raw), limits.maxDepth, limits.maxElements)
+}
+
+// decodeJSON is how untrusted json, request bodies and identity headers, is
+// decoded: it's checked with checkJSON first.
+func decodeJSON(raw []byte, v interface{}, limits jsonLimits) error {
+	err := checkJSON(raw, limits)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// parseIdentityHeader decodes the base64 encoded x-rh-identity header. The
+// org of the internal identity is used if there's none at the top level.
+func parseIdentityHeader(rawHeader string) (identity.XRHID, error) {
+	var id identity.XRHID
+	if len(rawHeader) > maxIdentityHeaderSize {
+		return id, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("x-rh-identity header exceeds %d bytes", maxIdentityHeaderSize))
+	}
+	idRaw, err := base64.StdEncoding.DecodeString(rawHeader)
+	if err != nil {
+		return id, echo.NewHTTPError(http.StatusBadRequest, "unable to b64 decode x-rh-identity header")
+	}
+
+	err = decodeJSON(idRaw, &id, identityLimits)
+	if err != nil {
+		return id, echo.NewHTTPError(http.StatusBadRequest, "x-rh-identity header does not contain valid JSON")
+	}
+
+	if id.Identity.OrgID == "" && id.Identity.Internal.OrgID != "" {
+		id.Identity.OrgID = id.Identity.Internal.OrgID
+	}
+	return id, nil
+}
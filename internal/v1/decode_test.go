@@ -0,0 +1,140 @@
+package v1
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/pkg/tutils"
+)
+
+var testLimits = RequestLimits{}.withDefaults().json()
+
+func TestCheckJSON(t *testing.T) {
+	require.NoError(t, checkJSON([]byte(`{"image_name": "żółw"}`), testLimits))
+	require.NoError(t, checkJSON([]byte(" [1, 2]\n"), testLimits))
+
+	require.ErrorContains(t, checkJSON([]byte("{\"image_name\": \"\xff\"}"), testLimits), "invalid UTF-8")
+	require.ErrorContains(t, checkJSON([]byte(" "), testLimits), "no json value")
+	require.ErrorContains(t, checkJSON([]byte(`{} {}`), testLimits), "unexpected data after the json value")
+	require.ErrorContains(t, checkJSON([]byte(`"a" "b"`), testLimits), "unexpected data after the json value")
+	require.ErrorContains(t, checkJSON([]byte(strings.Repeat("[", 40)+strings.Repeat("]", 40)), testLimits), "nested deeper")
+}
+
+func TestParseIdentityHeader(t *testing.T) {
+	id, err := parseIdentityHeader(tutils.AuthString0)
+	require.NoError(t, err)
+	require.Equal(t, "000000", id.Identity.OrgID)
+
+	id, err = parseIdentityHeader(base64.StdEncoding.EncodeToString([]byte(`{"identity": {"internal": {"org_id": "000123"}}}`)))
+	require.NoError(t, err)
+	require.Equal(t, "000123", id.Identity.OrgID)
+
+	bad := []string{
+		"not base64!",
+		base64.StdEncoding.EncodeToString([]byte(`{"identity": `)),
+		base64.StdEncoding.EncodeToString([]byte("{\"identity\": {\"org_id\": \"\xfe\"}}")),
+		base64.StdEncoding.EncodeToString([]byte(`{"identity": {}} {"identity": {}}`)),
+		base64.StdEncoding.EncodeToString([]byte(`{"identity": {"a": ` + strings.Repeat("[", 10) + strings.Repeat("]", 10) + `}}`)),
+		strings.Repeat("A", maxIdentityHeaderSize+4),
+	}
+	for _, header := range bad {
+		_, err = parseIdentityHeader(header)
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		require.Equal(t, http.StatusBadRequest, httpErr.Code)
+	}
+}
+
+func TestBinder(t *testing.T) {
+	bind := func(body string) (*CloneRequest, error) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		var clone CloneRequest
+		err := binder{limits: testLimits}.Bind(&clone, echo.New().NewContext(req, httptest.NewRecorder()))
+		return &clone, err
+	}
+	clone, err := bind(`{"region": "us-east-2"}`)
+	require.NoError(t, err)
+	ec2, err := clone.AsAWSEC2Clone()
+	require.NoError(t, err)
+	require.Equal(t, "us-east-2", ec2.Region)
+
+	_, err = bind("{\"region\": \"us-east-\xff\"}")
+	require.ErrorContains(t, err, "invalid UTF-8")
+	_, err = bind(`{"region": "us-east-2"} trailing`)
+	require.Error(t, err)
+}
+
+var composeRequestSeeds = []string{
+	`{"distribution": "rhel-9", "image_requests": [{"architecture": "x86_64", "image_type": "aws", "upload_request": {"type": "aws", "options": {"share_with_accounts": ["123456789012"]}}}]}`,
+	`{"distribution": "centos-9", "image_name": "edge", "customizations": {"packages": ["vim"], "users": [{"name": "user", "ssh_key": "ssh-rsa AAAA"}], "filesystem": [{"mountpoint": "/var", "min_size": 1073741824}]}, "image_requests": [{"architecture": "aarch64", "image_type": "guest-image", "upload_request": {"type": "aws.s3", "options": {}}}]}`,
+	`{"distribution": "rhel-8", "image_requests": [{"architecture": "x86_64", "image_type": "azure", "upload_request": {"type": "azure", "options": {"resource_group": "images", "tenant_id": "b8f86d22-4371-46ce-95e7-65c415f3b1e2", "subscription_id": "60631143-a7dc-4d15-988b-ba83f3c99711"}}}]}`,
+	`[]`,
+	`null`,
+}
+
+// FuzzDecodeComposeRequest checks that compose requests which make it past
+// decodeJSON are valid json within the limits, and survive a round trip.
+func FuzzDecodeComposeRequest(f *testing.F) {
+	for _, seed := range composeRequestSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		var cr ComposeRequest
+		if decodeJSON(raw, &cr, testLimits) != nil {
+			return
+		}
+		require.True(t, json.Valid(raw))
+		encoded, err := json.Marshal(cr)
+		require.NoError(t, err)
+		var again ComposeRequest
+		require.NoError(t, decodeJSON(encoded, &again, testLimits))
+	})
+}
+
+// FuzzCheckJSON checks that whatever checkJSON accepts decodes.
+func FuzzCheckJSON(f *testing.F) {
+	for _, seed := range composeRequestSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Add([]byte(`{"a": [[[{"b": 1e400}]]]}`))
+	f.Add([]byte("\"\\ud800\""))
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		if checkJSON(raw, jsonLimits{maxDepth: 4, maxElements: 8}) != nil {
+			return
+		}
+		var v interface{}
+		require.NoError(t, json.Unmarshal(raw, &v))
+	})
+}
+
+// FuzzParseIdentityHeader feeds base64 encoded json to the identity header
+// parser.
+func FuzzParseIdentityHeader(f *testing.F) {
+	for _, header := range []string{tutils.AuthString0, tutils.AuthString0WithoutEntitlements} {
+		raw, err := base64.StdEncoding.DecodeString(header)
+		require.NoError(f, err)
+		f.Add(raw)
+	}
+	f.Add([]byte(`{"identity": {"internal": {"org_id": "000123"}}}`))
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		id, err := parseIdentityHeader(base64.StdEncoding.EncodeToString(raw))
+		if err != nil {
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			require.Equal(t, http.StatusBadRequest, httpErr.Code)
+			return
+		}
+		require.True(t, json.Valid(raw))
+		if id.Identity.OrgID == "" {
+			require.Empty(t, id.Identity.Internal.OrgID)
+		}
+	})
+}
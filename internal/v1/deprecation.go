@@ -0,0 +1,189 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/prometheus"
+)
+
+// deprecation is a deprecated part of the api: an operation, a parameter of
+// one, or a value of a parameter or of a field of its request body. Requests
+// using it get Deprecation, Sunset and Link headers (RFC 9745 and RFC 8594).
+type deprecation struct {
+	// Label of the deprecation in the metrics, e.g. image_type=ami
+	Name string
+	// Lowercase operation id, empty for all operations
+	Operation string
+	// Query parameter, or JSON pointer of a field in the request body, in
+	// which * matches every array element. Empty for the whole operation.
+	Parameter string
+	Field     string
+	// Deprecated value of the parameter or field, empty for any value
+	Value string
+	// When it was deprecated
+	Since time.Time
+	// When it's going to be removed, if that's decided
+	Sunset time.Time
+	// Documentation of the deprecation or its replacement, optional
+	Link string
+}
+
+// deprecations are all deprecated parts of the api, mark parts deprecated in
+// the spec as well.
+var deprecations = imageTypeAliasDeprecations(map[ImageTypes]ImageTypes{
+	ImageTypesAmi:               ImageTypesAws,
+	ImageTypesRhelEdgeCommit:    ImageTypesEdgeCommit,
+	ImageTypesRhelEdgeInstaller: ImageTypesEdgeInstaller,
+	ImageTypesVhd:               ImageTypesAzure,
+})
+
+// imageTypeAliasDeprecations deprecates the backwards compatible aliases of
+// image types in compose requests and filters.
+func imageTypeAliasDeprecations(aliases map[ImageTypes]ImageTypes) []deprecation {
+	since := time.Date(2026, time.October, 14, 0, 0, 0, 0, time.UTC)
+	var ds []deprecation
+	for alias := range aliases {
+		name := fmt.Sprintf("image_type=%s", alias)
+		ds = append(ds,
+			deprecation{Name: name, Operation: "composeimage", Field: "/image_requests/*/image_type", Value: string(alias), Since: since},
+			deprecation{Name: name, Operation: "composeimagebatch", Field: "/composes/*/image_requests/*/image_type", Value: string(alias), Since: since},
+			deprecation{Name: name, Parameter: "ignoreImageTypes", Value: string(alias), Since: since},
+		)
+	}
+	return ds
+}
+
+func (d deprecation) usedBy(operation string, query map[string][]string, body interface{}) bool {
+	if d.Operation != "" && d.Operation != operation {
+		return false
+	}
+	var values []string
+	switch {
+	case d.Parameter != "":
+		var ok bool
+		values, ok = query[d.Parameter]
+		if !ok {
+			return false
+		}
+	case d.Field != "":
+		values = fieldValues(body, strings.Split(strings.TrimPrefix(d.Field, "/"), "/"))
+		if len(values) == 0 {
+			return false
+		}
+	default:
+		return true
+	}
+	if d.Value == "" {
+		return true
+	}
+	for _, v := range values {
+		if v == d.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldValues returns the string values at a JSON pointer in a decoded body,
+// with * matching every element of an array.
+func fieldValues(v interface{}, path []string) []string {
+	if len(path) == 0 {
+		if s, ok := v.(string); ok {
+			return []string{s}
+		}
+		return nil
+	}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return fieldValues(t[path[0]], path[1:])
+	case []interface{}:
+		if path[0] == "*" {
+			var values []string
+			for _, e := range t {
+				values = append(values, fieldValues(e, path[1:])...)
+			}
+			return values
+		}
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 || i >= len(t) {
+			return nil
+		}
+		return fieldValues(t[i], path[1:])
+	}
+	return nil
+}
+
+// setDeprecationHeaders sets the headers of the deprecations a request used,
+// the Sunset header being the earliest one of them.
+func setDeprecationHeaders(h http.Header, used []deprecation) {
+	if len(used) == 0 {
+		return
+	}
+	since := used[0].Since
+	var sunset time.Time
+	for _, d := range used {
+		if d.Since.Before(since) {
+			since = d.Since
+		}
+		if !d.Sunset.IsZero() && (sunset.IsZero() || d.Sunset.Before(sunset)) {
+			sunset = d.Sunset
+		}
+		if d.Link != "" {
+			h.Add("Link", fmt.Sprintf("<%s>; rel=\"deprecation\"", d.Link))
+		}
+	}
+	h.Set("Deprecation", fmt.Sprintf("@%d", since.Unix()))
+	if !sunset.IsZero() {
+		h.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+	}
+}
+
+// markDeprecations tells clients about the deprecated parts of the api their
+// requests use, and counts the uses.
+func (s *Server) markDeprecations(nextHandler echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		route, _, err := s.router.FindRoute(ctx.Request())
+		if err != nil {
+			return nextHandler(ctx)
+		}
+		operation := operationId(route)
+
+		var body interface{}
+		request := ctx.Request()
+		if request.Body != nil && strings.HasPrefix(request.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+			// the body is limited and validated by now, it's read again by
+			// the handler
+			raw, err := io.ReadAll(request.Body)
+			if err != nil {
+				return err
+			}
+			request.Body = io.NopCloser(bytes.NewReader(raw))
+			// bodies which aren't json don't use deprecated fields
+			_ = json.Unmarshal(raw, &body)
+		}
+
+		var used []deprecation
+		query := request.URL.Query()
+		for _, d := range deprecations {
+			if d.usedBy(operation, query, body) {
+				used = append(used, d)
+				prometheus.DeprecatedUses.WithLabelValues(d.Name).Inc()
+			}
+		}
+		if len(used) > 0 {
+			logrus.Debugf("Request to %s uses deprecated %s", operation, used[0].Name)
+		}
+		setDeprecationHeaders(ctx.Response().Header(), used)
+		return nextHandler(ctx)
+	}
+}
@@ -0,0 +1,121 @@
+package v1
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	legacyrouter "github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldValues(t *testing.T) {
+	body := map[string]interface{}{
+		"image_requests": []interface{}{
+			map[string]interface{}{"image_type": "ami"},
+			map[string]interface{}{"image_type": "aws", "size": 1.0},
+		},
+	}
+	require.Equal(t, []string{"ami", "aws"}, fieldValues(body, []string{"image_requests", "*", "image_type"}))
+	require.Equal(t, []string{"aws"}, fieldValues(body, []string{"image_requests", "1", "image_type"}))
+	require.Empty(t, fieldValues(body, []string{"image_requests", "2", "image_type"}))
+	require.Empty(t, fieldValues(body, []string{"image_requests", "*", "size"}))
+	require.Empty(t, fieldValues(nil, []string{"distribution"}))
+}
+
+func TestDeprecationUsedBy(t *testing.T) {
+	op := deprecation{Operation: "getcomposes"}
+	require.True(t, op.usedBy("getcomposes", nil, nil))
+	require.False(t, op.usedBy("composeimage", nil, nil))
+
+	param := deprecation{Parameter: "ignoreImageTypes", Value: "ami"}
+	require.True(t, param.usedBy("getcomposes", map[string][]string{"ignoreImageTypes": {"aws", "ami"}}, nil))
+	require.False(t, param.usedBy("getcomposes", map[string][]string{"ignoreImageTypes": {"aws"}}, nil))
+	require.False(t, param.usedBy("getcomposes", nil, nil))
+
+	field := deprecation{Operation: "composeimage", Field: "/distribution"}
+	require.True(t, field.usedBy("composeimage", nil, map[string]interface{}{"distribution": "rhel-8"}))
+	require.False(t, field.usedBy("composeimage", nil, map[string]interface{}{}))
+}
+
+func TestDeprecationHeaders(t *testing.T) {
+	h := http.Header{}
+	setDeprecationHeaders(h, nil)
+	require.Empty(t, h)
+
+	setDeprecationHeaders(h, []deprecation{
+		{Since: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), Sunset: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), Link: "https://example.com/a"},
+		{Since: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Sunset: time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)},
+	})
+	require.Equal(t, "@1767225600", h.Get("Deprecation"))
+	require.Equal(t, "Tue, 01 Dec 2026 00:00:00 GMT", h.Get("Sunset"))
+	require.Equal(t, []string{`<https://example.com/a>; rel="deprecation"`}, h.Values("Link"))
+}
+
+func TestMarkDeprecations(t *testing.T) {
+	spec, err := GetSwagger()
+	require.NoError(t, err)
+	router, err := legacyrouter.NewRouter(spec)
+	require.NoError(t, err)
+	s := &Server{router: router}
+
+	e := echo.New()
+	var handlerBody string
+	handler := func(ctx echo.Context) error {
+		// the handler still reads the body
+		body, err := io.ReadAll(ctx.Request().Body)
+		handlerBody = string(body)
+		return err
+	}
+	e.POST("/api/image-builder/v1/compose", handler, s.markDeprecations)
+	e.GET("/api/image-builder/v1/composes", handler, s.markDeprecations)
+
+	compose := func(imageType string) http.Header {
+		body := `{"distribution": "rhel-8", "image_requests": [{"architecture": "x86_64", "image_type": "` + imageType + `"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/api/image-builder/v1/compose", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, body, handlerBody)
+		return rec.Header()
+	}
+	require.NotEmpty(t, compose("ami").Get("Deprecation"))
+	require.Empty(t, compose("aws").Get("Deprecation"))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/image-builder/v1/composes?ignoreImageTypes=vhd", nil))
+	require.NotEmpty(t, rec.Header().Get("Deprecation"))
+}
+
+// Operations and parameters deprecated in the spec need to be deprecated
+// here too, so their clients are told.
+func TestSpecDeprecations(t *testing.T) {
+	spec, err := GetSwagger()
+	require.NoError(t, err)
+	deprecated := func(operation, parameter string) bool {
+		for _, d := range deprecations {
+			if (d.Operation == "" || d.Operation == operation) && d.Parameter == parameter && d.Field == "" && d.Value == "" {
+				return true
+			}
+		}
+		return false
+	}
+	for _, path := range spec.Paths {
+		for _, op := range path.Operations() {
+			opId := strings.ToLower(op.OperationID)
+			if op.Deprecated {
+				require.True(t, deprecated(opId, ""), "operation %s", opId)
+			}
+			for _, p := range append(path.Parameters, op.Parameters...) {
+				if p.Value != nil && p.Value.Deprecated {
+					require.True(t, deprecated(opId, p.Value.Name), "parameter %s of %s", p.Value.Name, opId)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/mail"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/email"
+)
+
+// Mailer renders a template with data and emails it, it's implemented by
+// email.Mailer.
+type Mailer interface {
+	Send(ctx context.Context, to, template string, data interface{}) error
+}
+
+// composeEmail is the data the compose-succeeded and compose-failed
+// templates are rendered with.
+type composeEmail struct {
+	// name of the image, or the compose id if it doesn't have one
+	Name      string
+	ComposeId string
+	Status    string
+	Reason    string
+}
+
+// validateNotifyEmail returns the address a compose asked to be notified at.
+func (s *Server) validateNotifyEmail(notifyEmail string) (*string, error) {
+	if s.mailer == nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Email notifications aren't available on this deployment")
+	}
+	addr, err := mail.ParseAddress(notifyEmail)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid notify_email address")
+	}
+	return &addr.Address, nil
+}
+
+// emailComposeFinished emails the outcome of a compose if it asked for it.
+// Emails to addresses over their rate limit are dropped rather than retried.
+func (s *Server) emailComposeFinished(composeId uuid.UUID, orgId, status string, reason *string) error {
+	if s.mailer == nil {
+		return nil
+	}
+	to, err := s.db.GetComposeNotifyEmail(composeId)
+	if err != nil {
+		return err
+	}
+	if to == nil {
+		return nil
+	}
+
+	data := composeEmail{
+		Name:      composeId.String(),
+		ComposeId: composeId.String(),
+		Status:    status,
+	}
+	if reason != nil {
+		data.Reason = *reason
+	}
+	compose, err := s.db.GetCompose(composeId, orgId)
+	if err == nil && compose.ImageName != nil && *compose.ImageName != "" {
+		data.Name = *compose.ImageName
+	}
+	template := "compose-failed"
+	if status == string(composer.ImageStatusValueSuccess) {
+		template = "compose-succeeded"
+	}
+
+	err = s.mailer.Send(context.Background(), *to, template, data)
+	if errors.Is(err, email.RateLimitedError) {
+		logrus.Warnf("Not emailing the outcome of compose %v, too many emails were sent to the address", composeId)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,36 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMailer struct {
+	sent chan sentEmail
+}
+
+type sentEmail struct {
+	to       string
+	template string
+	data     composeEmail
+}
+
+func (m *fakeMailer) Send(ctx context.Context, to, template string, data interface{}) error {
+	m.sent <- sentEmail{to, template, data.(composeEmail)}
+	return nil
+}
+
+func TestValidateNotifyEmail(t *testing.T) {
+	s := &Server{}
+	_, err := s.validateNotifyEmail("user@example.com")
+	require.ErrorContains(t, err, "Email notifications aren't available")
+
+	s.mailer = &fakeMailer{}
+	addr, err := s.validateNotifyEmail("User <user@example.com>")
+	require.NoError(t, err)
+	require.Equal(t, "user@example.com", *addr)
+	_, err = s.validateNotifyEmail("user")
+	require.ErrorContains(t, err, "Invalid notify_email")
+}
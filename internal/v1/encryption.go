@@ -0,0 +1,288 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/ec2"
+)
+
+// How long a step of encrypting the AMI of a compose may take, the copy
+// itself is waited for by retrying.
+const encryptionTimeout = 2 * time.Minute
+
+// awsEncryption returns the encryption of an aws upload, nil if its AMI isn't
+// encrypted. Composer can't encrypt images, so the AMI is copied with the key
+// once the compose succeeded and only the copy is shared. That's why the
+// accounts are moved out of the upload options of composer. The key is
+// checked up front, so composes don't build images which can't be
+// encrypted.
+func (h *Handlers) awsEncryption(ctx echo.Context, ur UploadRequest, uploadOptions *composer.UploadOptions) (*db.ComposeEncryptionEntry, error) {
+	if ur.Type != UploadTypesAws {
+		return nil, nil
+	}
+	uo, err := ur.Options.AsAWSUploadRequestOptions()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Unable to parse upload request options as aws options")
+	}
+	co, err := uploadOptions.AsAWSEC2UploadOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	if uo.Encrypted != nil && !*uo.Encrypted {
+		if uo.KmsKeyArn != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "A KMS key cannot be used for an unencrypted image")
+		}
+		return nil, nil
+	}
+	keyArn := uo.KmsKeyArn
+	// the default key only exists in the region of the service
+	if keyArn == nil && h.server.aws.DefaultKMSKeyArn != "" && co.Region == h.server.aws.Region {
+		keyArn = &h.server.aws.DefaultKMSKeyArn
+	}
+	if keyArn == nil {
+		if uo.Encrypted != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Images can only be encrypted with a KMS key, set kms_key_arn")
+		}
+		return nil, nil
+	}
+
+	if h.server.aws.EC2 == nil || h.server.aws.KMS == nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "This service doesn't encrypt images")
+	}
+	if awsPartitionOfRegion(co.Region) != AWSPartitionCommercial {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Images in the aws-us-gov partition can't be encrypted")
+	}
+	if uo.Regions != nil && len(*uo.Regions) > 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Encrypted images can't be copied into other regions, set encrypted to false")
+	}
+	err = validateKMSKeyArn(*keyArn, co.Region)
+	if err != nil {
+		return nil, err
+	}
+	key, err := h.server.aws.KMS.CheckKey(ctx.Request().Context(), co.Region, *keyArn)
+	if err != nil {
+		ctx.Logger().Warnf("KMS key %s can't be used: %v", *keyArn, err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("KMS key %s can't be used to encrypt the image: %v", *keyArn, err))
+	}
+
+	encryption := &db.ComposeEncryptionEntry{
+		Region:            co.Region,
+		KmsKeyId:          key.Arn,
+		ShareWithAccounts: co.ShareWithAccounts,
+	}
+	co.ShareWithAccounts = []string{}
+	err = uploadOptions.FromAWSEC2UploadOptions(co)
+	if err != nil {
+		return nil, err
+	}
+	return encryption, nil
+}
+
+// insertComposeEncryption stores the encryption of a compose, if it has one.
+func (s *Server) insertComposeEncryption(composeId uuid.UUID, encryption *db.ComposeEncryptionEntry) error {
+	if encryption == nil {
+		return nil
+	}
+	e := *encryption
+	e.ComposeId = composeId
+	return s.db.InsertComposeEncryption(e)
+}
+
+// encryptCompose copies the AMI of a successful compose with the key of its
+// encryption, shares the copy and deletes the image composer uploaded. The
+// encryption is claimed first, so concurrent dispatches don't work on it
+// twice. It's retried until the copy is available, the steps which are
+// done are skipped then. The encryption is marked as failed if this was
+// the last attempt.
+func (s *Server) encryptCompose(event outboxEvent, lastAttempt bool) error {
+	if event.UploadStatus == nil || event.UploadStatus.Type != UploadTypesAws {
+		return nil
+	}
+	e, err := s.db.GetComposeEncryption(event.ComposeId)
+	if errors.Is(err, db.ComposeEncryptionNotFoundError) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if e.Status == db.ComposeEncryptionDone || e.Status == db.ComposeEncryptionFailed {
+		return nil
+	}
+	if s.aws.EC2 == nil {
+		return fmt.Errorf("no EC2 client to encrypt the image with")
+	}
+	claimed, err := s.db.ClaimComposeEncryption(event.ComposeId)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		// retried until whoever claimed it is done
+		return fmt.Errorf("encryption of compose %v is claimed", event.ComposeId)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), encryptionTimeout)
+	defer cancel()
+	err = s.encryptImage(ctx, e, event.UploadStatus)
+	e.LastError = nil
+	if err != nil {
+		msg := err.Error()
+		e.LastError = &msg
+		if lastAttempt {
+			e.Status = db.ComposeEncryptionFailed
+		}
+	}
+	setErr := s.db.SetComposeEncryption(*e)
+	if setErr != nil {
+		logrus.Errorf("Error storing the encryption of compose %v: %v", event.ComposeId, setErr)
+		if err == nil {
+			return setErr
+		}
+	}
+	if err == nil {
+		logrus.Infof("Encrypted the image of compose %v as %s", event.ComposeId, *e.ImageId)
+	}
+	return err
+}
+
+// encryptImage moves an encryption a step further, it fails while the copy
+// is pending.
+func (s *Server) encryptImage(ctx context.Context, e *db.ComposeEncryptionEntry, us *UploadStatus) error {
+	if e.ImageId == nil {
+		status, err := us.Options.AsAWSUploadStatus()
+		if err != nil {
+			return err
+		}
+		// the compose id as the client token only ever copies it once
+		imageId, err := s.aws.EC2.CopyImage(ctx, e.Region, status.Ami, fmt.Sprintf("%s-encrypted", e.ComposeId), e.KmsKeyId, e.ComposeId.String())
+		if err != nil {
+			return err
+		}
+		e.SourceImageId = &status.Ami
+		e.ImageId = &imageId
+		e.Status = db.ComposeEncryptionCopying
+	}
+
+	image, err := s.aws.EC2.DescribeImage(ctx, e.Region, *e.ImageId)
+	if err != nil {
+		return err
+	}
+	if image == nil || image.State == ec2.ImageStatePending {
+		// new copies can take a moment to be described
+		return fmt.Errorf("copy %s is still pending", *e.ImageId)
+	}
+	if image.State != ec2.ImageStateAvailable {
+		e.Status = db.ComposeEncryptionFailed
+		return fmt.Errorf("copy %s is %s", *e.ImageId, image.State)
+	}
+
+	err = s.aws.EC2.ShareImage(ctx, e.Region, *e.ImageId, e.ShareWithAccounts)
+	if err != nil {
+		return err
+	}
+	err = s.aws.EC2.DeleteImage(ctx, e.Region, *e.SourceImageId)
+	if err != nil {
+		return err
+	}
+	e.Status = db.ComposeEncryptionDone
+	return nil
+}
+
+// encryptedImage returns the encrypted copy of the AMI of a compose, nil if
+// it isn't encrypted. It fails until the copy is shared, so the sinks which
+// hand out the AMI are retried until they can hand out the copy.
+func (s *Server) encryptedImage(composeId uuid.UUID) (*string, error) {
+	e, err := s.db.GetComposeEncryption(composeId)
+	if errors.Is(err, db.ComposeEncryptionNotFoundError) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	switch e.Status {
+	case db.ComposeEncryptionDone:
+		return e.ImageId, nil
+	case db.ComposeEncryptionFailed:
+		return nil, fmt.Errorf("encrypting the image of compose %v failed", composeId)
+	}
+	return nil, fmt.Errorf("the image of compose %v isn't encrypted yet", composeId)
+}
+
+// encryptedUploadStatus returns the upload status of a successful compose
+// with the encrypted copy of its AMI, if it's encrypted.
+func (s *Server) encryptedUploadStatus(composeId uuid.UUID, us *UploadStatus) (*UploadStatus, error) {
+	if us == nil || us.Type != UploadTypesAws {
+		return us, nil
+	}
+	imageId, err := s.encryptedImage(composeId)
+	if err != nil || imageId == nil {
+		return us, err
+	}
+	status, err := us.Options.AsAWSUploadStatus()
+	if err != nil {
+		return nil, err
+	}
+	status.Ami = *imageId
+	encrypted := *us
+	err = encrypted.Options.FromAWSUploadStatus(status)
+	if err != nil {
+		return nil, err
+	}
+	return &encrypted, nil
+}
+
+// encryptionStatus reports the encrypted copy of the AMI of a successful
+// compose instead of the image composer uploaded. The compose is still
+// uploading until the copy is shared, and failed if it can't be.
+func (h *Handlers) encryptionStatus(ctx echo.Context, composeEntry *db.ComposeEntry, status *ImageStatus) error {
+	us := status.UploadStatus
+	if us == nil || us.Type != UploadTypesAws {
+		return nil
+	}
+	e, err := h.server.db.GetComposeEncryption(composeEntry.Id)
+	if errors.Is(err, db.ComposeEncryptionNotFoundError) {
+		return nil
+	} else if err != nil {
+		ctx.Logger().Errorf("Error querying the encryption of compose %v: %v", composeEntry.Id, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the encryption of this compose")
+	}
+
+	switch e.Status {
+	case db.ComposeEncryptionDone:
+		encrypted, err := h.server.encryptedUploadStatus(composeEntry.Id, us)
+		if err != nil {
+			return err
+		}
+		status.UploadStatus = encrypted
+	case db.ComposeEncryptionFailed:
+		reason := "Unable to encrypt the image"
+		if e.LastError != nil {
+			reason = fmt.Sprintf("%s: %s", reason, *e.LastError)
+		}
+		status.Status = ImageStatusStatusFailure
+		status.Error = &ComposeStatusError{
+			Reason: reason,
+		}
+		failed := *us
+		failed.Status = UploadStatusStatusFailure
+		status.UploadStatus = &failed
+	default:
+		// the image composer uploaded isn't handed out
+		pending, err := pendingCloneStatus(e.Region)
+		if err != nil {
+			return err
+		}
+		status.Status = ImageStatusStatusUploading
+		status.UploadStatus = pending
+		status.UploadStatus.Status = UploadStatusStatusRunning
+	}
+	return nil
+}
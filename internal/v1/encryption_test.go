@@ -0,0 +1,259 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/ec2"
+	"github.com/osbuild/image-builder/internal/kms"
+)
+
+const encryptionKeyArn = "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+
+// newAWSMock answers the KMS and EC2 requests of encrypting an image, the
+// copy is pending for as many describe requests as pending says.
+func newAWSMock(t *testing.T, pending int, calls *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if target := r.Header.Get("X-Amz-Target"); target != "" {
+			var params map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+			if !strings.HasPrefix(params["KeyId"], "arn:aws:kms:us-east-1:123456789012:") {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"__type":"AccessDeniedException","message":"not allowed"}`)
+				return
+			}
+			if target == "TrentService.DescribeKey" {
+				fmt.Fprintf(w, `{"KeyMetadata":{"Arn":%q,"KeyState":"Enabled","KeyUsage":"ENCRYPT_DECRYPT","KeySpec":"SYMMETRIC_DEFAULT"}}`, encryptionKeyArn)
+				return
+			}
+			fmt.Fprint(w, `{"CiphertextBlob":"AAAA"}`)
+			return
+		}
+
+		require.NoError(t, r.ParseForm())
+		action := r.Form.Get("Action")
+		*calls = append(*calls, action)
+		switch action {
+		case "CopyImage":
+			require.Equal(t, encryptionKeyArn, r.Form.Get("KmsKeyId"))
+			fmt.Fprint(w, `<CopyImageResponse><imageId>ami-encrypted</imageId></CopyImageResponse>`)
+		case "DescribeImages":
+			state := ec2.ImageStateAvailable
+			if r.Form.Get("ImageId.1") == "ami-encrypted" && pending > 0 {
+				pending--
+				state = ec2.ImageStatePending
+			}
+			fmt.Fprintf(w, `<DescribeImagesResponse><imagesSet><item><imageId>%s</imageId><imageState>%s</imageState>
+<blockDeviceMapping><item><ebs><snapshotId>snap-plain</snapshotId></ebs></item></blockDeviceMapping></item></imagesSet></DescribeImagesResponse>`, r.Form.Get("ImageId.1"), state)
+		case "ModifyImageAttribute":
+			require.Equal(t, "ami-encrypted", r.Form.Get("ImageId"))
+			require.Equal(t, "123456123456", r.Form.Get("LaunchPermission.Add.1.UserId"))
+			fmt.Fprint(w, `<ModifyImageAttributeResponse><return>true</return></ModifyImageAttributeResponse>`)
+		case "DeregisterImage":
+			require.Equal(t, "ami-plain", r.Form.Get("ImageId"))
+			fmt.Fprint(w, `<DeregisterImageResponse><return>true</return></DeregisterImageResponse>`)
+		case "DeleteSnapshot":
+			require.Equal(t, "snap-plain", r.Form.Get("SnapshotId"))
+			fmt.Fprint(w, `<DeleteSnapshotResponse><return>true</return></DeleteSnapshotResponse>`)
+		default:
+			require.FailNowf(t, "Unexpected request to mocked ec2", "action: %s", action)
+		}
+	}))
+}
+
+func newAWSEncryptionConfig(t *testing.T, endpoint string) AWSConfig {
+	creds := credentials.NewStaticCredentials("key", "secret", "")
+	ec2Client, err := ec2.NewClient(ec2.Config{Endpoint: endpoint, Credentials: creds})
+	require.NoError(t, err)
+	kmsClient, err := kms.NewClient(kms.Config{Endpoint: endpoint, Credentials: creds})
+	require.NoError(t, err)
+	return AWSConfig{
+		Region: "us-east-1",
+		EC2:    ec2Client,
+		KMS:    kmsClient,
+	}
+}
+
+func TestAWSEncryption(t *testing.T) {
+	var calls []string
+	awsSrv := newAWSMock(t, 0, &calls)
+	defer awsSrv.Close()
+
+	newContext := func() echo.Context {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		return echo.New().NewContext(req, httptest.NewRecorder())
+	}
+	awsUpload := func(uo AWSUploadRequestOptions) UploadRequest {
+		uo.ShareWithAccounts = &[]string{"123456123456"}
+		var options UploadRequest_Options
+		require.NoError(t, options.FromAWSUploadRequestOptions(uo))
+		return UploadRequest{Type: UploadTypesAws, Options: options}
+	}
+	encryption := func(h *Handlers, uo AWSUploadRequestOptions) (*db.ComposeEncryptionEntry, composer.AWSEC2UploadOptions, error) {
+		var uploadOptions composer.UploadOptions
+		require.NoError(t, uploadOptions.FromAWSEC2UploadOptions(composer.AWSEC2UploadOptions{
+			Region:            "us-east-1",
+			ShareWithAccounts: []string{"123456123456"},
+		}))
+		e, err := h.awsEncryption(newContext(), awsUpload(uo), &uploadOptions)
+		co, convErr := uploadOptions.AsAWSEC2UploadOptions()
+		require.NoError(t, convErr)
+		return e, co, err
+	}
+
+	h := &Handlers{server: &Server{aws: newAWSEncryptionConfig(t, awsSrv.URL)}}
+
+	// composer doesn't share the image, only its encrypted copy is
+	e, co, err := encryption(h, AWSUploadRequestOptions{KmsKeyArn: common.ToPtr("arn:aws:kms:us-east-1:123456789012:alias/images")})
+	require.NoError(t, err)
+	require.Equal(t, &db.ComposeEncryptionEntry{
+		Region:            "us-east-1",
+		KmsKeyId:          encryptionKeyArn,
+		ShareWithAccounts: []string{"123456123456"},
+	}, e)
+	require.Empty(t, co.ShareWithAccounts)
+
+	// without a default key images are only encrypted on request
+	e, co, err = encryption(h, AWSUploadRequestOptions{})
+	require.NoError(t, err)
+	require.Nil(t, e)
+	require.Equal(t, []string{"123456123456"}, co.ShareWithAccounts)
+	_, _, err = encryption(h, AWSUploadRequestOptions{Encrypted: common.ToPtr(true)})
+	require.ErrorContains(t, err, "Images can only be encrypted with a KMS key")
+
+	// keys the service can't use are rejected before anything is built
+	_, _, err = encryption(h, AWSUploadRequestOptions{KmsKeyArn: common.ToPtr("arn:aws:kms:us-east-1:000000000000:key/other")})
+	require.ErrorContains(t, err, "can't be used to encrypt the image: DescribeKey of key arn:aws:kms:us-east-1:000000000000:key/other failed with AccessDeniedException")
+	_, _, err = encryption(h, AWSUploadRequestOptions{KmsKeyArn: common.ToPtr("arn:aws:kms:eu-west-1:123456789012:key/k")})
+	require.ErrorContains(t, err, "KMS key has to be in region us-east-1")
+	_, _, err = encryption(h, AWSUploadRequestOptions{KmsKeyArn: common.ToPtr(encryptionKeyArn), Encrypted: common.ToPtr(false)})
+	require.ErrorContains(t, err, "A KMS key cannot be used for an unencrypted image")
+	_, _, err = encryption(h, AWSUploadRequestOptions{KmsKeyArn: common.ToPtr(encryptionKeyArn), Regions: &[]string{"eu-west-1"}})
+	require.ErrorContains(t, err, "Encrypted images can't be copied into other regions")
+
+	// images are encrypted by default with the default key
+	h.server.aws.DefaultKMSKeyArn = encryptionKeyArn
+	e, _, err = encryption(h, AWSUploadRequestOptions{})
+	require.NoError(t, err)
+	require.Equal(t, encryptionKeyArn, e.KmsKeyId)
+	e, co, err = encryption(h, AWSUploadRequestOptions{Encrypted: common.ToPtr(false)})
+	require.NoError(t, err)
+	require.Nil(t, e)
+	require.Equal(t, []string{"123456123456"}, co.ShareWithAccounts)
+	require.Empty(t, calls)
+
+	// services which can't encrypt reject keys
+	h = &Handlers{server: &Server{aws: AWSConfig{Region: "us-east-1"}}}
+	_, _, err = encryption(h, AWSUploadRequestOptions{KmsKeyArn: common.ToPtr(encryptionKeyArn)})
+	require.ErrorContains(t, err, "This service doesn't encrypt images")
+}
+
+func TestEncryptCompose(t *testing.T) {
+	var calls []string
+	awsSrv := newAWSMock(t, 1, &calls)
+	defer awsSrv.Close()
+
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	composeId := uuid.New()
+	require.NoError(t, dbase.InsertCompose(composeId, "000000", "user000000@test.test", "000000", nil, []byte("{}")))
+	s := &Server{db: dbase, aws: newAWSEncryptionConfig(t, awsSrv.URL)}
+	require.NoError(t, s.insertComposeEncryption(composeId, &db.ComposeEncryptionEntry{
+		Region:            "us-east-1",
+		KmsKeyId:          encryptionKeyArn,
+		ShareWithAccounts: []string{"123456123456"},
+	}))
+
+	var options UploadStatus_Options
+	require.NoError(t, options.FromAWSUploadStatus(AWSUploadStatus{Ami: "ami-plain", Region: "us-east-1"}))
+	uploaded := &UploadStatus{Status: UploadStatusStatusSuccess, Type: UploadTypesAws, Options: options}
+	composeEntry := &db.ComposeEntry{Id: composeId, OrgId: "000000"}
+	status := func() ImageStatus {
+		is := ImageStatus{Status: ImageStatusStatusSuccess, UploadStatus: uploaded}
+		ctx := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+		require.NoError(t, (&Handlers{server: s}).encryptionStatus(ctx, composeEntry, &is))
+		return is
+	}
+
+	// the image composer uploaded isn't handed out while it's encrypted
+	is := status()
+	require.Equal(t, ImageStatusStatusUploading, is.Status)
+	require.Equal(t, UploadStatusStatusRunning, is.UploadStatus.Status)
+	aws, err := is.UploadStatus.Options.AsAWSUploadStatus()
+	require.NoError(t, err)
+	require.Empty(t, aws.Ami)
+	_, err = s.encryptedUploadStatus(composeId, uploaded)
+	require.ErrorContains(t, err, "isn't encrypted yet")
+
+	// it's retried while the copy is pending
+	event := outboxEvent{
+		composeEventData: composeEventData{ComposeId: composeId, OrgId: "000000", Status: "success"},
+		UploadStatus:     uploaded,
+	}
+	require.ErrorContains(t, s.encryptCompose(event, false), "copy ami-encrypted is still pending")
+	e, err := dbase.GetComposeEncryption(composeId)
+	require.NoError(t, err)
+	require.Equal(t, db.ComposeEncryptionCopying, e.Status)
+	require.Equal(t, "ami-plain", *e.SourceImageId)
+
+	// the copy is shared and the unencrypted image deleted
+	require.NoError(t, s.encryptCompose(event, false))
+	require.Equal(t, []string{"CopyImage", "DescribeImages", "DescribeImages", "ModifyImageAttribute", "DescribeImages", "DeregisterImage", "DeleteSnapshot"}, calls)
+	require.NoError(t, s.encryptCompose(event, false))
+	require.Len(t, calls, 7)
+
+	is = status()
+	require.Equal(t, ImageStatusStatusSuccess, is.Status)
+	aws, err = is.UploadStatus.Options.AsAWSUploadStatus()
+	require.NoError(t, err)
+	require.Equal(t, "ami-encrypted", aws.Ami)
+	us, err := s.encryptedUploadStatus(composeId, uploaded)
+	require.NoError(t, err)
+	aws, err = us.Options.AsAWSUploadStatus()
+	require.NoError(t, err)
+	require.Equal(t, "ami-encrypted", aws.Ami)
+
+	// composes which aren't encrypted keep their image
+	otherId := uuid.New()
+	us, err = s.encryptedUploadStatus(otherId, uploaded)
+	require.NoError(t, err)
+	require.Equal(t, uploaded, us)
+	require.NoError(t, s.encryptCompose(outboxEvent{composeEventData: composeEventData{ComposeId: otherId}, UploadStatus: uploaded}, false))
+
+	// encryptions which failed for good fail the compose
+	failedId := uuid.New()
+	require.NoError(t, dbase.InsertCompose(failedId, "000000", "user000000@test.test", "000000", nil, []byte("{}")))
+	require.NoError(t, s.insertComposeEncryption(failedId, &db.ComposeEncryptionEntry{
+		Region:            "us-east-1",
+		KmsKeyId:          "arn:aws:kms:us-east-1:000000000000:key/gone",
+		ShareWithAccounts: []string{"123456123456"},
+	}))
+	failedSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `<Response><Errors><Error><Code>InvalidKMSKey.InvalidState</Code><Message>key is pending deletion</Message></Error></Errors></Response>`)
+	}))
+	defer failedSrv.Close()
+	s.aws.EC2, err = ec2.NewClient(ec2.Config{Endpoint: failedSrv.URL, Credentials: credentials.NewStaticCredentials("key", "secret", "")})
+	require.NoError(t, err)
+	event.ComposeId = failedId
+	require.ErrorContains(t, s.encryptCompose(event, true), "InvalidKMSKey.InvalidState")
+	composeEntry.Id = failedId
+	is = status()
+	require.Equal(t, ImageStatusStatusFailure, is.Status)
+	require.Contains(t, is.Error.Reason, "Unable to encrypt the image: CopyImage in us-east-1 failed with InvalidKMSKey.InvalidState")
+	require.Equal(t, UploadStatusStatusFailure, is.UploadStatus.Status)
+	_, err = s.encryptedUploadStatus(failedId, uploaded)
+	require.ErrorContains(t, err, "failed")
+}
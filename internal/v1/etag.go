@@ -0,0 +1,52 @@
+package v1
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// weakETag returns a weak entity tag (RFC 9110) of a response body, the
+// responses are marshalled again for every request, which only promises the
+// same content.
+func weakETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return fmt.Sprintf(`W/"%x"`, sum[:16])
+}
+
+// etagMatches tells if an If-None-Match header lists a tag, comparing them
+// weakly.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	for _, t := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(t), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonWithETag responds with the json of v along with its ETag, or with 304
+// Not Modified if the client has it already, so polling clients only
+// download changes.
+func jsonWithETag(ctx echo.Context, code int, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	etag := weakETag(body)
+	header := ctx.Response().Header()
+	header.Set("ETag", etag)
+	// the responses are of the org of the caller
+	header.Set(echo.HeaderCacheControl, "private, no-cache")
+	if ifNoneMatch := ctx.Request().Header.Get("If-None-Match"); ifNoneMatch != "" && etagMatches(ifNoneMatch, etag) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+	return ctx.JSONBlob(code, body)
+}
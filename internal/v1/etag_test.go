@@ -0,0 +1,51 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestETagMatches(t *testing.T) {
+	etag := weakETag([]byte(`{"a": 1}`))
+	require.Equal(t, etag, weakETag([]byte(`{"a": 1}`)))
+	require.NotEqual(t, etag, weakETag([]byte(`{"a": 2}`)))
+	require.Regexp(t, `^W/"[0-9a-f]{32}"$`, etag)
+
+	require.True(t, etagMatches(etag, etag))
+	require.True(t, etagMatches(`"other", `+etag, etag))
+	require.True(t, etagMatches(etag[2:], etag))
+	require.True(t, etagMatches("*", etag))
+	require.False(t, etagMatches(`W/"other"`, etag))
+}
+
+func TestJSONWithETag(t *testing.T) {
+	e := echo.New()
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		require.NoError(t, jsonWithETag(e.NewContext(req, rec), http.StatusOK, map[string]string{"status": "building"}))
+		return rec
+	}
+
+	rec := get("")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"status": "building"}`, rec.Body.String())
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	require.Equal(t, "private, no-cache", rec.Header().Get("Cache-Control"))
+
+	rec = get(etag)
+	require.Equal(t, http.StatusNotModified, rec.Code)
+	require.Empty(t, rec.Body.String())
+	require.Equal(t, etag, rec.Header().Get("ETag"))
+
+	rec = get(`W/"stale"`)
+	require.Equal(t, http.StatusOK, rec.Code)
+}
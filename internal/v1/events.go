@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// Statuses of composes before they are built, composes which don't have to
+// wait are created and submitted at once.
+const (
+	composeEventCreated         = "created"
+	composeEventQueued          = "queued"
+	composeEventPendingApproval = "pending_approval"
+	composeEventSubmitted       = "submitted"
+	composeEventFailure         = "failure"
+)
+
+// recordComposeEvent stores a status of a compose which composer doesn't
+// report. The history is informational, so errors are only logged.
+func (s *Server) recordComposeEvent(composeId uuid.UUID, status string, reason *string) {
+	_, err := s.db.InsertComposeEvent(composeId, status, reason)
+	if err != nil {
+		logrus.Errorf("Error recording status %s of compose %v: %v", status, composeId, err)
+	}
+}
+
+func (h *Handlers) GetComposeEvents(ctx echo.Context, composeId uuid.UUID) error {
+	err := h.canUserAccessComposeId(ctx, composeId)
+	if err != nil {
+		return err
+	}
+
+	entries, err := h.server.db.GetComposeEvents(composeId)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying the events of compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the compose events")
+	}
+
+	events := ComposeEvents{
+		Data: []ComposeEvent{},
+	}
+	for _, e := range entries {
+		events.Data = append(events.Data, ComposeEvent{
+			Status:    e.Status,
+			Reason:    e.Reason,
+			CreatedAt: e.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return ctx.JSON(http.StatusOK, events)
+}
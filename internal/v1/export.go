@@ -0,0 +1,162 @@
+package v1
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+// composes are exported a page at a time, so the export of an org with many
+// of them is streamed instead of being held in memory
+const exportPageSize = 100
+
+var exportCSVHeader = []string{"id", "name", "distribution", "image_type", "targets", "status", "created_at"}
+
+// exportItem returns the exported row of a compose.
+func (s *Server) exportItem(c db.ComposeEntry) (ComposeExportItem, error) {
+	var cr ComposeRequest
+	err := json.Unmarshal(c.Request, &cr)
+	if err != nil {
+		return ComposeExportItem{}, err
+	}
+	distribution, imageType, _ := composeLabels(cr)
+	targets := []string{}
+	for _, ir := range cr.ImageRequests {
+		targets = append(targets, string(ir.UploadRequest.Type))
+	}
+
+	item := ComposeExportItem{
+		Id:           c.Id,
+		ImageName:    c.ImageName,
+		Distribution: distribution,
+		ImageType:    imageType,
+		Targets:      targets,
+		CreatedAt:    c.CreatedAt.Format(time.RFC3339),
+	}
+	// freshness doesn't matter, the export is what's known about a compose
+	cached, err := s.db.GetCachedComposeStatus(c.Id, 0)
+	if errors.Is(err, db.ComposeStatusNotFoundError) {
+		return item, nil
+	} else if err != nil {
+		return ComposeExportItem{}, err
+	}
+	var status composer.ComposeStatus
+	err = json.Unmarshal(cached.Status, &status)
+	if err != nil {
+		return ComposeExportItem{}, err
+	}
+	item.Status = string(status.ImageStatus.Status)
+	return item, nil
+}
+
+func (item ComposeExportItem) csvRecord() []string {
+	name := ""
+	if item.ImageName != nil {
+		name = *item.ImageName
+	}
+	return []string{
+		item.Id.String(),
+		name,
+		item.Distribution,
+		item.ImageType,
+		strings.Join(item.Targets, ";"),
+		item.Status,
+		item.CreatedAt,
+	}
+}
+
+func (h *Handlers) ExportComposes(ctx echo.Context, params ExportComposesParams) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	format := ExportComposesParamsFormatCsv
+	if params.Format != nil {
+		format = *params.Format
+	}
+	ignoreImageTypeStrings := convertIgnoreImageTypeToSlice(params.IgnoreImageTypes)
+
+	// fetch the first page before the response is started, so failing to
+	// query the database is still reported as an error
+	composes, _, err := h.server.db.GetComposes(idHeader.Identity.OrgID, (time.Hour * 24 * 14), exportPageSize, 0, ignoreImageTypeStrings)
+	if err != nil {
+		return err
+	}
+
+	resp := ctx.Response()
+	switch format {
+	case ExportComposesParamsFormatJson:
+		resp.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	default:
+		resp.Header().Set(echo.HeaderContentType, "text/csv")
+	}
+	resp.Header().Set(echo.HeaderContentDisposition, "attachment; filename=\"composes."+string(format)+"\"")
+	resp.WriteHeader(http.StatusOK)
+
+	csvWriter := csv.NewWriter(resp)
+	jsonEncoder := json.NewEncoder(resp)
+	if format == ExportComposesParamsFormatJson {
+		_, err = resp.Write([]byte("["))
+	} else {
+		err = csvWriter.Write(exportCSVHeader)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the response has started, errors from here on can only cut it short
+	first := true
+	offset := 0
+	for len(composes) > 0 {
+		for _, c := range composes {
+			item, err := h.server.exportItem(c)
+			if err != nil {
+				ctx.Logger().Errorf("Error exporting compose %v: %v", c.Id, err)
+				return nil
+			}
+			if format == ExportComposesParamsFormatJson {
+				if !first {
+					_, err = resp.Write([]byte(","))
+					if err != nil {
+						return nil
+					}
+				}
+				err = jsonEncoder.Encode(item)
+			} else {
+				err = csvWriter.Write(item.csvRecord())
+			}
+			if err != nil {
+				return nil
+			}
+			first = false
+		}
+		csvWriter.Flush()
+		resp.Flush()
+
+		if len(composes) < exportPageSize {
+			break
+		}
+		offset += len(composes)
+		composes, _, err = h.server.db.GetComposes(idHeader.Identity.OrgID, (time.Hour * 24 * 14), exportPageSize, offset, ignoreImageTypeStrings)
+		if err != nil {
+			ctx.Logger().Errorf("Error querying composes to export: %v", err)
+			return nil
+		}
+	}
+
+	if format == ExportComposesParamsFormatJson {
+		_, err = resp.Write([]byte("]\n"))
+		return err
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
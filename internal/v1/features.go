@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redhatinsights/identity"
+
+	"github.com/osbuild/image-builder/internal/distribution"
+	"github.com/osbuild/image-builder/internal/featureflags"
+)
+
+// Prefixes of the feature flags gating distributions, image types and upload
+// targets which aren't generally available yet, e.g.
+// "image-builder.image-type.wsl". Features without a flag are available to
+// everyone.
+const (
+	flagDistribution = "image-builder.distribution."
+	flagImageType    = "image-builder.image-type."
+	flagUploadTarget = "image-builder.upload-target."
+)
+
+func (s *Server) featureFlag(idHeader *identity.XRHID, name string) (enabled bool, defined bool) {
+	if s.flags == nil {
+		return false, false
+	}
+	return s.flags.Lookup(name, featureflags.Context{
+		OrgId:  idHeader.Identity.OrgID,
+		UserId: idHeader.Identity.User.Username,
+	})
+}
+
+func (s *Server) featureAvailable(idHeader *identity.XRHID, name string) bool {
+	enabled, defined := s.featureFlag(idHeader, name)
+	return enabled || !defined
+}
+
+// distroAllowed returns whether the org of the caller can build a
+// distribution. Restricted distributions are only available to orgs in the
+// allow file, or which their feature flag is enabled for.
+func (s *Server) distroAllowed(idHeader *identity.XRHID, d *distribution.DistributionFile) (bool, error) {
+	enabled, defined := s.featureFlag(idHeader, flagDistribution+d.Distribution.Name)
+	if !d.IsRestricted() {
+		return enabled || !defined, nil
+	}
+	if enabled {
+		return true, nil
+	}
+	return s.current().allowList.IsAllowed(idHeader.Identity.Internal.OrgID, d.Distribution.Name)
+}
+
+func (s *Server) availableImageTypes(idHeader *identity.XRHID, imageTypes []string) []string {
+	available := []string{}
+	for _, it := range imageTypes {
+		if s.featureAvailable(idHeader, flagImageType+it) {
+			available = append(available, it)
+		}
+	}
+	return available
+}
+
+// checkFeatures rejects image requests using image types or upload targets
+// which aren't available to the org of the caller yet.
+func (s *Server) checkFeatures(ctx echo.Context, idHeader *identity.XRHID, ir ImageRequest) error {
+	if !s.featureAvailable(idHeader, flagImageType+string(ir.ImageType)) {
+		return auditRejection(ctx, reasonNotEntitled, echo.NewHTTPError(http.StatusForbidden,
+			fmt.Sprintf("Image type %s is not available for this organization", ir.ImageType)))
+	}
+	if !s.featureAvailable(idHeader, flagUploadTarget+string(ir.UploadRequest.Type)) {
+		return auditRejection(ctx, reasonNotEntitled, echo.NewHTTPError(http.StatusForbidden,
+			fmt.Sprintf("Upload target %s is not available for this organization", ir.UploadRequest.Type)))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redhatinsights/identity"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/distribution"
+	"github.com/osbuild/image-builder/internal/featureflags"
+)
+
+// fakeFlags enables flags for the listed orgs, flags which aren't in the map
+// are undefined.
+type fakeFlags map[string][]string
+
+func (f fakeFlags) Lookup(name string, c featureflags.Context) (bool, bool) {
+	orgs, ok := f[name]
+	if !ok {
+		return false, false
+	}
+	for _, o := range orgs {
+		if o == c.OrgId {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+func TestFeatureFlags(t *testing.T) {
+	s := &Server{
+		flags: fakeFlags{
+			"image-builder.distribution.rhel-10-nightly":    {"000001"},
+			"image-builder.distribution.centos-10":          {"000001"},
+			"image-builder.image-type.wsl":                  {"000001"},
+			"image-builder.upload-target.oci.objectstorage": {},
+		},
+	}
+	s.settings.Store(&settings{allowList: common.AllowList{"000002": []string{"rhel-10-nightly"}}})
+	org := func(orgId string) *identity.XRHID {
+		return &identity.XRHID{Identity: identity.Identity{OrgID: orgId, Internal: identity.Internal{OrgID: orgId}}}
+	}
+	allowed := func(orgId string, d distribution.DistributionItem) bool {
+		ok, err := s.distroAllowed(org(orgId), &distribution.DistributionFile{Distribution: d})
+		require.NoError(t, err)
+		return ok
+	}
+
+	nightly := distribution.DistributionItem{Name: "rhel-10-nightly", RestrictedAccess: true}
+	require.True(t, allowed("000001", nightly))
+	require.True(t, allowed("000002", nightly))
+	require.False(t, allowed("000000", nightly))
+	centos := distribution.DistributionItem{Name: "centos-10"}
+	require.True(t, allowed("000001", centos))
+	require.False(t, allowed("000002", centos))
+	rhel := distribution.DistributionItem{Name: "rhel-9"}
+	require.True(t, allowed("000000", rhel))
+	fedora := distribution.DistributionItem{Name: "fedora-41", RestrictedAccess: true}
+	require.False(t, allowed("000001", fedora))
+
+	imageTypes := []string{"aws", "guest-image", "wsl"}
+	require.Equal(t, imageTypes, s.availableImageTypes(org("000001"), imageTypes))
+	require.Equal(t, []string{"aws", "guest-image"}, s.availableImageTypes(org("000000"), imageTypes))
+
+	check := func(orgId string, ir ImageRequest) error {
+		ctx := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+		return s.checkFeatures(ctx, org(orgId), ir)
+	}
+	require.NoError(t, check("000001", ImageRequest{ImageType: ImageTypesWsl, UploadRequest: UploadRequest{Type: UploadTypesAwsS3}}))
+	err := check("000000", ImageRequest{ImageType: ImageTypesWsl, UploadRequest: UploadRequest{Type: UploadTypesAwsS3}})
+	require.Equal(t, http.StatusForbidden, err.(*echo.HTTPError).Code)
+	require.Equal(t, "Image type wsl is not available for this organization", err.(*echo.HTTPError).Message)
+	err = check("000001", ImageRequest{ImageType: ImageTypesOci, UploadRequest: UploadRequest{Type: UploadTypesOciObjectstorage}})
+	require.Equal(t, "Upload target oci.objectstorage is not available for this organization", err.(*echo.HTTPError).Message)
+
+	// without flags only the allow file gates restricted distributions
+	s.flags = nil
+	require.False(t, allowed("000001", nightly))
+	require.True(t, allowed("000002", nightly))
+	require.True(t, allowed("000002", centos))
+	require.Equal(t, imageTypes, s.availableImageTypes(org("000000"), imageTypes))
+}
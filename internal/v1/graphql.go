@@ -0,0 +1,494 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/labstack/echo/v4"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+const (
+	maxGraphQLListLimit = 100
+	// bounds of what a single query costs, lists count with their limit
+	maxGraphQLObjects = 1000
+	// statuses which aren't cached are asked composer for
+	maxGraphQLComposerRequests = 20
+)
+
+// graphqlSDL is the read-only schema of the graphql endpoint, api.yaml
+// documents it for clients.
+const graphqlSDL = `
+scalar JSON
+
+type Query {
+	composes(limit: Int = 100, offset: Int = 0, ignoreImageTypes: [String!]): [Compose!]!
+	compose(id: ID!): Compose
+	clone(id: ID!): Clone
+}
+
+type Compose {
+	id: ID!
+	image_name: String
+	created_at: String!
+	request: JSON!
+	status: ComposeStatus
+	clones(limit: Int = 100, offset: Int = 0): [Clone!]!
+}
+
+type ComposeStatus {
+	status: String!
+	upload_status: UploadStatus
+	error: JSON
+}
+
+type Clone {
+	id: ID!
+	compose_id: ID!
+	created_at: String!
+	request: JSON!
+	status: UploadStatus
+}
+
+type UploadStatus {
+	status: String!
+	type: String!
+	options: JSON!
+}
+`
+
+// graphqlSchema resolves the fields one at a time, so the statuses and
+// counters of an execution aren't shared between goroutines. The schema is
+// documented, it isn't introspected.
+var graphqlSchema = graphql.MustParseSchema(graphqlSDL, &graphqlQuery{},
+	graphql.MaxParallelism(1),
+	graphql.DisableIntrospection(),
+)
+
+// graphqlError is an error of a field meant for clients, the messages of
+// other errors are only logged.
+type graphqlError string
+
+func (e graphqlError) Error() string {
+	return string(e)
+}
+
+type graphqlExecutionKey struct{}
+
+// graphqlExecution is the state of a query of the org of the request, it's
+// passed to the resolvers in their context.
+type graphqlExecution struct {
+	h        *Handlers
+	ctx      echo.Context
+	orgId    string
+	statuses map[uuid.UUID]*ImageStatus
+	// objects resolved and requests to composer made so far
+	objects          int
+	composerRequests int
+}
+
+func graphqlExecutionOf(ctx context.Context) *graphqlExecution {
+	return ctx.Value(graphqlExecutionKey{}).(*graphqlExecution)
+}
+
+// count adds n objects to the ones resolved by the query, and fails if that's
+// more than maxGraphQLObjects.
+func (ex *graphqlExecution) count(n int) error {
+	ex.objects += n
+	if ex.objects > maxGraphQLObjects {
+		return graphqlError(fmt.Sprintf("The query resolves more than %d objects", maxGraphQLObjects))
+	}
+	return nil
+}
+
+// composerRequest counts a request to composer, and fails if the query made
+// maxGraphQLComposerRequests already.
+func (ex *graphqlExecution) composerRequest() error {
+	if ex.composerRequests >= maxGraphQLComposerRequests {
+		return graphqlError(fmt.Sprintf("The query needs more than %d statuses which aren't known yet, query them again later", maxGraphQLComposerRequests))
+	}
+	ex.composerRequests++
+	return nil
+}
+
+type graphqlQuery struct{}
+
+func (graphqlQuery) Composes(ctx context.Context, args struct {
+	Limit            int32
+	Offset           int32
+	IgnoreImageTypes *[]string
+}) ([]*graphqlCompose, error) {
+	ex := graphqlExecutionOf(ctx)
+	limit, offset, err := graphqlPage(args.Limit, args.Offset)
+	if err != nil {
+		return nil, err
+	}
+	err = ex.count(limit)
+	if err != nil {
+		return nil, err
+	}
+	var ignoreImageTypes []string
+	if args.IgnoreImageTypes != nil {
+		ignoreImageTypes = *args.IgnoreImageTypes
+	}
+	// composes in the last 14 days, like the collection
+	composes, _, err := ex.h.server.db.GetComposes(ex.orgId, time.Hour*24*14, limit, offset, ignoreImageTypes)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := []*graphqlCompose{}
+	for i := range composes {
+		resolvers = append(resolvers, &graphqlCompose{ex: ex, entry: &composes[i]})
+	}
+	return resolvers, nil
+}
+
+func (graphqlQuery) Compose(ctx context.Context, args struct{ Id graphql.ID }) (*graphqlCompose, error) {
+	ex := graphqlExecutionOf(ctx)
+	id, err := graphqlId(args.Id)
+	if err != nil {
+		return nil, err
+	}
+	err = ex.count(1)
+	if err != nil {
+		return nil, err
+	}
+	composeEntry, err := ex.h.server.db.GetCompose(id, ex.orgId)
+	if errors.Is(err, db.ComposeNotFoundError) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &graphqlCompose{ex: ex, entry: composeEntry}, nil
+}
+
+func (graphqlQuery) Clone(ctx context.Context, args struct{ Id graphql.ID }) (*graphqlClone, error) {
+	ex := graphqlExecutionOf(ctx)
+	id, err := graphqlId(args.Id)
+	if err != nil {
+		return nil, err
+	}
+	err = ex.count(1)
+	if err != nil {
+		return nil, err
+	}
+	cloneEntry, err := ex.h.server.db.GetClone(id, ex.orgId)
+	if errors.Is(err, db.CloneNotFoundError) || (err == nil && cloneEntry == nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &graphqlClone{ex: ex, entry: cloneEntry}, nil
+}
+
+type graphqlCompose struct {
+	ex    *graphqlExecution
+	entry *db.ComposeEntry
+}
+
+func (c *graphqlCompose) Id() graphql.ID {
+	return graphql.ID(c.entry.Id.String())
+}
+
+func (c *graphqlCompose) ImageName() *string {
+	return c.entry.ImageName
+}
+
+func (c *graphqlCompose) CreatedAt() string {
+	return c.entry.CreatedAt.Format(time.RFC3339)
+}
+
+func (c *graphqlCompose) Request() graphqlJSON {
+	return graphqlJSON{value: c.entry.Request}
+}
+
+func (c *graphqlCompose) Status() (*graphqlComposeStatus, error) {
+	status, err := c.ex.composeStatus(c.entry)
+	if err != nil {
+		return nil, err
+	}
+	return &graphqlComposeStatus{status: status}, nil
+}
+
+func (c *graphqlCompose) Clones(args struct {
+	Limit  int32
+	Offset int32
+}) ([]*graphqlClone, error) {
+	limit, offset, err := graphqlPage(args.Limit, args.Offset)
+	if err != nil {
+		return nil, err
+	}
+	err = c.ex.count(limit)
+	if err != nil {
+		return nil, err
+	}
+	clones, _, err := c.ex.h.server.db.GetClonesForCompose(c.entry.Id, c.ex.orgId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := []*graphqlClone{}
+	for i := range clones {
+		clones[i].ComposeId = c.entry.Id
+		clones[i].ComposerBackend = c.entry.ComposerBackend
+		resolvers = append(resolvers, &graphqlClone{ex: c.ex, entry: &clones[i]})
+	}
+	return resolvers, nil
+}
+
+// composeStatus is looked up once per compose, even if the compose is
+// selected more than once. Statuses are served from the cache, even if it's
+// stale, only the ones which were never cached are asked composer for.
+func (ex *graphqlExecution) composeStatus(composeEntry *db.ComposeEntry) (*ImageStatus, error) {
+	if status, ok := ex.statuses[composeEntry.Id]; ok {
+		return status, nil
+	}
+
+	queued, err := ex.h.server.db.GetQueuedCompose(composeEntry.Id)
+	if err == nil {
+		status, err := queuedComposeStatus(composeEntry, queued)
+		if err != nil {
+			return nil, err
+		}
+		ex.statuses[composeEntry.Id] = &status.ImageStatus
+		return &status.ImageStatus, nil
+	} else if !errors.Is(err, db.QueuedComposeNotFoundError) {
+		return nil, err
+	}
+
+	var cloudStat *composer.ComposeStatus
+	cached, err := ex.h.server.db.GetCachedComposeStatus(composeEntry.Id, ex.h.server.composeStatusTTL)
+	if err == nil {
+		var cs composer.ComposeStatus
+		if json.Unmarshal(cached.Status, &cs) == nil {
+			cloudStat = &cs
+		}
+	} else if !errors.Is(err, db.ComposeStatusNotFoundError) {
+		return nil, err
+	}
+	if cloudStat == nil {
+		err = ex.composerRequest()
+		if err != nil {
+			return nil, err
+		}
+		cloudStat, err = ex.h.cachedComposeStatus(ex.ctx, composeEntry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	status, err := parseComposerImageStatus(cloudStat.ImageStatus)
+	if err != nil {
+		return nil, err
+	}
+	ex.statuses[composeEntry.Id] = status
+	return status, nil
+}
+
+type graphqlComposeStatus struct {
+	status *ImageStatus
+}
+
+func (s *graphqlComposeStatus) Status() string {
+	return string(s.status.Status)
+}
+
+func (s *graphqlComposeStatus) UploadStatus() *graphqlUploadStatus {
+	if s.status.UploadStatus == nil {
+		return nil
+	}
+	return &graphqlUploadStatus{us: s.status.UploadStatus}
+}
+
+func (s *graphqlComposeStatus) Error() (*graphqlJSON, error) {
+	if s.status.Error == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(s.status.Error)
+	if err != nil {
+		return nil, err
+	}
+	return &graphqlJSON{value: raw}, nil
+}
+
+type graphqlClone struct {
+	ex    *graphqlExecution
+	entry *db.CloneEntry
+}
+
+func (c *graphqlClone) Id() graphql.ID {
+	return graphql.ID(c.entry.Id.String())
+}
+
+func (c *graphqlClone) ComposeId() graphql.ID {
+	return graphql.ID(c.entry.ComposeId.String())
+}
+
+func (c *graphqlClone) CreatedAt() string {
+	return c.entry.CreatedAt.Format(time.RFC3339)
+}
+
+func (c *graphqlClone) Request() graphqlJSON {
+	return graphqlJSON{value: c.entry.Request}
+}
+
+func (c *graphqlClone) Status() (*graphqlUploadStatus, error) {
+	err := c.ex.composerRequest()
+	if err != nil {
+		return nil, err
+	}
+	cc, err := c.ex.h.server.composerOfBackend(c.entry.Id.String(), c.entry.ComposerBackend)
+	if err != nil {
+		return nil, err
+	}
+	us, err := c.ex.h.getCloneUploadStatus(c.ex.ctx, cc, c.entry.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &graphqlUploadStatus{us: us}, nil
+}
+
+type graphqlUploadStatus struct {
+	us *UploadStatus
+}
+
+func (u *graphqlUploadStatus) Status() string {
+	return string(u.us.Status)
+}
+
+func (u *graphqlUploadStatus) Type() string {
+	return string(u.us.Type)
+}
+
+func (u *graphqlUploadStatus) Options() (graphqlJSON, error) {
+	raw, err := json.Marshal(u.us.Options)
+	if err != nil {
+		return graphqlJSON{}, err
+	}
+	return graphqlJSON{value: raw}, nil
+}
+
+// graphqlJSON is a value of the JSON scalar, any json value.
+type graphqlJSON struct {
+	value json.RawMessage
+}
+
+func (graphqlJSON) ImplementsGraphQLType(name string) bool {
+	return name == "JSON"
+}
+
+func (j *graphqlJSON) UnmarshalGraphQL(input interface{}) error {
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	j.value = raw
+	return nil
+}
+
+func (j graphqlJSON) MarshalJSON() ([]byte, error) {
+	if j.value == nil {
+		return []byte("null"), nil
+	}
+	return j.value, nil
+}
+
+// graphqlPage is the limit and offset of a list, a limit of 0 or more than
+// maxGraphQLListLimit is maxGraphQLListLimit.
+func graphqlPage(limit, offset int32) (int, int, error) {
+	if limit < 0 {
+		return 0, 0, graphqlError(`Argument "limit" can't be negative`)
+	}
+	if offset < 0 {
+		return 0, 0, graphqlError(`Argument "offset" can't be negative`)
+	}
+	if limit == 0 || limit > maxGraphQLListLimit {
+		limit = maxGraphQLListLimit
+	}
+	return int(limit), int(offset), nil
+}
+
+func graphqlId(id graphql.ID) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(string(id))
+	if err != nil {
+		return uuid.UUID{}, graphqlError(`Argument "id" has to be a uuid`)
+	}
+	return parsed, nil
+}
+
+func (h *Handlers) QueryGraphQL(ctx echo.Context, params QueryGraphQLParams) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	operationName := ""
+	if params.OperationName != nil {
+		operationName = *params.OperationName
+	}
+	var variables map[string]interface{}
+	if params.Variables != nil && *params.Variables != "" {
+		err = json.Unmarshal([]byte(*params.Variables), &variables)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, GraphQLResponse{Errors: &[]GraphQLError{{Message: "Variables have to be a json object"}}})
+		}
+	}
+
+	ex := &graphqlExecution{
+		h:        h,
+		ctx:      ctx,
+		orgId:    idHeader.Identity.OrgID,
+		statuses: map[uuid.UUID]*ImageStatus{},
+	}
+	c := context.WithValue(ctx.Request().Context(), graphqlExecutionKey{}, ex)
+	result := graphqlSchema.Exec(c, params.Query, operationName, variables)
+
+	var resp GraphQLResponse
+	if len(result.Errors) > 0 {
+		errs := make([]GraphQLError, 0, len(result.Errors))
+		for _, qe := range result.Errors {
+			errs = append(errs, graphqlQueryError(ctx, qe.Message, qe.Path, qe.ResolverError))
+		}
+		resp.Errors = &errs
+	}
+	// the query didn't parse or validate, nothing was resolved
+	if len(result.Data) == 0 {
+		return ctx.JSON(http.StatusBadRequest, resp)
+	}
+	err = json.Unmarshal(result.Data, &resp.Data)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// graphqlQueryError is the error of a query as clients see it. The messages
+// of http and graphql errors are meant for clients, the ones of other errors
+// of resolvers are only logged.
+func graphqlQueryError(ctx echo.Context, message string, path []interface{}, resolverErr error) GraphQLError {
+	qe := GraphQLError{Message: message}
+	if path != nil {
+		qe.Path = &path
+	}
+	if resolverErr == nil {
+		return qe
+	}
+	var he *echo.HTTPError
+	var ge graphqlError
+	if errors.As(resolverErr, &he) {
+		qe.Message = fmt.Sprintf("%v", he.Message)
+	} else if errors.As(resolverErr, &ge) {
+		qe.Message = ge.Error()
+	} else {
+		ctx.Logger().Errorf("Error resolving graphql field %v: %v", path, resolverErr)
+		qe.Message = "Something went wrong resolving the field"
+	}
+	return qe
+}
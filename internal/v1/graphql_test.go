@@ -0,0 +1,105 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/pkg/tutils"
+)
+
+func TestValidateGraphQL(t *testing.T) {
+	for src, errs := range map[string]int{
+		`{ composes { id status { status upload_status { type } } clones { id } } }`: 0,
+		`{ compose(id: "x") { __typename id } clone(id: "y") { compose_id } }`:       0,
+		`{ blueprints { id } }`:                                    1,
+		`{ composes }`:                                             1,
+		`{ composes { id { name } } }`:                             2,
+		`{ composes(limit: 1, size: 2) { id } }`:                   1,
+		`{ composes { status { progress } clones(region: "a") } }`: 3,
+		`{ composes { clones { status { options } } } }`:           0,
+		`{ a: composes { b: clones { c: status { d: type } } } }`:  0,
+	} {
+		require.Len(t, graphqlSchema.Validate(src), errs, src)
+	}
+}
+
+func TestGraphQLLimits(t *testing.T) {
+	ex := &graphqlExecution{}
+	require.NoError(t, ex.count(maxGraphQLObjects))
+	require.Error(t, ex.count(1))
+
+	for i := 0; i < maxGraphQLComposerRequests; i++ {
+		require.NoError(t, ex.composerRequest())
+	}
+	require.Error(t, ex.composerRequest())
+}
+
+func TestGraphQLPage(t *testing.T) {
+	limit, offset, err := graphqlPage(0, 0)
+	require.NoError(t, err)
+	require.Equal(t, maxGraphQLListLimit, limit)
+	require.Equal(t, 0, offset)
+	limit, offset, err = graphqlPage(500, 10)
+	require.NoError(t, err)
+	require.Equal(t, maxGraphQLListLimit, limit)
+	require.Equal(t, 10, offset)
+	limit, _, err = graphqlPage(10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 10, limit)
+	_, _, err = graphqlPage(0, -1)
+	require.Error(t, err)
+
+	_, err = graphqlId("nope")
+	require.Error(t, err)
+}
+
+func TestGraphQLQueryError(t *testing.T) {
+	ctx := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/graphql", nil), httptest.NewRecorder())
+	path := []interface{}{"composes", 0, "status"}
+	require.Equal(t, "Syntax Error", graphqlQueryError(ctx, "Syntax Error", nil, nil).Message)
+	require.Nil(t, graphqlQueryError(ctx, "Syntax Error", nil, nil).Path)
+	require.Equal(t, "Compose not found", graphqlQueryError(ctx, "", path, echo.NewHTTPError(http.StatusNotFound, "Compose not found")).Message)
+	require.Equal(t, "too many", graphqlQueryError(ctx, "", path, graphqlError("too many")).Message)
+	qe := graphqlQueryError(ctx, "dial tcp: connection refused", path, errors.New("dial tcp: connection refused"))
+	require.Equal(t, "Something went wrong resolving the field", qe.Message)
+	require.Equal(t, path, *qe.Path)
+}
+
+func TestQueryGraphQLInvalid(t *testing.T) {
+	s := &Server{auth: NewIdentityHeaderAuthenticator(ServiceAccountConfig{})}
+	h := Handlers{server: s}
+	e := echo.New()
+	e.HTTPErrorHandler = s.HTTPErrorHandler
+	e.GET("/graphql", func(ctx echo.Context) error {
+		params := QueryGraphQLParams{Query: ctx.QueryParam("query")}
+		if v := ctx.QueryParam("variables"); v != "" {
+			params.Variables = &v
+		}
+		return h.QueryGraphQL(ctx, params)
+	}, s.authenticate)
+
+	for _, q := range []url.Values{
+		{"query": {`{ composes { id `}},
+		{"query": {`{ blueprints { id } }`}},
+		{"query": {`query($limit: Int) { composes(limit: $limit) { id } }`}, "variables": {`[1]`}},
+		{"query": {`{ composes { clones { status { options } } } } query other { composes { id } }`}},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/graphql?"+q.Encode(), nil)
+		req.Header.Set("X-Rh-Identity", tutils.AuthString0)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusBadRequest, rec.Code, q)
+
+		var resp GraphQLResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Nil(t, resp.Data)
+		require.NotEmpty(t, *resp.Errors)
+	}
+}
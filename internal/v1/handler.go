@@ -1,11 +1,15 @@
 package v1
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -17,6 +21,7 @@ import (
 	"github.com/osbuild/image-builder/internal/provisioning"
 
 	"github.com/labstack/echo/v4"
+	"github.com/redhatinsights/identity"
 	"github.com/sirupsen/logrus"
 )
 
@@ -25,33 +30,28 @@ const (
 
 	// 64 GiB
 	FSMaxSize = 68719476736
+
+	AWSPartitionCommercial = "aws"
+	AWSPartitionGovCloud   = "aws-us-gov"
 )
 
-func (h *Handlers) GetVersion(ctx echo.Context) error {
-	version := Version{h.server.spec.Info.Version}
-	return ctx.JSON(http.StatusOK, version)
-}
+// kmsKeyArnRegex matches the ARN of a KMS key or alias
+var kmsKeyArnRegex = regexp.MustCompile(`^arn:(aws|aws-us-gov):kms:([a-z0-9-]+):[0-9]{12}:(key|alias)/[a-zA-Z0-9/_-]+$`)
 
-func (h *Handlers) GetReadiness(ctx echo.Context) error {
-	resp, err := h.server.cClient.OpenAPI()
-	if err != nil {
-		return err
-	}
-	defer closeBody(resp.Body)
+// awsAccountIdRegex matches aws account ids
+var awsAccountIdRegex = regexp.MustCompile(`^[0-9]{12}$`)
 
-	if resp.StatusCode != 200 {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Failed to contact osbuild-composer: %s", body))
-	}
+// gcpEmailRegex and gcpDomainRegex match the principals images can be shared
+// with on GCP
+var gcpEmailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9-]+(\.[a-zA-Z0-9-]+)*\.[a-zA-Z]{2,}$`)
+var gcpDomainRegex = regexp.MustCompile(`^[a-zA-Z0-9-]+(\.[a-zA-Z0-9-]+)*\.[a-zA-Z]{2,}$`)
 
-	ready := map[string]string{
-		"readiness": "ready",
-	}
+// ostreeRefRegex matches valid ostree refs, see ostree_validate_rev in libostree
+var ostreeRefRegex = regexp.MustCompile(`^(?:[\w\d][-._\w\d]*/)*[\w\d][-._\w\d]*$`)
 
-	return ctx.JSON(http.StatusOK, ready)
+func (h *Handlers) GetVersion(ctx echo.Context) error {
+	version := Version{h.server.spec.Info.Version}
+	return ctx.JSON(http.StatusOK, version)
 }
 
 func (h *Handlers) GetOpenapiJson(ctx echo.Context) error {
@@ -67,14 +67,12 @@ func (h *Handlers) GetDistributions(ctx echo.Context) error {
 
 	var distributions DistributionsResponse
 	for k, d := range dr.Map() {
-		if d.IsRestricted() {
-			allowOk, err := h.server.allowList.IsAllowed(idHeader.Identity.Internal.OrgID, d.Distribution.Name)
-			if err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
-			}
-			if !allowOk {
-				continue
-			}
+		allowOk, err := h.server.distroAllowed(idHeader, d)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if !allowOk {
+			continue
 		}
 		distributions = append(distributions, DistributionItem{
 			Description: d.Distribution.Description,
@@ -82,10 +80,19 @@ func (h *Handlers) GetDistributions(ctx echo.Context) error {
 		})
 	}
 
-	return ctx.JSON(http.StatusOK, distributions)
+	// the order of the registry isn't stable, the ETag has to be
+	sort.Slice(distributions, func(i, j int) bool {
+		return distributions[i].Name < distributions[j].Name
+	})
+	return jsonWithETag(ctx, http.StatusOK, distributions)
 }
 
 func (h *Handlers) GetArchitectures(ctx echo.Context, distro Distributions) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
 	d, err := h.server.getDistro(ctx, distro)
 	if err != nil {
 		return err
@@ -123,14 +130,14 @@ func (h *Handlers) GetArchitectures(ctx echo.Context, distro Distributions) erro
 	if d.ArchX86 != nil {
 		archs = append(archs, ArchitectureItem{
 			Arch:         "x86_64",
-			ImageTypes:   d.ArchX86.ImageTypes,
+			ImageTypes:   h.server.supportedImageTypes(h.server.availableImageTypes(idHeader, d.ArchX86.ImageTypes)),
 			Repositories: reposArchX86,
 		})
 	}
 	if d.Aarch64 != nil {
 		archs = append(archs, ArchitectureItem{
 			Arch:         "aarch64",
-			ImageTypes:   d.Aarch64.ImageTypes,
+			ImageTypes:   h.server.supportedImageTypes(h.server.availableImageTypes(idHeader, d.Aarch64.ImageTypes)),
 			Repositories: reposAarch64,
 		})
 	}
@@ -204,65 +211,99 @@ func (h *Handlers) GetPackages(ctx echo.Context, params GetPackagesParams) error
 	})
 }
 
-func (h *Handlers) GetComposeStatus(ctx echo.Context, composeId uuid.UUID) error {
+func (h *Handlers) GetComposeStatus(ctx echo.Context, composeId uuid.UUID, params GetComposeStatusParams) error {
 	composeEntry, err := h.getComposeByIdAndOrgId(ctx, composeId)
 	if err != nil {
 		return err
 	}
 
-	resp, err := h.server.cClient.ComposeStatus(composeId)
+	var wait time.Duration
+	if params.Wait != nil {
+		wait, err = parseStatusWait(ctx, *params.Wait)
+		if err != nil {
+			return err
+		}
+	}
+
+	status, err := h.composeStatus(ctx, composeEntry)
 	if err != nil {
 		return err
 	}
-	defer closeBody(resp.Body)
-
-	if resp.StatusCode == http.StatusNotFound {
-		body, err := io.ReadAll(resp.Body)
+	if wait > 0 {
+		status, err = h.waitForComposeStatus(ctx, composeEntry, status, wait)
 		if err != nil {
 			return err
 		}
-		// Composes can get deleted in composer, usually when the image is expired
-		return echo.NewHTTPError(http.StatusNotFound, string(body))
-	} else if resp.StatusCode != http.StatusOK {
-		httpError := echo.NewHTTPError(http.StatusInternalServerError, "Failed querying compose status")
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			ctx.Logger().Errorf("Unable to parse composer's compose response: %v", err)
-		} else {
-			_ = httpError.SetInternal(fmt.Errorf("%s", body))
-		}
-		return httpError
 	}
+	return jsonWithETag(ctx, http.StatusOK, status)
+}
 
-	var composeRequest ComposeRequest
-	err = json.Unmarshal(composeEntry.Request, &composeRequest)
+// composeStatus returns the status of a compose, from the queue if it's
+// still queued, from composer otherwise.
+func (h *Handlers) composeStatus(ctx echo.Context, composeEntry *db.ComposeEntry) (*ComposeStatus, error) {
+	queued, err := h.server.db.GetQueuedCompose(composeEntry.Id)
+	if err == nil {
+		return queuedComposeStatus(composeEntry, queued)
+	} else if !errors.Is(err, db.QueuedComposeNotFoundError) {
+		ctx.Logger().Errorf("Error querying the queue for compose %v: %v", composeEntry.Id, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the compose")
+	}
+
+	cloudStat, err := h.cachedComposeStatus(ctx, composeEntry)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var cloudStat composer.ComposeStatus
-	err = json.NewDecoder(resp.Body).Decode(&cloudStat)
+	var composeRequest ComposeRequest
+	err = json.Unmarshal(composeEntry.Request, &composeRequest)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	us, err := parseComposerUploadStatus(cloudStat.ImageStatus.UploadStatus)
+	imageStatus, err := parseComposerImageStatus(cloudStat.ImageStatus)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	us := imageStatus.UploadStatus
 	status := ComposeStatus{
-		ImageStatus: ImageStatus{
-			Status:       ImageStatusStatus(cloudStat.ImageStatus.Status),
-			UploadStatus: us,
-		},
-		Request: composeRequest,
+		ImageStatus: *imageStatus,
+		Request:     composeRequest,
 	}
 
-	if cloudStat.ImageStatus.Error != nil {
-		status.ImageStatus.Error = parseComposeStatusError(cloudStat.ImageStatus.Error)
+	if cloudStat.ImageStatus.Status == composer.ImageStatusValueSuccess {
+		cloneStatuses, err := h.replicationStatuses(ctx, composeEntry)
+		if err != nil {
+			return nil, err
+		}
+		status.CloneStatuses = cloneStatuses
+
+		err = h.containerSignatureStatus(ctx, composeEntry, composeRequest, us)
+		if err != nil {
+			return nil, err
+		}
+
+		err = h.encryptionStatus(ctx, composeEntry, &status.ImageStatus)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return ctx.JSON(http.StatusOK, status)
+	return &status, nil
+}
+
+func parseComposerImageStatus(is composer.ImageStatus) (*ImageStatus, error) {
+	us, err := parseComposerUploadStatus(is.UploadStatus)
+	if err != nil {
+		return nil, err
+	}
+	status := ImageStatus{
+		Status:       ImageStatusStatus(is.Status),
+		UploadStatus: us,
+	}
+	if is.Error != nil {
+		status.Error = parseComposeStatusError(is.Error)
+	}
+	return &status, nil
 }
 
 func parseComposerUploadStatus(us *composer.UploadStatus) (*UploadStatus, error) {
@@ -318,6 +359,29 @@ func parseComposerUploadStatus(us *composer.UploadStatus) (*UploadStatus, error)
 		if err != nil {
 			return nil, err
 		}
+	case composer.UploadTypesContainer:
+		co, err := us.Options.AsContainerUploadStatus()
+		if err != nil {
+			return nil, err
+		}
+		err = options.FromContainerUploadStatus(ContainerUploadStatus{
+			Url:    co.Url,
+			Digest: co.Digest,
+		})
+		if err != nil {
+			return nil, err
+		}
+	case composer.UploadTypesPulpOstree:
+		co, err := us.Options.AsPulpOSTreeUploadStatus()
+		if err != nil {
+			return nil, err
+		}
+		err = options.FromPulpOSTreeUploadStatus(PulpOSTreeUploadStatus{
+			RepoUrl: co.RepoUrl,
+		})
+		if err != nil {
+			return nil, err
+		}
 	case composer.UploadTypesOciObjectstorage:
 		co, err := us.Options.AsOCIUploadStatus()
 		if err != nil {
@@ -402,37 +466,74 @@ func (h *Handlers) DeleteCompose(ctx echo.Context, composeId uuid.UUID) error {
 	return ctx.NoContent(http.StatusOK)
 }
 
-func (h *Handlers) GetComposeMetadata(ctx echo.Context, composeId uuid.UUID) error {
-	err := h.canUserAccessComposeId(ctx, composeId)
+func (h *Handlers) GetComposeArtifacts(ctx echo.Context, composeId uuid.UUID) error {
+	_, err := h.getComposeByIdAndOrgId(ctx, composeId)
 	if err != nil {
 		return err
 	}
 
-	resp, err := h.server.cClient.ComposeMetadata(composeId)
+	idHeader, err := getIdentityHeader(ctx)
 	if err != nil {
 		return err
 	}
-	defer closeBody(resp.Body)
 
-	if resp.StatusCode == http.StatusNotFound {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return err
+	// the artifacts are recorded from the outbox once the compose
+	// succeeded, so they remain available after composer expired it
+	artifacts, err := h.server.db.GetComposeArtifacts(composeId, idHeader.Identity.OrgID)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying artifacts for compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying artifacts for this compose")
+	}
+
+	data := []ComposeArtifact{}
+	for _, a := range artifacts {
+		data = append(data, ComposeArtifact{
+			Filename:     a.Filename,
+			Size:         a.Size,
+			Sha256:       a.Sha256,
+			CloudImageId: a.CloudImageId,
+			Signature:    a.Signature,
+		})
+	}
+	return ctx.JSON(http.StatusOK, ComposeArtifactsResponse{
+		Data: data,
+	})
+}
+
+// uploadStatusCloudImageId returns the identifier of the image in the cloud a
+// compose was uploaded to, or nil if the upload target has none.
+func uploadStatusCloudImageId(us *composer.UploadStatus) *string {
+	if us == nil {
+		return nil
+	}
+
+	switch us.Type {
+	case composer.UploadTypesAws:
+		co, err := us.Options.AsAWSEC2UploadStatus()
+		if err == nil {
+			return &co.Ami
 		}
-		return echo.NewHTTPError(http.StatusNotFound, string(body))
-	} else if resp.StatusCode != http.StatusOK {
-		httpError := echo.NewHTTPError(http.StatusInternalServerError, "Failed querying compose status")
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			ctx.Logger().Errorf("Unable to parse composer's compose response: %v", err)
-		} else {
-			_ = httpError.SetInternal(fmt.Errorf("%s", body))
+	case composer.UploadTypesAzure:
+		co, err := us.Options.AsAzureUploadStatus()
+		if err == nil {
+			return &co.ImageName
+		}
+	case composer.UploadTypesGcp:
+		co, err := us.Options.AsGCPUploadStatus()
+		if err == nil {
+			return &co.ImageName
 		}
-		return httpError
 	}
+	return nil
+}
 
-	var cloudStat composer.ComposeMetadata
-	err = json.NewDecoder(resp.Body).Decode(&cloudStat)
+func (h *Handlers) GetComposeMetadata(ctx echo.Context, composeId uuid.UUID) error {
+	composeEntry, err := h.getComposeByIdAndOrgId(ctx, composeId)
+	if err != nil {
+		return err
+	}
+
+	cloudStat, err := h.composeMetadata(ctx, composeEntry)
 	if err != nil {
 		return err
 	}
@@ -461,6 +562,44 @@ func (h *Handlers) GetComposeMetadata(ctx echo.Context, composeId uuid.UUID) err
 	return ctx.JSON(http.StatusOK, status)
 }
 
+// composeMetadata asks composer for the metadata of a compose, like the
+// packages which went into the image.
+func (h *Handlers) composeMetadata(ctx echo.Context, composeEntry *db.ComposeEntry) (*composer.ComposeMetadata, error) {
+	cc, err := h.server.composerOf(composeEntry)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cc.ComposeMetadata(ctx.Request().Context(), composeEntry.ComposerId)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp.Body)
+
+	if resp.StatusCode == http.StatusNotFound {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, echo.NewHTTPError(http.StatusNotFound, string(body))
+	} else if resp.StatusCode != http.StatusOK {
+		httpError := echo.NewHTTPError(http.StatusInternalServerError, "Failed querying compose status")
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			ctx.Logger().Errorf("Unable to parse composer's compose response: %v", err)
+		} else {
+			_ = httpError.SetInternal(fmt.Errorf("%s", body))
+		}
+		return nil, httpError
+	}
+
+	var cloudStat composer.ComposeMetadata
+	err = json.NewDecoder(resp.Body).Decode(&cloudStat)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudStat, nil
+}
+
 // return compose from the database or error when user does not have composeId associated to its OrgId in the DB
 func (h *Handlers) getComposeByIdAndOrgId(ctx echo.Context, composeId uuid.UUID) (*db.ComposeEntry, error) {
 	idHeader, err := getIdentityHeader(ctx)
@@ -548,7 +687,7 @@ func (h *Handlers) GetComposes(ctx echo.Context, params GetComposesParams) error
 		lastOffset = 0
 	}
 
-	return ctx.JSON(http.StatusOK, ComposesResponse{
+	return jsonWithETag(ctx, http.StatusOK, ComposesResponse{
 		Meta: struct {
 			Count int `json:"count"`
 		}{
@@ -573,13 +712,11 @@ func (h *Handlers) ComposeImage(ctx echo.Context) error {
 		return err
 	}
 
-	quotaOk, err := common.CheckQuota(idHeader.Identity.OrgID, h.server.db, h.server.quotaFile)
+	queue, release, err := h.server.checkComposeQuota(ctx, idHeader.Identity.OrgID, 1)
 	if err != nil {
 		return err
 	}
-	if !quotaOk {
-		return echo.NewHTTPError(http.StatusForbidden, "Quota exceeded for user")
-	}
+	defer release()
 
 	var composeRequest ComposeRequest
 	err = ctx.Bind(&composeRequest)
@@ -587,20 +724,106 @@ func (h *Handlers) ComposeImage(ctx echo.Context) error {
 		return err
 	}
 
+	pc, err := h.prepareCompose(ctx, idHeader, composeRequest)
+	if err != nil {
+		return err
+	}
+	composeId, err := h.submitCompose(ctx, idHeader, pc, queue)
+	if err != nil {
+		return err
+	}
+	setAuditResource(ctx, composeId)
+
+	resp := ComposeResponse{
+		Id: composeId,
+	}
+	if len(pc.warnings) > 0 {
+		resp.Warnings = &pc.warnings
+	}
+	return ctx.JSON(http.StatusCreated, resp)
+}
+
+// preparedCompose is a compose request which passed all checks, along with
+// the request to composer it's submitted as.
+type preparedCompose struct {
+	request     ComposeRequest
+	cloudCR     composer.ComposeRequest
+	backend     string
+	cc          *composer.ComposerClient
+	approval    bool
+	webhook     *WebhookRequest
+	notifyEmail *string
+	encryption  *db.ComposeEncryptionEntry
+	warnings    []string
+	// the key of the request and its hash if it's idempotent, set by the
+	// handlers which support them
+	idempotencyKey *db.IdempotencyKeyEntry
+}
+
+// prepareCompose checks a compose request and builds the request to composer,
+// nothing is submitted or stored yet.
+func (h *Handlers) prepareCompose(ctx echo.Context, idHeader *identity.XRHID, composeRequest ComposeRequest) (*preparedCompose, error) {
+	var err error
+	// the secret of the webhook isn't stored along with the request
+	webhook := composeRequest.Webhook
+	composeRequest.Webhook = nil
+	if webhook != nil {
+		err = h.server.validateWebhook(*webhook)
+		if err != nil {
+			return nil, err
+		}
+	}
+	notifyEmail := composeRequest.NotifyEmail
+	composeRequest.NotifyEmail = nil
+	if notifyEmail != nil {
+		notifyEmail, err = h.server.validateNotifyEmail(*notifyEmail)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	approval, err := h.server.applyComposePolicy(ctx, idHeader, &composeRequest)
+	if err != nil {
+		return nil, err
+	}
+	warnings, err := h.scanComposeSecrets(ctx, idHeader.Identity.OrgID, composeRequest)
+	if err != nil {
+		return nil, err
+	}
+	if !approval {
+		approval, err = h.server.db.GetApprovalRequired(idHeader.Identity.OrgID)
+		if err != nil {
+			ctx.Logger().Errorf("Error querying approval settings: %v", err)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the approval settings")
+		}
+	}
+
 	if string(composeRequest.ImageRequests[0].UploadRequest.Type) == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "Exactly one upload request should be included")
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Exactly one upload request should be included")
+	}
+
+	ur := composeRequest.ImageRequests[0].UploadRequest
+	err = h.checkUploadTarget(ctx, idHeader.Identity.OrgID, ur.Type, h.uploadTargetRegions(ur))
+	if err != nil {
+		return nil, err
 	}
 
 	d, err := h.server.getDistro(ctx, composeRequest.Distribution)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var repositories []composer.Repository
 	arch, err := d.Architecture(string(composeRequest.ImageRequests[0].Architecture))
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	err = h.server.checkFeatures(ctx, idHeader, composeRequest.ImageRequests[0])
+	if err != nil {
+		return nil, err
 	}
+
 	for _, r := range arch.Repositories {
 		// If no image type tags are defined for the repo, add the repo
 		contains := len(r.ImageTypeTags) == 0
@@ -621,12 +844,26 @@ func (h *Handlers) ComposeImage(ctx echo.Context) error {
 
 	uploadOptions, imageType, err := h.buildUploadOptions(ctx, composeRequest.ImageRequests[0].UploadRequest, composeRequest.ImageRequests[0].ImageType)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	encryption, err := h.awsEncryption(ctx, composeRequest.ImageRequests[0].UploadRequest, &uploadOptions)
+	if err != nil {
+		return nil, err
 	}
 
 	err = validateComposeRequest(&composeRequest)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	err = h.checkCommitSigningKey(ctx, idHeader.Identity.OrgID, composeRequest.ImageRequests[0].Ostree)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.checkRepositorySignatures(ctx, idHeader.Identity.OrgID, composeRequest.Customizations)
+	if err != nil {
+		return nil, err
 	}
 
 	distro := d.Distribution.Name
@@ -647,9 +884,41 @@ func (h *Handlers) ComposeImage(ctx echo.Context) error {
 		},
 	}
 
-	resp, err := h.server.cClient.Compose(cloudCR)
+	// queued composes are routed again once they're submitted
+	backend, cc := h.server.routeCompose(cloudCR)
+	err = checkCapabilities(cc, composeRequest.ImageRequests[0].UploadRequest.Type, cloudCR)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return &preparedCompose{
+		request:     composeRequest,
+		cloudCR:     cloudCR,
+		backend:     backend,
+		cc:          cc,
+		approval:    approval,
+		webhook:     webhook,
+		notifyEmail: notifyEmail,
+		encryption:  encryption,
+		warnings:    warnings,
+	}, nil
+}
+
+// submitCompose submits a prepared compose to composer, or queues it, and
+// stores it. It returns the id of the compose.
+func (h *Handlers) submitCompose(ctx echo.Context, idHeader *identity.XRHID, pc *preparedCompose, queue bool) (uuid.UUID, error) {
+	composeRequest := pc.request
+	relations, err := h.composeRelations(idHeader.Identity.OrgID, pc)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if queue || pc.approval {
+		return h.queueCompose(ctx, pc, relations)
+	}
+
+	resp, err := pc.cc.Compose(ctx.Request().Context(), pc.cloudCR)
+	if err != nil {
+		return uuid.Nil, err
 	}
 	defer closeBody(resp.Body)
 	if resp.StatusCode != http.StatusCreated {
@@ -661,7 +930,7 @@ func (h *Handlers) ComposeImage(ctx echo.Context) error {
 			_ = httpError.SetInternal(fmt.Errorf("%s", body))
 			var serviceStat composer.Error
 			if err := json.Unmarshal(body, &serviceStat); err != nil {
-				return httpError
+				return uuid.Nil, httpError
 			}
 			if serviceStat.Id == "10" {
 				httpError.Message = "Error resolving OSTree repo"
@@ -678,38 +947,73 @@ func (h *Handlers) ComposeImage(ctx echo.Context) error {
 				httpError.Code = http.StatusBadRequest
 			}
 		}
-		return httpError
+		return uuid.Nil, httpError
 	}
 
 	var composeResult composer.ComposeId
 	err = json.NewDecoder(resp.Body).Decode(&composeResult)
 	if err != nil {
-		return err
+		return uuid.Nil, err
 	}
 
 	rawCR, err := json.Marshal(composeRequest)
 	if err != nil {
-		return err
+		return uuid.Nil, err
 	}
 
-	err = h.server.db.InsertCompose(composeResult.Id, idHeader.Identity.AccountNumber, idHeader.Identity.User.Email, idHeader.Identity.Internal.OrgID, composeRequest.ImageName, rawCR)
-	if err != nil {
+	err = h.server.db.InsertComposeWithRelations(composeResult.Id, idHeader.Identity.AccountNumber, idHeader.Identity.User.Email, idHeader.Identity.Internal.OrgID, composeRequest.ImageName, rawCR, relations, h.server.composeCreatedOutbox(composeResult.Id, idHeader.Identity.OrgID, composeRequest)...)
+	if errors.Is(err, db.IdempotencyKeyExistsError) {
+		// a concurrent request with the same key was stored first, the
+		// compose composer got from this one isn't followed
+		logrus.Warnf("Compose %v of org %s was requested again with the same idempotency key", composeResult.Id, idHeader.Identity.OrgID)
+		return uuid.Nil, err
+	} else if err != nil {
 		logrus.Error("Error inserting id into db", err)
-		return err
+		return uuid.Nil, err
+	}
+	if pc.backend != "" {
+		err = h.server.db.SetComposeBackend(composeResult.Id, pc.backend)
+		if err != nil {
+			logrus.Error("Error storing the composer backend of the compose", err)
+			return uuid.Nil, err
+		}
 	}
+	composeCreated(composeRequest)
+	h.server.recordComposeEvent(composeResult.Id, composeEventCreated, nil)
 
 	ctx.Logger().Info("Compose result", composeResult)
-
-	return ctx.JSON(http.StatusCreated, ComposeResponse{
-		Id: composeResult.Id,
-	})
+	return composeResult.Id, nil
 }
 
-func (h *Handlers) buildUploadOptions(ctx echo.Context, ur UploadRequest, it ImageTypes) (composer.UploadOptions, composer.ImageTypes, error) {
-	var uploadOptions composer.UploadOptions
-	switch ur.Type {
-	case UploadTypesAws:
-		var composerImageType composer.ImageTypes
+// composeRelations returns the relations a prepared compose is stored with,
+// before anything is submitted.
+func (h *Handlers) composeRelations(orgId string, pc *preparedCompose) (*db.ComposeRelations, error) {
+	relations := db.ComposeRelations{
+		Encryption:     pc.encryption,
+		NotifyEmail:    pc.notifyEmail,
+		IdempotencyKey: pc.idempotencyKey,
+	}
+	var err error
+	relations.ReplicationRegions, relations.ShareWithAccounts, err = composeReplications(pc.request, pc.cloudCR)
+	if err != nil {
+		logrus.Errorf("Error resolving the regions of the compose: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong storing the compose")
+	}
+	if pc.webhook != nil {
+		// the webhook is stored with the id of the compose
+		relations.Webhook, err = h.server.newWebhook(orgId, nil, *pc.webhook)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &relations, nil
+}
+
+func (h *Handlers) buildUploadOptions(ctx echo.Context, ur UploadRequest, it ImageTypes) (composer.UploadOptions, composer.ImageTypes, error) {
+	var uploadOptions composer.UploadOptions
+	switch ur.Type {
+	case UploadTypesAws:
+		var composerImageType composer.ImageTypes
 		switch it {
 		case ImageTypesAws:
 			fallthrough
@@ -755,8 +1059,26 @@ func (h *Handlers) buildUploadOptions(ctx echo.Context, ur UploadRequest, it Ima
 				shareWithAccounts = append(shareWithAccounts, *uploadInfo.Aws.AccountId)
 			}
 		}
+
+		shareWithAccounts, err = h.validateAWSShareWithAccounts(ctx, shareWithAccounts)
+		if err != nil {
+			return uploadOptions, "", err
+		}
+
+		region, err := h.awsRegion(uo.Partition)
+		if err != nil {
+			return uploadOptions, "", err
+		}
+		if uo.Regions != nil {
+			for _, r := range *uo.Regions {
+				if awsPartitionOfRegion(r) != awsPartitionOfRegion(region) {
+					return uploadOptions, "", echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Region %s is not part of the %s partition", r, awsPartitionOfRegion(region)))
+				}
+			}
+		}
+
 		err = uploadOptions.FromAWSEC2UploadOptions(composer.AWSEC2UploadOptions{
-			Region:            h.server.aws.Region,
+			Region:            region,
 			ShareWithAccounts: shareWithAccounts,
 		})
 		if err != nil {
@@ -764,30 +1086,11 @@ func (h *Handlers) buildUploadOptions(ctx echo.Context, ur UploadRequest, it Ima
 		}
 		return uploadOptions, composerImageType, nil
 	case UploadTypesAwsS3:
-		var composerImageType composer.ImageTypes
-		switch it {
-		case ImageTypesEdgeCommit:
-			fallthrough
-		case ImageTypesRhelEdgeCommit:
-			composerImageType = composer.ImageTypesEdgeCommit
-		case ImageTypesEdgeInstaller:
-			fallthrough
-		case ImageTypesRhelEdgeInstaller:
-			composerImageType = composer.ImageTypesEdgeInstaller
-		case ImageTypesGuestImage:
-			composerImageType = composer.ImageTypesGuestImage
-		case ImageTypesImageInstaller:
-			composerImageType = composer.ImageTypesImageInstaller
-		case ImageTypesVsphere:
-			composerImageType = composer.ImageTypesVsphere
-		case ImageTypesVsphereOva:
-			composerImageType = composer.ImageTypesVsphereOva
-		case ImageTypesWsl:
-			composerImageType = composer.ImageTypesWsl
-		default:
-			return uploadOptions, "", echo.NewHTTPError(http.StatusBadRequest, "Invalid image type for upload target")
+		composerImageType, err := downloadImageType(it)
+		if err != nil {
+			return uploadOptions, "", err
 		}
-		err := uploadOptions.FromAWSS3UploadOptions(composer.AWSS3UploadOptions{
+		err = uploadOptions.FromAWSS3UploadOptions(composer.AWSS3UploadOptions{
 			Region: h.server.aws.Region,
 		})
 		if err != nil {
@@ -806,6 +1109,12 @@ func (h *Handlers) buildUploadOptions(ctx echo.Context, ur UploadRequest, it Ima
 		if err != nil {
 			return uploadOptions, "", echo.NewHTTPError(http.StatusBadRequest, "Unable to parse upload request options as GCP options")
 		}
+		if uo.ShareWithAccounts != nil {
+			err = validateGCPShareWithAccounts(*uo.ShareWithAccounts)
+			if err != nil {
+				return uploadOptions, "", err
+			}
+		}
 		err = uploadOptions.FromGCPUploadOptions(composer.GCPUploadOptions{
 			Bucket:            &h.server.gcp.Bucket,
 			Region:            h.server.gcp.Region,
@@ -878,6 +1187,59 @@ func (h *Handlers) buildUploadOptions(ctx echo.Context, ur UploadRequest, it Ima
 			return uploadOptions, "", err
 		}
 		return uploadOptions, composerImageType, nil
+	case UploadTypesPulpOstree:
+		var composerImageType composer.ImageTypes
+		switch it {
+		case ImageTypesEdgeCommit:
+			fallthrough
+		case ImageTypesRhelEdgeCommit:
+			composerImageType = composer.ImageTypesEdgeCommit
+		default:
+			return uploadOptions, "", echo.NewHTTPError(http.StatusBadRequest, "Invalid image type for upload target")
+		}
+		uo, err := ur.Options.AsPulpOSTreeUploadRequestOptions()
+		if err != nil {
+			return uploadOptions, "", echo.NewHTTPError(http.StatusBadRequest, "Unable to parse upload request options as Pulp options")
+		}
+		pulpOptions, err := h.pulpUploadOptions(ctx, uo)
+		if err != nil {
+			return uploadOptions, "", err
+		}
+		err = uploadOptions.FromPulpOSTreeUploadOptions(*pulpOptions)
+		if err != nil {
+			return uploadOptions, "", err
+		}
+		return uploadOptions, composerImageType, nil
+	case UploadTypesContainer:
+		var composerImageType composer.ImageTypes
+		switch it {
+		case ImageTypesEdgeContainer:
+			composerImageType = composer.ImageTypesEdgeContainer
+		default:
+			return uploadOptions, "", echo.NewHTTPError(http.StatusBadRequest, "Invalid image type for upload target")
+		}
+		uo, err := ur.Options.AsContainerUploadRequestOptions()
+		if err != nil {
+			return uploadOptions, "", echo.NewHTTPError(http.StatusBadRequest, "Unable to parse upload request options as container options")
+		}
+		err = h.server.checkSign(uo.Sign)
+		if err != nil {
+			return uploadOptions, "", err
+		}
+		if uo.Sign != nil {
+			err = validateRegistryHost(uo.Name)
+			if err != nil {
+				return uploadOptions, "", echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+		}
+		err = uploadOptions.FromContainerUploadOptions(composer.ContainerUploadOptions{
+			Name: &uo.Name,
+			Tag:  uo.Tag,
+		})
+		if err != nil {
+			return uploadOptions, "", err
+		}
+		return uploadOptions, composerImageType, nil
 	case UploadTypesOciObjectstorage:
 		if it != ImageTypesOci {
 			return uploadOptions, "", echo.NewHTTPError(http.StatusBadRequest, "Invalid image type for upload target")
@@ -892,6 +1254,153 @@ func (h *Handlers) buildUploadOptions(ctx echo.Context, ur UploadRequest, it Ima
 	}
 }
 
+// validateKMSKeyArn makes sure the key is a KMS key ARN which lives in the
+// region the image gets uploaded to, snapshots can only be encrypted with keys
+// from the same region.
+func validateKMSKeyArn(arn, region string) error {
+	m := kmsKeyArnRegex.FindStringSubmatch(arn)
+	if m == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid KMS key ARN %s", arn))
+	}
+	if m[1] != awsPartitionOfRegion(m[2]) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid KMS key ARN %s", arn))
+	}
+	if region != "" && m[2] != region {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("KMS key has to be in region %s", region))
+	}
+	return nil
+}
+
+// validateAWSShareWithAccounts checks the accounts an image is shared with
+// against the allow list of the organization.
+func (h *Handlers) validateAWSShareWithAccounts(ctx echo.Context, accounts []string) ([]string, error) {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	allowList, err := h.server.db.GetAWSShareAllowList(idHeader.Identity.OrgID)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying the aws share allow list: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the aws share allow list")
+	}
+	return checkAWSShareWithAccounts(accounts, allowList)
+}
+
+// checkAWSShareWithAccounts removes duplicate accounts, and reports every
+// account which isn't a valid account id or isn't on the allow list. An empty
+// allow list allows all accounts.
+func checkAWSShareWithAccounts(accounts, allowList []string) ([]string, error) {
+	allowed := map[string]bool{}
+	for _, a := range allowList {
+		allowed[a] = true
+	}
+
+	var invalid []string
+	var res []string
+	seen := map[string]bool{}
+	for _, account := range accounts {
+		if seen[account] {
+			continue
+		}
+		seen[account] = true
+
+		if !awsAccountIdRegex.MatchString(account) {
+			invalid = append(invalid, fmt.Sprintf("%s: not a 12 digit account id", account))
+			continue
+		}
+		if len(allowed) > 0 && !allowed[account] {
+			invalid = append(invalid, fmt.Sprintf("%s: not allowed by the organization", account))
+			continue
+		}
+		res = append(res, account)
+	}
+	if len(invalid) > 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid AWS share_with_accounts: %s", strings.Join(invalid, ", ")))
+	}
+	return res, nil
+}
+
+// validateGCPShareWithAccounts checks the syntax of all the principals the
+// image is shared with, and reports every invalid one. This catches typos
+// before the build, instead of when sharing the finished image fails.
+func validateGCPShareWithAccounts(accounts []string) error {
+	var invalid []string
+	for _, account := range accounts {
+		kind, id, found := strings.Cut(account, ":")
+		if !found {
+			invalid = append(invalid, fmt.Sprintf("%s: missing account type", account))
+			continue
+		}
+		switch kind {
+		case "user", "serviceAccount", "group":
+			if !gcpEmailRegex.MatchString(id) {
+				invalid = append(invalid, fmt.Sprintf("%s: invalid email address", account))
+			}
+		case "domain":
+			if !gcpDomainRegex.MatchString(id) {
+				invalid = append(invalid, fmt.Sprintf("%s: invalid domain", account))
+			}
+		default:
+			invalid = append(invalid, fmt.Sprintf("%s: unknown account type %s", account, kind))
+		}
+	}
+	if len(invalid) > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid GCP share_with_accounts: %s", strings.Join(invalid, ", ")))
+	}
+	return nil
+}
+
+// awsPartitionOfRegion returns the partition an aws region belongs to
+func awsPartitionOfRegion(region string) string {
+	if strings.HasPrefix(region, "us-gov-") {
+		return AWSPartitionGovCloud
+	}
+	return AWSPartitionCommercial
+}
+
+// awsRegion returns the configured region for the requested partition
+func (h *Handlers) awsRegion(partition *string) (string, error) {
+	if partition == nil || *partition == AWSPartitionCommercial {
+		return h.server.aws.Region, nil
+	}
+	if h.server.aws.GovRegion == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Uploading images to AWS GovCloud is not enabled")
+	}
+	if _, _, ok := h.server.composers.RegionBackend(h.server.aws.GovRegion); !ok {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Uploading images to AWS GovCloud is not enabled")
+	}
+	return h.server.aws.GovRegion, nil
+}
+
+// downloadImageType maps the image types which are delivered as a download
+// from an S3 bucket to their composer image type.
+func downloadImageType(it ImageTypes) (composer.ImageTypes, error) {
+	switch it {
+	case ImageTypesEdgeCommit:
+		fallthrough
+	case ImageTypesRhelEdgeCommit:
+		return composer.ImageTypesEdgeCommit, nil
+	case ImageTypesEdgeInstaller:
+		fallthrough
+	case ImageTypesRhelEdgeInstaller:
+		return composer.ImageTypesEdgeInstaller, nil
+	case ImageTypesGuestImage:
+		return composer.ImageTypesGuestImage, nil
+	case ImageTypesImageInstaller:
+		return composer.ImageTypesImageInstaller, nil
+	case ImageTypesLiveInstaller:
+		return composer.ImageTypesLiveInstaller, nil
+	case ImageTypesVsphere:
+		return composer.ImageTypesVsphere, nil
+	case ImageTypesVsphereOva:
+		return composer.ImageTypesVsphereOva, nil
+	case ImageTypesWsl:
+		return composer.ImageTypesWsl, nil
+	default:
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Invalid image type for upload target")
+	}
+}
+
 func buildOSTreeOptions(ostreeOptions *OSTree) *composer.OSTree {
 	if ostreeOptions == nil {
 		return nil
@@ -908,10 +1417,70 @@ func buildOSTreeOptions(ostreeOptions *OSTree) *composer.OSTree {
 	return cloudOptions
 }
 
+// validateOSTree makes sure the ostree options are consistent with each other
+// before they are passed on to composer.
+func validateOSTree(ostree *OSTree) error {
+	if ostree == nil {
+		return nil
+	}
+
+	if ostree.Ref != nil && !ostreeRefRegex.MatchString(*ostree.Ref) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid OSTree ref %s", *ostree.Ref))
+	}
+	if ostree.Parent != nil && ostree.Url == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "OSTree parent requires a url")
+	}
+	if ostree.Contenturl != nil && ostree.Url == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "OSTree contenturl requires a url")
+	}
+	for _, u := range []*string{ostree.Url, ostree.Contenturl} {
+		if u == nil {
+			continue
+		}
+		parsed, err := url.ParseRequestURI(*u)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid OSTree url %s", *u))
+		}
+	}
+	return nil
+}
+
 // validateComposeRequest makes sure the image size is not too large for AWS or Azure
 // It takes into account the requested image size, and the total size of requested
-// filesystem customizations.
+// filesystem customizations. It also rejects combinations of image types and
+// architectures which cannot be built.
 func validateComposeRequest(cr *ComposeRequest) error {
+	// WSL images are only built for x86_64
+	if cr.ImageRequests[0].ImageType == ImageTypesWsl && cr.ImageRequests[0].Architecture != ImageRequestArchitectureX8664 {
+		return echo.NewHTTPError(http.StatusBadRequest, "WSL images are only supported on x86_64")
+	}
+
+	err := validateOSTree(cr.ImageRequests[0].Ostree)
+	if err != nil {
+		return err
+	}
+
+	// signed commits are downloaded from s3 once they're built
+	if ostree := cr.ImageRequests[0].Ostree; ostree != nil && ostree.Sign != nil && *ostree.Sign {
+		switch cr.ImageRequests[0].ImageType {
+		case ImageTypesEdgeCommit, ImageTypesRhelEdgeCommit:
+		default:
+			return echo.NewHTTPError(http.StatusBadRequest, "Only edge commits can be signed")
+		}
+		if cr.ImageRequests[0].UploadRequest.Type != UploadTypesAwsS3 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Only edge commits uploaded to aws.s3 can be signed")
+		}
+	}
+
+	// installer customizations end up in the kickstart of the installer ISOs
+	if cr.Customizations != nil && cr.Customizations.Installer != nil {
+		switch cr.ImageRequests[0].ImageType {
+		case ImageTypesImageInstaller, ImageTypesEdgeInstaller, ImageTypesRhelEdgeInstaller:
+		default:
+			return echo.NewHTTPError(http.StatusBadRequest, "Installer customizations are only supported for installer image types")
+		}
+	}
+
 	var totalSize uint64
 	cust := cr.Customizations
 	if cust != nil && cust.Filesystem != nil {
@@ -1072,6 +1641,13 @@ func buildCustomizations(cust *Customizations) *composer.Customizations {
 		}
 	}
 
+	if cust.Installer != nil {
+		res.Installer = &composer.Installer{
+			Unattended:   cust.Installer.Unattended,
+			SudoNopasswd: cust.Installer.SudoNopasswd,
+		}
+	}
+
 	return res
 }
 
@@ -1094,98 +1670,142 @@ func (h *Handlers) CloneCompose(ctx echo.Context, composeId uuid.UUID) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the compose")
 	}
 
-	var resp *http.Response
-	var rawCR json.RawMessage
-	if ImageTypes(imageType) == ImageTypesAws || ImageTypes(imageType) == ImageTypesAmi {
-		var awsEC2CloneReq AWSEC2Clone
-		err = ctx.Bind(&awsEC2CloneReq)
-		if err != nil {
-			return err
-		}
+	if ImageTypes(imageType) != ImageTypesAws && ImageTypes(imageType) != ImageTypesAmi {
+		return echo.NewHTTPError(http.StatusBadRequest, "Cloning a compose is only available for AWS composes")
+	}
+	// the image composer uploaded is deleted once its encrypted copy is shared
+	_, err = h.server.db.GetComposeEncryption(composeId)
+	if err == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Encrypted composes can't be cloned")
+	} else if !errors.Is(err, db.ComposeEncryptionNotFoundError) {
+		ctx.Logger().Errorf("Error querying the encryption of compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the compose")
+	}
 
-		rawCR, err = json.Marshal(awsEC2CloneReq)
-		if err != nil {
-			return err
-		}
+	var awsEC2CloneReq AWSEC2Clone
+	err = ctx.Bind(&awsEC2CloneReq)
+	if err != nil {
+		return err
+	}
 
-		var shareWithAccounts []string
-		if awsEC2CloneReq.ShareWithAccounts != nil {
-			shareWithAccounts = append(shareWithAccounts, *awsEC2CloneReq.ShareWithAccounts...)
-		}
+	err = h.checkUploadTarget(ctx, idHeader.Identity.OrgID, UploadTypesAws, []string{awsEC2CloneReq.Region})
+	if err != nil {
+		return err
+	}
 
-		if awsEC2CloneReq.ShareWithSources != nil {
-			for _, source := range *awsEC2CloneReq.ShareWithSources {
-				resp, err := h.server.pClient.GetUploadInfo(ctx.Request().Context(), source)
-				if err != nil {
-					logrus.Error(err)
-					return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unable to request source: %s", source))
-				}
-				defer closeBody(resp.Body)
+	cloneId, err := h.createAWSEC2Clone(ctx, composeId, awsEC2CloneReq)
+	if err != nil {
+		return err
+	}
+	setAuditResource(ctx, cloneId)
 
-				var uploadInfo provisioning.V1SourceUploadInfoResponse
-				err = json.NewDecoder(resp.Body).Decode(&uploadInfo)
-				if err != nil {
-					return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Unable to resolve source: %s", source))
-				}
+	return ctx.JSON(http.StatusCreated, CloneResponse{
+		Id: cloneId,
+	})
+}
 
-				if uploadInfo.Aws == nil || uploadInfo.Aws.AccountId == nil || len(*uploadInfo.Aws.AccountId) != 12 {
-					return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unable to resolve source %s to an aws account id: %v", source, uploadInfo.Aws.AccountId))
-				}
+// createAWSEC2Clone asks composer to copy the AMI of a finished compose into
+// another region and stores the clone.
+func (h *Handlers) createAWSEC2Clone(ctx echo.Context, composeId uuid.UUID, awsEC2CloneReq AWSEC2Clone) (uuid.UUID, error) {
+	composeEntry, err := h.getComposeByIdAndOrgId(ctx, composeId)
+	if err != nil {
+		return uuid.Nil, err
+	}
 
-				logrus.Info(fmt.Sprintf("Resolved source %s, to account id %s", strings.Replace(source, "\n", "", -1), *uploadInfo.Aws.AccountId))
-				shareWithAccounts = append(shareWithAccounts, *uploadInfo.Aws.AccountId)
+	var shareWithAccounts []string
+	if awsEC2CloneReq.ShareWithAccounts != nil {
+		shareWithAccounts = append(shareWithAccounts, *awsEC2CloneReq.ShareWithAccounts...)
+	}
+
+	if awsEC2CloneReq.ShareWithSources != nil {
+		for _, source := range *awsEC2CloneReq.ShareWithSources {
+			resp, err := h.server.pClient.GetUploadInfo(ctx.Request().Context(), source)
+			if err != nil {
+				logrus.Error(err)
+				return uuid.Nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unable to request source: %s", source))
 			}
-		}
+			defer closeBody(resp.Body)
 
-		var ccb composer.CloneComposeBody
-		err = ccb.FromAWSEC2CloneCompose(composer.AWSEC2CloneCompose{
-			Region:            awsEC2CloneReq.Region,
-			ShareWithAccounts: &shareWithAccounts,
-		})
-		if err != nil {
-			return err
-		}
+			var uploadInfo provisioning.V1SourceUploadInfoResponse
+			err = json.NewDecoder(resp.Body).Decode(&uploadInfo)
+			if err != nil {
+				return uuid.Nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Unable to resolve source: %s", source))
+			}
 
-		resp, err = h.server.cClient.CloneCompose(composeId, ccb)
-		if err != nil {
-			return err
+			if uploadInfo.Aws == nil || uploadInfo.Aws.AccountId == nil || len(*uploadInfo.Aws.AccountId) != 12 {
+				return uuid.Nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unable to resolve source %s to an aws account id: %v", source, uploadInfo.Aws.AccountId))
+			}
+
+			logrus.Info(fmt.Sprintf("Resolved source %s, to account id %s", strings.Replace(source, "\n", "", -1), *uploadInfo.Aws.AccountId))
+			shareWithAccounts = append(shareWithAccounts, *uploadInfo.Aws.AccountId)
 		}
-	} else {
-		return echo.NewHTTPError(http.StatusBadRequest, "Cloning a compose is only available for AWS composes")
 	}
 
+	shareWithAccounts, err = h.validateAWSShareWithAccounts(ctx, shareWithAccounts)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return h.server.cloneCompose(ctx.Request().Context(), composeEntry, awsEC2CloneReq, shareWithAccounts)
+}
+
+// cloneCompose asks composer to copy the AMI of a finished compose into the
+// region of the clone request, shared with the already resolved and checked
+// accounts, and stores the clone.
+func (s *Server) cloneCompose(ctx context.Context, composeEntry *db.ComposeEntry, awsEC2CloneReq AWSEC2Clone, shareWithAccounts []string) (uuid.UUID, error) {
+	rawCR, err := json.Marshal(awsEC2CloneReq)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var ccb composer.CloneComposeBody
+	err = ccb.FromAWSEC2CloneCompose(composer.AWSEC2CloneCompose{
+		Region:            awsEC2CloneReq.Region,
+		ShareWithAccounts: &shareWithAccounts,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	cc, err := s.composerOf(composeEntry)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	resp, err := cc.CloneCompose(ctx, composeEntry.ComposerId, ccb)
+	if err != nil {
+		return uuid.Nil, err
+	}
 	if resp == nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong creating the clone")
+		return uuid.Nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong creating the clone")
 	}
 	defer closeBody(resp.Body)
 	if resp.StatusCode != http.StatusCreated {
 		var cError composer.Error
 		err = json.NewDecoder(resp.Body).Decode(&cError)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Unable to parse error returned by image-builder-composer service")
+			return uuid.Nil, echo.NewHTTPError(http.StatusInternalServerError, "Unable to parse error returned by image-builder-composer service")
 		}
 		if cError.Code == ComposeRunningOrFailedError {
-			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("image-builder-composer compose failed: %s", cError.Reason))
+			return uuid.Nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("image-builder-composer compose failed: %s", cError.Reason))
 		}
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("image-builder-composer service returned an error: %s", cError.Reason))
+		return uuid.Nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("image-builder-composer service returned an error: %s", cError.Reason))
 	}
 
 	var cloneResponse composer.CloneComposeResponse
 	err = json.NewDecoder(resp.Body).Decode(&cloneResponse)
 	if err != nil {
-		ctx.Logger().Errorf("Unable to decode CloneComposeResponse: %v", err)
-		return err
+		logrus.Errorf("Unable to decode CloneComposeResponse: %v", err)
+		return uuid.Nil, err
 	}
 
-	err = h.server.db.InsertClone(composeId, cloneResponse.Id, rawCR)
+	err = s.db.InsertClone(composeEntry.Id, cloneResponse.Id, rawCR)
 	if err != nil {
-		ctx.Logger().Errorf("Error inserting clone into db for compose %v: %v", err, composeId)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong saving the clone")
+		logrus.Errorf("Error inserting clone into db for compose %v: %v", composeEntry.Id, err)
+		return uuid.Nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong saving the clone")
 	}
+	s.storeCloneEvent(composeEntry.Id, cloneResponse.Id, OrgEventEventCloneCreated, composeEventCreated)
 
-	return ctx.JSON(http.StatusCreated, CloneResponse{
-		Id: cloneResponse.Id,
-	})
+	return cloneResponse.Id, nil
 }
 
 func (h *Handlers) GetCloneStatus(ctx echo.Context, id uuid.UUID) error {
@@ -1206,33 +1826,49 @@ func (h *Handlers) GetCloneStatus(ctx echo.Context, id uuid.UUID) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Requested clone cannot be found")
 	}
 
-	resp, err := h.server.cClient.CloneStatus(id)
+	cc, err := h.server.composerOfBackend(id.String(), cloneEntry.ComposerBackend)
 	if err != nil {
-		ctx.Logger().Errorf("Error requesting clone status for clone %v: %v", id, err)
 		return err
 	}
+	us, err := h.getCloneUploadStatus(ctx, cc, id)
+	if err != nil {
+		return err
+	}
+	if finishedUpload(composer.UploadStatusValue(us.Status)) {
+		h.server.cloneFinished(*cloneEntry, string(us.Status))
+	}
+
+	return ctx.JSON(http.StatusOK, us)
+}
+
+func (h *Handlers) getCloneUploadStatus(ctx echo.Context, cc *composer.ComposerClient, id uuid.UUID) (*UploadStatus, error) {
+	resp, err := cc.CloneStatus(ctx.Request().Context(), id)
+	if err != nil {
+		ctx.Logger().Errorf("Error requesting clone status for clone %v: %v", id, err)
+		return nil, err
+	}
 	defer closeBody(resp.Body)
 	if resp.StatusCode != http.StatusOK {
 		var cErr composer.Error
 		err = json.NewDecoder(resp.Body).Decode(&cErr)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, "Unable to parse composer error")
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Unable to parse composer error")
 		}
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Unable to create clone job: %v", cErr.Reason))
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("Unable to create clone job: %v", cErr.Reason))
 	}
 
 	var cloudStat composer.CloneStatus
 	err = json.NewDecoder(resp.Body).Decode(&cloudStat)
 	if err != nil {
 		ctx.Logger().Errorf("Unable to decode clone status: %v", err)
-		return err
+		return nil, err
 	}
 
 	var options UploadStatus_Options
 	uo, err := cloudStat.Options.AsAWSEC2UploadStatus()
 	if err != nil {
 		logrus.Errorf("Unable to decode clone status: %v", err)
-		return err
+		return nil, err
 	}
 
 	err = options.FromAWSUploadStatus(AWSUploadStatus{
@@ -1241,14 +1877,14 @@ func (h *Handlers) GetCloneStatus(ctx echo.Context, id uuid.UUID) error {
 	})
 	if err != nil {
 		logrus.Errorf("Unable to encode clone status: %v", err)
-		return err
+		return nil, err
 	}
 
-	return ctx.JSON(http.StatusOK, UploadStatus{
+	return &UploadStatus{
 		Status:  UploadStatusStatus(cloudStat.Status),
 		Type:    UploadTypes(cloudStat.Type),
 		Options: options,
-	})
+	}, nil
 }
 
 func (h *Handlers) GetComposeClones(ctx echo.Context, composeId uuid.UUID, params GetComposeClonesParams) error {
@@ -1339,9 +1975,138 @@ func (h *Handlers) GetOscapProfiles(ctx echo.Context, distribution Distributions
 }
 
 func (h *Handlers) GetOscapCustomizations(ctx echo.Context, distribution Distributions, profile DistributionProfileItem) error {
-	customizations, err := loadOscapCustomizations(h.server.distributionsDir, distribution, profile)
+	customizations, err := loadOscapCustomizations(h.server.current().distributionsDir, distribution, profile)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err)
 	}
 	return ctx.JSON(http.StatusOK, customizations)
 }
+
+func (h *Handlers) GetAPITokens(ctx echo.Context) error {
+	if authenticatedWithAPIToken(ctx) {
+		return echo.NewHTTPError(http.StatusForbidden, "API tokens can not be used to manage API tokens")
+	}
+
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries, err := h.server.db.GetAPITokens(idHeader.Identity.OrgID)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying api tokens: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying api tokens")
+	}
+
+	data := []APIToken{}
+	for _, e := range entries {
+		t := APIToken{
+			Id:        e.Id,
+			Name:      e.Name,
+			ReadOnly:  e.ReadOnly,
+			CreatedBy: e.CreatedBy,
+			CreatedAt: e.CreatedAt.Format(time.RFC3339),
+		}
+		if e.ExpiresAt != nil {
+			t.ExpiresAt = common.ToPtr(e.ExpiresAt.Format(time.RFC3339))
+		}
+		if e.LastUsedAt != nil {
+			t.LastUsedAt = common.ToPtr(e.LastUsedAt.Format(time.RFC3339))
+		}
+		data = append(data, t)
+	}
+	return ctx.JSON(http.StatusOK, APITokensResponse{
+		Data: data,
+	})
+}
+
+func (h *Handlers) CreateAPIToken(ctx echo.Context) error {
+	if authenticatedWithAPIToken(ctx) {
+		return echo.NewHTTPError(http.StatusForbidden, "API tokens can not be used to manage API tokens")
+	}
+
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	var req APITokenRequest
+	err = ctx.Bind(&req)
+	if err != nil {
+		return err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return echo.NewHTTPError(http.StatusBadRequest, "expires_at has to be in the future")
+	}
+
+	token, err := newAPIToken()
+	if err != nil {
+		return err
+	}
+
+	// the column has no time zone, the offset of the request would be lost
+	if req.ExpiresAt != nil {
+		req.ExpiresAt = common.ToPtr(req.ExpiresAt.UTC())
+	}
+
+	// only who created the token is stored, what they may do is looked up
+	// whenever it's used
+	entry := db.APITokenEntry{
+		Id:        uuid.New(),
+		OrgId:     idHeader.Identity.OrgID,
+		Name:      req.Name,
+		ReadOnly:  req.ReadOnly != nil && *req.ReadOnly,
+		CreatedAt: time.Now(),
+		ExpiresAt: req.ExpiresAt,
+	}
+	if idHeader.Identity.AccountNumber != "" {
+		entry.AccountNumber = common.ToPtr(idHeader.Identity.AccountNumber)
+	}
+	if idHeader.Identity.User.UserID != "" {
+		entry.UserId = common.ToPtr(idHeader.Identity.User.UserID)
+	}
+	if idHeader.Identity.User.Username != "" {
+		entry.CreatedBy = common.ToPtr(idHeader.Identity.User.Username)
+	}
+
+	err = h.server.db.InsertAPIToken(entry, hashAPIToken(token))
+	if err != nil {
+		ctx.Logger().Errorf("Error inserting api token: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong creating the api token")
+	}
+	setAuditResource(ctx, entry.Id)
+
+	created := APITokenCreated{
+		Id:        entry.Id,
+		Name:      entry.Name,
+		ReadOnly:  entry.ReadOnly,
+		CreatedBy: entry.CreatedBy,
+		CreatedAt: entry.CreatedAt.Format(time.RFC3339),
+		Token:     token,
+	}
+	if entry.ExpiresAt != nil {
+		created.ExpiresAt = common.ToPtr(entry.ExpiresAt.Format(time.RFC3339))
+	}
+	return ctx.JSON(http.StatusCreated, created)
+}
+
+func (h *Handlers) RevokeAPIToken(ctx echo.Context, id uuid.UUID) error {
+	if authenticatedWithAPIToken(ctx) {
+		return echo.NewHTTPError(http.StatusForbidden, "API tokens can not be used to manage API tokens")
+	}
+
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = h.server.db.RevokeAPIToken(id, idHeader.Identity.OrgID)
+	if err != nil {
+		if errors.Is(err, db.APITokenNotFoundError) {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return ctx.NoContent(http.StatusOK)
+}
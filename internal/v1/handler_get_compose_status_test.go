@@ -2,21 +2,36 @@ package v1
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/redhatinsights/identity"
 	"github.com/stretchr/testify/require"
 
+	"github.com/osbuild/image-builder/internal/common"
 	"github.com/osbuild/image-builder/internal/composer"
-	"github.com/osbuild/image-builder/internal/tutils"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/gpg"
+	"github.com/osbuild/image-builder/pkg/tutils"
 )
 
 func TestComposeStatus(t *testing.T) {
-	composeId := uuid.New()
 	var composerStatus composer.ComposeStatus
 	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if "Bearer" == r.Header.Get("Authorization") {
@@ -39,8 +54,6 @@ func TestComposeStatus(t *testing.T) {
 	}
 	crRaw, err := json.Marshal(cr)
 	require.NoError(t, err)
-	err = dbase.InsertCompose(composeId, "000000", "user000000@test.test", "000000", cr.ImageName, crRaw)
-	require.NoError(t, err)
 	srv, tokenSrv := startServerWithCustomDB(t, apiSrv.URL, "", dbase, "../../distributions", "")
 	defer func() {
 		err := srv.Shutdown(context.Background())
@@ -97,6 +110,26 @@ func TestComposeStatus(t *testing.T) {
 		Url: "url",
 	}))
 
+	var pulpUS composer.UploadStatus_Options
+	require.NoError(t, pulpUS.FromPulpOSTreeUploadStatus(composer.PulpOSTreeUploadStatus{
+		RepoUrl: "https://pulp.example.com/pulp/content/edge/",
+	}))
+	var ibPulpUS UploadStatus_Options
+	require.NoError(t, ibPulpUS.FromPulpOSTreeUploadStatus(PulpOSTreeUploadStatus{
+		RepoUrl: "https://pulp.example.com/pulp/content/edge/",
+	}))
+
+	var containerUS composer.UploadStatus_Options
+	require.NoError(t, containerUS.FromContainerUploadStatus(composer.ContainerUploadStatus{
+		Url:    "quay.io/myorg/edge:latest",
+		Digest: "sha256:fakedigest",
+	}))
+	var ibContainerUS UploadStatus_Options
+	require.NoError(t, ibContainerUS.FromContainerUploadStatus(ContainerUploadStatus{
+		Url:    "quay.io/myorg/edge:latest",
+		Digest: "sha256:fakedigest",
+	}))
+
 	payloads := []struct {
 		composerStatus composer.ComposeStatus
 		imageStatus    ImageStatus
@@ -206,10 +239,57 @@ func TestComposeStatus(t *testing.T) {
 				},
 			},
 		},
+		{
+			composerStatus: composer.ComposeStatus{
+				ImageStatus: composer.ImageStatus{
+					Status: composer.ImageStatusValueSuccess,
+					UploadStatus: &composer.UploadStatus{
+						Status:  composer.UploadStatusValue("success"),
+						Type:    composer.UploadTypesPulpOstree,
+						Options: pulpUS,
+					},
+				},
+				Status: composer.ComposeStatusValueSuccess,
+			},
+			imageStatus: ImageStatus{
+				Status: ImageStatusStatusSuccess,
+				UploadStatus: &UploadStatus{
+					Status:  UploadStatusStatusSuccess,
+					Type:    UploadTypesPulpOstree,
+					Options: ibPulpUS,
+				},
+			},
+		},
+		{
+			composerStatus: composer.ComposeStatus{
+				ImageStatus: composer.ImageStatus{
+					Status: composer.ImageStatusValueSuccess,
+					UploadStatus: &composer.UploadStatus{
+						Status:  composer.UploadStatusValue("success"),
+						Type:    composer.UploadTypesContainer,
+						Options: containerUS,
+					},
+				},
+				Status: composer.ComposeStatusValueSuccess,
+			},
+			imageStatus: ImageStatus{
+				Status: ImageStatusStatusSuccess,
+				UploadStatus: &UploadStatus{
+					Status:  UploadStatusStatusSuccess,
+					Type:    UploadTypesContainer,
+					Options: ibContainerUS,
+				},
+			},
+		},
 	}
 
 	for idx, payload := range payloads {
 		fmt.Printf("TT payload %d\n", idx)
+		// the status of finished composes is served from the database, so
+		// every payload gets a compose of its own
+		composeId := uuid.New()
+		err = dbase.InsertCompose(composeId, "000000", "user000000@test.test", "000000", cr.ImageName, crRaw)
+		require.NoError(t, err)
 		composerStatus = payload.composerStatus
 		respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s", composeId), &tutils.AuthString0)
 		require.Equal(t, http.StatusOK, respStatusCode)
@@ -221,3 +301,648 @@ func TestComposeStatus(t *testing.T) {
 		require.Equal(t, cr, result.Request)
 	}
 }
+
+func TestComposeStatusCached(t *testing.T) {
+	composeId := uuid.New()
+	requests := 0
+	status := composer.ImageStatusValueBuilding
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if "Bearer" == r.Header.Get("Authorization") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err := json.NewEncoder(w).Encode(composer.ComposeStatus{
+			ImageStatus: composer.ImageStatus{
+				Status: status,
+			},
+			Status: composer.ComposeStatusValuePending,
+		})
+		require.NoError(t, err)
+	}))
+	defer apiSrv.Close()
+
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	err = dbase.InsertCompose(composeId, "000000", "user000000@test.test", "000000", nil, json.RawMessage(`{"distribution": "rhel-9"}`))
+	require.NoError(t, err)
+	srv, tokenSrv := startServerWithCustomDB(t, apiSrv.URL, "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	getStatus := func() ImageStatusStatus {
+		respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s", composeId), &tutils.AuthString0)
+		require.Equal(t, http.StatusOK, respStatusCode)
+		var result ComposeStatus
+		require.NoError(t, json.Unmarshal([]byte(body), &result))
+		return result.ImageStatus.Status
+	}
+
+	// without a cache ttl, unfinished composes are always queried
+	require.Equal(t, ImageStatusStatusBuilding, getStatus())
+	require.Equal(t, ImageStatusStatusBuilding, getStatus())
+	require.Equal(t, 2, requests)
+
+	status = composer.ImageStatusValueFailure
+	require.Equal(t, ImageStatusStatusFailure, getStatus())
+	require.Equal(t, ImageStatusStatusFailure, getStatus())
+	require.Equal(t, 3, requests)
+}
+
+// TestComposeStatusFixture builds an image against composer responses
+// recorded into a fixture, re-record it with
+// RECORD_FIXTURES=1 RECORD_COMPOSER_URL=... RECORD_COMPOSER_TOKEN=...
+func TestComposeStatusFixture(t *testing.T) {
+	apiSrv := tutils.NewFixtureServer(t, "testdata/fixtures/composer-aws-compose.json", tutils.UpstreamFromEnv("RECORD_COMPOSER"))
+	srv, tokenSrv := startServer(t, apiSrv.URL, "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	var uo UploadRequest_Options
+	require.NoError(t, uo.FromAWSUploadRequestOptions(AWSUploadRequestOptions{
+		ShareWithAccounts: &[]string{"123456789012"},
+	}))
+	respStatusCode, body := tutils.PostResponseBody(t, "http://localhost:8086/api/image-builder/v1/compose", ComposeRequest{
+		Distribution: "rhel-9",
+		ImageRequests: []ImageRequest{
+			{
+				Architecture: "x86_64",
+				ImageType:    ImageTypesAws,
+				UploadRequest: UploadRequest{
+					Type:    UploadTypesAws,
+					Options: uo,
+				},
+			},
+		},
+	})
+	require.Equal(t, http.StatusCreated, respStatusCode)
+	var created ComposeResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &created))
+
+	getStatus := func() ComposeStatus {
+		respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s", created.Id), &tutils.AuthString0)
+		require.Equal(t, http.StatusOK, respStatusCode)
+		var result ComposeStatus
+		require.NoError(t, json.Unmarshal([]byte(body), &result))
+		return result
+	}
+	require.Equal(t, ImageStatusStatusBuilding, getStatus().ImageStatus.Status)
+
+	status := getStatus()
+	require.Equal(t, ImageStatusStatusSuccess, status.ImageStatus.Status)
+	require.NotNil(t, status.ImageStatus.UploadStatus)
+	require.Equal(t, UploadTypesAws, status.ImageStatus.UploadStatus.Type)
+	aws, err := status.ImageStatus.UploadStatus.Options.AsAWSUploadStatus()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(aws.Ami, "ami-"))
+	require.Equal(t, "rhel-9", string(status.Request.Distribution))
+}
+
+func TestComposeStatusWait(t *testing.T) {
+	composeId := uuid.New()
+	requests := 0
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if "Bearer" == r.Header.Get("Authorization") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		requests++
+		status := composer.ImageStatusValueBuilding
+		if requests > 2 {
+			status = composer.ImageStatusValueFailure
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err := json.NewEncoder(w).Encode(composer.ComposeStatus{
+			ImageStatus: composer.ImageStatus{
+				Status: status,
+			},
+			Status: composer.ComposeStatusValuePending,
+		})
+		require.NoError(t, err)
+	}))
+	defer apiSrv.Close()
+
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	err = dbase.InsertCompose(composeId, "000000", "user000000@test.test", "000000", nil, json.RawMessage(`{"distribution": "rhel-9"}`))
+	require.NoError(t, err)
+	srv, tokenSrv := startServerWithCustomDB(t, apiSrv.URL, "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	getStatus := func(wait string) ImageStatusStatus {
+		respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s?wait=%s", composeId, wait), &tutils.AuthString0)
+		require.Equal(t, http.StatusOK, respStatusCode)
+		var result ComposeStatus
+		require.NoError(t, json.Unmarshal([]byte(body), &result))
+		return result.ImageStatus.Status
+	}
+
+	// the unchanged status is returned once the wait is over
+	start := time.Now()
+	require.Equal(t, ImageStatusStatusBuilding, getStatus("1s"))
+	require.GreaterOrEqual(t, time.Since(start), time.Second)
+	require.Equal(t, 1, requests)
+
+	// or the changed one as soon as it's seen
+	require.Equal(t, ImageStatusStatusFailure, getStatus("1m"))
+	require.Equal(t, 3, requests)
+
+	// finished composes don't wait
+	start = time.Now()
+	require.Equal(t, ImageStatusStatusFailure, getStatus("1m"))
+	require.Less(t, time.Since(start), time.Second)
+
+	respStatusCode, _ := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s?wait=soon", composeId), &tutils.AuthString0)
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+}
+
+func TestComposeStatusReplicatesRegions(t *testing.T) {
+	composeId := uuid.New()
+	cloneId := uuid.New()
+	var clonesCreated, cloneStatusQueries atomic.Int32
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/token" {
+			err := json.NewEncoder(w).Encode(struct {
+				AccessToken string `json:"access_token"`
+			}{
+				AccessToken: "accesstoken",
+			})
+			require.NoError(t, err)
+			return
+		}
+		if "Bearer" == r.Header.Get("Authorization") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, fmt.Sprintf("/composes/%v", composeId)) && r.Method == "GET" {
+			var us composer.UploadStatus_Options
+			require.NoError(t, us.FromAWSEC2UploadStatus(composer.AWSEC2UploadStatus{
+				Ami:    "ami-fakeami",
+				Region: "us-east-1",
+			}))
+			w.WriteHeader(http.StatusOK)
+			err := json.NewEncoder(w).Encode(composer.ComposeStatus{
+				ImageStatus: composer.ImageStatus{
+					Status: composer.ImageStatusValueSuccess,
+					UploadStatus: &composer.UploadStatus{
+						Status:  composer.UploadStatusValue("success"),
+						Type:    composer.UploadTypesAws,
+						Options: us,
+					},
+				},
+				Status: composer.ComposeStatusValueSuccess,
+			})
+			require.NoError(t, err)
+		} else if strings.HasSuffix(r.URL.Path, fmt.Sprintf("/composes/%v/clone", composeId)) && r.Method == "POST" {
+			var clone composer.AWSEC2CloneCompose
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&clone))
+			require.Equal(t, []string{"123456123456"}, *clone.ShareWithAccounts)
+			// composer fails cloning into one of the regions
+			if clone.Region == "eu-central-1" {
+				w.WriteHeader(http.StatusInternalServerError)
+				err := json.NewEncoder(w).Encode(composer.Error{Reason: "cloning failed"})
+				require.NoError(t, err)
+				return
+			}
+			clonesCreated.Add(1)
+			w.WriteHeader(http.StatusCreated)
+			err := json.NewEncoder(w).Encode(composer.CloneComposeResponse{
+				Id: cloneId,
+			})
+			require.NoError(t, err)
+		} else if strings.HasSuffix(r.URL.Path, fmt.Sprintf("/clones/%v", cloneId)) && r.Method == "GET" {
+			cloneStatusQueries.Add(1)
+			var uo composer.CloneStatus_Options
+			require.NoError(t, uo.FromAWSEC2UploadStatus(composer.AWSEC2UploadStatus{
+				Ami:    "ami-fakeclone",
+				Region: "eu-west-1",
+			}))
+			w.WriteHeader(http.StatusOK)
+			err := json.NewEncoder(w).Encode(composer.CloneStatus{
+				Options: uo,
+				Status:  composer.Success,
+				Type:    composer.UploadTypesAws,
+			})
+			require.NoError(t, err)
+		} else {
+			require.FailNowf(t, "Unexpected request to mocked composer, path: %s", r.URL.Path)
+		}
+	}))
+	defer apiSrv.Close()
+
+	var uo UploadRequest_Options
+	require.NoError(t, uo.FromAWSUploadRequestOptions(AWSUploadRequestOptions{
+		ShareWithAccounts: &[]string{"123456123456"},
+		Regions:           &[]string{"eu-west-1", "eu-central-1"},
+	}))
+	cr := ComposeRequest{
+		Distribution: "rhel-9",
+		ImageRequests: []ImageRequest{
+			{
+				Architecture: "x86_64",
+				ImageType:    ImageTypesAws,
+				UploadRequest: UploadRequest{
+					Type:    UploadTypesAws,
+					Options: uo,
+				},
+			},
+		},
+	}
+	crRaw, err := json.Marshal(cr)
+	require.NoError(t, err)
+
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	err = dbase.InsertCompose(composeId, "000000", "user000000@test.test", "000000", cr.ImageName, crRaw)
+	require.NoError(t, err)
+	require.NoError(t, dbase.InsertComposeReplications(composeId, []string{"eu-west-1", "eu-central-1"}, []string{"123456123456"}))
+
+	// the outbox clones the regions once the compose succeeded, regions
+	// which were cloned aren't cloned again when it's retried
+	cc, err := composer.NewClient(composer.ComposerClientConfig{
+		ComposerURL:  apiSrv.URL,
+		TokenURL:     apiSrv.URL + "/token",
+		ClientId:     "rhsm-api",
+		ClientSecret: "secret",
+		Retry:        common.RetryPolicy{Attempts: 1},
+	})
+	require.NoError(t, err)
+	pool, err := composer.NewPool([]composer.Backend{{Name: composer.DefaultBackend, Client: cc}})
+	require.NoError(t, err)
+	s := &Server{db: dbase, composers: pool}
+	event := outboxEvent{
+		composeEventData: composeEventData{ComposeId: composeId, OrgId: "000000", Status: "success"},
+		UploadStatus:     &UploadStatus{Type: UploadTypesAws},
+	}
+	for i := 0; i < 2; i++ {
+		require.ErrorContains(t, s.replicateCompose(event), "eu-central-1")
+	}
+	require.Equal(t, int32(1), clonesCreated.Load())
+
+	srv, tokenSrv := startServerWithCustomDB(t, apiSrv.URL, "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	var ibCloneUS UploadStatus_Options
+	require.NoError(t, ibCloneUS.FromAWSUploadStatus(AWSUploadStatus{
+		Ami:    "ami-fakeclone",
+		Region: "eu-west-1",
+	}))
+	var ibPendingUS UploadStatus_Options
+	require.NoError(t, ibPendingUS.FromAWSUploadStatus(AWSUploadStatus{
+		Region: "eu-central-1",
+	}))
+
+	// querying the status doesn't clone, and the finished clone status is
+	// only queried once
+	for i := 0; i < 2; i++ {
+		respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s", composeId), &tutils.AuthString0)
+		require.Equal(t, http.StatusOK, respStatusCode)
+
+		var result ComposeStatus
+		err = json.Unmarshal([]byte(body), &result)
+		require.NoError(t, err)
+		require.Equal(t, &[]UploadStatus{
+			{
+				Status:  UploadStatusStatusPending,
+				Type:    UploadTypesAws,
+				Options: ibPendingUS,
+			},
+			{
+				Status:  UploadStatusStatusSuccess,
+				Type:    UploadTypesAws,
+				Options: ibCloneUS,
+			},
+		}, result.CloneStatuses)
+	}
+	require.Equal(t, int32(1), clonesCreated.Load())
+	require.Equal(t, int32(1), cloneStatusQueries.Load())
+}
+
+func TestSignContainerImage(t *testing.T) {
+	var manifests []string
+	registry := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "robot" || password != "hunter2" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/myorg/edge/blobs/uploads/":
+			w.Header().Set("Location", "/v2/myorg/edge/blobs/uploads/1")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodPut && r.URL.Path == "/v2/myorg/edge/blobs/uploads/1":
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/v2/myorg/edge/manifests/"):
+			manifests = append(manifests, strings.TrimPrefix(r.URL.Path, "/v2/myorg/edge/manifests/"))
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer registry.Close()
+	// the certificate of the test server is valid for example.com, and only
+	// public registries are signed for
+	transport := registry.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, registry.Listener.Addr().String())
+	}
+	image := "example.com/myorg/edge"
+	digest := "sha256:f5fbd0e5df5a33a2b1e0e5c6e52a7b3e6dba6c1e6a7f38ff4b0e0f1a6b5e7b2a"
+
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if "Bearer" == r.Header.Get("Authorization") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var us composer.UploadStatus_Options
+		require.NoError(t, us.FromContainerUploadStatus(composer.ContainerUploadStatus{
+			Url:    image + ":latest",
+			Digest: digest,
+		}))
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(composer.ComposeStatus{
+			ImageStatus: composer.ImageStatus{
+				Status: composer.ImageStatusValueSuccess,
+				UploadStatus: &composer.UploadStatus{
+					Status:  composer.UploadStatusValue("success"),
+					Type:    composer.UploadTypesContainer,
+					Options: us,
+				},
+			},
+			Status: composer.ComposeStatusValueSuccess,
+		})
+		require.NoError(t, err)
+	}))
+	defer apiSrv.Close()
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "accesstoken"}`)
+	}))
+	defer tokenSrv.Close()
+
+	compClient, err := composer.NewClient(composer.ComposerClientConfig{
+		ComposerURL:  apiSrv.URL,
+		TokenURL:     tokenSrv.URL,
+		ClientId:     "rhsm-api",
+		OfflineToken: "offlinetoken",
+	})
+	require.NoError(t, err)
+	composers, err := composer.NewPool([]composer.Backend{{Name: composer.DefaultBackend, Client: compClient}})
+	require.NoError(t, err)
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	orgSecrets := map[string]string{
+		"private_key":       string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})),
+		"registry_username": "robot",
+		"registry_password": "wrong",
+	}
+	h := &Handlers{
+		server: &Server{
+			cClient:   compClient,
+			composers: composers,
+			db:        dbase,
+			cosign: CosignConfig{
+				OrgSecret: func(ctx context.Context, orgId, key string) (string, error) {
+					require.Equal(t, "000000", orgId)
+					return orgSecrets[key], nil
+				},
+				Client: &http.Client{Transport: transport},
+			},
+		},
+	}
+
+	var uo UploadRequest_Options
+	require.NoError(t, uo.FromContainerUploadRequestOptions(ContainerUploadRequestOptions{
+		Name: image,
+		Sign: common.ToPtr(Key),
+	}))
+	crRaw, err := json.Marshal(ComposeRequest{
+		Distribution: "rhel-9",
+		ImageRequests: []ImageRequest{
+			{
+				Architecture:  "x86_64",
+				ImageType:     ImageTypesEdgeContainer,
+				UploadRequest: UploadRequest{Type: UploadTypesContainer, Options: uo},
+			},
+		},
+	})
+	require.NoError(t, err)
+	composeId := uuid.New()
+	require.NoError(t, dbase.InsertCompose(composeId, "000000", "user000000@test.test", "000000", nil, crRaw))
+
+	status := func() ContainerUploadStatus {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), identity.Key, identity.XRHID{
+			Identity: identity.Identity{OrgID: "000000"},
+		}))
+		rec := httptest.NewRecorder()
+		require.NoError(t, h.GetComposeStatus(echo.New().NewContext(req, rec), composeId, GetComposeStatusParams{}))
+		var result ComposeStatus
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		cs, err := result.ImageStatus.UploadStatus.Options.AsContainerUploadStatus()
+		require.NoError(t, err)
+		return cs
+	}
+
+	var us UploadStatus_Options
+	require.NoError(t, us.FromContainerUploadStatus(ContainerUploadStatus{
+		Url:    image + ":latest",
+		Digest: digest,
+	}))
+	event := outboxEvent{
+		composeEventData: composeEventData{ComposeId: composeId, OrgId: "000000", Status: "success"},
+		UploadStatus:     &UploadStatus{Status: UploadStatusStatusSuccess, Type: UploadTypesContainer, Options: us},
+	}
+
+	// querying the status doesn't sign the image
+	cs := status()
+	require.Nil(t, cs.Signature)
+	require.Equal(t, ContainerUploadStatusSignatureStatusPending, *cs.SignatureStatus)
+	require.Empty(t, manifests)
+
+	// the outbox retries signing, the failure shows up in the status
+	require.Error(t, h.server.signContainerImage(event, false))
+	cs = status()
+	require.Nil(t, cs.Signature)
+	require.Equal(t, ContainerUploadStatusSignatureStatusPending, *cs.SignatureStatus)
+	require.Contains(t, *cs.SignatureError, "refused the credentials")
+	require.Empty(t, manifests)
+
+	orgSecrets["registry_password"] = "hunter2"
+	signature := "example.com/myorg/edge:sha256-f5fbd0e5df5a33a2b1e0e5c6e52a7b3e6dba6c1e6a7f38ff4b0e0f1a6b5e7b2a.sig"
+	for i := 0; i < 2; i++ {
+		require.NoError(t, h.server.signContainerImage(event, false))
+		cs = status()
+		require.Equal(t, &signature, cs.Signature)
+		require.Equal(t, ContainerUploadStatusSignatureStatusSigned, *cs.SignatureStatus)
+		require.Nil(t, cs.SignatureError)
+	}
+	// the image is signed once
+	require.Equal(t, []string{"sha256-f5fbd0e5df5a33a2b1e0e5c6e52a7b3e6dba6c1e6a7f38ff4b0e0f1a6b5e7b2a.sig"}, manifests)
+
+	// the last failed attempt gives up
+	otherId := uuid.New()
+	require.NoError(t, dbase.InsertCompose(otherId, "000000", "user000000@test.test", "000000", nil, crRaw))
+	orgSecrets["registry_password"] = "wrong"
+	event.ComposeId = otherId
+	require.Error(t, h.server.signContainerImage(event, true))
+	sig, err := dbase.GetComposeSignature(otherId, "000000")
+	require.NoError(t, err)
+	require.Equal(t, db.ComposeSignatureFailed, sig.Status)
+
+	// the credentials aren't pushed to internal registries
+	orgSecrets["registry_password"] = "hunter2"
+	_, err = h.server.signImage(context.Background(), "000000", Key, "127.0.0.1:5000/myorg/edge", digest)
+	require.ErrorContains(t, err, "the registry of the image has to be public")
+	_, err = h.server.signImage(context.Background(), "000000", Key, "localhost/myorg/edge", digest)
+	require.ErrorContains(t, err, "the registry of the image has to be public")
+}
+
+func TestGetComposeArtifacts(t *testing.T) {
+	s3Id := uuid.New()
+	amiId := uuid.New()
+	pendingId := uuid.New()
+	var downloads atomic.Int32
+	var apiSrv *httptest.Server
+	apiSrv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bucket/composer-api-disk.qcow2" {
+			require.Equal(t, "abc", r.URL.Query().Get("X-Amz-Signature"))
+			downloads.Add(1)
+			_, err := w.Write([]byte("qcow2"))
+			require.NoError(t, err)
+			return
+		}
+		if "Bearer" == r.Header.Get("Authorization") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.Equal(t, "Bearer accesstoken", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+
+		var s composer.ComposeStatus
+		switch r.URL.Path {
+		case fmt.Sprintf("/api/image-builder-composer/v2/composes/%v", s3Id):
+			var us composer.UploadStatus_Options
+			require.NoError(t, us.FromAWSS3UploadStatus(composer.AWSS3UploadStatus{
+				Url: apiSrv.URL + "/bucket/composer-api-disk.qcow2?X-Amz-Signature=abc",
+			}))
+			s = composer.ComposeStatus{
+				Status: composer.ComposeStatusValueSuccess,
+				ImageStatus: composer.ImageStatus{
+					Status:       composer.ImageStatusValueSuccess,
+					UploadStatus: &composer.UploadStatus{Status: composer.Success, Type: composer.UploadTypesAwsS3, Options: us},
+				},
+			}
+		case fmt.Sprintf("/api/image-builder-composer/v2/composes/%v", amiId):
+			var us composer.UploadStatus_Options
+			require.NoError(t, us.FromAWSEC2UploadStatus(composer.AWSEC2UploadStatus{
+				Ami:    "ami-fakeami",
+				Region: "us-east-1",
+			}))
+			s = composer.ComposeStatus{
+				Status: composer.ComposeStatusValueSuccess,
+				ImageStatus: composer.ImageStatus{
+					Status:       composer.ImageStatusValueSuccess,
+					UploadStatus: &composer.UploadStatus{Status: composer.Success, Type: composer.UploadTypesAws, Options: us},
+				},
+			}
+		case fmt.Sprintf("/api/image-builder-composer/v2/composes/%v", pendingId):
+			s = composer.ComposeStatus{
+				Status: composer.ComposeStatusValuePending,
+				ImageStatus: composer.ImageStatus{
+					Status: composer.ImageStatusValueBuilding,
+				},
+			}
+		default:
+			require.FailNowf(t, "Unexpected request to mocked composer, path: %s", r.URL.Path)
+		}
+		err := json.NewEncoder(w).Encode(s)
+		require.NoError(t, err)
+	}))
+	defer apiSrv.Close()
+
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	for _, id := range []uuid.UUID{s3Id, amiId, pendingId} {
+		err = dbase.InsertCompose(id, "000000", "user000000@test.test", "000000", nil, json.RawMessage("{}"))
+		require.NoError(t, err)
+	}
+	srv, tokenSrv := startServerWithCustomDB(t, apiSrv.URL, "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	artifacts := func(id uuid.UUID) []ComposeArtifact {
+		respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s/artifacts", id), &tutils.AuthString0)
+		require.Equal(t, http.StatusOK, respStatusCode)
+		var result ComposeArtifactsResponse
+		require.NoError(t, json.Unmarshal([]byte(body), &result))
+		return result.Data
+	}
+
+	// nothing is known before the outbox recorded the artifacts
+	require.Empty(t, artifacts(s3Id))
+
+	cc, err := composer.NewClient(composer.ComposerClientConfig{
+		ComposerURL:  apiSrv.URL,
+		TokenURL:     tokenSrv.URL,
+		ClientId:     "rhsm-api",
+		OfflineToken: "offlinetoken",
+		Retry:        common.RetryPolicy{Attempts: 1},
+	})
+	require.NoError(t, err)
+	pool, err := composer.NewPool([]composer.Backend{{Name: composer.DefaultBackend, Client: cc}})
+	require.NoError(t, err)
+	s := &Server{db: dbase, composers: pool, gpgClient: gpg.NewClient(gpg.Config{})}
+	for _, id := range []uuid.UUID{s3Id, s3Id, amiId, pendingId} {
+		require.NoError(t, s.recordArtifacts(outboxEvent{composeEventData: composeEventData{ComposeId: id, OrgId: "000000", Status: "success"}}))
+	}
+
+	// aws.s3 images are downloaded once to measure them
+	require.Equal(t, int32(1), downloads.Load())
+	sum := sha256.Sum256([]byte("qcow2"))
+	require.Equal(t, []ComposeArtifact{
+		{
+			Filename: "composer-api-disk.qcow2",
+			Size:     common.ToPtr(int64(5)),
+			Sha256:   common.ToPtr(hex.EncodeToString(sum[:])),
+		},
+	}, artifacts(s3Id))
+	require.Equal(t, []ComposeArtifact{
+		{
+			Filename:     "ami-fakeami",
+			CloudImageId: common.ToPtr("ami-fakeami"),
+		},
+	}, artifacts(amiId))
+	require.Empty(t, artifacts(pendingId))
+
+	respStatusCode, _ := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s/artifacts", uuid.New()), &tutils.AuthString0)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+}
@@ -1,25 +1,49 @@
 package v1
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redhatinsights/identity"
 	"github.com/stretchr/testify/require"
 
+	"github.com/osbuild/image-builder/internal/awx"
 	"github.com/osbuild/image-builder/internal/common"
 	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/gpg"
+	"github.com/osbuild/image-builder/internal/ostree"
+	"github.com/osbuild/image-builder/internal/prometheus"
 	"github.com/osbuild/image-builder/internal/provisioning"
-	"github.com/osbuild/image-builder/internal/tutils"
+	"github.com/osbuild/image-builder/internal/signing"
+	v2 "github.com/osbuild/image-builder/internal/v2"
+	"github.com/osbuild/image-builder/internal/vulnscan"
+	"github.com/osbuild/image-builder/pkg/tutils"
 )
 
 func TestWithoutOsbuildComposerBackend(t *testing.T) {
@@ -268,6 +292,154 @@ func TestBuildOSTreeOptions(t *testing.T) {
 	}
 }
 
+func TestValidateOSTree(t *testing.T) {
+	cases := []struct {
+		in  *OSTree
+		err string
+	}{
+		{nil, ""},
+		{&OSTree{Ref: common.ToPtr("rhel/8/x86_64/edge")}, ""},
+		{&OSTree{Ref: common.ToPtr("rhel/8/x86_64/edge"), Url: common.ToPtr("https://example.org"), Parent: common.ToPtr("rhel/8/x86_64/edge")}, ""},
+		{&OSTree{Url: common.ToPtr("https://example.org"), Contenturl: common.ToPtr("https://content.example.org")}, ""},
+		{&OSTree{Ref: common.ToPtr("/rhel/edge")}, "Invalid OSTree ref /rhel/edge"},
+		{&OSTree{Ref: common.ToPtr("rhel//edge")}, "Invalid OSTree ref rhel//edge"},
+		{&OSTree{Parent: common.ToPtr("rhel/8/x86_64/edge")}, "OSTree parent requires a url"},
+		{&OSTree{Contenturl: common.ToPtr("https://content.example.org")}, "OSTree contenturl requires a url"},
+		{&OSTree{Url: common.ToPtr("example.org")}, "Invalid OSTree url example.org"},
+		{&OSTree{Url: common.ToPtr("ftp://example.org")}, "Invalid OSTree url ftp://example.org"},
+	}
+
+	for _, c := range cases {
+		err := validateOSTree(c.in)
+		if c.err == "" {
+			require.NoError(t, err, "input: %#v", c.in)
+		} else {
+			require.ErrorContains(t, err, c.err, "input: %#v", c.in)
+		}
+	}
+}
+
+func TestValidateKMSKeyArn(t *testing.T) {
+	require.NoError(t, validateKMSKeyArn("arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab", "us-east-1"))
+	require.NoError(t, validateKMSKeyArn("arn:aws:kms:us-east-1:123456789012:alias/my-key", "us-east-1"))
+	require.NoError(t, validateKMSKeyArn("arn:aws:kms:eu-west-1:123456789012:alias/my-key", ""))
+	require.ErrorContains(t, validateKMSKeyArn("1234abcd-12ab-34cd-56ef-1234567890ab", "us-east-1"), "Invalid KMS key ARN")
+	require.ErrorContains(t, validateKMSKeyArn("arn:aws:kms:us-east-1:1234:key/abcd", "us-east-1"), "Invalid KMS key ARN")
+	require.ErrorContains(t, validateKMSKeyArn("arn:aws:kms:eu-west-1:123456789012:key/abcd", "us-east-1"), "KMS key has to be in region us-east-1")
+	require.NoError(t, validateKMSKeyArn("arn:aws-us-gov:kms:us-gov-west-1:123456789012:key/abcd", "us-gov-west-1"))
+	require.ErrorContains(t, validateKMSKeyArn("arn:aws:kms:us-gov-west-1:123456789012:key/abcd", "us-gov-west-1"), "Invalid KMS key ARN")
+	require.ErrorContains(t, validateKMSKeyArn("arn:aws-us-gov:kms:us-east-1:123456789012:key/abcd", "us-east-1"), "Invalid KMS key ARN")
+}
+
+func TestCheckAWSShareWithAccounts(t *testing.T) {
+	accounts, err := checkAWSShareWithAccounts([]string{"123456789012", "210987654321", "123456789012"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"123456789012", "210987654321"}, accounts)
+
+	_, err = checkAWSShareWithAccounts([]string{"123456789012", "1234", "12345678901a"}, nil)
+	require.ErrorContains(t, err, "1234: not a 12 digit account id")
+	require.ErrorContains(t, err, "12345678901a: not a 12 digit account id")
+
+	accounts, err = checkAWSShareWithAccounts([]string{"123456789012"}, []string{"123456789012"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"123456789012"}, accounts)
+
+	_, err = checkAWSShareWithAccounts([]string{"123456789012", "210987654321"}, []string{"123456789012"})
+	require.ErrorContains(t, err, "210987654321: not allowed by the organization")
+}
+
+func TestValidateGCPShareWithAccounts(t *testing.T) {
+	require.NoError(t, validateGCPShareWithAccounts([]string{
+		"user:alice@example.com",
+		"serviceAccount:my-other-app@appspot.gserviceaccount.com",
+		"group:admins@example.com",
+		"domain:example.com",
+	}))
+
+	err := validateGCPShareWithAccounts([]string{
+		"user:alice@example.com",
+		"alice@example.com",
+		"user:alice",
+		"domain:example",
+		"team:admins@example.com",
+	})
+	require.ErrorContains(t, err, "alice@example.com: missing account type")
+	require.ErrorContains(t, err, "user:alice: invalid email address")
+	require.ErrorContains(t, err, "domain:example: invalid domain")
+	require.ErrorContains(t, err, "team:admins@example.com: unknown account type team")
+	require.NotContains(t, err.Error(), "user:alice@example.com:")
+}
+
+func TestAWSPartitionOfRegion(t *testing.T) {
+	require.Equal(t, AWSPartitionCommercial, awsPartitionOfRegion("us-east-1"))
+	require.Equal(t, AWSPartitionCommercial, awsPartitionOfRegion("eu-west-1"))
+	require.Equal(t, AWSPartitionGovCloud, awsPartitionOfRegion("us-gov-west-1"))
+	require.Equal(t, AWSPartitionGovCloud, awsPartitionOfRegion("us-gov-east-1"))
+}
+
+func TestAWSGovCloudRouting(t *testing.T) {
+	client := func() *composer.ComposerClient {
+		cc, err := composer.NewClient(composer.ComposerClientConfig{
+			ComposerURL:  "http://composer.example.com",
+			TokenURL:     "http://composer.example.com/token",
+			ClientId:     "id",
+			ClientSecret: "secret",
+		})
+		require.NoError(t, err)
+		return cc
+	}
+	def, gov := client(), client()
+	pool, err := composer.NewPool([]composer.Backend{
+		{Name: composer.DefaultBackend, Client: def},
+		{Name: "gov", Client: gov, Regions: []string{"us-gov-west-1"}},
+	})
+	require.NoError(t, err)
+	h := &Handlers{server: &Server{
+		aws:       AWSConfig{Region: "us-east-1", GovRegion: "us-gov-west-1"},
+		composers: pool,
+	}}
+
+	region, err := h.awsRegion(common.ToPtr(AWSPartitionGovCloud))
+	require.NoError(t, err)
+	require.Equal(t, "us-gov-west-1", region)
+	cr := composer.ComposeRequest{
+		Distribution: "rhel-9",
+		ImageRequest: &composer.ImageRequest{
+			UploadOptions: makeUploadOptions(t, composer.AWSEC2UploadOptions{Region: region}),
+		},
+	}
+	name, cc := h.server.routeCompose(cr)
+	require.Equal(t, "gov", name)
+	require.Same(t, gov, cc)
+
+	// the default composer has no GovCloud credentials
+	h.server.composers, err = composer.NewPool([]composer.Backend{{Name: composer.DefaultBackend, Client: def}})
+	require.NoError(t, err)
+	_, err = h.awsRegion(common.ToPtr(AWSPartitionGovCloud))
+	require.ErrorContains(t, err, "Uploading images to AWS GovCloud is not enabled")
+}
+
+func TestDownloadImageType(t *testing.T) {
+	cases := []struct {
+		in  ImageTypes
+		out composer.ImageTypes
+	}{
+		{ImageTypesGuestImage, composer.ImageTypesGuestImage},
+		{ImageTypesRhelEdgeCommit, composer.ImageTypesEdgeCommit},
+		{ImageTypesImageInstaller, composer.ImageTypesImageInstaller},
+		{ImageTypesLiveInstaller, composer.ImageTypesLiveInstaller},
+		{ImageTypesWsl, composer.ImageTypesWsl},
+	}
+	for _, c := range cases {
+		it, err := downloadImageType(c.in)
+		require.NoError(t, err)
+		require.Equal(t, c.out, it)
+	}
+
+	_, err := downloadImageType(ImageTypesAws)
+	require.Error(t, err)
+}
+
 func TestReadinessProbeNotReady(t *testing.T) {
 	srv, tokenSrv := startServer(t, "", "")
 	defer func() {
@@ -500,370 +672,2401 @@ func TestGetCloneStatus(t *testing.T) {
 	require.Equal(t, "us-east-2", awsUS.Region)
 }
 
-func TestValidateSpec(t *testing.T) {
-	spec, err := GetSwagger()
+func TestLaunchCompose(t *testing.T) {
+	id := uuid.New()
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		require.Equal(t, http.MethodGet, r.Method)
+		require.True(t, strings.HasSuffix(r.URL.Path, fmt.Sprintf("/composes/%v", id)))
+		w.WriteHeader(http.StatusOK)
+		err := json.NewEncoder(w).Encode(composer.ComposeStatus{
+			ImageStatus: composer.ImageStatus{Status: composer.ImageStatusValueSuccess},
+		})
+		require.NoError(t, err)
+	}))
+	defer apiSrv.Close()
+
+	provSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, tutils.AuthString0, r.Header.Get("x-rh-identity"))
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/reservations/aws":
+			var reservation provisioning.V1AWSReservationRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&reservation))
+			require.Equal(t, id.String(), *reservation.ImageId)
+			require.Equal(t, "1", *reservation.SourceId)
+			require.Equal(t, int64(5), *reservation.PubkeyId)
+			require.Equal(t, int32(1), *reservation.Amount)
+			require.Equal(t, "t3.small", *reservation.InstanceType)
+			w.WriteHeader(http.StatusOK)
+			require.NoError(t, json.NewEncoder(w).Encode(provisioning.V1AWSReservationResponse{ReservationId: common.ToPtr(int64(1234))}))
+		case r.Method == http.MethodGet && r.URL.Path == "/reservations/1234":
+			w.WriteHeader(http.StatusOK)
+			require.NoError(t, json.NewEncoder(w).Encode(provisioning.V1GenericReservationResponse{
+				Id:         common.ToPtr(int64(1234)),
+				Step:       common.ToPtr(int32(3)),
+				Steps:      common.ToPtr(int32(3)),
+				StepTitles: &[]string{"Ensure public key", "Find image", "Launch instance(s)"},
+				Success:    common.ToPtr(true),
+			}))
+		case r.Method == http.MethodGet && r.URL.Path == "/reservations/aws/1234":
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write([]byte(`{"reservation_id": 1234, "instances": [{"instance_id": "i-1", "detail": {"public_ipv4": "192.0.2.1"}}]}`))
+			require.NoError(t, err)
+		default:
+			require.FailNowf(t, "Unexpected request to mocked provisioning", "%s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer provSrv.Close()
+
+	dbase, err := dbc.NewDB()
 	require.NoError(t, err)
-	err = spec.Validate(context.Background())
+	err = dbase.InsertCompose(id, "500000", "user500000@test.test", "000000", nil, json.RawMessage(`
+{
+  "image_requests": [
+    {
+      "image_type": "aws",
+      "upload_request": {"type": "aws"}
+    }
+  ]
+}`))
+	require.NoError(t, err)
+	s3Id := uuid.New()
+	err = dbase.InsertCompose(s3Id, "500000", "user500000@test.test", "000000", nil, json.RawMessage(`
+{
+  "image_requests": [
+    {
+      "image_type": "guest-image",
+      "upload_request": {"type": "aws.s3"}
+    }
+  ]
+}`))
 	require.NoError(t, err)
+	srv, tokenSrv := startServerWithCustomDB(t, apiSrv.URL, provSrv.URL, dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	launchReq := LaunchRequest{
+		SourceId:     "1",
+		PubkeyId:     5,
+		InstanceType: common.ToPtr("t3.small"),
+	}
+	respStatusCode, _ := tutils.PostResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s/launch", s3Id), launchReq)
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+
+	respStatusCode, body := tutils.PostResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s/launch", id), launchReq)
+	require.Equal(t, http.StatusCreated, respStatusCode)
+	var lResp LaunchResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &lResp))
+	require.Equal(t, int64(1234), lResp.ReservationId)
+
+	var lsResp LaunchesResponse
+	respStatusCode, body = tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s/launches", id), &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &lsResp))
+	require.Len(t, lsResp.Data, 1)
+	require.Equal(t, int64(1234), lsResp.Data[0].ReservationId)
+	require.Equal(t, "aws", lsResp.Data[0].Provider)
+	// the defaults of the spec are filled in
+	launchReq.Amount = common.ToPtr(1)
+	launchReq.Poweroff = common.ToPtr(false)
+	require.Equal(t, launchReq, lsResp.Data[0].Request)
+
+	var status LaunchStatus
+	respStatusCode, body = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/launches/1234", &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &status))
+	require.Equal(t, LaunchStatusStatusSuccess, status.Status)
+	require.Equal(t, "Launch instance(s)", *status.StepTitle)
+	require.Equal(t, []LaunchInstance{{Id: "i-1", PublicIpv4: common.ToPtr("192.0.2.1")}}, *status.Instances)
+
+	// launches of other orgs aren't found
+	respStatusCode, _ = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/launches/1234", &tutils.AuthString1)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
 }
 
-func TestGetArchitectures(t *testing.T) {
-	distsDir := "../../distributions"
-	allowFile := "../common/testdata/allow.json"
-	srv, tokenSrv := startServerWithAllowFile(t, "", "", "", distsDir, allowFile)
+func TestAWXSettings(t *testing.T) {
+	id := uuid.New()
+	awxSrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		require.Equal(t, "/api/v2/job_templates/7/launch/", r.URL.Path)
+		var body struct {
+			ExtraVars awxExtraVars `json:"extra_vars"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, id, body.ExtraVars.ImageBuilder.ComposeId)
+		require.Equal(t, "rhel-8", body.ExtraVars.ImageBuilder.Distribution)
+		require.Equal(t, "aws", body.ExtraVars.ImageBuilder.ImageType)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte(`{"job": 42}`))
+		require.NoError(t, err)
+	}))
+	defer awxSrv.Close()
+
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	err = dbase.InsertCompose(id, "500000", "user500000@test.test", "000000", nil, json.RawMessage(`
+{
+  "distribution": "rhel-8",
+  "image_requests": [
+    {
+      "image_type": "aws",
+      "upload_request": {"type": "aws"}
+    }
+  ]
+}`))
+	require.NoError(t, err)
+	srv, tokenSrv := startServerWithCustomDB(t, "", "", dbase, "../../distributions", "")
 	defer func() {
 		err := srv.Shutdown(context.Background())
 		require.NoError(t, err)
 	}()
 	defer tokenSrv.Close()
 
-	t.Run("Basic centos-8", func(t *testing.T) {
-		respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/architectures/centos-8", &tutils.AuthString0)
-		require.Equal(t, 200, respStatusCode)
+	respStatusCode, _ := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/settings/awx", &tutils.AuthString0)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
 
-		var result Architectures
-		err := json.Unmarshal([]byte(body), &result)
-		require.NoError(t, err)
-		require.Equal(t, Architectures{
-			ArchitectureItem{
-				Arch:       "x86_64",
-				ImageTypes: []string{"aws", "gcp", "azure", "ami", "vhd", "guest-image", "image-installer", "oci", "vsphere", "vsphere-ova", "wsl"},
-				Repositories: []Repository{
-					{
-						Baseurl: common.ToPtr("http://mirror.centos.org/centos/8-stream/BaseOS/x86_64/os/"),
-						Rhsm:    false,
-					}, {
-						Baseurl: common.ToPtr("http://mirror.centos.org/centos/8-stream/AppStream/x86_64/os/"),
-						Rhsm:    false,
-					}, {
-						Baseurl: common.ToPtr("http://mirror.centos.org/centos/8-stream/extras/x86_64/os/"),
-						Rhsm:    false,
-					},
-				},
-			},
-			ArchitectureItem{
-				Arch:       "aarch64",
-				ImageTypes: []string{"aws", "guest-image", "image-installer"},
-				Repositories: []Repository{
-					{
-						Baseurl: common.ToPtr("http://mirror.centos.org/centos/8-stream/BaseOS/aarch64/os/"),
-						Rhsm:    false,
-					}, {
-						Baseurl: common.ToPtr("http://mirror.centos.org/centos/8-stream/AppStream/aarch64/os/"),
-						Rhsm:    false,
-					}, {
-						Baseurl: common.ToPtr("http://mirror.centos.org/centos/8-stream/extras/aarch64/os/"),
-						Rhsm:    false,
-					},
-				},
-			}}, result)
+	respStatusCode, _ = tutils.PutResponseBody(t, "http://localhost:8086/api/image-builder/v1/settings/awx", AWXSettingsRequest{
+		Url:           "http://awx.example.com",
+		Token:         "token",
+		JobTemplateId: 7,
 	})
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
 
-	t.Run("Restricted distribution", func(t *testing.T) {
-		respStatusCode, _ := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/architectures/fedora-39", &tutils.AuthString1)
-		require.Equal(t, 403, respStatusCode)
+	// controllers have to be public
+	respStatusCode, _ = tutils.PutResponseBody(t, "http://localhost:8086/api/image-builder/v1/settings/awx", AWXSettingsRequest{
+		Url:           awxSrv.URL,
+		Token:         "token",
+		JobTemplateId: 7,
 	})
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
 
-	t.Run("Restricted, but allowed distribution", func(t *testing.T) {
-		respStatusCode, _ := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/architectures/fedora-39", &tutils.AuthString0)
-		require.Equal(t, 200, respStatusCode)
+	// the certificate of the test server is valid for example.com
+	respStatusCode, body := tutils.PutResponseBody(t, "http://localhost:8086/api/image-builder/v1/settings/awx", AWXSettingsRequest{
+		Url:           "https://example.com",
+		Token:         "token",
+		JobTemplateId: 7,
 	})
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.NotContains(t, body, "token")
+	var settings AWXSettings
+	require.NoError(t, json.Unmarshal([]byte(body), &settings))
+	require.Equal(t, "https://example.com", settings.Url)
+	require.Equal(t, 7, settings.JobTemplateId)
+
+	// the token is only stored encrypted
+	entry, err := dbase.GetAWXSettings("000000")
+	require.NoError(t, err)
+	require.Equal(t, "kek-1", entry.Token.KEKId)
+	require.NotContains(t, string(entry.Token.Ciphertext), "token")
+
+	// the outbox launches the job of the successful compose
+	transport := awxSrv.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, awxSrv.Listener.Addr().String())
+	}
+	s := &Server{
+		db:        dbase,
+		awxClient: awx.NewClient(awx.Config{Transport: transport}),
+		keystore:  testKeystore(t),
+	}
+	require.NoError(t, s.launchAWXJob(outboxEvent{composeEventData: composeEventData{ComposeId: id, OrgId: "000000", Status: "success"}}, false))
+
+	var jobs AWXJobsResponse
+	respStatusCode, body = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/settings/awx/jobs", &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &jobs))
+	require.Equal(t, 1, jobs.Meta.Count)
+	require.Equal(t, id, jobs.Data[0].ComposeId)
+	require.Equal(t, AWXJobStatusLaunched, jobs.Data[0].Status)
+	require.Equal(t, 42, *jobs.Data[0].JobId)
+
+	respStatusCode, _ = tutils.DeleteResponseBody(t, "http://localhost:8086/api/image-builder/v1/settings/awx", &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	respStatusCode, _ = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/settings/awx", &tutils.AuthString0)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
 }
 
-func TestGetPackages(t *testing.T) {
-	distsDir := "../../distributions"
-	allowFile := "../common/testdata/allow.json"
-	srv, tokenSrv := startServerWithAllowFile(t, "", "", "", distsDir, allowFile)
+func TestArtifactSigningSettings(t *testing.T) {
+	entity, err := openpgp.NewEntity("Image Builder Test", "", "test@example.com", nil)
+	require.NoError(t, err)
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.SerializePrivate(w, nil))
+	require.NoError(t, w.Close())
+
+	content := []byte("a qcow2 image")
+	sum := sha256.Sum256(content)
+	s3Srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	defer s3Srv.Close()
+	// the signing service signs with the same key
+	var serviceSignature bytes.Buffer
+	require.NoError(t, openpgp.ArmoredDetachSign(&serviceSignature, entity, bytes.NewReader(content), nil))
+	signSrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer servicetoken", r.Header.Get("Authorization"))
+		var a gpg.Artifact
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&a))
+		require.Equal(t, "disk.qcow2", a.Filename)
+		require.Equal(t, s3Srv.URL, a.URL)
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]string{"signature": serviceSignature.String()}))
+	}))
+	defer signSrv.Close()
+	// the certificate of the test server is valid for example.com, and the
+	// public client doesn't connect to the loopback address
+	serviceURL := "https://example.com/sign"
+	transport := signSrv.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, signSrv.Listener.Addr().String())
+	}
+
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	srv, tokenSrv := startServerWithCustomDB(t, "", "", dbase, "../../distributions", "")
 	defer func() {
 		err := srv.Shutdown(context.Background())
 		require.NoError(t, err)
 	}()
 	defer tokenSrv.Close()
-	architectures := []string{"x86_64", "aarch64"}
 
-	t.Run("Simple search", func(t *testing.T) {
-		for _, arch := range architectures {
-			respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=rhel-8&architecture=%s&search=ssh", arch), &tutils.AuthString0)
-			require.Equal(t, 200, respStatusCode)
+	settingsURL := "http://localhost:8086/api/image-builder/v1/settings/artifact-signing"
+	respStatusCode, _ := tutils.GetResponseBody(t, settingsURL, &tutils.AuthString0)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+
+	for _, req := range []ArtifactSigningSettingsRequest{
+		{},
+		{PrivateKey: common.ToPtr(armored.String()), ServiceUrl: common.ToPtr(serviceURL)},
+		{PrivateKey: common.ToPtr(armored.String()), ServiceToken: common.ToPtr("servicetoken")},
+		{PrivateKey: common.ToPtr("not a key")},
+		{ServiceUrl: common.ToPtr("http://sign.example.com")},
+		{ServiceUrl: common.ToPtr(signSrv.URL)},
+		{ServiceUrl: common.ToPtr("https://169.254.169.254/sign")},
+	} {
+		respStatusCode, _ = tutils.PutResponseBody(t, settingsURL, req)
+		require.Equal(t, http.StatusBadRequest, respStatusCode)
+	}
 
-			var result PackagesResponse
-			err := json.Unmarshal([]byte(body), &result)
-			require.NoError(t, err)
-			require.Contains(t, result.Data[0].Name, "ssh")
-			require.Greater(t, result.Meta.Count, 0)
-			require.Contains(t, result.Links.First, "search=ssh")
-		}
+	respStatusCode, body := tutils.PutResponseBody(t, settingsURL, ArtifactSigningSettingsRequest{
+		PrivateKey: common.ToPtr(armored.String()),
 	})
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.NotContains(t, body, "PRIVATE KEY")
+	var settings ArtifactSigningSettings
+	require.NoError(t, json.Unmarshal([]byte(body), &settings))
+	require.Equal(t, fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint), *settings.Fingerprint)
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(*settings.PublicKey))
+	require.NoError(t, err)
+	// the private key is the artifact key of the org
+	artifactKey, err := dbase.GetSigningKeyInUse("000000", string(SigningKeyPurposeArtifact))
+	require.NoError(t, err)
+	require.Equal(t, settings.Fingerprint, artifactKey.Fingerprint)
+	require.NotContains(t, string(artifactKey.Ciphertext), "PRIVATE KEY")
+
+	// the outbox signs the qcow2 of successful composes uploaded to s3
+	s := &Server{
+		db:        dbase,
+		gpgClient: gpg.NewClient(gpg.Config{PublicTransport: transport}),
+		keystore:  testKeystore(t),
+	}
+	var us UploadStatus
+	us.Type = UploadTypesAwsS3
+	us.Status = UploadStatusStatusSuccess
+	require.NoError(t, us.Options.FromAWSS3UploadStatus(AWSS3UploadStatus{Url: s3Srv.URL}))
+	sign := func() (*ComposeArtifact, *ComposeArtifact) {
+		id := uuid.New()
+		require.NoError(t, dbase.InsertCompose(id, "500000", "user500000@test.test", "000000", nil, json.RawMessage(`{}`)))
+		require.NoError(t, dbase.InsertComposeArtifacts(id, []db.ArtifactEntry{
+			{Filename: "disk.qcow2", Size: common.ToPtr(int64(len(content))), Sha256: common.ToPtr(hex.EncodeToString(sum[:]))},
+			{Filename: "manifest.json", Size: common.ToPtr(int64(2)), Sha256: common.ToPtr("44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a")},
+		}))
+		require.NoError(t, s.signArtifacts(outboxEvent{composeEventData: composeEventData{ComposeId: id, OrgId: "000000", Status: "success"}, UploadStatus: &us}))
+
+		respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s/artifacts", id), &tutils.AuthString0)
+		require.Equal(t, http.StatusOK, respStatusCode)
+		var artifacts ComposeArtifactsResponse
+		require.NoError(t, json.Unmarshal([]byte(body), &artifacts))
+		require.Len(t, artifacts.Data, 2)
+		return &artifacts.Data[0], &artifacts.Data[1]
+	}
 
-	t.Run("Empty search", func(t *testing.T) {
-		for _, arch := range architectures {
-			respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=rhel-8&architecture=%s&search=4e3086991b3f452d82eed1f2122aefeb", arch), &tutils.AuthString0)
-			require.Equal(t, 200, respStatusCode)
-			var result PackagesResponse
-			err := json.Unmarshal([]byte(body), &result)
-			require.NoError(t, err)
-			require.Empty(t, result.Data)
-			require.Contains(t, body, "\"data\":[]")
+	qcow2, manifest := sign()
+	require.Nil(t, manifest.Signature)
+	_, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(content), strings.NewReader(*qcow2.Signature), nil)
+	require.NoError(t, err)
 
-			respStatusCode, body = tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?offset=121039&distribution=rhel-8&architecture=%s&search=4e3086991b3f452d82eed1f2122aefeb", arch), &tutils.AuthString0)
-			require.Equal(t, 200, respStatusCode)
-			err = json.Unmarshal([]byte(body), &result)
-			require.NoError(t, err)
-			require.Empty(t, result.Data)
-			require.Equal(t, fmt.Sprintf("/api/image-builder/v1.0/packages?search=4e3086991b3f452d82eed1f2122aefeb&distribution=rhel-8&architecture=%s&offset=0&limit=100", arch), result.Links.First)
-			require.Equal(t, fmt.Sprintf("/api/image-builder/v1.0/packages?search=4e3086991b3f452d82eed1f2122aefeb&distribution=rhel-8&architecture=%s&offset=0&limit=100", arch), result.Links.Last)
-		}
+	respStatusCode, body = tutils.PutResponseBody(t, settingsURL, ArtifactSigningSettingsRequest{
+		ServiceUrl:   common.ToPtr(serviceURL),
+		ServiceToken: common.ToPtr("servicetoken"),
 	})
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.NotContains(t, body, "servicetoken")
+	settings = ArtifactSigningSettings{}
+	require.NoError(t, json.Unmarshal([]byte(body), &settings))
+	require.Equal(t, serviceURL, *settings.ServiceUrl)
+	require.Nil(t, settings.Fingerprint)
+	// the artifact key would take precedence over the service
+	artifactKey, err = dbase.GetSigningKey(artifactKey.Id, "000000")
+	require.NoError(t, err)
+	require.NotNil(t, artifactKey.RevokedAt)
+	tokenKey, err := dbase.GetSigningKeyInUse("000000", string(SigningKeyPurposeSigningService))
+	require.NoError(t, err)
+	require.NotContains(t, string(tokenKey.Ciphertext), "servicetoken")
 
-	t.Run("Search with limit", func(t *testing.T) {
-		for _, arch := range architectures {
-			respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=rhel-8&architecture=%s&search=ssh&limit=1", arch), &tutils.AuthString0)
-			require.Equal(t, 200, respStatusCode)
-			var result PackagesResponse
-			err := json.Unmarshal([]byte(body), &result)
-			require.NoError(t, err)
-			require.Greater(t, result.Meta.Count, 1)
-		}
-	})
+	qcow2, manifest = sign()
+	require.Nil(t, manifest.Signature)
+	_, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(content), strings.NewReader(*qcow2.Signature), nil)
+	require.NoError(t, err)
+	usage, count, err := dbase.GetSigningKeyUsage(tokenKey.Id, "000000", 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Equal(t, string(SignArtifact), usage[0].Operation)
 
-	t.Run("Search with offset", func(t *testing.T) {
-		for _, arch := range architectures {
-			respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=rhel-8&architecture=%s&search=ssh&limit=1&offset=1", arch), &tutils.AuthString0)
-			require.Equal(t, 200, respStatusCode)
-			var result PackagesResponse
-			err := json.Unmarshal([]byte(body), &result)
-			require.NoError(t, err)
-			require.Greater(t, result.Meta.Count, 1)
+	respStatusCode, _ = tutils.DeleteResponseBody(t, settingsURL, &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	respStatusCode, _ = tutils.GetResponseBody(t, settingsURL, &tutils.AuthString0)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+	_, err = dbase.GetSigningKeyInUse("000000", string(SigningKeyPurposeSigningService))
+	require.ErrorIs(t, err, db.SigningKeyNotFoundError)
+}
 
-		}
-	})
+func TestSignCommit(t *testing.T) {
+	entity, err := openpgp.NewEntity("Image Builder Test", "", "test@example.com", nil)
+	require.NoError(t, err)
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.SerializePrivate(w, nil))
+	require.NoError(t, w.Close())
+
+	// the tarball of the repo with the commit object
+	object := []byte("a commit variant")
+	objectSum := sha256.Sum256(object)
+	commit := hex.EncodeToString(objectSum[:])
+	var tarball bytes.Buffer
+	tw := tar.NewWriter(&tarball)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "repo/" + ostree.ObjectPath(commit, "commit"), Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(object))}))
+	_, err = tw.Write(object)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	s3Srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(tarball.Bytes())
+	}))
+	defer s3Srv.Close()
 
-	t.Run("Search with invalid parameters", func(t *testing.T) {
-		for _, arch := range architectures {
-			respStatusCode, _ := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=rhel-8&architecture=%s&search=ssh&limit=-13", arch), &tutils.AuthString0)
-			require.Equal(t, 400, respStatusCode)
-			respStatusCode, _ = tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=rhel-8&architecture=%s&search=ssh&limit=13&offset=-2193", arch), &tutils.AuthString0)
-			require.Equal(t, 400, respStatusCode)
-			respStatusCode, _ = tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=none&architecture=%s&search=ssh", arch), &tutils.AuthString0)
-			require.Equal(t, 400, respStatusCode)
+	id := uuid.New()
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if "Bearer" == r.Header.Get("Authorization") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			require.NoError(t, json.NewEncoder(w).Encode(composer.ComposeId{Id: id}))
+			return
 		}
+		require.Equal(t, fmt.Sprintf("/api/image-builder-composer/v2/composes/%s/metadata", id), r.URL.Path)
+		require.NoError(t, json.NewEncoder(w).Encode(composer.ComposeMetadata{OstreeCommit: &commit}))
+	}))
+	defer apiSrv.Close()
+
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	srv, tokenSrv := startServerWithCustomDB(t, apiSrv.URL, "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	var uo UploadRequest_Options
+	require.NoError(t, uo.FromAWSS3UploadRequestOptions(AWSS3UploadRequestOptions{}))
+	compose := func(imageType ImageTypes) (int, string) {
+		return tutils.PostResponseBody(t, "http://localhost:8086/api/image-builder/v1/compose", ComposeRequest{
+			Distribution: "rhel-8",
+			ImageRequests: []ImageRequest{
+				{
+					Architecture: "x86_64",
+					ImageType:    imageType,
+					Ostree:       &OSTree{Sign: common.ToPtr(true)},
+					UploadRequest: UploadRequest{
+						Type:    UploadTypesAwsS3,
+						Options: uo,
+					},
+				},
+			},
+		})
+	}
+
+	respStatusCode, body := compose(ImageTypesGuestImage)
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+	require.Contains(t, body, "Only edge commits can be signed")
+	respStatusCode, body = compose(ImageTypesEdgeCommit)
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+	require.Contains(t, body, "needs a commit or artifact signing key")
+	// signing services can't sign commits
+	respStatusCode, _ = tutils.PutResponseBody(t, "http://localhost:8086/api/image-builder/v1/settings/artifact-signing", ArtifactSigningSettingsRequest{
+		ServiceUrl: common.ToPtr("https://sign.example.com"),
 	})
+	require.Equal(t, http.StatusOK, respStatusCode)
+	respStatusCode, _ = compose(ImageTypesEdgeCommit)
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
 
-	t.Run("Search restricted distribution", func(t *testing.T) {
-		for _, arch := range architectures {
-			respStatusCode, _ := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=fedora-39&architecture=%s&search=ssh", arch), &tutils.AuthString1)
-			require.Equal(t, 403, respStatusCode)
-		}
+	respStatusCode, _ = tutils.PutResponseBody(t, "http://localhost:8086/api/image-builder/v1/settings/artifact-signing", ArtifactSigningSettingsRequest{
+		PrivateKey: common.ToPtr(armored.String()),
 	})
+	require.Equal(t, http.StatusOK, respStatusCode)
+	respStatusCode, _ = compose(ImageTypesEdgeCommit)
+	require.Equal(t, http.StatusCreated, respStatusCode)
 
-	t.Run("Search restricted, but allowed distribution", func(t *testing.T) {
-		for _, arch := range architectures {
-			respStatusCode, _ := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=fedora-39&architecture=%s&search=ssh", arch), &tutils.AuthString0)
-			require.Equal(t, 200, respStatusCode)
-		}
+	signatureURL := fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s/commit-signature", id)
+	respStatusCode, _ = tutils.GetResponseBody(t, signatureURL, &tutils.AuthString0)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+
+	compClient, err := composer.NewClient(composer.ComposerClientConfig{
+		ComposerURL:  apiSrv.URL,
+		TokenURL:     tokenSrv.URL,
+		ClientId:     "rhsm-api",
+		OfflineToken: "offlinetoken",
 	})
+	require.NoError(t, err)
+	composers, err := composer.NewPool([]composer.Backend{{Name: composer.DefaultBackend, Client: compClient}})
+	require.NoError(t, err)
+	s := &Server{
+		db:        dbase,
+		composers: composers,
+		gpgClient: gpg.NewClient(gpg.Config{}),
+		keystore:  testKeystore(t),
+	}
+	var us UploadStatus
+	us.Type = UploadTypesAwsS3
+	us.Status = UploadStatusStatusSuccess
+	require.NoError(t, us.Options.FromAWSS3UploadStatus(AWSS3UploadStatus{Url: s3Srv.URL}))
+	// edge commits have no artifacts which are signed by themselves
+	require.NoError(t, dbase.InsertComposeArtifacts(id, []db.ArtifactEntry{
+		{Filename: "commit.tar", Size: common.ToPtr(int64(tarball.Len())), Sha256: common.ToPtr("44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a")},
+	}))
+	event := outboxEvent{composeEventData: composeEventData{ComposeId: id, OrgId: "000000", Status: "success"}, UploadStatus: &us}
+	require.NoError(t, s.signArtifacts(event))
+
+	signature, err := dbase.GetCommitSignature(id, "000000")
+	require.NoError(t, err)
+	require.Equal(t, commit, signature.Commit)
+	_, err = openpgp.CheckDetachedSignature(openpgp.EntityList{entity}, bytes.NewReader(object), bytes.NewReader(signature.Signature), nil)
+	require.NoError(t, err)
+
+	respStatusCode, body = tutils.GetResponseBody(t, signatureURL, &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.Equal(t, string(ostree.CommitMeta([][]byte{signature.Signature})), body)
+	respStatusCode, _ = tutils.GetResponseBody(t, signatureURL, &tutils.AuthString1)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+
+	// retries keep the signature
+	require.NoError(t, s.signArtifacts(event))
+	again, err := dbase.GetCommitSignature(id, "000000")
+	require.NoError(t, err)
+	require.Equal(t, signature.Signature, again.Signature)
 }
 
-func TestGetDistributions(t *testing.T) {
-	distsDir := "../../distributions"
-	allowFile := "../common/testdata/allow.json"
-	srv, tokenSrv := startServerWithAllowFile(t, "", "", "", distsDir, allowFile)
+type fakeScanner struct {
+	manifests []vulnscan.Manifest
+}
+
+func (f *fakeScanner) Name() string {
+	return "fake"
+}
+
+func (f *fakeScanner) Scan(ctx context.Context, m vulnscan.Manifest) ([]vulnscan.Finding, error) {
+	f.manifests = append(f.manifests, m)
+	return []vulnscan.Finding{
+		{Id: "CVE-2023-0286", Package: "openssl-libs", InstalledVersion: "1:3.0.7-16.el9", FixedVersion: "1:3.0.7-17.el9", Severity: vulnscan.SeverityHigh},
+		{Id: "CVE-2022-3715", Package: "bash", InstalledVersion: "5.1.8-6.el9", Severity: vulnscan.SeverityLow, Title: "a heap-buffer-overflow in valid_parameter_transform"},
+	}, nil
+}
+
+func TestComposeVulnerabilities(t *testing.T) {
+	id := uuid.New()
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if "Bearer" == r.Header.Get("Authorization") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(composer.ComposeMetadata{
+			Packages: &[]composer.PackageMetadata{
+				{Type: "rpm", Name: "bash", Version: "5.1.8", Release: "6.el9", Arch: "x86_64"},
+				{Type: "rpm", Name: "openssl-libs", Epoch: common.ToPtr("1"), Version: "3.0.7", Release: "16.el9", Arch: "x86_64"},
+			},
+		}))
+	}))
+	defer apiSrv.Close()
+
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	srv, tokenSrv := startServerWithCustomDB(t, apiSrv.URL, "", dbase, "../../distributions", "")
 	defer func() {
 		err := srv.Shutdown(context.Background())
 		require.NoError(t, err)
 	}()
 	defer tokenSrv.Close()
 
-	t.Run("Access to restricted distributions", func(t *testing.T) {
-		respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/distributions", &tutils.AuthString0)
-		require.Equal(t, 200, respStatusCode)
-		var result DistributionsResponse
-		err := json.Unmarshal([]byte(body), &result)
-		require.NoError(t, err)
-		distros := []string{}
-		for _, distro := range result {
-			distros = append(distros, distro.Name)
-		}
-		require.ElementsMatch(t, []string{"rhel-8", "rhel-8-nightly", "rhel-84", "rhel-85", "rhel-86", "rhel-87", "rhel-88", "rhel-9", "rhel-9-nightly", "rhel-90", "rhel-91", "rhel-92", "centos-8", "centos-9", "fedora-37", "fedora-38", "fedora-39", "fedora-40"}, distros)
+	compClient, err := composer.NewClient(composer.ComposerClientConfig{
+		ComposerURL:  apiSrv.URL,
+		TokenURL:     tokenSrv.URL,
+		ClientId:     "rhsm-api",
+		OfflineToken: "offlinetoken",
 	})
+	require.NoError(t, err)
+	composers, err := composer.NewPool([]composer.Backend{{Name: composer.DefaultBackend, Client: compClient}})
+	require.NoError(t, err)
+	scanner := &fakeScanner{}
+	s := &Server{
+		db:        dbase,
+		composers: composers,
+		scanner:   scanner,
+	}
 
-	t.Run("No access to restricted distributions", func(t *testing.T) {
-		respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/distributions", &tutils.AuthString1)
-		require.Equal(t, 200, respStatusCode)
-		var result DistributionsResponse
-		err := json.Unmarshal([]byte(body), &result)
+	require.NoError(t, dbase.InsertCompose(id, "500000", "user500000@test.test", "000000", nil, json.RawMessage(`{"distribution": "rhel-92"}`)))
+	webhook := db.WebhookEntry{Id: uuid.New(), OrgId: "000000", URL: "https://hooks.example.com", CreatedAt: time.Now()}
+	require.NoError(t, dbase.InsertWebhook(webhook))
+
+	vulnerabilitiesURL := fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s/vulnerabilities", id)
+	respStatusCode, _ := tutils.GetResponseBody(t, vulnerabilitiesURL, &tutils.AuthString0)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+
+	event := outboxEvent{composeEventData: composeEventData{ComposeId: id, OrgId: "000000", Status: "success"}}
+	require.NoError(t, s.scanCompose(event))
+	require.Equal(t, []vulnscan.Manifest{{
+		Name: id.String(),
+		OS:   vulnscan.OS{Family: "redhat", Version: "9"},
+		Packages: []vulnscan.Package{
+			{Name: "bash", Version: "5.1.8", Release: "6.el9", Arch: "x86_64"},
+			{Name: "openssl-libs", Epoch: 1, Version: "3.0.7", Release: "16.el9", Arch: "x86_64"},
+		},
+	}}, scanner.manifests)
+
+	respStatusCode, body := tutils.GetResponseBody(t, vulnerabilitiesURL, &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	var result VulnerabilitiesResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &result))
+	require.Equal(t, "fake", result.Scanner)
+	require.Equal(t, VulnerabilitySummary{High: 1, Low: 1}, result.Summary)
+	require.Equal(t, []Vulnerability{
+		{Id: "CVE-2023-0286", Package: "openssl-libs", InstalledVersion: "1:3.0.7-16.el9", FixedVersion: common.ToPtr("1:3.0.7-17.el9"), Severity: High},
+		{Id: "CVE-2022-3715", Package: "bash", InstalledVersion: "5.1.8-6.el9", Severity: Low, Title: common.ToPtr("a heap-buffer-overflow in valid_parameter_transform")},
+	}, result.Data)
+	respStatusCode, _ = tutils.GetResponseBody(t, vulnerabilitiesURL, &tutils.AuthString1)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+
+	// retries keep the findings, and the webhooks get the summary once
+	require.NoError(t, s.scanCompose(event))
+	require.Len(t, scanner.manifests, 1)
+	deliveries, total, err := dbase.GetWebhookDeliveries(webhook.Id, "000000", 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, webhookEventVulnerabilityScanFinished, deliveries[0].Event)
+	var payload webhookEvent
+	require.NoError(t, json.Unmarshal(deliveries[0].Payload, &payload))
+	require.Equal(t, id, payload.ComposeId)
+	require.Equal(t, &VulnerabilitySummary{High: 1, Low: 1}, payload.Vulnerabilities)
+
+	// composes of distributions scanners don't know aren't scanned
+	other := uuid.New()
+	require.NoError(t, dbase.InsertCompose(other, "500000", "user500000@test.test", "000000", nil, json.RawMessage(`{"distribution": "unknown"}`)))
+	require.NoError(t, s.scanCompose(outboxEvent{composeEventData: composeEventData{ComposeId: other, OrgId: "000000", Status: "success"}}))
+	require.Len(t, scanner.manifests, 1)
+}
+
+func TestExportComposes(t *testing.T) {
+	id := uuid.New()
+	id2 := uuid.New()
+
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	imageName := "MyImageName"
+	err = dbase.InsertCompose(id, "500000", "user500000@test.test", "000000", &imageName, json.RawMessage(`
+{
+  "distribution": "rhel-8",
+  "image_requests": [
+    {
+      "image_type": "aws",
+      "upload_request": {"type": "aws"}
+    }
+  ]
+}`))
+	require.NoError(t, err)
+	err = dbase.SetCachedComposeStatus(id, json.RawMessage(`{"image_status": {"status": "success"}}`))
+	require.NoError(t, err)
+	err = dbase.InsertCompose(id2, "500000", "user500000@test.test", "000000", nil, json.RawMessage(`
+{
+  "distribution": "rhel-9",
+  "image_requests": [
+    {
+      "image_type": "guest-image",
+      "upload_request": {"type": "aws.s3"}
+    }
+  ]
+}`))
+	require.NoError(t, err)
+
+	srv, tokenSrv := startServerWithCustomDB(t, "", "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
 		require.NoError(t, err)
-		distros := []string{}
-		for _, distro := range result {
-			distros = append(distros, distro.Name)
-		}
-		require.ElementsMatch(t, []string{"rhel-8", "rhel-84", "rhel-85", "rhel-86", "rhel-87", "rhel-88", "rhel-9", "rhel-90", "rhel-91", "rhel-92", "centos-8", "centos-9"}, distros)
-	})
+	}()
+	defer tokenSrv.Close()
+
+	respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/composes/export", &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, []string{"id", "name", "distribution", "image_type", "targets", "status", "created_at"}, records[0])
+	require.Equal(t, []string{id2.String(), "", "rhel-9", "guest-image", "aws.s3", ""}, records[1][:6])
+	require.Equal(t, []string{id.String(), "MyImageName", "rhel-8", "aws", "aws", "success"}, records[2][:6])
+
+	var items []ComposeExportItem
+	respStatusCode, body = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/composes/export?format=json&ignoreImageTypes=guest-image", &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &items))
+	require.Len(t, items, 1)
+	require.Equal(t, id, items[0].Id)
+	require.Equal(t, []string{"aws"}, items[0].Targets)
+	require.Equal(t, "success", items[0].Status)
+
+	// other orgs export nothing
+	respStatusCode, body = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/composes/export?format=json", &tutils.AuthString1)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &items))
+	require.Empty(t, items)
 }
 
-func TestGetProfiles(t *testing.T) {
-	distsDir := "../../distributions"
-	allowFile := "../common/testdata/allow.json"
-	srv, tokenSrv := startServerWithAllowFile(t, "", "", "", distsDir, allowFile)
+func TestUsageReport(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	var ids []uuid.UUID
+	for _, request := range []string{
+		`{"image_requests": [{"image_type": "aws", "upload_request": {"type": "aws"}}]}`,
+		`{"image_requests": [{"image_type": "aws", "upload_request": {"type": "aws"}}]}`,
+		`{"image_requests": [{"image_type": "guest-image", "upload_request": {"type": "aws.s3"}}]}`,
+	} {
+		id := uuid.New()
+		ids = append(ids, id)
+		err = dbase.InsertCompose(id, "500000", "user500000@test.test", "000000", nil, json.RawMessage(request))
+		require.NoError(t, err)
+	}
+	_, err = dbase.InsertComposeEvent(ids[0], "success", nil)
+	require.NoError(t, err)
+	_, err = dbase.InsertComposeEvent(ids[1], "failure", nil)
+	require.NoError(t, err)
+	err = dbase.InsertComposeArtifacts(ids[0], []db.ArtifactEntry{{Filename: "image.raw", Size: common.ToPtr(int64(2048)), Sha256: common.ToPtr("aa")}})
+	require.NoError(t, err)
+	err = dbase.InsertCompose(uuid.New(), "500001", "user500001@test.test", "000001", nil, json.RawMessage(`{"image_requests": [{"image_type": "aws", "upload_request": {"type": "aws"}}]}`))
+	require.NoError(t, err)
+
+	srv, tokenSrv := startServerWithCustomDB(t, "", "", dbase, "../../distributions", "")
 	defer func() {
 		err := srv.Shutdown(context.Background())
 		require.NoError(t, err)
 	}()
 	defer tokenSrv.Close()
 
-	t.Run("Access profiles on all rhel8 variants returns a correct list of profiles", func(t *testing.T) {
-		for _, dist := range []Distributions{
-			Rhel8, Rhel84, Rhel85, Rhel86, Rhel87, Rhel88, Rhel8Nightly, Centos8,
-		} {
-			respStatusCode, body := tutils.GetResponseBody(t,
-				fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/profiles", dist), &tutils.AuthString0)
-			require.Equal(t, 200, respStatusCode)
-			var result DistributionProfileResponse
-			err := json.Unmarshal([]byte(body), &result)
-			require.NoError(t, err)
-			require.ElementsMatch(t, DistributionProfileResponse{
-				XccdfOrgSsgprojectContentProfileAnssiBp28Enhanced,
-				XccdfOrgSsgprojectContentProfileAnssiBp28High,
-				XccdfOrgSsgprojectContentProfileAnssiBp28Intermediary,
-				XccdfOrgSsgprojectContentProfileAnssiBp28Minimal,
-				XccdfOrgSsgprojectContentProfileCis,
-				XccdfOrgSsgprojectContentProfileCisServerL1,
-				XccdfOrgSsgprojectContentProfileCisWorkstationL1,
-				XccdfOrgSsgprojectContentProfileCisWorkstationL2,
-				XccdfOrgSsgprojectContentProfileCui,
-				XccdfOrgSsgprojectContentProfileE8,
-				XccdfOrgSsgprojectContentProfileHipaa,
-				XccdfOrgSsgprojectContentProfileIsmO,
-				XccdfOrgSsgprojectContentProfileOspp,
-				XccdfOrgSsgprojectContentProfilePciDss,
-				XccdfOrgSsgprojectContentProfileStig,
-				XccdfOrgSsgprojectContentProfileStigGui,
-			}, result)
-		}
-	})
+	now := time.Now().UTC()
+	month := now.Format("2006-01")
+	respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/reports/usage", &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	var report UsageReport
+	require.NoError(t, json.Unmarshal([]byte(body), &report))
+	require.Equal(t, month, report.To)
+	require.Equal(t, time.Date(now.Year(), now.Month()-11, 1, 0, 0, 0, 0, time.UTC).Format("2006-01"), report.From)
+	require.Len(t, report.Data, 2)
+	require.Equal(t, month, report.Data[0].Month)
+	require.Equal(t, "aws", report.Data[0].ImageType)
+	require.Equal(t, "aws", report.Data[0].UploadTarget)
+	require.Equal(t, 2, report.Data[0].Builds)
+	require.Equal(t, 1, report.Data[0].Succeeded)
+	require.Equal(t, 1, report.Data[0].Failed)
+	require.Less(t, report.Data[0].BuildMinutes, 1.0)
+	require.Equal(t, int64(2048), report.Data[0].StorageBytes)
+	require.Equal(t, "guest-image", report.Data[1].ImageType)
+	require.Equal(t, "aws.s3", report.Data[1].UploadTarget)
+	require.Equal(t, 1, report.Data[1].Builds)
+	require.Equal(t, 0, report.Data[1].Succeeded)
+
+	respStatusCode, body = tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/reports/usage?from=%s&to=%s&format=csv", month, month), &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, []string{"month", "image_type", "upload_target", "builds", "succeeded", "failed", "build_minutes", "storage_bytes"}, records[0])
+	require.Equal(t, []string{month, "aws", "aws", "2", "1", "1"}, records[1][:6])
+	require.Equal(t, "2048", records[1][7])
 
-	t.Run("Access profiles on all rhel9 variants returns a correct list of profiles", func(t *testing.T) {
-		for _, dist := range []Distributions{
-			Rhel9, Rhel91, Rhel92, Rhel9Nightly, Centos9,
-		} {
-			respStatusCode, body := tutils.GetResponseBody(t,
-				fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/profiles", dist), &tutils.AuthString0)
-			require.Equal(t, 200, respStatusCode)
-			var result DistributionProfileResponse
-			err := json.Unmarshal([]byte(body), &result)
-			require.NoError(t, err)
-			require.ElementsMatch(t, DistributionProfileResponse{
-				XccdfOrgSsgprojectContentProfileAnssiBp28Enhanced,
-				XccdfOrgSsgprojectContentProfileAnssiBp28High,
-				XccdfOrgSsgprojectContentProfileAnssiBp28Intermediary,
-				XccdfOrgSsgprojectContentProfileAnssiBp28Minimal,
-				XccdfOrgSsgprojectContentProfileCis,
-				XccdfOrgSsgprojectContentProfileCisServerL1,
-				XccdfOrgSsgprojectContentProfileCisWorkstationL1,
-				XccdfOrgSsgprojectContentProfileCisWorkstationL2,
-				XccdfOrgSsgprojectContentProfileCui,
-				XccdfOrgSsgprojectContentProfileE8,
-				XccdfOrgSsgprojectContentProfileHipaa,
-				XccdfOrgSsgprojectContentProfileIsmO,
-				XccdfOrgSsgprojectContentProfileOspp,
-				XccdfOrgSsgprojectContentProfilePciDss,
-				XccdfOrgSsgprojectContentProfileStig,
-				XccdfOrgSsgprojectContentProfileStigGui,
-			}, result)
-		}
-	})
+	// months before the composes were started are empty
+	respStatusCode, body = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/reports/usage?from=2023-01&to=2023-12", &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &report))
+	require.Empty(t, report.Data)
 
-	t.Run("Access profiles on the other distros returns an error", func(t *testing.T) {
-		for _, dist := range []Distributions{Fedora37, Fedora38, Fedora39, Fedora40, Rhel90} {
-			respStatusCode, _ := tutils.GetResponseBody(t,
-				fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/profiles", dist), &tutils.AuthString0)
-			require.Equal(t, 400, respStatusCode)
-		}
-	})
+	for _, query := range []string{"from=2024-13", "from=2024-02&to=2024-01", "from=2020-01&to=2023-01", "format=xml"} {
+		respStatusCode, _ = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/reports/usage?"+query, &tutils.AuthString0)
+		require.Equal(t, http.StatusBadRequest, respStatusCode, query)
+	}
 }
 
-func TestGetCustomizations(t *testing.T) {
-	srv, tokenSrv := startServer(t, "", "")
+func TestBuildDurations(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	var ids []uuid.UUID
+	for _, request := range []string{
+		`{"distribution": "rhel-9", "image_requests": [{"image_type": "aws", "architecture": "x86_64"}]}`,
+		`{"distribution": "rhel-9", "image_requests": [{"image_type": "aws", "architecture": "x86_64"}]}`,
+		`{"distribution": "rhel-9", "image_requests": [{"image_type": "guest-image", "architecture": "aarch64"}]}`,
+	} {
+		id := uuid.New()
+		ids = append(ids, id)
+		err = dbase.InsertCompose(id, "500000", "user500000@test.test", "000000", nil, json.RawMessage(request))
+		require.NoError(t, err)
+	}
+	_, err = dbase.InsertComposeEvent(ids[0], "success", nil)
+	require.NoError(t, err)
+	_, err = dbase.InsertComposeEvent(ids[1], "failure", nil)
+	require.NoError(t, err)
+	_, err = dbase.InsertComposeEvent(ids[2], "success", nil)
+	require.NoError(t, err)
+
+	srv, tokenSrv := startServerWithCustomDB(t, "", "", dbase, "../../distributions", "")
 	defer func() {
 		err := srv.Shutdown(context.Background())
 		require.NoError(t, err)
 	}()
 	defer tokenSrv.Close()
 
-	t.Run("Access all customizations and check that they match", func(t *testing.T) {
-		for _, dist := range []Distributions{
-			Rhel8, Rhel84, Rhel85, Rhel86, Rhel87, Rhel88, Rhel8Nightly, Rhel9, Rhel91, Rhel92, Rhel9Nightly, Centos8, Centos9,
-		} {
-			respStatusCode, body := tutils.GetResponseBody(t,
-				fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/profiles", dist), &tutils.AuthString0)
-			require.Equal(t, 200, respStatusCode)
-			var result DistributionProfileResponse
-			err := json.Unmarshal([]byte(body), &result)
-			require.NoError(t, err)
-			for _, profile := range result {
-				// Get the customization from the API
-				var result Customizations
-				respStatusCode, body := tutils.GetResponseBody(t,
-					fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/%s/customizations", dist, profile), &tutils.AuthString0)
-				require.Equal(t, 200, respStatusCode)
-				err := json.Unmarshal([]byte(body), &result)
-				require.NoError(t, err)
-				// load the corresponding file from the disk
-				require.NoError(t, err)
-				jsonFile, err := os.Open(
-					path.Join(
-						"../../distributions",
-						string(dist),
-						"oscap",
-						string(profile),
-						"customizations.json"))
-				require.NoError(t, err)
-				defer jsonFile.Close()
-				bytes, err := io.ReadAll(jsonFile)
-				require.NoError(t, err)
-				var customizations Customizations
-				err = json.Unmarshal(bytes, &customizations)
-				require.NoError(t, err)
-				// Make sure we get the same result both ways
-				if result.Packages == nil {
-					require.Nil(t, customizations.Packages)
-				} else {
-					require.ElementsMatch(t, *customizations.Packages, *result.Packages)
-				}
-				if result.Filesystem == nil {
-					require.Nil(t, customizations.Filesystem)
-				} else {
-					require.ElementsMatch(t, *customizations.Filesystem, *result.Filesystem)
-				}
-			}
+	// the builds of all orgs are accounted for
+	respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/stats/build-durations", &tutils.AuthString1)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	var stats BuildDurations
+	require.NoError(t, json.Unmarshal([]byte(body), &stats))
+	require.Equal(t, "30d", stats.Window)
+	require.InDelta(t, 30*24*time.Hour, stats.Until.Sub(stats.Since), float64(time.Second))
+	require.Len(t, stats.Data, 2)
+	require.Equal(t, "rhel-9", stats.Data[0].Distribution)
+	require.Equal(t, "aws", stats.Data[0].ImageType)
+	require.Equal(t, "x86_64", stats.Data[0].Architecture)
+	require.Equal(t, 1, stats.Data[0].Builds)
+	require.Less(t, stats.Data[0].P95Seconds, 60.0)
+	require.Equal(t, "guest-image", stats.Data[1].ImageType)
+	require.Equal(t, "aarch64", stats.Data[1].Architecture)
+
+	respStatusCode, body = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/stats/build-durations?window=1d", &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &stats))
+	require.Equal(t, "1d", stats.Window)
+	require.Len(t, stats.Data, 2)
+
+	respStatusCode, _ = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/stats/build-durations?window=1y", &tutils.AuthString0)
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+}
+
+func TestV2Composes(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	var ids []uuid.UUID
+	for i := 0; i < 3; i++ {
+		id := uuid.New()
+		ids = append(ids, id)
+		err = dbase.InsertCompose(id, "500000", "user500000@test.test", "000000", nil, json.RawMessage(`{"distribution": "rhel-8"}`))
+		require.NoError(t, err)
+	}
+	err = dbase.SetCachedComposeStatus(ids[2], json.RawMessage(`{"image_status": {"status": "success"}}`))
+	require.NoError(t, err)
+
+	srv, tokenSrv := startServerWithCustomDB(t, "", "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	getPage := func(url string) ([]v2.Compose, string) {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		req.Header.Add("x-rh-identity", tutils.AuthString0)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var composes []v2.Compose
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&composes))
+		return composes, resp.Header.Get("Link")
+	}
+
+	// the newest compose comes first, and has a status
+	composes, link := getPage("http://localhost:8086/api/image-builder/v2/composes?limit=2")
+	require.Len(t, composes, 2)
+	require.Equal(t, ids[2], composes[0].Id)
+	require.Equal(t, "success", *composes[0].Status)
+	require.Equal(t, ids[1], composes[1].Id)
+	require.Nil(t, composes[1].Status)
+	require.Regexp(t, `^<.+>; rel="next"$`, link)
+
+	composes, link = getPage("http://localhost:8086" + strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`))
+	require.Len(t, composes, 1)
+	require.Equal(t, ids[0], composes[0].Id)
+	require.Empty(t, link)
+
+	respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v2/composes?cursor=invalid", &tutils.AuthString0)
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+	var v2Err v2.Error
+	require.NoError(t, json.Unmarshal([]byte(body), &v2Err))
+	require.Equal(t, "BAD_REQUEST", v2Err.Code)
+
+	respStatusCode, body = tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v2/composes/%v", ids[0]), &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	var compose v2.Compose
+	require.NoError(t, json.Unmarshal([]byte(body), &compose))
+	require.Equal(t, "rhel-8", compose.Request["distribution"])
+
+	// composes of other orgs aren't found, with a stable error
+	respStatusCode, body = tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v2/composes/%v", ids[0]), &tutils.AuthString1)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &v2Err))
+	require.Equal(t, "NOT_FOUND", v2Err.Code)
+
+	// deleting is idempotent
+	for i := 0; i < 2; i++ {
+		respStatusCode, _ = tutils.DeleteResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v2/composes/%v", ids[0]), &tutils.AuthString0)
+		require.Equal(t, http.StatusNoContent, respStatusCode)
+	}
+	respStatusCode, _ = tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v2/composes/%v", ids[0]), &tutils.AuthString0)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+}
+
+func TestV2CreateCompose(t *testing.T) {
+	var submitted []uuid.UUID
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if "Bearer" == r.Header.Get("Authorization") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		id := uuid.New()
+		submitted = append(submitted, id)
+		w.WriteHeader(http.StatusCreated)
+		require.NoError(t, json.NewEncoder(w).Encode(composer.ComposeId{Id: id}))
+	}))
+	defer apiSrv.Close()
+
+	srv, tokenSrv := startServer(t, apiSrv.URL, "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	post := func(key, body string) (int, string) {
+		req, err := http.NewRequest(http.MethodPost, "http://localhost:8086/api/image-builder/v2/composes", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add("x-rh-identity", tutils.AuthString0)
+		if key != "" {
+			req.Header.Add("Idempotency-Key", key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		raw, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(raw)
+	}
+	request := `{"distribution": "centos-8", "image_requests": [{"architecture": "x86_64", "image_type": "guest-image", "upload_request": {"type": "aws.s3", "options": {}}}]}`
+
+	respStatusCode, body := post("key", request)
+	require.Equal(t, http.StatusCreated, respStatusCode, body)
+	var created v2.Compose
+	require.NoError(t, json.Unmarshal([]byte(body), &created))
+	require.Len(t, submitted, 1)
+	require.Equal(t, submitted[0], created.Id)
+	require.Equal(t, "centos-8", created.Request["distribution"])
+
+	// a retry returns the compose without composing it again, the order
+	// of the keys doesn't matter
+	respStatusCode, body = post("key", `{"image_requests": [{"architecture": "x86_64", "image_type": "guest-image", "upload_request": {"type": "aws.s3", "options": {}}}], "distribution": "centos-8"}`)
+	require.Equal(t, http.StatusOK, respStatusCode, body)
+	var replayed v2.Compose
+	require.NoError(t, json.Unmarshal([]byte(body), &replayed))
+	require.Equal(t, created.Id, replayed.Id)
+	require.Len(t, submitted, 1)
+
+	// the key can't be used for another request
+	respStatusCode, body = post("key", strings.Replace(request, "centos-8", "rhel-8", 1))
+	require.Equal(t, http.StatusUnprocessableEntity, respStatusCode)
+	var v2Err v2.Error
+	require.NoError(t, json.Unmarshal([]byte(body), &v2Err))
+	require.Equal(t, "IDEMPOTENCY_KEY_REUSED", v2Err.Code)
+	require.Len(t, submitted, 1)
+
+	// requests without a key, or with another one, are composed each time
+	respStatusCode, _ = post("", request)
+	require.Equal(t, http.StatusCreated, respStatusCode)
+	respStatusCode, _ = post("other", request)
+	require.Equal(t, http.StatusCreated, respStatusCode)
+	require.Len(t, submitted, 3)
+
+	// the request is checked against the compose request of v1
+	respStatusCode, body = post("invalid", `{"distribution": "centos-8"}`)
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &v2Err))
+	require.Equal(t, "BAD_REQUEST", v2Err.Code)
+	respStatusCode, _ = post(strings.Repeat("k", 256), request)
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+	require.Len(t, submitted, 3)
+
+	// the compose of a deleted key isn't composed again
+	respStatusCode, _ = tutils.DeleteResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v2/composes/%v", created.Id), &tutils.AuthString0)
+	require.Equal(t, http.StatusNoContent, respStatusCode)
+	respStatusCode, _ = post("key", request)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+	require.Len(t, submitted, 3)
+}
+
+func TestValidateSpec(t *testing.T) {
+	spec, err := GetSwagger()
+	require.NoError(t, err)
+	err = spec.Validate(context.Background())
+	require.NoError(t, err)
+}
+
+func TestGetArchitectures(t *testing.T) {
+	distsDir := "../../distributions"
+	allowFile := "../common/testdata/allow.json"
+	srv, tokenSrv := startServerWithAllowFile(t, "", "", "", distsDir, allowFile)
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	t.Run("Basic centos-8", func(t *testing.T) {
+		respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/architectures/centos-8", &tutils.AuthString0)
+		require.Equal(t, 200, respStatusCode)
+
+		var result Architectures
+		err := json.Unmarshal([]byte(body), &result)
+		require.NoError(t, err)
+		require.Equal(t, Architectures{
+			ArchitectureItem{
+				Arch:       "x86_64",
+				ImageTypes: []string{"aws", "gcp", "azure", "ami", "vhd", "guest-image", "image-installer", "oci", "vsphere", "vsphere-ova", "wsl"},
+				Repositories: []Repository{
+					{
+						Baseurl: common.ToPtr("http://mirror.centos.org/centos/8-stream/BaseOS/x86_64/os/"),
+						Rhsm:    false,
+					}, {
+						Baseurl: common.ToPtr("http://mirror.centos.org/centos/8-stream/AppStream/x86_64/os/"),
+						Rhsm:    false,
+					}, {
+						Baseurl: common.ToPtr("http://mirror.centos.org/centos/8-stream/extras/x86_64/os/"),
+						Rhsm:    false,
+					},
+				},
+			},
+			ArchitectureItem{
+				Arch:       "aarch64",
+				ImageTypes: []string{"aws", "guest-image", "image-installer"},
+				Repositories: []Repository{
+					{
+						Baseurl: common.ToPtr("http://mirror.centos.org/centos/8-stream/BaseOS/aarch64/os/"),
+						Rhsm:    false,
+					}, {
+						Baseurl: common.ToPtr("http://mirror.centos.org/centos/8-stream/AppStream/aarch64/os/"),
+						Rhsm:    false,
+					}, {
+						Baseurl: common.ToPtr("http://mirror.centos.org/centos/8-stream/extras/aarch64/os/"),
+						Rhsm:    false,
+					},
+				},
+			}}, result)
+	})
+
+	t.Run("Restricted distribution", func(t *testing.T) {
+		respStatusCode, _ := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/architectures/fedora-39", &tutils.AuthString1)
+		require.Equal(t, 403, respStatusCode)
+	})
+
+	t.Run("Restricted, but allowed distribution", func(t *testing.T) {
+		respStatusCode, _ := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/architectures/fedora-39", &tutils.AuthString0)
+		require.Equal(t, 200, respStatusCode)
+	})
+}
+
+func TestGetPackages(t *testing.T) {
+	distsDir := "../../distributions"
+	allowFile := "../common/testdata/allow.json"
+	srv, tokenSrv := startServerWithAllowFile(t, "", "", "", distsDir, allowFile)
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+	architectures := []string{"x86_64", "aarch64"}
+
+	t.Run("Simple search", func(t *testing.T) {
+		for _, arch := range architectures {
+			respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=rhel-8&architecture=%s&search=ssh", arch), &tutils.AuthString0)
+			require.Equal(t, 200, respStatusCode)
+
+			var result PackagesResponse
+			err := json.Unmarshal([]byte(body), &result)
+			require.NoError(t, err)
+			require.Contains(t, result.Data[0].Name, "ssh")
+			require.Greater(t, result.Meta.Count, 0)
+			require.Contains(t, result.Links.First, "search=ssh")
 		}
 	})
-	t.Run("Access customizations on a distro that does not have customizations returns an error", func(t *testing.T) {
-		for _, dist := range []Distributions{Rhel8} {
-			respStatusCode, body := tutils.GetResponseBody(t,
-				fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/profiles", dist), &tutils.AuthString0)
+
+	t.Run("Empty search", func(t *testing.T) {
+		for _, arch := range architectures {
+			respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=rhel-8&architecture=%s&search=4e3086991b3f452d82eed1f2122aefeb", arch), &tutils.AuthString0)
 			require.Equal(t, 200, respStatusCode)
-			var result DistributionProfileResponse
+			var result PackagesResponse
 			err := json.Unmarshal([]byte(body), &result)
 			require.NoError(t, err)
-			for _, profile := range result {
-				respStatusCode, _ := tutils.GetResponseBody(t,
-					fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/%s/customizations", Fedora40, profile), &tutils.AuthString0)
-				require.Equal(t, 400, respStatusCode)
-			}
+			require.Empty(t, result.Data)
+			require.Contains(t, body, "\"data\":[]")
+
+			respStatusCode, body = tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?offset=121039&distribution=rhel-8&architecture=%s&search=4e3086991b3f452d82eed1f2122aefeb", arch), &tutils.AuthString0)
+			require.Equal(t, 200, respStatusCode)
+			err = json.Unmarshal([]byte(body), &result)
+			require.NoError(t, err)
+			require.Empty(t, result.Data)
+			require.Equal(t, fmt.Sprintf("/api/image-builder/v1.0/packages?search=4e3086991b3f452d82eed1f2122aefeb&distribution=rhel-8&architecture=%s&offset=0&limit=100", arch), result.Links.First)
+			require.Equal(t, fmt.Sprintf("/api/image-builder/v1.0/packages?search=4e3086991b3f452d82eed1f2122aefeb&distribution=rhel-8&architecture=%s&offset=0&limit=100", arch), result.Links.Last)
 		}
 	})
-	t.Run("Access non existing customizations on a distro returns an error", func(t *testing.T) {
-		for _, dist := range []Distributions{Rhel8} {
-			respStatusCode, body := tutils.GetResponseBody(t,
-				fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/profiles", dist), &tutils.AuthString0)
+
+	t.Run("Search with limit", func(t *testing.T) {
+		for _, arch := range architectures {
+			respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=rhel-8&architecture=%s&search=ssh&limit=1", arch), &tutils.AuthString0)
 			require.Equal(t, 200, respStatusCode)
-			var result DistributionProfileResponse
+			var result PackagesResponse
 			err := json.Unmarshal([]byte(body), &result)
 			require.NoError(t, err)
-			respStatusCode, _ = tutils.GetResponseBody(t,
-				fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/%s/customizations", dist, "badprofile"), &tutils.AuthString0)
+			require.Greater(t, result.Meta.Count, 1)
+		}
+	})
+
+	t.Run("Search with offset", func(t *testing.T) {
+		for _, arch := range architectures {
+			respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=rhel-8&architecture=%s&search=ssh&limit=1&offset=1", arch), &tutils.AuthString0)
+			require.Equal(t, 200, respStatusCode)
+			var result PackagesResponse
+			err := json.Unmarshal([]byte(body), &result)
+			require.NoError(t, err)
+			require.Greater(t, result.Meta.Count, 1)
+
+		}
+	})
+
+	t.Run("Search with invalid parameters", func(t *testing.T) {
+		for _, arch := range architectures {
+			respStatusCode, _ := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=rhel-8&architecture=%s&search=ssh&limit=-13", arch), &tutils.AuthString0)
+			require.Equal(t, 400, respStatusCode)
+			respStatusCode, _ = tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=rhel-8&architecture=%s&search=ssh&limit=13&offset=-2193", arch), &tutils.AuthString0)
+			require.Equal(t, 400, respStatusCode)
+			respStatusCode, _ = tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=none&architecture=%s&search=ssh", arch), &tutils.AuthString0)
 			require.Equal(t, 400, respStatusCode)
 		}
 	})
+
+	t.Run("Search restricted distribution", func(t *testing.T) {
+		for _, arch := range architectures {
+			respStatusCode, _ := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=fedora-39&architecture=%s&search=ssh", arch), &tutils.AuthString1)
+			require.Equal(t, 403, respStatusCode)
+		}
+	})
+
+	t.Run("Search restricted, but allowed distribution", func(t *testing.T) {
+		for _, arch := range architectures {
+			respStatusCode, _ := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/packages?distribution=fedora-39&architecture=%s&search=ssh", arch), &tutils.AuthString0)
+			require.Equal(t, 200, respStatusCode)
+		}
+	})
+}
+
+func TestGetDistributions(t *testing.T) {
+	distsDir := "../../distributions"
+	allowFile := "../common/testdata/allow.json"
+	srv, tokenSrv := startServerWithAllowFile(t, "", "", "", distsDir, allowFile)
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	t.Run("Access to restricted distributions", func(t *testing.T) {
+		respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/distributions", &tutils.AuthString0)
+		require.Equal(t, 200, respStatusCode)
+		var result DistributionsResponse
+		err := json.Unmarshal([]byte(body), &result)
+		require.NoError(t, err)
+		distros := []string{}
+		for _, distro := range result {
+			distros = append(distros, distro.Name)
+		}
+		require.ElementsMatch(t, []string{"rhel-8", "rhel-8-nightly", "rhel-84", "rhel-85", "rhel-86", "rhel-87", "rhel-88", "rhel-9", "rhel-9-nightly", "rhel-90", "rhel-91", "rhel-92", "centos-8", "centos-9", "fedora-37", "fedora-38", "fedora-39", "fedora-40"}, distros)
+	})
+
+	t.Run("No access to restricted distributions", func(t *testing.T) {
+		respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/distributions", &tutils.AuthString1)
+		require.Equal(t, 200, respStatusCode)
+		var result DistributionsResponse
+		err := json.Unmarshal([]byte(body), &result)
+		require.NoError(t, err)
+		distros := []string{}
+		for _, distro := range result {
+			distros = append(distros, distro.Name)
+		}
+		require.ElementsMatch(t, []string{"rhel-8", "rhel-84", "rhel-85", "rhel-86", "rhel-87", "rhel-88", "rhel-9", "rhel-90", "rhel-91", "rhel-92", "centos-8", "centos-9"}, distros)
+	})
+}
+
+func TestGetProfiles(t *testing.T) {
+	distsDir := "../../distributions"
+	allowFile := "../common/testdata/allow.json"
+	srv, tokenSrv := startServerWithAllowFile(t, "", "", "", distsDir, allowFile)
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	t.Run("Access profiles on all rhel8 variants returns a correct list of profiles", func(t *testing.T) {
+		for _, dist := range []Distributions{
+			Rhel8, Rhel84, Rhel85, Rhel86, Rhel87, Rhel88, Rhel8Nightly, Centos8,
+		} {
+			respStatusCode, body := tutils.GetResponseBody(t,
+				fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/profiles", dist), &tutils.AuthString0)
+			require.Equal(t, 200, respStatusCode)
+			var result DistributionProfileResponse
+			err := json.Unmarshal([]byte(body), &result)
+			require.NoError(t, err)
+			require.ElementsMatch(t, DistributionProfileResponse{
+				XccdfOrgSsgprojectContentProfileAnssiBp28Enhanced,
+				XccdfOrgSsgprojectContentProfileAnssiBp28High,
+				XccdfOrgSsgprojectContentProfileAnssiBp28Intermediary,
+				XccdfOrgSsgprojectContentProfileAnssiBp28Minimal,
+				XccdfOrgSsgprojectContentProfileCis,
+				XccdfOrgSsgprojectContentProfileCisServerL1,
+				XccdfOrgSsgprojectContentProfileCisWorkstationL1,
+				XccdfOrgSsgprojectContentProfileCisWorkstationL2,
+				XccdfOrgSsgprojectContentProfileCui,
+				XccdfOrgSsgprojectContentProfileE8,
+				XccdfOrgSsgprojectContentProfileHipaa,
+				XccdfOrgSsgprojectContentProfileIsmO,
+				XccdfOrgSsgprojectContentProfileOspp,
+				XccdfOrgSsgprojectContentProfilePciDss,
+				XccdfOrgSsgprojectContentProfileStig,
+				XccdfOrgSsgprojectContentProfileStigGui,
+			}, result)
+		}
+	})
+
+	t.Run("Access profiles on all rhel9 variants returns a correct list of profiles", func(t *testing.T) {
+		for _, dist := range []Distributions{
+			Rhel9, Rhel91, Rhel92, Rhel9Nightly, Centos9,
+		} {
+			respStatusCode, body := tutils.GetResponseBody(t,
+				fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/profiles", dist), &tutils.AuthString0)
+			require.Equal(t, 200, respStatusCode)
+			var result DistributionProfileResponse
+			err := json.Unmarshal([]byte(body), &result)
+			require.NoError(t, err)
+			require.ElementsMatch(t, DistributionProfileResponse{
+				XccdfOrgSsgprojectContentProfileAnssiBp28Enhanced,
+				XccdfOrgSsgprojectContentProfileAnssiBp28High,
+				XccdfOrgSsgprojectContentProfileAnssiBp28Intermediary,
+				XccdfOrgSsgprojectContentProfileAnssiBp28Minimal,
+				XccdfOrgSsgprojectContentProfileCis,
+				XccdfOrgSsgprojectContentProfileCisServerL1,
+				XccdfOrgSsgprojectContentProfileCisWorkstationL1,
+				XccdfOrgSsgprojectContentProfileCisWorkstationL2,
+				XccdfOrgSsgprojectContentProfileCui,
+				XccdfOrgSsgprojectContentProfileE8,
+				XccdfOrgSsgprojectContentProfileHipaa,
+				XccdfOrgSsgprojectContentProfileIsmO,
+				XccdfOrgSsgprojectContentProfileOspp,
+				XccdfOrgSsgprojectContentProfilePciDss,
+				XccdfOrgSsgprojectContentProfileStig,
+				XccdfOrgSsgprojectContentProfileStigGui,
+			}, result)
+		}
+	})
+
+	t.Run("Access profiles on the other distros returns an error", func(t *testing.T) {
+		for _, dist := range []Distributions{Fedora37, Fedora38, Fedora39, Fedora40, Rhel90} {
+			respStatusCode, _ := tutils.GetResponseBody(t,
+				fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/profiles", dist), &tutils.AuthString0)
+			require.Equal(t, 400, respStatusCode)
+		}
+	})
+}
+
+func TestGetCustomizations(t *testing.T) {
+	srv, tokenSrv := startServer(t, "", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	t.Run("Access all customizations and check that they match", func(t *testing.T) {
+		for _, dist := range []Distributions{
+			Rhel8, Rhel84, Rhel85, Rhel86, Rhel87, Rhel88, Rhel8Nightly, Rhel9, Rhel91, Rhel92, Rhel9Nightly, Centos8, Centos9,
+		} {
+			respStatusCode, body := tutils.GetResponseBody(t,
+				fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/profiles", dist), &tutils.AuthString0)
+			require.Equal(t, 200, respStatusCode)
+			var result DistributionProfileResponse
+			err := json.Unmarshal([]byte(body), &result)
+			require.NoError(t, err)
+			for _, profile := range result {
+				// Get the customization from the API
+				var result Customizations
+				respStatusCode, body := tutils.GetResponseBody(t,
+					fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/%s/customizations", dist, profile), &tutils.AuthString0)
+				require.Equal(t, 200, respStatusCode)
+				err := json.Unmarshal([]byte(body), &result)
+				require.NoError(t, err)
+				// load the corresponding file from the disk
+				require.NoError(t, err)
+				jsonFile, err := os.Open(
+					path.Join(
+						"../../distributions",
+						string(dist),
+						"oscap",
+						string(profile),
+						"customizations.json"))
+				require.NoError(t, err)
+				defer jsonFile.Close()
+				bytes, err := io.ReadAll(jsonFile)
+				require.NoError(t, err)
+				var customizations Customizations
+				err = json.Unmarshal(bytes, &customizations)
+				require.NoError(t, err)
+				// Make sure we get the same result both ways
+				if result.Packages == nil {
+					require.Nil(t, customizations.Packages)
+				} else {
+					require.ElementsMatch(t, *customizations.Packages, *result.Packages)
+				}
+				if result.Filesystem == nil {
+					require.Nil(t, customizations.Filesystem)
+				} else {
+					require.ElementsMatch(t, *customizations.Filesystem, *result.Filesystem)
+				}
+			}
+		}
+	})
+	t.Run("Access customizations on a distro that does not have customizations returns an error", func(t *testing.T) {
+		for _, dist := range []Distributions{Rhel8} {
+			respStatusCode, body := tutils.GetResponseBody(t,
+				fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/profiles", dist), &tutils.AuthString0)
+			require.Equal(t, 200, respStatusCode)
+			var result DistributionProfileResponse
+			err := json.Unmarshal([]byte(body), &result)
+			require.NoError(t, err)
+			for _, profile := range result {
+				respStatusCode, _ := tutils.GetResponseBody(t,
+					fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/%s/customizations", Fedora40, profile), &tutils.AuthString0)
+				require.Equal(t, 400, respStatusCode)
+			}
+		}
+	})
+	t.Run("Access non existing customizations on a distro returns an error", func(t *testing.T) {
+		for _, dist := range []Distributions{Rhel8} {
+			respStatusCode, body := tutils.GetResponseBody(t,
+				fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/profiles", dist), &tutils.AuthString0)
+			require.Equal(t, 200, respStatusCode)
+			var result DistributionProfileResponse
+			err := json.Unmarshal([]byte(body), &result)
+			require.NoError(t, err)
+			respStatusCode, _ = tutils.GetResponseBody(t,
+				fmt.Sprintf("http://localhost:8086/api/image-builder/v1/oscap/%s/%s/customizations", dist, "badprofile"), &tutils.AuthString0)
+			require.Equal(t, 400, respStatusCode)
+		}
+	})
+}
+
+func TestGetUsage(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	srv, tokenSrv := startServerWithCustomDB(t, "", "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	getUsage := func() Usage {
+		respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/usage", &tutils.AuthString0)
+		require.Equal(t, http.StatusOK, respStatusCode)
+		var usage Usage
+		require.NoError(t, json.Unmarshal([]byte(body), &usage))
+		return usage
+	}
+
+	usage := getUsage()
+	require.Nil(t, usage.Quota.BuildsPerDay)
+	require.Nil(t, usage.Quota.ConcurrentBuilds)
+	require.Zero(t, usage.BuildsToday)
+	require.Zero(t, usage.StorageBytes)
+	reset, err := time.Parse(time.RFC3339, usage.Quota.DailyReset)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), reset, 24*time.Hour)
+
+	err = dbase.SetQuota(db.QuotaEntry{OrgId: "000000", BuildsPerDay: common.ToPtr(10), ConcurrentBuilds: common.ToPtr(1)})
+	require.NoError(t, err)
+	finished := uuid.New()
+	err = dbase.InsertCompose(finished, "500000", "user@test.test", "000000", nil, json.RawMessage(`{}`))
+	require.NoError(t, err)
+	_, err = dbase.InsertComposeEvent(finished, "success", nil)
+	require.NoError(t, err)
+	err = dbase.InsertComposeArtifacts(finished, []db.ArtifactEntry{
+		{
+			Filename: "disk.qcow2",
+			Size:     common.ToPtr(int64(1024)),
+			Sha256:   common.ToPtr("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"),
+		},
+	})
+	require.NoError(t, err)
+	err = dbase.InsertCompose(uuid.New(), "500000", "user@test.test", "000000", nil, json.RawMessage(`{}`))
+	require.NoError(t, err)
+	err = dbase.InsertQueuedCompose(uuid.New(), "500000", "user@test.test", "000000", nil, json.RawMessage(`{}`), json.RawMessage(`{}`), false, nil)
+	require.NoError(t, err)
+	// other orgs aren't accounted for
+	err = dbase.InsertCompose(uuid.New(), "500001", "user@test.test", "000001", nil, json.RawMessage(`{}`))
+	require.NoError(t, err)
+
+	usage = getUsage()
+	require.Equal(t, 10, *usage.Quota.BuildsPerDay)
+	require.Nil(t, usage.Quota.BuildsPerMonth)
+	require.Equal(t, 1, *usage.Quota.ConcurrentBuilds)
+	require.Equal(t, 3, usage.BuildsToday)
+	require.Equal(t, 3, usage.BuildsThisMonth)
+	require.Equal(t, 1, usage.BuildsInProgress)
+	require.Equal(t, 1, usage.BuildsQueued)
+	require.Equal(t, int64(1024), usage.StorageBytes)
+}
+
+func TestRecordComposeStatus(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	s := &Server{
+		db: dbase,
+	}
+
+	id := uuid.New()
+	request := json.RawMessage(`{"distribution": "rhel-9", "image_requests": [{"architecture": "x86_64", "image_type": "guest-image", "upload_request": {"type": "aws.s3", "options": {}}}]}`)
+	err = dbase.InsertCompose(id, "500000", "user@test.test", "000000", nil, request)
+	require.NoError(t, err)
+	compose, err := dbase.GetCompose(id, "000000")
+	require.NoError(t, err)
+
+	outcomes := prometheus.ComposeOutcomes.WithLabelValues("rhel-9", "guest-image", "aws.s3", "success")
+	before := testutil.ToFloat64(outcomes)
+
+	// the outcome is only counted once, however often the status is polled
+	for _, status := range []composer.ImageStatusValue{composer.ImageStatusValueBuilding, composer.ImageStatusValueSuccess, composer.ImageStatusValueSuccess} {
+		require.NoError(t, s.recordComposeStatus(*compose, composer.ImageStatus{Status: status}))
+	}
+	require.Equal(t, before+1, testutil.ToFloat64(outcomes))
+	events, err := dbase.GetComposeEvents(id)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+}
+
+func TestEmailComposeFinished(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	mailer := &fakeMailer{sent: make(chan sentEmail, 1)}
+	s := &Server{
+		db:     dbase,
+		mailer: mailer,
+	}
+
+	imageName := "my-image"
+	id := uuid.New()
+	err = dbase.InsertCompose(id, "500000", "user@test.test", "000000", &imageName, json.RawMessage(`{}`))
+	require.NoError(t, err)
+	// composes without an address aren't emailed
+	require.NoError(t, s.emailComposeFinished(id, "000000", "success", nil))
+	require.Empty(t, mailer.sent)
+
+	err = dbase.SetComposeNotifyEmail(id, "user@test.test")
+	require.NoError(t, err)
+	reason := "osbuild failed"
+	require.NoError(t, s.emailComposeFinished(id, "000000", "failure", &reason))
+	require.Equal(t, sentEmail{
+		to:       "user@test.test",
+		template: "compose-failed",
+		data: composeEmail{
+			Name:      imageName,
+			ComposeId: id.String(),
+			Status:    "failure",
+			Reason:    reason,
+		},
+	}, <-mailer.sent)
+}
+
+func TestGetComposeEvents(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+
+	id := uuid.New()
+	err = dbase.InsertCompose(id, "500000", "user@test.test", "000000", nil, json.RawMessage(`{}`))
+	require.NoError(t, err)
+	compose, err := dbase.GetCompose(id, "000000")
+	require.NoError(t, err)
+
+	srv, tokenSrv := startServerWithCustomDB(t, "", "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	s := &Server{
+		db: dbase,
+	}
+	s.recordComposeEvent(id, composeEventCreated, nil)
+	require.NoError(t, s.recordComposeStatus(*compose, composer.ImageStatus{Status: composer.ImageStatusValueBuilding}))
+	require.NoError(t, s.recordComposeStatus(*compose, composer.ImageStatus{
+		Status: composer.ImageStatusValueFailure,
+		Error: &composer.ComposeStatusError{
+			Reason: "osbuild failed",
+		},
+	}))
+
+	respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s/events", id), &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	var events ComposeEvents
+	require.NoError(t, json.Unmarshal([]byte(body), &events))
+	require.Len(t, events.Data, 3)
+	require.Equal(t, "created", events.Data[0].Status)
+	require.Equal(t, "building", events.Data[1].Status)
+	require.Nil(t, events.Data[1].Reason)
+	require.Equal(t, "failure", events.Data[2].Status)
+	require.Equal(t, "osbuild failed", *events.Data[2].Reason)
+
+	// the events of composes of other orgs aren't accessible
+	respStatusCode, _ = tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s/events", id), &tutils.AuthString1)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+}
+
+func TestQueryGraphQL(t *testing.T) {
+	composeId := uuid.New()
+	cloneId := uuid.New()
+	var statusRequests atomic.Int32
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if "Bearer" == r.Header.Get("Authorization") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if strings.HasSuffix(r.URL.Path, fmt.Sprintf("/clones/%v", cloneId)) {
+			var uo composer.CloneStatus_Options
+			require.NoError(t, uo.FromAWSEC2UploadStatus(composer.AWSEC2UploadStatus{
+				Ami:    "ami-1",
+				Region: "us-east-2",
+			}))
+			err := json.NewEncoder(w).Encode(composer.CloneStatus{
+				Options: uo,
+				Status:  composer.Running,
+				Type:    composer.UploadTypesAws,
+			})
+			require.NoError(t, err)
+			return
+		}
+		statusRequests.Add(1)
+		err := json.NewEncoder(w).Encode(composer.ComposeStatus{
+			ImageStatus: composer.ImageStatus{
+				Status: composer.ImageStatusValueBuilding,
+			},
+			Status: composer.ComposeStatusValuePending,
+		})
+		require.NoError(t, err)
+	}))
+	defer apiSrv.Close()
+
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	err = dbase.InsertCompose(composeId, "000000", "user000000@test.test", "000000", common.ToPtr("my-image"), json.RawMessage(`{"distribution": "rhel-9"}`))
+	require.NoError(t, err)
+	err = dbase.InsertClone(composeId, cloneId, json.RawMessage(`{"region": "us-east-2"}`))
+	require.NoError(t, err)
+	srv, tokenSrv := startServerWithCustomDB(t, apiSrv.URL, "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	query := func(auth *string, q string, variables string) GraphQLResponse {
+		params := url.Values{"query": {q}}
+		if variables != "" {
+			params.Set("variables", variables)
+		}
+		u := fmt.Sprintf("http://localhost:8086/api/image-builder/v1/graphql?%s", params.Encode())
+		respStatusCode, body := tutils.GetResponseBody(t, u, auth)
+		require.Equal(t, http.StatusOK, respStatusCode)
+		var resp GraphQLResponse
+		require.NoError(t, json.Unmarshal([]byte(body), &resp))
+		return resp
+	}
+
+	resp := query(&tutils.AuthString0, `query($limit: Int) {
+		composes(limit: $limit) {
+			id
+			name: image_name
+			request
+			status { status }
+			clones { id compose_id status { status type options } }
+		}
+	}`, `{"limit": 10}`)
+	require.Nil(t, resp.Errors)
+	data, err := json.Marshal(resp.Data)
+	require.NoError(t, err)
+	require.JSONEq(t, fmt.Sprintf(`{"composes": [{
+		"id": "%[1]s",
+		"name": "my-image",
+		"request": {"distribution": "rhel-9"},
+		"status": {"status": "building"},
+		"clones": [{
+			"id": "%[2]s",
+			"compose_id": "%[1]s",
+			"status": {"status": "running", "type": "aws", "options": {"ami": "ami-1", "region": "us-east-2"}}
+		}]
+	}]}`, composeId, cloneId), string(data))
+
+	// the status is served from the cache once it's cached
+	resp = query(&tutils.AuthString0, `{ composes { status { status } } again: composes { status { status } } }`, "")
+	require.Nil(t, resp.Errors)
+	require.Equal(t, int32(1), statusRequests.Load())
+
+	// unknown ids resolve to null, bad ones are errors of their field
+	resp = query(&tutils.AuthString0, fmt.Sprintf(`{ clone(id: "%s") { id } compose(id: "%s") { id } bad: compose(id: "x") { id } }`, cloneId, uuid.New()), "")
+	require.Equal(t, cloneId.String(), (*resp.Data)["clone"].(map[string]interface{})["id"])
+	require.Nil(t, (*resp.Data)["compose"])
+	require.Nil(t, (*resp.Data)["bad"])
+	require.Len(t, *resp.Errors, 1)
+	require.Equal(t, []interface{}{"bad"}, *(*resp.Errors)[0].Path)
+
+	// lists count with their limit, a query can't resolve more than 1000 objects
+	resp = query(&tutils.AuthString0, `{ a: composes { id } b: composes { id } c: composes { id } d: composes { id } e: composes { id } f: composes { id }
+		g: composes { id } h: composes { id } i: composes { id } j: composes { id } k: composes { id } }`, "")
+	require.Len(t, *resp.Errors, 1)
+	require.Equal(t, "The query resolves more than 1000 objects", (*resp.Errors)[0].Message)
+	require.Len(t, *(*resp.Errors)[0].Path, 1)
+
+	// the composes of other orgs aren't visible
+	resp = query(&tutils.AuthString1, fmt.Sprintf(`{ composes { id } compose(id: "%s") { id } }`, composeId), "")
+	require.Empty(t, (*resp.Data)["composes"])
+	require.Nil(t, (*resp.Data)["compose"])
+}
+
+func TestGetComposesETag(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	srv, tokenSrv := startServerWithCustomDB(t, "", "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	get := func(ifNoneMatch string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, "http://localhost:8086/api/image-builder/v1/composes", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Rh-Identity", tutils.AuthString0)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := get("")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	etag := resp.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+	require.Equal(t, http.StatusNotModified, get(etag).StatusCode)
+
+	// a new compose changes the tag
+	err = dbase.InsertCompose(uuid.New(), "500000", "user500000@test.test", "000000", nil, json.RawMessage("{}"))
+	require.NoError(t, err)
+	resp = get(etag)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotEqual(t, etag, resp.Header.Get("ETag"))
+}
+
+func TestGetComposeProvenance(t *testing.T) {
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if "Bearer" == r.Header.Get("Authorization") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.HasSuffix(r.URL.Path, "/metadata") {
+			fmt.Fprint(w, `{"status": "pending", "image_status": {"status": "building"}}`)
+			return
+		}
+		err := json.NewEncoder(w).Encode(composer.ComposeMetadata{
+			OstreeCommit: common.ToPtr("b0bca2a4"),
+			Packages: &[]composer.PackageMetadata{
+				{Type: "rpm", Name: "bash", Version: "5.1.8", Release: "6.el9", Arch: "x86_64", Sigmd5: "2ee0ba6e"},
+			},
+		})
+		require.NoError(t, err)
+	}))
+	defer apiSrv.Close()
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "accesstoken"}`)
+	}))
+	defer tokenSrv.Close()
+
+	compClient, err := composer.NewClient(composer.ComposerClientConfig{
+		ComposerURL:  apiSrv.URL,
+		TokenURL:     tokenSrv.URL,
+		ClientId:     "rhsm-api",
+		OfflineToken: "offlinetoken",
+	})
+	require.NoError(t, err)
+	composers, err := composer.NewPool([]composer.Backend{{Name: composer.DefaultBackend, Client: compClient}})
+	require.NoError(t, err)
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	h := &Handlers{
+		server: &Server{
+			cClient:   compClient,
+			composers: composers,
+			db:        dbase,
+		},
+	}
+
+	orgId := "provenance-org"
+	request := json.RawMessage(`{"distribution": "rhel-9"}`)
+	built, building, imported := uuid.New(), uuid.New(), uuid.New()
+	for _, id := range []uuid.UUID{built, building, imported} {
+		require.NoError(t, dbase.InsertCompose(id, "", "user@test.test", orgId, nil, request))
+	}
+	require.NoError(t, dbase.InsertComposeArtifacts(built, []db.ArtifactEntry{
+		{Filename: "disk.qcow2", Size: common.ToPtr(int64(1024)), Sha256: common.ToPtr("9f86d081884c7d65")},
+	}))
+	require.NoError(t, dbase.InsertComposeArtifacts(imported, []db.ArtifactEntry{
+		{Filename: "ami-0c830793775595d4b", CloudImageId: common.ToPtr("ami-0c830793775595d4b")},
+	}))
+	_, err = dbase.InsertComposeEvent(built, string(ImageStatusStatusSuccess), nil)
+	require.NoError(t, err)
+
+	run := func(id uuid.UUID, signed bool) (int, []byte) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), identity.Key, identity.XRHID{
+			Identity: identity.Identity{OrgID: orgId},
+		}))
+		rec := httptest.NewRecorder()
+		err := h.GetComposeProvenance(echo.New().NewContext(req, rec), id, GetComposeProvenanceParams{Signed: &signed})
+		if err != nil {
+			return err.(*echo.HTTPError).Code, nil
+		}
+		return rec.Code, rec.Body.Bytes()
+	}
+
+	code, body := run(built, false)
+	require.Equal(t, http.StatusOK, code)
+	var statement provenanceStatement
+	require.NoError(t, json.Unmarshal(body, &statement))
+	require.Equal(t, inTotoStatementType, statement.Type)
+	require.Equal(t, []ProvenanceSubject{{Name: "disk.qcow2", Digest: map[string]string{"sha256": "9f86d081884c7d65"}}}, statement.Subject)
+	require.Equal(t, slsaProvenanceType, statement.PredicateType)
+	require.Equal(t, DefaultProvenanceBuilderId, statement.Predicate.RunDetails.Builder.Id)
+	require.Equal(t, built.String(), statement.Predicate.RunDetails.Metadata.InvocationId)
+	require.NotNil(t, statement.Predicate.RunDetails.Metadata.FinishedOn)
+	require.Equal(t, "b0bca2a4", statement.Predicate.BuildDefinition.InternalParameters["ostree_commit"])
+	require.Equal(t, map[string]interface{}{"distribution": "rhel-9"}, statement.Predicate.BuildDefinition.ExternalParameters["request"])
+	require.Equal(t, []slsaResource{
+		{URI: "pkg:rpm/bash@5.1.8-6.el9?arch=x86_64", Digest: map[string]string{"md5": "2ee0ba6e"}},
+	}, statement.Predicate.BuildDefinition.ResolvedDependencies)
+
+	// nothing to sign with
+	code, _ = run(built, true)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	h.server.provenance = ProvenanceConfig{BuilderId: "https://builder.example.com", Signer: key}
+	code, body = run(built, true)
+	require.Equal(t, http.StatusOK, code)
+	var envelope signing.Envelope
+	require.NoError(t, json.Unmarshal(body, &envelope))
+	require.Equal(t, inTotoPayloadType, envelope.PayloadType)
+	payload, err := envelope.Open(key.Public())
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(payload, &statement))
+	require.Equal(t, "https://builder.example.com", statement.Predicate.RunDetails.Builder.Id)
+
+	code, _ = run(building, false)
+	require.Equal(t, http.StatusConflict, code)
+	// cloud images have no digest to be the subject of the provenance
+	code, _ = run(imported, false)
+	require.Equal(t, http.StatusConflict, code)
+	code, _ = run(uuid.New(), false)
+	require.Equal(t, http.StatusNotFound, code)
+}
+
+func TestGetComposeSBOM(t *testing.T) {
+	built, building := uuid.New(), uuid.New()
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if "Bearer" == r.Header.Get("Authorization") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(r.URL.Path, building.String()) {
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		err := json.NewEncoder(w).Encode(composer.ComposeMetadata{
+			Packages: &[]composer.PackageMetadata{
+				{Type: "rpm", Name: "bash", Epoch: common.ToPtr("1"), Version: "5.1.8", Release: "6.el9", Arch: "x86_64", Sigmd5: "2ee0ba6e"},
+			},
+		})
+		require.NoError(t, err)
+	}))
+	defer apiSrv.Close()
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "accesstoken"}`)
+	}))
+	defer tokenSrv.Close()
+
+	compClient, err := composer.NewClient(composer.ComposerClientConfig{
+		ComposerURL:  apiSrv.URL,
+		TokenURL:     tokenSrv.URL,
+		ClientId:     "rhsm-api",
+		OfflineToken: "offlinetoken",
+	})
+	require.NoError(t, err)
+	composers, err := composer.NewPool([]composer.Backend{{Name: composer.DefaultBackend, Client: compClient}})
+	require.NoError(t, err)
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	h := &Handlers{
+		server: &Server{
+			cClient:   compClient,
+			composers: composers,
+			db:        dbase,
+		},
+	}
+
+	orgId := "sbom-org"
+	for _, id := range []uuid.UUID{built, building} {
+		require.NoError(t, dbase.InsertCompose(id, "", "user@test.test", orgId, common.ToPtr("my-image"), json.RawMessage(`{}`)))
+	}
+
+	run := func(id uuid.UUID, format *GetComposeSBOMParamsFormat) (int, string, []byte) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), identity.Key, identity.XRHID{
+			Identity: identity.Identity{OrgID: orgId},
+		}))
+		rec := httptest.NewRecorder()
+		err := h.GetComposeSBOM(echo.New().NewContext(req, rec), id, GetComposeSBOMParams{Format: format})
+		if err != nil {
+			return err.(*echo.HTTPError).Code, "", nil
+		}
+		return rec.Code, rec.Header().Get(echo.HeaderContentType), rec.Body.Bytes()
+	}
+
+	code, contentType, spdx := run(built, nil)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "application/spdx+json", contentType)
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(spdx, &doc))
+	require.Equal(t, "SPDX-2.3", doc["spdxVersion"])
+	require.Equal(t, "my-image", doc["name"])
+	require.Equal(t, fmt.Sprintf("%s/composes/%s/sbom", DefaultProvenanceBuilderId, built), doc["documentNamespace"])
+	require.Contains(t, string(spdx), "pkg:rpm/bash@5.1.8-6.el9?arch=x86_64&epoch=1")
+
+	// the stored document is served from then on
+	code, _, again := run(built, common.ToPtr(Spdx))
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, spdx, again)
+
+	code, contentType, cyclonedx := run(built, common.ToPtr(Cyclonedx))
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "application/vnd.cyclonedx+json", contentType)
+	require.NoError(t, json.Unmarshal(cyclonedx, &doc))
+	require.Equal(t, "CycloneDX", doc["bomFormat"])
+	code, _, again = run(built, common.ToPtr(Cyclonedx))
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, cyclonedx, again)
+
+	code, _, _ = run(building, nil)
+	require.Equal(t, http.StatusConflict, code)
+	code, _, _ = run(uuid.New(), nil)
+	require.Equal(t, http.StatusNotFound, code)
+}
+
+func TestCheckRepositorySignatures(t *testing.T) {
+	entity, err := openpgp.NewEntity("Image Builder Test", "", "test@example.com", nil)
+	require.NoError(t, err)
+	var public bytes.Buffer
+	w, err := armor.Encode(&public, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+	repomd := []byte(`<?xml version="1.0" encoding="UTF-8"?><repomd/>`)
+	var signature bytes.Buffer
+	require.NoError(t, openpgp.ArmoredDetachSign(&signature, entity, bytes.NewReader(repomd), nil))
+	repoSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/signed/repodata/repomd.xml", "/unsigned/repodata/repomd.xml":
+			_, _ = w.Write(repomd)
+		case "/signed/repodata/repomd.xml.asc":
+			_, _ = w.Write(signature.Bytes())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer repoSrv.Close()
+	// the public client doesn't connect to the loopback address
+	transport := repoSrv.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, repoSrv.Listener.Addr().String())
+	}
+	repoURL := "http://repo.example.com"
+
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	h := &Handlers{
+		server: &Server{
+			db:        dbase,
+			gpgClient: gpg.NewClient(gpg.Config{PublicTransport: transport}),
+		},
+	}
+	orgId := "repository-signatures-org"
+	newContext := func(body string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(context.WithValue(req.Context(), identity.Key, identity.XRHID{
+			Identity: identity.Identity{
+				OrgID: orgId,
+			},
+		}))
+		rec := httptest.NewRecorder()
+		return echo.New().NewContext(req, rec), rec
+	}
+	check := func(cust Customizations) error {
+		ctx, _ := newContext("")
+		return h.checkRepositorySignatures(ctx, orgId, &cust)
+	}
+
+	unchecked := Customizations{
+		PayloadRepositories: &[]Repository{
+			{Baseurl: common.ToPtr(repoURL + "/unsigned")},
+		},
+	}
+	require.NoError(t, check(unchecked))
+
+	ctx, rec := newContext(`{"require_signatures": true}`)
+	require.NoError(t, h.UpdateRepositorySignatureSettings(ctx))
+	require.JSONEq(t, `{"require_signatures": true}`, rec.Body.String())
+	ctx, rec = newContext("")
+	require.NoError(t, h.GetRepositorySignatureSettings(ctx))
+	require.JSONEq(t, `{"require_signatures": true}`, rec.Body.String())
+
+	require.Equal(t, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Repository %s/unsigned has to set check_gpg and check_repo_gpg", repoURL)), check(unchecked))
+	err = check(Customizations{
+		CustomRepositories: &[]CustomRepository{
+			{Id: "nokey", Baseurl: &[]string{repoURL + "/signed"}, CheckGpg: common.ToPtr(true), CheckRepoGpg: common.ToPtr(true)},
+		},
+	})
+	require.Equal(t, echo.NewHTTPError(http.StatusBadRequest, "Repository nokey needs a gpg key"), err)
+	err = check(Customizations{
+		CustomRepositories: &[]CustomRepository{
+			{Id: "metalink", Metalink: common.ToPtr(repoURL + "/metalink"), Gpgkey: &[]string{public.String()}, CheckGpg: common.ToPtr(true), CheckRepoGpg: common.ToPtr(true)},
+		},
+	})
+	require.Equal(t, echo.NewHTTPError(http.StatusBadRequest, "Repository metalink needs a baseurl to verify its metadata"), err)
+	err = check(Customizations{
+		PayloadRepositories: &[]Repository{
+			{Baseurl: common.ToPtr(repoURL + "/unsigned"), Gpgkey: common.ToPtr(public.String()), CheckGpg: common.ToPtr(true), CheckRepoGpg: common.ToPtr(true)},
+		},
+	})
+	require.Equal(t, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Repository %s/unsigned can't be verified with its gpg keys", repoURL)), err)
+	// why downloading failed isn't told, or internal hosts could be probed
+	err = check(Customizations{
+		PayloadRepositories: &[]Repository{
+			{Baseurl: common.ToPtr(repoSrv.URL + "/signed"), Gpgkey: common.ToPtr(public.String()), CheckGpg: common.ToPtr(true), CheckRepoGpg: common.ToPtr(true)},
+		},
+	})
+	require.Equal(t, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Repository %s/signed can't be verified with its gpg keys", repoSrv.URL)), err)
+
+	require.NoError(t, check(Customizations{
+		PayloadRepositories: &[]Repository{
+			{Baseurl: common.ToPtr(repoURL + "/signed"), Gpgkey: common.ToPtr(public.String()), CheckGpg: common.ToPtr(true), CheckRepoGpg: common.ToPtr(true)},
+			// red hat repositories aren't checked
+			{Baseurl: common.ToPtr("https://cdn.redhat.com/content/dist/rhel9"), Rhsm: true},
+		},
+		CustomRepositories: &[]CustomRepository{
+			{Id: "signed", Baseurl: &[]string{repoURL + "/signed/"}, Gpgkey: &[]string{public.String()}, CheckGpg: common.ToPtr(true), CheckRepoGpg: common.ToPtr(true)},
+		},
+	}))
+}
+
+// callHandler calls a handler directly with a json body, as a user of an
+// org. Errors are returned as their code and message.
+func callHandler(orgId string, handler func(echo.Context) error, body string) (int, string) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), identity.Key, identity.XRHID{
+		Identity: identity.Identity{
+			OrgID: orgId,
+		},
+	}))
+	rec := httptest.NewRecorder()
+	err := handler(echo.New().NewContext(req, rec))
+	if err != nil {
+		he := err.(*echo.HTTPError)
+		return he.Code, fmt.Sprintf("%v", he.Message)
+	}
+	return rec.Code, rec.Body.String()
+}
+
+func TestSigningKeys(t *testing.T) {
+	entity, err := openpgp.NewEntity("Image Builder Test", "", "test@example.com", nil)
+	require.NoError(t, err)
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.SerializePrivate(w, nil))
+	require.NoError(t, w.Close())
+	keyring := openpgp.EntityList{entity}
+
+	content := []byte("a qcow2 image")
+	sum := sha256.Sum256(content)
+	downloads := 0
+	s3Srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		_, _ = w.Write(content)
+	}))
+	defer s3Srv.Close()
+
+	keys := testKeystore(t)
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	s := &Server{
+		db:        dbase,
+		gpgClient: gpg.NewClient(gpg.Config{}),
+		keystore:  keys,
+		spec:      &openapi3.T{Info: &openapi3.Info{Version: "1.0"}},
+	}
+	h := &Handlers{server: s}
+
+	run := func(handler func(echo.Context) error, body string) (int, string) {
+		return callHandler("signing-keys-org", handler, body)
+	}
+	orgId := "signing-keys-org"
+	privateKey, err := json.Marshal(armored.String())
+	require.NoError(t, err)
+
+	for body, msg := range map[string]string{
+		`{"purpose": "artifact", "secret": "0123456789abcdef"}`:                  "A artifact key has to be a private key",
+		`{"purpose": "artifact", "private_key": "not a key"}`:                    "Invalid private key: reading the key failed",
+		`{"purpose": "webhook", "secret": "short"}`:                              "The secret has to be at least 16 characters long",
+		fmt.Sprintf(`{"purpose": "webhook", "private_key": %s}`, privateKey):     "A webhook key has to be a secret",
+		fmt.Sprintf(`{"purpose": "certificate", "private_key": %s}`, privateKey): `Unknown purpose "certificate"`,
+	} {
+		code, resp := run(h.CreateSigningKey, body)
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, resp, msg)
+	}
+
+	code, body := run(h.CreateSigningKey, fmt.Sprintf(`{"purpose": "artifact", "private_key": %s}`, privateKey))
+	require.Equal(t, http.StatusCreated, code)
+	require.NotContains(t, body, "PRIVATE KEY")
+	var artifactKey SigningKey
+	require.NoError(t, json.Unmarshal([]byte(body), &artifactKey))
+	require.Equal(t, InUse, artifactKey.Status)
+	require.Equal(t, fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint), *artifactKey.Fingerprint)
+	code, _ = run(h.CreateSigningKey, fmt.Sprintf(`{"purpose": "artifact", "private_key": %s}`, privateKey))
+	require.Equal(t, http.StatusConflict, code)
+
+	// only the envelope is stored
+	stored, err := dbase.GetSigningKey(artifactKey.Id, orgId)
+	require.NoError(t, err)
+	require.Equal(t, "kek-1", stored.KEKId)
+	require.NotContains(t, string(stored.Ciphertext), "PRIVATE KEY")
+
+	// artifacts and commits are signed with the artifact key
+	var us UploadStatus
+	us.Type = UploadTypesAwsS3
+	us.Status = UploadStatusStatusSuccess
+	require.NoError(t, us.Options.FromAWSS3UploadStatus(AWSS3UploadStatus{Url: s3Srv.URL}))
+	composeId := uuid.New()
+	require.NoError(t, dbase.InsertCompose(composeId, "500000", "user500000@test.test", orgId, nil, json.RawMessage(`{}`)))
+	require.NoError(t, dbase.InsertComposeArtifacts(composeId, []db.ArtifactEntry{
+		{Filename: "disk.qcow2", Size: common.ToPtr(int64(len(content))), Sha256: common.ToPtr(hex.EncodeToString(sum[:]))},
+	}))
+	require.NoError(t, s.signArtifacts(outboxEvent{composeEventData: composeEventData{ComposeId: composeId, OrgId: orgId, Status: "success"}, UploadStatus: &us}))
+	artifacts, err := dbase.GetComposeArtifacts(composeId, orgId)
+	require.NoError(t, err)
+	_, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(content), strings.NewReader(*artifacts[0].Signature), nil)
+	require.NoError(t, err)
+	commitKey, err := s.commitSigningKey(orgId)
+	require.NoError(t, err)
+	require.Equal(t, artifactKey.Id, *commitKey.id)
+
+	// artifacts which are measured are signed in the same download
+	downloads = 0
+	key, err := s.managedGPGKey(orgId, SigningKeyPurposeArtifact)
+	require.NoError(t, err)
+	measured, err := s.downloadArtifact(context.Background(), s3Srv.URL+"/disk.qcow2", key)
+	require.NoError(t, err)
+	require.Equal(t, 1, downloads)
+	require.Equal(t, int64(len(content)), *measured.Size)
+	require.Equal(t, hex.EncodeToString(sum[:]), *measured.Sha256)
+	_, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(content), strings.NewReader(*measured.Signature), nil)
+	require.NoError(t, err)
+	measured, err = s.downloadArtifact(context.Background(), s3Srv.URL+"/image.raw", key)
+	require.NoError(t, err)
+	require.Nil(t, measured.Signature)
+
+	code, body = run(func(ctx echo.Context) error {
+		return h.GetSigningKeyUsage(ctx, artifactKey.Id, GetSigningKeyUsageParams{})
+	}, "")
+	require.Equal(t, http.StatusOK, code)
+	var usage SigningKeyUsageResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &usage))
+	require.Equal(t, 1, usage.Meta.Count)
+	require.Equal(t, SignArtifact, usage.Data[0].Operation)
+	require.Equal(t, composeId, *usage.Data[0].ResourceId)
+
+	// rotated keys aren't used anymore
+	code, body = run(func(ctx echo.Context) error { return h.RotateSigningKey(ctx, artifactKey.Id) }, fmt.Sprintf(`{"private_key": %s}`, privateKey))
+	require.Equal(t, http.StatusCreated, code)
+	var rotated SigningKey
+	require.NoError(t, json.Unmarshal([]byte(body), &rotated))
+	require.Equal(t, SigningKeyPurposeArtifact, rotated.Purpose)
+	require.NotEqual(t, artifactKey.Id, rotated.Id)
+	code, _ = run(func(ctx echo.Context) error { return h.RotateSigningKey(ctx, artifactKey.Id) }, fmt.Sprintf(`{"private_key": %s}`, privateKey))
+	require.Equal(t, http.StatusConflict, code)
+	code, body = run(func(ctx echo.Context) error { return h.GetSigningKey(ctx, artifactKey.Id) }, "")
+	require.Equal(t, http.StatusOK, code)
+	require.NoError(t, json.Unmarshal([]byte(body), &artifactKey))
+	require.Equal(t, Rotated, artifactKey.Status)
+	require.Equal(t, rotated.Id, *artifactKey.ReplacedBy)
+	commitKey, err = s.commitSigningKey(orgId)
+	require.NoError(t, err)
+	require.Equal(t, rotated.Id, *commitKey.id)
+
+	// revoked keys neither
+	code, body = run(func(ctx echo.Context) error { return h.RevokeSigningKey(ctx, rotated.Id) }, "")
+	require.Equal(t, http.StatusOK, code)
+	require.NoError(t, json.Unmarshal([]byte(body), &rotated))
+	require.Equal(t, Revoked, rotated.Status)
+	code, _ = run(func(ctx echo.Context) error { return h.RevokeSigningKey(ctx, rotated.Id) }, "")
+	require.Equal(t, http.StatusConflict, code)
+	commitKey, err = s.commitSigningKey(orgId)
+	require.NoError(t, err)
+	require.Nil(t, commitKey)
+
+	code, _ = run(h.CreateSigningKey, `{"purpose": "webhook", "secret": "0123456789abcdef"}`)
+	require.Equal(t, http.StatusCreated, code)
+	webhookKey, err := s.webhookKey(orgId)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789abcdef", webhookKey.secret)
+
+	code, body = run(h.GetSigningKeys, "")
+	require.Equal(t, http.StatusOK, code)
+	var list SigningKeysResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &list))
+	require.Len(t, list.Data, 3)
+	require.Equal(t, SigningKeyPurposeWebhook, list.Data[0].Purpose)
+
+	code, _ = run(func(ctx echo.Context) error { return h.GetSigningKey(ctx, uuid.New()) }, "")
+	require.Equal(t, http.StatusNotFound, code)
+	h.server.keystore = nil
+	code, _ = run(h.CreateSigningKey, `{"purpose": "webhook", "secret": "0123456789abcdef"}`)
+	require.Equal(t, http.StatusNotImplemented, code)
+}
+
+type memoryObjectStore struct {
+	objects map[string][]byte
+	err     error
+}
+
+func (m *memoryObjectStore) PutObject(ctx context.Context, region, bucket, key string, body []byte, contentType string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.objects[fmt.Sprintf("%s/%s/%s", region, bucket, key)] = body
+	return nil
+}
+
+func (m *memoryObjectStore) GetObject(ctx context.Context, region, bucket, key string) ([]byte, error) {
+	body, ok := m.objects[fmt.Sprintf("%s/%s/%s", region, bucket, key)]
+	if !ok {
+		return nil, fmt.Errorf("getting %s from bucket %s failed with NoSuchKey", key, bucket)
+	}
+	return body, nil
+}
+
+func TestComplianceExport(t *testing.T) {
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "accesstoken"}`)
+	}))
+	defer tokenSrv.Close()
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, strings.HasSuffix(r.URL.Path, "/metadata"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"packages": [{"type": "rpm", "name": "bash", "version": "5.1.8", "release": "6.el9", "arch": "x86_64", "sigmd5": "abc"}]}`)
+	}))
+	defer apiSrv.Close()
+	compClient, err := composer.NewClient(composer.ComposerClientConfig{
+		ComposerURL:  apiSrv.URL,
+		TokenURL:     tokenSrv.URL,
+		ClientId:     "rhsm-api",
+		OfflineToken: "offlinetoken",
+	})
+	require.NoError(t, err)
+	composers, err := composer.NewPool([]composer.Backend{{Name: composer.DefaultBackend, Client: compClient}})
+	require.NoError(t, err)
+
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	store := &memoryObjectStore{objects: map[string][]byte{}}
+	s := &Server{
+		db:        dbase,
+		composers: composers,
+		spec:      &openapi3.T{Info: &openapi3.Info{Version: "1.0"}},
+		complianceExport: ComplianceExportConfig{
+			Store:  store,
+			Window: time.Hour,
+		},
+	}
+	h := &Handlers{server: s}
+	orgId := "compliance-export-org"
+	run := func(handler func(echo.Context) error, body string) (int, string) {
+		return callHandler(orgId, handler, body)
+	}
+
+	code, _ := run(h.GetComplianceExportSettings, "")
+	require.Equal(t, http.StatusNotFound, code)
+	for body, msg := range map[string]string{
+		`{"bucket": "evidence.example.com", "region": "us-east-1"}`:                  "bucket names with dots aren't supported",
+		`{"bucket": "evidence", "region": "us east"}`:                                `Invalid region "us east"`,
+		`{"bucket": "evidence", "region": "us-east-1", "prefix": "/image-builder/"}`: "can't start with a slash",
+		`{"bucket": "evidence", "region": "us-east-1", "prefix": "image builder/"}`:  "The prefix can only contain",
+	} {
+		code, resp := run(h.UpdateComplianceExportSettings, body)
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, resp, msg)
+	}
+	code, body := run(h.UpdateComplianceExportSettings, `{"bucket": "evidence", "region": "eu-west-1", "prefix": "image-builder/"}`)
+	require.Equal(t, http.StatusOK, code)
+	var settings ComplianceExportSettings
+	require.NoError(t, json.Unmarshal([]byte(body), &settings))
+	require.Equal(t, "image-builder/", settings.Prefix)
+	require.Equal(t, "image-builder/image-builder-verification", settings.VerificationKey)
+	require.NotEmpty(t, settings.VerificationToken)
+	// the token is kept while the bucket and prefix stay the same
+	code, body = run(h.UpdateComplianceExportSettings, `{"bucket": "evidence", "region": "eu-west-1", "prefix": "image-builder/"}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, settings.VerificationToken)
+
+	for _, o := range []string{orgId, "other-org"} {
+		require.NoError(t, dbase.InsertAuditLogEntry(db.AuditLogEntry{OrgId: o, UserId: "user", Email: "user@example.com", Method: "POST", Path: "/compose", RequestDigest: "abc", Status: 201, RemoteIP: "10.0.0.1"}))
+	}
+	composeId := uuid.New()
+	require.NoError(t, dbase.InsertCompose(composeId, "500000", "user500000@test.test", orgId, nil, json.RawMessage(`{"distribution": "rhel-9"}`)))
+	require.NoError(t, dbase.InsertComposeArtifacts(composeId, []db.ArtifactEntry{{Filename: "disk.qcow2", Size: common.ToPtr(int64(1)), Sha256: common.ToPtr("0123")}}))
+	_, err = dbase.InsertComposeEvent(composeId, "success", nil)
+	require.NoError(t, err)
+
+	// nothing is exported before the bucket was verified
+	entry, err := dbase.GetComplianceExportSettings(orgId)
+	require.NoError(t, err)
+	require.ErrorContains(t, s.verifyComplianceBucket(context.Background(), *entry), "NoSuchKey")
+	verification := "eu-west-1/evidence/image-builder/image-builder-verification"
+	store.objects[verification] = []byte(settings.VerificationToken + "\n")
+	require.NoError(t, s.verifyComplianceBucket(context.Background(), *entry))
+	// another org naming the bucket can't have the service write into it
+	code, _ = callHandler("other-org", h.UpdateComplianceExportSettings, `{"bucket": "evidence", "region": "eu-west-1", "prefix": "image-builder/"}`)
+	require.Equal(t, http.StatusOK, code)
+	other, err := dbase.GetComplianceExportSettings("other-org")
+	require.NoError(t, err)
+	require.NotEqual(t, settings.VerificationToken, other.VerificationToken)
+	require.ErrorContains(t, s.verifyComplianceBucket(context.Background(), *other), "doesn't hold the verification token")
+
+	// windows are only exported once they passed
+	now := time.Now().UTC()
+	s.exportCompliance(now)
+	require.Len(t, store.objects, 1)
+	s.exportCompliance(now.Add(3 * time.Hour))
+	delete(store.objects, verification)
+	otherExports, _, err := dbase.GetComplianceExports("other-org", 100, 0)
+	require.NoError(t, err)
+	require.Len(t, otherExports, 1)
+	require.Contains(t, *otherExports[0].LastError, "doesn't hold the verification token")
+	code, body = run(func(ctx echo.Context) error { return h.GetComplianceExports(ctx, GetComplianceExportsParams{}) }, "")
+	require.Equal(t, http.StatusOK, code)
+	var exports ComplianceExportsResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &exports))
+	require.GreaterOrEqual(t, exports.Meta.Count, 3)
+	require.Len(t, store.objects, 3*exports.Meta.Count)
+
+	auditLogEntries, composes := 0, 0
+	for _, e := range exports.Data {
+		require.Equal(t, ComplianceExportStatusExported, e.Status)
+		auditLogEntries += e.AuditLogEntries
+		composes += e.Composes
+
+		start, err := time.Parse(time.RFC3339, e.WindowStart)
+		require.NoError(t, err)
+		end, err := time.Parse(time.RFC3339, e.WindowEnd)
+		require.NoError(t, err)
+		require.Equal(t, time.Hour, end.Sub(start))
+		prefix := fmt.Sprintf("eu-west-1/evidence/image-builder/%s_%s/", start.Format("20060102T150405Z"), end.Format("20060102T150405Z"))
+		var manifest complianceExportManifest
+		require.NoError(t, json.Unmarshal(store.objects[prefix+"manifest.json"], &manifest))
+		require.Equal(t, 1, manifest.SchemaVersion)
+		require.Equal(t, orgId, manifest.OrgId)
+		require.Len(t, manifest.Objects, 2)
+		for _, o := range manifest.Objects {
+			object := store.objects["eu-west-1/evidence/"+o.Key]
+			sum := sha256.Sum256(object)
+			require.Equal(t, hex.EncodeToString(sum[:]), o.Sha256)
+			require.Equal(t, len(object), o.Size)
+			require.Equal(t, o.Records, bytes.Count(object, []byte("\n")))
+		}
+		if e.Composes == 1 {
+			var provenance complianceProvenance
+			require.NoError(t, json.Unmarshal(store.objects[prefix+"provenance.jsonl"], &provenance))
+			require.Equal(t, composeId, provenance.ComposeId)
+			require.Equal(t, "disk.qcow2", provenance.Statement.Subject[0].Name)
+			require.Equal(t, "pkg:rpm/bash@5.1.8-6.el9?arch=x86_64", provenance.Statement.Predicate.BuildDefinition.ResolvedDependencies[0].URI)
+		}
+		if e.AuditLogEntries == 1 {
+			var entry AuditLogEntry
+			require.NoError(t, json.Unmarshal(store.objects[prefix+"audit-log.jsonl"], &entry))
+			require.Equal(t, "/compose", entry.Path)
+		}
+	}
+	require.Equal(t, 1, auditLogEntries)
+	require.Equal(t, 1, composes)
+
+	// failed windows are retried before the ones after them
+	store.objects[verification] = []byte(settings.VerificationToken)
+	store.err = fmt.Errorf("access denied")
+	s.exportCompliance(now.Add(6 * time.Hour))
+	code, body = run(func(ctx echo.Context) error { return h.GetComplianceExports(ctx, GetComplianceExportsParams{}) }, "")
+	require.Equal(t, http.StatusOK, code)
+	count := exports.Meta.Count
+	require.NoError(t, json.Unmarshal([]byte(body), &exports))
+	require.Equal(t, count+1, exports.Meta.Count)
+	require.Equal(t, ComplianceExportStatusPending, exports.Data[0].Status)
+	require.Equal(t, "access denied", *exports.Data[0].Error)
+	require.Equal(t, 1, exports.Data[0].Attempts)
+	store.err = nil
+	s.exportCompliance(now.Add(6 * time.Hour))
+	_, count, err = dbase.GetComplianceExports(orgId, 100, 0)
+	require.NoError(t, err)
+	require.Equal(t, exports.Meta.Count, count)
+
+	code, _ = run(h.DeleteComplianceExportSettings, "")
+	require.Equal(t, http.StatusOK, code)
+	code, _ = run(h.DeleteComplianceExportSettings, "")
+	require.Equal(t, http.StatusNotFound, code)
+	s.complianceExport.Store = nil
+	code, _ = run(h.UpdateComplianceExportSettings, `{"bucket": "evidence", "region": "eu-west-1"}`)
+	require.Equal(t, http.StatusNotImplemented, code)
+}
+
+func TestPulpUploadOptions(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	h := &Handlers{
+		server: &Server{
+			db: dbase,
+		},
+	}
+	newContext := func(body string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(context.WithValue(req.Context(), identity.Key, identity.XRHID{
+			Identity: identity.Identity{
+				OrgID: "pulp-org",
+			},
+		}))
+		rec := httptest.NewRecorder()
+		return echo.New().NewContext(req, rec), rec
+	}
+	options := func(uo PulpOSTreeUploadRequestOptions) (*composer.PulpOSTreeUploadOptions, error) {
+		ctx, _ := newContext("")
+		return h.pulpUploadOptions(ctx, uo)
+	}
+
+	_, err = options(PulpOSTreeUploadRequestOptions{})
+	require.Equal(t, echo.NewHTTPError(http.StatusBadRequest, "The pulp.ostree upload target requires a basepath, in the request or the pulp settings"), err)
+	// without a server address composer uses the one it is configured with
+	uo, err := options(PulpOSTreeUploadRequestOptions{Basepath: common.ToPtr("edge")})
+	require.NoError(t, err)
+	require.Equal(t, composer.PulpOSTreeUploadOptions{Basepath: "edge"}, *uo)
+
+	ctx, _ := newContext(`{"server_address": "http://pulp.example.com"}`)
+	require.Equal(t, echo.NewHTTPError(http.StatusBadRequest, "The pulp server address has to be an absolute https url"), h.UpdatePulpSettings(ctx))
+	ctx, rec := newContext(`{"server_address": "https://pulp.example.com", "repository": "edge", "basepath": "edge/rhel-9"}`)
+	require.NoError(t, h.UpdatePulpSettings(ctx))
+	var settings PulpSettings
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &settings))
+	require.Equal(t, "https://pulp.example.com", settings.ServerAddress)
+	require.Equal(t, "edge/rhel-9", *settings.Basepath)
+
+	uo, err = options(PulpOSTreeUploadRequestOptions{})
+	require.NoError(t, err)
+	require.Equal(t, composer.PulpOSTreeUploadOptions{
+		ServerAddress: common.ToPtr("https://pulp.example.com"),
+		Repository:    common.ToPtr("edge"),
+		Basepath:      "edge/rhel-9",
+	}, *uo)
+	uo, err = options(PulpOSTreeUploadRequestOptions{Basepath: common.ToPtr("edge/centos-9")})
+	require.NoError(t, err)
+	require.Equal(t, "edge/centos-9", uo.Basepath)
+	require.Equal(t, common.ToPtr("edge"), uo.Repository)
+
+	// other servers don't get the repository and basepath of the configured one
+	_, err = options(PulpOSTreeUploadRequestOptions{ServerAddress: common.ToPtr("https://other.example.com")})
+	require.Error(t, err)
+	uo, err = options(PulpOSTreeUploadRequestOptions{ServerAddress: common.ToPtr("https://other.example.com"), Basepath: common.ToPtr("edge")})
+	require.NoError(t, err)
+	require.Nil(t, uo.Repository)
+	_, err = options(PulpOSTreeUploadRequestOptions{ServerAddress: common.ToPtr("http://other.example.com"), Basepath: common.ToPtr("edge")})
+	require.Equal(t, echo.NewHTTPError(http.StatusBadRequest, "The pulp server address has to be an absolute https url"), err)
+
+	ctx, _ = newContext("")
+	require.NoError(t, h.DeletePulpSettings(ctx))
+	ctx, _ = newContext("")
+	require.Equal(t, echo.NewHTTPError(http.StatusNotFound, "No Pulp instance is configured"), h.GetPulpSettings(ctx))
 }
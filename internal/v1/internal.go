@@ -0,0 +1,270 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/prometheus"
+)
+
+type SupportCompose struct {
+	Id            uuid.UUID             `json:"id"`
+	OrgId         string                `json:"org_id"`
+	AccountNumber string                `json:"account_number,omitempty"`
+	Email         *string               `json:"email,omitempty"`
+	ImageName     *string               `json:"image_name,omitempty"`
+	CreatedAt     string                `json:"created_at"`
+	Deleted       bool                  `json:"deleted"`
+	Request       json.RawMessage       `json:"request"`
+	Clones        []SupportClone        `json:"clones"`
+	StatusHistory []SupportComposeEvent `json:"status_history"`
+	ComposerJob   SupportComposerJob    `json:"composer_job"`
+}
+
+// SupportComposerJob is the job of a compose in composer. The status is the
+// one composer returns, or the one it last reported to the service if
+// composer can't be asked, in which case the error says why.
+type SupportComposerJob struct {
+	Id                uuid.UUID       `json:"id"`
+	Backend           string          `json:"backend,omitempty"`
+	Status            json.RawMessage `json:"status,omitempty"`
+	StatusRefreshedAt string          `json:"status_refreshed_at,omitempty"`
+	Error             string          `json:"error,omitempty"`
+}
+
+// SupportComposeSummary is a compose found by a search.
+type SupportComposeSummary struct {
+	Id        uuid.UUID `json:"id"`
+	OrgId     string    `json:"org_id"`
+	ImageName *string   `json:"image_name,omitempty"`
+	CreatedAt string    `json:"created_at"`
+	Deleted   bool      `json:"deleted"`
+}
+
+type SupportComposesResponse struct {
+	Data []SupportComposeSummary `json:"data"`
+}
+
+type SupportClone struct {
+	Id        uuid.UUID       `json:"id"`
+	CreatedAt string          `json:"created_at"`
+	Request   json.RawMessage `json:"request"`
+}
+
+type SupportComposeEvent struct {
+	Status    string  `json:"status"`
+	Reason    *string `json:"reason,omitempty"`
+	CreatedAt string  `json:"created_at"`
+}
+
+// attachInternal registers the support tooling endpoints. They aren't part of
+// the public api, turnpike forwards requests of associates to them.
+func (s *Server) attachInternal(h *Handlers) {
+	g := s.echo.Group(fmt.Sprintf("%s/internal", RoutePrefix()),
+		prometheus.StatusMiddleware,
+		s.authenticate,
+		onlyAssociateAccounts,
+		s.recordAuditLog,
+	)
+	g.GET("/analytics", h.GetSupportAnalytics)
+	g.GET("/audit", h.ExportSupportAuditLog)
+	g.GET("/composer/queues", h.GetSupportComposerQueues)
+	g.GET("/composes", h.SearchSupportComposes)
+	g.GET("/composes/:composeId", h.GetSupportCompose)
+	g.GET("/log-levels", h.GetSupportLogLevels)
+	g.PUT("/log-levels", h.UpdateSupportLogLevels)
+	g.GET("/quotas/:orgId", h.GetSupportQuota)
+	g.PUT("/quotas/:orgId", h.UpdateSupportQuota)
+	g.GET("/quotas/:orgId/boosts", h.GetSupportQuotaBoosts)
+	g.POST("/quotas/:orgId/boosts", h.CreateSupportQuotaBoost)
+	g.DELETE("/quotas/:orgId/boosts/:boostId", h.DeleteSupportQuotaBoost)
+	g.GET("/reports/usage", h.GetSupportUsageReport)
+}
+
+func onlyAssociateAccounts(nextHandler echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		idh, err := getIdentityHeader(ctx)
+		if err != nil {
+			return err
+		}
+
+		if idh.Identity.Type != "Associate" {
+			return auditRejection(ctx, reasonUnsupportedAccount, echo.NewHTTPError(http.StatusForbidden, "Internal endpoints are only accessible to associates"))
+		}
+
+		return nextHandler(ctx)
+	}
+}
+
+// GetSupportCompose looks up a compose regardless of the org it belongs to.
+func (h *Handlers) GetSupportCompose(ctx echo.Context) error {
+	composeId, err := uuid.Parse(ctx.Param("composeId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid compose id")
+	}
+
+	idh, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+	ctx.Logger().Infof("Associate %s looked up compose %v", idh.Identity.Associate.Email, composeId)
+
+	compose, err := h.server.db.GetComposeForSupport(composeId)
+	if err != nil {
+		if errors.Is(err, db.ComposeNotFoundError) {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	cloneEntries, _, err := h.server.db.GetClonesForCompose(composeId, compose.OrgId, 100, 0)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	clones := []SupportClone{}
+	for _, c := range cloneEntries {
+		clones = append(clones, SupportClone{
+			Id:        c.Id,
+			CreatedAt: c.CreatedAt.Format(time.RFC3339),
+			Request:   c.Request,
+		})
+	}
+
+	eventEntries, err := h.server.db.GetComposeEvents(composeId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	events := []SupportComposeEvent{}
+	for _, e := range eventEntries {
+		events = append(events, SupportComposeEvent{
+			Status:    e.Status,
+			Reason:    e.Reason,
+			CreatedAt: e.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, SupportCompose{
+		Id:            compose.Id,
+		OrgId:         compose.OrgId,
+		AccountNumber: compose.AccountNumber,
+		Email:         compose.Email,
+		ImageName:     compose.ImageName,
+		CreatedAt:     compose.CreatedAt.Format(time.RFC3339),
+		Deleted:       compose.Deleted,
+		Request:       compose.Request,
+		Clones:        clones,
+		StatusHistory: events,
+		ComposerJob:   h.supportComposerJob(ctx, &compose.ComposeEntry),
+	})
+}
+
+func (h *Handlers) supportComposerJob(ctx echo.Context, compose *db.ComposeEntry) SupportComposerJob {
+	job := SupportComposerJob{
+		Id:      compose.ComposerId,
+		Backend: compose.ComposerBackend,
+	}
+
+	status, err := h.composerJobStatus(ctx, compose)
+	if err == nil {
+		job.Status = status
+		return job
+	}
+	ctx.Logger().Warnf("Unable to get the status of compose %v from composer: %v", compose.Id, err)
+	job.Error = err.Error()
+	cached, err := h.server.db.GetCachedComposeStatus(compose.Id, 0)
+	if err == nil {
+		job.Status = cached.Status
+		job.StatusRefreshedAt = cached.RefreshedAt.Format(time.RFC3339)
+	} else if !errors.Is(err, db.ComposeStatusNotFoundError) {
+		ctx.Logger().Errorf("Unable to get the cached status of compose %v: %v", compose.Id, err)
+	}
+	return job
+}
+
+// composerJobStatus returns the status of the job as composer returns it.
+func (h *Handlers) composerJobStatus(ctx echo.Context, compose *db.ComposeEntry) (json.RawMessage, error) {
+	cc, err := h.server.composerOf(compose)
+	if err != nil {
+		return nil, fmt.Errorf("the composer backend %q is not available", compose.ComposerBackend)
+	}
+	resp, err := cc.ComposeStatus(ctx.Request().Context(), compose.ComposerId)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp.Body)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("composer responded with %d: %s", resp.StatusCode, body)
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("composer responded with invalid json")
+	}
+	return body, nil
+}
+
+var amiRegex = regexp.MustCompile(`^ami-[0-9a-f]{8,17}$`)
+
+// maximum number of composes a search returns
+const supportSearchLimit = 100
+
+// SearchSupportComposes finds the composes of any org which were uploaded as
+// an ami or have an image name.
+func (h *Handlers) SearchSupportComposes(ctx echo.Context) error {
+	var ami, imageName *string
+	if v := ctx.QueryParam("ami"); v != "" {
+		if !amiRegex.MatchString(v) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid ami id")
+		}
+		ami = &v
+	}
+	if v := ctx.QueryParam("image_name"); v != "" {
+		imageName = &v
+	}
+	if ami == nil && imageName == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Search by ami or image_name")
+	}
+	limit := supportSearchLimit
+	if v := ctx.QueryParam("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 || l > supportSearchLimit {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("The limit must be between 1 and %d", supportSearchLimit))
+		}
+		limit = l
+	}
+
+	idh, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+	ctx.Logger().Infof("Associate %s searched composes by ami %q and image name %q", idh.Identity.Associate.Email,
+		ctx.QueryParam("ami"), ctx.QueryParam("image_name"))
+
+	composes, err := h.server.db.SearchComposesForSupport(ami, imageName, limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	data := []SupportComposeSummary{}
+	for _, c := range composes {
+		data = append(data, SupportComposeSummary{
+			Id:        c.Id,
+			OrgId:     c.OrgId,
+			ImageName: c.ImageName,
+			CreatedAt: c.CreatedAt.Format(time.RFC3339),
+			Deleted:   c.Deleted,
+		})
+	}
+	return ctx.JSON(http.StatusOK, SupportComposesResponse{Data: data})
+}
@@ -0,0 +1,474 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/pkg/tutils"
+)
+
+func TestGetSupportCompose(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	composeId := uuid.New()
+	err = dbase.InsertCompose(composeId, "500000", "user500000@test.test", "500000", nil, json.RawMessage(`{"image_requests": []}`))
+	require.NoError(t, err)
+	_, err = dbase.InsertComposeEvent(composeId, "building", nil)
+	require.NoError(t, err)
+	_, err = dbase.InsertComposeEvent(composeId, "success", nil)
+	require.NoError(t, err)
+	err = dbase.SetCachedComposeStatus(composeId, json.RawMessage(`{"status": "success", "image_status": {"status": "success"}}`))
+	require.NoError(t, err)
+
+	srv, tokenSrv := startServerWithCustomDB(t, "", "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	associate := base64.StdEncoding.EncodeToString([]byte(`{"identity": {"type": "Associate", "associate": {"email": "support@example.com", "Role": ["image-builder-support"]}}}`))
+
+	respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/internal/composes/%s", composeId), &associate)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	var result SupportCompose
+	err = json.Unmarshal([]byte(body), &result)
+	require.NoError(t, err)
+	require.Equal(t, composeId, result.Id)
+	require.Equal(t, "500000", result.OrgId)
+	require.Equal(t, "user500000@test.test", *result.Email)
+	require.JSONEq(t, `{"image_requests": []}`, string(result.Request))
+	require.Empty(t, result.Clones)
+	require.Len(t, result.StatusHistory, 2)
+	require.Equal(t, "building", result.StatusHistory[0].Status)
+	require.Equal(t, "success", result.StatusHistory[1].Status)
+	// composer can't be reached, so the status it last reported is returned
+	require.Equal(t, composeId, result.ComposerJob.Id)
+	require.NotEmpty(t, result.ComposerJob.Error)
+	require.NotEmpty(t, result.ComposerJob.StatusRefreshedAt)
+	require.JSONEq(t, `{"status": "success", "image_status": {"status": "success"}}`, string(result.ComposerJob.Status))
+
+	respStatusCode, _ = tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/internal/composes/%s", uuid.New()), &associate)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+
+	// users can't use the internal endpoints, not even for their own composes
+	auth := tutils.GetCompleteBase64Header("500000")
+	respStatusCode, _ = tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/internal/composes/%s", composeId), &auth)
+	require.Equal(t, http.StatusForbidden, respStatusCode)
+}
+
+func TestSupportComposerJob(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	composeId := uuid.New()
+	err = dbase.InsertCompose(composeId, "500000", "user500000@test.test", "500000", nil, json.RawMessage(`{"image_requests": []}`))
+	require.NoError(t, err)
+
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if "Bearer" == r.Header.Get("Authorization") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.Equal(t, fmt.Sprintf("/api/image-builder-composer/v2/composes/%v", composeId), r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte(`{"id": "x", "kind": "ComposeStatus", "status": "pending", "image_status": {"status": "building"}}`))
+		require.NoError(t, err)
+	}))
+	defer apiSrv.Close()
+
+	srv, tokenSrv := startServerWithCustomDB(t, apiSrv.URL, "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	associate := base64.StdEncoding.EncodeToString([]byte(`{"identity": {"type": "Associate", "associate": {"email": "support@example.com", "Role": ["image-builder-support"]}}}`))
+	respStatusCode, body := tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/internal/composes/%s", composeId), &associate)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	var result SupportCompose
+	err = json.Unmarshal([]byte(body), &result)
+	require.NoError(t, err)
+	require.Equal(t, composeId, result.ComposerJob.Id)
+	require.Empty(t, result.ComposerJob.Error)
+	require.Empty(t, result.ComposerJob.StatusRefreshedAt)
+	require.JSONEq(t, `{"id": "x", "kind": "ComposeStatus", "status": "pending", "image_status": {"status": "building"}}`, string(result.ComposerJob.Status))
+}
+
+func TestSearchSupportComposes(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	imageName := "escalated-image"
+	var ids []uuid.UUID
+	for _, orgId := range []string{"500000", "500001"} {
+		id := uuid.New()
+		ids = append(ids, id)
+		err = dbase.InsertCompose(id, orgId, "user@test.test", orgId, &imageName, json.RawMessage(`{"image_requests": []}`))
+		require.NoError(t, err)
+		time.Sleep(time.Millisecond)
+	}
+	err = dbase.SetCachedComposeStatus(ids[0], json.RawMessage(`{"status": "success", "image_status": {"status": "success", "upload_status": {"type": "aws", "status": "success", "options": {"ami": "ami-0123456789abcdef0", "region": "us-east-1"}}}}`))
+	require.NoError(t, err)
+
+	srv, tokenSrv := startServerWithCustomDB(t, "", "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	associate := base64.StdEncoding.EncodeToString([]byte(`{"identity": {"type": "Associate", "associate": {"email": "support@example.com", "Role": ["image-builder-support"]}}}`))
+	search := func(query string) (int, SupportComposesResponse) {
+		respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/internal/composes?"+query, &associate)
+		var result SupportComposesResponse
+		if respStatusCode == http.StatusOK {
+			require.NoError(t, json.Unmarshal([]byte(body), &result))
+		}
+		return respStatusCode, result
+	}
+
+	respStatusCode, result := search("ami=ami-0123456789abcdef0")
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.Len(t, result.Data, 1)
+	require.Equal(t, ids[0], result.Data[0].Id)
+	require.Equal(t, "500000", result.Data[0].OrgId)
+
+	// composes of all orgs, newest first
+	respStatusCode, result = search("image_name=escalated-image")
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.Len(t, result.Data, 2)
+	require.Equal(t, ids[1], result.Data[0].Id)
+	require.Equal(t, "500001", result.Data[0].OrgId)
+	require.Equal(t, ids[0], result.Data[1].Id)
+
+	respStatusCode, result = search("image_name=escalated-image&limit=1")
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.Len(t, result.Data, 1)
+	respStatusCode, result = search("ami=ami-0123456789abcdef1")
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.Empty(t, result.Data)
+
+	for _, query := range []string{"", "ami=ami-xyz", "image_name=escalated-image&limit=0", "image_name=escalated-image&limit=101"} {
+		respStatusCode, _ = search(query)
+		require.Equal(t, http.StatusBadRequest, respStatusCode, query)
+	}
+
+	auth := tutils.GetCompleteBase64Header("500000")
+	respStatusCode, _ = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/internal/composes?image_name=escalated-image", &auth)
+	require.Equal(t, http.StatusForbidden, respStatusCode)
+}
+
+func TestSupportAnalytics(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	var ids []uuid.UUID
+	for _, orgId := range []string{"500000", "500000", "500000", "500001"} {
+		id := uuid.New()
+		ids = append(ids, id)
+		err = dbase.InsertCompose(id, orgId, "user@test.test", orgId, nil, json.RawMessage(`{"image_requests": []}`))
+		require.NoError(t, err)
+	}
+	for i, status := range []string{"success", "failure", "building", "success"} {
+		_, err = dbase.InsertComposeEvent(ids[i], status, nil)
+		require.NoError(t, err)
+	}
+	err = dbase.InsertComposeArtifacts(ids[0], []db.ArtifactEntry{
+		{Filename: "disk.qcow2", Size: common.ToPtr(int64(1000)), Sha256: common.ToPtr("aa")},
+		{Filename: "disk.qcow2.sig", Size: common.ToPtr(int64(24)), Sha256: common.ToPtr("bb")},
+	})
+	require.NoError(t, err)
+	err = dbase.DeleteCompose(ids[0], "500000")
+	require.NoError(t, err)
+
+	srv, tokenSrv := startServerWithCustomDB(t, "", "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	associate := base64.StdEncoding.EncodeToString([]byte(`{"identity": {"type": "Associate", "associate": {"email": "support@example.com", "Role": ["image-builder-support"]}}}`))
+	analytics := func(query string) (int, SupportAnalyticsResponse) {
+		respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/internal/analytics?"+query, &associate)
+		var result SupportAnalyticsResponse
+		if respStatusCode == http.StatusOK {
+			require.NoError(t, json.Unmarshal([]byte(body), &result))
+		}
+		return respStatusCode, result
+	}
+
+	respStatusCode, result := analytics("period=month")
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.Equal(t, "month", result.Period)
+	require.Len(t, result.Data, 2)
+	require.Equal(t, "500000", result.Data[0].OrgId)
+	require.Equal(t, 3, result.Data[0].Builds)
+	require.Equal(t, 1, result.Data[0].Succeeded)
+	require.Equal(t, 1, result.Data[0].Failed)
+	require.Equal(t, 0.5, *result.Data[0].SuccessRate)
+	require.Equal(t, 2, result.Data[0].Artifacts)
+	require.Equal(t, int64(1024), result.Data[0].StorageBytes)
+	require.Equal(t, "500001", result.Data[1].OrgId)
+	require.Equal(t, 1.0, *result.Data[1].SuccessRate)
+	require.Equal(t, int64(0), result.Data[1].StorageBytes)
+
+	respStatusCode, result = analytics("org_id=500001")
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.Equal(t, "day", result.Period)
+	require.Len(t, result.Data, 1)
+	require.Equal(t, 1, result.Data[0].Builds)
+
+	respStatusCode, result = analytics(fmt.Sprintf("until=%s", time.Now().Add(-time.Hour).Format(time.RFC3339)))
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.Empty(t, result.Data)
+
+	for _, query := range []string{"period=year", "since=yesterday", "since=2024-02-01T00:00:00Z&until=2024-01-01T00:00:00Z"} {
+		respStatusCode, _ = analytics(query)
+		require.Equal(t, http.StatusBadRequest, respStatusCode, query)
+	}
+}
+
+func TestSupportUsageReport(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	for _, orgId := range []string{"500000", "500001"} {
+		err = dbase.InsertCompose(uuid.New(), orgId, "user@test.test", orgId, nil, json.RawMessage(`{"image_requests": [{"image_type": "aws", "upload_request": {"type": "aws"}}]}`))
+		require.NoError(t, err)
+	}
+
+	srv, tokenSrv := startServerWithCustomDB(t, "", "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	associate := base64.StdEncoding.EncodeToString([]byte(`{"identity": {"type": "Associate", "associate": {"email": "support@example.com", "Role": ["image-builder-support"]}}}`))
+	respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/internal/reports/usage", &associate)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	var report SupportUsageReport
+	require.NoError(t, json.Unmarshal([]byte(body), &report))
+	require.Len(t, report.Data, 2)
+	require.Equal(t, "500000", report.Data[0].OrgId)
+	require.Equal(t, "aws", report.Data[0].ImageType)
+	require.Equal(t, 1, report.Data[0].Builds)
+	require.Equal(t, "500001", report.Data[1].OrgId)
+
+	respStatusCode, body = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/internal/reports/usage?org_id=500001&format=csv", &associate)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, "org_id", records[0][0])
+	require.Equal(t, []string{"500001", time.Now().UTC().Format("2006-01"), "aws", "aws", "1"}, records[1][:5])
+
+	respStatusCode, _ = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/internal/reports/usage?format=xml", &associate)
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+}
+
+func TestSupportQuota(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	srv, tokenSrv := startServerWithCustomDB(t, "", "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	associate := base64.StdEncoding.EncodeToString([]byte(`{"identity": {"type": "Associate", "associate": {"email": "support@example.com", "Role": ["image-builder-support"]}}}`))
+	quotaURL := "http://localhost:8086/api/image-builder/internal/quotas/000000"
+	setQuota := func(quota SupportQuota) (int, SupportQuota) {
+		buf, err := json.Marshal(quota)
+		require.NoError(t, err)
+		request, err := http.NewRequest(http.MethodPut, quotaURL, bytes.NewReader(buf))
+		require.NoError(t, err)
+		request.Header.Add("Content-Type", "application/json")
+		request.Header.Add("x-rh-identity", associate)
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		var result SupportQuota
+		if response.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(response.Body).Decode(&result))
+		}
+		return response.StatusCode, result
+	}
+	defer func() {
+		respStatusCode, _ := setQuota(SupportQuota{})
+		require.Equal(t, http.StatusOK, respStatusCode)
+	}()
+
+	respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/internal/quotas/700000", &associate)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	var quota SupportQuota
+	require.NoError(t, json.Unmarshal([]byte(body), &quota))
+	require.Equal(t, SupportQuota{OrgId: "700000"}, quota)
+
+	respStatusCode, _ = setQuota(SupportQuota{BuildsPerDay: common.ToPtr(-1)})
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+
+	// users can't change their own quota
+	respStatusCode, _ = tutils.GetResponseBody(t, quotaURL, &tutils.AuthString0)
+	require.Equal(t, http.StatusForbidden, respStatusCode)
+
+	var uo UploadRequest_Options
+	require.NoError(t, uo.FromAWSUploadRequestOptions(AWSUploadRequestOptions{
+		ShareWithAccounts: &[]string{"123456789012"},
+	}))
+	payload := ComposeRequest{
+		Distribution: "centos-8",
+		ImageRequests: []ImageRequest{
+			{
+				Architecture: "x86_64",
+				ImageType:    ImageTypesAws,
+				UploadRequest: UploadRequest{
+					Type:    UploadTypesAws,
+					Options: uo,
+				},
+			},
+		},
+	}
+	compose := func() (int, string) {
+		return tutils.PostResponseBody(t, "http://localhost:8086/api/image-builder/v1/compose", payload)
+	}
+
+	respStatusCode, quota = setQuota(SupportQuota{BuildsPerDay: common.ToPtr(0)})
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.Equal(t, 0, *quota.BuildsPerDay)
+	require.Nil(t, quota.BuildsPerMonth)
+	require.NotEmpty(t, quota.UpdatedAt)
+	respStatusCode, body = compose()
+	require.Equal(t, http.StatusForbidden, respStatusCode)
+	require.Contains(t, body, "Builds are disabled for this organization")
+
+	// the org has at least one unfinished compose today
+	err = dbase.InsertCompose(uuid.New(), "500000", "user@test.test", "000000", nil, json.RawMessage(`{"image_requests": []}`))
+	require.NoError(t, err)
+
+	respStatusCode, _ = setQuota(SupportQuota{BuildsPerDay: common.ToPtr(100000), BuildsPerMonth: common.ToPtr(1)})
+	require.Equal(t, http.StatusOK, respStatusCode)
+	respStatusCode, body = compose()
+	require.Equal(t, http.StatusTooManyRequests, respStatusCode)
+	require.Contains(t, body, "The monthly quota of 1 builds is used up, it resets at")
+
+	respStatusCode, _ = setQuota(SupportQuota{ConcurrentBuilds: common.ToPtr(1)})
+	require.Equal(t, http.StatusOK, respStatusCode)
+	respStatusCode, body = compose()
+	require.Equal(t, http.StatusCreated, respStatusCode)
+	var result ComposeResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &result))
+	respStatusCode, body = tutils.GetResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s", result.Id), &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	var status ComposeStatus
+	require.NoError(t, json.Unmarshal([]byte(body), &status))
+	require.Equal(t, ImageStatusStatusQueued, status.ImageStatus.Status)
+	require.Equal(t, payload.Distribution, status.Request.Distribution)
+
+	respStatusCode, _ = setQuota(SupportQuota{})
+	require.Equal(t, http.StatusOK, respStatusCode)
+	respStatusCode, _ = compose()
+	require.NotEqual(t, http.StatusTooManyRequests, respStatusCode)
+	require.NotEqual(t, http.StatusForbidden, respStatusCode)
+}
+
+func TestSupportQuotaBoosts(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	srv, tokenSrv := startServerWithCustomDB(t, "", "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	associate := base64.StdEncoding.EncodeToString([]byte(`{"identity": {"type": "Associate", "associate": {"email": "support@example.com", "Role": ["image-builder-support"]}}}`))
+	boostsURL := "http://localhost:8086/api/image-builder/internal/quotas/000000/boosts"
+	do := func(method, url string, body interface{}) (int, string) {
+		var reader io.Reader
+		if body != nil {
+			buf, err := json.Marshal(body)
+			require.NoError(t, err)
+			reader = bytes.NewReader(buf)
+		}
+		request, err := http.NewRequest(method, url, reader)
+		require.NoError(t, err)
+		request.Header.Add("Content-Type", "application/json")
+		request.Header.Add("x-rh-identity", associate)
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		buf, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response.StatusCode, string(buf)
+	}
+	usage := func() Usage {
+		respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/usage", &tutils.AuthString0)
+		require.Equal(t, http.StatusOK, respStatusCode)
+		var result Usage
+		require.NoError(t, json.Unmarshal([]byte(body), &result))
+		return result
+	}
+
+	err = dbase.SetQuota(db.QuotaEntry{OrgId: "000000", BuildsPerDay: common.ToPtr(5)})
+	require.NoError(t, err)
+
+	expiresAt := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+	respStatusCode, body := do(http.MethodPost, boostsURL, SupportQuotaBoost{BuildsPerDay: 10, ExpiresAt: expiresAt})
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+	require.Contains(t, body, "Quota boosts need a reason")
+	respStatusCode, body = do(http.MethodPost, boostsURL, SupportQuotaBoost{Reason: "escalation", ExpiresAt: expiresAt})
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+	require.Contains(t, body, "Quota boost doesn't raise any limit")
+	respStatusCode, body = do(http.MethodPost, boostsURL, SupportQuotaBoost{BuildsPerDay: 10, Reason: "escalation",
+		ExpiresAt: time.Now().Add(365 * 24 * time.Hour).Format(time.RFC3339)})
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+	require.Contains(t, body, "Quota boosts have to expire within 31 days")
+
+	respStatusCode, body = do(http.MethodPost, boostsURL, SupportQuotaBoost{BuildsPerDay: 10, ConcurrentBuilds: 2, Reason: "escalation", ExpiresAt: expiresAt})
+	require.Equal(t, http.StatusCreated, respStatusCode)
+	var boost SupportQuotaBoost
+	require.NoError(t, json.Unmarshal([]byte(body), &boost))
+	require.Equal(t, "000000", boost.OrgId)
+	require.Equal(t, "support@example.com", boost.CreatedBy)
+	boostExpiry, err := time.Parse(time.RFC3339, boost.ExpiresAt)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(48*time.Hour), boostExpiry, time.Minute)
+
+	respStatusCode, body = do(http.MethodGet, boostsURL, nil)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	var boosts []SupportQuotaBoost
+	require.NoError(t, json.Unmarshal([]byte(body), &boosts))
+	require.Equal(t, []SupportQuotaBoost{boost}, boosts)
+
+	// unlimited limits stay unlimited
+	result := usage()
+	require.Equal(t, 15, *result.Quota.BuildsPerDay)
+	require.Nil(t, result.Quota.ConcurrentBuilds)
+
+	// users can't boost their own quota
+	respStatusCode, _ = tutils.GetResponseBody(t, boostsURL, &tutils.AuthString0)
+	require.Equal(t, http.StatusForbidden, respStatusCode)
+
+	respStatusCode, _ = do(http.MethodDelete, "http://localhost:8086/api/image-builder/internal/quotas/000001/boosts/"+boost.Id.String(), nil)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+	respStatusCode, _ = do(http.MethodDelete, boostsURL+"/"+boost.Id.String(), nil)
+	require.Equal(t, http.StatusNoContent, respStatusCode)
+	require.Equal(t, 5, *usage().Quota.BuildsPerDay)
+}
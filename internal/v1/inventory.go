@@ -0,0 +1,144 @@
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/kafka"
+)
+
+// inventoryImage is the message registering the image of a successful
+// compose with the console inventory, so systems running it can be
+// associated with how it was built.
+type inventoryImage struct {
+	// the compose id identifies the image across services
+	ImageId      uuid.UUID     `json:"image_id"`
+	OrgId        string        `json:"org_id"`
+	ImageName    *string       `json:"image_name,omitempty"`
+	Distribution string        `json:"distribution"`
+	ImageType    string        `json:"image_type"`
+	UploadStatus *UploadStatus `json:"upload_status,omitempty"`
+	// sha256 of the packages of the image, empty if composer has no
+	// package list of it, e.g. for edge commits
+	ManifestDigest string `json:"manifest_digest,omitempty"`
+	Packages       int    `json:"packages"`
+	CreatedAt      string `json:"created_at"`
+	BuiltAt        string `json:"built_at"`
+}
+
+// manifestDigest returns a digest of the packages of an image which doesn't
+// depend on the order composer lists them in.
+func manifestDigest(packages []composer.PackageMetadata) string {
+	if len(packages) == 0 {
+		return ""
+	}
+	nevras := make([]string, len(packages))
+	for i, p := range packages {
+		epoch := "0"
+		if p.Epoch != nil && *p.Epoch != "" {
+			epoch = *p.Epoch
+		}
+		nevras[i] = fmt.Sprintf("%s-%s:%s-%s.%s", p.Name, epoch, p.Version, p.Release, p.Arch)
+	}
+	sort.Strings(nevras)
+	sum := sha256.Sum256([]byte(strings.Join(nevras, "\n") + "\n"))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// composerMetadata asks composer for the metadata of a compose outside of a
+// request, e.g. when a sink of the outbox needs it.
+func (s *Server) composerMetadata(ctx context.Context, compose *db.ComposeEntry) (*composer.ComposeMetadata, error) {
+	cc, err := s.composerOf(compose)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cc.ComposeMetadata(ctx, compose.ComposerId)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("querying the metadata of compose %v failed with %d: %s", compose.Id, resp.StatusCode, body)
+	}
+	var metadata composer.ComposeMetadata
+	err = json.NewDecoder(resp.Body).Decode(&metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
+// composePackages returns the packages composer installed into the image of
+// a compose.
+func (s *Server) composePackages(ctx context.Context, compose *db.ComposeEntry) ([]composer.PackageMetadata, error) {
+	metadata, err := s.composerMetadata(ctx, compose)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Packages == nil {
+		return nil, nil
+	}
+	return *metadata.Packages, nil
+}
+
+// registerImage registers the image of a successful compose with the
+// inventory, if it's configured.
+func (s *Server) registerImage(event outboxEvent) error {
+	if s.inventory == nil {
+		return nil
+	}
+	us, err := s.encryptedUploadStatus(event.ComposeId, event.UploadStatus)
+	if err != nil {
+		return err
+	}
+	compose, err := s.db.GetCompose(event.ComposeId, event.OrgId)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	packages, err := s.composePackages(ctx, compose)
+	if err != nil {
+		return err
+	}
+
+	var cr ComposeRequest
+	// the image is registered even if its request can't be read
+	_ = json.Unmarshal(compose.Request, &cr)
+	distribution, imageType, _ := composeLabels(cr)
+	value, err := json.Marshal(inventoryImage{
+		ImageId:        event.ComposeId,
+		OrgId:          event.OrgId,
+		ImageName:      compose.ImageName,
+		Distribution:   distribution,
+		ImageType:      imageType,
+		UploadStatus:   us,
+		ManifestDigest: manifestDigest(packages),
+		Packages:       len(packages),
+		CreatedAt:      compose.CreatedAt.UTC().Format(time.RFC3339),
+		BuiltAt:        event.Time.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+	return s.inventory.SendMessages([]kafka.Message{
+		{
+			Key:   []byte(event.OrgId),
+			Value: value,
+			Time:  event.Time,
+		},
+	})
+}
@@ -0,0 +1,27 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/composer"
+)
+
+func TestManifestDigest(t *testing.T) {
+	require.Empty(t, manifestDigest(nil))
+
+	bash := composer.PackageMetadata{Name: "bash", Version: "5.1.8", Release: "6.el9", Arch: "x86_64"}
+	shadow := composer.PackageMetadata{Name: "shadow-utils", Epoch: common.ToPtr("2"), Version: "4.9", Release: "6.el9", Arch: "x86_64"}
+	digest := manifestDigest([]composer.PackageMetadata{bash, shadow})
+	require.Regexp(t, "^sha256:[0-9a-f]{64}$", digest)
+	// the order composer lists the packages in doesn't matter
+	require.Equal(t, digest, manifestDigest([]composer.PackageMetadata{shadow, bash}))
+	// an empty epoch is the zero epoch
+	bash.Epoch = common.ToPtr("")
+	require.Equal(t, digest, manifestDigest([]composer.PackageMetadata{shadow, bash}))
+
+	shadow.Release = "7.el9"
+	require.NotEqual(t, digest, manifestDigest([]composer.PackageMetadata{shadow, bash}))
+}
@@ -0,0 +1,134 @@
+package v1
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Operations which submit builds or launch their images, an organization's ip
+// allow list is enforced on these. Approving a compose releases it to be
+// built.
+var ipRestrictedOperations = map[string]bool{
+	"composeimage":      true,
+	"composeimagebatch": true,
+	"clonecompose":      true,
+	"approvecompose":    true,
+	"launchcompose":     true,
+}
+
+// parseIPAllowList validates and normalizes the networks of an allow list,
+// single addresses are turned into a network containing just that address.
+func parseIPAllowList(cidrs []string) ([]string, error) {
+	var normalized []string
+	for _, c := range cidrs {
+		_, network, err := net.ParseCIDR(c)
+		if err != nil {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return nil, fmt.Errorf("%q is neither a network in CIDR notation nor an ip address", c)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			network = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+		}
+		normalized = append(normalized, network.String())
+	}
+	return normalized, nil
+}
+
+func ipAllowed(ip net.IP, cidrs []string) bool {
+	for _, c := range cidrs {
+		_, network, err := net.ParseCIDR(c)
+		if err != nil {
+			continue
+		}
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// enforceIPAllowList rejects builds submitted from outside the networks the
+// organization allows. The client address is taken from the X-Forwarded-For
+// header, see Attach.
+func (s *Server) enforceIPAllowList(nextHandler echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		route, _, err := s.router.FindRoute(ctx.Request())
+		if err != nil || !ipRestrictedOperations[operationId(route)] {
+			return nextHandler(ctx)
+		}
+
+		idHeader, err := getIdentityHeader(ctx)
+		if err != nil {
+			return err
+		}
+
+		cidrs, err := s.db.GetIPAllowList(idHeader.Identity.OrgID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the ip allow list").SetInternal(err)
+		}
+		if len(cidrs) == 0 {
+			return nextHandler(ctx)
+		}
+
+		clientIP := ctx.RealIP()
+		ip := net.ParseIP(clientIP)
+		if ip == nil || !ipAllowed(ip, cidrs) {
+			return auditRejection(ctx, reasonIPNotAllowed, echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Builds can not be submitted from %s for this organization", clientIP)))
+		}
+		return nextHandler(ctx)
+	}
+}
+
+func (h *Handlers) GetIPAllowList(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	cidrs, err := h.server.db.GetIPAllowList(idHeader.Identity.OrgID)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying ip allow list: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the ip allow list")
+	}
+	if cidrs == nil {
+		cidrs = []string{}
+	}
+	return ctx.JSON(http.StatusOK, IPAllowList{
+		Cidrs: cidrs,
+	})
+}
+
+func (h *Handlers) UpdateIPAllowList(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	var req IPAllowList
+	err = ctx.Bind(&req)
+	if err != nil {
+		return err
+	}
+
+	cidrs, err := parseIPAllowList(req.Cidrs)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	err = h.server.db.SetIPAllowList(idHeader.Identity.OrgID, cidrs)
+	if err != nil {
+		ctx.Logger().Errorf("Error updating ip allow list: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong updating the ip allow list")
+	}
+
+	ctx.Logger().Infof("Ip allow list of org %s set to %v", idHeader.Identity.OrgID, cidrs)
+	return h.GetIPAllowList(ctx)
+}
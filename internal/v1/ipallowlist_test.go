@@ -0,0 +1,32 @@
+package v1
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIPAllowList(t *testing.T) {
+	cidrs, err := parseIPAllowList([]string{"192.0.2.0/24", "192.0.2.17/24", "198.51.100.7", "2001:db8::1", "2001:db8::/32"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"192.0.2.0/24", "192.0.2.0/24", "198.51.100.7/32", "2001:db8::1/128", "2001:db8::/32"}, cidrs)
+
+	_, err = parseIPAllowList([]string{"192.0.2.0/24", "example.com"})
+	require.Error(t, err)
+	_, err = parseIPAllowList([]string{"192.0.2.0/33"})
+	require.Error(t, err)
+
+	cidrs, err = parseIPAllowList(nil)
+	require.NoError(t, err)
+	require.Empty(t, cidrs)
+}
+
+func TestIPAllowed(t *testing.T) {
+	cidrs := []string{"192.0.2.0/24", "2001:db8::/32"}
+	require.True(t, ipAllowed(net.ParseIP("192.0.2.10"), cidrs))
+	require.True(t, ipAllowed(net.ParseIP("2001:db8::10"), cidrs))
+	require.False(t, ipAllowed(net.ParseIP("198.51.100.10"), cidrs))
+	require.False(t, ipAllowed(net.ParseIP("2001:db9::10"), cidrs))
+	require.False(t, ipAllowed(net.ParseIP("192.0.2.10"), nil))
+}
@@ -0,0 +1,348 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/provisioning"
+)
+
+// launchProvider returns the provisioning provider instances of a compose
+// are launched on, which is where its image was uploaded to.
+func launchProvider(cr ComposeRequest) (string, bool) {
+	if len(cr.ImageRequests) == 0 {
+		return "", false
+	}
+	switch cr.ImageRequests[0].UploadRequest.Type {
+	case UploadTypesAws:
+		return "aws", true
+	case UploadTypesAzure:
+		return "azure", true
+	case UploadTypesGcp:
+		return "gcp", true
+	}
+	return "", false
+}
+
+// reservationRequest translates a launch into the reservation request of a
+// provider. Provisioning resolves the compose id to the image it produced.
+func reservationRequest(provider string, composeId uuid.UUID, lr LaunchRequest) interface{} {
+	imageId := composeId.String()
+	amount := 1
+	if lr.Amount != nil {
+		amount = *lr.Amount
+	}
+	switch provider {
+	case "aws":
+		return provisioning.V1AWSReservationRequest{
+			Amount:           common.ToPtr(int32(amount)),
+			ImageId:          &imageId,
+			InstanceType:     lr.InstanceType,
+			LaunchTemplateId: lr.LaunchTemplateId,
+			Name:             lr.Name,
+			Poweroff:         lr.Poweroff,
+			PubkeyId:         &lr.PubkeyId,
+			Region:           lr.Region,
+			SourceId:         &lr.SourceId,
+		}
+	case "azure":
+		return provisioning.V1AzureReservationRequest{
+			Amount:       common.ToPtr(int64(amount)),
+			ImageId:      &imageId,
+			InstanceSize: lr.InstanceType,
+			Location:     lr.Region,
+			Name:         lr.Name,
+			Poweroff:     lr.Poweroff,
+			PubkeyId:     &lr.PubkeyId,
+			SourceId:     &lr.SourceId,
+		}
+	default:
+		return provisioning.V1GCPReservationRequest{
+			Amount:           common.ToPtr(int64(amount)),
+			ImageId:          &imageId,
+			LaunchTemplateId: lr.LaunchTemplateId,
+			MachineType:      lr.InstanceType,
+			NamePattern:      lr.Name,
+			Poweroff:         lr.Poweroff,
+			PubkeyId:         &lr.PubkeyId,
+			SourceId:         &lr.SourceId,
+			Zone:             lr.Region,
+		}
+	}
+}
+
+// provisioningError turns an error response of provisioning into one for the
+// user, whose requests provisioning rejects as bad requests.
+func provisioningError(ctx echo.Context, resp *http.Response, msg string) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, msg)
+	}
+	var pErr provisioning.V1ResponseError
+	if json.Unmarshal(body, &pErr) != nil || pErr.Msg == nil {
+		pErr.Msg = common.ToPtr(string(body))
+	}
+	ctx.Logger().Errorf("%s, provisioning returned %d: %s", msg, resp.StatusCode, *pErr.Msg)
+	if resp.StatusCode == http.StatusNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("%s: %s", msg, *pErr.Msg))
+	}
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s: %s", msg, *pErr.Msg))
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, msg)
+}
+
+func (h *Handlers) LaunchCompose(ctx echo.Context, composeId uuid.UUID) error {
+	composeEntry, err := h.getComposeByIdAndOrgId(ctx, composeId)
+	if err != nil {
+		return err
+	}
+
+	var cr ComposeRequest
+	err = json.Unmarshal(composeEntry.Request, &cr)
+	if err != nil {
+		return err
+	}
+	provider, ok := launchProvider(cr)
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "Only composes uploaded to aws, azure or gcp can be launched")
+	}
+
+	var launchReq LaunchRequest
+	err = ctx.Bind(&launchReq)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.server.db.GetQueuedCompose(composeId)
+	if err == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Compose %v hasn't been built yet", composeId))
+	} else if !errors.Is(err, db.QueuedComposeNotFoundError) {
+		ctx.Logger().Errorf("Error querying the queue for compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the compose")
+	}
+	cloudStat, err := h.cachedComposeStatus(ctx, composeEntry)
+	if err != nil {
+		return err
+	}
+	if cloudStat.ImageStatus.Status != composer.ImageStatusValueSuccess {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Compose %v didn't finish successfully", composeId))
+	}
+
+	resp, err := h.server.pClient.CreateReservation(ctx.Request().Context(), provider, reservationRequest(provider, composeId, launchReq))
+	if err != nil {
+		ctx.Logger().Errorf("Error creating a reservation for compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong launching the compose")
+	}
+	defer closeBody(resp.Body)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return provisioningError(ctx, resp, "Unable to launch the compose")
+	}
+
+	// the reservation ids of all providers share the same field
+	var reservation provisioning.V1AWSReservationResponse
+	err = json.NewDecoder(resp.Body).Decode(&reservation)
+	if err != nil || reservation.ReservationId == nil {
+		ctx.Logger().Errorf("Unable to decode the reservation of compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong launching the compose")
+	}
+
+	rawLR, err := json.Marshal(launchReq)
+	if err != nil {
+		return err
+	}
+	err = h.server.db.InsertLaunch(composeId, *reservation.ReservationId, provider, rawLR)
+	if err != nil {
+		ctx.Logger().Errorf("Error inserting launch %d of compose %v: %v", *reservation.ReservationId, composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong saving the launch")
+	}
+	logAction(ctx, "launch_compose", logrus.Fields{"compose_id": composeId, "reservation_id": *reservation.ReservationId, "provider": provider}, "Compose launched")
+
+	return ctx.JSON(http.StatusCreated, LaunchResponse{
+		ReservationId: *reservation.ReservationId,
+	})
+}
+
+func (h *Handlers) GetLaunchStatus(ctx echo.Context, reservationId int64) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	launch, err := h.server.db.GetLaunch(reservationId, idHeader.Identity.OrgID)
+	if err != nil {
+		if errors.Is(err, db.LaunchNotFoundError) {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+		ctx.Logger().Errorf("Error querying launch %d: %v", reservationId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying this launch")
+	}
+
+	resp, err := h.server.pClient.GetReservation(ctx.Request().Context(), reservationId)
+	if err != nil {
+		ctx.Logger().Errorf("Error requesting reservation %d: %v", reservationId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying this launch")
+	}
+	defer closeBody(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return provisioningError(ctx, resp, "Unable to query the launch")
+	}
+	var reservation provisioning.V1GenericReservationResponse
+	err = json.NewDecoder(resp.Body).Decode(&reservation)
+	if err != nil {
+		ctx.Logger().Errorf("Unable to decode reservation %d: %v", reservationId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying this launch")
+	}
+
+	status := LaunchStatus{
+		ReservationId: reservationId,
+		Provider:      launch.Provider,
+		Status:        LaunchStatusStatusPending,
+		Error:         reservation.Error,
+	}
+	if reservation.Error != nil && *reservation.Error == "" {
+		status.Error = nil
+	}
+	if reservation.Step != nil {
+		status.Step = common.ToPtr(int(*reservation.Step))
+		if reservation.StepTitles != nil && *reservation.Step > 0 && int(*reservation.Step) <= len(*reservation.StepTitles) {
+			status.StepTitle = &(*reservation.StepTitles)[*reservation.Step-1]
+		}
+	}
+	if reservation.Steps != nil {
+		status.Steps = common.ToPtr(int(*reservation.Steps))
+	}
+	if reservation.Success != nil && !*reservation.Success {
+		status.Status = LaunchStatusStatusFailure
+	} else if reservation.Success != nil {
+		status.Status = LaunchStatusStatusSuccess
+		instances, err := h.launchInstances(ctx, launch.Provider, reservationId)
+		if err != nil {
+			return err
+		}
+		status.Instances = &instances
+	}
+
+	return ctx.JSON(http.StatusOK, status)
+}
+
+// launchInstances returns the instances of a successful launch.
+func (h *Handlers) launchInstances(ctx echo.Context, provider string, reservationId int64) ([]LaunchInstance, error) {
+	resp, err := h.server.pClient.GetProviderReservation(ctx.Request().Context(), provider, reservationId)
+	if err != nil {
+		ctx.Logger().Errorf("Error requesting %s reservation %d: %v", provider, reservationId, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying this launch")
+	}
+	defer closeBody(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, provisioningError(ctx, resp, "Unable to query the instances of the launch")
+	}
+
+	// the instances of all providers share the same fields
+	var reservation provisioning.V1AWSReservationResponse
+	err = json.NewDecoder(resp.Body).Decode(&reservation)
+	if err != nil {
+		ctx.Logger().Errorf("Unable to decode %s reservation %d: %v", provider, reservationId, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying this launch")
+	}
+
+	instances := []LaunchInstance{}
+	if reservation.Instances != nil {
+		for _, i := range *reservation.Instances {
+			if i.InstanceId == nil {
+				continue
+			}
+			instance := LaunchInstance{Id: *i.InstanceId}
+			if i.Detail != nil {
+				instance.PublicIpv4 = i.Detail.PublicIpv4
+				instance.PublicDns = i.Detail.PublicDns
+				instance.PrivateIpv4 = i.Detail.PrivateIpv4
+			}
+			instances = append(instances, instance)
+		}
+	}
+	return instances, nil
+}
+
+func (h *Handlers) GetComposeLaunches(ctx echo.Context, composeId uuid.UUID, params GetComposeLaunchesParams) error {
+	err := h.canUserAccessComposeId(ctx, composeId)
+	if err != nil {
+		return err
+	}
+
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	limit := 100
+	if params.Limit != nil && *params.Limit > 0 {
+		limit = *params.Limit
+	}
+
+	offset := 0
+	if params.Offset != nil {
+		offset = *params.Offset
+	}
+
+	launches, count, err := h.server.db.GetLaunchesForCompose(composeId, idHeader.Identity.OrgID, limit, offset)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying launches for compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying launches for this compose")
+	}
+
+	data := []LaunchesResponseItem{}
+	for _, l := range launches {
+		var lr LaunchRequest
+		err = json.Unmarshal(l.Request, &lr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying launches for this compose")
+		}
+		data = append(data, LaunchesResponseItem{
+			ReservationId: l.ReservationId,
+			ComposeId:     composeId,
+			Provider:      l.Provider,
+			Request:       lr,
+			CreatedAt:     l.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	lastOffset := count - 1
+	if lastOffset < 0 {
+		lastOffset = 0
+	}
+
+	spec, err := GetSwagger()
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, LaunchesResponse{
+		Meta: struct {
+			Count int `json:"count"`
+		}{
+			count,
+		},
+		Links: struct {
+			First string `json:"first"`
+			Last  string `json:"last"`
+		}{
+			fmt.Sprintf("%v/v%v/composes/%v/launches?offset=%v&limit=%v",
+				RoutePrefix(), spec.Info.Version, composeId, 0, limit),
+			fmt.Sprintf("%v/v%v/composes/%v/launches?offset=%v&limit=%v",
+				RoutePrefix(), spec.Info.Version, composeId, lastOffset, limit),
+		},
+		Data: data,
+	})
+}
@@ -0,0 +1,56 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/provisioning"
+)
+
+func TestLaunchProvider(t *testing.T) {
+	for uploadType, provider := range map[UploadTypes]string{
+		UploadTypesAws:   "aws",
+		UploadTypesAzure: "azure",
+		UploadTypesGcp:   "gcp",
+	} {
+		p, ok := launchProvider(ComposeRequest{ImageRequests: []ImageRequest{{UploadRequest: UploadRequest{Type: uploadType}}}})
+		require.True(t, ok)
+		require.Equal(t, provider, p)
+	}
+	_, ok := launchProvider(ComposeRequest{ImageRequests: []ImageRequest{{UploadRequest: UploadRequest{Type: UploadTypesAwsS3}}}})
+	require.False(t, ok)
+	_, ok = launchProvider(ComposeRequest{})
+	require.False(t, ok)
+}
+
+func TestReservationRequest(t *testing.T) {
+	id := uuid.New()
+	lr := LaunchRequest{
+		SourceId:     "1",
+		PubkeyId:     5,
+		Amount:       common.ToPtr(2),
+		InstanceType: common.ToPtr("Standard_B1s"),
+		Region:       common.ToPtr("eastus"),
+	}
+
+	azure := reservationRequest("azure", id, lr).(provisioning.V1AzureReservationRequest)
+	require.Equal(t, id.String(), *azure.ImageId)
+	require.Equal(t, int64(2), *azure.Amount)
+	require.Equal(t, "Standard_B1s", *azure.InstanceSize)
+	require.Equal(t, "eastus", *azure.Location)
+	require.Equal(t, "1", *azure.SourceId)
+	require.Equal(t, int64(5), *azure.PubkeyId)
+
+	lr.Amount = nil
+	gcp := reservationRequest("gcp", id, lr).(provisioning.V1GCPReservationRequest)
+	require.Equal(t, int64(1), *gcp.Amount)
+	require.Equal(t, "Standard_B1s", *gcp.MachineType)
+	require.Equal(t, "eastus", *gcp.Zone)
+
+	aws := reservationRequest("aws", id, lr).(provisioning.V1AWSReservationRequest)
+	require.Equal(t, int32(1), *aws.Amount)
+	require.Equal(t, "eastus", *aws.Region)
+}
@@ -0,0 +1,92 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/logger"
+)
+
+type SupportLogLevels struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules"`
+}
+
+// SupportLogLevelsUpdate changes the global level if set, and the levels of
+// the modules listed. Modules set to an empty level follow the global one.
+type SupportLogLevelsUpdate struct {
+	Level   *string           `json:"level,omitempty"`
+	Modules map[string]string `json:"modules,omitempty"`
+}
+
+// GetSupportLogLevels returns the log levels of the instance serving the
+// request, levels aren't shared between replicas.
+func (h *Handlers) GetSupportLogLevels(ctx echo.Context) error {
+	level, modules := logger.Level()
+	levels := SupportLogLevels{
+		Level:   level.String(),
+		Modules: map[string]string{},
+	}
+	for name, l := range modules {
+		levels.Modules[name] = l.String()
+	}
+	return ctx.JSON(http.StatusOK, levels)
+}
+
+// UpdateSupportLogLevels changes the log levels until the instance restarts,
+// or they are changed again.
+func (h *Handlers) UpdateSupportLogLevels(ctx echo.Context) error {
+	var req SupportLogLevelsUpdate
+	err := ctx.Bind(&req)
+	if err != nil {
+		return err
+	}
+
+	var global *logrus.Level
+	if req.Level != nil {
+		level, err := logrus.ParseLevel(*req.Level)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid level: %v", err))
+		}
+		global = &level
+	}
+	modules := map[string]*logrus.Level{}
+	known := map[string]bool{}
+	for _, name := range logger.Modules() {
+		known[name] = true
+	}
+	for name, value := range req.Modules {
+		if !known[name] {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unknown module %s, expected one of %v", name, logger.Modules()))
+		}
+		if value == "" {
+			modules[name] = nil
+			continue
+		}
+		level, err := logrus.ParseLevel(value)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid level of module %s: %v", name, err))
+		}
+		modules[name] = &level
+	}
+
+	// validated everything before changing anything
+	if global != nil {
+		logger.SetLevel(*global)
+	}
+	for name, level := range modules {
+		err = logger.SetModuleLevel(name, level)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	logAction(ctx, "log_levels_updated", logrus.Fields{
+		"level":   req.Level,
+		"modules": req.Modules,
+	}, "Changed the log levels")
+	return h.GetSupportLogLevels(ctx)
+}
@@ -0,0 +1,56 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/logger"
+)
+
+func TestUpdateSupportLogLevels(t *testing.T) {
+	defer logger.SetLevel(logrus.GetLevel())
+	defer func() {
+		require.NoError(t, logger.SetModuleLevel(logger.ModuleDB, nil))
+	}()
+
+	e := echo.New()
+	var h Handlers
+	update := func(body string) (int, SupportLogLevels) {
+		req := httptest.NewRequest(http.MethodPut, "/log-levels", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		err := h.UpdateSupportLogLevels(e.NewContext(req, rec))
+		if he, ok := err.(*echo.HTTPError); ok {
+			return he.Code, SupportLogLevels{}
+		}
+		require.NoError(t, err)
+		var levels SupportLogLevels
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &levels))
+		return rec.Code, levels
+	}
+
+	code, levels := update(`{"level": "warning", "modules": {"db": "debug"}}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "warning", levels.Level)
+	require.Equal(t, "debug", levels.Modules[logger.ModuleDB])
+	require.Equal(t, "warning", levels.Modules[logger.ModuleAuth])
+
+	// modules set to an empty level follow the global level again
+	code, levels = update(`{"modules": {"db": ""}}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "warning", levels.Modules[logger.ModuleDB])
+
+	// nothing is changed if part of the update is invalid
+	code, _ = update(`{"level": "error", "modules": {"unknown": "debug"}}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = update(`{"modules": {"db": "verbose"}}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, logrus.WarnLevel, logrus.GetLevel())
+}
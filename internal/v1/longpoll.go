@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+const (
+	maxStatusWait = 2 * time.Minute
+	// how long before the deadline of the request a wait returns, to leave
+	// time to respond
+	statusWaitMargin = 5 * time.Second
+	// how often the status is refreshed while waiting, the stream of the
+	// org tells about finished composes sooner
+	minStatusWaitPoll = 5 * time.Second
+)
+
+// parseStatusWait returns how long a status request waits for a change, at
+// most maxStatusWait and not past the deadline of the request.
+func parseStatusWait(ctx echo.Context, wait string) (time.Duration, error) {
+	d, err := time.ParseDuration(wait)
+	if err != nil || d < 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "wait has to be a duration like 30s or 2m")
+	}
+	if d > maxStatusWait {
+		d = maxStatusWait
+	}
+	if deadline, ok := ctx.Request().Context().Deadline(); ok {
+		if left := time.Until(deadline) - statusWaitMargin; left < d {
+			d = left
+		}
+	}
+	return d, nil
+}
+
+// waitForComposeStatus returns the status of a compose once it differs from
+// status, or status once wait elapsed. The status is refreshed when the
+// stream of the org has an event of the compose, and every so often in case
+// nobody else refreshes it.
+func (h *Handlers) waitForComposeStatus(ctx echo.Context, composeEntry *db.ComposeEntry, status *ComposeStatus, wait time.Duration) (*ComposeStatus, error) {
+	if status.ImageStatus.Status == ImageStatusStatusSuccess || status.ImageStatus.Status == ImageStatusStatusFailure {
+		return status, nil
+	}
+
+	events := h.server.stream.subscribe(composeEntry.OrgId)
+	defer h.server.stream.unsubscribe(composeEntry.OrgId, events)
+
+	poll := h.server.composeStatusTTL
+	if poll < minStatusWaitPoll {
+		poll = minStatusWaitPoll
+	}
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return status, nil
+		case <-ctx.Request().Context().Done():
+			return status, nil
+		case event, ok := <-events:
+			if !ok {
+				// dropped for falling behind, polling still works
+				events = nil
+				continue
+			}
+			if event.ComposeId != composeEntry.Id {
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		current, err := h.composeStatus(ctx, composeEntry)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current.ImageStatus, status.ImageStatus) {
+			return current, nil
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatusWait(t *testing.T) {
+	ctx := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	wait, err := parseStatusWait(ctx, "30s")
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Second, wait)
+	wait, err = parseStatusWait(ctx, "1h")
+	require.NoError(t, err)
+	require.Equal(t, maxStatusWait, wait)
+	_, err = parseStatusWait(ctx, "soon")
+	require.Error(t, err)
+	_, err = parseStatusWait(ctx, "-1s")
+	require.Error(t, err)
+
+	// waits end before the deadline of the request
+	reqCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	ctx.SetRequest(ctx.Request().WithContext(reqCtx))
+	wait, err = parseStatusWait(ctx, "2m")
+	require.NoError(t, err)
+	require.Less(t, wait, time.Minute-statusWaitMargin+time.Second)
+	require.Greater(t, wait, time.Minute-statusWaitMargin-time.Second)
+}
@@ -0,0 +1,58 @@
+package v1
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/prometheus"
+)
+
+// composeLabels returns the distribution, image type and upload target of a
+// compose request, for labelling metrics.
+func composeLabels(cr ComposeRequest) (string, string, string) {
+	if len(cr.ImageRequests) == 0 {
+		return string(cr.Distribution), "", ""
+	}
+	ir := cr.ImageRequests[0]
+	return string(cr.Distribution), string(ir.ImageType), string(ir.UploadRequest.Type)
+}
+
+func composeCreated(cr ComposeRequest) {
+	distribution, imageType, uploadTarget := composeLabels(cr)
+	prometheus.Composes.WithLabelValues(distribution, imageType, uploadTarget).Inc()
+}
+
+// recordComposeStatus stores the status of a compose, along with the reason
+// it failed, and records the outcome in the metrics, notifies the webhooks and
+// publishes the event if it's the first time it was seen finished.
+func (s *Server) recordComposeStatus(compose db.ComposeEntry, imageStatus composer.ImageStatus) error {
+	status := imageStatus.Status
+	var reason *string
+	if status == composer.ImageStatusValueFailure && imageStatus.Error != nil {
+		reason = &imageStatus.Error.Reason
+	}
+	finished := status == composer.ImageStatusValueSuccess || status == composer.ImageStatusValueFailure
+	var outbox []db.OutboxEntry
+	if finished {
+		// the upload status is only informational for the outbox
+		us, _ := parseComposerUploadStatus(imageStatus.UploadStatus)
+		outbox = s.composeFinishedOutbox(compose.Id, compose.OrgId, string(status), reason, us)
+	}
+	recorded, err := s.db.InsertComposeEvent(compose.Id, string(status), reason, outbox...)
+	if err != nil {
+		return err
+	}
+	if !recorded || !finished {
+		return nil
+	}
+
+	var cr ComposeRequest
+	// composes with requests which can't be read are still counted
+	_ = json.Unmarshal(compose.Request, &cr)
+	distribution, imageType, uploadTarget := composeLabels(cr)
+	prometheus.ComposeOutcomes.WithLabelValues(distribution, imageType, uploadTarget, string(status)).Inc()
+	prometheus.ComposeDuration.WithLabelValues(distribution, imageType, uploadTarget, string(status)).Observe(time.Since(compose.CreatedAt).Seconds())
+	return nil
+}
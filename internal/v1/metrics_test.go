@@ -0,0 +1,29 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComposeLabels(t *testing.T) {
+	distribution, imageType, uploadTarget := composeLabels(ComposeRequest{
+		Distribution: "rhel-9",
+		ImageRequests: []ImageRequest{
+			{
+				ImageType: ImageTypesAws,
+				UploadRequest: UploadRequest{
+					Type: UploadTypesAws,
+				},
+			},
+		},
+	})
+	require.Equal(t, "rhel-9", distribution)
+	require.Equal(t, "aws", imageType)
+	require.Equal(t, "aws", uploadTarget)
+
+	distribution, imageType, uploadTarget = composeLabels(ComposeRequest{})
+	require.Empty(t, distribution)
+	require.Empty(t, imageType)
+	require.Empty(t, uploadTarget)
+}
@@ -27,8 +27,15 @@ func (s *Server) ValidateRequest(nextHandler echo.HandlerFunc) echo.HandlerFunc
 			Route:      route,
 		}
 
+		if s.requestValidation == ValidationOff {
+			return nextHandler(ctx)
+		}
 		context := request.Context()
 		if err := openapi3filter.ValidateRequest(context, requestValidationInput); err != nil {
+			if s.requestValidation == ValidationReport {
+				reportSchemaViolation("request", operationId(route), err)
+				return nextHandler(ctx)
+			}
 			return echo.NewHTTPError(http.StatusBadRequest, err)
 		}
 		return nextHandler(ctx)
@@ -47,7 +54,7 @@ func noAssociateAccounts(nextHandler echo.HandlerFunc) echo.HandlerFunc {
 			// should not be able to access image-builder as long as we don't explicitly enable turnpike
 			// access, or another such service forwards them to us. Explicitly reject such accounts for
 			// now.
-			return echo.NewHTTPError(http.StatusBadRequest, "unsupported account type: 'Associate'")
+			return auditRejection(ctx, reasonUnsupportedAccount, echo.NewHTTPError(http.StatusBadRequest, "unsupported account type: 'Associate'"))
 		}
 
 		return nextHandler(ctx)
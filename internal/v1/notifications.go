@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/kafka"
+)
+
+// Finished composes are sent to the console notifications service, which
+// notifies the users of the org according to their preferences. The event
+// types have to be registered for the application in the service.
+const (
+	notificationVersion     = "v1.2.0"
+	notificationBundle      = "rhel"
+	notificationApplication = "image-builder"
+
+	notificationComposeSucceeded = "compose-succeeded"
+	notificationComposeFailed    = "compose-failed"
+)
+
+// notificationAction is the message format of the notifications ingress
+// topic.
+type notificationAction struct {
+	Version     string                  `json:"version"`
+	Id          uuid.UUID               `json:"id"`
+	Bundle      string                  `json:"bundle"`
+	Application string                  `json:"application"`
+	EventType   string                  `json:"event_type"`
+	Timestamp   string                  `json:"timestamp"`
+	OrgId       string                  `json:"org_id"`
+	Context     map[string]string       `json:"context"`
+	Events      []notificationEvent     `json:"events"`
+	Recipients  []notificationRecipient `json:"recipients"`
+}
+
+type notificationEvent struct {
+	Metadata map[string]string   `json:"metadata"`
+	Payload  notificationPayload `json:"payload"`
+}
+
+type notificationPayload struct {
+	ComposeId uuid.UUID `json:"compose_id"`
+	Status    string    `json:"status"`
+	Reason    *string   `json:"reason,omitempty"`
+}
+
+// recipients are left to the preferences of the users, by not listing any
+type notificationRecipient struct{}
+
+// notifyComposeFinished sends the outcome of a compose, which finished at t,
+// to the notifications service, if it's configured.
+func (s *Server) notifyComposeFinished(composeId uuid.UUID, orgId, status string, reason *string, t time.Time) error {
+	if s.notifications == nil {
+		return nil
+	}
+	eventType := notificationComposeFailed
+	if status == string(composer.ImageStatusValueSuccess) {
+		eventType = notificationComposeSucceeded
+	}
+	action := notificationAction{
+		Version:     notificationVersion,
+		Id:          uuid.New(),
+		Bundle:      notificationBundle,
+		Application: notificationApplication,
+		EventType:   eventType,
+		// the service expects a local date time in utc
+		Timestamp: t.UTC().Format("2006-01-02T15:04:05"),
+		OrgId:     orgId,
+		Context:   map[string]string{"compose_id": composeId.String()},
+		Events: []notificationEvent{
+			{
+				Metadata: map[string]string{},
+				Payload: notificationPayload{
+					ComposeId: composeId,
+					Status:    status,
+					Reason:    reason,
+				},
+			},
+		},
+		Recipients: []notificationRecipient{},
+	}
+	value, err := json.Marshal(action)
+	if err != nil {
+		return err
+	}
+	return s.notifications.SendMessages([]kafka.Message{
+		{
+			Key:   []byte(orgId),
+			Value: value,
+			Headers: []kafka.Header{
+				{Key: "rh-message-id", Value: []byte(action.Id.String())},
+			},
+			Time: t,
+		},
+	})
+}
@@ -0,0 +1,50 @@
+package v1
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+)
+
+func TestNotifyComposeFinished(t *testing.T) {
+	notifications := &fakePublisher{}
+	s := &Server{notifications: notifications}
+
+	id := uuid.New()
+	finished := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, s.notifyComposeFinished(id, "000000", "failure", common.ToPtr("osbuild failed"), finished))
+	require.NoError(t, s.notifyComposeFinished(id, "000000", "success", nil, finished))
+	require.Len(t, notifications.messages, 2)
+
+	var failed notificationAction
+	require.NoError(t, json.Unmarshal(notifications.messages[0].Value, &failed))
+	require.Equal(t, "000000", string(notifications.messages[0].Key))
+	require.Equal(t, "rh-message-id", notifications.messages[0].Headers[0].Key)
+	require.Equal(t, failed.Id.String(), string(notifications.messages[0].Headers[0].Value))
+	require.Equal(t, "v1.2.0", failed.Version)
+	require.Equal(t, "rhel", failed.Bundle)
+	require.Equal(t, "image-builder", failed.Application)
+	require.Equal(t, notificationComposeFailed, failed.EventType)
+	require.Equal(t, "000000", failed.OrgId)
+	require.Equal(t, "2026-01-02T03:04:05", failed.Timestamp)
+	require.Equal(t, []notificationEvent{
+		{
+			Metadata: map[string]string{},
+			Payload: notificationPayload{
+				ComposeId: id,
+				Status:    "failure",
+				Reason:    common.ToPtr("osbuild failed"),
+			},
+		},
+	}, failed.Events)
+
+	var succeeded notificationAction
+	require.NoError(t, json.Unmarshal(notifications.messages[1].Value, &succeeded))
+	require.Equal(t, notificationComposeSucceeded, succeeded.EventType)
+	require.Contains(t, string(notifications.messages[1].Value), `"recipients":[]`)
+}
@@ -0,0 +1,284 @@
+package v1
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/redhatinsights/identity"
+
+	"github.com/osbuild/image-builder/internal/logger"
+)
+
+// Unknown key ids cause the key set to be fetched again, but not more often
+// than this.
+const jwksRefreshInterval = time.Minute
+
+type jwksKeySet struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func newJWKSKeySet(url string) *jwksKeySet {
+	return &jwksKeySet{
+		url: url,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		keys: map[string]*rsa.PublicKey{},
+	}
+}
+
+func (ks *jwksKeySet) key(kid string) (*rsa.PublicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if k, ok := ks.keys[kid]; ok {
+		return k, nil
+	}
+
+	if time.Since(ks.fetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	keys, err := ks.fetch()
+	if err != nil {
+		return nil, err
+	}
+	ks.keys = keys
+	ks.fetchedAt = time.Now()
+
+	if k, ok := ks.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (ks *jwksKeySet) fetch() (map[string]*rsa.PublicKey, error) {
+	resp, err := ks.client.Get(ks.url)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching key set failed with status %d", resp.StatusCode)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus for key %q: %v", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent for key %q: %v", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+// TokenClaimsConfig is how the claims of bearer tokens map to callers.
+type TokenClaimsConfig struct {
+	// Clients tokens have to be issued for, either as their audience or as
+	// their authorized party.
+	Audiences []string
+	// Services, e.g. rhel, all callers are entitled to.
+	Entitlements []string
+	// Optional claim listing further services the caller is entitled to.
+	EntitlementsClaim string
+}
+
+type tokenValidator struct {
+	issuer string
+	claims TokenClaimsConfig
+	jwks   *jwksKeySet
+}
+
+func newTokenValidator(issuer, jwksURL string, claims TokenClaimsConfig) *tokenValidator {
+	return &tokenValidator{
+		issuer: issuer,
+		claims: claims,
+		jwks:   newJWKSKeySet(jwksURL),
+	}
+}
+
+// validate checks the signature, expiry, issuer and audience of a bearer
+// token and returns its claims.
+func (tv *tokenValidator) validate(token string) (jwt.MapClaims, error) {
+	parser := jwt.Parser{
+		ValidMethods: []string{"RS256", "RS384", "RS512"},
+	}
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, ok := t.Header["kid"].(string)
+		if !ok {
+			return nil, fmt.Errorf("token has no key id")
+		}
+		return tv.jwks.key(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the parser only checks the expiry of tokens which have one
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, fmt.Errorf("token has no expiry")
+	}
+	if !claims.VerifyIssuer(tv.issuer, true) {
+		return nil, fmt.Errorf("token issued by unexpected issuer")
+	}
+	if !tv.issuedForClient(claims) {
+		return nil, fmt.Errorf("token issued for unexpected client")
+	}
+	return claims, nil
+}
+
+// issuedForClient fails closed, tokens aren't accepted without audiences.
+func (tv *tokenValidator) issuedForClient(claims jwt.MapClaims) bool {
+	azp, _ := claims["azp"].(string)
+	for _, client := range tv.claims.Audiences {
+		if azp == client || claims.VerifyAudience(client, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// entitlements returns what the caller is entitled to. Rhel is always
+// listed, so callers who aren't entitled to it aren't mistaken for identity
+// headers lacking the entitlement.
+func (tv *tokenValidator) entitlements(claims jwt.MapClaims) map[string]identity.ServiceDetails {
+	entitlements := map[string]identity.ServiceDetails{
+		"rhel": {},
+	}
+	for _, service := range tv.claims.Entitlements {
+		entitlements[service] = identity.ServiceDetails{IsEntitled: true}
+	}
+	if tv.claims.EntitlementsClaim == "" {
+		return entitlements
+	}
+	switch v := claims[tv.claims.EntitlementsClaim].(type) {
+	case string:
+		entitlements[v] = identity.ServiceDetails{IsEntitled: true}
+	case []interface{}:
+		for _, service := range v {
+			if s, ok := service.(string); ok {
+				entitlements[s] = identity.ServiceDetails{IsEntitled: true}
+			}
+		}
+	}
+	return entitlements
+}
+
+// identityHeaderFromToken validates a bearer token and encodes the identity
+// it represents as an identity header.
+func (tv *tokenValidator) identityHeaderFromToken(token, orgIdClaim, identityType string) (string, error) {
+	claims, err := tv.validate(token)
+	if err != nil {
+		logger.Module(logger.ModuleAuth).Warnf("Rejecting bearer token: %v", err)
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "invalid bearer token")
+	}
+
+	orgId, _ := claims[orgIdClaim].(string)
+	if orgId == "" {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, fmt.Sprintf("bearer token is missing the %s claim", orgIdClaim))
+	}
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "bearer token is missing the sub claim")
+	}
+	username, _ := claims["preferred_username"].(string)
+	email, _ := claims["email"].(string)
+
+	idHeader, err := json.Marshal(identity.XRHID{
+		Identity: identity.Identity{
+			OrgID: orgId,
+			Internal: identity.Internal{
+				OrgID: orgId,
+			},
+			User: identity.User{
+				Username: username,
+				Email:    email,
+				UserID:   subject,
+			},
+			Type: identityType,
+		},
+		Entitlements: tv.entitlements(claims),
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(idHeader), nil
+}
+
+// discoverJWKSURL looks up the key set of an issuer in its openid
+// configuration.
+func discoverJWKSURL(issuer string) (string, error) {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+	resp, err := client.Get(strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return "", err
+	}
+	defer closeBody(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching openid configuration failed with status %d", resp.StatusCode)
+	}
+
+	var conf struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&conf)
+	if err != nil {
+		return "", err
+	}
+	if conf.JWKSURI == "" {
+		return "", fmt.Errorf("openid configuration of %s has no jwks_uri", issuer)
+	}
+	return conf.JWKSURI, nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return "", false
+	}
+	return strings.TrimPrefix(auth, "Bearer "), true
+}
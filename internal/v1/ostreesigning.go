@@ -0,0 +1,129 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/ostree"
+)
+
+// signCommitRequested tells whether a compose asked for its ostree commit to
+// be signed.
+func signCommitRequested(o *OSTree) bool {
+	return o != nil && o.Sign != nil && *o.Sign
+}
+
+// commitSigningKey returns the key the commits of an org are signed with,
+// its commit key, else its artifact key, which is also the private key of
+// its artifact signing settings. Commits can't be handed to signing
+// services, ostree needs binary signatures of the commit object rather than
+// of the tarball which is uploaded.
+func (s *Server) commitSigningKey(orgId string) (*orgGPGKey, error) {
+	for _, purpose := range []SigningKeyPurpose{SigningKeyPurposeCommit, SigningKeyPurposeArtifact} {
+		key, err := s.managedGPGKey(orgId, purpose)
+		if err != nil || key != nil {
+			return key, err
+		}
+	}
+	return nil, nil
+}
+
+// checkCommitSigningKey makes sure an org which has its commits signed has a
+// private key to sign them with.
+func (h *Handlers) checkCommitSigningKey(ctx echo.Context, orgId string, o *OSTree) error {
+	if !signCommitRequested(o) {
+		return nil
+	}
+	key, err := h.server.commitSigningKey(orgId)
+	if err != nil {
+		ctx.Logger().Errorf("Error looking up the commit signing key of org %s: %v", orgId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong looking up the commit signing key")
+	}
+	if key == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Signing commits needs a commit or artifact signing key")
+	}
+	return nil
+}
+
+// GetComposeCommitSignature returns the commitmeta object holding the
+// signature of the commit of a compose.
+func (h *Handlers) GetComposeCommitSignature(ctx echo.Context, composeId uuid.UUID) error {
+	composeEntry, err := h.getComposeByIdAndOrgId(ctx, composeId)
+	if err != nil {
+		return err
+	}
+
+	signature, err := h.server.db.GetCommitSignature(composeId, composeEntry.OrgId)
+	if errors.Is(err, db.CommitSignatureNotFoundError) {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("The commit of compose %v isn't signed", composeId))
+	} else if err != nil {
+		ctx.Logger().Errorf("Error querying the commit signature of compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the commit signature of this compose")
+	}
+	return ctx.Blob(http.StatusOK, echo.MIMEOctetStream, ostree.CommitMeta([][]byte{signature.Signature}))
+}
+
+// signCommit signs the ostree commit of a successful compose which asked for
+// it. The commit object is read from the tarball at url, so only what was
+// really built gets signed.
+func (s *Server) signCommit(ctx context.Context, key *orgGPGKey, compose *db.ComposeEntry, url string) error {
+	var cr ComposeRequest
+	err := json.Unmarshal(compose.Request, &cr)
+	if err != nil {
+		return err
+	}
+	if len(cr.ImageRequests) == 0 || !signCommitRequested(cr.ImageRequests[0].Ostree) {
+		return nil
+	}
+	_, err = s.db.GetCommitSignature(compose.Id, compose.OrgId)
+	if err == nil {
+		return nil
+	} else if !errors.Is(err, db.CommitSignatureNotFoundError) {
+		return err
+	}
+	if key == nil {
+		return fmt.Errorf("the commit of compose %v can't be signed without a private key", compose.Id)
+	}
+
+	metadata, err := s.composerMetadata(ctx, compose)
+	if err != nil {
+		return err
+	}
+	if metadata.OstreeCommit == nil {
+		return fmt.Errorf("composer didn't report the commit of compose %v", compose.Id)
+	}
+	commit := *metadata.OstreeCommit
+
+	body, err := s.gpgClient.Download(ctx, url)
+	if err != nil {
+		return fmt.Errorf("downloading the commit of compose %v failed: %w", compose.Id, err)
+	}
+	defer closeBody(body)
+	object, err := ostree.ReadCommit(body, commit)
+	if err != nil {
+		return err
+	}
+	signature, err := key.SignBinary(bytes.NewReader(object))
+	if err != nil {
+		return err
+	}
+	err = s.db.InsertCommitSignature(compose.Id, db.CommitSignatureEntry{
+		Commit:    commit,
+		Signature: signature,
+	})
+	if err != nil {
+		return err
+	}
+	s.recordKeyUsage(key.id, SignCommit, compose.Id)
+	logrus.Infof("Signed commit %s of compose %v", commit, compose.Id)
+	return nil
+}
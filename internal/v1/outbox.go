@@ -0,0 +1,250 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/prometheus"
+)
+
+// The events of compose state changes are written to the outbox in the same
+// transaction as the change, one entry for every sink, and dispatched in the
+// background. Each sink gets every event at least once, even if the service
+// dies before dispatching it.
+const (
+	outboxSinkWebhooks      = "webhooks"
+	outboxSinkEvents        = "events"
+	outboxSinkNotifications = "notifications"
+	outboxSinkEmail         = "email"
+	outboxSinkAWX           = "awx"
+	outboxSinkSigning       = "signing"
+	outboxSinkInventory     = "inventory"
+	outboxSinkStream        = "stream"
+	outboxSinkScan          = "scan"
+	outboxSinkArtifacts     = "artifacts"
+	outboxSinkReplication   = "replication"
+	outboxSinkCosign        = "cosign"
+	outboxSinkEncryption    = "encryption"
+
+	outboxEventComposeCreated  = "compose_created"
+	outboxEventComposeFinished = "compose_finished"
+
+	defaultOutboxInterval = 5 * time.Second
+
+	outboxBatchSize   = 100
+	outboxMaxAttempts = 10
+	// how long dispatched entries are kept for support
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+// outboxEvent is the payload of outbox entries, each sink renders it in its
+// own format when it's dispatched.
+type outboxEvent struct {
+	composeEventData
+	// where the image of a successful compose was uploaded to
+	UploadStatus *UploadStatus `json:"upload_status,omitempty"`
+	// when the state changed
+	Time time.Time `json:"time"`
+}
+
+// composeCreatedOutbox returns the outbox entries of a created compose for
+// the sinks which are configured.
+func (s *Server) composeCreatedOutbox(composeId uuid.UUID, orgId string, cr ComposeRequest) []db.OutboxEntry {
+	sinks := []string{outboxSinkStream}
+	if s.events != nil {
+		sinks = append(sinks, outboxSinkEvents)
+	}
+	distribution, imageType, uploadTarget := composeLabels(cr)
+	return outboxEntries(sinks, outboxEventComposeCreated, outboxEvent{
+		composeEventData{
+			ComposeId:    composeId,
+			OrgId:        orgId,
+			Status:       composeEventCreated,
+			ImageName:    cr.ImageName,
+			Distribution: distribution,
+			ImageType:    imageType,
+			UploadTarget: uploadTarget,
+		},
+		nil,
+		time.Now().UTC(),
+	})
+}
+
+// composeFinishedOutbox returns the outbox entries of a finished compose for
+// its webhooks, the stream of its org and the sinks which are configured, and for the awx job
+// template of the org, recording and signing its artifacts, cloning it into
+// its additional regions, signing its container image, the inventory, the
+// vulnerability scanner and encrypting its AMI if it succeeded.
+func (s *Server) composeFinishedOutbox(composeId uuid.UUID, orgId, status string, reason *string, uploadStatus *UploadStatus) []db.OutboxEntry {
+	sinks := []string{outboxSinkWebhooks, outboxSinkStream}
+	if s.events != nil {
+		sinks = append(sinks, outboxSinkEvents)
+	}
+	if s.notifications != nil {
+		sinks = append(sinks, outboxSinkNotifications)
+	}
+	if s.mailer != nil {
+		sinks = append(sinks, outboxSinkEmail)
+	}
+	if status == string(composer.ImageStatusValueSuccess) {
+		sinks = append(sinks, outboxSinkAWX, outboxSinkArtifacts, outboxSinkSigning, outboxSinkReplication, outboxSinkCosign)
+		if s.inventory != nil {
+			sinks = append(sinks, outboxSinkInventory)
+		}
+		if s.scanner != nil {
+			sinks = append(sinks, outboxSinkScan)
+		}
+		if s.aws.EC2 != nil {
+			sinks = append(sinks, outboxSinkEncryption)
+		}
+	}
+	return outboxEntries(sinks, outboxEventComposeFinished, outboxEvent{
+		composeEventData{
+			ComposeId: composeId,
+			OrgId:     orgId,
+			Status:    status,
+			Reason:    reason,
+		},
+		uploadStatus,
+		time.Now().UTC(),
+	})
+}
+
+func outboxEntries(sinks []string, event string, payload outboxEvent) []db.OutboxEntry {
+	if len(sinks) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		// nothing in the payload fails to encode
+		logrus.Errorf("Error encoding %s event of compose %v: %v", event, payload.ComposeId, err)
+		return nil
+	}
+	var entries []db.OutboxEntry
+	for _, sink := range sinks {
+		entries = append(entries, db.OutboxEntry{
+			Sink:      sink,
+			Event:     event,
+			ComposeId: payload.ComposeId,
+			Payload:   raw,
+		})
+	}
+	return entries
+}
+
+// RunOutbox dispatches the outbox until ctx is done, and prunes old entries.
+// Entries of sinks which were configured when they were written, but aren't
+// anymore, are dropped.
+func (s *Server) RunOutbox(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastPruned := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.dispatchOutbox()
+			if time.Since(lastPruned) > time.Hour {
+				lastPruned = time.Now()
+				_, err := s.db.DeleteOutboxEntriesBefore(outboxRetention)
+				if err != nil {
+					logrus.Errorf("Error pruning the outbox: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// dispatchOutbox dispatches batches of due outbox entries until none are
+// left, entries which fail are retried with backoff.
+func (s *Server) dispatchOutbox() {
+	for {
+		entries, err := s.db.ClaimOutboxEntries(outboxBatchSize)
+		if err != nil {
+			logrus.Errorf("Error claiming outbox entries: %v", err)
+			return
+		}
+		for _, e := range entries {
+			status := db.OutboxDispatched
+			var lastError *string
+			var retryIn time.Duration
+			err := s.dispatchOutboxEntry(e)
+			if err != nil {
+				logrus.Warnf("Unable to dispatch %s event of compose %v to %s: %v", e.Event, e.ComposeId, e.Sink, err)
+				msg := err.Error()
+				lastError = &msg
+				status = db.OutboxPending
+				retryIn = retryDelay(e.Attempts + 1)
+				if e.Attempts+1 >= outboxMaxAttempts {
+					status = db.OutboxFailed
+				}
+			}
+			prometheus.OutboxDispatches.WithLabelValues(e.Sink, status).Inc()
+			err = s.db.SetOutboxEntryResult(e.Id, status, lastError, retryIn)
+			if err != nil {
+				logrus.Errorf("Error storing the result of outbox entry %v: %v", e.Id, err)
+			}
+		}
+		if len(entries) < outboxBatchSize {
+			return
+		}
+	}
+}
+
+func (s *Server) dispatchOutboxEntry(e db.OutboxEntry) error {
+	var event outboxEvent
+	err := json.Unmarshal(e.Payload, &event)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case e.Sink == outboxSinkWebhooks && e.Event == outboxEventComposeFinished:
+		return s.storeWebhookEvent(event.ComposeId, event.ComposeId, webhookEvent{
+			Event:      webhookEventComposeFinished,
+			ComposeId:  event.ComposeId,
+			Status:     event.Status,
+			Reason:     event.Reason,
+			FinishedAt: event.Time.Format(time.RFC3339),
+		})
+	case e.Sink == outboxSinkEvents && e.Event == outboxEventComposeCreated:
+		return s.publishComposeEvent(cloudEventComposeCreated, event.composeEventData, event.Time)
+	case e.Sink == outboxSinkEvents && e.Event == outboxEventComposeFinished:
+		return s.publishComposeFinished(event.composeEventData, event.Time)
+	case e.Sink == outboxSinkNotifications && e.Event == outboxEventComposeFinished:
+		return s.notifyComposeFinished(event.ComposeId, event.OrgId, event.Status, event.Reason, event.Time)
+	case e.Sink == outboxSinkEmail && e.Event == outboxEventComposeFinished:
+		return s.emailComposeFinished(event.ComposeId, event.OrgId, event.Status, event.Reason)
+	case e.Sink == outboxSinkAWX && e.Event == outboxEventComposeFinished:
+		return s.launchAWXJob(event, e.Attempts+1 >= outboxMaxAttempts)
+	case e.Sink == outboxSinkArtifacts && e.Event == outboxEventComposeFinished:
+		return s.recordArtifacts(event)
+	case e.Sink == outboxSinkSigning && e.Event == outboxEventComposeFinished:
+		return s.signArtifacts(event)
+	case e.Sink == outboxSinkReplication && e.Event == outboxEventComposeFinished:
+		return s.replicateCompose(event)
+	case e.Sink == outboxSinkCosign && e.Event == outboxEventComposeFinished:
+		return s.signContainerImage(event, e.Attempts+1 >= outboxMaxAttempts)
+	case e.Sink == outboxSinkEncryption && e.Event == outboxEventComposeFinished:
+		return s.encryptCompose(event, e.Attempts+1 >= outboxMaxAttempts)
+	case e.Sink == outboxSinkInventory && e.Event == outboxEventComposeFinished:
+		return s.registerImage(event)
+	case e.Sink == outboxSinkScan && e.Event == outboxEventComposeFinished:
+		return s.scanCompose(event)
+	case e.Sink == outboxSinkStream:
+		return s.storeOrgEvent(event.ComposeId, event.ComposeId, OrgEvent{
+			Event:     OrgEventEvent(e.Event),
+			ComposeId: event.ComposeId,
+			Time:      event.Time.Format(time.RFC3339),
+		}, event.composeEventData)
+	}
+	return fmt.Errorf("unknown sink %s or event %s", e.Sink, e.Event)
+}
@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/vulnscan"
+)
+
+func outboxSinks(entries []db.OutboxEntry) []string {
+	var sinks []string
+	for _, e := range entries {
+		sinks = append(sinks, e.Sink)
+	}
+	return sinks
+}
+
+func TestComposeOutbox(t *testing.T) {
+	id := uuid.New()
+	s := &Server{}
+	// webhooks and the stream are always served, nothing else is configured
+	require.Equal(t, []string{outboxSinkStream}, outboxSinks(s.composeCreatedOutbox(id, "000000", ComposeRequest{})))
+	require.Equal(t, []string{outboxSinkWebhooks, outboxSinkStream}, outboxSinks(s.composeFinishedOutbox(id, "000000", "failure", nil, nil)))
+	// successful composes launch the awx job template of their org and get
+	// their artifacts recorded and signed
+	require.Equal(t, []string{outboxSinkWebhooks, outboxSinkStream, outboxSinkAWX, outboxSinkArtifacts, outboxSinkSigning, outboxSinkReplication, outboxSinkCosign}, outboxSinks(s.composeFinishedOutbox(id, "000000", "success", nil, nil)))
+
+	s = &Server{
+		events:        &fakePublisher{},
+		notifications: &fakePublisher{},
+		mailer:        &fakeMailer{},
+		inventory:     &fakePublisher{},
+		scanner:       vulnscan.NewTrivy(vulnscan.TrivyConfig{}),
+	}
+	require.Equal(t, []string{outboxSinkStream, outboxSinkEvents}, outboxSinks(s.composeCreatedOutbox(id, "000000", ComposeRequest{})))
+	require.Equal(t, []string{outboxSinkWebhooks, outboxSinkStream, outboxSinkEvents, outboxSinkNotifications, outboxSinkEmail, outboxSinkAWX, outboxSinkArtifacts, outboxSinkSigning, outboxSinkReplication, outboxSinkCosign, outboxSinkInventory, outboxSinkScan},
+		outboxSinks(s.composeFinishedOutbox(id, "000000", "success", nil, nil)))
+	entries := s.composeFinishedOutbox(id, "000000", "failure", common.ToPtr("osbuild failed"), nil)
+	require.Equal(t, []string{outboxSinkWebhooks, outboxSinkStream, outboxSinkEvents, outboxSinkNotifications, outboxSinkEmail}, outboxSinks(entries))
+
+	for _, e := range entries {
+		require.Equal(t, outboxEventComposeFinished, e.Event)
+		require.Equal(t, id, e.ComposeId)
+		var event outboxEvent
+		require.NoError(t, json.Unmarshal(e.Payload, &event))
+		require.Equal(t, id, event.ComposeId)
+		require.Equal(t, "000000", event.OrgId)
+		require.Equal(t, "failure", event.Status)
+		require.Equal(t, "osbuild failed", *event.Reason)
+		require.False(t, event.Time.IsZero())
+	}
+
+	// the notifications sink renders the entry without a database
+	notifications := s.notifications.(*fakePublisher)
+	require.NoError(t, s.dispatchOutboxEntry(entries[3]))
+	require.Len(t, notifications.messages, 1)
+
+	entries[3].Sink = "unknown"
+	require.Error(t, s.dispatchOutboxEntry(entries[3]))
+}
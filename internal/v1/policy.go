@@ -0,0 +1,53 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redhatinsights/identity"
+)
+
+type composePolicyInput struct {
+	Request  ComposeRequest    `json:"request"`
+	Identity identity.Identity `json:"identity"`
+}
+
+// applyComposePolicy rejects compose requests the policy denies, or replaces
+// them with the request the policy returns. Requests are rejected if the
+// policy can't be evaluated. Returns whether the policy requires the compose
+// to be approved.
+func (s *Server) applyComposePolicy(ctx echo.Context, idHeader *identity.XRHID, composeRequest *ComposeRequest) (bool, error) {
+	if s.policy == nil {
+		return false, nil
+	}
+
+	decision, err := s.policy.Evaluate(ctx.Request().Context(), composePolicyInput{
+		Request:  *composeRequest,
+		Identity: idHeader.Identity,
+	})
+	if err != nil {
+		return false, echo.NewHTTPError(http.StatusInternalServerError, "Unable to evaluate the compose policy").SetInternal(err)
+	}
+
+	if len(decision.Deny) > 0 {
+		return false, auditRejection(ctx, reasonPolicyDenied, echo.NewHTTPError(http.StatusForbidden,
+			fmt.Sprintf("Compose request denied by policy: %s", strings.Join(decision.Deny, "; "))))
+	}
+
+	if decision.Request != nil {
+		var mutated ComposeRequest
+		err = json.Unmarshal(decision.Request, &mutated)
+		if err != nil {
+			return false, echo.NewHTTPError(http.StatusInternalServerError, "Compose policy returned an invalid request").SetInternal(err)
+		}
+		if len(mutated.ImageRequests) != 1 {
+			return false, echo.NewHTTPError(http.StatusInternalServerError, "Compose policy returned an invalid request")
+		}
+		ctx.Logger().Infof("Compose request of org %s changed by policy", idHeader.Identity.OrgID)
+		*composeRequest = mutated
+	}
+	return decision.RequireApproval, nil
+}
@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redhatinsights/identity"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/policy"
+)
+
+func TestApplyComposePolicy(t *testing.T) {
+	// the fake policy denies sharing images, pins the distribution and
+	// requires approvals
+	policySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input composePolicyInput `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		result := map[string]interface{}{"deny": []string{}}
+		switch body.Input.Identity.OrgID {
+		case "000001":
+			result["deny"] = []string{"no sharing", "no aws"}
+		case "000002":
+			req := body.Input.Request
+			req.Distribution = "rhel-9"
+			result["request"] = req
+		case "000003":
+			result["request"] = map[string]interface{}{"image_requests": []interface{}{}}
+		case "000004":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case "000005":
+			result["require_approval"] = true
+		}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"result": result}))
+	}))
+	defer policySrv.Close()
+
+	pc, err := policy.NewClient(policy.PolicyClientConfig{URL: policySrv.URL, Path: "imagebuilder/compose"})
+	require.NoError(t, err)
+	s := &Server{
+		policy: pc,
+	}
+	run := func(orgId string) (ComposeRequest, bool, error) {
+		ctx := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+		idHeader := &identity.XRHID{Identity: identity.Identity{OrgID: orgId}}
+		cr := ComposeRequest{
+			Distribution: "centos-9",
+			ImageRequests: []ImageRequest{
+				{
+					Architecture: "x86_64",
+					ImageType:    ImageTypesAws,
+				},
+			},
+		}
+		approval, err := s.applyComposePolicy(ctx, idHeader, &cr)
+		return cr, approval, err
+	}
+
+	cr, approval, err := run("000000")
+	require.NoError(t, err)
+	require.False(t, approval)
+	require.Equal(t, Distributions("centos-9"), cr.Distribution)
+
+	_, _, err = run("000001")
+	require.Equal(t, http.StatusForbidden, err.(*echo.HTTPError).Code)
+	require.Equal(t, "Compose request denied by policy: no sharing; no aws", err.(*echo.HTTPError).Message)
+
+	cr, _, err = run("000002")
+	require.NoError(t, err)
+	require.Equal(t, Distributions("rhel-9"), cr.Distribution)
+	require.Equal(t, ImageTypesAws, cr.ImageRequests[0].ImageType)
+
+	_, _, err = run("000003")
+	require.Equal(t, http.StatusInternalServerError, err.(*echo.HTTPError).Code)
+	_, _, err = run("000004")
+	require.Equal(t, http.StatusInternalServerError, err.(*echo.HTTPError).Code)
+
+	_, approval, err = run("000005")
+	require.NoError(t, err)
+	require.True(t, approval)
+
+	// without a policy everything is allowed
+	s.policy = nil
+	cr, approval, err = run("000001")
+	require.NoError(t, err)
+	require.False(t, approval)
+	require.Equal(t, Distributions("centos-9"), cr.Distribution)
+}
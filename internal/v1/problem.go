@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// The gateway tags every request with an id, which support looks up the
+// logs of a request by.
+const requestIdHeader = "X-Rh-Insights-Request-Id"
+
+const mimeProblemJSON = "application/problem+json"
+
+// requestId returns the id of a request, the one of the gateway or a new one
+// for requests which didn't pass it, and returns it to the client.
+func requestId(c echo.Context) string {
+	if id := c.Response().Header().Get(requestIdHeader); id != "" {
+		return id
+	}
+	id := c.Request().Header.Get(requestIdHeader)
+	if id == "" {
+		id = uuid.NewString()
+	}
+	c.Response().Header().Set(requestIdHeader, id)
+	return id
+}
+
+func tagRequestId(nextHandler echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		requestId(ctx)
+		return nextHandler(ctx)
+	}
+}
+
+// statusErrorCode returns the code of errors which don't carry one, derived
+// from their status, e.g. NOT_FOUND.
+func statusErrorCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		text = http.StatusText(http.StatusInternalServerError)
+	}
+	return strings.ToUpper(strings.ReplaceAll(strings.ReplaceAll(text, "-", "_"), " ", "_"))
+}
+
+// invalidParams returns the parts of a request an error of the openapi
+// validation is about.
+func invalidParams(err error) []InvalidParam {
+	var reqErr *openapi3filter.RequestError
+	if !errors.As(err, &reqErr) {
+		return nil
+	}
+	reason := reqErr.Reason
+	if reqErr.Err != nil {
+		reason = reqErr.Err.Error()
+	}
+	if reqErr.Parameter != nil {
+		return []InvalidParam{{Name: reqErr.Parameter.Name, Reason: reason}}
+	}
+
+	param := InvalidParam{Name: "body", Reason: reason}
+	var schemaErr *openapi3.SchemaError
+	if errors.As(reqErr.Err, &schemaErr) {
+		pointer := "/" + strings.Join(schemaErr.JSONPointer(), "/")
+		param.Pointer = &pointer
+		param.Reason = schemaErr.Reason
+	}
+	return []InvalidParam{param}
+}
@@ -0,0 +1,67 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	legacyrouter "github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusErrorCode(t *testing.T) {
+	require.Equal(t, "NOT_FOUND", statusErrorCode(http.StatusNotFound))
+	require.Equal(t, "REQUEST_ENTITY_TOO_LARGE", statusErrorCode(http.StatusRequestEntityTooLarge))
+	require.Equal(t, "NON_AUTHORITATIVE_INFORMATION", statusErrorCode(http.StatusNonAuthoritativeInfo))
+	require.Equal(t, "INTERNAL_SERVER_ERROR", statusErrorCode(599))
+}
+
+func TestRequestId(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(requestIdHeader, "request-1")
+	ctx := echo.New().NewContext(req, httptest.NewRecorder())
+	require.Equal(t, "request-1", requestId(ctx))
+	require.Equal(t, "request-1", ctx.Response().Header().Get(requestIdHeader))
+
+	// requests without one get a new id, which sticks
+	ctx = echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	id := requestId(ctx)
+	require.NotEmpty(t, id)
+	require.Equal(t, id, requestId(ctx))
+}
+
+func TestValidationProblem(t *testing.T) {
+	spec, err := GetSwagger()
+	require.NoError(t, err)
+	router, err := legacyrouter.NewRouter(spec)
+	require.NoError(t, err)
+	s := &Server{router: router}
+
+	e := echo.New()
+	e.HTTPErrorHandler = s.HTTPErrorHandler
+	e.POST("/api/image-builder/v1/compose", func(ctx echo.Context) error { return nil }, s.ValidateRequest)
+
+	body, err := json.Marshal(map[string]interface{}{
+		"distribution":   "rhel-8",
+		"image_requests": []map[string]interface{}{{"architecture": "sparc", "image_type": "aws", "upload_request": map[string]interface{}{"type": "aws", "options": map[string]interface{}{}}}},
+	})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/api/image-builder/v1/compose", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	var problem HTTPErrorList
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	require.Equal(t, "BAD_REQUEST", problem.Code)
+	require.NotNil(t, problem.RequestId)
+	require.NotNil(t, problem.InvalidParams)
+	params := *problem.InvalidParams
+	require.Len(t, params, 1)
+	require.Equal(t, "body", params[0].Name)
+	require.Equal(t, "/image_requests/0/architecture", *params[0].Pointer)
+}
@@ -0,0 +1,238 @@
+package v1
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/signing"
+)
+
+const DefaultProvenanceBuilderId = "https://console.redhat.com/api/image-builder"
+
+const (
+	inTotoStatementType = "https://in-toto.io/Statement/v1"
+	inTotoPayloadType   = "application/vnd.in-toto+json"
+	slsaProvenanceType  = "https://slsa.dev/provenance/v1"
+	composeBuildType    = "https://github.com/osbuild/image-builder/compose@v1"
+)
+
+type ProvenanceConfig struct {
+	// Identifies the instance in the provenance it generates, defaults to
+	// DefaultProvenanceBuilderId.
+	BuilderId string
+	// Signs the provenance of composes if asked for, which can't be asked
+	// for if nil.
+	Signer crypto.Signer
+}
+
+type provenanceStatement struct {
+	Type          string              `json:"_type"`
+	Subject       []ProvenanceSubject `json:"subject"`
+	PredicateType string              `json:"predicateType"`
+	Predicate     slsaProvenance      `json:"predicate"`
+}
+
+type slsaProvenance struct {
+	BuildDefinition slsaBuildDefinition `json:"buildDefinition"`
+	RunDetails      slsaRunDetails      `json:"runDetails"`
+}
+
+type slsaBuildDefinition struct {
+	BuildType            string                 `json:"buildType"`
+	ExternalParameters   map[string]interface{} `json:"externalParameters"`
+	InternalParameters   map[string]interface{} `json:"internalParameters,omitempty"`
+	ResolvedDependencies []slsaResource         `json:"resolvedDependencies,omitempty"`
+}
+
+type slsaResource struct {
+	URI         string                 `json:"uri"`
+	Digest      map[string]string      `json:"digest,omitempty"`
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
+}
+
+type slsaRunDetails struct {
+	Builder  slsaBuilder       `json:"builder"`
+	Metadata slsaBuildMetadata `json:"metadata"`
+}
+
+type slsaBuilder struct {
+	Id      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+type slsaBuildMetadata struct {
+	InvocationId string     `json:"invocationId"`
+	StartedOn    *time.Time `json:"startedOn,omitempty"`
+	FinishedOn   *time.Time `json:"finishedOn,omitempty"`
+}
+
+// GetComposeProvenance describes how the artifacts of a compose were built.
+// Nothing of it is stored, it's put together from the compose, its
+// artifacts and events, and the package list composer keeps as long as the
+// compose.
+func (h *Handlers) GetComposeProvenance(ctx echo.Context, composeId uuid.UUID, params GetComposeProvenanceParams) error {
+	signed := params.Signed != nil && *params.Signed
+	if signed && h.server.provenance.Signer == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "This service doesn't sign provenance")
+	}
+
+	composeEntry, err := h.getComposeByIdAndOrgId(ctx, composeId)
+	if err != nil {
+		return err
+	}
+
+	artifacts, err := h.server.db.GetComposeArtifacts(composeId, composeEntry.OrgId)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying artifacts for compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying artifacts for this compose")
+	}
+	if len(artifacts) == 0 {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Compose %v hasn't finished successfully, or its artifacts haven't been recorded yet", composeId))
+	}
+
+	metadata, err := h.composeMetadata(ctx, composeEntry)
+	if err != nil {
+		return err
+	}
+	events, err := h.server.db.GetComposeEvents(composeId)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying events of compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the events of this compose")
+	}
+
+	statement := newProvenanceStatement(h.server.provenanceBuilderId(), composeEntry, artifacts, metadata, events)
+	if len(statement.Subject) == 0 {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Compose %v only has images imported into a cloud, which have no checksum", composeId))
+	}
+	if !signed {
+		return ctx.JSON(http.StatusOK, statement)
+	}
+
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return err
+	}
+	envelope, err := signing.SignEnvelope(h.server.provenance.Signer, inTotoPayloadType, payload)
+	if err != nil {
+		ctx.Logger().Errorf("Error signing the provenance of compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong signing the provenance of this compose")
+	}
+	return ctx.JSON(http.StatusOK, envelope)
+}
+
+// provenanceBuilderId identifies this instance in the documents it
+// generates.
+func (s *Server) provenanceBuilderId() string {
+	if s.provenance.BuilderId == "" {
+		return DefaultProvenanceBuilderId
+	}
+	return s.provenance.BuilderId
+}
+
+func newProvenanceStatement(builderId string, compose *db.ComposeEntry, artifacts []db.ArtifactEntry, metadata *composer.ComposeMetadata, events []db.ComposeEventEntry) provenanceStatement {
+	var subjects []ProvenanceSubject
+	for _, a := range artifacts {
+		// cloud images have no checksum to be verified against
+		if a.Sha256 == nil {
+			continue
+		}
+		subjects = append(subjects, ProvenanceSubject{
+			Name:   a.Filename,
+			Digest: map[string]string{"sha256": *a.Sha256},
+		})
+	}
+
+	requestDigest := sha256.Sum256(compose.Request)
+	internal := map[string]interface{}{
+		"composer_job_id": compose.ComposerId.String(),
+	}
+	if compose.ComposerBackend != "" {
+		internal["composer_backend"] = compose.ComposerBackend
+	}
+	if metadata.OstreeCommit != nil {
+		internal["ostree_commit"] = *metadata.OstreeCommit
+	}
+
+	var dependencies []slsaResource
+	if metadata.Packages != nil {
+		for _, p := range *metadata.Packages {
+			dependencies = append(dependencies, packageResource(p))
+		}
+	}
+
+	startedOn := compose.CreatedAt.UTC()
+	md := slsaBuildMetadata{
+		InvocationId: compose.ComposerId.String(),
+		StartedOn:    &startedOn,
+	}
+	for _, e := range events {
+		if e.Status == string(ImageStatusStatusSuccess) {
+			finishedOn := e.CreatedAt.UTC()
+			md.FinishedOn = &finishedOn
+		}
+	}
+
+	return provenanceStatement{
+		Type:          inTotoStatementType,
+		Subject:       subjects,
+		PredicateType: slsaProvenanceType,
+		Predicate: slsaProvenance{
+			BuildDefinition: slsaBuildDefinition{
+				BuildType: composeBuildType,
+				ExternalParameters: map[string]interface{}{
+					"request":        compose.Request,
+					"request_sha256": hex.EncodeToString(requestDigest[:]),
+				},
+				InternalParameters:   internal,
+				ResolvedDependencies: dependencies,
+			},
+			RunDetails: slsaRunDetails{
+				Builder: slsaBuilder{
+					Id:      builderId,
+					Version: builderVersion(),
+				},
+				Metadata: md,
+			},
+		},
+	}
+}
+
+// packageResource is a package of the image as a package url, with the md5
+// of its header and payload.
+func packageResource(p composer.PackageMetadata) slsaResource {
+	r := slsaResource{
+		URI: sbomPackage(p).PURL(),
+	}
+	if p.Sigmd5 != "" {
+		r.Digest = map[string]string{"md5": p.Sigmd5}
+	}
+	if p.Signature != nil {
+		r.Annotations = map[string]interface{}{"signature": *p.Signature}
+	}
+	return r
+}
+
+// builderVersion is the commit the service was built from, if go knows.
+func builderVersion() map[string]string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			return map[string]string{"image-builder": s.Value}
+		}
+	}
+	return nil
+}
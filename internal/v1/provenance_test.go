@@ -0,0 +1,38 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/composer"
+)
+
+func TestPackageResource(t *testing.T) {
+	r := packageResource(composer.PackageMetadata{
+		Type:      "rpm",
+		Name:      "gcc-c++",
+		Epoch:     common.ToPtr("1"),
+		Version:   "11.4.1",
+		Release:   "2.el9",
+		Arch:      "x86_64",
+		Sigmd5:    "0ebd8c3b2583983ac2e1e6ae9b1a9ca7",
+		Signature: common.ToPtr("RSA/SHA256, Key ID 199e2f91fd431d51"),
+	})
+	require.Equal(t, "pkg:rpm/gcc-c++@11.4.1-2.el9?arch=x86_64&epoch=1", r.URI)
+	require.Equal(t, map[string]string{"md5": "0ebd8c3b2583983ac2e1e6ae9b1a9ca7"}, r.Digest)
+	require.Equal(t, "RSA/SHA256, Key ID 199e2f91fd431d51", r.Annotations["signature"])
+
+	r = packageResource(composer.PackageMetadata{
+		Type:    "rpm",
+		Name:    "bash",
+		Epoch:   common.ToPtr("0"),
+		Version: "5.1.8",
+		Release: "6.el9",
+		Arch:    "x86_64",
+	})
+	require.Equal(t, "pkg:rpm/bash@5.1.8-6.el9?arch=x86_64", r.URI)
+	require.Nil(t, r.Digest)
+	require.Nil(t, r.Annotations)
+}
@@ -0,0 +1,138 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+func validatePulpServerAddress(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "The pulp server address has to be an absolute https url")
+	}
+	return nil
+}
+
+// pulpUploadOptions fills the options of a pulp.ostree upload which the
+// request leaves out from the Pulp settings of the org.
+func (h *Handlers) pulpUploadOptions(ctx echo.Context, uo PulpOSTreeUploadRequestOptions) (*composer.PulpOSTreeUploadOptions, error) {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	options := composer.PulpOSTreeUploadOptions{
+		ServerAddress: uo.ServerAddress,
+		Repository:    uo.Repository,
+	}
+	if uo.Basepath != nil {
+		options.Basepath = *uo.Basepath
+	}
+
+	if options.ServerAddress == nil || options.Repository == nil || options.Basepath == "" {
+		settings, err := h.server.db.GetPulpSettings(idHeader.Identity.OrgID)
+		if err != nil && !errors.Is(err, db.PulpSettingsNotFoundError) {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the pulp settings").SetInternal(err)
+		}
+		// a request naming another server doesn't get the repository of
+		// the configured one
+		if settings != nil && (options.ServerAddress == nil || *options.ServerAddress == settings.ServerAddress) {
+			options.ServerAddress = &settings.ServerAddress
+			if options.Repository == nil {
+				options.Repository = settings.Repository
+			}
+			if options.Basepath == "" && settings.Basepath != nil {
+				options.Basepath = *settings.Basepath
+			}
+		}
+	}
+
+	if options.ServerAddress != nil {
+		err = validatePulpServerAddress(*options.ServerAddress)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.Basepath == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "The pulp.ostree upload target requires a basepath, in the request or the pulp settings")
+	}
+	return &options, nil
+}
+
+func (h *Handlers) GetPulpSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	settings, err := h.server.db.GetPulpSettings(idHeader.Identity.OrgID)
+	if errors.Is(err, db.PulpSettingsNotFoundError) {
+		return echo.NewHTTPError(http.StatusNotFound, "No Pulp instance is configured")
+	} else if err != nil {
+		ctx.Logger().Errorf("Error querying pulp settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the pulp settings")
+	}
+	return ctx.JSON(http.StatusOK, PulpSettings{
+		ServerAddress: settings.ServerAddress,
+		Repository:    settings.Repository,
+		Basepath:      settings.Basepath,
+		UpdatedAt:     settings.UpdatedAt.Format(time.RFC3339),
+	})
+}
+
+func (h *Handlers) UpdatePulpSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	var req PulpSettingsRequest
+	err = ctx.Bind(&req)
+	if err != nil {
+		return err
+	}
+	err = validatePulpServerAddress(req.ServerAddress)
+	if err != nil {
+		return err
+	}
+
+	err = h.server.db.SetPulpSettings(db.PulpSettingsEntry{
+		OrgId:         idHeader.Identity.OrgID,
+		ServerAddress: req.ServerAddress,
+		Repository:    req.Repository,
+		Basepath:      req.Basepath,
+	})
+	if err != nil {
+		ctx.Logger().Errorf("Error updating pulp settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong updating the pulp settings")
+	}
+
+	logAction(ctx, "update_pulp_settings", logrus.Fields{"org_id": idHeader.Identity.OrgID, "server_address": req.ServerAddress}, "Pulp settings updated")
+	return h.GetPulpSettings(ctx)
+}
+
+func (h *Handlers) DeletePulpSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = h.server.db.DeletePulpSettings(idHeader.Identity.OrgID)
+	if errors.Is(err, db.PulpSettingsNotFoundError) {
+		return echo.NewHTTPError(http.StatusNotFound, "No Pulp instance is configured")
+	} else if err != nil {
+		ctx.Logger().Errorf("Error deleting pulp settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong deleting the pulp settings")
+	}
+
+	logAction(ctx, "delete_pulp_settings", logrus.Fields{"org_id": idHeader.Identity.OrgID}, "Pulp settings deleted")
+	return ctx.NoContent(http.StatusOK)
+}
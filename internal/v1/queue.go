@@ -0,0 +1,267 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+// Upper bound of composes submitted for orgs without a concurrent build
+// limit, which only have queued composes if the limit was lifted.
+const queueBatchSize = 100
+
+// queueCompose stores a compose which exceeds the concurrent build limit of
+// the org, or which needs to be approved, it gets submitted to composer by
+// the queue once other builds finished. Its relations are stored with it, so
+// a queued compose isn't left without them.
+func (h *Handlers) queueCompose(ctx echo.Context, pc *preparedCompose, relations *db.ComposeRelations) (uuid.UUID, error) {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	composeRequest := pc.request
+	pendingApproval := pc.approval
+	composeId := uuid.New()
+	rawCloudCR, err := json.Marshal(pc.cloudCR)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	rawCR, err := json.Marshal(composeRequest)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = h.server.db.InsertQueuedCompose(composeId, idHeader.Identity.AccountNumber, idHeader.Identity.User.Email, idHeader.Identity.Internal.OrgID, composeRequest.ImageName, rawCR, rawCloudCR, pendingApproval, relations, h.server.composeCreatedOutbox(composeId, idHeader.Identity.OrgID, composeRequest)...)
+	if errors.Is(err, db.IdempotencyKeyExistsError) {
+		return uuid.Nil, err
+	} else if err != nil {
+		ctx.Logger().Errorf("Error queueing compose: %v", err)
+		return uuid.Nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong queueing the compose")
+	}
+
+	composeCreated(composeRequest)
+	h.server.recordComposeEvent(composeId, composeEventCreated, nil)
+	if pendingApproval {
+		h.server.recordComposeEvent(composeId, composeEventPendingApproval, nil)
+		logAction(ctx, "request_approval", logrus.Fields{"compose_id": composeId}, "Compose pending approval")
+		h.server.notifyApproval(approvalEvent{
+			Event:     approvalEventRequested,
+			ComposeId: composeId,
+			OrgId:     idHeader.Identity.OrgID,
+			User:      idHeader.Identity.User.Email,
+		})
+	} else {
+		h.server.recordComposeEvent(composeId, composeEventQueued, nil)
+	}
+	ctx.Logger().Infof("Queued compose %v of org %s", composeId, idHeader.Identity.OrgID)
+	return composeId, nil
+}
+
+func queuedComposeStatus(composeEntry *db.ComposeEntry, queued *db.QueuedComposeEntry) (*ComposeStatus, error) {
+	var composeRequest ComposeRequest
+	err := json.Unmarshal(composeEntry.Request, &composeRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	status := ComposeStatus{
+		ImageStatus: ImageStatus{
+			Status: ImageStatusStatusQueued,
+		},
+		Request: composeRequest,
+	}
+	if queued.PendingApproval {
+		status.ImageStatus.Status = ImageStatusStatusPendingApproval
+	}
+	if queued.Error != nil {
+		status.ImageStatus.Status = ImageStatusStatusFailure
+		status.ImageStatus.Error = &ComposeStatusError{
+			Reason: *queued.Error,
+		}
+	}
+	return &status, nil
+}
+
+// RunComposeQueue submits queued composes until ctx is done.
+func (s *Server) RunComposeQueue(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.processComposeQueue()
+		}
+	}
+}
+
+// processComposeQueue submits the queued composes of each org, as far as its
+// concurrent build limit allows. Errors are logged, the queue is processed
+// again on the next tick.
+func (s *Server) processComposeQueue() {
+	orgs, err := s.db.GetOrgsWithQueuedComposes()
+	if err != nil {
+		logrus.Errorf("Error querying the compose queue: %v", err)
+		return
+	}
+
+	for _, orgId := range orgs {
+		concurrentBuilds, err := s.refreshBuildSlots(orgId)
+		if err != nil {
+			logrus.Errorf("Error querying the build slots of org %s: %v", orgId, err)
+			continue
+		}
+
+		queued, err := s.db.ClaimQueuedComposes(orgId, queueBatchSize, concurrentBuilds, unfinishedComposeWindow)
+		if err != nil {
+			logrus.Errorf("Error claiming queued composes of org %s: %v", orgId, err)
+			continue
+		}
+		for _, q := range queued {
+			err = s.submitQueuedCompose(q)
+			if err != nil {
+				logrus.Errorf("Error submitting queued compose %v: %v", q.ComposeId, err)
+			}
+		}
+	}
+}
+
+// refreshBuildSlots returns the concurrent build limit of an org, nil if it
+// doesn't have one. The status of unfinished composes is refreshed first, as
+// it's otherwise only recorded when users poll it. The free slots are counted
+// when the queued composes are claimed, along with the claims and
+// reservations of other replicas.
+func (s *Server) refreshBuildSlots(orgId string) (*int, error) {
+	quota, err := s.getQuota(orgId)
+	if errors.Is(err, db.QuotaNotFoundError) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if quota.ConcurrentBuilds == nil {
+		return nil, nil
+	}
+
+	unfinished, err := s.db.GetUnfinishedComposesSince(orgId, unfinishedComposeWindow)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range unfinished {
+		imageStatus, err := s.composeStatus(&c)
+		if err != nil {
+			// still counted, it might still be building
+			logrus.Warnf("Unable to refresh status of compose %v: %v", c.Id, err)
+			continue
+		}
+		err = s.recordComposeStatus(c, imageStatus)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return quota.ConcurrentBuilds, nil
+}
+
+func (s *Server) composeStatus(compose *db.ComposeEntry) (composer.ImageStatus, error) {
+	cc, err := s.composerOf(compose)
+	if err != nil {
+		return composer.ImageStatus{}, err
+	}
+	resp, err := cc.ComposeStatus(context.Background(), compose.ComposerId)
+	if err != nil {
+		return composer.ImageStatus{}, err
+	}
+	defer closeBody(resp.Body)
+
+	if resp.StatusCode == http.StatusNotFound {
+		// expired in composer, so it finished a long time ago
+		return composer.ImageStatus{
+			Status: composer.ImageStatusValueFailure,
+			Error: &composer.ComposeStatusError{
+				Reason: "The compose expired in the build system",
+			},
+		}, nil
+	} else if resp.StatusCode != http.StatusOK {
+		return composer.ImageStatus{}, fmt.Errorf("composer responded with %d", resp.StatusCode)
+	}
+
+	var cloudStat composer.ComposeStatus
+	err = json.NewDecoder(resp.Body).Decode(&cloudStat)
+	if err != nil {
+		return composer.ImageStatus{}, err
+	}
+	return cloudStat.ImageStatus, nil
+}
+
+// submitQueuedCompose sends a queued compose to composer. Composes composer
+// refuses are marked as failed, on other errors they stay claimed until the
+// claim expires and are retried then.
+func (s *Server) submitQueuedCompose(q db.QueuedComposeEntry) error {
+	var cloudCR composer.ComposeRequest
+	err := json.Unmarshal(q.ComposerRequest, &cloudCR)
+	if err != nil {
+		return s.failQueuedCompose(q.ComposeId, q.OrgId, "Unable to read the queued compose request")
+	}
+
+	backend, cc := s.routeCompose(cloudCR)
+	resp, err := cc.Compose(context.Background(), cloudCR)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp.Body)
+
+	if resp.StatusCode >= http.StatusBadRequest && resp.StatusCode < http.StatusInternalServerError {
+		reason := "Composer refused the compose request"
+		var serviceStat composer.Error
+		body, err := io.ReadAll(resp.Body)
+		if err == nil && json.Unmarshal(body, &serviceStat) == nil && serviceStat.Reason != "" {
+			reason = serviceStat.Reason
+		}
+		logrus.Warnf("Composer refused queued compose %v: %s", q.ComposeId, body)
+		return s.failQueuedCompose(q.ComposeId, q.OrgId, reason)
+	} else if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("composer responded with %d", resp.StatusCode)
+	}
+
+	var composeResult composer.ComposeId
+	err = json.NewDecoder(resp.Body).Decode(&composeResult)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.CompleteQueuedCompose(q.ComposeId, composeResult.Id)
+	if err != nil {
+		return err
+	}
+	if backend != "" {
+		err = s.db.SetComposeBackend(q.ComposeId, backend)
+		if err != nil {
+			return err
+		}
+	}
+	s.recordComposeEvent(q.ComposeId, composeEventSubmitted, nil)
+	logrus.Infof("Submitted queued compose %v of org %s as %v", q.ComposeId, q.OrgId, composeResult.Id)
+	return nil
+}
+
+func (s *Server) failQueuedCompose(composeId uuid.UUID, orgId, reason string) error {
+	err := s.db.FailQueuedCompose(composeId, reason, s.composeFinishedOutbox(composeId, orgId, composeEventFailure, &reason, nil)...)
+	if err != nil {
+		return err
+	}
+	s.recordComposeEvent(composeId, composeEventFailure, &reason)
+	return nil
+}
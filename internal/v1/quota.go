@@ -0,0 +1,419 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+// Composes which haven't been seen to finish within this window aren't
+// counted as concurrent builds anymore. Their status is only known if someone
+// polled it, and composer gives up on builds long before.
+const unfinishedComposeWindow = 24 * time.Hour
+
+type SupportQuota struct {
+	OrgId            string `json:"org_id"`
+	BuildsPerDay     *int   `json:"builds_per_day"`
+	BuildsPerMonth   *int   `json:"builds_per_month"`
+	ConcurrentBuilds *int   `json:"concurrent_builds"`
+	UpdatedAt        string `json:"updated_at,omitempty"`
+}
+
+// SupportQuotaBoost is added to the limits of a quota until it expires,
+// limits which aren't set stay unlimited.
+type SupportQuotaBoost struct {
+	Id               uuid.UUID `json:"id"`
+	OrgId            string    `json:"org_id"`
+	BuildsPerDay     int       `json:"builds_per_day"`
+	BuildsPerMonth   int       `json:"builds_per_month"`
+	ConcurrentBuilds int       `json:"concurrent_builds"`
+	Reason           string    `json:"reason"`
+	CreatedBy        string    `json:"created_by"`
+	CreatedAt        string    `json:"created_at"`
+	ExpiresAt        string    `json:"expires_at"`
+}
+
+// Boosts are meant to get customers through escalations, permanent changes
+// belong into the quota.
+const maxQuotaBoostDuration = 31 * 24 * time.Hour
+
+type quotaPeriod struct {
+	start time.Time
+	reset time.Time
+}
+
+// quotaPeriods returns the day and month now falls into, in UTC.
+func quotaPeriods(now time.Time) (day, month quotaPeriod) {
+	now = now.UTC()
+	day = quotaPeriod{
+		start: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		reset: time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC),
+	}
+	month = quotaPeriod{
+		start: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+		reset: time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	return day, month
+}
+
+// getQuota returns the quota of an org, raised by the boosts which haven't
+// expired yet.
+func (s *Server) getQuota(orgId string) (*db.QuotaEntry, error) {
+	quota, err := s.db.GetQuota(orgId)
+	if err != nil {
+		return nil, err
+	}
+	boosts, err := s.db.GetQuotaBoosts(orgId)
+	if err != nil {
+		return nil, err
+	}
+	boost := func(limit *int, extra int) *int {
+		if limit == nil {
+			return nil
+		}
+		boosted := *limit + extra
+		return &boosted
+	}
+	for _, b := range boosts {
+		quota.BuildsPerDay = boost(quota.BuildsPerDay, b.BuildsPerDay)
+		quota.BuildsPerMonth = boost(quota.BuildsPerMonth, b.BuildsPerMonth)
+		quota.ConcurrentBuilds = boost(quota.ConcurrentBuilds, b.ConcurrentBuilds)
+	}
+	return quota, nil
+}
+
+// quotaExceeded returns the error for a limit which has been reached, a limit
+// of zero means the org isn't allowed to build at all.
+func quotaExceeded(ctx echo.Context, limit int, message string, retryAfter time.Duration) error {
+	if limit == 0 {
+		return auditRejection(ctx, reasonQuotaExceeded, echo.NewHTTPError(http.StatusForbidden, "Builds are disabled for this organization"))
+	}
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	ctx.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return auditRejection(ctx, reasonQuotaExceeded, echo.NewHTTPError(http.StatusTooManyRequests, message))
+}
+
+// checkComposeQuota enforces the quota file and the quota of an org stored in
+// the db for a number of new builds. The builds are reserved until release is
+// called, which has to happen once they're stored as composes.
+func (s *Server) checkComposeQuota(ctx echo.Context, orgId string, builds int) (queue bool, release func(), err error) {
+	quotaOk, err := common.CheckQuota(orgId, s.db, s.current().quotaFile, builds)
+	if err != nil {
+		return false, nil, err
+	}
+	if !quotaOk {
+		return false, nil, auditRejection(ctx, reasonQuotaExceeded, echo.NewHTTPError(http.StatusForbidden, "Quota exceeded for user"))
+	}
+	return s.checkBuildQuota(ctx, orgId, builds)
+}
+
+// checkBuildQuota enforces the quota of an org stored in the db. Daily and
+// monthly limits reset at the start of the day and month in UTC. Builds
+// exceeding the concurrent build limit aren't rejected, but should be queued.
+// The builds are reserved while they're checked, so concurrent requests of
+// the org can't exceed the quota together.
+func (s *Server) checkBuildQuota(ctx echo.Context, orgId string, builds int) (queue bool, release func(), err error) {
+	release = func() {}
+	quota, err := s.getQuota(orgId)
+	if errors.Is(err, db.QuotaNotFoundError) {
+		return false, release, nil
+	} else if err != nil {
+		return false, nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the quota").SetInternal(err)
+	}
+	if quota.BuildsPerDay == nil && quota.BuildsPerMonth == nil && quota.ConcurrentBuilds == nil {
+		return false, release, nil
+	}
+	if quota.ConcurrentBuilds != nil && *quota.ConcurrentBuilds == 0 {
+		return false, nil, quotaExceeded(ctx, 0, "", 0)
+	}
+
+	now := time.Now().UTC()
+	day, month := quotaPeriods(now)
+	reservation := uuid.New()
+	usage, err := s.db.ReserveBuilds(reservation, orgId, builds, now.Sub(day.start), now.Sub(month.start), unfinishedComposeWindow)
+	if err != nil {
+		return false, nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the quota").SetInternal(err)
+	}
+	release = func() {
+		err := s.db.ReleaseBuilds(reservation)
+		if err != nil {
+			// it expires
+			logrus.Errorf("Error releasing the reserved builds of org %s: %v", orgId, err)
+		}
+	}
+
+	limits := []struct {
+		name  string
+		limit *int
+		count int
+		quotaPeriod
+	}{
+		{
+			name:        "daily",
+			limit:       quota.BuildsPerDay,
+			count:       usage.BuildsToday,
+			quotaPeriod: day,
+		},
+		{
+			name:        "monthly",
+			limit:       quota.BuildsPerMonth,
+			count:       usage.BuildsThisMonth,
+			quotaPeriod: month,
+		},
+	}
+	for _, l := range limits {
+		if l.limit == nil {
+			continue
+		}
+		if l.count+builds > *l.limit {
+			release()
+			return false, nil, quotaExceeded(ctx, *l.limit,
+				fmt.Sprintf("The %s quota of %d builds is used up, it resets at %s", l.name, *l.limit, l.reset.Format(time.RFC3339)),
+				l.reset.Sub(now))
+		}
+	}
+
+	// queue behind the composes which are already waiting
+	if quota.ConcurrentBuilds != nil && (usage.BuildsInProgress+builds > *quota.ConcurrentBuilds || usage.Queued) {
+		return true, release, nil
+	}
+	return false, release, nil
+}
+
+// GetUsage returns the quota of the org of the caller and how much of it is
+// used up.
+func (h *Handlers) GetUsage(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+	orgId := idHeader.Identity.OrgID
+	usageError := func(err error) error {
+		ctx.Logger().Errorf("Error querying usage of org %s: %v", orgId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the usage")
+	}
+
+	now := time.Now().UTC()
+	day, month := quotaPeriods(now)
+	usage := Usage{
+		Quota: UsageQuota{
+			DailyReset:   day.reset.Format(time.RFC3339),
+			MonthlyReset: month.reset.Format(time.RFC3339),
+		},
+	}
+
+	quota, err := h.server.getQuota(orgId)
+	if err == nil {
+		usage.Quota.BuildsPerDay = quota.BuildsPerDay
+		usage.Quota.BuildsPerMonth = quota.BuildsPerMonth
+		usage.Quota.ConcurrentBuilds = quota.ConcurrentBuilds
+	} else if !errors.Is(err, db.QuotaNotFoundError) {
+		return usageError(err)
+	}
+
+	usage.BuildsToday, err = h.server.db.CountComposesSince(orgId, now.Sub(day.start))
+	if err != nil {
+		return usageError(err)
+	}
+	usage.BuildsThisMonth, err = h.server.db.CountComposesSince(orgId, now.Sub(month.start))
+	if err != nil {
+		return usageError(err)
+	}
+	usage.BuildsInProgress, err = h.server.db.CountUnfinishedComposesSince(orgId, unfinishedComposeWindow)
+	if err != nil {
+		return usageError(err)
+	}
+	usage.BuildsQueued, err = h.server.db.CountQueuedComposes(orgId)
+	if err != nil {
+		return usageError(err)
+	}
+	usage.StorageBytes, err = h.server.db.GetStorageUsage(orgId)
+	if err != nil {
+		return usageError(err)
+	}
+
+	return ctx.JSON(http.StatusOK, usage)
+}
+
+// GetSupportQuota returns the quota of an org, all limits are null if it
+// doesn't have one.
+func (h *Handlers) GetSupportQuota(ctx echo.Context) error {
+	orgId := ctx.Param("orgId")
+	quota, err := h.server.db.GetQuota(orgId)
+	if errors.Is(err, db.QuotaNotFoundError) {
+		return ctx.JSON(http.StatusOK, SupportQuota{
+			OrgId: orgId,
+		})
+	} else if err != nil {
+		ctx.Logger().Errorf("Error querying quota of org %s: %v", orgId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the quota")
+	}
+
+	return ctx.JSON(http.StatusOK, SupportQuota{
+		OrgId:            quota.OrgId,
+		BuildsPerDay:     quota.BuildsPerDay,
+		BuildsPerMonth:   quota.BuildsPerMonth,
+		ConcurrentBuilds: quota.ConcurrentBuilds,
+		UpdatedAt:        quota.UpdatedAt.Format(time.RFC3339),
+	})
+}
+
+// UpdateSupportQuota replaces the quota of an org, null limits aren't
+// enforced.
+func (h *Handlers) UpdateSupportQuota(ctx echo.Context) error {
+	orgId := ctx.Param("orgId")
+
+	var req SupportQuota
+	err := ctx.Bind(&req)
+	if err != nil {
+		return err
+	}
+	for _, limit := range []*int{req.BuildsPerDay, req.BuildsPerMonth, req.ConcurrentBuilds} {
+		if limit != nil && *limit < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Quota limits can not be negative")
+		}
+	}
+
+	err = h.server.db.SetQuota(db.QuotaEntry{
+		OrgId:            orgId,
+		BuildsPerDay:     req.BuildsPerDay,
+		BuildsPerMonth:   req.BuildsPerMonth,
+		ConcurrentBuilds: req.ConcurrentBuilds,
+	})
+	if err != nil {
+		ctx.Logger().Errorf("Error updating quota of org %s: %v", orgId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong updating the quota")
+	}
+
+	logAction(ctx, "update_quota", logrus.Fields{
+		"org_id":            orgId,
+		"builds_per_day":    req.BuildsPerDay,
+		"builds_per_month":  req.BuildsPerMonth,
+		"concurrent_builds": req.ConcurrentBuilds,
+	}, fmt.Sprintf("Updated the quota of org %s", orgId))
+	return h.GetSupportQuota(ctx)
+}
+
+func supportQuotaBoost(b db.QuotaBoostEntry) SupportQuotaBoost {
+	return SupportQuotaBoost{
+		Id:               b.Id,
+		OrgId:            b.OrgId,
+		BuildsPerDay:     b.BuildsPerDay,
+		BuildsPerMonth:   b.BuildsPerMonth,
+		ConcurrentBuilds: b.ConcurrentBuilds,
+		Reason:           b.Reason,
+		CreatedBy:        b.CreatedBy,
+		CreatedAt:        b.CreatedAt.Format(time.RFC3339),
+		ExpiresAt:        b.ExpiresAt.Format(time.RFC3339),
+	}
+}
+
+// GetSupportQuotaBoosts lists the boosts of an org which haven't expired.
+func (h *Handlers) GetSupportQuotaBoosts(ctx echo.Context) error {
+	orgId := ctx.Param("orgId")
+	boostEntries, err := h.server.db.GetQuotaBoosts(orgId)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying quota boosts of org %s: %v", orgId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the quota boosts")
+	}
+
+	boosts := []SupportQuotaBoost{}
+	for _, b := range boostEntries {
+		boosts = append(boosts, supportQuotaBoost(b))
+	}
+	return ctx.JSON(http.StatusOK, boosts)
+}
+
+// CreateSupportQuotaBoost temporarily raises the quota of an org, the
+// associate has to state why.
+func (h *Handlers) CreateSupportQuotaBoost(ctx echo.Context) error {
+	orgId := ctx.Param("orgId")
+
+	var req SupportQuotaBoost
+	err := ctx.Bind(&req)
+	if err != nil {
+		return err
+	}
+	if req.BuildsPerDay < 0 || req.BuildsPerMonth < 0 || req.ConcurrentBuilds < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Quota boosts can not be negative")
+	}
+	if req.BuildsPerDay == 0 && req.BuildsPerMonth == 0 && req.ConcurrentBuilds == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Quota boost doesn't raise any limit")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Quota boosts need a reason")
+	}
+	expiresAt, err := time.Parse(time.RFC3339, req.ExpiresAt)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid expiry, expected a RFC3339 timestamp")
+	}
+	expiresIn := time.Until(expiresAt)
+	if expiresIn <= 0 || expiresIn > maxQuotaBoostDuration {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Quota boosts have to expire within %d days", int(maxQuotaBoostDuration.Hours()/24)))
+	}
+
+	idh, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+	boost, err := h.server.db.InsertQuotaBoost(db.QuotaBoostEntry{
+		Id:               uuid.New(),
+		OrgId:            orgId,
+		BuildsPerDay:     req.BuildsPerDay,
+		BuildsPerMonth:   req.BuildsPerMonth,
+		ConcurrentBuilds: req.ConcurrentBuilds,
+		Reason:           req.Reason,
+		CreatedBy:        idh.Identity.Associate.Email,
+	}, expiresIn)
+	if err != nil {
+		ctx.Logger().Errorf("Error inserting quota boost of org %s: %v", orgId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong creating the quota boost")
+	}
+
+	logAction(ctx, "create_quota_boost", logrus.Fields{
+		"org_id":            orgId,
+		"boost_id":          boost.Id,
+		"builds_per_day":    boost.BuildsPerDay,
+		"builds_per_month":  boost.BuildsPerMonth,
+		"concurrent_builds": boost.ConcurrentBuilds,
+		"expires_at":        boost.ExpiresAt.Format(time.RFC3339),
+		"reason":            boost.Reason,
+	}, fmt.Sprintf("Boosted the quota of org %s", orgId))
+	setAuditResource(ctx, boost.Id)
+
+	return ctx.JSON(http.StatusCreated, supportQuotaBoost(*boost))
+}
+
+// DeleteSupportQuotaBoost revokes a boost before it expires.
+func (h *Handlers) DeleteSupportQuotaBoost(ctx echo.Context) error {
+	orgId := ctx.Param("orgId")
+	boostId, err := uuid.Parse(ctx.Param("boostId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid quota boost id")
+	}
+
+	err = h.server.db.DeleteQuotaBoost(boostId, orgId)
+	if errors.Is(err, db.QuotaBoostNotFoundError) {
+		return echo.NewHTTPError(http.StatusNotFound, "Quota boost not found")
+	} else if err != nil {
+		ctx.Logger().Errorf("Error deleting quota boost %v: %v", boostId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong deleting the quota boost")
+	}
+
+	logAction(ctx, "delete_quota_boost", logrus.Fields{
+		"org_id":   orgId,
+		"boost_id": boostId,
+	}, fmt.Sprintf("Revoked a quota boost of org %s", orgId))
+	return ctx.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,33 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaExceeded(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+	err := quotaExceeded(ctx, 10, "used up", 90*time.Second+time.Millisecond)
+	require.Equal(t, http.StatusTooManyRequests, err.(*echo.HTTPError).Code)
+	require.Equal(t, "91", rec.Header().Get("Retry-After"))
+
+	rec = httptest.NewRecorder()
+	ctx = echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+	err = quotaExceeded(ctx, 0, "used up", time.Hour)
+	require.Equal(t, http.StatusForbidden, err.(*echo.HTTPError).Code)
+	require.Empty(t, rec.Header().Get("Retry-After"))
+}
+
+func TestQuotaPeriods(t *testing.T) {
+	day, month := quotaPeriods(time.Date(2023, time.December, 31, 22, 30, 0, 0, time.FixedZone("", -2*60*60)))
+	require.Equal(t, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), day.start)
+	require.Equal(t, time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC), day.reset)
+	require.Equal(t, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), month.start)
+	require.Equal(t, time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), month.reset)
+}
@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// rateLimit limits the requests of each user of an org, identities without a
+// username share the bucket of their org. Requests are let through if the
+// limiter fails, limiting is not worth an outage.
+func (s *Server) rateLimit(nextHandler echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		if s.rateLimiter == nil {
+			return nextHandler(ctx)
+		}
+
+		idHeader, err := getIdentityHeader(ctx)
+		if err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s/%s", idHeader.Identity.OrgID, idHeader.Identity.User.Username)
+
+		result, err := s.rateLimiter.Take(ctx.Request().Context(), key)
+		if err != nil {
+			ctx.Logger().Errorf("Error applying the rate limit, letting the request through: %v", err)
+			return nextHandler(ctx)
+		}
+
+		header := ctx.Response().Header()
+		header.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("X-RateLimit-Reset", ceilSeconds(result.Reset))
+		if !result.Allowed {
+			header.Set("Retry-After", ceilSeconds(result.RetryAfter))
+			return echo.NewHTTPError(http.StatusTooManyRequests, fmt.Sprintf("Too many requests, retry in %s seconds", ceilSeconds(result.RetryAfter)))
+		}
+		return nextHandler(ctx)
+	}
+}
@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/ratelimit"
+	"github.com/osbuild/image-builder/pkg/tutils"
+)
+
+type failingLimiter struct{}
+
+func (failingLimiter) Take(context.Context, string) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("redis is down")
+}
+
+func TestRateLimit(t *testing.T) {
+	s := &Server{
+		auth:        NewIdentityHeaderAuthenticator(ServiceAccountConfig{}),
+		rateLimiter: ratelimit.NewMemoryLimiter(ratelimit.Config{Rate: 1.0 / 60, Burst: 2}),
+	}
+	run := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/image-builder/v1/composes", nil)
+		req.Header.Set("X-Rh-Identity", auth)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		err := s.authenticate(s.rateLimit(func(ctx echo.Context) error {
+			return ctx.NoContent(http.StatusOK)
+		}))(ctx)
+		if err != nil {
+			rec.Code = err.(*echo.HTTPError).Code
+		}
+		return rec
+	}
+
+	rec := run(tutils.AuthString0)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("X-RateLimit-Limit"))
+	require.Equal(t, "1", rec.Header().Get("X-RateLimit-Remaining"))
+	require.Equal(t, "60", rec.Header().Get("X-RateLimit-Reset"))
+
+	rec = run(tutils.AuthString0)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+
+	rec = run(tutils.AuthString0)
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "60", rec.Header().Get("Retry-After"))
+
+	// other orgs have their own bucket
+	rec = run(tutils.AuthString1)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	s.rateLimiter = failingLimiter{}
+	rec = run(tutils.AuthString0)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get("X-RateLimit-Limit"))
+}
@@ -0,0 +1,217 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/labstack/echo/v4"
+)
+
+type role int
+
+const (
+	roleNone role = iota
+	// can view composes, clones and their statuses
+	roleViewer
+	// can additionally build, clone and delete images
+	roleBuilder
+	// can additionally change the settings and policies of the org
+	roleAdmin
+)
+
+func (r role) String() string {
+	switch r {
+	case roleViewer:
+		return "viewer"
+	case roleBuilder:
+		return "builder"
+	case roleAdmin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+// Operations which change the settings of an org, all other operations
+// require the viewer role for GET requests and the builder role otherwise.
+// Operation ids are compared in lower case, the embedded spec has them
+// capitalized.
+var adminOperations = map[string]bool{
+	"getapitokens":   true,
+	"createapitoken": true,
+	"revokeapitoken": true,
+
+	"getipallowlist":    true,
+	"updateipallowlist": true,
+
+	"getuploadtargetpolicy":    true,
+	"updateuploadtargetpolicy": true,
+
+	"getapprovalsettings":    true,
+	"updateapprovalsettings": true,
+
+	"getsecretscanningsettings":    true,
+	"updatesecretscanningsettings": true,
+
+	"getrepositorysignaturesettings":    true,
+	"updaterepositorysignaturesettings": true,
+
+	"getcomplianceexportsettings":    true,
+	"updatecomplianceexportsettings": true,
+	"deletecomplianceexportsettings": true,
+	"getcomplianceexports":           true,
+
+	"getauditlog": true,
+
+	"getwebhooks":          true,
+	"createwebhook":        true,
+	"deletewebhook":        true,
+	"getwebhookdeliveries": true,
+
+	"getsigningkeys":     true,
+	"createsigningkey":   true,
+	"getsigningkey":      true,
+	"rotatesigningkey":   true,
+	"revokesigningkey":   true,
+	"getsigningkeyusage": true,
+
+	"getawxsettings":    true,
+	"updateawxsettings": true,
+	"deleteawxsettings": true,
+	"getawxjobs":        true,
+
+	"getartifactsigningsettings":    true,
+	"updateartifactsigningsettings": true,
+	"deleteartifactsigningsettings": true,
+
+	"getpulpsettings":    true,
+	"updatepulpsettings": true,
+	"deletepulpsettings": true,
+}
+
+var publicOperations = map[string]bool{
+	"getversion":     true,
+	"getreadiness":   true,
+	"getopenapijson": true,
+}
+
+// Operations which System identities, i.e. cert authenticated hosts and
+// in-cluster services, are allowed to use. They can only look up metadata of
+// composes.
+var systemOperations = map[string]bool{
+	"getversion":          true,
+	"getreadiness":        true,
+	"getopenapijson":      true,
+	"getcomposestatus":    true,
+	"getcompose":          true,
+	"getcomposemetadata":  true,
+	"getcomposeartifacts": true,
+	"getclonestatus":      true,
+}
+
+func operationId(route *routers.Route) string {
+	if route.Operation == nil {
+		return ""
+	}
+	return strings.ToLower(route.Operation.OperationID)
+}
+
+func requiredRole(route *routers.Route) role {
+	if publicOperations[operationId(route)] {
+		return roleNone
+	}
+	if adminOperations[operationId(route)] {
+		return roleAdmin
+	}
+	if route.Method == http.MethodGet || route.Method == http.MethodHead {
+		return roleViewer
+	}
+	return roleBuilder
+}
+
+// roleFromPermissions maps rbac permissions of the form
+// "image-builder:resource:verb" to the highest role they grant.
+func roleFromPermissions(permissions []string) role {
+	r := roleNone
+	for _, p := range permissions {
+		parts := strings.Split(p, ":")
+		if len(parts) != 3 || parts[0] != "image-builder" {
+			continue
+		}
+		resource, verb := parts[1], parts[2]
+
+		granted := roleNone
+		switch {
+		case (resource == "*" || resource == "settings") && (verb == "*" || verb == "write"):
+			granted = roleAdmin
+		case verb == "*" || verb == "write":
+			granted = roleBuilder
+		case verb == "read":
+			granted = roleViewer
+		}
+		if granted > r {
+			r = granted
+		}
+	}
+	return r
+}
+
+// callerRole returns the role of the caller. Org admins can do everything,
+// as can everyone if rbac is not configured. Systems have no permissions in
+// rbac, they are limited to systemOperations instead.
+func (s *Server) callerRole(ctx echo.Context) (role, error) {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return roleNone, err
+	}
+	if idHeader.Identity.Type == "System" {
+		return roleViewer, nil
+	}
+
+	if s.rbac == nil {
+		return roleAdmin, nil
+	}
+	if idHeader.Identity.User.OrgAdmin {
+		return roleAdmin, nil
+	}
+
+	permissions, err := s.rbac.Permissions(ctx.Request().Context())
+	if err != nil {
+		return roleNone, echo.NewHTTPError(http.StatusInternalServerError, "Unable to query permissions").SetInternal(err)
+	}
+	return roleFromPermissions(permissions), nil
+}
+
+func (s *Server) enforceRoles(nextHandler echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		route, _, err := s.router.FindRoute(ctx.Request())
+		if err != nil {
+			// unknown routes get rejected by ValidateRequest
+			return nextHandler(ctx)
+		}
+
+		idHeader, err := getIdentityHeader(ctx)
+		if err != nil {
+			return err
+		}
+		if idHeader.Identity.Type == "System" && !systemOperations[operationId(route)] {
+			return auditRejection(ctx, reasonSystemDenied, echo.NewHTTPError(http.StatusForbidden, "System identities can only query the status and artifacts of composes"))
+		}
+
+		required := requiredRole(route)
+		if required == roleNone {
+			return nextHandler(ctx)
+		}
+
+		r, err := s.callerRole(ctx)
+		if err != nil {
+			return err
+		}
+		if r < required {
+			return auditRejection(ctx, reasonRoleDenied, echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("This operation requires the %s role", required)))
+		}
+		return nextHandler(ctx)
+	}
+}
@@ -0,0 +1,136 @@
+package v1
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	legacyrouter "github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/rbac"
+)
+
+func TestRoleFromPermissions(t *testing.T) {
+	require.Equal(t, roleNone, roleFromPermissions(nil))
+	require.Equal(t, roleNone, roleFromPermissions([]string{"inventory:*:*", "image-builder:composes"}))
+	require.Equal(t, roleViewer, roleFromPermissions([]string{"image-builder:composes:read"}))
+	require.Equal(t, roleViewer, roleFromPermissions([]string{"image-builder:*:read"}))
+	require.Equal(t, roleBuilder, roleFromPermissions([]string{"image-builder:composes:read", "image-builder:composes:write"}))
+	require.Equal(t, roleBuilder, roleFromPermissions([]string{"image-builder:composes:*"}))
+	require.Equal(t, roleAdmin, roleFromPermissions([]string{"image-builder:settings:write"}))
+	require.Equal(t, roleAdmin, roleFromPermissions([]string{"image-builder:composes:read", "image-builder:*:*"}))
+}
+
+func TestEnforceRoles(t *testing.T) {
+	// the identity header holds the permissions the fake rbac service returns
+	rbacSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := base64.StdEncoding.DecodeString(r.Header.Get("x-rh-identity"))
+		require.NoError(t, err)
+		var id struct {
+			Identity struct {
+				User struct {
+					Username string `json:"username"`
+				} `json:"user"`
+			} `json:"identity"`
+		}
+		require.NoError(t, json.Unmarshal(raw, &id))
+
+		data := []map[string]string{}
+		if id.Identity.User.Username != "" {
+			data = append(data, map[string]string{"permission": id.Identity.User.Username})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"data": data}))
+	}))
+	defer rbacSrv.Close()
+
+	spec, err := GetSwagger()
+	require.NoError(t, err)
+	spec.AddServer(&openapi3.Server{URL: fmt.Sprintf("%s/v%s", RoutePrefix(), spec.Info.Version)})
+	router, err := legacyrouter.NewRouter(spec)
+	require.NoError(t, err)
+	rbacClient, err := rbac.NewClient(rbac.RBACClientConfig{URL: rbacSrv.URL})
+	require.NoError(t, err)
+	s := &Server{
+		router: router,
+		auth:   NewIdentityHeaderAuthenticator(ServiceAccountConfig{}),
+		rbac:   rbacClient,
+	}
+
+	runAs := func(identityType, method, path, permission string, orgAdmin bool) int {
+		header := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(
+			`{"identity": {"type": %q, "org_id": "000000", "internal": {"org_id": "000000"}, "user": {"username": %q, "is_org_admin": %v}}}`,
+			identityType, permission, orgAdmin)))
+		req := httptest.NewRequest(method, RoutePrefix()+"/v1"+path, nil)
+		req.Header.Set("X-Rh-Identity", header)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		err := s.authenticate(s.enforceRoles(func(ctx echo.Context) error {
+			return ctx.NoContent(http.StatusOK)
+		}))(ctx)
+		if err != nil {
+			return err.(*echo.HTTPError).Code
+		}
+		return rec.Code
+	}
+	run := func(method, path, permission string, orgAdmin bool) int {
+		return runAs("User", method, path, permission, orgAdmin)
+	}
+
+	composePath := "/composes/123e4567-e89b-12d3-a456-426655440000"
+	require.Equal(t, http.StatusOK, run(http.MethodGet, "/version", "", false))
+	require.Equal(t, http.StatusForbidden, run(http.MethodGet, composePath, "", false))
+	require.Equal(t, http.StatusOK, run(http.MethodGet, composePath, "image-builder:composes:read", false))
+	require.Equal(t, http.StatusForbidden, run(http.MethodDelete, composePath, "image-builder:composes:read", false))
+	require.Equal(t, http.StatusOK, run(http.MethodDelete, composePath, "image-builder:composes:write", false))
+	require.Equal(t, http.StatusForbidden, run(http.MethodGet, "/tokens", "image-builder:composes:write", false))
+	require.Equal(t, http.StatusOK, run(http.MethodGet, "/tokens", "image-builder:settings:write", false))
+	require.Equal(t, http.StatusOK, run(http.MethodPost, "/tokens", "", true))
+
+	// systems can only look up compose metadata
+	require.Equal(t, http.StatusOK, runAs("System", http.MethodGet, composePath, "", false))
+	require.Equal(t, http.StatusOK, runAs("System", http.MethodGet, composePath+"/artifacts", "", false))
+	require.Equal(t, http.StatusOK, runAs("System", http.MethodGet, composePath+"/metadata", "", false))
+	require.Equal(t, http.StatusForbidden, runAs("System", http.MethodGet, "/composes", "", false))
+	require.Equal(t, http.StatusForbidden, runAs("System", http.MethodDelete, composePath, "", false))
+	require.Equal(t, http.StatusForbidden, runAs("System", http.MethodPost, "/compose", "", false))
+}
+
+func TestSystemIdentitiesWithoutRBAC(t *testing.T) {
+	spec, err := GetSwagger()
+	require.NoError(t, err)
+	spec.AddServer(&openapi3.Server{URL: fmt.Sprintf("%s/v%s", RoutePrefix(), spec.Info.Version)})
+	router, err := legacyrouter.NewRouter(spec)
+	require.NoError(t, err)
+	s := &Server{
+		router: router,
+		auth:   NewIdentityHeaderAuthenticator(ServiceAccountConfig{}),
+	}
+
+	run := func(method, path string) int {
+		header := base64.StdEncoding.EncodeToString([]byte(
+			`{"identity": {"type": "System", "org_id": "000000", "internal": {"org_id": "000000"}, "system": {"cn": "host"}}}`))
+		req := httptest.NewRequest(method, RoutePrefix()+"/v1"+path, nil)
+		req.Header.Set("X-Rh-Identity", header)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		err := s.authenticate(s.enforceRoles(func(ctx echo.Context) error {
+			return ctx.NoContent(http.StatusOK)
+		}))(ctx)
+		if err != nil {
+			return err.(*echo.HTTPError).Code
+		}
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusOK, run(http.MethodGet, "/composes/123e4567-e89b-12d3-a456-426655440000"))
+	require.Equal(t, http.StatusOK, run(http.MethodGet, "/clones/123e4567-e89b-12d3-a456-426655440000"))
+	require.Equal(t, http.StatusForbidden, run(http.MethodGet, "/tokens"))
+	require.Equal(t, http.StatusForbidden, run(http.MethodPost, "/compose"))
+}
@@ -0,0 +1,87 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// The outcome of the checks of composer and its token endpoint is reused for
+// this long, so the probes of every replica don't turn into a steady load on
+// them.
+const readinessCacheTTL = 30 * time.Second
+
+const readinessCheckTimeout = 5 * time.Second
+
+type readinessCache struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	checks    map[string]string
+}
+
+// get returns the cached checks, or runs them if they are stale.
+func (rc *readinessCache) get(now time.Time, run func() map[string]string) map[string]string {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.checks == nil || now.Sub(rc.checkedAt) >= readinessCacheTTL {
+		rc.checks = run()
+		rc.checkedAt = now
+	}
+	return rc.checks
+}
+
+func checkOutcome(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
+
+// GetReadiness checks that the instance can serve composes: the database can
+// be queried, composer is reachable and a token for it can be acquired. Unlike
+// the liveness probe, which only tells whether the process responds, a
+// replica failing this is taken out of the rotation.
+func (h *Handlers) GetReadiness(ctx echo.Context) error {
+	reqCtx, cancel := context.WithTimeout(ctx.Request().Context(), readinessCheckTimeout)
+	defer cancel()
+
+	checks := map[string]string{
+		"database": checkOutcome(h.server.db.Ping(reqCtx)),
+	}
+	cached := h.server.readiness.get(time.Now(), func() map[string]string {
+		return map[string]string{
+			"composer":       checkOutcome(h.checkComposer(reqCtx)),
+			"composer_token": checkOutcome(h.server.cClient.AcquireToken()),
+		}
+	})
+	for name, outcome := range cached {
+		checks[name] = outcome
+	}
+
+	for _, outcome := range checks {
+		if outcome != "ok" {
+			ctx.Logger().Warnf("Not ready: %v", checks)
+			return ctx.JSON(http.StatusServiceUnavailable, Readiness{
+				Readiness: "not ready",
+				Checks:    &checks,
+			})
+		}
+	}
+	return ctx.JSON(http.StatusOK, Readiness{Readiness: "ready"})
+}
+
+func (h *Handlers) checkComposer(ctx context.Context) error {
+	resp, err := h.server.cClient.OpenAPI(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("composer responded with %d", resp.StatusCode)
+	}
+	return nil
+}
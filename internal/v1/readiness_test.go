@@ -0,0 +1,24 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadinessCache(t *testing.T) {
+	var rc readinessCache
+	runs := 0
+	run := func() map[string]string {
+		runs++
+		return map[string]string{"composer": "ok"}
+	}
+
+	now := time.Now()
+	require.Equal(t, map[string]string{"composer": "ok"}, rc.get(now, run))
+	rc.get(now.Add(readinessCacheTTL-time.Second), run)
+	require.Equal(t, 1, runs)
+	rc.get(now.Add(readinessCacheTTL), run)
+	require.Equal(t, 2, runs)
+}
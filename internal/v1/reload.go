@@ -0,0 +1,70 @@
+package v1
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/distribution"
+)
+
+// ReloadableConfig is the part of the config which can change while the
+// server runs, see ServerConfig.Reload.
+type ReloadableConfig struct {
+	QuotaFile        string
+	AllowFile        string
+	DistributionsDir string
+}
+
+// settings are what's loaded of a ReloadableConfig. A reload swaps them as a
+// whole, requests which already started finish with the previous ones.
+type settings struct {
+	quotaFile        string
+	allowList        common.AllowList
+	allDistros       *distribution.AllDistroRegistry
+	distributionsDir string
+}
+
+// current returns the settings, which are empty for servers which weren't
+// attached, e.g. in tests.
+func (s *Server) current() *settings {
+	if st := s.settings.Load(); st != nil {
+		return st
+	}
+	return &settings{}
+}
+
+// reload loads rc and replaces the settings, unless rc can't be loaded.
+func (s *Server) reload(rc ReloadableConfig) error {
+	allowList, err := common.LoadAllowList(rc.AllowFile)
+	if err != nil {
+		return err
+	}
+	adr, err := distribution.LoadDistroRegistry(rc.DistributionsDir)
+	if err != nil {
+		return err
+	}
+	if len(adr.Available(true).List()) == 0 {
+		return fmt.Errorf("no distributions defined in %s", rc.DistributionsDir)
+	}
+	s.settings.Store(&settings{
+		quotaFile:        rc.QuotaFile,
+		allowList:        allowList,
+		allDistros:       adr,
+		distributionsDir: rc.DistributionsDir,
+	})
+	return nil
+}
+
+// runReloads applies each received config until reloads is closed.
+func (s *Server) runReloads(reloads <-chan ReloadableConfig) {
+	for rc := range reloads {
+		err := s.reload(rc)
+		if err != nil {
+			logrus.Errorf("Unable to reload the config, keeping the previous one: %v", err)
+			continue
+		}
+		logrus.Infof("Reloaded the quota file %q, the allow file %q and the distributions of %s", rc.QuotaFile, rc.AllowFile, rc.DistributionsDir)
+	}
+}
@@ -0,0 +1,44 @@
+package v1
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	allowFile := filepath.Join(t.TempDir(), "allow.json")
+	require.NoError(t, os.WriteFile(allowFile, []byte(`{"000001": ["rhel-10-nightly"]}`), 0600))
+
+	s := &Server{}
+	require.Nil(t, s.current().allDistros)
+	require.NoError(t, s.reload(ReloadableConfig{
+		QuotaFile:        "/etc/image-builder/quotas.json",
+		AllowFile:        allowFile,
+		DistributionsDir: "../../distributions",
+	}))
+	first := s.current()
+	require.Equal(t, "/etc/image-builder/quotas.json", first.quotaFile)
+	require.Equal(t, []string{"rhel-10-nightly"}, first.allowList["000001"])
+	require.NotEmpty(t, first.allDistros.Available(true).List())
+
+	// configs which can't be loaded don't replace the settings
+	require.Error(t, s.reload(ReloadableConfig{AllowFile: allowFile, DistributionsDir: t.TempDir()}))
+	require.Error(t, s.reload(ReloadableConfig{AllowFile: "/nonexistent", DistributionsDir: "../../distributions"}))
+	require.Same(t, first, s.current())
+
+	require.NoError(t, os.WriteFile(allowFile, []byte(`{}`), 0600))
+	reloads := make(chan ReloadableConfig)
+	done := make(chan struct{})
+	go func() {
+		s.runReloads(reloads)
+		close(done)
+	}()
+	reloads <- ReloadableConfig{AllowFile: allowFile, DistributionsDir: "../../distributions"}
+	close(reloads)
+	<-done
+	require.Empty(t, s.current().quotaFile)
+	require.Empty(t, s.current().allowList)
+}
@@ -0,0 +1,177 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+// How long requesting the clones of all regions of a compose may take.
+const replicationTimeout = 2 * time.Minute
+
+// composeReplications returns the additional regions of an aws compose and
+// the accounts its image is shared with, none for other composes.
+func composeReplications(cr ComposeRequest, cloudCR composer.ComposeRequest) ([]string, []string, error) {
+	if len(cr.ImageRequests) == 0 || cr.ImageRequests[0].UploadRequest.Type != UploadTypesAws {
+		return nil, nil, nil
+	}
+	uo, err := cr.ImageRequests[0].UploadRequest.Options.AsAWSUploadRequestOptions()
+	if err != nil || uo.Regions == nil || len(*uo.Regions) == 0 {
+		return nil, nil, nil
+	}
+	if cloudCR.ImageRequest == nil || cloudCR.ImageRequest.UploadOptions == nil {
+		return nil, nil, nil
+	}
+	co, err := cloudCR.ImageRequest.UploadOptions.AsAWSEC2UploadOptions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var regions []string
+	for _, r := range *uo.Regions {
+		if r != co.Region {
+			regions = append(regions, r)
+		}
+	}
+	return regions, co.ShareWithAccounts, nil
+}
+
+// replicateCompose clones the AMI of a successful compose into its
+// additional regions. Every region is claimed before its clone is
+// requested, so concurrent dispatches don't clone it twice, and regions
+// which have their clone already are skipped when this is retried.
+func (s *Server) replicateCompose(event outboxEvent) error {
+	if event.UploadStatus == nil || event.UploadStatus.Type != UploadTypesAws {
+		return nil
+	}
+	replications, err := s.db.GetComposeReplications(event.ComposeId, 0)
+	if err != nil {
+		return err
+	}
+	if len(replications) == 0 {
+		return nil
+	}
+	compose, err := s.db.GetCompose(event.ComposeId, event.OrgId)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), replicationTimeout)
+	defer cancel()
+	var failed []string
+	for _, r := range replications {
+		if r.CloneId != nil {
+			continue
+		}
+		claimed, err := s.db.ClaimComposeReplication(event.ComposeId, r.Region)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			// retried until whoever claimed it stored the clone
+			failed = append(failed, fmt.Sprintf("%s is claimed", r.Region))
+			continue
+		}
+
+		accounts := r.ShareWithAccounts
+		cloneId, err := s.cloneCompose(ctx, compose, AWSEC2Clone{
+			Region:            r.Region,
+			ShareWithAccounts: &accounts,
+		}, accounts)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", r.Region, err))
+			err = s.db.SetComposeReplicationClone(event.ComposeId, r.Region, nil)
+			if err != nil {
+				logrus.Errorf("Error releasing region %s of compose %v: %v", r.Region, event.ComposeId, err)
+			}
+			continue
+		}
+		err = s.db.SetComposeReplicationClone(event.ComposeId, r.Region, &cloneId)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("Cloned compose %v to region %s as %v", event.ComposeId, r.Region, cloneId)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to clone into %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// replicationStatuses returns the statuses of the clones into the additional
+// regions of a compose. Regions which aren't cloned yet are pending. The
+// statuses are cached like the compose status, finished ones for good.
+func (h *Handlers) replicationStatuses(ctx echo.Context, composeEntry *db.ComposeEntry) (*[]UploadStatus, error) {
+	replications, err := h.server.db.GetComposeReplications(composeEntry.Id, h.server.composeStatusTTL)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying the regions of compose %v: %v", composeEntry.Id, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying clones for this compose")
+	}
+	if len(replications) == 0 {
+		return nil, nil
+	}
+
+	statuses := []UploadStatus{}
+	for _, r := range replications {
+		if r.CloneId == nil {
+			us, err := pendingCloneStatus(r.Region)
+			if err != nil {
+				return nil, err
+			}
+			statuses = append(statuses, *us)
+			continue
+		}
+
+		var cached UploadStatus
+		if r.Status != nil && json.Unmarshal(r.Status, &cached) == nil &&
+			(r.Fresh || finishedUpload(composer.UploadStatusValue(cached.Status))) {
+			statuses = append(statuses, cached)
+			continue
+		}
+
+		cc, err := h.server.composerOf(composeEntry)
+		if err != nil {
+			return nil, err
+		}
+		us, err := h.getCloneUploadStatus(ctx, cc, *r.CloneId)
+		if err != nil {
+			ctx.Logger().Errorf("Unable to get status of clone %v: %v", *r.CloneId, err)
+			continue
+		}
+		raw, err := json.Marshal(us)
+		if err != nil {
+			return nil, err
+		}
+		// the cache only spares composer, don't fail the request
+		err = h.server.db.SetComposeReplicationStatus(composeEntry.Id, r.Region, raw)
+		if err != nil {
+			ctx.Logger().Errorf("Error caching status of clone %v: %v", *r.CloneId, err)
+		}
+		statuses = append(statuses, *us)
+	}
+	return &statuses, nil
+}
+
+func pendingCloneStatus(region string) (*UploadStatus, error) {
+	var options UploadStatus_Options
+	err := options.FromAWSUploadStatus(AWSUploadStatus{
+		Region: region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &UploadStatus{
+		Status:  UploadStatusStatusPending,
+		Type:    UploadTypesAws,
+		Options: options,
+	}, nil
+}
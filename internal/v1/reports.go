@@ -0,0 +1,205 @@
+package v1
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+const (
+	reportMonthFormat = "2006-01"
+	// most months a report spans
+	maxReportMonths = 36
+)
+
+var usageReportCSVHeader = []string{"month", "image_type", "upload_target", "builds", "succeeded", "failed", "build_minutes", "storage_bytes"}
+
+// SupportUsageReport is the usage report of all orgs, its entries say which
+// org they belong to.
+type SupportUsageReport struct {
+	From string                    `json:"from"`
+	To   string                    `json:"to"`
+	Data []SupportUsageReportEntry `json:"data"`
+}
+
+type SupportUsageReportEntry struct {
+	OrgId string `json:"org_id"`
+	UsageReportEntry
+}
+
+// reportMonths parses the first and last month of a report, and returns the
+// time the first one starts and the one after the last one starts.
+func reportMonths(from, to *string) (time.Time, time.Time, error) {
+	last := time.Now().UTC()
+	last = time.Date(last.Year(), last.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var err error
+	if to != nil {
+		last, err = time.Parse(reportMonthFormat, *to)
+		if err != nil {
+			return time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid to, expected a month like 2024-01")
+		}
+	}
+	first := last.AddDate(0, -11, 0)
+	if from != nil {
+		first, err = time.Parse(reportMonthFormat, *from)
+		if err != nil {
+			return time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid from, expected a month like 2024-01")
+		}
+	}
+	if last.Before(first) {
+		return time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "from has to be before to")
+	}
+	if !first.AddDate(0, maxReportMonths, 0).After(last) {
+		return time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Reports span at most %d months", maxReportMonths))
+	}
+	return first, last.AddDate(0, 1, 0), nil
+}
+
+func usageReportEntry(e db.UsageReportEntry) UsageReportEntry {
+	return UsageReportEntry{
+		Month:        e.Month.UTC().Format(reportMonthFormat),
+		ImageType:    e.ImageType,
+		UploadTarget: e.UploadTarget,
+		Builds:       e.Builds,
+		Succeeded:    e.Succeeded,
+		Failed:       e.Failed,
+		BuildMinutes: float64(e.BuildSeconds) / 60,
+		StorageBytes: e.ArtifactBytes,
+	}
+}
+
+func (e UsageReportEntry) csvRecord() []string {
+	return []string{
+		e.Month,
+		e.ImageType,
+		e.UploadTarget,
+		strconv.Itoa(e.Builds),
+		strconv.Itoa(e.Succeeded),
+		strconv.Itoa(e.Failed),
+		strconv.FormatFloat(e.BuildMinutes, 'f', 2, 64),
+		strconv.FormatInt(e.StorageBytes, 10),
+	}
+}
+
+// writeUsageReportCSV writes the rows of a report, prefixed by their org if
+// orgIds isn't nil.
+func writeUsageReportCSV(ctx echo.Context, entries []UsageReportEntry, orgIds []string) error {
+	resp := ctx.Response()
+	resp.Header().Set(echo.HeaderContentType, "text/csv")
+	resp.Header().Set(echo.HeaderContentDisposition, "attachment; filename=\"usage.csv\"")
+	resp.WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(resp)
+	header := usageReportCSVHeader
+	if orgIds != nil {
+		header = append([]string{"org_id"}, header...)
+	}
+	err := w.Write(header)
+	if err != nil {
+		return err
+	}
+	for i, e := range entries {
+		record := e.csvRecord()
+		if orgIds != nil {
+			record = append([]string{orgIds[i]}, record...)
+		}
+		err = w.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// GetUsageReport returns the usage of the org of the caller per month, image
+// type and upload target, for charging builds back.
+func (h *Handlers) GetUsageReport(ctx echo.Context, params GetUsageReportParams) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+	since, until, err := reportMonths(params.From, params.To)
+	if err != nil {
+		return err
+	}
+
+	entries, err := h.server.db.GetUsageReport(idHeader.Identity.OrgID, since, until)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying the usage report: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the usage report")
+	}
+	data := []UsageReportEntry{}
+	for _, e := range entries {
+		data = append(data, usageReportEntry(e))
+	}
+
+	if params.Format != nil && *params.Format == GetUsageReportParamsFormatCsv {
+		return writeUsageReportCSV(ctx, data, nil)
+	}
+	return ctx.JSON(http.StatusOK, UsageReport{
+		From: since.Format(reportMonthFormat),
+		To:   until.AddDate(0, -1, 0).Format(reportMonthFormat),
+		Data: data,
+	})
+}
+
+// GetSupportUsageReport returns the usage report of all orgs, or of the one
+// in org_id.
+func (h *Handlers) GetSupportUsageReport(ctx echo.Context) error {
+	var from, to *string
+	if v := ctx.QueryParam("from"); v != "" {
+		from = &v
+	}
+	if v := ctx.QueryParam("to"); v != "" {
+		to = &v
+	}
+	since, until, err := reportMonths(from, to)
+	if err != nil {
+		return err
+	}
+	format := ctx.QueryParam("format")
+	if format != "" && format != "json" && format != "csv" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid format, expected json or csv")
+	}
+
+	idh, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+	orgId := ctx.QueryParam("org_id")
+	ctx.Logger().Infof("Associate %s looked up the usage report of %q from %v to %v", idh.Identity.Associate.Email, orgId, since, until)
+
+	entries, err := h.server.db.GetUsageReport(orgId, since, until)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	data := []SupportUsageReportEntry{}
+	for _, e := range entries {
+		data = append(data, SupportUsageReportEntry{
+			OrgId:            e.OrgId,
+			UsageReportEntry: usageReportEntry(e),
+		})
+	}
+
+	if format == "csv" {
+		var rows []UsageReportEntry
+		orgIds := []string{}
+		for _, e := range data {
+			rows = append(rows, e.UsageReportEntry)
+			orgIds = append(orgIds, e.OrgId)
+		}
+		return writeUsageReportCSV(ctx, rows, orgIds)
+	}
+	return ctx.JSON(http.StatusOK, SupportUsageReport{
+		From: since.Format(reportMonthFormat),
+		To:   until.AddDate(0, -1, 0).Format(reportMonthFormat),
+		Data: data,
+	})
+}
@@ -0,0 +1,157 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// how long verifying the metadata of all repositories of a compose may take
+const repositoryVerificationTimeout = time.Minute
+
+// signedRepository is a payload or custom repository as far as verifying its
+// signatures is concerned.
+type signedRepository struct {
+	// how the repository is called in errors, its id or url
+	name         string
+	baseurls     []string
+	checkGpg     bool
+	checkRepoGpg bool
+	gpgkeys      []string
+}
+
+func signedRepositories(cust *Customizations) []signedRepository {
+	var repos []signedRepository
+	if cust == nil {
+		return repos
+	}
+	if cust.PayloadRepositories != nil {
+		for _, r := range *cust.PayloadRepositories {
+			// the Red Hat CDN needs the entitlement of the worker, and
+			// its content is trusted anyway
+			if r.Rhsm {
+				continue
+			}
+			repo := signedRepository{
+				checkGpg:     r.CheckGpg != nil && *r.CheckGpg,
+				checkRepoGpg: r.CheckRepoGpg != nil && *r.CheckRepoGpg,
+			}
+			switch {
+			case r.Baseurl != nil:
+				repo.name = *r.Baseurl
+				repo.baseurls = []string{*r.Baseurl}
+			case r.Metalink != nil:
+				repo.name = *r.Metalink
+			case r.Mirrorlist != nil:
+				repo.name = *r.Mirrorlist
+			}
+			if r.Gpgkey != nil && *r.Gpgkey != "" {
+				repo.gpgkeys = []string{*r.Gpgkey}
+			}
+			repos = append(repos, repo)
+		}
+	}
+	if cust.CustomRepositories != nil {
+		for _, r := range *cust.CustomRepositories {
+			repo := signedRepository{
+				name:         r.Id,
+				checkGpg:     r.CheckGpg != nil && *r.CheckGpg,
+				checkRepoGpg: r.CheckRepoGpg != nil && *r.CheckRepoGpg,
+			}
+			if r.Baseurl != nil {
+				repo.baseurls = *r.Baseurl
+			}
+			if r.Gpgkey != nil {
+				repo.gpgkeys = *r.Gpgkey
+			}
+			repos = append(repos, repo)
+		}
+	}
+	return repos
+}
+
+// checkRepositorySignatures makes sure the repositories of a compose request
+// check signatures and that their metadata is signed by their keys, if the
+// org requires it.
+func (h *Handlers) checkRepositorySignatures(ctx echo.Context, orgId string, cust *Customizations) error {
+	repos := signedRepositories(cust)
+	if len(repos) == 0 {
+		return nil
+	}
+	required, err := h.server.db.GetRepositorySignaturesRequired(orgId)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying repository signature settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the repository signature settings")
+	}
+	if !required {
+		return nil
+	}
+
+	for _, r := range repos {
+		if !r.checkGpg || !r.checkRepoGpg {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Repository %s has to set check_gpg and check_repo_gpg", r.name))
+		}
+		if len(r.gpgkeys) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Repository %s needs a gpg key", r.name))
+		}
+		if len(r.baseurls) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Repository %s needs a baseurl to verify its metadata", r.name))
+		}
+	}
+
+	verifyCtx, cancel := context.WithTimeout(ctx.Request().Context(), repositoryVerificationTimeout)
+	defer cancel()
+	for _, r := range repos {
+		for _, baseurl := range r.baseurls {
+			err = h.server.gpgClient.VerifyRepository(verifyCtx, baseurl, r.gpgkeys)
+			if err != nil {
+				// the error would tell which internal hosts answer
+				ctx.Logger().Warnf("Verifying repository %s of org %s failed: %v", r.name, orgId, err)
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Repository %s can't be verified with its gpg keys", r.name))
+			}
+		}
+	}
+	return nil
+}
+
+func (h *Handlers) GetRepositorySignatureSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	required, err := h.server.db.GetRepositorySignaturesRequired(idHeader.Identity.OrgID)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying repository signature settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the repository signature settings")
+	}
+	return ctx.JSON(http.StatusOK, RepositorySignatureSettings{
+		RequireSignatures: required,
+	})
+}
+
+func (h *Handlers) UpdateRepositorySignatureSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	var req RepositorySignatureSettings
+	err = ctx.Bind(&req)
+	if err != nil {
+		return err
+	}
+
+	err = h.server.db.SetRepositorySignaturesRequired(idHeader.Identity.OrgID, req.RequireSignatures)
+	if err != nil {
+		ctx.Logger().Errorf("Error updating repository signature settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong updating the repository signature settings")
+	}
+
+	logAction(ctx, "update_repository_signature_settings", logrus.Fields{"org_id": idHeader.Identity.OrgID, "require_signatures": req.RequireSignatures}, "Repository signature settings updated")
+	return h.GetRepositorySignatureSettings(ctx)
+}
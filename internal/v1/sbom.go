@@ -0,0 +1,102 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/sbom"
+)
+
+// GetComposeSBOM returns the packages of the image of a compose as an SBOM.
+// The package list of a finished compose doesn't change, so the document is
+// stored the first time it's generated, which also keeps its timestamp and
+// serial number the same.
+func (h *Handlers) GetComposeSBOM(ctx echo.Context, composeId uuid.UUID, params GetComposeSBOMParams) error {
+	format := sbom.FormatSPDX
+	if params.Format != nil {
+		format = string(*params.Format)
+	}
+
+	composeEntry, err := h.getComposeByIdAndOrgId(ctx, composeId)
+	if err != nil {
+		return err
+	}
+
+	document, err := h.server.db.GetComposeSBOM(composeId, composeEntry.OrgId, format)
+	if err == nil {
+		return ctx.Blob(http.StatusOK, sbom.MediaType(format), document)
+	} else if !errors.Is(err, db.ComposeSBOMNotFoundError) {
+		ctx.Logger().Errorf("Error querying the SBOM of compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the SBOM of this compose")
+	}
+
+	metadata, err := h.composeMetadata(ctx, composeEntry)
+	if err != nil {
+		return err
+	}
+	if metadata.Packages == nil || len(*metadata.Packages) == 0 {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Compose %v hasn't finished successfully", composeId))
+	}
+
+	doc := sbom.Document{
+		Name:      composeId.String(),
+		Namespace: fmt.Sprintf("%s/composes/%s/sbom", h.server.provenanceBuilderId(), composeId),
+		Created:   time.Now(),
+	}
+	if composeEntry.ImageName != nil && *composeEntry.ImageName != "" {
+		doc.Name = *composeEntry.ImageName
+	}
+	for _, p := range *metadata.Packages {
+		doc.Packages = append(doc.Packages, sbomPackage(p))
+	}
+	switch format {
+	case sbom.FormatSPDX:
+		document, err = sbom.SPDX(doc)
+	case sbom.FormatCycloneDX:
+		document, err = sbom.CycloneDX(doc)
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unknown SBOM format %s", format))
+	}
+	if err != nil {
+		return err
+	}
+
+	err = h.server.db.InsertComposeSBOM(composeId, format, document)
+	if err != nil {
+		ctx.Logger().Errorf("Error storing the SBOM of compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong storing the SBOM of this compose")
+	}
+	// a concurrent request may have stored its document first, which is
+	// the one all requests get from now on
+	document, err = h.server.db.GetComposeSBOM(composeId, composeEntry.OrgId, format)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying the SBOM of compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the SBOM of this compose")
+	}
+	return ctx.Blob(http.StatusOK, sbom.MediaType(format), document)
+}
+
+func sbomPackage(p composer.PackageMetadata) sbom.Package {
+	pkg := sbom.Package{
+		Type:    p.Type,
+		Name:    p.Name,
+		Version: p.Version,
+		Release: p.Release,
+		Arch:    p.Arch,
+		Sigmd5:  p.Sigmd5,
+	}
+	if p.Epoch != nil {
+		pkg.Epoch = *p.Epoch
+	}
+	if p.Signature != nil {
+		pkg.Signature = *p.Signature
+	}
+	return pkg
+}
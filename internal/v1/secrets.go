@@ -0,0 +1,113 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/keystore"
+)
+
+var errNoKeystore = errors.New("no key encryption key is configured")
+
+// The additional data binds a sealed secret to where it's stored, so it can't
+// be copied into another row.
+func webhookSecretAdditionalData(id uuid.UUID) []byte {
+	return []byte("webhooks/" + id.String())
+}
+
+func awxTokenAdditionalData(orgId string) []byte {
+	return []byte("awx_settings/" + orgId)
+}
+
+// sealSecret encrypts a secret with the current key encryption key.
+func (s *Server) sealSecret(plaintext, additionalData []byte) (db.SealedSecretEntry, error) {
+	if s.keystore == nil {
+		return db.SealedSecretEntry{}, errNoKeystore
+	}
+	envelope, err := s.keystore.Encrypt(plaintext, additionalData)
+	if err != nil {
+		return db.SealedSecretEntry{}, err
+	}
+	return db.SealedSecretEntry{
+		KEKId:            envelope.KeyId,
+		EncryptedDataKey: envelope.DataKey,
+		Ciphertext:       envelope.Ciphertext,
+	}, nil
+}
+
+// openSecret decrypts a secret sealSecret encrypted.
+func (s *Server) openSecret(secret db.SealedSecretEntry, additionalData []byte) ([]byte, error) {
+	if s.keystore == nil {
+		return nil, errNoKeystore
+	}
+	return s.keystore.Decrypt(keystore.Envelope{
+		KeyId:      secret.KEKId,
+		DataKey:    secret.EncryptedDataKey,
+		Ciphertext: secret.Ciphertext,
+	}, additionalData)
+}
+
+// movePlaintextSecrets moves the private keys and service tokens of
+// artifact signing settings to the signing keys, their plaintext is dropped.
+// It's run on every start, as there's nothing to do once all of them are
+// moved.
+func (s *Server) movePlaintextSecrets() error {
+	settings, err := s.db.GetPlaintextArtifactSigningSecrets()
+	if err != nil {
+		return err
+	}
+	for _, p := range settings {
+		err = s.moveArtifactSigningSecrets(p)
+		if err != nil {
+			return fmt.Errorf("moving the artifact signing secrets of org %s failed: %w", p.OrgId, err)
+		}
+	}
+	if len(settings) > 0 {
+		logrus.Infof("Moved the private keys and service tokens of %d artifact signing settings to the signing keys", len(settings))
+	}
+	return nil
+}
+
+// moveArtifactSigningSecrets has the private key of artifact signing
+// settings become the artifact key of the org, and the token of its signing
+// service its signing_service key. Keys the org has already took precedence
+// over the settings, so what they replace is dropped.
+func (s *Server) moveArtifactSigningSecrets(p db.PlaintextArtifactSigningEntry) error {
+	var keys []db.SigningKeyEntry
+	for _, m := range []struct {
+		purpose  SigningKeyPurpose
+		material SigningKeyMaterial
+	}{
+		{SigningKeyPurposeArtifact, SigningKeyMaterial{PrivateKey: p.PrivateKey}},
+		{SigningKeyPurposeSigningService, SigningKeyMaterial{Secret: p.ServiceToken}},
+	} {
+		if m.material.PrivateKey == nil && m.material.Secret == nil {
+			continue
+		}
+		_, err := s.db.GetSigningKeyInUse(p.OrgId, string(m.purpose))
+		if err == nil {
+			continue
+		} else if !errors.Is(err, db.SigningKeyNotFoundError) {
+			return err
+		}
+		key, err := s.newSigningKey(p.OrgId, m.purpose, m.material)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, *key)
+	}
+	return s.db.MoveArtifactSigningSecrets(p.OrgId, keys)
+}
+
+// runMovePlaintextSecrets moves the plaintext secrets in the background, the
+// ones it misses are moved on the next start.
+func (s *Server) runMovePlaintextSecrets() {
+	err := s.movePlaintextSecrets()
+	if err != nil {
+		logrus.Errorf("Error moving the plaintext secrets: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package v1
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+// plaintextDB has the artifact signing secrets of a db from before they were
+// signing keys.
+type plaintextDB struct {
+	db.DB
+	signing []db.PlaintextArtifactSigningEntry
+}
+
+func (d *plaintextDB) GetPlaintextArtifactSigningSecrets() ([]db.PlaintextArtifactSigningEntry, error) {
+	return append([]db.PlaintextArtifactSigningEntry{}, d.signing...), nil
+}
+
+func (d *plaintextDB) MoveArtifactSigningSecrets(orgId string, keys []db.SigningKeyEntry) error {
+	for i, p := range d.signing {
+		if p.OrgId == orgId {
+			d.signing = append(d.signing[:i], d.signing[i+1:]...)
+			break
+		}
+	}
+	return d.DB.MoveArtifactSigningSecrets(orgId, keys)
+}
+
+func TestSealSecret(t *testing.T) {
+	s := &Server{}
+	_, err := s.sealSecret([]byte("token"), awxTokenAdditionalData("000000"))
+	require.ErrorIs(t, err, errNoKeystore)
+
+	s.keystore = testKeystore(t)
+	sealed, err := s.sealSecret([]byte("token"), awxTokenAdditionalData("000000"))
+	require.NoError(t, err)
+	require.NotContains(t, string(sealed.Ciphertext), "token")
+	token, err := s.openSecret(sealed, awxTokenAdditionalData("000000"))
+	require.NoError(t, err)
+	require.Equal(t, "token", string(token))
+
+	// sealed secrets are bound to where they're stored
+	_, err = s.openSecret(sealed, awxTokenAdditionalData("000001"))
+	require.Error(t, err)
+	_, err = s.openSecret(sealed, webhookSecretAdditionalData(uuid.New()))
+	require.Error(t, err)
+}
+
+func TestMovePlaintextArtifactSigningSecrets(t *testing.T) {
+	entity, err := openpgp.NewEntity("Image Builder Test", "", "test@example.com", nil)
+	require.NoError(t, err)
+	var armored bytes.Buffer
+	w, err := armor.Encode(&armored, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.SerializePrivate(w, nil))
+	require.NoError(t, w.Close())
+	privateKey := armored.String()
+
+	memory := db.NewMemoryDB()
+	// the artifact key of 000001 took precedence over its settings
+	s := &Server{db: memory, keystore: testKeystore(t)}
+	managed, err := s.newSigningKey("000001", SigningKeyPurposeArtifact, SigningKeyMaterial{PrivateKey: &privateKey})
+	require.NoError(t, err)
+	require.NoError(t, memory.InsertSigningKey(*managed))
+	dbase := &plaintextDB{
+		DB: memory,
+		signing: []db.PlaintextArtifactSigningEntry{
+			{OrgId: "000000", PrivateKey: &privateKey},
+			{OrgId: "000001", PrivateKey: &privateKey},
+			{OrgId: "000002", ServiceToken: common.ToPtr("servicetoken")},
+		},
+	}
+	s.db = dbase
+	require.NoError(t, s.movePlaintextSecrets())
+	require.Empty(t, dbase.signing)
+
+	key, err := s.managedGPGKey("000000", SigningKeyPurposeArtifact)
+	require.NoError(t, err)
+	require.Equal(t, managed.Fingerprint, common.ToPtr(key.Fingerprint()))
+	key, err = s.managedGPGKey("000001", SigningKeyPurposeArtifact)
+	require.NoError(t, err)
+	require.Equal(t, managed.Id, *key.id)
+	token, plaintext, err := s.signingKeyInUse("000002", SigningKeyPurposeSigningService)
+	require.NoError(t, err)
+	require.NotNil(t, token)
+	require.Equal(t, "servicetoken", string(plaintext))
+}
@@ -0,0 +1,89 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/secretscan"
+)
+
+// scanComposeSecrets looks for credentials in the parts of a compose request
+// which end up in the image. Depending on the settings of the org, requests
+// with credentials are rejected, or built with the findings as warnings.
+func (h *Handlers) scanComposeSecrets(ctx echo.Context, orgId string, composeRequest ComposeRequest) ([]string, error) {
+	// activation keys and the upload options carry credentials on purpose,
+	// neither is part of the image
+	skip := []string{"/customizations/subscription"}
+	for i := range composeRequest.ImageRequests {
+		skip = append(skip, fmt.Sprintf("/image_requests/%d/upload_request", i))
+	}
+	findings, err := secretscan.Scan(composeRequest, skip...)
+	if err != nil {
+		return nil, err
+	}
+	if len(findings) == 0 {
+		return nil, nil
+	}
+
+	mode, err := h.server.db.GetSecretScanningMode(orgId)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying secret scanning settings: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the secret scanning settings")
+	}
+
+	var warnings []string
+	for _, f := range findings {
+		// only where the credential is, never the credential itself
+		ctx.Logger().Warnf("Compose request of org %s contains %s", orgId, f)
+		warnings = append(warnings, f.String())
+	}
+	if SecretScanningSettingsMode(mode) == Reject {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("The compose request contains credentials: %s", strings.Join(warnings, ", ")))
+	}
+	return warnings, nil
+}
+
+func (h *Handlers) GetSecretScanningSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	mode, err := h.server.db.GetSecretScanningMode(idHeader.Identity.OrgID)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying secret scanning settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the secret scanning settings")
+	}
+	if mode == "" {
+		mode = string(Warn)
+	}
+	return ctx.JSON(http.StatusOK, SecretScanningSettings{
+		Mode: SecretScanningSettingsMode(mode),
+	})
+}
+
+func (h *Handlers) UpdateSecretScanningSettings(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	var req SecretScanningSettings
+	err = ctx.Bind(&req)
+	if err != nil {
+		return err
+	}
+
+	err = h.server.db.SetSecretScanningMode(idHeader.Identity.OrgID, string(req.Mode))
+	if err != nil {
+		ctx.Logger().Errorf("Error updating secret scanning settings: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong updating the secret scanning settings")
+	}
+
+	logAction(ctx, "update_secret_scanning_settings", logrus.Fields{"org_id": idHeader.Identity.OrgID, "mode": req.Mode}, "Secret scanning settings updated")
+	return h.GetSecretScanningSettings(ctx)
+}
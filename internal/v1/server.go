@@ -2,19 +2,35 @@
 package v1
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/osbuild/image-builder/internal/awx"
 	"github.com/osbuild/image-builder/internal/common"
 	"github.com/osbuild/image-builder/internal/composer"
 	"github.com/osbuild/image-builder/internal/db"
 	"github.com/osbuild/image-builder/internal/distribution"
+	"github.com/osbuild/image-builder/internal/ec2"
+	"github.com/osbuild/image-builder/internal/featureflags"
+	"github.com/osbuild/image-builder/internal/gpg"
+	"github.com/osbuild/image-builder/internal/keystore"
+	"github.com/osbuild/image-builder/internal/kms"
+	"github.com/osbuild/image-builder/internal/policy"
 	"github.com/osbuild/image-builder/internal/prometheus"
 	"github.com/osbuild/image-builder/internal/provisioning"
+	"github.com/osbuild/image-builder/internal/ratelimit"
+	"github.com/osbuild/image-builder/internal/rbac"
+	v2 "github.com/osbuild/image-builder/internal/v2"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/getkin/kin-openapi/routers"
@@ -25,18 +41,40 @@ import (
 )
 
 type Server struct {
-	echo             *echo.Echo
-	cClient          *composer.ComposerClient
-	pClient          *provisioning.ProvisioningClient
-	spec             *openapi3.T
-	router           routers.Router
-	db               db.DB
-	aws              AWSConfig
-	gcp              GCPConfig
-	quotaFile        string
-	allowList        common.AllowList
-	allDistros       *distribution.AllDistroRegistry
-	distributionsDir string
+	echo               *echo.Echo
+	cClient            *composer.ComposerClient
+	pClient            *provisioning.ProvisioningClient
+	spec               *openapi3.T
+	router             routers.Router
+	db                 db.DB
+	aws                AWSConfig
+	gcp                GCPConfig
+	auth               Authenticator
+	rbac               *rbac.RBACClient
+	rateLimiter        ratelimit.Limiter
+	requestLimits      RequestLimits
+	policy             *policy.PolicyClient
+	approvalWebhook    string
+	flags              featureflags.Flags
+	readiness          *readinessCache
+	composeStatusTTL   time.Duration
+	composers          *composer.Pool
+	events             EventPublisher
+	notifications      EventPublisher
+	mailer             Mailer
+	awxClient          *awx.Client
+	gpgClient          *gpg.Client
+	inventory          EventPublisher
+	requestValidation  ValidationMode
+	responseValidation ValidationMode
+	provenance         ProvenanceConfig
+	cosign             CosignConfig
+	scanner            VulnerabilityScanner
+	keystore           *keystore.Keystore
+	complianceExport   ComplianceExportConfig
+	stream             *streamHub
+	streamOrigins      []string
+	settings           atomic.Pointer[settings]
 }
 
 type ServerConfig struct {
@@ -50,10 +88,100 @@ type ServerConfig struct {
 	AllowFile        string
 	AllDistros       *distribution.AllDistroRegistry
 	DistributionsDir string
+	// Defaults to trusting the identity header if nil.
+	Authenticator Authenticator
+	// Roles aren't enforced if nil.
+	RBACClient *rbac.RBACClient
+	// Requests aren't rate limited if nil.
+	RateLimiter ratelimit.Limiter
+	// Compose requests aren't checked against a policy if nil.
+	PolicyClient *policy.PolicyClient
+	// Zero limits are replaced by the defaults.
+	RequestLimits RequestLimits
+	// How often queued composes are submitted to composer, the queue isn't
+	// processed if zero.
+	ComposeQueueInterval time.Duration
+	// Receives the approval requests and decisions of composes, as
+	// notifications for approvers. Not sent if empty.
+	ApprovalWebhookURL string
+	// Features are only gated by the distribution files and the allow
+	// file if nil.
+	FeatureFlags featureflags.Flags
+	// How long the status of an unfinished compose is served from the
+	// database before composer is asked again. The status of finished
+	// composes is always served from the database.
+	ComposeStatusCacheTTL time.Duration
+	// Composer backends new composes are routed to, the first one being
+	// the default. Defaults to CompClient alone if nil.
+	Composers *composer.Pool
+	// How often the queues and workers of the composer backends with a
+	// metrics url are recorded in the gauges, never if zero.
+	ComposerQueueInterval time.Duration
+	// How often finished composes and clones are looked for and their
+	// events delivered to webhooks, webhooks aren't served if zero.
+	WebhookInterval time.Duration
+	// Publishes the lifecycle events of composes, not published if nil.
+	Events EventPublisher
+	// How often the unfinished composes of all orgs are refreshed, so their
+	// outcome is recorded and their events are sent without anyone polling
+	// them. Every replica syncs, each compose is claimed by one of them for
+	// half the interval. Zero is replaced by the default.
+	StatusSyncInterval time.Duration
+	// Sends finished composes to the console notifications service, not
+	// sent if nil.
+	Notifications EventPublisher
+	// Emails the outcome of composes with a notify_email, which composes
+	// can't ask for if nil.
+	Mailer Mailer
+	// How often the outbox of compose events is dispatched to webhooks,
+	// Events, Notifications and Mailer. Zero is replaced by the default.
+	OutboxInterval time.Duration
+	// Registers the images of successful composes with the console
+	// inventory, not registered if nil.
+	Inventory EventPublisher
+	// What's done with requests which don't match the spec, enforced if
+	// empty.
+	RequestValidation ValidationMode
+	// What's done with the responses of handlers which don't match the
+	// spec, not validated if empty.
+	ResponseValidation ValidationMode
+	// Replaces QuotaFile, AllowFile, AllDistros and DistributionsDir with
+	// each config received, e.g. on SIGHUP. Nothing is reloaded if nil.
+	Reload <-chan ReloadableConfig
+	// Builder of the provenance of composes, and the key it's signed
+	// with.
+	Provenance ProvenanceConfig
+	// Signs the container images composes push if they ask for it.
+	Cosign CosignConfig
+	// Scans the packages of successful composes, which aren't scanned if
+	// nil.
+	Scanner VulnerabilityScanner
+	// Encrypts the signing keys of orgs, which can't be stored if nil.
+	Keystore *keystore.Keystore
+	// Exports the audit log and the provenance of composes to the buckets
+	// of orgs.
+	ComplianceExport ComplianceExportConfig
+	// Origins of the pages allowed to open the event stream, e.g.
+	// "https://console.redhat.com". Clients which don't send an Origin,
+	// which browsers always do, are allowed regardless.
+	StreamAllowedOrigins []string
 }
 
 type AWSConfig struct {
 	Region string
+	// Region in the aws-us-gov partition, GovCloud uploads are disabled if
+	// empty. Image builder has no credentials of its own, the uploads go to
+	// the composer backend which lists the region, whose workers have to
+	// hold GovCloud credentials. They're disabled without such a backend.
+	GovRegion string
+	// Encrypt AMIs by copying them once they're uploaded, with credentials
+	// of the account composer uploads into. AMIs can't be encrypted if
+	// either is nil.
+	EC2 *ec2.Client
+	KMS *kms.Client
+	// Key AMIs in Region are encrypted with if the request doesn't name
+	// one, they're only encrypted on request if empty.
+	DefaultKMSKeyArn string
 }
 
 type GCPConfig struct {
@@ -80,6 +208,17 @@ func Attach(conf *ServerConfig) error {
 
 	majorVersion := strings.Split(spec.Info.Version, ".")[0]
 
+	v2Spec, err := v2.GetSwagger()
+	if err != nil {
+		return err
+	}
+	v2Spec.AddServer(&openapi3.Server{URL: fmt.Sprintf("%s/v%s", RoutePrefix(), v2Spec.Info.Version)})
+	v2Router, err := legacyrouter.NewRouter(v2Spec)
+	if err != nil {
+		return err
+	}
+	v2MajorVersion := strings.Split(v2Spec.Info.Version, ".")[0]
+
 	allowList, err := common.LoadAllowList(conf.AllowFile)
 	if err != nil {
 		return err
@@ -90,36 +229,132 @@ func Attach(conf *ServerConfig) error {
 		conf.CompClient,
 		conf.ProvClient,
 		spec,
-		router,
+		apiRouter{router, v2Router},
 		conf.DBase,
 		conf.AwsConfig,
 		conf.GcpConfig,
-		conf.QuotaFile,
-		allowList,
-		conf.AllDistros,
-		conf.DistributionsDir,
+		conf.Authenticator,
+		conf.RBACClient,
+		conf.RateLimiter,
+		conf.RequestLimits.withDefaults(),
+		conf.PolicyClient,
+		conf.ApprovalWebhookURL,
+		conf.FeatureFlags,
+		&readinessCache{},
+		conf.ComposeStatusCacheTTL,
+		conf.Composers,
+		conf.Events,
+		conf.Notifications,
+		conf.Mailer,
+		awx.NewClient(awx.Config{}),
+		gpg.NewClient(gpg.Config{}),
+		conf.Inventory,
+		conf.RequestValidation,
+		conf.ResponseValidation,
+		conf.Provenance,
+		conf.Cosign,
+		conf.Scanner,
+		conf.Keystore,
+		conf.ComplianceExport,
+		newStreamHub(),
+		conf.StreamAllowedOrigins,
+		atomic.Pointer[settings]{},
+	}
+	s.settings.Store(&settings{
+		quotaFile:        conf.QuotaFile,
+		allowList:        allowList,
+		allDistros:       conf.AllDistros,
+		distributionsDir: conf.DistributionsDir,
+	})
+	if s.composers == nil {
+		s.composers, err = composer.NewPool([]composer.Backend{
+			{Name: composer.DefaultBackend, Client: conf.CompClient},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	s.cClient = s.composers.Default()
+	if s.auth == nil {
+		s.auth = NewIdentityHeaderAuthenticator(ServiceAccountConfig{})
 	}
 	var h Handlers
 	h.server = &s
-	s.echo.Binder = binder{}
+	s.echo.Binder = binder{limits: s.requestLimits.json()}
 	s.echo.HTTPErrorHandler = s.HTTPErrorHandler
+	// The gateway appends the address of the client to X-Forwarded-For,
+	// only the private addresses of the hops within the cluster are
+	// skipped to find it.
+	s.echo.IPExtractor = echo.ExtractIPFromXFFHeader()
 
 	middlewares := []echo.MiddlewareFunc{
+		tagRequestId,
 		prometheus.StatusMiddleware,
-		echo.WrapMiddleware(identity.Extractor),
-		echo.WrapMiddleware(identity.BasePolicy),
+		s.authenticate,
+		auditedBasePolicy,
 		noAssociateAccounts,
+		s.rateLimit,
+		s.limitRequestBody,
+		s.recordAuditLog,
 		s.ValidateRequest,
+		s.validateResponses,
+		s.markDeprecations,
+		s.enforceRoles,
+		s.enforceIPAllowList,
 		prometheus.PrometheusMW,
 	}
 
 	RegisterHandlers(s.echo.Group(fmt.Sprintf("%s/v%s", RoutePrefix(), majorVersion), middlewares...), &h)
 	RegisterHandlers(s.echo.Group(fmt.Sprintf("%s/v%s", RoutePrefix(), spec.Info.Version), middlewares...), &h)
+	h2 := v2Handlers{server: &s, handlers: &h, spec: v2Spec}
+	v2.RegisterHandlers(s.echo.Group(fmt.Sprintf("%s/v%s", RoutePrefix(), v2MajorVersion), middlewares...), &h2)
+	v2.RegisterHandlers(s.echo.Group(fmt.Sprintf("%s/v%s", RoutePrefix(), v2Spec.Info.Version), middlewares...), &h2)
+	s.attachInternal(&h)
+
+	// the background jobs stop when the echo server is shut down
+	ctx, cancel := context.WithCancel(context.Background())
+	conf.EchoServer.Server.RegisterOnShutdown(cancel)
+	if conf.ComposeQueueInterval > 0 {
+		go s.RunComposeQueue(ctx, conf.ComposeQueueInterval)
+	}
+	if conf.WebhookInterval > 0 {
+		go s.RunWebhooks(ctx, conf.WebhookInterval)
+	}
+	if conf.ComposerQueueInterval > 0 {
+		go s.RunComposerQueueMonitor(ctx, conf.ComposerQueueInterval)
+	}
+	statusSyncInterval := conf.StatusSyncInterval
+	if statusSyncInterval <= 0 {
+		statusSyncInterval = defaultStatusSyncInterval
+	}
+	go s.RunStatusSync(ctx, statusSyncInterval)
+	outboxInterval := conf.OutboxInterval
+	if outboxInterval <= 0 {
+		outboxInterval = defaultOutboxInterval
+	}
+	go s.RunOutbox(ctx, outboxInterval)
+	if s.complianceExport.Store != nil {
+		interval := s.complianceExport.Interval
+		if interval <= 0 {
+			interval = defaultComplianceExportInterval
+		}
+		go s.RunComplianceExports(ctx, interval)
+	}
+	go s.RunStream(ctx, defaultStreamInterval)
+	if s.keystore != nil {
+		go s.runMovePlaintextSecrets()
+	}
+	if conf.Reload != nil {
+		go s.runReloads(conf.Reload)
+	}
 
 	/* Used for the livenessProbe */
 	s.echo.GET("/status", func(c echo.Context) error {
 		return h.GetVersion(c)
 	})
+	s.echo.GET("/live", func(c echo.Context) error {
+		return h.GetVersion(c)
+	})
 
 	/* Used for the readinessProbe */
 	h.server.echo.GET("/ready", func(c echo.Context) error {
@@ -154,8 +389,10 @@ func RoutePrefix() string {
 
 // A simple echo.Binder(), which only accepts application/json, but is more
 // strict than echo's DefaultBinder. It does not handle binding query
-// parameters either.
-type binder struct{}
+// parameters either. Bodies are decoded with decodeJSON.
+type binder struct {
+	limits jsonLimits
+}
 
 func (b binder) Bind(i interface{}, ctx echo.Context) error {
 	request := ctx.Request()
@@ -165,7 +402,10 @@ func (b binder) Bind(i interface{}, ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusUnsupportedMediaType, "request must be json-encoded")
 	}
 
-	err := json.NewDecoder(request.Body).Decode(i)
+	raw, err := io.ReadAll(request.Body)
+	if err == nil {
+		err = decodeJSON(raw, i, b.limits)
+	}
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("cannot parse request body: %v", err))
 	}
@@ -173,8 +413,34 @@ func (b binder) Bind(i interface{}, ctx echo.Context) error {
 	return nil
 }
 
+// codedMessage is the message of errors which carry a machine readable code
+// for clients, besides the message for humans.
+type codedMessage struct {
+	code    string
+	message string
+}
+
+func (cm codedMessage) String() string {
+	return cm.message
+}
+
+const errCodeComposerUnavailable = "COMPOSER_UNAVAILABLE"
+
+func newCodedHTTPError(status int, code, message string) *echo.HTTPError {
+	return echo.NewHTTPError(status, codedMessage{code: code, message: message})
+}
+
 func (s *Server) HTTPErrorHandler(err error, c echo.Context) {
-	var errors []HTTPError
+	var circuitOpen *composer.CircuitOpenError
+	if errors.As(err, &circuitOpen) {
+		// fail fast rather than waiting for composer to time out
+		retryAfter := int(math.Ceil(circuitOpen.RetryAfter.Seconds()))
+		c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		err = newCodedHTTPError(http.StatusServiceUnavailable, errCodeComposerUnavailable,
+			"The build system is unavailable, please retry later")
+	}
+
+	var httpErrors []HTTPError
 	he, ok := err.(*echo.HTTPError)
 	if ok {
 		if he.Internal != nil {
@@ -198,19 +464,56 @@ func (s *Server) HTTPErrorHandler(err error, c echo.Context) {
 		}
 	}
 
-	errors = append(errors, HTTPError{
-		Title:  strconv.Itoa(he.Code),
-		Detail: fmt.Sprintf("%v", he.Message),
-	})
+	// errors of v2 always carry a code
+	if isV2Request(c) {
+		v2Error := v2.Error{
+			Code:    statusErrorCode(he.Code),
+			Message: fmt.Sprintf("%v", he.Message),
+		}
+		if cm, ok := he.Message.(codedMessage); ok {
+			v2Error.Code = cm.code
+		}
+		if !c.Response().Committed {
+			if c.Request().Method == http.MethodHead {
+				err = c.NoContent(he.Code)
+			} else {
+				err = c.JSON(he.Code, &v2Error)
+			}
+			if err != nil {
+				c.Logger().Error(err)
+			}
+		}
+		return
+	}
+
+	httpError := httpErrorOf(he)
+	problem := HTTPErrorList{
+		Type:      "about:blank",
+		Title:     http.StatusText(he.Code),
+		Status:    he.Code,
+		Detail:    httpError.Detail,
+		Code:      statusErrorCode(he.Code),
+		RequestId: common.ToPtr(requestId(c)),
+	}
+	if cm, ok := he.Message.(codedMessage); ok {
+		problem.Code = cm.code
+	}
+	if msgErr, ok := he.Message.(error); ok {
+		if params := invalidParams(msgErr); params != nil {
+			problem.InvalidParams = &params
+		}
+	}
+	httpErrors = append(httpErrors, httpError)
+	problem.Errors = httpErrors
 
 	// Send response
 	if !c.Response().Committed {
 		if c.Request().Method == http.MethodHead {
 			err = c.NoContent(he.Code)
 		} else {
-			err = c.JSON(he.Code, &HTTPErrorList{
-				errors,
-			})
+			c.Response().Header().Set(echo.HeaderContentType, mimeProblemJSON)
+			c.Response().WriteHeader(he.Code)
+			err = json.NewEncoder(c.Response()).Encode(&problem)
 		}
 		if err != nil {
 			c.Logger().Error(err)
@@ -218,14 +521,30 @@ func (s *Server) HTTPErrorHandler(err error, c echo.Context) {
 	}
 }
 
+// httpErrorOf returns an error in the format of the errors of a problem.
+func httpErrorOf(he *echo.HTTPError) HTTPError {
+	httpError := HTTPError{
+		Title:  strconv.Itoa(he.Code),
+		Detail: fmt.Sprintf("%v", he.Message),
+	}
+	if cm, ok := he.Message.(codedMessage); ok {
+		httpError.Code = common.ToPtr(cm.code)
+	}
+	return httpError
+}
+
 func (s *Server) distroRegistry(ctx echo.Context) *distribution.DistroRegistry {
-	return s.allDistros.Available(s.isEntitled(ctx))
+	return s.current().allDistros.Available(s.isEntitled(ctx))
 }
 
 // wraps DistroRegistry.Get and verifies the user has access
 func (s *Server) getDistro(ctx echo.Context, distro Distributions) (*distribution.DistributionFile, error) {
 	d, err := s.distroRegistry(ctx).Get(string(distro))
 	if err == distribution.DistributionNotFound {
+		// distinguish distributions which require a RHEL entitlement
+		if _, e := s.current().allDistros.Available(true).Get(string(distro)); e == nil {
+			return nil, auditRejection(ctx, reasonNotEntitled, echo.NewHTTPError(http.StatusBadRequest, err))
+		}
 		return nil, echo.NewHTTPError(http.StatusBadRequest, err)
 	}
 	if err != nil {
@@ -237,15 +556,13 @@ func (s *Server) getDistro(ctx echo.Context, distro Distributions) (*distributio
 		return nil, err
 	}
 
-	if d.IsRestricted() {
-		allowOk, err := s.allowList.IsAllowed(idHeader.Identity.Internal.OrgID, d.Distribution.Name)
-		if err != nil {
-			return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
-		}
-		if !allowOk {
-			message := fmt.Sprintf("This account's organization is not authorized to build %s images", string(d.Distribution.Name))
-			return nil, echo.NewHTTPError(http.StatusForbidden, message)
-		}
+	allowOk, err := s.distroAllowed(idHeader, d)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if !allowOk {
+		message := fmt.Sprintf("This account's organization is not authorized to build %s images", string(d.Distribution.Name))
+		return nil, auditRejection(ctx, reasonNotEntitled, echo.NewHTTPError(http.StatusForbidden, message))
 	}
 	return d, nil
 }
@@ -1,12 +1,21 @@
 package v1
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
-	"github.com/osbuild/image-builder/internal/tutils"
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/pkg/tutils"
 )
 
 func TestIdentity(t *testing.T) {
@@ -64,3 +73,330 @@ func TestIdentity(t *testing.T) {
 		require.Contains(t, body, "invalid or missing org_id")
 	})
 }
+
+func TestAPITokens(t *testing.T) {
+	srv, tokenSrv := startServer(t, "", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	bearerRequest := func(method, url, token string, body interface{}) (int, string) {
+		var reader io.Reader
+		if body != nil {
+			buf, err := json.Marshal(body)
+			require.NoError(t, err)
+			reader = bytes.NewReader(buf)
+		}
+		request, err := http.NewRequest(method, url, reader)
+		require.NoError(t, err)
+		request.Header.Add("Content-Type", "application/json")
+		request.Header.Add("Authorization", "Bearer "+token)
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		respBody, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response.StatusCode, string(respBody)
+	}
+
+	createToken := func(req APITokenRequest) APITokenCreated {
+		respStatusCode, body := tutils.PostResponseBody(t, "http://localhost:8086/api/image-builder/v1/tokens", req)
+		require.Equal(t, http.StatusCreated, respStatusCode)
+		var token APITokenCreated
+		require.NoError(t, json.Unmarshal([]byte(body), &token))
+		require.True(t, strings.HasPrefix(token.Token, apiTokenPrefix))
+		return token
+	}
+
+	rw := createToken(APITokenRequest{Name: "ci"})
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	ro := createToken(APITokenRequest{Name: "monitoring", ReadOnly: common.ToPtr(true), ExpiresAt: &expiresAt})
+	require.Equal(t, expiresAt.Format(time.RFC3339), *ro.ExpiresAt)
+	// expiry times with an offset are stored in utc
+	cet := time.FixedZone("CEST", 2*60*60)
+	offset := createToken(APITokenRequest{Name: "offset", ExpiresAt: common.ToPtr(expiresAt.In(cet))})
+	require.Equal(t, expiresAt.Format(time.RFC3339), *offset.ExpiresAt)
+
+	respStatusCode, body := tutils.PostResponseBody(t, "http://localhost:8086/api/image-builder/v1/tokens", APITokenRequest{Name: "expired", ExpiresAt: common.ToPtr(time.Now().Add(-time.Hour))})
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+	require.Contains(t, body, "expires_at has to be in the future")
+
+	respStatusCode, body = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/tokens", &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	var tokens APITokensResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &tokens))
+	require.Len(t, tokens.Data, 3)
+	require.NotContains(t, body, rw.Token)
+	require.Nil(t, tokens.Data[0].LastUsedAt)
+	for _, token := range tokens.Data {
+		if token.Id == offset.Id {
+			require.Equal(t, expiresAt.Format(time.RFC3339), *token.ExpiresAt)
+		}
+	}
+	respStatusCode, _ = tutils.DeleteResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/tokens/%s", offset.Id), &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+
+	// tokens are scoped to the org
+	respStatusCode, body = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/tokens", &tutils.AuthString1)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &tokens))
+	require.Empty(t, tokens.Data)
+
+	respStatusCode, _ = bearerRequest("GET", "http://localhost:8086/api/image-builder/v1/composes", rw.Token, nil)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	respStatusCode, _ = bearerRequest("GET", "http://localhost:8086/api/image-builder/v1/composes", ro.Token, nil)
+	require.Equal(t, http.StatusOK, respStatusCode)
+
+	respStatusCode, body = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/tokens", &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &tokens))
+	for _, token := range tokens.Data {
+		require.NotNil(t, token.LastUsedAt)
+	}
+
+	respStatusCode, body = bearerRequest("DELETE", fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s", uuid.New()), ro.Token, nil)
+	require.Equal(t, http.StatusForbidden, respStatusCode)
+	require.Contains(t, body, "read-only api tokens")
+
+	respStatusCode, body = bearerRequest("POST", "http://localhost:8086/api/image-builder/v1/tokens", rw.Token, APITokenRequest{Name: "escalate"})
+	require.Equal(t, http.StatusForbidden, respStatusCode)
+	require.Contains(t, body, "API tokens can not be used to manage API tokens")
+
+	respStatusCode, _ = bearerRequest("GET", "http://localhost:8086/api/image-builder/v1/composes", apiTokenPrefix+"unknown", nil)
+	require.Equal(t, http.StatusUnauthorized, respStatusCode)
+
+	// revoking a token only works for its org
+	respStatusCode, _ = bearerRequest("DELETE", fmt.Sprintf("http://localhost:8086/api/image-builder/v1/tokens/%s", rw.Id), rw.Token, nil)
+	require.Equal(t, http.StatusForbidden, respStatusCode)
+	respStatusCode, _ = tutils.DeleteResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/tokens/%s", rw.Id), &tutils.AuthString1)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+	respStatusCode, _ = tutils.DeleteResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/tokens/%s", rw.Id), &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+
+	respStatusCode, _ = bearerRequest("GET", "http://localhost:8086/api/image-builder/v1/composes", rw.Token, nil)
+	require.Equal(t, http.StatusUnauthorized, respStatusCode)
+}
+
+func TestIPAllowList(t *testing.T) {
+	srv, tokenSrv := startServer(t, "", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	request := func(method, url, forwardedFor string, body interface{}) (int, string) {
+		var reader io.Reader
+		if body != nil {
+			buf, err := json.Marshal(body)
+			require.NoError(t, err)
+			reader = bytes.NewReader(buf)
+		}
+		request, err := http.NewRequest(method, url, reader)
+		require.NoError(t, err)
+		request.Header.Add("Content-Type", "application/json")
+		request.Header.Add("x-rh-identity", tutils.AuthString0)
+		if forwardedFor != "" {
+			request.Header.Add("X-Forwarded-For", forwardedFor)
+		}
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		respBody, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response.StatusCode, string(respBody)
+	}
+
+	allowListURL := "http://localhost:8086/api/image-builder/v1/settings/ip-allowlist"
+	defer func() {
+		respStatusCode, _ := request("PUT", allowListURL, "", IPAllowList{Cidrs: []string{}})
+		require.Equal(t, http.StatusOK, respStatusCode)
+	}()
+
+	respStatusCode, body := tutils.GetResponseBody(t, allowListURL, &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	var allowList IPAllowList
+	require.NoError(t, json.Unmarshal([]byte(body), &allowList))
+	require.Empty(t, allowList.Cidrs)
+
+	respStatusCode, body = request("PUT", allowListURL, "", IPAllowList{Cidrs: []string{"not-an-ip"}})
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+	require.Contains(t, body, "not-an-ip")
+
+	respStatusCode, body = request("PUT", allowListURL, "", IPAllowList{Cidrs: []string{"192.0.2.0/24", "198.51.100.7"}})
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &allowList))
+	require.ElementsMatch(t, []string{"192.0.2.0/24", "198.51.100.7/32"}, allowList.Cidrs)
+
+	// the allow list is scoped to the org
+	respStatusCode, body = tutils.GetResponseBody(t, allowListURL, &tutils.AuthString1)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &allowList))
+	require.Empty(t, allowList.Cidrs)
+
+	var clone CloneRequest
+	require.NoError(t, clone.FromAWSEC2Clone(AWSEC2Clone{Region: "us-east-2"}))
+	cloneURL := fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s/clone", uuid.New())
+
+	respStatusCode, body = request("POST", cloneURL, "198.51.100.8", clone)
+	require.Equal(t, http.StatusForbidden, respStatusCode)
+	require.Contains(t, body, "Builds can not be submitted from 198.51.100.8")
+
+	// a spoofed address prepended by the client is ignored
+	respStatusCode, _ = request("POST", cloneURL, "192.0.2.10, 198.51.100.8", clone)
+	require.Equal(t, http.StatusForbidden, respStatusCode)
+
+	// the compose doesn't exist, but the request made it past the allow list
+	respStatusCode, _ = request("POST", cloneURL, "198.51.100.8, 198.51.100.7", clone)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+
+	// approving a compose releases it to be built, launching starts instances
+	// of its image
+	composeURL := fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s", uuid.New())
+	respStatusCode, body = request("POST", composeURL+"/approve", "198.51.100.8", nil)
+	require.Equal(t, http.StatusForbidden, respStatusCode)
+	require.Contains(t, body, "Builds can not be submitted from 198.51.100.8")
+	respStatusCode, _ = request("POST", composeURL+"/approve", "198.51.100.7", nil)
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+	respStatusCode, body = request("POST", composeURL+"/launch", "198.51.100.8", LaunchRequest{})
+	require.Equal(t, http.StatusForbidden, respStatusCode)
+	require.Contains(t, body, "Builds can not be submitted from 198.51.100.8")
+	respStatusCode, _ = request("POST", composeURL+"/launch", "198.51.100.7", LaunchRequest{})
+	require.Equal(t, http.StatusNotFound, respStatusCode)
+
+	// requests which don't submit builds aren't restricted
+	respStatusCode, _ = request("GET", "http://localhost:8086/api/image-builder/v1/composes", "198.51.100.8", nil)
+	require.Equal(t, http.StatusOK, respStatusCode)
+}
+
+func TestUploadTargetPolicy(t *testing.T) {
+	dbase, err := dbc.NewDB()
+	require.NoError(t, err)
+	composeId := uuid.New()
+	err = dbase.InsertCompose(composeId, "500000", "user@test.test", "000000", nil, json.RawMessage(`{"image_requests": [{"image_type": "aws"}]}`))
+	require.NoError(t, err)
+	srv, tokenSrv := startServerWithCustomDB(t, "", "", dbase, "../../distributions", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	policyURL := "http://localhost:8086/api/image-builder/v1/settings/upload-targets"
+	respStatusCode, body := tutils.GetResponseBody(t, policyURL, &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.JSONEq(t, `{"targets": []}`, body)
+
+	respStatusCode, body = tutils.PutResponseBody(t, policyURL, UploadTargetPolicy{
+		Targets: []UploadTargetRule{{Type: UploadTypesAws}, {Type: UploadTypesAws}},
+	})
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+	require.Contains(t, body, "upload target aws is listed more than once")
+
+	respStatusCode, body = tutils.PutResponseBody(t, policyURL, UploadTargetPolicy{
+		Targets: []UploadTargetRule{{Type: UploadTypesAws, Regions: &[]string{"eu-west-1"}}},
+	})
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.JSONEq(t, `{"targets": [{"type": "aws", "regions": ["eu-west-1"]}]}`, body)
+
+	// the policy is scoped to the org
+	respStatusCode, body = tutils.GetResponseBody(t, policyURL, &tutils.AuthString1)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.JSONEq(t, `{"targets": []}`, body)
+
+	compose := func(uploadType UploadTypes, uo UploadRequest_Options) (int, HTTPErrorList) {
+		payload := ComposeRequest{
+			Distribution: "centos-8",
+			ImageRequests: []ImageRequest{
+				{
+					Architecture: "x86_64",
+					ImageType:    ImageTypesAws,
+					UploadRequest: UploadRequest{
+						Type:    uploadType,
+						Options: uo,
+					},
+				},
+			},
+		}
+		respStatusCode, body := tutils.PostResponseBody(t, "http://localhost:8086/api/image-builder/v1/compose", payload)
+		var errs HTTPErrorList
+		require.NoError(t, json.Unmarshal([]byte(body), &errs))
+		return respStatusCode, errs
+	}
+
+	var gcp UploadRequest_Options
+	require.NoError(t, gcp.FromGCPUploadRequestOptions(GCPUploadRequestOptions{
+		ShareWithAccounts: &[]string{"user:example@example.com"},
+	}))
+	respStatusCode, errs := compose(UploadTypesGcp, gcp)
+	require.Equal(t, http.StatusForbidden, respStatusCode)
+	require.Len(t, errs.Errors, 1)
+	require.Equal(t, errCodeUploadTargetNotAllowed, *errs.Errors[0].Code)
+
+	var aws UploadRequest_Options
+	require.NoError(t, aws.FromAWSUploadRequestOptions(AWSUploadRequestOptions{
+		ShareWithAccounts: &[]string{"123456789012"},
+		Regions:           &[]string{"us-east-2"},
+	}))
+	respStatusCode, errs = compose(UploadTypesAws, aws)
+	require.Equal(t, http.StatusForbidden, respStatusCode)
+	require.Len(t, errs.Errors, 1)
+	require.Equal(t, errCodeUploadRegionNotAllowed, *errs.Errors[0].Code)
+	require.Equal(t, "Uploading images to aws region us-east-2 is not allowed for this organization", errs.Errors[0].Detail)
+
+	var clone CloneRequest
+	require.NoError(t, clone.FromAWSEC2Clone(AWSEC2Clone{Region: "us-east-2"}))
+	respStatusCode, body = tutils.PostResponseBody(t, fmt.Sprintf("http://localhost:8086/api/image-builder/v1/composes/%s/clone", composeId), clone)
+	require.Equal(t, http.StatusForbidden, respStatusCode)
+	require.Contains(t, body, errCodeUploadRegionNotAllowed)
+}
+
+func TestAuditLog(t *testing.T) {
+	srv, tokenSrv := startServer(t, "", "")
+	defer func() {
+		err := srv.Shutdown(context.Background())
+		require.NoError(t, err)
+	}()
+	defer tokenSrv.Close()
+
+	policyURL := "http://localhost:8086/api/image-builder/v1/settings/upload-targets"
+	respStatusCode, _ := tutils.PutResponseBody(t, policyURL, UploadTargetPolicy{
+		Targets: []UploadTargetRule{{Type: UploadTypesAws}},
+	})
+	require.Equal(t, http.StatusOK, respStatusCode)
+	respStatusCode, _ = tutils.PutResponseBody(t, policyURL, UploadTargetPolicy{
+		Targets: []UploadTargetRule{{Type: UploadTypesAws}, {Type: UploadTypesAws}},
+	})
+	require.Equal(t, http.StatusBadRequest, respStatusCode)
+
+	// reads aren't recorded
+	respStatusCode, _ = tutils.GetResponseBody(t, policyURL, &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+
+	respStatusCode, body := tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/audit", &tutils.AuthString0)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	var result AuditLogResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &result))
+	require.Equal(t, 2, result.Meta.Count)
+	require.Len(t, result.Data, 2)
+
+	// newest first
+	require.Equal(t, http.StatusBadRequest, result.Data[0].Status)
+	require.Equal(t, http.StatusOK, result.Data[1].Status)
+	for _, e := range result.Data {
+		require.Equal(t, http.MethodPut, e.Method)
+		require.Equal(t, "/api/image-builder/v1/settings/upload-targets", e.Path)
+		require.Nil(t, e.ResourceId)
+		require.Len(t, e.RequestDigest, 64)
+	}
+	require.NotEqual(t, result.Data[0].RequestDigest, result.Data[1].RequestDigest)
+
+	// the log is scoped to the org
+	respStatusCode, body = tutils.GetResponseBody(t, "http://localhost:8086/api/image-builder/v1/audit", &tutils.AuthString1)
+	require.Equal(t, http.StatusOK, respStatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &result))
+	require.Equal(t, 0, result.Meta.Count)
+	require.Empty(t, result.Data)
+}
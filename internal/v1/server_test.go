@@ -1,6 +1,8 @@
 package v1
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -17,9 +19,10 @@ import (
 	"github.com/osbuild/image-builder/internal/composer"
 	"github.com/osbuild/image-builder/internal/db"
 	"github.com/osbuild/image-builder/internal/distribution"
+	"github.com/osbuild/image-builder/internal/keystore"
 	"github.com/osbuild/image-builder/internal/logger"
 	"github.com/osbuild/image-builder/internal/provisioning"
-	"github.com/osbuild/image-builder/internal/tutils"
+	"github.com/osbuild/image-builder/pkg/tutils"
 )
 
 var dbc *tutils.PSQLContainer
@@ -147,6 +150,7 @@ func startServerWithCustomDB(t *testing.T, url, provURL string, dbase db.DB, dis
 		AllowFile:        allowFile,
 		AllDistros:       adr,
 		DistributionsDir: distsDir,
+		Keystore:         testKeystore(t),
 	}
 
 	err = Attach(serverConfig)
@@ -175,6 +179,13 @@ func startServerWithCustomDB(t *testing.T, url, provURL string, dbase db.DB, dis
 	return echoServer, tokenServer
 }
 
+// testKeystore returns a keystore with a fixed key encryption key.
+func testKeystore(t *testing.T) *keystore.Keystore {
+	keys, err := keystore.Parse("kek-1:" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)))
+	require.NoError(t, err)
+	return keys
+}
+
 func startServer(t *testing.T, url, provURL string) (*echo.Echo, *httptest.Server) {
 	dbase, err := dbc.NewDB()
 	require.NoError(t, err)
@@ -0,0 +1,411 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/redhatinsights/identity"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/gpg"
+	"github.com/osbuild/image-builder/internal/keystore"
+)
+
+// webhook secrets are HMAC keys, anything shorter is easy to guess
+const minWebhookSecretLength = 16
+
+// signingKeyAdditionalData binds the envelope of a key to the key and its
+// org, so envelopes can't be swapped between rows.
+func signingKeyAdditionalData(id uuid.UUID, orgId string) []byte {
+	return []byte(id.String() + "/" + orgId)
+}
+
+func signingKeyStatus(k db.SigningKeyEntry) SigningKeyStatus {
+	switch {
+	case k.RevokedAt != nil:
+		return Revoked
+	case k.ReplacedBy != nil:
+		return Rotated
+	}
+	return InUse
+}
+
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+func signingKey(k db.SigningKeyEntry) SigningKey {
+	return SigningKey{
+		Id:          k.Id,
+		Purpose:     SigningKeyPurpose(k.Purpose),
+		Status:      signingKeyStatus(k),
+		Fingerprint: k.Fingerprint,
+		PublicKey:   k.PublicKey,
+		CreatedAt:   k.CreatedAt.UTC(),
+		RotatedAt:   utcTime(k.RotatedAt),
+		ReplacedBy:  k.ReplacedBy,
+		RevokedAt:   utcTime(k.RevokedAt),
+	}
+}
+
+// newSigningKey checks the key material of a purpose and encrypts it.
+func (s *Server) newSigningKey(orgId string, purpose SigningKeyPurpose, material SigningKeyMaterial) (*db.SigningKeyEntry, error) {
+	key := db.SigningKeyEntry{
+		Id:      uuid.New(),
+		OrgId:   orgId,
+		Purpose: string(purpose),
+	}
+	var plaintext []byte
+	switch purpose {
+	case SigningKeyPurposeArtifact, SigningKeyPurposeCommit:
+		if material.PrivateKey == nil || material.Secret != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("A %s key has to be a private key", purpose))
+		}
+		parsed, err := gpg.ParseKey(*material.PrivateKey)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid private key: %v", err))
+		}
+		publicKey, err := parsed.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		fingerprint := parsed.Fingerprint()
+		key.Fingerprint = &fingerprint
+		key.PublicKey = &publicKey
+		plaintext = []byte(*material.PrivateKey)
+	case SigningKeyPurposeWebhook:
+		if material.Secret == nil || material.PrivateKey != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "A webhook key has to be a secret")
+		}
+		if len(*material.Secret) < minWebhookSecretLength {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("The secret has to be at least %d characters long", minWebhookSecretLength))
+		}
+		plaintext = []byte(*material.Secret)
+	case SigningKeyPurposeSigningService:
+		if material.Secret == nil || material.PrivateKey != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "A signing_service key has to be the token of the signing service")
+		}
+		plaintext = []byte(*material.Secret)
+	default:
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unknown purpose %q", purpose))
+	}
+
+	if s.keystore == nil {
+		return nil, errNoKeystore
+	}
+	envelope, err := s.keystore.Encrypt(plaintext, signingKeyAdditionalData(key.Id, orgId))
+	if err != nil {
+		return nil, err
+	}
+	key.KEKId = envelope.KeyId
+	key.EncryptedDataKey = envelope.DataKey
+	key.Ciphertext = envelope.Ciphertext
+	return &key, nil
+}
+
+// checkKeystore makes sure keys can be encrypted before anything is stored.
+func (h *Handlers) checkKeystore() error {
+	if h.server.keystore == nil {
+		return echo.NewHTTPError(http.StatusNotImplemented, "Signing keys can't be stored, no key encryption key is configured")
+	}
+	return nil
+}
+
+func (h *Handlers) GetSigningKeys(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys, err := h.server.db.GetSigningKeys(idHeader.Identity.OrgID)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying signing keys: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the signing keys")
+	}
+	data := []SigningKey{}
+	for _, k := range keys {
+		data = append(data, signingKey(k))
+	}
+	return ctx.JSON(http.StatusOK, SigningKeysResponse{
+		Data: data,
+	})
+}
+
+func (h *Handlers) CreateSigningKey(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+	err = h.checkKeystore()
+	if err != nil {
+		return err
+	}
+
+	var req SigningKeyRequest
+	err = ctx.Bind(&req)
+	if err != nil {
+		return err
+	}
+	orgId := idHeader.Identity.OrgID
+	key, err := h.server.newSigningKey(orgId, req.Purpose, SigningKeyMaterial{
+		PrivateKey: req.PrivateKey,
+		Secret:     req.Secret,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = h.server.db.GetSigningKeyInUse(orgId, key.Purpose)
+	if err == nil {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("A %s key is in use already, rotate it to replace it", key.Purpose))
+	} else if !errors.Is(err, db.SigningKeyNotFoundError) {
+		ctx.Logger().Errorf("Error querying signing keys: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the signing keys")
+	}
+	err = h.server.db.InsertSigningKey(*key)
+	if err != nil {
+		ctx.Logger().Errorf("Error inserting signing key: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong storing the signing key")
+	}
+	setAuditResource(ctx, key.Id)
+
+	logAction(ctx, "create_signing_key", signingKeyFields(key), "Signing key created")
+	return h.respondSigningKey(ctx, http.StatusCreated, key.Id, orgId)
+}
+
+func signingKeyFields(key *db.SigningKeyEntry) logrus.Fields {
+	fields := logrus.Fields{"org_id": key.OrgId, "key_id": key.Id, "purpose": key.Purpose}
+	if key.Fingerprint != nil {
+		fields["fingerprint"] = *key.Fingerprint
+	}
+	return fields
+}
+
+// respondSigningKey responds with a key as it's stored, with its timestamps.
+func (h *Handlers) respondSigningKey(ctx echo.Context, code int, id uuid.UUID, orgId string) error {
+	key, err := h.server.db.GetSigningKey(id, orgId)
+	if errors.Is(err, db.SigningKeyNotFoundError) {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Signing key %v not found", id))
+	} else if err != nil {
+		ctx.Logger().Errorf("Error querying signing key %v: %v", id, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the signing key")
+	}
+	return ctx.JSON(code, signingKey(*key))
+}
+
+func (h *Handlers) GetSigningKey(ctx echo.Context, id uuid.UUID) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+	return h.respondSigningKey(ctx, http.StatusOK, id, idHeader.Identity.OrgID)
+}
+
+// signingKeyToRotate returns a key of the org which is in use.
+func (h *Handlers) signingKeyToRotate(ctx echo.Context, idHeader *identity.XRHID, id uuid.UUID) (*db.SigningKeyEntry, error) {
+	key, err := h.server.db.GetSigningKey(id, idHeader.Identity.OrgID)
+	if errors.Is(err, db.SigningKeyNotFoundError) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Signing key %v not found", id))
+	} else if err != nil {
+		ctx.Logger().Errorf("Error querying signing key %v: %v", id, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the signing key")
+	}
+	if status := signingKeyStatus(*key); status != InUse {
+		return nil, echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Signing key %v was %s already", id, status))
+	}
+	return key, nil
+}
+
+func (h *Handlers) RotateSigningKey(ctx echo.Context, id uuid.UUID) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+	err = h.checkKeystore()
+	if err != nil {
+		return err
+	}
+
+	var req SigningKeyMaterial
+	err = ctx.Bind(&req)
+	if err != nil {
+		return err
+	}
+	old, err := h.signingKeyToRotate(ctx, idHeader, id)
+	if err != nil {
+		return err
+	}
+	key, err := h.server.newSigningKey(old.OrgId, SigningKeyPurpose(old.Purpose), req)
+	if err != nil {
+		return err
+	}
+	err = h.server.db.RotateSigningKey(id, old.OrgId, *key)
+	if errors.Is(err, db.SigningKeyNotFoundError) {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Signing key %v isn't in use anymore", id))
+	} else if err != nil {
+		ctx.Logger().Errorf("Error rotating signing key %v: %v", id, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong rotating the signing key")
+	}
+
+	fields := signingKeyFields(key)
+	fields["replaced_key_id"] = id
+	logAction(ctx, "rotate_signing_key", fields, "Signing key rotated")
+	return h.respondSigningKey(ctx, http.StatusCreated, key.Id, old.OrgId)
+}
+
+func (h *Handlers) RevokeSigningKey(ctx echo.Context, id uuid.UUID) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	key, err := h.server.db.GetSigningKey(id, idHeader.Identity.OrgID)
+	if errors.Is(err, db.SigningKeyNotFoundError) {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Signing key %v not found", id))
+	} else if err != nil {
+		ctx.Logger().Errorf("Error querying signing key %v: %v", id, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the signing key")
+	}
+	// rotated keys can still be revoked, e.g. once they leaked
+	err = h.server.db.RevokeSigningKey(id, key.OrgId)
+	if errors.Is(err, db.SigningKeyNotFoundError) {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Signing key %v was revoked already", id))
+	} else if err != nil {
+		ctx.Logger().Errorf("Error revoking signing key %v: %v", id, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong revoking the signing key")
+	}
+
+	logAction(ctx, "revoke_signing_key", signingKeyFields(key), "Signing key revoked")
+	return h.respondSigningKey(ctx, http.StatusOK, id, key.OrgId)
+}
+
+func (h *Handlers) GetSigningKeyUsage(ctx echo.Context, id uuid.UUID, params GetSigningKeyUsageParams) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.server.db.GetSigningKey(id, idHeader.Identity.OrgID)
+	if errors.Is(err, db.SigningKeyNotFoundError) {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Signing key %v not found", id))
+	} else if err != nil {
+		ctx.Logger().Errorf("Error querying signing key %v: %v", id, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the signing key")
+	}
+
+	limit := 100
+	if params.Limit != nil && *params.Limit > 0 {
+		limit = *params.Limit
+	}
+	offset := 0
+	if params.Offset != nil {
+		offset = *params.Offset
+	}
+
+	usage, count, err := h.server.db.GetSigningKeyUsage(id, idHeader.Identity.OrgID, limit, offset)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying the usage of signing key %v: %v", id, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the usage of the signing key")
+	}
+
+	data := []SigningKeyUsage{}
+	for _, u := range usage {
+		data = append(data, SigningKeyUsage{
+			Operation:  SigningKeyUsageOperation(u.Operation),
+			ResourceId: u.ResourceId,
+			UsedAt:     u.UsedAt.UTC(),
+		})
+	}
+
+	lastOffset := count - 1
+	if lastOffset < 0 {
+		lastOffset = 0
+	}
+
+	return ctx.JSON(http.StatusOK, SigningKeyUsageResponse{
+		Meta: struct {
+			Count int `json:"count"`
+		}{
+			count,
+		},
+		Links: struct {
+			First string `json:"first"`
+			Last  string `json:"last"`
+		}{
+			fmt.Sprintf("%v/v%v/keys/%v/usage?offset=0&limit=%v",
+				RoutePrefix(), h.server.spec.Info.Version, id, limit),
+			fmt.Sprintf("%v/v%v/keys/%v/usage?offset=%v&limit=%v",
+				RoutePrefix(), h.server.spec.Info.Version, id, lastOffset, limit),
+		},
+		Data: data,
+	})
+}
+
+// signingKeyInUse decrypts the key an org signs with for purpose, nil if it
+// has none or keys aren't managed by this service.
+func (s *Server) signingKeyInUse(orgId string, purpose SigningKeyPurpose) (*db.SigningKeyEntry, []byte, error) {
+	if s.keystore == nil {
+		return nil, nil, nil
+	}
+	key, err := s.db.GetSigningKeyInUse(orgId, string(purpose))
+	if errors.Is(err, db.SigningKeyNotFoundError) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := s.keystore.Decrypt(keystore.Envelope{
+		KeyId:      key.KEKId,
+		DataKey:    key.EncryptedDataKey,
+		Ciphertext: key.Ciphertext,
+	}, signingKeyAdditionalData(key.Id, key.OrgId))
+	if err != nil {
+		return nil, nil, fmt.Errorf("decrypting signing key %v failed: %w", key.Id, err)
+	}
+	return key, plaintext, nil
+}
+
+// orgGPGKey is an OpenPGP key an org signs with, the id is set if it's
+// managed by the keys endpoints.
+type orgGPGKey struct {
+	*gpg.Key
+	id *uuid.UUID
+}
+
+// managedGPGKey returns the artifact or commit key an org signs with, nil if
+// it has none.
+func (s *Server) managedGPGKey(orgId string, purpose SigningKeyPurpose) (*orgGPGKey, error) {
+	key, plaintext, err := s.signingKeyInUse(orgId, purpose)
+	if err != nil || key == nil {
+		return nil, err
+	}
+	parsed, err := gpg.ParseKey(string(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	return &orgGPGKey{parsed, &key.Id}, nil
+}
+
+// recordKeyUsage adds to the audit trail of a managed key. It's only logged
+// if that fails, the signature was made already.
+func (s *Server) recordKeyUsage(keyId *uuid.UUID, operation SigningKeyUsageOperation, resourceId uuid.UUID) {
+	if keyId == nil {
+		return
+	}
+	err := s.db.InsertSigningKeyUsage(db.SigningKeyUsageEntry{
+		KeyId:      *keyId,
+		Operation:  string(operation),
+		ResourceId: &resourceId,
+	})
+	if err != nil {
+		logrus.Errorf("Error recording the %s of %v with signing key %v: %v", operation, resourceId, *keyId, err)
+	}
+}
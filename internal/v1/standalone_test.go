@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redhatinsights/identity"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func decodeIdentity(t *testing.T, header string) identity.XRHID {
+	raw, err := base64.StdEncoding.DecodeString(header)
+	require.NoError(t, err)
+	var id identity.XRHID
+	require.NoError(t, json.Unmarshal(raw, &id))
+	return id
+}
+
+func TestStandaloneAuthenticator(t *testing.T) {
+	_, err := NewStandaloneAuthenticator(StandaloneConfig{})
+	require.Error(t, err)
+
+	a, err := NewStandaloneAuthenticator(StandaloneConfig{OrgId: "000000", Username: "developer"})
+	require.NoError(t, err)
+
+	// identity headers of the caller are ignored
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Rh-Identity", "bogus")
+	header, err := a.IdentityHeader(req)
+	require.NoError(t, err)
+	id := decodeIdentity(t, header)
+	require.Equal(t, "000000", id.Identity.OrgID)
+	require.Equal(t, "000000", id.Identity.Internal.OrgID)
+	require.Equal(t, "developer", id.Identity.User.Username)
+	require.True(t, id.Identity.User.OrgAdmin)
+	require.Equal(t, "User", id.Identity.Type)
+	require.True(t, id.Entitlements["rhel"].IsEntitled)
+}
+
+func TestStandaloneAuthenticatorBasicAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	usersFile := filepath.Join(t.TempDir(), "users")
+	require.NoError(t, os.WriteFile(usersFile, []byte("# users\nalice:"+string(hash)+"\n\n"), 0600))
+
+	a, err := NewStandaloneAuthenticator(StandaloneConfig{OrgId: "000000", UsersFile: usersFile})
+	require.NoError(t, err)
+
+	identityHeader := func(username, password string) (string, error) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		return a.IdentityHeader(req)
+	}
+
+	header, err := identityHeader("alice", "secret")
+	require.NoError(t, err)
+	require.Equal(t, "alice", decodeIdentity(t, header).Identity.User.Username)
+
+	for _, creds := range [][2]string{{"", ""}, {"alice", "wrong"}, {"bob", "secret"}} {
+		_, err = identityHeader(creds[0], creds[1])
+		require.Error(t, err)
+		require.Equal(t, http.StatusUnauthorized, err.(*echo.HTTPError).Code)
+	}
+
+	require.NoError(t, os.WriteFile(usersFile, []byte("alice:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0600))
+	_, err = NewStandaloneAuthenticator(StandaloneConfig{OrgId: "000000", UsersFile: usersFile})
+	require.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var buildDurationWindows = map[GetBuildDurationsParamsWindow]time.Duration{
+	N1d:  24 * time.Hour,
+	N7d:  7 * 24 * time.Hour,
+	N30d: 30 * 24 * time.Hour,
+	N90d: 90 * 24 * time.Hour,
+}
+
+// GetBuildDurations returns the percentiles of how long the builds of all
+// orgs took, so new builds can be given an estimate and capacity planned.
+func (h *Handlers) GetBuildDurations(ctx echo.Context, params GetBuildDurationsParams) error {
+	window := N30d
+	if params.Window != nil {
+		window = *params.Window
+	}
+	d, ok := buildDurationWindows[window]
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid window, expected 1d, 7d, 30d or 90d")
+	}
+	until := time.Now().UTC()
+	since := until.Add(-d)
+
+	entries, err := h.server.db.GetBuildDurations(since, until)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying the build durations: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the build durations")
+	}
+	data := []BuildDurationStats{}
+	for _, e := range entries {
+		data = append(data, BuildDurationStats{
+			Distribution: e.Distribution,
+			ImageType:    e.ImageType,
+			Architecture: e.Arch,
+			Builds:       e.Builds,
+			P50Seconds:   e.P50Seconds,
+			P95Seconds:   e.P95Seconds,
+		})
+	}
+	return ctx.JSON(http.StatusOK, BuildDurations{
+		Window: string(window),
+		Since:  since,
+		Until:  until,
+		Data:   data,
+	})
+}
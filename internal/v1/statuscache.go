@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+// cachedComposeStatus returns the status composer last reported for a compose
+// if the compose finished, or the status was refreshed recently enough, so
+// clients polling for the status don't each translate into a request to
+// composer. Otherwise the status is fetched from composer and stored.
+func (h *Handlers) cachedComposeStatus(ctx echo.Context, composeEntry *db.ComposeEntry) (*composer.ComposeStatus, error) {
+	cached, err := h.server.db.GetCachedComposeStatus(composeEntry.Id, h.server.composeStatusTTL)
+	if err == nil {
+		var cloudStat composer.ComposeStatus
+		err = json.Unmarshal(cached.Status, &cloudStat)
+		if err == nil && (cached.Fresh || finished(cloudStat.ImageStatus.Status)) {
+			return &cloudStat, nil
+		}
+	} else if !errors.Is(err, db.ComposeStatusNotFoundError) {
+		// composer still has the status
+		ctx.Logger().Errorf("Error querying the cached status of compose %v: %v", composeEntry.Id, err)
+	}
+
+	cc, err := h.server.composerOf(composeEntry)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cc.ComposeStatus(ctx.Request().Context(), composeEntry.ComposerId)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp.Body)
+
+	if resp.StatusCode == http.StatusNotFound {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		// Composes can get deleted in composer, usually when the image is expired
+		return nil, echo.NewHTTPError(http.StatusNotFound, string(body))
+	} else if resp.StatusCode != http.StatusOK {
+		httpError := echo.NewHTTPError(http.StatusInternalServerError, "Failed querying compose status")
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			ctx.Logger().Errorf("Unable to parse composer's compose response: %v", err)
+		} else {
+			_ = httpError.SetInternal(fmt.Errorf("%s", body))
+		}
+		return nil, httpError
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var cloudStat composer.ComposeStatus
+	err = json.Unmarshal(body, &cloudStat)
+	if err != nil {
+		return nil, err
+	}
+
+	// the cache and the status history only spare composer and help support,
+	// don't fail the request
+	err = h.server.db.SetCachedComposeStatus(composeEntry.Id, body)
+	if err != nil {
+		ctx.Logger().Errorf("Error caching status of compose %v: %v", composeEntry.Id, err)
+	}
+	err = h.server.recordComposeStatus(*composeEntry, cloudStat.ImageStatus)
+	if err != nil {
+		ctx.Logger().Errorf("Error recording status of compose %v: %v", composeEntry.Id, err)
+	}
+	return &cloudStat, nil
+}
+
+func finished(status composer.ImageStatusValue) bool {
+	return status == composer.ImageStatusValueSuccess || status == composer.ImageStatusValueFailure
+}
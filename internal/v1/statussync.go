@@ -0,0 +1,68 @@
+package v1
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultStatusSyncInterval = time.Minute
+
+// RunStatusSync refreshes the status of the unfinished composes of all orgs
+// until ctx is done, so finished composes are recorded whether anyone polls
+// them or not. Their events, notifications, webhooks, awx jobs and clones
+// follow from that, and the build durations are measured up to it. Every
+// replica runs it, each compose is claimed so only one of them asks composer
+// about it per interval.
+func (s *Server) RunStatusSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			orgs, err := s.db.GetOrgsWithUnfinishedComposesSince(unfinishedComposeWindow)
+			if err != nil {
+				logrus.Errorf("Error querying orgs with unfinished composes: %v", err)
+				continue
+			}
+			for _, orgId := range orgs {
+				s.syncComposeStatuses(orgId, interval)
+			}
+		}
+	}
+}
+
+// syncComposeStatuses refreshes the status of the unfinished composes of an
+// org no other replica refreshed within the interval, recording, notifying
+// and publishing the ones which finished.
+func (s *Server) syncComposeStatuses(orgId string, interval time.Duration) {
+	unfinished, err := s.db.GetUnfinishedComposesSince(orgId, unfinishedComposeWindow)
+	if err != nil {
+		logrus.Errorf("Error querying unfinished composes of org %s: %v", orgId, err)
+		return
+	}
+	for _, c := range unfinished {
+		// the tickers of the replicas drift, claiming for less than the
+		// interval keeps a compose from being skipped by all of them
+		claimed, err := s.db.ClaimComposeStatusSync(c.Id, interval/2)
+		if err != nil {
+			logrus.Errorf("Error claiming status sync of compose %v: %v", c.Id, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		imageStatus, err := s.composeStatus(&c)
+		if err != nil {
+			logrus.Warnf("Unable to refresh status of compose %v: %v", c.Id, err)
+			continue
+		}
+		err = s.recordComposeStatus(c, imageStatus)
+		if err != nil {
+			logrus.Errorf("Error recording status of compose %v: %v", c.Id, err)
+		}
+	}
+}
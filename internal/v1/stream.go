@@ -0,0 +1,272 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+const (
+	// how often the replicas look for new events of the orgs they stream
+	defaultStreamInterval = 2 * time.Second
+	streamBatchSize       = 100
+	// events a client can fall behind by before it's disconnected
+	streamBufferSize   = 64
+	streamPingInterval = 30 * time.Second
+	streamWriteTimeout = 10 * time.Second
+	// clients have nothing to send but control frames
+	streamReadLimit = 64 * 1024
+	// streamed events are only of interest while they happen
+	orgEventRetention = 24 * time.Hour
+)
+
+// streamHub fans the events of orgs out to the websockets of the orgs
+// connected to this replica.
+type streamHub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan OrgEvent]struct{}
+}
+
+func newStreamHub() *streamHub {
+	return &streamHub{subscribers: map[string]map[chan OrgEvent]struct{}{}}
+}
+
+func (h *streamHub) subscribe(orgId string) chan OrgEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan OrgEvent, streamBufferSize)
+	if h.subscribers[orgId] == nil {
+		h.subscribers[orgId] = map[chan OrgEvent]struct{}{}
+	}
+	h.subscribers[orgId][ch] = struct{}{}
+	return ch
+}
+
+func (h *streamHub) unsubscribe(orgId string, ch chan OrgEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[orgId][ch]; !ok {
+		return
+	}
+	delete(h.subscribers[orgId], ch)
+	if len(h.subscribers[orgId]) == 0 {
+		delete(h.subscribers, orgId)
+	}
+	close(ch)
+}
+
+func (h *streamHub) watched() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers) > 0
+}
+
+// publish sends an event to the subscribers of its org. Subscribers which
+// fell too far behind are dropped, their channel is closed.
+func (h *streamHub) publish(orgId string, event OrgEvent) {
+	h.mu.Lock()
+	var slow []chan OrgEvent
+	for ch := range h.subscribers[orgId] {
+		select {
+		case ch <- event:
+		default:
+			slow = append(slow, ch)
+		}
+	}
+	h.mu.Unlock()
+	for _, ch := range slow {
+		h.unsubscribe(orgId, ch)
+	}
+}
+
+// storeOrgEvent stores an event of a compose, or of one of its clones, to be
+// streamed to the clients of its org by every replica.
+func (s *Server) storeOrgEvent(composeId, resourceId uuid.UUID, event OrgEvent, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(raw, &event.Data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.db.InsertOrgEvent(composeId, string(event.Event), resourceId, payload)
+}
+
+// storeCloneEvent stores an event of a clone for the stream of its org.
+// Errors are only logged, the clone changed regardless.
+func (s *Server) storeCloneEvent(composeId, cloneId uuid.UUID, event OrgEventEvent, status string) {
+	err := s.storeOrgEvent(composeId, cloneId, OrgEvent{
+		Event:     event,
+		ComposeId: composeId,
+		CloneId:   &cloneId,
+		Time:      time.Now().UTC().Format(time.RFC3339),
+	}, map[string]string{"status": status})
+	if err != nil {
+		logrus.Errorf("Error storing %s event of %v: %v", event, cloneId, err)
+	}
+}
+
+// RunStream sends the events stored by all replicas to the clients connected
+// to this one, until ctx is done, and prunes old events.
+func (s *Server) RunStream(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastPruned := time.Time{}
+	lastId := int64(-1)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			lastId = s.streamOrgEvents(lastId)
+			if time.Since(lastPruned) > time.Hour {
+				lastPruned = time.Now()
+				_, err := s.db.DeleteOrgEventsBefore(orgEventRetention)
+				if err != nil {
+					logrus.Errorf("Error pruning the org events: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// streamOrgEvents publishes the events after lastId and returns the id of the
+// last one. While nobody is connected, only the id of the last event is
+// kept track of, so clients get the events from when they connected.
+func (s *Server) streamOrgEvents(lastId int64) int64 {
+	if lastId < 0 || !s.stream.watched() {
+		id, err := s.db.GetLastOrgEventId()
+		if err != nil {
+			logrus.Errorf("Error querying the last org event: %v", err)
+			return lastId
+		}
+		return id
+	}
+	for {
+		entries, err := s.db.GetOrgEventsAfter(lastId, streamBatchSize)
+		if err != nil {
+			logrus.Errorf("Error querying org events: %v", err)
+			return lastId
+		}
+		for _, e := range entries {
+			lastId = e.Id
+			s.publishOrgEvent(e)
+		}
+		if len(entries) < streamBatchSize {
+			return lastId
+		}
+	}
+}
+
+func (s *Server) publishOrgEvent(e db.OrgEventEntry) {
+	var event OrgEvent
+	err := json.Unmarshal(e.Payload, &event)
+	if err != nil {
+		logrus.Errorf("Error decoding org event %d: %v", e.Id, err)
+		return
+	}
+	event.Id = e.Id
+	s.stream.publish(e.OrgId, event)
+}
+
+// streamOriginAllowed returns if the Origin of a request is one of origins,
+// e.g. "https://console.redhat.com". Browsers send the cookies of the server
+// with the handshakes of any page, so pages which aren't allowed mustn't be
+// able to read the stream with them. Requests without an Origin aren't sent
+// by browsers and are allowed.
+func streamOriginAllowed(r *http.Request, origins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, o := range origins {
+		if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handlers) StreamEvents(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+	orgId := idHeader.Identity.OrgID
+
+	// rejected before the upgrade, so the errors are the ones of the api
+	if !websocket.IsWebSocketUpgrade(ctx.Request()) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Expected a websocket handshake")
+	}
+	if !streamOriginAllowed(ctx.Request(), h.server.streamOrigins) {
+		return echo.NewHTTPError(http.StatusForbidden, "Websockets aren't allowed from this origin")
+	}
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	conn, err := upgrader.Upgrade(ctx.Response(), ctx.Request(), nil)
+	if err != nil {
+		// the upgrader responded already
+		ctx.Logger().Errorf("Error upgrading to a websocket: %v", err)
+		return nil
+	}
+	defer conn.Close()
+	conn.SetReadLimit(streamReadLimit)
+
+	events := h.server.stream.subscribe(orgId)
+	defer h.server.stream.unsubscribe(orgId, events)
+
+	// clients only send control frames, which are answered while reading
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+		for {
+			_, _, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-disconnected:
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow to keep up with the events"),
+					time.Now().Add(time.Second))
+				return nil
+			}
+			raw, err := json.Marshal(event)
+			if err != nil {
+				return nil
+			}
+			if conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout)) != nil || conn.WriteMessage(websocket.TextMessage, raw) != nil {
+				return nil
+			}
+		case <-ping.C:
+			if conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)) != nil {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/pkg/tutils"
+)
+
+func TestStreamHub(t *testing.T) {
+	hub := newStreamHub()
+	require.False(t, hub.watched())
+	a1 := hub.subscribe("000000")
+	a2 := hub.subscribe("000000")
+	b := hub.subscribe("000001")
+	require.True(t, hub.watched())
+
+	hub.publish("000000", OrgEvent{Id: 1})
+	require.Equal(t, int64(1), (<-a1).Id)
+	require.Equal(t, int64(1), (<-a2).Id)
+	require.Empty(t, b)
+
+	// subscribers which fall behind are dropped
+	for i := 0; i <= streamBufferSize; i++ {
+		hub.publish("000001", OrgEvent{Id: int64(i)})
+	}
+	for range b {
+	}
+	hub.unsubscribe("000001", b)
+
+	hub.unsubscribe("000000", a1)
+	hub.unsubscribe("000000", a2)
+	require.False(t, hub.watched())
+}
+
+func TestStreamOriginAllowed(t *testing.T) {
+	origins := []string{"https://console.redhat.com/"}
+	for origin, allowed := range map[string]bool{
+		"":                           true,
+		"https://console.redhat.com": true,
+		"https://CONSOLE.redhat.com": true,
+		"https://example.com":        false,
+		"http://console.redhat.com":  false,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		require.Equal(t, allowed, streamOriginAllowed(r, origins), origin)
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	s := &Server{
+		auth:          NewIdentityHeaderAuthenticator(ServiceAccountConfig{}),
+		stream:        newStreamHub(),
+		streamOrigins: []string{"https://console.redhat.com"},
+	}
+	h := Handlers{server: s}
+	e := echo.New()
+	e.HTTPErrorHandler = s.HTTPErrorHandler
+	e.GET("/api/image-builder/v1/ws", h.StreamEvents, s.authenticate)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	// plain requests aren't upgraded
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/image-builder/v1/ws", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Rh-Identity", tutils.AuthString0)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// nor are the handshakes of other pages
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Origin", "https://example.com")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	header := http.Header{}
+	header.Set("Origin", "https://console.redhat.com")
+	header.Set("X-Rh-Identity", tutils.AuthString0)
+	c, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/api/image-builder/v1/ws", header)
+	require.NoError(t, err)
+	defer c.Close()
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	require.Eventually(t, s.stream.watched, time.Second, 10*time.Millisecond)
+
+	// only the events of the org of the caller are sent
+	composeId := uuid.New()
+	s.stream.publish("000001", OrgEvent{Id: 1, Event: OrgEventEventComposeCreated, ComposeId: uuid.New()})
+	s.stream.publish("000000", OrgEvent{
+		Id:        2,
+		Event:     OrgEventEventComposeFinished,
+		ComposeId: composeId,
+		Time:      "2026-10-14T00:00:00Z",
+		Data:      map[string]interface{}{"status": "success"},
+	})
+	messageType, message, err := c.ReadMessage()
+	require.NoError(t, err)
+	require.Equal(t, websocket.TextMessage, messageType)
+	var event OrgEvent
+	require.NoError(t, json.Unmarshal(message, &event))
+	require.Equal(t, int64(2), event.Id)
+	require.Equal(t, OrgEventEventComposeFinished, event.Event)
+	require.Equal(t, composeId, event.ComposeId)
+	require.Equal(t, "success", event.Data["status"])
+
+	// the subscription ends with the connection
+	c.Close()
+	require.Eventually(t, func() bool { return !s.stream.watched() }, time.Second, 10*time.Millisecond)
+}
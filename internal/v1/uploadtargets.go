@@ -0,0 +1,163 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Error codes of uploads the upload target policy of an org doesn't allow.
+const (
+	errCodeUploadTargetNotAllowed = "UPLOAD_TARGET_NOT_ALLOWED"
+	errCodeUploadRegionNotAllowed = "UPLOAD_REGION_NOT_ALLOWED"
+)
+
+// parseUploadTargetPolicy validates an upload target policy, every target may
+// only be listed once.
+func parseUploadTargetPolicy(policy UploadTargetPolicy) error {
+	seen := map[UploadTypes]bool{}
+	for _, rule := range policy.Targets {
+		if seen[rule.Type] {
+			return fmt.Errorf("upload target %s is listed more than once", rule.Type)
+		}
+		seen[rule.Type] = true
+		if rule.Regions == nil {
+			continue
+		}
+		for _, r := range *rule.Regions {
+			if r == "" {
+				return fmt.Errorf("regions of upload target %s can not be empty", rule.Type)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) getUploadTargetPolicy(orgId string) ([]UploadTargetRule, error) {
+	raw, err := s.db.GetUploadTargetPolicy(orgId)
+	if err != nil || raw == nil {
+		return nil, err
+	}
+	var targets []UploadTargetRule
+	err = json.Unmarshal(raw, &targets)
+	if err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// uploadTargetRegions returns the regions an upload request puts images into,
+// as far as they are known before building the image.
+func (h *Handlers) uploadTargetRegions(ur UploadRequest) []string {
+	var regions []string
+	switch ur.Type {
+	case UploadTypesAws:
+		uo, err := ur.Options.AsAWSUploadRequestOptions()
+		if err != nil {
+			return nil
+		}
+		region, err := h.awsRegion(uo.Partition)
+		if err == nil {
+			regions = append(regions, region)
+		}
+		if uo.Regions != nil {
+			regions = append(regions, *uo.Regions...)
+		}
+	case UploadTypesAwsS3:
+		regions = append(regions, h.server.aws.Region)
+	case UploadTypesGcp:
+		regions = append(regions, h.server.gcp.Region)
+	}
+	return regions
+}
+
+// checkUploadTarget rejects uploads to targets, or regions of them, the upload
+// target policy of the org doesn't list.
+func (h *Handlers) checkUploadTarget(ctx echo.Context, orgId string, uploadType UploadTypes, regions []string) error {
+	targets, err := h.server.getUploadTargetPolicy(orgId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the upload target policy").SetInternal(err)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	var rule *UploadTargetRule
+	for i := range targets {
+		if targets[i].Type == uploadType {
+			rule = &targets[i]
+			break
+		}
+	}
+	if rule == nil {
+		return auditRejection(ctx, reasonUploadTargetDenied, newCodedHTTPError(http.StatusForbidden, errCodeUploadTargetNotAllowed,
+			fmt.Sprintf("Uploading images to %s is not allowed for this organization", uploadType)))
+	}
+	if rule.Regions == nil || len(*rule.Regions) == 0 {
+		return nil
+	}
+
+	allowed := map[string]bool{}
+	for _, r := range *rule.Regions {
+		allowed[r] = true
+	}
+	for _, r := range regions {
+		if r != "" && !allowed[r] {
+			return auditRejection(ctx, reasonUploadTargetDenied, newCodedHTTPError(http.StatusForbidden, errCodeUploadRegionNotAllowed,
+				fmt.Sprintf("Uploading images to %s region %s is not allowed for this organization", uploadType, r)))
+		}
+	}
+	return nil
+}
+
+func (h *Handlers) GetUploadTargetPolicy(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	targets, err := h.server.getUploadTargetPolicy(idHeader.Identity.OrgID)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying upload target policy: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the upload target policy")
+	}
+	if targets == nil {
+		targets = []UploadTargetRule{}
+	}
+	return ctx.JSON(http.StatusOK, UploadTargetPolicy{
+		Targets: targets,
+	})
+}
+
+func (h *Handlers) UpdateUploadTargetPolicy(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	var req UploadTargetPolicy
+	err = ctx.Bind(&req)
+	if err != nil {
+		return err
+	}
+
+	err = parseUploadTargetPolicy(req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	targets, err := json.Marshal(req.Targets)
+	if err != nil {
+		return err
+	}
+	err = h.server.db.SetUploadTargetPolicy(idHeader.Identity.OrgID, targets)
+	if err != nil {
+		ctx.Logger().Errorf("Error updating upload target policy: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong updating the upload target policy")
+	}
+
+	ctx.Logger().Infof("Upload target policy of org %s set to %s", idHeader.Identity.OrgID, targets)
+	return h.GetUploadTargetPolicy(ctx)
+}
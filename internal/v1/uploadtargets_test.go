@@ -0,0 +1,69 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/composer"
+)
+
+func TestParseUploadTargetPolicy(t *testing.T) {
+	require.NoError(t, parseUploadTargetPolicy(UploadTargetPolicy{}))
+	require.NoError(t, parseUploadTargetPolicy(UploadTargetPolicy{
+		Targets: []UploadTargetRule{
+			{Type: UploadTypesAws, Regions: &[]string{"eu-west-1", "eu-central-1"}},
+			{Type: UploadTypesAzure},
+		},
+	}))
+
+	err := parseUploadTargetPolicy(UploadTargetPolicy{
+		Targets: []UploadTargetRule{{Type: UploadTypesGcp}, {Type: UploadTypesGcp}},
+	})
+	require.EqualError(t, err, "upload target gcp is listed more than once")
+	err = parseUploadTargetPolicy(UploadTargetPolicy{
+		Targets: []UploadTargetRule{{Type: UploadTypesAws, Regions: &[]string{""}}},
+	})
+	require.EqualError(t, err, "regions of upload target aws can not be empty")
+}
+
+func TestCodedHTTPError(t *testing.T) {
+	run := func(err error) string {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set(requestIdHeader, "request-1")
+		ctx := echo.New().NewContext(req, rec)
+		(&Server{}).HTTPErrorHandler(err, ctx)
+		require.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+		return rec.Body.String()
+	}
+
+	require.JSONEq(t, `{"type": "about:blank", "title": "Forbidden", "status": 403, "detail": "not allowed", "code": "UPLOAD_TARGET_NOT_ALLOWED", "request_id": "request-1",
+		"errors": [{"title": "403", "detail": "not allowed", "code": "UPLOAD_TARGET_NOT_ALLOWED"}]}`,
+		run(newCodedHTTPError(http.StatusForbidden, errCodeUploadTargetNotAllowed, "not allowed")))
+	require.JSONEq(t, `{"type": "about:blank", "title": "Forbidden", "status": 403, "detail": "not allowed", "code": "FORBIDDEN", "request_id": "request-1",
+		"errors": [{"title": "403", "detail": "not allowed"}]}`,
+		run(echo.NewHTTPError(http.StatusForbidden, "not allowed")))
+}
+
+func TestCircuitOpenError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/image-builder/v1/compose", nil)
+	req.Header.Set(requestIdHeader, "request-1")
+	ctx := echo.New().NewContext(req, rec)
+	(&Server{}).HTTPErrorHandler(fmt.Errorf("composing: %w", &composer.CircuitOpenError{
+		Endpoint:   "compose",
+		RetryAfter: 1500 * time.Millisecond,
+	}), ctx)
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("Retry-After"))
+	require.JSONEq(t, `{"type": "about:blank", "title": "Service Unavailable", "status": 503, "detail": "The build system is unavailable, please retry later",
+		"code": "COMPOSER_UNAVAILABLE", "request_id": "request-1",
+		"errors": [{"title": "503", "detail": "The build system is unavailable, please retry later", "code": "COMPOSER_UNAVAILABLE"}]}`,
+		rec.Body.String())
+}
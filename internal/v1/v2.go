@@ -0,0 +1,334 @@
+package v1
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+	v2 "github.com/osbuild/image-builder/internal/v2"
+)
+
+// v2Handlers serve the second version of the api, with the server, the
+// middlewares and the handlers of the first one.
+type v2Handlers struct {
+	server   *Server
+	handlers *Handlers
+	spec     *openapi3.T
+}
+
+const errCodeIdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED"
+
+// apiRouter finds the routes of requests in the specs of every version of
+// the api, so the middlewares work the same for all of them.
+type apiRouter []routers.Router
+
+func (r apiRouter) FindRoute(req *http.Request) (*routers.Route, map[string]string, error) {
+	err := routers.ErrPathNotFound
+	for _, router := range r {
+		var route *routers.Route
+		var params map[string]string
+		route, params, err = router.FindRoute(req)
+		// the routers return copies of ErrPathNotFound
+		var routeErr *routers.RouteError
+		if errors.As(err, &routeErr) && routeErr.Reason == routers.ErrPathNotFound.Error() {
+			continue
+		}
+		return route, params, err
+	}
+	return nil, nil, err
+}
+
+func isV2Request(c echo.Context) bool {
+	return strings.HasPrefix(c.Request().URL.Path, RoutePrefix()+"/v2")
+}
+
+// composeCursor is the position in the composes of an org a page continues
+// after, opaque to clients.
+type composeCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	Id        uuid.UUID `json:"id"`
+}
+
+func encodeComposeCursor(c db.ComposeEntry) string {
+	// nothing in the cursor fails to encode
+	raw, _ := json.Marshal(composeCursor{c.CreatedAt, c.Id})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeComposeCursor(cursor string) (*db.ComposeCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+	}
+	var c composeCursor
+	err = json.Unmarshal(raw, &c)
+	if err != nil || c.Id == uuid.Nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+	}
+	return &db.ComposeCursor{CreatedAt: c.CreatedAt.UTC(), Id: c.Id}, nil
+}
+
+// v2Compose returns the resource of a compose, with the status composer last
+// reported for it.
+func (s *Server) v2Compose(c db.ComposeEntry) (v2.Compose, error) {
+	var request map[string]interface{}
+	err := json.Unmarshal(c.Request, &request)
+	if err != nil {
+		return v2.Compose{}, err
+	}
+	compose := v2.Compose{
+		Id:        c.Id,
+		ImageName: c.ImageName,
+		CreatedAt: c.CreatedAt.UTC(),
+		Request:   request,
+	}
+
+	cached, err := s.db.GetCachedComposeStatus(c.Id, 0)
+	if errors.Is(err, db.ComposeStatusNotFoundError) {
+		return compose, nil
+	} else if err != nil {
+		return v2.Compose{}, err
+	}
+	var status composer.ComposeStatus
+	err = json.Unmarshal(cached.Status, &status)
+	if err != nil {
+		return v2.Compose{}, err
+	}
+	compose.Status = common.ToPtr(string(status.ImageStatus.Status))
+	return compose, nil
+}
+
+func (h *v2Handlers) GetOpenapiJson(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, h.spec)
+}
+
+func (h *v2Handlers) ListComposes(ctx echo.Context, params v2.ListComposesParams) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	limit := 100
+	if params.Limit != nil && *params.Limit > 0 {
+		limit = *params.Limit
+	}
+	var after *db.ComposeCursor
+	if params.Cursor != nil {
+		after, err = decodeComposeCursor(*params.Cursor)
+		if err != nil {
+			return err
+		}
+	}
+	var ignoreImageTypes []string
+	if params.IgnoreImageTypes != nil {
+		ignoreImageTypes = *params.IgnoreImageTypes
+	}
+
+	// composes in the last 14 days, one more than asked for to know if
+	// there's a next page
+	entries, err := h.server.db.GetComposesAfter(idHeader.Identity.OrgID, (time.Hour * 24 * 14), limit+1, after, ignoreImageTypes)
+	if err != nil {
+		return err
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		next := url.Values{}
+		next.Set("limit", fmt.Sprint(limit))
+		next.Set("cursor", encodeComposeCursor(entries[limit-1]))
+		for _, it := range ignoreImageTypes {
+			next.Add("ignoreImageTypes", it)
+		}
+		ctx.Response().Header().Set("Link", fmt.Sprintf("<%s/v%s/composes?%s>; rel=\"next\"",
+			RoutePrefix(), strings.Split(h.spec.Info.Version, ".")[0], next.Encode()))
+	}
+
+	composes := []v2.Compose{}
+	for _, e := range entries {
+		c, err := h.server.v2Compose(e)
+		if err != nil {
+			return err
+		}
+		composes = append(composes, c)
+	}
+	return ctx.JSON(http.StatusOK, composes)
+}
+
+// composeRequest checks the body of a compose request against the schema of
+// the compose request of v1, which the requests of v2 aren't validated
+// against by the middleware. It returns the request with the hash of its
+// content, the order of its keys and its whitespace don't change the hash.
+func (h *v2Handlers) composeRequest(body map[string]interface{}) (ComposeRequest, string, error) {
+	var composeRequest ComposeRequest
+	if h.server.requestValidation != ValidationOff {
+		err := h.server.spec.Components.Schemas["ComposeRequest"].Value.VisitJSON(body)
+		if err != nil {
+			if h.server.requestValidation != ValidationReport {
+				return composeRequest, "", echo.NewHTTPError(http.StatusBadRequest, &openapi3filter.RequestError{
+					Reason: "doesn't match the compose request of v1",
+					Err:    err,
+				})
+			}
+			reportSchemaViolation("request", "createCompose", err)
+		}
+	}
+
+	// maps are marshalled with sorted keys
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return composeRequest, "", err
+	}
+	err = json.Unmarshal(raw, &composeRequest)
+	if err != nil {
+		return composeRequest, "", echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("cannot parse request body: %v", err))
+	}
+	hash := sha256.Sum256(raw)
+	return composeRequest, hex.EncodeToString(hash[:]), nil
+}
+
+// idempotentCompose returns the compose an earlier request with the same
+// idempotency key created, nil if there's none.
+func (h *v2Handlers) idempotentCompose(orgId string, key db.IdempotencyKeyEntry) (*v2.Compose, error) {
+	existing, err := h.server.db.GetIdempotencyKey(orgId, key.Key)
+	if errors.Is(err, db.IdempotencyKeyNotFoundError) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if existing.RequestHash != key.RequestHash {
+		return nil, newCodedHTTPError(http.StatusUnprocessableEntity, errCodeIdempotencyKeyReused,
+			"The idempotency key was used for a different request")
+	}
+	entry, err := h.server.db.GetCompose(existing.ComposeId, orgId)
+	if errors.Is(err, db.ComposeNotFoundError) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "The compose of the idempotency key was deleted")
+	} else if err != nil {
+		return nil, err
+	}
+	compose, err := h.server.v2Compose(*entry)
+	if err != nil {
+		return nil, err
+	}
+	return &compose, nil
+}
+
+func (h *v2Handlers) CreateCompose(ctx echo.Context, params v2.CreateComposeParams) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+	orgId := idHeader.Identity.OrgID
+
+	var body map[string]interface{}
+	err = ctx.Bind(&body)
+	if err != nil {
+		return err
+	}
+	composeRequest, requestHash, err := h.composeRequest(body)
+	if err != nil {
+		return err
+	}
+
+	// a retried request neither counts towards the quota nor is composed
+	// again
+	var key *db.IdempotencyKeyEntry
+	if params.IdempotencyKey != nil {
+		key = &db.IdempotencyKeyEntry{Key: *params.IdempotencyKey, RequestHash: requestHash}
+		compose, err := h.idempotentCompose(orgId, *key)
+		if err != nil {
+			return err
+		}
+		if compose != nil {
+			setAuditResource(ctx, compose.Id)
+			return ctx.JSON(http.StatusOK, compose)
+		}
+	}
+
+	queue, release, err := h.server.checkComposeQuota(ctx, orgId, 1)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	pc, err := h.handlers.prepareCompose(ctx, idHeader, composeRequest)
+	if err != nil {
+		return err
+	}
+	pc.idempotencyKey = key
+	composeId, err := h.handlers.submitCompose(ctx, idHeader, pc, queue)
+	if errors.Is(err, db.IdempotencyKeyExistsError) {
+		// a concurrent request with the same key was stored first
+		compose, err := h.idempotentCompose(orgId, *key)
+		if err != nil {
+			return err
+		}
+		if compose == nil {
+			return fmt.Errorf("the compose of idempotency key %q wasn't found", key.Key)
+		}
+		setAuditResource(ctx, compose.Id)
+		return ctx.JSON(http.StatusOK, compose)
+	} else if err != nil {
+		return err
+	}
+	setAuditResource(ctx, composeId)
+
+	entry, err := h.server.db.GetCompose(composeId, orgId)
+	if err != nil {
+		return err
+	}
+	compose, err := h.server.v2Compose(*entry)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusCreated, compose)
+}
+
+func (h *v2Handlers) GetCompose(ctx echo.Context, composeId uuid.UUID) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	entry, err := h.server.db.GetCompose(composeId, idHeader.Identity.OrgID)
+	if errors.Is(err, db.ComposeNotFoundError) {
+		return echo.NewHTTPError(http.StatusNotFound, "Compose not found")
+	} else if err != nil {
+		return err
+	}
+	compose, err := h.server.v2Compose(*entry)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, compose)
+}
+
+func (h *v2Handlers) DeleteCompose(ctx echo.Context, composeId uuid.UUID) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	// deleting a deleted compose of the org succeeds again
+	err = h.server.db.DeleteCompose(composeId, idHeader.Identity.OrgID)
+	if errors.Is(err, db.ComposeNotFoundError) {
+		return echo.NewHTTPError(http.StatusNotFound, "Compose not found")
+	} else if err != nil {
+		return err
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,57 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	legacyrouter "github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/db"
+	v2 "github.com/osbuild/image-builder/internal/v2"
+)
+
+func TestAPIRouter(t *testing.T) {
+	spec, err := GetSwagger()
+	require.NoError(t, err)
+	spec.AddServer(&openapi3.Server{URL: fmt.Sprintf("%s/v%s", RoutePrefix(), spec.Info.Version)})
+	router, err := legacyrouter.NewRouter(spec)
+	require.NoError(t, err)
+	v2Spec, err := v2.GetSwagger()
+	require.NoError(t, err)
+	v2Router, err := legacyrouter.NewRouter(v2Spec)
+	require.NoError(t, err)
+	r := apiRouter{router, v2Router}
+
+	id := uuid.New()
+	route, _, err := r.FindRoute(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/image-builder/v1/composes/%v", id), nil))
+	require.NoError(t, err)
+	require.Equal(t, "getcomposestatus", operationId(route))
+	route, params, err := r.FindRoute(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/image-builder/v2/composes/%v", id), nil))
+	require.NoError(t, err)
+	require.Equal(t, "getcompose", operationId(route))
+	require.Equal(t, id.String(), params["composeId"])
+
+	_, _, err = r.FindRoute(httptest.NewRequest(http.MethodGet, "/api/image-builder/v3/composes", nil))
+	require.Error(t, err)
+}
+
+func TestComposeCursor(t *testing.T) {
+	c := db.ComposeEntry{
+		Id:        uuid.New(),
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 678000, time.UTC),
+	}
+	cursor, err := decodeComposeCursor(encodeComposeCursor(c))
+	require.NoError(t, err)
+	require.Equal(t, db.ComposeCursor{CreatedAt: c.CreatedAt, Id: c.Id}, *cursor)
+
+	_, err = decodeComposeCursor("not a cursor")
+	require.Error(t, err)
+	_, err = decodeComposeCursor("e30")
+	require.Error(t, err)
+}
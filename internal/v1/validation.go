@@ -0,0 +1,195 @@
+package v1
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/prometheus"
+	"github.com/osbuild/image-builder/internal/sbom"
+)
+
+// ValidationMode is what's done with requests or responses which don't match
+// the spec.
+type ValidationMode string
+
+const (
+	// ValidationOff doesn't validate them.
+	ValidationOff ValidationMode = "off"
+	// ValidationReport logs and counts them, and serves them anyway.
+	ValidationReport ValidationMode = "report"
+	// ValidationEnforce rejects them, requests with a 400 and responses
+	// with a 500.
+	ValidationEnforce ValidationMode = "enforce"
+)
+
+// ParseValidationMode parses a validation mode, empty being the given default.
+func ParseValidationMode(mode string, def ValidationMode) (ValidationMode, error) {
+	switch ValidationMode(mode) {
+	case "":
+		return def, nil
+	case ValidationOff, ValidationReport, ValidationEnforce:
+		return ValidationMode(mode), nil
+	}
+	return "", fmt.Errorf("invalid validation mode %q, expected off, report or enforce", mode)
+}
+
+func init() {
+	// SBOMs are json with a content type of their own
+	for _, mediaType := range []string{sbom.MediaType(sbom.FormatSPDX), sbom.MediaType(sbom.FormatCycloneDX)} {
+		openapi3filter.RegisterBodyDecoder(mediaType, openapi3filter.RegisteredBodyDecoder("application/json"))
+	}
+}
+
+// Larger responses, like compose exports, are served without being validated.
+const maxValidatedResponseSize = 1024 * 1024
+
+func reportSchemaViolation(direction, operation string, err error) {
+	prometheus.SchemaViolations.WithLabelValues(direction, operation).Inc()
+	logrus.Warnf("The %s of %s doesn't match the spec: %v", direction, operation, err)
+}
+
+// responseRecorder keeps a copy of a response to validate it once the handler
+// is done. When enforcing, the response is held back until then, unless it
+// outgrows what's validated.
+type responseRecorder struct {
+	http.ResponseWriter
+	hold      bool
+	status    int
+	body      bytes.Buffer
+	oversized bool
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	r.status = code
+	if !r.hold {
+		r.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.oversized {
+		return r.ResponseWriter.Write(b)
+	}
+	if r.body.Len()+len(b) > maxValidatedResponseSize {
+		r.oversized = true
+		if r.hold {
+			err := r.release()
+			if err != nil {
+				return 0, err
+			}
+		}
+		r.body.Reset()
+		return r.ResponseWriter.Write(b)
+	}
+	r.body.Write(b)
+	if r.hold {
+		return len(b), nil
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) Flush() {
+	if r.hold {
+		return
+	}
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return r.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// release writes the response held back so far.
+func (r *responseRecorder) release() error {
+	r.hold = false
+	if r.status == 0 {
+		return nil
+	}
+	r.ResponseWriter.WriteHeader(r.status)
+	_, err := r.ResponseWriter.Write(r.body.Bytes())
+	return err
+}
+
+// validateResponses checks what the handlers respond against the spec, so
+// handlers drifting from it are noticed before clients trip over it. The
+// errors handlers return are served by the error handler and not validated.
+func (s *Server) validateResponses(nextHandler echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		if s.responseValidation != ValidationReport && s.responseValidation != ValidationEnforce {
+			return nextHandler(ctx)
+		}
+		request := ctx.Request()
+		route, params, err := s.router.FindRoute(request)
+		if err != nil {
+			return nextHandler(ctx)
+		}
+
+		resp := ctx.Response()
+		writer := resp.Writer
+		header := resp.Header().Clone()
+		recorder := &responseRecorder{
+			ResponseWriter: writer,
+			hold:           s.responseValidation == ValidationEnforce,
+		}
+		resp.Writer = recorder
+		err = nextHandler(ctx)
+		resp.Writer = writer
+		if err != nil || recorder.oversized || recorder.status == 0 {
+			if recorder.hold {
+				if releaseErr := recorder.release(); releaseErr != nil {
+					return releaseErr
+				}
+			}
+			return err
+		}
+
+		input := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: &openapi3filter.RequestValidationInput{
+				Request:    request,
+				PathParams: params,
+				Route:      route,
+			},
+			Status: recorder.status,
+			Header: resp.Header(),
+			Options: &openapi3filter.Options{
+				IncludeResponseStatus: true,
+			},
+		}
+		input.SetBodyBytes(recorder.body.Bytes())
+		err = openapi3filter.ValidateResponse(request.Context(), input)
+		if err == nil {
+			if recorder.hold {
+				return recorder.release()
+			}
+			return nil
+		}
+		reportSchemaViolation("response", operationId(route), err)
+		if !recorder.hold {
+			return nil
+		}
+
+		// nothing was written yet, so the error replaces the response
+		for k := range resp.Header() {
+			delete(resp.Header(), k)
+		}
+		for k, v := range header {
+			resp.Header()[k] = v
+		}
+		resp.Committed = false
+		resp.Status = 0
+		resp.Size = 0
+		return echo.NewHTTPError(http.StatusInternalServerError, "The response doesn't match the api specification")
+	}
+}
@@ -0,0 +1,111 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	legacyrouter "github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseValidationMode(t *testing.T) {
+	mode, err := ParseValidationMode("", ValidationEnforce)
+	require.NoError(t, err)
+	require.Equal(t, ValidationEnforce, mode)
+	mode, err = ParseValidationMode("report", ValidationEnforce)
+	require.NoError(t, err)
+	require.Equal(t, ValidationReport, mode)
+	_, err = ParseValidationMode("strict", ValidationOff)
+	require.Error(t, err)
+}
+
+func TestValidateResponses(t *testing.T) {
+	spec, err := GetSwagger()
+	require.NoError(t, err)
+	router, err := legacyrouter.NewRouter(spec)
+	require.NoError(t, err)
+
+	serve := func(mode ValidationMode, version interface{}) *httptest.ResponseRecorder {
+		s := &Server{router: router, responseValidation: mode}
+		e := echo.New()
+		e.HTTPErrorHandler = s.HTTPErrorHandler
+		e.GET("/api/image-builder/v1/version", func(ctx echo.Context) error {
+			ctx.Response().Header().Set("X-Handler", "version")
+			return ctx.JSON(http.StatusOK, version)
+		}, tagRequestId, s.validateResponses)
+		req := httptest.NewRequest(http.MethodGet, "/api/image-builder/v1/version", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	valid := map[string]string{"version": "1.0"}
+	invalid := map[string]int{"version": 1}
+	for _, mode := range []ValidationMode{ValidationOff, ValidationReport, ValidationEnforce} {
+		rec := serve(mode, valid)
+		require.Equal(t, http.StatusOK, rec.Code, mode)
+		require.JSONEq(t, `{"version": "1.0"}`, rec.Body.String(), mode)
+		require.Equal(t, "version", rec.Header().Get("X-Handler"), mode)
+	}
+
+	// responses drifting from the spec are only served when not enforcing
+	for _, mode := range []ValidationMode{ValidationOff, ValidationReport} {
+		rec := serve(mode, invalid)
+		require.Equal(t, http.StatusOK, rec.Code, mode)
+		require.JSONEq(t, `{"version": 1}`, rec.Body.String(), mode)
+	}
+	rec := serve(ValidationEnforce, invalid)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Equal(t, mimeProblemJSON, rec.Header().Get(echo.HeaderContentType))
+	require.Empty(t, rec.Header().Get("X-Handler"))
+	// the request id set before the handler is kept
+	require.NotEmpty(t, rec.Header().Get(requestIdHeader))
+	require.NotContains(t, rec.Body.String(), `"version"`)
+}
+
+func TestValidateResponsesOversized(t *testing.T) {
+	spec, err := GetSwagger()
+	require.NoError(t, err)
+	router, err := legacyrouter.NewRouter(spec)
+	require.NoError(t, err)
+	s := &Server{router: router, responseValidation: ValidationEnforce}
+
+	e := echo.New()
+	e.GET("/api/image-builder/v1/ready", func(ctx echo.Context) error {
+		return ctx.String(http.StatusOK, strings.Repeat("x", maxValidatedResponseSize+1))
+	}, s.validateResponses)
+
+	// responses too large to validate are served as they are
+	req := httptest.NewRequest(http.MethodGet, "/api/image-builder/v1/ready", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, maxValidatedResponseSize+1, rec.Body.Len())
+}
+
+func TestValidateRequestModes(t *testing.T) {
+	spec, err := GetSwagger()
+	require.NoError(t, err)
+	router, err := legacyrouter.NewRouter(spec)
+	require.NoError(t, err)
+
+	for mode, code := range map[ValidationMode]int{
+		ValidationOff:     http.StatusOK,
+		ValidationReport:  http.StatusOK,
+		ValidationEnforce: http.StatusBadRequest,
+	} {
+		s := &Server{router: router, requestValidation: mode}
+		e := echo.New()
+		e.HTTPErrorHandler = s.HTTPErrorHandler
+		e.GET("/api/image-builder/v1/composes", func(ctx echo.Context) error {
+			return ctx.NoContent(http.StatusOK)
+		}, s.ValidateRequest)
+		req := httptest.NewRequest(http.MethodGet, "/api/image-builder/v1/composes?limit=many", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, code, rec.Code, mode)
+	}
+}
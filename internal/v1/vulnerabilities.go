@@ -0,0 +1,211 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/vulnscan"
+)
+
+// how long fetching the packages of a compose and scanning them may take
+const vulnerabilityScanTimeout = 15 * time.Minute
+
+// VulnerabilityScanner finds the vulnerabilities of the packages of an image.
+type VulnerabilityScanner interface {
+	Name() string
+	Scan(ctx context.Context, m vulnscan.Manifest) ([]vulnscan.Finding, error)
+}
+
+// scanOS returns the distribution scanners match the packages of composes of
+// distribution against, e.g. redhat 9 for rhel-92. Only the major version of
+// rhel matters to them.
+func scanOS(distribution string) (vulnscan.OS, bool) {
+	parts := strings.Split(distribution, "-")
+	if len(parts) < 2 || parts[1] == "" {
+		return vulnscan.OS{}, false
+	}
+	switch parts[0] {
+	case "rhel":
+		return vulnscan.OS{Family: "redhat", Version: parts[1][:1]}, true
+	case "centos", "fedora":
+		return vulnscan.OS{Family: parts[0], Version: parts[1]}, true
+	}
+	return vulnscan.OS{}, false
+}
+
+func manifestPackages(packages []composer.PackageMetadata) []vulnscan.Package {
+	var scanned []vulnscan.Package
+	for _, p := range packages {
+		if p.Type != "rpm" {
+			continue
+		}
+		pkg := vulnscan.Package{
+			Name:    p.Name,
+			Version: p.Version,
+			Release: p.Release,
+			Arch:    p.Arch,
+		}
+		if p.Epoch != nil {
+			pkg.Epoch, _ = strconv.Atoi(*p.Epoch)
+		}
+		scanned = append(scanned, pkg)
+	}
+	return scanned
+}
+
+func vulnerabilitySummary(findings []vulnscan.Finding) VulnerabilitySummary {
+	summary := vulnscan.Summary(findings)
+	return VulnerabilitySummary{
+		Critical: summary[vulnscan.SeverityCritical],
+		High:     summary[vulnscan.SeverityHigh],
+		Medium:   summary[vulnscan.SeverityMedium],
+		Low:      summary[vulnscan.SeverityLow],
+		Unknown:  summary[vulnscan.SeverityUnknown],
+	}
+}
+
+func (h *Handlers) GetComposeVulnerabilities(ctx echo.Context, composeId uuid.UUID) error {
+	composeEntry, err := h.getComposeByIdAndOrgId(ctx, composeId)
+	if err != nil {
+		return err
+	}
+
+	scan, err := h.server.db.GetVulnerabilityScan(composeId, composeEntry.OrgId)
+	if errors.Is(err, db.VulnerabilityScanNotFoundError) {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Compose %v wasn't scanned for vulnerabilities", composeId))
+	} else if err != nil {
+		ctx.Logger().Errorf("Error querying the vulnerability scan of compose %v: %v", composeId, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the vulnerabilities of this compose")
+	}
+	var findings []vulnscan.Finding
+	err = json.Unmarshal(scan.Findings, &findings)
+	if err != nil {
+		return err
+	}
+
+	data := []Vulnerability{}
+	for i := range findings {
+		f := &findings[i]
+		v := Vulnerability{
+			Id:               f.Id,
+			Package:          f.Package,
+			InstalledVersion: f.InstalledVersion,
+			Severity:         VulnerabilitySeverity(f.Severity),
+		}
+		if f.FixedVersion != "" {
+			v.FixedVersion = &f.FixedVersion
+		}
+		if f.Title != "" {
+			v.Title = &f.Title
+		}
+		if f.URL != "" {
+			v.Url = &f.URL
+		}
+		data = append(data, v)
+	}
+	return ctx.JSON(http.StatusOK, VulnerabilitiesResponse{
+		Scanner:   scan.Scanner,
+		ScannedAt: scan.ScannedAt.UTC(),
+		Summary:   vulnerabilitySummary(findings),
+		Data:      data,
+	})
+}
+
+// scanCompose submits the packages of a successful compose to the scanner,
+// stores its findings and posts their summary to the webhooks of the
+// compose. Composes of distributions the scanner doesn't know aren't
+// scanned. The findings of a compose which was scanned already are kept
+// when this is retried.
+func (s *Server) scanCompose(event outboxEvent) error {
+	scan, err := s.db.GetVulnerabilityScan(event.ComposeId, event.OrgId)
+	if errors.Is(err, db.VulnerabilityScanNotFoundError) {
+		var scanned bool
+		scanned, err = s.scanPackages(event)
+		if err != nil || !scanned {
+			return err
+		}
+		scan, err = s.db.GetVulnerabilityScan(event.ComposeId, event.OrgId)
+	}
+	if err != nil {
+		return err
+	}
+
+	var findings []vulnscan.Finding
+	err = json.Unmarshal(scan.Findings, &findings)
+	if err != nil {
+		return err
+	}
+	summary := vulnerabilitySummary(findings)
+	return s.storeWebhookEvent(event.ComposeId, event.ComposeId, webhookEvent{
+		Event:           webhookEventVulnerabilityScanFinished,
+		ComposeId:       event.ComposeId,
+		Status:          event.Status,
+		FinishedAt:      scan.ScannedAt.UTC().Format(time.RFC3339),
+		Vulnerabilities: &summary,
+	})
+}
+
+// scanPackages scans the packages of a compose and stores the findings,
+// it returns false if the compose can't be scanned.
+func (s *Server) scanPackages(event outboxEvent) (bool, error) {
+	compose, err := s.db.GetCompose(event.ComposeId, event.OrgId)
+	if err != nil {
+		return false, err
+	}
+	var cr ComposeRequest
+	err = json.Unmarshal(compose.Request, &cr)
+	if err != nil {
+		return false, err
+	}
+	distro, ok := scanOS(string(cr.Distribution))
+	if !ok {
+		logrus.Infof("Not scanning compose %v, %s can't be scanned", event.ComposeId, cr.Distribution)
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), vulnerabilityScanTimeout)
+	defer cancel()
+	packages, err := s.composePackages(ctx, compose)
+	if err != nil {
+		return false, err
+	}
+	manifest := vulnscan.Manifest{
+		Name:     event.ComposeId.String(),
+		OS:       distro,
+		Packages: manifestPackages(packages),
+	}
+	if len(manifest.Packages) == 0 {
+		logrus.Infof("Not scanning compose %v, composer didn't report its packages", event.ComposeId)
+		return false, nil
+	}
+
+	findings, err := s.scanner.Scan(ctx, manifest)
+	if err != nil {
+		return false, err
+	}
+	raw, err := json.Marshal(findings)
+	if err != nil {
+		return false, err
+	}
+	err = s.db.InsertVulnerabilityScan(event.ComposeId, db.VulnerabilityScanEntry{
+		Scanner:  s.scanner.Name(),
+		Findings: raw,
+	})
+	if err != nil {
+		return false, err
+	}
+	logrus.Infof("Scanned compose %v with %s, %d vulnerabilities found", event.ComposeId, s.scanner.Name(), len(findings))
+	return true, nil
+}
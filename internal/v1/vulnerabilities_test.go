@@ -0,0 +1,27 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/vulnscan"
+)
+
+func TestScanOS(t *testing.T) {
+	for distribution, expected := range map[string]vulnscan.OS{
+		"rhel-8":         {Family: "redhat", Version: "8"},
+		"rhel-92":        {Family: "redhat", Version: "9"},
+		"rhel-9-nightly": {Family: "redhat", Version: "9"},
+		"centos-9":       {Family: "centos", Version: "9"},
+		"fedora-39":      {Family: "fedora", Version: "39"},
+	} {
+		os, ok := scanOS(distribution)
+		require.True(t, ok, distribution)
+		require.Equal(t, expected, os, distribution)
+	}
+	for _, distribution := range []string{"", "rhel", "rhel-", "sles-15"} {
+		_, ok := scanOS(distribution)
+		require.False(t, ok, distribution)
+	}
+}
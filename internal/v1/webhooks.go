@@ -0,0 +1,488 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/image-builder/internal/common"
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/prometheus"
+)
+
+const (
+	webhookEventComposeFinished           = "compose_finished"
+	webhookEventCloneFinished             = "clone_finished"
+	webhookEventVulnerabilityScanFinished = "vulnerability_scan_finished"
+)
+
+const (
+	// deliveries attempted per interval
+	webhookBatchSize = 100
+	// attempts after which a delivery fails, the last one is made about an
+	// hour after the event
+	webhookMaxAttempts = 8
+	webhookRetryBase   = 30 * time.Second
+	webhookRetryMax    = time.Hour
+)
+
+// webhookEvent is posted to the webhooks of a compose and its org once the
+// compose, or one of its clones, finished, and once its packages were
+// scanned for vulnerabilities.
+type webhookEvent struct {
+	Event     string     `json:"event"`
+	ComposeId uuid.UUID  `json:"compose_id"`
+	CloneId   *uuid.UUID `json:"clone_id,omitempty"`
+	// success or failure
+	Status     string  `json:"status"`
+	Reason     *string `json:"reason,omitempty"`
+	FinishedAt string  `json:"finished_at"`
+	// counts of the findings of a vulnerability scan
+	Vulnerabilities *VulnerabilitySummary `json:"vulnerabilities,omitempty"`
+}
+
+// Redirects aren't followed, the url of a webhook is where its events go.
+// Users pick the url, so only public addresses are connected to, the results
+// of deliveries would otherwise tell which internal hosts and ports answer.
+var webhookClient = common.NewPublicHTTPClient(common.HTTPTimeouts{
+	Connect: 10 * time.Second,
+	Request: 10 * time.Second,
+}, nil)
+
+// validateWebhookURL rejects urls which are known not to be public up front,
+// the addresses names resolve to are checked when they're delivered to.
+func validateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "The webhook url has to be an absolute https url")
+	}
+	err = common.ValidatePublicHost(u.Hostname())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("The webhook url has to be public: %v", err))
+	}
+	return nil
+}
+
+// validateWebhook checks a webhook before anything is submitted or stored,
+// its secret is only stored encrypted.
+func (s *Server) validateWebhook(req WebhookRequest) error {
+	if s.keystore == nil {
+		return echo.NewHTTPError(http.StatusNotImplemented, "Webhooks can't be registered, no key encryption key is configured")
+	}
+	return validateWebhookURL(req.Url)
+}
+
+// insertWebhook registers a webhook of an org, or of a single compose if
+// composeId is set.
+func (s *Server) insertWebhook(orgId string, composeId *uuid.UUID, req WebhookRequest) (*db.WebhookEntry, error) {
+	webhook, err := s.newWebhook(orgId, composeId, req)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.InsertWebhook(*webhook)
+	if err != nil {
+		logrus.Errorf("Error inserting webhook: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong registering the webhook")
+	}
+	return webhook, nil
+}
+
+// newWebhook is the webhook of a request with its secret encrypted, it isn't
+// stored yet.
+func (s *Server) newWebhook(orgId string, composeId *uuid.UUID, req WebhookRequest) (*db.WebhookEntry, error) {
+	err := s.validateWebhook(req)
+	if err != nil {
+		return nil, err
+	}
+	webhook := db.WebhookEntry{
+		Id:        uuid.New(),
+		OrgId:     orgId,
+		ComposeId: composeId,
+		URL:       req.Url,
+		CreatedAt: time.Now(),
+	}
+	webhook.Secret, err = s.sealSecret([]byte(req.Secret), webhookSecretAdditionalData(webhook.Id))
+	if err != nil {
+		logrus.Errorf("Error encrypting the secret of webhook %v: %v", webhook.Id, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong registering the webhook")
+	}
+	return &webhook, nil
+}
+
+func webhookResponse(w db.WebhookEntry) Webhook {
+	return Webhook{
+		Id:        w.Id,
+		Url:       w.URL,
+		ComposeId: w.ComposeId,
+		CreatedAt: w.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func (h *Handlers) GetWebhooks(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries, err := h.server.db.GetWebhooks(idHeader.Identity.OrgID)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying webhooks: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the webhooks")
+	}
+
+	data := []Webhook{}
+	for _, e := range entries {
+		data = append(data, webhookResponse(e))
+	}
+	return ctx.JSON(http.StatusOK, WebhooksResponse{
+		Data: data,
+	})
+}
+
+func (h *Handlers) CreateWebhook(ctx echo.Context) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	var req WebhookRequest
+	err = ctx.Bind(&req)
+	if err != nil {
+		return err
+	}
+
+	webhook, err := h.server.insertWebhook(idHeader.Identity.OrgID, nil, req)
+	if err != nil {
+		return err
+	}
+	setAuditResource(ctx, webhook.Id)
+	logAction(ctx, "create_webhook", logrus.Fields{"webhook_id": webhook.Id, "url": webhook.URL}, "Webhook registered")
+	return ctx.JSON(http.StatusCreated, webhookResponse(*webhook))
+}
+
+func (h *Handlers) DeleteWebhook(ctx echo.Context, id uuid.UUID) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = h.server.db.DeleteWebhook(id, idHeader.Identity.OrgID)
+	if err != nil {
+		if errors.Is(err, db.WebhookNotFoundError) {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+		ctx.Logger().Errorf("Error removing webhook %v: %v", id, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong removing the webhook")
+	}
+	logAction(ctx, "delete_webhook", logrus.Fields{"webhook_id": id}, "Webhook removed")
+	return ctx.NoContent(http.StatusOK)
+}
+
+func (h *Handlers) GetWebhookDeliveries(ctx echo.Context, id uuid.UUID, params GetWebhookDeliveriesParams) error {
+	idHeader, err := getIdentityHeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.server.db.GetWebhook(id, idHeader.Identity.OrgID)
+	if err != nil {
+		if errors.Is(err, db.WebhookNotFoundError) {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+		ctx.Logger().Errorf("Error querying webhook %v: %v", id, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the webhook")
+	}
+
+	limit := 100
+	if params.Limit != nil && *params.Limit > 0 {
+		limit = *params.Limit
+	}
+	offset := 0
+	if params.Offset != nil {
+		offset = *params.Offset
+	}
+
+	entries, count, err := h.server.db.GetWebhookDeliveries(id, idHeader.Identity.OrgID, limit, offset)
+	if err != nil {
+		ctx.Logger().Errorf("Error querying deliveries of webhook %v: %v", id, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Something went wrong querying the webhook deliveries")
+	}
+
+	data := []WebhookDelivery{}
+	for _, e := range entries {
+		d := WebhookDelivery{
+			Id:           e.Id,
+			Event:        WebhookDeliveryEvent(e.Event),
+			ResourceId:   e.ResourceId,
+			Status:       WebhookDeliveryStatus(e.Status),
+			Attempts:     e.Attempts,
+			ResponseCode: e.ResponseCode,
+			Error:        e.LastError,
+			CreatedAt:    e.CreatedAt.Format(time.RFC3339),
+		}
+		if e.DeliveredAt != nil {
+			d.DeliveredAt = common.ToPtr(e.DeliveredAt.Format(time.RFC3339))
+		}
+		if e.Status == db.WebhookDeliveryPending {
+			d.NextAttemptAt = common.ToPtr(e.NextAttemptAt.Format(time.RFC3339))
+		}
+		data = append(data, d)
+	}
+
+	lastOffset := count - 1
+	if lastOffset < 0 {
+		lastOffset = 0
+	}
+
+	spec, err := GetSwagger()
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, WebhookDeliveriesResponse{
+		Meta: struct {
+			Count int `json:"count"`
+		}{
+			count,
+		},
+		Links: struct {
+			First string `json:"first"`
+			Last  string `json:"last"`
+		}{
+			fmt.Sprintf("%v/v%v/webhooks/%v/deliveries?offset=%v&limit=%v",
+				RoutePrefix(), spec.Info.Version, id, 0, limit),
+			fmt.Sprintf("%v/v%v/webhooks/%v/deliveries?offset=%v&limit=%v",
+				RoutePrefix(), spec.Info.Version, id, lastOffset, limit),
+		},
+		Data: data,
+	})
+}
+
+// cloneFinished stores the event of a finished clone for the webhooks of its
+// compose and the stream of its org. Errors are only logged, the clone
+// finished regardless.
+func (s *Server) cloneFinished(clone db.CloneEntry, status string) {
+	s.storeCloneEvent(clone.ComposeId, clone.Id, OrgEventEventCloneFinished, status)
+	err := s.storeWebhookEvent(clone.ComposeId, clone.Id, webhookEvent{
+		Event:      webhookEventCloneFinished,
+		ComposeId:  clone.ComposeId,
+		CloneId:    &clone.Id,
+		Status:     status,
+		FinishedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		logrus.Errorf("Error storing %s event of %v: %v", webhookEventCloneFinished, clone.Id, err)
+	}
+}
+
+// storeWebhookEvent stores an event for the webhooks of a compose, they're
+// delivered in the background. Finished composes get here through the outbox.
+func (s *Server) storeWebhookEvent(composeId, resourceId uuid.UUID, event webhookEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.InsertWebhookDeliveries(composeId, event.Event, resourceId, payload)
+	return err
+}
+
+// RunWebhooks looks for finished composes and clones webhooks are interested
+// in, and delivers their events, until ctx is done.
+func (s *Server) RunWebhooks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.watchWebhookOrgs(ctx)
+			s.deliverWebhookEvents()
+		}
+	}
+}
+
+// watchWebhookOrgs refreshes the status of the unfinished clones of orgs with
+// webhooks, their status is otherwise only known once someone polls it. The
+// status sync covers their composes.
+func (s *Server) watchWebhookOrgs(ctx context.Context) {
+	orgs, err := s.db.GetOrgsWithWebhooks(unfinishedComposeWindow)
+	if err != nil {
+		logrus.Errorf("Error querying orgs with webhooks: %v", err)
+		return
+	}
+	for _, orgId := range orgs {
+		clones, err := s.db.GetUnnotifiedClonesSince(orgId, unfinishedComposeWindow)
+		if err != nil {
+			logrus.Errorf("Error querying the clones of org %s: %v", orgId, err)
+			continue
+		}
+		for _, c := range clones {
+			status, err := s.cloneStatus(ctx, c)
+			if err != nil {
+				logrus.Warnf("Unable to refresh status of clone %v: %v", c.Id, err)
+				continue
+			}
+			if finishedUpload(status) {
+				s.cloneFinished(c, string(status))
+			}
+		}
+	}
+}
+
+func (s *Server) cloneStatus(ctx context.Context, clone db.CloneEntry) (composer.UploadStatusValue, error) {
+	cc, err := s.composers.Client(clone.ComposerBackend)
+	if err != nil {
+		return "", err
+	}
+	resp, err := cc.CloneStatus(ctx, clone.Id)
+	if err != nil {
+		return "", err
+	}
+	defer closeBody(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("composer responded with %d", resp.StatusCode)
+	}
+	var cloudStat composer.CloneStatus
+	err = json.NewDecoder(resp.Body).Decode(&cloudStat)
+	if err != nil {
+		return "", err
+	}
+	return cloudStat.Status, nil
+}
+
+func finishedUpload(status composer.UploadStatusValue) bool {
+	return status == composer.Success || status == composer.Failure
+}
+
+// deliverWebhookEvents attempts the deliveries which are due.
+func (s *Server) deliverWebhookEvents() {
+	deliveries, err := s.db.ClaimWebhookDeliveries(webhookBatchSize)
+	if err != nil {
+		logrus.Errorf("Error claiming webhook deliveries: %v", err)
+		return
+	}
+	keys := map[string]*webhookKey{}
+	for _, d := range deliveries {
+		secret, err := s.openSecret(d.Secret, webhookSecretAdditionalData(d.WebhookId))
+		if err != nil {
+			// attempted again once the claim expires
+			logrus.Errorf("Error decrypting the secret of webhook %v: %v", d.WebhookId, err)
+			continue
+		}
+		key, ok := keys[d.OrgId]
+		if !ok {
+			key, err = s.webhookKey(d.OrgId)
+			if err != nil {
+				// attempted again once the claim expires
+				logrus.Errorf("Error looking up the webhook key of org %s: %v", d.OrgId, err)
+				continue
+			}
+			keys[d.OrgId] = key
+		}
+		status, code, attemptErr := deliverWebhookEvent(d, string(secret), key, time.Now())
+		if key != nil {
+			s.recordKeyUsage(&key.id, SignWebhookEvent, d.ResourceId)
+		}
+		var lastError *string
+		if attemptErr != nil {
+			lastError = common.ToPtr(attemptErr.Error())
+			logrus.Warnf("Unable to deliver %s event %v to webhook %v: %v", d.Event, d.Id, d.WebhookId, attemptErr)
+		}
+		err = s.db.SetWebhookDeliveryResult(d.Id, status, code, lastError, retryDelay(d.Attempts+1))
+		if err != nil {
+			logrus.Errorf("Error storing the result of webhook delivery %v: %v", d.Id, err)
+		}
+		prometheus.WebhookDeliveries.WithLabelValues(status).Inc()
+	}
+}
+
+// webhookKey is the managed key the events of an org are signed with, next
+// to the secret of each webhook.
+type webhookKey struct {
+	id     uuid.UUID
+	secret string
+}
+
+// webhookKey returns the webhook key of an org, nil if it has none.
+func (s *Server) webhookKey(orgId string) (*webhookKey, error) {
+	key, plaintext, err := s.signingKeyInUse(orgId, SigningKeyPurposeWebhook)
+	if err != nil || key == nil {
+		return nil, err
+	}
+	return &webhookKey{key.Id, string(plaintext)}, nil
+}
+
+// deliverWebhookEvent posts the event of a delivery to its webhook, and
+// returns the status of the delivery afterwards along with the status the
+// webhook responded with. Events are signed with the webhook key of the org
+// as well if it has one.
+func deliverWebhookEvent(d db.WebhookDeliveryEntry, secret string, key *webhookKey, now time.Time) (string, *int, error) {
+	failed := db.WebhookDeliveryPending
+	if d.Attempts+1 >= webhookMaxAttempts {
+		failed = db.WebhookDeliveryFailed
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return db.WebhookDeliveryFailed, nil, err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Image-Builder-Event", d.Event)
+	req.Header.Set("X-Image-Builder-Delivery", d.Id.String())
+	req.Header.Set("X-Image-Builder-Timestamp", timestamp)
+	req.Header.Set("X-Image-Builder-Signature", "sha256="+signWebhookEvent(secret, timestamp, d.Payload))
+	if key != nil {
+		req.Header.Set("X-Image-Builder-Key-Id", key.id.String())
+		req.Header.Set("X-Image-Builder-Key-Signature", "sha256="+signWebhookEvent(key.secret, timestamp, d.Payload))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return failed, nil, err
+	}
+	defer closeBody(resp.Body)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return failed, &resp.StatusCode, fmt.Errorf("webhook responded with %d", resp.StatusCode)
+	}
+	return db.WebhookDeliveryDelivered, &resp.StatusCode, nil
+}
+
+// signWebhookEvent returns the hex encoded HMAC-SHA256 of the timestamp and
+// the body of an event joined by a dot, receivers recompute it with their
+// secret and reject old timestamps to detect replays.
+func signWebhookEvent(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryDelay is how long to wait before the attempt following the given one,
+// doubling each time. Webhook deliveries and outbox entries share it.
+func retryDelay(attempt int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempt && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > webhookRetryMax {
+		delay = webhookRetryMax
+	}
+	return delay
+}
@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/db"
+)
+
+func TestValidateWebhookURL(t *testing.T) {
+	require.NoError(t, validateWebhookURL("https://ci.example.com/hooks"))
+	require.Error(t, validateWebhookURL("http://ci.example.com/hooks"))
+	require.Error(t, validateWebhookURL("/hooks"))
+	require.Error(t, validateWebhookURL("https://"))
+	require.Error(t, validateWebhookURL("https://localhost:8080/hooks"))
+	require.Error(t, validateWebhookURL("https://10.0.0.1/hooks"))
+	require.Error(t, validateWebhookURL("https://[fd00::1]/hooks"))
+	require.Error(t, validateWebhookURL("https://169.254.169.254/latest/meta-data"))
+}
+
+func TestDeliverWebhookEventInternal(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("internal addresses must not be delivered to")
+	}))
+	defer srv.Close()
+
+	d := db.WebhookDeliveryEntry{
+		Id:      uuid.New(),
+		Event:   webhookEventComposeFinished,
+		Payload: []byte(`{}`),
+		URL:     srv.URL,
+	}
+	result, code, err := deliverWebhookEvent(d, "0123456789abcdef", nil, time.Now())
+	require.ErrorContains(t, err, "127.0.0.1 isn't a public address")
+	require.NotEqual(t, db.WebhookDeliveryDelivered, result)
+	require.Nil(t, code)
+}
+
+func TestRetryDelay(t *testing.T) {
+	require.Equal(t, 30*time.Second, retryDelay(1))
+	require.Equal(t, time.Minute, retryDelay(2))
+	require.Equal(t, 32*time.Minute, retryDelay(7))
+	require.Equal(t, time.Hour, retryDelay(20))
+}
+
+func TestDeliverWebhookEvent(t *testing.T) {
+	status := http.StatusOK
+	var received *http.Request
+	var body []byte
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		if status == http.StatusFound {
+			w.Header().Set("Location", "/elsewhere")
+		}
+		var err error
+		body, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	client := webhookClient
+	tlsClient := *webhookClient
+	tlsClient.Transport = srv.Client().Transport
+	webhookClient = &tlsClient
+	defer func() {
+		webhookClient = client
+	}()
+
+	d := db.WebhookDeliveryEntry{
+		Id:      uuid.New(),
+		Event:   webhookEventComposeFinished,
+		Payload: []byte(`{"event":"compose_finished","status":"success"}`),
+		URL:     srv.URL,
+	}
+	secret := "0123456789abcdef"
+	now := time.Unix(1700000000, 0)
+	result, code, err := deliverWebhookEvent(d, secret, nil, now)
+	require.NoError(t, err)
+	require.Equal(t, db.WebhookDeliveryDelivered, result)
+	require.Equal(t, http.StatusOK, *code)
+
+	require.Equal(t, string(d.Payload), string(body))
+	require.Equal(t, webhookEventComposeFinished, received.Header.Get("X-Image-Builder-Event"))
+	require.Equal(t, d.Id.String(), received.Header.Get("X-Image-Builder-Delivery"))
+	require.Equal(t, "1700000000", received.Header.Get("X-Image-Builder-Timestamp"))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("1700000000." + string(d.Payload)))
+	require.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), received.Header.Get("X-Image-Builder-Signature"))
+	require.Empty(t, received.Header.Get("X-Image-Builder-Key-Signature"))
+
+	// the webhook key of the org signs as well
+	key := &webhookKey{uuid.New(), "fedcba9876543210"}
+	_, _, err = deliverWebhookEvent(d, secret, key, now)
+	require.NoError(t, err)
+	require.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), received.Header.Get("X-Image-Builder-Signature"))
+	require.Equal(t, key.id.String(), received.Header.Get("X-Image-Builder-Key-Id"))
+	keyMac := hmac.New(sha256.New, []byte(key.secret))
+	keyMac.Write([]byte("1700000000." + string(d.Payload)))
+	require.Equal(t, "sha256="+hex.EncodeToString(keyMac.Sum(nil)), received.Header.Get("X-Image-Builder-Key-Signature"))
+
+	// failed attempts are retried until the last one
+	status = http.StatusServiceUnavailable
+	result, code, err = deliverWebhookEvent(d, secret, nil, now)
+	require.Error(t, err)
+	require.Equal(t, db.WebhookDeliveryPending, result)
+	require.Equal(t, http.StatusServiceUnavailable, *code)
+	d.Attempts = webhookMaxAttempts - 1
+	result, _, err = deliverWebhookEvent(d, secret, nil, now)
+	require.Error(t, err)
+	require.Equal(t, db.WebhookDeliveryFailed, result)
+
+	// redirects aren't followed
+	status = http.StatusFound
+	d.Attempts = 0
+	result, code, err = deliverWebhookEvent(d, secret, nil, now)
+	require.Error(t, err)
+	require.Equal(t, db.WebhookDeliveryPending, result)
+	require.Equal(t, http.StatusFound, *code)
+	require.Equal(t, "/", received.URL.Path)
+}
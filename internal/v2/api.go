@@ -0,0 +1,339 @@
+// Package v2 provides primitives to interact with the openapi HTTP API.
+//
+// Code generated by github.com/deepmap/oapi-codegen version v1.12.4 DO NOT EDIT.
+package v2
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/deepmap/oapi-codegen/pkg/runtime"
+	openapi_types "github.com/deepmap/oapi-codegen/pkg/types"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+)
+
+// Compose defines model for Compose.
+type Compose struct {
+	CreatedAt time.Time          `json:"created_at"`
+	Id        openapi_types.UUID `json:"id"`
+	ImageName *string            `json:"image_name,omitempty"`
+
+	// Request the compose request, as it was sent to v1
+	Request map[string]interface{} `json:"request"`
+
+	// Status status composer last reported, missing if it was never asked
+	Status *string `json:"status,omitempty"`
+}
+
+// Error defines model for Error.
+type Error struct {
+	// Code Machine readable code of the error, e.g. `NOT_FOUND` or `QUOTA_EXCEEDED`. Codes aren't changed or
+	// removed within a major version.
+	Code string `json:"code"`
+
+	// Message what went wrong, for humans
+	Message string `json:"message"`
+}
+
+// ErrorResponse defines model for ErrorResponse.
+type ErrorResponse = Error
+
+// ListComposesParams defines parameters for ListComposes.
+type ListComposesParams struct {
+	// Limit max amount of composes, default 100
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Cursor Where the page starts, taken from the `Link` header of the previous page.
+	Cursor *string `form:"cursor,omitempty" json:"cursor,omitempty"`
+
+	// IgnoreImageTypes Filter the composes on image type. The filter is optional and can be specified multiple times.
+	IgnoreImageTypes *[]string `form:"ignoreImageTypes,omitempty" json:"ignoreImageTypes,omitempty"`
+}
+
+// CreateComposeJSONBody defines parameters for CreateCompose.
+type CreateComposeJSONBody = map[string]interface{}
+
+// CreateComposeParams defines parameters for CreateCompose.
+type CreateComposeParams struct {
+	// IdempotencyKey Key of the request chosen by the client, e.g. a uuid. Keys are unique within the
+	// organization, a key can't be used for a different request.
+	IdempotencyKey *string `json:"Idempotency-Key,omitempty"`
+}
+
+// CreateComposeJSONRequestBody defines body for CreateCompose for application/json ContentType.
+type CreateComposeJSONRequestBody = CreateComposeJSONBody
+
+// ServerInterface represents all server handlers.
+type ServerInterface interface {
+	// list the composes of the organization
+	// (GET /composes)
+	ListComposes(ctx echo.Context, params ListComposesParams) error
+	// compose an image
+	// (POST /composes)
+	CreateCompose(ctx echo.Context, params CreateComposeParams) error
+	// delete a compose
+	// (DELETE /composes/{composeId})
+	DeleteCompose(ctx echo.Context, composeId openapi_types.UUID) error
+	// get a compose
+	// (GET /composes/{composeId})
+	GetCompose(ctx echo.Context, composeId openapi_types.UUID) error
+	// get the openapi json specification
+	// (GET /openapi.json)
+	GetOpenapiJson(ctx echo.Context) error
+}
+
+// ServerInterfaceWrapper converts echo contexts to parameters.
+type ServerInterfaceWrapper struct {
+	Handler ServerInterface
+}
+
+// ListComposes converts echo context to params.
+func (w *ServerInterfaceWrapper) ListComposes(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListComposesParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "cursor" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "cursor", ctx.QueryParams(), &params.Cursor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter cursor: %s", err))
+	}
+
+	// ------------- Optional query parameter "ignoreImageTypes" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "ignoreImageTypes", ctx.QueryParams(), &params.IgnoreImageTypes)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter ignoreImageTypes: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ListComposes(ctx, params)
+	return err
+}
+
+// CreateCompose converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateCompose(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params CreateComposeParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "Idempotency-Key" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("Idempotency-Key")]; found {
+		var IdempotencyKey string
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for Idempotency-Key, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "Idempotency-Key", runtime.ParamLocationHeader, valueList[0], &IdempotencyKey)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter Idempotency-Key: %s", err))
+		}
+
+		params.IdempotencyKey = &IdempotencyKey
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.CreateCompose(ctx, params)
+	return err
+}
+
+// DeleteCompose converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteCompose(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "composeId" -------------
+	var composeId openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "composeId", runtime.ParamLocationPath, ctx.Param("composeId"), &composeId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter composeId: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.DeleteCompose(ctx, composeId)
+	return err
+}
+
+// GetCompose converts echo context to params.
+func (w *ServerInterfaceWrapper) GetCompose(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "composeId" -------------
+	var composeId openapi_types.UUID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "composeId", runtime.ParamLocationPath, ctx.Param("composeId"), &composeId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter composeId: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetCompose(ctx, composeId)
+	return err
+}
+
+// GetOpenapiJson converts echo context to params.
+func (w *ServerInterfaceWrapper) GetOpenapiJson(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetOpenapiJson(ctx)
+	return err
+}
+
+// This is a simple interface which specifies echo.Route addition functions which
+// are present on both echo.Echo and echo.Group, since we want to allow using
+// either of them for path registration
+type EchoRouter interface {
+	CONNECT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	DELETE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	HEAD(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	OPTIONS(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	PATCH(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	PUT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+	TRACE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+}
+
+// RegisterHandlers adds each server route to the EchoRouter.
+func RegisterHandlers(router EchoRouter, si ServerInterface) {
+	RegisterHandlersWithBaseURL(router, si, "")
+}
+
+// Registers handlers, and prepends BaseURL to the paths, so that the paths
+// can be served under a prefix.
+func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL string) {
+
+	wrapper := ServerInterfaceWrapper{
+		Handler: si,
+	}
+
+	router.GET(baseURL+"/composes", wrapper.ListComposes)
+	router.POST(baseURL+"/composes", wrapper.CreateCompose)
+	router.DELETE(baseURL+"/composes/:composeId", wrapper.DeleteCompose)
+	router.GET(baseURL+"/composes/:composeId", wrapper.GetCompose)
+	router.GET(baseURL+"/openapi.json", wrapper.GetOpenapiJson)
+
+}
+
+// Base64 encoded, gzipped, json marshaled Swagger object
+var swaggerSpec = []string{
+
+	"H4sIAAAAAAAC/8RYbW8buRH+KwO2wH1ZrV4ipz0B/ZA6vsJNmqB3ObTAybDGy9Eu411yTXIlq4H+ezHk",
+	"rt5Wdu6covfF0JKct2dmHg79RWSmqo0m7Z2YfRGWXG20o/BxZa2xP7YrvJAZ7Ul7/ol1XaoMvTJ6+NkZ",
+	"zWsuK6hC/vVHS0sxE38Y7rUP464bBq1iu90mQpLLrKpZiZgJXxBYemjIeViiKkkKPtTKsdpL1hZdqa2p",
+	"yXoVPc0soSd5i8G3pbEV/xISPQ28qkgkwm9qEjPhvFU6F9tEKHl0tmmUPHuswpxuNVbBbG+7dZj3+tFk",
+	"0d8uqgTQgfKwRgeOtAdvYDXeGzV3nynzrNV59I3rK43rnV4LJToPlmpjPckEKuWc0jmoZWdG04osoLsn",
+	"Do4esarLEECTZeRcP+A2JGVJitkvImBygO4+4JszbsfU9rNjJPVj+QdmhdIMDkq8KxktSWCWwMgRa0qA",
+	"0jyFxYePn25/+Pjzh7cLMBYW//z546c3t1f/vry6env1dpHCpZHkAC3p7zxkBeqcJBg715YqsyIJa+UL",
+	"pQGhws/GwoqsU0anc32Eyc7MuTKoyDnMz4SxLtDDmrO5tkbnCSyNhaKpUH8d3QDMXncfUhZQemn6Zn8i",
+	"u1IZgWfzlkrcOAilCneNKmVXci6d67n+kZxpbBZBAku+sZokV6MvaMOLScDINB5QA+kVlaYmRrYsKWOT",
+	"QXSu0VrcuARqzFtcIWusM9bNQt4W75W+X0BBKMlyMjEchSLagsaWbYrnWtOjB6MpqllYKv8yF7w4F4sE",
+	"UEtQblfSRgf5UPCsMYVQbJ1b5ZoBQA2LsLyI8QDCgjFeQFYq0t5BhprR2kCX/lJl1LJb7HHxpsasIJik",
+	"I5GIxpZiJgrvazcbDtfrdYphOzU2H7aybvj++vLqw09Xg0k6SgtflVwwXvlQVdeclEFICllwMW0iEW0R",
+	"iplgS9tEmJo01krMxKt0lDIt1OiL0EDDtuHDR05n2Oa9cj4i3B3tOikgNp6CDHnTtA70qqzzEQBu1EDj",
+	"17LVc9kZYw8sVuTJOjH75dRmhY+AlWm0Z1ud3QQkLbEpPYxHjKDiow8N2Y1IOohLVSkvkoMLo5URs/Fo",
+	"lLBmVTVV96V0+7XrD6U95cS3SHLq1b8KshQiD4XnPFrvEvB4TxqW1lTn6zQIWFop07hYYHP9hPex4I/c",
+	"77X5qVc/qNKTPUmQbjuWpVP4VBAs4zHlwARJLEMfcNXeEbiaMrVUJKFqSq/qkoAvN/e0qyrXxlIowU+b",
+	"mtyR08pT5c5eau1CaHax3d4kx3PBZDT6TdPAztBzY0F3t/fN9waFllMOik4kIqYymOHc9ltkTz0QmCfo",
+	"CCxxyDzJk4zzbMLZyelo9FSQO/iGxzMVi7mmqtBuQls4f7aHjc1Rq/8EfBmg2pybOLq+DSKxsgIB96aQ",
+	"daGyAlQ8eLLLIqtxCm92C5FI9VwvriVVtfGks83gHW0WrKIVl2B0RjOwVBP6MIO0kmzDYUVwT5v26jk2",
+	"3F0HgZP2TjoDuPehiC5G2CA3HkrjfNcYlrxVJHd3WGBbdmKHxFz7tcroHOVdhummK7+vcN472nRJ6XzL",
+	"2DcNd5sYVbho2skFgafKFN7RJl69jVYPDXXzSAj7MLcJYIApQ55k7ggaRpYHCgSplkuypHcQHXR9rP19",
+	"258k6qhyK3x8Tzr3hZhNLi4Cu3bf4/68crMb+P5q5OY3df2vHYe/e34YPp6ZvG1o+4109KtY6PzzpIuA",
+	"R+t2KubEowZCWyqyx12zq321TwgnmHt4Mhr/jl6LF/IVS716kdT0JVKTyYukvv82Hu7wwvaCDtu7GWz4",
+	"pf11LbexzkvyZx4Gb8O62xNwckR7a1WW4Dz/zcIM5c0arXTw0BiPKQRx5jCEaEH2GPOQOiC86Eg6wByV",
+	"Pkd00aE90Z000fT5plWu8+Ol+TzCOOrag8NK28n22Ou/kX/S5f933/9PIs/JH4f97IVzLQ/mnI7v+WFw",
+	"MI925ShOmfKQ9/fv3PHkFU0vXv9pQH/+/m4wnshXA5xevB5MJ69fX1xMp6NRGNy/8p+R7Q03RftoSTu0",
+	"n0rhx3ju73zsG9N45n44hmw/ZHDNmqypWG8/CWGyin4Bm+nm62iZI8acUyIq8ihugiV+v3WJim/DIdZq",
+	"qA4fecPVRGyTZ/f5xXez/e8AL5SA+n0TAAA=",
+}
+
+// GetSwagger returns the content of the embedded swagger specification file
+// or error if failed to decode
+func decodeSpec() ([]byte, error) {
+	zipped, err := base64.StdEncoding.DecodeString(strings.Join(swaggerSpec, ""))
+	if err != nil {
+		return nil, fmt.Errorf("error base64 decoding spec: %s", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(zipped))
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing spec: %s", err)
+	}
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(zr)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing spec: %s", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+var rawSpec = decodeSpecCached()
+
+// a naive cached of a decoded swagger spec
+func decodeSpecCached() func() ([]byte, error) {
+	data, err := decodeSpec()
+	return func() ([]byte, error) {
+		return data, err
+	}
+}
+
+// Constructs a synthetic filesystem for resolving external references when loading openapi specifications.
+func PathToRawSpec(pathToFile string) map[string]func() ([]byte, error) {
+	var res = make(map[string]func() ([]byte, error))
+	if len(pathToFile) > 0 {
+		res[pathToFile] = rawSpec
+	}
+
+	return res
+}
+
+// GetSwagger returns the Swagger specification corresponding to the generated code
+// in this file. The external references of Swagger specification are resolved.
+// The logic of resolving external references is tightly connected to "import-mapping" feature.
+// Externally referenced files must be embedded in the corresponding golang packages.
+// Urls can be supported but this task was out of the scope.
+func GetSwagger() (swagger *openapi3.T, err error) {
+	var resolvePath = PathToRawSpec("")
+
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	loader.ReadFromURIFunc = func(loader *openapi3.Loader, url *url.URL) ([]byte, error) {
+		var pathToFile = url.String()
+		pathToFile = path.Clean(pathToFile)
+		getSpec, ok := resolvePath[pathToFile]
+		if !ok {
+			err1 := fmt.Errorf("path not found: %s", pathToFile)
+			return nil, err1
+		}
+		return getSpec()
+	}
+	var specData []byte
+	specData, err = rawSpec()
+	if err != nil {
+		return
+	}
+	swagger, err = loader.LoadFromData(specData)
+	if err != nil {
+		return
+	}
+	return
+}
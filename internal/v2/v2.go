@@ -0,0 +1,5 @@
+// Package v2 is the generated second version of the api, which is served
+// by the v1 server alongside the first one.
+package v2
+
+//go:generate go run -mod=mod github.com/deepmap/oapi-codegen/cmd/oapi-codegen --config server.cfg.yaml -o api.go api.yaml
@@ -0,0 +1,299 @@
+// Package vulnscan submits the packages of images to a vulnerability scanner
+// and returns what it found. Trivy's server mode is supported, it scans the
+// package list alone. Clair only indexes the layers of container images.
+package vulnscan
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/osbuild/image-builder/internal/common"
+)
+
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	SeverityUnknown  = "unknown"
+)
+
+// Severities in descending order.
+var Severities = []string{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityUnknown}
+
+// OS is the distribution the packages belong to, which selects the advisories
+// they're matched against, e.g. redhat and 9.
+type OS struct {
+	Family  string
+	Version string
+}
+
+type Package struct {
+	Name    string
+	Epoch   int
+	Version string
+	Release string
+	Arch    string
+}
+
+// Manifest is what's installed in an image.
+type Manifest struct {
+	// Shows up as the target of the scan, e.g. the compose id
+	Name     string
+	OS       OS
+	Packages []Package
+}
+
+// digest identifies the manifest in the cache of the scanner.
+func (m Manifest) digest() (string, error) {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// Finding is a vulnerability of an installed package.
+type Finding struct {
+	// CVE or advisory id
+	Id               string `json:"id"`
+	Package          string `json:"package"`
+	InstalledVersion string `json:"installed_version"`
+	// Empty if there's no fix yet
+	FixedVersion string `json:"fixed_version,omitempty"`
+	Severity     string `json:"severity"`
+	Title        string `json:"title,omitempty"`
+	URL          string `json:"url,omitempty"`
+}
+
+// Summary counts findings by severity.
+func Summary(findings []Finding) map[string]int {
+	summary := map[string]int{}
+	for _, s := range Severities {
+		summary[s] = 0
+	}
+	for _, f := range findings {
+		summary[f.Severity]++
+	}
+	return summary
+}
+
+// Default timeouts, scans of a few thousand packages take a while.
+var defaultTimeouts = common.HTTPTimeouts{
+	Connect: 30 * time.Second,
+	Read:    5 * time.Minute,
+	Request: 10 * time.Minute,
+}
+
+// Trivy scans with a trivy server, as `trivy server` runs it.
+type Trivy struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+type TrivyConfig struct {
+	// Base url of the server, e.g. http://trivy:4954
+	URL string
+	// Optional, the --token the server was started with
+	Token string
+	// Defaults to a request timeout of 10 minutes.
+	Timeouts common.HTTPTimeouts
+	// Optional, e.g. for servers with certificates of a private CA.
+	TLSConfig *tls.Config
+}
+
+func NewTrivy(conf TrivyConfig) *Trivy {
+	if conf.Timeouts == (common.HTTPTimeouts{}) {
+		conf.Timeouts = defaultTimeouts
+	}
+	return &Trivy{
+		url:    strings.TrimSuffix(conf.URL, "/"),
+		token:  conf.Token,
+		client: common.NewHTTPClient(conf.Timeouts, conf.TLSConfig),
+	}
+}
+
+func (t *Trivy) Name() string {
+	return "trivy"
+}
+
+// The messages of the twirp api of trivy, in their json encoding.
+type trivyPackage struct {
+	Name    string `json:"name"`
+	Epoch   int    `json:"epoch,omitempty"`
+	Version string `json:"version"`
+	Release string `json:"release"`
+	Arch    string `json:"arch"`
+}
+
+type trivyOS struct {
+	Family string `json:"family"`
+	Name   string `json:"name"`
+}
+
+type trivyPackageInfo struct {
+	FilePath string         `json:"file_path"`
+	Packages []trivyPackage `json:"packages"`
+}
+
+type trivyBlobInfo struct {
+	SchemaVersion int                `json:"schema_version"`
+	OS            trivyOS            `json:"os"`
+	PackageInfos  []trivyPackageInfo `json:"package_infos"`
+}
+
+type trivyVulnerability struct {
+	VulnerabilityId  string `json:"vulnerability_id"`
+	PkgName          string `json:"pkg_name"`
+	InstalledVersion string `json:"installed_version"`
+	FixedVersion     string `json:"fixed_version"`
+	Severity         string `json:"severity"`
+	Title            string `json:"title"`
+	PrimaryURL       string `json:"primary_url"`
+}
+
+type trivyScanResponse struct {
+	Results []struct {
+		Target          string               `json:"target"`
+		Vulnerabilities []trivyVulnerability `json:"vulnerabilities"`
+	} `json:"results"`
+}
+
+// Scan puts the packages into the cache of the server as the single layer
+// of an artifact, and scans that artifact.
+func (t *Trivy) Scan(ctx context.Context, m Manifest) ([]Finding, error) {
+	digest, err := m.digest()
+	if err != nil {
+		return nil, err
+	}
+
+	// the packages are reported as if they were read from the rpm database
+	info := trivyPackageInfo{FilePath: "var/lib/rpm/rpmdb.sqlite"}
+	for _, p := range m.Packages {
+		info.Packages = append(info.Packages, trivyPackage(p))
+	}
+	blob := trivyBlobInfo{
+		SchemaVersion: 2,
+		OS:            trivyOS{Family: m.OS.Family, Name: m.OS.Version},
+		PackageInfos:  []trivyPackageInfo{info},
+	}
+
+	err = t.call(ctx, "trivy.cache.v1.Cache/PutBlob", map[string]interface{}{
+		"diff_id":   digest,
+		"blob_info": blob,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = t.call(ctx, "trivy.cache.v1.Cache/PutArtifact", map[string]interface{}{
+		"artifact_id":   digest,
+		"artifact_info": map[string]int{"schema_version": 1},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp trivyScanResponse
+	err = t.call(ctx, "trivy.scanner.v1.Scanner/Scan", map[string]interface{}{
+		"target":      m.Name,
+		"artifact_id": digest,
+		"blob_ids":    []string{digest},
+		"options": map[string][]string{
+			// older servers know the package types as vuln_type
+			"vuln_type": {"os"},
+			"pkg_types": {"os"},
+			"scanners":  {"vuln"},
+		},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, r := range resp.Results {
+		for _, v := range r.Vulnerabilities {
+			findings = append(findings, Finding{
+				Id:               v.VulnerabilityId,
+				Package:          v.PkgName,
+				InstalledVersion: v.InstalledVersion,
+				FixedVersion:     v.FixedVersion,
+				Severity:         severity(v.Severity),
+				Title:            v.Title,
+				URL:              v.PrimaryURL,
+			})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank(findings[i].Severity) < severityRank(findings[j].Severity)
+	})
+	return findings, nil
+}
+
+// call calls a method of the twirp api of the server.
+func (t *Trivy) call(ctx context.Context, method string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/twirp/%s", t.url, method), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.token != "" {
+		req.Header.Set("Trivy-Token", t.token)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var twirpErr struct {
+			Code string `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(raw, &twirpErr) == nil && twirpErr.Code != "" {
+			return fmt.Errorf("trivy %s failed with %s: %s", method, twirpErr.Code, twirpErr.Msg)
+		}
+		return fmt.Errorf("trivy %s failed with %d: %s", method, resp.StatusCode, raw)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// severity maps the severities of trivy, e.g. HIGH, to the ones of findings.
+func severity(s string) string {
+	s = strings.ToLower(s)
+	for _, known := range Severities {
+		if s == known {
+			return s
+		}
+	}
+	return SeverityUnknown
+}
+
+func severityRank(s string) int {
+	for i, known := range Severities {
+		if s == known {
+			return i
+		}
+	}
+	return len(Severities)
+}
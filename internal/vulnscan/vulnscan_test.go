@@ -0,0 +1,80 @@
+package vulnscan
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrivyScan(t *testing.T) {
+	manifest := Manifest{
+		Name: "1a5d8a50-6a1e-4a56-8c2d-1e6f0c8b3c4d",
+		OS:   OS{Family: "redhat", Version: "9"},
+		Packages: []Package{
+			{Name: "bash", Version: "5.1.8", Release: "6.el9", Arch: "x86_64"},
+			{Name: "openssl-libs", Epoch: 1, Version: "3.0.7", Release: "16.el9", Arch: "x86_64"},
+		},
+	}
+	var blobId string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Trivy-Token") != "trivytoken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code": "unauthenticated", "msg": "invalid token"}`))
+			return
+		}
+		var body map[string]json.RawMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		switch r.URL.Path {
+		case "/twirp/trivy.cache.v1.Cache/PutBlob":
+			require.NoError(t, json.Unmarshal(body["diff_id"], &blobId))
+			require.JSONEq(t, `{
+				"schema_version": 2,
+				"os": {"family": "redhat", "name": "9"},
+				"package_infos": [{
+					"file_path": "var/lib/rpm/rpmdb.sqlite",
+					"packages": [
+						{"name": "bash", "version": "5.1.8", "release": "6.el9", "arch": "x86_64"},
+						{"name": "openssl-libs", "epoch": 1, "version": "3.0.7", "release": "16.el9", "arch": "x86_64"}
+					]
+				}]
+			}`, string(body["blob_info"]))
+			_, _ = w.Write([]byte(`{}`))
+		case "/twirp/trivy.cache.v1.Cache/PutArtifact":
+			require.Equal(t, `"`+blobId+`"`, string(body["artifact_id"]))
+			_, _ = w.Write([]byte(`{}`))
+		case "/twirp/trivy.scanner.v1.Scanner/Scan":
+			require.Equal(t, `"`+manifest.Name+`"`, string(body["target"]))
+			require.Equal(t, `["`+blobId+`"]`, string(body["blob_ids"]))
+			_, _ = w.Write([]byte(`{
+				"os": {"family": "redhat", "name": "9"},
+				"results": [{
+					"target": "1a5d8a50-6a1e-4a56-8c2d-1e6f0c8b3c4d (redhat 9)",
+					"vulnerabilities": [
+						{"vulnerability_id": "CVE-2022-3715", "pkg_name": "bash", "installed_version": "5.1.8-6.el9", "severity": "LOW", "title": "a heap-buffer-overflow in valid_parameter_transform"},
+						{"vulnerability_id": "CVE-2023-0286", "pkg_name": "openssl-libs", "installed_version": "1:3.0.7-16.el9", "fixed_version": "1:3.0.7-17.el9", "severity": "HIGH", "primary_url": "https://access.redhat.com/security/cve/CVE-2023-0286"},
+						{"vulnerability_id": "CVE-2023-9999", "pkg_name": "bash", "installed_version": "5.1.8-6.el9", "severity": "NEGLIGIBLE"}
+					]
+				}]
+			}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	findings, err := NewTrivy(TrivyConfig{URL: srv.URL + "/", Token: "trivytoken"}).Scan(context.Background(), manifest)
+	require.NoError(t, err)
+	require.Equal(t, []Finding{
+		{Id: "CVE-2023-0286", Package: "openssl-libs", InstalledVersion: "1:3.0.7-16.el9", FixedVersion: "1:3.0.7-17.el9", Severity: SeverityHigh, URL: "https://access.redhat.com/security/cve/CVE-2023-0286"},
+		{Id: "CVE-2022-3715", Package: "bash", InstalledVersion: "5.1.8-6.el9", Severity: SeverityLow, Title: "a heap-buffer-overflow in valid_parameter_transform"},
+		{Id: "CVE-2023-9999", Package: "bash", InstalledVersion: "5.1.8-6.el9", Severity: SeverityUnknown},
+	}, findings)
+	require.Equal(t, map[string]int{"critical": 0, "high": 1, "medium": 0, "low": 1, "unknown": 1}, Summary(findings))
+
+	_, err = NewTrivy(TrivyConfig{URL: srv.URL}).Scan(context.Background(), manifest)
+	require.ErrorContains(t, err, "trivy trivy.cache.v1.Cache/PutBlob failed with unauthenticated: invalid token")
+}
@@ -0,0 +1,134 @@
+// Package ibtest runs a real image builder in tests, for services and tools
+// which talk to it and would otherwise mock its api.
+//
+// The service runs against the fakes of composer and provisioning of the
+// development mode and an in-memory database:
+//
+//	srv := ibtest.StartServer(t, ibtest.Config{})
+//	status, body := tutils.GetResponseBody(t, srv.APIURL()+"/composes", &tutils.AuthString0)
+//
+// To test against postgres, pass a database of tutils.PSQLContainer:
+//
+//	psql, err := tutils.NewPSQLContainer()
+//	...
+//	dbURL, err := psql.NewDatabase()
+//	...
+//	srv := ibtest.StartServer(t, ibtest.Config{DatabaseURL: dbURL})
+//
+// Requests are authenticated with identity headers, as behind the gateway of
+// console.redhat.com.
+package ibtest
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/composer"
+	"github.com/osbuild/image-builder/internal/db"
+	"github.com/osbuild/image-builder/internal/devmode"
+	"github.com/osbuild/image-builder/internal/distribution"
+	"github.com/osbuild/image-builder/internal/provisioning"
+	v1 "github.com/osbuild/image-builder/internal/v1"
+)
+
+type Config struct {
+	// Url of a database with the schema of image builder, e.g. of
+	// tutils.PSQLContainer.NewDatabase. The database is in memory if empty.
+	DatabaseURL string
+
+	// The distributions of the module if empty.
+	DistributionsDir string
+
+	// Optional allow and quota files, nothing is restricted without them.
+	AllowFile string
+	QuotaFile string
+
+	// How long composes take, devmode.DefaultBuildTime if 0.
+	BuildTime time.Duration
+}
+
+// Server is an image builder started by StartServer, it's stopped when the
+// test is done.
+type Server struct {
+	// Where the server listens, e.g. for client.Config.
+	URL string
+
+	// The fakes the server talks to.
+	Fakes *devmode.Server
+}
+
+// APIURL is the base url of the v1 api.
+func (s *Server) APIURL() string {
+	return s.URL + v1.RoutePrefix() + "/v1"
+}
+
+// StartServer starts image builder on a free port of localhost.
+func StartServer(t *testing.T, conf Config) *Server {
+	t.Helper()
+
+	f := devmode.New()
+	f.BuildTime = conf.BuildTime
+	fakes, err := f.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = fakes.Close() })
+
+	compClient, err := composer.NewClient(composer.ComposerClientConfig{
+		ComposerURL:  fakes.ComposerURL,
+		TokenURL:     fakes.TokenURL,
+		ClientId:     "ibtest",
+		OfflineToken: "ibtest",
+	})
+	require.NoError(t, err)
+	provClient, err := provisioning.NewClient(provisioning.ProvisioningClientConfig{
+		URL: fakes.ProvisioningURL,
+	})
+	require.NoError(t, err)
+
+	dbase := db.NewMemoryDB()
+	if conf.DatabaseURL != "" {
+		dbase, err = db.InitDBConnectionPool(conf.DatabaseURL)
+		require.NoError(t, err)
+	}
+
+	distsDir := conf.DistributionsDir
+	if distsDir == "" {
+		distsDir = distributionsDir()
+	}
+	adr, err := distribution.LoadDistroRegistry(distsDir)
+	require.NoError(t, err)
+
+	echoServer := echo.New()
+	echoServer.HideBanner = true
+	err = v1.Attach(&v1.ServerConfig{
+		EchoServer:       echoServer,
+		CompClient:       compClient,
+		ProvClient:       provClient,
+		DBase:            dbase,
+		QuotaFile:        conf.QuotaFile,
+		AllowFile:        conf.AllowFile,
+		AllDistros:       adr,
+		DistributionsDir: distsDir,
+		Authenticator:    v1.NewIdentityHeaderAuthenticator(v1.ServiceAccountConfig{}),
+	})
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(echoServer)
+	t.Cleanup(srv.Close)
+	return &Server{
+		URL:   srv.URL,
+		Fakes: fakes,
+	}
+}
+
+// distributionsDir is where the distributions of the module are, also when
+// the package is used from the module cache.
+func distributionsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "distributions")
+}
@@ -0,0 +1,58 @@
+package ibtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/image-builder/internal/client"
+	v1 "github.com/osbuild/image-builder/internal/v1"
+	"github.com/osbuild/image-builder/pkg/tutils"
+)
+
+func TestStartServer(t *testing.T) {
+	testCompose(t, StartServer(t, Config{BuildTime: time.Millisecond}))
+}
+
+func TestStartServerPostgres(t *testing.T) {
+	psql, err := tutils.NewPSQLContainer()
+	if err != nil {
+		t.Skipf("postgres is needed: %v", err)
+	}
+	defer func() {
+		require.NoError(t, psql.Stop())
+	}()
+	dbURL, err := psql.NewDatabase()
+	require.NoError(t, err)
+
+	testCompose(t, StartServer(t, Config{DatabaseURL: dbURL, BuildTime: time.Millisecond}))
+}
+
+func testCompose(t *testing.T, srv *Server) {
+	c, err := client.New(client.Config{URL: srv.URL, Identity: tutils.AuthString0})
+	require.NoError(t, err)
+
+	var uo v1.UploadRequest_Options
+	require.NoError(t, uo.FromAWSS3UploadRequestOptions(v1.AWSS3UploadRequestOptions{}))
+	id, err := c.Compose(context.Background(), v1.ComposeRequest{
+		Distribution: "rhel-9",
+		ImageRequests: []v1.ImageRequest{
+			{
+				Architecture: "x86_64",
+				ImageType:    v1.ImageTypesGuestImage,
+				UploadRequest: v1.UploadRequest{
+					Type:    v1.UploadTypesAwsS3,
+					Options: uo,
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+	status, err := c.ComposeStatus(context.Background(), id, 0)
+	require.NoError(t, err)
+	require.Equal(t, v1.ImageStatusStatusSuccess, status.ImageStatus.Status)
+}
@@ -0,0 +1,238 @@
+package tutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// RecordFixturesEnv is the env variable which makes NewFixtureServer record
+// its fixture from the upstream instead of replaying it.
+const RecordFixturesEnv = "RECORD_FIXTURES"
+
+const redacted = "REDACTED"
+
+// Interaction is a request to a service the tests talk to, and its
+// response, as they're kept in fixture files.
+type Interaction struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+type FixtureRequest struct {
+	Method string `json:"method"`
+	// Path with the query, if any
+	Path string          `json:"path"`
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+type FixtureResponse struct {
+	Status      int             `json:"status"`
+	ContentType string          `json:"content_type,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"`
+	// Text is the body of responses which aren't json
+	Text string `json:"text,omitempty"`
+}
+
+// FixtureUpstream is the real service a fixture is recorded from. Token
+// replaces the access token of the client under test, which only knows
+// the token server of the test.
+type FixtureUpstream struct {
+	URL   string
+	Token string
+}
+
+// UpstreamFromEnv is the upstream of the env variables <prefix>_URL and
+// <prefix>_TOKEN, e.g. RECORD_COMPOSER_URL and RECORD_COMPOSER_TOKEN.
+func UpstreamFromEnv(prefix string) FixtureUpstream {
+	return FixtureUpstream{
+		URL:   os.Getenv(prefix + "_URL"),
+		Token: os.Getenv(prefix + "_TOKEN"),
+	}
+}
+
+// NewFixtureServer serves the interactions of the fixture file at path, the
+// first unused one of the same method and path answers a request. Once
+// they're used up the last one answers again, so polling a status works.
+// Requests which aren't in the fixture fail the test.
+//
+// With RECORD_FIXTURES=1 requests are forwarded to the upstream instead and
+// the fixture file is written once the test finished. Tokens, passwords and
+// secrets are redacted from what's recorded.
+func NewFixtureServer(t *testing.T, path string, upstream FixtureUpstream) *httptest.Server {
+	if os.Getenv(RecordFixturesEnv) != "" {
+		if upstream.URL == "" {
+			t.Fatalf("%s is set, but there's no upstream to record %s from", RecordFixturesEnv, path)
+		}
+		return newRecorder(t, path, upstream)
+	}
+
+	raw, err := os.ReadFile(filepath.Clean(path))
+	require.NoError(t, err)
+	var interactions []Interaction
+	require.NoError(t, json.Unmarshal(raw, &interactions))
+
+	var mu sync.Mutex
+	used := make([]bool, len(interactions))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		match := -1
+		for i, in := range interactions {
+			if in.Request.Method != r.Method || in.Request.Path != redactQuery(r.URL) {
+				continue
+			}
+			match = i
+			if !used[i] {
+				break
+			}
+		}
+		if match < 0 {
+			t.Errorf("%s %s isn't in fixture %s", r.Method, r.URL.RequestURI(), path)
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		used[match] = true
+		writeFixtureResponse(t, w, interactions[match].Response)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeFixtureResponse(t *testing.T, w http.ResponseWriter, resp FixtureResponse) {
+	if resp.ContentType != "" {
+		w.Header().Set("Content-Type", resp.ContentType)
+	}
+	w.WriteHeader(resp.Status)
+	body := []byte(resp.Text)
+	if resp.Body != nil {
+		body = resp.Body
+	}
+	_, err := w.Write(body)
+	require.NoError(t, err)
+}
+
+func newRecorder(t *testing.T, path string, upstream FixtureUpstream) *httptest.Server {
+	var mu sync.Mutex
+	var interactions []Interaction
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBody, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(r.Method, strings.TrimSuffix(upstream.URL, "/")+r.URL.RequestURI(), bytes.NewReader(reqBody))
+		require.NoError(t, err)
+		req.Header = r.Header.Clone()
+		if upstream.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+upstream.Token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Errorf("Unable to forward %s %s to %s: %v", r.Method, r.URL.RequestURI(), upstream.URL, err)
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		in := Interaction{
+			Request: FixtureRequest{
+				Method: r.Method,
+				Path:   redactQuery(r.URL),
+				Body:   redactBody(t, reqBody),
+			},
+			Response: FixtureResponse{
+				Status:      resp.StatusCode,
+				ContentType: resp.Header.Get("Content-Type"),
+				Body:        redactBody(t, respBody),
+			},
+		}
+		if in.Response.Body == nil {
+			in.Response.Text = string(respBody)
+		}
+		mu.Lock()
+		interactions = append(interactions, in)
+		mu.Unlock()
+
+		// the test gets what's kept, so it passes the same way on replay
+		writeFixtureResponse(t, w, in.Response)
+	}))
+	t.Cleanup(func() {
+		srv.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		raw, err := json.MarshalIndent(interactions, "", "  ")
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(t, os.WriteFile(path, append(raw, '\n'), 0600))
+		t.Logf("Recorded %d interactions with %s into %s", len(interactions), upstream.URL, path)
+	})
+	return srv
+}
+
+// isSecretKey tells whether values of key are left out of fixtures.
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range []string{"token", "secret", "password", "private_key", "credentials"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactBody returns the json body with secrets redacted, or nil if it's
+// empty or not json.
+func redactBody(t *testing.T, body []byte) json.RawMessage {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	var v interface{}
+	if json.Unmarshal(body, &v) != nil {
+		return nil
+	}
+	raw, err := json.Marshal(redactValue(v))
+	require.NoError(t, err)
+	return raw
+}
+
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			if isSecretKey(k) && value != nil && value != "" {
+				v[k] = redacted
+				continue
+			}
+			v[k] = redactValue(value)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	}
+	return v
+}
+
+func redactQuery(u *url.URL) string {
+	q := u.Query()
+	if len(q) == 0 {
+		return u.RequestURI()
+	}
+	for k := range q {
+		if isSecretKey(k) {
+			q.Set(k, redacted)
+		}
+	}
+	return u.EscapedPath() + "?" + q.Encode()
+}
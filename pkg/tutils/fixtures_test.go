@@ -0,0 +1,67 @@
+package tutils
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixtureServer(t *testing.T) {
+	polls := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer upstream-token", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/token":
+			_, err := w.Write([]byte(`{"access_token": "very-secret", "expires_in": 300}`))
+			require.NoError(t, err)
+		case "/status":
+			polls++
+			_, err := w.Write([]byte(`{"status": "poll-` + strings.Repeat("i", polls) + `"}`))
+			require.NoError(t, err)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	get := func(srv *httptest.Server, path string) string {
+		resp, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	path := filepath.Join(t.TempDir(), "fixtures", "upstream.json")
+	t.Run("record", func(t *testing.T) {
+		t.Setenv(RecordFixturesEnv, "1")
+		srv := NewFixtureServer(t, path, FixtureUpstream{URL: upstream.URL, Token: "upstream-token"})
+		require.JSONEq(t, `{"access_token": "REDACTED", "expires_in": 300}`, get(srv, "/token?client_secret=abc"))
+		require.JSONEq(t, `{"status": "poll-i"}`, get(srv, "/status"))
+		require.JSONEq(t, `{"status": "poll-ii"}`, get(srv, "/status"))
+	})
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(raw), "very-secret")
+	require.NotContains(t, string(raw), "abc")
+	require.NotContains(t, string(raw), "upstream-token")
+
+	t.Run("replay", func(t *testing.T) {
+		srv := NewFixtureServer(t, path, FixtureUpstream{})
+		require.JSONEq(t, `{"access_token": "REDACTED", "expires_in": 300}`, get(srv, "/token?client_secret=xyz"))
+		require.JSONEq(t, `{"status": "poll-i"}`, get(srv, "/status"))
+		require.JSONEq(t, `{"status": "poll-ii"}`, get(srv, "/status"))
+		// the last one answers once they're used up
+		require.JSONEq(t, `{"status": "poll-ii"}`, get(srv, "/status"))
+	})
+	require.Equal(t, 2, polls)
+}
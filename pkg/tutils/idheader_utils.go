@@ -66,12 +66,14 @@ func getBase64Header(header string, orgId string) string {
 	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(header, orgId)))
 }
 
-// returns a base64 encoded string of the idHeader
+// GetCompleteBase64Header returns the identity header of an entitled org admin
+// of orgId.
 func GetCompleteBase64Header(orgId string) string {
 	return getBase64Header(completeIdHeader, orgId)
 }
 
-// returns a base64 encoded string of the idHeader without the orgId
+// GetBase64HeaderWithoutEntitlements returns the identity header of an org
+// admin of orgId without entitlements.
 func GetBase64HeaderWithoutEntitlements(orgId string) string {
 	return getBase64Header(idHeaderWithoutEntitlements, orgId)
 }
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"math/rand"
 	"os/exec"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 
@@ -12,6 +14,8 @@ import (
 	"github.com/osbuild/image-builder/internal/db"
 )
 
+// PSQLContainer is a throwaway postgres in a podman or docker container, each
+// test gets a database of its own with NewDB or NewDatabase.
 type PSQLContainer struct {
 	name string
 	id   string
@@ -35,6 +39,8 @@ func containerRuntime() (string, error) {
 	return "", fmt.Errorf("No container runtime found (looked for podman or docker)")
 }
 
+// NewPSQLContainer starts a postgres container and waits until it accepts
+// connections. Stop it when done.
 func NewPSQLContainer() (*PSQLContainer, error) {
 	rt, err := containerRuntime()
 	if err != nil {
@@ -101,23 +107,32 @@ func (p *PSQLContainer) execQuery(dbase, cmd string) (string, error) {
 	return p.execCommand(args...)
 }
 
+// migrationsDir is where the migrations of the module are, also when the
+// package is used from the module cache.
+func migrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "internal", "db", "migrations-tern")
+}
+
 func (p *PSQLContainer) Stop() error {
 	_, err := p.execCommand("kill", p.name)
 	return err
 }
 
-func (p *PSQLContainer) NewDB() (db.DB, error) {
+// NewDatabase creates a database with the schema of image builder and returns
+// the url to connect to it.
+func (p *PSQLContainer) NewDatabase() (string, error) {
 	dbName := fmt.Sprintf("test%s", strings.Replace(uuid.New().String(), "-", "", -1))
 	_, err := p.execQuery("", fmt.Sprintf("CREATE DATABASE %s", dbName))
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
 	/* #nosec G204 */
 	cmd := exec.Command(
 		"tern",
 		"migrate",
-		"-m", "../db/migrations-tern/",
+		"-m", migrationsDir(),
 		"--database", dbName,
 		"--host", "localhost",
 		"--port", fmt.Sprintf("%d", p.port),
@@ -125,8 +140,17 @@ func (p *PSQLContainer) NewDB() (db.DB, error) {
 	)
 
 	_, err = cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("postgres://postgres@localhost:%d/%s", p.port, dbName), nil
+}
+
+// NewDB is NewDatabase, connected to.
+func (p *PSQLContainer) NewDB() (db.DB, error) {
+	url, err := p.NewDatabase()
 	if err != nil {
 		return nil, err
 	}
-	return db.InitDBConnectionPool(fmt.Sprintf("postgres://postgres@localhost:%d/%s", p.port, dbName))
+	return db.InitDBConnectionPool(url)
 }
@@ -0,0 +1,131 @@
+// Package tutils helps to test against a real image builder, in the tests of
+// image builder and of services and tools which talk to it.
+//
+// Identity headers are built with GetCompleteBase64Header, the Get, Post, Put
+// and Delete helpers send requests as the org of AuthString0 and return the
+// status and body of the response. PSQLContainer runs postgres for the
+// database of the service, see package ibtest to start the service itself.
+// NewFixtureServer replays the recorded requests and responses of services
+// like composer.
+package tutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// org_id 000000
+var AuthString0 = GetCompleteBase64Header("000000")
+var AuthString0WithoutEntitlements = GetBase64HeaderWithoutEntitlements("000000")
+
+// org_id 000001
+var AuthString1 = GetCompleteBase64Header("000001")
+
+// GetResponseError sends a GET request as org 000000, without failing the
+// test if that fails, e.g. while waiting for a server to come up.
+func GetResponseError(url string) (*http.Response, error) {
+	client := &http.Client{}
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Add("x-rh-identity", AuthString0)
+
+	return client.Do(request)
+}
+
+// GetResponseBody sends a GET request with the identity header auth, or none
+// if auth is nil.
+func GetResponseBody(t *testing.T, url string, auth *string) (int, string) {
+	client := &http.Client{}
+	request, err := http.NewRequest("GET", url, nil)
+	require.NoError(t, err)
+	if auth != nil {
+		request.Header.Add("x-rh-identity", *auth)
+	}
+
+	response, err := client.Do(request)
+	require.NoError(t, err)
+	if err != nil {
+		/* #nosec G307 */
+		defer response.Body.Close()
+	}
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	return response.StatusCode, string(body)
+}
+
+// PostResponseBody sends body as json as org 000000.
+func PostResponseBody(t *testing.T, url string, compose interface{}) (int, string) {
+	buf, err := json.Marshal(compose)
+	require.NoError(t, err)
+
+	client := &http.Client{}
+	request, err := http.NewRequest("POST", url, bytes.NewReader(buf))
+	require.NoError(t, err)
+	request.Header.Add("Content-Type", "application/json")
+	request.Header.Add("x-rh-identity", AuthString0)
+
+	response, err := client.Do(request)
+	require.NoError(t, err)
+	if err != nil {
+		/* #nosec G307 */
+		defer response.Body.Close()
+	}
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	return response.StatusCode, string(body)
+}
+
+// PutResponseBody sends body as json as org 000000.
+func PutResponseBody(t *testing.T, url string, body interface{}) (int, string) {
+	buf, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	client := &http.Client{}
+	request, err := http.NewRequest("PUT", url, bytes.NewReader(buf))
+	require.NoError(t, err)
+	request.Header.Add("Content-Type", "application/json")
+	request.Header.Add("x-rh-identity", AuthString0)
+
+	response, err := client.Do(request)
+	require.NoError(t, err)
+	/* #nosec G307 */
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	return response.StatusCode, string(respBody)
+}
+
+// DeleteResponseBody sends a DELETE request with the identity header auth, or
+// none if auth is nil.
+func DeleteResponseBody(t *testing.T, url string, auth *string) (int, string) {
+	client := &http.Client{}
+	request, err := http.NewRequest("DELETE", url, nil)
+	require.NoError(t, err)
+	if auth != nil {
+		request.Header.Add("x-rh-identity", *auth)
+	}
+
+	response, err := client.Do(request)
+	require.NoError(t, err)
+	/* #nosec G307 */
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	return response.StatusCode, string(body)
+}